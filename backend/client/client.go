@@ -0,0 +1,305 @@
+// Package client is a reference Go client for the Silence Notes API.
+//
+// Client wraps the note, sync and change feed endpoints. Replica builds an
+// offline-first copy of a user's notes on top of it: edits are made locally
+// and reconciled with the server by Sync. App developers can use it directly
+// or as a model for their own implementation, and check either with the
+// conformance suite in package synctest.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the API of a Silence Notes server
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client for the API at baseURL, for example
+// "http://localhost:8080/api/v1"
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetToken sets the access token sent with every request
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetHTTPClient replaces the HTTP client used for requests
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// Note is a note as returned by the server
+type Note struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	Version   int       `json:"version"`
+	Tags      []string  `json:"tags,omitempty"`
+	IsPrivate bool      `json:"is_private"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NoteUpdate changes a note. Nil fields are left unchanged. A non-zero
+// Version makes the update conditional: it is sent as If-Match and the
+// update fails with a conflict when the note has changed on the server
+// since that version.
+type NoteUpdate struct {
+	Title   *string `json:"title,omitempty"`
+	Content *string `json:"content,omitempty"`
+	Version int     `json:"-"`
+}
+
+// NoteList is a page of notes
+type NoteList struct {
+	Notes   []Note `json:"notes"`
+	Total   int    `json:"total"`
+	HasMore bool   `json:"has_more"`
+}
+
+// SyncPage is a page of notes changed since a point in time
+type SyncPage struct {
+	Notes      []Note `json:"notes"`
+	Total      int    `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	ServerTime string `json:"server_time"`
+}
+
+// Change is an entry of the change feed
+type Change struct {
+	// Seq is the position of the change among the changes of the user
+	Seq        int64     `json:"seq"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	Operation  string    `json:"operation"`
+	Version    *int      `json:"version,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// ChangeFeed is a page of the change feed. NextCursor is passed to the next
+// ListChanges call.
+type ChangeFeed struct {
+	Changes    []Change `json:"changes"`
+	NextCursor string   `json:"next_cursor"`
+	HasMore    bool     `json:"has_more"`
+}
+
+// APIError is an error response from the server
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	// Current is the server copy of a note whose conditional update
+	// failed, when the server sent it
+	Current *Note
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// IsConflict reports whether err is a version conflict on a conditional update
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed) || hasStatus(err, http.StatusConflict)
+}
+
+// IsNotFound reports whether err is a not found response, e.g. for a note
+// deleted on the server
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsCursorExpired reports whether err means a change feed cursor is older
+// than the feed's retention, so a full sync is needed
+func IsCursorExpired(err error) bool {
+	return hasStatus(err, http.StatusGone)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// CreateNote creates a note
+func (c *Client) CreateNote(ctx context.Context, title, content string) (*Note, error) {
+	return c.CreateNoteWithKey(ctx, "", title, content)
+}
+
+// CreateNoteWithKey creates a note with an Idempotency-Key, so retrying a
+// create whose response was lost returns the note created the first time
+// instead of a second one. An empty key sends no header.
+func (c *Client) CreateNoteWithKey(ctx context.Context, key, title, content string) (*Note, error) {
+	request := map[string]string{"title": title, "content": content}
+	header := http.Header{}
+	if key != "" {
+		header.Set("Idempotency-Key", key)
+	}
+	var note Note
+	if err := c.send(ctx, http.MethodPost, "/notes", header, request, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetNote returns a note
+func (c *Client) GetNote(ctx context.Context, id string) (*Note, error) {
+	var note Note
+	if err := c.do(ctx, http.MethodGet, "/notes/"+url.PathEscape(id), nil, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateNote updates a note
+func (c *Client) UpdateNote(ctx context.Context, id string, update NoteUpdate) (*Note, error) {
+	header := http.Header{}
+	if update.Version != 0 {
+		header.Set("If-Match", strconv.Quote(strconv.Itoa(update.Version)))
+	}
+	var note Note
+	if err := c.send(ctx, http.MethodPut, "/notes/"+url.PathEscape(id), header, update, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// DeleteNote deletes a note
+func (c *Client) DeleteNote(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/notes/"+url.PathEscape(id), nil, nil)
+}
+
+// ListNotes returns a page of notes, newest first
+func (c *Client) ListNotes(ctx context.Context, limit, offset int) (*NoteList, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	var list NoteList
+	if err := c.do(ctx, http.MethodGet, "/notes?"+query.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// SyncNotes returns the notes changed since a point in time. The change feed
+// is preferred for incremental sync, since it also reports deletions.
+func (c *Client) SyncNotes(ctx context.Context, since time.Time, limit, offset int) (*SyncPage, error) {
+	query := url.Values{}
+	query.Set("since", since.UTC().Format(time.RFC3339))
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	var page SyncPage
+	if err := c.do(ctx, http.MethodGet, "/notes/sync?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ListChanges returns the changes after a cursor, oldest first. An empty
+// cursor reads from the start of the feed.
+func (c *Client) ListChanges(ctx context.Context, cursor string, limit int) (*ChangeFeed, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("since", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var feed ChangeFeed
+	if err := c.do(ctx, http.MethodGet, "/changes?"+query.Encode(), nil, &feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// envelope is the standard response format of the API
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Current *Note  `json:"current"`
+	} `json:"error"`
+}
+
+// do sends a request and decodes the data of the response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.send(ctx, method, path, nil, body, out)
+}
+
+// send is do with extra request headers
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var result envelope
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(&result)
+
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if decodeErr == nil && result.Error != nil {
+			apiErr.Code = result.Error.Code
+			apiErr.Message = result.Error.Message
+			apiErr.Current = result.Error.Current
+		}
+		return apiErr
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("failed to decode response data: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/client"
+	"github.com/gpd/my-notes/client/synctest"
+	"github.com/google/uuid"
+)
+
+// fakeServer is an in-memory implementation of the note and change feed
+// endpoints, following the API's versioning and envelope rules
+type fakeServer struct {
+	mu      sync.Mutex
+	notes   map[string]*client.Note
+	changes []client.Change
+	// purged is the number of changes dropped from the start of the feed
+	purged int
+	// created holds the note created for each Idempotency-Key
+	created map[string]client.Note
+	// lostCreates is the number of creates whose response is lost, as if
+	// a proxy timed out after the server handled them
+	lostCreates int
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *httptest.Server) {
+	f := &fakeServer{notes: make(map[string]*client.Note), created: make(map[string]client.Note)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /notes", f.createNote)
+	mux.HandleFunc("GET /notes", f.listNotes)
+	mux.HandleFunc("GET /notes/{id}", f.getNote)
+	mux.HandleFunc("PUT /notes/{id}", f.updateNote)
+	mux.HandleFunc("DELETE /notes/{id}", f.deleteNote)
+	mux.HandleFunc("GET /changes", f.listChanges)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+// purge drops the whole change feed, expiring every cursor
+func (f *fakeServer) purge() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.purged += len(f.changes)
+	f.changes = nil
+}
+
+func (f *fakeServer) record(id, operation string, version int) {
+	f.changes = append(f.changes, client.Change{
+		EntityType: "note",
+		EntityID:   id,
+		Operation:  operation,
+		Version:    &version,
+		ChangedAt:  time.Now(),
+	})
+}
+
+func (f *fakeServer) createNote(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title   string `json:"title"`
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := r.Header.Get("Idempotency-Key")
+	if created, ok := f.created[key]; ok && key != "" {
+		respond(w, http.StatusCreated, created)
+		return
+	}
+	now := time.Now()
+	note := &client.Note{ID: uuid.New().String(), Title: req.Title, Content: req.Content, Version: 1, CreatedAt: now, UpdatedAt: now}
+	f.notes[note.ID] = note
+	f.record(note.ID, "created", note.Version)
+	if key != "" {
+		f.created[key] = *note
+	}
+	if f.lostCreates > 0 {
+		f.lostCreates--
+		respondError(w, http.StatusGatewayTimeout, "TIMEOUT", "Gateway timeout")
+		return
+	}
+	respond(w, http.StatusCreated, note)
+}
+
+func (f *fakeServer) listNotes(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	notes := make([]client.Note, 0, len(f.notes))
+	for _, note := range f.notes {
+		notes = append(notes, *note)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+
+	list := client.NoteList{Notes: []client.Note{}, Total: len(notes)}
+	if offset < len(notes) {
+		end := min(offset+limit, len(notes))
+		list.Notes = notes[offset:end]
+		list.HasMore = end < len(notes)
+	}
+	respond(w, http.StatusOK, list)
+}
+
+func (f *fakeServer) getNote(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	note, ok := f.notes[r.PathValue("id")]
+	if !ok {
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "Note not found")
+		return
+	}
+	respond(w, http.StatusOK, note)
+}
+
+func (f *fakeServer) updateNote(w http.ResponseWriter, r *http.Request) {
+	var req client.NoteUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid request body")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	note, ok := f.notes[r.PathValue("id")]
+	if !ok {
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "Note not found")
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != strconv.Quote(strconv.Itoa(note.Version)) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"error":   map[string]interface{}{"code": "PRECONDITION_FAILED", "message": "Note has been modified", "current": note},
+		})
+		return
+	}
+	if req.Title != nil {
+		note.Title = *req.Title
+	}
+	if req.Content != nil {
+		note.Content = *req.Content
+	}
+	note.Version++
+	note.UpdatedAt = time.Now()
+	f.record(note.ID, "updated", note.Version)
+	respond(w, http.StatusOK, note)
+}
+
+func (f *fakeServer) deleteNote(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	note, ok := f.notes[r.PathValue("id")]
+	if !ok {
+		respondError(w, http.StatusNotFound, "NOT_FOUND", "Note not found")
+		return
+	}
+	delete(f.notes, note.ID)
+	f.record(note.ID, "deleted", note.Version+1)
+	respond(w, http.StatusOK, map[string]string{"message": "Note deleted successfully"})
+}
+
+// listChanges uses the position in the feed as the cursor
+func (f *fakeServer) listChanges(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	position := 0
+	if since := r.URL.Query().Get("since"); since != "" {
+		var err error
+		if position, err = strconv.Atoi(since); err != nil {
+			respondError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid cursor")
+			return
+		}
+		if position < f.purged {
+			respondError(w, http.StatusGone, "CURSOR_EXPIRED", "Cursor has expired")
+			return
+		}
+	}
+	position = max(position, f.purged)
+
+	start := position - f.purged
+	end := min(start+limit, len(f.changes))
+	feed := client.ChangeFeed{
+		Changes:    append([]client.Change{}, f.changes[start:end]...),
+		NextCursor: strconv.Itoa(end + f.purged),
+		HasMore:    end < len(f.changes),
+	}
+	respond(w, http.StatusOK, feed)
+}
+
+func respond(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": data})
+}
+
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   map[string]string{"code": code, "message": message},
+	})
+}
+
+// conformanceReplica adapts client.Replica to the synctest interface
+type conformanceReplica struct {
+	*client.Replica
+}
+
+func (r conformanceReplica) Create(title, content string) (string, error) {
+	return r.Replica.Create(title, content), nil
+}
+
+func (r conformanceReplica) Notes() ([]synctest.Note, error) {
+	var notes []synctest.Note
+	for _, note := range r.Replica.Notes() {
+		notes = append(notes, synctest.Note{ID: note.ID, Title: note.Title, Content: note.Content})
+	}
+	return notes, nil
+}
+
+func TestReplicaConformance(t *testing.T) {
+	_, server := newFakeServer(t)
+
+	synctest.Run(t, func(t *testing.T) synctest.Replica {
+		return conformanceReplica{client.NewReplica(client.NewClient(server.URL))}
+	})
+}
+
+// TestReplicaConformanceLive runs the suite against a real server, e.g.
+// MY_NOTES_API_URL=http://localhost:8080/api/v1 MY_NOTES_TOKEN=<access token>
+func TestReplicaConformanceLive(t *testing.T) {
+	baseURL, token := os.Getenv("MY_NOTES_API_URL"), os.Getenv("MY_NOTES_TOKEN")
+	if baseURL == "" || token == "" {
+		t.Skip("MY_NOTES_API_URL and MY_NOTES_TOKEN not set")
+	}
+
+	synctest.Run(t, func(t *testing.T) synctest.Replica {
+		c := client.NewClient(baseURL)
+		c.SetToken(token)
+		return conformanceReplica{client.NewReplica(c)}
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	_, server := newFakeServer(t)
+	c := client.NewClient(server.URL)
+	ctx := context.Background()
+
+	_, err := c.GetNote(ctx, uuid.New().String())
+	if !client.IsNotFound(err) {
+		t.Fatalf("Expected not found, got %v", err)
+	}
+
+	note, err := c.CreateNote(ctx, "Title", "Content")
+	if err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	content := "Changed"
+	if _, err := c.UpdateNote(ctx, note.ID, client.NoteUpdate{Content: &content, Version: note.Version + 1}); !client.IsConflict(err) {
+		t.Fatalf("Expected conflict, got %v", err)
+	}
+
+	_, err = c.ListChanges(ctx, "not-a-cursor", 10)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "Invalid cursor" {
+		t.Errorf("Expected the server's error message, got %v", err)
+	}
+}
+
+func TestReplicaRecoversFromExpiredCursor(t *testing.T) {
+	fake, server := newFakeServer(t)
+	ctx := context.Background()
+
+	a := client.NewReplica(client.NewClient(server.URL))
+	b := client.NewReplica(client.NewClient(server.URL))
+	kept := a.Create("Kept", "v1")
+	dropped := a.Create("Dropped", "gone")
+	if err := a.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if err := b.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	// Changes made while b is offline are purged before b syncs again
+	a.Update(kept, "Kept", "v2")
+	a.Delete(dropped)
+	if err := a.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	fake.purge()
+
+	local := b.Create("Local", "offline")
+	if err := b.Sync(ctx); err != nil {
+		t.Fatalf("Sync after expired cursor failed: %v", err)
+	}
+
+	notes := b.Notes()
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 notes, got %+v", notes)
+	}
+	byTitle := map[string]client.LocalNote{}
+	for _, note := range notes {
+		byTitle[note.Title] = note
+	}
+	if byTitle["Kept"].Content != "v2" {
+		t.Errorf("Expected the remote edit, got %+v", byTitle["Kept"])
+	}
+	if note, ok := b.Get(local); !ok || note.ServerID == "" || note.Dirty {
+		t.Errorf("Expected the local note to be uploaded, got %+v", note)
+	}
+}
+
+func TestReplicaRetriesLostCreate(t *testing.T) {
+	fake, server := newFakeServer(t)
+	c := client.NewClient(server.URL)
+	ctx := context.Background()
+
+	r := client.NewReplica(c)
+	id := r.Create("Once", "created a single time")
+	fake.lostCreates = 1
+	if err := r.Sync(ctx); err == nil {
+		t.Fatal("Expected the lost create to fail the sync")
+	}
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	list, err := c.ListNotes(ctx, 100, 0)
+	if err != nil {
+		t.Fatalf("ListNotes failed: %v", err)
+	}
+	if list.Total != 1 {
+		t.Fatalf("Expected the retry to reuse the first note, got %d notes", list.Total)
+	}
+	if note, _ := r.Get(id); note.ServerID != list.Notes[0].ID || note.Dirty {
+		t.Errorf("Expected the local note to be linked to the server note, got %+v", note)
+	}
+	if len(r.Notes()) != 1 {
+		t.Errorf("Expected a single local note, got %+v", r.Notes())
+	}
+}
+
+func TestReplicaStateRoundTrip(t *testing.T) {
+	_, server := newFakeServer(t)
+	c := client.NewClient(server.URL)
+	ctx := context.Background()
+
+	r := client.NewReplica(c)
+	synced := r.Create("Synced", "on the server")
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	pending := r.Create("Pending", "not yet")
+
+	data, err := json.Marshal(r.State())
+	if err != nil {
+		t.Fatalf("Failed to encode state: %v", err)
+	}
+	var state client.ReplicaState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to decode state: %v", err)
+	}
+
+	restored := client.RestoreReplica(c, state)
+	if err := restored.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(restored.Notes()) != 2 {
+		t.Fatalf("Expected 2 notes, got %+v", restored.Notes())
+	}
+	if note, _ := restored.Get(pending); note.ServerID == "" {
+		t.Error("Expected the pending note to be uploaded after restore")
+	}
+	if note, _ := restored.Get(synced); note.Version != 1 {
+		t.Errorf("Expected the synced note to be unchanged, got %+v", note)
+	}
+
+	list, err := c.ListNotes(ctx, 100, 0)
+	if err != nil {
+		t.Fatalf("ListNotes failed: %v", err)
+	}
+	if list.Total != 2 {
+		t.Errorf("Expected 2 notes on the server, got %d", list.Total)
+	}
+}
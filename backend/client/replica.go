@@ -0,0 +1,562 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// changePageSize is the number of changes or notes read per request
+const changePageSize = 100
+
+// ErrNoteNotFound is returned for local edits of a note the replica does not have
+var ErrNoteNotFound = errors.New("note not found")
+
+// LocalNote is a note held by a Replica
+type LocalNote struct {
+	// ID is assigned when the note is created locally, or is the server ID
+	// for notes first seen on the server. It never changes.
+	ID string `json:"id"`
+	// ServerID is empty until the note has been created on the server
+	ServerID string `json:"server_id,omitempty"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	// Version is the server version the local copy is based on
+	Version int `json:"version"`
+	// Dirty is set while the note has local changes not yet on the server
+	Dirty bool `json:"dirty,omitempty"`
+	// Deleted marks a local delete not yet on the server
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// ReplicaState is everything a Replica needs to resume, for apps to persist
+// between runs
+type ReplicaState struct {
+	Notes []LocalNote `json:"notes"`
+	// Cursor is the change feed position the replica has applied up to
+	Cursor string `json:"cursor,omitempty"`
+	// Synced is set once the replica has taken a full copy of the server
+	Synced bool `json:"synced"`
+}
+
+// ConflictResolver merges a local edit with the server copy the edit
+// conflicted with. The note it returns is written to the server.
+type ConflictResolver func(local LocalNote, remote Note) LocalNote
+
+// PreferLocal resolves conflicts by keeping the local edit
+func PreferLocal(local LocalNote, remote Note) LocalNote {
+	return local
+}
+
+// PreferRemote resolves conflicts by discarding the local edit
+func PreferRemote(local LocalNote, remote Note) LocalNote {
+	local.Title = remote.Title
+	local.Content = remote.Content
+	return local
+}
+
+// replicaNote is a LocalNote with a count of local edits, so a push can tell
+// whether the note was edited again while the request was in flight
+type replicaNote struct {
+	LocalNote
+	revision int
+}
+
+// Replica is an offline-first copy of a user's notes. Edits are applied
+// locally and sent to the server by Sync, which also pulls changes made
+// elsewhere. A Replica is safe for concurrent use; edits made during a Sync
+// are kept and sent by the next one.
+type Replica struct {
+	client  *Client
+	resolve ConflictResolver
+
+	// syncMu serializes Sync calls; mu guards the fields below and is not
+	// held during requests
+	syncMu   sync.Mutex
+	mu       sync.Mutex
+	notes    map[string]*replicaNote
+	byServer map[string]string
+	cursor   string
+	synced   bool
+}
+
+// NewReplica creates an empty Replica that syncs through c
+func NewReplica(c *Client) *Replica {
+	return RestoreReplica(c, ReplicaState{})
+}
+
+// RestoreReplica creates a Replica from a state saved with State
+func RestoreReplica(c *Client, state ReplicaState) *Replica {
+	r := &Replica{
+		client:   c,
+		resolve:  PreferLocal,
+		notes:    make(map[string]*replicaNote),
+		byServer: make(map[string]string),
+		cursor:   state.Cursor,
+		synced:   state.Synced,
+	}
+	for _, note := range state.Notes {
+		r.notes[note.ID] = &replicaNote{LocalNote: note}
+		if note.ServerID != "" {
+			r.byServer[note.ServerID] = note.ID
+		}
+	}
+	return r
+}
+
+// SetConflictResolver sets how edits that conflict with a newer server
+// version are resolved. The default is PreferLocal.
+func (r *Replica) SetConflictResolver(resolve ConflictResolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resolve = resolve
+}
+
+// State returns a copy of the replica's state
+func (r *Replica) State() ReplicaState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := ReplicaState{Cursor: r.cursor, Synced: r.synced}
+	for _, note := range r.notes {
+		state.Notes = append(state.Notes, note.LocalNote)
+	}
+	sort.Slice(state.Notes, func(i, j int) bool { return state.Notes[i].ID < state.Notes[j].ID })
+	return state
+}
+
+// Create adds a note locally and returns its ID
+func (r *Replica) Create(title, content string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := uuid.New().String()
+	r.notes[id] = &replicaNote{
+		LocalNote: LocalNote{ID: id, Title: title, Content: content, Dirty: true},
+		revision:  1,
+	}
+	return id
+}
+
+// Update changes a note locally
+func (r *Replica) Update(id, title, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[id]
+	if !ok || note.Deleted {
+		return ErrNoteNotFound
+	}
+	note.Title = title
+	note.Content = content
+	note.Dirty = true
+	note.revision++
+	return nil
+}
+
+// Delete deletes a note locally
+func (r *Replica) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[id]
+	if !ok || note.Deleted {
+		return ErrNoteNotFound
+	}
+	if note.ServerID == "" {
+		delete(r.notes, id)
+		return nil
+	}
+	note.Deleted = true
+	note.Dirty = true
+	note.revision++
+	return nil
+}
+
+// Get returns a note
+func (r *Replica) Get(id string) (LocalNote, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, ok := r.notes[id]
+	if !ok || note.Deleted {
+		return LocalNote{}, false
+	}
+	return note.LocalNote, true
+}
+
+// Notes returns the notes that are not deleted, ordered by ID
+func (r *Replica) Notes() []LocalNote {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notes := make([]LocalNote, 0, len(r.notes))
+	for _, note := range r.notes {
+		if !note.Deleted {
+			notes = append(notes, note.LocalNote)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes
+}
+
+// Sync sends local changes to the server, then applies the changes made
+// elsewhere since the last Sync
+func (r *Replica) Sync(ctx context.Context) error {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	if err := r.push(ctx); err != nil {
+		return err
+	}
+	return r.pull(ctx)
+}
+
+// push sends every dirty note to the server
+func (r *Replica) push(ctx context.Context) error {
+	r.mu.Lock()
+	var pending []replicaNote
+	for _, note := range r.notes {
+		if note.Dirty {
+			pending = append(pending, *note)
+		}
+	}
+	r.mu.Unlock()
+
+	// Push in a stable order so retries behave the same way
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+
+	for _, note := range pending {
+		var err error
+		switch {
+		case note.Deleted:
+			err = r.pushDelete(ctx, note)
+		case note.ServerID == "":
+			err = r.pushCreate(ctx, note)
+		default:
+			err = r.pushUpdate(ctx, note)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Replica) pushDelete(ctx context.Context, note replicaNote) error {
+	if err := r.client.DeleteNote(ctx, note.ServerID); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to delete note %s: %w", note.ID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.notes, note.ID)
+	delete(r.byServer, note.ServerID)
+	return nil
+}
+
+func (r *Replica) pushCreate(ctx context.Context, note replicaNote) error {
+	// A create that succeeds on the server but whose response is lost is
+	// sent again by the next Sync; the key makes the server return the
+	// note it already created. It changes with the content, so a note
+	// edited before the retry is created again rather than rejected.
+	created, err := r.client.CreateNoteWithKey(ctx, createKey(note), note.Title, note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to create note %s: %w", note.ID, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.notes[note.ID]
+	if !ok {
+		// Deleted locally while the create was in flight
+		r.notes[note.ID] = &replicaNote{
+			LocalNote: LocalNote{ID: note.ID, ServerID: created.ID, Version: created.Version, Deleted: true, Dirty: true},
+		}
+		r.byServer[created.ID] = note.ID
+		return nil
+	}
+	current.ServerID = created.ID
+	current.Version = created.Version
+	current.Dirty = current.revision != note.revision
+	r.byServer[created.ID] = note.ID
+	return nil
+}
+
+// createKey returns the Idempotency-Key of a note's create
+func createKey(note replicaNote) string {
+	sum := sha256.Sum256([]byte(note.Title + "\x00" + note.Content))
+	return "replica-" + note.ID + "-" + hex.EncodeToString(sum[:8])
+}
+
+func (r *Replica) pushUpdate(ctx context.Context, note replicaNote) error {
+	title, content := note.Title, note.Content
+	updated, err := r.client.UpdateNote(ctx, note.ServerID, NoteUpdate{
+		Title:   &title,
+		Content: &content,
+		Version: note.Version,
+	})
+	switch {
+	case err == nil:
+		r.applyPushed(note, updated)
+		return nil
+	case IsNotFound(err):
+		// Deleted on the server after the local edit; the edit wins and the
+		// note is created again
+		r.detach(note.ServerID)
+		note.ServerID = ""
+		return r.pushCreate(ctx, note)
+	case !IsConflict(err):
+		return fmt.Errorf("failed to update note %s: %w", note.ID, err)
+	}
+
+	// The conflict carries the server copy; older servers need a GET
+	var apiErr *APIError
+	var remote *Note
+	if errors.As(err, &apiErr) && apiErr.Current != nil {
+		remote = apiErr.Current
+	} else if remote, err = r.client.GetNote(ctx, note.ServerID); err != nil {
+		if IsNotFound(err) {
+			r.detach(note.ServerID)
+			note.ServerID = ""
+			return r.pushCreate(ctx, note)
+		}
+		return fmt.Errorf("failed to get note %s: %w", note.ID, err)
+	}
+
+	r.mu.Lock()
+	resolve := r.resolve
+	r.mu.Unlock()
+
+	resolved := resolve(note.LocalNote, *remote)
+	if resolved.Title == remote.Title && resolved.Content == remote.Content {
+		r.applyPushed(note, remote)
+		return nil
+	}
+
+	title, content = resolved.Title, resolved.Content
+	updated, err = r.client.UpdateNote(ctx, note.ServerID, NoteUpdate{
+		Title:   &title,
+		Content: &content,
+		Version: remote.Version,
+	})
+	if err != nil {
+		if IsConflict(err) {
+			// Changed again in the meantime; the next Sync retries
+			return nil
+		}
+		return fmt.Errorf("failed to update note %s: %w", note.ID, err)
+	}
+	r.applyPushed(note, updated)
+	return nil
+}
+
+// applyPushed records the server copy of a pushed note, unless the note was
+// edited again locally while the push was in flight
+func (r *Replica) applyPushed(pushed replicaNote, server *Note) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.notes[pushed.ID]
+	if !ok {
+		return
+	}
+	current.Version = server.Version
+	if current.revision == pushed.revision {
+		current.Title = server.Title
+		current.Content = server.Content
+		current.Dirty = false
+	}
+}
+
+// detach forgets the server copy of a note deleted on the server, so that
+// local changes to it are sent as a new note
+func (r *Replica) detach(serverID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.byServer[serverID]
+	if !ok {
+		return
+	}
+	delete(r.byServer, serverID)
+	if note, ok := r.notes[id]; ok {
+		note.ServerID = ""
+		note.Version = 0
+	}
+}
+
+// pull applies the change feed since the stored cursor, or takes a full copy
+// of the server when there is no usable cursor
+func (r *Replica) pull(ctx context.Context) error {
+	r.mu.Lock()
+	cursor, synced := r.cursor, r.synced
+	r.mu.Unlock()
+
+	if !synced {
+		return r.bootstrap(ctx)
+	}
+
+	for {
+		feed, err := r.client.ListChanges(ctx, cursor, changePageSize)
+		if err != nil {
+			if IsCursorExpired(err) {
+				return r.bootstrap(ctx)
+			}
+			return fmt.Errorf("failed to list changes: %w", err)
+		}
+
+		for _, change := range feed.Changes {
+			if err := r.applyChange(ctx, change); err != nil {
+				return err
+			}
+		}
+
+		cursor = feed.NextCursor
+		r.mu.Lock()
+		r.cursor = cursor
+		r.mu.Unlock()
+
+		if !feed.HasMore {
+			return nil
+		}
+	}
+}
+
+func (r *Replica) applyChange(ctx context.Context, change Change) error {
+	if change.EntityType != "note" {
+		return nil
+	}
+
+	if change.Operation == "deleted" {
+		r.applyRemoteDelete(change.EntityID)
+		return nil
+	}
+
+	r.mu.Lock()
+	id, known := r.byServer[change.EntityID]
+	upToDate := known && change.Version != nil && *change.Version <= r.notes[id].Version
+	r.mu.Unlock()
+	if upToDate {
+		return nil
+	}
+
+	note, err := r.client.GetNote(ctx, change.EntityID)
+	if err != nil {
+		if IsNotFound(err) {
+			// Deleted since; a later change in the feed says so
+			return nil
+		}
+		return fmt.Errorf("failed to get note %s: %w", change.EntityID, err)
+	}
+	r.applyRemote(note)
+	return nil
+}
+
+// applyRemote stores the server copy of a note. Notes with local changes
+// are left alone; their next push resolves the conflict.
+func (r *Replica) applyRemote(remote *Note) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.byServer[remote.ID]
+	if !ok {
+		r.notes[remote.ID] = &replicaNote{LocalNote: LocalNote{
+			ID:       remote.ID,
+			ServerID: remote.ID,
+			Title:    remote.Title,
+			Content:  remote.Content,
+			Version:  remote.Version,
+		}}
+		r.byServer[remote.ID] = remote.ID
+		return
+	}
+
+	note := r.notes[id]
+	if note.Dirty || remote.Version < note.Version {
+		return
+	}
+	note.Title = remote.Title
+	note.Content = remote.Content
+	note.Version = remote.Version
+}
+
+func (r *Replica) applyRemoteDelete(serverID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.byServer[serverID]
+	if !ok {
+		return
+	}
+	delete(r.byServer, serverID)
+
+	note := r.notes[id]
+	if note.Dirty && !note.Deleted {
+		// Edited locally; the edit wins and the next push creates the note again
+		note.ServerID = ""
+		note.Version = 0
+		return
+	}
+	delete(r.notes, id)
+}
+
+// bootstrap replaces the replica's copy of the server with a full listing,
+// keeping local changes. The feed is read to its end first so that changes
+// made during the listing are applied by the next pull.
+func (r *Replica) bootstrap(ctx context.Context) error {
+	cursor := ""
+	for {
+		feed, err := r.client.ListChanges(ctx, cursor, changePageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list changes: %w", err)
+		}
+		cursor = feed.NextCursor
+		if !feed.HasMore {
+			break
+		}
+	}
+
+	remote := make(map[string]bool)
+	for offset := 0; ; offset += changePageSize {
+		list, err := r.client.ListNotes(ctx, changePageSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to list notes: %w", err)
+		}
+		for i := range list.Notes {
+			remote[list.Notes[i].ID] = true
+			r.applyRemote(&list.Notes[i])
+		}
+		if !list.HasMore {
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Notes missing from the listing were deleted on the server
+	for serverID, id := range r.byServer {
+		if remote[serverID] {
+			continue
+		}
+		delete(r.byServer, serverID)
+		note := r.notes[id]
+		if note.Dirty && !note.Deleted {
+			note.ServerID = ""
+			note.Version = 0
+		} else {
+			delete(r.notes, id)
+		}
+	}
+
+	r.cursor = cursor
+	r.synced = true
+	return nil
+}
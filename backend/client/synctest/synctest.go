@@ -0,0 +1,383 @@
+// Package synctest is a conformance suite for offline-first clients of the
+// Silence Notes sync API.
+//
+// An app plugs its sync engine in through the Replica interface and calls
+// Run from a test. Each scenario drives two or more replicas of the same
+// account through offline edits and syncs, and checks that they converge on
+// the same notes without losing or duplicating any.
+package synctest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Note is a note as seen by a replica
+type Note struct {
+	ID      string
+	Title   string
+	Content string
+}
+
+// Replica is a client's local copy of an account's notes. Create, Update and
+// Delete must work offline; only Sync talks to the server. IDs are local and
+// may differ between replicas of the same account.
+type Replica interface {
+	Create(title, content string) (string, error)
+	Update(id, title, content string) error
+	Delete(id string) error
+	Notes() ([]Note, error)
+	Sync(ctx context.Context) error
+}
+
+// NewReplica returns a new, empty replica of the account under test. Every
+// call must return a replica of the same account.
+type NewReplica func(t *testing.T) Replica
+
+// Run runs the conformance scenarios as subtests. The account should have no
+// other activity while the suite runs; notes created by the suite are
+// deleted at the end of each scenario.
+func Run(t *testing.T, newReplica NewReplica) {
+	scenarios := []struct {
+		name string
+		run  func(*scenario)
+	}{
+		{"offline create is uploaded", testOfflineCreate},
+		{"edits propagate", testEditsPropagate},
+		{"deletes propagate", testDeletesPropagate},
+		{"repeated offline edits", testRepeatedEdits},
+		{"concurrent edits converge", testConcurrentEdits},
+		{"edit of a remotely deleted note is kept", testEditRemoteDelete},
+		{"repeated sync is idempotent", testIdempotentSync},
+		{"new replica bootstraps", testBootstrap},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			s := &scenario{
+				t:          t,
+				newReplica: newReplica,
+				prefix:     fmt.Sprintf("synctest-%d", time.Now().UnixNano()),
+			}
+			defer s.cleanup()
+			sc.run(s)
+		})
+	}
+}
+
+// scenario tracks the replicas of one scenario and scopes its notes with a
+// unique title prefix, so notes already in the account are ignored
+type scenario struct {
+	t          *testing.T
+	newReplica NewReplica
+	prefix     string
+	replicas   []Replica
+}
+
+func (s *scenario) replica() Replica {
+	r := s.newReplica(s.t)
+	s.replicas = append(s.replicas, r)
+	s.sync(r)
+	return r
+}
+
+func (s *scenario) title(name string) string {
+	return s.prefix + " " + name
+}
+
+func (s *scenario) sync(r Replica) {
+	s.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.Sync(ctx); err != nil {
+		s.t.Fatalf("Sync failed: %v", err)
+	}
+}
+
+func (s *scenario) create(r Replica, name, content string) string {
+	s.t.Helper()
+	id, err := r.Create(s.title(name), content)
+	if err != nil {
+		s.t.Fatalf("Create failed: %v", err)
+	}
+	return id
+}
+
+func (s *scenario) update(r Replica, id, name, content string) {
+	s.t.Helper()
+	if err := r.Update(id, s.title(name), content); err != nil {
+		s.t.Fatalf("Update failed: %v", err)
+	}
+}
+
+func (s *scenario) delete(r Replica, id string) {
+	s.t.Helper()
+	if err := r.Delete(id); err != nil {
+		s.t.Fatalf("Delete failed: %v", err)
+	}
+}
+
+// notes returns the scenario's notes in r, sorted by title
+func (s *scenario) notes(r Replica) []Note {
+	s.t.Helper()
+	all, err := r.Notes()
+	if err != nil {
+		s.t.Fatalf("Notes failed: %v", err)
+	}
+	var notes []Note
+	for _, note := range all {
+		if strings.HasPrefix(note.Title, s.prefix+" ") {
+			notes = append(notes, note)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Title < notes[j].Title })
+	return notes
+}
+
+// find returns the scenario note with the given name in r
+func (s *scenario) find(r Replica, name string) (Note, bool) {
+	s.t.Helper()
+	for _, note := range s.notes(r) {
+		if note.Title == s.title(name) {
+			return note, true
+		}
+	}
+	return Note{}, false
+}
+
+// expect checks that r holds exactly the given notes, as name to content
+func (s *scenario) expect(r Replica, label string, want map[string]string) {
+	s.t.Helper()
+	got := make(map[string]string)
+	for _, note := range s.notes(r) {
+		name := note.Title[len(s.prefix)+1:]
+		if _, dup := got[name]; dup {
+			s.t.Errorf("%s: note %q is duplicated", label, name)
+		}
+		got[name] = note.Content
+	}
+	for name, content := range want {
+		if gotContent, ok := got[name]; !ok {
+			s.t.Errorf("%s: note %q is missing", label, name)
+		} else if gotContent != content {
+			s.t.Errorf("%s: note %q has content %q, want %q", label, name, gotContent, content)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			s.t.Errorf("%s: unexpected note %q", label, name)
+		}
+	}
+}
+
+// converged checks that all replicas hold the same notes
+func (s *scenario) converged() {
+	s.t.Helper()
+	if len(s.replicas) < 2 {
+		return
+	}
+	reference := s.notes(s.replicas[0])
+	for i, r := range s.replicas[1:] {
+		notes := s.notes(r)
+		if len(notes) != len(reference) {
+			s.t.Errorf("Replica %d has %d notes, replica 0 has %d", i+1, len(notes), len(reference))
+			continue
+		}
+		for j := range notes {
+			if notes[j].Title != reference[j].Title || notes[j].Content != reference[j].Content {
+				s.t.Errorf("Replica %d has %q: %q, replica 0 has %q: %q", i+1,
+					notes[j].Title, notes[j].Content, reference[j].Title, reference[j].Content)
+			}
+		}
+	}
+}
+
+// cleanup deletes the scenario's notes through a fresh replica
+func (s *scenario) cleanup() {
+	r := s.newReplica(s.t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.Sync(ctx); err != nil {
+		s.t.Logf("Cleanup sync failed: %v", err)
+		return
+	}
+	notes, err := r.Notes()
+	if err != nil {
+		s.t.Logf("Cleanup failed: %v", err)
+		return
+	}
+	for _, note := range notes {
+		if strings.HasPrefix(note.Title, s.prefix+" ") {
+			if err := r.Delete(note.ID); err != nil {
+				s.t.Logf("Cleanup delete failed: %v", err)
+			}
+		}
+	}
+	if err := r.Sync(ctx); err != nil {
+		s.t.Logf("Cleanup sync failed: %v", err)
+	}
+}
+
+func testOfflineCreate(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	s.create(a, "first", "one")
+	s.create(a, "second", "two")
+	s.expect(a, "before sync", map[string]string{"first": "one", "second": "two"})
+
+	s.sync(a)
+	s.sync(b)
+	s.expect(b, "other replica", map[string]string{"first": "one", "second": "two"})
+	s.converged()
+}
+
+func testEditsPropagate(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	id := s.create(a, "note", "draft")
+	s.sync(a)
+	s.sync(b)
+
+	s.update(a, id, "note", "final")
+	s.sync(a)
+	s.sync(b)
+	s.expect(b, "after edit", map[string]string{"note": "final"})
+
+	note, ok := s.find(b, "note")
+	if !ok {
+		s.t.Fatal("Note missing from other replica")
+	}
+	s.update(b, note.ID, "renamed", "final")
+	s.sync(b)
+	s.sync(a)
+	s.expect(a, "after rename", map[string]string{"renamed": "final"})
+	s.converged()
+}
+
+func testDeletesPropagate(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	s.create(a, "keep", "kept")
+	drop := s.create(a, "drop", "dropped")
+	s.sync(a)
+	s.sync(b)
+
+	s.delete(a, drop)
+	s.sync(a)
+	s.sync(b)
+	s.expect(b, "after delete", map[string]string{"keep": "kept"})
+
+	// Created and deleted offline: never reaches the other replica
+	offline := s.create(a, "offline", "gone")
+	s.delete(a, offline)
+	s.sync(a)
+	s.sync(b)
+	s.expect(b, "after offline delete", map[string]string{"keep": "kept"})
+	s.converged()
+}
+
+func testRepeatedEdits(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	id := s.create(a, "note", "v1")
+	for i := 2; i <= 5; i++ {
+		s.update(a, id, "note", fmt.Sprintf("v%d", i))
+	}
+	s.sync(a)
+	s.sync(b)
+	s.expect(b, "after offline edits", map[string]string{"note": "v5"})
+
+	for i := 6; i <= 8; i++ {
+		s.update(a, id, "note", fmt.Sprintf("v%d", i))
+	}
+	s.sync(a)
+	s.sync(b)
+	s.expect(b, "after more edits", map[string]string{"note": "v8"})
+	s.converged()
+}
+
+func testConcurrentEdits(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	id := s.create(a, "note", "base")
+	s.sync(a)
+	s.sync(b)
+	note, ok := s.find(b, "note")
+	if !ok {
+		s.t.Fatal("Note missing from other replica")
+	}
+
+	// Both edit the same version offline
+	s.update(a, id, "note", "from a")
+	s.update(b, note.ID, "note", "from b")
+	s.sync(a)
+	s.sync(b)
+	s.sync(a)
+
+	// Whichever edit wins, the replicas agree and the note is not duplicated
+	notes := s.notes(a)
+	if len(notes) != 1 {
+		s.t.Fatalf("Expected 1 note after conflict, got %d", len(notes))
+	}
+	if notes[0].Content != "from a" && notes[0].Content != "from b" {
+		s.t.Errorf("Expected one of the edits to win, got %q", notes[0].Content)
+	}
+	s.converged()
+}
+
+func testEditRemoteDelete(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	id := s.create(a, "note", "base")
+	s.sync(a)
+	s.sync(b)
+	note, ok := s.find(b, "note")
+	if !ok {
+		s.t.Fatal("Note missing from other replica")
+	}
+
+	s.delete(a, id)
+	s.update(b, note.ID, "note", "edited")
+	s.sync(a)
+	s.sync(b)
+	s.sync(a)
+
+	// Either the edit or the delete may win, but the replicas must agree
+	s.converged()
+	if notes := s.notes(a); len(notes) > 1 {
+		s.t.Errorf("Expected at most 1 note, got %d", len(notes))
+	}
+}
+
+func testIdempotentSync(s *scenario) {
+	a, b := s.replica(), s.replica()
+
+	s.create(a, "note", "once")
+	for i := 0; i < 3; i++ {
+		s.sync(a)
+	}
+	for i := 0; i < 3; i++ {
+		s.sync(b)
+	}
+	s.expect(a, "syncing replica", map[string]string{"note": "once"})
+	s.expect(b, "other replica", map[string]string{"note": "once"})
+}
+
+func testBootstrap(s *scenario) {
+	a := s.replica()
+
+	id := s.create(a, "kept", "v1")
+	dropped := s.create(a, "dropped", "gone")
+	s.sync(a)
+	s.update(a, id, "kept", "v2")
+	s.delete(a, dropped)
+	s.sync(a)
+
+	b := s.replica()
+	s.expect(b, "new replica", map[string]string{"kept": "v2"})
+	s.converged()
+}
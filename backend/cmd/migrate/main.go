@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/database"
+)
+
+func main() {
+	action := flag.String("action", "status", "Migration action: up, down, to, status, verify or create")
+	version := flag.String("version", "", "Target version for -action to (0 rolls back every migration)")
+	name := flag.String("name", "", "Migration name for -action create")
+	dryRun := flag.Bool("dry-run", false, "Print the SQL of the migrations instead of running it")
+	path := flag.String("path", "", "Migrations directory (default: migrations or backend/migrations)")
+	flag.Parse()
+
+	migrationsPath := *path
+	if migrationsPath == "" {
+		migrationsPath = "migrations"
+		if _, err := os.Stat("backend/migrations"); err == nil {
+			migrationsPath = "backend/migrations"
+		}
+	}
+
+	// Creating a migration does not need the database
+	if *action == "create" {
+		if *name == "" {
+			log.Fatal("❌ -name is required for -action create")
+		}
+		if err := database.NewMigrator(nil, migrationsPath).Create(*name); err != nil {
+			log.Fatalf("❌ Failed to create migration: %v", err)
+		}
+		return
+	}
+
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		log.Fatalf("❌ Failed to load config: %v", err)
+	}
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator := database.NewMigrator(db, migrationsPath)
+	migrator.SetDryRun(*dryRun)
+
+	switch *action {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "to":
+		if *version == "" {
+			log.Fatal("❌ -version is required for -action to")
+		}
+		err = migrator.To(*version)
+	case "status":
+		err = migrator.Status()
+	case "verify":
+		var drifted []string
+		drifted, err = migrator.Verify()
+		if err == nil && len(drifted) > 0 {
+			for _, v := range drifted {
+				fmt.Printf("✗ %s changed or was removed after it was applied\n", v)
+			}
+			db.Close()
+			os.Exit(1)
+		}
+		if err == nil {
+			fmt.Println("All applied migrations match their files")
+		}
+	default:
+		log.Fatalf("❌ Unknown action %q", *action)
+	}
+	if err != nil {
+		log.Fatalf("❌ Migration %s failed: %v", *action, err)
+	}
+}
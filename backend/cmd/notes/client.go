@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// apiClient calls the API of a Silence Notes server with an API key
+type apiClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// newAPIClient creates an apiClient for the API at baseURL, for example
+// "http://localhost:8080/api/v1"
+func newAPIClient(baseURL, apiKey string) *apiClient {
+	return &apiClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// apiResponse is the standard response format of the API with the data left
+// undecoded
+type apiResponse struct {
+	Success bool             `json:"success"`
+	Data    json.RawMessage  `json:"data"`
+	Error   *models.APIError `json:"error"`
+}
+
+// requestError is an error response from the server
+type requestError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("server responded %d: %s", e.StatusCode, e.Message)
+}
+
+// do sends a request and returns the raw data of the response. When out is
+// not nil, the data is also decoded into it.
+func (c *apiClient) do(ctx context.Context, method, path string, body, out interface{}) (json.RawMessage, error) {
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return nil, fmt.Errorf("failed to decode response data: %w", err)
+		}
+	}
+	return result.Data, nil
+}
+
+// download sends a GET request and copies the response body to w. It
+// returns the file name suggested by the server, if any.
+func (c *apiClient) download(ctx context.Context, path string, w io.Writer) (string, error) {
+	resp, err := c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", path, err)
+	}
+
+	_, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	return params["filename"], nil
+}
+
+// send sends a request, turning error responses into a requestError
+func (c *apiClient) send(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("User-Agent", "my-notes-cli/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, &requestError{StatusCode: resp.StatusCode, Message: errorMessage(resp)}
+	}
+	return resp, nil
+}
+
+// errorMessage returns the message of an error response. Handlers respond
+// with an APIError, while middleware errors are plain strings.
+func errorMessage(resp *http.Response) string {
+	var result struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err == nil {
+		var apiErr models.APIError
+		var message string
+		switch {
+		case json.Unmarshal(result.Error, &apiErr) == nil && apiErr.Message != "":
+			if apiErr.Details != "" {
+				return apiErr.Message + ": " + apiErr.Details
+			}
+			return apiErr.Message
+		case json.Unmarshal(result.Error, &message) == nil && message != "":
+			return message
+		}
+	}
+	return http.StatusText(resp.StatusCode)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// newListCommand creates "notes list"
+func newListCommand(c *cli) *cobra.Command {
+	var limit, offset int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List notes, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{}
+			query.Set("limit", strconv.Itoa(limit))
+			query.Set("offset", strconv.Itoa(offset))
+
+			var list models.NoteList
+			data, err := client.do(cmd.Context(), http.MethodGet, "/notes?"+query.Encode(), nil, &list)
+			if err != nil {
+				return err
+			}
+			if c.jsonOutput {
+				return printJSON(c, data)
+			}
+			return printNoteList(c, &list)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 20, "number of notes (max 100)")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of notes to skip")
+	return cmd
+}
+
+// newAddCommand creates "notes add"
+func newAddCommand(c *cli) *cobra.Command {
+	var title string
+	var tags []string
+	var private bool
+
+	cmd := &cobra.Command{
+		Use:   "add <text>",
+		Short: "Create a note",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			content := strings.TrimSpace(args[0])
+			if len(tags) > 0 {
+				content += "\n\n" + strings.Join(hashtags(tags), " ")
+			}
+			request := models.CreateNoteRequest{Title: title, Content: content, Private: private}
+
+			var note models.NoteResponse
+			data, err := client.do(cmd.Context(), http.MethodPost, "/notes", request, &note)
+			if err != nil {
+				return err
+			}
+			if c.jsonOutput {
+				return printJSON(c, data)
+			}
+			fmt.Fprintf(c.out, "Created note %s\n", note.ID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&title, "title", "", "note title")
+	cmd.Flags().StringSliceVarP(&tags, "tag", "t", nil, "tag to add, repeatable")
+	cmd.Flags().BoolVar(&private, "private", false, "create a private note")
+	return cmd
+}
+
+// newSearchCommand creates "notes search"
+func newSearchCommand(c *cli) *cobra.Command {
+	var tags []string
+	var limit, offset int
+
+	cmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search notes by text and tags",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && len(tags) == 0 {
+				return fmt.Errorf("a query or at least one --tag is required")
+			}
+			client, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			query := url.Values{}
+			if len(args) == 1 {
+				query.Set("query", args[0])
+			}
+			if len(tags) > 0 {
+				query.Set("tags", strings.Join(hashtags(tags), ","))
+			}
+			query.Set("limit", strconv.Itoa(limit))
+			query.Set("offset", strconv.Itoa(offset))
+
+			var list models.NoteList
+			data, err := client.do(cmd.Context(), http.MethodGet, "/search/notes?"+query.Encode(), nil, &list)
+			if err != nil {
+				return err
+			}
+			if c.jsonOutput {
+				return printJSON(c, data)
+			}
+			return printNoteList(c, &list)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&tags, "tag", "t", nil, "tag every result must have, repeatable")
+	cmd.Flags().IntVar(&limit, "limit", 20, "number of notes (max 100)")
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of notes to skip")
+	return cmd
+}
+
+// newPrettifyCommand creates "notes prettify"
+func newPrettifyCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "prettify <id>",
+		Short: "Clean up a note with the LLM",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			var result models.PrettifyNoteResponse
+			data, err := client.do(cmd.Context(), http.MethodPost, "/notes/"+url.PathEscape(args[0])+"/prettify", nil, &result)
+			if err != nil {
+				return err
+			}
+			if c.jsonOutput {
+				return printJSON(c, data)
+			}
+
+			fmt.Fprintln(c.out, result.Content)
+			if len(result.ChangesMade) > 0 {
+				fmt.Fprintf(c.out, "\nChanges: %s\n", strings.Join(result.ChangesMade, "; "))
+			}
+			if len(result.SuggestedTags) > 0 {
+				fmt.Fprintf(c.out, "Suggested tags: %s\n", strings.Join(result.SuggestedTags, " "))
+			}
+			return nil
+		},
+	}
+}
+
+// newExportCommand creates "notes export"
+func newExportCommand(c *cli) *cobra.Command {
+	var format, output, query string
+	var tags []string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Download an export of your notes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := c.client()
+			if err != nil {
+				return err
+			}
+
+			params := url.Values{}
+			params.Set("format", format)
+			if len(tags) > 0 {
+				params.Set("tags", strings.Join(hashtags(tags), ","))
+			}
+			if query != "" {
+				params.Set("q", query)
+			}
+
+			// Buffer the export so a failed download does not leave a partial file
+			var buf bytes.Buffer
+			filename, err := client.download(cmd.Context(), "/export?"+params.Encode(), &buf)
+			if err != nil {
+				return err
+			}
+
+			if output == "-" {
+				_, err := c.out.Write(buf.Bytes())
+				return err
+			}
+			if output == "" {
+				output = filename
+			}
+			if output == "" {
+				output = "notes-export." + format
+			}
+			if err := os.WriteFile(output, buf.Bytes(), 0o600); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+			fmt.Fprintf(c.out, "Exported notes to %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", models.ExportFormatJSON, "export format supported by the server")
+	cmd.Flags().StringVarP(&output, "output", "o", "", `file to write, "-" for standard output (default: name suggested by the server)`)
+	cmd.Flags().StringSliceVarP(&tags, "tag", "t", nil, "only export notes with this tag, repeatable")
+	cmd.Flags().StringVar(&query, "query", "", "only export notes matching this search query")
+	return cmd
+}
+
+// hashtags prefixes tags given without "#"
+func hashtags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if !strings.HasPrefix(tag, "#") {
+			tag = "#" + tag
+		}
+		result = append(result, tag)
+	}
+	return result
+}
+
+// printJSON prints response data indented
+func printJSON(c *cli, data json.RawMessage) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return fmt.Errorf("failed to format response: %w", err)
+	}
+	buf.WriteByte('\n')
+	_, err := c.out.Write(buf.Bytes())
+	return err
+}
+
+// printNoteList prints notes as a table
+func printNoteList(c *cli, list *models.NoteList) error {
+	if len(list.Notes) == 0 {
+		fmt.Fprintln(c.out, "No notes found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUPDATED\tTITLE\tTAGS")
+	for _, note := range list.Notes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", note.ID, note.UpdatedAt.Local().Format("2006-01-02 15:04"),
+			noteTitle(note), strings.Join(note.Tags, " "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if list.HasMore {
+		fmt.Fprintf(c.out, "Showing %d of %d notes\n", len(list.Notes), list.Total)
+	}
+	return nil
+}
+
+// noteTitle returns the title of a note, or the start of its first line
+func noteTitle(note models.NoteResponse) string {
+	title := ""
+	if note.Title != nil {
+		title = *note.Title
+	}
+	if title == "" {
+		title, _, _ = strings.Cut(strings.TrimSpace(note.Content), "\n")
+	}
+	if runes := []rune(title); len(runes) > 50 {
+		title = string(runes[:49]) + "…"
+	}
+	return title
+}
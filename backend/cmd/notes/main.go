@@ -0,0 +1,78 @@
+// Command notes is a command line client for the Silence Notes API. It
+// authenticates with an API key, created with POST /api/v1/api-keys.
+//
+//	notes list
+//	notes add "Call the dentist" -t errands
+//	notes search -t work "quarterly review"
+//	notes prettify <id>
+//	notes export --format json -o notes.json
+//
+// The server and key are read from the --server and --api-key flags or the
+// NOTES_SERVER and NOTES_API_KEY environment variables. With --json, commands
+// print the API response data for scripting.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultServer is the API used when neither --server nor NOTES_SERVER is set
+const defaultServer = "http://localhost:8080/api/v1"
+
+// cli holds the global flags shared by all commands
+type cli struct {
+	server     string
+	apiKey     string
+	jsonOutput bool
+	out        io.Writer
+}
+
+// client returns an API client for the configured server and key
+func (c *cli) client() (*apiClient, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("an API key is required: pass --api-key or set NOTES_API_KEY")
+	}
+	return newAPIClient(c.server, c.apiKey), nil
+}
+
+// newRootCommand creates the notes command with its subcommands, writing
+// output to out
+func newRootCommand(out io.Writer) *cobra.Command {
+	c := &cli{out: out}
+
+	server := os.Getenv("NOTES_SERVER")
+	if server == "" {
+		server = defaultServer
+	}
+
+	root := &cobra.Command{
+		Use:           "notes",
+		Short:         "Work with your Silence Notes from the command line",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.SetOut(out)
+	root.PersistentFlags().StringVar(&c.server, "server", server, "API base URL (env NOTES_SERVER)")
+	root.PersistentFlags().StringVar(&c.apiKey, "api-key", os.Getenv("NOTES_API_KEY"), "API key (env NOTES_API_KEY)")
+	root.PersistentFlags().BoolVar(&c.jsonOutput, "json", false, "print the API response as JSON")
+
+	root.AddCommand(
+		newListCommand(c),
+		newAddCommand(c),
+		newSearchCommand(c),
+		newPrettifyCommand(c),
+		newExportCommand(c),
+	)
+	return root
+}
+
+func main() {
+	if err := newRootCommand(os.Stdout).Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// testServer records the last request and answers with the given response
+type testServer struct {
+	*httptest.Server
+	request *http.Request
+	body    []byte
+}
+
+func newTestServer(t *testing.T, status int, response string) *testServer {
+	t.Helper()
+	ts := &testServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.request = r
+		ts.body, _ = readAll(r)
+		if strings.HasPrefix(r.URL.Path, "/export") {
+			w.Header().Set("Content-Disposition", `attachment; filename="notes-2024-03-01.json"`)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func readAll(r *http.Request) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(r.Body)
+	return buf.Bytes(), err
+}
+
+// run executes the CLI against a test server and returns its output
+func run(t *testing.T, ts *testServer, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := newRootCommand(&out)
+	cmd.SetArgs(append([]string{"--server", ts.URL, "--api-key", "mn_test"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestListNotes(t *testing.T) {
+	title := "Roadmap"
+	list := models.NoteList{
+		Notes: []models.NoteResponse{
+			{ID: uuid.New(), Title: &title, Content: "Q3 plans", Tags: []string{"#work"}},
+			{ID: uuid.New(), Content: "Buy milk\nand bread"},
+		},
+		Total: 2,
+	}
+	data, _ := json.Marshal(models.NewAPIResponse(list))
+	ts := newTestServer(t, http.StatusOK, string(data))
+
+	out, err := run(t, ts, "list", "--limit", "5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ts.request.URL.String(); got != "/notes?limit=5&offset=0" {
+		t.Errorf("Unexpected request %s", got)
+	}
+	if got := ts.request.Header.Get("Authorization"); got != "Bearer mn_test" {
+		t.Errorf("Expected the API key as bearer token, got %q", got)
+	}
+	for _, want := range []string{"Roadmap", "#work", "Buy milk"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "and bread") {
+		t.Errorf("Expected only the first line of untitled notes:\n%s", out)
+	}
+
+	out, err = run(t, ts, "list", "--json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded models.NoteList
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil || len(decoded.Notes) != 2 {
+		t.Errorf("Expected the note list as JSON, got %q (%v)", out, err)
+	}
+}
+
+func TestAddNote(t *testing.T) {
+	id := uuid.New()
+	data, _ := json.Marshal(models.NewAPIResponse(models.NoteResponse{ID: id}))
+	ts := newTestServer(t, http.StatusCreated, string(data))
+
+	out, err := run(t, ts, "add", "Call the dentist", "-t", "errands", "-t", "#health")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var request models.CreateNoteRequest
+	if err := json.Unmarshal(ts.body, &request); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	if request.Content != "Call the dentist\n\n#errands #health" {
+		t.Errorf("Unexpected content %q", request.Content)
+	}
+	if out != "Created note "+id.String()+"\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestSearchNotesRequiresQueryOrTag(t *testing.T) {
+	data, _ := json.Marshal(models.NewAPIResponse(models.NoteList{}))
+	ts := newTestServer(t, http.StatusOK, string(data))
+
+	if _, err := run(t, ts, "search"); err == nil {
+		t.Error("Expected a search without query and tags to be rejected")
+	}
+
+	out, err := run(t, ts, "search", "-t", "work")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ts.request.URL.Query().Get("tags"); got != "#work" {
+		t.Errorf("Expected the tag filter, got %q", got)
+	}
+	if out != "No notes found\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestExportNotes(t *testing.T) {
+	ts := newTestServer(t, http.StatusOK, `{"notes":[]}`)
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	out, err := run(t, ts, "export", "-t", "work")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ts.request.URL.Query().Get("format"); got != "json" {
+		t.Errorf("Expected the json format by default, got %q", got)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "notes-2024-03-01.json"))
+	if err != nil || string(content) != `{"notes":[]}` {
+		t.Errorf("Expected the export under the server's file name, got %q (%v)", content, err)
+	}
+	if !strings.Contains(out, "notes-2024-03-01.json") {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	data, _ := json.Marshal(models.NewAPIErrorResponse("NOT_FOUND", "Note not found", ""))
+	ts := newTestServer(t, http.StatusNotFound, string(data))
+
+	_, err := run(t, ts, "prettify", "missing")
+	if err == nil || err.Error() != "server responded 404: Note not found" {
+		t.Errorf("Expected the API error message, got %v", err)
+	}
+
+	ts = newTestServer(t, http.StatusForbidden, `{"error":"API keys cannot access this endpoint","code":403,"success":false}`)
+	_, err = run(t, ts, "list")
+	if err == nil || !strings.Contains(err.Error(), "API keys cannot access this endpoint") {
+		t.Errorf("Expected the middleware error message, got %v", err)
+	}
+
+	cmd := newRootCommand(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--api-key", "", "list"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "API key is required") {
+		t.Errorf("Expected a missing API key to be reported, got %v", err)
+	}
+}
@@ -9,10 +9,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gpd/my-notes/internal/app"
 	"github.com/gpd/my-notes/internal/config"
 	"github.com/gpd/my-notes/internal/database"
-	"github.com/gpd/my-notes/internal/handlers"
 	"github.com/gpd/my-notes/internal/server"
+	"github.com/gpd/my-notes/internal/telemetry"
 )
 
 func main() {
@@ -34,6 +35,13 @@ func main() {
 	log.Printf("🗄️  Database: %s:%d/%s", cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
 	log.Printf("🔧 Environment: %s", cfg.App.Environment)
 
+	// Initialize tracing
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Tracing, cfg.App.Version)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up tracing: %v", err)
+	}
+	log.Printf("🔭 Tracing exporter: %s", cfg.Tracing.Exporter)
+
 	// Initialize database
 	log.Println("📊 Initializing database connection...")
 	db, err := database.NewConnection(cfg.Database)
@@ -64,13 +72,13 @@ func main() {
 	}
 	log.Println("✅ Database migrations completed")
 
-	// Initialize handlers
-	log.Println("🎯 Initializing handlers...")
-	handlers := handlers.NewHandlers()
+	// Build services and handlers
+	log.Println("🎯 Initializing services and handlers...")
+	application := app.New(cfg, db, app.Options{})
 
 	// Create server
 	log.Println("🖥️  Creating HTTP server...")
-	srv := server.NewServer(cfg, handlers, db)
+	srv := server.NewServer(application)
 
 	// Start server in a goroutine
 	go func() {
@@ -80,6 +88,16 @@ func main() {
 		}
 	}()
 
+	// Start the gRPC API on its own port
+	if cfg.Server.GRPCEnabled() {
+		go func() {
+			log.Printf("🚀 gRPC server starting on %s", cfg.Server.GRPCAddress())
+			if err := srv.StartGRPC(); err != nil {
+				log.Fatalf("❌ gRPC server failed to start: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -98,5 +116,10 @@ func main() {
 		log.Println("✅ Server shutdown completed")
 	}
 
+	// Flush pending spans
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("❌ Failed to flush traces: %v", err)
+	}
+
 	log.Println("👋 Silence Notes Backend API stopped")
 }
\ No newline at end of file
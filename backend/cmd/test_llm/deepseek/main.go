@@ -89,7 +89,7 @@ func main() {
 	streamPrompt := "Say 'Hello, DeepSeek streaming works!' exactly like that."
 	fmt.Printf("%s[STREAM OUTPUT]%s ", ColorBlue, ColorReset)
 	streamingFunc := func(ctx context.Context, chunk []byte) error {
-		fmt.Printf(string(chunk))
+		fmt.Print(string(chunk))
 		return nil
 	}
 	err = llmClient.Stream(ctx, streamPrompt, streamingFunc)
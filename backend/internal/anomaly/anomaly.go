@@ -0,0 +1,144 @@
+// Package anomaly flags unusual account activity such as mass deletions,
+// exports from a new country or bursts of API key creation.
+package anomaly
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Activity event types. Logins only contribute to a user's country history.
+const (
+	EventLogin         = "login"
+	EventNoteDeleted   = "note_deleted"
+	EventExport        = "export"
+	EventAPIKeyCreated = "api_key_created"
+)
+
+// Rules raised by the detector
+const (
+	RuleMassDeletion     = "mass_deletion"
+	RuleExportNewCountry = "export_new_country"
+	RuleAPIKeyBurst      = "api_key_burst"
+)
+
+// Finding severities. High severity findings can lock the account.
+const (
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// Event is one recorded account activity. Count is the number of items the
+// event covers, e.g. the notes removed by a bulk delete.
+type Event struct {
+	Type      string
+	Count     int
+	Country   string
+	CreatedAt time.Time
+}
+
+// Finding is an unusual pattern found in a user's activity
+type Finding struct {
+	Rule     string         `json:"rule"`
+	Severity string         `json:"severity"`
+	Summary  string         `json:"summary"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// Thresholds configure when activity counts as unusual
+type Thresholds struct {
+	MassDeletionCount  int
+	MassDeletionWindow time.Duration
+	APIKeyBurstCount   int
+	APIKeyBurstWindow  time.Duration
+	// ExportWindow is how far back exports are checked for a new country
+	ExportWindow time.Duration
+}
+
+// DefaultThresholds returns the thresholds used when none are configured
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		MassDeletionCount:  50,
+		MassDeletionWindow: time.Hour,
+		APIKeyBurstCount:   3,
+		APIKeyBurstWindow:  time.Hour,
+		ExportWindow:       time.Hour,
+	}
+}
+
+// Lookback returns how far back events must be loaded to evaluate every rule
+func (t Thresholds) Lookback() time.Duration {
+	lookback := t.MassDeletionWindow
+	if t.APIKeyBurstWindow > lookback {
+		lookback = t.APIKeyBurstWindow
+	}
+	if t.ExportWindow > lookback {
+		lookback = t.ExportWindow
+	}
+	return lookback
+}
+
+// Detect evaluates a user's recent events. knownCountries holds the countries
+// the user was seen in before the recent events; an empty set means there is
+// no history to compare against and the new-country rule is skipped.
+func Detect(events []Event, knownCountries map[string]bool, now time.Time, t Thresholds) []Finding {
+	var findings []Finding
+
+	deleted := 0
+	apiKeys := 0
+	newCountries := make(map[string]bool)
+	for _, event := range events {
+		age := now.Sub(event.CreatedAt)
+		switch event.Type {
+		case EventNoteDeleted:
+			if age <= t.MassDeletionWindow {
+				deleted += max(event.Count, 1)
+			}
+		case EventAPIKeyCreated:
+			if age <= t.APIKeyBurstWindow {
+				apiKeys += max(event.Count, 1)
+			}
+		case EventExport:
+			country := strings.ToUpper(event.Country)
+			if age <= t.ExportWindow && country != "" && len(knownCountries) > 0 && !knownCountries[country] {
+				newCountries[country] = true
+			}
+		}
+	}
+
+	if t.MassDeletionCount > 0 && deleted >= t.MassDeletionCount {
+		findings = append(findings, Finding{
+			Rule:     RuleMassDeletion,
+			Severity: SeverityHigh,
+			Summary:  fmt.Sprintf("%d notes deleted within %s", deleted, t.MassDeletionWindow),
+			Details:  map[string]any{"deleted": deleted, "window_seconds": int(t.MassDeletionWindow.Seconds())},
+		})
+	}
+
+	if len(newCountries) > 0 {
+		countries := make([]string, 0, len(newCountries))
+		for country := range newCountries {
+			countries = append(countries, country)
+		}
+		sort.Strings(countries)
+		findings = append(findings, Finding{
+			Rule:     RuleExportNewCountry,
+			Severity: SeverityHigh,
+			Summary:  fmt.Sprintf("notes exported from a new country (%s)", strings.Join(countries, ", ")),
+			Details:  map[string]any{"countries": countries},
+		})
+	}
+
+	if t.APIKeyBurstCount > 0 && apiKeys >= t.APIKeyBurstCount {
+		findings = append(findings, Finding{
+			Rule:     RuleAPIKeyBurst,
+			Severity: SeverityMedium,
+			Summary:  fmt.Sprintf("%d API keys created within %s", apiKeys, t.APIKeyBurstWindow),
+			Details:  map[string]any{"created": apiKeys, "window_seconds": int(t.APIKeyBurstWindow.Seconds())},
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,89 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	thresholds := DefaultThresholds()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+
+	tests := []struct {
+		name      string
+		events    []Event
+		countries map[string]bool
+		want      []string
+	}{
+		{
+			name:   "no activity",
+			events: nil,
+			want:   nil,
+		},
+		{
+			name: "mass deletion from bulk and single deletes",
+			events: []Event{
+				{Type: EventNoteDeleted, Count: 45, CreatedAt: ago(10 * time.Minute)},
+				{Type: EventNoteDeleted, Count: 1, CreatedAt: ago(5 * time.Minute)},
+				{Type: EventNoteDeleted, Count: 4, CreatedAt: ago(time.Minute)},
+			},
+			want: []string{RuleMassDeletion},
+		},
+		{
+			name: "deletions outside the window are ignored",
+			events: []Event{
+				{Type: EventNoteDeleted, Count: 40, CreatedAt: ago(2 * time.Hour)},
+				{Type: EventNoteDeleted, Count: 20, CreatedAt: ago(time.Minute)},
+			},
+			want: nil,
+		},
+		{
+			name:      "export from a new country",
+			events:    []Event{{Type: EventExport, Country: "br", CreatedAt: ago(time.Minute)}},
+			countries: map[string]bool{"DE": true},
+			want:      []string{RuleExportNewCountry},
+		},
+		{
+			name:      "export from a known country",
+			events:    []Event{{Type: EventExport, Country: "DE", CreatedAt: ago(time.Minute)}},
+			countries: map[string]bool{"DE": true},
+			want:      nil,
+		},
+		{
+			name:   "export without history",
+			events: []Event{{Type: EventExport, Country: "BR", CreatedAt: ago(time.Minute)}},
+			want:   nil,
+		},
+		{
+			name: "api key burst",
+			events: []Event{
+				{Type: EventAPIKeyCreated, CreatedAt: ago(30 * time.Minute)},
+				{Type: EventAPIKeyCreated, CreatedAt: ago(20 * time.Minute)},
+				{Type: EventAPIKeyCreated, CreatedAt: ago(10 * time.Minute)},
+			},
+			want: []string{RuleAPIKeyBurst},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Detect(tt.events, tt.countries, now, thresholds)
+			if len(findings) != len(tt.want) {
+				t.Fatalf("Expected rules %v, got %+v", tt.want, findings)
+			}
+			for i, rule := range tt.want {
+				if findings[i].Rule != rule {
+					t.Errorf("Expected rule %s, got %s", rule, findings[i].Rule)
+				}
+			}
+		})
+	}
+}
+
+func TestThresholdsLookback(t *testing.T) {
+	thresholds := Thresholds{MassDeletionWindow: time.Hour, APIKeyBurstWindow: 3 * time.Hour, ExportWindow: 2 * time.Hour}
+	if got := thresholds.Lookback(); got != 3*time.Hour {
+		t.Errorf("Lookback() = %s, want 3h", got)
+	}
+}
@@ -0,0 +1,842 @@
+// Package app is the composition root of the API server. It builds the
+// database services, the LLM client, the middleware and the handlers from
+// the configuration, with their dependencies, in one place.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/gpd/my-notes/internal/anomaly"
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/graphql"
+	"github.com/gpd/my-notes/internal/grpcserver"
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/lifecycle"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/middleware"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/ocr"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/transcription"
+	"google.golang.org/grpc"
+)
+
+// App holds everything the server serves, built by New. Its fields may be
+// replaced before the server is created, for example to serve a test
+// handler.
+type App struct {
+	Config   *config.Config
+	DB       *sql.DB
+	Services *Services
+	Handlers *handlers.Handlers
+
+	// Jobs runs the schedulers and background jobs, drained on shutdown
+	Jobs *lifecycle.Manager
+
+	// LLM is nil without a configured provider, and Tokenizer when its
+	// encoding cannot be loaded
+	LLM       *llm.ResilientLLM
+	Tokenizer *llm.Tiktoken
+
+	// Authentication and the middleware of the HTTP API
+	TokenService *auth.TokenService
+	LoginGuard   *auth.LoginGuard
+	SecurityMW   *middleware.SecurityMiddleware
+	SessionMW    *middleware.SessionMiddleware
+	RateLimitMW  *middleware.RateLimitingMiddleware
+	SessionStore sessions.Store
+
+	// GRPC is the gRPC API, served on its own port
+	GRPC *grpc.Server
+}
+
+// Services holds the services built by New. Services that need an LLM are
+// nil when it is unavailable.
+type Services struct {
+	Users             *services.UserService
+	Sessions          *services.SessionService
+	LoginAlerts       *services.LoginAlertService
+	TwoFactor         *services.TwoFactorService
+	Blacklist         *services.BlacklistService
+	APIKeys           *services.APIKeyService
+	Idempotency       *services.IdempotencyService
+	Tags              *services.TagService
+	Notes             *services.NoteService
+	SavedSearches     *services.SavedSearchService
+	Notifications     *services.NotificationService
+	NotificationRules *services.NotificationRuleService
+	Subscriptions     *services.SubscriptionService
+	Links             *services.LinkService
+	Confirmations     *services.ConfirmationService
+	LegalHolds        *services.LegalHoldService
+	Activity          *services.ActivityService
+	Anomaly           *services.AnomalyService
+	Audit             *services.AuditService
+	Migrations        *services.MigrationService
+	Changes           *services.ChangeService
+	Progress          *services.ProgressService
+	Tasks             *services.TaskService
+	Boards            *services.BoardService
+	Templates         *services.TemplateService
+	Recurrences       *services.RecurrenceService
+	DailyNotes        *services.DailyNoteService
+	Focus             *services.FocusService
+	Digest            *services.DigestService
+	Webhooks          *services.WebhookService
+	Imports           *services.ImportService
+	Exports           *services.ExportService
+	Admin             *services.AdminService
+	LLMUsage          *services.LLMUsageService
+	LLMCache          *services.LLMCacheService
+	Prompts           *prompts.Registry
+	Prettify          *services.PrettifyService
+	Proofread         *services.ProofreadService
+	Translation       *services.TranslationService
+	SemanticSearch    *services.SemanticSearchService
+	QA                *services.QAService
+}
+
+// Options replaces dependencies New otherwise builds from the
+// configuration. Unset fields are built as configured.
+type Options struct {
+	// LLM replaces the client of the configured LLM providers
+	LLM *llm.ResilientLLM
+	// EmailSender replaces the SMTP sender, or the log sender without SMTP
+	EmailSender email.Sender
+	// ConfirmationSender replaces the log sender of the one-time codes of
+	// bulk operations
+	ConfirmationSender services.ConfirmationSender
+}
+
+// New builds the services, LLM client, middleware and handlers of the
+// server on db, and starts their background jobs
+func New(cfg *config.Config, db *sql.DB, opts Options) *App {
+	a := &App{
+		Config:   cfg,
+		DB:       db,
+		Handlers: handlers.NewHandlers(),
+		Jobs:     lifecycle.New(),
+	}
+
+	// Initialize user service
+	userService := services.NewUserService(a.DB)
+
+	// Initialize tag service
+	tagService := services.NewTagService(a.DB)
+
+	// Initialize token service
+	tokenSecret := a.Config.Auth.JWTSecret
+	if tokenSecret == "" {
+		log.Println("⚠️  Warning: Using default JWT secret key - please set JWT_SECRET in production")
+		tokenSecret = "your-secret-key-change-in-production"
+	}
+	a.TokenService = auth.NewTokenService(
+		tokenSecret,
+		time.Duration(a.Config.Auth.TokenExpiry)*time.Hour,
+		time.Duration(a.Config.Auth.RefreshExpiry)*time.Hour,
+		"silence-notes",
+		"silence-notes-users",
+	)
+
+	// Initialize blacklist service for token revocation
+	blacklistSvc := services.NewBlacklistService(a.DB)
+	a.TokenService.SetBlacklist(blacklistSvc)
+
+	// Start blacklist cleanup goroutine
+	blacklistCleanupLoop(a.Jobs, blacklistSvc, 1*time.Hour)
+
+	// Initialize security configuration
+	securityConfig := config.SecurityConfigFor(a.Config)
+
+	// Initialize security middleware
+	a.SecurityMW = middleware.NewSecurityMiddleware(
+		a.TokenService,
+		userService,
+		securityConfig,
+		&securityConfig.CORS,
+	)
+
+	// Initialize session middleware
+	sessionConfig := &middleware.SessionConfig{
+		SessionTimeout:    securityConfig.Session.SessionTimeout,
+		MaxSessions:       securityConfig.Session.MaxSessions,
+		EnableConcurrency: securityConfig.Session.EnableConcurrency,
+		InactiveTimeout:   securityConfig.Session.InactiveTimeout,
+		RefreshThreshold:  securityConfig.Session.RefreshThreshold,
+	}
+	a.SessionMW = middleware.NewSessionMiddleware(userService, a.DB, sessionConfig)
+
+	// Initialize rate limiting middleware
+	rateLimitConfig := &middleware.RateLimitConfig{
+		GlobalRequestsPerSecond:  securityConfig.RateLimiting.GlobalRequestsPerSecond,
+		GlobalBurstSize:          securityConfig.RateLimiting.GlobalBurstSize,
+		UserRequestsPerMinute:    securityConfig.RateLimiting.UserRequestsPerMinute,
+		UserRequestsPerHour:      securityConfig.RateLimiting.UserRequestsPerHour,
+		UserRequestsPerDay:       securityConfig.RateLimiting.UserRequestsPerDay,
+		AuthRequestsPerMinute:    securityConfig.RateLimiting.AuthRequestsPerMinute,
+		ProfileRequestsPerMinute: securityConfig.RateLimiting.ProfileRequestsPerMinute,
+		SearchRequestsPerMinute:  securityConfig.RateLimiting.SearchRequestsPerMinute,
+		WhitelistedIPs:           securityConfig.RateLimiting.WhitelistedIPs,
+		WhitelistedUsers:         securityConfig.RateLimiting.WhitelistedUsers,
+	}
+	a.RateLimitMW = middleware.NewRateLimitingMiddleware(userService, a.TokenService, rateLimitConfig)
+
+	// Initialize session store
+	sessionSecret := []byte(a.Config.Auth.JWTSecret)
+	if len(sessionSecret) == 0 {
+		sessionSecret = []byte("your-session-secret-change-in-production")
+		log.Println("⚠️  Warning: Using default session secret - please set JWT_SECRET in production")
+	}
+	a.SessionStore = sessions.NewCookieStore(sessionSecret)
+	if cookieStore, ok := a.SessionStore.(*sessions.CookieStore); ok {
+		cookieStore.Options = &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(securityConfig.Session.SessionTimeout.Seconds()),
+			HttpOnly: true,
+			Secure:   a.Config.App.Environment == "production",
+			SameSite: http.SameSiteStrictMode,
+		}
+	}
+
+	// Initialize auth handlers
+	authHandler := handlers.NewAuthHandler(
+		a.TokenService,
+		userService,
+	)
+	authHandler.SetBlacklist(blacklistSvc)
+
+	// Sessions accept only their latest refresh token
+	sessionService := services.NewSessionService(a.DB)
+	authHandler.SetSessionService(sessionService)
+
+	// Initialize Chrome extension auth handler
+	chromeAuthHandler := handlers.NewChromeAuthHandler(
+		a.TokenService,
+		userService,
+	)
+	chromeAuthHandler.SetSessionService(sessionService)
+
+	// Back off and then lock out clients repeatedly failing to authenticate
+	a.LoginGuard = auth.NewLoginGuard(auth.LoginGuardConfig{
+		FreeAttempts:    a.Config.Login.FreeAttempts,
+		BaseDelay:       time.Duration(a.Config.Login.BackoffBase) * time.Second,
+		MaxDelay:        time.Duration(a.Config.Login.BackoffMax) * time.Second,
+		LockoutAttempts: a.Config.Login.LockoutAttempts,
+		LockoutDuration: time.Duration(a.Config.Login.LockoutDuration) * time.Minute,
+		Window:          time.Duration(a.Config.Login.AttemptWindow) * time.Minute,
+	})
+	authHandler.SetLoginGuard(a.LoginGuard)
+	chromeAuthHandler.SetLoginGuard(a.LoginGuard)
+	a.SecurityMW.SetLoginGuard(a.LoginGuard)
+	loginGuardCleanupLoop(a.Jobs, a.LoginGuard, 10*time.Minute)
+
+	// Initialize LLM components for semantic search
+	var tokenizer *llm.Tiktoken
+	var resilientLLM *llm.ResilientLLM
+	var semanticSearchService *services.SemanticSearchService
+	var prettifyService *services.PrettifyService
+	var qaService *services.QAService
+	var proofreadService *services.ProofreadService
+	var translationService *services.TranslationService
+
+	// Initialize encryption for private notes
+	noteEncryptor := encryption.NewNoteEncryptor(nil)
+	twoFactorEncryptor := encryption.NewTwoFactorEncryptor(nil)
+	if a.Config.Encryption.MasterKey != "" {
+		keyProvider, err := encryption.NewStaticKeyProvider(a.Config.Encryption.MasterKey)
+		if err != nil {
+			log.Printf("⚠️  Invalid encryption master key: %v - private notes disabled", err)
+		} else {
+			noteEncryptor = encryption.NewNoteEncryptor(keyProvider)
+			twoFactorEncryptor = encryption.NewTwoFactorEncryptor(keyProvider)
+			log.Println("✅ Private note encryption enabled")
+		}
+	} else {
+		log.Println("ℹ️  No encryption master key configured - private notes disabled")
+	}
+
+	// Notes up to the configured content limit fit in a request. The limit
+	// counts characters, which take up to 6 bytes each once JSON-escaped.
+	// Batch creation keeps the default request limit.
+	models.SetMaxContentLength(a.Config.Notes.MaxContentLength)
+	if noteRequestSize := 6*int64(a.Config.Notes.MaxContentLength) + 64<<10; noteRequestSize > 1<<20 {
+		for _, path := range []string{"/api/v1/notes", "/api/v1/notes/*", "/api/v1/notes/*/append", "/api/v1/notes/*/prepend"} {
+			a.SecurityMW.SetRequestSizeLimit(path, noteRequestSize)
+		}
+	}
+
+	// Initialize note service with saved searches, notifications, search subscriptions and links
+	noteService := services.NewNoteService(a.DB, tagService)
+	noteService.SetEncryptor(noteEncryptor)
+
+	// Users may require a TOTP code on sign-in; their secrets are encrypted
+	// with their own keys when a master key is configured
+	twoFactorService := services.NewTwoFactorService(a.DB, "Silence Notes")
+	twoFactorService.SetEncryptor(twoFactorEncryptor)
+	chromeAuthHandler.SetTwoFactorService(twoFactorService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, a.TokenService)
+	twoFactorHandler.SetSessionService(sessionService)
+	twoFactorHandler.SetLoginGuard(a.LoginGuard)
+	a.Handlers.SetTwoFactorHandler(twoFactorHandler)
+	savedSearchService := services.NewSavedSearchService(a.DB, noteService)
+	notificationService := services.NewNotificationService(a.DB)
+	subscriptionService := services.NewSubscriptionService(a.DB, noteService, notificationService, savedSearchService)
+	noteService.AddWriteListener(subscriptionService)
+	linkService := services.NewLinkService(a.DB, noteService)
+	noteService.AddWriteListener(linkService)
+
+	// Destructive bulk operations need a one-time code beyond the threshold.
+	// Codes are logged until an email sender is configured.
+	confirmationSender := opts.ConfirmationSender
+	if confirmationSender == nil {
+		confirmationSender = services.LogConfirmationSender{}
+	}
+	confirmationService := services.NewConfirmationService(a.DB, confirmationSender,
+		a.Config.Confirmation.BulkThreshold, time.Duration(a.Config.Confirmation.CodeTTL)*time.Minute)
+	confirmationCleanupLoop(a.Jobs, confirmationService, 1*time.Hour)
+
+	// Notes and accounts under legal hold cannot be deleted
+	legalHoldService := services.NewLegalHoldService(a.DB)
+	noteService.SetLegalHolds(legalHoldService)
+	userService.SetLegalHolds(legalHoldService)
+
+	// Deleted accounts can be restored until the grace period ends
+	userService.SetDeletionGracePeriod(time.Duration(a.Config.Account.DeletionGraceDays) * 24 * time.Hour)
+	accountDeletionLoop(a.Jobs, userService, 1*time.Hour)
+
+	// Record account activity and analyze it for unusual patterns
+	activityService := services.NewActivityService(a.DB)
+	thresholds := anomaly.DefaultThresholds()
+	thresholds.MassDeletionCount = a.Config.Anomaly.MassDeletionCount
+	thresholds.APIKeyBurstCount = a.Config.Anomaly.APIKeyBurstCount
+	anomalyService := services.NewAnomalyService(a.DB, notificationService, thresholds,
+		a.Config.Anomaly.AutoLock, time.Duration(a.Config.Anomaly.LockDuration)*time.Hour)
+	if a.Config.Anomaly.Interval > 0 {
+		anomalyAnalysisLoop(a.Jobs, anomalyService, time.Duration(a.Config.Anomaly.Interval)*time.Minute)
+	} else {
+		log.Println("ℹ️  Anomaly detection disabled")
+	}
+	activityCleanupLoop(a.Jobs, activityService, 1*time.Hour)
+	chromeAuthHandler.SetActivityService(activityService)
+
+	// Tell users about sign-ins from devices and locations they have not used
+	var loginAlertService *services.LoginAlertService
+	if a.Config.Login.NewDeviceAlerts {
+		loginAlertService = services.NewLoginAlertService(a.DB, notificationService)
+		chromeAuthHandler.SetLoginAlertService(loginAlertService)
+	}
+
+	// Record who did what to which note in the audit log
+	auditService := services.NewAuditService(a.DB, time.Duration(a.Config.Audit.RetentionDays)*24*time.Hour)
+	auditCleanupLoop(a.Jobs, auditService, 1*time.Hour)
+
+	// Migrate user data to and from other deployments
+	migrationService := services.NewMigrationService(a.DB, noteService, userService, savedSearchService,
+		subscriptionService, time.Duration(a.Config.Migration.TransferTTL)*time.Hour, a.Config.Migration.AllowInsecure)
+	migrationService.SetLinkListener(linkService)
+	migrationService.SetJobRunner(a.Jobs)
+	a.Jobs.Go("resume migrations", func(ctx context.Context) {
+		resumed, err := migrationService.ResumeInterruptedMigrations(ctx)
+		if err != nil {
+			log.Printf("⚠️  Failed to resume interrupted migrations: %v", err)
+		} else if resumed > 0 {
+			log.Printf("🔄 Resumed %d interrupted migrations", resumed)
+		}
+	})
+	migrationCleanupLoop(a.Jobs, migrationService, 1*time.Hour)
+
+	// Serve the change log to sync clients and purge old records
+	changeService := services.NewChangeService(a.DB)
+	changeCleanupLoop(a.Jobs, changeService, 1*time.Hour)
+	tombstoneCleanupLoop(a.Jobs, noteService, 1*time.Hour)
+
+	// Roll up checklist progress across the notes of a tag
+	progressService := services.NewProgressService(a.DB, noteService)
+
+	// Track checklist items of notes as tasks
+	taskService := services.NewTaskService(a.DB, noteService)
+	noteService.AddWriteListener(taskService)
+
+	// Show notes on kanban boards by their status tags
+	boardService := services.NewBoardService(a.DB, noteService)
+
+	// Start notes from templates
+	templateService := services.NewTemplateService(a.DB, noteService)
+	migrationService.SetTemplateService(templateService)
+
+	// Create notes from templates on a schedule
+	recurrenceService := services.NewRecurrenceService(a.DB, templateService)
+	recurrenceLoop(a.Jobs, recurrenceService, 1*time.Minute)
+
+	// Keep a journal note per day
+	dailyNoteService := services.NewDailyNoteService(a.DB, noteService)
+	dailyNoteService.SetTemplateService(templateService)
+
+	// Track time spent on notes in focus sessions
+	focusService := services.NewFocusService(a.DB)
+	noteService.SetFocusTimes(focusService)
+
+	// Email daily and weekly digests to users who opted in. Emails are
+	// logged until an SMTP server is configured.
+	emailSender := opts.EmailSender
+	if emailSender == nil {
+		emailSender = email.LogSender{}
+		if a.Config.Email.SMTPHost != "" {
+			emailSender = email.NewSMTPSender(a.Config.Email.SMTPHost, a.Config.Email.SMTPPort,
+				a.Config.Email.SMTPUsername, a.Config.Email.SMTPPassword, a.Config.Email.From)
+		} else {
+			log.Println("ℹ️  No SMTP server configured - emails are logged")
+		}
+	}
+	// Notify users of notes with the tags of their notification rules
+	notificationRuleService := services.NewNotificationRuleService(a.DB, notificationService, emailSender)
+	noteService.AddWriteListener(notificationRuleService)
+
+	digestService := services.NewDigestService(a.DB, progressService, emailSender, a.Config.Digest.SendHour)
+	if a.Config.Digest.Interval > 0 {
+		digestLoop(a.Jobs, digestService, time.Duration(a.Config.Digest.Interval)*time.Minute)
+	} else {
+		log.Println("ℹ️  Digest emails disabled")
+	}
+
+	// Send the note and tag events queued for webhooks by database triggers
+	webhookService := services.NewWebhookService(a.DB, a.Config.Webhook.AllowInsecure)
+	if a.Config.Webhook.Interval > 0 {
+		webhookDeliveryLoop(a.Jobs, webhookService, time.Duration(a.Config.Webhook.Interval)*time.Second)
+	} else {
+		log.Println("ℹ️  Webhook deliveries disabled")
+	}
+
+	// Initialize import service and clean up abandoned import sessions
+	importService := services.NewImportService(a.DB, noteService)
+	importService.SetLinkListener(linkService)
+	importService.SetMaxContentLength(a.Config.Notes.MaxContentLength)
+	importCleanupLoop(a.Jobs, importService, 1*time.Hour)
+
+	// Let administrators manage accounts and run cleanup jobs on demand
+	adminService := services.NewAdminService(a.DB)
+	adminService.RegisterMaintenanceTask("cleanup_unused_tags", tagService.CleanupUnusedTags)
+	adminService.RegisterMaintenanceTask("cleanup_expired_tokens", blacklistSvc.CleanupExpiredTokens)
+	adminService.RegisterMaintenanceTask("cleanup_expired_confirmations", confirmationService.CleanupExpiredConfirmations)
+	adminService.RegisterMaintenanceTask("cleanup_old_activity", activityService.CleanupOldEvents)
+	adminService.RegisterMaintenanceTask("cleanup_old_audit_entries", auditService.CleanupOldEntries)
+	adminService.RegisterMaintenanceTask("purge_deleted_accounts", userService.PurgeScheduledDeletions)
+	adminService.RegisterMaintenanceTask("rebuild_note_tasks", taskService.RebuildTasks)
+	adminService.RegisterMaintenanceTask("detect_note_languages", noteService.DetectLanguages)
+	adminService.RegisterMaintenanceTask("cleanup_expired_transfers", migrationService.CleanupExpiredTransfers)
+	adminService.RegisterMaintenanceTask("cleanup_old_changes", changeService.CleanupOldChanges)
+	adminService.RegisterMaintenanceTask("cleanup_note_tombstones", noteService.CleanupOldTombstones)
+	adminService.RegisterMaintenanceTask("cleanup_expired_imports", importService.CleanupExpiredSessions)
+	adminService.RegisterMaintenanceTask("cleanup_old_webhook_deliveries", webhookService.CleanupOldDeliveries)
+
+	// Account for the LLM tokens each user consumes and enforce budgets
+	llmUsageService := services.NewLLMUsageService(a.DB, a.Config.LLM.MonthlyTokenBudget)
+
+	// Cache prettify and digest responses so unchanged content skips the LLM
+	var llmCacheService *services.LLMCacheService
+	if a.Config.LLM.CacheTTL > 0 {
+		llmCacheService = services.NewLLMCacheService(a.DB, time.Duration(a.Config.LLM.CacheTTL)*time.Hour)
+		noteService.AddWriteListener(llmCacheService)
+		adminService.RegisterMaintenanceTask("cleanup_expired_llm_cache", llmCacheService.CleanupExpired)
+		llmCacheCleanupLoop(a.Jobs, llmCacheService, 1*time.Hour)
+	} else {
+		log.Println("ℹ️  LLM response cache disabled")
+	}
+
+	// Render LLM prompts from templates administrators can override and reload
+	promptRegistry := prompts.New(a.Config.LLM.PromptsDir)
+	if _, err := promptRegistry.Reload(); err != nil {
+		log.Printf("⚠️  Failed to load prompt overrides: %v - using built-in prompts", err)
+	}
+	tagService.SetPrompts(promptRegistry)
+	digestService.SetPrompts(promptRegistry)
+
+	log.Printf("🔍 Checking LLM configuration...")
+	log.Printf("   LLM providers: %v", llm.ProviderChain(a.Config))
+	log.Printf("   Credentials configured: %t", llm.Configured(a.Config))
+
+	resilientLLM = opts.LLM
+	if resilientLLM == nil && llm.Configured(a.Config) {
+		var err error
+		log.Printf("🔧 Creating LLM client...")
+		resilientLLM, err = llm.NewResilientLLM(context.Background(), a.Config, nil)
+		if err != nil {
+			log.Printf("⚠️  Failed to create LLM client: %v - AI features disabled", err)
+		}
+	} else if resilientLLM == nil {
+		log.Println("ℹ️  No LLM provider configured - semantic search disabled")
+		log.Println("ℹ️  Prettify service disabled")
+		log.Println("ℹ️  Proofreading disabled")
+		log.Println("ℹ️  Translation disabled")
+		log.Println("ℹ️  Tag suggestions disabled")
+		log.Println("ℹ️  Question answering disabled")
+		log.Println("   Set LLM_DEEPSEEK_TENCENT_API_KEY, configure LLM_PROVIDERS, or set LLM_TYPE=OLLAMA to enable")
+	}
+
+	if resilientLLM != nil {
+		var err error
+		// The tokenizer downloads its encoding on first use, so it may be
+		// unavailable offline. Only the features that budget prompts by
+		// tokens need it; usage is then taken from provider reports.
+		log.Printf("🔧 Creating tokenizer...")
+		tokenizer, err = llm.NewTokenizer()
+		if err != nil {
+			log.Printf("⚠️  Failed to create tokenizer: %v - semantic search and question answering disabled", err)
+		}
+		resilientLLM.SetUsageRecorder(llmUsageService, tokenizer)
+
+		log.Printf("🔧 Initializing prettify service...")
+		prettifyService = services.NewPrettifyService(
+			resilientLLM,
+			noteService,
+			tagService,
+			a.DB,
+		)
+		prettifyService.SetPrompts(promptRegistry)
+		if tokenizer != nil {
+			prettifyService.SetTokenizer(tokenizer, a.Config.LLM.PrettifyChunkTokens)
+		}
+		if llmCacheService != nil {
+			prettifyService.SetCache(llmCacheService)
+			digestService.SetCache(llmCacheService)
+		}
+		log.Println("✅ Prettify service enabled")
+		proofreadService = services.NewProofreadService(a.DB, noteService, resilientLLM)
+		proofreadService.SetPrompts(promptRegistry)
+		log.Println("✅ Proofreading enabled")
+		translationService = services.NewTranslationService(a.DB, noteService, resilientLLM)
+		translationService.SetPrompts(promptRegistry)
+		log.Println("✅ Translation enabled")
+		if a.Config.LLM.GenerateTitles {
+			titleService := services.NewTitleService(resilientLLM)
+			titleService.SetPrompts(promptRegistry)
+			noteService.SetTitleGenerator(titleService, time.Duration(a.Config.LLM.TitleTimeout)*time.Second)
+			log.Println("✅ Title generation enabled")
+		}
+		tagService.SetLLM(resilientLLM)
+		log.Println("✅ Tag suggestions enabled")
+		if a.Config.Digest.Summarize {
+			digestService.SetLLM(resilientLLM)
+		}
+		if tokenizer != nil {
+			log.Printf("🔧 Initializing semantic search service...")
+			semanticSearchService = services.NewSemanticSearchService(
+				resilientLLM,
+				tokenizer,
+				noteService,
+				a.Config.LLM.MaxSearchTokenLength,
+			)
+			log.Println("✅ Semantic search enabled")
+			qaService = services.NewQAService(
+				resilientLLM,
+				tokenizer,
+				noteService,
+				a.Config.LLM.QAContextTokens,
+			)
+			log.Println("✅ Question answering enabled")
+		}
+		a.Handlers.Health.SetLLM(resilientLLM)
+		if a.Config.LLM.HealthCheckInterval > 0 {
+			llmHealthCheckLoop(a.Jobs, resilientLLM, time.Duration(a.Config.LLM.HealthCheckInterval)*time.Second)
+		}
+	}
+
+	// Initialize notes handler
+	notesHandler := handlers.NewNotesHandler(noteService, semanticSearchService, prettifyService, confirmationService)
+	notesHandler.SetActivityService(activityService)
+	notesHandler.SetAuditService(auditService)
+	notesHandler.SetFocusTimes(focusService)
+	notesHandler.SetChangeService(changeService)
+
+	// Initialize tags handler
+	tagsHandler := handlers.NewTagsHandler(tagService, noteService, confirmationService)
+	tagsHandler.SetUserService(userService)
+
+	// Initialize auth handlers
+	a.Handlers.SetAuthHandlers(authHandler, chromeAuthHandler)
+
+	// Initialize notes handler
+	a.Handlers.SetNotesHandler(notesHandler)
+
+	// Initialize tags handler
+	a.Handlers.SetTagsHandler(tagsHandler)
+
+	// Initialize subscription and notification handlers
+	a.Handlers.SetSubscriptionsHandler(handlers.NewSubscriptionsHandler(subscriptionService))
+	a.Handlers.SetNotificationsHandler(handlers.NewNotificationsHandler(notificationService))
+	a.Handlers.SetNotificationRulesHandler(handlers.NewNotificationRulesHandler(notificationRuleService))
+	a.Handlers.SetSavedSearchesHandler(handlers.NewSavedSearchesHandler(savedSearchService))
+
+	// Initialize account handler
+	a.Handlers.SetAccountHandler(handlers.NewAccountHandler(userService, confirmationService))
+
+	// Initialize note links handler
+	a.Handlers.SetLinksHandler(handlers.NewLinksHandler(linkService))
+
+	// Initialize question answering handler
+	if qaService != nil {
+		a.Handlers.SetQAHandler(handlers.NewQAHandler(qaService))
+	}
+
+	// Initialize proofreading handler
+	if proofreadService != nil {
+		a.Handlers.SetProofreadHandler(handlers.NewProofreadHandler(proofreadService))
+	}
+
+	// Initialize note translation handler
+	if translationService != nil {
+		a.Handlers.SetTranslationHandler(handlers.NewTranslationHandler(translationService))
+	}
+
+	// Initialize import wizard handler; uploads may exceed the default request size limit
+	importService.SetArchiveLimits(int64(a.Config.Import.MaxArchiveSize)<<20, a.Config.Import.MaxArchiveNotes)
+	importsHandler := handlers.NewImportsHandler(importService)
+	importsHandler.SetMaxUploadSize(int64(a.Config.Import.MaxUploadSize) << 20)
+	a.Handlers.SetImportsHandler(importsHandler)
+	a.SecurityMW.SetRequestSizeLimit("/api/v1/imports", int64(a.Config.Import.MaxUploadSize)<<20)
+	a.SecurityMW.SetRequestSizeLimit("/api/v1/imports/archive", int64(a.Config.Import.MaxArchiveSize)<<20)
+	a.SecurityMW.SetRequestSizeLimit("/api/v1/imports/vault", int64(a.Config.Import.MaxArchiveSize)<<20)
+
+	// Services of the per-user data and watermark policy of exports, whose
+	// handlers are initialized below
+	notebookService := services.NewNotebookService(a.DB)
+	commentService := services.NewCommentService(a.DB, notificationService, emailSender)
+	organizationService := services.NewOrganizationService(a.DB, emailSender)
+	apiKeyService := services.NewAPIKeyService(a.DB)
+
+	// Initialize note export handler
+	exportService := services.NewExportService(noteService)
+	exportService.SetAccountSources(userService, savedSearchService, subscriptionService, auditService)
+	exportService.SetWorkspaceSources(templateService, notebookService, recurrenceService, commentService, webhookService, apiKeyService)
+	exportService.SetWatermarkPolicy(organizationService)
+	exportsHandler := handlers.NewExportsHandler(exportService)
+	exportsHandler.SetActivityService(activityService)
+	a.Handlers.SetExportsHandler(exportsHandler)
+
+	// Initialize change feed handler
+	a.Handlers.SetChangesHandler(handlers.NewChangesHandler(changeService))
+
+	// Initialize LLM usage handler
+	a.Handlers.SetUsageHandler(handlers.NewUsageHandler(llmUsageService))
+
+	// Initialize checklist progress handler
+	a.Handlers.SetProgressHandler(handlers.NewProgressHandler(progressService))
+
+	// Initialize note tasks handler
+	tasksHandler := handlers.NewTasksHandler(taskService)
+	tasksHandler.SetAuditService(auditService)
+	a.Handlers.SetTasksHandler(tasksHandler)
+
+	// Initialize kanban boards handler
+	boardsHandler := handlers.NewBoardsHandler(boardService)
+	boardsHandler.SetAuditService(auditService)
+	a.Handlers.SetBoardsHandler(boardsHandler)
+
+	// Initialize notes calendar handler, with the iCal feed of due dates and
+	// recurring notes
+	calendarFeedService := services.NewCalendarFeedService(a.DB)
+	calendarFeedService.SetRecurrenceService(recurrenceService)
+	calendarHandler := handlers.NewCalendarHandler(services.NewCalendarService(a.DB))
+	calendarHandler.SetCalendarFeedService(calendarFeedService)
+	a.Handlers.SetCalendarHandler(calendarHandler)
+
+	// Initialize note templates handler
+	templatesHandler := handlers.NewTemplatesHandler(templateService)
+	templatesHandler.SetAuditService(auditService)
+	a.Handlers.SetTemplatesHandler(templatesHandler)
+
+	// Initialize recurring notes handler
+	a.Handlers.SetRecurrencesHandler(handlers.NewRecurrencesHandler(recurrenceService))
+
+	// Initialize daily notes handler
+	a.Handlers.SetDailyNotesHandler(handlers.NewDailyNotesHandler(dailyNoteService))
+
+	// Initialize note properties handler
+	a.Handlers.SetPropertiesHandler(handlers.NewPropertiesHandler(services.NewPropertyService(a.DB)))
+
+	// Initialize notebooks handler
+	a.Handlers.SetNotebooksHandler(handlers.NewNotebooksHandler(notebookService))
+
+	// Initialize organizations handler; invitations are emailed
+	a.Handlers.SetOrganizationsHandler(handlers.NewOrganizationsHandler(organizationService))
+
+	// Initialize activity feed handler
+	a.Handlers.SetFeedHandler(handlers.NewFeedHandler(services.NewFeedService(a.DB)))
+
+	// Initialize note publishing handler
+	a.Handlers.SetPublicationsHandler(handlers.NewPublicationsHandler(services.NewPublicationService(a.DB, noteService)))
+
+	// Initialize note comments handler; mentions notify in the app and by email
+	a.Handlers.SetCommentsHandler(handlers.NewCommentsHandler(commentService))
+
+	// Initialize email-to-note handler; Mailgun forwards mail for the inbound domain
+	maxMessageSize := int64(a.Config.Inbound.MaxMessageSize) << 20
+	a.SecurityMW.SetRequestSizeLimit("/api/v1/inbound/mailgun", maxMessageSize)
+	a.Handlers.SetInboundEmailHandler(handlers.NewInboundEmailHandler(
+		services.NewInboundEmailService(a.DB, noteService, a.Config.Inbound.Domain),
+		a.Config.Inbound.MailgunSigningKey, maxMessageSize))
+
+	// Initialize RSS and Atom feeds of recent notes
+	a.Handlers.SetNoteFeedsHandler(handlers.NewNoteFeedsHandler(services.NewNoteFeedService(a.DB)))
+
+	// Initialize focus session handler
+	a.Handlers.SetFocusHandler(handlers.NewFocusHandler(focusService))
+
+	// Initialize digest email handler
+	a.Handlers.SetDigestHandler(handlers.NewDigestHandler(digestService))
+
+	// Initialize webhook handler
+	a.Handlers.SetWebhooksHandler(handlers.NewWebhooksHandler(webhookService))
+
+	// Initialize audit log handler
+	a.Handlers.SetAuditHandler(handlers.NewAuditHandler(auditService))
+
+	// Initialize API key and capture handlers; captures authenticate by API key
+	if a.SecurityMW != nil {
+		a.SecurityMW.SetAPIKeyService(apiKeyService)
+	}
+	apiKeysHandler := handlers.NewAPIKeysHandler(apiKeyService)
+	apiKeysHandler.SetActivityService(activityService)
+	a.Handlers.SetAPIKeysHandler(apiKeysHandler)
+	captureHandler := handlers.NewCaptureHandler(noteService)
+	captureHandler.SetDailyNoteService(dailyNoteService)
+	webClipService := services.NewWebClipService(noteService, int64(a.Config.WebClip.MaxPageSize)<<20, a.Config.WebClip.AllowInsecure)
+	webClipService.SetPrompts(promptRegistry)
+	if resilientLLM != nil && a.Config.WebClip.Summarize {
+		webClipService.SetLLM(resilientLLM)
+	}
+	captureHandler.SetWebClipService(webClipService)
+	a.Handlers.SetCaptureHandler(captureHandler)
+
+	// Initialize image text recognition; the text of images uploaded to notes
+	// becomes searchable
+	switch a.Config.OCR.Engine {
+	case "":
+	case "tesseract":
+		tesseract := ocr.NewTesseract(a.Config.OCR.TesseractPath, a.Config.OCR.Languages)
+		if err := tesseract.Check(); err != nil {
+			log.Printf("⚠️  %v - image text recognition disabled", err)
+			break
+		}
+		maxImageSize := int64(a.Config.OCR.MaxImageSize) << 20
+		a.SecurityMW.SetRequestSizeLimit("/api/v1/notes/*/image-text", maxImageSize)
+		a.Handlers.SetImageTextHandler(handlers.NewImageTextHandler(
+			services.NewImageTextService(a.DB, noteService, tesseract), maxImageSize))
+	default:
+		log.Printf("⚠️  Unknown OCR engine %q - image text recognition disabled", a.Config.OCR.Engine)
+	}
+
+	// Initialize the normalizer of rich text pasted into notes
+	a.Handlers.SetPasteHandler(handlers.NewPasteHandler(services.NewPasteService()))
+
+	// Initialize voice memo transcription; memos become notes of their transcripts
+	switch a.Config.Transcription.Provider {
+	case "":
+	case "openai":
+		transcriptionService := services.NewTranscriptionService(noteService, transcription.NewOpenAI(
+			a.Config.Transcription.BaseURL, a.Config.Transcription.APIKey, a.Config.Transcription.Model,
+			time.Duration(a.Config.Transcription.Timeout)*time.Second))
+		if prettifyService != nil {
+			transcriptionService.SetPrettifier(prettifyService)
+		}
+		maxAudioSize := int64(a.Config.Transcription.MaxAudioSize) << 20
+		a.SecurityMW.SetRequestSizeLimit("/api/v1/notes/transcribe", maxAudioSize)
+		a.Handlers.SetTranscriptionHandler(handlers.NewTranscriptionHandler(transcriptionService, maxAudioSize))
+	default:
+		log.Printf("⚠️  Unknown transcription provider %q - voice memos disabled", a.Config.Transcription.Provider)
+	}
+
+	// Note creations retried with the same Idempotency-Key get the original response
+	idempotencyService := services.NewIdempotencyService(a.DB)
+	idempotencyCleanupLoop(a.Jobs, idempotencyService, 1*time.Hour)
+
+	// Initialize the GraphQL endpoint
+	graphQLSchema, err := graphql.NewSchema(noteService, tagService)
+	if err != nil {
+		log.Printf("⚠️  Failed to parse GraphQL schema: %v - GraphQL disabled", err)
+	} else {
+		a.Handlers.SetGraphQLHandler(handlers.NewGraphQLHandler(graphQLSchema))
+	}
+
+	// Initialize the gRPC API, authenticated by session token or API key
+	a.GRPC = grpcserver.NewServer(noteService, tagService,
+		grpcserver.NewAuthenticator(a.TokenService, apiKeyService, userService))
+
+	// Initialize data migration handler
+	migrationsHandler := handlers.NewMigrationsHandler(migrationService)
+	migrationsHandler.SetActivityService(activityService)
+	a.Handlers.SetMigrationsHandler(migrationsHandler)
+
+	// Initialize administrator handler
+	adminHandler := handlers.NewAdminHandler(anomalyService, legalHoldService)
+	adminHandler.SetAdminService(adminService)
+	var promptCache handlers.PromptCache
+	if llmCacheService != nil {
+		promptCache = llmCacheService
+	}
+	adminHandler.SetPrompts(promptRegistry, promptCache)
+	a.Handlers.SetAdminHandler(adminHandler)
+
+	log.Printf("✅ Security services initialized")
+	log.Printf("🔒 Security mode: %s", a.Config.App.Environment)
+	log.Printf("🚦 Rate limiting: %.0f req/sec global, %d req/min per user",
+		securityConfig.RateLimiting.GlobalRequestsPerSecond,
+		securityConfig.RateLimiting.UserRequestsPerMinute)
+
+	a.LLM = resilientLLM
+	a.Tokenizer = tokenizer
+	a.Services = &Services{
+		Users:             userService,
+		Sessions:          sessionService,
+		LoginAlerts:       loginAlertService,
+		TwoFactor:         twoFactorService,
+		Blacklist:         blacklistSvc,
+		APIKeys:           apiKeyService,
+		Idempotency:       idempotencyService,
+		Tags:              tagService,
+		Notes:             noteService,
+		SavedSearches:     savedSearchService,
+		Notifications:     notificationService,
+		NotificationRules: notificationRuleService,
+		Subscriptions:     subscriptionService,
+		Links:             linkService,
+		Confirmations:     confirmationService,
+		LegalHolds:        legalHoldService,
+		Activity:          activityService,
+		Anomaly:           anomalyService,
+		Audit:             auditService,
+		Migrations:        migrationService,
+		Changes:           changeService,
+		Progress:          progressService,
+		Tasks:             taskService,
+		Boards:            boardService,
+		Templates:         templateService,
+		Recurrences:       recurrenceService,
+		DailyNotes:        dailyNoteService,
+		Focus:             focusService,
+		Digest:            digestService,
+		Webhooks:          webhookService,
+		Imports:           importService,
+		Exports:           exportService,
+		Admin:             adminService,
+		LLMUsage:          llmUsageService,
+		LLMCache:          llmCacheService,
+		Prompts:           promptRegistry,
+		Prettify:          prettifyService,
+		Proofread:         proofreadService,
+		Translation:       translationService,
+		SemanticSearch:    semanticSearchService,
+		QA:                qaService,
+	}
+
+	return a
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	cfg := &config.Config{
+		App:  config.AppConfig{Environment: "test"},
+		Auth: config.AuthConfig{JWTSecret: "test-secret-key-that-is-long-enough-for-validation"},
+	}
+
+	a := New(cfg, db, Options{})
+	t.Cleanup(func() { a.Jobs.Drain(context.Background()) })
+	require.NotNil(t, a.Services)
+	assert.NotNil(t, a.Services.Notes)
+	assert.NotNil(t, a.Handlers.Notes)
+	assert.NotNil(t, a.TokenService)
+
+	// Without an LLM provider the features needing one are left out
+	assert.Nil(t, a.LLM)
+	assert.Nil(t, a.Services.Prettify)
+	assert.Nil(t, a.Handlers.QA)
+}
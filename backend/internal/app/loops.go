@@ -0,0 +1,249 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/lifecycle"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// blacklistCleanupLoop runs periodic cleanup of expired blacklist entries
+func blacklistCleanupLoop(jobs *lifecycle.Manager, svc *services.BlacklistService, interval time.Duration) {
+	jobs.Every("blacklist cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupExpiredTokens(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup expired tokens: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d expired blacklist entries", rows)
+		}
+	})
+}
+
+// loginGuardCleanupLoop periodically forgets the failed authentication
+// attempts that no longer count
+func loginGuardCleanupLoop(jobs *lifecycle.Manager, guard *auth.LoginGuard, interval time.Duration) {
+	jobs.Every("login guard cleanup", interval, func(ctx context.Context) {
+		guard.Cleanup()
+	})
+}
+
+// llmHealthCheckLoop periodically probes the LLM providers, so that failed
+// providers are tried again once they recover
+func llmHealthCheckLoop(jobs *lifecycle.Manager, client *llm.ResilientLLM, interval time.Duration) {
+	jobs.Every("LLM health check", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		for _, status := range client.CheckHealth(ctx) {
+			if !status.Healthy {
+				log.Printf("WARNING: LLM provider %s is unhealthy: %s", status.Name, status.LastError)
+			}
+		}
+	})
+}
+
+// importCleanupLoop runs periodic cleanup of expired import sessions
+func importCleanupLoop(jobs *lifecycle.Manager, svc *services.ImportService, interval time.Duration) {
+	jobs.Every("import cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupExpiredSessions(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup expired import sessions: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d expired import sessions", rows)
+		}
+	})
+}
+
+// migrationCleanupLoop runs periodic cleanup of expired migration transfers
+func migrationCleanupLoop(jobs *lifecycle.Manager, svc *services.MigrationService, interval time.Duration) {
+	jobs.Every("migration cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupExpiredTransfers(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup expired migration transfers: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d expired migration transfers", rows)
+		}
+	})
+}
+
+// changeCleanupLoop runs periodic cleanup of old change log records
+func changeCleanupLoop(jobs *lifecycle.Manager, svc *services.ChangeService, interval time.Duration) {
+	jobs.Every("change log cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupOldChanges(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup change log: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d change log records", rows)
+		}
+	})
+}
+
+// tombstoneCleanupLoop runs periodic cleanup of the deleted notes reported to sync
+func tombstoneCleanupLoop(jobs *lifecycle.Manager, svc *services.NoteService, interval time.Duration) {
+	jobs.Every("tombstone cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupOldTombstones(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup note tombstones: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d note tombstones", rows)
+		}
+	})
+}
+
+// recurrenceLoop periodically creates the notes of due recurrences
+func recurrenceLoop(jobs *lifecycle.Manager, svc *services.RecurrenceService, interval time.Duration) {
+	jobs.Every("recurrences", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		created, err := svc.RunDueRecurrences(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: failed to run recurrences: %v", err)
+		} else if created > 0 {
+			log.Printf("Created %d recurring notes", created)
+		}
+	})
+}
+
+// llmCacheCleanupLoop runs periodic cleanup of expired LLM responses
+func llmCacheCleanupLoop(jobs *lifecycle.Manager, svc *services.LLMCacheService, interval time.Duration) {
+	jobs.Every("LLM cache cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupExpired(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup LLM cache: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d cached LLM responses", rows)
+		}
+	})
+}
+
+// confirmationCleanupLoop runs periodic cleanup of expired confirmation codes
+func confirmationCleanupLoop(jobs *lifecycle.Manager, svc *services.ConfirmationService, interval time.Duration) {
+	jobs.Every("confirmation cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupExpiredConfirmations(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup expired confirmations: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d expired confirmations", rows)
+		}
+	})
+}
+
+// idempotencyCleanupLoop runs periodic cleanup of expired idempotency keys
+func idempotencyCleanupLoop(jobs *lifecycle.Manager, svc *services.IdempotencyService, interval time.Duration) {
+	jobs.Every("idempotency cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupExpiredKeys(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup expired idempotency keys: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d expired idempotency keys", rows)
+		}
+	})
+}
+
+// anomalyAnalysisLoop periodically analyzes recent account activity
+func anomalyAnalysisLoop(jobs *lifecycle.Manager, svc *services.AnomalyService, interval time.Duration) {
+	since := time.Now().Add(-interval)
+	jobs.Every("anomaly analysis", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		started := time.Now()
+		raised, err := svc.Analyze(ctx, since)
+		if err != nil {
+			log.Printf("ERROR: failed to analyze account activity: %v", err)
+		} else {
+			since = started
+			if raised > 0 {
+				log.Printf("Flagged %d account anomalies for review", raised)
+			}
+		}
+	})
+}
+
+// digestLoop periodically sends the digest emails that are due
+func digestLoop(jobs *lifecycle.Manager, svc *services.DigestService, interval time.Duration) {
+	jobs.Every("digests", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
+		sent, err := svc.SendDueDigests(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: failed to send digest emails: %v", err)
+		} else if sent > 0 {
+			log.Printf("Sent %d digest emails", sent)
+		}
+	})
+}
+
+// activityCleanupLoop runs periodic cleanup of old activity events
+func activityCleanupLoop(jobs *lifecycle.Manager, svc *services.ActivityService, interval time.Duration) {
+	jobs.Every("activity cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupOldEvents(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup activity events: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d old activity events", rows)
+		}
+	})
+}
+
+// auditCleanupLoop runs periodic cleanup of audit entries past the retention period
+func auditCleanupLoop(jobs *lifecycle.Manager, svc *services.AuditService, interval time.Duration) {
+	jobs.Every("audit cleanup", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.CleanupOldEntries(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to cleanup audit entries: %v", err)
+		} else if rows > 0 {
+			log.Printf("Cleaned up %d old audit entries", rows)
+		}
+	})
+}
+
+// accountDeletionLoop periodically purges accounts whose deletion grace period has ended
+func accountDeletionLoop(jobs *lifecycle.Manager, svc *services.UserService, interval time.Duration) {
+	jobs.Every("account deletion", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		rows, err := svc.PurgeScheduledDeletions(ctx)
+		if err != nil {
+			log.Printf("ERROR: failed to purge deleted accounts: %v", err)
+		} else if rows > 0 {
+			log.Printf("Purged %d deleted accounts", rows)
+		}
+	})
+}
+
+// webhookDeliveryLoop periodically sends the webhook deliveries that are due
+func webhookDeliveryLoop(jobs *lifecycle.Manager, svc *services.WebhookService, interval time.Duration) {
+	jobs.Every("webhook delivery", interval, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
+		delivered, err := svc.DeliverPending(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: failed to deliver webhooks: %v", err)
+		} else if delivered > 0 {
+			log.Printf("Delivered %d webhook events", delivered)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+// Package apperrors defines the errors services return for failures a client
+// can act on, such as a missing note or a stale version. Each error has a
+// kind, which decides its HTTP status, and a machine-readable code that error
+// responses carry, such as NOTE_VERSION_CONFLICT.
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of errors, matched with errors.Is
+var (
+	ErrValidation         = errors.New("validation failed")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrGone               = errors.New("gone")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrTooLarge           = errors.New("too large")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrUnavailable        = errors.New("unavailable")
+)
+
+// Codes of errors without a more specific one
+const (
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodeNotFound           = "NOT_FOUND"
+	CodeConflict           = "CONFLICT"
+	CodeGone               = "GONE"
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+	CodeTooLarge           = "PAYLOAD_TOO_LARGE"
+	CodeLocked             = "LOCKED"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeUnavailable        = "SERVICE_UNAVAILABLE"
+	CodeInternal           = "INTERNAL_ERROR"
+)
+
+// kinds maps each kind to its status and default code
+var kinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{ErrValidation, http.StatusBadRequest, CodeBadRequest},
+	{ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized},
+	{ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrConflict, http.StatusConflict, CodeConflict},
+	{ErrGone, http.StatusGone, CodeGone},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFailed},
+	{ErrTooLarge, http.StatusRequestEntityTooLarge, CodeTooLarge},
+	{ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited},
+	{ErrUnavailable, http.StatusServiceUnavailable, CodeUnavailable},
+}
+
+// Error is an error of a known kind. Its message is sent to clients, so it
+// must not carry internal details.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	// Err is the error this one was made from, if any
+	Err error
+}
+
+// New creates an error of a kind with a code and a message
+func New(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap creates an error of a kind from another error, such as a failed
+// request validation, taking its message
+func Wrap(kind error, code string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Message: err.Error(), Err: err}
+}
+
+// NotFound creates an ErrNotFound error
+func NotFound(code, message string) *Error {
+	return New(ErrNotFound, code, message)
+}
+
+// Conflict creates an ErrConflict error
+func Conflict(code, message string) *Error {
+	return New(ErrConflict, code, message)
+}
+
+// Validation creates an ErrValidation error
+func Validation(code, message string) *Error {
+	return New(ErrValidation, code, message)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind and the wrapped error, so errors.Is and errors.As
+// match both
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Status returns the HTTP status of an error: the status of its kind, or 500
+// for errors of no known kind
+func Status(err error) int {
+	for _, k := range kinds {
+		if errors.Is(err, k.kind) {
+			return k.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Code returns the machine-readable code of an error: its own code, the
+// default code of its kind, or INTERNAL_ERROR
+func Code(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Code != "" {
+		return appErr.Code
+	}
+	for _, k := range kinds {
+		if errors.Is(err, k.kind) {
+			return k.code
+		}
+	}
+	return CodeInternal
+}
+
+// CodeForStatus returns the default code of an HTTP error status, for errors
+// raised outside services, such as by middleware
+func CodeForStatus(status int) string {
+	for _, k := range kinds {
+		if k.status == status {
+			return k.code
+		}
+	}
+	if status == http.StatusLocked {
+		return CodeLocked
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
@@ -0,0 +1,68 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatusAndCode(t *testing.T) {
+	notFound := NotFound("NOTE_NOT_FOUND", "note not found")
+	cause := errors.New("name is required")
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"typed error", notFound, http.StatusNotFound, "NOTE_NOT_FOUND"},
+		{"wrapped typed error", fmt.Errorf("note %s: %w", "1", notFound), http.StatusNotFound, "NOTE_NOT_FOUND"},
+		{"error without a code", New(ErrConflict, "", "conflict"), http.StatusConflict, CodeConflict},
+		{"kind only", fmt.Errorf("retry later: %w", ErrUnavailable), http.StatusServiceUnavailable, CodeUnavailable},
+		{"wrapped cause", Wrap(ErrValidation, "INVALID_WEBHOOK", cause), http.StatusBadRequest, "INVALID_WEBHOOK"},
+		{"untyped error", errors.New("failed to query notes: connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Status(tt.err); got != tt.status {
+				t.Errorf("Status() = %d, want %d", got, tt.status)
+			}
+			if got := Code(tt.err); got != tt.code {
+				t.Errorf("Code() = %s, want %s", got, tt.code)
+			}
+		})
+	}
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	cause := errors.New("name is required")
+	err := Wrap(ErrValidation, "INVALID_WEBHOOK", cause)
+
+	if !errors.Is(err, cause) || !errors.Is(err, ErrValidation) {
+		t.Error("expected the error to match its kind and its cause")
+	}
+	if err.Error() != "name is required" {
+		t.Errorf("Error() = %q, want the message of the cause", err.Error())
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:          CodeBadRequest,
+		http.StatusNotFound:            CodeNotFound,
+		http.StatusTooManyRequests:     CodeRateLimited,
+		http.StatusLocked:              CodeLocked,
+		http.StatusRequestTimeout:      CodeBadRequest,
+		http.StatusBadGateway:          CodeInternal,
+		http.StatusInternalServerError: CodeInternal,
+	}
+
+	for status, code := range tests {
+		if got := CodeForStatus(status); got != code {
+			t.Errorf("CodeForStatus(%d) = %s, want %s", status, got, code)
+		}
+	}
+}
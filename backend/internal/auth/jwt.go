@@ -10,6 +10,12 @@ import (
 	"github.com/google/uuid"
 )
 
+// Token types carried in the typ claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 // Claims represents the JWT claims structure
 type Claims struct {
 	UserID    string `json:"user_id"`
@@ -17,6 +23,10 @@ type Claims struct {
 	Email     string `json:"email"`
 	Issuer    string `json:"iss"`
 	Audience  string `json:"aud"`
+	TokenType string `json:"typ,omitempty"` // empty on tokens issued before token types
+	// TwoFactor is set on the tokens of sessions that signed in with a
+	// two-factor code, which accounts with two-factor authentication require
+	TwoFactor bool `json:"tfa,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -26,6 +36,9 @@ type TokenPair struct {
 	RefreshToken string `json:"refresh_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in"`
+	// RefreshTokenID is the ID of the refresh token, recorded on the session
+	// so that only the latest refresh token is accepted
+	RefreshTokenID string `json:"-"`
 }
 
 // BlacklistChecker checks if a token has been revoked
@@ -60,7 +73,7 @@ func (s *TokenService) SetBlacklist(blacklist BlacklistChecker) {
 }
 
 // generateTokenPair generates a new access token and refresh token pair with a specific session ID
-func (s *TokenService) generateTokenPair(user *models.User, sessionID string) (*TokenPair, error) {
+func (s *TokenService) generateTokenPair(user *models.User, sessionID string, twoFactor bool) (*TokenPair, error) {
 	now := time.Now()
 	tokenID := generateTokenID()
 
@@ -71,6 +84,8 @@ func (s *TokenService) generateTokenPair(user *models.User, sessionID string) (*
 		Email:     user.Email,
 		Issuer:    s.issuer,
 		Audience:  s.audience,
+		TokenType: TokenTypeAccess,
+		TwoFactor: twoFactor,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -94,6 +109,8 @@ func (s *TokenService) generateTokenPair(user *models.User, sessionID string) (*
 		Email:     user.Email,
 		Issuer:    s.issuer,
 		Audience:  s.audience,
+		TokenType: TokenTypeRefresh,
+		TwoFactor: twoFactor,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.refreshExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -110,21 +127,35 @@ func (s *TokenService) generateTokenPair(user *models.User, sessionID string) (*
 	}
 
 	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
-		TokenType:    "Bearer",
-		ExpiresIn:    int(s.accessExpiry.Seconds()),
+		AccessToken:    accessTokenString,
+		RefreshToken:   refreshTokenString,
+		TokenType:      "Bearer",
+		ExpiresIn:      int(s.accessExpiry.Seconds()),
+		RefreshTokenID: refreshTokenID,
 	}, nil
 }
 
 // GenerateTokenPair generates a new access token and refresh token pair
 func (s *TokenService) GenerateTokenPair(user *models.User) (*TokenPair, error) {
-	return s.generateTokenPair(user, generateTokenID())
+	return s.generateTokenPair(user, generateTokenID(), false)
 }
 
 // GenerateTokenPairWithSession generates a new access token and refresh token pair with a specific session ID
 func (s *TokenService) GenerateTokenPairWithSession(user *models.User, sessionID string) (*TokenPair, error) {
-	return s.generateTokenPair(user, sessionID)
+	return s.generateTokenPair(user, sessionID, false)
+}
+
+// GenerateTwoFactorTokenPair generates a token pair for a session that signed
+// in with a two-factor code
+func (s *TokenService) GenerateTwoFactorTokenPair(user *models.User, sessionID string) (*TokenPair, error) {
+	return s.generateTokenPair(user, sessionID, true)
+}
+
+// TwoFactorSatisfied reports whether tokens with claims may act for user.
+// Once an account enables two-factor authentication, tokens of sessions that
+// signed in without a code are refused.
+func TwoFactorSatisfied(claims *Claims, user *models.User) bool {
+	return !user.TwoFactorEnabled || claims.TwoFactor
 }
 
 // ValidateToken validates a JWT token and returns the claims
@@ -171,9 +202,11 @@ func (s *TokenService) ValidateRefreshToken(ctx context.Context, tokenString str
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
-	// Additional check for refresh token usage
-	// In a real implementation, you might want to check if this token ID
-	// has been marked as a refresh token in your database
+	// Access tokens cannot be exchanged for new tokens. Whether this is the
+	// session's current refresh token is checked when it is rotated.
+	if claims.TokenType == TokenTypeAccess {
+		return nil, fmt.Errorf("invalid refresh token: not a refresh token")
+	}
 	return claims, nil
 }
 
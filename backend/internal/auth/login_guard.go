@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// LoginGuardConfig configures the backoff applied to failed authentication
+type LoginGuardConfig struct {
+	// FreeAttempts is the number of failures allowed before backing off
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure past FreeAttempts. It
+	// doubles with each further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAttempts is the number of failures locking the key out for
+	// LockoutDuration, 0 disables lockouts
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	// Window is how long a failure counts; a key without failures for that
+	// long starts over
+	Window time.Duration
+}
+
+// DefaultLoginGuardConfig returns the backoff used when none is configured
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		FreeAttempts:    5,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: 20,
+		LockoutDuration: 15 * time.Minute,
+		Window:          15 * time.Minute,
+	}
+}
+
+// loginAttempts tracks the recent failures of one key
+type loginAttempts struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// LoginGuard slows down guessing of credentials. Failed attempts are counted
+// per key, an IP address or a user (see IPKey and UserKey): past the free
+// attempts each failure blocks the key for an exponentially growing delay,
+// and too many failures lock it out. Counts are kept in memory, so each
+// server instance guards on its own.
+type LoginGuard struct {
+	config LoginGuardConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+// NewLoginGuard creates a new LoginGuard
+func NewLoginGuard(config LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		config:   config,
+		now:      time.Now,
+		attempts: make(map[string]*loginAttempts),
+	}
+}
+
+// IPKey returns the guard key of a client IP address
+func IPKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// UserKey returns the guard key of a user
+func UserKey(userID string) string {
+	return "user:" + userID
+}
+
+// Wait returns how long the caller must wait before attempting to
+// authenticate as any of keys, 0 when an attempt is allowed
+func (g *LoginGuard) Wait(keys ...string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var wait time.Duration
+	for _, key := range keys {
+		if a, ok := g.attempts[key]; ok {
+			if remaining := a.blockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+	}
+	return wait
+}
+
+// Fail records a failed attempt for each of keys and returns how long the
+// caller must now wait
+func (g *LoginGuard) Fail(keys ...string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var wait time.Duration
+	for _, key := range keys {
+		a, ok := g.attempts[key]
+		if !ok || now.Sub(a.lastFailure) > g.config.Window {
+			a = &loginAttempts{}
+			g.attempts[key] = a
+		}
+		a.failures++
+		a.lastFailure = now
+
+		if delay := g.delay(a.failures); delay > 0 {
+			if until := now.Add(delay); until.After(a.blockedUntil) {
+				a.blockedUntil = until
+			}
+			if g.config.LockoutAttempts > 0 && a.failures == g.config.LockoutAttempts {
+				log.Printf("WARNING: %s locked out for %v after %d failed authentication attempts", key, delay, a.failures)
+			}
+		}
+		if remaining := a.blockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// delay returns the wait imposed after the given number of failures
+func (g *LoginGuard) delay(failures int) time.Duration {
+	if g.config.LockoutAttempts > 0 && failures >= g.config.LockoutAttempts {
+		return g.config.LockoutDuration
+	}
+	excess := failures - g.config.FreeAttempts
+	if excess <= 0 || g.config.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := g.config.BaseDelay
+	for i := 1; i < excess && delay < g.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if g.config.MaxDelay > 0 && delay > g.config.MaxDelay {
+		delay = g.config.MaxDelay
+	}
+	return delay
+}
+
+// Succeed forgets the failures of keys after a successful attempt
+func (g *LoginGuard) Succeed(keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range keys {
+		delete(g.attempts, key)
+	}
+}
+
+// Cleanup forgets the keys that are no longer blocked and whose failures
+// are past the window, and returns the number of keys forgotten
+func (g *LoginGuard) Cleanup() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	removed := 0
+	for key, a := range g.attempts {
+		if now.After(a.blockedUntil) && now.Sub(a.lastFailure) > g.config.Window {
+			delete(g.attempts, key)
+			removed++
+		}
+	}
+	return removed
+}
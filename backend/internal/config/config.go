@@ -17,6 +17,25 @@ type Config struct {
 	App      AppConfig      `yaml:"app" env-prefix:"APP_"`
 	CORS     CORSConfig     `yaml:"cors" env-prefix:"CORS_"`
 	LLM      LLMConfig      `yaml:"llm" env-prefix:"LLM_"`
+	Encryption EncryptionConfig `yaml:"encryption" env-prefix:"ENCRYPTION_"`
+	Confirmation ConfirmationConfig `yaml:"confirmation" env-prefix:"CONFIRMATION_"`
+	Anomaly  AnomalyConfig  `yaml:"anomaly" env-prefix:"ANOMALY_"`
+	Admin    AdminConfig    `yaml:"admin" env-prefix:"ADMIN_"`
+	Migration MigrationConfig `yaml:"migration" env-prefix:"MIGRATION_"`
+	Import    ImportConfig    `yaml:"import" env-prefix:"IMPORT_"`
+	Tracing   TracingConfig   `yaml:"tracing" env-prefix:"TRACING_"`
+	Email     EmailConfig     `yaml:"email" env-prefix:"EMAIL_"`
+	Digest    DigestConfig    `yaml:"digest" env-prefix:"DIGEST_"`
+	Webhook   WebhookConfig   `yaml:"webhook" env-prefix:"WEBHOOK_"`
+	Audit     AuditConfig     `yaml:"audit" env-prefix:"AUDIT_"`
+	Account   AccountConfig   `yaml:"account" env-prefix:"ACCOUNT_"`
+	Inbound   InboundConfig   `yaml:"inbound" env-prefix:"INBOUND_"`
+	WebClip   WebClipConfig   `yaml:"web_clip" env-prefix:"WEB_CLIP_"`
+	OCR       OCRConfig       `yaml:"ocr" env-prefix:"OCR_"`
+	Transcription TranscriptionConfig `yaml:"transcription" env-prefix:"TRANSCRIPTION_"`
+	Notes     NotesConfig     `yaml:"notes" env-prefix:"NOTES_"`
+	Security  HTTPSecurityConfig `yaml:"security" env-prefix:"SECURITY_"`
+	Login     LoginConfig     `yaml:"login" env-prefix:"LOGIN_"`
 }
 
 // ServerConfig represents server configuration
@@ -26,18 +45,31 @@ type ServerConfig struct {
 	ReadTimeout  int    `yaml:"read_timeout" env:"READ_TIMEOUT" envDefault:"30"`
 	WriteTimeout int    `yaml:"write_timeout" env:"WRITE_TIMEOUT" envDefault:"30"`
 	IdleTimeout  int    `yaml:"idle_timeout" env:"IDLE_TIMEOUT" envDefault:"60"`
+	GRPCPort     string `yaml:"grpc_port" env:"GRPC_PORT" envDefault:"9090"` // port of the gRPC API, 0 disables
 }
 
 // DatabaseConfig represents database configuration
 type DatabaseConfig struct {
+	// Driver is "postgres" or "sqlite". SQLite needs no server and serves
+	// local development and tests; features relying on PostgreSQL are
+	// unavailable with it.
+	Driver   string `yaml:"driver" env:"DRIVER" envDefault:"postgres"`
 	Host     string `yaml:"host" env:"HOST" envDefault:"localhost"`
 	Port     int    `yaml:"port" env:"PORT" envDefault:"5432"`
 	Name     string `yaml:"name" env:"NAME" envDefault:"notes_dev"`
 	User     string `yaml:"user" env:"USER" envDefault:"postgres"`
 	Password string `yaml:"password" env:"PASSWORD" envRequired:"true"`
 	SSLMode  string `yaml:"ssl_mode" env:"SSLMODE" envDefault:"disable"`
+	// Path is the database file of the SQLite driver
+	Path string `yaml:"path" env:"PATH" envDefault:"notes_dev.db"`
 }
 
+// Database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
 	JWTSecret        string `yaml:"jwt_secret" env:"JWT_SECRET" envRequired:"true"`
@@ -56,11 +88,12 @@ type AppConfig struct {
 	Version     string `yaml:"version" env:"VERSION" envDefault:"1.0.0"`
 }
 
-// CORSConfig represents CORS configuration
+// CORSConfig represents CORS configuration. Fields left empty keep the
+// defaults of the environment, see SecurityConfigFor.
 type CORSConfig struct {
-	AllowedOrigins   []string `yaml:"allowed_origins" env:"ALLOWED_ORIGINS" envDefault:"*"`
-	AllowedMethods   []string `yaml:"allowed_methods" env:"ALLOWED_METHODS" envDefault:"GET,POST,PUT,DELETE,OPTIONS"`
-	AllowedHeaders   []string `yaml:"allowed_headers" env:"ALLOWED_HEADERS" envDefault:"*"`
+	AllowedOrigins   []string `yaml:"allowed_origins" env:"ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" env:"ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" env:"ALLOWED_HEADERS"`
 	ExposedHeaders   []string `yaml:"exposed_headers" env:"EXPOSED_HEADERS" envDefault:""`
 	AllowCredentials bool     `yaml:"allow_credentials" env:"ALLOW_CREDENTIALS" envDefault:"false"`
 	MaxAge           int      `yaml:"max_age" env:"MAX_AGE" envDefault:"86400"`
@@ -68,14 +101,180 @@ type CORSConfig struct {
 
 // LLMConfig represents LLM service configuration
 type LLMConfig struct {
-	Type                   string `yaml:"type" env:"TYPE" envDefault:"DEEPSEEK_TENCENT"`
-	RequestTimeout         int    `yaml:"request_timeout" env:"REQUEST_TIMEOUT" envDefault:"30"`
-	DeepseekTencentModel   string `yaml:"deepseek_tencent_model" env:"DEEPSEEK_TENCENT_MODEL" envDefault:"deepseek-v3"`
-	DeepseekTencentAPIKey  string `yaml:"deepseek_tencent_api_key" env:"DEEPSEEK_TENCENT_API_KEY"`
-	DeepseekTencentBaseURL string `yaml:"deepseek_tencent_base_url" env:"DEEPSEEK_TENCENT_BASE_URL" envDefault:"https://api.lkeap.tencentcloud.com/v1"`
-	MaxSearchTokenLength   int    `yaml:"max_search_token_length" env:"MAX_SEARCH_TOKEN_LENGTH" envDefault:"100000"`
+	Type                   string   `yaml:"type" env:"TYPE" envDefault:"DEEPSEEK_TENCENT"`
+	RequestTimeout         int      `yaml:"request_timeout" env:"REQUEST_TIMEOUT" envDefault:"30"`
+	DeepseekTencentModel   string   `yaml:"deepseek_tencent_model" env:"DEEPSEEK_TENCENT_MODEL" envDefault:"deepseek-v3"`
+	DeepseekTencentAPIKey  string   `yaml:"deepseek_tencent_api_key" env:"DEEPSEEK_TENCENT_API_KEY"`
+	DeepseekTencentBaseURL string   `yaml:"deepseek_tencent_base_url" env:"DEEPSEEK_TENCENT_BASE_URL" envDefault:"https://api.lkeap.tencentcloud.com/v1"`
+	DeepseekTencentTimeout int      `yaml:"deepseek_tencent_timeout" env:"DEEPSEEK_TENCENT_TIMEOUT"` // seconds, 0 uses RequestTimeout
+	DeepseekTencentCost    float64  `yaml:"deepseek_tencent_cost" env:"DEEPSEEK_TENCENT_COST"`       // USD per 1K tokens
+	OpenAIModel            string   `yaml:"openai_model" env:"OPENAI_MODEL" envDefault:"gpt-4o-mini"`
+	OpenAIAPIKey           string   `yaml:"openai_api_key" env:"OPENAI_API_KEY"`
+	OpenAIBaseURL          string   `yaml:"openai_base_url" env:"OPENAI_BASE_URL" envDefault:"https://api.openai.com/v1"`
+	OpenAITimeout          int      `yaml:"openai_timeout" env:"OPENAI_TIMEOUT"`
+	OpenAICost             float64  `yaml:"openai_cost" env:"OPENAI_COST"`
+	AnthropicModel         string   `yaml:"anthropic_model" env:"ANTHROPIC_MODEL" envDefault:"claude-3-5-haiku-latest"`
+	AnthropicAPIKey        string   `yaml:"anthropic_api_key" env:"ANTHROPIC_API_KEY"`
+	AnthropicBaseURL       string   `yaml:"anthropic_base_url" env:"ANTHROPIC_BASE_URL" envDefault:"https://api.anthropic.com/v1"`
+	AnthropicTimeout       int      `yaml:"anthropic_timeout" env:"ANTHROPIC_TIMEOUT"`
+	AnthropicCost          float64  `yaml:"anthropic_cost" env:"ANTHROPIC_COST"`
+	OllamaModel            string   `yaml:"ollama_model" env:"OLLAMA_MODEL" envDefault:"llama3.1"`
+	OllamaBaseURL          string   `yaml:"ollama_base_url" env:"OLLAMA_BASE_URL" envDefault:"http://localhost:11434"`
+	OllamaTimeout          int      `yaml:"ollama_timeout" env:"OLLAMA_TIMEOUT"`
+	OllamaCost             float64  `yaml:"ollama_cost" env:"OLLAMA_COST"`
+	Providers              []string `yaml:"providers" env:"PROVIDERS"`                                         // fallback chain in order of preference, defaults to Type
+	HealthCheckInterval    int      `yaml:"health_check_interval" env:"HEALTH_CHECK_INTERVAL" envDefault:"60"` // seconds between provider probes, 0 disables
+	MaxSearchTokenLength   int      `yaml:"max_search_token_length" env:"MAX_SEARCH_TOKEN_LENGTH" envDefault:"100000"`
+	QAContextTokens        int      `yaml:"qa_context_tokens" env:"QA_CONTEXT_TOKENS" envDefault:"6000"`         // note tokens given to the LLM per question
+	PrettifyChunkTokens    int      `yaml:"prettify_chunk_tokens" env:"PRETTIFY_CHUNK_TOKENS" envDefault:"2000"` // longer notes are prettified in chunks, 0 disables
+	MonthlyTokenBudget     int      `yaml:"monthly_token_budget" env:"MONTHLY_TOKEN_BUDGET"`                     // tokens per user per calendar month, 0 is unlimited
+	CacheTTL               int      `yaml:"cache_ttl" env:"CACHE_TTL" envDefault:"168"`                          // hours prettify and digest responses are cached, 0 disables
+	PromptsDir             string   `yaml:"prompts_dir" env:"PROMPTS_DIR"`                                       // prompt template overrides, see internal/llm/prompts
+	GenerateTitles         bool     `yaml:"generate_titles" env:"GENERATE_TITLES" envDefault:"false"`            // LLM titles for notes created without one
+	TitleTimeout           int      `yaml:"title_timeout" env:"TITLE_TIMEOUT" envDefault:"5"`                    // seconds before falling back to the first line
+}
+
+// EncryptionConfig represents encryption-at-rest configuration for private notes
+type EncryptionConfig struct {
+	MasterKey string `yaml:"master_key" env:"MASTER_KEY"` // base64-encoded 32-byte key
+}
+
+// ConfirmationConfig represents confirmation requirements for destructive operations
+type ConfirmationConfig struct {
+	BulkThreshold int `yaml:"bulk_threshold" env:"BULK_THRESHOLD" envDefault:"20"` // notes affected before a code is required
+	CodeTTL       int `yaml:"code_ttl" env:"CODE_TTL" envDefault:"10"`             // minutes
+}
+
+// AnomalyConfig represents account activity anomaly detection configuration
+type AnomalyConfig struct {
+	Interval          int    `yaml:"interval" env:"INTERVAL" envDefault:"5"`                        // minutes between analyzer runs, 0 disables
+	MassDeletionCount int    `yaml:"mass_deletion_count" env:"MASS_DELETION_COUNT" envDefault:"50"` // notes deleted within an hour
+	APIKeyBurstCount  int    `yaml:"api_key_burst_count" env:"API_KEY_BURST_COUNT" envDefault:"3"`  // API keys created within an hour
+	AutoLock          bool   `yaml:"auto_lock" env:"AUTO_LOCK" envDefault:"false"`                  // lock accounts read-only on high severity findings
+	LockDuration      int    `yaml:"lock_duration" env:"LOCK_DURATION" envDefault:"24"`             // hours
+	CountryHeader     string `yaml:"country_header" env:"COUNTRY_HEADER" envDefault:"CF-IPCountry"` // request header carrying the client country
+}
+
+// AdminConfig represents administrator configuration
+type AdminConfig struct {
+	Emails []string `yaml:"emails" env:"EMAILS"` // administrators besides users with the admin role
+}
+
+// MigrationConfig represents server-to-server data migration configuration
+type MigrationConfig struct {
+	TransferTTL   int  `yaml:"transfer_ttl" env:"TRANSFER_TTL" envDefault:"72"`          // hours a transfer accepts chunks
+	AllowInsecure bool `yaml:"allow_insecure" env:"ALLOW_INSECURE" envDefault:"false"` // allow http and internal destinations
+}
+
+// ImportConfig represents import upload limits
+type ImportConfig struct {
+	MaxUploadSize   int `yaml:"max_upload_size" env:"MAX_UPLOAD_SIZE" envDefault:"1"`       // MB, spreadsheet uploads
+	MaxArchiveSize  int `yaml:"max_archive_size" env:"MAX_ARCHIVE_SIZE" envDefault:"50"`    // MB, streamed JSON archives
+	MaxArchiveNotes int `yaml:"max_archive_notes" env:"MAX_ARCHIVE_NOTES" envDefault:"10000"` // notes per archive
+}
+
+// TracingConfig represents OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"EXPORTER" envDefault:"none"`                       // none, stdout or otlp
+	Endpoint    string  `yaml:"endpoint" env:"ENDPOINT" envDefault:"localhost:4318"`             // OTLP/HTTP collector host:port
+	Insecure    bool    `yaml:"insecure" env:"INSECURE" envDefault:"false"`                      // send OTLP over plain HTTP
+	ServiceName string  `yaml:"service_name" env:"SERVICE_NAME" envDefault:"silence-notes-api"` // service.name resource attribute
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO" envDefault:"1"`                  // fraction of new traces recorded
+}
+
+// EmailConfig represents the SMTP server emails are sent through
+type EmailConfig struct {
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`                   // emails are logged when empty
+	SMTPPort     int    `yaml:"smtp_port" env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	From         string `yaml:"from" env:"FROM" envDefault:"Silence Notes <notes@localhost>"`
+}
+
+// DigestConfig represents the scheduling of digest emails
+type DigestConfig struct {
+	Interval  int  `yaml:"interval" env:"INTERVAL" envDefault:"15"`     // minutes between checks for due digests, 0 disables
+	SendHour  int  `yaml:"send_hour" env:"SEND_HOUR" envDefault:"7"`    // hour of the day (UTC) digests are sent from
+	Summarize bool `yaml:"summarize" env:"SUMMARIZE" envDefault:"true"` // add an LLM summary when an LLM is configured
+}
+
+// WebhookConfig represents the delivery of outbound webhooks
+type WebhookConfig struct {
+	Interval      int  `yaml:"interval" env:"INTERVAL" envDefault:"15"`                  // seconds between delivery runs, 0 disables
+	AllowInsecure bool `yaml:"allow_insecure" env:"ALLOW_INSECURE" envDefault:"false"` // allow http and internal webhook URLs
+}
+
+// AuditConfig represents the audit log of note actions
+type AuditConfig struct {
+	RetentionDays int `yaml:"retention_days" env:"RETENTION_DAYS" envDefault:"365"` // days entries are kept, 0 keeps them forever
+}
+
+// AccountConfig represents the deletion of user accounts
+type AccountConfig struct {
+	DeletionGraceDays int `yaml:"deletion_grace_days" env:"DELETION_GRACE_DAYS" envDefault:"30"` // days deleted accounts can be restored, 0 deletes at once
+}
+
+// InboundConfig represents email-to-note ingestion through Mailgun routes
+type InboundConfig struct {
+	Domain            string `yaml:"domain" env:"DOMAIN"`                                   // domain of the inbound addresses, empty disables
+	MailgunSigningKey string `yaml:"mailgun_signing_key" env:"MAILGUN_SIGNING_KEY"`         // key verifying Mailgun webhooks
+	MaxMessageSize    int    `yaml:"max_message_size" env:"MAX_MESSAGE_SIZE" envDefault:"25"` // MB, including attachments
+}
+
+// WebClipConfig represents the fetching of web pages clipped by browser extensions
+type WebClipConfig struct {
+	MaxPageSize   int  `yaml:"max_page_size" env:"MAX_PAGE_SIZE" envDefault:"5"`          // MB of HTML fetched per page
+	Summarize     bool `yaml:"summarize" env:"SUMMARIZE" envDefault:"true"`               // allow LLM summaries when an LLM is configured
+	AllowInsecure bool `yaml:"allow_insecure" env:"ALLOW_INSECURE" envDefault:"false"` // allow pages on internal addresses
+}
+
+// OCRConfig represents the recognition of text in images uploaded to notes
+type OCRConfig struct {
+	Engine        string `yaml:"engine" env:"ENGINE"`                                        // "tesseract", empty disables
+	TesseractPath string `yaml:"tesseract_path" env:"TESSERACT_PATH" envDefault:"tesseract"` // tesseract binary
+	Languages     string `yaml:"languages" env:"LANGUAGES" envDefault:"eng"`                 // tesseract languages, such as eng+deu
+	MaxImageSize  int    `yaml:"max_image_size" env:"MAX_IMAGE_SIZE" envDefault:"10"`        // MB per uploaded image
 }
 
+// TranscriptionConfig represents the speech-to-text backend voice memos are sent to
+type TranscriptionConfig struct {
+	Provider     string `yaml:"provider" env:"PROVIDER"`                                         // "openai" for OpenAI-compatible APIs, empty disables
+	BaseURL      string `yaml:"base_url" env:"BASE_URL" envDefault:"https://api.openai.com/v1"` // API base URL
+	APIKey       string `yaml:"api_key" env:"API_KEY"`                                           // API key, optional for local servers
+	Model        string `yaml:"model" env:"MODEL" envDefault:"whisper-1"`                        // speech-to-text model
+	Timeout      int    `yaml:"timeout" env:"TIMEOUT" envDefault:"120"`                          // seconds per memo
+	MaxAudioSize int    `yaml:"max_audio_size" env:"MAX_AUDIO_SIZE" envDefault:"25"`             // MB per uploaded memo
+}
+
+// NotesConfig represents the limits of notes
+type NotesConfig struct {
+	MaxContentLength int `yaml:"max_content_length" env:"MAX_CONTENT_LENGTH" envDefault:"10000"` // characters of content per note, at most 1048576, 0 for the default
+}
+
+// HTTPSecurityConfig overrides the security headers and request body limit
+// of the environment. Empty values keep the environment's defaults.
+type HTTPSecurityConfig struct {
+	ContentSecurityPolicy   string `yaml:"content_security_policy" env:"CONTENT_SECURITY_POLICY"`
+	StrictTransportSecurity string `yaml:"strict_transport_security" env:"STRICT_TRANSPORT_SECURITY"` // "off" disables HSTS
+	MaxRequestSize          int64  `yaml:"max_request_size" env:"MAX_REQUEST_SIZE"`                   // bytes of request body, except on upload endpoints
+}
+
+// LoginConfig represents the protection of sign-in and API key
+// authentication against guessing
+type LoginConfig struct {
+	FreeAttempts    int  `yaml:"free_attempts" env:"FREE_ATTEMPTS" envDefault:"5"`            // failed attempts per IP address or user before backing off
+	BackoffBase     int  `yaml:"backoff_base" env:"BACKOFF_BASE" envDefault:"1"`              // seconds of the first backoff, doubling with each failure, 0 disables
+	BackoffMax      int  `yaml:"backoff_max" env:"BACKOFF_MAX" envDefault:"60"`               // seconds
+	LockoutAttempts int  `yaml:"lockout_attempts" env:"LOCKOUT_ATTEMPTS" envDefault:"20"`     // failed attempts locking out the IP address or user, 0 disables
+	LockoutDuration int  `yaml:"lockout_duration" env:"LOCKOUT_DURATION" envDefault:"15"`     // minutes
+	AttemptWindow   int  `yaml:"attempt_window" env:"ATTEMPT_WINDOW" envDefault:"15"`         // minutes without failures after which they are forgotten
+	NewDeviceAlerts bool `yaml:"new_device_alerts" env:"NEW_DEVICE_ALERTS" envDefault:"true"` // notify users of sign-ins from new devices and locations
+}
+
+// MaxNoteContentLength is the highest note content limit that can be
+// configured, in characters
+const MaxNoteContentLength = 1 << 20
+
 // LoadConfig loads configuration from environment variables and optional config file
 func LoadConfig(configPath string) (*Config, error) {
 	// Load .env file if it exists
@@ -103,14 +302,17 @@ func LoadConfig(configPath string) (*Config, error) {
 			ReadTimeout:  getEnvInt("SERVER_READ_TIMEOUT", 30),
 			WriteTimeout: getEnvInt("SERVER_WRITE_TIMEOUT", 30),
 			IdleTimeout:  getEnvInt("SERVER_IDLE_TIMEOUT", 60),
+			GRPCPort:     getEnv("SERVER_GRPC_PORT", "9090"),
 		},
 		Database: DatabaseConfig{
+			Driver:   strings.ToLower(getEnv("DB_DRIVER", DriverPostgres)),
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnvInt("DB_PORT", 5432),
 			Name:     getEnv("DB_NAME", "notes_dev"),
 			User:     getEnv("DB_USER", "postgres"),
 			Password: getEnv("DB_PASSWORD", ""),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Path:     getEnv("DB_PATH", "notes_dev.db"),
 		},
 		Auth: AuthConfig{
 			JWTSecret:         getEnv("JWT_SECRET", ""),
@@ -127,9 +329,9 @@ func LoadConfig(configPath string) (*Config, error) {
 			Version:     getEnv("APP_VERSION", "1.0.0"),
 		},
 		CORS: CORSConfig{
-			AllowedOrigins:   getEnvSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
-			AllowedMethods:   getEnvSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			AllowedHeaders:   getEnvSlice("CORS_ALLOWED_HEADERS", []string{"*"}),
+			AllowedOrigins:   getEnvSlice("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods:   getEnvSlice("CORS_ALLOWED_METHODS", nil),
+			AllowedHeaders:   getEnvSlice("CORS_ALLOWED_HEADERS", nil),
 			ExposedHeaders:   getEnvSlice("CORS_EXPOSED_HEADERS", []string{}),
 			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
 			MaxAge:           getEnvInt("CORS_MAX_AGE", 86400),
@@ -140,7 +342,129 @@ func LoadConfig(configPath string) (*Config, error) {
 			DeepseekTencentModel:   getEnv("LLM_DEEPSEEK_TENCENT_MODEL", "deepseek-v3"),
 			DeepseekTencentAPIKey:  getEnv("LLM_DEEPSEEK_TENCENT_API_KEY", ""),
 			DeepseekTencentBaseURL: getEnv("LLM_DEEPSEEK_TENCENT_BASE_URL", "https://api.lkeap.tencentcloud.com/v1"),
+			DeepseekTencentTimeout: getEnvInt("LLM_DEEPSEEK_TENCENT_TIMEOUT", 0),
+			DeepseekTencentCost:    getEnvFloat("LLM_DEEPSEEK_TENCENT_COST", 0),
+			OpenAIModel:            getEnv("LLM_OPENAI_MODEL", "gpt-4o-mini"),
+			OpenAIAPIKey:           getEnv("LLM_OPENAI_API_KEY", ""),
+			OpenAIBaseURL:          getEnv("LLM_OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			OpenAITimeout:          getEnvInt("LLM_OPENAI_TIMEOUT", 0),
+			OpenAICost:             getEnvFloat("LLM_OPENAI_COST", 0),
+			AnthropicModel:         getEnv("LLM_ANTHROPIC_MODEL", "claude-3-5-haiku-latest"),
+			AnthropicAPIKey:        getEnv("LLM_ANTHROPIC_API_KEY", ""),
+			AnthropicBaseURL:       getEnv("LLM_ANTHROPIC_BASE_URL", "https://api.anthropic.com/v1"),
+			AnthropicTimeout:       getEnvInt("LLM_ANTHROPIC_TIMEOUT", 0),
+			AnthropicCost:          getEnvFloat("LLM_ANTHROPIC_COST", 0),
+			OllamaModel:            getEnv("LLM_OLLAMA_MODEL", "llama3.1"),
+			OllamaBaseURL:          getEnv("LLM_OLLAMA_BASE_URL", "http://localhost:11434"),
+			OllamaTimeout:          getEnvInt("LLM_OLLAMA_TIMEOUT", 0),
+			OllamaCost:             getEnvFloat("LLM_OLLAMA_COST", 0),
+			Providers:              getEnvSlice("LLM_PROVIDERS", nil),
+			HealthCheckInterval:    getEnvInt("LLM_HEALTH_CHECK_INTERVAL", 60),
 			MaxSearchTokenLength:   getEnvInt("LLM_MAX_SEARCH_TOKEN_LENGTH", 100000),
+			QAContextTokens:        getEnvInt("LLM_QA_CONTEXT_TOKENS", 6000),
+			PrettifyChunkTokens:    getEnvInt("LLM_PRETTIFY_CHUNK_TOKENS", 2000),
+			MonthlyTokenBudget:     getEnvInt("LLM_MONTHLY_TOKEN_BUDGET", 0),
+			CacheTTL:               getEnvInt("LLM_CACHE_TTL", 168),
+			PromptsDir:             getEnv("LLM_PROMPTS_DIR", ""),
+			GenerateTitles:         getEnvBool("LLM_GENERATE_TITLES", false),
+			TitleTimeout:           getEnvInt("LLM_TITLE_TIMEOUT", 5),
+		},
+		Encryption: EncryptionConfig{
+			MasterKey: getEnv("ENCRYPTION_MASTER_KEY", ""),
+		},
+		Confirmation: ConfirmationConfig{
+			BulkThreshold: getEnvInt("CONFIRMATION_BULK_THRESHOLD", 20),
+			CodeTTL:       getEnvInt("CONFIRMATION_CODE_TTL", 10),
+		},
+		Anomaly: AnomalyConfig{
+			Interval:          getEnvInt("ANOMALY_INTERVAL", 5),
+			MassDeletionCount: getEnvInt("ANOMALY_MASS_DELETION_COUNT", 50),
+			APIKeyBurstCount:  getEnvInt("ANOMALY_API_KEY_BURST_COUNT", 3),
+			AutoLock:          getEnvBool("ANOMALY_AUTO_LOCK", false),
+			LockDuration:      getEnvInt("ANOMALY_LOCK_DURATION", 24),
+			CountryHeader:     getEnv("ANOMALY_COUNTRY_HEADER", "CF-IPCountry"),
+		},
+		Admin: AdminConfig{
+			Emails: getEnvSlice("ADMIN_EMAILS", []string{}),
+		},
+		Migration: MigrationConfig{
+			TransferTTL:   getEnvInt("MIGRATION_TRANSFER_TTL", 72),
+			AllowInsecure: getEnvBool("MIGRATION_ALLOW_INSECURE", false),
+		},
+		Import: ImportConfig{
+			MaxUploadSize:   getEnvInt("IMPORT_MAX_UPLOAD_SIZE", 1),
+			MaxArchiveSize:  getEnvInt("IMPORT_MAX_ARCHIVE_SIZE", 50),
+			MaxArchiveNotes: getEnvInt("IMPORT_MAX_ARCHIVE_NOTES", 10000),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			Endpoint:    getEnv("TRACING_ENDPOINT", "localhost:4318"),
+			Insecure:    getEnvBool("TRACING_INSECURE", false),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "silence-notes-api"),
+			SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		Email: EmailConfig{
+			SMTPHost:     getEnv("EMAIL_SMTP_HOST", ""),
+			SMTPPort:     getEnvInt("EMAIL_SMTP_PORT", 587),
+			SMTPUsername: getEnv("EMAIL_SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("EMAIL_SMTP_PASSWORD", ""),
+			From:         getEnv("EMAIL_FROM", "Silence Notes <notes@localhost>"),
+		},
+		Digest: DigestConfig{
+			Interval:  getEnvInt("DIGEST_INTERVAL", 15),
+			SendHour:  getEnvInt("DIGEST_SEND_HOUR", 7),
+			Summarize: getEnvBool("DIGEST_SUMMARIZE", true),
+		},
+		Webhook: WebhookConfig{
+			Interval:      getEnvInt("WEBHOOK_INTERVAL", 15),
+			AllowInsecure: getEnvBool("WEBHOOK_ALLOW_INSECURE", false),
+		},
+		Audit: AuditConfig{
+			RetentionDays: getEnvInt("AUDIT_RETENTION_DAYS", 365),
+		},
+		Account: AccountConfig{
+			DeletionGraceDays: getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 30),
+		},
+		Inbound: InboundConfig{
+			Domain:            strings.ToLower(getEnv("INBOUND_DOMAIN", "")),
+			MailgunSigningKey: getEnv("INBOUND_MAILGUN_SIGNING_KEY", ""),
+			MaxMessageSize:    getEnvInt("INBOUND_MAX_MESSAGE_SIZE", 25),
+		},
+		WebClip: WebClipConfig{
+			MaxPageSize:   getEnvInt("WEB_CLIP_MAX_PAGE_SIZE", 5),
+			Summarize:     getEnvBool("WEB_CLIP_SUMMARIZE", true),
+			AllowInsecure: getEnvBool("WEB_CLIP_ALLOW_INSECURE", false),
+		},
+		OCR: OCRConfig{
+			Engine:        strings.ToLower(getEnv("OCR_ENGINE", "")),
+			TesseractPath: getEnv("OCR_TESSERACT_PATH", "tesseract"),
+			Languages:     getEnv("OCR_LANGUAGES", "eng"),
+			MaxImageSize:  getEnvInt("OCR_MAX_IMAGE_SIZE", 10),
+		},
+		Transcription: TranscriptionConfig{
+			Provider:     strings.ToLower(getEnv("TRANSCRIPTION_PROVIDER", "")),
+			BaseURL:      getEnv("TRANSCRIPTION_BASE_URL", "https://api.openai.com/v1"),
+			APIKey:       getEnv("TRANSCRIPTION_API_KEY", ""),
+			Model:        getEnv("TRANSCRIPTION_MODEL", "whisper-1"),
+			Timeout:      getEnvInt("TRANSCRIPTION_TIMEOUT", 120),
+			MaxAudioSize: getEnvInt("TRANSCRIPTION_MAX_AUDIO_SIZE", 25),
+		},
+		Notes: NotesConfig{
+			MaxContentLength: getEnvInt("NOTES_MAX_CONTENT_LENGTH", 10000),
+		},
+		Security: HTTPSecurityConfig{
+			ContentSecurityPolicy:   getEnv("SECURITY_CONTENT_SECURITY_POLICY", ""),
+			StrictTransportSecurity: getEnv("SECURITY_STRICT_TRANSPORT_SECURITY", ""),
+			MaxRequestSize:          int64(getEnvInt("SECURITY_MAX_REQUEST_SIZE", 0)),
+		},
+		Login: LoginConfig{
+			FreeAttempts:    getEnvInt("LOGIN_FREE_ATTEMPTS", 5),
+			BackoffBase:     getEnvInt("LOGIN_BACKOFF_BASE", 1),
+			BackoffMax:      getEnvInt("LOGIN_BACKOFF_MAX", 60),
+			LockoutAttempts: getEnvInt("LOGIN_LOCKOUT_ATTEMPTS", 20),
+			LockoutDuration: getEnvInt("LOGIN_LOCKOUT_DURATION", 15),
+			AttemptWindow:   getEnvInt("LOGIN_ATTEMPT_WINDOW", 15),
+			NewDeviceAlerts: getEnvBool("LOGIN_NEW_DEVICE_ALERTS", true),
 		},
 	}
 
@@ -153,16 +477,28 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return fmt.Errorf("server port is required")
 	}
+	if c.Server.GRPCEnabled() && c.Server.GRPCPort == c.Server.Port {
+		return fmt.Errorf("gRPC port must differ from the server port")
+	}
 
 	// Validate database config
-	if c.Database.Host == "" {
-		return fmt.Errorf("database host is required")
-	}
-	if c.Database.Password == "" {
-		return fmt.Errorf("database password is required")
-	}
-	if c.Database.Name == "" {
-		return fmt.Errorf("database name is required")
+	switch c.Database.Driver {
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			return fmt.Errorf("database path is required")
+		}
+	case DriverPostgres, "":
+		if c.Database.Host == "" {
+			return fmt.Errorf("database host is required")
+		}
+		if c.Database.Password == "" {
+			return fmt.Errorf("database password is required")
+		}
+		if c.Database.Name == "" {
+			return fmt.Errorf("database name is required")
+		}
+	default:
+		return fmt.Errorf("invalid database driver: %s", c.Database.Driver)
 	}
 
 	// Validate auth config
@@ -179,6 +515,44 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid environment: %s", c.App.Environment)
 	}
 
+	// Validate tracing config
+	validExporters := []string{"none", "stdout", "otlp"}
+	if c.Tracing.Exporter != "" && !contains(validExporters, c.Tracing.Exporter) {
+		return fmt.Errorf("invalid tracing exporter: %s", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+
+	// Validate digest config
+	if c.Digest.SendHour < 0 || c.Digest.SendHour > 23 {
+		return fmt.Errorf("digest send hour must be between 0 and 23")
+	}
+
+	// Validate audit config
+	if c.Audit.RetentionDays < 0 {
+		return fmt.Errorf("audit retention days cannot be negative")
+	}
+
+	// Validate account config
+	if c.Account.DeletionGraceDays < 0 {
+		return fmt.Errorf("account deletion grace days cannot be negative")
+	}
+
+	// Validate CORS config: browsers refuse credentials with a wildcard
+	// origin, and echoing any origin with credentials would let every site in
+	if c.CORS.AllowCredentials && contains(c.CORS.AllowedOrigins, "*") {
+		return fmt.Errorf("CORS cannot allow credentials for any origin (*)")
+	}
+	if c.Security.MaxRequestSize < 0 {
+		return fmt.Errorf("max request size cannot be negative")
+	}
+
+	// Validate notes config
+	if c.Notes.MaxContentLength < 0 || c.Notes.MaxContentLength > MaxNoteContentLength {
+		return fmt.Errorf("note content length limit must be between 0 (default) and %d characters", MaxNoteContentLength)
+	}
+
 	return nil
 }
 
@@ -208,6 +582,16 @@ func (c *ServerConfig) Address() string {
 	return fmt.Sprintf("%s:%s", c.Host, c.Port)
 }
 
+// GRPCEnabled reports whether the gRPC API is served
+func (c *ServerConfig) GRPCEnabled() bool {
+	return c.GRPCPort != "" && c.GRPCPort != "0"
+}
+
+// GRPCAddress returns the address of the gRPC API
+func (c *ServerConfig) GRPCAddress() string {
+	return fmt.Sprintf("%s:%s", c.Host, c.GRPCPort)
+}
+
 // Helper functions
 
 func getEnv(key, defaultValue string) string {
@@ -235,6 +619,15 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
@@ -256,9 +649,16 @@ func UseLLMDuringTest() bool {
 	return getEnvBool("USE_LLM_DURING_TEST", false)
 }
 
-// GetTestDatabaseConfig returns database config for testing (uses TEST_DB_* vars)
+// GetTestDatabaseConfig returns database config for testing (uses TEST_DB_* vars).
+// Tests use SQLite unless PostgreSQL tests are enabled with
+// USE_POSTGRE_DURING_TEST, so they run without a database server.
 func GetTestDatabaseConfig() DatabaseConfig {
+	driver := DriverSQLite
+	if getEnvBool("USE_POSTGRE_DURING_TEST", false) {
+		driver = DriverPostgres
+	}
 	return DatabaseConfig{
+		Driver:   strings.ToLower(getEnv("TEST_DB_DRIVER", driver)),
 		Host:     getEnv("TEST_DB_HOST", getEnv("DB_HOST", "localhost")),
 		Port:     getEnvInt("TEST_DB_PORT", getEnvInt("DB_PORT", 5432)),
 		Name:     getEnv("TEST_DB_NAME", getEnv("DB_NAME", "notes_test")),
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -37,6 +38,10 @@ func TestLLMConfigFromEnv(t *testing.T) {
 	os.Setenv("LLM_DEEPSEEK_TENCENT_MODEL", "test-model")
 	os.Setenv("LLM_MAX_SEARCH_TOKEN_LENGTH", "50000")
 	os.Setenv("LLM_REQUEST_TIMEOUT", "60")
+	os.Setenv("LLM_PROVIDERS", "DEEPSEEK_TENCENT,OLLAMA")
+	os.Setenv("LLM_OLLAMA_TIMEOUT", "120")
+	defer os.Unsetenv("LLM_PROVIDERS")
+	defer os.Unsetenv("LLM_OLLAMA_TIMEOUT")
 
 	cfg, err := LoadConfig("")
 	if err != nil {
@@ -61,4 +66,55 @@ func TestLLMConfigFromEnv(t *testing.T) {
 	if cfg.LLM.DeepseekTencentModel != "test-model" {
 		t.Errorf("Expected LLM.DeepseekTencentModel test-model, got %s", cfg.LLM.DeepseekTencentModel)
 	}
+	if len(cfg.LLM.Providers) != 2 || cfg.LLM.Providers[1] != "OLLAMA" {
+		t.Errorf("Expected LLM.Providers [DEEPSEEK_TENCENT OLLAMA], got %v", cfg.LLM.Providers)
+	}
+	if cfg.LLM.OllamaTimeout != 120 {
+		t.Errorf("Expected LLM.OllamaTimeout 120, got %d", cfg.LLM.OllamaTimeout)
+	}
+}
+
+func TestSecurityConfigFor(t *testing.T) {
+	// Without overrides the environment's settings apply
+	security := SecurityConfigFor(&Config{App: AppConfig{Environment: "development"}})
+	if !security.CORS.AllowCredentials || len(security.CORS.AllowedOrigins) != 4 {
+		t.Errorf("Expected the development CORS settings, got %+v", security.CORS)
+	}
+	if security.Headers.StrictTransportSecurity != "" {
+		t.Errorf("Expected no HSTS in development, got %q", security.Headers.StrictTransportSecurity)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("SECURITY_STRICT_TRANSPORT_SECURITY", "off")
+	t.Setenv("SECURITY_MAX_REQUEST_SIZE", "2048")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	cfg.App.Environment = "development"
+	security = SecurityConfigFor(cfg)
+	if len(security.CORS.AllowedOrigins) != 1 || security.CORS.AllowedOrigins[0] != "*" {
+		t.Errorf("Expected the configured origins, got %v", security.CORS.AllowedOrigins)
+	}
+	if security.CORS.AllowCredentials {
+		t.Error("Expected no credentials for a wildcard origin")
+	}
+	if security.MaxRequestSize != 2048 {
+		t.Errorf("Expected a 2048 byte request limit, got %d", security.MaxRequestSize)
+	}
+
+	cfg.App.Environment = "production"
+	if security = SecurityConfigFor(cfg); security.Headers.StrictTransportSecurity != "" {
+		t.Errorf("Expected HSTS turned off, got %q", security.Headers.StrictTransportSecurity)
+	}
+
+	cfg.Database = DatabaseConfig{Driver: DriverSQLite, Path: "notes.db"}
+	cfg.Auth.JWTSecret = "test-secret-key-that-is-long-enough-for-validation"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	cfg.CORS.AllowCredentials = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Expected credentials for a wildcard origin to be rejected, got %v", err)
+	}
 }
@@ -25,6 +25,9 @@ type SecurityConfig struct {
 
 	// Token configuration
 	Token TokenConfig `yaml:"token"`
+
+	// MaxRequestSize is the request body limit of endpoints without their own
+	MaxRequestSize int64 `yaml:"max_request_size" default:"1048576"`
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -161,9 +164,9 @@ func GetDefaultSecurityConfig() *SecurityConfig {
 		},
 		CORS: CORSConfig{
 			AllowedOrigins:   []string{"http://localhost:3000", "chrome-extension://*"},
-			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Request-ID"},
-			ExposedHeaders:   []string{},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Request-ID", "X-Confirmation-ID", "X-Confirmation-Code", "X-API-Key", "Idempotency-Key", "If-Match"},
+			ExposedHeaders:   []string{"ETag"},
 			AllowCredentials: false,
 			MaxAge:           86400,
 		},
@@ -201,6 +204,7 @@ func GetDefaultSecurityConfig() *SecurityConfig {
 			Issuer:          "silence-notes",
 			Audience:        "silence-notes-users",
 		},
+		MaxRequestSize: 1 << 20,
 	}
 }
 
@@ -257,6 +261,63 @@ func GetProductionSecurityConfig() *SecurityConfig {
 	return config
 }
 
+// SecurityConfigFor returns the security config of the environment of cfg,
+// with the CORS settings and security headers cfg overrides
+func SecurityConfigFor(cfg *Config) *SecurityConfig {
+	var security *SecurityConfig
+	switch cfg.App.Environment {
+	case "development":
+		security = GetDevelopmentSecurityConfig()
+	case "production":
+		security = GetProductionSecurityConfig()
+	default:
+		security = GetDefaultSecurityConfig()
+	}
+
+	cors := cfg.CORS
+	if len(cors.AllowedOrigins) > 0 {
+		security.CORS.AllowedOrigins = cors.AllowedOrigins
+	}
+	if len(cors.AllowedMethods) > 0 {
+		security.CORS.AllowedMethods = cors.AllowedMethods
+	}
+	if len(cors.AllowedHeaders) > 0 {
+		security.CORS.AllowedHeaders = cors.AllowedHeaders
+	}
+	if len(cors.ExposedHeaders) > 0 {
+		security.CORS.ExposedHeaders = cors.ExposedHeaders
+	}
+	if cors.AllowCredentials {
+		security.CORS.AllowCredentials = true
+	}
+	if cors.MaxAge > 0 {
+		security.CORS.MaxAge = cors.MaxAge
+	}
+	// A wildcard origin never gets credentials, even from the environment
+	for _, origin := range security.CORS.AllowedOrigins {
+		if origin == "*" {
+			security.CORS.AllowCredentials = false
+		}
+	}
+
+	headers := cfg.Security
+	if headers.ContentSecurityPolicy != "" {
+		security.Headers.ContentSecurityPolicy = headers.ContentSecurityPolicy
+	}
+	switch headers.StrictTransportSecurity {
+	case "":
+	case "off":
+		security.Headers.StrictTransportSecurity = ""
+	default:
+		security.Headers.StrictTransportSecurity = headers.StrictTransportSecurity
+	}
+	if headers.MaxRequestSize > 0 {
+		security.MaxRequestSize = headers.MaxRequestSize
+	}
+
+	return security
+}
+
 // IsDevelopmentMode checks if the application is running in development mode
 func IsDevelopmentMode() bool {
 	// This would typically check an environment variable
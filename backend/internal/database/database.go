@@ -4,20 +4,34 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/gpd/my-notes/internal/config"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 // NewConnection creates a new database connection with connection pooling
 func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
+	if cfg.Driver == config.DriverSQLite {
+		return openSQLite(cfg.Path)
+	}
+
 	// Build connection string
 	dsn := cfg.DSN()
 
-	// Open database connection
-	db, err := sql.Open("postgres", dsn)
+	// Open database connection, with a span for each query
+	db, err := otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -42,6 +56,19 @@ func CreateTestDatabase(cfg config.DatabaseConfig) (*sql.DB, error) {
 	// Create a unique database name for testing
 	testDBName := fmt.Sprintf("%s_test_%d", cfg.Name, time.Now().UnixNano())
 
+	// SQLite test databases are files in the temporary directory
+	if cfg.Driver == config.DriverSQLite {
+		testCfg := cfg
+		testCfg.Path = filepath.Join(os.TempDir(), testDBName+".db")
+		testDB, err := NewConnection(testCfg)
+		if err != nil {
+			removeSQLite(testCfg.Path)
+			return nil, fmt.Errorf("failed to create test database: %w", err)
+		}
+		log.Printf("Test database created: %s", testCfg.Path)
+		return testDB, nil
+	}
+
 	// Connect to postgres database to create test database
 	adminCfg := cfg
 	adminCfg.Name = "postgres"
@@ -80,6 +107,11 @@ func DropTestDatabase(db *sql.DB) {
 		return
 	}
 
+	if DialectOf(db) == SQLite {
+		dropSQLiteTestDatabase(db)
+		return
+	}
+
 	// Get database name from connection
 	var dbName string
 	err := db.QueryRow("SELECT current_database()").Scan(&dbName)
@@ -151,4 +183,28 @@ func dropTestDatabase(cfg config.DatabaseConfig, dbName string) {
 		log.Printf("Failed to drop test database %s: %v", dbName, err)
 		return
 	}
-}
\ No newline at end of file
+}
+
+// dropSQLiteTestDatabase closes an SQLite test database and removes its file
+func dropSQLiteTestDatabase(db *sql.DB) {
+	file, err := sqliteFile(db)
+	if err != nil {
+		log.Printf("Failed to get database file: %v", err)
+		return
+	}
+
+	// Only remove test databases, like DropTestDatabase
+	if !strings.Contains(strings.ToLower(filepath.Base(file)), "test") {
+		log.Printf("Skipping drop of non-test database: %s", file)
+		return
+	}
+
+	db.Close()
+	forgetDialect(db)
+	if err := removeSQLite(file); err != nil {
+		log.Printf("Failed to drop test database %s: %v", file, err)
+		return
+	}
+
+	log.Printf("Test database dropped: %s", file)
+}
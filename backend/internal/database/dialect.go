@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/lib/pq"
+)
+
+// Dialect is the SQL dialect of a database connection. Most queries are
+// shared by PostgreSQL and SQLite; the few that differ are built with it.
+type Dialect string
+
+// Supported dialects
+const (
+	Postgres Dialect = config.DriverPostgres
+	SQLite   Dialect = config.DriverSQLite
+)
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = make(map[*sql.DB]Dialect)
+)
+
+// DialectOf returns the dialect of a connection opened by NewConnection.
+// Other connections are assumed to be PostgreSQL.
+func DialectOf(db *sql.DB) Dialect {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	if dialect, ok := dialects[db]; ok {
+		return dialect
+	}
+	return Postgres
+}
+
+// setDialect records the dialect of a connection
+func setDialect(db *sql.DB, dialect Dialect) {
+	dialectsMu.Lock()
+	dialects[db] = dialect
+	dialectsMu.Unlock()
+}
+
+// forgetDialect removes a closed connection from the recorded dialects
+func forgetDialect(db *sql.DB) {
+	dialectsMu.Lock()
+	delete(dialects, db)
+	dialectsMu.Unlock()
+}
+
+// AnyOf returns a condition matching column against any element of the array
+// parameter at argIndex, whose value is built by Array. PostgreSQL casts the
+// array to elemType[] when elemType is set.
+func (d Dialect) AnyOf(column string, argIndex int, elemType string) string {
+	if d == SQLite {
+		return fmt.Sprintf("%s IN (SELECT value FROM json_each($%d))", column, argIndex)
+	}
+	if elemType != "" {
+		return fmt.Sprintf("%s = ANY($%d::%s[])", column, argIndex, elemType)
+	}
+	return fmt.Sprintf("%s = ANY($%d)", column, argIndex)
+}
+
+// AnyOfIgnoringCase returns a condition matching the text column against any
+// element of the text array parameter at argIndex, comparing both sides by
+// LOWER. SQLite's LOWER folds ASCII letters only.
+func (d Dialect) AnyOfIgnoringCase(column string, argIndex int) string {
+	if d == SQLite {
+		return fmt.Sprintf("LOWER(%s) IN (SELECT LOWER(value) FROM json_each($%d))", column, argIndex)
+	}
+	return fmt.Sprintf("LOWER(%s) IN (SELECT LOWER(value) FROM unnest($%d::text[]) AS value)", column, argIndex)
+}
+
+// Array returns the value of an array parameter matched with AnyOf. values
+// must be a slice. SQLite has no arrays, so it receives the elements as a
+// JSON array.
+func (d Dialect) Array(values interface{}) interface{} {
+	if d == SQLite {
+		encoded, err := json.Marshal(values)
+		if err != nil {
+			// Slices of strings and UUIDs always encode
+			panic(fmt.Sprintf("cannot encode array parameter: %v", err))
+		}
+		return string(encoded)
+	}
+	return pq.Array(values)
+}
+
+// ILike returns a case-insensitive LIKE condition of column and the pattern
+// parameter at argIndex. SQLite's LIKE ignores the case of ASCII letters only.
+func (d Dialect) ILike(column string, argIndex int) string {
+	if d == SQLite {
+		return fmt.Sprintf("%s LIKE $%d", column, argIndex)
+	}
+	return fmt.Sprintf("%s ILIKE $%d", column, argIndex)
+}
+
+// Regexp returns a condition matching column against the case-insensitive
+// regular expression parameter at argIndex. SQLite matches with Go's regexp
+// package, PostgreSQL with its own, which agree on common syntax.
+func (d Dialect) Regexp(column string, argIndex int) string {
+	if d == SQLite {
+		return fmt.Sprintf("%s REGEXP $%d", column, argIndex)
+	}
+	return fmt.Sprintf("%s ~* $%d", column, argIndex)
+}
+
+// JSONText returns the text of the top-level key of a JSON column, or NULL
+// when the column or key is missing
+func (d Dialect) JSONText(column, key string) string {
+	if d == SQLite {
+		return fmt.Sprintf("json_extract(%s, '$.%s')", column, key)
+	}
+	return fmt.Sprintf("(%s->>'%s')", column, key)
+}
+
+// JSONRemove returns a JSON column without a top-level key
+func (d Dialect) JSONRemove(column, key string) string {
+	if d == SQLite {
+		return fmt.Sprintf("json_remove(%s, '$.%s')", column, key)
+	}
+	return fmt.Sprintf("(%s - '%s')", column, key)
+}
+
+// HasFullTextSearch reports whether notes have a full-text search vector.
+// SQLite matches search terms as substrings only.
+func (d Dialect) HasFullTextSearch() bool {
+	return d == Postgres
+}
@@ -1,70 +1,170 @@
 package database
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// sqliteMigrationsDir is the directory of the SQLite migrations within the
+// migrations directory
+const sqliteMigrationsDir = "sqlite"
+
 // Migrator handles database migrations
 type Migrator struct {
 	db             *sql.DB
+	dialect        Dialect
 	migrationsPath string
+	dryRun         bool
 }
 
-// NewMigrator creates a new migrator instance
+// NewMigrator creates a new migrator instance. SQLite databases are migrated
+// with the migrations in the sqlite directory of migrationsPath.
 func NewMigrator(db *sql.DB, migrationsPath string) *Migrator {
+	dialect := DialectOf(db)
+	if dialect == SQLite {
+		migrationsPath = filepath.Join(migrationsPath, sqliteMigrationsDir)
+	}
 	return &Migrator{
 		db:             db,
+		dialect:        dialect,
 		migrationsPath: migrationsPath,
 	}
 }
 
+// SetDryRun makes the migrator print the SQL it would run instead of
+// running it
+func (m *Migrator) SetDryRun(dryRun bool) {
+	m.dryRun = dryRun
+}
+
 // CreateMigrationsTable creates the migrations tracking table
 func (m *Migrator) CreateMigrationsTable() error {
+	if m.dialect == SQLite {
+		_, err := m.db.Exec(`
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version TEXT PRIMARY KEY,
+				applied_at TIMESTAMP DEFAULT (NOW()),
+				checksum TEXT
+			)
+		`)
+		return err
+	}
+
 	query := `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version VARCHAR(255) PRIMARY KEY,
 			applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		)
+		);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);
 	`
 	_, err := m.db.Exec(query)
 	return err
 }
 
+// prepare creates the migrations tracking table, unless in a dry run, which
+// must not change the database
+func (m *Migrator) prepare() error {
+	if m.dryRun {
+		return nil
+	}
+	if err := m.CreateMigrationsTable(); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+// Checksum returns the checksum of a migration file's content
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
 // GetAppliedMigrations returns the list of applied migrations
 func (m *Migrator) GetAppliedMigrations() (map[string]bool, error) {
-	query := "SELECT version FROM schema_migrations"
+	checksums, err := m.appliedChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(checksums))
+	for version := range checksums {
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+// appliedChecksums returns the applied migrations with the checksums they
+// were applied with. Migrations applied before checksums were recorded have
+// an empty checksum.
+func (m *Migrator) appliedChecksums() (map[string]string, error) {
+	// A dry run against a new database has no tracking table yet
+	exists, err := m.hasMigrationsTable()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[string]string{}, nil
+	}
+
+	// SQLite tracking tables always had checksums
+	hasChecksum := true
+	if m.dialect != SQLite {
+		err = m.db.QueryRow(`
+			SELECT EXISTS (
+				SELECT FROM information_schema.columns
+				WHERE table_name = 'schema_migrations' AND column_name = 'checksum'
+			)`).Scan(&hasChecksum)
+		if err != nil {
+			return nil, err
+		}
+	}
+	query := "SELECT version, '' FROM schema_migrations"
+	if hasChecksum {
+		query = "SELECT version, COALESCE(checksum, '') FROM schema_migrations"
+	}
+
 	rows, err := m.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	applied := make(map[string]bool)
+	checksums := make(map[string]string)
 	for rows.Next() {
-		var version string
-		if err := rows.Scan(&version); err != nil {
+		var version, checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
 			return nil, err
 		}
-		applied[version] = true
+		checksums[version] = checksum
 	}
 
-	return applied, rows.Err()
+	return checksums, rows.Err()
 }
 
-// GetPendingMigrations returns the list of pending migrations
-func (m *Migrator) GetPendingMigrations() ([]string, error) {
-	applied, err := m.GetAppliedMigrations()
-	if err != nil {
-		return nil, err
+// hasMigrationsTable reports whether the migrations tracking table exists
+func (m *Migrator) hasMigrationsTable() (bool, error) {
+	query := "SELECT to_regclass('schema_migrations') IS NOT NULL"
+	if m.dialect == SQLite {
+		query = "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'"
 	}
 
+	var exists bool
+	err := m.db.QueryRow(query).Scan(&exists)
+	return exists, err
+}
+
+// availableMigrations returns the versions of the migration files, sorted
+func (m *Migrator) availableMigrations() ([]string, error) {
 	files, err := os.ReadDir(m.migrationsPath)
 	if err != nil {
 		return nil, err
@@ -73,10 +173,7 @@ func (m *Migrator) GetPendingMigrations() ([]string, error) {
 	var migrations []string
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".up.sql") {
-			version := strings.TrimSuffix(file.Name(), ".up.sql")
-			if !applied[version] {
-				migrations = append(migrations, version)
-			}
+			migrations = append(migrations, strings.TrimSuffix(file.Name(), ".up.sql"))
 		}
 	}
 
@@ -86,10 +183,86 @@ func (m *Migrator) GetPendingMigrations() ([]string, error) {
 	return migrations, nil
 }
 
-// Up applies all pending migrations
+// GetPendingMigrations returns the list of pending migrations
+func (m *Migrator) GetPendingMigrations() ([]string, error) {
+	applied, err := m.GetAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := m.availableMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []string
+	for _, version := range available {
+		if !applied[version] {
+			migrations = append(migrations, version)
+		}
+	}
+	return migrations, nil
+}
+
+// Verify compares the applied migrations with their files and returns the
+// versions whose file changed or disappeared since they were applied.
+// Migrations applied before checksums were recorded take the checksum of
+// their current file.
+func (m *Migrator) Verify() ([]string, error) {
+	checksums, err := m.appliedChecksums()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	var drifted []string
+	for version, checksum := range checksums {
+		content, err := os.ReadFile(filepath.Join(m.migrationsPath, version+".up.sql"))
+		if os.IsNotExist(err) {
+			drifted = append(drifted, version)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+
+		current := Checksum(content)
+		if checksum == "" {
+			if !m.dryRun {
+				if _, err := m.db.Exec("UPDATE schema_migrations SET checksum = $1 WHERE version = $2", current, version); err != nil {
+					return nil, fmt.Errorf("failed to record checksum of migration %s: %w", version, err)
+				}
+			}
+			continue
+		}
+		if checksum != current {
+			drifted = append(drifted, version)
+		}
+	}
+
+	sort.Strings(drifted)
+	return drifted, nil
+}
+
+// warnDrift prints the applied migrations whose files changed
+func (m *Migrator) warnDrift() error {
+	drifted, err := m.Verify()
+	if err != nil {
+		return err
+	}
+	for _, version := range drifted {
+		fmt.Printf("WARNING: migration %s changed or was removed after it was applied\n", version)
+	}
+	return nil
+}
+
+// Up applies all pending migrations. Applied migrations whose files changed
+// are reported, but do not stop the pending ones.
 func (m *Migrator) Up() error {
-	if err := m.CreateMigrationsTable(); err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+	if err := m.prepare(); err != nil {
+		return err
+	}
+	if err := m.warnDrift(); err != nil {
+		return err
 	}
 
 	pending, err := m.GetPendingMigrations()
@@ -143,6 +316,92 @@ func (m *Migrator) Down() error {
 	return nil
 }
 
+// To migrates the database to a version, applying the pending migrations up
+// to it and rolling back the applied ones after it. The version is the
+// number a migration file starts with, or 0 to roll back every migration.
+// It refuses to run when applied migrations changed since they were applied.
+func (m *Migrator) To(target string) error {
+	if err := m.prepare(); err != nil {
+		return err
+	}
+	drifted, err := m.Verify()
+	if err != nil {
+		return err
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("migrations changed after they were applied: %s", strings.Join(drifted, ", "))
+	}
+
+	available, err := m.availableMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %w", err)
+	}
+	version, err := resolveVersion(available, target)
+	if err != nil {
+		return err
+	}
+	applied, err := m.GetAppliedMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	rollback, apply := planMigration(available, applied, version)
+	if len(rollback) == 0 && len(apply) == 0 {
+		fmt.Printf("Already at version %s\n", target)
+		return nil
+	}
+
+	for _, v := range rollback {
+		if err := m.rollbackMigration(v); err != nil {
+			return fmt.Errorf("failed to rollback migration %s: %w", v, err)
+		}
+		fmt.Printf("Rolled back migration: %s\n", v)
+	}
+	for _, v := range apply {
+		if err := m.applyMigration(v); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", v, err)
+		}
+		fmt.Printf("Applied migration: %s\n", v)
+	}
+
+	fmt.Printf("Migrated to version %s\n", target)
+	return nil
+}
+
+// resolveVersion returns the migration a target version refers to: the one
+// named after it or starting with it and an underscore. Target 0 resolves to
+// no migration.
+func resolveVersion(available []string, target string) (string, error) {
+	if target == "0" {
+		return "", nil
+	}
+	for _, version := range available {
+		if version == target || strings.HasPrefix(version, target+"_") {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("migration version %s not found", target)
+}
+
+// planMigration returns the applied migrations after version to roll back,
+// newest first, and the pending migrations up to version to apply, oldest
+// first. An empty version rolls back every migration.
+func planMigration(available []string, applied map[string]bool, version string) (rollback, apply []string) {
+	for v := range applied {
+		if version == "" || v > version {
+			rollback = append(rollback, v)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(rollback)))
+
+	for _, v := range available {
+		if version != "" && v <= version && !applied[v] {
+			apply = append(apply, v)
+		}
+	}
+	return rollback, apply
+}
+
 // applyMigration applies a single migration
 func (m *Migrator) applyMigration(version string) error {
 	// Read migration file
@@ -152,6 +411,11 @@ func (m *Migrator) applyMigration(version string) error {
 		return fmt.Errorf("failed to read migration file %s: %w", upFile, err)
 	}
 
+	if m.dryRun {
+		fmt.Printf("-- %s\n%s\n", filepath.Base(upFile), strings.TrimSpace(string(content)))
+		return nil
+	}
+
 	// Start transaction
 	tx, err := m.db.Begin()
 	if err != nil {
@@ -165,7 +429,7 @@ func (m *Migrator) applyMigration(version string) error {
 	}
 
 	// Record migration
-	if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)", version, Checksum(content)); err != nil {
 		return fmt.Errorf("failed to record migration: %w", err)
 	}
 
@@ -190,6 +454,11 @@ func (m *Migrator) rollbackMigration(version string) error {
 		return fmt.Errorf("failed to read rollback file %s: %w", downFile, err)
 	}
 
+	if m.dryRun {
+		fmt.Printf("-- %s\n%s\n", filepath.Base(downFile), strings.TrimSpace(string(content)))
+		return nil
+	}
+
 	// Start transaction
 	tx, err := m.db.Begin()
 	if err != nil {
@@ -227,6 +496,15 @@ func (m *Migrator) Status() error {
 		return fmt.Errorf("failed to get pending migrations: %w", err)
 	}
 
+	drifted, err := m.Verify()
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]bool, len(drifted))
+	for _, version := range drifted {
+		changed[version] = true
+	}
+
 	fmt.Println("Migration Status:")
 	fmt.Println("================")
 
@@ -240,7 +518,11 @@ func (m *Migrator) Status() error {
 		}
 		sort.Strings(appliedList)
 		for _, version := range appliedList {
-			fmt.Printf("  ✓ %s\n", version)
+			if changed[version] {
+				fmt.Printf("  ✗ %s (changed or removed after it was applied)\n", version)
+			} else {
+				fmt.Printf("  ✓ %s\n", version)
+			}
 		}
 	}
 
@@ -254,4 +536,40 @@ func (m *Migrator) Status() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Create writes empty up and down files for a new migration, numbered after
+// the latest one
+func (m *Migrator) Create(name string) error {
+	available, err := m.availableMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	version := nextVersion(available, time.Now())
+	base := version + "_" + strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(m.migrationsPath, base+"."+direction+".sql")
+		if err := os.WriteFile(path, []byte("-- "+base+" "+direction+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write migration file %s: %w", path, err)
+		}
+		fmt.Printf("Created migration file: %s\n", path)
+	}
+	return nil
+}
+
+// nextVersion returns the number of a new migration: the date followed by a
+// sequence number that sorts after every existing migration
+func nextVersion(available []string, now time.Time) string {
+	next := now.Format("20060102") + "0001"
+	for _, version := range available {
+		number, _, _ := strings.Cut(version, "_")
+		if len(number) == len(next) && number >= next {
+			n, err := strconv.ParseInt(number, 10, 64)
+			if err == nil {
+				next = strconv.FormatInt(n+1, 10)
+			}
+		}
+	}
+	return next
+}
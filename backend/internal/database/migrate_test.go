@@ -0,0 +1,81 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testMigrations = []string{"0001_users", "0002_notes", "0003_tags"}
+
+func TestResolveVersion(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{"0", "", false},
+		{"0002", "0002_notes", false},
+		{"0003_tags", "0003_tags", false},
+		{"000", "", true},
+		{"0004", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveVersion(testMigrations, tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveVersion(%q) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("resolveVersion(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	tests := []struct {
+		name         string
+		applied      map[string]bool
+		version      string
+		wantRollback []string
+		wantApply    []string
+	}{
+		{"forward", map[string]bool{"0001_users": true}, "0003_tags", nil, []string{"0002_notes", "0003_tags"}},
+		{"backward", map[string]bool{"0001_users": true, "0002_notes": true, "0003_tags": true}, "0001_users", []string{"0003_tags", "0002_notes"}, nil},
+		{"all the way down", map[string]bool{"0001_users": true, "0002_notes": true}, "", []string{"0002_notes", "0001_users"}, nil},
+		{"fills gaps", map[string]bool{"0001_users": true, "0003_tags": true}, "0002_notes", []string{"0003_tags"}, []string{"0002_notes"}},
+		{"current", map[string]bool{"0001_users": true}, "0001_users", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rollback, apply := planMigration(testMigrations, tt.applied, tt.version)
+			if !reflect.DeepEqual(rollback, tt.wantRollback) {
+				t.Errorf("Expected rollback %v, got %v", tt.wantRollback, rollback)
+			}
+			if !reflect.DeepEqual(apply, tt.wantApply) {
+				t.Errorf("Expected apply %v, got %v", tt.wantApply, apply)
+			}
+		})
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	now := time.Date(2026, 2, 16, 10, 0, 0, 0, time.UTC)
+
+	if got := nextVersion([]string{"202602150003_a"}, now); got != "202602160001" {
+		t.Errorf("Expected the first version of the day, got %s", got)
+	}
+	if got := nextVersion([]string{"202602160001_a", "202602160008_b"}, now); got != "202602160009" {
+		t.Errorf("Expected the version after the latest, got %s", got)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	if Checksum([]byte("SELECT 1;")) == Checksum([]byte("SELECT 2;")) {
+		t.Error("Expected different content to have different checksums")
+	}
+	if len(Checksum(nil)) != 64 {
+		t.Error("Expected a hex encoded sha256 checksum")
+	}
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Conditions builds the WHERE clause of a query and its parameters. The
+// parameters are numbered as conditions are added, so conditions never
+// count $n placeholders themselves.
+type Conditions struct {
+	dialect    Dialect
+	conditions []string
+	args       []interface{}
+}
+
+// NewConditions creates an empty WHERE clause for a dialect
+func NewConditions(dialect Dialect) *Conditions {
+	return &Conditions{dialect: dialect}
+}
+
+// Next returns the index of the next parameter
+func (c *Conditions) Next() int {
+	return len(c.args) + 1
+}
+
+// Add adds a condition whose parameters, numbered from Next, are args
+func (c *Conditions) Add(condition string, args ...interface{}) {
+	c.conditions = append(c.conditions, condition)
+	c.args = append(c.args, args...)
+}
+
+// Compare adds a condition comparing column to value with op, such as "="
+// or ">="
+func (c *Conditions) Compare(column, op string, value interface{}) {
+	c.Add(fmt.Sprintf("%s %s $%d", column, op, c.Next()), value)
+}
+
+// AnyOf adds a condition matching column against any of values, a slice.
+// PostgreSQL casts the values to elemType[] when elemType is set.
+func (c *Conditions) AnyOf(column string, values interface{}, elemType string) {
+	c.Add(c.dialect.AnyOf(column, c.Next(), elemType), c.dialect.Array(values))
+}
+
+// NoneOf adds a condition matching column against none of values. Rows
+// whose column is NULL are kept.
+func (c *Conditions) NoneOf(column string, values interface{}, elemType string) {
+	c.Add(fmt.Sprintf("(%s IS NULL OR NOT %s)", column, c.dialect.AnyOf(column, c.Next(), elemType)),
+		c.dialect.Array(values))
+}
+
+// Where returns the WHERE clause of the conditions combined with AND, or ""
+// without conditions
+func (c *Conditions) Where() string {
+	if len(c.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(c.conditions, " AND ")
+}
+
+// Args returns the parameters of the conditions
+func (c *Conditions) Args() []interface{} {
+	return c.args
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		dialect   Dialect
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			dialect: Postgres,
+			wantWhere: "WHERE user_id = $1 AND (title ILIKE $2 OR content ILIKE $2) AND color = ANY($3) AND " +
+				"(source IS NULL OR NOT source = ANY($4::text[])) AND created_at >= $5",
+		},
+		{
+			dialect: SQLite,
+			wantWhere: "WHERE user_id = $1 AND (title ILIKE $2 OR content ILIKE $2) AND color IN (SELECT value FROM json_each($3)) AND " +
+				"(source IS NULL OR NOT source IN (SELECT value FROM json_each($4))) AND created_at >= $5",
+			wantArgs: []interface{}{"user", "%plan%", `["red"]`, `["web"]`, "2026-01-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		conditions := NewConditions(tt.dialect)
+		if where := conditions.Where(); where != "" {
+			t.Errorf("Expected no WHERE clause without conditions, got %q", where)
+		}
+
+		conditions.Compare("user_id", "=", "user")
+		conditions.Add("(title ILIKE $2 OR content ILIKE $2)", "%plan%")
+		conditions.AnyOf("color", []string{"red"}, "")
+		conditions.NoneOf("source", []string{"web"}, "text")
+		conditions.Compare("created_at", ">=", "2026-01-01")
+
+		if where := conditions.Where(); where != tt.wantWhere {
+			t.Errorf("%s: Where() = %q, want %q", tt.dialect, where, tt.wantWhere)
+		}
+		if conditions.Next() != 6 || len(conditions.Args()) != 5 {
+			t.Errorf("%s: Expected 5 parameters, got %v", tt.dialect, conditions.Args())
+		}
+		if tt.wantArgs != nil && !reflect.DeepEqual(conditions.Args(), tt.wantArgs) {
+			t.Errorf("%s: Args() = %v, want %v", tt.dialect, conditions.Args(), tt.wantArgs)
+		}
+	}
+}
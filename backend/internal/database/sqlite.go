@@ -0,0 +1,101 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"github.com/google/uuid"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"modernc.org/sqlite"
+)
+
+// sqliteTimeFormat is how SQLite stores times. Times written in the same zone
+// compare as text like they compare as times.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+func init() {
+	// PostgreSQL functions used by shared queries and column defaults
+	sqlite.MustRegisterScalarFunction("now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().Format(sqliteTimeFormat), nil
+	})
+	sqlite.MustRegisterScalarFunction("gen_random_uuid", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return uuid.New().String(), nil
+	})
+	// X REGEXP Y calls regexp(Y, X); matching ignores case like ~* does
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, ok := args[0].(string)
+		if !ok {
+			return nil, nil
+		}
+		text, ok := args[1].(string)
+		if !ok {
+			return nil, nil
+		}
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(text), nil
+	})
+}
+
+// openSQLite opens the SQLite database file at path, creating it if needed
+func openSQLite(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_format", "sqlite")
+	dsn := "file:" + path + "?" + params.Encode()
+
+	// Open database connection, with a span for each query
+	db, err := otelsql.Open("sqlite", dsn,
+		otelsql.WithAttributes(semconv.DBSystemNameSQLite),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite has a single writer, waiting busy_timeout for the others
+	db.SetMaxOpenConns(8)
+	db.SetMaxIdleConns(8)
+
+	// Test connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	setDialect(db, SQLite)
+	log.Printf("Database connection established to SQLite %s", path)
+
+	return db, nil
+}
+
+// sqliteFile returns the file of an SQLite connection
+func sqliteFile(db *sql.DB) (string, error) {
+	var file string
+	err := db.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file)
+	return file, err
+}
+
+// removeSQLite removes an SQLite database file along with its journal files
+func removeSQLite(file string) error {
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(file + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Remove(file)
+}
@@ -0,0 +1,90 @@
+// Package email sends plain text emails to users, over SMTP or to the server
+// log when no mail server is configured.
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LogSender writes emails to the server log. It is used until an SMTP server
+// is configured.
+type LogSender struct{}
+
+// Send logs the recipient and subject of the message
+func (LogSender) Send(ctx context.Context, message Message) error {
+	log.Printf("[Email] To %s: %s (%d bytes)", message.To, message.Subject, len(message.Body))
+	return nil
+}
+
+// SMTPSender delivers emails through an SMTP server. Credentials are sent
+// with PLAIN authentication, which net/smtp only allows over TLS or to
+// localhost.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPSender creates an SMTPSender. Without a username no authentication
+// is attempted.
+func NewSMTPSender(host string, port int, username, password, from string) *SMTPSender {
+	sender := &SMTPSender{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		from: from,
+	}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
+
+// Send delivers the message. net/smtp does not take a context, so a message
+// is not sent once the context is done but a running delivery is not aborted.
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, buildMessage(s.from, message, time.Now())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildMessage formats the message with the headers of a UTF-8 plain text email
+func buildMessage(from string, message Message, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	// SMTP requires CRLF line endings
+	body := strings.ReplaceAll(message.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
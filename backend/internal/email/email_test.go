@@ -0,0 +1,32 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	message := buildMessage("notes@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Your daily digest – 3 new notes",
+		Body:    "Hello\nWorld\r\n",
+	}, date)
+
+	got := string(message)
+	for _, header := range []string{
+		"From: notes@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: =?utf-8?q?Your_daily_digest_=E2=80=93_3_new_notes?=\r\n",
+		"Date: Fri, 01 Mar 2024 07:00:00 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+	} {
+		if !strings.Contains(got, header) {
+			t.Errorf("Expected header %q in\n%s", header, got)
+		}
+	}
+	if !strings.HasSuffix(got, "\r\n\r\nHello\r\nWorld\r\n") {
+		t.Errorf("Expected the body with CRLF line endings, got %q", got)
+	}
+}
@@ -0,0 +1,153 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/google/uuid"
+)
+
+// ciphertextPrefix marks stored values produced by this package and carries the format version
+const ciphertextPrefix = "enc:v1:"
+
+// Key infos bind derived keys to their purpose so the master key can be reused safely elsewhere
+const (
+	noteKeyInfo      = "my-notes note content v1"
+	twoFactorKeyInfo = "my-notes totp secret v1"
+)
+
+// ErrKeyUnavailable is returned when no master key is configured
+var ErrKeyUnavailable = apperrors.New(apperrors.ErrUnavailable, "ENCRYPTION_UNAVAILABLE", "encryption key not available")
+
+// ErrInvalidCiphertext is returned when a stored value cannot be decrypted
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// KeyProvider supplies the master key used to derive per-user keys.
+// Implementations may read it from the environment or fetch it from a KMS.
+type KeyProvider interface {
+	MasterKey(ctx context.Context) ([]byte, error)
+}
+
+// StaticKeyProvider serves a master key loaded once at startup (e.g. from an env variable)
+type StaticKeyProvider struct {
+	key []byte
+}
+
+// NewStaticKeyProvider creates a key provider from a base64-encoded 32-byte master key
+func NewStaticKeyProvider(encodedKey string) (*StaticKeyProvider, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode master key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+	return &StaticKeyProvider{key: key}, nil
+}
+
+// MasterKey returns the configured master key
+func (p *StaticKeyProvider) MasterKey(ctx context.Context) ([]byte, error) {
+	return p.key, nil
+}
+
+// NoteEncryptor encrypts note content with AES-GCM using a key derived per user
+type NoteEncryptor struct {
+	provider KeyProvider
+	keyInfo  string
+}
+
+// NewNoteEncryptor creates a new NoteEncryptor; a nil provider yields an encryptor
+// that reports itself unavailable
+func NewNoteEncryptor(provider KeyProvider) *NoteEncryptor {
+	return &NoteEncryptor{provider: provider, keyInfo: noteKeyInfo}
+}
+
+// NewTwoFactorEncryptor creates an encryptor of TOTP secrets. Its keys are
+// derived apart from those of note content, so neither decrypts the other.
+func NewTwoFactorEncryptor(provider KeyProvider) *NoteEncryptor {
+	return &NoteEncryptor{provider: provider, keyInfo: twoFactorKeyInfo}
+}
+
+// Available reports whether a master key is configured
+func (e *NoteEncryptor) Available() bool {
+	return e != nil && e.provider != nil
+}
+
+// Encrypt encrypts plaintext for the given user and returns an encoded, prefixed ciphertext
+func (e *NoteEncryptor) Encrypt(ctx context.Context, userID uuid.UUID, plaintext string) (string, error) {
+	aead, err := e.userCipher(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), userID[:])
+	return ciphertextPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt for the given user
+func (e *NoteEncryptor) Decrypt(ctx context.Context, userID uuid.UUID, ciphertext string) (string, error) {
+	if !IsEncrypted(ciphertext) {
+		return "", ErrInvalidCiphertext
+	}
+
+	aead, err := e.userCipher(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, ciphertextPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, body := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, body, userID[:])
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a stored value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+// userCipher derives the user's key from the master key with HKDF-SHA256 and returns an AES-GCM cipher
+func (e *NoteEncryptor) userCipher(ctx context.Context, userID uuid.UUID) (cipher.AEAD, error) {
+	if !e.Available() {
+		return nil, ErrKeyUnavailable
+	}
+
+	masterKey, err := e.provider.MasterKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master key: %w", err)
+	}
+
+	userKey, err := hkdf.Key(sha256.New, masterKey, userID[:], e.keyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive user key: %w", err)
+	}
+
+	block, err := aes.NewCipher(userKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,99 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func testEncryptor(t *testing.T) *NoteEncryptor {
+	t.Helper()
+	provider, err := NewStaticKeyProvider(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	return NewNoteEncryptor(provider)
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	encryptor := testEncryptor(t)
+	userID := uuid.New()
+
+	ciphertext, err := encryptor.Encrypt(ctx, userID, "my secret note #private")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !IsEncrypted(ciphertext) {
+		t.Fatalf("expected ciphertext to carry prefix, got %q", ciphertext)
+	}
+	if strings.Contains(ciphertext, "secret") {
+		t.Fatalf("ciphertext leaks plaintext: %q", ciphertext)
+	}
+
+	plaintext, err := encryptor.Decrypt(ctx, userID, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if plaintext != "my secret note #private" {
+		t.Errorf("expected original plaintext, got %q", plaintext)
+	}
+}
+
+func TestDecryptWithOtherUserFails(t *testing.T) {
+	ctx := context.Background()
+	encryptor := testEncryptor(t)
+
+	ciphertext, err := encryptor.Encrypt(ctx, uuid.New(), "only mine")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	if _, err := encryptor.Decrypt(ctx, uuid.New(), ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("expected ErrInvalidCiphertext, got %v", err)
+	}
+}
+
+func TestTwoFactorKeysDifferFromNoteKeys(t *testing.T) {
+	ctx := context.Background()
+	provider, err := NewStaticKeyProvider(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	if err != nil {
+		t.Fatalf("failed to create key provider: %v", err)
+	}
+	notes, secrets := NewNoteEncryptor(provider), NewTwoFactorEncryptor(provider)
+	userID := uuid.New()
+
+	ciphertext, err := secrets.Encrypt(ctx, userID, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, err := notes.Decrypt(ctx, userID, ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("expected the note key to fail on a TOTP secret, got %v", err)
+	}
+	if plaintext, err := secrets.Decrypt(ctx, userID, ciphertext); err != nil || plaintext != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Decrypt() = %q, %v", plaintext, err)
+	}
+}
+
+func TestUnavailableEncryptor(t *testing.T) {
+	encryptor := NewNoteEncryptor(nil)
+	if encryptor.Available() {
+		t.Fatal("expected encryptor without provider to be unavailable")
+	}
+	if _, err := encryptor.Encrypt(context.Background(), uuid.New(), "text"); !errors.Is(err, ErrKeyUnavailable) {
+		t.Errorf("expected ErrKeyUnavailable, got %v", err)
+	}
+}
+
+func TestNewStaticKeyProviderRejectsShortKey(t *testing.T) {
+	if _, err := NewStaticKeyProvider(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected error for short master key")
+	}
+	if _, err := NewStaticKeyProvider("not base64!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
@@ -0,0 +1,58 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+)
+
+// loaderTagLimit is the number of tags loaded for a query
+const loaderTagLimit = 1000
+
+type tagLoaderKey struct{}
+
+// tagLoader loads the user's tags once per query, so resolving the tags of
+// many notes does not query each tag
+type tagLoader struct {
+	once     sync.Once
+	err      error
+	byName   map[string]*models.TagResponse
+	byID     map[uuid.UUID]*models.TagResponse
+	children map[uuid.UUID][]*models.TagResponse
+}
+
+// tagLoaderFrom returns the loader of the query in ctx
+func tagLoaderFrom(ctx context.Context) *tagLoader {
+	if loader, ok := ctx.Value(tagLoaderKey{}).(*tagLoader); ok {
+		return loader
+	}
+	return &tagLoader{}
+}
+
+// load loads the user's tags on first use
+func (l *tagLoader) load(ctx context.Context, tagService services.TagServiceInterface, userID string) error {
+	l.once.Do(func() {
+		list, err := tagService.GetAllTags(ctx, userID, loaderTagLimit, 0)
+		if err != nil {
+			l.err = err
+			return
+		}
+
+		l.byName = make(map[string]*models.TagResponse, len(list.Tags))
+		l.byID = make(map[uuid.UUID]*models.TagResponse, len(list.Tags))
+		l.children = make(map[uuid.UUID][]*models.TagResponse)
+		for i := range list.Tags {
+			tag := &list.Tags[i]
+			l.byName[strings.ToLower(tag.Name)] = tag
+			l.byID[tag.ID] = tag
+			if tag.ParentID != nil {
+				l.children[*tag.ParentID] = append(l.children[*tag.ParentID], tag)
+			}
+		}
+	})
+	return l.err
+}
@@ -0,0 +1,332 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// maxPageSize caps the limit of every list
+const maxPageSize = 100
+
+// queryResolver resolves the root Query type
+type queryResolver struct {
+	noteService services.NoteServiceInterface
+	tagService  services.TagServiceInterface
+}
+
+// userID returns the ID of the user the query runs for
+func userID(ctx context.Context) (string, error) {
+	user, ok := ctx.Value("user").(*models.User)
+	if !ok {
+		return "", fmt.Errorf("user not authenticated")
+	}
+	return user.ID.String(), nil
+}
+
+// pageLimit clamps a requested page size to 1..maxPageSize
+func pageLimit(limit int32) int {
+	if limit <= 0 {
+		return 20
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return int(limit)
+}
+
+// pageOffset clamps a requested offset to 0 or more
+func pageOffset(offset int32) int {
+	if offset < 0 {
+		return 0
+	}
+	return int(offset)
+}
+
+func (r *queryResolver) Note(ctx context.Context, args struct{ ID graphqlgo.ID }) (*noteResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := r.noteService.GetNoteByID(ctx, uid, string(args.ID))
+	if err != nil {
+		if errors.Is(err, services.ErrNoteNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	response := note.ToResponse()
+	response.Tags = note.ExtractHashtags()
+	return r.newNoteResolver(response), nil
+}
+
+func (r *queryResolver) Notes(ctx context.Context, args struct {
+	Limit    int32
+	Offset   int32
+	OrderBy  string
+	OrderDir string
+}) (*noteConnectionResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.noteService.ListNotes(ctx, uid, pageLimit(args.Limit), pageOffset(args.Offset), args.OrderBy, args.OrderDir)
+	if err != nil {
+		return nil, err
+	}
+	return r.newNoteConnection(list), nil
+}
+
+func (r *queryResolver) Search(ctx context.Context, args struct {
+	Query  string
+	Tags   []string
+	Limit  int32
+	Offset int32
+}) (*noteConnectionResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.SearchNotesRequest{
+		Query:  args.Query,
+		Tags:   args.Tags,
+		Limit:  pageLimit(args.Limit),
+		Offset: pageOffset(args.Offset),
+	}
+	list, err := r.noteService.SearchNotes(ctx, uid, request)
+	if err != nil {
+		return nil, err
+	}
+	return r.newNoteConnection(list), nil
+}
+
+func (r *queryResolver) Tag(ctx context.Context, args struct{ Name string }) (*tagResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.tagByName(ctx, uid, search.NormalizeTag(strings.TrimSpace(args.Name)))
+}
+
+func (r *queryResolver) Tags(ctx context.Context, args struct {
+	Limit  int32
+	Offset int32
+}) (*tagConnectionResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.tagService.GetAllTags(ctx, uid, pageLimit(args.Limit), pageOffset(args.Offset))
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &tagConnectionResolver{total: list.Total, hasMore: list.HasMore}
+	for _, tag := range list.Tags {
+		connection.nodes = append(connection.nodes, r.newTagResolver(tag))
+	}
+	return connection, nil
+}
+
+// tagByName returns a tag from the query's loaded tags, falling back to the
+// database for tags without notes. It returns nil if the tag does not exist.
+func (r *queryResolver) tagByName(ctx context.Context, uid, name string) (*tagResolver, error) {
+	loader := tagLoaderFrom(ctx)
+	if err := loader.load(ctx, r.tagService, uid); err != nil {
+		return nil, err
+	}
+	if tag, ok := loader.byName[strings.ToLower(name)]; ok {
+		return r.newTagResolver(*tag), nil
+	}
+
+	tag, err := r.tagService.GetTagByName(ctx, uid, name)
+	if err != nil {
+		if errors.Is(err, services.ErrTagNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.newTagResolver(tag.ToResponse()), nil
+}
+
+func (r *queryResolver) newNoteResolver(note models.NoteResponse) *noteResolver {
+	return &noteResolver{root: r, note: note}
+}
+
+func (r *queryResolver) newTagResolver(tag models.TagResponse) *tagResolver {
+	return &tagResolver{root: r, tag: tag}
+}
+
+func (r *queryResolver) newNoteConnection(list *models.NoteList) *noteConnectionResolver {
+	connection := &noteConnectionResolver{
+		nodes:   make([]*noteResolver, 0, len(list.Notes)),
+		total:   list.Total,
+		hasMore: list.HasMore,
+	}
+	for _, note := range list.Notes {
+		connection.nodes = append(connection.nodes, r.newNoteResolver(note))
+	}
+	return connection
+}
+
+// noteResolver resolves the Note type
+type noteResolver struct {
+	root *queryResolver
+	note models.NoteResponse
+}
+
+func (r *noteResolver) ID() graphqlgo.ID { return graphqlgo.ID(r.note.ID.String()) }
+func (r *noteResolver) Title() *string   { return r.note.Title }
+func (r *noteResolver) Content() string  { return r.note.Content }
+func (r *noteResolver) Version() int32   { return int32(r.note.Version) }
+func (r *noteResolver) IsPrivate() bool  { return r.note.IsPrivate }
+func (r *noteResolver) Locked() bool     { return r.note.Locked }
+func (r *noteResolver) AiImproved() bool { return r.note.AIImproved }
+func (r *noteResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: r.note.CreatedAt}
+}
+func (r *noteResolver) UpdatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: r.note.UpdatedAt}
+}
+
+func (r *noteResolver) Language() *string {
+	if r.note.Language == "" {
+		return nil
+	}
+	return &r.note.Language
+}
+
+func (r *noteResolver) PrettifiedAt() *graphqlgo.Time {
+	if r.note.PrettifiedAt == nil {
+		return nil
+	}
+	return &graphqlgo.Time{Time: *r.note.PrettifiedAt}
+}
+
+// Tags resolves the tags in the note's content
+func (r *noteResolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]*tagResolver, 0, len(r.note.Tags))
+	for _, name := range r.note.Tags {
+		tag, err := r.root.tagByName(ctx, uid, name)
+		if err != nil {
+			return nil, err
+		}
+		if tag != nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// tagResolver resolves the Tag type
+type tagResolver struct {
+	root *queryResolver
+	tag  models.TagResponse
+}
+
+func (r *tagResolver) ID() graphqlgo.ID { return graphqlgo.ID(r.tag.ID.String()) }
+func (r *tagResolver) Name() string     { return r.tag.Name }
+func (r *tagResolver) NoteCount() int32 { return int32(r.tag.NoteCount) }
+func (r *tagResolver) CreatedAt() graphqlgo.Time {
+	return graphqlgo.Time{Time: r.tag.CreatedAt}
+}
+
+// Parent resolves the parent of a nested tag
+func (r *tagResolver) Parent(ctx context.Context) (*tagResolver, error) {
+	if r.tag.ParentID == nil {
+		return nil, nil
+	}
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := tagLoaderFrom(ctx)
+	if err := loader.load(ctx, r.root.tagService, uid); err != nil {
+		return nil, err
+	}
+	if parent, ok := loader.byID[*r.tag.ParentID]; ok {
+		return r.root.newTagResolver(*parent), nil
+	}
+
+	parent, err := r.root.tagService.GetTagByID(ctx, uid, r.tag.ParentID.String())
+	if err != nil {
+		return nil, err
+	}
+	return r.root.newTagResolver(parent.ToResponse()), nil
+}
+
+// Children resolves the nested tags in use
+func (r *tagResolver) Children(ctx context.Context) ([]*tagResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := tagLoaderFrom(ctx)
+	if err := loader.load(ctx, r.root.tagService, uid); err != nil {
+		return nil, err
+	}
+	children := make([]*tagResolver, 0, len(loader.children[r.tag.ID]))
+	for _, child := range loader.children[r.tag.ID] {
+		children = append(children, r.root.newTagResolver(*child))
+	}
+	return children, nil
+}
+
+// Notes resolves the notes carrying the tag
+func (r *tagResolver) Notes(ctx context.Context, args struct {
+	Limit           int32
+	Offset          int32
+	IncludeChildren bool
+}) (*noteConnectionResolver, error) {
+	uid, err := userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.root.noteService.GetNotesByTag(ctx, uid, r.tag.Name, args.IncludeChildren,
+		pageLimit(args.Limit), pageOffset(args.Offset))
+	if err != nil {
+		return nil, err
+	}
+	return r.root.newNoteConnection(list), nil
+}
+
+// noteConnectionResolver resolves the NoteConnection type
+type noteConnectionResolver struct {
+	nodes   []*noteResolver
+	total   int
+	hasMore bool
+}
+
+func (r *noteConnectionResolver) Nodes() []*noteResolver { return r.nodes }
+func (r *noteConnectionResolver) Total() int32           { return int32(r.total) }
+func (r *noteConnectionResolver) HasMore() bool          { return r.hasMore }
+
+// tagConnectionResolver resolves the TagConnection type
+type tagConnectionResolver struct {
+	nodes   []*tagResolver
+	total   int
+	hasMore bool
+}
+
+func (r *tagConnectionResolver) Nodes() []*tagResolver { return r.nodes }
+func (r *tagConnectionResolver) Total() int32          { return int32(r.total) }
+func (r *tagConnectionResolver) HasMore() bool         { return r.hasMore }
@@ -0,0 +1,42 @@
+// Package graphql serves a GraphQL view of notes and tags, so clients can
+// fetch nested data (note → tags → notes) in one request and only the fields
+// they need.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+// Query limits guarding against expensive nested queries
+const (
+	maxQueryDepth  = 8
+	maxQueryLength = 10000
+	maxParallelism = 10
+)
+
+// NewSchema parses the schema and binds it to resolvers backed by the services
+func NewSchema(noteService services.NoteServiceInterface, tagService services.TagServiceInterface) (*graphqlgo.Schema, error) {
+	resolver := &queryResolver{
+		noteService: noteService,
+		tagService:  tagService,
+	}
+	return graphqlgo.ParseSchema(schemaSource, resolver,
+		graphqlgo.MaxDepth(maxQueryDepth),
+		graphqlgo.MaxQueryLength(maxQueryLength),
+		graphqlgo.MaxParallelism(maxParallelism),
+	)
+}
+
+// WithUser returns ctx prepared to execute a query for user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	ctx = context.WithValue(ctx, "user", user)
+	return context.WithValue(ctx, tagLoaderKey{}, &tagLoader{})
+}
@@ -0,0 +1,180 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+)
+
+// fakeNoteService serves notes from memory; unused methods panic
+type fakeNoteService struct {
+	services.NoteServiceInterface
+	notes []models.Note
+}
+
+func (s *fakeNoteService) GetNoteByID(ctx context.Context, userID, noteID string) (*models.Note, error) {
+	for i := range s.notes {
+		if s.notes[i].ID.String() == noteID {
+			return &s.notes[i], nil
+		}
+	}
+	return nil, services.ErrNoteNotFound
+}
+
+func (s *fakeNoteService) ListNotes(ctx context.Context, userID string, limit, offset int, orderBy, orderDir string) (*models.NoteList, error) {
+	list := &models.NoteList{Total: len(s.notes), Limit: limit}
+	for i := offset; i < len(s.notes) && len(list.Notes) < limit; i++ {
+		response := s.notes[i].ToResponse()
+		response.Tags = s.notes[i].ExtractHashtags()
+		list.Notes = append(list.Notes, response)
+	}
+	list.HasMore = offset+len(list.Notes) < len(s.notes)
+	return list, nil
+}
+
+func (s *fakeNoteService) GetNotesByTag(ctx context.Context, userID, tag string, includeChildren bool, limit, offset int) (*models.NoteList, error) {
+	list := &models.NoteList{}
+	for _, note := range s.notes {
+		for _, noteTag := range note.ExtractHashtags() {
+			if noteTag == tag || (includeChildren && strings.HasPrefix(noteTag, tag+"/")) {
+				list.Notes = append(list.Notes, note.ToResponse())
+				break
+			}
+		}
+	}
+	list.Total = len(list.Notes)
+	return list, nil
+}
+
+// fakeTagService serves tags from memory and counts loads
+type fakeTagService struct {
+	services.TagServiceInterface
+	tags  []models.TagResponse
+	loads int
+}
+
+func (s *fakeTagService) GetAllTags(ctx context.Context, userID string, limit, offset int) (*models.TagList, error) {
+	s.loads++
+	return &models.TagList{Tags: s.tags, Total: len(s.tags), Limit: limit}, nil
+}
+
+func (s *fakeTagService) GetTagByName(ctx context.Context, userID, tagName string) (*models.Tag, error) {
+	return nil, services.ErrTagNotFound
+}
+
+func newTestSchema(t *testing.T) (*fakeNoteService, *fakeTagService, func(query string) map[string]any) {
+	t.Helper()
+	userID := uuid.New()
+	workID := uuid.New()
+	noteService := &fakeNoteService{notes: []models.Note{
+		{ID: uuid.New(), UserID: userID, Content: "Offsite agenda #work/offsite", CreatedAt: time.Now()},
+		{ID: uuid.New(), UserID: userID, Content: "Quarterly review #work", CreatedAt: time.Now()},
+		{ID: uuid.New(), UserID: userID, Content: "Buy milk #errands", CreatedAt: time.Now()},
+	}}
+	tagService := &fakeTagService{tags: []models.TagResponse{
+		{ID: workID, Name: "#work", NoteCount: 1},
+		{ID: uuid.New(), ParentID: &workID, Name: "#work/offsite", NoteCount: 1},
+		{ID: uuid.New(), Name: "#errands", NoteCount: 1},
+	}}
+
+	schema, err := NewSchema(noteService, tagService)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	exec := func(query string) map[string]any {
+		ctx := WithUser(context.Background(), &models.User{ID: userID})
+		response := schema.Exec(ctx, query, "", nil)
+		if len(response.Errors) > 0 {
+			t.Fatalf("Query failed: %v", response.Errors)
+		}
+		var data map[string]any
+		if err := json.Unmarshal(response.Data, &data); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return data
+	}
+	return noteService, tagService, exec
+}
+
+func TestNestedQuery(t *testing.T) {
+	_, tagService, exec := newTestSchema(t)
+
+	data := exec(`{
+		notes(limit: 10) {
+			total
+			nodes { content tags { name parent { name } } }
+		}
+	}`)
+
+	notes := data["notes"].(map[string]any)
+	if notes["total"].(float64) != 3 {
+		t.Errorf("Expected 3 notes, got %v", notes["total"])
+	}
+	first := notes["nodes"].([]any)[0].(map[string]any)
+	tag := first["tags"].([]any)[0].(map[string]any)
+	if tag["name"] != "#work/offsite" || tag["parent"].(map[string]any)["name"] != "#work" {
+		t.Errorf("Unexpected tag %v", tag)
+	}
+	if tagService.loads != 1 {
+		t.Errorf("Expected tags to be loaded once per query, got %d loads", tagService.loads)
+	}
+}
+
+func TestTagNotes(t *testing.T) {
+	_, _, exec := newTestSchema(t)
+
+	data := exec(`{
+		tag(name: "work") {
+			children { name }
+			notes(includeChildren: true) { total nodes { content } }
+		}
+		missing: tag(name: "#nope") { name }
+	}`)
+
+	tag := data["tag"].(map[string]any)
+	children := tag["children"].([]any)
+	if len(children) != 1 || children[0].(map[string]any)["name"] != "#work/offsite" {
+		t.Errorf("Unexpected children %v", children)
+	}
+	if total := tag["notes"].(map[string]any)["total"].(float64); total != 2 {
+		t.Errorf("Expected notes of nested tags to be included, got %v", total)
+	}
+	if data["missing"] != nil {
+		t.Errorf("Expected an unknown tag to be null, got %v", data["missing"])
+	}
+}
+
+func TestNoteByID(t *testing.T) {
+	noteService, _, exec := newTestSchema(t)
+
+	data := exec(fmt.Sprintf(`{ note(id: %q) { content isPrivate } missing: note(id: %q) { id } }`,
+		noteService.notes[2].ID, uuid.New()))
+
+	if note := data["note"].(map[string]any); note["content"] != "Buy milk #errands" {
+		t.Errorf("Unexpected note %v", note)
+	}
+	if data["missing"] != nil {
+		t.Errorf("Expected an unknown note to be null, got %v", data["missing"])
+	}
+}
+
+func TestQueryDepthLimit(t *testing.T) {
+	schema, err := NewSchema(&fakeNoteService{}, &fakeTagService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := `{ tags { nodes { notes { nodes { tags { notes { nodes { tags { notes { total } } } } } } } } } }`
+	response := schema.Exec(WithUser(context.Background(), &models.User{ID: uuid.New()}), query, "", nil)
+	if len(response.Errors) == 0 {
+		t.Error("Expected a deeply nested query to be rejected")
+	}
+}
@@ -0,0 +1,135 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// writeMethods are the methods rejected while an account is locked read-only
+var writeMethods = map[string]bool{
+	"/notes.v1.NoteService/CreateNote": true,
+	"/notes.v1.NoteService/UpdateNote": true,
+	"/notes.v1.NoteService/DeleteNote": true,
+}
+
+// Authenticator authenticates calls with the session token or API key in the
+// "authorization: Bearer <token>" metadata and adds the user to the context
+// under "user", like the HTTP middleware
+type Authenticator struct {
+	tokenService  *auth.TokenService
+	apiKeyService services.APIKeyServiceInterface
+	userService   services.UserServiceInterface
+}
+
+// NewAuthenticator creates a new Authenticator
+func NewAuthenticator(tokenService *auth.TokenService, apiKeyService services.APIKeyServiceInterface, userService services.UserServiceInterface) *Authenticator {
+	return &Authenticator{
+		tokenService:  tokenService,
+		apiKeyService: apiKeyService,
+		userService:   userService,
+	}
+}
+
+// UnaryInterceptor authenticates unary calls
+func (a *Authenticator) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authenticates streaming calls
+func (a *Authenticator) StreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate returns ctx with the user calling method
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+	token = strings.TrimSpace(token)
+
+	var userID string
+	var claims *auth.Claims
+	if strings.HasPrefix(token, services.APIKeyPrefix) {
+		if a.apiKeyService == nil {
+			return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+		}
+		id, err := a.apiKeyService.Authenticate(ctx, token)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+			}
+			log.Printf("ERROR: failed to authenticate API key: %v", err)
+			return nil, status.Error(codes.Internal, "Failed to authenticate API key")
+		}
+		userID = id
+	} else {
+		// Refresh tokens only renew sessions
+		var err error
+		claims, err = a.tokenService.ValidateToken(ctx, token)
+		if err != nil || claims.TokenType != auth.TokenTypeAccess {
+			return nil, status.Error(codes.Unauthenticated, "Invalid token")
+		}
+		userID = claims.UserID
+	}
+
+	user, err := a.userService.GetByID(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "User not found")
+	}
+	if user.IsDisabled() {
+		return nil, status.Error(codes.PermissionDenied, "Account has been disabled")
+	}
+	// API keys stand on their own; tokens need the session's two-factor sign-in
+	if claims != nil && !auth.TwoFactorSatisfied(claims, user) {
+		return nil, status.Error(codes.Unauthenticated, "Two-factor authentication required, sign in again with a code")
+	}
+	if writeMethods[method] && user.IsReadOnly() {
+		return nil, status.Error(codes.FailedPrecondition, "Account is temporarily read-only after unusual activity")
+	}
+
+	return context.WithValue(ctx, "user", user), nil
+}
+
+// authenticatedStream is a server stream with the authenticated context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the authenticated context
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// userFromContext returns the user added by the Authenticator
+func userFromContext(ctx context.Context) (*models.User, error) {
+	user, ok := ctx.Value("user").(*models.User)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+	}
+	return user, nil
+}
@@ -0,0 +1,136 @@
+package grpcserver
+
+import (
+	"context"
+
+	notesv1 "github.com/gpd/my-notes/api/proto/notes/v1"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NoteServer implements notesv1.NoteServiceServer
+type NoteServer struct {
+	notesv1.UnimplementedNoteServiceServer
+	noteService services.NoteServiceInterface
+}
+
+// NewNoteServer creates a new NoteServer
+func NewNoteServer(noteService services.NoteServiceInterface) *NoteServer {
+	return &NoteServer{noteService: noteService}
+}
+
+// ListNotes returns a page of the user's notes
+func (s *NoteServer) ListNotes(ctx context.Context, request *notesv1.ListNotesRequest) (*notesv1.ListNotesResponse, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy := request.GetOrderBy()
+	if orderBy == "" {
+		orderBy = "created_at"
+	}
+	orderDir := request.GetOrderDir()
+	if orderDir == "" {
+		orderDir = "desc"
+	}
+	offset := int(request.GetOffset())
+	if offset < 0 {
+		offset = 0
+	}
+
+	list, err := s.noteService.ListNotes(ctx, user.ID.String(), pageLimit(request.GetLimit()), offset, orderBy, orderDir)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &notesv1.ListNotesResponse{
+		Notes:   make([]*notesv1.Note, 0, len(list.Notes)),
+		Total:   int32(list.Total),
+		HasMore: list.HasMore,
+	}
+	for i := range list.Notes {
+		response.Notes = append(response.Notes, noteToProto(&list.Notes[i]))
+	}
+	return response, nil
+}
+
+// GetNote returns a note
+func (s *NoteServer) GetNote(ctx context.Context, request *notesv1.GetNoteRequest) (*notesv1.Note, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Note ID is required")
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, user.ID.String(), request.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return modelNoteToProto(note), nil
+}
+
+// CreateNote creates a note
+func (s *NoteServer) CreateNote(ctx context.Context, request *notesv1.CreateNoteRequest) (*notesv1.Note, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.noteService.CreateNote(ctx, user.ID.String(), &models.CreateNoteRequest{
+		Title:   request.GetTitle(),
+		Content: request.GetContent(),
+		Private: request.GetPrivate(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return modelNoteToProto(note), nil
+}
+
+// UpdateNote changes the fields set in the request
+func (s *NoteServer) UpdateNote(ctx context.Context, request *notesv1.UpdateNoteRequest) (*notesv1.Note, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Note ID is required")
+	}
+
+	update := &models.UpdateNoteRequest{
+		Title:   request.Title,
+		Content: request.Content,
+		Private: request.Private,
+	}
+	if request.Version != nil {
+		version := int(request.GetVersion())
+		update.Version = &version
+	}
+
+	note, err := s.noteService.UpdateNote(ctx, user.ID.String(), request.GetId(), update)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return modelNoteToProto(note), nil
+}
+
+// DeleteNote deletes a note
+func (s *NoteServer) DeleteNote(ctx context.Context, request *notesv1.DeleteNoteRequest) (*notesv1.DeleteNoteResponse, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Note ID is required")
+	}
+
+	if err := s.noteService.DeleteNote(ctx, user.ID.String(), request.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &notesv1.DeleteNoteResponse{}, nil
+}
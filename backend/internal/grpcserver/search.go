@@ -0,0 +1,78 @@
+package grpcserver
+
+import (
+	"strings"
+
+	notesv1 "github.com/gpd/my-notes/api/proto/notes/v1"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"google.golang.org/grpc"
+)
+
+// Search result limits
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// SearchServer implements notesv1.SearchServiceServer
+type SearchServer struct {
+	notesv1.UnimplementedSearchServiceServer
+	noteService services.NoteServiceInterface
+}
+
+// NewSearchServer creates a new SearchServer
+func NewSearchServer(noteService services.NoteServiceInterface) *SearchServer {
+	return &SearchServer{noteService: noteService}
+}
+
+// SearchNotes streams the notes matching a search, fetching the next page
+// once the previous one was sent
+func (s *SearchServer) SearchNotes(request *notesv1.SearchNotesRequest, stream grpc.ServerStreamingServer[notesv1.Note]) error {
+	ctx := stream.Context()
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	limit := int(request.GetLimit())
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	tags := make([]string, 0, len(request.GetTags()))
+	for _, tag := range request.GetTags() {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	sent := 0
+	for sent < limit {
+		page := &models.SearchNotesRequest{
+			Query:    request.GetQuery(),
+			Tags:     tags,
+			Limit:    min(streamPageSize, limit-sent),
+			Offset:   sent,
+			OrderBy:  request.GetOrderBy(),
+			OrderDir: request.GetOrderDir(),
+		}
+		list, err := s.noteService.SearchNotes(ctx, user.ID.String(), page)
+		if err != nil {
+			return toStatus(err)
+		}
+		for i := range list.Notes {
+			if err := stream.Send(noteToProto(&list.Notes[i])); err != nil {
+				return err
+			}
+		}
+		sent += len(list.Notes)
+		if !list.HasMore || len(list.Notes) == 0 {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package grpcserver serves the note, tag and search services over gRPC,
+// next to the REST API. The API is defined in api/proto/notes/v1.
+package grpcserver
+
+import (
+	"errors"
+
+	notesv1 "github.com/gpd/my-notes/api/proto/notes/v1"
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewServer creates a gRPC server with the note, tag and search services.
+// Every call is authenticated by authenticator.
+func NewServer(noteService services.NoteServiceInterface, tagService services.TagServiceInterface, authenticator *Authenticator) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(authenticator.StreamInterceptor),
+	)
+	notesv1.RegisterNoteServiceServer(server, NewNoteServer(noteService))
+	notesv1.RegisterTagServiceServer(server, NewTagServer(tagService, noteService))
+	notesv1.RegisterSearchServiceServer(server, NewSearchServer(noteService))
+	return server
+}
+
+// toStatus converts a service error to a gRPC status, like the REST handlers
+// map errors to HTTP statuses
+func toStatus(err error) error {
+	message := err.Error()
+	var parseErr *search.ParseError
+	switch {
+	case errors.Is(err, services.ErrLegalHold):
+		return status.Error(codes.FailedPrecondition, message)
+	case errors.Is(err, services.ErrVersionMismatch):
+		return status.Error(codes.Aborted, message)
+	case errors.Is(err, apperrors.ErrNotFound):
+		return status.Error(codes.NotFound, message)
+	case errors.Is(err, apperrors.ErrConflict):
+		return status.Error(codes.AlreadyExists, message)
+	case errors.Is(err, apperrors.ErrValidation), errors.As(err, &parseErr):
+		return status.Error(codes.InvalidArgument, message)
+	case errors.Is(err, apperrors.ErrUnavailable):
+		return status.Error(codes.Unavailable, message)
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// pageLimit returns limit defaulted to 20 and capped at 100
+func pageLimit(limit int32) int {
+	if limit <= 0 {
+		return 20
+	}
+	if limit > 100 {
+		return 100
+	}
+	return int(limit)
+}
+
+// noteToProto converts a note response to its protobuf message
+func noteToProto(note *models.NoteResponse) *notesv1.Note {
+	message := &notesv1.Note{
+		Id:               note.ID.String(),
+		Title:            note.Title,
+		Content:          note.Content,
+		Tags:             note.Tags,
+		Version:          int32(note.Version),
+		IsPrivate:        note.IsPrivate,
+		Locked:           note.Locked,
+		Language:         note.Language,
+		AiImproved:       note.AIImproved,
+		CreatedAt:        timestamppb.New(note.CreatedAt),
+		UpdatedAt:        timestamppb.New(note.UpdatedAt),
+		TotalTimeSeconds: note.TotalTimeSeconds,
+	}
+	if note.PrettifiedAt != nil {
+		message.PrettifiedAt = timestamppb.New(*note.PrettifiedAt)
+	}
+	return message
+}
+
+// modelNoteToProto converts a note with the tags in its content
+func modelNoteToProto(note *models.Note) *notesv1.Note {
+	response := note.ToResponse()
+	response.Tags = note.ExtractHashtags()
+	return noteToProto(&response)
+}
+
+// tagToProto converts a tag response to its protobuf message
+func tagToProto(tag *models.TagResponse) *notesv1.Tag {
+	message := &notesv1.Tag{
+		Id:        tag.ID.String(),
+		Name:      tag.Name,
+		NoteCount: int32(tag.NoteCount),
+		CreatedAt: timestamppb.New(tag.CreatedAt),
+	}
+	if tag.ParentID != nil {
+		parentID := tag.ParentID.String()
+		message.ParentId = &parentID
+	}
+	return message
+}
+
+// tagTreeToProto converts tag tree nodes to their protobuf messages
+func tagTreeToProto(nodes []models.TagTreeNode) []*notesv1.TagTreeNode {
+	messages := make([]*notesv1.TagTreeNode, 0, len(nodes))
+	for i := range nodes {
+		messages = append(messages, &notesv1.TagTreeNode{
+			Tag:      tagToProto(&nodes[i].TagResponse),
+			Children: tagTreeToProto(nodes[i].Children),
+		})
+	}
+	return messages
+}
@@ -0,0 +1,268 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	notesv1 "github.com/gpd/my-notes/api/proto/notes/v1"
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testAPIKey = services.APIKeyPrefix + "0123456789abcdef"
+
+// fakeNoteService serves notes from memory; unused methods panic
+type fakeNoteService struct {
+	services.NoteServiceInterface
+	notes    []models.Note
+	searches []*models.SearchNotesRequest
+}
+
+func (s *fakeNoteService) CreateNote(ctx context.Context, userID string, request *models.CreateNoteRequest) (*models.Note, error) {
+	if request.Content == "" {
+		return nil, apperrors.Validation("INVALID_NOTE", "content is required")
+	}
+	note := request.ToNote(uuid.MustParse(userID))
+	note.ID = uuid.New()
+	note.Version = 1
+	s.notes = append(s.notes, *note)
+	return note, nil
+}
+
+func (s *fakeNoteService) GetNoteByID(ctx context.Context, userID, noteID string) (*models.Note, error) {
+	for i := range s.notes {
+		if s.notes[i].ID.String() == noteID && s.notes[i].UserID.String() == userID {
+			return &s.notes[i], nil
+		}
+	}
+	return nil, services.ErrNoteNotFound
+}
+
+func (s *fakeNoteService) UpdateNote(ctx context.Context, userID, noteID string, request *models.UpdateNoteRequest) (*models.Note, error) {
+	note, err := s.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Version != nil && *request.Version != note.Version {
+		return nil, services.ErrVersionMismatch
+	}
+	request.ApplyUpdates(note)
+	note.Version++
+	return note, nil
+}
+
+func (s *fakeNoteService) ListNotes(ctx context.Context, userID string, limit, offset int, orderBy, orderDir string) (*models.NoteList, error) {
+	return s.SearchNotes(ctx, userID, &models.SearchNotesRequest{Limit: limit, Offset: offset})
+}
+
+func (s *fakeNoteService) SearchNotes(ctx context.Context, userID string, request *models.SearchNotesRequest) (*models.NoteList, error) {
+	s.searches = append(s.searches, request)
+	list := &models.NoteList{Notes: []models.NoteResponse{}, Total: len(s.notes)}
+	for i := request.Offset; i < len(s.notes) && len(list.Notes) < request.Limit; i++ {
+		list.Notes = append(list.Notes, s.notes[i].ToResponse())
+	}
+	list.HasMore = request.Offset+len(list.Notes) < len(s.notes)
+	return list, nil
+}
+
+// fakeAPIKeyService accepts testAPIKey for its user
+type fakeAPIKeyService struct {
+	services.APIKeyServiceInterface
+	userID string
+}
+
+func (s *fakeAPIKeyService) Authenticate(ctx context.Context, key string) (string, error) {
+	if key != testAPIKey {
+		return "", services.ErrInvalidAPIKey
+	}
+	return s.userID, nil
+}
+
+// fakeUserService returns the users it holds
+type fakeUserService struct {
+	services.UserServiceInterface
+	users map[string]*models.User
+}
+
+func (s *fakeUserService) GetByID(ctx context.Context, userID string) (*models.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+type testEnv struct {
+	user         *models.User
+	noteService  *fakeNoteService
+	tokenService *auth.TokenService
+	conn         *grpc.ClientConn
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	user := &models.User{ID: uuid.New(), Email: "grpc@example.com"}
+	env := &testEnv{
+		user:         user,
+		noteService:  &fakeNoteService{},
+		tokenService: auth.NewTokenService("test-secret-key-for-grpc-tests-only", time.Hour, time.Hour, "silence-notes", "silence-notes-users"),
+	}
+	authenticator := NewAuthenticator(env.tokenService,
+		&fakeAPIKeyService{userID: user.ID.String()},
+		&fakeUserService{users: map[string]*models.User{user.ID.String(): user}})
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(env.noteService, nil, authenticator)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	env.conn = conn
+	return env
+}
+
+// withToken returns a context authenticated with token
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthentication(t *testing.T) {
+	env := newTestEnv(t)
+	client := notesv1.NewNoteServiceClient(env.conn)
+	request := &notesv1.CreateNoteRequest{Content: "Hello #grpc"}
+
+	tokens, err := env.tokenService.GenerateTokenPair(env.user)
+	if err != nil {
+		t.Fatalf("Failed to generate tokens: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"no metadata", context.Background(), codes.Unauthenticated},
+		{"invalid token", withToken("not-a-token"), codes.Unauthenticated},
+		{"refresh token", withToken(tokens.RefreshToken), codes.Unauthenticated},
+		{"revoked API key", withToken(services.APIKeyPrefix + "revoked"), codes.Unauthenticated},
+		{"access token", withToken(tokens.AccessToken), codes.OK},
+		{"API key", withToken(testAPIKey), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.CreateNote(tt.ctx, request)
+			if got := status.Code(err); got != tt.code {
+				t.Errorf("Expected %v, got %v (%v)", tt.code, got, err)
+			}
+		})
+	}
+
+	env.user.ReadOnlyUntil = func() *time.Time { until := time.Now().Add(time.Hour); return &until }()
+	if _, err := client.CreateNote(withToken(testAPIKey), request); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected writes of read-only accounts to be rejected, got %v", err)
+	}
+	if _, err := client.ListNotes(withToken(testAPIKey), &notesv1.ListNotesRequest{}); status.Code(err) == codes.FailedPrecondition {
+		t.Errorf("Expected reads of read-only accounts to be allowed")
+	}
+}
+
+func TestNoteService(t *testing.T) {
+	env := newTestEnv(t)
+	client := notesv1.NewNoteServiceClient(env.conn)
+	ctx := withToken(testAPIKey)
+
+	created, err := client.CreateNote(ctx, &notesv1.CreateNoteRequest{Content: "Plan the #work/offsite"})
+	if err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	if created.GetTitle() != "Plan the #work/offsite" || len(created.GetTags()) != 1 || created.GetVersion() != 1 {
+		t.Errorf("Unexpected note %v", created)
+	}
+
+	if _, err := client.CreateNote(ctx, &notesv1.CreateNoteRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected an invalid note to be rejected, got %v", err)
+	}
+
+	got, err := client.GetNote(ctx, &notesv1.GetNoteRequest{Id: created.GetId()})
+	if err != nil || got.GetContent() != created.GetContent() {
+		t.Errorf("GetNote returned %v, %v", got, err)
+	}
+	if _, err := client.GetNote(ctx, &notesv1.GetNoteRequest{Id: uuid.NewString()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+
+	content := "Plan the #work/offsite in May"
+	stale := int32(5)
+	if _, err := client.UpdateNote(ctx, &notesv1.UpdateNoteRequest{Id: created.GetId(), Content: &content, Version: &stale}); status.Code(err) != codes.Aborted {
+		t.Errorf("Expected a version conflict to abort, got %v", err)
+	}
+	current := created.GetVersion()
+	updated, err := client.UpdateNote(ctx, &notesv1.UpdateNoteRequest{Id: created.GetId(), Content: &content, Version: &current})
+	if err != nil || updated.GetContent() != content || updated.GetVersion() != 2 {
+		t.Errorf("UpdateNote returned %v, %v", updated, err)
+	}
+}
+
+func TestSearchNotesStreamsPages(t *testing.T) {
+	env := newTestEnv(t)
+	for i := 0; i < 250; i++ {
+		env.noteService.notes = append(env.noteService.notes, models.Note{
+			ID: uuid.New(), UserID: env.user.ID, Content: fmt.Sprintf("note %d", i),
+		})
+	}
+	client := notesv1.NewSearchServiceClient(env.conn)
+
+	count := func(request *notesv1.SearchNotesRequest) int {
+		stream, err := client.SearchNotes(withToken(testAPIKey), request)
+		if err != nil {
+			t.Fatalf("SearchNotes failed: %v", err)
+		}
+		received := 0
+		for {
+			_, err := stream.Recv()
+			if err == io.EOF {
+				return received
+			}
+			if err != nil {
+				t.Fatalf("Failed to receive: %v", err)
+			}
+			received++
+		}
+	}
+
+	if got := count(&notesv1.SearchNotesRequest{Query: "note", Limit: 1000}); got != 250 {
+		t.Errorf("Expected every match to be streamed, got %d", got)
+	}
+	if got := len(env.noteService.searches); got != 3 {
+		t.Errorf("Expected 3 pages to be fetched, got %d", got)
+	}
+
+	if got := count(&notesv1.SearchNotesRequest{Query: "note", Limit: 150}); got != 150 {
+		t.Errorf("Expected the limit to be respected, got %d", got)
+	}
+	if got := count(&notesv1.SearchNotesRequest{Query: "note"}); got != defaultSearchLimit {
+		t.Errorf("Expected %d results by default, got %d", defaultSearchLimit, got)
+	}
+}
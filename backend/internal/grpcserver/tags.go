@@ -0,0 +1,107 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	notesv1 "github.com/gpd/my-notes/api/proto/notes/v1"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamPageSize is the number of notes fetched per page while streaming
+const streamPageSize = 100
+
+// TagServer implements notesv1.TagServiceServer
+type TagServer struct {
+	notesv1.UnimplementedTagServiceServer
+	tagService  services.TagServiceInterface
+	noteService services.NoteServiceInterface
+}
+
+// NewTagServer creates a new TagServer
+func NewTagServer(tagService services.TagServiceInterface, noteService services.NoteServiceInterface) *TagServer {
+	return &TagServer{
+		tagService:  tagService,
+		noteService: noteService,
+	}
+}
+
+// ListTags returns a page of the user's tags
+func (s *TagServer) ListTags(ctx context.Context, request *notesv1.ListTagsRequest) (*notesv1.ListTagsResponse, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	offset := int(request.GetOffset())
+	if offset < 0 {
+		offset = 0
+	}
+
+	list, err := s.tagService.GetAllTags(ctx, user.ID.String(), pageLimit(request.GetLimit()), offset)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &notesv1.ListTagsResponse{
+		Tags:    make([]*notesv1.Tag, 0, len(list.Tags)),
+		Total:   int32(list.Total),
+		HasMore: list.HasMore,
+	}
+	for i := range list.Tags {
+		response.Tags = append(response.Tags, tagToProto(&list.Tags[i]))
+	}
+	return response, nil
+}
+
+// GetTagTree returns the user's tags nested under their parents
+func (s *TagServer) GetTagTree(ctx context.Context, request *notesv1.GetTagTreeRequest) (*notesv1.GetTagTreeResponse, error) {
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tag := strings.TrimSpace(request.GetTag())
+	if tag != "" {
+		tag = search.NormalizeTag(tag)
+	}
+
+	tree, err := s.tagService.GetTagTree(ctx, user.ID.String(), tag)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &notesv1.GetTagTreeResponse{Roots: tagTreeToProto(tree)}, nil
+}
+
+// ListNotesByTag streams every note with a tag, a page at a time
+func (s *TagServer) ListNotesByTag(request *notesv1.ListNotesByTagRequest, stream grpc.ServerStreamingServer[notesv1.Note]) error {
+	ctx := stream.Context()
+	user, err := userFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	tag := strings.TrimSpace(request.GetTag())
+	if tag == "" {
+		return status.Error(codes.InvalidArgument, "Tag is required")
+	}
+	if !strings.HasPrefix(tag, "#") {
+		tag = "#" + tag
+	}
+
+	for offset := 0; ; offset += streamPageSize {
+		list, err := s.noteService.GetNotesByTag(ctx, user.ID.String(), tag, request.GetIncludeChildren(), streamPageSize, offset)
+		if err != nil {
+			return toStatus(err)
+		}
+		for i := range list.Notes {
+			if err := stream.Send(noteToProto(&list.Notes[i])); err != nil {
+				return err
+			}
+		}
+		if !list.HasMore || len(list.Notes) == 0 {
+			return nil
+		}
+	}
+}
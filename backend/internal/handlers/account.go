@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// AccountHandler handles account-level HTTP requests
+type AccountHandler struct {
+	userService         services.UserServiceInterface
+	confirmationService services.ConfirmationServiceInterface
+}
+
+// AccountDeletionResponse reports the outcome of deleting an account.
+// DeletionScheduledAt is set while the account can still be restored.
+type AccountDeletionResponse struct {
+	Message             string     `json:"message"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+// NewAccountHandler creates a new AccountHandler instance
+func NewAccountHandler(userService services.UserServiceInterface, confirmationService services.ConfirmationServiceInterface) *AccountHandler {
+	return &AccountHandler{
+		userService:         userService,
+		confirmationService: confirmationService,
+	}
+}
+
+// DeleteAccount handles DELETE /api/v1/account
+// Deleting an account always requires a confirmation code. The account is
+// purged once the deletion grace period ends, or at once without one.
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	confirmationID, code := confirmationFromRequest(r)
+	err := h.confirmationService.Require(r.Context(), user.ID, models.OperationDeleteAccount,
+		"account:"+user.ID.String(), 1, confirmationID, code)
+	if err != nil {
+		if !respondWithConfirmationError(w, err) {
+			respondWithAppError(w, err)
+		}
+		return
+	}
+
+	scheduledAt, err := h.userService.ScheduleDeletion(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	response := AccountDeletionResponse{Message: "Account deleted successfully"}
+	if scheduledAt != nil {
+		response.Message = "Account scheduled for deletion"
+		response.DeletionScheduledAt = scheduledAt
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// RestoreAccount handles POST /api/v1/account/restore
+// Cancels a scheduled deletion while the grace period lasts
+func (h *AccountHandler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.userService.CancelDeletion(r.Context(), user.ID.String()); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Account restored successfully"})
+}
+
+// GetSettings handles GET /api/v1/account/settings
+func (h *AccountHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	settings, err := h.userService.GetSettings(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /api/v1/account/settings
+func (h *AccountHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.UpdateUserSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := request.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	settings, err := h.userService.UpdateSettings(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
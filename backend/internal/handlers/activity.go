@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+)
+
+// recordActivity records an account activity event for anomaly detection.
+// Recording is best effort and never fails the request.
+func recordActivity(r *http.Request, activityService services.ActivityServiceInterface, userID uuid.UUID, eventType string, count int) {
+	if activityService == nil {
+		return
+	}
+
+	ipAddress, _ := r.Context().Value("clientIP").(string)
+	country, _ := r.Context().Value("clientCountry").(string)
+	if err := activityService.Record(r.Context(), userID, eventType, count, ipAddress, country); err != nil {
+		log.Printf("ERROR: failed to record %s activity for user %s: %v", eventType, userID, err)
+	}
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// PromptRegistry holds the LLM prompt templates
+type PromptRegistry interface {
+	Status() *prompts.Status
+	Reload() (*prompts.Status, error)
+}
+
+// PromptCache drops the cached LLM responses of features whose prompts changed
+type PromptCache interface {
+	InvalidateFeatures(ctx context.Context, features ...string) (int64, error)
+}
+
+// AdminHandler handles administrator HTTP requests
+type AdminHandler struct {
+	anomalyService   services.AnomalyServiceInterface
+	legalHoldService services.LegalHoldServiceInterface
+	adminService     services.AdminServiceInterface
+	prompts          PromptRegistry
+	promptCache      PromptCache
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(anomalyService services.AnomalyServiceInterface, legalHoldService services.LegalHoldServiceInterface) *AdminHandler {
+	return &AdminHandler{
+		anomalyService:   anomalyService,
+		legalHoldService: legalHoldService,
+	}
+}
+
+// SetAdminService sets the service behind user management and maintenance
+func (h *AdminHandler) SetAdminService(adminService services.AdminServiceInterface) {
+	h.adminService = adminService
+}
+
+// SetPrompts sets the prompt registry to manage. Responses cached in cache,
+// which may be nil, are dropped when a reload changes their prompt.
+func (h *AdminHandler) SetPrompts(registry PromptRegistry, cache PromptCache) {
+	h.prompts = registry
+	h.promptCache = cache
+}
+
+// ListAnomalies handles GET /api/v1/admin/anomalies
+func (h *AdminHandler) ListAnomalies(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	status := r.URL.Query().Get("status")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	list, err := h.anomalyService.ListAnomalies(r.Context(), status, limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// ReviewAnomaly handles POST /api/v1/admin/anomalies/{id}/review
+func (h *AdminHandler) ReviewAnomaly(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	anomalyID := mux.Vars(r)["id"]
+	if anomalyID == "" {
+		respondWithError(w, http.StatusBadRequest, "Anomaly ID is required")
+		return
+	}
+
+	var request models.ReviewAnomalyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	anomaly, err := h.anomalyService.ReviewAnomaly(r.Context(), user.ID, anomalyID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, anomaly)
+}
+
+// ListLegalHolds handles GET /api/v1/admin/legal-holds
+func (h *AdminHandler) ListLegalHolds(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	activeOnly := r.URL.Query().Get("active") == "true"
+
+	holds, err := h.legalHoldService.ListHolds(r.Context(), userID, activeOnly)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"legal_holds": holds,
+		"count":       len(holds),
+	})
+}
+
+// CreateLegalHold handles POST /api/v1/admin/legal-holds
+func (h *AdminHandler) CreateLegalHold(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.CreateLegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	hold, err := h.legalHoldService.ApplyHold(r.Context(), user.ID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, hold)
+}
+
+// ReleaseLegalHold handles POST /api/v1/admin/legal-holds/{id}/release
+func (h *AdminHandler) ReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	holdID := mux.Vars(r)["id"]
+	if holdID == "" {
+		respondWithError(w, http.StatusBadRequest, "Legal hold ID is required")
+		return
+	}
+
+	var request models.ReleaseLegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	hold, err := h.legalHoldService.ReleaseHold(r.Context(), user.ID, holdID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, hold)
+}
+
+// GetLegalHoldAudit handles GET /api/v1/admin/legal-holds/{id}/audit
+func (h *AdminHandler) GetLegalHoldAudit(w http.ResponseWriter, r *http.Request) {
+	holdID := mux.Vars(r)["id"]
+	if holdID == "" {
+		respondWithError(w, http.StatusBadRequest, "Legal hold ID is required")
+		return
+	}
+
+	entries, err := h.legalHoldService.GetAuditLog(r.Context(), holdID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+	})
+}
+
+// ListUsers handles GET /api/v1/admin/users
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	list, err := h.adminService.ListUsers(r.Context(), query, limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// GetUser handles GET /api/v1/admin/users/{id}
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	user, err := h.adminService.GetUser(r.Context(), userID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// DisableUser handles POST /api/v1/admin/users/{id}/disable
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	admin, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	userID := mux.Vars(r)["id"]
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	// The reason is optional, so an empty body is accepted
+	var request models.DisableUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.adminService.DisableUser(r.Context(), admin.ID, userID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// EnableUser handles POST /api/v1/admin/users/{id}/enable
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	user, err := h.adminService.EnableUser(r.Context(), userID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// SetUserRole handles PUT /api/v1/admin/users/{id}/role
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	admin, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	userID := mux.Vars(r)["id"]
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var request models.SetUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	user, err := h.adminService.SetUserRole(r.Context(), admin.ID, userID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// ListMaintenanceTasks handles GET /api/v1/admin/maintenance
+func (h *AdminHandler) ListMaintenanceTasks(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks": h.adminService.MaintenanceTasks(),
+	})
+}
+
+// RunMaintenanceTask handles POST /api/v1/admin/maintenance/{task}
+func (h *AdminHandler) RunMaintenanceTask(w http.ResponseWriter, r *http.Request) {
+	task := mux.Vars(r)["task"]
+
+	result, err := h.adminService.RunMaintenanceTask(r.Context(), task)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	log.Printf("[RunMaintenanceTask] %s affected %d rows", result.Task, result.Affected)
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// ListPrompts handles GET /api/v1/admin/prompts
+func (h *AdminHandler) ListPrompts(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.prompts.Status())
+}
+
+// ReloadPrompts handles POST /api/v1/admin/prompts/reload
+func (h *AdminHandler) ReloadPrompts(w http.ResponseWriter, r *http.Request) {
+	status, err := h.prompts.Reload()
+	if err != nil {
+		respondWithAppError(w, apperrors.Wrap(apperrors.ErrValidation, "INVALID_PROMPT", err))
+		return
+	}
+
+	if h.promptCache != nil && len(status.Changed) > 0 {
+		if _, err := h.promptCache.InvalidateFeatures(r.Context(), status.Changed...); err != nil {
+			log.Printf("[ReloadPrompts] WARNING: Failed to invalidate cached responses: %v", err)
+		}
+	}
+
+	log.Printf("[ReloadPrompts] Reloaded prompts, changed: %v", status.Changed)
+	respondWithJSON(w, http.StatusOK, status)
+}
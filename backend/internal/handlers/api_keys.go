@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/anomaly"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// APIKeysHandler handles API key HTTP requests
+type APIKeysHandler struct {
+	apiKeyService   services.APIKeyServiceInterface
+	activityService services.ActivityServiceInterface
+}
+
+// NewAPIKeysHandler creates a new APIKeysHandler instance
+func NewAPIKeysHandler(apiKeyService services.APIKeyServiceInterface) *APIKeysHandler {
+	return &APIKeysHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// SetActivityService sets the service recording key creation for anomaly detection
+func (h *APIKeysHandler) SetActivityService(activityService services.ActivityServiceInterface) {
+	h.activityService = activityService
+}
+
+// CreateAPIKey handles POST /api/v1/api-keys
+// The key is only returned in this response.
+func (h *APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	key, err := h.apiKeyService.CreateKey(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordActivity(r, h.activityService, user.ID, anomaly.EventAPIKeyCreated, 1)
+
+	respondWithJSON(w, http.StatusCreated, key)
+}
+
+// ListAPIKeys handles GET /api/v1/api-keys
+func (h *APIKeysHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"api_keys": keys,
+		"total":    len(keys),
+	})
+}
+
+// RevokeAPIKey handles DELETE /api/v1/api-keys/{id}
+func (h *APIKeysHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	keyID := mux.Vars(r)["id"]
+	if keyID == "" {
+		respondWithError(w, http.StatusBadRequest, "API key ID is required")
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(r.Context(), user.ID.String(), keyID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "API key revoked successfully"})
+}
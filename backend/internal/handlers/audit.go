@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+)
+
+// AuditHandler handles audit log HTTP requests
+type AuditHandler struct {
+	auditService services.AuditServiceInterface
+}
+
+// NewAuditHandler creates a new AuditHandler instance
+func NewAuditHandler(auditService services.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLog handles GET /api/v1/audit
+func (h *AuditHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	filter, ok := auditFilterFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.UserID = user.ID.String()
+
+	h.respondWithAuditLog(w, r, filter)
+}
+
+// ListAllAuditLog handles GET /api/v1/admin/audit
+func (h *AuditHandler) ListAllAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilterFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter.UserID = r.URL.Query().Get("user_id")
+
+	h.respondWithAuditLog(w, r, filter)
+}
+
+// respondWithAuditLog sends the page of audit entries matching filter
+func (h *AuditHandler) respondWithAuditLog(w http.ResponseWriter, r *http.Request, filter models.AuditLogFilter) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	list, err := h.auditService.List(r.Context(), filter, limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// auditFilterFromRequest reads the action, since and until query parameters.
+// It responds with an error and returns false for invalid timestamps.
+func auditFilterFromRequest(w http.ResponseWriter, r *http.Request) (models.AuditLogFilter, bool) {
+	filter := models.AuditLogFilter{Action: r.URL.Query().Get("action")}
+
+	for name, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid "+name+" timestamp, expected RFC 3339")
+			return filter, false
+		}
+		*target = &t
+	}
+
+	return filter, true
+}
+
+// recordAudit appends an action on notes to the audit log, with the client
+// the request came from. noteID is nil for bulk actions. Recording is best
+// effort and never fails the request.
+func recordAudit(r *http.Request, auditService services.AuditServiceInterface, userID uuid.UUID, action string, noteID *uuid.UUID, count int) {
+	if auditService == nil {
+		return
+	}
+
+	ipAddress, _ := r.Context().Value("clientIP").(string)
+	entry := &models.AuditEntry{
+		UserID:       userID,
+		Action:       action,
+		ResourceType: models.AuditResourceNote,
+		ResourceID:   noteID,
+		Count:        count,
+		IPAddress:    ipAddress,
+		UserAgent:    r.UserAgent(),
+	}
+	if err := auditService.Record(r.Context(), entry); err != nil {
+		log.Printf("ERROR: failed to record %s audit entry for user %s: %v", action, userID, err)
+	}
+}
@@ -3,14 +3,20 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/auth"
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
 	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
 )
 
 // BlacklistAdder adds tokens to the blacklist
@@ -20,9 +26,11 @@ type BlacklistAdder interface {
 
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
-	tokenService *auth.TokenService
-	userService  services.UserServiceInterface
-	blacklist    BlacklistAdder // optional blacklist adder
+	tokenService   *auth.TokenService
+	userService    services.UserServiceInterface
+	blacklist      BlacklistAdder                   // optional blacklist adder
+	sessionService services.SessionServiceInterface // optional, enables refresh token rotation
+	loginGuard     *auth.LoginGuard                 // optional, slows down failed refreshes
 }
 
 // NewAuthHandler creates a new AuthHandler instance
@@ -41,6 +49,17 @@ func (h *AuthHandler) SetBlacklist(blacklist BlacklistAdder) {
 	h.blacklist = blacklist
 }
 
+// SetSessionService sets the service rotating refresh tokens and managing sessions
+func (h *AuthHandler) SetSessionService(sessionService services.SessionServiceInterface) {
+	h.sessionService = sessionService
+}
+
+// SetLoginGuard sets the guard slowing down repeated failed refreshes from an
+// IP address and refreshes for users locked out by failed sign-ins
+func (h *AuthHandler) SetLoginGuard(loginGuard *auth.LoginGuard) {
+	h.loginGuard = loginGuard
+}
+
 // RefreshToken handles POST /api/v1/auth/refresh
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req auth.RefreshTokenRequest
@@ -55,27 +74,90 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ipKey := ""
+	if h.loginGuard != nil {
+		ipAddress, _ := clientDevice(r)
+		ipKey = auth.IPKey(ipAddress)
+		if wait := h.loginGuard.Wait(ipKey); wait > 0 {
+			respondLoginBlocked(w, wait)
+			return
+		}
+	}
+
 	// Validate refresh token
 	claims, err := h.tokenService.ValidateRefreshToken(r.Context(), req.RefreshToken)
 	if err != nil {
+		if h.loginGuard != nil {
+			h.loginGuard.Fail(ipKey)
+		}
 		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
 		return
 	}
 
+	userKey := auth.UserKey(claims.UserID)
+	if h.loginGuard != nil {
+		if wait := h.loginGuard.Wait(userKey); wait > 0 {
+			respondLoginBlocked(w, wait)
+			return
+		}
+	}
+
 	// Get user from database
-	user, err := h.userService.GetByID(claims.UserID)
+	user, err := h.userService.GetByID(r.Context(), claims.UserID)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "User not found")
 		return
 	}
 
-	// Generate new token pair
-	tokenPair, err := h.tokenService.GenerateTokenPair(user)
+	if user.IsDisabled() {
+		respondWithError(w, http.StatusForbidden, "Account has been disabled")
+		return
+	}
+
+	// Sessions that signed in before two-factor authentication was enabled
+	// must sign in again with a code
+	if !auth.TwoFactorSatisfied(claims, user) {
+		respondWithAppError(w, services.ErrTwoFactorRequired)
+		return
+	}
+
+	// Generate new token pair for the same session, keeping its two-factor
+	// authentication
+	var tokenPair *auth.TokenPair
+	if claims.TwoFactor {
+		tokenPair, err = h.tokenService.GenerateTwoFactorTokenPair(user, claims.SessionID)
+	} else {
+		tokenPair, err = h.tokenService.GenerateTokenPairWithSession(user, claims.SessionID)
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate tokens")
 		return
 	}
 
+	// Rotate the session's refresh token so the presented one cannot be used again
+	if h.sessionService != nil {
+		err := h.sessionService.RotateRefreshToken(r.Context(), user.ID.String(), claims.SessionID, claims.ID, tokenPair.RefreshTokenID)
+		// A replayed token may be stolen, so it counts against the address
+		// presenting it. It is not a guess at the user's credentials, and
+		// neither is a refresh of an ended session, so the user is not
+		// held back by either.
+		if h.loginGuard != nil && errors.Is(err, services.ErrRefreshTokenReused) {
+			h.loginGuard.Fail(ipKey)
+		}
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			log.Printf("WARNING: refresh token reuse for session %s of user %s, session revoked", claims.SessionID, user.ID)
+			respondWithError(w, http.StatusUnauthorized, "Refresh token has already been used; the session has been revoked")
+			return
+		case errors.Is(err, services.ErrSessionNotFound):
+			respondWithError(w, http.StatusUnauthorized, "Session has ended")
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Failed to refresh session")
+			return
+		}
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"access_token":  tokenPair.AccessToken,
 		"refresh_token": tokenPair.RefreshToken,
@@ -111,11 +193,65 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// End the session so its refresh token is no longer accepted
+	if h.sessionService != nil {
+		err := h.sessionService.RevokeSession(r.Context(), user.ID.String(), claims.SessionID, services.SessionRevokedLogout)
+		if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+			log.Printf("WARNING: failed to end session during logout: %v", err)
+		}
+	}
+
 	respondWithJSON(w, http.StatusOK, map[string]string{
 		"message": "Successfully logged out",
 	})
 }
 
+// ListSessions handles GET /api/v1/auth/sessions
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+	currentSessionID, _ := r.Context().Value("sessionID").(string)
+
+	sessions, err := h.sessionService.ListSessions(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	responses := make([]models.UserSessionResponse, len(sessions))
+	for i := range sessions {
+		responses[i] = sessions[i].ToResponse()
+		responses[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	respondWithJSON(w, http.StatusOK, responses)
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/{id}
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.sessionService.RevokeSession(r.Context(), user.ID.String(), mux.Vars(r)["id"], services.SessionRevokedByUser)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		respondWithError(w, http.StatusNotFound, "Session not found")
+		return
+	} else if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Session revoked successfully"})
+}
+
 // ValidateToken handles GET /api/v1/auth/validate
 func (h *AuthHandler) ValidateToken(w http.ResponseWriter, r *http.Request) {
 	// This endpoint is protected by auth middleware, so if we reach here,
@@ -142,42 +278,56 @@ func (h *AuthHandler) ValidateToken(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// respondWithError sends an error response
 // HTTP error codes
 const (
-	ErrCodeBadRequest    = "BAD_REQUEST"
-	ErrCodeUnauthorized  = "UNAUTHORIZED"
-	ErrCodeForbidden     = "FORBIDDEN"
-	ErrCodeNotFound      = "NOT_FOUND"
-	ErrCodeConflict      = "CONFLICT"
-	ErrCodeInternalError = "INTERNAL_ERROR"
+	ErrCodeBadRequest           = apperrors.CodeBadRequest
+	ErrCodeUnauthorized         = apperrors.CodeUnauthorized
+	ErrCodeForbidden            = apperrors.CodeForbidden
+	ErrCodeNotFound             = apperrors.CodeNotFound
+	ErrCodeConflict             = apperrors.CodeConflict
+	ErrCodeInternalError        = apperrors.CodeInternal
+	ErrCodeInvalidQuery         = "INVALID_QUERY"
+	ErrCodeConfirmationRequired = "CONFIRMATION_REQUIRED"
+	ErrCodeCursorExpired        = "CURSOR_EXPIRED"
+	ErrCodePreconditionFailed   = apperrors.CodePreconditionFailed
+	ErrCodeValidationFailed     = "VALIDATION_FAILED"
 )
 
-// respondWithError sends an error response with standard format
+// respondWithError sends an error response with standard format, coded after
+// its HTTP status
 func respondWithError(w http.ResponseWriter, code int, message string) {
-	errorCode := ErrCodeInternalError
-	details := ""
-
-	// Map HTTP status codes to error codes
-	switch code {
-	case http.StatusBadRequest:
-		errorCode = ErrCodeBadRequest
-	case http.StatusUnauthorized:
-		errorCode = ErrCodeUnauthorized
-	case http.StatusForbidden:
-		errorCode = ErrCodeForbidden
-	case http.StatusNotFound:
-		errorCode = ErrCodeNotFound
-	case http.StatusConflict:
-		errorCode = ErrCodeConflict
-	}
-
-	// If message contains details (separated by ": "), split them
-	if parts := strings.SplitN(message, ": ", 2); len(parts) == 2 {
-		message = parts[0]
-		details = parts[1]
+	writeError(w, code, apperrors.CodeForStatus(code), message)
+}
+
+// respondLoginBlocked tells a client held back by the login guard how long to
+// wait before trying again
+func respondLoginBlocked(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, http.StatusTooManyRequests, "Too many failed sign-in attempts, try again later")
+}
+
+// respondWithAppError sends the response for an error returned by a service.
+// Errors of a known kind get its status and their code; any other error is
+// logged and hidden behind a 500.
+func respondWithAppError(w http.ResponseWriter, err error) {
+	status := apperrors.Status(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("Internal error: %v", err)
+		writeError(w, status, ErrCodeInternalError, "Internal server error")
+		return
 	}
 
+	message := err.Error()
+	if message != "" {
+		message = strings.ToUpper(message[:1]) + message[1:]
+	}
+	writeError(w, status, apperrors.Code(err), message)
+}
+
+// writeError writes an error envelope. Details follow the first ": " of the
+// message.
+func writeError(w http.ResponseWriter, status int, errorCode, message string) {
+	message, details := splitErrorDetails(message)
 	apiResponse := models.NewAPIErrorResponse(errorCode, message, details)
 
 	response, err := json.Marshal(apiResponse)
@@ -190,7 +340,50 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// splitErrorDetails splits an error message at its first ": " into the
+// message and its details
+func splitErrorDetails(message string) (string, string) {
+	if parts := strings.SplitN(message, ": ", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return message, ""
+}
+
+// appErrorBody returns the error respondWithAppError sends for err, for
+// responses listing the outcomes of several items such as partial batches
+func appErrorBody(err error) *models.APIError {
+	if apperrors.Status(err) == http.StatusInternalServerError {
+		log.Printf("Internal error: %v", err)
+		return &models.APIError{Code: ErrCodeInternalError, Message: "Internal server error"}
+	}
+
+	message := err.Error()
+	if message != "" {
+		message = strings.ToUpper(message[:1]) + message[1:]
+	}
+	message, details := splitErrorDetails(message)
+	return &models.APIError{Code: apperrors.Code(err), Message: message, Details: details}
+}
+
+// respondWithQueryError sends a 400 response locating a search query syntax error
+func respondWithQueryError(w http.ResponseWriter, parseErr *search.ParseError) {
+	position := parseErr.Position
+	apiResponse := models.NewAPIErrorResponse(ErrCodeInvalidQuery, "Invalid search query", parseErr.Message)
+	apiResponse.Error.Position = &position
+	apiResponse.Error.Length = parseErr.Length
+
+	response, err := json.Marshal(apiResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to marshal response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
 	w.Write(response)
 }
 
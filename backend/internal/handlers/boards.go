@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// BoardsHandler handles kanban board HTTP requests
+type BoardsHandler struct {
+	boardService services.BoardServiceInterface
+	auditService services.AuditServiceInterface
+}
+
+// NewBoardsHandler creates a new BoardsHandler instance
+func NewBoardsHandler(boardService services.BoardServiceInterface) *BoardsHandler {
+	return &BoardsHandler{
+		boardService: boardService,
+	}
+}
+
+// SetAuditService sets the service recording card moves as note updates
+func (h *BoardsHandler) SetAuditService(auditService services.AuditServiceInterface) {
+	h.auditService = auditService
+}
+
+// CreateBoard handles POST /api/v1/boards
+func (h *BoardsHandler) CreateBoard(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateBoardRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	board, err := h.boardService.CreateBoard(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, board)
+}
+
+// ListBoards handles GET /api/v1/boards
+func (h *BoardsHandler) ListBoards(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	boards, err := h.boardService.ListBoards(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"boards": boards,
+		"total":  len(boards),
+	})
+}
+
+// GetBoard handles GET /api/v1/boards/{id}?limit=50
+// Returns the board's notes grouped by column
+func (h *BoardsHandler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	view, err := h.boardService.GetBoard(r.Context(), user.ID.String(), mux.Vars(r)["id"], limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, view)
+}
+
+// DeleteBoard handles DELETE /api/v1/boards/{id}
+func (h *BoardsHandler) DeleteBoard(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.boardService.DeleteBoard(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Board deleted successfully"})
+}
+
+// MoveCard handles POST /api/v1/boards/{id}/move
+// Moves a note to a column by swapping its status tag
+func (h *BoardsHandler) MoveCard(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.MoveCardRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	note, err := h.boardService.MoveCard(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordAudit(r, h.auditService, user.ID, models.AuditActionUpdate, &note.ID, 1)
+
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+	w.Header().Set(HeaderETag, noteETag(note.Version))
+	respondWithJSON(w, http.StatusOK, noteResponse)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// CalendarHandler handles notes calendar HTTP requests
+type CalendarHandler struct {
+	calendarService     services.CalendarServiceInterface
+	calendarFeedService services.CalendarFeedServiceInterface
+}
+
+// NewCalendarHandler creates a new CalendarHandler instance
+func NewCalendarHandler(calendarService services.CalendarServiceInterface) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+	}
+}
+
+// SetCalendarFeedService enables the iCal feed of due dates and recurring
+// notes
+func (h *CalendarHandler) SetCalendarFeedService(calendarFeedService services.CalendarFeedServiceInterface) {
+	h.calendarFeedService = calendarFeedService
+}
+
+// GetCalendar handles GET /api/v1/calendar?from=2024-03-01&to=2024-03-31&tz=Europe/Berlin
+// The range defaults to the current month in the time zone, which defaults to UTC
+func (h *CalendarHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+	request := &models.CalendarRequest{
+		Location:    time.UTC,
+		NotesPerDay: models.DefaultCalendarNotesPerDay,
+	}
+
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid time zone")
+			return
+		}
+		request.Location = loc
+	}
+
+	// Default to the current month
+	now := time.Now().In(request.Location)
+	request.From = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, request.Location)
+	request.To = request.From.AddDate(0, 1, -1)
+	if from := query.Get("from"); from != "" {
+		parsed, err := time.ParseInLocation(search.DateLayout, from, request.Location)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+		request.From = parsed
+	}
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.ParseInLocation(search.DateLayout, to, request.Location)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		request.To = parsed
+	}
+
+	if value := query.Get("notes_per_day"); value != "" {
+		perDay, err := strconv.Atoi(value)
+		if err != nil || perDay < 0 || perDay > models.MaxCalendarNotesPerDay {
+			respondWithError(w, http.StatusBadRequest, "notes_per_day must be between 0 and "+strconv.Itoa(models.MaxCalendarNotesPerDay))
+			return
+		}
+		request.NotesPerDay = perDay
+	}
+
+	calendar, err := h.calendarService.GetCalendar(r.Context(), user.ID.String(), request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, calendar)
+}
+
+// GetCalendarFeed handles GET /api/v1/calendar/feed
+func (h *CalendarHandler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	feed, err := h.calendarFeedService.GetFeed(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feed)
+}
+
+// EnableCalendarFeed handles POST /api/v1/calendar/feed
+// The response holds the feed token and URL path, which are only shown once;
+// enabling again replaces the token
+func (h *CalendarHandler) EnableCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	feed, err := h.calendarFeedService.EnableFeed(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, feed)
+}
+
+// DisableCalendarFeed handles DELETE /api/v1/calendar/feed
+func (h *CalendarHandler) DisableCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.calendarFeedService.DisableFeed(r.Context(), user.ID.String()); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Calendar feed disabled successfully"})
+}
+
+// ServeCalendarFeed handles GET /api/v1/calendar.ics?token=..., the iCal
+// feed calendar apps subscribe to, authenticated by the token
+func (h *CalendarHandler) ServeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	events, err := h.calendarFeedService.GetFeedEvents(r.Context(), r.URL.Query().Get("token"))
+	if errors.Is(err, services.ErrCalendarFeedNotFound) {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Internal error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(models.FormatICalendar("Silence Notes", events)))
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// CaptureHandler handles notes captured by automation tools, browser
+// extensions and email forwarding, authenticated by API key
+type CaptureHandler struct {
+	noteService      services.NoteServiceInterface
+	dailyNoteService services.DailyNoteServiceInterface
+	webClipService   services.WebClipServiceInterface
+}
+
+// NewCaptureHandler creates a new CaptureHandler instance
+func NewCaptureHandler(noteService services.NoteServiceInterface) *CaptureHandler {
+	return &CaptureHandler{
+		noteService: noteService,
+	}
+}
+
+// SetDailyNoteService enables captures appended to today's daily note
+func (h *CaptureHandler) SetDailyNoteService(dailyNoteService services.DailyNoteServiceInterface) {
+	h.dailyNoteService = dailyNoteService
+}
+
+// SetWebClipService enables clipping web pages
+func (h *CaptureHandler) SetWebClipService(webClipService services.WebClipServiceInterface) {
+	h.webClipService = webClipService
+}
+
+// Capture handles POST /api/v1/capture
+// Creates a note from a minimal payload, tagged #capture, or appends it as
+// bullets to today's daily note when daily is set
+func (h *CaptureHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by API key middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if request.Daily {
+		h.captureDaily(w, r, user, &request)
+		return
+	}
+
+	noteRequest, err := request.ToCreateNoteRequest()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	note, err := h.noteService.CreateNote(r.Context(), user.ID.String(), noteRequest)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		} else {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+
+	respondWithJSON(w, http.StatusCreated, noteResponse)
+}
+
+// captureDaily appends a capture to today's daily note in the request's
+// time zone
+func (h *CaptureHandler) captureDaily(w http.ResponseWriter, r *http.Request, user *models.User, request *models.CaptureRequest) {
+	if h.dailyNoteService == nil {
+		respondWithError(w, http.StatusBadRequest, "Daily notes are not available")
+		return
+	}
+
+	entry, err := request.ToDailyEntry()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	day, err := parseDay("today", request.TimeZone)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	note, err := h.dailyNoteService.AppendToDailyNote(r.Context(), user.ID.String(), day, entry)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+
+	w.Header().Set(HeaderETag, noteETag(note.Version))
+	respondWithJSON(w, http.StatusOK, noteResponse)
+}
+
+// CaptureWeb handles POST /api/v1/capture/web
+// Fetches the page of a URL and creates a note of its readable article,
+// tagged #webclip
+func (h *CaptureHandler) CaptureWeb(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by API key middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if h.webClipService == nil {
+		respondWithError(w, http.StatusBadRequest, "Web clipping is not available")
+		return
+	}
+
+	// Parse request body
+	var request models.WebClipRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	note, err := h.webClipService.ClipPage(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+
+	respondWithJSON(w, http.StatusCreated, noteResponse)
+}
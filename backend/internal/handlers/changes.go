@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// ChangesHandler handles change feed HTTP requests
+type ChangesHandler struct {
+	changeService services.ChangeServiceInterface
+}
+
+// NewChangesHandler creates a new ChangesHandler instance
+func NewChangesHandler(changeService services.ChangeServiceInterface) *ChangesHandler {
+	return &ChangesHandler{
+		changeService: changeService,
+	}
+}
+
+// ListChanges handles GET /api/v1/changes?since=cursor
+func (h *ChangesHandler) ListChanges(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse query parameters
+	since := r.URL.Query().Get("since")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	feed, err := h.changeService.ListChanges(r.Context(), user.ID.String(), since, limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feed)
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/gpd/my-notes/internal/anomaly"
 	"github.com/gpd/my-notes/internal/auth"
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/gpd/my-notes/internal/services"
@@ -26,6 +30,9 @@ var tokenCache sync.Map
 // ChromeAuthRequest represents the request from Chrome extension
 type ChromeAuthRequest struct {
 	Token string `json:"token" validate:"required"`
+	// TwoFactorCode is the authenticator or recovery code of users with
+	// two-factor authentication enabled
+	TwoFactorCode string `json:"two_factor_code,omitempty"`
 }
 
 // ChromeAuthResponse represents the response to Chrome extension
@@ -42,6 +49,12 @@ type ChromeAuthResponse struct {
 type ChromeAuthHandler struct {
 	tokenService *auth.TokenService
 	userService  services.UserServiceInterface
+	activityService services.ActivityServiceInterface
+	sessionService  services.SessionServiceInterface
+	loginGuard      *auth.LoginGuard
+	loginAlerts     services.LoginAlertServiceInterface
+	twoFactor       services.TwoFactorServiceInterface
+	tokenInfoURL    string
 }
 
 // NewChromeAuthHandler creates a new ChromeAuthHandler instance
@@ -52,9 +65,46 @@ func NewChromeAuthHandler(
 	return &ChromeAuthHandler{
 		tokenService: tokenService,
 		userService:  userService,
+		tokenInfoURL: googleTokenInfoURL,
 	}
 }
 
+// SetTokenInfoURL sets the endpoint Chrome tokens are validated against,
+// Google's tokeninfo endpoint by default
+func (h *ChromeAuthHandler) SetTokenInfoURL(tokenInfoURL string) {
+	h.tokenInfoURL = tokenInfoURL
+}
+
+// SetActivityService sets the service recording sign-ins, which make up the
+// country history used by anomaly detection
+func (h *ChromeAuthHandler) SetActivityService(activityService services.ActivityServiceInterface) {
+	h.activityService = activityService
+}
+
+// SetSessionService sets the service recording the refresh token issued to
+// each session, which enables refresh token rotation
+func (h *ChromeAuthHandler) SetSessionService(sessionService services.SessionServiceInterface) {
+	h.sessionService = sessionService
+}
+
+// SetLoginGuard sets the guard slowing down repeated failed sign-ins from an
+// IP address and sign-ins to users locked out by failed attempts elsewhere
+func (h *ChromeAuthHandler) SetLoginGuard(loginGuard *auth.LoginGuard) {
+	h.loginGuard = loginGuard
+}
+
+// SetLoginAlertService sets the service notifying users of sign-ins from new
+// devices and locations
+func (h *ChromeAuthHandler) SetLoginAlertService(loginAlerts services.LoginAlertServiceInterface) {
+	h.loginAlerts = loginAlerts
+}
+
+// SetTwoFactorService sets the service checking the codes of users with
+// two-factor authentication enabled
+func (h *ChromeAuthHandler) SetTwoFactorService(twoFactor services.TwoFactorServiceInterface) {
+	h.twoFactor = twoFactor
+}
+
 // ExchangeChromeToken exchanges Chrome Identity token for app tokens
 func (h *ChromeAuthHandler) ExchangeChromeToken(w http.ResponseWriter, r *http.Request) {
 	var req ChromeAuthRequest
@@ -68,52 +118,92 @@ func (h *ChromeAuthHandler) ExchangeChromeToken(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	ipAddress, userAgent := clientDevice(r)
+	if h.loginGuard != nil {
+		if wait := h.loginGuard.Wait(auth.IPKey(ipAddress)); wait > 0 {
+			respondLoginBlocked(w, wait)
+			return
+		}
+	}
+
 	// Validate Chrome token with Google
 	userInfo, err := h.validateChromeToken(req.Token)
 	if err != nil {
+		if h.loginGuard != nil {
+			h.loginGuard.Fail(auth.IPKey(ipAddress))
+		}
 		respondWithError(w, http.StatusUnauthorized, fmt.Sprintf("Invalid Chrome token: %v", err))
 		return
 	}
 
 	// Get or create user
-	user, err := h.getOrCreateUser(userInfo)
+	user, err := h.getOrCreateUser(r.Context(), userInfo)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create user: %v", err))
 		return
 	}
 
-	// Check if user already has an existing Chrome extension session
-	existingSessions, err := h.userService.GetActiveSessions(user.ID.String())
-	if err == nil {
-		// Look for existing Chrome extension sessions
-		for _, existingSession := range existingSessions {
-			if existingSession.UserAgent == "Chrome-Extension" && existingSession.IsActive {
-				// Reuse existing Chrome extension session
-				if err := h.sendAuthResponse(w, user, existingSession.ID); err != nil {
-					respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to generate tokens: %v", err))
-					return
-				}
-				return
+	if user.IsDisabled() {
+		respondWithError(w, http.StatusForbidden, "Account has been disabled")
+		return
+	}
+
+	if h.loginGuard != nil {
+		if wait := h.loginGuard.Wait(auth.UserKey(user.ID.String())); wait > 0 {
+			respondLoginBlocked(w, wait)
+			return
+		}
+	}
+
+	// Users with two-factor authentication need a code on every sign-in,
+	// including to sessions they already have on this browser
+	twoFactor := false
+	if user.TwoFactorEnabled && h.twoFactor != nil {
+		if req.TwoFactorCode == "" {
+			respondWithAppError(w, services.ErrTwoFactorRequired)
+			return
+		}
+		if err := h.twoFactor.Verify(r.Context(), user.ID, req.TwoFactorCode); err != nil {
+			if h.loginGuard != nil && errors.Is(err, services.ErrInvalidTwoFactorCode) {
+				h.loginGuard.Fail(auth.IPKey(ipAddress), auth.UserKey(user.ID.String()))
 			}
+			respondWithAppError(w, err)
+			return
 		}
+		if h.loginGuard != nil {
+			h.loginGuard.Succeed(auth.UserKey(user.ID.String()))
+		}
+		twoFactor = true
 	}
 
-	// No existing Chrome session found, create a new one
-	session, err := h.userService.CreateSession(user.ID.String(), "127.0.0.1", "Chrome-Extension")
-	var sessionID string
+	recordActivity(r, h.activityService, user.ID, anomaly.EventLogin, 1)
+
+	// Every sign-in gets its own session. Sessions are not shared between
+	// devices with the same user agent, since each refresh rotates the
+	// session's token and the other device would look like a token thief.
+	session, err := h.userService.CreateSession(r.Context(), user.ID.String(), ipAddress, userAgent)
 	if err != nil {
-		// For Chrome extensions, create a simple session if CreateSession fails
-		sessionID = fmt.Sprintf("chrome-session-%s", user.ID.String())
-	} else {
-		sessionID = session.ID
+		log.Printf("ERROR: failed to create session for user %s: %v", user.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	sessionID := session.ID
+	if h.loginAlerts != nil {
+		country, _ := r.Context().Value("clientCountry").(string)
+		if _, err := h.loginAlerts.CheckSignIn(r.Context(), user.ID, sessionID, ipAddress, userAgent, country); err != nil {
+			log.Printf("ERROR: failed to check sign-in of user %s for a new device: %v", user.ID, err)
+		}
 	}
 
-	if err := h.sendAuthResponse(w, user, sessionID); err != nil {
+	if err := h.sendAuthResponse(w, r, user, sessionID, twoFactor); err != nil {
 		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to generate tokens: %v", err))
 		return
 	}
 }
 
+// googleTokenInfoURL is Google's tokeninfo endpoint
+const googleTokenInfoURL = "https://www.googleapis.com/oauth2/v2/tokeninfo"
+
 // Cache duration for token validation (50 minutes, less than Google's 1-hour token lifetime)
 const tokenCacheDuration = 50 * time.Minute
 
@@ -134,10 +224,7 @@ func (h *ChromeAuthHandler) validateChromeToken(token string) (*auth.GoogleUserI
 	// For Chrome extensions, we need to validate the token with Google's tokeninfo endpoint
 	// This is a simpler validation that doesn't require PKCE
 
-	// Google tokeninfo endpoint
-	tokenInfoURL := "https://www.googleapis.com/oauth2/v2/tokeninfo"
-
-	req, err := http.NewRequest("GET", tokenInfoURL, nil)
+	req, err := http.NewRequest("GET", h.tokenInfoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tokeninfo request: %w", err)
 	}
@@ -219,9 +306,9 @@ func (h *ChromeAuthHandler) validateChromeToken(token string) (*auth.GoogleUserI
 }
 
 // getOrCreateUser gets an existing user or creates a new one
-func (h *ChromeAuthHandler) getOrCreateUser(googleUserInfo *auth.GoogleUserInfo) (*models.User, error) {
+func (h *ChromeAuthHandler) getOrCreateUser(ctx context.Context, googleUserInfo *auth.GoogleUserInfo) (*models.User, error) {
 	// Use the existing user service to create or update user from Google info
-	user, err := h.userService.CreateOrUpdateFromGoogle(googleUserInfo)
+	user, err := h.userService.CreateOrUpdateFromGoogle(ctx, googleUserInfo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create or update user: %w", err)
 	}
@@ -229,14 +316,30 @@ func (h *ChromeAuthHandler) getOrCreateUser(googleUserInfo *auth.GoogleUserInfo)
 	return user, nil
 }
 
-// sendAuthResponse generates tokens and sends auth response for Chrome extension
-func (h *ChromeAuthHandler) sendAuthResponse(w http.ResponseWriter, user *models.User, sessionID string) error {
+// sendAuthResponse generates tokens and sends auth response for Chrome extension.
+// twoFactor marks the tokens of sign-ins that passed two-factor authentication.
+func (h *ChromeAuthHandler) sendAuthResponse(w http.ResponseWriter, r *http.Request, user *models.User, sessionID string, twoFactor bool) error {
 	// Generate JWT tokens with the session ID
-	tokenPair, err := h.tokenService.GenerateTokenPairWithSession(user, sessionID)
+	var tokenPair *auth.TokenPair
+	var err error
+	if twoFactor {
+		tokenPair, err = h.tokenService.GenerateTwoFactorTokenPair(user, sessionID)
+	} else {
+		tokenPair, err = h.tokenService.GenerateTokenPairWithSession(user, sessionID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
+	// Make the new refresh token the only one the session accepts
+	if h.sessionService != nil {
+		ipAddress, userAgent := clientDevice(r)
+		err := h.sessionService.IssueRefreshToken(r.Context(), sessionID, tokenPair.RefreshTokenID, ipAddress, userAgent)
+		if err != nil {
+			return fmt.Errorf("failed to record refresh token: %w", err)
+		}
+	}
+
 	response := ChromeAuthResponse{
 		User:         user.ToResponse(),
 		AccessToken:  tokenPair.AccessToken,
@@ -249,3 +352,16 @@ func (h *ChromeAuthHandler) sendAuthResponse(w http.ResponseWriter, user *models
 	respondWithJSON(w, http.StatusOK, response)
 	return nil
 }
+
+// clientDevice returns the IP address and user agent recorded on a session
+func clientDevice(r *http.Request) (string, string) {
+	ipAddress, _ := r.Context().Value("clientIP").(string)
+	if ipAddress == "" {
+		ipAddress = "127.0.0.1"
+	}
+	userAgent := r.Header.Get("User-Agent")
+	if userAgent == "" {
+		userAgent = "Chrome-Extension"
+	}
+	return ipAddress, userAgent
+}
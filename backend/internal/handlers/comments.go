@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// CommentsHandler handles note comment HTTP requests
+type CommentsHandler struct {
+	commentService services.CommentServiceInterface
+}
+
+// NewCommentsHandler creates a new CommentsHandler instance
+func NewCommentsHandler(commentService services.CommentServiceInterface) *CommentsHandler {
+	return &CommentsHandler{
+		commentService: commentService,
+	}
+}
+
+// commentNoteID returns the ID of the note a comment request is about: the
+// {id} of /notes routes or the note published at the {slug} of
+// /publications routes
+func (h *CommentsHandler) commentNoteID(r *http.Request) (string, error) {
+	vars := mux.Vars(r)
+	if slug, ok := vars["slug"]; ok {
+		return h.commentService.NoteIDBySlug(r.Context(), slug)
+	}
+	return vars["id"], nil
+}
+
+// ListComments handles GET /api/v1/notes/{id}/comments and
+// GET /api/v1/publications/{slug}/comments
+func (h *CommentsHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID, err := h.commentNoteID(r)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	// Parse query parameters
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	list, err := h.commentService.ListComments(r.Context(), user.ID.String(), noteID, limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// CreateComment handles POST /api/v1/notes/{id}/comments and
+// POST /api/v1/publications/{slug}/comments
+func (h *CommentsHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID, err := h.commentNoteID(r)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	// Parse request body
+	var request models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	comment, err := h.commentService.CreateComment(r.Context(), user.ID.String(), noteID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, comment)
+}
+
+// UpdateComment handles PUT /api/v1/notes/{id}/comments/{commentID}
+func (h *CommentsHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	// Parse request body
+	var request models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	comment, err := h.commentService.UpdateComment(r.Context(), user.ID.String(), vars["id"], vars["commentID"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /api/v1/notes/{id}/comments/{commentID}
+func (h *CommentsHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.commentService.DeleteComment(r.Context(), user.ID.String(), vars["id"], vars["commentID"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Comment deleted successfully"})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// Headers carrying the confirmation of a destructive operation
+const (
+	HeaderConfirmationID   = "X-Confirmation-ID"
+	HeaderConfirmationCode = "X-Confirmation-Code"
+)
+
+// confirmationFromRequest returns the confirmation ID and code sent with a request
+func confirmationFromRequest(r *http.Request) (string, string) {
+	return strings.TrimSpace(r.Header.Get(HeaderConfirmationID)), strings.TrimSpace(r.Header.Get(HeaderConfirmationCode))
+}
+
+// noteSetScope identifies a set of note IDs independently of order and duplicates
+func noteSetScope(noteIDs []string) string {
+	unique := make(map[string]bool, len(noteIDs))
+	sorted := make([]string, 0, len(noteIDs))
+	for _, id := range noteIDs {
+		id = strings.ToLower(id)
+		if !unique[id] {
+			unique[id] = true
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Strings(sorted)
+
+	sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return "notes:" + hex.EncodeToString(sum[:])
+}
+
+// respondWithConfirmationError responds to confirmation errors and reports
+// whether err was one
+func respondWithConfirmationError(w http.ResponseWriter, err error) bool {
+	var required *services.ConfirmationRequiredError
+	if errors.As(err, &required) {
+		apiResponse := models.NewAPIErrorResponse(ErrCodeConfirmationRequired, "Confirmation required",
+			"a confirmation code has been sent; repeat the request with the "+HeaderConfirmationID+" and "+HeaderConfirmationCode+" headers")
+		apiResponse.Error.Confirmation = required.Confirmation
+
+		response, err := json.Marshal(apiResponse)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to marshal response")
+			return true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionRequired)
+		w.Write(response)
+		return true
+	}
+
+	if errors.Is(err, services.ErrInvalidConfirmationCode) || errors.Is(err, services.ErrConfirmationExpired) {
+		respondWithAppError(w, err)
+		return true
+	}
+	return false
+}
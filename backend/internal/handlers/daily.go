@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// DailyNotesHandler handles journal mode HTTP requests
+type DailyNotesHandler struct {
+	dailyNoteService services.DailyNoteServiceInterface
+}
+
+// NewDailyNotesHandler creates a new DailyNotesHandler instance
+func NewDailyNotesHandler(dailyNoteService services.DailyNoteServiceInterface) *DailyNotesHandler {
+	return &DailyNotesHandler{
+		dailyNoteService: dailyNoteService,
+	}
+}
+
+// parseDay returns midnight of a YYYY-MM-DD date, or of today when date is
+// empty or "today", in the IANA time zone tz, which defaults to UTC
+func parseDay(date, tz string) (time.Time, error) {
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return time.Time{}, errors.New("Invalid time zone")
+		}
+	}
+
+	if date == "" || date == "today" {
+		now := time.Now().In(loc)
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc), nil
+	}
+	day, err := time.ParseInLocation(search.DateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid date, expected YYYY-MM-DD or today")
+	}
+	return day, nil
+}
+
+// GetDailyNote handles GET /api/v1/daily/{date}?tz=Europe/Berlin
+// Returns the note of the date, creating it from the user's journal template
+// on first access
+func (h *DailyNotesHandler) GetDailyNote(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	day, err := parseDay(mux.Vars(r)["date"], r.URL.Query().Get("tz"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	note, created, err := h.dailyNoteService.GetDailyNote(r.Context(), user.ID.String(), day)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set(HeaderETag, noteETag(note.Version))
+	respondWithJSON(w, status, noteResponse)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// DigestHandler handles digest email HTTP requests
+type DigestHandler struct {
+	digestService services.DigestServiceInterface
+}
+
+// NewDigestHandler creates a new DigestHandler instance
+func NewDigestHandler(digestService services.DigestServiceInterface) *DigestHandler {
+	return &DigestHandler{
+		digestService: digestService,
+	}
+}
+
+// PreviewDigest handles GET /api/v1/digest/preview
+// Returns the digest the user would receive now, without sending it. The
+// frequency query parameter defaults to daily.
+func (h *DigestHandler) PreviewDigest(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	frequency := r.URL.Query().Get("frequency")
+	if frequency == "" {
+		frequency = models.DigestFrequencyDaily
+	}
+
+	digest, err := h.digestService.BuildDigest(r.Context(), user.ID.String(), frequency, time.Now())
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		} else {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, digest)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/openapi"
+)
+
+// swaggerUIVersion is the Swagger UI release loaded by the docs page
+const swaggerUIVersion = "5.17.14"
+
+// docsContentSecurityPolicy replaces the API policy on the docs page, which
+// loads Swagger UI from unpkg
+const docsContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; " +
+	"style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https://unpkg.com; connect-src 'self'"
+
+// docsPage is the Swagger UI page. The spec URL is relative so the page
+// works behind any prefix.
+var docsPage = fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Silence Notes API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@%[1]s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@%[1]s/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`, swaggerUIVersion)
+
+// DocsHandler serves the OpenAPI spec of the API and Swagger UI to browse it
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler creates a new DocsHandler serving doc
+func NewDocsHandler(doc *openapi.Document) (*DocsHandler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI spec: %w", err)
+	}
+	return &DocsHandler{spec: spec}, nil
+}
+
+// Spec handles GET /api/v1/openapi.json
+func (h *DocsHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+// UI handles GET /api/v1/docs
+func (h *DocsHandler) UI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsContentSecurityPolicy)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(docsPage))
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// Headers carrying note versions for optimistic locking
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// noteETag returns the entity tag of a note version
+func noteETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatches reports whether an If-Match header matches a note version. It
+// uses strong comparison, so weak and malformed entity tags never match.
+func ifMatches(header string, version int) bool {
+	etag := noteETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondWithPreconditionFailed sends a 412 response for a conditional
+// update, carrying the current copy of the note and its ETag so the client
+// can merge and retry
+func respondWithPreconditionFailed(w http.ResponseWriter, current models.NoteResponse) {
+	apiResponse := models.NewAPIErrorResponse(ErrCodePreconditionFailed, "Note has been modified",
+		"the current version is in error.current; merge and retry with its ETag in If-Match")
+	apiResponse.Error.Current = &current
+
+	response, err := json.Marshal(apiResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to marshal response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(HeaderETag, noteETag(current.Version))
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Write(response)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/anomaly"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// ExportsHandler handles note export HTTP requests
+type ExportsHandler struct {
+	exportService   services.ExportServiceInterface
+	activityService services.ActivityServiceInterface
+}
+
+// NewExportsHandler creates a new ExportsHandler instance
+func NewExportsHandler(exportService services.ExportServiceInterface) *ExportsHandler {
+	return &ExportsHandler{
+		exportService: exportService,
+	}
+}
+
+// SetActivityService sets the service recording exports for anomaly detection
+func (h *ExportsHandler) SetActivityService(activityService services.ActivityServiceInterface) {
+	h.activityService = activityService
+}
+
+// ExportNotes handles GET /api/v1/export?format=json&tags=work&since=2024-01-01
+// Streams the notes matching the filter as a downloadable archive, spreadsheet or document
+func (h *ExportsHandler) ExportNotes(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ExportFormatJSON
+	}
+
+	filter, err := exportFilterFromRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contentType := "application/json"
+	switch format {
+	case models.ExportFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+	case models.ExportFormatPDF:
+		contentType = "application/pdf"
+	}
+	out := &exportWriter{w: w, contentType: contentType, filename: fmt.Sprintf("notes-%s.%s", time.Now().UTC().Format("2006-01-02"), format)}
+	count, err := h.exportService.ExportNotes(r.Context(), user.ID.String(), format, filter, out)
+	if err != nil {
+		if out.started {
+			// The status has been sent; the client sees a truncated archive
+			log.Printf("[ExportsHandler] ERROR: export for user %s stopped after %d notes: %v", user.ID, count, err)
+			return
+		}
+		var parseErr *search.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			respondWithQueryError(w, parseErr)
+		case strings.HasPrefix(err.Error(), "failed to"):
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+		default:
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	recordActivity(r, h.activityService, user.ID, anomaly.EventExport, 1)
+}
+
+// ExportAccountData handles GET /api/v1/account/data
+// Streams everything stored for the account as a machine-readable archive
+func (h *ExportsHandler) ExportAccountData(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	out := &exportWriter{w: w, contentType: "application/json", filename: fmt.Sprintf("account-%s.json", time.Now().UTC().Format("2006-01-02"))}
+	count, err := h.exportService.ExportAccountData(r.Context(), user.ID.String(), out)
+	if err != nil {
+		if out.started {
+			log.Printf("[ExportsHandler] ERROR: account export for user %s stopped after %d notes: %v", user.ID, count, err)
+			return
+		}
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordActivity(r, h.activityService, user.ID, anomaly.EventExport, 1)
+}
+
+// exportFilterFromRequest reads the export filter from the query string. Tags
+// are comma-separated; since and until are YYYY-MM-DD creation dates, with
+// since inclusive and until exclusive.
+func exportFilterFromRequest(r *http.Request) (*models.ExportFilter, error) {
+	query := r.URL.Query()
+	filter := &models.ExportFilter{Query: strings.TrimSpace(query.Get("q"))}
+
+	if tagsParam := query.Get("tags"); tagsParam != "" {
+		for _, tag := range strings.Split(tagsParam, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	parseDate := func(names ...string) (*time.Time, error) {
+		for _, name := range names {
+			value := query.Get(name)
+			if value == "" {
+				continue
+			}
+			date, err := time.Parse(search.DateLayout, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s date %q (expected YYYY-MM-DD)", name, value)
+			}
+			return &date, nil
+		}
+		return nil, nil
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseDate("since", "created_after"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedBefore, err = parseDate("until", "created_before"); err != nil {
+		return nil, err
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return nil, errors.New("since must be earlier than until")
+	}
+	if filter.NotebookID, err = notebookParam(r); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// exportWriter sends the download headers when the export starts writing, so
+// errors found before then can still be returned as JSON errors
+type exportWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
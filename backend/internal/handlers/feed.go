@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// FeedHandler handles activity feed HTTP requests
+type FeedHandler struct {
+	feedService services.FeedServiceInterface
+}
+
+// NewFeedHandler creates a new FeedHandler instance
+func NewFeedHandler(feedService services.FeedServiceInterface) *FeedHandler {
+	return &FeedHandler{
+		feedService: feedService,
+	}
+}
+
+// ListFeed handles GET /api/v1/activity?cursor=&notebook_id=
+func (h *FeedHandler) ListFeed(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse query parameters
+	notebookID, err := notebookParam(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	feed, err := h.feedService.ListFeed(r.Context(), user.ID.String(), notebookID, cursor, limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feed)
+}
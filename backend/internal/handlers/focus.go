@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// FocusHandler handles focus session and time tracking HTTP requests
+type FocusHandler struct {
+	focusService services.FocusServiceInterface
+}
+
+// NewFocusHandler creates a new FocusHandler instance
+func NewFocusHandler(focusService services.FocusServiceInterface) *FocusHandler {
+	return &FocusHandler{
+		focusService: focusService,
+	}
+}
+
+// StartSession handles POST /api/v1/notes/{id}/sessions/start
+func (h *FocusHandler) StartSession(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID := mux.Vars(r)["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	response, err := h.focusService.StartSession(r.Context(), user.ID.String(), noteID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// StopSession handles POST /api/v1/notes/{id}/sessions/stop
+func (h *FocusHandler) StopSession(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID := mux.Vars(r)["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	session, err := h.focusService.StopSession(r.Context(), user.ID.String(), noteID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, session)
+}
+
+// GetTimeSummary handles GET /api/v1/analytics/time
+func (h *FocusHandler) GetTimeSummary(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+	request := &models.TimeSummaryRequest{
+		Period:   query.Get("period"),
+		Location: time.UTC,
+	}
+	if request.Period == "" {
+		request.Period = models.TimePeriodDay
+	}
+
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid time zone")
+			return
+		}
+		request.Location = loc
+	}
+
+	// Default to the last 7 days, or the last 4 weeks
+	request.To = time.Now().In(request.Location)
+	if to := query.Get("to"); to != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", to, request.Location)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		request.To = parsed
+	}
+	if request.Period == models.TimePeriodWeek {
+		request.From = request.To.AddDate(0, 0, -27)
+	} else {
+		request.From = request.To.AddDate(0, 0, -6)
+	}
+	if from := query.Get("from"); from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, request.Location)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+		request.From = parsed
+	}
+
+	summary, err := h.focusService.GetTimeSummary(r.Context(), user.ID.String(), request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/graphql"
+	"github.com/gpd/my-notes/internal/models"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// maxGraphQLRequestSize limits the size of GraphQL request bodies
+const maxGraphQLRequestSize = 1 << 20
+
+// GraphQLHandler handles GraphQL queries over notes and tags
+type GraphQLHandler struct {
+	schema *graphqlgo.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler instance
+func NewGraphQLHandler(schema *graphqlgo.Schema) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: schema,
+	}
+}
+
+// graphQLRequest is the body of a GraphQL request
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Query handles POST /api/v1/graphql
+// Responds with a standard GraphQL response ({data, errors}) instead of the
+// API envelope, so GraphQL clients work unchanged
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request graphQLRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if request.Query == "" {
+		respondWithError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+
+	// Execute query; field errors are returned in the response
+	ctx := graphql.WithUser(r.Context(), user)
+	response := h.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
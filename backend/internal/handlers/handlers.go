@@ -5,8 +5,49 @@ type Handlers struct {
 	Health     *HealthHandler
 	Auth       *AuthHandler
 	ChromeAuth *ChromeAuthHandler
+	TwoFactor  *TwoFactorHandler
 	Notes      *NotesHandler
 	Tags       *TagsHandler
+	Subscriptions *SubscriptionsHandler
+	Notifications *NotificationsHandler
+	NotificationRules *NotificationRulesHandler
+	SavedSearches *SavedSearchesHandler
+	Imports       *ImportsHandler
+	Exports       *ExportsHandler
+	Links         *LinksHandler
+	Account       *AccountHandler
+	Admin         *AdminHandler
+	QA            *QAHandler
+	Migrations    *MigrationsHandler
+	Changes       *ChangesHandler
+	Progress      *ProgressHandler
+	Focus         *FocusHandler
+	Digest        *DigestHandler
+	Webhooks      *WebhooksHandler
+	APIKeys       *APIKeysHandler
+	Usage         *UsageHandler
+	Capture       *CaptureHandler
+	GraphQL       *GraphQLHandler
+	Audit         *AuditHandler
+	Tasks         *TasksHandler
+	Boards        *BoardsHandler
+	Calendar      *CalendarHandler
+	Templates     *TemplatesHandler
+	Recurrences   *RecurrencesHandler
+	DailyNotes    *DailyNotesHandler
+	Properties    *PropertiesHandler
+	Notebooks     *NotebooksHandler
+	Organizations *OrganizationsHandler
+	Feed          *FeedHandler
+	Publications  *PublicationsHandler
+	Comments      *CommentsHandler
+	InboundEmail  *InboundEmailHandler
+	NoteFeeds     *NoteFeedsHandler
+	ImageText     *ImageTextHandler
+	Transcription *TranscriptionHandler
+	Paste         *PasteHandler
+	Proofread     *ProofreadHandler
+	Translation   *TranslationHandler
 }
 
 // NewHandlers creates a new handlers instance
@@ -25,6 +66,11 @@ func (h *Handlers) SetAuthHandlers(authHandler *AuthHandler, chromeAuthHandler *
 	h.ChromeAuth = chromeAuthHandler
 }
 
+// SetTwoFactorHandler initializes the two-factor authentication handler with service dependencies
+func (h *Handlers) SetTwoFactorHandler(twoFactorHandler *TwoFactorHandler) {
+	h.TwoFactor = twoFactorHandler
+}
+
 // SetNotesHandler initializes the notes handler with service dependencies
 func (h *Handlers) SetNotesHandler(notesHandler *NotesHandler) {
 	h.Notes = notesHandler
@@ -33,4 +79,203 @@ func (h *Handlers) SetNotesHandler(notesHandler *NotesHandler) {
 // SetTagsHandler initializes the tags handler with service dependencies
 func (h *Handlers) SetTagsHandler(tagsHandler *TagsHandler) {
 	h.Tags = tagsHandler
-}
\ No newline at end of file
+}
+// SetSubscriptionsHandler initializes the search subscriptions handler with service dependencies
+func (h *Handlers) SetSubscriptionsHandler(subscriptionsHandler *SubscriptionsHandler) {
+	h.Subscriptions = subscriptionsHandler
+}
+
+// SetNotificationsHandler initializes the notifications handler with service dependencies
+func (h *Handlers) SetNotificationsHandler(notificationsHandler *NotificationsHandler) {
+	h.Notifications = notificationsHandler
+}
+
+// SetNotificationRulesHandler initializes the notification rules handler with service dependencies
+func (h *Handlers) SetNotificationRulesHandler(notificationRulesHandler *NotificationRulesHandler) {
+	h.NotificationRules = notificationRulesHandler
+}
+
+// SetSavedSearchesHandler initializes the saved searches handler with service dependencies
+func (h *Handlers) SetSavedSearchesHandler(savedSearchesHandler *SavedSearchesHandler) {
+	h.SavedSearches = savedSearchesHandler
+}
+
+// SetImportsHandler initializes the import wizard handler with service dependencies
+func (h *Handlers) SetImportsHandler(importsHandler *ImportsHandler) {
+	h.Imports = importsHandler
+}
+
+// SetLinksHandler initializes the note links handler with service dependencies
+func (h *Handlers) SetLinksHandler(linksHandler *LinksHandler) {
+	h.Links = linksHandler
+}
+
+// SetAccountHandler initializes the account handler with service dependencies
+func (h *Handlers) SetAccountHandler(accountHandler *AccountHandler) {
+	h.Account = accountHandler
+}
+
+// SetAdminHandler initializes the administrator handler with service dependencies
+func (h *Handlers) SetAdminHandler(adminHandler *AdminHandler) {
+	h.Admin = adminHandler
+}
+
+// SetQAHandler initializes the question answering handler with service dependencies
+func (h *Handlers) SetQAHandler(qaHandler *QAHandler) {
+	h.QA = qaHandler
+}
+
+// SetExportsHandler initializes the note export handler with service dependencies
+func (h *Handlers) SetExportsHandler(exportsHandler *ExportsHandler) {
+	h.Exports = exportsHandler
+}
+
+// SetMigrationsHandler initializes the data migration handler with service dependencies
+func (h *Handlers) SetMigrationsHandler(migrationsHandler *MigrationsHandler) {
+	h.Migrations = migrationsHandler
+}
+
+// SetChangesHandler initializes the change feed handler with service dependencies
+func (h *Handlers) SetChangesHandler(changesHandler *ChangesHandler) {
+	h.Changes = changesHandler
+}
+
+// SetProgressHandler initializes the checklist progress handler with service dependencies
+func (h *Handlers) SetProgressHandler(progressHandler *ProgressHandler) {
+	h.Progress = progressHandler
+}
+
+// SetFocusHandler initializes the focus session handler with service dependencies
+func (h *Handlers) SetFocusHandler(focusHandler *FocusHandler) {
+	h.Focus = focusHandler
+}
+
+// SetDigestHandler initializes the digest email handler with service dependencies
+func (h *Handlers) SetDigestHandler(digestHandler *DigestHandler) {
+	h.Digest = digestHandler
+}
+
+// SetWebhooksHandler initializes the webhook handler with service dependencies
+func (h *Handlers) SetWebhooksHandler(webhooksHandler *WebhooksHandler) {
+	h.Webhooks = webhooksHandler
+}
+
+// SetAPIKeysHandler initializes the API key handler with service dependencies
+func (h *Handlers) SetAPIKeysHandler(apiKeysHandler *APIKeysHandler) {
+	h.APIKeys = apiKeysHandler
+}
+
+// SetUsageHandler initializes the LLM usage handler with service dependencies
+func (h *Handlers) SetUsageHandler(usageHandler *UsageHandler) {
+	h.Usage = usageHandler
+}
+
+// SetCaptureHandler initializes the capture handler with service dependencies
+func (h *Handlers) SetCaptureHandler(captureHandler *CaptureHandler) {
+	h.Capture = captureHandler
+}
+
+// SetGraphQLHandler initializes the GraphQL handler with service dependencies
+func (h *Handlers) SetGraphQLHandler(graphQLHandler *GraphQLHandler) {
+	h.GraphQL = graphQLHandler
+}
+
+// SetAuditHandler initializes the audit log handler with service dependencies
+func (h *Handlers) SetAuditHandler(auditHandler *AuditHandler) {
+	h.Audit = auditHandler
+}
+
+// SetTasksHandler initializes the note tasks handler with service dependencies
+func (h *Handlers) SetTasksHandler(tasksHandler *TasksHandler) {
+	h.Tasks = tasksHandler
+}
+
+// SetBoardsHandler initializes the kanban boards handler with service dependencies
+func (h *Handlers) SetBoardsHandler(boardsHandler *BoardsHandler) {
+	h.Boards = boardsHandler
+}
+
+// SetCalendarHandler initializes the notes calendar handler with service dependencies
+func (h *Handlers) SetCalendarHandler(calendarHandler *CalendarHandler) {
+	h.Calendar = calendarHandler
+}
+
+// SetTemplatesHandler initializes the note templates handler with service dependencies
+func (h *Handlers) SetTemplatesHandler(templatesHandler *TemplatesHandler) {
+	h.Templates = templatesHandler
+}
+
+// SetRecurrencesHandler initializes the recurring notes handler with service dependencies
+func (h *Handlers) SetRecurrencesHandler(recurrencesHandler *RecurrencesHandler) {
+	h.Recurrences = recurrencesHandler
+}
+
+// SetDailyNotesHandler initializes the daily notes handler with service dependencies
+func (h *Handlers) SetDailyNotesHandler(dailyNotesHandler *DailyNotesHandler) {
+	h.DailyNotes = dailyNotesHandler
+}
+
+// SetPropertiesHandler initializes the note properties handler with service dependencies
+func (h *Handlers) SetPropertiesHandler(propertiesHandler *PropertiesHandler) {
+	h.Properties = propertiesHandler
+}
+
+// SetNotebooksHandler initializes the notebooks handler with service dependencies
+func (h *Handlers) SetNotebooksHandler(notebooksHandler *NotebooksHandler) {
+	h.Notebooks = notebooksHandler
+}
+
+// SetOrganizationsHandler initializes the organizations handler with service dependencies
+func (h *Handlers) SetOrganizationsHandler(organizationsHandler *OrganizationsHandler) {
+	h.Organizations = organizationsHandler
+}
+
+// SetFeedHandler initializes the activity feed handler with service dependencies
+func (h *Handlers) SetFeedHandler(feedHandler *FeedHandler) {
+	h.Feed = feedHandler
+}
+
+// SetPublicationsHandler initializes the note publishing handler with service dependencies
+func (h *Handlers) SetPublicationsHandler(publicationsHandler *PublicationsHandler) {
+	h.Publications = publicationsHandler
+}
+
+// SetCommentsHandler initializes the note comments handler with service dependencies
+func (h *Handlers) SetCommentsHandler(commentsHandler *CommentsHandler) {
+	h.Comments = commentsHandler
+}
+
+// SetInboundEmailHandler initializes the email-to-note handler with service dependencies
+func (h *Handlers) SetInboundEmailHandler(inboundEmailHandler *InboundEmailHandler) {
+	h.InboundEmail = inboundEmailHandler
+}
+
+// SetNoteFeedsHandler initializes the RSS and Atom feeds handler with service dependencies
+func (h *Handlers) SetNoteFeedsHandler(noteFeedsHandler *NoteFeedsHandler) {
+	h.NoteFeeds = noteFeedsHandler
+}
+
+// SetImageTextHandler initializes the image text recognition handler with service dependencies
+func (h *Handlers) SetImageTextHandler(imageTextHandler *ImageTextHandler) {
+	h.ImageText = imageTextHandler
+}
+
+// SetTranscriptionHandler initializes the voice memo transcription handler with service dependencies
+func (h *Handlers) SetTranscriptionHandler(transcriptionHandler *TranscriptionHandler) {
+	h.Transcription = transcriptionHandler
+}
+
+// SetPasteHandler initializes the pasted content handler with service dependencies
+func (h *Handlers) SetPasteHandler(pasteHandler *PasteHandler) {
+	h.Paste = pasteHandler
+}
+
+// SetProofreadHandler initializes the spelling and grammar fix handler with service dependencies
+func (h *Handlers) SetProofreadHandler(proofreadHandler *ProofreadHandler) {
+	h.Proofread = proofreadHandler
+}
+
+// SetTranslationHandler initializes the note translation handler with service dependencies
+func (h *Handlers) SetTranslationHandler(translationHandler *TranslationHandler) {
+	h.Translation = translationHandler
+}
@@ -2,12 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/gpd/my-notes/internal/llm"
 )
 
+// LLMStatusReporter reports the health of the LLM providers
+type LLMStatusReporter interface {
+	ProviderStatuses() []llm.ProviderStatus
+}
+
 // HealthHandler handles health check requests
-type HealthHandler struct{}
+type HealthHandler struct {
+	llm LLMStatusReporter
+}
 
 // NewHealthHandler creates a new health handler
 func NewHealthHandler() *HealthHandler {
@@ -31,6 +41,31 @@ type Check struct {
 
 var startTime = time.Now()
 
+// SetLLM adds the LLM providers to the health checks
+func (h *HealthHandler) SetLLM(llm LLMStatusReporter) {
+	h.llm = llm
+}
+
+// llmCheck summarizes the LLM providers. Prettify and other LLM features keep
+// working while any provider is healthy.
+func (h *HealthHandler) llmCheck() Check {
+	statuses := h.llm.ProviderStatuses()
+	healthy := 0
+	for _, status := range statuses {
+		if status.Healthy {
+			healthy++
+		}
+	}
+	check := Check{Status: "ok", Message: fmt.Sprintf("%d of %d providers healthy", healthy, len(statuses))}
+	switch {
+	case healthy == 0:
+		check.Status = "down"
+	case healthy < len(statuses):
+		check.Status = "degraded"
+	}
+	return check
+}
+
 // HealthCheck handles the health check endpoint
 func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	uptime := time.Since(startTime)
@@ -48,6 +83,10 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
+	if h.llm != nil {
+		response.Checks["llm"] = h.llmCheck()
+	}
+
 	// TODO: Add database health check
 	// TODO: Add Redis health check
 	// TODO: Add other service health checks
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// ImageTextHandler handles HTTP requests for the text recognized in images
+// uploaded to notes
+type ImageTextHandler struct {
+	imageTextService services.ImageTextServiceInterface
+	maxImageSize     int64
+}
+
+// NewImageTextHandler creates a new ImageTextHandler accepting images of up
+// to maxImageSize bytes
+func NewImageTextHandler(imageTextService services.ImageTextServiceInterface, maxImageSize int64) *ImageTextHandler {
+	return &ImageTextHandler{
+		imageTextService: imageTextService,
+		maxImageSize:     maxImageSize,
+	}
+}
+
+// AddImage handles POST /api/v1/notes/{id}/image-text
+// Accepts a multipart upload with a "file" field, or the raw image as the
+// request body. The recognized text is appended to the note's image text.
+func (h *ImageTextHandler) AddImage(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxImageSize)
+	defer r.Body.Close()
+
+	_, image, err := readImportUpload(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Image too large (max %dMB)", h.maxImageSize>>20))
+		} else {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	vars := mux.Vars(r)
+	response, err := h.imageTextService.AddImage(r.Context(), user.ID.String(), vars["id"], image)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// ClearImageText handles DELETE /api/v1/notes/{id}/image-text
+func (h *ImageTextHandler) ClearImageText(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	note, err := h.imageTextService.ClearImageText(r.Context(), user.ID.String(), vars["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, note.ToResponse())
+}
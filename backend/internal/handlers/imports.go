@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/importer"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// defaultMaxImportSize limits uploaded spreadsheet files, matching the default request size limit
+const defaultMaxImportSize = 1 << 20
+
+// ImportsHandler handles the import wizard HTTP requests
+type ImportsHandler struct {
+	importService services.ImportServiceInterface
+	maxUploadSize int64
+}
+
+// NewImportsHandler creates a new ImportsHandler instance
+func NewImportsHandler(importService services.ImportServiceInterface) *ImportsHandler {
+	return &ImportsHandler{
+		importService: importService,
+		maxUploadSize: defaultMaxImportSize,
+	}
+}
+
+// SetMaxUploadSize sets the size limit in bytes of spreadsheet uploads. The
+// file is kept until the import is executed, so it is read whole.
+func (h *ImportsHandler) SetMaxUploadSize(maxBytes int64) {
+	h.maxUploadSize = maxBytes
+}
+
+// CreateImport handles POST /api/v1/imports
+// Accepts a multipart upload with a "file" field, or the raw file as the
+// request body with an optional ?filename= query parameter
+func (h *ImportsHandler) CreateImport(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	defer r.Body.Close()
+
+	filename, data, err := readImportUpload(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import file too large (max %dMB)", h.maxUploadSize>>20))
+		} else {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	session, err := h.importService.CreateImportSession(r.Context(), user.ID.String(), filename, data)
+	if err != nil {
+		respondWithImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, session)
+}
+
+// GetImport handles GET /api/v1/imports/{id}
+func (h *ImportsHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	session, err := h.importService.GetImportSession(r.Context(), user.ID.String(), vars["id"])
+	if err != nil {
+		respondWithImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, session)
+}
+
+// SubmitMapping handles POST /api/v1/imports/{id}/mapping
+func (h *ImportsHandler) SubmitMapping(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var mapping importer.Mapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	vars := mux.Vars(r)
+	session, err := h.importService.SubmitImportMapping(r.Context(), user.ID.String(), vars["id"], &mapping)
+	if err != nil {
+		respondWithImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, session)
+}
+
+// ExecuteImport handles POST /api/v1/imports/{id}/execute
+func (h *ImportsHandler) ExecuteImport(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	session, err := h.importService.ExecuteImport(r.Context(), user.ID.String(), vars["id"])
+	if err != nil {
+		respondWithImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, session)
+}
+
+// ImportArchive handles POST /api/v1/imports/archive
+// Accepts a JSON archive of notes the same ways as CreateImport. The archive
+// is imported as it is read; the returned session holds the result.
+func (h *ImportsHandler) ImportArchive(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+	defer r.Body.Close()
+
+	filename, archive, err := openImportArchive(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.importService.ImportArchive(r.Context(), user.ID.String(), filename, archive)
+	if err != nil {
+		respondWithImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, session)
+}
+
+// ImportVault handles POST /api/v1/imports/vault
+// Accepts a ZIP of a Markdown folder tree, such as an Obsidian vault, the
+// same ways as CreateImport. Each Markdown file becomes a note.
+func (h *ImportsHandler) ImportVault(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+	defer r.Body.Close()
+
+	filename, archive, err := openImportArchive(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.importService.ImportVault(r.Context(), user.ID.String(), filename, archive)
+	if err != nil {
+		respondWithImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, session)
+}
+
+// openImportArchive returns the uploaded archive name and a reader streaming
+// its content. Multipart uploads are read part by part rather than parsed
+// into memory.
+func openImportArchive(r *http.Request) (string, io.Reader, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.URL.Query().Get("filename"), r.Body, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", nil, errors.New("invalid multipart upload")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return "", nil, errors.New("multipart upload must include a \"file\" field")
+		}
+		if part.FormName() == "file" {
+			return part.FileName(), part, nil
+		}
+	}
+}
+
+// readImportUpload returns the uploaded file name and content
+func readImportUpload(r *http.Request) (string, []byte, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return "", nil, err
+			}
+			return "", nil, errors.New("multipart upload must include a \"file\" field")
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		return header.Filename, data, err
+	}
+
+	data, err := io.ReadAll(r.Body)
+	return r.URL.Query().Get("filename"), data, err
+}
+
+// respondWithImportError maps import service errors to HTTP responses
+func respondWithImportError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "import session not found":
+		respondWithError(w, http.StatusNotFound, "Import session not found")
+	case err.Error() == "import has already been executed", err.Error() == "import session has no mapping":
+		respondWithError(w, http.StatusConflict, err.Error())
+	case strings.HasPrefix(err.Error(), "archive exceeds"):
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	default:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// mailgunSignatureMaxAge is how old the timestamp of a signed Mailgun
+// request may be, limiting replays
+const mailgunSignatureMaxAge = 15 * time.Minute
+
+// InboundEmailHandler handles users' inbound email addresses and the emails
+// the mail provider forwards to them
+type InboundEmailHandler struct {
+	inboundEmailService services.InboundEmailServiceInterface
+	signingKey          string
+	maxMessageSize      int64
+}
+
+// NewInboundEmailHandler creates a new InboundEmailHandler instance.
+// Mailgun requests are verified with signingKey and rejected without one.
+func NewInboundEmailHandler(inboundEmailService services.InboundEmailServiceInterface, signingKey string, maxMessageSize int64) *InboundEmailHandler {
+	return &InboundEmailHandler{
+		inboundEmailService: inboundEmailService,
+		signingKey:          signingKey,
+		maxMessageSize:      maxMessageSize,
+	}
+}
+
+// GetAddress handles GET /api/v1/inbound-email
+// Returns the user's secret address, creating it on first use
+func (h *InboundEmailHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	address, err := h.inboundEmailService.GetAddress(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, address)
+}
+
+// RotateAddress handles POST /api/v1/inbound-email/rotate
+func (h *InboundEmailHandler) RotateAddress(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	address, err := h.inboundEmailService.RotateAddress(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, address)
+}
+
+// DisableAddress handles DELETE /api/v1/inbound-email
+func (h *InboundEmailHandler) DisableAddress(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.inboundEmailService.DisableAddress(r.Context(), user.ID.String()); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Inbound address disabled successfully"})
+}
+
+// ReceiveMailgun handles POST /api/v1/inbound/mailgun, the target of a
+// Mailgun route forwarding mail for the inbound domain. Emails that can never
+// become notes are answered 406, which tells Mailgun not to retry them.
+func (h *InboundEmailHandler) ReceiveMailgun(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxMessageSize)
+	defer r.Body.Close()
+
+	if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Email too large (max %dMB)", h.maxMessageSize>>20))
+		} else {
+			respondWithError(w, http.StatusBadRequest, "Invalid email payload")
+		}
+		return
+	}
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	} else if err := r.ParseForm(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid email payload")
+		return
+	}
+
+	if !h.verifyMailgunSignature(r.FormValue("timestamp"), r.FormValue("token"), r.FormValue("signature")) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	email := &models.InboundEmail{
+		Recipients: []string{r.FormValue("recipient")},
+		From:       r.FormValue("from"),
+		Subject:    r.FormValue("subject"),
+		Body:       r.FormValue("body-plain"),
+	}
+	if r.MultipartForm != nil {
+		for field, files := range r.MultipartForm.File {
+			if !strings.HasPrefix(field, "attachment") {
+				continue
+			}
+			for _, file := range files {
+				email.Attachments = append(email.Attachments, models.InboundAttachment{
+					Filename:    file.Filename,
+					ContentType: file.Header.Get("Content-Type"),
+					Size:        file.Size,
+				})
+			}
+		}
+	}
+
+	note, err := h.inboundEmailService.ReceiveEmail(r.Context(), email)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrValidation) || errors.Is(err, apperrors.ErrForbidden) {
+			respondWithError(w, http.StatusNotAcceptable, err.Error())
+			return
+		}
+		log.Printf("Failed to receive email: %v", err)
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"note_id": note.ID.String()})
+}
+
+// verifyMailgunSignature checks the HMAC-SHA256 Mailgun signs the timestamp
+// and token of its requests with, and that the timestamp is recent
+func (h *InboundEmailHandler) verifyMailgunSignature(timestamp, token, signature string) bool {
+	if h.signingKey == "" || token == "" {
+		return false
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > mailgunSignatureMaxAge || age < -mailgunSignatureMaxAge {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.signingKey))
+	mac.Write([]byte(timestamp + token))
+	expected, err := hex.DecodeString(signature)
+	return err == nil && hmac.Equal(mac.Sum(nil), expected)
+}
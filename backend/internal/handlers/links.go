@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// LinksHandler handles note link (backlinks and graph) HTTP requests
+type LinksHandler struct {
+	linkService services.LinkServiceInterface
+}
+
+// NewLinksHandler creates a new LinksHandler instance
+func NewLinksHandler(linkService services.LinkServiceInterface) *LinksHandler {
+	return &LinksHandler{
+		linkService: linkService,
+	}
+}
+
+// GetBacklinks handles GET /api/v1/notes/{id}/backlinks
+func (h *LinksHandler) GetBacklinks(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	backlinks, err := h.linkService.GetBacklinks(r.Context(), user.ID.String(), vars["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"backlinks": backlinks,
+		"total":     len(backlinks),
+	})
+}
+
+// GetGraph handles GET /api/v1/notes/graph
+func (h *LinksHandler) GetGraph(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	includeOrphans := r.URL.Query().Get("include_orphans") == "true"
+	graph, err := h.linkService.GetGraph(r.Context(), user.ID.String(), includeOrphans)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, graph)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/anomaly"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// maxMigrationChunkSize limits migration chunks, matching the request size limit
+const maxMigrationChunkSize = 1 << 20
+
+// MigrationsHandler handles data migration between deployments. Transfer
+// endpoints are called by the source deployment and authenticate with the
+// transfer token instead of a user session.
+type MigrationsHandler struct {
+	migrationService services.MigrationServiceInterface
+	activityService  services.ActivityServiceInterface
+}
+
+// NewMigrationsHandler creates a new MigrationsHandler instance
+func NewMigrationsHandler(migrationService services.MigrationServiceInterface) *MigrationsHandler {
+	return &MigrationsHandler{
+		migrationService: migrationService,
+	}
+}
+
+// SetActivityService sets the service recording outgoing migrations as exports
+// for anomaly detection
+func (h *MigrationsHandler) SetActivityService(activityService services.ActivityServiceInterface) {
+	h.activityService = activityService
+}
+
+// CreateTransfer handles POST /api/v1/migrations/incoming
+func (h *MigrationsHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	transfer, err := h.migrationService.CreateTransfer(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, transfer)
+}
+
+// GetTransfer handles GET /api/v1/migrations/incoming/{id}
+func (h *MigrationsHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transfer, err := h.migrationService.GetTransfer(r.Context(), vars["id"], r.Header.Get(services.MigrationTokenHeader))
+	if err != nil {
+		respondWithMigrationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, transfer)
+}
+
+// ReceiveChunk handles PUT /api/v1/migrations/incoming/{id}/chunks/{sequence}
+func (h *MigrationsHandler) ReceiveChunk(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sequence, err := strconv.Atoi(vars["sequence"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chunk sequence")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMigrationChunkSize)
+	defer r.Body.Close()
+
+	var chunk models.MigrationChunk
+	if err := json.NewDecoder(r.Body).Decode(&chunk); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Chunk too large (max 1MB)")
+		} else {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		}
+		return
+	}
+	if chunk.Sequence != sequence {
+		respondWithError(w, http.StatusBadRequest, "Chunk sequence does not match the URL")
+		return
+	}
+
+	transfer, err := h.migrationService.ReceiveChunk(r.Context(), vars["id"], r.Header.Get(services.MigrationTokenHeader), &chunk)
+	if err != nil {
+		respondWithMigrationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, transfer)
+}
+
+// StartMigration handles POST /api/v1/migrations/outgoing
+func (h *MigrationsHandler) StartMigration(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.StartMigrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	migration, err := h.migrationService.StartMigration(r.Context(), user.ID, &request)
+	if err != nil {
+		respondWithMigrationError(w, err)
+		return
+	}
+
+	recordActivity(r, h.activityService, user.ID, anomaly.EventExport, 1)
+	respondWithJSON(w, http.StatusAccepted, migration)
+}
+
+// GetMigration handles GET /api/v1/migrations/outgoing/{id}
+func (h *MigrationsHandler) GetMigration(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	migration, err := h.migrationService.GetMigration(r.Context(), user.ID, vars["id"])
+	if err != nil {
+		respondWithMigrationError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, migration)
+}
+
+// ResumeMigration handles POST /api/v1/migrations/outgoing/{id}/resume
+func (h *MigrationsHandler) ResumeMigration(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	migration, err := h.migrationService.ResumeMigration(r.Context(), user.ID, vars["id"])
+	if err != nil {
+		respondWithMigrationError(w, err)
+		return
+	}
+
+	recordActivity(r, h.activityService, user.ID, anomaly.EventExport, 1)
+	respondWithJSON(w, http.StatusAccepted, migration)
+}
+
+// respondWithMigrationError maps migration service errors to HTTP responses
+func respondWithMigrationError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "migration transfer not found", err.Error() == "migration not found":
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case err.Error() == "invalid migration token":
+		respondWithError(w, http.StatusUnauthorized, "Invalid migration token")
+	case strings.HasPrefix(err.Error(), "expected chunk"), strings.HasSuffix(err.Error(), "already completed"),
+		err.Error() == "migration is already running":
+		respondWithError(w, http.StatusConflict, err.Error())
+	case strings.HasPrefix(err.Error(), "destination rejected"):
+		respondWithError(w, http.StatusBadGateway, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+	default:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// rssDocument is an RSS 2.0 feed
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomDocument is an Atom 1.0 feed
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Link      *atomLink   `xml:"link,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// NoteFeedsHandler handles RSS and Atom feeds of recent notes
+type NoteFeedsHandler struct {
+	noteFeedService services.NoteFeedServiceInterface
+}
+
+// NewNoteFeedsHandler creates a new NoteFeedsHandler instance
+func NewNoteFeedsHandler(noteFeedService services.NoteFeedServiceInterface) *NoteFeedsHandler {
+	return &NoteFeedsHandler{
+		noteFeedService: noteFeedService,
+	}
+}
+
+// CreateFeed handles POST /api/v1/feeds
+// The response holds the feed token and URL paths, which are only shown once
+func (h *NoteFeedsHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateNoteFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	feed, err := h.noteFeedService.CreateFeed(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, feed)
+}
+
+// ListFeeds handles GET /api/v1/feeds
+func (h *NoteFeedsHandler) ListFeeds(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	feeds, err := h.noteFeedService.ListFeeds(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"feeds": feeds,
+		"total": len(feeds),
+	})
+}
+
+// DeleteFeed handles DELETE /api/v1/feeds/{id}
+func (h *NoteFeedsHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.noteFeedService.DeleteFeed(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Feed deleted successfully"})
+}
+
+// ServeRSS handles GET /feeds/{token}/rss, authenticated by the token
+func (h *NoteFeedsHandler) ServeRSS(w http.ResponseWriter, r *http.Request) {
+	content, ok := h.feedContent(w, r)
+	if !ok {
+		return
+	}
+
+	base := requestBaseURL(r)
+	document := rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         content.Feed.Name,
+			Link:          base + r.URL.Path,
+			Description:   feedDescription(&content.Feed),
+			LastBuildDate: content.Updated().UTC().Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(content.Items)),
+		},
+	}
+	for _, item := range content.Items {
+		entry := rssItem{
+			Title:       feedItemTitle(item),
+			GUID:        rssGUID{Value: "urn:uuid:" + item.NoteID.String()},
+			PubDate:     item.PublishedAt.UTC().Format(time.RFC1123Z),
+			Description: item.HTML,
+		}
+		if item.Path != "" {
+			entry.Link = base + item.Path
+		}
+		document.Channel.Items = append(document.Channel.Items, entry)
+	}
+
+	writeFeed(w, "application/rss+xml; charset=utf-8", document)
+}
+
+// ServeAtom handles GET /feeds/{token}/atom, authenticated by the token
+func (h *NoteFeedsHandler) ServeAtom(w http.ResponseWriter, r *http.Request) {
+	content, ok := h.feedContent(w, r)
+	if !ok {
+		return
+	}
+
+	base := requestBaseURL(r)
+	document := atomDocument{
+		ID:      "urn:uuid:" + content.Feed.ID.String(),
+		Title:   content.Feed.Name,
+		Updated: content.Updated().UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: base + r.URL.Path, Rel: "self"},
+		Entries: make([]atomEntry, 0, len(content.Items)),
+	}
+	for _, item := range content.Items {
+		entry := atomEntry{
+			ID:        "urn:uuid:" + item.NoteID.String(),
+			Title:     feedItemTitle(item),
+			Published: item.PublishedAt.UTC().Format(time.RFC3339),
+			Updated:   item.UpdatedAt.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Value: item.HTML},
+		}
+		if item.Path != "" {
+			entry.Link = &atomLink{Href: base + item.Path, Rel: "alternate"}
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+
+	writeFeed(w, "application/atom+xml; charset=utf-8", document)
+}
+
+// feedContent returns the feed of the request's token, answering the
+// request when there is none
+func (h *NoteFeedsHandler) feedContent(w http.ResponseWriter, r *http.Request) (*models.NoteFeedContent, bool) {
+	content, err := h.noteFeedService.GetFeedContent(r.Context(), mux.Vars(r)["token"])
+	if errors.Is(err, services.ErrNoteFeedNotFound) {
+		http.Error(w, "Feed not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Internal error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return content, true
+}
+
+// writeFeed writes a feed document as XML. Feeds are private to the token
+// holder, so they are neither cached by proxies nor indexed.
+func writeFeed(w http.ResponseWriter, contentType string, document interface{}) {
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		log.Printf("Internal error: failed to render feed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+// feedDescription describes a feed for RSS readers
+func feedDescription(feed *models.NoteFeed) string {
+	if feed.Kind == models.NoteFeedPublished {
+		return "Recently published notes"
+	}
+	return "Recently created notes"
+}
+
+// feedItemTitle returns the title of a note in a feed
+func feedItemTitle(item models.NoteFeedItem) string {
+	if item.Title == "" {
+		return "Untitled note"
+	}
+	return item.Title
+}
+
+// requestBaseURL returns the scheme and host the request was sent to,
+// honoring the X-Forwarded-Proto header of proxies terminating TLS
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// NotebooksHandler handles HTTP requests for notebooks
+type NotebooksHandler struct {
+	notebookService services.NotebookServiceInterface
+}
+
+// NewNotebooksHandler creates a new NotebooksHandler instance
+func NewNotebooksHandler(notebookService services.NotebookServiceInterface) *NotebooksHandler {
+	return &NotebooksHandler{
+		notebookService: notebookService,
+	}
+}
+
+// CreateNotebook handles POST /api/v1/notebooks
+func (h *NotebooksHandler) CreateNotebook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.NotebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	notebook, err := h.notebookService.CreateNotebook(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, notebook)
+}
+
+// ListNotebooks handles GET /api/v1/notebooks
+func (h *NotebooksHandler) ListNotebooks(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	notebooks, err := h.notebookService.ListNotebooks(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"notebooks": notebooks,
+		"total":     len(notebooks),
+	})
+}
+
+// RenameNotebook handles PUT /api/v1/notebooks/{id}
+func (h *NotebooksHandler) RenameNotebook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.NotebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	notebook, err := h.notebookService.RenameNotebook(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, notebook)
+}
+
+// DeleteNotebook handles DELETE /api/v1/notebooks/{id}
+// Notes of the notebook move to the default notebook
+func (h *NotebooksHandler) DeleteNotebook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.notebookService.DeleteNotebook(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notebook deleted successfully"})
+}
+
+// notebookParam reads the notebook_id query parameter filtering notes
+func notebookParam(r *http.Request) (*uuid.UUID, error) {
+	value := r.URL.Query().Get("notebook_id")
+	if value == "" {
+		return nil, nil
+	}
+	notebookID, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notebook_id %q", value)
+	}
+	return &notebookID, nil
+}
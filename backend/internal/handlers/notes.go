@@ -4,15 +4,22 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gpd/my-notes/internal/anomaly"
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
 	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/validation"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
@@ -21,6 +28,11 @@ type NotesHandler struct {
 	noteService          services.NoteServiceInterface
 	semanticSearchService *services.SemanticSearchService
 	prettifyService      *services.PrettifyService
+	confirmationService  services.ConfirmationServiceInterface
+	activityService      services.ActivityServiceInterface
+	auditService         services.AuditServiceInterface
+	focusTimes           services.FocusTimeSource
+	changeService        services.ChangeServiceInterface
 }
 
 // NewNotesHandler creates a new NotesHandler instance
@@ -28,14 +40,50 @@ func NewNotesHandler(
 	noteService services.NoteServiceInterface,
 	semanticSearchService *services.SemanticSearchService,
 	prettifyService *services.PrettifyService,
+	confirmationService services.ConfirmationServiceInterface,
 ) *NotesHandler {
 	return &NotesHandler{
 		noteService:          noteService,
 		semanticSearchService: semanticSearchService,
 		prettifyService:      prettifyService,
+		confirmationService:  confirmationService,
 	}
 }
 
+// SetActivityService sets the service recording deletions for anomaly detection
+func (h *NotesHandler) SetActivityService(activityService services.ActivityServiceInterface) {
+	h.activityService = activityService
+}
+
+// SetAuditService sets the service recording note actions in the audit log
+func (h *NotesHandler) SetAuditService(auditService services.AuditServiceInterface) {
+	h.auditService = auditService
+}
+
+// SetFocusTimes sets the source of the time spent on notes
+func (h *NotesHandler) SetFocusTimes(focusTimes services.FocusTimeSource) {
+	h.focusTimes = focusTimes
+}
+
+// SetChangeService sets the change log read by syncs after a last_seq
+func (h *NotesHandler) SetChangeService(changeService services.ChangeServiceInterface) {
+	h.changeService = changeService
+}
+
+// addTotalTime sets the time spent on a single note. The note is returned
+// without it when the time cannot be read.
+func (h *NotesHandler) addTotalTime(r *http.Request, noteResponse *models.NoteResponse) {
+	if h.focusTimes == nil {
+		return
+	}
+	totals, err := h.focusTimes.TotalTimes(r.Context(), []uuid.UUID{noteResponse.ID})
+	if err != nil {
+		log.Printf("Failed to get time spent on note %s: %v", noteResponse.ID, err)
+		return
+	}
+	noteResponse.TotalTimeSeconds = totals[noteResponse.ID]
+}
+
 // CreateNote handles POST /api/notes
 func (h *NotesHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
@@ -54,13 +102,19 @@ func (h *NotesHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	if !validateRequest(w, &request) {
+		return
+	}
+
 	// Create note
-	note, err := h.noteService.CreateNote(user.ID.String(), &request)
+	note, err := h.noteService.CreateNote(r.Context(), user.ID.String(), &request)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
+	recordAudit(r, h.auditService, user.ID, models.AuditActionCreate, &note.ID, 1)
+
 	// Get tags for the created note
 	tags := note.ExtractHashtags()
 	noteResponse := note.ToResponse()
@@ -107,11 +161,53 @@ func (h *NotesHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[ListNotes] Query params: limit=%d, offset=%d, order_by=%s, order_dir=%s", limit, offset, orderBy, orderDir)
 
+	// Filtering by notebook, color or property, or sorting by property,
+	// lists notes through search
+	filters, err := propertyFilters(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	color := models.NormalizeNoteColor(r.URL.Query().Get("color"))
+	if err := models.ValidateNoteColor(color); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	notebookID, err := notebookParam(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if sortProperty := r.URL.Query().Get("sort_property"); len(filters) > 0 || sortProperty != "" || color != "" || notebookID != nil {
+		request := &models.SearchNotesRequest{
+			Limit:        limit,
+			Offset:       offset,
+			OrderBy:      orderBy,
+			OrderDir:     orderDir,
+			Properties:   filters,
+			SortProperty: sortProperty,
+			NotebookID:   notebookID,
+		}
+		if color != "" {
+			request.Query = "color:" + color
+		}
+		if !validateRequest(w, request) {
+			return
+		}
+		noteList, err := h.noteService.SearchNotes(r.Context(), user.ID.String(), request)
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, noteList)
+		return
+	}
+
 	// Get notes
-	noteList, err := h.noteService.ListNotes(user.ID.String(), limit, offset, orderBy, orderDir)
+	noteList, err := h.noteService.ListNotes(r.Context(), user.ID.String(), limit, offset, orderBy, orderDir)
 	if err != nil {
 		log.Printf("[ListNotes] ERROR: Failed to list notes for user %s: %v", user.ID, err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -137,13 +233,9 @@ func (h *NotesHandler) GetNote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get note
-	note, err := h.noteService.GetNoteByID(user.ID.String(), noteID)
+	note, err := h.noteService.GetNoteByID(r.Context(), user.ID.String(), noteID)
 	if err != nil {
-		if err.Error() == "note not found" {
-			respondWithError(w, http.StatusNotFound, "Note not found")
-		} else {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -151,11 +243,15 @@ func (h *NotesHandler) GetNote(w http.ResponseWriter, r *http.Request) {
 	tags := note.ExtractHashtags()
 	noteResponse := note.ToResponse()
 	noteResponse.Tags = tags
+	h.addTotalTime(r, &noteResponse)
 
+	w.Header().Set(HeaderETag, noteETag(note.Version))
 	respondWithJSON(w, http.StatusOK, noteResponse)
 }
 
-// UpdateNote handles PUT /api/notes/{id}
+// UpdateNote handles PUT /api/notes/{id}. An If-Match header with the ETag
+// from GET makes the update fail with 412 when the note has changed since;
+// the version field of the body is the older way to do the same.
 func (h *NotesHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value("user").(*models.User)
@@ -181,27 +277,159 @@ func (h *NotesHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	// If-Match pins the update to the version it matched, so an update
+	// racing this one still fails
+	if ifMatch := r.Header.Get(HeaderIfMatch); ifMatch != "" {
+		current, err := h.noteService.GetNoteByID(r.Context(), user.ID.String(), noteID)
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+		if !ifMatches(ifMatch, current.Version) {
+			h.respondWithCurrentNote(w, r, current)
+			return
+		}
+		request.Version = &current.Version
+	}
+
 	// Update note
-	note, err := h.noteService.UpdateNote(user.ID.String(), noteID, &request)
+	note, err := h.noteService.UpdateNote(r.Context(), user.ID.String(), noteID, &request)
 	if err != nil {
-		if err.Error() == "note not found" {
-			respondWithError(w, http.StatusNotFound, "Note not found")
-		} else if strings.Contains(err.Error(), "version mismatch") || strings.Contains(err.Error(), "concurrent update") {
-			respondWithError(w, http.StatusConflict, err.Error())
+		if errors.Is(err, services.ErrVersionMismatch) {
+			current, getErr := h.noteService.GetNoteByID(r.Context(), user.ID.String(), noteID)
+			if getErr != nil {
+				respondWithAppError(w, err)
+				return
+			}
+			h.respondWithCurrentNote(w, r, current)
 		} else {
-			respondWithError(w, http.StatusBadRequest, err.Error())
+			respondWithAppError(w, err)
 		}
 		return
 	}
 
+	recordAudit(r, h.auditService, user.ID, models.AuditActionUpdate, &note.ID, 1)
+
 	// Get tags for the updated note
 	tags := note.ExtractHashtags()
 	noteResponse := note.ToResponse()
 	noteResponse.Tags = tags
+	h.addTotalTime(r, &noteResponse)
 
+	w.Header().Set(HeaderETag, noteETag(note.Version))
 	respondWithJSON(w, http.StatusOK, noteResponse)
 }
 
+// respondWithCurrentNote sends the 412 response of a conditional update that
+// did not match the current note
+func (h *NotesHandler) respondWithCurrentNote(w http.ResponseWriter, r *http.Request, current *models.Note) {
+	noteResponse := current.ToResponse()
+	noteResponse.Tags = current.ExtractHashtags()
+	h.addTotalTime(r, &noteResponse)
+	respondWithPreconditionFailed(w, noteResponse)
+}
+
+// AppendToNote handles PATCH /api/notes/{id}/append
+func (h *NotesHandler) AppendToNote(w http.ResponseWriter, r *http.Request) {
+	h.insertContent(w, r, false)
+}
+
+// PrependToNote handles PATCH /api/notes/{id}/prepend
+func (h *NotesHandler) PrependToNote(w http.ResponseWriter, r *http.Request) {
+	h.insertContent(w, r, true)
+}
+
+// insertContent adds the text of the request to the start or end of a note
+func (h *NotesHandler) insertContent(w http.ResponseWriter, r *http.Request, prepend bool) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Get note ID from URL
+	vars := mux.Vars(r)
+	noteID := vars["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	var request models.InsertContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	note, err := h.noteService.InsertContent(r.Context(), user.ID.String(), noteID, request.Content, prepend)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordAudit(r, h.auditService, user.ID, models.AuditActionUpdate, &note.ID, 1)
+
+	// Get tags for the updated note
+	tags := note.ExtractHashtags()
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = tags
+	h.addTotalTime(r, &noteResponse)
+
+	w.Header().Set(HeaderETag, noteETag(note.Version))
+	respondWithJSON(w, http.StatusOK, noteResponse)
+}
+
+// DuplicateNote handles POST /api/notes/{id}/duplicate
+func (h *NotesHandler) DuplicateNote(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Get note ID from URL
+	vars := mux.Vars(r)
+	noteID := vars["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	// The body is optional; without one the copy keeps the note's title
+	var request models.DuplicateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	note, err := h.noteService.DuplicateNote(r.Context(), user.ID.String(), noteID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordAudit(r, h.auditService, user.ID, models.AuditActionCreate, &note.ID, 1)
+
+	// Get tags for the new note
+	tags := note.ExtractHashtags()
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = tags
+
+	respondWithJSON(w, http.StatusCreated, noteResponse)
+}
+
 // DeleteNote handles DELETE /api/notes/{id}
 func (h *NotesHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
@@ -220,16 +448,19 @@ func (h *NotesHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete note
-	err := h.noteService.DeleteNote(user.ID.String(), noteID)
+	err := h.noteService.DeleteNote(r.Context(), user.ID.String(), noteID)
 	if err != nil {
-		if err.Error() == "note not found" {
-			respondWithError(w, http.StatusNotFound, "Note not found")
-		} else {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+		respondWithAppError(w, err)
 		return
 	}
 
+	recordActivity(r, h.activityService, user.ID, anomaly.EventNoteDeleted, 1)
+	var deletedID *uuid.UUID
+	if id, err := uuid.Parse(noteID); err == nil {
+		deletedID = &id
+	}
+	recordAudit(r, h.auditService, user.ID, models.AuditActionDelete, deletedID, 1)
+
 	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Note deleted successfully"})
 }
 
@@ -285,10 +516,117 @@ func (h *NotesHandler) SearchNotes(w http.ResponseWriter, r *http.Request) {
 	}
 	request.Offset = offset
 
+	// Parse property filters and ordering
+	filters, err := propertyFilters(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request.Properties = filters
+	request.SortProperty = r.URL.Query().Get("sort_property")
+
+	if request.NotebookID, err = notebookParam(r); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Apply the default sort order before checking the request
+	request.Validate()
+	if !validateRequest(w, request) {
+		return
+	}
+
 	// Search notes
-	noteList, err := h.noteService.SearchNotes(user.ID.String(), request)
+	noteList, err := h.noteService.SearchNotes(r.Context(), user.ID.String(), request)
+	if err != nil {
+		var parseErr *search.ParseError
+		if errors.As(err, &parseErr) {
+			respondWithQueryError(w, parseErr)
+			return
+		}
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, noteList)
+}
+
+// ListUntaggedNotes handles GET /api/notes/untagged
+func (h *NotesHandler) ListUntaggedNotes(w http.ResponseWriter, r *http.Request) {
+	request := listRequest(r, "created_at", "desc")
+	request.Query = "is:untagged"
+	h.respondWithSearch(w, r, request)
+}
+
+// ListStaleNotes handles GET /api/notes/stale, listing notes not updated
+// in the last days days, the longest untouched first by default
+func (h *NotesHandler) ListStaleNotes(w http.ResponseWriter, r *http.Request) {
+	days := models.DefaultStaleDays
+	if param := r.URL.Query().Get("days"); param != "" {
+		var err error
+		days, err = strconv.Atoi(param)
+		if err != nil || days < 1 || days > models.MaxStaleDays {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", models.MaxStaleDays))
+			return
+		}
+	}
+
+	request := listRequest(r, "updated_at", "asc")
+	updatedBefore := time.Now().AddDate(0, 0, -days)
+	request.UpdatedBefore = &updatedBefore
+	h.respondWithSearch(w, r, request)
+}
+
+// listRequest returns the search request of a note listing with the
+// pagination and sorting parameters of ListNotes, ordered by orderBy and
+// orderDir when the request does not say
+func listRequest(r *http.Request, orderBy, orderDir string) *models.SearchNotesRequest {
+	request := &models.SearchNotesRequest{
+		OrderBy:  r.URL.Query().Get("order_by"),
+		OrderDir: r.URL.Query().Get("order_dir"),
+	}
+	if request.OrderBy == "" {
+		request.OrderBy = orderBy
+	}
+	if request.OrderDir == "" {
+		request.OrderDir = orderDir
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	request.Limit = limit
+
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	request.Offset = offset
+
+	return request
+}
+
+// respondWithSearch responds with the notes of a search request built by
+// the handler
+func (h *NotesHandler) respondWithSearch(w http.ResponseWriter, r *http.Request, request *models.SearchNotesRequest) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if !validateRequest(w, request) {
+		return
+	}
+
+	noteList, err := h.noteService.SearchNotes(r.Context(), user.ID.String(), request)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -301,7 +639,7 @@ func (h *NotesHandler) handleSemanticSearch(w http.ResponseWriter, r *http.Reque
 
 	notes, duration, err := h.semanticSearchService.Search(ctx, user.ID.String(), query)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -324,6 +662,7 @@ func (h *NotesHandler) handleSemanticSearch(w http.ResponseWriter, r *http.Reque
 }
 
 // GetNotesByTag handles GET /api/notes/tags/{tag}
+// With include_children=true, notes with tags nested below the tag match too.
 func (h *NotesHandler) GetNotesByTag(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value("user").(*models.User)
@@ -359,10 +698,12 @@ func (h *NotesHandler) GetNotesByTag(w http.ResponseWriter, r *http.Request) {
 		offset = 0
 	}
 
+	includeChildren, _ := strconv.ParseBool(r.URL.Query().Get("include_children"))
+
 	// Get notes by tag
-	noteList, err := h.noteService.GetNotesByTag(user.ID.String(), tag, limit, offset)
+	noteList, err := h.noteService.GetNotesByTag(r.Context(), user.ID.String(), tag, includeChildren, limit, offset)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -376,10 +717,14 @@ type syncParams struct {
 	Timestamp      time.Time
 	SyncToken      string
 	IncludeDeleted bool
+	// LastSeq is the change log sequence number the client synced up to,
+	// or nil to sync by cursor or timestamp
+	LastSeq *int64
 }
 
-// parseSyncParams extracts and validates sync query parameters from the request
-func parseSyncParams(r *http.Request) (*syncParams, error) {
+// parseSyncParams extracts and validates sync query parameters from the
+// request. The cursor of the last sync takes precedence over since.
+func parseSyncParams(r *http.Request, now time.Time) (*syncParams, error) {
 	// Parse limit parameter
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	if limit <= 0 || limit > 1000 {
@@ -392,41 +737,59 @@ func parseSyncParams(r *http.Request) (*syncParams, error) {
 		offset = 0
 	}
 
-	// Parse timestamp parameter
+	// Parse cursor or timestamp parameter
 	timestampParam := r.URL.Query().Get("since")
 	var timestamp time.Time
 	var err error
 
-	if timestampParam != "" {
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		timestamp, err = services.ParseSyncCursor(cursor, now)
+		if err != nil {
+			return nil, err
+		}
+	} else if timestampParam != "" {
 		timestamp, err = time.Parse(time.RFC3339, timestampParam)
 		if err != nil {
-			return nil, fmt.Errorf("invalid timestamp format. Use RFC3339 format")
+			return nil, apperrors.Validation("INVALID_TIMESTAMP", "invalid timestamp format. Use RFC3339 format")
 		}
 	} else {
 		// If no timestamp provided, use a default lookback period (24 hours)
-		timestamp = time.Now().Add(-24 * time.Hour)
+		timestamp = now.Add(-24 * time.Hour)
 	}
 
 	// Parse sync token and include deleted flag
 	syncToken := r.URL.Query().Get("sync_token")
 	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
 
-	return &syncParams{
+	params := &syncParams{
 		Limit:          limit,
 		Offset:         offset,
 		Timestamp:      timestamp,
 		SyncToken:      syncToken,
 		IncludeDeleted: includeDeleted,
-	}, nil
+	}
+
+	// Parse last_seq parameter
+	if lastSeqParam := r.URL.Query().Get("last_seq"); lastSeqParam != "" {
+		lastSeq, err := strconv.ParseInt(lastSeqParam, 10, 64)
+		if err != nil || lastSeq < 0 {
+			return nil, services.ErrInvalidCursor
+		}
+		params.LastSeq = &lastSeq
+	}
+
+	return params, nil
 }
 
-// enrichNotesWithSyncMetadata converts notes to NoteResponse objects with tags and sync metadata
-func (h *NotesHandler) enrichNotesWithSyncMetadata(notes []models.Note, conflicts []models.NoteConflict) []models.NoteResponse {
+// enrichNotesWithSyncMetadata converts notes to NoteResponse objects with their tags and sync metadata
+func (h *NotesHandler) enrichNotesWithSyncMetadata(notes []models.Note, tags map[uuid.UUID][]string, conflicts []models.NoteConflict) []models.NoteResponse {
 	var noteResponses []models.NoteResponse
 	for _, note := range notes {
-		tags := note.ExtractHashtags()
 		noteResponse := note.ToResponse()
-		noteResponse.Tags = tags
+		noteResponse.Tags = tags[note.ID]
+		if noteResponse.Tags == nil {
+			noteResponse.Tags = []string{}
+		}
 		noteResponse.SyncMetadata = map[string]interface{}{
 			"sync_version":   note.Version,
 			"conflict_status": h.getConflictStatus(note, conflicts),
@@ -438,7 +801,7 @@ func (h *NotesHandler) enrichNotesWithSyncMetadata(notes []models.Note, conflict
 }
 
 // buildSyncResponse constructs a comprehensive sync response with metadata
-func (h *NotesHandler) buildSyncResponse(noteResponses []models.NoteResponse, total int, params *syncParams, conflicts []models.NoteConflict, syncToken string) models.SyncResponse {
+func (h *NotesHandler) buildSyncResponse(noteResponses []models.NoteResponse, deleted []uuid.UUID, total int, params *syncParams, conflicts []models.NoteConflict, syncToken, cursor string) models.SyncResponse {
 	now := time.Now().Format(time.RFC3339)
 	return models.SyncResponse{
 		Notes:      noteResponses,
@@ -456,10 +819,15 @@ func (h *NotesHandler) buildSyncResponse(noteResponses []models.NoteResponse, to
 			UpdatedNotes:  len(noteResponses),
 			HasConflicts:  len(conflicts) > 0,
 		},
+		Deleted: deleted,
+		Cursor:  cursor,
 	}
 }
 
-// SyncNotes handles GET /api/notes/sync
+// SyncNotes handles GET /api/notes/sync. It returns the notes changed since
+// the cursor of the last sync, including tag-only changes, and the IDs of
+// the notes deleted since. While has_more is true the client pages with the
+// same cursor, and passes the cursor of the first page to its next sync.
 func (h *NotesHandler) SyncNotes(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value("user").(*models.User)
@@ -468,10 +836,11 @@ func (h *NotesHandler) SyncNotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse sync parameters
-	params, err := parseSyncParams(r)
+	// Parse sync parameters; the cursor is taken before reading any change
+	now := time.Now()
+	params, err := parseSyncParams(r, now)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -482,32 +851,125 @@ func (h *NotesHandler) SyncNotes(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if params.LastSeq != nil {
+		h.syncEvents(w, r, user, params)
+		return
+	}
+
 	// Get notes since timestamp with sync support
-	notes, total, err := h.noteService.GetNotesForSync(user.ID.String(), params.Limit, params.Offset, &params.Timestamp, params.IncludeDeleted)
+	notes, total, err := h.noteService.GetNotesForSync(r.Context(), user.ID.String(), params.Limit, params.Offset, &params.Timestamp, params.IncludeDeleted)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
+	// Get notes deleted since timestamp
+	deleted, err := h.noteService.GetDeletedNoteIDs(r.Context(), user.ID.String(), params.Timestamp)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	// Get the tags of the notes, which may have changed without the notes
+	ids := make([]uuid.UUID, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+	tags := map[uuid.UUID][]string{}
+	if len(ids) > 0 {
+		if tags, err = h.noteService.GetTagsForNotes(r.Context(), ids); err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+	}
+
 	// Check for potential conflicts
-	conflicts, err := h.noteService.DetectConflicts(user.ID.String(), notes)
+	conflicts, err := h.noteService.DetectConflicts(r.Context(), user.ID.String(), notes)
 	if err != nil {
 		// Log error but don't fail the sync
 		conflicts = []models.NoteConflict{}
 	}
 
 	// Generate new sync token
-	newSyncToken := h.generateSyncToken(user.ID.String(), time.Now())
+	newSyncToken := h.generateSyncToken(user.ID.String(), now)
 
 	// Enrich notes with sync metadata
-	noteResponses := h.enrichNotesWithSyncMetadata(notes, conflicts)
+	noteResponses := h.enrichNotesWithSyncMetadata(notes, tags, conflicts)
 
 	// Build and send sync response
-	response := h.buildSyncResponse(noteResponses, total, params, conflicts, newSyncToken)
+	response := h.buildSyncResponse(noteResponses, deleted, total, params, conflicts, newSyncToken, services.NewSyncCursor(now))
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-// BatchCreateNotes handles POST /api/notes/batch
+// syncEvents answers a sync after a last_seq with the note changes of the
+// change log in order, the current state of the notes they changed, and the
+// notes deleted. The client passes last_seq of the response to its next sync.
+func (h *NotesHandler) syncEvents(w http.ResponseWriter, r *http.Request, user *models.User, params *syncParams) {
+	if h.changeService == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Change log is not available")
+		return
+	}
+
+	events, err := h.changeService.ListEvents(r.Context(), user.ID.String(), models.ChangeEntityNote, *params.LastSeq, params.Limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	// The last event of each note tells whether it still exists
+	lastOperation := make(map[uuid.UUID]string)
+	var order []uuid.UUID
+	for _, event := range events.Events {
+		if _, seen := lastOperation[event.EntityID]; !seen {
+			order = append(order, event.EntityID)
+		}
+		lastOperation[event.EntityID] = event.Operation
+	}
+	var changed []uuid.UUID
+	deleted := []uuid.UUID{}
+	for _, id := range order {
+		if lastOperation[id] == models.ChangeOperationDeleted {
+			deleted = append(deleted, id)
+		} else {
+			changed = append(changed, id)
+		}
+	}
+
+	// Notes deleted after this page of events are missing and left to the next page
+	notes, err := h.noteService.GetNotesByIDs(r.Context(), user.ID.String(), changed)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	ids := make([]uuid.UUID, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+	tags := map[uuid.UUID][]string{}
+	if len(ids) > 0 {
+		if tags, err = h.noteService.GetTagsForNotes(r.Context(), ids); err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+	}
+
+	conflicts, err := h.noteService.DetectConflicts(r.Context(), user.ID.String(), notes)
+	if err != nil {
+		// Log error but don't fail the sync
+		conflicts = []models.NoteConflict{}
+	}
+
+	noteResponses := h.enrichNotesWithSyncMetadata(notes, tags, conflicts)
+	response := h.buildSyncResponse(noteResponses, deleted, len(noteResponses), params, conflicts,
+		h.generateSyncToken(user.ID.String(), time.Now()), "")
+	response.HasMore = events.HasMore
+	response.Events = events.Events
+	response.LastSeq = &events.LastSeq
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// BatchCreateNotes handles POST /api/notes/batch. With atomic=false each
+// note is created on its own and the outcome of every note is returned.
 func (h *NotesHandler) BatchCreateNotes(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value("user").(*models.User)
@@ -525,6 +987,12 @@ func (h *NotesHandler) BatchCreateNotes(w http.ResponseWriter, r *http.Request)
 	}
 	defer r.Body.Close()
 
+	// Partial batches validate each note on its own
+	atomic := r.URL.Query().Get("atomic") != "false"
+	if atomic && !validateRequest(w, requests) {
+		return
+	}
+
 	// Validate batch size
 	if len(requests) == 0 {
 		respondWithError(w, http.StatusBadRequest, "At least one note is required")
@@ -535,20 +1003,40 @@ func (h *NotesHandler) BatchCreateNotes(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !atomic {
+		results := &models.BatchResults{Results: []models.BatchItemResult{}}
+		for i := range requests {
+			if err := validation.Struct(&requests[i]); err != nil {
+				results.Fail(i, batchItemError(err))
+				continue
+			}
+			notes, err := h.noteService.BatchCreateNotes(r.Context(), user.ID.String(), []*models.CreateNoteRequest{&requests[i]})
+			if err != nil {
+				results.Fail(i, batchItemError(err))
+				continue
+			}
+			recordAudit(r, h.auditService, user.ID, models.AuditActionCreate, &notes[0].ID, 1)
+			results.Succeed(i, models.BatchItemCreated, batchNoteResponse(&notes[0]))
+		}
+		respondWithJSON(w, http.StatusOK, results)
+		return
+	}
+
 	// Create notes in batch
 	requestPointers := make([]*models.CreateNoteRequest, len(requests))
 	for i := range requests {
 		requestPointers[i] = &requests[i]
 	}
-	notes, err := h.noteService.BatchCreateNotes(user.ID.String(), requestPointers)
+	notes, err := h.noteService.BatchCreateNotes(r.Context(), user.ID.String(), requestPointers)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
 	// Convert to response format with tags
 	var noteResponses []models.NoteResponse
 	for _, note := range notes {
+		recordAudit(r, h.auditService, user.ID, models.AuditActionCreate, &note.ID, 1)
 		tags := note.ExtractHashtags()
 		noteResponse := note.ToResponse()
 		noteResponse.Tags = tags
@@ -561,7 +1049,8 @@ func (h *NotesHandler) BatchCreateNotes(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// BatchUpdateNotes handles PUT /api/notes/batch
+// BatchUpdateNotes handles PUT /api/notes/batch. With atomic=false each
+// note is updated on its own and the outcome of every update is returned.
 func (h *NotesHandler) BatchUpdateNotes(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value("user").(*models.User)
@@ -575,7 +1064,7 @@ func (h *NotesHandler) BatchUpdateNotes(w http.ResponseWriter, r *http.Request)
 		Updates []struct {
 			NoteID  string                 `json:"note_id"`
 			Updates models.UpdateNoteRequest `json:"updates"`
-		} `json:"updates"`
+		} `json:"updates" validate:"dive"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&batchRequest); err != nil {
@@ -584,6 +1073,12 @@ func (h *NotesHandler) BatchUpdateNotes(w http.ResponseWriter, r *http.Request)
 	}
 	defer r.Body.Close()
 
+	// Partial batches validate each update on its own
+	atomic := r.URL.Query().Get("atomic") != "false"
+	if atomic && !validateRequest(w, &batchRequest) {
+		return
+	}
+
 	// Validate batch size
 	if len(batchRequest.Updates) == 0 {
 		respondWithError(w, http.StatusBadRequest, "At least one update is required")
@@ -605,20 +1100,36 @@ func (h *NotesHandler) BatchUpdateNotes(w http.ResponseWriter, r *http.Request)
 		updateRequests[i].Request = &update.Updates
 	}
 
+	if !atomic {
+		results := &models.BatchResults{Results: []models.BatchItemResult{}}
+		for i := range updateRequests {
+			if err := validation.Struct(updateRequests[i].Request); err != nil {
+				results.Fail(i, batchItemError(err))
+				continue
+			}
+			notes, err := h.noteService.BatchUpdateNotes(r.Context(), user.ID.String(), updateRequests[i:i+1])
+			if err != nil {
+				results.Fail(i, batchItemError(err))
+				continue
+			}
+			recordAudit(r, h.auditService, user.ID, models.AuditActionUpdate, &notes[0].ID, 1)
+			results.Succeed(i, models.BatchItemUpdated, batchNoteResponse(&notes[0]))
+		}
+		respondWithJSON(w, http.StatusOK, results)
+		return
+	}
+
 	// Update notes in batch
-	notes, err := h.noteService.BatchUpdateNotes(user.ID.String(), updateRequests)
+	notes, err := h.noteService.BatchUpdateNotes(r.Context(), user.ID.String(), updateRequests)
 	if err != nil {
-		if strings.Contains(err.Error(), "version mismatch") {
-			respondWithError(w, http.StatusConflict, err.Error())
-		} else {
-			respondWithError(w, http.StatusBadRequest, err.Error())
-		}
+		respondWithAppError(w, err)
 		return
 	}
 
 	// Convert to response format with tags
 	var noteResponses []models.NoteResponse
 	for _, note := range notes {
+		recordAudit(r, h.auditService, user.ID, models.AuditActionUpdate, &note.ID, 1)
 		tags := note.ExtractHashtags()
 		noteResponse := note.ToResponse()
 		noteResponse.Tags = tags
@@ -631,6 +1142,78 @@ func (h *NotesHandler) BatchUpdateNotes(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// batchNoteResponse returns the response of a note written by a batch, with
+// the tags in its content
+func batchNoteResponse(note *models.Note) *models.NoteResponse {
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+	return &noteResponse
+}
+
+// batchItemError returns the error of an item of a partial batch, listing
+// its invalid fields when it failed validation
+func batchItemError(err error) *models.APIError {
+	var invalid *validation.Error
+	if errors.As(err, &invalid) {
+		return validationErrorBody(invalid)
+	}
+	return appErrorBody(err)
+}
+
+// BatchDeleteNotes handles POST /api/notes/batch/delete
+// Deleting more notes than the configured threshold requires a confirmation code
+func (h *NotesHandler) BatchDeleteNotes(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.BatchDeleteNotesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	// Validate batch size
+	if len(request.NoteIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one note ID is required")
+		return
+	}
+	if len(request.NoteIDs) > 1000 {
+		respondWithError(w, http.StatusBadRequest, "Maximum 1000 notes allowed per batch")
+		return
+	}
+
+	confirmationID, code := confirmationFromRequest(r)
+	err := h.confirmationService.Require(r.Context(), user.ID, models.OperationBulkDelete,
+		noteSetScope(request.NoteIDs), len(request.NoteIDs), confirmationID, code)
+	if err != nil {
+		if !respondWithConfirmationError(w, err) {
+			respondWithAppError(w, err)
+		}
+		return
+	}
+
+	deleted, err := h.noteService.BatchDeleteNotes(r.Context(), user.ID.String(), request.NoteIDs)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	if deleted > 0 {
+		recordActivity(r, h.activityService, user.ID, anomaly.EventNoteDeleted, deleted)
+		recordAudit(r, h.auditService, user.ID, models.AuditActionDelete, nil, deleted)
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"deleted": deleted,
+	})
+}
+
 // GetNoteStats handles GET /api/notes/stats
 func (h *NotesHandler) GetNoteStats(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
@@ -641,9 +1224,9 @@ func (h *NotesHandler) GetNoteStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get basic stats
-	noteList, err := h.noteService.ListNotes(user.ID.String(), 1, 0, "created_at", "desc")
+	noteList, err := h.noteService.ListNotes(r.Context(), user.ID.String(), 1, 0, "created_at", "desc")
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -778,11 +1361,7 @@ func (h *NotesHandler) PrettifyNote(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[PrettifyNote]   Context deadline exceeded: %v", ctx.Err() == context.DeadlineExceeded)
 		log.Printf("[PrettifyNote] ========================================")
 
-		if strings.Contains(err.Error(), "too short") {
-			respondWithError(w, http.StatusBadRequest, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+		respondWithAppError(w, err)
 		return
 	}
 
@@ -793,5 +1372,9 @@ func (h *NotesHandler) PrettifyNote(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[PrettifyNote]   Changes made: %v", result.ChangesMade)
 	log.Printf("[PrettifyNote]   Suggested tags: %v", result.SuggestedTags)
 	log.Printf("[PrettifyNote] ========================================")
+
+	if !result.Unchanged {
+		recordAudit(r, h.auditService, user.ID, models.AuditActionPrettify, &result.ID, 1)
+	}
 	respondWithJSON(w, http.StatusOK, result)
 }
\ No newline at end of file
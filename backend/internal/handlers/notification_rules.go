@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// NotificationRulesHandler handles notification rule HTTP requests
+type NotificationRulesHandler struct {
+	ruleService services.NotificationRuleServiceInterface
+}
+
+// NewNotificationRulesHandler creates a new NotificationRulesHandler instance
+func NewNotificationRulesHandler(ruleService services.NotificationRuleServiceInterface) *NotificationRulesHandler {
+	return &NotificationRulesHandler{
+		ruleService: ruleService,
+	}
+}
+
+// CreateRule handles POST /api/v1/notification-rules
+func (h *NotificationRulesHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateNotificationRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	rule, err := h.ruleService.CreateRule(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, rule)
+}
+
+// ListRules handles GET /api/v1/notification-rules
+func (h *NotificationRulesHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	rules, err := h.ruleService.ListRules(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"rules": rules,
+		"total": len(rules),
+	})
+}
+
+// DeleteRule handles DELETE /api/v1/notification-rules/{id}
+func (h *NotificationRulesHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	ruleID := mux.Vars(r)["id"]
+	if ruleID == "" {
+		respondWithError(w, http.StatusBadRequest, "Rule ID is required")
+		return
+	}
+
+	if err := h.ruleService.DeleteRule(r.Context(), user.ID.String(), ruleID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notification rule deleted successfully"})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// NotificationsHandler handles notification HTTP requests
+type NotificationsHandler struct {
+	notificationService services.NotificationServiceInterface
+}
+
+// NewNotificationsHandler creates a new NotificationsHandler instance
+func NewNotificationsHandler(notificationService services.NotificationServiceInterface) *NotificationsHandler {
+	return &NotificationsHandler{
+		notificationService: notificationService,
+	}
+}
+
+// ListNotifications handles GET /api/v1/notifications
+func (h *NotificationsHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse query parameters
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	list, err := h.notificationService.ListNotifications(r.Context(), user.ID.String(), unreadOnly, limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// MarkRead handles POST /api/v1/notifications/{id}/read
+func (h *NotificationsHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	notificationID := mux.Vars(r)["id"]
+	if notificationID == "" {
+		respondWithError(w, http.StatusBadRequest, "Notification ID is required")
+		return
+	}
+
+	if err := h.notificationService.MarkRead(r.Context(), user.ID.String(), notificationID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notification marked as read"})
+}
+
+// MarkAllRead handles POST /api/v1/notifications/read-all
+func (h *NotificationsHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	count, err := h.notificationService.MarkAllRead(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"marked_read": count})
+}
@@ -0,0 +1,1295 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/importer"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/openapi"
+)
+
+// messageResponse is the data of responses confirming an action
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// Query parameters shared by list routes
+var (
+	limitParam  = openapi.Param{Name: "limit", Type: "integer", Description: "Maximum number of results"}
+	offsetParam = openapi.Param{Name: "offset", Type: "integer", Description: "Number of results to skip"}
+	orderParams = []openapi.Param{
+		{Name: "order_by", Description: "created_at, updated_at or title"},
+		{Name: "order_dir", Description: "asc or desc"},
+	}
+	propertyParams = []openapi.Param{
+		{Name: "prop.{name}", Description: "Notes whose property equals the value; prop.{name}.min and prop.{name}.max bound number and date properties"},
+		{Name: "sort_property", Description: "Property to order notes by instead of order_by, notes without a value last"},
+	}
+	notebookIDParam = openapi.Param{Name: "notebook_id", Description: "Only notes in this notebook"}
+	atomicParam     = openapi.Param{Name: "atomic", Type: "boolean", Description: "false writes each note on its own and returns the outcome of every note as models.BatchResults with 200, instead of failing the whole batch"}
+)
+
+// Query parameters filtering the audit log
+var auditParams = []openapi.Param{
+	{Name: "action", Description: "create, update, delete or prettify"},
+	{Name: "since", Description: "Only entries at or after this RFC 3339 time"},
+	{Name: "until", Description: "Only entries before this RFC 3339 time"},
+	limitParam,
+	offsetParam,
+}
+
+// Header and errors of routes that replay their response to retries
+var (
+	idempotencyHeaders = []openapi.Param{{
+		Name:        "Idempotency-Key",
+		Description: "Unique key of the request; retries with the same key within 24 hours get the original response",
+	}}
+	idempotencyErrors = []int{http.StatusConflict, http.StatusUnprocessableEntity}
+)
+
+// APIRoutes documents every REST route for the OpenAPI spec, keyed by
+// method and path template. Routes added to the router must be added here;
+// building the spec fails for undocumented routes.
+var APIRoutes = map[string]openapi.Route{
+	// Documentation
+	"GET /api/v1/openapi.json": {
+		Summary:  "Get this OpenAPI document",
+		Auth:     openapi.AuthPublic,
+		Response: map[string]interface{}{},
+		Raw:      true,
+	},
+	"GET /p/{slug}": {
+		Summary:             "View a published note",
+		Auth:                openapi.AuthPublic,
+		ResponseContentType: "text/html",
+	},
+	"GET /feeds/{token}/rss": {
+		Summary:             "Read a note feed as RSS",
+		Description:         "Authenticated by the token in the URL. Holds the 50 latest notes of the feed, rendered as HTML.",
+		Auth:                openapi.AuthPublic,
+		ResponseContentType: "application/rss+xml",
+	},
+	"GET /feeds/{token}/atom": {
+		Summary:             "Read a note feed as Atom",
+		Description:         "Authenticated by the token in the URL. Holds the 50 latest notes of the feed, rendered as HTML.",
+		Auth:                openapi.AuthPublic,
+		ResponseContentType: "application/atom+xml",
+	},
+	"GET /api/v1/docs": {
+		Summary:             "Browse the API with Swagger UI",
+		Auth:                openapi.AuthPublic,
+		ResponseContentType: "text/html",
+	},
+
+	// Health and authentication
+	"GET /api/v1/health": {
+		Summary:  "Check that the server is running",
+		Auth:     openapi.AuthPublic,
+		Response: HealthResponse{},
+		Raw:      true,
+	},
+	"POST /api/v1/auth/refresh": {
+		Summary: "Exchange a refresh token for a new token pair",
+		Auth:    openapi.AuthPublic,
+		Request: auth.RefreshTokenRequest{},
+		Response: struct {
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+			TokenType    string `json:"token_type"`
+			ExpiresIn    int    `json:"expires_in"`
+		}{},
+	},
+	"GET /api/v1/auth/validate": {
+		Summary: "Validate an access token",
+		Auth:    openapi.AuthPublic,
+		Response: struct {
+			Valid   bool                `json:"valid"`
+			User    models.UserResponse `json:"user"`
+			Expires time.Time           `json:"expires"`
+		}{},
+	},
+	"POST /api/v1/auth/chrome": {
+		Summary:  "Sign in with a Google token from the Chrome extension",
+		Auth:     openapi.AuthPublic,
+		Request:  ChromeAuthRequest{},
+		Response: ChromeAuthResponse{},
+	},
+	"DELETE /api/v1/auth/logout": {
+		Summary:  "Sign out and revoke the current token",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/auth/sessions": {
+		Summary:  "List active sessions",
+		Response: []models.UserSessionResponse{},
+	},
+	"DELETE /api/v1/auth/sessions/{id}": {
+		Summary:  "Revoke a session",
+		Response: messageResponse{},
+	},
+
+	// Two-factor authentication
+	"GET /api/v1/auth/2fa": {
+		Summary:  "Get the two-factor authentication status",
+		Response: models.TwoFactorStatus{},
+	},
+	"POST /api/v1/auth/2fa/enroll": {
+		Summary:     "Start enrolling an authenticator app",
+		Description: "Returns a new secret and its otpauth:// URI to show as a QR code. It takes effect once confirmed with a code.",
+		Errors:      []int{http.StatusConflict},
+		Response:    models.TwoFactorEnrollment{},
+	},
+	"POST /api/v1/auth/2fa/enable": {
+		Summary:     "Enable two-factor authentication",
+		Description: "Confirms the enrollment with a code from the app. Returns the recovery codes, shown only once, and new tokens for the current session; other sessions must sign in again with a code.",
+		Request:     models.TwoFactorCodeRequest{},
+		Errors:      []int{http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests},
+		Response: struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+			AccessToken   string   `json:"access_token"`
+			RefreshToken  string   `json:"refresh_token"`
+			TokenType     string   `json:"token_type"`
+			ExpiresIn     int      `json:"expires_in"`
+		}{},
+	},
+	"POST /api/v1/auth/2fa/disable": {
+		Summary:  "Disable two-factor authentication",
+		Request:  models.TwoFactorCodeRequest{},
+		Errors:   []int{http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests},
+		Response: messageResponse{},
+	},
+	"POST /api/v1/auth/2fa/recovery-codes": {
+		Summary:     "Replace the recovery codes",
+		Description: "The new codes are shown only once.",
+		Request:     models.TwoFactorCodeRequest{},
+		Errors:      []int{http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests},
+		Response:    models.TwoFactorRecoveryCodes{},
+	},
+
+	// Notes
+	"GET /api/v1/notes": {
+		Summary:  "List notes",
+		Query: append(append([]openapi.Param{
+			limitParam,
+			offsetParam,
+			{Name: "color", Description: "Notes with this color label"},
+			notebookIDParam,
+		}, orderParams...), propertyParams...),
+		Response: models.NoteList{},
+	},
+	"POST /api/v1/notes": {
+		Summary:  "Create a note",
+		Headers:  idempotencyHeaders,
+		Request:  models.CreateNoteRequest{},
+		Status:   http.StatusCreated,
+		Errors:   idempotencyErrors,
+		Response: models.NoteResponse{},
+	},
+	"GET /api/v1/notes/{id}": {
+		Summary:     "Get a note",
+		Description: "The ETag header carries the note version, for If-Match on updates.",
+		Response:    models.NoteResponse{},
+	},
+	"PUT /api/v1/notes/{id}": {
+		Summary: "Update a note",
+		Description: "Updates are rejected with 412 when If-Match, or the deprecated version field, does not match " +
+			"the stored note; error.current carries the stored note. The ETag header carries the new version.",
+		Headers:  []openapi.Param{{Name: "If-Match", Description: "ETag of the note from GET, or *"}},
+		Request:  models.UpdateNoteRequest{},
+		Response: models.NoteResponse{},
+		Errors:   []int{http.StatusPreconditionFailed, http.StatusUnprocessableEntity},
+	},
+	"DELETE /api/v1/notes/{id}": {
+		Summary:  "Delete a note",
+		Response: messageResponse{},
+	},
+	"POST /api/v1/notes/{id}/prettify": {
+		Summary:     "Clean up a note with the LLM",
+		Description: "The prettified content must keep the URLs, hashtags and code blocks of the note. Otherwise the LLM is asked once more, and when it still loses content the note is left unchanged with unchanged set and the lost items listed.",
+		Response:    models.PrettifyNoteResponse{},
+	},
+	"POST /api/v1/notes/{id}/proofread": {
+		Summary:     "Propose spelling and grammar fixes for a note",
+		Description: "Only words are corrected: lines changing their structure, URLs, hashtags, [[links]] or code are kept as they were and listed in kept_lines. The note is unchanged until the proposal is applied; proposals expire after an hour. Without changes no proposal is stored and id is omitted.",
+		Response:    models.ProofreadProposal{},
+		Errors:      []int{http.StatusServiceUnavailable},
+	},
+	"POST /api/v1/notes/{id}/proofread/{proposalID}/apply": {
+		Summary:     "Apply proposed spelling and grammar fixes",
+		Description: "Fails with 409 when the note changed after it was proofread.",
+		Response:    models.NoteResponse{},
+		Errors:      []int{http.StatusConflict},
+	},
+	"DELETE /api/v1/notes/{id}/proofread/{proposalID}": {
+		Summary: "Discard proposed spelling and grammar fixes",
+	},
+	"POST /api/v1/notes/{id}/translate": {
+		Summary:     "Translate a note with the LLM",
+		Description: "Creates the translation as a new note ending in a [[link]] to the original, in the original's notebook when you can write there. The languages of the note are detected; parts already in the requested language are kept. Private notes are refused.",
+		Query:       []openapi.Param{{Name: "lang", Description: "ISO-639-1 code of the language to translate to", Required: true}},
+		Status:      http.StatusCreated,
+		Response:    models.TranslatedNote{},
+		Errors:      []int{http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable},
+	},
+	"PATCH /api/v1/notes/{id}/append": {
+		Summary:     "Append text to a note",
+		Description: "Adds content on a new line at the end of the note, without a version; tags are updated. The ETag header carries the new version.",
+		Request:     models.InsertContentRequest{},
+		Response:    models.NoteResponse{},
+	},
+	"PATCH /api/v1/notes/{id}/prepend": {
+		Summary:     "Prepend text to a note",
+		Description: "Adds content on a line of its own at the start of the note, without a version; tags are updated. The ETag header carries the new version.",
+		Request:     models.InsertContentRequest{},
+		Response:    models.NoteResponse{},
+	},
+	"GET /api/v1/notes/{id}/publish": {
+		Summary:  "Get the publication of a note",
+		Response: models.Publication{},
+	},
+	"POST /api/v1/notes/{id}/publish": {
+		Summary:     "Publish a note",
+		Description: "Publishes the note read-only at /p/{slug}, or changes the slug or noindex option of its publication. Without a slug, new publications get one from the note title. Private notes cannot be published.",
+		Request:     models.PublishNoteRequest{},
+		Response:    models.Publication{},
+		Errors:      []int{http.StatusConflict},
+	},
+	"DELETE /api/v1/notes/{id}/publish": {
+		Summary:  "Unpublish a note",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/notes/{id}/comments": {
+		Summary:  "List the comments on a shared or published note",
+		Query:    []openapi.Param{limitParam, offsetParam},
+		Response: models.CommentList{},
+	},
+	"POST /api/v1/notes/{id}/comments": {
+		Summary:     "Comment on a shared or published note",
+		Description: "Users mentioned as @name or @email among the note's author, organization members and commenters are notified in the app and by email.",
+		Request:     models.CommentRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.Comment{},
+	},
+	"PUT /api/v1/notes/{id}/comments/{commentID}": {
+		Summary:  "Edit a comment",
+		Request:  models.CommentRequest{},
+		Response: models.Comment{},
+	},
+	"DELETE /api/v1/notes/{id}/comments/{commentID}": {
+		Summary:  "Delete a comment",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/publications/{slug}/comments": {
+		Summary:  "List the comments on a published note",
+		Query:    []openapi.Param{limitParam, offsetParam},
+		Response: models.CommentList{},
+	},
+	"POST /api/v1/publications/{slug}/comments": {
+		Summary:  "Comment on a published note",
+		Request:  models.CommentRequest{},
+		Status:   http.StatusCreated,
+		Response: models.Comment{},
+	},
+	"POST /api/v1/notes/{id}/duplicate": {
+		Summary:     "Duplicate a note",
+		Description: "Copies the note and its tags into a new note, in its notebook when writable, or in notebook_id when set.",
+		Request:     models.DuplicateNoteRequest{},
+		Response:    models.NoteResponse{},
+	},
+	"GET /api/v1/notes/sync": {
+		Summary:     "Get notes changed since the last sync",
+		Description: "Returns the notes changed since the cursor of the last sync, including notes whose tags changed, with their tags, and the IDs of the notes deleted since. While has_more is true, page with the same cursor and keep the cursor of the first page for the next sync.",
+		Query: []openapi.Param{
+			limitParam,
+			offsetParam,
+			{Name: "last_seq", Type: "integer", Description: "last_seq returned by the last sync; returns the note changes of the change log after it, in order"},
+			{Name: "cursor", Description: "Cursor returned by the last sync; takes precedence over since"},
+			{Name: "since", Description: "RFC 3339 timestamp of the last sync"},
+			{Name: "sync_token", Description: "Token returned by the last sync"},
+			{Name: "include_deleted", Type: "boolean", Description: "Include deleted notes"},
+		},
+		Response: models.SyncResponse{},
+		Errors:   []int{http.StatusGone},
+	},
+	"POST /api/v1/notes/batch": {
+		Summary: "Create up to 50 notes",
+		Headers: idempotencyHeaders,
+		Query:   []openapi.Param{atomicParam},
+		Request: []models.CreateNoteRequest{},
+		Status:  http.StatusCreated,
+		Errors:  idempotencyErrors,
+		Response: struct {
+			Notes []models.NoteResponse `json:"notes"`
+			Count int                   `json:"count"`
+		}{},
+	},
+	"PUT /api/v1/notes/batch": {
+		Summary: "Update up to 50 notes",
+		Headers: idempotencyHeaders,
+		Query:   []openapi.Param{atomicParam},
+		Errors:  idempotencyErrors,
+		Request: struct {
+			Updates []struct {
+				NoteID  string                   `json:"note_id"`
+				Updates models.UpdateNoteRequest `json:"updates"`
+			} `json:"updates"`
+		}{},
+		Response: struct {
+			Notes []models.NoteResponse `json:"notes"`
+			Count int                   `json:"count"`
+		}{},
+	},
+	"POST /api/v1/notes/batch/delete": {
+		Summary: "Delete several notes",
+		Headers: idempotencyHeaders,
+		Request: models.BatchDeleteNotesRequest{},
+		Errors:  []int{http.StatusConflict, http.StatusUnprocessableEntity, http.StatusPreconditionRequired},
+		Response: struct {
+			Deleted int `json:"deleted"`
+		}{},
+	},
+	"GET /api/v1/notes/stats": {
+		Summary: "Get note statistics",
+		Response: struct {
+			TotalNotes int       `json:"total_notes"`
+			LastSync   time.Time `json:"last_sync"`
+		}{},
+	},
+	"GET /api/v1/notes/tags/{tag}": {
+		Summary: "List notes with a tag",
+		Query: []openapi.Param{
+			limitParam,
+			offsetParam,
+			{Name: "include_children", Type: "boolean", Description: "Include notes tagged with nested tags"},
+		},
+		Response: models.NoteList{},
+	},
+	"GET /api/v1/notes/untagged": {
+		Summary:  "List notes without tags",
+		Query:    append([]openapi.Param{limitParam, offsetParam}, orderParams...),
+		Response: models.NoteList{},
+	},
+	"GET /api/v1/notes/stale": {
+		Summary:     "List notes not updated recently",
+		Description: "Lists notes not updated in the last days days, the longest untouched first unless order_by and order_dir say otherwise.",
+		Query: append([]openapi.Param{
+			{Name: "days", Type: "integer", Description: "Days without updates, 90 by default (max 3650)"},
+			limitParam,
+			offsetParam,
+		}, orderParams...),
+		Response: models.NoteList{},
+	},
+	"GET /api/v1/search/notes": {
+		Summary:     "Search notes",
+		Description: "The query supports tag:, title:, source:, color:, is:untagged, before: and after: filters, quoted phrases and /regex/ terms. Malformed queries fail with INVALID_QUERY and the position of the error.",
+		Query: append([]openapi.Param{
+			{Name: "query", Description: "Search query"},
+			{Name: "tags", Type: "array", Description: "Tags every result must have"},
+			{Name: "semantic", Type: "boolean", Description: "Search by meaning with embeddings"},
+			notebookIDParam,
+			limitParam,
+			offsetParam,
+		}, append(orderParams, propertyParams...)...),
+		Errors:   []int{http.StatusUnprocessableEntity},
+		Response: models.NoteList{},
+	},
+	"GET /api/v1/notes/graph": {
+		Summary:  "Get the graph of links between notes",
+		Query:    []openapi.Param{{Name: "include_orphans", Type: "boolean", Description: "Include notes without links"}},
+		Response: models.NoteGraph{},
+	},
+	"GET /api/v1/notes/{id}/backlinks": {
+		Summary: "List the notes linking to a note",
+		Response: struct {
+			Backlinks []models.Backlink `json:"backlinks"`
+			Total     int               `json:"total"`
+		}{},
+	},
+	"POST /api/v1/notes/{id}/image-text": {
+		Summary:            "Recognize the text in an image of a note",
+		Description:        "Accepts a multipart upload with a file field, or the raw PNG, JPEG, GIF, WebP or BMP image. The recognized text is appended to the note's image_text, which search matches; the image is not stored. Private notes are refused.",
+		RequestContentType: "application/octet-stream",
+		Response:           models.ImageTextResponse{},
+		Errors:             []int{http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable},
+	},
+	"DELETE /api/v1/notes/{id}/image-text": {
+		Summary:  "Remove the text recognized in a note's images",
+		Response: models.NoteResponse{},
+	},
+	"POST /api/v1/notes/normalize": {
+		Summary:     "Convert pasted rich text to Markdown",
+		Description: "Converts HTML copied from Google Docs, Word or a web page, or else the plain text, to Markdown ready to create a note with. Tracking parameters and images are removed. Nothing is stored.",
+		Request:     models.NormalizePasteRequest{},
+		Response:    models.NormalizedPaste{},
+		Errors:      []int{http.StatusRequestEntityTooLarge},
+	},
+	"POST /api/v1/notes/transcribe": {
+		Summary:            "Create a note from a voice memo",
+		Description:        "Accepts a multipart upload with a file field, or the raw MP3, MP4, M4A, WAV, WebM, Ogg or FLAC audio. The transcript becomes a note with the time of each segment; the audio is not stored.",
+		Query:              []openapi.Param{{Name: "title"}, {Name: "tags", Type: "array"}, {Name: "language", Description: "ISO-639-1 code of the spoken language"}, {Name: "prettify", Type: "boolean"}},
+		RequestContentType: "application/octet-stream",
+		Status:             http.StatusCreated,
+		Response:           models.TranscribedNote{},
+		Errors:             []int{http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable},
+	},
+	"POST /api/v1/notes/ask": {
+		Summary:     "Answer a question from your notes",
+		Description: "Clients accepting text/event-stream receive the answer as server-sent events.",
+		Request:     models.AskNotesRequest{},
+		Response:    models.QAAnswer{},
+	},
+	"POST /api/v1/notes/{id}/tags/suggest": {
+		Summary:  "Suggest tags for a note",
+		Response: models.TagSuggestionsResponse{},
+	},
+	"POST /api/v1/notes/{id}/sessions/start": {
+		Summary:  "Start a focus session on a note",
+		Response: models.StartFocusSessionResponse{},
+	},
+	"POST /api/v1/notes/{id}/sessions/stop": {
+		Summary:  "Stop the focus session on a note",
+		Response: models.FocusSession{},
+	},
+	"GET /api/v1/feeds": {
+		Summary: "List note feeds",
+		Response: struct {
+			Feeds []models.NoteFeed `json:"feeds"`
+			Total int               `json:"total"`
+		}{},
+	},
+	"POST /api/v1/feeds": {
+		Summary:     "Create an RSS and Atom feed of recent notes",
+		Description: "Feeds hold recently created (recent) or published (published) notes, of a notebook you can read or of your own notes. The token and feed paths are only returned here.",
+		Request:     models.CreateNoteFeedRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.CreatedNoteFeed{},
+	},
+	"DELETE /api/v1/feeds/{id}": {
+		Summary:  "Delete a note feed",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/inbound-email": {
+		Summary:     "Get your inbound email address",
+		Description: "Mail sent to the address becomes a note: the subject is the title and the plain text body the content. The address is created on first use.",
+		Response:    models.InboundAddress{},
+	},
+	"POST /api/v1/inbound-email/rotate": {
+		Summary:     "Replace your inbound email address",
+		Description: "Mail to the previous address is no longer accepted.",
+		Response:    models.InboundAddress{},
+	},
+	"DELETE /api/v1/inbound-email": {
+		Summary:  "Disable your inbound email address",
+		Response: messageResponse{},
+	},
+	"POST /api/v1/inbound/mailgun": {
+		Summary:            "Receive an email from a Mailgun route",
+		Description:        "Verified by the Mailgun webhook signature. Emails for unknown addresses or that cannot become notes are answered 406 so Mailgun does not retry them.",
+		Auth:               openapi.AuthPublic,
+		RequestContentType: "multipart/form-data",
+		Response: struct {
+			NoteID string `json:"note_id"`
+		}{},
+		Errors: []int{http.StatusRequestEntityTooLarge},
+	},
+	"POST /api/v1/capture": {
+		Summary:     "Capture a note from an integration",
+		Description: "With daily set, the text is appended as bullets to today's daily note instead, answering 200.",
+		Auth:        openapi.AuthAPIKey,
+		Request:     models.CaptureRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.NoteResponse{},
+	},
+	"POST /api/v1/capture/web": {
+		Summary:     "Clip a web page as a note",
+		Description: "The server fetches the page, extracts its readable article as Markdown and creates a note with the source URL, tagged #webclip. With summarize set and an LLM configured, a summary is added above the article.",
+		Auth:        openapi.AuthAPIKey,
+		Request:     models.WebClipRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.NoteResponse{},
+		Errors:      []int{http.StatusRequestEntityTooLarge},
+	},
+
+	// Tags
+	"GET /api/v1/tags": {
+		Summary:  "List tags",
+		Query:    []openapi.Param{limitParam, offsetParam},
+		Response: models.TagList{},
+	},
+	"GET /api/v1/tags/tree": {
+		Summary: "Get nested tags as a tree",
+		Query:   []openapi.Param{{Name: "tag", Description: "Only the subtree under this tag"}},
+		Response: struct {
+			Tags []models.TagTreeNode `json:"tags"`
+		}{},
+	},
+	"GET /api/v1/tags/graph": {
+		Summary:     "Get the tag co-occurrence graph",
+		Description: "Nodes are the most used tags with their note counts; an edge joins two of them with the number of notes having both.",
+		Query:       []openapi.Param{{Name: "limit", Type: "integer", Description: "Number of tags, 100 by default (max 500)"}},
+		Response:    models.TagGraph{},
+	},
+	"GET /api/v1/tags/trending": {
+		Summary:     "Get trending tags",
+		Description: "Compares how often each tag was added to notes in the window with the window before it. New tags come first, then tags by growth.",
+		Query: []openapi.Param{
+			{Name: "window", Description: "Days or hours, such as 7d or 24h; 7d by default (max 365d)"},
+			limitParam,
+		},
+		Response: models.TrendingTags{},
+	},
+	"POST /api/v1/tags/merge": {
+		Summary: "Merge a tag into another",
+		Request: models.MergeTagsRequest{},
+		Errors:  []int{http.StatusUnprocessableEntity},
+		Response: struct {
+			Source      string `json:"source"`
+			Target      string `json:"target"`
+			NotesMerged int    `json:"notes_merged"`
+		}{},
+	},
+	"PUT /api/v1/tags/{id}": {
+		Summary: "Rename a tag",
+		Request: models.UpdateTagRequest{},
+		Errors:  []int{http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity},
+		Response: struct {
+			Tag            models.TagResponse `json:"tag"`
+			NotesRewritten int                `json:"notes_rewritten"`
+		}{},
+	},
+	"GET /api/v1/progress": {
+		Summary:  "Get checklist progress of the notes with a tag",
+		Query:    []openapi.Param{{Name: "tag", Required: true}},
+		Response: models.TagProgress{},
+	},
+	"GET /api/v1/tasks": {
+		Summary: "List checklist items across notes",
+		Query: []openapi.Param{
+			{Name: "tag", Description: "Only tasks of notes with this tag"},
+			{Name: "status", Description: "open (default), done or all"},
+			limitParam,
+			offsetParam,
+		},
+		Response: models.TaskList{},
+	},
+	"PATCH /api/v1/tasks/{id}": {
+		Summary:     "Check or uncheck a task",
+		Description: "Rewrites the task's line in the note. Fails with TASK_CHANGED when the line no longer holds the task, and NOTE_VERSION_CONFLICT when the note is edited at the same time.",
+		Request:     models.UpdateTaskRequest{},
+		Response:    models.Task{},
+		Errors:      []int{http.StatusConflict},
+	},
+	"GET /api/v1/boards": {
+		Summary: "List kanban boards",
+		Response: struct {
+			Boards []models.Board `json:"boards"`
+			Total  int            `json:"total"`
+		}{},
+	},
+	"POST /api/v1/boards": {
+		Summary:  "Create a kanban board from status tags",
+		Request:  models.CreateBoardRequest{},
+		Status:   http.StatusCreated,
+		Response: models.Board{},
+	},
+	"GET /api/v1/boards/{id}": {
+		Summary:     "Get a board with its notes grouped by column",
+		Description: "A note with the tags of several columns is shown in the first of them only.",
+		Query:       []openapi.Param{{Name: "limit", Type: "integer", Description: "Maximum notes per column"}},
+		Response:    models.BoardView{},
+	},
+	"DELETE /api/v1/boards/{id}": {
+		Summary:  "Delete a board, keeping its notes",
+		Response: messageResponse{},
+	},
+	"POST /api/v1/boards/{id}/move": {
+		Summary:     "Move a note to a column",
+		Description: "Swaps the note's status tag in its content and tags in one versioned update. Fails with NOTE_VERSION_CONFLICT when the note is edited at the same time.",
+		Request:     models.MoveCardRequest{},
+		Response:    models.NoteResponse{},
+		Errors:      []int{http.StatusConflict},
+	},
+	"GET /api/v1/calendar": {
+		Summary:     "Count the notes created each day",
+		Description: "Days without notes are left out. The range defaults to the current month and may span up to 366 days.",
+		Query: []openapi.Param{
+			{Name: "from", Description: "First day, YYYY-MM-DD"},
+			{Name: "to", Description: "Last day, YYYY-MM-DD, inclusive"},
+			{Name: "tz", Description: "IANA time zone the days are in, UTC by default"},
+			{Name: "notes_per_day", Type: "integer", Description: "Notes listed per day, 0 for counts only (default 5, max 50)"},
+		},
+		Response: models.Calendar{},
+	},
+	"GET /api/v1/calendar/feed": {
+		Summary:  "Get your iCal feed of due dates and recurring notes",
+		Response: models.CalendarFeed{},
+	},
+	"POST /api/v1/calendar/feed": {
+		Summary:     "Enable your iCal feed of due dates and recurring notes",
+		Description: "Calendar apps subscribe to the returned path. Enabling an enabled feed replaces its token, so the previous URL stops working. The token is only returned here.",
+		Status:      http.StatusCreated,
+		Response:    models.CreatedCalendarFeed{},
+	},
+	"DELETE /api/v1/calendar/feed": {
+		Summary:  "Disable your iCal feed",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/calendar.ics": {
+		Summary:     "Read an iCal feed of due dates and recurring notes",
+		Description: "Authenticated by the feed token. Holds an all-day event per note and date property set on it, and a repeating event per active recurring note.",
+		Auth:        openapi.AuthPublic,
+		Query: []openapi.Param{
+			{Name: "token", Description: "Feed token"},
+		},
+		ResponseContentType: "text/calendar",
+	},
+	"GET /api/v1/templates": {
+		Summary: "List note templates",
+		Response: struct {
+			Templates []models.Template `json:"templates"`
+			Total     int               `json:"total"`
+		}{},
+	},
+	"POST /api/v1/templates": {
+		Summary:  "Create a note template",
+		Request:  models.TemplateRequest{},
+		Status:   http.StatusCreated,
+		Response: models.Template{},
+		Errors:   []int{http.StatusConflict},
+	},
+	"GET /api/v1/templates/gallery": {
+		Summary:     "Browse the public templates of every user",
+		Description: "Popular templates are those most used and cloned.",
+		Query: []openapi.Param{
+			{Name: "q", Description: "Text to find in names and descriptions"},
+			{Name: "category", Description: "Only templates of this category"},
+			{Name: "sort", Description: "popular (default) or recent"},
+			limitParam,
+			offsetParam,
+		},
+		Response: models.TemplateGallery{},
+	},
+	"POST /api/v1/templates/{id}/clone": {
+		Summary:     "Copy a public template into your templates",
+		Description: "The copy is private. Fails with TEMPLATE_EXISTS when you have a template of that name; pass another name.",
+		Request:     models.CloneTemplateRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.Template{},
+		Errors:      []int{http.StatusConflict},
+	},
+	"GET /api/v1/templates/{id}": {
+		Summary:  "Get a note template",
+		Response: models.Template{},
+	},
+	"PUT /api/v1/templates/{id}": {
+		Summary:  "Replace a note template",
+		Request:  models.TemplateRequest{},
+		Response: models.Template{},
+		Errors:   []int{http.StatusConflict},
+	},
+	"DELETE /api/v1/templates/{id}": {
+		Summary:  "Delete a note template, keeping notes created from it",
+		Response: messageResponse{},
+	},
+	"POST /api/v1/templates/{id}/apply": {
+		Summary:     "Render a template without creating a note",
+		Description: "Fills in the placeholders of the title and content and adds the template's tags to the content.",
+		Request:     models.ApplyTemplateRequest{},
+		Response:    models.AppliedTemplate{},
+	},
+	"POST /api/v1/templates/{id}/create-note": {
+		Summary:     "Create a note from a template",
+		Description: "Renders the template and creates the note in one transaction that also increments the template's usage_count.",
+		Request:     models.CreateNoteFromTemplateRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.NoteResponse{},
+	},
+	"GET /api/v1/recurrences": {
+		Summary: "List recurring notes",
+		Response: struct {
+			Recurrences []models.Recurrence `json:"recurrences"`
+			Total       int                 `json:"total"`
+		}{},
+	},
+	"POST /api/v1/recurrences": {
+		Summary:     "Create notes from a template on a schedule",
+		Description: "Runs daily, weekly on a weekday or monthly on a day of the month, at a time of day in a time zone.",
+		Request:     models.CreateRecurrenceRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.Recurrence{},
+	},
+	"PATCH /api/v1/recurrences/{id}": {
+		Summary:     "Pause or resume a recurrence",
+		Description: "Runs missed while paused are skipped.",
+		Request:     models.UpdateRecurrenceRequest{},
+		Response:    models.Recurrence{},
+	},
+	"DELETE /api/v1/recurrences/{id}": {
+		Summary:  "Delete a recurrence, keeping its notes",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/properties": {
+		Summary: "List note properties",
+		Response: struct {
+			Properties []models.PropertyDefinition `json:"properties"`
+			Total      int                         `json:"total"`
+		}{},
+	},
+	"POST /api/v1/properties": {
+		Summary:     "Define a note property",
+		Description: "Properties are typed string, number, date or select fields set on notes with their properties object.",
+		Request:     models.CreatePropertyRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.PropertyDefinition{},
+	},
+	"DELETE /api/v1/properties/{id}": {
+		Summary:  "Delete a note property and its values",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/notebooks": {
+		Summary:     "List notebooks",
+		Description: "The default notebook comes first, then the other personal notebooks and those shared by the user's organizations, each by name and with its note count.",
+		Response: struct {
+			Notebooks []models.Notebook `json:"notebooks"`
+			Total     int               `json:"total"`
+		}{},
+	},
+	"POST /api/v1/notebooks": {
+		Summary:  "Create a notebook",
+		Request:  models.NotebookRequest{},
+		Status:   http.StatusCreated,
+		Errors:   []int{http.StatusConflict},
+		Response: models.Notebook{},
+	},
+	"PUT /api/v1/notebooks/{id}": {
+		Summary:  "Rename a notebook",
+		Request:  models.NotebookRequest{},
+		Errors:   []int{http.StatusConflict},
+		Response: models.Notebook{},
+	},
+	"DELETE /api/v1/notebooks/{id}": {
+		Summary:     "Delete a notebook",
+		Description: "Notes of the notebook move to the default notebook, which cannot be deleted.",
+		Errors:      []int{http.StatusConflict},
+		Response:    messageResponse{},
+	},
+	"GET /api/v1/organizations": {
+		Summary:     "List organizations",
+		Description: "Organizations the user is a member of, with the user's role.",
+		Response: struct {
+			Organizations []models.Organization `json:"organizations"`
+			Total         int                   `json:"total"`
+		}{},
+	},
+	"POST /api/v1/organizations": {
+		Summary:     "Create an organization",
+		Description: "The user becomes its first admin.",
+		Request:     models.CreateOrganizationRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.Organization{},
+	},
+	"PATCH /api/v1/organizations/{id}": {
+		Summary:     "Update an organization",
+		Description: "Admins only. Renames the organization or sets whether exports of the notes in its notebooks are watermarked.",
+		Request:     models.UpdateOrganizationRequest{},
+		Response:    models.Organization{},
+		Errors:      []int{http.StatusForbidden},
+	},
+	"DELETE /api/v1/organizations/{id}": {
+		Summary:     "Delete an organization",
+		Description: "Admins only. Notes of its notebooks move to the default notebooks of their authors.",
+		Errors:      []int{http.StatusForbidden},
+		Response:    messageResponse{},
+	},
+	"GET /api/v1/organizations/{id}/members": {
+		Summary: "List the members of an organization",
+		Response: struct {
+			Members []models.OrgMember `json:"members"`
+			Total   int                `json:"total"`
+		}{},
+	},
+	"PUT /api/v1/organizations/{id}/members/{userID}": {
+		Summary:     "Change the role of a member",
+		Description: "Admins only. The last admin cannot step down.",
+		Request:     models.UpdateMemberRequest{},
+		Errors:      []int{http.StatusForbidden, http.StatusConflict},
+		Response:    models.OrgMember{},
+	},
+	"DELETE /api/v1/organizations/{id}/members/{userID}": {
+		Summary:     "Remove a member",
+		Description: "Admins remove anyone; members remove themselves to leave. The last admin cannot leave.",
+		Errors:      []int{http.StatusForbidden, http.StatusConflict},
+		Response:    messageResponse{},
+	},
+	"GET /api/v1/organizations/{id}/invitations": {
+		Summary:     "List pending invitations",
+		Description: "Admins only.",
+		Errors:      []int{http.StatusForbidden},
+		Response: struct {
+			Invitations []models.OrgInvitation `json:"invitations"`
+			Total       int                    `json:"total"`
+		}{},
+	},
+	"POST /api/v1/organizations/{id}/invitations": {
+		Summary:     "Invite someone by email",
+		Description: "Admins only. The invitation token is emailed to the invitee and returned once; it expires after 7 days.",
+		Request:     models.InviteMemberRequest{},
+		Status:      http.StatusCreated,
+		Errors:      []int{http.StatusForbidden, http.StatusConflict},
+		Response:    models.OrgInvitation{},
+	},
+	"POST /api/v1/invitations/{token}/accept": {
+		Summary:     "Accept an invitation",
+		Description: "The invitation must be for the user's email address.",
+		Response:    models.Organization{},
+	},
+	"GET /api/v1/organizations/{id}/notebooks": {
+		Summary: "List the notebooks of an organization",
+		Response: struct {
+			Notebooks []models.Notebook `json:"notebooks"`
+			Total     int               `json:"total"`
+		}{},
+	},
+	"POST /api/v1/organizations/{id}/notebooks": {
+		Summary:     "Create a shared notebook",
+		Description: "Admins only. Members read its notes; editors and admins also write them.",
+		Request:     models.NotebookRequest{},
+		Status:      http.StatusCreated,
+		Errors:      []int{http.StatusForbidden, http.StatusConflict},
+		Response:    models.Notebook{},
+	},
+	"DELETE /api/v1/organizations/{id}/notebooks/{notebookID}": {
+		Summary:     "Delete a shared notebook",
+		Description: "Admins only. Notes of the notebook move to the default notebooks of their authors.",
+		Errors:      []int{http.StatusForbidden},
+		Response:    messageResponse{},
+	},
+	"GET /api/v1/daily/{date}": {
+		Summary:     "Get the daily note of a date",
+		Description: "The date is YYYY-MM-DD or today. The note is created on first access from the journal template of the account settings, answering 201.",
+		Query: []openapi.Param{
+			{Name: "tz", Description: "IANA time zone of today, UTC by default"},
+		},
+		Response: models.NoteResponse{},
+	},
+	"GET /api/v1/usage/llm": {
+		Summary:     "Get your LLM token usage and budget this month",
+		Description: "Usage covers the current calendar month in UTC, by feature. LLM features fail with 429 LLM_BUDGET_EXCEEDED once the budget is used up.",
+		Response:    models.LLMUsage{},
+	},
+
+	// Changes, analytics and digests
+	"GET /api/v1/changes": {
+		Summary: "Get the feed of note changes",
+		Errors:  []int{http.StatusGone},
+		Query: []openapi.Param{
+			{Name: "since", Description: "Cursor returned by the previous page"},
+			limitParam,
+		},
+		Response: models.ChangeFeed{},
+	},
+	"GET /api/v1/activity": {
+		Summary:     "Get the activity feed",
+		Description: "Notes created, edited, deleted and prettified, tags added, members joining and notebooks shared, newest first: the user's own notes and those of the notebooks and organizations shared with them.",
+		Query: []openapi.Param{
+			{Name: "cursor", Description: "Cursor returned by the previous page, for older events"},
+			{Name: "notebook_id", Description: "Only events of this notebook"},
+			limitParam,
+		},
+		Response: models.Feed{},
+	},
+	"GET /api/v1/analytics/time": {
+		Summary: "Summarize focus time",
+		Query: []openapi.Param{
+			{Name: "period", Description: "day or week"},
+			{Name: "from", Description: "Start date, YYYY-MM-DD"},
+			{Name: "to", Description: "End date, YYYY-MM-DD"},
+			{Name: "tz", Description: "IANA time zone of the buckets"},
+		},
+		Response: models.TimeSummary{},
+	},
+	"GET /api/v1/digest/preview": {
+		Summary:  "Preview the email digest",
+		Query:    []openapi.Param{{Name: "frequency", Description: "daily or weekly"}},
+		Response: models.Digest{},
+	},
+
+	// Saved searches and subscriptions
+	"GET /api/v1/saved-searches": {
+		Summary: "List saved searches",
+		Response: struct {
+			SavedSearches []models.SavedSearch `json:"saved_searches"`
+			Total         int                  `json:"total"`
+		}{},
+	},
+	"POST /api/v1/saved-searches": {
+		Summary:  "Save a search",
+		Request:  models.CreateSavedSearchRequest{},
+		Status:   http.StatusCreated,
+		Response: models.SavedSearch{},
+	},
+	"GET /api/v1/saved-searches/{id}": {
+		Summary:  "Get a saved search",
+		Response: models.SavedSearch{},
+	},
+	"DELETE /api/v1/saved-searches/{id}": {
+		Summary:  "Delete a saved search",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/saved-searches/{id}/notes": {
+		Summary: "Run a saved search",
+		Query:   []openapi.Param{limitParam, offsetParam},
+		Response: struct {
+			SavedSearch models.SavedSearch `json:"saved_search"`
+			Results     models.NoteList    `json:"results"`
+		}{},
+	},
+	"GET /api/v1/subscriptions": {
+		Summary: "List search subscriptions",
+		Response: struct {
+			Subscriptions []models.SearchSubscription `json:"subscriptions"`
+			Total         int                         `json:"total"`
+		}{},
+	},
+	"POST /api/v1/subscriptions": {
+		Summary:  "Subscribe to a search",
+		Request:  models.CreateSearchSubscriptionRequest{},
+		Status:   http.StatusCreated,
+		Response: models.SearchSubscription{},
+	},
+	"DELETE /api/v1/subscriptions/{id}": {
+		Summary:  "Delete a search subscription",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/notifications": {
+		Summary: "List notifications",
+		Query: []openapi.Param{
+			{Name: "unread", Type: "boolean", Description: "Only unread notifications"},
+			limitParam,
+			offsetParam,
+		},
+		Response: models.NotificationList{},
+	},
+	"POST /api/v1/notifications/read-all": {
+		Summary: "Mark all notifications as read",
+		Response: struct {
+			MarkedRead int `json:"marked_read"`
+		}{},
+	},
+	"POST /api/v1/notifications/{id}/read": {
+		Summary:  "Mark a notification as read",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/notification-rules": {
+		Summary: "List notification rules",
+		Response: struct {
+			Rules []models.NotificationRule `json:"rules"`
+			Total int                       `json:"total"`
+		}{},
+	},
+	"POST /api/v1/notification-rules": {
+		Summary:     "Create a notification rule",
+		Description: "Notifies when a note with the tag is created (note_created) or put in a notebook shared with you (note_shared), in the app, by email or to webhooks subscribed to rule.matched.",
+		Request:     models.CreateNotificationRuleRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.NotificationRule{},
+	},
+	"DELETE /api/v1/notification-rules/{id}": {
+		Summary:  "Delete a notification rule",
+		Response: messageResponse{},
+	},
+
+	// Integrations
+	"GET /api/v1/api-keys": {
+		Summary: "List API keys",
+		Response: struct {
+			APIKeys []models.APIKey `json:"api_keys"`
+			Total   int             `json:"total"`
+		}{},
+	},
+	"POST /api/v1/api-keys": {
+		Summary:     "Create an API key",
+		Description: "The key is only returned in this response.",
+		Request:     models.CreateAPIKeyRequest{},
+		Status:      http.StatusCreated,
+		Response:    models.CreatedAPIKey{},
+	},
+	"DELETE /api/v1/api-keys/{id}": {
+		Summary:  "Revoke an API key",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/webhooks": {
+		Summary: "List webhooks",
+		Response: struct {
+			Webhooks []models.Webhook `json:"webhooks"`
+			Total    int              `json:"total"`
+		}{},
+	},
+	"POST /api/v1/webhooks": {
+		Summary:  "Register a webhook",
+		Request:  models.CreateWebhookRequest{},
+		Status:   http.StatusCreated,
+		Response: models.Webhook{},
+	},
+	"DELETE /api/v1/webhooks/{id}": {
+		Summary:  "Delete a webhook",
+		Response: messageResponse{},
+	},
+	"GET /api/v1/webhooks/{id}/deliveries": {
+		Summary: "List recent deliveries of a webhook",
+		Query:   []openapi.Param{limitParam},
+		Response: struct {
+			Deliveries []models.WebhookDelivery `json:"deliveries"`
+			Total      int                      `json:"total"`
+		}{},
+	},
+	"GET /api/v1/audit": {
+		Summary:     "List your audit log",
+		Description: "Note creations, updates, deletions and prettifies with the IP address and user agent they came from, newest first. Entries are kept for AUDIT_RETENTION_DAYS.",
+		Query:       auditParams,
+		Response:    models.AuditLogList{},
+	},
+	"POST /api/v1/graphql": {
+		Summary:     "Query notes and tags with GraphQL",
+		Description: "Responds with a standard GraphQL response instead of the API envelope.",
+		Request:     graphQLRequest{},
+		Response: struct {
+			Data   map[string]interface{}   `json:"data,omitempty"`
+			Errors []map[string]interface{} `json:"errors,omitempty"`
+		}{},
+		Raw: true,
+	},
+
+	// Account
+	"DELETE /api/v1/account": {
+		Summary: "Delete the account and all its data",
+		Description: "Fails with CONFIRMATION_REQUIRED until the request carries the emailed confirmation code. " +
+			"The account is purged when the deletion grace period ends and can be restored until then.",
+		Response: AccountDeletionResponse{},
+		Errors:   []int{http.StatusConflict, http.StatusPreconditionRequired},
+	},
+	"POST /api/v1/account/restore": {
+		Summary:     "Restore an account scheduled for deletion",
+		Description: "Fails with DELETION_NOT_SCHEDULED when the account is not scheduled for deletion.",
+		Response:    messageResponse{},
+		Errors:      []int{http.StatusConflict},
+	},
+	"GET /api/v1/account/data": {
+		Summary:             "Download an export of all account data",
+		Description:         "Streams the account, settings, sessions, saved searches, subscriptions, audit log, templates, personal notebooks, recurrences, comments, webhooks, API key metadata and every note as one JSON document.",
+		ResponseContentType: "application/octet-stream",
+	},
+	"GET /api/v1/account/settings": {
+		Summary:  "Get account settings",
+		Response: models.UserSettings{},
+	},
+	"PUT /api/v1/account/settings": {
+		Summary:  "Update account settings",
+		Request:  models.UpdateUserSettingsRequest{},
+		Response: models.UserSettings{},
+	},
+
+	// Imports and exports
+	"POST /api/v1/imports": {
+		Summary:            "Upload a CSV or JSON file to import",
+		Description:        "Accepts a multipart upload with a file field, or the raw file with a filename query parameter.",
+		Query:              []openapi.Param{{Name: "filename"}},
+		RequestContentType: "application/octet-stream",
+		Status:             http.StatusCreated,
+		Response:           models.ImportSession{},
+		Errors:             []int{http.StatusRequestEntityTooLarge},
+	},
+	"POST /api/v1/imports/archive": {
+		Summary:            "Import a JSON archive of notes",
+		Query:              []openapi.Param{{Name: "filename"}},
+		RequestContentType: "application/octet-stream",
+		Response:           models.ImportSession{},
+	},
+	"POST /api/v1/imports/vault": {
+		Summary:            "Import a ZIP of Markdown files",
+		Query:              []openapi.Param{{Name: "filename"}},
+		RequestContentType: "application/octet-stream",
+		Response:           models.ImportSession{},
+	},
+	"GET /api/v1/imports/{id}": {
+		Summary:  "Get an import",
+		Response: models.ImportSession{},
+	},
+	"POST /api/v1/imports/{id}/mapping": {
+		Summary:  "Map the columns of an import to note fields",
+		Request:  importer.Mapping{},
+		Response: models.ImportSession{},
+	},
+	"POST /api/v1/imports/{id}/execute": {
+		Summary:  "Run an import",
+		Response: models.ImportSession{},
+	},
+	"GET /api/v1/export": {
+		Summary: "Download an export of notes",
+		Query: []openapi.Param{
+			{Name: "format", Description: "Export format, json, csv or pdf"},
+			{Name: "tags", Type: "array", Description: "Only notes with these tags"},
+			{Name: "q", Description: "Only notes matching this search query"},
+			{Name: "since", Description: "Only notes created on or after this date, YYYY-MM-DD"},
+			{Name: "until", Description: "Only notes created before this date, YYYY-MM-DD"},
+			notebookIDParam,
+		},
+		ResponseContentType: "application/octet-stream",
+	},
+
+	// Migrations between deployments
+	"POST /api/v1/migrations/incoming": {
+		Summary:     "Open a transfer to receive notes from another deployment",
+		Description: "The returned token authenticates the source deployment.",
+		Status:      http.StatusCreated,
+		Response:    models.MigrationTransfer{},
+	},
+	"GET /api/v1/migrations/incoming/{id}": {
+		Summary:  "Get a transfer",
+		Auth:     openapi.AuthTransferToken,
+		Response: models.MigrationTransfer{},
+	},
+	"PUT /api/v1/migrations/incoming/{id}/chunks/{sequence}": {
+		Summary:  "Send a chunk of notes to a transfer",
+		Auth:     openapi.AuthTransferToken,
+		Request:  models.MigrationChunk{},
+		Response: models.MigrationTransfer{},
+		Errors:   []int{http.StatusConflict, http.StatusRequestEntityTooLarge},
+	},
+	"POST /api/v1/migrations/outgoing": {
+		Summary:  "Start moving notes to another deployment",
+		Request:  models.StartMigrationRequest{},
+		Status:   http.StatusAccepted,
+		Response: models.OutgoingMigration{},
+	},
+	"GET /api/v1/migrations/outgoing/{id}": {
+		Summary:  "Get an outgoing migration",
+		Response: models.OutgoingMigration{},
+	},
+	"POST /api/v1/migrations/outgoing/{id}/resume": {
+		Summary:  "Resume a failed outgoing migration",
+		Status:   http.StatusAccepted,
+		Response: models.OutgoingMigration{},
+	},
+
+	// Administration
+	"GET /api/v1/admin/anomalies": {
+		Summary: "List account anomalies",
+		Auth:    openapi.AuthAdmin,
+		Query: []openapi.Param{
+			{Name: "status", Description: "open, confirmed or dismissed"},
+			limitParam,
+			offsetParam,
+		},
+		Response: models.AccountAnomalyList{},
+	},
+	"POST /api/v1/admin/anomalies/{id}/review": {
+		Summary:  "Review an account anomaly",
+		Auth:     openapi.AuthAdmin,
+		Request:  models.ReviewAnomalyRequest{},
+		Response: models.AccountAnomaly{},
+	},
+	"GET /api/v1/admin/legal-holds": {
+		Summary: "List legal holds",
+		Auth:    openapi.AuthAdmin,
+		Query: []openapi.Param{
+			{Name: "user_id"},
+			{Name: "active", Type: "boolean", Description: "Only holds not released"},
+		},
+		Response: struct {
+			LegalHolds []models.LegalHold `json:"legal_holds"`
+			Count      int                `json:"count"`
+		}{},
+	},
+	"POST /api/v1/admin/legal-holds": {
+		Summary:  "Place a legal hold on an account",
+		Auth:     openapi.AuthAdmin,
+		Request:  models.CreateLegalHoldRequest{},
+		Status:   http.StatusCreated,
+		Response: models.LegalHold{},
+	},
+	"POST /api/v1/admin/legal-holds/{id}/release": {
+		Summary:  "Release a legal hold",
+		Auth:     openapi.AuthAdmin,
+		Request:  models.ReleaseLegalHoldRequest{},
+		Response: models.LegalHold{},
+	},
+	"GET /api/v1/admin/legal-holds/{id}/audit": {
+		Summary: "Get the audit trail of a legal hold",
+		Auth:    openapi.AuthAdmin,
+		Response: struct {
+			Entries []models.LegalHoldAuditEntry `json:"entries"`
+		}{},
+	},
+	"GET /api/v1/admin/users": {
+		Summary: "List users",
+		Auth:    openapi.AuthAdmin,
+		Query: []openapi.Param{
+			{Name: "q", Description: "Email or name to search for"},
+			limitParam,
+			offsetParam,
+		},
+		Response: models.AdminUserList{},
+	},
+	"GET /api/v1/admin/users/{id}": {
+		Summary:  "Get a user",
+		Auth:     openapi.AuthAdmin,
+		Response: models.AdminUser{},
+	},
+	"POST /api/v1/admin/users/{id}/disable": {
+		Summary:  "Disable a user",
+		Auth:     openapi.AuthAdmin,
+		Request:  models.DisableUserRequest{},
+		Response: models.AdminUser{},
+	},
+	"POST /api/v1/admin/users/{id}/enable": {
+		Summary:  "Enable a user",
+		Auth:     openapi.AuthAdmin,
+		Response: models.AdminUser{},
+	},
+	"PUT /api/v1/admin/users/{id}/role": {
+		Summary:  "Change the role of a user",
+		Auth:     openapi.AuthAdmin,
+		Request:  models.SetUserRoleRequest{},
+		Response: models.AdminUser{},
+	},
+	"PUT /api/v1/admin/users/{id}/llm-budget": {
+		Summary:     "Set the monthly LLM token budget of a user",
+		Description: "A null budget restores the default of LLM_MONTHLY_TOKEN_BUDGET; 0 blocks LLM features for the user.",
+		Auth:        openapi.AuthAdmin,
+		Request:     models.SetLLMBudgetRequest{},
+		Response:    models.LLMUsage{},
+		Errors:      []int{http.StatusUnprocessableEntity},
+	},
+	"GET /api/v1/admin/audit": {
+		Summary:  "List the audit log of every user",
+		Auth:     openapi.AuthAdmin,
+		Query:    append([]openapi.Param{{Name: "user_id", Description: "Only entries of this user"}}, auditParams...),
+		Response: models.AuditLogList{},
+	},
+	"GET /api/v1/admin/prompts": {
+		Summary:  "List LLM prompt templates",
+		Auth:     openapi.AuthAdmin,
+		Response: prompts.Status{},
+	},
+	"POST /api/v1/admin/prompts/reload": {
+		Summary:     "Reload LLM prompt templates",
+		Description: "Reloads the overrides in LLM_PROMPTS_DIR. Invalid templates are rejected and the loaded ones kept. Cached responses of changed prompts are dropped.",
+		Auth:        openapi.AuthAdmin,
+		Response:    prompts.Status{},
+	},
+	"GET /api/v1/admin/maintenance": {
+		Summary: "List maintenance tasks",
+		Auth:    openapi.AuthAdmin,
+		Response: struct {
+			Tasks []string `json:"tasks"`
+		}{},
+	},
+	"POST /api/v1/admin/maintenance/{task}": {
+		Summary:  "Run a maintenance task",
+		Auth:     openapi.AuthAdmin,
+		Response: models.MaintenanceResult{},
+	},
+}
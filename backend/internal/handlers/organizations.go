@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// OrganizationsHandler handles HTTP requests for organizations, their
+// members, invitations and shared notebooks
+type OrganizationsHandler struct {
+	organizationService services.OrganizationServiceInterface
+}
+
+// NewOrganizationsHandler creates a new OrganizationsHandler instance
+func NewOrganizationsHandler(organizationService services.OrganizationServiceInterface) *OrganizationsHandler {
+	return &OrganizationsHandler{
+		organizationService: organizationService,
+	}
+}
+
+// CreateOrganization handles POST /api/v1/organizations
+// The user becomes the organization's first admin
+func (h *OrganizationsHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	organization, err := h.organizationService.CreateOrganization(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, organization)
+}
+
+// ListOrganizations handles GET /api/v1/organizations
+func (h *OrganizationsHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	organizations, err := h.organizationService.ListOrganizations(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"organizations": organizations,
+		"total":         len(organizations),
+	})
+}
+
+// UpdateOrganization handles PATCH /api/v1/organizations/{id}
+// Admins rename the organization or change its export watermark policy
+func (h *OrganizationsHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.UpdateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	organization, err := h.organizationService.UpdateOrganization(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, organization)
+}
+
+// DeleteOrganization handles DELETE /api/v1/organizations/{id}
+// Notes of its notebooks move to the default notebooks of their authors
+func (h *OrganizationsHandler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.organizationService.DeleteOrganization(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Organization deleted successfully"})
+}
+
+// ListMembers handles GET /api/v1/organizations/{id}/members
+func (h *OrganizationsHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	members, err := h.organizationService.ListMembers(r.Context(), user.ID.String(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"members": members,
+		"total":   len(members),
+	})
+}
+
+// UpdateMember handles PUT /api/v1/organizations/{id}/members/{userID}
+func (h *OrganizationsHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	member, err := h.organizationService.UpdateMember(r.Context(), user.ID.String(), vars["id"], vars["userID"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, member)
+}
+
+// RemoveMember handles DELETE /api/v1/organizations/{id}/members/{userID}
+// Members may remove themselves to leave the organization
+func (h *OrganizationsHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.organizationService.RemoveMember(r.Context(), user.ID.String(), vars["id"], vars["userID"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Member removed successfully"})
+}
+
+// InviteMember handles POST /api/v1/organizations/{id}/invitations
+func (h *OrganizationsHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.InviteMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	invitation, err := h.organizationService.InviteMember(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, invitation)
+}
+
+// ListInvitations handles GET /api/v1/organizations/{id}/invitations
+func (h *OrganizationsHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	invitations, err := h.organizationService.ListInvitations(r.Context(), user.ID.String(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"invitations": invitations,
+		"total":       len(invitations),
+	})
+}
+
+// AcceptInvitation handles POST /api/v1/invitations/{token}/accept
+func (h *OrganizationsHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	organization, err := h.organizationService.AcceptInvitation(r.Context(), user, mux.Vars(r)["token"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, organization)
+}
+
+// CreateNotebook handles POST /api/v1/organizations/{id}/notebooks
+func (h *OrganizationsHandler) CreateNotebook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.NotebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	notebook, err := h.organizationService.CreateNotebook(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, notebook)
+}
+
+// ListNotebooks handles GET /api/v1/organizations/{id}/notebooks
+func (h *OrganizationsHandler) ListNotebooks(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	notebooks, err := h.organizationService.ListNotebooks(r.Context(), user.ID.String(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"notebooks": notebooks,
+		"total":     len(notebooks),
+	})
+}
+
+// DeleteNotebook handles DELETE /api/v1/organizations/{id}/notebooks/{notebookID}
+// Notes of the notebook move to the default notebooks of their authors
+func (h *OrganizationsHandler) DeleteNotebook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.organizationService.DeleteNotebook(r.Context(), user.ID.String(), vars["id"], vars["notebookID"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notebook deleted successfully"})
+}
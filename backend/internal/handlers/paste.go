@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// PasteHandler handles pasted content normalization HTTP requests
+type PasteHandler struct {
+	pasteService services.PasteServiceInterface
+}
+
+// NewPasteHandler creates a new PasteHandler instance
+func NewPasteHandler(pasteService services.PasteServiceInterface) *PasteHandler {
+	return &PasteHandler{
+		pasteService: pasteService,
+	}
+}
+
+// NormalizePaste handles POST /api/v1/notes/normalize
+func (h *PasteHandler) NormalizePaste(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	if _, ok := r.Context().Value("user").(*models.User); !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.NormalizePasteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	paste, err := h.pasteService.NormalizePaste(r.Context(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, paste)
+}
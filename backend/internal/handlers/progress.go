@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// ProgressHandler handles checklist progress HTTP requests
+type ProgressHandler struct {
+	progressService services.ProgressServiceInterface
+}
+
+// NewProgressHandler creates a new ProgressHandler instance
+func NewProgressHandler(progressService services.ProgressServiceInterface) *ProgressHandler {
+	return &ProgressHandler{
+		progressService: progressService,
+	}
+}
+
+// GetTagProgress handles GET /api/v1/progress?tag=%23projectX
+func (h *ProgressHandler) GetTagProgress(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+	if tag == "" || tag == "#" {
+		respondWithError(w, http.StatusBadRequest, "Tag is required")
+		return
+	}
+
+	// Ensure tag starts with #
+	if !strings.HasPrefix(tag, "#") {
+		tag = "#" + tag
+	}
+
+	progress, err := h.progressService.GetTagProgress(r.Context(), user.ID.String(), tag)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, progress)
+}
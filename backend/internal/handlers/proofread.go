@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// ProofreadHandler handles HTTP requests for spelling and grammar fixes
+// proposed by the LLM
+type ProofreadHandler struct {
+	proofreadService services.ProofreadServiceInterface
+}
+
+// NewProofreadHandler creates a new ProofreadHandler
+func NewProofreadHandler(proofreadService services.ProofreadServiceInterface) *ProofreadHandler {
+	return &ProofreadHandler{
+		proofreadService: proofreadService,
+	}
+}
+
+// ProofreadNote handles POST /api/v1/notes/{id}/proofread
+// Returns the proposed fixes; the note is only changed once the proposal is
+// applied.
+func (h *ProofreadHandler) ProofreadNote(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	proposal, err := h.proofreadService.ProofreadNote(r.Context(), user.ID.String(), vars["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, proposal)
+}
+
+// ApplyProofread handles POST /api/v1/notes/{id}/proofread/{proposalID}/apply
+func (h *ProofreadHandler) ApplyProofread(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	note, err := h.proofreadService.ApplyProofread(r.Context(), user.ID.String(), vars["id"], vars["proposalID"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, note.ToResponse())
+}
+
+// DiscardProofread handles DELETE /api/v1/notes/{id}/proofread/{proposalID}
+func (h *ProofreadHandler) DiscardProofread(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.proofreadService.DiscardProofread(r.Context(), user.ID.String(), vars["id"], vars["proposalID"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Proofread discarded successfully"})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// propertyParamPrefix prefixes the query parameters filtering notes by
+// property, such as prop.status=done or prop.due.max=2024-03-31
+const propertyParamPrefix = "prop."
+
+// PropertiesHandler handles HTTP requests for custom note properties
+type PropertiesHandler struct {
+	propertyService services.PropertyServiceInterface
+}
+
+// NewPropertiesHandler creates a new PropertiesHandler instance
+func NewPropertiesHandler(propertyService services.PropertyServiceInterface) *PropertiesHandler {
+	return &PropertiesHandler{
+		propertyService: propertyService,
+	}
+}
+
+// CreateProperty handles POST /api/v1/properties
+func (h *PropertiesHandler) CreateProperty(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.CreatePropertyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	property, err := h.propertyService.CreateProperty(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, property)
+}
+
+// ListProperties handles GET /api/v1/properties
+func (h *PropertiesHandler) ListProperties(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	properties, err := h.propertyService.ListProperties(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"properties": properties,
+		"total":      len(properties),
+	})
+}
+
+// DeleteProperty handles DELETE /api/v1/properties/{id}
+// Deletes the property and its values on every note
+func (h *PropertiesHandler) DeleteProperty(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.propertyService.DeleteProperty(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Property deleted successfully"})
+}
+
+// propertyFilters returns the property filters of a query: prop.name=value
+// matches equal values, and prop.name.min and prop.name.max bound number
+// and date properties
+func propertyFilters(query url.Values) ([]models.PropertyFilter, error) {
+	var filters []models.PropertyFilter
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, propertyParamPrefix)
+		if !ok {
+			continue
+		}
+		op := models.PropertyFilterEq
+		if base, suffix, found := strings.Cut(name, "."); found {
+			name, op = base, suffix
+			if op != models.PropertyFilterMin && op != models.PropertyFilterMax {
+				return nil, fmt.Errorf("invalid property filter %s, expected %s<name>, .min or .max", key, propertyParamPrefix)
+			}
+		}
+		for _, value := range values {
+			filters = append(filters, models.PropertyFilter{Name: name, Op: op, Value: value})
+		}
+	}
+
+	// Query parameters are unordered; sort them so queries are stable
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Name != filters[j].Name {
+			return filters[i].Name < filters[j].Name
+		}
+		return filters[i].Op < filters[j].Op
+	})
+	return filters, nil
+}
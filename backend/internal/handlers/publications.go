@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// publishedNoteContentSecurityPolicy replaces the API policy on published
+// notes: the page runs no scripts and loads nothing but its inline styles
+const publishedNoteContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; " +
+	"base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// publishedNotePage is the page of a published note. The content is HTML
+// sanitized by the markdown package.
+var publishedNotePage = template.Must(template.New("published").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  {{- if .NoIndex}}
+  <meta name="robots" content="noindex">
+  {{- end}}
+  <title>{{.Title}}</title>
+  <style>
+    body { max-width: 42rem; margin: 2rem auto; padding: 0 1rem; font: 1.05rem/1.6 system-ui, sans-serif; color: #222; }
+    pre { overflow-x: auto; padding: 0.75rem; background: #f4f4f4; }
+    code { font-family: ui-monospace, monospace; }
+    blockquote { margin-left: 0; padding-left: 1rem; border-left: 3px solid #ccc; color: #555; }
+    footer { margin-top: 3rem; font-size: 0.85rem; color: #777; }
+  </style>
+</head>
+<body>
+  <article>
+    {{- if .ShowTitle}}
+    <h1>{{.Title}}</h1>
+    {{- end}}
+    {{.Content}}
+  </article>
+  {{- if not .UpdatedAt.IsZero}}
+  <footer>Updated {{.UpdatedAt.Format "January 2, 2006"}}</footer>
+  {{- end}}
+</body>
+</html>
+`))
+
+// publishedNoteView is the data of publishedNotePage
+type publishedNoteView struct {
+	Title     string
+	ShowTitle bool
+	Content   template.HTML
+	NoIndex   bool
+	UpdatedAt time.Time
+}
+
+// PublicationsHandler handles publishing notes and serves published notes
+type PublicationsHandler struct {
+	publicationService services.PublicationServiceInterface
+}
+
+// NewPublicationsHandler creates a new PublicationsHandler instance
+func NewPublicationsHandler(publicationService services.PublicationServiceInterface) *PublicationsHandler {
+	return &PublicationsHandler{
+		publicationService: publicationService,
+	}
+}
+
+// PublishNote handles POST /api/v1/notes/{id}/publish
+func (h *PublicationsHandler) PublishNote(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID := mux.Vars(r)["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	// The body is optional; without one the note gets a slug from its title
+	var request models.PublishNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	publication, err := h.publicationService.PublishNote(r.Context(), user.ID.String(), noteID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, publication)
+}
+
+// GetPublication handles GET /api/v1/notes/{id}/publish
+func (h *PublicationsHandler) GetPublication(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID := mux.Vars(r)["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	publication, err := h.publicationService.GetPublication(r.Context(), user.ID.String(), noteID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, publication)
+}
+
+// UnpublishNote handles DELETE /api/v1/notes/{id}/publish
+func (h *PublicationsHandler) UnpublishNote(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID := mux.Vars(r)["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	if err := h.publicationService.UnpublishNote(r.Context(), user.ID.String(), noteID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Note unpublished successfully"})
+}
+
+// ServePublishedNote handles GET /p/{slug}, the public page of a published
+// note, without authentication
+func (h *PublicationsHandler) ServePublishedNote(w http.ResponseWriter, r *http.Request) {
+	view := publishedNoteView{Title: "Note not found", ShowTitle: true}
+	status := http.StatusOK
+
+	published, err := h.publicationService.GetPublishedNote(r.Context(), mux.Vars(r)["slug"])
+	switch {
+	case errors.Is(err, services.ErrPublicationNotFound):
+		status = http.StatusNotFound
+		view.NoIndex = true
+	case err != nil:
+		log.Printf("Internal error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	default:
+		view = publishedNoteView{
+			Title:     published.Title,
+			ShowTitle: published.Title != "",
+			Content:   template.HTML(published.HTML),
+			NoIndex:   published.NoIndex,
+			UpdatedAt: published.UpdatedAt,
+		}
+		if view.Title == "" {
+			view.Title = "Untitled note"
+		}
+	}
+
+	var page bytes.Buffer
+	if err := publishedNotePage.Execute(&page, view); err != nil {
+		log.Printf("Internal error: failed to render published note: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", publishedNoteContentSecurityPolicy)
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	if view.NoIndex {
+		w.Header().Set("X-Robots-Tag", "noindex")
+	}
+	w.WriteHeader(status)
+	w.Write(page.Bytes())
+}
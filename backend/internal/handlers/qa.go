@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// qaStreamTimeout bounds how long a streamed answer may take
+const qaStreamTimeout = 2 * time.Minute
+
+// QAHandler handles question answering over notes
+type QAHandler struct {
+	qaService services.QAServiceInterface
+}
+
+// NewQAHandler creates a new QAHandler instance
+func NewQAHandler(qaService services.QAServiceInterface) *QAHandler {
+	return &QAHandler{
+		qaService: qaService,
+	}
+}
+
+// AskNotes handles POST /api/v1/notes/ask. Clients accepting
+// text/event-stream receive the answer as server-sent events while it is
+// generated; other clients receive the complete answer as JSON.
+func (h *QAHandler) AskNotes(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.AskNotesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	qa, err := h.qaService.PrepareAnswer(r.Context(), user.ID.String(), request.Question)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "question") {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			respondWithAppError(w, err)
+		}
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		answer, err := h.qaService.StreamAnswer(r.Context(), qa, func(string) error { return nil })
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, answer)
+		return
+	}
+
+	h.streamAnswer(w, r, qa)
+}
+
+// streamAnswer writes the answer as server-sent events: "sources" first, then
+// "answer" chunks, then "citations" and finally "done", or "error" on failure
+func (h *QAHandler) streamAnswer(w http.ResponseWriter, r *http.Request, qa *services.QAContext) {
+	ctx, cancel := context.WithTimeout(r.Context(), qaStreamTimeout)
+	defer cancel()
+
+	// The server write timeout is shorter than a generated answer may take
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Now().Add(qaStreamTimeout)); err != nil {
+		log.Printf("Failed to extend write deadline for answer stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	if err := send("sources", qa.Sources); err != nil {
+		return
+	}
+
+	answer, err := h.qaService.StreamAnswer(ctx, qa, func(chunk string) error {
+		return send("answer", map[string]string{"text": chunk})
+	})
+	if err != nil {
+		log.Printf("Failed to stream answer for question: %v", err)
+		if errors.Is(err, services.ErrLLMBudgetExceeded) {
+			send("error", map[string]string{"code": apperrors.Code(err), "message": "Monthly LLM token budget exceeded"})
+			return
+		}
+		send("error", map[string]string{"message": "Failed to generate answer"})
+		return
+	}
+
+	if err := send("citations", answer.Citations); err != nil {
+		return
+	}
+	send("done", map[string]interface{}{})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// RecurrencesHandler handles HTTP requests for notes created from templates
+// on a schedule
+type RecurrencesHandler struct {
+	recurrenceService services.RecurrenceServiceInterface
+}
+
+// NewRecurrencesHandler creates a new RecurrencesHandler instance
+func NewRecurrencesHandler(recurrenceService services.RecurrenceServiceInterface) *RecurrencesHandler {
+	return &RecurrencesHandler{
+		recurrenceService: recurrenceService,
+	}
+}
+
+// CreateRecurrence handles POST /api/v1/recurrences
+func (h *RecurrencesHandler) CreateRecurrence(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.CreateRecurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	recurrence, err := h.recurrenceService.CreateRecurrence(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, recurrence)
+}
+
+// ListRecurrences handles GET /api/v1/recurrences
+func (h *RecurrencesHandler) ListRecurrences(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	recurrences, err := h.recurrenceService.ListRecurrences(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"recurrences": recurrences,
+		"total":       len(recurrences),
+	})
+}
+
+// UpdateRecurrence handles PATCH /api/v1/recurrences/{id}
+// Pauses or resumes a recurrence
+func (h *RecurrencesHandler) UpdateRecurrence(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.UpdateRecurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	recurrence, err := h.recurrenceService.SetRecurrencePaused(r.Context(), user.ID.String(), mux.Vars(r)["id"], *request.Paused)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, recurrence)
+}
+
+// DeleteRecurrence handles DELETE /api/v1/recurrences/{id}
+func (h *RecurrencesHandler) DeleteRecurrence(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.recurrenceService.DeleteRecurrence(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Recurrence deleted successfully"})
+}
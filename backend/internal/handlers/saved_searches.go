@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// SavedSearchesHandler handles saved search (smart folder) HTTP requests
+type SavedSearchesHandler struct {
+	savedSearchService services.SavedSearchServiceInterface
+}
+
+// NewSavedSearchesHandler creates a new SavedSearchesHandler instance
+func NewSavedSearchesHandler(savedSearchService services.SavedSearchServiceInterface) *SavedSearchesHandler {
+	return &SavedSearchesHandler{
+		savedSearchService: savedSearchService,
+	}
+}
+
+// CreateSavedSearch handles POST /api/v1/saved-searches
+func (h *SavedSearchesHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateSavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	saved, err := h.savedSearchService.CreateSavedSearch(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		var parseErr *search.ParseError
+		if errors.As(err, &parseErr) {
+			respondWithQueryError(w, parseErr)
+		} else {
+			respondWithAppError(w, err)
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, saved)
+}
+
+// ListSavedSearches handles GET /api/v1/saved-searches
+func (h *SavedSearchesHandler) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	searches, err := h.savedSearchService.ListSavedSearches(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"saved_searches": searches,
+		"total":          len(searches),
+	})
+}
+
+// GetSavedSearch handles GET /api/v1/saved-searches/{id}
+func (h *SavedSearchesHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	saved, err := h.savedSearchService.GetSavedSearch(r.Context(), user.ID.String(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, saved)
+}
+
+// DeleteSavedSearch handles DELETE /api/v1/saved-searches/{id}
+func (h *SavedSearchesHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.savedSearchService.DeleteSavedSearch(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Saved search deleted successfully"})
+}
+
+// ExecuteSavedSearch handles GET /api/v1/saved-searches/{id}/notes
+func (h *SavedSearchesHandler) ExecuteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse pagination
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	saved, noteList, err := h.savedSearchService.ExecuteSavedSearch(r.Context(), user.ID.String(), mux.Vars(r)["id"], limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"saved_search": saved,
+		"results":      noteList,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// SubscriptionsHandler handles search subscription HTTP requests
+type SubscriptionsHandler struct {
+	subscriptionService services.SubscriptionServiceInterface
+}
+
+// NewSubscriptionsHandler creates a new SubscriptionsHandler instance
+func NewSubscriptionsHandler(subscriptionService services.SubscriptionServiceInterface) *SubscriptionsHandler {
+	return &SubscriptionsHandler{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// CreateSubscription handles POST /api/v1/subscriptions
+func (h *SubscriptionsHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateSearchSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	subscription, err := h.subscriptionService.CreateSubscription(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		var parseErr *search.ParseError
+		if errors.As(err, &parseErr) {
+			respondWithQueryError(w, parseErr)
+			return
+		}
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, subscription)
+}
+
+// ListSubscriptions handles GET /api/v1/subscriptions
+func (h *SubscriptionsHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	subscriptions, err := h.subscriptionService.ListSubscriptions(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"subscriptions": subscriptions,
+		"total":         len(subscriptions),
+	})
+}
+
+// DeleteSubscription handles DELETE /api/v1/subscriptions/{id}
+func (h *SubscriptionsHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	subscriptionID := mux.Vars(r)["id"]
+	if subscriptionID == "" {
+		respondWithError(w, http.StatusBadRequest, "Subscription ID is required")
+		return
+	}
+
+	if err := h.subscriptionService.DeleteSubscription(r.Context(), user.ID.String(), subscriptionID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Subscription deleted successfully"})
+}
@@ -1,25 +1,42 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
 	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 // TagsHandler handles tag-related HTTP requests
 type TagsHandler struct {
-	tagService services.TagServiceInterface
+	tagService          services.TagServiceInterface
+	noteService         services.NoteServiceInterface
+	confirmationService services.ConfirmationServiceInterface
+	userService         services.UserServiceInterface
 }
 
 // NewTagsHandler creates a new TagsHandler instance
-func NewTagsHandler(tagService services.TagServiceInterface) *TagsHandler {
+func NewTagsHandler(tagService services.TagServiceInterface, noteService services.NoteServiceInterface, confirmationService services.ConfirmationServiceInterface) *TagsHandler {
 	return &TagsHandler{
-		tagService: tagService,
+		tagService:          tagService,
+		noteService:         noteService,
+		confirmationService: confirmationService,
 	}
 }
 
+// SetUserService sets the service providing the auto-apply tag suggestions setting
+func (h *TagsHandler) SetUserService(userService services.UserServiceInterface) {
+	h.userService = userService
+}
+
 // GetTags handles GET /api/v1/tags
 func (h *TagsHandler) GetTags(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
@@ -44,11 +61,288 @@ func (h *TagsHandler) GetTags(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get tags for user
-	tagList, err := h.tagService.GetAllTags(user.ID.String(), limit, offset)
+	tagList, err := h.tagService.GetAllTags(r.Context(), user.ID.String(), limit, offset)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithAppError(w, err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, tagList)
 }
+
+// GetTagTree handles GET /api/v1/tags/tree
+// Returns the user's tags nested below their parents. The optional tag query
+// parameter limits the tree to the subtree below that tag.
+func (h *TagsHandler) GetTagTree(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+	if tag != "" {
+		tag = search.NormalizeTag(tag)
+	}
+
+	tree, err := h.tagService.GetTagTree(r.Context(), user.ID.String(), tag)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tree,
+	})
+}
+
+// GetTagGraph handles GET /api/v1/tags/graph
+// Returns the user's most used tags, limited by the limit query parameter,
+// and how many notes each pair of them shares.
+func (h *TagsHandler) GetTagGraph(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	graph, err := h.tagService.GetTagGraph(r.Context(), user.ID.String(), limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, graph)
+}
+
+// GetTrendingTags handles GET /api/v1/tags/trending
+// Compares tag use in the window query parameter, such as 7d, with the
+// window before it.
+func (h *TagsHandler) GetTrendingTags(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	trending, err := h.tagService.GetTrendingTags(r.Context(), user.ID.String(), r.URL.Query().Get("window"), limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, trending)
+}
+
+// MergeTags handles POST /api/v1/tags/merge
+// Replaces the source hashtag with the target in the user's notes. Merges
+// affecting more notes than the configured threshold require a confirmation code.
+func (h *TagsHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.MergeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	source := search.NormalizeTag(strings.TrimSpace(request.Source))
+	target := search.NormalizeTag(strings.TrimSpace(request.Target))
+	if source == "#" || target == "#" {
+		respondWithError(w, http.StatusBadRequest, "Source and target tags are required")
+		return
+	}
+
+	// Count the affected notes to decide whether a confirmation is needed
+	affected, err := h.noteService.GetNotesByTag(r.Context(), user.ID.String(), source, false, 1, 0)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	confirmationID, code := confirmationFromRequest(r)
+	err = h.confirmationService.Require(r.Context(), user.ID, models.OperationMergeTags,
+		"tags:"+source+">"+target, affected.Total, confirmationID, code)
+	if err != nil {
+		if !respondWithConfirmationError(w, err) {
+			respondWithAppError(w, err)
+		}
+		return
+	}
+
+	merged, err := h.noteService.MergeTags(r.Context(), user.ID.String(), source, target)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"source":       source,
+		"target":       target,
+		"notes_merged": merged,
+	})
+}
+
+// UpdateTag handles PUT /api/v1/tags/{id}
+// Renames a tag and the nested tags below it. With rewrite_content, the
+// hashtag is also rewritten in the user's notes; rewriting more notes than
+// the configured threshold requires a confirmation code.
+func (h *TagsHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	tagID := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(tagID); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	// Parse request body
+	var request models.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	if request.RewriteContent {
+		tag, err := h.tagService.GetTagByID(r.Context(), user.ID.String(), tagID)
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+
+		// Count the affected notes to decide whether a confirmation is needed
+		affected, err := h.noteService.GetNotesByTag(r.Context(), user.ID.String(), tag.Name, true, 1, 0)
+		if err != nil {
+			respondWithAppError(w, err)
+			return
+		}
+
+		confirmationID, code := confirmationFromRequest(r)
+		err = h.confirmationService.Require(r.Context(), user.ID, models.OperationRenameTag,
+			"tags:"+tagID+">"+request.Name, affected.Total, confirmationID, code)
+		if err != nil {
+			if !respondWithConfirmationError(w, err) {
+				respondWithAppError(w, err)
+			}
+			return
+		}
+	}
+
+	tag, rewritten, err := h.noteService.RenameTag(r.Context(), user.ID.String(), tagID, &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"tag":             tag.ToResponse(),
+		"notes_rewritten": rewritten,
+	})
+}
+
+// SuggestTags handles POST /api/v1/notes/{id}/tags/suggest
+// Suggests hashtags for a note. For users who opted in, confident suggestions
+// are added to the note right away.
+func (h *TagsHandler) SuggestTags(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID := mux.Vars(r)["id"]
+	if noteID == "" {
+		respondWithError(w, http.StatusBadRequest, "Note ID is required")
+		return
+	}
+
+	suggestions, err := h.tagService.SuggestTagsForNote(r.Context(), user.ID.String(), noteID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	response := models.TagSuggestionsResponse{
+		Suggestions: suggestions,
+		Applied:     []string{},
+	}
+	response.NoteID, _ = uuid.Parse(noteID)
+
+	if h.userService != nil {
+		settings, err := h.userService.GetSettings(r.Context(), user.ID.String())
+		if err != nil {
+			log.Printf("[TagsHandler] WARNING: Failed to get settings of user %s: %v", user.ID, err)
+		} else if settings.AutoApplyTagSuggestions {
+			if note, applied := h.applySuggestions(r.Context(), user.ID.String(), noteID, suggestions); note != nil {
+				noteResponse := note.ToResponse()
+				noteResponse.Tags = note.ExtractHashtags()
+				response.Note = &noteResponse
+				response.Applied = applied
+			}
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// applySuggestions appends confident suggestions to the note content and
+// returns the updated note, or nil when nothing was applied
+func (h *TagsHandler) applySuggestions(ctx context.Context, userID, noteID string, suggestions []models.TagSuggestion) (*models.Note, []string) {
+	var applied []string
+	for _, suggestion := range suggestions {
+		if suggestion.Confidence >= services.AutoApplyConfidence {
+			applied = append(applied, suggestion.Tag)
+		}
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	note, err := h.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		log.Printf("[TagsHandler] WARNING: Failed to get note %s for tag suggestions: %v", noteID, err)
+		return nil, nil
+	}
+
+	content := strings.TrimRight(note.Content, " \t\n") + "\n\n" + strings.Join(applied, " ")
+	updated, err := h.noteService.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{
+		Content: &content,
+		Version: &note.Version,
+	})
+	if err != nil {
+		// The note changed in the meantime; the suggestions are still returned
+		log.Printf("[TagsHandler] WARNING: Failed to apply tag suggestions to note %s: %v", noteID, err)
+		return nil, nil
+	}
+
+	return updated, applied
+}
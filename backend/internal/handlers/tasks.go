@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// TasksHandler handles HTTP requests for the checklist items of notes
+type TasksHandler struct {
+	taskService  services.TaskServiceInterface
+	auditService services.AuditServiceInterface
+}
+
+// NewTasksHandler creates a new TasksHandler instance
+func NewTasksHandler(taskService services.TaskServiceInterface) *TasksHandler {
+	return &TasksHandler{
+		taskService: taskService,
+	}
+}
+
+// SetAuditService sets the service recording task toggles as note updates
+func (h *TasksHandler) SetAuditService(auditService services.AuditServiceInterface) {
+	h.auditService = auditService
+}
+
+// ListTasks handles GET /api/v1/tasks?tag=%23projectX&status=open
+func (h *TasksHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	filter := models.TaskFilter{
+		Tag:    strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag"))),
+		Status: r.URL.Query().Get("status"),
+	}
+	// Tags are stored lowercase and start with #
+	if filter.Tag != "" && !strings.HasPrefix(filter.Tag, "#") {
+		filter.Tag = "#" + filter.Tag
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	list, err := h.taskService.ListTasks(r.Context(), user.ID.String(), filter, limit, offset)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+// UpdateTask handles PATCH /api/v1/tasks/{id}
+// Checks or unchecks the task by rewriting its line in the note
+func (h *TasksHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.UpdateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	task, err := h.taskService.SetTaskChecked(r.Context(), user.ID.String(), mux.Vars(r)["id"], *request.Checked)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordAudit(r, h.auditService, user.ID, models.AuditActionUpdate, &task.NoteID, 1)
+	respondWithJSON(w, http.StatusOK, task)
+}
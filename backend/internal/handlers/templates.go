@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// TemplatesHandler handles note template HTTP requests
+type TemplatesHandler struct {
+	templateService services.TemplateServiceInterface
+	auditService    services.AuditServiceInterface
+}
+
+// NewTemplatesHandler creates a new TemplatesHandler instance
+func NewTemplatesHandler(templateService services.TemplateServiceInterface) *TemplatesHandler {
+	return &TemplatesHandler{
+		templateService: templateService,
+	}
+}
+
+// SetAuditService sets the service recording notes created from templates
+func (h *TemplatesHandler) SetAuditService(auditService services.AuditServiceInterface) {
+	h.auditService = auditService
+}
+
+// CreateTemplate handles POST /api/v1/templates
+func (h *TemplatesHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	template, err := h.templateService.CreateTemplate(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, template)
+}
+
+// ListTemplates handles GET /api/v1/templates
+func (h *TemplatesHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	list, err := h.templateService.ListTemplates(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"templates": list,
+		"total":     len(list),
+	})
+}
+
+// GetTemplate handles GET /api/v1/templates/{id}
+func (h *TemplatesHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	template, err := h.templateService.GetTemplate(r.Context(), user.ID.String(), mux.Vars(r)["id"])
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, template)
+}
+
+// UpdateTemplate handles PUT /api/v1/templates/{id}
+func (h *TemplatesHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request models.TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	template, err := h.templateService.UpdateTemplate(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /api/v1/templates/{id}
+func (h *TemplatesHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.templateService.DeleteTemplate(r.Context(), user.ID.String(), mux.Vars(r)["id"]); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Template deleted successfully"})
+}
+
+// ListGallery handles GET /api/v1/templates/gallery?q=journal&category=daily&sort=popular&limit=20&offset=0
+// Lists the public templates of every user
+func (h *TemplatesHandler) ListGallery(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	gallery, err := h.templateService.ListGallery(r.Context(), user.ID.String(), &models.GalleryFilter{
+		Query:    query.Get("q"),
+		Category: query.Get("category"),
+		Sort:     query.Get("sort"),
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, gallery)
+}
+
+// CloneTemplate handles POST /api/v1/templates/{id}/clone
+// Copies a public template into the user's templates
+func (h *TemplatesHandler) CloneTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// The body is optional; without one the copy keeps the template's name
+	var request models.CloneTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	template, err := h.templateService.CloneTemplate(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, template)
+}
+
+// ApplyTemplate handles POST /api/v1/templates/{id}/apply
+// Returns the rendered title and content without creating a note
+func (h *TemplatesHandler) ApplyTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// The body is optional; without one only computed variables are set
+	var request models.ApplyTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	applied, err := h.templateService.ApplyTemplate(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, applied)
+}
+
+// CreateNoteFromTemplate handles POST /api/v1/templates/{id}/create-note
+// Renders the template and creates a note from it
+func (h *TemplatesHandler) CreateNoteFromTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// The body is optional; without one only computed variables are set
+	var request models.CreateNoteFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	note, err := h.templateService.CreateNoteFromTemplate(r.Context(), user.ID.String(), mux.Vars(r)["id"], &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	recordAudit(r, h.auditService, user.ID, models.AuditActionCreate, &note.ID, 1)
+
+	noteResponse := note.ToResponse()
+	noteResponse.Tags = note.ExtractHashtags()
+	respondWithJSON(w, http.StatusCreated, noteResponse)
+}
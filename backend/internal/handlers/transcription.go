@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// TranscriptionHandler handles voice memo transcription HTTP requests
+type TranscriptionHandler struct {
+	transcriptionService services.TranscriptionServiceInterface
+	maxAudioSize         int64
+}
+
+// NewTranscriptionHandler creates a new TranscriptionHandler accepting memos
+// of up to maxAudioSize bytes
+func NewTranscriptionHandler(transcriptionService services.TranscriptionServiceInterface, maxAudioSize int64) *TranscriptionHandler {
+	return &TranscriptionHandler{
+		transcriptionService: transcriptionService,
+		maxAudioSize:         maxAudioSize,
+	}
+}
+
+// TranscribeMemo handles POST /api/v1/notes/transcribe
+// Accepts a multipart upload with a "file" field, or the raw audio as the
+// request body. The title, tags, language and prettify options are form
+// fields or query parameters.
+func (h *TranscriptionHandler) TranscribeMemo(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxAudioSize)
+	defer r.Body.Close()
+
+	filename, audio, err := readImportUpload(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Audio too large (max %dMB)", h.maxAudioSize>>20))
+		} else {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	request := &models.TranscribeRequest{
+		Title:    r.FormValue("title"),
+		Language: r.FormValue("language"),
+		Prettify: r.FormValue("prettify") == "true",
+	}
+	if tags := r.FormValue("tags"); tags != "" {
+		request.Tags = strings.Split(tags, ",")
+	}
+
+	note, err := h.transcriptionService.TranscribeMemo(r.Context(), user.ID.String(), audio, filename, request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, note)
+}
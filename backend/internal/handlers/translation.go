@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// TranslationHandler handles HTTP requests for note translations
+type TranslationHandler struct {
+	translationService services.TranslationServiceInterface
+}
+
+// NewTranslationHandler creates a new TranslationHandler
+func NewTranslationHandler(translationService services.TranslationServiceInterface) *TranslationHandler {
+	return &TranslationHandler{
+		translationService: translationService,
+	}
+}
+
+// TranslateNote handles POST /api/v1/notes/{id}/translate?lang=en
+// The translation is created as a new note linking to the original.
+func (h *TranslationHandler) TranslateNote(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	request := &models.TranslateRequest{Language: r.URL.Query().Get("lang")}
+	note, err := h.translationService.TranslateNote(r.Context(), user.ID.String(), vars["id"], request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, note)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// TwoFactorHandler handles enrollment in and management of TOTP two-factor
+// authentication
+type TwoFactorHandler struct {
+	twoFactorService services.TwoFactorServiceInterface
+	tokenService     *auth.TokenService
+	sessionService   services.SessionServiceInterface
+	loginGuard       *auth.LoginGuard
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler instance
+func NewTwoFactorHandler(twoFactorService services.TwoFactorServiceInterface, tokenService *auth.TokenService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		tokenService:     tokenService,
+	}
+}
+
+// SetSessionService sets the service recording the refresh token issued
+// when two-factor authentication is enabled
+func (h *TwoFactorHandler) SetSessionService(sessionService services.SessionServiceInterface) {
+	h.sessionService = sessionService
+}
+
+// SetLoginGuard sets the guard slowing down users entering wrong codes
+func (h *TwoFactorHandler) SetLoginGuard(loginGuard *auth.LoginGuard) {
+	h.loginGuard = loginGuard
+}
+
+// GetStatus handles GET /api/v1/auth/2fa
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	status, err := h.twoFactorService.GetStatus(r.Context(), user.ID)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// Enroll handles POST /api/v1/auth/2fa/enroll
+// The secret is only returned in this response; it takes effect once
+// confirmed with a code by Enable.
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	enrollment, err := h.twoFactorService.BeginEnrollment(r.Context(), user)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, enrollment)
+}
+
+// Enable handles POST /api/v1/auth/2fa/enable
+// The response carries the recovery codes, returned only once, and new
+// tokens for the current session. Other sessions must sign in again with a
+// code.
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	user, request, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactorService.ConfirmEnrollment(r.Context(), user.ID, request.Code)
+	if err != nil {
+		h.respondCodeError(w, user, err)
+		return
+	}
+
+	// Tokens of the session were issued without a code, and are refused now
+	claims, _ := r.Context().Value("claims").(*auth.Claims)
+	sessionID := ""
+	if claims != nil {
+		sessionID = claims.SessionID
+	}
+	tokenPair, err := h.tokenService.GenerateTwoFactorTokenPair(user, sessionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to generate tokens")
+		return
+	}
+	if h.sessionService != nil {
+		ipAddress, userAgent := clientDevice(r)
+		err := h.sessionService.IssueRefreshToken(r.Context(), sessionID, tokenPair.RefreshTokenID, ipAddress, userAgent)
+		if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+			respondWithError(w, http.StatusInternalServerError, "Failed to record refresh token")
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+		"access_token":   tokenPair.AccessToken,
+		"refresh_token":  tokenPair.RefreshToken,
+		"token_type":     tokenPair.TokenType,
+		"expires_in":     tokenPair.ExpiresIn,
+	})
+}
+
+// Disable handles POST /api/v1/auth/2fa/disable
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	user, request, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactorService.Disable(r.Context(), user.ID, request.Code); err != nil {
+		h.respondCodeError(w, user, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles POST /api/v1/auth/2fa/recovery-codes
+// The new codes replace the old ones and are only returned in this response.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, request, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(r.Context(), user.ID, request.Code)
+	if err != nil {
+		h.respondCodeError(w, user, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.TwoFactorRecoveryCodes{RecoveryCodes: codes})
+}
+
+// codeRequest reads the code of a request for the authenticated user,
+// responding and returning false when the request cannot go on
+func (h *TwoFactorHandler) codeRequest(w http.ResponseWriter, r *http.Request) (*models.User, *models.TwoFactorCodeRequest, bool) {
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return nil, nil, false
+	}
+
+	var request models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return nil, nil, false
+	}
+	if err := request.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	if h.loginGuard != nil {
+		if wait := h.loginGuard.Wait(auth.UserKey(user.ID.String())); wait > 0 {
+			respondLoginBlocked(w, wait)
+			return nil, nil, false
+		}
+	}
+	return user, &request, true
+}
+
+// respondCodeError responds with an error checking a code, counting wrong
+// codes against the user
+func (h *TwoFactorHandler) respondCodeError(w http.ResponseWriter, user *models.User, err error) {
+	if h.loginGuard != nil && errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		h.loginGuard.Fail(auth.UserKey(user.ID.String()))
+	}
+	respondWithAppError(w, err)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// UsageHandler handles LLM usage and budget HTTP requests
+type UsageHandler struct {
+	usageService services.LLMUsageServiceInterface
+}
+
+// NewUsageHandler creates a new UsageHandler instance
+func NewUsageHandler(usageService services.LLMUsageServiceInterface) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetLLMUsage handles GET /api/v1/usage/llm
+func (h *UsageHandler) GetLLMUsage(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	usage, err := h.usageService.GetUsage(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, usage)
+}
+
+// SetUserLLMBudget handles PUT /api/v1/admin/users/{id}/llm-budget
+func (h *UsageHandler) SetUserLLMBudget(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if userID == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	var request models.SetLLMBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+	if !validateRequest(w, &request) {
+		return
+	}
+
+	usage, err := h.usageService.SetBudget(r.Context(), userID, request.MonthlyTokens)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, usage)
+}
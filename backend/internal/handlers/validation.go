@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/validation"
+)
+
+// validateRequest checks a decoded request against its validate tags. It
+// responds and returns false when the request is invalid.
+func validateRequest(w http.ResponseWriter, request interface{}) bool {
+	err := validation.Struct(request)
+	if err == nil {
+		return true
+	}
+
+	var invalid *validation.Error
+	if !errors.As(err, &invalid) {
+		respondWithAppError(w, err)
+		return false
+	}
+	respondWithValidationError(w, invalid)
+	return false
+}
+
+// respondWithValidationError sends a 422 response listing the invalid fields
+// of a request
+func respondWithValidationError(w http.ResponseWriter, invalid *validation.Error) {
+	apiResponse := &models.APIResponse{Error: validationErrorBody(invalid)}
+
+	response, err := json.Marshal(apiResponse)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to marshal response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	w.Write(response)
+}
+
+// validationErrorBody returns the error listing the invalid fields of a
+// request
+func validationErrorBody(invalid *validation.Error) *models.APIError {
+	return &models.APIError{
+		Code:    ErrCodeValidationFailed,
+		Message: "Validation failed",
+		Details: "error.fields lists each invalid field",
+		Fields:  invalid.Fields,
+	}
+}
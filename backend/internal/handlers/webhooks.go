@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// WebhooksHandler handles outbound webhook HTTP requests
+type WebhooksHandler struct {
+	webhookService services.WebhookServiceInterface
+}
+
+// NewWebhooksHandler creates a new WebhooksHandler instance
+func NewWebhooksHandler(webhookService services.WebhookServiceInterface) *WebhooksHandler {
+	return &WebhooksHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook handles POST /api/v1/webhooks
+func (h *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Parse request body
+	var request models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), user.ID.String(), &request)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, webhook)
+}
+
+// ListWebhooks handles GET /api/v1/webhooks
+func (h *WebhooksHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(r.Context(), user.ID.String())
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"webhooks": webhooks,
+		"total":    len(webhooks),
+	})
+}
+
+// DeleteWebhook handles DELETE /api/v1/webhooks/{id}
+func (h *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	webhookID := mux.Vars(r)["id"]
+	if webhookID == "" {
+		respondWithError(w, http.StatusBadRequest, "Webhook ID is required")
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), user.ID.String(), webhookID); err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries handles GET /api/v1/webhooks/{id}/deliveries
+// Returns the most recent deliveries of a webhook with their status, response
+// code and error, for debugging integrations.
+func (h *WebhooksHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value("user").(*models.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	webhookID := mux.Vars(r)["id"]
+	if webhookID == "" {
+		respondWithError(w, http.StatusBadRequest, "Webhook ID is required")
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), user.ID.String(), webhookID, limit)
+	if err != nil {
+		respondWithAppError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
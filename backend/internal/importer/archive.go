@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// archiveNote is a note as it appears in a JSON archive
+type archiveNote struct {
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	Color     string   `json:"color"`
+	Icon      string   `json:"icon"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// ArchiveReader streams notes out of a JSON archive without loading the whole
+// file. The archive is either an array of notes or an object whose "notes"
+// key holds that array; other keys of the object are skipped.
+type ArchiveReader struct {
+	dec     *json.Decoder
+	started bool
+	done    bool
+	count   int
+	// maxContentLength is the content limit of notes, in characters
+	maxContentLength int
+}
+
+// NewArchiveReader creates an ArchiveReader reading from r, rejecting notes
+// with content longer than maxContentLength characters
+func NewArchiveReader(r io.Reader, maxContentLength int) *ArchiveReader {
+	return &ArchiveReader{dec: json.NewDecoder(r), maxContentLength: maxContentLength}
+}
+
+// Count returns the number of notes read so far, valid or not
+func (a *ArchiveReader) Count() int {
+	return a.count
+}
+
+// Next returns the next note as a record. A note that fails validation is
+// reported as a RowError, after which reading can continue; any other error
+// means the archive cannot be read further. Next returns io.EOF once the
+// notes array is exhausted.
+func (a *ArchiveReader) Next() (Record, error) {
+	if !a.started {
+		a.started = true
+		if err := a.findNotes(); err != nil {
+			a.done = true
+			return Record{}, err
+		}
+	}
+	if a.done {
+		return Record{}, io.EOF
+	}
+
+	if !a.dec.More() {
+		a.done = true
+		if _, err := a.dec.Token(); err != nil {
+			return Record{}, archiveSyntaxError(err)
+		}
+		return Record{}, io.EOF
+	}
+
+	a.count++
+	var note archiveNote
+	if err := a.dec.Decode(&note); err != nil {
+		// A field of the wrong type only spoils this note; the decoder has
+		// already consumed it
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Record{}, RowError{Row: a.count, Column: typeErr.Field, Message: "invalid value"}
+		}
+		a.done = true
+		return Record{}, archiveSyntaxError(err)
+	}
+
+	return a.toRecord(note)
+}
+
+// findNotes advances the decoder to the first element of the notes array
+func (a *ArchiveReader) findNotes() error {
+	tok, err := a.dec.Token()
+	if err == io.EOF {
+		return fmt.Errorf("archive is empty")
+	} else if err != nil {
+		return archiveSyntaxError(err)
+	}
+
+	switch tok {
+	case json.Delim('['):
+		return nil
+	case json.Delim('{'):
+	default:
+		return fmt.Errorf("archive must be a JSON array of notes or an object with a \"notes\" array")
+	}
+
+	for a.dec.More() {
+		key, err := a.dec.Token()
+		if err != nil {
+			return archiveSyntaxError(err)
+		}
+		if key != "notes" {
+			if err := skipValue(a.dec); err != nil {
+				return archiveSyntaxError(err)
+			}
+			continue
+		}
+
+		tok, err := a.dec.Token()
+		if err != nil {
+			return archiveSyntaxError(err)
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("archive \"notes\" must be an array")
+		}
+		return nil
+	}
+	return fmt.Errorf("archive has no \"notes\" array")
+}
+
+// toRecord validates a note the same way spreadsheet rows are validated
+func (a *ArchiveReader) toRecord(note archiveNote) (Record, error) {
+	record := Record{
+		Row:     a.count,
+		Title:   strings.TrimSpace(note.Title),
+		Content: strings.TrimSpace(note.Content),
+		Tags:    splitTags(strings.Join(note.Tags, ","), ","),
+		Color:   strings.TrimSpace(note.Color),
+		Icon:    strings.TrimSpace(note.Icon),
+	}
+
+	if record.Content == "" {
+		return Record{}, RowError{Row: a.count, Column: "content", Message: "content is empty"}
+	}
+	if len(record.Title) > maxTitleLength {
+		return Record{}, RowError{Row: a.count, Column: "title",
+			Message: fmt.Sprintf("title too long (max %d characters)", maxTitleLength)}
+	}
+
+	if rawDate := strings.TrimSpace(note.CreatedAt); rawDate != "" {
+		date, err := parseDate(rawDate, "")
+		if err != nil {
+			return Record{}, RowError{Row: a.count, Column: "created_at", Message: err.Error()}
+		}
+		record.CreatedAt = &date
+	}
+
+	// Tags the content already uses, as in exported notes, are not repeated
+	present := make(map[string]bool)
+	for _, tag := range hashtagRegex.FindAllString(record.Content, -1) {
+		present[strings.ToLower(tag)] = true
+	}
+	var appended []string
+	for _, tag := range record.Tags {
+		if !present[strings.ToLower(tag)] {
+			appended = append(appended, tag)
+		}
+	}
+	if len(appended) > 0 {
+		record.Content += "\n\n" + strings.Join(appended, " ")
+	}
+	if utf8.RuneCountInString(record.Content) > a.maxContentLength {
+		return Record{}, RowError{Row: a.count, Column: "content",
+			Message: fmt.Sprintf("content too long (max %d characters)", a.maxContentLength)}
+	}
+
+	return record, nil
+}
+
+// skipValue consumes the next value, token by token so large values are not
+// held in memory
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// archiveSyntaxError describes a decoding failure. Errors from the underlying
+// reader are returned unchanged so callers can recognise them.
+func archiveSyntaxError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("invalid JSON at byte %d: %v", syntaxErr.Offset, err)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("archive ends unexpectedly")
+	}
+	return err
+}
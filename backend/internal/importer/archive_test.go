@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readArchive reads every note of an archive, collecting records and row errors
+func readArchive(t *testing.T, data string) ([]Record, []RowError, error) {
+	t.Helper()
+
+	reader := NewArchiveReader(strings.NewReader(data), testMaxContentLength)
+	var records []Record
+	var rowErrors []RowError
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records, rowErrors, nil
+		}
+		var rowErr RowError
+		if errors.As(err, &rowErr) {
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		if err != nil {
+			return records, rowErrors, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestArchiveReaderFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"array", `[{"title":"A","content":"first"},{"content":"second"}]`},
+		{"object", `{"version":2,"exported":{"by":["x",{"y":[1]}]},"notes":[{"title":"A","content":"first"},{"content":"second"}],"tags":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, rowErrors, err := readArchive(t, tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rowErrors) != 0 {
+				t.Errorf("unexpected row errors %v", rowErrors)
+			}
+			if len(records) != 2 || records[0].Title != "A" || records[1].Content != "second" || records[1].Row != 2 {
+				t.Errorf("unexpected records %+v", records)
+			}
+		})
+	}
+}
+
+func TestArchiveReaderValidatesNotes(t *testing.T) {
+	data := `{"notes":[
+		{"content":"  "},
+		{"content":"tagged","tags":["work","to do"],"created_at":"2024-03-01"},
+		{"content":"bad date","created_at":"yesterday"},
+		{"content":42},
+		"not a note",
+		{"title":"` + strings.Repeat("t", maxTitleLength+1) + `","content":"x"},
+		{"content":"last"}
+	]}`
+
+	records, rowErrors, err := readArchive(t, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	if records[0].Content != "tagged\n\n#work #to_do" || records[0].CreatedAt == nil || records[0].Row != 2 {
+		t.Errorf("unexpected tagged record %+v", records[0])
+	}
+	if records[1].Row != 7 {
+		t.Errorf("expected last record at position 7, got %d", records[1].Row)
+	}
+
+	wantRows := []int{1, 3, 4, 5, 6}
+	if len(rowErrors) != len(wantRows) {
+		t.Fatalf("expected %d row errors, got %v", len(wantRows), rowErrors)
+	}
+	for i, row := range wantRows {
+		if rowErrors[i].Row != row {
+			t.Errorf("row error %d: expected row %d, got %+v", i, row, rowErrors[i])
+		}
+	}
+}
+
+func TestArchiveReaderSkipsTagsInContent(t *testing.T) {
+	records, _, err := readArchive(t, `[{"content":"Standup #Work","tags":["#work","team"]}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Content != "Standup #Work\n\n#team" {
+		t.Errorf("expected only tags missing from the content to be appended, got %+v", records)
+	}
+}
+
+func TestArchiveReaderKeepsStyle(t *testing.T) {
+	records, _, err := readArchive(t, `[{"content":"Standup","color":" blue ","icon":"📌"}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Color != "blue" || records[0].Icon != "📌" {
+		t.Errorf("expected the color and icon to be kept, got %+v", records)
+	}
+}
+
+func TestArchiveReaderStopsOnBrokenArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		records int
+	}{
+		{"empty", ``, 0},
+		{"scalar", `"notes"`, 0},
+		{"no notes", `{"version":1}`, 0},
+		{"notes not array", `{"notes":{}}`, 0},
+		{"truncated", `[{"content":"kept"},{"content":"lo`, 1},
+		{"syntax", `[{"content":"kept"} {"content":"x"}]`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, _, err := readArchive(t, tt.data)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if len(records) != tt.records {
+				t.Errorf("expected %d records before the error, got %d", tt.records, len(records))
+			}
+		})
+	}
+}
+
+func TestArchiveReaderPassesReaderErrors(t *testing.T) {
+	errLimit := errors.New("limit reached")
+	r := io.MultiReader(strings.NewReader(`[{"content":"kept"},`), &failingReader{err: errLimit})
+
+	reader := NewArchiveReader(r, testMaxContentLength)
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("expected first note, got %v", err)
+	}
+	if _, err := reader.Next(); !errors.Is(err, errLimit) {
+		t.Errorf("expected reader error, got %v", err)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after a fatal error, got %v", err)
+	}
+}
+
+// failingReader returns err on every read
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
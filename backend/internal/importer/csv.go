@@ -0,0 +1,389 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// candidateDelimiters are tried in order when sniffing a CSV file
+var candidateDelimiters = []rune{',', ';', '\t', '|'}
+
+// sniffLines is the number of leading lines used to detect the delimiter
+const sniffLines = 20
+
+// maxTitleLength is the title limit of imported notes, matching note
+// validation
+const maxTitleLength = 500
+
+
+// dateLayouts are tried in order when a mapping has no explicit date format
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"02 Jan 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// tagInvalidChars matches characters that cannot appear in a hashtag
+var tagInvalidChars = regexp.MustCompile(`[^\w]+`)
+
+// tagName cleans a tag name, replacing characters that are not allowed in
+// hashtags with underscores. Nested tag segments separated by "/" are kept.
+func tagName(value string) string {
+	var segments []string
+	for _, segment := range strings.Split(value, "/") {
+		segment = strings.Trim(tagInvalidChars.ReplaceAllString(strings.TrimSpace(segment), "_"), "_")
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Table is a parsed CSV file with a header row
+type Table struct {
+	Delimiter rune
+	Columns   []string
+	Rows      [][]string
+}
+
+// ParseCSV parses CSV data, detecting the delimiter and treating the first
+// row as the header. Short rows are padded so every row has one value per column.
+func ParseCSV(data []byte) (*Table, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("file is not valid UTF-8 text")
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	delimiter := detectDelimiter(data)
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("file must have a header row and at least one data row")
+	}
+
+	table := &Table{Delimiter: delimiter, Columns: headerColumns(records[0])}
+	for _, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		row := make([]string, len(table.Columns))
+		copy(row, record)
+		table.Rows = append(table.Rows, row)
+	}
+	if len(table.Rows) == 0 {
+		return nil, fmt.Errorf("file has no data rows")
+	}
+
+	return table, nil
+}
+
+// detectDelimiter picks the candidate that splits the leading lines into the
+// same number (greater than one) of fields most consistently
+func detectDelimiter(data []byte) rune {
+	lines := bytes.SplitN(data, []byte("\n"), sniffLines+1)
+	if len(lines) > sniffLines {
+		lines = lines[:sniffLines]
+	}
+	sample := bytes.Join(lines, []byte("\n"))
+
+	best, bestScore := ',', 0
+	for _, delimiter := range candidateDelimiters {
+		reader := csv.NewReader(bytes.NewReader(sample))
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		records, _ := reader.ReadAll()
+		if len(records) == 0 || len(records[0]) < 2 {
+			continue
+		}
+		score := 0
+		for _, record := range records {
+			if len(record) == len(records[0]) {
+				score += len(record)
+			}
+		}
+		if score > bestScore {
+			best, bestScore = delimiter, score
+		}
+	}
+	return best
+}
+
+// headerColumns names empty header cells and disambiguates duplicates
+func headerColumns(header []string) []string {
+	columns := make([]string, len(header))
+	seen := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = fmt.Sprintf("Column %d", i+1)
+		}
+		seen[strings.ToLower(name)]++
+		if n := seen[strings.ToLower(name)]; n > 1 {
+			name = fmt.Sprintf("%s (%d)", name, n)
+		}
+		columns[i] = name
+	}
+	return columns
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Sample returns up to n leading data rows
+func (t *Table) Sample(n int) [][]string {
+	if n > len(t.Rows) {
+		n = len(t.Rows)
+	}
+	return t.Rows[:n]
+}
+
+// columnIndex returns the index of a column by name, ignoring case
+func (t *Table) columnIndex(name string) int {
+	for i, column := range t.Columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Mapping assigns table columns to note fields. Only Content is required.
+type Mapping struct {
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content"`
+	Tags    string `json:"tags,omitempty"`
+	Date    string `json:"date,omitempty"`
+	// DateFormat is an optional Go time layout (e.g. "02/01/2006") for the date column
+	DateFormat string `json:"date_format,omitempty"`
+	// TagSeparator splits the tags column; defaults to commas and semicolons,
+	// or whitespace when the cell has neither
+	TagSeparator string `json:"tag_separator,omitempty"`
+}
+
+// headerHints maps note fields to column names that usually hold them
+var headerHints = map[string][]string{
+	"title":   {"title", "subject", "name", "heading", "headline"},
+	"content": {"content", "body", "text", "note", "notes", "description", "details", "message"},
+	"tags":    {"tags", "tag", "labels", "label", "categories", "category", "keywords"},
+	"date":    {"date", "created", "created_at", "createdat", "created at", "timestamp", "time", "modified"},
+}
+
+// SuggestMapping guesses a mapping from the column names
+func (t *Table) SuggestMapping() Mapping {
+	pick := func(field string) string {
+		for _, hint := range headerHints[field] {
+			if i := t.columnIndex(hint); i >= 0 {
+				return t.Columns[i]
+			}
+		}
+		return ""
+	}
+
+	mapping := Mapping{
+		Title:   pick("title"),
+		Content: pick("content"),
+		Tags:    pick("tags"),
+		Date:    pick("date"),
+	}
+	if mapping.Content == "" {
+		// Fall back to the first column not used for anything else
+		for _, column := range t.Columns {
+			if column != mapping.Title && column != mapping.Tags && column != mapping.Date {
+				mapping.Content = column
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// ValidateMapping checks that the mapping refers to existing, distinct columns
+func (t *Table) ValidateMapping(m Mapping) error {
+	if m.Content == "" {
+		return fmt.Errorf("a content column is required")
+	}
+
+	used := make(map[int]string)
+	for field, column := range map[string]string{"title": m.Title, "content": m.Content, "tags": m.Tags, "date": m.Date} {
+		if column == "" {
+			continue
+		}
+		i := t.columnIndex(column)
+		if i < 0 {
+			return fmt.Errorf("%s column %q does not exist", field, column)
+		}
+		if other, ok := used[i]; ok {
+			return fmt.Errorf("column %q is mapped to both %s and %s", column, other, field)
+		}
+		used[i] = field
+	}
+	return nil
+}
+
+// Record is a table row converted to note fields
+type Record struct {
+	Row       int        `json:"row"`
+	Title     string     `json:"title,omitempty"`
+	Content   string     `json:"content"`
+	Tags      []string   `json:"tags,omitempty"`
+	// Color and Icon are carried over from archives, checked on import
+	Color     string     `json:"color,omitempty"`
+	Icon      string     `json:"icon,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// RowError reports why a row cannot be imported. Row is the spreadsheet row
+// number, counting the header as row 1; for archives it is the position of
+// the note, starting at 1. Vault imports also name the file.
+type RowError struct {
+	Row     int    `json:"row"`
+	File    string `json:"file,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// Apply converts every row using the mapping. Tags are appended to the content
+// as hashtags so they are picked up like any other note tag. Rows that fail
+// validation, including content longer than maxContentLength characters, are
+// reported and left out of the records.
+func (t *Table) Apply(m Mapping, maxContentLength int) ([]Record, []RowError, error) {
+	if err := t.ValidateMapping(m); err != nil {
+		return nil, nil, err
+	}
+
+	value := func(row []string, column string) string {
+		if column == "" {
+			return ""
+		}
+		return strings.TrimSpace(row[t.columnIndex(column)])
+	}
+
+	var records []Record
+	var rowErrors []RowError
+	for i, row := range t.Rows {
+		rowNumber := i + 2
+		record := Record{
+			Row:     rowNumber,
+			Title:   value(row, m.Title),
+			Content: value(row, m.Content),
+			Tags:    splitTags(value(row, m.Tags), m.TagSeparator),
+		}
+
+		if record.Content == "" {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Column: m.Content, Message: "content is empty"})
+			continue
+		}
+		if len(record.Title) > maxTitleLength {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Column: m.Title,
+				Message: fmt.Sprintf("title too long (max %d characters)", maxTitleLength)})
+			continue
+		}
+
+		if rawDate := value(row, m.Date); rawDate != "" {
+			date, err := parseDate(rawDate, m.DateFormat)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: rowNumber, Column: m.Date, Message: err.Error()})
+				continue
+			}
+			record.CreatedAt = &date
+		}
+
+		if len(record.Tags) > 0 {
+			record.Content += "\n\n" + strings.Join(record.Tags, " ")
+		}
+		if utf8.RuneCountInString(record.Content) > maxContentLength {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Column: m.Content,
+				Message: fmt.Sprintf("content too long (max %d characters)", maxContentLength)})
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rowErrors, nil
+}
+
+// splitTags splits a tags cell into hashtags, replacing characters that are
+// not allowed in hashtags with underscores
+func splitTags(value, separator string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var parts []string
+	switch {
+	case separator != "":
+		parts = strings.Split(value, separator)
+	case strings.ContainsAny(value, ",;\n"):
+		parts = strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ';' || r == '\n'
+		})
+	default:
+		parts = strings.Fields(value)
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		name := tagName(part)
+		if name == "" {
+			continue
+		}
+		tag := "#" + name
+		if !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseDate parses a date cell with the given layout, or the common layouts when empty
+func parseDate(value, layout string) (time.Time, error) {
+	if layout != "" {
+		date, err := time.Parse(layout, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("date %q does not match format %q", value, layout)
+		}
+		return date, nil
+	}
+	for _, candidate := range dateLayouts {
+		if date, err := time.Parse(candidate, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q (set date_format for custom formats)", value)
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMaxContentLength is the content limit imports are read with, the
+// default of notes
+const testMaxContentLength = 10000
+
+func TestParseCSVDetectsDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		delimiter rune
+	}{
+		{"comma", "Title,Body\nA,first\nB,second\n", ','},
+		{"semicolon", "Title;Body\nA;\"first, with comma\"\nB;second\n", ';'},
+		{"tab", "Title\tBody\nA\tfirst\n", '\t'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseCSV([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseCSV returned error: %v", err)
+			}
+			if table.Delimiter != tt.delimiter {
+				t.Errorf("expected delimiter %q, got %q", tt.delimiter, table.Delimiter)
+			}
+			if !reflect.DeepEqual(table.Columns, []string{"Title", "Body"}) {
+				t.Errorf("unexpected columns %v", table.Columns)
+			}
+		})
+	}
+}
+
+func TestParseCSVHeaders(t *testing.T) {
+	table, err := ParseCSV([]byte("\xef\xbb\xbfname,,name\nx,y\n,,\n"))
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+	if !reflect.DeepEqual(table.Columns, []string{"name", "Column 2", "name (2)"}) {
+		t.Errorf("unexpected columns %v", table.Columns)
+	}
+	if len(table.Rows) != 1 || len(table.Rows[0]) != 3 {
+		t.Errorf("expected one padded row, got %v", table.Rows)
+	}
+
+	if _, err := ParseCSV([]byte("only,header\n")); err == nil {
+		t.Error("expected error for file without data rows")
+	}
+}
+
+func TestSuggestMapping(t *testing.T) {
+	table := &Table{Columns: []string{"Subject", "Description", "Labels", "Created At"}}
+	want := Mapping{Title: "Subject", Content: "Description", Tags: "Labels", Date: "Created At"}
+	if got := table.SuggestMapping(); got != want {
+		t.Errorf("SuggestMapping() = %+v, want %+v", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	data := "Subject,Body,Labels,When\n" +
+		"Standup,Discussed roadmap,\"work, team sync\",2024-03-01\n" +
+		"Empty,,,\n" +
+		"Bad date,Something,,yesterday\n"
+	table, err := ParseCSV([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+
+	records, rowErrors, err := table.Apply(Mapping{Title: "Subject", Content: "Body", Tags: "Labels", Date: "When"}, testMaxContentLength)
+	if err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	if record.Row != 2 || record.Title != "Standup" {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if !reflect.DeepEqual(record.Tags, []string{"#work", "#team_sync"}) {
+		t.Errorf("unexpected tags %v", record.Tags)
+	}
+	if !strings.HasSuffix(record.Content, "\n\n#work #team_sync") {
+		t.Errorf("expected tags appended to content, got %q", record.Content)
+	}
+	if record.CreatedAt == nil || record.CreatedAt.Format("2006-01-02") != "2024-03-01" {
+		t.Errorf("unexpected created_at %v", record.CreatedAt)
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Row != 3 || rowErrors[1].Row != 4 || rowErrors[1].Column != "When" {
+		t.Errorf("unexpected row errors %+v", rowErrors)
+	}
+}
+
+func TestApplyLimitsContentCharacters(t *testing.T) {
+	data := "Body\n" + strings.Repeat("é", 5) + "\n" + strings.Repeat("é", 6) + "\n"
+	table, err := ParseCSV([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+
+	records, rowErrors, err := table.Apply(Mapping{Content: "Body"}, 5)
+	if err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if len(records) != 1 || records[0].Row != 2 {
+		t.Errorf("Expected content at the limit to be kept, got %+v", records)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Row != 3 || rowErrors[0].Message != "content too long (max 5 characters)" {
+		t.Errorf("unexpected row errors %+v", rowErrors)
+	}
+}
+
+func TestValidateMapping(t *testing.T) {
+	table := &Table{Columns: []string{"a", "b"}}
+	invalid := []Mapping{
+		{},
+		{Content: "missing"},
+		{Title: "a", Content: "a"},
+	}
+	for _, m := range invalid {
+		if err := table.ValidateMapping(m); err == nil {
+			t.Errorf("expected error for mapping %+v", m)
+		}
+	}
+	if err := table.ValidateMapping(Mapping{Title: "A", Content: "b"}); err != nil {
+		t.Errorf("expected case-insensitive column match, got %v", err)
+	}
+}
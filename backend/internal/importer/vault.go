@@ -0,0 +1,407 @@
+package importer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxVaultFileSize bounds how much of a single file is decompressed, so a
+// small archive cannot expand into a huge note. It fits the largest content
+// limit, 1M characters of up to 4 bytes each.
+const maxVaultFileSize = 4 << 20
+
+// vaultWikiLinkRegex matches [[Target]], [[Target|label]] and ![[embeds]]
+var vaultWikiLinkRegex = regexp.MustCompile(`(!?)\[\[([^\[\]\n|]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+// vaultMarkdownLinkRegex matches [label](target) and ![embeds](target)
+var vaultMarkdownLinkRegex = regexp.MustCompile(`(!?)\[([^\[\]\n]*)\]\(([^()\s]+)\)`)
+
+// vaultInlineTagRegex matches Obsidian inline tags, which may be nested
+// (#project/alpha) or contain hyphens
+var vaultInlineTagRegex = regexp.MustCompile(`(^|\s)#(\w[\w/-]*)`)
+
+// hashtagRegex matches hashtags the way notes extract them
+var hashtagRegex = regexp.MustCompile(`#\w+(?:/\w+)*`)
+
+// VaultNote is a Markdown file of a vault converted to a note. The ID is
+// assigned up front so links between files can be rewritten before any
+// note is created.
+type VaultNote struct {
+	Record
+	ID   uuid.UUID `json:"id"`
+	Path string    `json:"path"`
+}
+
+// vaultFile is a Markdown file found in the archive
+type vaultFile struct {
+	file *zip.File
+	path string
+	id   uuid.UUID
+}
+
+// ReadVault converts the Markdown files of a zipped folder tree, such as an
+// Obsidian vault, to notes. Each .md file becomes a note titled after the file
+// unless its front matter sets a title. Front matter tags are appended to the
+// content as hashtags, inline tags are normalized into hashtags, and
+// relative wiki and Markdown links to other files of the vault are rewritten
+// to note:// links. Files that fail validation are reported as row errors;
+// other files, and hidden folders such as .obsidian, are ignored. Notes are
+// limited to maxContentLength characters of content.
+func ReadVault(r io.ReaderAt, size int64, maxNotes, maxContentLength int) ([]VaultNote, []RowError, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file is not a valid ZIP archive")
+	}
+
+	files := vaultMarkdownFiles(archive)
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("archive contains no Markdown files")
+	}
+	if len(files) > maxNotes {
+		return nil, nil, fmt.Errorf("archive contains %d Markdown files; vaults are limited to %d notes", len(files), maxNotes)
+	}
+
+	// Read and validate every file first, so links only point at notes that
+	// will be created
+	var notes []VaultNote
+	var rowErrors []RowError
+	index := newVaultIndex()
+	for i, f := range files {
+		note, err := readVaultFile(f, i+1, maxContentLength)
+		if err != nil {
+			rowErrors = append(rowErrors, *err)
+			continue
+		}
+		index.add(f.path, f.id)
+		notes = append(notes, note)
+	}
+
+	valid := notes[:0]
+	for _, note := range notes {
+		note.Content = index.rewriteLinks(note.Content, path.Dir(note.Path))
+		if utf8.RuneCountInString(note.Content) > maxContentLength {
+			rowErrors = append(rowErrors, RowError{Row: note.Row, File: note.Path, Column: "content",
+				Message: fmt.Sprintf("content too long (max %d characters)", maxContentLength)})
+			continue
+		}
+		valid = append(valid, note)
+	}
+
+	return valid, rowErrors, nil
+}
+
+// vaultMarkdownFiles lists the Markdown files of an archive sorted by path,
+// leaving out hidden files and folders. When every file sits in the same
+// top-level folder, that folder is treated as the vault root.
+func vaultMarkdownFiles(archive *zip.Reader) []vaultFile {
+	var files []vaultFile
+	for _, f := range archive.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, `\`, "/"))
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(name), ".md") || isHiddenVaultPath(name) {
+			continue
+		}
+		files = append(files, vaultFile{file: f, path: strings.TrimPrefix(name, "/"), id: uuid.New()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+
+	if len(files) > 0 {
+		root, _, found := strings.Cut(files[0].path, "/")
+		for _, f := range files {
+			if !found || !strings.HasPrefix(f.path, root+"/") {
+				found = false
+				break
+			}
+		}
+		if found {
+			for i := range files {
+				files[i].path = strings.TrimPrefix(files[i].path, root+"/")
+			}
+		}
+	}
+	return files
+}
+
+// isHiddenVaultPath reports whether a path is inside a hidden or system folder
+func isHiddenVaultPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// readVaultFile reads a Markdown file and converts it to a note without
+// rewriting its links
+func readVaultFile(f vaultFile, row, maxContentLength int) (VaultNote, *RowError) {
+	rowError := func(column, message string) (VaultNote, *RowError) {
+		return VaultNote{}, &RowError{Row: row, File: f.path, Column: column, Message: message}
+	}
+
+	rc, err := f.file.Open()
+	if err != nil {
+		return rowError("", "file cannot be read")
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, maxVaultFileSize+1))
+	rc.Close()
+	if err != nil {
+		return rowError("", "file cannot be read")
+	}
+	if len(data) > maxVaultFileSize {
+		return rowError("content", fmt.Sprintf("content too long (max %d characters)", maxContentLength))
+	}
+	content := strings.TrimPrefix(string(data), "\ufeff")
+	if !utf8.ValidString(content) {
+		return rowError("", "file is not valid UTF-8 text")
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	frontMatter, body := splitFrontMatter(content)
+	note := VaultNote{
+		Record: Record{
+			Row:     row,
+			Title:   strings.TrimSuffix(path.Base(f.path), path.Ext(f.path)),
+			Content: strings.TrimSpace(normalizeInlineTags(body)),
+		},
+		ID:   f.id,
+		Path: f.path,
+	}
+	if title := firstValue(frontMatter, "title"); title != "" {
+		note.Title = title
+	}
+
+	if note.Content == "" {
+		return rowError("content", "content is empty")
+	}
+	if len(note.Title) > maxTitleLength {
+		return rowError("title", fmt.Sprintf("title too long (max %d characters)", maxTitleLength))
+	}
+
+	// Dates in unexpected formats are ignored rather than losing the note
+	for _, key := range []string{"created", "created_at", "date"} {
+		if rawDate := firstValue(frontMatter, key); rawDate != "" {
+			if date, err := parseDate(rawDate, ""); err == nil {
+				note.CreatedAt = &date
+				break
+			}
+		}
+	}
+
+	// Front matter tags already used inline are not repeated
+	present := make(map[string]bool)
+	for _, tag := range hashtagRegex.FindAllString(note.Content, -1) {
+		present[strings.ToLower(tag)] = true
+	}
+	for _, key := range []string{"tags", "tag"} {
+		for _, tag := range splitTags(strings.Join(frontMatter[key], ","), "") {
+			if !present[strings.ToLower(tag)] {
+				present[strings.ToLower(tag)] = true
+				note.Tags = append(note.Tags, tag)
+			}
+		}
+	}
+	if len(note.Tags) > 0 {
+		note.Content += "\n\n" + strings.Join(note.Tags, " ")
+	}
+
+	return note, nil
+}
+
+// splitFrontMatter separates a leading YAML front matter block from the body.
+// Only the subset notes use is understood: "key: value" pairs, inline
+// [a, b] lists and "- item" block lists. Keys are lower-cased.
+func splitFrontMatter(content string) (map[string][]string, string) {
+	if !strings.HasPrefix(content, "---\n") {
+		return nil, content
+	}
+	block, body, found := strings.Cut(content[len("---\n"):], "\n---")
+	if !found {
+		return nil, content
+	}
+	// The closing delimiter must be a line of its own
+	if rest, ok := strings.CutPrefix(body, "\n"); ok {
+		body = rest
+	} else if strings.TrimSpace(body) != "" {
+		return nil, content
+	}
+
+	values := make(map[string][]string)
+	var key string
+	for _, line := range strings.Split(block, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && key != "" {
+			values[key] = append(values[key], unquoteYAML(item))
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = unquoteYAML(item); item != "" {
+					values[key] = append(values[key], item)
+				}
+			}
+		default:
+			values[key] = append(values[key], unquoteYAML(value))
+		}
+	}
+	return values, body
+}
+
+// unquoteYAML trims whitespace and matching quotes from a YAML scalar
+func unquoteYAML(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return strings.TrimSpace(value)
+}
+
+// firstValue returns the first value of a front matter key
+func firstValue(frontMatter map[string][]string, key string) string {
+	if len(frontMatter[key]) == 0 {
+		return ""
+	}
+	return frontMatter[key][0]
+}
+
+// normalizeInlineTags rewrites hyphenated inline tags outside code blocks so
+// they are picked up whole as hashtags; nested tags keep their hierarchy
+func normalizeInlineTags(content string) string {
+	return mapOutsideCode(content, func(line string) string {
+		return vaultInlineTagRegex.ReplaceAllStringFunc(line, func(match string) string {
+			groups := vaultInlineTagRegex.FindStringSubmatch(match)
+			name := tagName(groups[2])
+			return groups[1] + "#" + name
+		})
+	})
+}
+
+// mapOutsideCode applies fn to every line outside fenced code blocks
+func mapOutsideCode(content string, fn func(string) string) string {
+	lines := strings.Split(content, "\n")
+	inCode := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			lines[i] = fn(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// vaultIndex resolves link targets to the IDs of the vault's notes
+type vaultIndex struct {
+	// byPath is keyed by the lower-cased path without the .md extension
+	byPath map[string]uuid.UUID
+	// byName is keyed by the lower-cased file name without the extension;
+	// files are added in path order, so the first match wins
+	byName map[string]uuid.UUID
+}
+
+func newVaultIndex() *vaultIndex {
+	return &vaultIndex{byPath: make(map[string]uuid.UUID), byName: make(map[string]uuid.UUID)}
+}
+
+// add indexes a note by its path and file name
+func (x *vaultIndex) add(notePath string, id uuid.UUID) {
+	key := strings.ToLower(strings.TrimSuffix(notePath, path.Ext(notePath)))
+	x.byPath[key] = id
+	if _, ok := x.byName[path.Base(key)]; !ok {
+		x.byName[path.Base(key)] = id
+	}
+}
+
+// resolve finds the note a link from a file in dir points to. Targets are
+// tried relative to dir, then to the vault root, then by file name.
+func (x *vaultIndex) resolve(dir, target string) (uuid.UUID, bool) {
+	target, _, _ = strings.Cut(target, "#")
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return uuid.Nil, false
+	}
+	if ext := path.Ext(target); strings.EqualFold(ext, ".md") {
+		target = target[:len(target)-len(ext)]
+	}
+	key := strings.ToLower(target)
+
+	if id, ok := x.byPath[strings.TrimPrefix(path.Join(dir, key), "/")]; ok {
+		return id, true
+	}
+	if id, ok := x.byPath[strings.TrimPrefix(path.Clean(key), "/")]; ok {
+		return id, true
+	}
+	if !strings.Contains(key, "/") {
+		id, ok := x.byName[key]
+		return id, ok
+	}
+	return uuid.Nil, false
+}
+
+// rewriteLinks rewrites links to other notes of the vault to note:// links.
+// Embeds, external links and links that do not resolve are left unchanged.
+func (x *vaultIndex) rewriteLinks(content, dir string) string {
+	if dir == "." {
+		dir = ""
+	}
+	return mapOutsideCode(content, func(line string) string {
+		line = vaultWikiLinkRegex.ReplaceAllStringFunc(line, func(match string) string {
+			groups := vaultWikiLinkRegex.FindStringSubmatch(match)
+			if groups[1] != "" {
+				return match
+			}
+			id, ok := x.resolve(dir, groups[2])
+			if !ok {
+				return match
+			}
+			label := strings.TrimSpace(groups[3])
+			if label == "" {
+				label = strings.TrimSpace(groups[2])
+			}
+			return fmt.Sprintf("[%s](note://%s)", label, id)
+		})
+
+		return vaultMarkdownLinkRegex.ReplaceAllStringFunc(line, func(match string) string {
+			groups := vaultMarkdownLinkRegex.FindStringSubmatch(match)
+			if groups[1] != "" || strings.Contains(groups[3], ":") {
+				return match
+			}
+			target, err := url.PathUnescape(groups[3])
+			if err != nil {
+				return match
+			}
+			file, _, _ := strings.Cut(target, "#")
+			if !strings.EqualFold(path.Ext(file), ".md") {
+				return match
+			}
+			id, ok := x.resolve(dir, target)
+			if !ok {
+				return match
+			}
+			return fmt.Sprintf("[%s](note://%s)", groups[2], id)
+		})
+	})
+}
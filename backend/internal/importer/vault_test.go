@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// zipVault builds a ZIP archive from file paths and contents
+func zipVault(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// readTestVault reads a vault, failing the test on archive-level errors
+func readTestVault(t *testing.T, files map[string]string) (map[string]VaultNote, []RowError) {
+	t.Helper()
+
+	r := zipVault(t, files)
+	notes, rowErrors, err := ReadVault(r, r.Size(), 100, testMaxContentLength)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byPath := make(map[string]VaultNote)
+	for _, note := range notes {
+		byPath[note.Path] = note
+	}
+	return byPath, rowErrors
+}
+
+func TestReadVaultFrontMatter(t *testing.T) {
+	notes, rowErrors := readTestVault(t, map[string]string{
+		"Vault/Daily/2024-03-01.md": "---\ntitle: \"Standup\"\ntags: [work, \"team-sync\"]\ncreated: 2024-03-01\n---\nDiscussed roadmap #work",
+		"Vault/Ideas.md":            "---\ntags:\n  - project/alpha\n  - reading\naliases: [Backlog]\n---\n\nRead more\n",
+	})
+	if len(rowErrors) != 0 {
+		t.Fatalf("unexpected row errors %v", rowErrors)
+	}
+
+	standup, ok := notes["Daily/2024-03-01.md"]
+	if !ok {
+		t.Fatalf("Expected the vault folder to be stripped from paths, got %v", notes)
+	}
+	if standup.Title != "Standup" {
+		t.Errorf("Expected the front matter title, got %q", standup.Title)
+	}
+	if standup.Content != "Discussed roadmap #work\n\n#team_sync" {
+		t.Errorf("Expected front matter tags not used inline to be appended, got %q", standup.Content)
+	}
+	if standup.CreatedAt == nil || standup.CreatedAt.Format("2006-01-02") != "2024-03-01" {
+		t.Errorf("Expected the front matter creation date, got %v", standup.CreatedAt)
+	}
+
+	ideas := notes["Ideas.md"]
+	if ideas.Title != "Ideas" {
+		t.Errorf("Expected the file name as title, got %q", ideas.Title)
+	}
+	if ideas.Content != "Read more\n\n#project/alpha #reading" {
+		t.Errorf("Expected block list tags to be appended, got %q", ideas.Content)
+	}
+}
+
+func TestReadVaultInlineTags(t *testing.T) {
+	notes, _ := readTestVault(t, map[string]string{
+		"Tasks.md": "Plan #project/alpha and #to-do\n```\n#not/a-tag\n```\n# Heading",
+	})
+
+	want := "Plan #project/alpha and #to_do\n```\n#not/a-tag\n```\n# Heading"
+	if got := notes["Tasks.md"].Content; got != want {
+		t.Errorf("Expected inline tags outside code to be normalized, got %q", got)
+	}
+}
+
+func TestReadVaultRewritesLinks(t *testing.T) {
+	notes, _ := readTestVault(t, map[string]string{
+		"Index.md":            "See [[Plan]], [[Projects/Plan|the plan]], [[Plan#Goals]] and [[Missing]].\n![[diagram.png]] [site](https://example.com)",
+		"Projects/Plan.md":    "Back to [index](../Index.md) and [[Notes#Draft|notes]]\n```\n[[Index]]\n```",
+		"Projects/Notes.md":   "Draft",
+		"Archive/Plan 2.md":   "Old [plan](Plan%202.md)",
+		".obsidian/config.md": "hidden",
+		"diagram.png":         "png",
+	})
+	if len(notes) != 4 {
+		t.Fatalf("Expected hidden and non-Markdown files to be ignored, got %d notes", len(notes))
+	}
+
+	index := notes["Index.md"]
+	plan := notes["Projects/Plan.md"]
+	notesNote := notes["Projects/Notes.md"]
+	archived := notes["Archive/Plan 2.md"]
+
+	link := func(label string, note VaultNote) string {
+		return "[" + label + "](note://" + note.ID.String() + ")"
+	}
+
+	wantIndex := "See " + link("Plan", plan) + ", " + link("the plan", plan) + ", " + link("Plan#Goals", plan) +
+		" and [[Missing]].\n![[diagram.png]] [site](https://example.com)"
+	if index.Content != wantIndex {
+		t.Errorf("Unexpected index content:\n got %q\nwant %q", index.Content, wantIndex)
+	}
+
+	wantPlan := "Back to " + link("index", index) + " and " + link("notes", notesNote) + "\n```\n[[Index]]\n```"
+	if plan.Content != wantPlan {
+		t.Errorf("Unexpected plan content:\n got %q\nwant %q", plan.Content, wantPlan)
+	}
+
+	if want := "Old " + link("plan", archived); archived.Content != want {
+		t.Errorf("Expected escaped relative links to resolve, got %q", archived.Content)
+	}
+}
+
+func TestReadVaultValidatesFiles(t *testing.T) {
+	notes, rowErrors := readTestVault(t, map[string]string{
+		"Empty.md": "---\ntags: [x]\n---\n",
+		"Long.md":  strings.Repeat("a", testMaxContentLength+1),
+		"Link.md":  "[[Empty]]",
+	})
+
+	if len(rowErrors) != 2 {
+		t.Fatalf("Expected 2 row errors, got %v", rowErrors)
+	}
+	if rowErrors[0].File != "Empty.md" || rowErrors[0].Message != "content is empty" {
+		t.Errorf("Unexpected first row error %+v", rowErrors[0])
+	}
+	if rowErrors[1].File != "Long.md" || rowErrors[1].Row != 3 {
+		t.Errorf("Unexpected second row error %+v", rowErrors[1])
+	}
+	if notes["Link.md"].Content != "[[Empty]]" {
+		t.Errorf("Expected links to skipped files to be left unchanged, got %q", notes["Link.md"].Content)
+	}
+}
+
+func TestReadVaultRejectsArchives(t *testing.T) {
+	r := zipVault(t, map[string]string{"a.md": "a", "b.md": "b", "c.md": "c"})
+	if _, _, err := ReadVault(r, r.Size(), 2, testMaxContentLength); err == nil || !strings.Contains(err.Error(), "limited to 2 notes") {
+		t.Errorf("Expected the note limit to be enforced, got %v", err)
+	}
+
+	r = zipVault(t, map[string]string{"image.png": "png"})
+	if _, _, err := ReadVault(r, r.Size(), 10, testMaxContentLength); err == nil {
+		t.Error("Expected an archive without Markdown files to be rejected")
+	}
+
+	data := strings.NewReader("not a zip")
+	if _, _, err := ReadVault(data, data.Size(), 10, testMaxContentLength); err == nil {
+		t.Error("Expected an invalid archive to be rejected")
+	}
+}
@@ -0,0 +1,141 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Code is an ISO 639-1 language code stored on notes
+type Code string
+
+const (
+	// English content, searched with the "english" text search configuration
+	English Code = "en"
+	// Indonesian (Bahasa) content, searched with the "indonesian" text search configuration
+	Indonesian Code = "id"
+	// Unknown is used when there is not enough signal to pick a language
+	Unknown Code = "und"
+)
+
+// minSignalWords is the minimum number of stopword hits needed before a
+// language is chosen; shorter texts fall back to Unknown
+const minSignalWords = 2
+
+// englishStopwords are high-frequency English function words
+var englishStopwords = map[string]bool{
+	"the": true, "and": true, "is": true, "are": true, "was": true, "were": true,
+	"of": true, "to": true, "in": true, "that": true, "it": true, "for": true,
+	"with": true, "on": true, "this": true, "be": true, "as": true, "have": true,
+	"has": true, "not": true, "you": true, "i": true, "we": true, "they": true,
+	"at": true, "by": true, "from": true, "or": true, "but": true, "will": true,
+	"can": true, "should": true, "would": true, "an": true, "a": true, "my": true,
+	"our": true, "your": true, "what": true, "which": true, "there": true, "if": true,
+}
+
+// indonesianStopwords are high-frequency Indonesian function words
+var indonesianStopwords = map[string]bool{
+	"yang": true, "dan": true, "di": true, "ke": true, "dari": true, "ini": true,
+	"itu": true, "untuk": true, "dengan": true, "tidak": true, "ada": true, "akan": true,
+	"saya": true, "kita": true, "kami": true, "pada": true, "adalah": true, "juga": true,
+	"atau": true, "bisa": true, "sudah": true, "karena": true, "dalam": true, "mereka": true,
+	"apa": true, "belum": true, "lagi": true, "harus": true, "jadi": true, "oleh": true,
+	"sebagai": true, "tetapi": true, "tapi": true, "kalau": true, "jika": true, "aku": true,
+	"kamu": true, "anda": true, "sangat": true, "lebih": true, "masih": true, "bagaimana": true,
+	"nya": true, "dengannya": true, "hari": true, "besok": true, "kemarin": true, "perlu": true,
+}
+
+// indonesianAffixes are common Indonesian prefixes and suffixes used as a weaker signal
+var (
+	indonesianPrefixes = []string{"meng", "meny", "mem", "men", "ber", "ter", "per", "di"}
+	indonesianSuffixes = []string{"kan", "nya", "lah", "kah"}
+)
+
+// Detect guesses the language of the given text using stopword frequencies.
+// It is intentionally lightweight: notes are short and only English and
+// Indonesian need to be told apart for stemming.
+func Detect(text string) Code {
+	words := tokenize(text)
+	if len(words) == 0 {
+		return Unknown
+	}
+
+	var englishScore, indonesianScore float64
+	for _, word := range words {
+		if englishStopwords[word] {
+			englishScore++
+		}
+		if indonesianStopwords[word] {
+			indonesianScore++
+		} else if hasIndonesianAffix(word) {
+			indonesianScore += 0.25
+		}
+	}
+
+	if englishScore < minSignalWords && indonesianScore < minSignalWords {
+		return Unknown
+	}
+	if indonesianScore > englishScore {
+		return Indonesian
+	}
+	if englishScore > indonesianScore {
+		return English
+	}
+	return Unknown
+}
+
+// SearchConfig returns the PostgreSQL text search configuration for the language
+func (c Code) SearchConfig() string {
+	switch c {
+	case English:
+		return "english"
+	case Indonesian:
+		return "indonesian"
+	default:
+		return "simple"
+	}
+}
+
+// IsValid reports whether the code is one of the supported languages
+func (c Code) IsValid() bool {
+	return c == English || c == Indonesian || c == Unknown
+}
+
+// SearchConfigs returns every text search configuration a query must be
+// analyzed with so that notes in any supported language can match
+func SearchConfigs() []string {
+	return []string{English.SearchConfig(), Indonesian.SearchConfig(), Unknown.SearchConfig()}
+}
+
+// tokenize lowercases text and splits it into letter-only words, skipping hashtags and URLs
+func tokenize(text string) []string {
+	var words []string
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if strings.HasPrefix(field, "#") || strings.Contains(field, "://") {
+			continue
+		}
+		word := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// hasIndonesianAffix reports whether a word carries a typical Indonesian affix
+func hasIndonesianAffix(word string) bool {
+	if len(word) < 6 {
+		return false
+	}
+	for _, prefix := range indonesianPrefixes {
+		if strings.HasPrefix(word, prefix) {
+			for _, suffix := range indonesianSuffixes {
+				if strings.HasSuffix(word, suffix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Code
+	}{
+		{"english sentence", "We need to finish the report for the client and send it by Friday", English},
+		{"indonesian sentence", "Saya harus menyelesaikan laporan ini untuk klien dan mengirimkannya besok", Indonesian},
+		{"indonesian with hashtags", "Rapat dengan tim yang membahas anggaran untuk proyek baru #kerja #rapat", Indonesian},
+		{"english with url", "Read this article about the new release https://example.com/yang-dan-di", English},
+		{"too short", "groceries", Unknown},
+		{"empty", "", Unknown},
+		{"only hashtags", "#todo #work", Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchConfig(t *testing.T) {
+	if English.SearchConfig() != "english" {
+		t.Errorf("expected english config, got %s", English.SearchConfig())
+	}
+	if Indonesian.SearchConfig() != "indonesian" {
+		t.Errorf("expected indonesian config, got %s", Indonesian.SearchConfig())
+	}
+	if Code("fr").SearchConfig() != "simple" {
+		t.Errorf("expected simple config for unsupported language, got %s", Code("fr").SearchConfig())
+	}
+}
+
+func TestName(t *testing.T) {
+	if Indonesian.Name() != "Indonesian" || Code("ja").Name() != "Japanese" {
+		t.Errorf("unexpected names %q, %q", Indonesian.Name(), Code("ja").Name())
+	}
+	if Unknown.Name() != "" || Code("xx").Name() != "" {
+		t.Errorf("expected no name for unsupported languages")
+	}
+}
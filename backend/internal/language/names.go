@@ -0,0 +1,35 @@
+package language
+
+// names are the English names of the languages notes can be translated to
+var names = map[Code]string{
+	"ar":       "Arabic",
+	"de":       "German",
+	English:    "English",
+	"es":       "Spanish",
+	"fr":       "French",
+	"hi":       "Hindi",
+	Indonesian: "Indonesian",
+	"it":       "Italian",
+	"ja":       "Japanese",
+	"jv":       "Javanese",
+	"ko":       "Korean",
+	"ms":       "Malay",
+	"nl":       "Dutch",
+	"pl":       "Polish",
+	"pt":       "Portuguese",
+	"ru":       "Russian",
+	"su":       "Sundanese",
+	"sv":       "Swedish",
+	"th":       "Thai",
+	"tl":       "Tagalog",
+	"tr":       "Turkish",
+	"uk":       "Ukrainian",
+	"vi":       "Vietnamese",
+	"zh":       "Chinese",
+}
+
+// Name returns the English name of the language, or "" when notes cannot be
+// translated to it
+func (c Code) Name() string {
+	return names[c]
+}
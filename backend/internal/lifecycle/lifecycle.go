@@ -0,0 +1,161 @@
+// Package lifecycle tracks the background jobs of the server, such as the
+// periodic schedulers and migration pushes, so that shutdown stops starting
+// new jobs and waits for the ones in flight instead of killing them.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStopping is returned for jobs submitted once the manager is draining
+var ErrStopping = errors.New("server is shutting down")
+
+// abortTimeout is how long Drain waits, once its deadline has passed, for
+// the jobs it cancelled to save their progress and return
+const abortTimeout = 5 * time.Second
+
+// Manager runs background jobs and drains them on shutdown. Jobs get a
+// context that is only cancelled when draining times out, so that an LLM
+// call in flight is allowed to finish.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	stopping chan struct{}
+	stopped  bool
+	running  map[string]int
+	wg       sync.WaitGroup
+}
+
+// New creates a manager accepting jobs
+func New() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+		running:  make(map[string]int),
+	}
+}
+
+// Go runs job in the background. Once the manager is draining the job is
+// not run and ErrStopping is returned, so the caller can save it for the
+// next start.
+func (m *Manager) Go(name string, job func(ctx context.Context)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return ErrStopping
+	}
+
+	m.wg.Add(1)
+	m.running[name]++
+	go func() {
+		defer m.done(name)
+		job(m.ctx)
+	}()
+	return nil
+}
+
+// Every runs job every interval until the manager drains. A run in flight
+// when draining starts is waited for, but no run starts after it.
+func (m *Manager) Every(name string, interval time.Duration, job func(ctx context.Context)) {
+	m.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stopping:
+				return
+			case <-ticker.C:
+				// Both cases may be ready; never start a run while draining
+				if m.Stopping() {
+					return
+				}
+				job(ctx)
+			}
+		}
+	})
+}
+
+// Stopping reports whether the manager has stopped accepting jobs
+func (m *Manager) Stopping() bool {
+	select {
+	case <-m.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops accepting jobs and ends the schedulers between runs, without
+// waiting for the jobs in flight
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.stopped {
+		m.stopped = true
+		close(m.stopping)
+	}
+}
+
+// Drain stops accepting jobs and waits for the jobs in flight. When ctx ends
+// first, the context of the jobs is cancelled so they can save their
+// progress, and the error names the jobs that did not finish.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.Stop()
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Out of time: interrupt the jobs and give them a moment to save
+	running := m.Running()
+	m.cancel()
+	select {
+	case <-finished:
+	case <-time.After(abortTimeout):
+	}
+	return fmt.Errorf("interrupted background jobs: %s", strings.Join(running, ", "))
+}
+
+// Running returns the names of the jobs in flight, sorted
+func (m *Manager) Running() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.running))
+	for name := range m.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// done records the end of a job
+func (m *Manager) done(name string) {
+	m.mu.Lock()
+	m.running[name]--
+	if m.running[name] == 0 {
+		delete(m.running, name)
+	}
+	m.mu.Unlock()
+	m.wg.Done()
+}
@@ -0,0 +1,81 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrainWaitsForJobs(t *testing.T) {
+	m := New()
+	release := make(chan struct{})
+	var finished atomic.Bool
+	if err := m.Go("summarize", func(ctx context.Context) {
+		<-release
+		finished.Store(ctx.Err() == nil)
+	}); err != nil {
+		t.Fatalf("Go failed: %v", err)
+	}
+
+	drained := make(chan error)
+	go func() { drained <- m.Drain(context.Background()) }()
+
+	// No job is accepted while draining
+	time.Sleep(10 * time.Millisecond)
+	if err := m.Go("prettify", func(ctx context.Context) {}); !errors.Is(err, ErrStopping) {
+		t.Errorf("Expected ErrStopping, got %v", err)
+	}
+	if running := m.Running(); len(running) != 1 || running[0] != "summarize" {
+		t.Errorf("Expected the summarize job running, got %v", running)
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Errorf("Drain failed: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Expected the job to finish with a live context")
+	}
+}
+
+func TestDrainCancelsJobsPastDeadline(t *testing.T) {
+	m := New()
+	var cancelled atomic.Bool
+	m.Go("digest", func(ctx context.Context) {
+		<-ctx.Done()
+		cancelled.Store(true)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Drain(ctx)
+	if err == nil || err.Error() != "interrupted background jobs: digest" {
+		t.Errorf("Expected the digest job to be interrupted, got %v", err)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the job context to be cancelled")
+	}
+}
+
+func TestEveryStopsBetweenRuns(t *testing.T) {
+	m := New()
+	var runs atomic.Int32
+	m.Every("cleanup", time.Millisecond, func(ctx context.Context) {
+		runs.Add(1)
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := m.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	after := runs.Load()
+	if after == 0 {
+		t.Error("Expected the job to run before draining")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != after {
+		t.Error("Expected no run after draining")
+	}
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"strings"
+)
+
+// TokenCounter counts the tokens of text
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// chunkSeparator joins the blocks of a chunk, and chunks when reassembled
+const chunkSeparator = "\n\n"
+
+// Chunk splits text into chunks of at most maxTokens tokens for LLM calls
+// that cannot take it whole. Text is split between paragraphs, keeping
+// fenced code blocks together; a block longer than maxTokens is split between
+// lines, and a line between words. The same text always gives the same
+// chunks, and joining them with JoinChunks restores it up to whitespace
+// between paragraphs. Text within the budget is returned as one chunk.
+func Chunk(text string, maxTokens int, counter TokenCounter) []string {
+	if maxTokens <= 0 || counter.CountTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var pieces []string
+	for _, block := range splitBlocks(text) {
+		pieces = append(pieces, splitToFit(block, maxTokens, counter)...)
+	}
+	return pack(pieces, maxTokens, counter)
+}
+
+// JoinChunks reassembles chunks split by Chunk
+func JoinChunks(chunks []string) string {
+	trimmed := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			trimmed = append(trimmed, chunk)
+		}
+	}
+	return strings.Join(trimmed, chunkSeparator)
+}
+
+// splitBlocks splits text into paragraphs separated by blank lines. Blank
+// lines within fenced code blocks do not end the block.
+func splitBlocks(text string) []string {
+	var blocks, current []string
+	inFence := false
+	flush := func() {
+		if block := strings.TrimSpace(strings.Join(current, "\n")); block != "" {
+			blocks = append(blocks, block)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+// splitToFit splits a block longer than maxTokens between lines, and lines
+// longer than maxTokens between words
+func splitToFit(block string, maxTokens int, counter TokenCounter) []string {
+	if counter.CountTokens(block) <= maxTokens {
+		return []string{block}
+	}
+
+	lines := strings.Split(block, "\n")
+	if len(lines) > 1 {
+		var pieces []string
+		for _, line := range lines {
+			pieces = append(pieces, splitToFit(line, maxTokens, counter)...)
+		}
+		return packWith(pieces, "\n", maxTokens, counter)
+	}
+
+	words := strings.Fields(block)
+	if len(words) <= 1 {
+		// A single word longer than the budget cannot be split meaningfully
+		return []string{block}
+	}
+	return packWith(words, " ", maxTokens, counter)
+}
+
+// pack joins consecutive pieces into chunks of at most maxTokens tokens
+func pack(pieces []string, maxTokens int, counter TokenCounter) []string {
+	return packWith(pieces, chunkSeparator, maxTokens, counter)
+}
+
+// packWith joins consecutive pieces with separator into chunks of at most
+// maxTokens tokens. Pieces longer than maxTokens get a chunk of their own.
+func packWith(pieces []string, separator string, maxTokens int, counter TokenCounter) []string {
+	var chunks []string
+	current := ""
+	for _, piece := range pieces {
+		if current == "" {
+			current = piece
+			continue
+		}
+		if candidate := current + separator + piece; counter.CountTokens(candidate) <= maxTokens {
+			current = candidate
+			continue
+		}
+		chunks = append(chunks, current)
+		current = piece
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wordCounter counts words as tokens
+type wordCounter struct{}
+
+func (wordCounter) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestChunk(t *testing.T) {
+	text := "one two three\n\nfour five\n\n\nsix seven eight nine"
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		want      []string
+	}{
+		{"within budget", 20, []string{text}},
+		{"no budget", 0, []string{text}},
+		{"paragraphs packed", 5, []string{"one two three\n\nfour five", "six seven eight nine"}},
+		{"paragraph per chunk", 4, []string{"one two three", "four five", "six seven eight nine"}},
+		{"long paragraph split between words", 3, []string{"one two three", "four five", "six seven eight", "nine"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Chunk(text, tt.maxTokens, wordCounter{})
+			if !reflect.DeepEqual(chunks, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, chunks)
+			}
+			if !reflect.DeepEqual(Chunk(text, tt.maxTokens, wordCounter{}), chunks) {
+				t.Error("Expected the same text to give the same chunks")
+			}
+		})
+	}
+}
+
+func TestChunkKeepsCodeBlocks(t *testing.T) {
+	code := "```go\nfunc a() {\n\n\treturn\n}\n```"
+	text := "intro text here\n\n" + code + "\n\noutro"
+
+	// The code block is 7 tokens and its blank line does not split it
+	chunks := Chunk(text, 7, wordCounter{})
+	want := []string{"intro text here", code, "outro"}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("Expected %q, got %q", want, chunks)
+	}
+}
+
+func TestChunkSplitsLongBlocksBetweenLines(t *testing.T) {
+	text := "a b\nc d\ne f"
+
+	chunks := Chunk(text, 4, wordCounter{})
+	want := []string{"a b\nc d", "e f"}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("Expected %q, got %q", want, chunks)
+	}
+}
+
+func TestJoinChunks(t *testing.T) {
+	joined := JoinChunks([]string{" first\n", "", "second "})
+	if joined != "first\n\nsecond" {
+		t.Errorf("Unexpected reassembly %q", joined)
+	}
+}
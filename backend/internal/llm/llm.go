@@ -2,69 +2,180 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/telemetry"
 	"github.com/sony/gobreaker"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/openai"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ResilientLLM wraps an LLM with circuit breaker for resilience
+// ResilientLLM routes LLM calls through an ordered chain of providers. Each
+// provider has its own circuit breaker and timeout, and a failed call is
+// retried on the next provider in the chain.
 type ResilientLLM struct {
-	llm     llms.Model
-	breaker *gobreaker.CircuitBreaker
+	providers []*provider
+	usage     UsageRecorder
+	tokenizer *Tiktoken
 }
 
-// NewResilientLLM creates a new resilient LLM client based on configuration
+// NewResilientLLM creates a new resilient LLM client based on configuration.
+// Providers in the chain without credentials are skipped. A breaker passed by
+// the caller guards the primary provider.
 func NewResilientLLM(ctx context.Context, cfg *config.Config, breaker *gobreaker.CircuitBreaker) (*ResilientLLM, error) {
-	var llmClient llms.Model
-	var err error
+	// Provider requests carry the caller's trace context
+	httpClient := &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
 
-	switch cfg.LLM.Type {
-	case "DEEPSEEK_TENCENT":
-		if cfg.LLM.DeepseekTencentAPIKey == "" {
-			return nil, fmt.Errorf("DEEPSEEK_TENCENT_API_KEY is required")
+	var providers []*provider
+	var missing []error
+	for _, name := range ProviderChain(cfg) {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported LLM type: %s", name)
 		}
-		llmClient, err = openai.New(
-			openai.WithToken(cfg.LLM.DeepseekTencentAPIKey),
-			openai.WithBaseURL(cfg.LLM.DeepseekTencentBaseURL),
-			openai.WithModel(cfg.LLM.DeepseekTencentModel),
-		)
+		settings := factory.settings(&cfg.LLM)
+		if factory.keyEnv != "" && settings.apiKey == "" {
+			log.Printf("[LLM] Skipping provider %s: %s is not set", name, factory.keyEnv)
+			missing = append(missing, fmt.Errorf("%s is required", factory.keyEnv))
+			continue
+		}
+
+		client, err := factory.create(settings, httpClient)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create DeepSeek Tencent client: %w", err)
+			return nil, fmt.Errorf("failed to create %s client: %w", name, err)
+		}
+		timeout := time.Duration(cfg.LLM.RequestTimeout) * time.Second
+		if settings.timeout > 0 {
+			timeout = time.Duration(settings.timeout) * time.Second
+		}
+		providers = append(providers, &provider{
+			name:    name,
+			model:   settings.model,
+			llm:     client,
+			timeout: timeout,
+			cost:    settings.cost,
+			breaker: newBreaker(name),
+		})
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no LLM provider configured: %w", errors.Join(missing...))
+	}
+
+	if breaker != nil {
+		providers[0].breaker = breaker
+	}
+	return &ResilientLLM{providers: providers}, nil
+}
+
+// errStreamInterrupted marks a stream that failed after sending output. It is
+// not retried, as the next provider would repeat the output.
+var errStreamInterrupted = errors.New("stream interrupted")
+
+// ordered returns the providers in chain order, with those that failed
+// recently moved to the end as a last resort
+func (r *ResilientLLM) ordered() []*provider {
+	healthy := make([]*provider, 0, len(r.providers))
+	var unhealthy []*provider
+	for _, p := range r.providers {
+		if p.isHealthy() {
+			healthy = append(healthy, p)
+		} else {
+			unhealthy = append(unhealthy, p)
 		}
-	default:
-		return nil, fmt.Errorf("unsupported LLM type: %s", cfg.LLM.Type)
-	}
-
-	// Create circuit breaker if not provided
-	if breaker == nil {
-		breaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:        "llm",
-			MaxRequests: 3,
-			Interval:    60 * time.Second,
-			Timeout:     30 * time.Second,
-			ReadyToTrip: func(counts gobreaker.Counts) bool {
-				return counts.ConsecutiveFailures > 2
-			},
+	}
+	return append(healthy, unhealthy...)
+}
+
+// route makes a call on each provider in turn until one succeeds, and returns
+// the response with the provider that gave it. It stops once the caller's
+// context is done, since later providers would fail too.
+func (r *ResilientLLM) route(ctx context.Context, span trace.Span,
+	call func(ctx context.Context, p *provider) (*llms.ContentResponse, error)) (*llms.ContentResponse, *provider, error) {
+	var errs []error
+	for attempt, p := range r.ordered() {
+		attemptCtx, cancel := p.withTimeout(ctx)
+		result, err := p.breaker.Execute(func() (interface{}, error) {
+			return call(attemptCtx, p)
 		})
+		cancel()
+
+		if err == nil {
+			response, _ := result.(*llms.ContentResponse)
+			p.recordSuccess()
+			span.SetAttributes(
+				attribute.String("llm.provider", p.name),
+				attribute.String("gen_ai.response.model", p.model),
+				attribute.Int("llm.fallback_attempts", attempt),
+			)
+			return response, p, nil
+		}
+
+		if ctx.Err() != nil {
+			// Clients do not always wrap the context error
+			if !errors.Is(err, ctx.Err()) {
+				err = fmt.Errorf("%w: %w", ctx.Err(), err)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+			break
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		if errors.Is(err, errStreamInterrupted) {
+			break
+		}
+		log.Printf("[LLM] Provider %s failed: %v", p.name, err)
+		if !errors.Is(err, gobreaker.ErrOpenState) && !errors.Is(err, gobreaker.ErrTooManyRequests) {
+			p.recordFailure(err)
+		}
 	}
+	return nil, nil, errors.Join(errs...)
+}
+
+// startSpan starts a span for an LLM call routed through the provider chain.
+// The model and circuit breaker state are those of the primary provider.
+func (r *ResilientLLM) startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	primary := r.providers[0]
+	return telemetry.Tracer().Start(ctx, "llm."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.operation.name", operation),
+			attribute.String("gen_ai.request.model", primary.model),
+			attribute.String("llm.circuit_breaker.state", primary.breaker.State().String()),
+			attribute.Int("llm.providers", len(r.providers)),
+		),
+	)
+}
 
-	return &ResilientLLM{
-		llm:     llmClient,
-		breaker: breaker,
-	}, nil
+// endSpan records the outcome of an LLM call. Calls rejected by an open
+// circuit breaker are marked, since they never reach a provider.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+			span.SetAttributes(attribute.Bool("llm.circuit_breaker.rejected", true))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
 
 // GenerateFromSinglePrompt generates a completion from a single prompt
-func (r *ResilientLLM) GenerateFromSinglePrompt(ctx context.Context, prompt string) (string, error) {
+func (r *ResilientLLM) GenerateFromSinglePrompt(ctx context.Context, prompt string) (response string, err error) {
+	ctx, span := r.startSpan(ctx, "generate")
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(attribute.Int("llm.prompt_length", len(prompt)))
+
 	startTime := time.Now()
-	promptLen := len(prompt)
 	log.Printf("[LLM] Starting GenerateFromSinglePrompt")
-	log.Printf("[LLM]   Prompt length: %d chars", promptLen)
+	log.Printf("[LLM]   Prompt length: %d chars", len(prompt))
 	log.Printf("[LLM]   Prompt preview (first 200 chars): %s", truncateString(prompt, 200))
 
 	// Check context deadline
@@ -75,79 +186,35 @@ func (r *ResilientLLM) GenerateFromSinglePrompt(ctx context.Context, prompt stri
 		log.Printf("[LLM]   Context has NO deadline")
 	}
 
+	if err := r.CheckBudget(ctx); err != nil {
+		return "", err
+	}
+
 	// Check context before making the call
-	select {
-	case <-ctx.Done():
-		elapsed := time.Since(startTime)
-		log.Printf("[LLM] ERROR: Context cancelled before API call (elapsed: %v)", elapsed)
-		log.Printf("[LLM]   Context error: %v", ctx.Err())
+	if ctx.Err() != nil {
+		log.Printf("[LLM] ERROR: Context cancelled before API call: %v", ctx.Err())
 		return "", fmt.Errorf("context cancelled before API call: %w", ctx.Err())
-	default:
-		// Context is valid, proceed
 	}
 
-	log.Printf("[LLM] Calling LLM API via circuit breaker...")
-	log.Printf("[LLM]   Circuit breaker state: %v", r.breaker.State())
-
-	result, err := r.breaker.Execute(func() (interface{}, error) {
-		log.Printf("[LLM]   Inside circuit breaker Execute function")
-
-		// Check context again before calling API
-		select {
-		case <-ctx.Done():
-			log.Printf("[LLM]   ERROR: Context cancelled inside circuit breaker (before API call)")
-			log.Printf("[LLM]   Context error: %v", ctx.Err())
-			return nil, ctx.Err()
-		default:
-		}
-
-		log.Printf("[LLM]   Calling llms.GenerateFromSinglePrompt...")
-		apiStart := time.Now()
-
-		// Monitor context during the API call
-		resultChan := make(chan interface{}, 1)
-		errChan := make(chan error, 1)
-
-		go func() {
-			result, err := llms.GenerateFromSinglePrompt(ctx, r.llm, prompt)
-			resultChan <- result
-			errChan <- err
-		}()
-
-		// Wait for result or context cancellation
-		select {
-		case <-ctx.Done():
-			apiDuration := time.Since(apiStart)
-			log.Printf("[LLM]   ERROR: Context cancelled during API call!")
-			log.Printf("[LLM]   API call duration before cancellation: %v", apiDuration)
-			log.Printf("[LLM]   Context error: %v", ctx.Err())
-			return nil, fmt.Errorf("context cancelled during LLM API call after %v: %w", apiDuration, ctx.Err())
-		case result := <-resultChan:
-			apiDuration := time.Since(apiStart)
-			log.Printf("[LLM]   LLM API call completed in %v", apiDuration)
-			return result, <-errChan
-		}
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	result, p, err := r.route(ctx, span, func(ctx context.Context, p *provider) (*llms.ContentResponse, error) {
+		log.Printf("[LLM]   Calling provider %s (model %s, timeout %v)", p.name, p.model, p.timeout)
+		return p.llm.GenerateContent(ctx, messages)
 	})
-
-	elapsed := time.Since(startTime)
-	log.Printf("[LLM] Total GenerateFromSinglePrompt duration: %v", elapsed)
+	log.Printf("[LLM] Total GenerateFromSinglePrompt duration: %v", time.Since(startTime))
 
 	if err != nil {
-		log.Printf("[LLM] ERROR: LLM call failed")
-		log.Printf("[LLM]   Error type: %T", err)
-		log.Printf("[LLM]   Error message: %v", err)
+		log.Printf("[LLM] ERROR: LLM call failed on every provider: %v", err)
 		log.Printf("[LLM]   Context error after failure: %v", ctx.Err())
-		log.Printf("[LLM]   Context deadline exceeded: %v", ctx.Err() == context.DeadlineExceeded)
-		log.Printf("[LLM]   Circuit breaker state after failure: %v", r.breaker.State())
 		return "", fmt.Errorf("LLM API call failed: %w", err)
 	}
-
-	response, ok := result.(string)
-	if !ok {
-		log.Printf("[LLM] ERROR: Type assertion failed, expected string but got %T", result)
-		return "", fmt.Errorf("unexpected response type from LLM: %T", result)
+	if result == nil || len(result.Choices) == 0 {
+		return "", fmt.Errorf("LLM API call failed: empty response")
 	}
 
+	response = result.Choices[0].Content
+	r.account(ctx, span, p, result, prompt, response)
+	span.SetAttributes(attribute.Int("llm.response_length", len(response)))
 	log.Printf("[LLM] SUCCESS: Got response from LLM")
 	log.Printf("[LLM]   Response length: %d chars", len(response))
 	log.Printf("[LLM]   Response preview (first 200 chars): %s", truncateString(response, 200))
@@ -164,21 +231,53 @@ func truncateString(s string, maxLen int) string {
 }
 
 // GenerateContent generates a completion from message content
-func (r *ResilientLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent) (*llms.ContentResponse, error) {
-	result, err := r.breaker.Execute(func() (interface{}, error) {
-		return r.llm.GenerateContent(ctx, messages)
+func (r *ResilientLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent) (_ *llms.ContentResponse, err error) {
+	ctx, span := r.startSpan(ctx, "chat")
+	defer func() { endSpan(span, err) }()
+
+	if err := r.CheckBudget(ctx); err != nil {
+		return nil, err
+	}
+
+	response, p, err := r.route(ctx, span, func(ctx context.Context, p *provider) (*llms.ContentResponse, error) {
+		return p.llm.GenerateContent(ctx, messages)
 	})
 	if err != nil {
 		return nil, err
 	}
-	return result.(*llms.ContentResponse), nil
+	r.account(ctx, span, p, response, messagesText(messages), responseText(response))
+	return response, nil
 }
 
-// Stream generates a streaming completion from a single prompt
-func (r *ResilientLLM) Stream(ctx context.Context, prompt string, streamingFunc func(context.Context, []byte) error) error {
-	_, err := r.breaker.Execute(func() (interface{}, error) {
-		response, err := llms.GenerateFromSinglePrompt(ctx, r.llm, prompt, llms.WithStreamingFunc(streamingFunc))
+// Stream generates a streaming completion from a single prompt. Streams are
+// bounded by the caller's deadline only, since they may run longer than a
+// single request. A stream that fails after sending output is not retried on
+// the next provider.
+func (r *ResilientLLM) Stream(ctx context.Context, prompt string, streamingFunc func(context.Context, []byte) error) (err error) {
+	ctx, span := r.startSpan(ctx, "stream")
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(attribute.Int("llm.prompt_length", len(prompt)))
+
+	if err := r.CheckBudget(ctx); err != nil {
+		return err
+	}
+
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)}
+	var completion strings.Builder
+	response, p, err := r.route(ctx, span, func(_ context.Context, p *provider) (*llms.ContentResponse, error) {
+		completion.Reset()
+		response, err := p.llm.GenerateContent(ctx, messages, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			completion.Write(chunk)
+			return streamingFunc(ctx, chunk)
+		}))
+		if err != nil && completion.Len() > 0 {
+			return nil, fmt.Errorf("%w: %w", errStreamInterrupted, err)
+		}
 		return response, err
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	r.account(ctx, span, p, response, prompt, completion.String())
+	return nil
 }
@@ -2,9 +2,20 @@ package llm
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gpd/my-notes/internal/config"
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNewResilientLLM(t *testing.T) {
@@ -26,3 +37,135 @@ func TestNewResilientLLM(t *testing.T) {
 		t.Error("Expected non-nil LLM")
 	}
 }
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestLLMCallPropagatesTraceContext(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Type:                   "DEEPSEEK_TENCENT",
+			DeepseekTencentAPIKey:  "test-key",
+			DeepseekTencentBaseURL: server.URL,
+			DeepseekTencentModel:   "deepseek-v3",
+		},
+	}
+	llm, err := NewResilientLLM(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("NewResilientLLM failed: %v", err)
+	}
+
+	response, err := llm.GenerateFromSinglePrompt(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("GenerateFromSinglePrompt failed: %v", err)
+	}
+	if response != "hello" {
+		t.Errorf("Expected hello, got %q", response)
+	}
+
+	var llmSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "llm.generate" {
+			llmSpan = span
+		}
+	}
+	if llmSpan == nil {
+		t.Fatal("Expected an llm.generate span")
+	}
+	if traceparent == "" || !strings.Contains(traceparent, llmSpan.SpanContext().TraceID().String()) {
+		t.Errorf("Expected the provider request to carry trace %s, got traceparent %q", llmSpan.SpanContext().TraceID(), traceparent)
+	}
+}
+
+func TestLLMSpanMarksCircuitBreakerRejection(t *testing.T) {
+	recorder := recordSpans(t)
+
+	breaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "test",
+		Timeout:     time.Minute,
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return true },
+	})
+	breaker.Execute(func() (interface{}, error) { return nil, errors.New("provider down") })
+
+	llm := &ResilientLLM{providers: []*provider{{name: "test", breaker: breaker}}}
+	if _, err := llm.GenerateContent(context.Background(), nil); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("Expected the open breaker to reject the call, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected an error status, got %v", spans[0].Status())
+	}
+	rejected := false
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "llm.circuit_breaker.rejected" && attr.Value.AsBool() {
+			rejected = true
+		}
+	}
+	if !rejected {
+		t.Errorf("Expected the span to be marked as rejected, got %v", spans[0].Attributes())
+	}
+}
+
+func TestLLMCallHonorsRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Type:                   "DEEPSEEK_TENCENT",
+			DeepseekTencentAPIKey:  "test-key",
+			DeepseekTencentBaseURL: server.URL,
+			DeepseekTencentModel:   "deepseek-v3",
+			RequestTimeout:         30,
+		},
+	}
+	llm, err := NewResilientLLM(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("NewResilientLLM failed: %v", err)
+	}
+
+	// The caller's deadline is shorter than the configured timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = llm.GenerateFromSinglePrompt(ctx, "hi")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the call to stop at the caller's deadline, took %v", elapsed)
+	}
+}
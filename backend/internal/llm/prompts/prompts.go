@@ -0,0 +1,290 @@
+// Package prompts renders the LLM prompts of the note features from Go
+// templates. The built-in templates are embedded in the binary; a file of
+// the same name in the override directory replaces one, and overrides can be
+// reloaded at runtime to iterate on prompts without a deploy.
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Prompt names. Prompts of LLM features are named after the feature.
+const (
+	Prettify           = "prettify"
+	PrettifyCorrection = "prettify_correction"
+	TagSuggestions     = "tag_suggestions"
+	Digest             = "digest"
+	WebClip            = "web_clip"
+	Proofread          = "proofread"
+	Translate          = "translate"
+	Title              = "title"
+)
+
+// templateExt is the extension of prompt template files
+const templateExt = ".tmpl"
+
+//go:embed *.tmpl
+var embedded embed.FS
+
+// PrettifyData is rendered by the prettify prompt
+type PrettifyData struct {
+	Title    string
+	Content  string
+	UserTags []string
+}
+
+// PrettifyCorrectionData is rendered by the prompt retrying a prettification
+// that lost content of the note
+type PrettifyCorrectionData struct {
+	Prompt string   // the prettify prompt
+	Lost   []string // URLs, hashtags and code blocks missing from the previous response
+}
+
+// TagSuggestionsData is rendered by the tag suggestions prompt
+type TagSuggestionsData struct {
+	MaxSuggestions int
+	Title          string
+	Content        string
+	UserTags       []string
+}
+
+// DigestTodo is an open to-do listed in the digest prompt
+type DigestTodo struct {
+	Title     string
+	OpenItems int
+}
+
+// DigestData is rendered by the digest summary prompt
+type DigestData struct {
+	Frequency     string
+	NewNotesTotal int
+	NewNotes      []string
+	OpenTodos     []DigestTodo
+}
+
+// WebClipData is rendered by the prompt summarizing clipped web pages
+type WebClipData struct {
+	Title   string
+	Content string
+}
+
+// ProofreadData is rendered by the spelling and grammar prompt
+type ProofreadData struct {
+	Title   string
+	Content string
+}
+
+// TranslateData is rendered by the prompt translating notes
+type TranslateData struct {
+	// Language is the English name of the language to translate to
+	Language string
+	Title    string
+	Content  string
+}
+
+// TitleData is rendered by the prompt titling notes created without a title
+type TitleData struct {
+	Content string
+}
+
+// samples holds data of the type each prompt is rendered with. Templates are
+// rendered with it when loaded, so one referring to unknown fields is
+// rejected instead of failing the feature later.
+var samples = map[string]interface{}{
+	Prettify:           PrettifyData{},
+	PrettifyCorrection: PrettifyCorrectionData{},
+	TagSuggestions:     TagSuggestionsData{},
+	Digest:             DigestData{},
+	WebClip:            WebClipData{},
+	Proofread:          ProofreadData{},
+	Translate:          TranslateData{},
+	Title:              TitleData{},
+}
+
+// funcs are the functions available to templates
+var funcs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Info describes a loaded prompt
+type Info struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // "embedded", or the path of the override file
+	Hash   string `json:"hash"`
+}
+
+// Status lists the loaded prompts
+type Status struct {
+	Dir      string    `json:"dir,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Prompts  []Info    `json:"prompts"`
+	Changed  []string  `json:"changed,omitempty"` // prompts changed by the last reload
+}
+
+// prompt is a parsed template and where it came from
+type prompt struct {
+	template *template.Template
+	info     Info
+}
+
+// Registry holds the prompt templates. It is safe for concurrent use.
+type Registry struct {
+	dir string
+
+	mu       sync.RWMutex
+	prompts  map[string]*prompt
+	loadedAt time.Time
+	changed  []string
+}
+
+// New creates a registry of the built-in prompts. Overrides in dir are
+// loaded by Reload; an empty dir disables overrides.
+func New(dir string) *Registry {
+	prompts, err := load("")
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in prompt: %v", err))
+	}
+	return &Registry{dir: dir, prompts: prompts, loadedAt: time.Now()}
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns a shared registry of the built-in prompts
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		defaultRegistry = New("")
+	})
+	return defaultRegistry
+}
+
+// Reload loads the prompts again, applying the overrides. When a template is
+// invalid the loaded prompts are kept and an error is returned.
+func (r *Registry) Reload() (*Status, error) {
+	prompts, err := load(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.changed = r.changed[:0]
+	for name, p := range prompts {
+		if old, ok := r.prompts[name]; !ok || old.info.Hash != p.info.Hash {
+			r.changed = append(r.changed, name)
+		}
+	}
+	sort.Strings(r.changed)
+	r.prompts = prompts
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+
+	return r.Status(), nil
+}
+
+// Status returns the loaded prompts
+func (r *Registry) Status() *Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := &Status{
+		Dir:      r.dir,
+		LoadedAt: r.loadedAt,
+		Prompts:  make([]Info, 0, len(r.prompts)),
+		Changed:  append([]string(nil), r.changed...),
+	}
+	for _, p := range r.prompts {
+		status.Prompts = append(status.Prompts, p.info)
+	}
+	sort.Slice(status.Prompts, func(i, j int) bool {
+		return status.Prompts[i].Name < status.Prompts[j].Name
+	})
+	return status
+}
+
+// Render renders the named prompt with data. Surrounding whitespace is
+// trimmed, so template files may end with a newline.
+func (r *Registry) Render(name string, data interface{}) (string, error) {
+	r.mu.RLock()
+	p, ok := r.prompts[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown prompt %q", name)
+	}
+
+	var out bytes.Buffer
+	if err := p.template.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// load parses the built-in prompts and the overrides in dir
+func load(dir string) (map[string]*prompt, error) {
+	prompts := make(map[string]*prompt, len(samples))
+	for name := range samples {
+		text, err := embedded.ReadFile(name + templateExt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in prompt %s: %w", name, err)
+		}
+		p, err := parse(name, string(text), "embedded")
+		if err != nil {
+			return nil, err
+		}
+		prompts[name] = p
+	}
+	if dir == "" {
+		return prompts, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+templateExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt overrides: %w", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), templateExt)
+		if _, ok := samples[name]; !ok {
+			return nil, fmt.Errorf("unknown prompt %q in %s", name, path)
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt %s: %w", name, err)
+		}
+		p, err := parse(name, string(text), path)
+		if err != nil {
+			return nil, err
+		}
+		prompts[name] = p
+	}
+	return prompts, nil
+}
+
+// parse parses a prompt template and checks it renders with the prompt's
+// data type
+func parse(name, text, source string) (*prompt, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt %s: %w", name, err)
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, samples[name]); err != nil {
+		return nil, fmt.Errorf("invalid prompt %s: %w", name, err)
+	}
+
+	hash := sha256.Sum256([]byte(text))
+	return &prompt{
+		template: tmpl,
+		info:     Info{Name: name, Source: source, Hash: hex.EncodeToString(hash[:6])},
+	}, nil
+}
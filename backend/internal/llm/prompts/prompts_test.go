@@ -0,0 +1,98 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltInPrompts(t *testing.T) {
+	prompt, err := Default().Render(TagSuggestions, TagSuggestionsData{
+		MaxSuggestions: 5,
+		Title:          "Trip",
+		Content:        "Pack the tent",
+		UserTags:       []string{"#travel", "#todo"},
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	for _, want := range []string{"Suggest up to 5 hashtags", "Title: Trip\nContent: Pack the tent", "#travel, #todo"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected the prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if strings.HasSuffix(prompt, "\n") {
+		t.Error("Expected the trailing newline of the template to be trimmed")
+	}
+
+	if _, err := Default().Render("unknown", nil); err == nil {
+		t.Error("Expected an error rendering an unknown prompt")
+	}
+}
+
+func TestReloadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	registry := New(dir)
+
+	status, err := registry.Reload()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(status.Changed) != 0 {
+		t.Errorf("Expected no changes without overrides, got %v", status.Changed)
+	}
+
+	override := filepath.Join(dir, "digest.tmpl")
+	if err := os.WriteFile(override, []byte("Summarize the {{.Frequency}} digest\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, err = registry.Reload()
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(status.Changed) != 1 || status.Changed[0] != Digest {
+		t.Errorf("Expected the digest prompt to change, got %v", status.Changed)
+	}
+	for _, info := range status.Prompts {
+		if info.Name == Digest && info.Source != override {
+			t.Errorf("Expected the digest prompt from %s, got %s", override, info.Source)
+		}
+	}
+
+	prompt, err := registry.Render(Digest, DigestData{Frequency: "daily"})
+	if err != nil || prompt != "Summarize the daily digest" {
+		t.Errorf("Expected the override to render, got %q, %v", prompt, err)
+	}
+}
+
+func TestReloadRejectsInvalidOverrides(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		text string
+	}{
+		{"syntax error", "digest.tmpl", "{{.Frequency"},
+		{"unknown field", "digest.tmpl", "{{.Content}}"},
+		{"unknown prompt", "summary.tmpl", "Summarize"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			registry := New(dir)
+			if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.text), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := registry.Reload(); err == nil {
+				t.Fatal("Expected Reload to fail")
+			}
+			// The loaded prompts are kept
+			prompt, err := registry.Render(Digest, DigestData{Frequency: "weekly"})
+			if err != nil || !strings.Contains(prompt, "weekly notes digest") {
+				t.Errorf("Expected the built-in prompt to be kept, got %q, %v", prompt, err)
+			}
+		})
+	}
+}
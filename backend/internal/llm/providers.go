@@ -0,0 +1,276 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/sony/gobreaker"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// Provider types that can appear in the fallback chain
+const (
+	ProviderDeepseekTencent = "DEEPSEEK_TENCENT"
+	ProviderOpenAI          = "OPENAI"
+	ProviderAnthropic       = "ANTHROPIC"
+	ProviderOllama          = "OLLAMA"
+)
+
+// providerSettings is the configuration of one provider
+type providerSettings struct {
+	model   string
+	apiKey  string
+	baseURL string
+	timeout int     // seconds, 0 uses the request timeout
+	cost    float64 // USD per 1K tokens
+}
+
+// providerFactory describes how to configure and create a provider client
+type providerFactory struct {
+	keyEnv   string // variable holding the required API key, empty for local providers
+	settings func(cfg *config.LLMConfig) providerSettings
+	create   func(settings providerSettings, httpClient *http.Client) (llms.Model, error)
+}
+
+// registry holds the supported providers by type
+var registry = map[string]providerFactory{
+	ProviderDeepseekTencent: {
+		keyEnv: "DEEPSEEK_TENCENT_API_KEY",
+		settings: func(cfg *config.LLMConfig) providerSettings {
+			return providerSettings{cfg.DeepseekTencentModel, cfg.DeepseekTencentAPIKey, cfg.DeepseekTencentBaseURL,
+				cfg.DeepseekTencentTimeout, cfg.DeepseekTencentCost}
+		},
+		create: newOpenAICompatible,
+	},
+	ProviderOpenAI: {
+		keyEnv: "OPENAI_API_KEY",
+		settings: func(cfg *config.LLMConfig) providerSettings {
+			return providerSettings{cfg.OpenAIModel, cfg.OpenAIAPIKey, cfg.OpenAIBaseURL, cfg.OpenAITimeout, cfg.OpenAICost}
+		},
+		create: newOpenAICompatible,
+	},
+	ProviderAnthropic: {
+		keyEnv: "ANTHROPIC_API_KEY",
+		settings: func(cfg *config.LLMConfig) providerSettings {
+			return providerSettings{cfg.AnthropicModel, cfg.AnthropicAPIKey, cfg.AnthropicBaseURL,
+				cfg.AnthropicTimeout, cfg.AnthropicCost}
+		},
+		create: func(settings providerSettings, httpClient *http.Client) (llms.Model, error) {
+			return anthropic.New(
+				anthropic.WithToken(settings.apiKey),
+				anthropic.WithBaseURL(settings.baseURL),
+				anthropic.WithModel(settings.model),
+				anthropic.WithHTTPClient(httpClient),
+			)
+		},
+	},
+	ProviderOllama: {
+		settings: func(cfg *config.LLMConfig) providerSettings {
+			return providerSettings{model: cfg.OllamaModel, baseURL: cfg.OllamaBaseURL,
+				timeout: cfg.OllamaTimeout, cost: cfg.OllamaCost}
+		},
+		create: func(settings providerSettings, httpClient *http.Client) (llms.Model, error) {
+			return ollama.New(
+				ollama.WithServerURL(settings.baseURL),
+				ollama.WithModel(settings.model),
+				ollama.WithHTTPClient(httpClient),
+			)
+		},
+	},
+}
+
+// newOpenAICompatible creates a client for providers serving the OpenAI API
+func newOpenAICompatible(settings providerSettings, httpClient *http.Client) (llms.Model, error) {
+	return openai.New(
+		openai.WithToken(settings.apiKey),
+		openai.WithBaseURL(settings.baseURL),
+		openai.WithModel(settings.model),
+		openai.WithHTTPClient(httpClient),
+	)
+}
+
+// ProviderChain returns the configured provider types in order of preference.
+// It defaults to the single provider of LLM_TYPE. Types are case-insensitive.
+func ProviderChain(cfg *config.Config) []string {
+	names := cfg.LLM.Providers
+	if len(names) == 0 {
+		names = []string{cfg.LLM.Type}
+	}
+	chain := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.ToUpper(strings.TrimSpace(name)); name != "" {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// Configured reports whether any provider in the chain has its credentials
+func Configured(cfg *config.Config) bool {
+	for _, name := range ProviderChain(cfg) {
+		factory, ok := registry[name]
+		if ok && (factory.keyEnv == "" || factory.settings(&cfg.LLM).apiKey != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// provider is one LLM in the fallback chain, with its own circuit breaker
+// and timeout
+type provider struct {
+	name    string
+	model   string
+	llm     llms.Model
+	timeout time.Duration
+	cost    float64
+	breaker *gobreaker.CircuitBreaker
+
+	mu        sync.Mutex
+	unhealthy bool
+	lastError string
+	checkedAt time.Time
+	calls     int64
+	tokens    int64
+}
+
+// newBreaker creates the circuit breaker of a provider
+func newBreaker(name string) *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "llm-" + name,
+		MaxRequests: 3,
+		Interval:    60 * time.Second,
+		Timeout:     30 * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures > 2
+		},
+	})
+}
+
+// withTimeout bounds a call by the provider timeout. A shorter deadline set
+// by the caller still applies.
+func (p *provider) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.timeout)
+}
+
+// recordSuccess marks the provider healthy and counts the call
+func (p *provider) recordSuccess() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unhealthy = false
+	p.lastError = ""
+	p.calls++
+}
+
+// addTokens adds the tokens of a call to the provider's usage
+func (p *provider) addTokens(tokens int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens += int64(tokens)
+}
+
+// recordFailure marks the provider unhealthy until a call or probe succeeds
+func (p *provider) recordFailure(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unhealthy = true
+	p.lastError = err.Error()
+}
+
+// isHealthy reports whether the provider should be tried before the others
+func (p *provider) isHealthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.unhealthy && p.breaker.State() != gobreaker.StateOpen
+}
+
+// estimateCost returns the cost in USD of the given number of tokens
+func (p *provider) estimateCost(tokens int) float64 {
+	return float64(tokens) / 1000 * p.cost
+}
+
+// ProviderStatus reports the health and usage of a provider
+type ProviderStatus struct {
+	Name         string    `json:"name"`
+	Model        string    `json:"model"`
+	Healthy      bool      `json:"healthy"`
+	CircuitState string    `json:"circuit_state"`
+	LastError    string    `json:"last_error,omitempty"`
+	CheckedAt    time.Time `json:"checked_at,omitempty"`
+	Calls        int64     `json:"calls"`
+	Tokens       int64     `json:"tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+}
+
+// ProviderStatuses returns the status of each provider in chain order
+func (r *ResilientLLM) ProviderStatuses() []ProviderStatus {
+	statuses := make([]ProviderStatus, len(r.providers))
+	for i, p := range r.providers {
+		healthy := p.isHealthy()
+		p.mu.Lock()
+		statuses[i] = ProviderStatus{
+			Name:         p.name,
+			Model:        p.model,
+			Healthy:      healthy,
+			CircuitState: p.breaker.State().String(),
+			LastError:    p.lastError,
+			CheckedAt:    p.checkedAt,
+			Calls:        p.calls,
+			Tokens:       p.tokens,
+			CostUSD:      p.estimateCost(int(p.tokens)),
+		}
+		p.mu.Unlock()
+	}
+	return statuses
+}
+
+// CheckHealth probes each provider with a one-token completion. Probes go
+// through the provider's circuit breaker, so an open breaker is only probed
+// once its timeout has passed.
+func (r *ResilientLLM) CheckHealth(ctx context.Context) []ProviderStatus {
+	var wg sync.WaitGroup
+	for _, p := range r.providers {
+		wg.Add(1)
+		go func(p *provider) {
+			defer wg.Done()
+			probeCtx, cancel := p.withTimeout(ctx)
+			defer cancel()
+
+			_, err := p.breaker.Execute(func() (interface{}, error) {
+				return p.llm.GenerateContent(probeCtx,
+					[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "ping")},
+					llms.WithMaxTokens(1))
+			})
+			p.mu.Lock()
+			p.checkedAt = time.Now()
+			p.mu.Unlock()
+			switch {
+			case err == nil:
+				p.mu.Lock()
+				p.unhealthy = false
+				p.lastError = ""
+				p.mu.Unlock()
+			case errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests):
+				// Still cooling down; keep the last known state
+			default:
+				log.Printf("[LLM] Health check of provider %s failed: %v", p.name, err)
+				p.recordFailure(fmt.Errorf("health check failed: %w", err))
+			}
+		}(p)
+	}
+	wg.Wait()
+	return r.ProviderStatuses()
+}
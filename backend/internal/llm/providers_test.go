@@ -0,0 +1,241 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/sony/gobreaker"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// stubModel answers every call with response, or fails with err. Chunks are
+// streamed before failing.
+type stubModel struct {
+	response string
+	chunks   []string
+	err      error
+	calls    int
+}
+
+func (m *stubModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	opts := llms.CallOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for _, chunk := range m.chunks {
+			if err := opts.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        m.response,
+		GenerationInfo: map[string]any{"PromptTokens": 300, "CompletionTokens": 200},
+	}}}, nil
+}
+
+func (m *stubModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// chainOf builds a ResilientLLM over the given models, named p0, p1, ...
+func chainOf(models ...*stubModel) *ResilientLLM {
+	r := &ResilientLLM{}
+	for i, model := range models {
+		name := "p" + string(rune('0'+i))
+		r.providers = append(r.providers, &provider{name: name, model: name, llm: model, cost: 0.002, breaker: newBreaker(name)})
+	}
+	return r
+}
+
+func TestFallsBackToNextProvider(t *testing.T) {
+	primary := &stubModel{err: errors.New("provider down")}
+	secondary := &stubModel{response: "pretty"}
+	r := chainOf(primary, secondary)
+
+	response, err := r.GenerateFromSinglePrompt(context.Background(), "hi")
+	if err != nil {
+		t.Fatalf("GenerateFromSinglePrompt failed: %v", err)
+	}
+	if response != "pretty" {
+		t.Errorf("Expected the secondary response, got %q", response)
+	}
+
+	statuses := r.ProviderStatuses()
+	if statuses[0].Healthy || statuses[0].LastError == "" {
+		t.Errorf("Expected the primary to be marked unhealthy, got %+v", statuses[0])
+	}
+	if !statuses[1].Healthy || statuses[1].Calls != 1 || statuses[1].Tokens != 500 || statuses[1].CostUSD != 0.001 {
+		t.Errorf("Expected the secondary to record the call, got %+v", statuses[1])
+	}
+
+	// The failed primary is tried last until it recovers
+	r.GenerateFromSinglePrompt(context.Background(), "hi")
+	if primary.calls != 1 || secondary.calls != 2 {
+		t.Errorf("Expected the secondary to be tried first, got %d and %d calls", primary.calls, secondary.calls)
+	}
+}
+
+func TestAllProvidersFailing(t *testing.T) {
+	r := chainOf(&stubModel{err: errors.New("first down")}, &stubModel{err: errors.New("second down")})
+
+	_, err := r.GenerateContent(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, message := range []string{"p0: first down", "p1: second down"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q in %v", message, err)
+		}
+	}
+}
+
+func TestOpenBreakerSkipsProvider(t *testing.T) {
+	primary := &stubModel{response: "primary"}
+	r := chainOf(primary, &stubModel{response: "secondary"})
+	r.providers[0].breaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Timeout:     time.Minute,
+		ReadyToTrip: func(counts gobreaker.Counts) bool { return true },
+	})
+	r.providers[0].breaker.Execute(func() (interface{}, error) { return nil, errors.New("provider down") })
+
+	response, err := r.GenerateFromSinglePrompt(context.Background(), "hi")
+	if err != nil || response != "secondary" {
+		t.Fatalf("Expected the secondary response, got %q, %v", response, err)
+	}
+	if primary.calls != 0 {
+		t.Errorf("Expected the open breaker to skip the primary, got %d calls", primary.calls)
+	}
+}
+
+func TestCallerDeadlineStopsFallback(t *testing.T) {
+	secondary := &stubModel{response: "secondary"}
+	r := chainOf(&stubModel{err: context.Canceled}, secondary)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.GenerateContent(ctx, nil); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if secondary.calls != 0 {
+		t.Errorf("Expected no fallback once the caller gave up, got %d calls", secondary.calls)
+	}
+}
+
+func TestStreamFallback(t *testing.T) {
+	t.Run("before output", func(t *testing.T) {
+		r := chainOf(&stubModel{err: errors.New("provider down")}, &stubModel{chunks: []string{"a", "b"}})
+		var output strings.Builder
+		err := r.Stream(context.Background(), "hi", func(ctx context.Context, chunk []byte) error {
+			output.Write(chunk)
+			return nil
+		})
+		if err != nil || output.String() != "ab" {
+			t.Errorf("Expected the secondary stream, got %q, %v", output.String(), err)
+		}
+	})
+
+	t.Run("after output", func(t *testing.T) {
+		secondary := &stubModel{chunks: []string{"b"}}
+		r := chainOf(&stubModel{chunks: []string{"a"}, err: errors.New("connection reset")}, secondary)
+		err := r.Stream(context.Background(), "hi", func(ctx context.Context, chunk []byte) error { return nil })
+		if !errors.Is(err, errStreamInterrupted) {
+			t.Errorf("Expected an interrupted stream, got %v", err)
+		}
+		if secondary.calls != 0 {
+			t.Errorf("Expected no retry after output was sent, got %d calls", secondary.calls)
+		}
+	})
+}
+
+func TestCheckHealth(t *testing.T) {
+	down := &stubModel{err: errors.New("provider down")}
+	r := chainOf(down, &stubModel{response: "pong"})
+
+	statuses := r.CheckHealth(context.Background())
+	if statuses[0].Healthy || statuses[0].CheckedAt.IsZero() {
+		t.Errorf("Expected the failing provider to be unhealthy, got %+v", statuses[0])
+	}
+	if !statuses[1].Healthy {
+		t.Errorf("Expected the other provider to be healthy, got %+v", statuses[1])
+	}
+
+	down.err = nil
+	if statuses = r.CheckHealth(context.Background()); !statuses[0].Healthy {
+		t.Errorf("Expected the provider to recover, got %+v", statuses[0])
+	}
+}
+
+func TestNewResilientLLMChain(t *testing.T) {
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Type:                  "DEEPSEEK_TENCENT",
+			Providers:             []string{"openai", " DEEPSEEK_TENCENT", "ollama"},
+			DeepseekTencentAPIKey: "test-key",
+			DeepseekTencentModel:  "deepseek-v3",
+			DeepseekTencentCost:   0.001,
+			OllamaModel:           "llama3.1",
+			OllamaBaseURL:         "http://localhost:11434",
+			OllamaTimeout:         120,
+			RequestTimeout:        30,
+		},
+	}
+
+	r, err := NewResilientLLM(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("NewResilientLLM failed: %v", err)
+	}
+	// OpenAI has no key and is skipped
+	if len(r.providers) != 2 || r.providers[0].name != ProviderDeepseekTencent || r.providers[1].name != ProviderOllama {
+		t.Fatalf("Unexpected chain: %+v", r.ProviderStatuses())
+	}
+	if r.providers[0].timeout != 30*time.Second || r.providers[1].timeout != 120*time.Second {
+		t.Errorf("Expected per-provider timeouts, got %v and %v", r.providers[0].timeout, r.providers[1].timeout)
+	}
+
+	cfg.LLM.Providers = []string{"OPENAI"}
+	if _, err := NewResilientLLM(context.Background(), cfg, nil); err == nil || !strings.Contains(err.Error(), "OPENAI_API_KEY is required") {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+	if Configured(cfg) {
+		t.Error("Expected a chain without credentials to be unconfigured")
+	}
+
+	cfg.LLM.Providers = []string{"GEMINI"}
+	if _, err := NewResilientLLM(context.Background(), cfg, nil); err == nil || !strings.Contains(err.Error(), "unsupported LLM type") {
+		t.Errorf("Expected an unsupported type error, got %v", err)
+	}
+}
+
+func TestOllamaWithoutAPIKey(t *testing.T) {
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Type:          "ollama",
+			OllamaModel:   "llama3.1",
+			OllamaBaseURL: "http://localhost:11434",
+		},
+	}
+
+	if chain := ProviderChain(cfg); len(chain) != 1 || chain[0] != ProviderOllama {
+		t.Fatalf("Expected LLM_TYPE to select Ollama, got %v", chain)
+	}
+	if !Configured(cfg) {
+		t.Error("Expected Ollama to need no API key")
+	}
+	r, err := NewResilientLLM(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatalf("NewResilientLLM failed: %v", err)
+	}
+	if len(r.providers) != 1 || r.providers[0].model != "llama3.1" {
+		t.Errorf("Unexpected chain: %+v", r.ProviderStatuses())
+	}
+}
@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Usage is the token usage of one LLM call
+type Usage struct {
+	UserID           string
+	Feature          string
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+}
+
+// TotalTokens returns the prompt and completion tokens of the call
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// UsageRecorder stores the token usage of LLM calls and enforces budgets
+type UsageRecorder interface {
+	CheckBudget(ctx context.Context, userID string) error
+	RecordUsage(ctx context.Context, usage Usage) error
+}
+
+// caller identifies the user and feature an LLM call is made for
+type caller struct {
+	userID  string
+	feature string
+}
+
+type callerKey struct{}
+
+// WithCaller attributes the LLM calls made with ctx to a user and feature,
+// for usage accounting and budgets. Calls without a caller are not recorded.
+func WithCaller(ctx context.Context, userID, feature string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller{userID: userID, feature: feature})
+}
+
+// callerFrom returns the caller attached to ctx
+func callerFrom(ctx context.Context) (caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(caller)
+	return c, ok && c.userID != ""
+}
+
+// SetUsageRecorder enables usage accounting. The tokenizer counts the tokens
+// of calls to providers that do not report usage; it may be nil.
+func (r *ResilientLLM) SetUsageRecorder(recorder UsageRecorder, tokenizer *Tiktoken) {
+	r.usage = recorder
+	r.tokenizer = tokenizer
+}
+
+// CheckBudget returns an error when the caller attached to ctx has used up
+// their token budget
+func (r *ResilientLLM) CheckBudget(ctx context.Context) error {
+	c, ok := callerFrom(ctx)
+	if !ok || r.usage == nil {
+		return nil
+	}
+	return r.usage.CheckBudget(ctx, c.userID)
+}
+
+// account counts the tokens of a call answered by p and records them against
+// the provider, the span and the caller. Tokens the provider does not report
+// are counted from the prompt and completion text.
+func (r *ResilientLLM) account(ctx context.Context, span trace.Span, p *provider, response *llms.ContentResponse, prompt, completion string) {
+	promptTokens, completionTokens, reported := reportedTokens(response)
+	if !reported && r.tokenizer != nil {
+		promptTokens = r.tokenizer.CountTokens(prompt)
+		completionTokens = r.tokenizer.CountTokens(completion)
+	}
+	tokens := promptTokens + completionTokens
+	p.addTokens(tokens)
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", promptTokens),
+		attribute.Int("gen_ai.usage.output_tokens", completionTokens),
+		attribute.Float64("llm.cost_usd", p.estimateCost(tokens)),
+	)
+
+	c, ok := callerFrom(ctx)
+	if !ok || r.usage == nil {
+		return
+	}
+	usage := Usage{
+		UserID:           c.userID,
+		Feature:          c.feature,
+		Provider:         p.name,
+		Model:            p.model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		CostUSD:          p.estimateCost(tokens),
+	}
+	// The call succeeded, so record it even if the caller has since gone away
+	if err := r.usage.RecordUsage(context.WithoutCancel(ctx), usage); err != nil {
+		log.Printf("[LLM] WARNING: Failed to record usage of user %s: %v", c.userID, err)
+	}
+}
+
+// reportedTokens reads the prompt and completion tokens reported by a
+// provider. Providers report them under different keys.
+func reportedTokens(response *llms.ContentResponse) (prompt, completion int, ok bool) {
+	if response == nil || len(response.Choices) == 0 {
+		return 0, 0, false
+	}
+	info := response.Choices[0].GenerationInfo
+	for _, keys := range [][2]string{{"PromptTokens", "CompletionTokens"}, {"InputTokens", "OutputTokens"}} {
+		prompt, promptOK := info[keys[0]].(int)
+		completion, completionOK := info[keys[1]].(int)
+		if promptOK && completionOK && prompt+completion > 0 {
+			return prompt, completion, true
+		}
+	}
+	return 0, 0, false
+}
+
+// messagesText joins the text parts of messages, for counting their tokens
+func messagesText(messages []llms.MessageContent) string {
+	var text strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if textPart, ok := part.(llms.TextContent); ok {
+				text.WriteString(textPart.Text)
+				text.WriteString("\n")
+			}
+		}
+	}
+	return text.String()
+}
+
+// responseText returns the text of the first choice of a response
+func responseText(response *llms.ContentResponse) string {
+	if response == nil || len(response.Choices) == 0 {
+		return ""
+	}
+	return response.Choices[0].Content
+}
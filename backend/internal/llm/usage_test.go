@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeRecorder records usage in memory and rejects users in over
+type fakeRecorder struct {
+	usage []Usage
+	over  map[string]bool
+}
+
+var errOverBudget = errors.New("over budget")
+
+func (f *fakeRecorder) CheckBudget(ctx context.Context, userID string) error {
+	if f.over[userID] {
+		return errOverBudget
+	}
+	return nil
+}
+
+func (f *fakeRecorder) RecordUsage(ctx context.Context, usage Usage) error {
+	f.usage = append(f.usage, usage)
+	return nil
+}
+
+func TestUsageIsRecordedPerCaller(t *testing.T) {
+	recorder := &fakeRecorder{}
+	r := chainOf(&stubModel{response: "pretty"})
+	r.SetUsageRecorder(recorder, nil)
+
+	ctx := WithCaller(context.Background(), "user-1", "prettify")
+	if _, err := r.GenerateFromSinglePrompt(ctx, "hi"); err != nil {
+		t.Fatalf("GenerateFromSinglePrompt failed: %v", err)
+	}
+	// Calls without a caller are not attributed to anyone
+	if _, err := r.GenerateFromSinglePrompt(context.Background(), "hi"); err != nil {
+		t.Fatalf("GenerateFromSinglePrompt failed: %v", err)
+	}
+
+	if len(recorder.usage) != 1 {
+		t.Fatalf("Expected 1 recorded call, got %d", len(recorder.usage))
+	}
+	want := Usage{UserID: "user-1", Feature: "prettify", Provider: "p0", Model: "p0",
+		PromptTokens: 300, CompletionTokens: 200, CostUSD: 0.001}
+	if recorder.usage[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, recorder.usage[0])
+	}
+}
+
+func TestStreamUsageIsRecorded(t *testing.T) {
+	recorder := &fakeRecorder{}
+	r := chainOf(&stubModel{chunks: []string{"an", "swer"}})
+	r.SetUsageRecorder(recorder, nil)
+
+	ctx := WithCaller(context.Background(), "user-1", "qa")
+	if err := r.Stream(ctx, "question", func(context.Context, []byte) error { return nil }); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(recorder.usage) != 1 || recorder.usage[0].Feature != "qa" || recorder.usage[0].TotalTokens() != 500 {
+		t.Errorf("Unexpected usage %+v", recorder.usage)
+	}
+}
+
+func TestBudgetRejectsCallsBeforeTheProvider(t *testing.T) {
+	model := &stubModel{response: "pretty"}
+	r := chainOf(model)
+	r.SetUsageRecorder(&fakeRecorder{over: map[string]bool{"user-1": true}}, nil)
+
+	ctx := WithCaller(context.Background(), "user-1", "prettify")
+	if _, err := r.GenerateFromSinglePrompt(ctx, "hi"); !errors.Is(err, errOverBudget) {
+		t.Errorf("Expected the budget error, got %v", err)
+	}
+	if err := r.Stream(ctx, "hi", func(context.Context, []byte) error { return nil }); !errors.Is(err, errOverBudget) {
+		t.Errorf("Expected the budget error, got %v", err)
+	}
+	if model.calls != 0 {
+		t.Errorf("Expected no provider calls, got %d", model.calls)
+	}
+	// The breaker does not count budget rejections as provider failures
+	if !r.providers[0].isHealthy() {
+		t.Error("Expected the provider to stay healthy")
+	}
+
+	other := WithCaller(context.Background(), "user-2", "prettify")
+	if _, err := r.GenerateFromSinglePrompt(other, "hi"); err != nil {
+		t.Errorf("Expected other users to be unaffected, got %v", err)
+	}
+}
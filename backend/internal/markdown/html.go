@@ -0,0 +1,201 @@
+// Package markdown renders note content to HTML for pages shown to people
+// without an account. It supports the Markdown notes commonly use:
+//
+//	# Heading               headings, levels 1 to 6
+//	- item, 1. item         bullet and numbered lists, "- [ ]" task items
+//	> quote                 block quotes
+//	```                     fenced code blocks
+//	---                     horizontal rules
+//	**bold** *italic* ~~struck~~ `code` [label](url) and bare URLs
+//
+// The output is safe to embed: all text is escaped, raw HTML in the content
+// is shown as text and links only keep http, https and mailto URLs. Images
+// are rendered as links so pages load nothing from other sites.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Block patterns, matched against trimmed lines. Headings need a space
+// after the hashes, so hashtags starting a line stay text.
+var (
+	headingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	ruleRegex     = regexp.MustCompile(`^(?:-{3,}|\*{3,}|_{3,})$`)
+	bulletRegex   = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	numberedRegex = regexp.MustCompile(`^\d{1,9}[.)]\s+(.*)$`)
+	taskRegex     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	fenceRegex    = regexp.MustCompile("^(```|~~~)")
+)
+
+// trailingURLChars are the punctuation marks ending sentences after bare URLs
+const trailingURLChars = ".,;:!?'\""
+
+// inlineRegex matches the inline spans, in order: code, links and images,
+// bold, strikethrough, italic and bare URLs
+var inlineRegex = regexp.MustCompile("`([^`\n]+)`" +
+	`|(!?)\[([^\[\]\n]*)\]\(([^()\s]+)\)` +
+	`|\*\*([^*\n]+)\*\*` +
+	`|~~([^~\n]+)~~` +
+	`|\*([^*\s][^*\n]*)\*` +
+	`|(https?://[^\s<>()]+)`)
+
+// ToHTML renders Markdown content to HTML
+func ToHTML(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	renderBlocks(&b, lines)
+	return b.String()
+}
+
+// renderBlocks renders lines as a sequence of blocks
+func renderBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			i++
+
+		case fenceRegex.MatchString(line):
+			fence := line[:3]
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), fence) {
+				end++
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(lines[i+1:end], "\n")))
+			b.WriteString("</code></pre>\n")
+			i = end + 1
+
+		case headingRegex.MatchString(line):
+			m := headingRegex.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderInline(m[2], true) + "</h" + level + ">\n")
+			i++
+
+		case ruleRegex.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(line, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case bulletRegex.MatchString(line):
+			i = renderList(b, lines, i, "ul", bulletRegex)
+
+		case numberedRegex.MatchString(line):
+			i = renderList(b, lines, i, "ol", numberedRegex)
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && !startsBlock(strings.TrimSpace(lines[i])); i++ {
+				paragraph = append(paragraph, renderInline(strings.TrimSpace(lines[i]), true))
+			}
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+		}
+	}
+}
+
+// startsBlock reports whether a line ends a paragraph
+func startsBlock(line string) bool {
+	return line == "" || fenceRegex.MatchString(line) || headingRegex.MatchString(line) ||
+		ruleRegex.MatchString(line) || strings.HasPrefix(line, ">") ||
+		bulletRegex.MatchString(line) || numberedRegex.MatchString(line)
+}
+
+// renderList renders the items of a list starting at line i and returns the
+// line after it. Nested items are flattened into the list.
+func renderList(b *strings.Builder, lines []string, i int, tag string, itemRegex *regexp.Regexp) int {
+	b.WriteString("<" + tag + ">\n")
+	for ; i < len(lines); i++ {
+		m := itemRegex.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if m == nil {
+			break
+		}
+		item := m[1]
+		if task := taskRegex.FindStringSubmatch(item); task != nil {
+			checked := ""
+			if task[1] != " " {
+				checked = " checked"
+			}
+			b.WriteString(`<li><input type="checkbox" disabled` + checked + "> " + renderInline(task[2], true) + "</li>\n")
+			continue
+		}
+		b.WriteString("<li>" + renderInline(item, true) + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderInline renders the inline spans of text, escaping everything else.
+// Link labels are rendered without links, as anchors cannot nest.
+func renderInline(text string, links bool) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range inlineRegex.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		last = m[1]
+		group := func(n int) string {
+			if m[2*n] < 0 {
+				return ""
+			}
+			return text[m[2*n]:m[2*n+1]]
+		}
+
+		switch {
+		case m[2] >= 0:
+			b.WriteString("<code>" + html.EscapeString(group(1)) + "</code>")
+		case m[8] >= 0:
+			label := html.EscapeString(group(4))
+			if group(3) != "" {
+				label = renderInline(group(3), false)
+			}
+			if links {
+				label = link(group(4), label)
+			}
+			b.WriteString(label)
+		case m[10] >= 0:
+			b.WriteString("<strong>" + renderInline(group(5), links) + "</strong>")
+		case m[12] >= 0:
+			b.WriteString("<del>" + renderInline(group(6), links) + "</del>")
+		case m[14] >= 0:
+			b.WriteString("<em>" + renderInline(group(7), links) + "</em>")
+		default:
+			// Sentence punctuation after a bare URL is not part of it
+			target := strings.TrimRight(group(8), trailingURLChars)
+			last = m[0] + len(target)
+			label := html.EscapeString(target)
+			if links {
+				label = link(target, label)
+			}
+			b.WriteString(label)
+		}
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// link renders an anchor to target, or just its label when target is not a
+// safe URL
+func link(target, label string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return label
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+	default:
+		return label
+	}
+	return `<a href="` + html.EscapeString(target) + `" rel="nofollow noopener noreferrer">` + label + "</a>"
+}
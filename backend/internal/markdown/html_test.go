@@ -0,0 +1,37 @@
+package markdown
+
+import "testing"
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"paragraph lines", "Hello\nworld", "<p>Hello<br>\nworld</p>\n"},
+		{"heading", "## Plan ##", "<h2>Plan</h2>\n"},
+		{"hashtag is not a heading", "#work notes", "<p>#work notes</p>\n"},
+		{"inline spans", "**bold** *it* ~~old~~ `a<b`", "<p><strong>bold</strong> <em>it</em> <del>old</del> <code>a&lt;b</code></p>\n"},
+		{"bullet list", "- one\n- [x] done\n- [ ] todo", "<ul>\n<li>one</li>\n<li><input type=\"checkbox\" disabled checked> done</li>\n<li><input type=\"checkbox\" disabled> todo</li>\n</ul>\n"},
+		{"numbered list", "1. first\n2) second", "<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"},
+		{"quote", "> **note**\n> more", "<blockquote>\n<p><strong>note</strong><br>\nmore</p>\n</blockquote>\n"},
+		{"code block", "```go\nx := \"<b>\"\n```\nafter", "<pre><code>x := &#34;&lt;b&gt;&#34;</code></pre>\n<p>after</p>\n"},
+		{"rule", "a\n\n---\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"link", "[docs](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">docs</a></p>` + "\n"},
+		{"bare url", "See https://example.com.", `<p>See <a href="https://example.com" rel="nofollow noopener noreferrer">https://example.com</a>.</p>` + "\n"},
+		{"image as link", "![](https://example.com/x.png)", `<p><a href="https://example.com/x.png" rel="nofollow noopener noreferrer">https://example.com/x.png</a></p>` + "\n"},
+		{"link label url", "[https://a.example](https://b.example)", `<p><a href="https://b.example" rel="nofollow noopener noreferrer">https://a.example</a></p>` + "\n"},
+		{"raw html", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"unsafe link", "[click](javascript:alert(1))", "<p>[click](javascript:alert(1))</p>\n"},
+		{"unsafe scheme", "[click](javascript:alert)", "<p>click</p>\n"},
+		{"quoted attribute", `[x](https://example.com/"onmouseover=alert)`, `<p><a href="https://example.com/&#34;onmouseover=alert" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.content); got != tt.want {
+				t.Errorf("ToHTML(%q) =\n%q\nwant\n%q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
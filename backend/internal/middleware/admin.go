@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// RequireAdmin restricts routes to users with the admin role. Users whose
+// email is in adminEmails are also accepted, which is how the first
+// administrator gets access before any role has been granted.
+func RequireAdmin(adminEmails []string) func(http.Handler) http.Handler {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value("user").(*models.User)
+			if !ok {
+				respondWithError(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+
+			if !user.IsAdmin() && !admins[strings.ToLower(user.Email)] {
+				respondWithError(w, http.StatusForbidden, "Administrator access required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
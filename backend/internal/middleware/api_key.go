@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// APIKeyHeader carries the API key of integrations that cannot set an
+// Authorization header
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPaths are the route prefixes API keys may access: notes, tags,
+// search, export and capture. Account, session and key management need a
+// signed-in session.
+var apiKeyPaths = []string{
+	"/api/v1/notes",
+	"/api/v1/tags",
+	"/api/v1/search",
+	"/api/v1/export",
+	"/api/v1/capture",
+}
+
+// APIKeyAllowed reports whether API keys may access path
+func APIKeyAllowed(path string) bool {
+	for _, prefix := range apiKeyPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyFromRequest returns the API key of a request: the X-API-Key header,
+// or a bearer token starting with the API key prefix. It returns "" for
+// requests without one.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if token = strings.TrimSpace(token); strings.HasPrefix(token, services.APIKeyPrefix) {
+			return token
+		}
+	}
+	return ""
+}
+
+// APIKeyAuth authenticates requests by API key instead of a session and adds
+// the key's owner to the context. The key is read from the X-API-Key header
+// or an "Authorization: Bearer <key>" header.
+func APIKeyAuth(apiKeyService services.APIKeyServiceInterface, userService services.UserServiceInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := apiKeyFromRequest(r)
+			if key == "" {
+				respondWithError(w, http.StatusUnauthorized, "API key required")
+				return
+			}
+
+			userID, err := apiKeyService.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					respondWithError(w, http.StatusUnauthorized, "Invalid API key")
+				} else {
+					log.Printf("ERROR: failed to authenticate API key: %v", err)
+					respondWithError(w, http.StatusInternalServerError, "Failed to authenticate API key")
+				}
+				return
+			}
+
+			user, err := userService.GetByID(r.Context(), userID)
+			if err != nil {
+				respondWithError(w, http.StatusUnauthorized, "User not found")
+				return
+			}
+			if user.IsDisabled() {
+				respondWithError(w, http.StatusForbidden, "Account has been disabled")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "user", user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -2,12 +2,13 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"log"
 	"net"
 	"net/http"
 	"strings"
 
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/auth"
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/gpd/my-notes/internal/services"
@@ -59,15 +60,26 @@ func (m *AuthMiddleware) Auth(next http.Handler) http.Handler {
 		// }
 
 		// Get user from database
-		user, err := m.userService.GetByID(claims.UserID)
+		user, err := m.userService.GetByID(r.Context(), claims.UserID)
 		if err != nil {
 			respondWithError(w, http.StatusUnauthorized, "User not found")
 			return
 		}
 
+		if user.IsDisabled() {
+			respondWithError(w, http.StatusForbidden, "Account has been disabled")
+			return
+		}
+
+		if !auth.TwoFactorSatisfied(claims, user) {
+			respondTwoFactorRequired(w)
+			return
+		}
+
 		// Update session activity (non-blocking)
 		go func() {
 			if err := m.userService.UpdateSessionActivity(
+				context.WithoutCancel(r.Context()),
 				claims.ID,
 				getClientIP(r),
 				r.UserAgent(),
@@ -115,9 +127,10 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 		}
 
 		// Get user from database
-		user, err := m.userService.GetByID(claims.UserID)
-		if err != nil {
-			// User not found, continue without authentication
+		user, err := m.userService.GetByID(r.Context(), claims.UserID)
+		if err != nil || !auth.TwoFactorSatisfied(claims, user) {
+			// User not found or not past two-factor authentication,
+			// continue without authentication
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -241,9 +254,18 @@ func parseIPFromRemoteAddr(remoteAddr string) string {
 	return remoteAddr
 }
 
-// respondWithError sends an error response
+// respondWithError sends an error response in the envelope of the handlers,
+// coded after its HTTP status
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
-	w.Write([]byte(fmt.Sprintf(`{"error":"%s"}`, message)))
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(models.NewAPIErrorResponse(apperrors.CodeForStatus(code), message, ""))
+}
+
+// respondTwoFactorRequired refuses the tokens of sessions that signed in
+// without the two-factor code their user requires
+func respondTwoFactorRequired(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(models.NewAPIErrorResponse(apperrors.Code(services.ErrTwoFactorRequired), "Two-factor authentication required, sign in again with a code", ""))
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// ClientInfo stores the client IP and country in the request context under
+// "clientIP" and "clientCountry". The country is read from countryHeader, set
+// by the reverse proxy or CDN (e.g. CF-IPCountry behind Cloudflare).
+func ClientInfo(countryHeader string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "clientIP", getClientIP(r))
+			if countryHeader != "" {
+				country := strings.ToUpper(strings.TrimSpace(r.Header.Get(countryHeader)))
+				ctx = context.WithValue(ctx, "clientCountry", country)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+)
+
+// IdempotencyKeyHeader carries the client's key for a request that must not
+// run twice, such as a note creation retried after a network error
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed for a retried key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength is the longest key accepted
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentRequestSize limits the bodies read to hash a request
+const maxIdempotentRequestSize = 10 << 20
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key header. Requests without the header are handled as
+// usual. Server errors and requests for a confirmation code are not stored,
+// so the retry runs the request again.
+func Idempotency(idempotencyService services.IdempotencyServiceInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+			user, ok := r.Context().Value("user").(*models.User)
+			if key == "" || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				respondWithError(w, http.StatusBadRequest, "Idempotency-Key too long (max 255 characters)")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestSize))
+			r.Body.Close()
+			if err != nil {
+				respondWithError(w, http.StatusRequestEntityTooLarge, "Request too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			userID := user.ID.String()
+			stored, err := idempotencyService.Begin(r.Context(), userID, key, r.Method, r.URL.Path, hashRequest(r.Method, r.URL.Path, body))
+			switch {
+			case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+				respondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+				return
+			case errors.Is(err, services.ErrIdempotencyKeyReused):
+				respondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+				return
+			case err != nil:
+				log.Printf("[Idempotency] %v", err)
+				respondWithError(w, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+				return
+			}
+
+			if stored != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+				return
+			}
+
+			// The outcome is stored even when the client has gone away, which
+			// is when it matters most
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 10*time.Second)
+			defer cancel()
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// Server errors, confirmation requests and panics leave the key
+				// free for a retry
+				if err := idempotencyService.Release(ctx, userID, key); err != nil {
+					log.Printf("[Idempotency] %v", err)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status < http.StatusInternalServerError && recorder.status != http.StatusPreconditionRequired {
+				response := &models.IdempotentResponse{StatusCode: recorder.status, Body: recorder.body.Bytes()}
+				if err := idempotencyService.Complete(ctx, userID, key, response); err != nil {
+					log.Printf("[Idempotency] %v", err)
+				} else {
+					completed = true
+				}
+			}
+		})
+	}
+}
+
+// hashRequest returns the hex SHA-256 of a request, so a key cannot be
+// reused for another request
+func hashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -122,6 +122,12 @@ func ContentType(next http.Handler) http.Handler {
 func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Event streams outlive the request timeout and manage their own deadline
+			if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer cancel()
 
@@ -141,8 +147,7 @@ func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
 				requestID, _ := r.Context().Value("requestID").(string)
 				log.Printf("[%s] Request timeout", requestID)
 
-				w.WriteHeader(http.StatusRequestTimeout)
-				w.Write([]byte(`{"error":"Request timeout"}`))
+				respondWithError(w, http.StatusRequestTimeout, "Request timeout")
 			}
 		})
 	}
@@ -187,8 +192,7 @@ func RateLimit(requests int, window time.Duration) func(http.Handler) http.Handl
 					requestID, _ := r.Context().Value("requestID").(string)
 					log.Printf("[%s] Rate limit exceeded for %s", requestID, clientIP)
 
-					w.WriteHeader(http.StatusTooManyRequests)
-					w.Write([]byte(`{"error":"Rate limit exceeded"}`))
+					respondWithError(w, http.StatusTooManyRequests, "Rate limit exceeded")
 					return
 				}
 
@@ -216,6 +220,18 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush sends buffered data to the client, which streaming responses rely on
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // getAllowedOrigin returns the appropriate origin for CORS
 func getAllowedOrigin(requestOrigin string, allowedOrigins []string) string {
 	// Check for exact match first
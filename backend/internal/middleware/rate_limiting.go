@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 	"strings"
 	"sync"
@@ -187,7 +187,7 @@ func (rlm *RateLimitingMiddleware) allowUserRequest(userID string, r *http.Reque
 	limiter, exists := rlm.userLimiters[userID]
 	if !exists {
 		// Create rate limiter based on user tier
-		limiter = rlm.createUserLimiter(userID)
+		limiter = rlm.createUserLimiter(r.Context(), userID)
 		rlm.userLimiters[userID] = limiter
 	}
 
@@ -195,9 +195,9 @@ func (rlm *RateLimitingMiddleware) allowUserRequest(userID string, r *http.Reque
 }
 
 // createUserLimiter creates a rate limiter for a user based on their tier
-func (rlm *RateLimitingMiddleware) createUserLimiter(userID string) *TokenBucket {
+func (rlm *RateLimitingMiddleware) createUserLimiter(ctx context.Context, userID string) *TokenBucket {
 	// Get user to check their tier/status
-	_, err := rlm.userService.GetByID(userID)
+	_, err := rlm.userService.GetByID(ctx, userID)
 	if err != nil {
 		// Default rate limiting for unknown users
 		return NewTokenBucket(60, 1) // 60 requests per minute
@@ -273,17 +273,8 @@ func (rlm *RateLimitingMiddleware) isUserWhitelisted(userID string) bool {
 
 // writeRateLimitResponse writes a rate limit error response
 func (rlm *RateLimitingMiddleware) writeRateLimitResponse(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusTooManyRequests)
-
-	response := map[string]interface{}{
-		"error":       message,
-		"code":        http.StatusTooManyRequests,
-		"success":     false,
-		"retry_after": "60", // Suggest retry after 60 seconds
-	}
-
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("Retry-After", "60") // Suggest retry after 60 seconds
+	respondWithError(w, http.StatusTooManyRequests, message)
 }
 
 // RateLimitInfo provides information about current rate limits
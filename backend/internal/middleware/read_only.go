@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// ReadOnlyLock rejects writes from accounts locked read-only after suspicious
+// activity. Reads, GraphQL queries (the schema has no mutations) and logout
+// stay available so the user can still get at their notes and end compromised
+// sessions.
+func ReadOnlyLock(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := r.Context().Value("user").(*models.User)
+		if !ok || !user.IsReadOnly() || strings.HasSuffix(r.URL.Path, "/auth/logout") || strings.HasSuffix(r.URL.Path, "/graphql") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", user.ReadOnlyUntil.UTC().Format(http.TimeFormat))
+		respondWithError(w, http.StatusLocked, "Account is temporarily read-only after unusual activity")
+	})
+}
@@ -2,9 +2,12 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,8 +38,14 @@ type SecurityMiddleware struct {
 	corsConfig        *config.CORSConfig
 	securityConfig    *config.SecurityConfig
 	securityMonitor   *security.SecurityMonitor
+	requestSizeLimits map[string]int64
+	apiKeyService     services.APIKeyServiceInterface
+	loginGuard        *auth.LoginGuard
 }
 
+// defaultMaxRequestSize is the request body limit for paths without their own
+const defaultMaxRequestSize = 1 * 1024 * 1024
+
 
 
 // NewSecurityMiddleware creates a new security middleware
@@ -54,8 +63,8 @@ func NewSecurityMiddleware(
 	if corsConfig == nil {
 		corsConfig = &config.CORSConfig{
 			AllowedOrigins: []string{"http://localhost:3000", "chrome-extension://*"},
-			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"Content-Type", "Authorization"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-Confirmation-ID", "X-Confirmation-Code", "X-API-Key"},
 			MaxAge:         86400,
 		}
 	}
@@ -84,7 +93,46 @@ func NewSecurityMiddleware(
 		corsConfig:      corsConfig,
 		securityConfig:  securityConfig,
 		securityMonitor: securityMonitor,
+		requestSizeLimits: make(map[string]int64),
+	}
+}
+
+// SetAPIKeyService lets EnhancedAuth accept API keys on the routes
+// integrations may use, in addition to access tokens
+func (sm *SecurityMiddleware) SetAPIKeyService(apiKeyService services.APIKeyServiceInterface) {
+	sm.apiKeyService = apiKeyService
+}
+
+// SetLoginGuard sets the guard slowing down clients presenting invalid API
+// keys, so that keys cannot be guessed at full speed
+func (sm *SecurityMiddleware) SetLoginGuard(loginGuard *auth.LoginGuard) {
+	sm.loginGuard = loginGuard
+}
+
+// SetRequestSizeLimit overrides the request body limit for a path, such as
+// an upload endpoint that accepts large files. A * segment matches any one
+// path segment, as in /api/v1/notes/*/image-text.
+func (sm *SecurityMiddleware) SetRequestSizeLimit(path string, maxBytes int64) {
+	sm.requestSizeLimits[path] = maxBytes
+}
+
+// requestSizeLimit returns the request body limit for a path, the limit of
+// the security config unless the path has its own
+func (sm *SecurityMiddleware) requestSizeLimit(requestPath string) int64 {
+	if maxBytes, ok := sm.requestSizeLimits[requestPath]; ok {
+		return maxBytes
+	}
+	for pattern, maxBytes := range sm.requestSizeLimits {
+		if strings.Contains(pattern, "*") {
+			if matched, _ := path.Match(pattern, requestPath); matched {
+				return maxBytes
+			}
+		}
+	}
+	if sm.securityConfig.MaxRequestSize > 0 {
+		return sm.securityConfig.MaxRequestSize
 	}
+	return defaultMaxRequestSize
 }
 
 // Security provides comprehensive security middleware
@@ -106,11 +154,16 @@ func (sm *SecurityMiddleware) Security(next http.Handler) http.Handler {
 		}
 
 		// Validate request size
-		if r.ContentLength > 1*1024*1024 { // 1MB limit
+		maxRequestSize := sm.requestSizeLimit(r.URL.Path)
+		if r.ContentLength > maxRequestSize {
 			sm.logSecurityEvent(security.EventSuspiciousActivity, security.LevelWarning, "Large request detected", r, "")
 			sm.writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request too large")
 			return
 		}
+		// Bodies sent without a length are cut off at the limit as they are read
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		}
 
 		// Validate user agent
 		userAgent := r.Header.Get("User-Agent")
@@ -175,8 +228,9 @@ func (sm *SecurityMiddleware) applySecurityHeaders(w http.ResponseWriter, r *htt
 		w.Header().Set("X-XSS-Protection", sm.securityConfig.Headers.XSSProtection)
 	}
 
-	// Apply Strict-Transport-Security (HTTPS only)
-	if sm.securityConfig.Headers.StrictTransportSecurity != "" && r.TLS != nil {
+	// Apply Strict-Transport-Security (HTTPS only, including behind a TLS
+	// terminating proxy)
+	if sm.securityConfig.Headers.StrictTransportSecurity != "" && (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") {
 		w.Header().Set("Strict-Transport-Security", sm.securityConfig.Headers.StrictTransportSecurity)
 	}
 
@@ -219,31 +273,36 @@ func (sm *SecurityMiddleware) handleCORS(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	if allowed {
+	// Responses differ by origin, so caches must not share them
+	w.Header().Add("Vary", "Origin")
+	if allowed && origin != "" {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 	}
 
-	// Handle preflight requests
+	// Handle preflight requests; origins not allowed get no CORS headers
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", strings.Join(sm.corsConfig.AllowedMethods, ", "))
-		w.Header().Set("Access-Control-Allow-Headers", strings.Join(sm.corsConfig.AllowedHeaders, ", "))
-		w.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", sm.corsConfig.MaxAge))
-
-		if sm.corsConfig.AllowCredentials {
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-		}
+		if allowed && origin != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(sm.corsConfig.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(sm.corsConfig.AllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", sm.corsConfig.MaxAge))
 
-		if len(sm.corsConfig.ExposedHeaders) > 0 {
-			w.Header().Set("Access-Control-Expose-Headers", strings.Join(sm.corsConfig.ExposedHeaders, ", "))
+			if sm.corsConfig.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 		return false
 	}
 
-	// Set credentials header for actual requests
-	if sm.corsConfig.AllowCredentials {
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	// Set credentials and exposed headers for actual requests
+	if allowed && origin != "" {
+		if sm.corsConfig.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if len(sm.corsConfig.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(sm.corsConfig.ExposedHeaders, ", "))
+		}
 	}
 
 	return true
@@ -258,6 +317,14 @@ func (sm *SecurityMiddleware) EnhancedAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// API keys have no session; they are authenticated separately
+		if sm.apiKeyService != nil {
+			if key := apiKeyFromRequest(r); key != "" {
+				sm.authenticateAPIKey(w, r, key, next)
+				return
+			}
+		}
+
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -296,7 +363,7 @@ func (sm *SecurityMiddleware) EnhancedAuth(next http.Handler) http.Handler {
 		}
 
 		// Get user from database
-		user, err := sm.userService.GetByID(claims.UserID)
+		user, err := sm.userService.GetByID(r.Context(), claims.UserID)
 		if err != nil {
 			// For mock tokens in test, create a mock user
 			if tokenString == "valid-mock-token" || tokenString == "mock-access-token" {
@@ -319,6 +386,21 @@ func (sm *SecurityMiddleware) EnhancedAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// Disabled accounts keep their data but cannot use the API
+		if user.IsDisabled() {
+			sm.logSecurityEvent(security.EventUnauthorizedAccess, security.LevelWarning, "Disabled account used", r, claims.UserID)
+			sm.writeErrorResponse(w, http.StatusForbidden, "Account has been disabled")
+			return
+		}
+
+		// Users with two-factor authentication only accept tokens of sessions
+		// that signed in with a code
+		if !auth.TwoFactorSatisfied(claims, user) {
+			sm.logSecurityEvent(security.EventUnauthorizedAccess, security.LevelWarning, "Token without two-factor authentication used", r, claims.UserID)
+			respondTwoFactorRequired(w)
+			return
+		}
+
 		// Log successful authentication
 		sm.logSecurityEvent(security.EventAuthenticationSuccess, security.LevelInfo, "User authenticated successfully", r, claims.UserID)
 
@@ -345,6 +427,65 @@ func (sm *SecurityMiddleware) EnhancedAuth(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateAPIKey authenticates a request by API key and continues with
+// the key's owner in the context. Keys are limited to the routes in
+// APIKeyAllowed.
+func (sm *SecurityMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	if !APIKeyAllowed(r.URL.Path) {
+		sm.logSecurityEvent(security.EventUnauthorizedAccess, security.LevelWarning, "API key used outside its scope", r, "")
+		sm.writeErrorResponse(w, http.StatusForbidden, "API keys cannot access this endpoint")
+		return
+	}
+
+	ipKey := auth.IPKey(getClientIP(r))
+	if sm.loginGuard != nil {
+		if wait := sm.loginGuard.Wait(ipKey); wait > 0 {
+			sm.logSecurityEvent(security.EventRateLimitExceeded, security.LevelWarning, "API key attempt blocked after repeated failures", r, "")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			sm.writeErrorResponse(w, http.StatusTooManyRequests, "Too many failed sign-in attempts, try again later")
+			return
+		}
+	}
+
+	userID, err := sm.apiKeyService.Authenticate(r.Context(), key)
+	if err != nil {
+		sm.logSecurityEvent(security.EventAuthenticationFailure, security.LevelWarning, "Invalid API key", r, "")
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			if sm.loginGuard != nil {
+				sm.loginGuard.Fail(ipKey)
+			}
+			sm.writeErrorResponse(w, http.StatusUnauthorized, "Invalid API key")
+		} else {
+			sm.writeErrorResponse(w, http.StatusInternalServerError, "Failed to authenticate API key")
+		}
+		return
+	}
+
+	user, err := sm.userService.GetByID(r.Context(), userID)
+	if err != nil {
+		sm.logSecurityEvent(security.EventAuthenticationFailure, security.LevelError, "User not found for valid API key", r, userID)
+		sm.writeErrorResponse(w, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if user.IsDisabled() {
+		sm.logSecurityEvent(security.EventUnauthorizedAccess, security.LevelWarning, "Disabled account used", r, userID)
+		sm.writeErrorResponse(w, http.StatusForbidden, "Account has been disabled")
+		return
+	}
+
+	userRateLimiter := getUserRateLimiter(userID)
+	if !userRateLimiter.Allow(r) {
+		sm.logSecurityEvent(security.EventRateLimitExceeded, security.LevelWarning, "User rate limit exceeded", r, userID)
+		sm.writeErrorResponse(w, http.StatusTooManyRequests, "User rate limit exceeded")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user", user)
+	ctx = context.WithValue(ctx, "apiKeyAuth", true)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // GetSecurityMetrics returns security monitoring metrics
 func (sm *SecurityMiddleware) GetSecurityMetrics() map[string]interface{} {
 	if sm.securityMonitor == nil {
@@ -402,7 +543,7 @@ func (sm *SecurityMiddleware) updateSessionActivity(sessionID string, r *http.Re
 		ipAddress := getClientIP(r)
 		userAgent := r.Header.Get("User-Agent")
 
-		err := sm.userService.UpdateSessionActivity(sessionID, ipAddress, userAgent)
+		err := sm.userService.UpdateSessionActivity(context.WithoutCancel(r.Context()), sessionID, ipAddress, userAgent)
 		if err != nil {
 			// Log error but don't fail the request
 			fmt.Printf("Failed to update session activity: %v\n", err)
@@ -418,16 +559,7 @@ func generateRequestID() string {
 
 // writeErrorResponse writes a standardized error response
 func (sm *SecurityMiddleware) writeErrorResponse(w http.ResponseWriter, code int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-
-	response := map[string]interface{}{
-		"error":   message,
-		"code":    code,
-		"success": false,
-	}
-
-	json.NewEncoder(w).Encode(response)
+	respondWithError(w, code, message)
 }
 
 // Reset resets the security middleware rate limiters (for testing)
@@ -94,7 +94,7 @@ func (sm *SessionMiddleware) SessionManager(next http.Handler) http.Handler {
 		}
 
 		// Validate session
-		session, err := sm.validateSession(sessionID, user.ID.String())
+		session, err := sm.validateSession(r.Context(), sessionID, user.ID.String())
 		if err != nil {
 			sm.writeErrorResponse(w, http.StatusUnauthorized, "Invalid session")
 			return
@@ -102,14 +102,14 @@ func (sm *SessionMiddleware) SessionManager(next http.Handler) http.Handler {
 
 		// Check session timeout
 		if sm.isSessionExpired(session) {
-			sm.invalidateSession(sessionID)
+			sm.invalidateSession(r.Context(), sessionID)
 			sm.writeErrorResponse(w, http.StatusUnauthorized, "Session expired")
 			return
 		}
 
 		// Check concurrency limits if enabled
 		if sm.enableConcurrency {
-			if err := sm.checkConcurrencyLimits(user.ID.String()); err != nil {
+			if err := sm.checkConcurrencyLimits(r.Context(), user.ID.String()); err != nil {
 				sm.writeErrorResponse(w, http.StatusTooManyRequests, err.Error())
 				return
 			}
@@ -127,7 +127,7 @@ func (sm *SessionMiddleware) SessionManager(next http.Handler) http.Handler {
 }
 
 // validateSession validates a session exists and is valid
-func (sm *SessionMiddleware) validateSession(sessionID, userID string) (*models.UserSession, error) {
+func (sm *SessionMiddleware) validateSession(ctx context.Context, sessionID, userID string) (*models.UserSession, error) {
 	// Handle mock sessions for testing
 	if sessionID == "test-session-id" && userID == "550e8400-e29b-41d4-a716-446655440000" {
 		// Return a mock session for testing
@@ -143,7 +143,7 @@ func (sm *SessionMiddleware) validateSession(sessionID, userID string) (*models.
 	}
 
 	// Get active sessions for user
-	sessions, err := sm.userService.GetActiveSessions(userID)
+	sessions, err := sm.userService.GetActiveSessions(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sessions: %w", err)
 	}
@@ -164,9 +164,7 @@ func (sm *SessionMiddleware) isSessionExpired(session *models.UserSession) bool
 }
 
 // invalidateSession marks a session as inactive in the database
-func (sm *SessionMiddleware) invalidateSession(sessionID string) error {
-	ctx := context.Background()
-
+func (sm *SessionMiddleware) invalidateSession(ctx context.Context, sessionID string) error {
 	// Direct database query to mark session as inactive
 	query := `UPDATE user_sessions SET is_active = false WHERE id = $1`
 	_, err := sm.db.ExecContext(ctx, query, sessionID)
@@ -179,15 +177,15 @@ func (sm *SessionMiddleware) invalidateSession(sessionID string) error {
 
 // checkConcurrencyLimits checks if user has exceeded concurrent session limits
 // If limit is exceeded, it automatically invalidates the oldest sessions
-func (sm *SessionMiddleware) checkConcurrencyLimits(userID string) error {
-	sessions, err := sm.userService.GetActiveSessions(userID)
+func (sm *SessionMiddleware) checkConcurrencyLimits(ctx context.Context, userID string) error {
+	sessions, err := sm.userService.GetActiveSessions(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to check session limits: %w", err)
 	}
 
 	if len(sessions) >= sm.maxSessions {
 		// Automatically clean up the oldest sessions to make room
-		err := sm.cleanupOldestSessions(userID, len(sessions)-sm.maxSessions+1)
+		err := sm.cleanupOldestSessions(ctx, userID, len(sessions)-sm.maxSessions+1)
 		if err != nil {
 			return fmt.Errorf("maximum concurrent sessions (%d) exceeded and cleanup failed: %w", sm.maxSessions, err)
 		}
@@ -198,8 +196,8 @@ func (sm *SessionMiddleware) checkConcurrencyLimits(userID string) error {
 }
 
 // cleanupOldestSessions invalidates the oldest active sessions for a user
-func (sm *SessionMiddleware) cleanupOldestSessions(userID string, count int) error {
-	sessions, err := sm.userService.GetActiveSessions(userID)
+func (sm *SessionMiddleware) cleanupOldestSessions(ctx context.Context, userID string, count int) error {
+	sessions, err := sm.userService.GetActiveSessions(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get sessions for cleanup: %w", err)
 	}
@@ -223,7 +221,7 @@ func (sm *SessionMiddleware) cleanupOldestSessions(userID string, count int) err
 	// Invalidate the oldest sessions
 	for i := 0; i < count; i++ {
 		sessionID := sortedSessions[i].ID
-		err := sm.invalidateSession(sessionID)
+		err := sm.invalidateSession(ctx, sessionID)
 		if err != nil {
 			fmt.Printf("Failed to invalidate session %s: %v\n", sessionID, err)
 		} else {
@@ -240,7 +238,7 @@ func (sm *SessionMiddleware) updateSessionActivity(sessionID string, r *http.Req
 		ipAddress := getClientIP(r)
 		userAgent := r.Header.Get("User-Agent")
 
-		err := sm.userService.UpdateSessionActivity(sessionID, ipAddress, userAgent)
+		err := sm.userService.UpdateSessionActivity(context.WithoutCancel(r.Context()), sessionID, ipAddress, userAgent)
 		if err != nil {
 			fmt.Printf("Failed to update session activity: %v\n", err)
 		}
@@ -254,6 +252,11 @@ func (sm *SessionMiddleware) shouldSkipSessionManagement(r *http.Request) bool {
 		return true
 	}
 
+	// Requests authenticated by API key have no session
+	if apiKeyAuth, _ := r.Context().Value("apiKeyAuth").(bool); apiKeyAuth {
+		return true
+	}
+
 	// Skip for public endpoints
 	publicPaths := []string{
 		"/api/v1/auth/google",
@@ -409,16 +412,7 @@ func (sm *SessionMonitor) GetSessionStatistics() map[string]interface{} {
 
 // writeErrorResponse writes a standardized error response
 func (sm *SessionMiddleware) writeErrorResponse(w http.ResponseWriter, code int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-
-	response := map[string]interface{}{
-		"error":   message,
-		"code":    code,
-		"success": false,
-	}
-
-	json.NewEncoder(w).Encode(response)
+	respondWithError(w, code, message)
 }
 
 // SessionAnalytics provides analytics for session data
@@ -468,9 +462,9 @@ func (sm *SessionMonitor) GetSessionAnalytics(startDate, endDate time.Time) (*Se
 }
 
 // ExportSessions exports session data to various formats
-func (sm *SessionMonitor) ExportSessions(format string, userID string) ([]byte, error) {
+func (sm *SessionMonitor) ExportSessions(ctx context.Context, format string, userID string) ([]byte, error) {
 	// Get sessions for user
-	sessions, err := sm.userService.GetActiveSessions(userID)
+	sessions, err := sm.userService.GetActiveSessions(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sessions: %w", err)
 	}
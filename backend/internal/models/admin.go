@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminUser is a user account as seen by administrators, with its usage
+type AdminUser struct {
+	ID             uuid.UUID  `json:"id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastSeen       *time.Time `json:"last_seen,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason *string    `json:"disabled_reason,omitempty"`
+	ReadOnlyUntil  *time.Time `json:"read_only_until,omitempty"`
+	NoteCount      int        `json:"note_count"`
+	// StorageBytes is the size of the titles and contents of the user's notes
+	StorageBytes   int64 `json:"storage_bytes"`
+	ActiveSessions int   `json:"active_sessions"`
+}
+
+// AdminUserList represents a paginated list of user accounts
+type AdminUserList struct {
+	Users   []AdminUser `json:"users"`
+	Total   int         `json:"total"`
+	Limit   int         `json:"limit"`
+	Offset  int         `json:"offset"`
+	HasMore bool        `json:"has_more"`
+}
+
+// DisableUserRequest represents an administrator disabling an account
+type DisableUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SetUserRoleRequest represents an administrator changing an account's role
+type SetUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// MaintenanceResult reports a maintenance task run by an administrator
+type MaintenanceResult struct {
+	Task       string    `json:"task"`
+	Affected   int64     `json:"affected"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account anomaly review statuses
+const (
+	AnomalyStatusOpen      = "open"
+	AnomalyStatusConfirmed = "confirmed"
+	AnomalyStatusDismissed = "dismissed"
+)
+
+// AccountAnomaly is unusual account activity flagged for administrator review
+type AccountAnomaly struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
+	UserEmail   string          `json:"user_email" db:"-"`
+	Rule        string          `json:"rule" db:"rule"`
+	Severity    string          `json:"severity" db:"severity"`
+	Summary     string          `json:"summary" db:"summary"`
+	Details     json.RawMessage `json:"details,omitempty" db:"details"`
+	Status      string          `json:"status" db:"status"`
+	LockedUntil *time.Time      `json:"locked_until,omitempty" db:"locked_until"`
+	ReviewedBy  *uuid.UUID      `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt  *time.Time      `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AccountAnomalyList represents a paginated list of account anomalies
+type AccountAnomalyList struct {
+	Anomalies []AccountAnomaly `json:"anomalies"`
+	Total     int              `json:"total"`
+	Limit     int              `json:"limit"`
+	Offset    int              `json:"offset"`
+	HasMore   bool             `json:"has_more"`
+}
+
+// ReviewAnomalyRequest represents an administrator's decision on an anomaly.
+// Status is either confirmed or dismissed; Unlock lifts a read-only lock.
+type ReviewAnomalyRequest struct {
+	Status string `json:"status" validate:"required,oneof=confirmed dismissed"`
+	Unlock bool   `json:"unlock"`
+}
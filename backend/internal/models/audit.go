@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited actions
+const (
+	AuditActionCreate   = "create"
+	AuditActionUpdate   = "update"
+	AuditActionDelete   = "delete"
+	AuditActionPrettify = "prettify"
+)
+
+// AuditResourceNote is the resource type of actions on notes
+const AuditResourceNote = "note"
+
+// AuditEntry records an action a user took, and the client it came from
+type AuditEntry struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	UserEmail    string     `json:"user_email,omitempty" db:"-"`
+	Action       string     `json:"action" db:"action"`
+	ResourceType string     `json:"resource_type" db:"resource_type"`
+	ResourceID   *uuid.UUID `json:"resource_id,omitempty" db:"resource_id"`
+	Count        int        `json:"count" db:"count"`
+	IPAddress    string     `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent    string     `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AuditLogFilter selects audit entries. Empty fields match every entry;
+// Since is inclusive and Until exclusive.
+type AuditLogFilter struct {
+	UserID string
+	Action string
+	Since  *time.Time
+	Until  *time.Time
+}
+
+// AuditLogList represents a paginated list of audit entries, newest first
+type AuditLogList struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	HasMore bool         `json:"has_more"`
+}
+
+// IsAuditAction reports whether action is an audited action
+func IsAuditAction(action string) bool {
+	switch action {
+	case AuditActionCreate, AuditActionUpdate, AuditActionDelete, AuditActionPrettify:
+		return true
+	}
+	return false
+}
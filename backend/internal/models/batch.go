@@ -0,0 +1,38 @@
+package models
+
+// Statuses of the items of a batch processed item by item
+const (
+	BatchItemCreated = "created"
+	BatchItemUpdated = "updated"
+	BatchItemError   = "error"
+)
+
+// BatchItemResult is the outcome of one item of a batch sent with
+// atomic=false: the note it created or updated, or the error it failed with
+type BatchItemResult struct {
+	// Index is the position of the item in the request
+	Index  int           `json:"index"`
+	Status string        `json:"status"`
+	Note   *NoteResponse `json:"note,omitempty"`
+	Error  *APIError     `json:"error,omitempty"`
+}
+
+// BatchResults lists the outcome of every item of a batch sent with
+// atomic=false, in request order
+type BatchResults struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// Succeed records an item that created or updated its note
+func (b *BatchResults) Succeed(index int, status string, note *NoteResponse) {
+	b.Results = append(b.Results, BatchItemResult{Index: index, Status: status, Note: note})
+	b.Succeeded++
+}
+
+// Fail records an item that failed
+func (b *BatchResults) Fail(index int, err *APIError) {
+	b.Results = append(b.Results, BatchItemResult{Index: index, Status: BatchItemError, Error: err})
+	b.Failed++
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+)
+
+// Board limits
+const (
+	MinBoardColumns = 2
+	MaxBoardColumns = 10
+)
+
+// Board is a kanban board whose columns are status tags such as #todo,
+// #doing and #done. A note is a card in the column of its status tag.
+type Board struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Columns   []string  `json:"columns" db:"columns"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BoardColumn is a column of a board with its cards, most recently updated first
+type BoardColumn struct {
+	Tag   string         `json:"tag"`
+	Notes []NoteResponse `json:"notes"`
+	// HasMore is set when the column has more cards than were returned
+	HasMore bool `json:"has_more"`
+}
+
+// BoardView is a board with its notes grouped by column
+type BoardView struct {
+	Board   *Board        `json:"board"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+// CreateBoardRequest represents the request to create a board
+type CreateBoardRequest struct {
+	Name    string   `json:"name" validate:"required,max=100"`
+	Columns []string `json:"columns" validate:"required"`
+}
+
+// Validate validates and normalizes the request. Columns are lowercased and
+// get a leading # when missing.
+func (r *CreateBoardRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 100 {
+		return fmt.Errorf("name too long (max 100 characters)")
+	}
+
+	columns := make([]string, 0, len(r.Columns))
+	seen := make(map[string]bool, len(r.Columns))
+	for _, column := range r.Columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column == "" {
+			continue
+		}
+		column = search.NormalizeTag(column)
+		if seen[column] {
+			return fmt.Errorf("column %s is listed twice", column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	if len(columns) < MinBoardColumns || len(columns) > MaxBoardColumns {
+		return fmt.Errorf("a board needs between %d and %d columns", MinBoardColumns, MaxBoardColumns)
+	}
+	if err := ValidateTags(columns); err != nil {
+		return err
+	}
+	r.Columns = columns
+	return nil
+}
+
+// MoveCardRequest represents the request to move a note to a column
+type MoveCardRequest struct {
+	NoteID string `json:"note_id" validate:"required"`
+	Column string `json:"column" validate:"required"`
+	// Version is the note version the move was made against, if known
+	Version *int `json:"version,omitempty" validate:"omitempty,min=1"`
+}
+
+// MoveCard returns content with its status tag changed to the column tag to.
+// The first hashtag of any board column is replaced with to and the others
+// are removed; content without one gets to on a new line. The rest of the
+// content is left as is.
+func MoveCard(content string, columns []string, to string) string {
+	isColumn := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		isColumn[column] = true
+	}
+
+	var b strings.Builder
+	replaced := false
+	last := 0
+	for _, match := range spacedHashtagRegex.FindAllStringIndex(content, -1) {
+		start, end := match[0], match[1]
+		tag := strings.ToLower(strings.ReplaceAll(content[start:end], " ", ""))
+		if !isColumn[tag] {
+			continue
+		}
+		if replaced {
+			// Drop the space separating the removed tag from the text before it
+			if start > last && content[start-1] == ' ' {
+				start--
+			}
+			b.WriteString(content[last:start])
+		} else {
+			b.WriteString(content[last:start])
+			b.WriteString(to)
+			replaced = true
+		}
+		last = end
+	}
+	if !replaced {
+		return strings.TrimRight(content, "\n") + "\n" + to
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
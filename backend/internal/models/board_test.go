@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestMoveCard(t *testing.T) {
+	columns := []string{"#todo", "#doing", "#done"}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"replaces the status tag", "Ship release #todo #work", "Ship release #doing #work"},
+		{"matches tags case-insensitively", "#TODO Ship release", "#doing Ship release"},
+		{"removes other status tags", "Ship #todo release #done\n#work", "Ship #doing release\n#work"},
+		{"leaves other tags alone", "Ship #todos and #todo/later #work", "Ship #todos and #todo/later #work\n#doing"},
+		{"appends a missing status tag", "Ship release\n", "Ship release\n#doing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MoveCard(tt.content, columns, "#doing"); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateBoardRequestValidate(t *testing.T) {
+	request := &CreateBoardRequest{Name: " Sprint ", Columns: []string{"Todo", " #doing", "", "done"}}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if request.Name != "Sprint" || len(request.Columns) != 3 || request.Columns[0] != "#todo" || request.Columns[2] != "#done" {
+		t.Errorf("Unexpected normalized request %+v", request)
+	}
+
+	invalid := []*CreateBoardRequest{
+		{Name: "", Columns: []string{"#todo", "#done"}},
+		{Name: "Sprint", Columns: []string{"#todo"}},
+		{Name: "Sprint", Columns: []string{"#todo", "#TODO"}},
+		{Name: "Sprint", Columns: []string{"#todo", "#in progress"}},
+	}
+	for _, request := range invalid {
+		if err := request.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", request)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Calendar limits
+const (
+	// MaxCalendarDays is the longest range a calendar covers
+	MaxCalendarDays = 366
+	// DefaultCalendarNotesPerDay and MaxCalendarNotesPerDay bound the notes
+	// listed per day; every note is counted
+	DefaultCalendarNotesPerDay = 5
+	MaxCalendarNotesPerDay     = 50
+)
+
+// CalendarRequest selects the days of a calendar. From and To are dates in
+// Location and both inclusive.
+type CalendarRequest struct {
+	From        time.Time
+	To          time.Time
+	Location    *time.Location
+	NotesPerDay int
+}
+
+// CalendarNote is a note listed on a calendar day
+type CalendarNote struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	IsPrivate bool      `json:"is_private,omitempty"`
+}
+
+// CalendarDay is a day with the notes created on it, oldest first
+type CalendarDay struct {
+	Date  string         `json:"date"`
+	Count int            `json:"count"`
+	Notes []CalendarNote `json:"notes"`
+	// HasMore is set when the day has more notes than were listed
+	HasMore bool `json:"has_more"`
+}
+
+// Calendar is the number of notes created each day of a date range. Days
+// without notes are left out.
+type Calendar struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	TimeZone string        `json:"time_zone"`
+	Total    int           `json:"total"`
+	Days     []CalendarDay `json:"days"`
+}
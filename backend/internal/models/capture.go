@@ -0,0 +1,145 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// CaptureTag is added to every note created through the capture endpoint
+const CaptureTag = "#capture"
+
+// captureSourceInvalidChars matches characters not allowed in a source tag segment
+var captureSourceInvalidChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// CaptureRequest is the minimal payload automation tools, browser extensions
+// and email forwarding send to capture a note
+type CaptureRequest struct {
+	Text   string   `json:"text" validate:"required"`
+	Tags   []string `json:"tags,omitempty"`
+	Source string   `json:"source,omitempty" validate:"max=50"`
+	// Daily appends the text to today's daily note instead of creating a
+	// note
+	Daily bool `json:"daily,omitempty"`
+	// TimeZone is the IANA time zone deciding which day is today, UTC by
+	// default
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// ToCreateNoteRequest validates the request and returns the note to create.
+// The tags, CaptureTag and a nested tag for the source, such as
+// #capture/zapier, are appended to the text, and the source is recorded in
+// the note's metadata.
+func (r *CaptureRequest) ToCreateNoteRequest() (*CreateNoteRequest, error) {
+	text, tags, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	content := text
+	if len(tags) > 0 {
+		content += "\n\n" + strings.Join(tags, " ")
+	}
+	if utf8.RuneCountInString(content) > MaxContentLength() {
+		return nil, fmt.Errorf("text too long (max %d characters including tags)", MaxContentLength())
+	}
+
+	request := &CreateNoteRequest{Content: content}
+	if r.Source != "" {
+		request.Metadata = &NoteMetadata{Source: r.Source}
+	}
+	return request, nil
+}
+
+// ToDailyEntry validates the request and returns the lines to append to a
+// daily note: a bullet per line of text, with the tags of
+// ToCreateNoteRequest after the last one
+func (r *CaptureRequest) ToDailyEntry() (string, error) {
+	text, tags, err := r.parse()
+	if err != nil {
+		return "", err
+	}
+
+	var bullets []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			bullets = append(bullets, "- "+line)
+		}
+	}
+	if len(tags) > 0 {
+		bullets[len(bullets)-1] += " " + strings.Join(tags, " ")
+	}
+	return strings.Join(bullets, "\n"), nil
+}
+
+// parse validates the request and returns its trimmed text and the tags to
+// add that the text does not contain yet
+func (r *CaptureRequest) parse() (string, []string, error) {
+	text := strings.TrimSpace(r.Text)
+	if text == "" {
+		return "", nil, fmt.Errorf("text is required")
+	}
+	if len(r.Source) > 50 {
+		return "", nil, fmt.Errorf("source too long (max 50 characters)")
+	}
+
+	tags := []string{CaptureTag}
+	if source := captureSourceTag(r.Source); source != "" {
+		tags = append(tags, source)
+	}
+	userTags, err := normalizeCaptureTags(r.Tags)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return text, missingTags(text, append(tags, userTags...)), nil
+}
+
+// normalizeCaptureTags returns tags lowercased and starting with #, skipping
+// empty ones
+func normalizeCaptureTags(tags []string) ([]string, error) {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if !strings.HasPrefix(tag, "#") {
+			tag = "#" + tag
+		}
+		if hashtagRegex.FindString(tag) != tag {
+			return nil, fmt.Errorf("tag %s must contain only letters, digits, underscores, and / between nested tags", tag)
+		}
+		normalized = append(normalized, strings.ToLower(tag))
+	}
+	return normalized, nil
+}
+
+// missingTags returns the tags text does not contain yet, without
+// duplicates
+func missingTags(text string, tags []string) []string {
+	present := make(map[string]bool)
+	for _, tag := range hashtagRegex.FindAllString(strings.ToLower(text), -1) {
+		present[tag] = true
+	}
+	var missing []string
+	for _, tag := range tags {
+		if !present[tag] {
+			present[tag] = true
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// captureSourceTag returns the nested capture tag of a source, or "" when
+// the source has no usable characters
+func captureSourceTag(source string) string {
+	segment := captureSourceInvalidChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(source)), "_")
+	segment = strings.Trim(segment, "_")
+	if segment == "" {
+		return ""
+	}
+	return CaptureTag + "/" + segment
+}
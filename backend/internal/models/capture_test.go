@@ -0,0 +1,62 @@
+package models
+
+import "testing"
+
+func TestCaptureRequestToCreateNoteRequest(t *testing.T) {
+	request := CaptureRequest{
+		Text:   "  Call the dentist #Errands \n",
+		Tags:   []string{"errands", "#home/chores", " "},
+		Source: "Zapier Webhooks",
+	}
+	note, err := request.ToCreateNoteRequest()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Call the dentist #Errands\n\n#capture #capture/zapier_webhooks #home/chores"
+	if note.Content != want {
+		t.Errorf("Unexpected content:\n got %q\nwant %q", note.Content, want)
+	}
+	if note.Metadata == nil || note.Metadata.Source != "Zapier Webhooks" {
+		t.Errorf("Expected the source in the metadata, got %+v", note.Metadata)
+	}
+
+	note, err = (&CaptureRequest{Text: "Idea"}).ToCreateNoteRequest()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if note.Content != "Idea\n\n#capture" {
+		t.Errorf("Expected only the capture tag without a source, got %q", note.Content)
+	}
+}
+
+func TestCaptureRequestRejectsInvalidPayloads(t *testing.T) {
+	for _, request := range []CaptureRequest{
+		{Text: "   "},
+		{Text: "Idea", Tags: []string{"#not a tag"}},
+		{Text: "Idea", Tags: []string{"#to-do"}},
+		{Text: "Idea", Source: string(make([]byte, 51))},
+	} {
+		if _, err := request.ToCreateNoteRequest(); err == nil {
+			t.Errorf("Expected %+v to be rejected", request)
+		}
+	}
+}
+
+func TestCaptureRequestToDailyEntry(t *testing.T) {
+	request := CaptureRequest{
+		Text:   "Call the dentist\n\n  Buy milk #errands ",
+		Tags:   []string{"errands", "home"},
+		Source: "Shortcuts",
+		Daily:  true,
+	}
+	entry, err := request.ToDailyEntry()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "- Call the dentist\n- Buy milk #errands #capture #capture/shortcuts #home"
+	if entry != want {
+		t.Errorf("Unexpected entry:\n got %q\nwant %q", entry, want)
+	}
+}
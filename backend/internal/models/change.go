@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entity types recorded in the change log
+const (
+	ChangeEntityNote         = "note"
+	ChangeEntitySavedSearch  = "saved_search"
+	ChangeEntitySubscription = "subscription"
+)
+
+// Change log operations
+const (
+	ChangeOperationCreated = "created"
+	ChangeOperationUpdated = "updated"
+	ChangeOperationDeleted = "deleted"
+)
+
+// ChangeRecord is a change to an entity visible through the API
+type ChangeRecord struct {
+	// Seq is the position of the change among the changes of the user
+	Seq        int64     `json:"seq" db:"seq"`
+	EntityType string    `json:"entity_type" db:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id" db:"entity_id"`
+	Operation  string    `json:"operation" db:"operation"`
+	// Version is the entity version after the change, for notes
+	Version   *int      `json:"version,omitempty" db:"version"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// ChangeFeed is a page of the change log. Clients pass NextCursor as the
+// since parameter of the next request.
+type ChangeFeed struct {
+	Changes    []ChangeRecord `json:"changes"`
+	NextCursor string         `json:"next_cursor"`
+	HasMore    bool           `json:"has_more"`
+}
+
+// ChangeEvents is a page of the changes of a user after a sequence number.
+// Clients pass LastSeq as the last_seq parameter of the next request.
+type ChangeEvents struct {
+	Events  []ChangeRecord `json:"events"`
+	LastSeq int64          `json:"last_seq"`
+	HasMore bool           `json:"has_more"`
+}
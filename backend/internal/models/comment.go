@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxCommentLength is the length of a comment
+const MaxCommentLength = 5000
+
+// mentionRegex matches a mention: @ followed by an email address or the
+// part of one before its @, such as @alice or @alice@example.com. The @ must
+// not follow a word character, so email addresses in the text are not
+// mentions.
+var mentionRegex = regexp.MustCompile(`(?:^|[^\w@.])@([\w.%+-]*\w(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// Comment is a comment on a shared or published note
+type Comment struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	NoteID uuid.UUID `json:"note_id" db:"note_id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	// AuthorEmail is the email address of the commenting user
+	AuthorEmail string    `json:"author_email"`
+	Content     string    `json:"content" db:"content"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CommentList represents a paginated list of comments
+type CommentList struct {
+	Comments []Comment `json:"comments"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+	HasMore  bool      `json:"has_more"`
+}
+
+// CommentRequest represents the request to create or edit a comment
+type CommentRequest struct {
+	Content string `json:"content" validate:"required,max=5000"`
+}
+
+// Validate validates and trims the request
+func (r *CommentRequest) Validate() error {
+	r.Content = strings.TrimSpace(r.Content)
+	if r.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	if len(r.Content) > MaxCommentLength {
+		return fmt.Errorf("content too long (max %d characters)", MaxCommentLength)
+	}
+	return nil
+}
+
+// ExtractMentions returns the lowercased mentions of a comment, without
+// their @ and without duplicates
+func ExtractMentions(content string) []string {
+	var mentions []string
+	seen := make(map[string]bool)
+	for _, m := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		mention := strings.ToLower(m[1])
+		if !seen[mention] {
+			seen[mention] = true
+			mentions = append(mentions, mention)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"local part", "@alice can you check?", []string{"alice"}},
+		{"email", "cc @Bob.Smith@Example.com.", []string{"bob.smith@example.com"}},
+		{"several", "(@alice, @carol) and @alice again", []string{"alice", "carol"}},
+		{"email in text", "write to alice@example.com", nil},
+		{"lone at", "meet @ noon", nil},
+		{"trailing dot", "thanks @dave.", []string{"dave"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractMentions(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Destructive operations that can require a confirmation code
+const (
+	OperationDeleteAccount = "delete_account"
+	OperationBulkDelete    = "bulk_delete"
+	OperationMergeTags     = "merge_tags"
+	OperationRenameTag     = "rename_tag"
+)
+
+// Confirmation is a pending one-time code confirming a destructive operation.
+// The code is sent out of band; the client repeats the request with the
+// confirmation ID and code.
+type Confirmation struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"-" db:"user_id"`
+	Operation string    `json:"operation" db:"operation"`
+	Affected  int       `json:"affected" db:"affected"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// BatchDeleteNotesRequest represents the request to delete several notes
+type BatchDeleteNotesRequest struct {
+	NoteIDs []string `json:"note_ids" validate:"required,min=1,max=1000"`
+}
+
+// MergeTagsRequest represents the request to merge one tag into another in a
+// user's notes
+type MergeTagsRequest struct {
+	Source string `json:"source" validate:"required"`
+	Target string `json:"target" validate:"required"`
+}
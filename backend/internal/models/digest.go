@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Digest frequencies users can opt in to
+const (
+	DigestFrequencyOff    = "off"
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
+
+// ValidateDigestFrequency checks that frequency is a known digest frequency
+func ValidateDigestFrequency(frequency string) error {
+	switch frequency {
+	case DigestFrequencyOff, DigestFrequencyDaily, DigestFrequencyWeekly:
+		return nil
+	}
+	return fmt.Errorf("digest frequency must be one of off, daily or weekly")
+}
+
+// DigestPeriod returns how far back a digest of the given frequency looks
+func DigestPeriod(frequency string) time.Duration {
+	if frequency == DigestFrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// DigestScheduledAt returns the latest time at or before now a digest of the
+// given frequency was due. Daily digests are due every day at sendHour (UTC),
+// weekly digests on Mondays at sendHour.
+func DigestScheduledAt(frequency string, now time.Time, sendHour int) time.Time {
+	now = now.UTC()
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), sendHour, 0, 0, 0, time.UTC)
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	if frequency == DigestFrequencyWeekly {
+		daysSinceMonday := (int(scheduled.Weekday()) + 6) % 7
+		scheduled = scheduled.AddDate(0, 0, -daysSinceMonday)
+	}
+	return scheduled
+}
+
+// DigestNote is a note listed in a digest. Date is when a new note was
+// created, or when an open to-do was last updated. Private notes are listed
+// without their title.
+type DigestNote struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Date      time.Time `json:"date"`
+	IsPrivate bool      `json:"is_private"`
+	// Unchecked is the number of open checklist items of a #todo note
+	Unchecked int `json:"unchecked,omitempty"`
+}
+
+// Digest summarizes a user's notes over a period
+type Digest struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Frequency   string    `json:"frequency"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	// NewNotes lists the notes created during the period, newest first
+	NewNotes []DigestNote `json:"new_notes"`
+	// NewNotesTotal counts every new note, including those left out of NewNotes
+	NewNotesTotal int `json:"new_notes_total"`
+	// OpenTodos lists notes tagged #todo with unchecked checklist items
+	OpenTodos []DigestNote `json:"open_todos"`
+	// Summary is an LLM summary of the digest, when available
+	Summary string `json:"summary,omitempty"`
+}
+
+// IsEmpty reports whether the digest has nothing to tell the user
+func (d *Digest) IsEmpty() bool {
+	return d.NewNotesTotal == 0 && len(d.OpenTodos) == 0
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestScheduledAt(t *testing.T) {
+	// Friday, March 1st 2024
+	friday := func(hour int) time.Time { return time.Date(2024, 3, 1, hour, 30, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		frequency string
+		now       time.Time
+		want      time.Time
+	}{
+		{"daily after send hour", DigestFrequencyDaily, friday(9), time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)},
+		{"daily before send hour", DigestFrequencyDaily, friday(6), time.Date(2024, 2, 29, 7, 0, 0, 0, time.UTC)},
+		{"weekly on a friday", DigestFrequencyWeekly, friday(9), time.Date(2024, 2, 26, 7, 0, 0, 0, time.UTC)},
+		{"weekly on monday before send hour", DigestFrequencyWeekly, time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC), time.Date(2024, 2, 26, 7, 0, 0, 0, time.UTC)},
+		{"weekly on monday after send hour", DigestFrequencyWeekly, time.Date(2024, 3, 4, 7, 0, 0, 0, time.UTC), time.Date(2024, 3, 4, 7, 0, 0, 0, time.UTC)},
+		{"other time zone", DigestFrequencyDaily, time.Date(2024, 3, 1, 8, 0, 0, 0, time.FixedZone("UTC+2", 2*3600)), time.Date(2024, 2, 29, 7, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DigestScheduledAt(tt.frequency, tt.now, 7); !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateUserSettingsRequestValidate(t *testing.T) {
+	for _, frequency := range []string{DigestFrequencyOff, DigestFrequencyDaily, DigestFrequencyWeekly} {
+		request := &UpdateUserSettingsRequest{DigestFrequency: &frequency}
+		if err := request.Validate(); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", frequency, err)
+		}
+	}
+
+	monthly := "monthly"
+	if err := (&UpdateUserSettingsRequest{DigestFrequency: &monthly}).Validate(); err == nil {
+		t.Error("Expected an unknown frequency to be rejected")
+	}
+	if err := (&UpdateUserSettingsRequest{}).Validate(); err != nil {
+		t.Errorf("Expected an empty request to be valid, got %v", err)
+	}
+}
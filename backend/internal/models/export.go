@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export formats
+const (
+	// ExportFormatJSON is a note archive that the archive import can read back
+	ExportFormatJSON = "json"
+	// ExportFormatCSV is a spreadsheet with one row per note and its tags
+	// in one column, which the spreadsheet import can read back
+	ExportFormatCSV = "csv"
+	// ExportFormatPDF is a printable document with a table of contents
+	// followed by each note on its own page
+	ExportFormatPDF = "pdf"
+)
+
+// ExportFilter narrows an export to part of the user's notes. Empty fields
+// do not filter; notes must match every field that is set.
+type ExportFilter struct {
+	// Tags are hashtags every exported note must have
+	Tags []string `json:"tags,omitempty"`
+	// CreatedAfter is inclusive and CreatedBefore is exclusive, both on the
+	// note creation date
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	// Query is a search query using the note search language
+	Query string `json:"query,omitempty"`
+	// NotebookID scopes the export to one notebook
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty"`
+}
+
+// ExportedNote is a note as written to a JSON export
+type ExportedNote struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	Private   bool      `json:"private,omitempty"`
+	Color     string    `json:"color,omitempty"`
+	Icon      string    `json:"icon,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Watermark names who exported the note and when, for notes of
+	// organizations that watermark exports
+	Watermark string `json:"watermark,omitempty"`
+}
+
+// AccountExport is the account data written ahead of the notes in a full
+// account export
+type AccountExport struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	Account       *User                `json:"account"`
+	Settings      *UserSettings        `json:"settings"`
+	Sessions      []UserSession        `json:"sessions"`
+	SavedSearches []SavedSearch        `json:"saved_searches"`
+	Subscriptions []SearchSubscription `json:"subscriptions"`
+	AuditLog      []AuditEntry         `json:"audit_log"`
+	Templates     []Template           `json:"templates"`
+	Notebooks     []Notebook           `json:"notebooks"`
+	Recurrences   []Recurrence         `json:"recurrences"`
+	Comments      []Comment            `json:"comments"`
+	Webhooks      []Webhook            `json:"webhooks"`
+	APIKeys       []APIKey             `json:"api_keys"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Activity feed event types
+const (
+	FeedNoteCreated    = "note_created"
+	FeedNoteUpdated    = "note_updated"
+	FeedNoteDeleted    = "note_deleted"
+	FeedNotePrettified = "note_prettified"
+	FeedTagAdded       = "tag_added"
+	FeedMemberJoined   = "member_joined"
+	FeedNotebookShared = "notebook_shared"
+)
+
+// FeedEvent is something that happened to notes a user can read or in
+// their organizations. Note events come from the audit log, so they are kept
+// for the audit retention period.
+type FeedEvent struct {
+	Type string `json:"type"`
+	// ActorID is the user who acted; for tag_added, the author of the note
+	ActorID        uuid.UUID  `json:"actor_id"`
+	ActorEmail     string     `json:"actor_email,omitempty"`
+	NoteID         *uuid.UUID `json:"note_id,omitempty"`
+	NoteTitle      string     `json:"note_title,omitempty"`
+	NotebookID     *uuid.UUID `json:"notebook_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	Tag            string     `json:"tag,omitempty"`
+	// Count is the number of notes of batch events, 1 otherwise
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Feed is a page of activity, newest first. Clients pass NextCursor
+// as the cursor parameter to get older events.
+type Feed struct {
+	Events     []FeedEvent `json:"events"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Time summary periods
+const (
+	TimePeriodDay  = "day"
+	TimePeriodWeek = "week"
+)
+
+// FocusSession is a period of time spent on a note
+type FocusSession struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	NoteID    uuid.UUID  `json:"note_id" db:"note_id"`
+	StartedAt time.Time  `json:"started_at" db:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	// DurationSeconds is the time spent so far for a running session
+	DurationSeconds int64 `json:"duration_seconds" db:"-"`
+}
+
+// StartFocusSessionResponse is the started session and, when the user was
+// focused on another note, the session that was stopped to start it
+type StartFocusSessionResponse struct {
+	Session *FocusSession `json:"session"`
+	Stopped *FocusSession `json:"stopped,omitempty"`
+}
+
+// TimeSummaryRequest selects the range and grouping of a time summary
+type TimeSummaryRequest struct {
+	Period   string
+	From     time.Time // first day, midnight in Location
+	To       time.Time // last day, midnight in Location
+	Location *time.Location
+}
+
+// TimeSummary is the time spent on notes per day or week
+type TimeSummary struct {
+	Period       string       `json:"period"`
+	TimeZone     string       `json:"time_zone"`
+	From         string       `json:"from"`
+	To           string       `json:"to"`
+	TotalSeconds int64        `json:"total_seconds"`
+	Buckets      []TimeBucket `json:"buckets"`
+}
+
+// TimeBucket is the time spent on notes in one day or week
+type TimeBucket struct {
+	// Start is the first day of the bucket, as YYYY-MM-DD
+	Start        string     `json:"start"`
+	TotalSeconds int64      `json:"total_seconds"`
+	Notes        []NoteTime `json:"notes"`
+}
+
+// NoteTime is the time spent on a note within a bucket
+type NoteTime struct {
+	NoteID  uuid.UUID `json:"note_id"`
+	Title   string    `json:"title"`
+	Seconds int64     `json:"seconds"`
+}
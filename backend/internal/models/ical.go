@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarFeedPath is where the iCal feed is served; the token is passed in
+// the token query parameter
+const CalendarFeedPath = "/api/v1/calendar.ics"
+
+// MaxCalendarFeedEvents bounds the events of an iCal feed
+const MaxCalendarFeedEvents = 1000
+
+// icalDateLayout and icalTimeLayout format iCalendar dates and local times
+const (
+	icalDateLayout = "20060102"
+	icalTimeLayout = "20060102T150405"
+)
+
+// icalLineLength is the longest content line in octets, without the CRLF
+const icalLineLength = 75
+
+// CalendarFeed is a user's iCal feed of due dates and recurring notes, read
+// by calendar apps with the token in its URL. Only a hash of the token is
+// stored.
+type CalendarFeed struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatedCalendarFeed is a new feed together with its token and URL path,
+// which are returned only once
+type CreatedCalendarFeed struct {
+	CalendarFeed
+	Token string `json:"token"`
+	Path  string `json:"path"`
+}
+
+// CalendarEvent is an event of an iCal feed: an all-day event on Start's
+// date, or an event at Start in TimeZone repeating by RRule
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	AllDay      bool
+	TimeZone    string
+	// RRule is the iCalendar recurrence rule, such as FREQ=WEEKLY;BYDAY=FR
+	RRule string
+	// Stamp is when the event last changed
+	Stamp time.Time
+}
+
+// RRule returns the iCalendar recurrence rule of the schedule. Monthly runs
+// on days some months lack fall on the month's last day, as they do in
+// Next.
+func (r *Recurrence) RRule() string {
+	switch r.Frequency {
+	case RecurrenceWeekly:
+		return "FREQ=WEEKLY;BYDAY=" + strings.ToUpper(time.Weekday(r.Weekday).String()[:2])
+	case RecurrenceMonthly:
+		if r.MonthDay <= 28 {
+			return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d", r.MonthDay)
+		}
+		days := make([]string, 0, r.MonthDay-27)
+		for day := 28; day <= r.MonthDay; day++ {
+			days = append(days, fmt.Sprint(day))
+		}
+		return "FREQ=MONTHLY;BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+	default:
+		return "FREQ=DAILY"
+	}
+}
+
+// FormatICalendar returns an iCalendar document (RFC 5545) of events
+func FormatICalendar(name string, events []CalendarEvent) string {
+	var b strings.Builder
+	line := func(content string) {
+		writeICalLine(&b, content)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Silence Notes//Notes//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText(name))
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeICalText(event.UID))
+		line("DTSTAMP:" + event.Stamp.UTC().Format(icalTimeLayout) + "Z")
+		if event.AllDay {
+			line("DTSTART;VALUE=DATE:" + event.Start.Format(icalDateLayout))
+			line("DTEND;VALUE=DATE:" + event.Start.AddDate(0, 0, 1).Format(icalDateLayout))
+		} else if event.TimeZone != "" && event.TimeZone != "UTC" {
+			line("DTSTART;TZID=" + event.TimeZone + ":" + event.Start.Format(icalTimeLayout))
+			line("DURATION:PT30M")
+		} else {
+			line("DTSTART:" + event.Start.UTC().Format(icalTimeLayout) + "Z")
+			line("DURATION:PT30M")
+		}
+		if event.RRule != "" {
+			line("RRULE:" + event.RRule)
+		}
+		line("SUMMARY:" + escapeICalText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:" + escapeICalText(event.Description))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(text)
+}
+
+// writeICalLine writes a content line ending in CRLF, folding it into lines
+// of at most icalLineLength octets without splitting characters
+func writeICalLine(b *strings.Builder, content string) {
+	limit := icalLineLength
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space
+		limit = icalLineLength - 1
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRRule(t *testing.T) {
+	tests := []struct {
+		recurrence Recurrence
+		want       string
+	}{
+		{Recurrence{Frequency: RecurrenceDaily}, "FREQ=DAILY"},
+		{Recurrence{Frequency: RecurrenceWeekly, Weekday: int(time.Friday)}, "FREQ=WEEKLY;BYDAY=FR"},
+		{Recurrence{Frequency: RecurrenceMonthly, MonthDay: 15}, "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{Recurrence{Frequency: RecurrenceMonthly, MonthDay: 30}, "FREQ=MONTHLY;BYMONTHDAY=28,29,30;BYSETPOS=-1"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.recurrence.RRule(); got != tt.want {
+			t.Errorf("RRule() of %+v = %q, want %q", tt.recurrence, got, tt.want)
+		}
+	}
+}
+
+func TestFormatICalendar(t *testing.T) {
+	stamp := time.Date(2024, 3, 4, 12, 30, 0, 0, time.UTC)
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	events := []CalendarEvent{
+		{UID: "due@test", Summary: "Pay rent; bills, etc.\nSoon", Description: "due", Start: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), AllDay: true, Stamp: stamp},
+		{UID: "weekly@test", Summary: "Review", Start: time.Date(2024, 3, 8, 16, 30, 0, 0, berlin), TimeZone: "Europe/Berlin", RRule: "FREQ=WEEKLY;BYDAY=FR", Stamp: stamp},
+		{UID: "long@test", Summary: strings.Repeat("é", 60), Start: stamp, Stamp: stamp},
+	}
+
+	got := FormatICalendar("Notes", events)
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"DTSTAMP:20240304T123000Z\r\n",
+		"DTSTART;VALUE=DATE:20240331\r\nDTEND;VALUE=DATE:20240401\r\n",
+		`SUMMARY:Pay rent\; bills\, etc.\nSoon` + "\r\n",
+		"DTSTART;TZID=Europe/Berlin:20240308T163000\r\nDURATION:PT30M\r\nRRULE:FREQ=WEEKLY;BYDAY=FR\r\n",
+		"DTSTART:20240304T123000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in\n%s", want, got)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+	unfolded := strings.ReplaceAll(got, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+strings.Repeat("é", 60)+"\r\n") {
+		t.Errorf("Expected the folded summary to unfold, got\n%s", got)
+	}
+}
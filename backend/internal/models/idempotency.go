@@ -0,0 +1,8 @@
+package models
+
+// IdempotentResponse is the stored response of the first request sent with
+// an idempotency key, replayed to retries of the request
+type IdempotentResponse struct {
+	StatusCode int
+	Body       []byte
+}
@@ -0,0 +1,33 @@
+package models
+
+import "net/http"
+
+// MaxNoteImageTextLength is the length of the text recognized in a note's
+// images, in bytes
+const MaxNoteImageTextLength = 50000
+
+// ImageTextTypes are the image types text is recognized in
+var ImageTextTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
+// DetectImageType returns the type of an image by its content, or "" when
+// it is not one of ImageTextTypes
+func DetectImageType(image []byte) string {
+	contentType := http.DetectContentType(image)
+	if !ImageTextTypes[contentType] {
+		return ""
+	}
+	return contentType
+}
+
+// ImageTextResponse is the response to an image uploaded to a note
+type ImageTextResponse struct {
+	// Text is the text recognized in the image, empty when it has none
+	Text string       `json:"text"`
+	Note NoteResponse `json:"note"`
+}
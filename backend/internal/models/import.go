@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gpd/my-notes/internal/importer"
+	"github.com/google/uuid"
+)
+
+// Import session statuses
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusMapped    = "mapped"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Import formats
+const (
+	// ImportFormatCSV is the format of delimited spreadsheet exports
+	ImportFormatCSV = "csv"
+	// ImportFormatJSON is the format of note archives, imported without a mapping step
+	ImportFormatJSON = "json"
+	// ImportFormatVault is the format of zipped Markdown folder trees such as Obsidian vaults
+	ImportFormatVault = "vault"
+)
+
+// ImportSession is an uploaded file going through the import wizard:
+// upload, map columns to note fields, then execute
+type ImportSession struct {
+	ID       uuid.UUID         `json:"id" db:"id"`
+	UserID   uuid.UUID         `json:"user_id" db:"user_id"`
+	Status   string            `json:"status" db:"status"`
+	Filename string            `json:"filename,omitempty" db:"filename"`
+	Format   string            `json:"format" db:"format"`
+	Columns  []string          `json:"columns" db:"columns"`
+	RowCount int               `json:"row_count" db:"row_count"`
+	Mapping  *importer.Mapping `json:"mapping,omitempty" db:"mapping"`
+	Result   *ImportResult     `json:"result,omitempty" db:"result"`
+	// SampleRows and SuggestedMapping help the client build the mapping step
+	SampleRows       [][]string        `json:"sample_rows,omitempty" db:"-"`
+	SuggestedMapping *importer.Mapping `json:"suggested_mapping,omitempty" db:"-"`
+	// Validation is returned when a mapping is submitted
+	Validation *ImportValidation `json:"validation,omitempty" db:"-"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at" db:"updated_at"`
+	ExpiresAt  time.Time         `json:"expires_at" db:"expires_at"`
+}
+
+// ImportValidation reports how the rows of a session convert under a mapping
+type ImportValidation struct {
+	ValidRows int                 `json:"valid_rows"`
+	Errors    []importer.RowError `json:"errors"`
+	Preview   []importer.Record   `json:"preview"`
+}
+
+// ImportResult is the outcome of executing an import
+type ImportResult struct {
+	Created int                 `json:"created"`
+	Skipped int                 `json:"skipped"`
+	Errors  []importer.RowError `json:"errors"`
+	Message string              `json:"message,omitempty"`
+}
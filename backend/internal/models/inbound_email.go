@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InboundEmailSource is the metadata source of notes created from email
+const InboundEmailSource = "email"
+
+// maxInboundTitleLength is the title limit of notes
+const maxInboundTitleLength = 500
+
+// InboundAddress is the secret address a user emails notes to
+type InboundAddress struct {
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InboundAttachment describes a file attached to an inbound email
+type InboundAttachment struct {
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+// InboundEmail is an email received by the mail provider for an inbound
+// address
+type InboundEmail struct {
+	// Recipients are the addresses the email was delivered to
+	Recipients  []string
+	From        string
+	Subject     string
+	Body        string
+	Attachments []InboundAttachment
+}
+
+// ToCreateNoteRequest returns the note an email becomes: the subject is the
+// title and the plain text body the content, cut off at the note limits.
+// Attachments are not stored; their names are listed after the body.
+func (e *InboundEmail) ToCreateNoteRequest() (*CreateNoteRequest, error) {
+	title := truncateRunes(strings.Join(strings.Fields(e.Subject), " "), maxInboundTitleLength)
+	content := strings.TrimSpace(strings.ReplaceAll(e.Body, "\r\n", "\n"))
+
+	if len(e.Attachments) > 0 {
+		lines := []string{"Attachments (not stored):"}
+		for _, attachment := range e.Attachments {
+			name := strings.TrimSpace(attachment.Filename)
+			if name == "" {
+				name = "unnamed"
+			}
+			lines = append(lines, fmt.Sprintf("- %s (%s)", name, formatAttachmentSize(attachment.Size)))
+		}
+		if content != "" {
+			content += "\n\n"
+		}
+		content += strings.Join(lines, "\n")
+	}
+
+	if content == "" {
+		content = title
+	}
+	if content == "" {
+		return nil, fmt.Errorf("email has no subject or body")
+	}
+
+	return &CreateNoteRequest{
+		Title:    title,
+		Content:  truncateRunes(content, MaxContentLength()),
+		Metadata: &NoteMetadata{Source: InboundEmailSource},
+	}, nil
+}
+
+// truncateRunes cuts s to at most n runes
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n]))
+}
+
+// formatAttachmentSize formats a size in bytes for people
+func formatAttachmentSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
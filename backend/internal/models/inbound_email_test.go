@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestInboundEmailToCreateNoteRequest(t *testing.T) {
+	email := &InboundEmail{
+		Subject: "  Fwd:\tTrip   ideas ",
+		Body:    "Kyoto\r\nOsaka\r\n",
+		Attachments: []InboundAttachment{
+			{Filename: "map.pdf", Size: 2048},
+			{Size: 10},
+		},
+	}
+	request, err := email.ToCreateNoteRequest()
+	if err != nil {
+		t.Fatalf("ToCreateNoteRequest() error = %v", err)
+	}
+	if request.Title != "Fwd: Trip ideas" {
+		t.Errorf("Title = %q", request.Title)
+	}
+	want := "Kyoto\nOsaka\n\nAttachments (not stored):\n- map.pdf (2.0 KB)\n- unnamed (10 B)"
+	if request.Content != want {
+		t.Errorf("Content = %q, want %q", request.Content, want)
+	}
+	if request.Metadata == nil || request.Metadata.Source != InboundEmailSource {
+		t.Errorf("Metadata = %+v", request.Metadata)
+	}
+
+	// The subject stands in for an empty body
+	request, err = (&InboundEmail{Subject: "Call mom"}).ToCreateNoteRequest()
+	if err != nil || request.Content != "Call mom" {
+		t.Errorf("ToCreateNoteRequest() = %+v, %v", request, err)
+	}
+
+	request, err = (&InboundEmail{Body: strings.Repeat("é", MaxContentLength()+1)}).ToCreateNoteRequest()
+	if err != nil || utf8.RuneCountInString(request.Content) != MaxContentLength() {
+		t.Errorf("Expected the body cut off at the content limit, got %v", err)
+	}
+
+	if _, err := (&InboundEmail{Body: " \r\n "}).ToCreateNoteRequest(); err == nil {
+		t.Error("Expected an empty email rejected")
+	}
+}
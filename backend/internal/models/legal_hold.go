@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Legal hold audit actions
+const (
+	LegalHoldActionApplied  = "applied"
+	LegalHoldActionReleased = "released"
+)
+
+// LegalHold prevents a user's notes from being deleted. A hold without a note
+// ID covers every note of the user and the account itself.
+type LegalHold struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	NoteID     *uuid.UUID `json:"note_id,omitempty" db:"note_id"`
+	Reason     string     `json:"reason" db:"reason"`
+	CreatedBy  uuid.UUID  `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReleasedBy *uuid.UUID `json:"released_by,omitempty" db:"released_by"`
+	ReleasedAt *time.Time `json:"released_at,omitempty" db:"released_at"`
+}
+
+// IsActive reports whether the hold is still in force
+func (h *LegalHold) IsActive() bool {
+	return h.ReleasedAt == nil
+}
+
+// LegalHoldAuditEntry records a legal hold being applied or released
+type LegalHoldAuditEntry struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	HoldID    uuid.UUID `json:"hold_id" db:"hold_id"`
+	Action    string    `json:"action" db:"action"`
+	ActorID   uuid.UUID `json:"actor_id" db:"actor_id"`
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateLegalHoldRequest represents the request to place a user's notes, or
+// a single note, under legal hold
+type CreateLegalHoldRequest struct {
+	UserID string  `json:"user_id" validate:"required,uuid"`
+	NoteID *string `json:"note_id,omitempty" validate:"omitempty,uuid"`
+	Reason string  `json:"reason" validate:"required,max=1000"`
+}
+
+// ReleaseLegalHoldRequest represents the request to release a legal hold
+type ReleaseLegalHoldRequest struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
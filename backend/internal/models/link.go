@@ -0,0 +1,84 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// wikiLinkRegex matches [[Target]] and [[Target|label]] links
+var wikiLinkRegex = regexp.MustCompile(`\[\[([^\[\]\n|]+)(?:\|[^\[\]\n]*)?\]\]`)
+
+// noteURILinkRegex matches note://UUID links
+var noteURILinkRegex = regexp.MustCompile(`note://([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+
+// LinkRef is a link found in note content. Links by ID set TargetID; wiki
+// links by title set TargetTitle and are resolved against note titles.
+type LinkRef struct {
+	TargetID    *uuid.UUID
+	TargetTitle string
+}
+
+// ExtractLinks extracts note links from the note content. [[UUID]] is treated
+// as a link by ID. Duplicate links are returned once.
+func (n *Note) ExtractLinks() []LinkRef {
+	var links []LinkRef
+	seenIDs := make(map[uuid.UUID]bool)
+	seenTitles := make(map[string]bool)
+
+	addID := func(id uuid.UUID) {
+		if id != n.ID && !seenIDs[id] {
+			seenIDs[id] = true
+			links = append(links, LinkRef{TargetID: &id})
+		}
+	}
+
+	for _, match := range noteURILinkRegex.FindAllStringSubmatch(n.Content, -1) {
+		addID(uuid.MustParse(match[1]))
+	}
+
+	for _, match := range wikiLinkRegex.FindAllStringSubmatch(n.Content, -1) {
+		target := strings.TrimSpace(match[1])
+		if target == "" {
+			continue
+		}
+		if id, err := uuid.Parse(target); err == nil {
+			addID(id)
+			continue
+		}
+		key := strings.ToLower(target)
+		if !seenTitles[key] {
+			seenTitles[key] = true
+			links = append(links, LinkRef{TargetTitle: target})
+		}
+	}
+
+	return links
+}
+
+// Backlink is a note linking to another note
+type Backlink struct {
+	NoteID    uuid.UUID `json:"note_id"`
+	Title     *string   `json:"title,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GraphNode is a note in the link graph
+type GraphNode struct {
+	ID    uuid.UUID `json:"id"`
+	Title *string   `json:"title,omitempty"`
+}
+
+// GraphEdge is a link from one note to another
+type GraphEdge struct {
+	Source uuid.UUID `json:"source"`
+	Target uuid.UUID `json:"target"`
+}
+
+// NoteGraph is a user's note link graph for visualization
+type NoteGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
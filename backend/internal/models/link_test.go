@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExtractLinks(t *testing.T) {
+	self := uuid.New()
+	target := uuid.New()
+	note := &Note{
+		ID: self,
+		Content: "See [[Project Plan]] and [[project plan|the plan]], also note://" + target.String() +
+			" and [[" + target.String() + "]]. Ignore [[]] and links to note://" + self.String(),
+	}
+
+	links := note.ExtractLinks()
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %d: %+v", len(links), links)
+	}
+	if links[0].TargetID == nil || *links[0].TargetID != target {
+		t.Errorf("Expected first link to target %s, got %+v", target, links[0])
+	}
+	if links[1].TargetID != nil || links[1].TargetTitle != "Project Plan" {
+		t.Errorf("Expected wiki link to 'Project Plan', got %+v", links[1])
+	}
+}
+
+func TestExtractLinksNone(t *testing.T) {
+	note := &Note{ID: uuid.New(), Content: "Plain text with [single brackets] and note://not-a-uuid"}
+	if links := note.ExtractLinks(); len(links) != 0 {
+		t.Errorf("Expected no links, got %+v", links)
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// LLMUsage is a user's LLM token usage in the current calendar month (UTC)
+// and their budget for it
+type LLMUsage struct {
+	PeriodStart      time.Time         `json:"period_start"`
+	PeriodEnd        time.Time         `json:"period_end"`
+	Calls            int64             `json:"calls"`
+	PromptTokens     int64             `json:"prompt_tokens"`
+	CompletionTokens int64             `json:"completion_tokens"`
+	TotalTokens      int64             `json:"total_tokens"`
+	CostUSD          float64           `json:"cost_usd"`
+	Budget           *int64            `json:"budget"`    // tokens per month, null when unlimited
+	Remaining        *int64            `json:"remaining"` // tokens left this month, null when unlimited
+	Features         []LLMFeatureUsage `json:"features"`
+}
+
+// LLMFeatureUsage is the usage of one LLM feature, such as prettify
+type LLMFeatureUsage struct {
+	Feature     string  `json:"feature"`
+	Calls       int64   `json:"calls"`
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+}
+
+// SetLLMBudgetRequest sets the monthly token budget of a user. A null budget
+// restores the default.
+type SetLLMBudgetRequest struct {
+	MonthlyTokens *int64 `json:"monthly_tokens" validate:"omitempty,min=0"`
+}
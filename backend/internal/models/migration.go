@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Incoming migration transfer statuses
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusReceiving = "receiving"
+	TransferStatusCompleted = "completed"
+)
+
+// Outgoing migration statuses
+const (
+	MigrationStatusRunning   = "running"
+	MigrationStatusFailed    = "failed"
+	MigrationStatusCompleted = "completed"
+	// MigrationStatusInterrupted marks a push stopped by a server shutdown,
+	// resumed when the server starts again
+	MigrationStatusInterrupted = "interrupted"
+)
+
+// MigrationTransfer is a destination's end of a migration: it accepts the
+// chunks a source deployment pushes with the transfer token
+type MigrationTransfer struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"-" db:"user_id"`
+	Status        string     `json:"status" db:"status"`
+	NextChunk     int        `json:"next_chunk" db:"next_chunk"`
+	NotesReceived int        `json:"notes_received" db:"notes_received"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	// Token is only returned when the transfer is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// OutgoingMigration is a source's end of a migration pushing a user's data to
+// another deployment
+type OutgoingMigration struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	UserID         uuid.UUID  `json:"-" db:"user_id"`
+	DestinationURL string     `json:"destination_url" db:"destination_url"`
+	TransferID     uuid.UUID  `json:"transfer_id" db:"transfer_id"`
+	Status         string     `json:"status" db:"status"`
+	ChunksSent     int        `json:"chunks_sent" db:"chunks_sent"`
+	NotesSent      int        `json:"notes_sent" db:"notes_sent"`
+	NotesSkipped   int        `json:"notes_skipped" db:"notes_skipped"`
+	Error          *string    `json:"error,omitempty" db:"error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// StartMigrationRequest represents the request to push the user's data to the
+// transfer created on another deployment
+type StartMigrationRequest struct {
+	DestinationURL string `json:"destination_url" validate:"required,url"`
+	TransferID     string `json:"transfer_id" validate:"required,uuid"`
+	Token          string `json:"token" validate:"required"`
+}
+
+// MigrationChunk is one numbered part of a migration. Chunk 0 carries the
+// account data, the following chunks carry notes, and the last one is Final.
+type MigrationChunk struct {
+	Sequence      int                     `json:"sequence"`
+	Final         bool                    `json:"final"`
+	Settings      *UserSettings           `json:"settings,omitempty"`
+	SavedSearches []MigrationSavedSearch  `json:"saved_searches,omitempty"`
+	Subscriptions []MigrationSubscription `json:"subscriptions,omitempty"`
+	Templates     []MigrationTemplate     `json:"templates,omitempty"`
+	Notes         []MigrationNote         `json:"notes,omitempty"`
+}
+
+// MigrationNote is a note as sent between deployments. Private notes are sent
+// decrypted and encrypted again with the destination's key.
+type MigrationNote struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	Private   bool      `json:"private,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MigrationSavedSearch is a saved search as sent between deployments
+type MigrationSavedSearch struct {
+	Name     string   `json:"name"`
+	Query    string   `json:"query,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	OrderBy  string   `json:"order_by"`
+	OrderDir string   `json:"order_dir"`
+}
+
+// MigrationSubscription is a search subscription as sent between deployments.
+// Subscriptions following a saved search refer to it by name.
+type MigrationSubscription struct {
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	SavedSearch string `json:"saved_search,omitempty"`
+}
+
+// MigrationTemplate is a note template as sent between deployments
+type MigrationTemplate struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Public      bool     `json:"public,omitempty"`
+}
@@ -3,14 +3,50 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/gpd/my-notes/internal/language"
 	"github.com/google/uuid"
 )
 
+// DefaultMaxContentLength is the content limit of notes, in characters,
+// unless configured otherwise with SetMaxContentLength
+const DefaultMaxContentLength = 10000
+
+// maxContentLength is the content limit of notes, set once at startup
+var maxContentLength = DefaultMaxContentLength
+
+// ErrContentTooLong is returned for notes whose content is longer than
+// MaxContentLength
+var ErrContentTooLong = errors.New("content too long")
+
+// SetMaxContentLength sets the content limit of notes, in characters. It is
+// called at startup, before notes are validated.
+func SetMaxContentLength(n int) {
+	if n <= 0 {
+		n = DefaultMaxContentLength
+	}
+	maxContentLength = n
+}
+
+// MaxContentLength returns the content limit of notes, in characters
+func MaxContentLength() int {
+	return maxContentLength
+}
+
+// CheckContentLength returns an error wrapping ErrContentTooLong when
+// content is longer than MaxContentLength
+func CheckContentLength(content string) error {
+	if utf8.RuneCountInString(content) > maxContentLength {
+		return fmt.Errorf("%w (max %d characters)", ErrContentTooLong, maxContentLength)
+	}
+	return nil
+}
+
 // Note represents a note in the system
 type Note struct {
 	ID           uuid.UUID   `json:"id" db:"id"`
@@ -22,6 +58,23 @@ type Note struct {
 	Version      int         `json:"version" db:"version"`
 	PrettifiedAt *time.Time  `json:"prettified_at,omitempty" db:"prettified_at"`
 	AIImproved   bool        `json:"ai_improved" db:"ai_improved"`
+	Language     string      `json:"language" db:"language"`
+	IsPrivate    bool        `json:"is_private" db:"is_private"`
+	// Metadata describes where the note was written
+	Metadata     *NoteMetadata `json:"metadata,omitempty" db:"metadata"`
+	// Properties are the values of the user's custom properties
+	Properties   NoteProperties `json:"properties,omitempty" db:"properties"`
+	// Color is one of NoteColors, empty without a color label
+	Color        string      `json:"color,omitempty" db:"color"`
+	// Icon is an emoji shown with the note
+	Icon         string      `json:"icon,omitempty" db:"icon"`
+	// NotebookID is the notebook the note belongs to
+	NotebookID   uuid.UUID   `json:"notebook_id" db:"notebook_id"`
+	// ImageText is the text recognized in images uploaded to the note,
+	// searched along with the content
+	ImageText    *string     `json:"image_text,omitempty" db:"image_text"`
+	// Locked is set when a private note was read without the encryption key; Content is empty
+	Locked       bool        `json:"locked,omitempty" db:"-"`
 }
 
 // NoteResponse is the safe response format for note data
@@ -37,6 +90,17 @@ type NoteResponse struct {
 	SyncMetadata map[string]interface{}   `json:"sync_metadata,omitempty"`
 	PrettifiedAt *time.Time               `json:"prettified_at,omitempty"`
 	AIImproved   bool                     `json:"ai_improved"`
+	Language     string                   `json:"language,omitempty"`
+	IsPrivate    bool                     `json:"is_private"`
+	Locked       bool                     `json:"locked,omitempty"`
+	Metadata     *NoteMetadata            `json:"metadata,omitempty"`
+	Properties   NoteProperties           `json:"properties,omitempty"`
+	Color        string                   `json:"color,omitempty"`
+	Icon         string                   `json:"icon,omitempty"`
+	NotebookID   uuid.UUID                `json:"notebook_id"`
+	ImageText    *string                  `json:"image_text,omitempty"`
+	// TotalTimeSeconds is the time spent on the note in focus sessions
+	TotalTimeSeconds int64 `json:"total_time_seconds"`
 }
 
 // ToResponse converts Note to NoteResponse
@@ -51,13 +115,30 @@ func (n *Note) ToResponse() NoteResponse {
 		Version:      n.Version,
 		PrettifiedAt: n.PrettifiedAt,
 		AIImproved:   n.AIImproved,
+		Language:     n.Language,
+		IsPrivate:    n.IsPrivate,
+		Locked:       n.Locked,
+		Metadata:     n.Metadata,
+		Properties:   n.Properties,
+		Color:        n.Color,
+		Icon:         n.Icon,
+		NotebookID:   n.NotebookID,
+		ImageText:    n.ImageText,
 	}
 }
 
+// DetectLanguage detects the language of the note title and content and stores it on the note
+func (n *Note) DetectLanguage() {
+	text := n.Content
+	if n.Title != nil {
+		text = *n.Title + "\n" + text
+	}
+	n.Language = string(language.Detect(text))
+}
+
 // ExtractHashtags extracts hashtags from the note content
 func (nr *NoteResponse) ExtractHashtags() []string {
-	// Regular expression to match hashtags (#word)
-	hashtagRegex := regexp.MustCompile(`#\w+`)
+	// Match hashtags (#word), including nested ones (#word/child)
 	matches := hashtagRegex.FindAllString(nr.Content, -1)
 
 	// Remove duplicates and ensure they start with #
@@ -87,8 +168,8 @@ func (n *Note) Validate() error {
 	if n.Content == "" {
 		return fmt.Errorf("content is required")
 	}
-	if len(n.Content) > 10000 {
-		return fmt.Errorf("content too long (max 10000 characters)")
+	if err := CheckContentLength(n.Content); err != nil {
+		return err
 	}
 	if n.Title != nil && len(*n.Title) > 500 {
 		return fmt.Errorf("title too long (max 500 characters)")
@@ -96,13 +177,21 @@ func (n *Note) Validate() error {
 	if n.Version < 1 {
 		return fmt.Errorf("version must be at least 1")
 	}
+	if err := ValidateNoteColor(n.Color); err != nil {
+		return err
+	}
+	if err := ValidateNoteIcon(n.Icon); err != nil {
+		return err
+	}
+	if n.Metadata != nil {
+		return n.Metadata.Validate()
+	}
 	return nil
 }
 
 // ExtractHashtags extracts hashtags from the note content
 func (n *Note) ExtractHashtags() []string {
-	// Regular expression to match hashtags (#word)
-	hashtagRegex := regexp.MustCompile(`#\w+`)
+	// Match hashtags (#word), including nested ones (#word/child)
 	matches := hashtagRegex.FindAllString(n.Content, -1)
 
 	// Remove duplicates and ensure they start with #
@@ -161,7 +250,24 @@ type NoteList struct {
 // CreateNoteRequest represents the request to create a new note
 type CreateNoteRequest struct {
 	Title   string `json:"title,omitempty" validate:"max=500"`
-	Content string `json:"content" validate:"required,max=10000"`
+	Content string `json:"content" validate:"required,maxcontent"`
+	Private bool   `json:"private,omitempty"`
+	// Metadata describes where the note was written, such as its location
+	// and source app
+	Metadata *NoteMetadata `json:"metadata,omitempty"`
+	// Properties are values of the user's custom properties by name
+	Properties map[string]any `json:"properties,omitempty"`
+	// Color is a color label, one of NoteColors
+	Color string `json:"color,omitempty"`
+	// Icon is an emoji shown with the note
+	Icon string `json:"icon,omitempty"`
+	// NotebookID is the notebook to create the note in, the user's default
+	// notebook when empty
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty"`
+	// CreatedAt preserves the original creation time of imported notes
+	CreatedAt *time.Time `json:"-"`
+	// ID preserves the identity of notes migrated from another deployment
+	ID *uuid.UUID `json:"-"`
 }
 
 // ToNote converts CreateNoteRequest to Note model
@@ -169,8 +275,9 @@ func (r *CreateNoteRequest) ToNote(userID uuid.UUID) *Note {
 	var title *string
 	if r.Title != "" {
 		title = &r.Title
-	} else {
-		// Generate title from first line of content
+	} else if !r.Private {
+		// Generate title from first line of content (titles are stored in
+		// plaintext, so private notes never derive one from their content)
 		lines := strings.Split(r.Content, "\n")
 		if len(lines) > 0 && len(lines[0]) > 0 {
 			firstLine := lines[0]
@@ -182,22 +289,48 @@ func (r *CreateNoteRequest) ToNote(userID uuid.UUID) *Note {
 	}
 
 	now := time.Now()
+	createdAt := now
+	if r.CreatedAt != nil {
+		createdAt = *r.CreatedAt
+	}
+	id := uuid.New()
+	if r.ID != nil {
+		id = *r.ID
+	}
+	var notebookID uuid.UUID
+	if r.NotebookID != nil {
+		notebookID = *r.NotebookID
+	}
 	return &Note{
-		ID:        uuid.New(),
-		UserID:    userID,
-		Title:     title,
-		Content:   r.Content,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Version:   1,
+		ID:         id,
+		UserID:     userID,
+		Title:      title,
+		Content:    r.Content,
+		CreatedAt:  createdAt,
+		UpdatedAt:  now,
+		Version:    1,
+		IsPrivate:  r.Private,
+		Metadata:   r.Metadata.Normalize(),
+		Properties: r.Properties,
+		Color:      NormalizeNoteColor(r.Color),
+		Icon:       strings.TrimSpace(r.Icon),
+		NotebookID: notebookID,
 	}
 }
 
 // UpdateNoteRequest represents the request to update a note
 type UpdateNoteRequest struct {
 	Title   *string `json:"title,omitempty" validate:"omitempty,max=500"`
-	Content *string `json:"content,omitempty" validate:"omitempty,max=10000"`
+	Content *string `json:"content,omitempty" validate:"omitempty,maxcontent"`
 	Version *int    `json:"version,omitempty" validate:"omitempty,min=1"`
+	Private *bool   `json:"private,omitempty"`
+	// Properties sets property values by name; null removes a value
+	Properties map[string]any `json:"properties,omitempty"`
+	// Color and Icon replace the note's; empty strings remove them
+	Color *string `json:"color,omitempty"`
+	Icon  *string `json:"icon,omitempty"`
+	// NotebookID moves the note to another notebook
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty"`
 }
 
 // ApplyUpdates applies the updates to the note
@@ -209,12 +342,44 @@ func (r *UpdateNoteRequest) ApplyUpdates(note *Note) bool {
 		updated = true
 	}
 
+	if r.Private != nil && *r.Private != note.IsPrivate {
+		note.IsPrivate = *r.Private
+		updated = true
+	}
+
+	if r.Color != nil {
+		note.Color = NormalizeNoteColor(*r.Color)
+		updated = true
+	}
+
+	if r.NotebookID != nil && *r.NotebookID != note.NotebookID {
+		note.NotebookID = *r.NotebookID
+		updated = true
+	}
+
+	if r.Icon != nil {
+		note.Icon = strings.TrimSpace(*r.Icon)
+		updated = true
+	}
+
+	if len(r.Properties) > 0 {
+		properties := make(NoteProperties, len(note.Properties)+len(r.Properties))
+		for name, value := range note.Properties {
+			properties[name] = value
+		}
+		for name, value := range r.Properties {
+			properties[name] = value
+		}
+		note.Properties = properties
+		updated = true
+	}
+
 	if r.Content != nil {
 		note.Content = *r.Content
 		updated = true
 
 		// Auto-update title if not explicitly provided
-		if r.Title == nil {
+		if r.Title == nil && !note.IsPrivate {
 			lines := strings.Split(*r.Content, "\n")
 			if len(lines) > 0 && len(lines[0]) > 0 {
 				firstLine := lines[0]
@@ -233,6 +398,62 @@ func (r *UpdateNoteRequest) ApplyUpdates(note *Note) bool {
 	return updated
 }
 
+// DuplicateTitlePrefix starts the titles of duplicates made with PrefixTitle
+const DuplicateTitlePrefix = "Copy of "
+
+// DuplicateNoteRequest represents the request to duplicate a note
+type DuplicateNoteRequest struct {
+	// PrefixTitle starts the copy's title with DuplicateTitlePrefix
+	PrefixTitle bool `json:"prefix_title,omitempty"`
+	// NotebookID is the notebook of the copy, the original's when empty
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty"`
+}
+
+// DuplicateRequest returns the request creating a copy of the note. The
+// copy keeps the content, title, privacy, metadata and style; properties
+// are only kept when keepProperties is set, as they follow the schema of
+// the note's author.
+func (n *Note) DuplicateRequest(request *DuplicateNoteRequest, keepProperties bool) *CreateNoteRequest {
+	duplicate := &CreateNoteRequest{
+		Content:    n.Content,
+		Private:    n.IsPrivate,
+		Metadata:   n.Metadata,
+		Color:      n.Color,
+		Icon:       n.Icon,
+		NotebookID: request.NotebookID,
+	}
+	if n.Title != nil {
+		duplicate.Title = *n.Title
+		if request.PrefixTitle {
+			duplicate.Title = DuplicateTitlePrefix + duplicate.Title
+			// The prefix may push a long title over the limit
+			for len(duplicate.Title) > 500 {
+				_, size := utf8.DecodeLastRuneInString(duplicate.Title)
+				duplicate.Title = duplicate.Title[:len(duplicate.Title)-size]
+			}
+		}
+	}
+	if keepProperties {
+		duplicate.Properties = n.Properties
+	}
+	return duplicate
+}
+
+// InsertContentRequest represents the request to append or prepend text
+// to a note
+type InsertContentRequest struct {
+	Content string `json:"content" validate:"required,maxcontent"`
+}
+
+// InsertContent returns content with text added on a line of its own, at
+// the start when prepend is set and at the end otherwise
+func InsertContent(content, text string, prepend bool) string {
+	if prepend {
+		return strings.TrimRight(text, "\n") + "\n" + content
+	}
+	return strings.TrimRight(content, "\n") + "\n" + text
+}
+
 // SearchNotesRequest represents the request to search notes
 type SearchNotesRequest struct {
 	Query    string   `json:"query,omitempty" form:"query"`
@@ -241,8 +462,29 @@ type SearchNotesRequest struct {
 	Offset   int      `json:"offset,omitempty" form:"offset" validate:"min=0"`
 	OrderBy  string   `json:"order_by,omitempty" form:"order_by" validate:"oneof=created_at updated_at title"`
 	OrderDir string   `json:"order_dir,omitempty" form:"order_dir" validate:"oneof=asc desc"`
+	// Properties are filters on property values, all of which must match
+	Properties []PropertyFilter `json:"properties,omitempty"`
+	// SortProperty orders notes by a property instead of OrderBy, in
+	// OrderDir with notes without a value last
+	SortProperty string `json:"sort_property,omitempty" form:"sort_property"`
+	// NotebookID limits results to the notes of one notebook
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty" form:"notebook_id"`
+	// UpdatedBefore limits results to notes last updated before the time
+	UpdatedBefore *time.Time `json:"updated_before,omitempty"`
+	// OwnedOnly limits results to the user's own notes, leaving out the
+	// notes other members wrote in shared notebooks
+	OwnedOnly bool `json:"-"`
 }
 
+// Stale note limits
+const (
+	// DefaultStaleDays is how many days a note goes without updates before
+	// it is stale
+	DefaultStaleDays = 90
+	// MaxStaleDays is the largest number of days accepted for stale notes
+	MaxStaleDays = 3650
+)
+
 // Validate validates the search request
 func (r *SearchNotesRequest) Validate() error {
 	if r.Limit == 0 {
@@ -338,6 +580,15 @@ type SyncResponse struct {
 	ServerTime string           `json:"server_time"`
 	Conflicts  []NoteConflict   `json:"conflicts,omitempty"`
 	Metadata   SyncMetadata     `json:"metadata"`
+	// Deleted lists the IDs of the notes deleted since the last sync
+	Deleted []uuid.UUID `json:"deleted"`
+	// Cursor is passed as cursor to the next sync to get the changes since
+	// this one. Syncs after a last_seq return LastSeq instead.
+	Cursor string `json:"cursor,omitempty"`
+	// Events lists the note changes after last_seq in order, and LastSeq is
+	// the last_seq to pass to the next sync
+	Events  []ChangeRecord `json:"events,omitempty"`
+	LastSeq *int64         `json:"last_seq,omitempty"`
 }
 
 // SyncMetadata contains metadata about sync operations
@@ -374,6 +625,8 @@ type PrettifyNoteResponse struct {
 	NoteResponse
 	SuggestedTags []string `json:"suggested_tags"`
 	ChangesMade   []string `json:"changes_made"`
+	Unchanged     bool     `json:"unchanged,omitempty"`    // the LLM kept losing content, so the note was left as it was
+	LostContent   []string `json:"lost_content,omitempty"` // URLs, hashtags and code blocks the prettified content lost
 }
 
 // APIResponse represents the standard API response format
@@ -388,6 +641,24 @@ type APIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	// Position and Length locate the error within user input such as a search query
+	Position *int  `json:"position,omitempty"`
+	Length   int   `json:"length,omitempty"`
+	// Confirmation is set when the operation needs a confirmation code
+	Confirmation *Confirmation `json:"confirmation,omitempty"`
+	// Current is the server copy of a note a conditional update did not match
+	Current *NoteResponse `json:"current,omitempty"`
+	// Fields lists the invalid fields of a request that failed validation
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes an invalid field of a request
+type FieldError struct {
+	// Field is the path of the field in the request JSON, such as
+	// "content" or "updates[0].updates.title"
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 // NewAPIResponse creates a successful API response
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Note feed kinds
+const (
+	// NoteFeedRecent feeds recently created notes
+	NoteFeedRecent = "recent"
+	// NoteFeedPublished feeds recently published notes, linking to their
+	// public pages
+	NoteFeedPublished = "published"
+)
+
+// NoteFeedItemLimit is the number of notes a feed holds
+const NoteFeedItemLimit = 50
+
+// Note feed formats, the last segment of feed paths
+const (
+	NoteFeedRSS  = "rss"
+	NoteFeedAtom = "atom"
+)
+
+// NoteFeed is an RSS and Atom feed of a user's recent notes, or of the notes
+// of one notebook, for feed readers and automations. Readers authenticate
+// with the token in the feed URL; only its hash is stored.
+type NoteFeed struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Name   string    `json:"name" db:"name"`
+	Kind   string    `json:"kind" db:"kind"`
+	// NotebookID limits the feed to a notebook; without one it holds the
+	// user's own notes
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty" db:"notebook_id"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreatedNoteFeed is a new feed together with its token and URL paths,
+// which are returned only once
+type CreatedNoteFeed struct {
+	NoteFeed
+	Token    string `json:"token"`
+	RSSPath  string `json:"rss_path"`
+	AtomPath string `json:"atom_path"`
+}
+
+// NoteFeedPath returns the path a feed is served at in a format
+func NoteFeedPath(token, format string) string {
+	return "/feeds/" + token + "/" + format
+}
+
+// CreateNoteFeedRequest represents the request to create a feed
+type CreateNoteFeedRequest struct {
+	Name       string     `json:"name" validate:"required,max=100"`
+	Kind       string     `json:"kind,omitempty"`
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty"`
+}
+
+// Validate validates and normalizes the request. The kind defaults to
+// NoteFeedRecent.
+func (r *CreateNoteFeedRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 100 {
+		return fmt.Errorf("name too long (max 100 characters)")
+	}
+
+	r.Kind = strings.ToLower(strings.TrimSpace(r.Kind))
+	switch r.Kind {
+	case "":
+		r.Kind = NoteFeedRecent
+	case NoteFeedRecent, NoteFeedPublished:
+	default:
+		return fmt.Errorf("kind must be %s or %s", NoteFeedRecent, NoteFeedPublished)
+	}
+	return nil
+}
+
+// NoteFeedItem is a note in a feed, with its content rendered as HTML
+type NoteFeedItem struct {
+	NoteID uuid.UUID
+	Title  string
+	HTML   string
+	// Path is the public page of published notes, empty for others
+	Path string
+	// PublishedAt is when the note was created, or published for feeds of
+	// published notes
+	PublishedAt time.Time
+	UpdatedAt   time.Time
+}
+
+// NoteFeedContent is a feed with its notes, newest first
+type NoteFeedContent struct {
+	Feed  NoteFeed
+	Items []NoteFeedItem
+}
+
+// Updated returns when the feed last changed: the latest update of its
+// notes, or its creation when it has none
+func (c *NoteFeedContent) Updated() time.Time {
+	updated := c.Feed.CreatedAt
+	for _, item := range c.Items {
+		if item.UpdatedAt.After(updated) {
+			updated = item.UpdatedAt
+		}
+	}
+	return updated
+}
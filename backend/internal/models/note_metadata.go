@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Note metadata limits
+const (
+	MaxNoteSourceLength    = 50
+	MaxNoteDeviceLength    = 100
+	MaxNoteSourceURLLength = 2048
+)
+
+// NoteMetadata describes where a note was written: the location, the app it
+// came from, such as web or telegram, and the device. It is set when the note
+// is created and, unlike the content of private notes, stored unencrypted.
+type NoteMetadata struct {
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Device    string   `json:"device,omitempty"`
+	// SourceURL is the page the note was clipped from
+	SourceURL string `json:"source_url,omitempty"`
+}
+
+// Normalize trims the metadata and lowercases the source, so sources are
+// matched regardless of case. It returns nil for empty metadata.
+func (m *NoteMetadata) Normalize() *NoteMetadata {
+	if m == nil {
+		return nil
+	}
+	normalized := *m
+	normalized.Source = strings.ToLower(strings.TrimSpace(m.Source))
+	normalized.Device = strings.TrimSpace(m.Device)
+	normalized.SourceURL = strings.TrimSpace(m.SourceURL)
+	if normalized == (NoteMetadata{}) {
+		return nil
+	}
+	return &normalized
+}
+
+// Validate validates the metadata. Coordinates are set together.
+func (m *NoteMetadata) Validate() error {
+	if (m.Latitude == nil) != (m.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+	if m.Latitude != nil && (*m.Latitude < -90 || *m.Latitude > 90) {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if m.Longitude != nil && (*m.Longitude < -180 || *m.Longitude > 180) {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	if len(m.Source) > MaxNoteSourceLength {
+		return fmt.Errorf("source too long (max %d characters)", MaxNoteSourceLength)
+	}
+	if len(m.Device) > MaxNoteDeviceLength {
+		return fmt.Errorf("device too long (max %d characters)", MaxNoteDeviceLength)
+	}
+	if len(m.SourceURL) > MaxNoteSourceURLLength {
+		return fmt.Errorf("source URL too long (max %d characters)", MaxNoteSourceURLLength)
+	}
+	return nil
+}
+
+// Scan implements the sql.Scanner interface for the JSON metadata column
+func (m *NoteMetadata) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("cannot scan %T into NoteMetadata", value)
+	}
+}
+
+// Value implements the driver.Valuer interface for the JSON metadata column
+func (m *NoteMetadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	// Strings, as lib/pq sends []byte as bytea
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestNoteMetadataNormalize(t *testing.T) {
+	if (&NoteMetadata{Source: "  ", Device: " "}).Normalize() != nil {
+		t.Error("Expected empty metadata to normalize to nil")
+	}
+
+	metadata := (&NoteMetadata{Source: " Telegram ", Device: " Pixel 8 "}).Normalize()
+	if metadata == nil || metadata.Source != "telegram" || metadata.Device != "Pixel 8" {
+		t.Errorf("Unexpected normalized metadata %+v", metadata)
+	}
+}
+
+func TestNoteMetadataValidate(t *testing.T) {
+	coordinate := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		metadata NoteMetadata
+		valid    bool
+	}{
+		{"location", NoteMetadata{Latitude: coordinate(52.52), Longitude: coordinate(13.405)}, true},
+		{"source only", NoteMetadata{Source: "web"}, true},
+		{"latitude only", NoteMetadata{Latitude: coordinate(52.52)}, false},
+		{"latitude out of range", NoteMetadata{Latitude: coordinate(91), Longitude: coordinate(0)}, false},
+		{"longitude out of range", NoteMetadata{Latitude: coordinate(0), Longitude: coordinate(-181)}, false},
+		{"source too long", NoteMetadata{Source: string(make([]byte, MaxNoteSourceLength+1))}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.metadata.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
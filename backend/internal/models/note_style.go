@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NoteColors are the color labels notes can carry
+var NoteColors = []string{"red", "orange", "yellow", "green", "teal", "blue", "purple", "pink", "brown", "gray"}
+
+// MaxNoteIconLength is the length in bytes of a note icon, enough for emoji
+// built from several code points such as flags and skin tones
+const MaxNoteIconLength = 32
+
+// NormalizeNoteColor returns a color label in stored form
+func NormalizeNoteColor(color string) string {
+	return strings.ToLower(strings.TrimSpace(color))
+}
+
+// ValidateNoteColor checks that color is empty or one of NoteColors
+func ValidateNoteColor(color string) error {
+	if color != "" && !slices.Contains(NoteColors, color) {
+		return fmt.Errorf("color must be one of %s", strings.Join(NoteColors, ", "))
+	}
+	return nil
+}
+
+// ValidateNoteIcon checks that icon is empty or a single emoji: symbols
+// with their modifiers and joiners, without letters or spaces
+func ValidateNoteIcon(icon string) error {
+	if icon == "" {
+		return nil
+	}
+	if len(icon) > MaxNoteIconLength || utf8.RuneCountInString(icon) > 8 {
+		return fmt.Errorf("icon must be a single emoji")
+	}
+
+	symbol := false
+	for _, r := range icon {
+		if r == utf8.RuneError || unicode.IsLetter(r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return fmt.Errorf("icon must be a single emoji")
+		}
+		if unicode.Is(unicode.So, r) {
+			symbol = true
+		}
+	}
+	if !symbol {
+		return fmt.Errorf("icon must be a single emoji")
+	}
+	return nil
+}
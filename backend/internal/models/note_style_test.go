@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateNoteColor(t *testing.T) {
+	for _, color := range []string{"", "red", "gray"} {
+		if err := ValidateNoteColor(color); err != nil {
+			t.Errorf("ValidateNoteColor(%q) = %v, want nil", color, err)
+		}
+	}
+	for _, color := range []string{"Red", "magenta", "#ff0000"} {
+		if ValidateNoteColor(color) == nil {
+			t.Errorf("ValidateNoteColor(%q) = nil, want error", color)
+		}
+	}
+	if got := NormalizeNoteColor(" Blue "); got != "blue" {
+		t.Errorf("NormalizeNoteColor() = %q, want blue", got)
+	}
+}
+
+func TestValidateNoteIcon(t *testing.T) {
+	tests := []struct {
+		icon  string
+		valid bool
+	}{
+		{"", true},
+		{"📌", true},
+		{"❤️", true},
+		{"👍🏽", true},
+		{"👩‍💻", true},
+		{"🇮🇩", true},
+		{"a", false},
+		{"📌 x", false},
+		{"!", false},
+		{"📌📌📌📌📌📌📌📌📌", false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateNoteIcon(tt.icon); (err == nil) != tt.valid {
+			t.Errorf("ValidateNoteIcon(%q) = %v, want valid %v", tt.icon, err, tt.valid)
+		}
+	}
+}
+
+func TestUpdateNoteRequestStyle(t *testing.T) {
+	note := &Note{UserID: uuid.New(), Content: "x", Version: 1, Color: "red", Icon: "📌"}
+	color, icon := "Green", ""
+	if !(&UpdateNoteRequest{Color: &color, Icon: &icon}).ApplyUpdates(note) {
+		t.Fatal("Expected the update to change the note")
+	}
+	if note.Color != "green" || note.Icon != "" {
+		t.Errorf("Unexpected style color %q icon %q", note.Color, note.Icon)
+	}
+	if err := note.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
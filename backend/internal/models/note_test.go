@@ -2,7 +2,11 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/google/uuid"
 )
 
 func TestLLMNoteResponse(t *testing.T) {
@@ -39,3 +43,32 @@ func TestSemanticSearchRequest(t *testing.T) {
 		t.Errorf("Expected query 'test query', got '%s'", req.Query)
 	}
 }
+
+func TestMaxContentLength(t *testing.T) {
+	defer SetMaxContentLength(DefaultMaxContentLength)
+
+	note := Note{UserID: uuid.New(), Content: strings.Repeat("a", DefaultMaxContentLength+1), Version: 1}
+	if err := note.Validate(); !errors.Is(err, ErrContentTooLong) {
+		t.Errorf("Expected ErrContentTooLong over the default limit, got %v", err)
+	}
+
+	// The limit counts characters, not bytes
+	note.Content = strings.Repeat("é", DefaultMaxContentLength)
+	if err := note.Validate(); err != nil {
+		t.Errorf("Expected multibyte content at the limit to be valid, got %v", err)
+	}
+
+	note.Content = strings.Repeat("a", DefaultMaxContentLength+1)
+	SetMaxContentLength(1 << 20)
+	if err := note.Validate(); err != nil {
+		t.Errorf("Expected the note within the raised limit, got %v", err)
+	}
+	if MaxContentLength() != 1<<20 {
+		t.Errorf("MaxContentLength() = %d", MaxContentLength())
+	}
+
+	SetMaxContentLength(0)
+	if MaxContentLength() != DefaultMaxContentLength {
+		t.Errorf("Expected the default limit for 0, got %d", MaxContentLength())
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notebook limits
+const (
+	// MaxNotebooks is the number of notebooks a user may have
+	MaxNotebooks = 100
+	// MaxNotebookNameLength is the length of a notebook name
+	MaxNotebookNameLength = 100
+)
+
+// DefaultNotebookName names the notebook created for every user, which
+// holds notes created without a notebook
+const DefaultNotebookName = "Personal"
+
+// Notebook is a workspace grouping notes, such as Personal, Work or a
+// project. Every note belongs to exactly one notebook; each user has one
+// default notebook, which cannot be deleted.
+type Notebook struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	IsDefault bool      `json:"is_default" db:"is_default"`
+	// OrganizationID is the organization sharing the notebook, nil for
+	// personal notebooks
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty" db:"organization_id"`
+	// NoteCount is the number of notes in the notebook
+	NoteCount int       `json:"note_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NotebookRequest represents the request to create or rename a notebook
+type NotebookRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// Validate validates and trims the request
+func (r *NotebookRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > MaxNotebookNameLength {
+		return fmt.Errorf("name too long (max %d characters)", MaxNotebookNameLength)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	// NotificationTypeSearchMatch is sent when a note starts matching a search subscription
+	NotificationTypeSearchMatch = "search_match"
+	// NotificationTypeSecurityAlert is sent when unusual account activity is detected
+	NotificationTypeSecurityAlert = "security_alert"
+	// NotificationTypeTagRule is sent when a note matches a tag notification rule
+	NotificationTypeTagRule = "tag_rule"
+	// NotificationTypeMention is sent when a user is mentioned in a note comment
+	NotificationTypeMention = "comment_mention"
+)
+
+// Notification represents an in-app notification for a user
+type Notification struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	UserID    uuid.UUID       `json:"user_id" db:"user_id"`
+	Type      string          `json:"type" db:"type"`
+	Title     string          `json:"title" db:"title"`
+	Body      string          `json:"body" db:"body"`
+	Data      json.RawMessage `json:"data,omitempty" db:"data"`
+	ReadAt    *time.Time      `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// NotificationList represents a paginated list of notifications
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	Total         int            `json:"total"`
+	Unread        int            `json:"unread"`
+	Limit         int            `json:"limit"`
+	Offset        int            `json:"offset"`
+	HasMore       bool           `json:"has_more"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events a notification rule can notify about
+const (
+	// RuleEventNoteCreated is a note of the rule's owner created with the tag
+	RuleEventNoteCreated = "note_created"
+	// RuleEventNoteShared is a note with the tag that another member puts in
+	// a notebook shared with the rule's owner
+	RuleEventNoteShared = "note_shared"
+)
+
+// NotificationRuleEvents lists every event a rule can notify about
+var NotificationRuleEvents = []string{RuleEventNoteCreated, RuleEventNoteShared}
+
+// Channels notification rules deliver through
+const (
+	NotificationChannelInApp   = "in_app"
+	NotificationChannelEmail   = "email"
+	NotificationChannelWebhook = "webhook"
+)
+
+// NotificationChannels lists every channel a rule can deliver through
+var NotificationChannels = []string{NotificationChannelInApp, NotificationChannelEmail, NotificationChannelWebhook}
+
+// MaxNotificationRules is the number of notification rules a user may have
+const MaxNotificationRules = 50
+
+// NotificationRule notifies its owner when a note with its tag is created
+// or shared with them. Each note notifies a rule at most once.
+type NotificationRule struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Tag       string    `json:"tag" db:"tag"`
+	Events    []string  `json:"events" db:"events"`
+	Channels  []string  `json:"channels" db:"channels"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Matches reports whether the rule notifies about event for a note with tags
+func (r *NotificationRule) Matches(event string, tags []string) bool {
+	if !slices.Contains(r.Events, event) {
+		return false
+	}
+	for _, tag := range tags {
+		if strings.EqualFold(tag, r.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateNotificationRuleRequest represents the request to create a
+// notification rule
+type CreateNotificationRuleRequest struct {
+	Tag      string   `json:"tag" validate:"required,max=100"`
+	Events   []string `json:"events,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+// Validate validates and normalizes the request. The tag is lowercased
+// with a leading #; without events the rule notifies about all of them, and
+// without channels it notifies in the app.
+func (r *CreateNotificationRuleRequest) Validate() error {
+	r.Tag = strings.ToLower(strings.TrimSpace(r.Tag))
+	if r.Tag != "" && !strings.HasPrefix(r.Tag, "#") {
+		r.Tag = "#" + r.Tag
+	}
+	if r.Tag == "" || r.Tag == "#" {
+		return fmt.Errorf("tag is required")
+	}
+	if err := ValidateTags([]string{r.Tag}); err != nil {
+		return err
+	}
+
+	events, err := ruleOptions(r.Events, NotificationRuleEvents, "event")
+	if err != nil {
+		return err
+	}
+	r.Events = events
+	if len(r.Events) == 0 {
+		r.Events = append([]string(nil), NotificationRuleEvents...)
+	}
+
+	channels, err := ruleOptions(r.Channels, NotificationChannels, "channel")
+	if err != nil {
+		return err
+	}
+	r.Channels = channels
+	if len(r.Channels) == 0 {
+		r.Channels = []string{NotificationChannelInApp}
+	}
+	return nil
+}
+
+// ruleOptions checks that values are among known, removing duplicates
+func ruleOptions(values, known []string, kind string) ([]string, error) {
+	options := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !slices.Contains(known, value) {
+			return nil, fmt.Errorf("unknown %s %q: %ss must be among %s", kind, value, kind, strings.Join(known, ", "))
+		}
+		if !slices.Contains(options, value) {
+			options = append(options, value)
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization member roles. Admins manage members, invitations and shared
+// notebooks; editors also write notes in shared notebooks; viewers read them.
+const (
+	OrgRoleAdmin  = "admin"
+	OrgRoleEditor = "editor"
+	OrgRoleViewer = "viewer"
+)
+
+// Organization limits
+const (
+	// MaxOrganizationNameLength is the length of an organization name
+	MaxOrganizationNameLength = 100
+	// MaxOrganizationMembers is the number of members and pending
+	// invitations of an organization
+	MaxOrganizationMembers = 50
+	// OrgInvitationTTL is how long an invitation can be accepted
+	OrgInvitationTTL = 7 * 24 * time.Hour
+)
+
+// OrgRoleCanEdit reports whether a role may write notes in the
+// organization's notebooks
+func OrgRoleCanEdit(role string) bool {
+	return role == OrgRoleAdmin || role == OrgRoleEditor
+}
+
+// validOrgRole reports whether role is one of the member roles
+func validOrgRole(role string) bool {
+	return role == OrgRoleAdmin || role == OrgRoleEditor || role == OrgRoleViewer
+}
+
+// Organization is a team sharing notebooks. Its members read the notes of
+// the shared notebooks and, depending on their role, write them.
+type Organization struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	// WatermarkExports requires exports of the notes in the organization's
+	// notebooks to name who exported them and when
+	WatermarkExports bool `json:"watermark_exports" db:"watermark_exports"`
+	// Role is the role of the requesting user
+	Role string `json:"role,omitempty"`
+}
+
+// OrgMember is a user's membership of an organization
+type OrgMember struct {
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role" db:"role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// OrgInvitation invites an email address to join an organization. Only a
+// hash of the token is stored; the token itself is returned once, when the
+// invitation is created, and sent to the invitee by email.
+type OrgInvitation struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Role           string     `json:"role" db:"role"`
+	InvitedBy      *uuid.UUID `json:"invited_by,omitempty" db:"invited_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	Token          string     `json:"token,omitempty"`
+}
+
+// CreateOrganizationRequest represents the request to create an organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// Validate validates and trims the request
+func (r *CreateOrganizationRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > MaxOrganizationNameLength {
+		return fmt.Errorf("name too long (max %d characters)", MaxOrganizationNameLength)
+	}
+	return nil
+}
+
+// UpdateOrganizationRequest represents the request to change an
+// organization. Fields left out are kept.
+type UpdateOrganizationRequest struct {
+	Name             *string `json:"name,omitempty" validate:"omitempty,max=100"`
+	WatermarkExports *bool   `json:"watermark_exports,omitempty"`
+}
+
+// Validate validates and trims the request
+func (r *UpdateOrganizationRequest) Validate() error {
+	if r.Name == nil {
+		return nil
+	}
+	name := strings.TrimSpace(*r.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > MaxOrganizationNameLength {
+		return fmt.Errorf("name too long (max %d characters)", MaxOrganizationNameLength)
+	}
+	r.Name = &name
+	return nil
+}
+
+// InviteMemberRequest represents the request to invite someone to an
+// organization
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
+
+// Validate validates the request and lowercases the email
+func (r *InviteMemberRequest) Validate() error {
+	address, err := mail.ParseAddress(strings.TrimSpace(r.Email))
+	if err != nil {
+		return fmt.Errorf("invalid email address")
+	}
+	r.Email = strings.ToLower(address.Address)
+	if !validOrgRole(r.Role) {
+		return fmt.Errorf("role must be one of admin, editor, viewer")
+	}
+	return nil
+}
+
+// UpdateMemberRequest represents the request to change a member's role
+type UpdateMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
+
+// Validate validates the request
+func (r *UpdateMemberRequest) Validate() error {
+	if !validOrgRole(r.Role) {
+		return fmt.Errorf("role must be one of admin, editor, viewer")
+	}
+	return nil
+}
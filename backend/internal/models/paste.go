@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MaxPasteLength is the longest pasted HTML or text accepted, in bytes
+const MaxPasteLength = 512 * 1024
+
+// pasteInvisibleChars are removed from pasted text: zero-width spaces and
+// joiners, word joiners, byte order marks and soft hyphens. Line endings,
+// non-breaking spaces and Unicode line separators are normalized.
+var pasteInvisibleChars = strings.NewReplacer(
+	"\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "", "\u00ad", "",
+	"\r\n", "\n", "\r", "\n", "\u00a0", " ", "\u202f", " ", "\u2028", "\n", "\u2029", "\n\n",
+)
+
+var (
+	// pasteBulletRegex matches list bullets that are not Markdown
+	pasteBulletRegex = regexp.MustCompile(`(?m)^([ \t]*)[\x{2022}\x{25e6}\x{25aa}\x{25ab}\x{25cf}\x{25cb}\x{25a0}\x{25a1}\x{2023}\x{2043}\x{00b7}][ \t]+`)
+	// pasteBlankLinesRegex matches runs of blank lines
+	pasteBlankLinesRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// NormalizePasteRequest is rich text pasted into a note. Clipboards hold
+// both HTML and plain text; HTML is used when present.
+type NormalizePasteRequest struct {
+	HTML string `json:"html,omitempty"`
+	Text string `json:"text,omitempty"`
+	// BaseURL resolves relative links of HTML copied from a web page
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+// NormalizedPaste is pasted content as Markdown ready to create a note with
+type NormalizedPaste struct {
+	Content string `json:"content"`
+}
+
+// Validate validates the request
+func (r *NormalizePasteRequest) Validate() error {
+	if strings.TrimSpace(r.HTML) == "" && strings.TrimSpace(r.Text) == "" {
+		return fmt.Errorf("html or text is required")
+	}
+	if len(r.HTML) > MaxPasteLength || len(r.Text) > MaxPasteLength {
+		return fmt.Errorf("pasted content too long (max %d KB)", MaxPasteLength/1024)
+	}
+	if r.BaseURL != "" {
+		parsed, err := url.Parse(r.BaseURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("base_url must be an http or https URL")
+		}
+	}
+	return nil
+}
+
+// NormalizePastedText cleans pasted text: line endings and non-breaking
+// spaces are normalized, invisible characters removed, bullets turned into
+// Markdown list items, trailing spaces trimmed and runs of blank lines
+// collapsed
+func NormalizePastedText(text string) string {
+	text = pasteInvisibleChars.Replace(text)
+	text = pasteBulletRegex.ReplaceAllString(text, "$1- ")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = strings.Join(lines, "\n")
+	return strings.TrimSpace(pasteBlankLinesRegex.ReplaceAllString(text, "\n\n"))
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizePasteRequestValidate(t *testing.T) {
+	valid := NormalizePasteRequest{HTML: "<p>Hi</p>", BaseURL: "https://example.com/post"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+	for _, invalid := range []NormalizePasteRequest{
+		{},
+		{Text: "  "},
+		{Text: strings.Repeat("a", MaxPasteLength+1)},
+		{HTML: "<p>Hi</p>", BaseURL: "javascript:alert(1)"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestNormalizePastedText(t *testing.T) {
+	pasted := "\ufeffShopping list \r\n\r\n\r\n\u2022 milk\u200b\r\n  \u25e6 oat\r\n\u00b7 eggs\t\r\nco\u00adoperate\n\n\n"
+	want := "Shopping list\n\n- milk\n  - oat\n- eggs\ncooperate"
+	if got := NormalizePastedText(pasted); got != want {
+		t.Errorf("NormalizePastedText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// checklistItemRegex matches a Markdown task list item: "- [ ] task",
+// "* [x] task" or "1. [X] task"
+var checklistItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[([ xX])\](?:\s|$)`)
+
+// ChecklistCounts is the number of checked and unchecked checklist items
+type ChecklistCounts struct {
+	Checked   int `json:"checked"`
+	Unchecked int `json:"unchecked"`
+}
+
+// Total returns the number of checklist items
+func (c ChecklistCounts) Total() int {
+	return c.Checked + c.Unchecked
+}
+
+// ChecklistItem is a checklist item of a note
+type ChecklistItem struct {
+	// Line is the 1-based number of the item's line in the note content
+	Line    int
+	Text    string
+	Checked bool
+}
+
+// CountChecklist counts the checklist items in the note content
+func (n *Note) CountChecklist() ChecklistCounts {
+	var counts ChecklistCounts
+	for _, item := range n.ChecklistItems() {
+		if item.Checked {
+			counts.Checked++
+		} else {
+			counts.Unchecked++
+		}
+	}
+	return counts
+}
+
+// ChecklistItems returns the checklist items in the note content. Items
+// inside fenced code blocks are examples rather than tasks and are skipped.
+func (n *Note) ChecklistItems() []ChecklistItem {
+	var items []ChecklistItem
+	fence := ""
+	for i, line := range strings.Split(n.Content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		match := checklistItemRegex.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		items = append(items, ChecklistItem{
+			Line:    i + 1,
+			Text:    strings.TrimSpace(line[match[1]:]),
+			Checked: line[match[2]] != ' ',
+		})
+	}
+	return items
+}
+
+// SetChecklistItem checks or unchecks the checklist item on a 1-based line of
+// the note content, leaving the rest of the content untouched. It returns
+// false when the line holds no checklist item.
+func (n *Note) SetChecklistItem(line int, checked bool) bool {
+	for _, item := range n.ChecklistItems() {
+		if item.Line != line {
+			continue
+		}
+		lines := strings.Split(n.Content, "\n")
+		match := checklistItemRegex.FindStringSubmatchIndex(lines[line-1])
+		mark := " "
+		if checked {
+			mark = "x"
+		}
+		lines[line-1] = lines[line-1][:match[2]] + mark + lines[line-1][match[3]:]
+		n.Content = strings.Join(lines, "\n")
+		return true
+	}
+	return false
+}
+
+// NoteProgress is the checklist progress of a single note
+type NoteProgress struct {
+	NoteID    uuid.UUID `json:"note_id"`
+	Title     string    `json:"title"`
+	Checked   int       `json:"checked"`
+	Unchecked int       `json:"unchecked"`
+	UpdatedAt time.Time `json:"updated_at"`
+	IsPrivate bool      `json:"is_private"`
+}
+
+// TagProgress rolls up the checklist progress of every note with a tag
+type TagProgress struct {
+	Tag       string `json:"tag"`
+	Checked   int    `json:"checked"`
+	Unchecked int    `json:"unchecked"`
+	Total     int    `json:"total"`
+	// PercentComplete is the share of checked items, 0 when there are none
+	PercentComplete float64 `json:"percent_complete"`
+	// Notes lists the notes that have checklist items, most recently updated first
+	Notes []NoteProgress `json:"notes"`
+	// LockedNotes counts private notes that could not be read without the encryption key
+	LockedNotes int `json:"locked_notes,omitempty"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "testing"
+
+func TestCountChecklist(t *testing.T) {
+	note := &Note{Content: "# Launch #projectX\n" +
+		"- [x] Write spec\n" +
+		"  * [ ] Review spec\n" +
+		"1. [X] Book venue\n" +
+		"2) [ ] Send invites\n" +
+		"- [] not an item\n" +
+		"- [x]not an item either\n" +
+		"Some text with - [ ] in the middle\n" +
+		"```\n- [ ] example inside a code block\n```\n" +
+		"+ [ ]"}
+
+	counts := note.CountChecklist()
+	if counts.Checked != 2 || counts.Unchecked != 3 {
+		t.Errorf("Expected 2 checked and 3 unchecked, got %+v", counts)
+	}
+	if counts.Total() != 5 {
+		t.Errorf("Expected 5 items, got %d", counts.Total())
+	}
+}
+
+func TestCountChecklistNone(t *testing.T) {
+	note := &Note{Content: "No tasks here, just [x] marks in text"}
+	if counts := note.CountChecklist(); counts.Total() != 0 {
+		t.Errorf("Expected no items, got %+v", counts)
+	}
+}
+
+func TestChecklistItems(t *testing.T) {
+	note := &Note{Content: "# Launch\n- [x] Write spec\n```\n- [ ] example\n```\n  * [ ]  Review spec  \n1. [X] Book venue"}
+
+	items := note.ChecklistItems()
+	want := []ChecklistItem{
+		{Line: 2, Text: "Write spec", Checked: true},
+		{Line: 6, Text: "Review spec"},
+		{Line: 7, Text: "Book venue", Checked: true},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d items, got %+v", len(want), items)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("Item %d: expected %+v, got %+v", i, want[i], items[i])
+		}
+	}
+}
+
+func TestSetChecklistItem(t *testing.T) {
+	note := &Note{Content: "- [ ] Write spec\r\n```\n- [ ] example\n```\n1. [X] Book venue"}
+
+	if !note.SetChecklistItem(1, true) || !note.SetChecklistItem(5, false) {
+		t.Fatal("Expected both items to be set")
+	}
+	if want := "- [x] Write spec\r\n```\n- [ ] example\n```\n1. [ ] Book venue"; note.Content != want {
+		t.Errorf("Expected %q, got %q", want, note.Content)
+	}
+
+	// Lines without an item, including examples in code blocks, are left alone
+	for _, line := range []int{0, 2, 3, 6} {
+		if note.SetChecklistItem(line, true) {
+			t.Errorf("Expected line %d not to be set", line)
+		}
+	}
+}
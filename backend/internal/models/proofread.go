@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fields of a note a proofread changes
+const (
+	ProofreadFieldTitle   = "title"
+	ProofreadFieldContent = "content"
+)
+
+// ProofreadChange is a line a proofread corrected
+type ProofreadChange struct {
+	// Field is ProofreadFieldTitle or ProofreadFieldContent
+	Field string `json:"field"`
+	// Line is the 1-based content line, 0 for the title
+	Line      int    `json:"line"`
+	Original  string `json:"original"`
+	Proofread string `json:"proofread"`
+}
+
+// ProofreadProposal is a proofread note waiting for the user to apply it.
+// It applies to the version of the note that was proofread only.
+type ProofreadProposal struct {
+	// ID is empty when the proofread found nothing to correct
+	ID      *uuid.UUID `json:"id,omitempty"`
+	NoteID  uuid.UUID  `json:"note_id"`
+	Version int        `json:"version"`
+	Title   *string    `json:"title,omitempty"`
+	Content string     `json:"content"`
+	// Changes lists the corrected lines, the diff shown for confirmation
+	Changes []ProofreadChange `json:"changes"`
+	// KeptLines are the content lines left as they were because their
+	// corrections touched formatting, URLs, hashtags or code
+	KeptLines []int      `json:"kept_lines,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
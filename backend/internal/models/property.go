@@ -0,0 +1,289 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/search"
+)
+
+// Property types
+const (
+	PropertyString = "string"
+	PropertyNumber = "number"
+	PropertyDate   = "date"
+	PropertySelect = "select"
+)
+
+// Property filter operators. Min and max are inclusive and apply to number
+// and date properties.
+const (
+	PropertyFilterEq  = "eq"
+	PropertyFilterMin = "min"
+	PropertyFilterMax = "max"
+)
+
+// Property limits
+const (
+	// MaxProperties is the number of properties a user may define
+	MaxProperties = 50
+	// MaxPropertyOptions is the number of options of a select property
+	MaxPropertyOptions = 50
+	// MaxPropertyValueLength is the length of string values and options
+	MaxPropertyValueLength = 500
+)
+
+// propertyNameRegex matches property names. Names are used as JSON keys in
+// SQL, so they are restricted to lowercase letters, digits and underscores.
+var propertyNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// PropertyDefinition is a typed field a user can set on their notes, such as
+// a status select or a due date
+type PropertyDefinition struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Name   string    `json:"name" db:"name"`
+	Type   string    `json:"type" db:"type"`
+	// Options are the values of select properties
+	Options   PropertyOptions `json:"options,omitempty" db:"options"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// PropertyOptions are the options of a select property, stored as JSON
+type PropertyOptions []string
+
+// Scan implements the sql.Scanner interface for the JSON options column
+func (o *PropertyOptions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return fmt.Errorf("cannot scan %T into PropertyOptions", value)
+	}
+}
+
+// Value implements the driver.Valuer interface for the JSON options column
+func (o PropertyOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal([]string(o))
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// CreatePropertyRequest represents the request to define a property
+type CreatePropertyRequest struct {
+	Name    string   `json:"name" validate:"required,max=50"`
+	Type    string   `json:"type" validate:"required,oneof=string number date select"`
+	Options []string `json:"options,omitempty"`
+}
+
+// Validate validates and normalizes the request. Names are lowercased, and
+// select properties need at least one option.
+func (r *CreatePropertyRequest) Validate() error {
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	if !propertyNameRegex.MatchString(r.Name) {
+		return fmt.Errorf("name must start with a letter and contain only lowercase letters, digits and underscores (max 50 characters)")
+	}
+
+	switch r.Type {
+	case PropertyString, PropertyNumber, PropertyDate:
+		if len(r.Options) > 0 {
+			return fmt.Errorf("only select properties have options")
+		}
+		return nil
+	case PropertySelect:
+	default:
+		return fmt.Errorf("type must be one of string, number, date, select")
+	}
+
+	options := make([]string, 0, len(r.Options))
+	seen := make(map[string]bool, len(r.Options))
+	for _, option := range r.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || seen[strings.ToLower(option)] {
+			continue
+		}
+		if len(option) > MaxPropertyValueLength {
+			return fmt.Errorf("option too long (max %d characters)", MaxPropertyValueLength)
+		}
+		seen[strings.ToLower(option)] = true
+		options = append(options, option)
+	}
+	if len(options) == 0 {
+		return fmt.Errorf("select properties need at least one option")
+	}
+	if len(options) > MaxPropertyOptions {
+		return fmt.Errorf("too many options (max %d)", MaxPropertyOptions)
+	}
+	r.Options = options
+	return nil
+}
+
+// NoteProperties are the property values of a note by property name, stored
+// as JSON. Numbers are float64 and dates YYYY-MM-DD strings.
+type NoteProperties map[string]any
+
+// Scan implements the sql.Scanner interface for the JSON properties column
+func (p *NoteProperties) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return fmt.Errorf("cannot scan %T into NoteProperties", value)
+	}
+}
+
+// Value implements the driver.Valuer interface for the JSON properties column
+func (p NoteProperties) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(map[string]any(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// PropertySchema is a user's property definitions by name
+type PropertySchema map[string]PropertyDefinition
+
+// Normalize checks property values against the schema and returns them in
+// stored form: strings trimmed, select values spelled as their option. Nil
+// and empty string values are dropped, and nil is returned when no value is
+// left.
+func (s PropertySchema) Normalize(values map[string]any) (NoteProperties, error) {
+	properties := make(NoteProperties, len(values))
+	for name, value := range values {
+		definition, ok := s[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown property %q", name)
+		}
+		if value == nil {
+			continue
+		}
+
+		normalized, err := definition.normalize(value)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", name, err)
+		}
+		if normalized != "" {
+			properties[name] = normalized
+		}
+	}
+
+	if len(properties) == 0 {
+		return nil, nil
+	}
+	return properties, nil
+}
+
+// normalize returns the stored form of a value, "" for empty strings
+func (d *PropertyDefinition) normalize(value any) (any, error) {
+	if d.Type == PropertyNumber {
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return number, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("must be a string")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", nil
+	}
+
+	switch d.Type {
+	case PropertyDate:
+		if _, err := time.Parse(search.DateLayout, text); err != nil {
+			return nil, fmt.Errorf("must be a date as YYYY-MM-DD")
+		}
+	case PropertySelect:
+		option, ok := d.option(text)
+		if !ok {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(d.Options, ", "))
+		}
+		text = option
+	default:
+		if len(text) > MaxPropertyValueLength {
+			return nil, fmt.Errorf("too long (max %d characters)", MaxPropertyValueLength)
+		}
+	}
+	return text, nil
+}
+
+// option returns the option of a select property matching value, ignoring
+// case
+func (d *PropertyDefinition) option(value string) (string, bool) {
+	for _, option := range d.Options {
+		if strings.EqualFold(option, value) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// PropertyFilter narrows notes to those whose property compares to Value
+// with Op, such as status eq done or due max 2024-03-31
+type PropertyFilter struct {
+	Name  string `json:"name"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// Argument checks a filter against the schema and returns its definition
+// and the value to compare the stored value with
+func (s PropertySchema) Argument(filter PropertyFilter) (*PropertyDefinition, any, error) {
+	definition, ok := s[filter.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown property %q", filter.Name)
+	}
+
+	switch filter.Op {
+	case PropertyFilterEq:
+	case PropertyFilterMin, PropertyFilterMax:
+		if definition.Type != PropertyNumber && definition.Type != PropertyDate {
+			return nil, nil, fmt.Errorf("property %s: %s applies to number and date properties only", filter.Name, filter.Op)
+		}
+	default:
+		return nil, nil, fmt.Errorf("property %s: unknown filter %q", filter.Name, filter.Op)
+	}
+
+	var value any = filter.Value
+	if definition.Type == PropertyNumber {
+		number, err := strconv.ParseFloat(filter.Value, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("property %s: must be a number", filter.Name)
+		}
+		value = number
+	}
+	normalized, err := definition.normalize(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("property %s: %w", filter.Name, err)
+	}
+	return &definition, normalized, nil
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCreatePropertyRequestValidate(t *testing.T) {
+	request := CreatePropertyRequest{Name: " Status ", Type: PropertySelect, Options: []string{"Todo", " Done ", "done", ""}}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.Name != "status" || !reflect.DeepEqual(request.Options, []string{"Todo", "Done"}) {
+		t.Errorf("Unexpected normalized request %+v", request)
+	}
+
+	for _, invalid := range []CreatePropertyRequest{
+		{Name: "due date", Type: PropertyDate},
+		{Name: "1st", Type: PropertyString},
+		{Name: "status", Type: PropertySelect},
+		{Name: "estimate", Type: PropertyNumber, Options: []string{"1"}},
+		{Name: "owner", Type: "person"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestPropertySchemaNormalize(t *testing.T) {
+	schema := PropertySchema{
+		"status":   {Name: "status", Type: PropertySelect, Options: PropertyOptions{"Todo", "Done"}},
+		"estimate": {Name: "estimate", Type: PropertyNumber},
+		"due":      {Name: "due", Type: PropertyDate},
+		"owner":    {Name: "owner", Type: PropertyString},
+	}
+
+	properties, err := schema.Normalize(map[string]any{
+		"status":   "done",
+		"estimate": 2.5,
+		"due":      "2024-03-31",
+		"owner":    "  ",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := NoteProperties{"status": "Done", "estimate": 2.5, "due": "2024-03-31"}
+	if !reflect.DeepEqual(properties, want) {
+		t.Errorf("Normalize() = %v, want %v", properties, want)
+	}
+
+	if properties, err := schema.Normalize(map[string]any{"owner": nil}); err != nil || properties != nil {
+		t.Errorf("Expected removed values to normalize to nil, got %v, %v", properties, err)
+	}
+
+	for _, invalid := range []map[string]any{
+		{"priority": "high"},
+		{"status": "Blocked"},
+		{"estimate": "2"},
+		{"due": "March 31"},
+		{"owner": 3.0},
+	} {
+		if _, err := schema.Normalize(invalid); err == nil {
+			t.Errorf("Expected %v to be invalid", invalid)
+		}
+	}
+}
+
+func TestPropertySchemaArgument(t *testing.T) {
+	schema := PropertySchema{
+		"status":   {Name: "status", Type: PropertySelect, Options: PropertyOptions{"Todo", "Done"}},
+		"estimate": {Name: "estimate", Type: PropertyNumber},
+	}
+
+	if _, value, err := schema.Argument(PropertyFilter{Name: "estimate", Op: PropertyFilterMin, Value: "3"}); err != nil || value != 3.0 {
+		t.Errorf("Argument() = %v, %v, want 3", value, err)
+	}
+	if _, value, err := schema.Argument(PropertyFilter{Name: "status", Op: PropertyFilterEq, Value: "todo"}); err != nil || value != "Todo" {
+		t.Errorf("Argument() = %v, %v, want Todo", value, err)
+	}
+	if _, _, err := schema.Argument(PropertyFilter{Name: "status", Op: PropertyFilterMax, Value: "Todo"}); err == nil {
+		t.Error("Expected max to be rejected for select properties")
+	}
+	if _, _, err := schema.Argument(PropertyFilter{Name: "estimate", Op: PropertyFilterEq, Value: "many"}); err == nil {
+		t.Error("Expected a non-numeric value to be rejected")
+	}
+}
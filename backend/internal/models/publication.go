@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Slug limits of published notes
+const (
+	MinSlugLength = 3
+	MaxSlugLength = 80
+)
+
+// slugRegex matches a slug: lowercase words of letters and digits joined by
+// single hyphens
+var slugRegex = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// slugSeparatorRegex matches the runs of characters that slugs replace
+// with a hyphen
+var slugSeparatorRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Publication is a note published read-only at a public URL, /p/{slug},
+// that anyone can open without an account
+type Publication struct {
+	NoteID uuid.UUID `json:"note_id" db:"note_id"`
+	Slug   string    `json:"slug" db:"slug"`
+	// Path is where the note is served, relative to the server
+	Path string `json:"path"`
+	// NoIndex asks search engines not to index the page
+	NoIndex     bool      `json:"noindex" db:"noindex"`
+	PublishedAt time.Time `json:"published_at" db:"published_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PublicationPath returns the path a slug is served at
+func PublicationPath(slug string) string {
+	return "/p/" + slug
+}
+
+// PublishNoteRequest represents the request to publish a note or change
+// its publication
+type PublishNoteRequest struct {
+	// Slug is the custom slug; without one, new publications get a slug
+	// from the note title and existing ones keep theirs
+	Slug string `json:"slug,omitempty"`
+	// NoIndex sets the noindex option; unset keeps the current one
+	NoIndex *bool `json:"noindex,omitempty"`
+}
+
+// Validate validates and normalizes the request. Slugs are lowercased.
+func (r *PublishNoteRequest) Validate() error {
+	r.Slug = strings.ToLower(strings.TrimSpace(r.Slug))
+	if r.Slug == "" {
+		return nil
+	}
+	if len(r.Slug) < MinSlugLength || len(r.Slug) > MaxSlugLength {
+		return fmt.Errorf("slug must be %d to %d characters", MinSlugLength, MaxSlugLength)
+	}
+	if !slugRegex.MatchString(r.Slug) {
+		return fmt.Errorf("slug must be lowercase letters and digits separated by hyphens")
+	}
+	return nil
+}
+
+// GenerateSlug returns a slug for a note: its title's words followed by a
+// random suffix that keeps slugs of equal titles apart and unguessable
+func GenerateSlug(title string) string {
+	base := strings.Trim(slugSeparatorRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(base) > MaxSlugLength-9 {
+		base = strings.TrimRight(base[:MaxSlugLength-9], "-")
+	}
+	if base == "" {
+		base = "note"
+	}
+	return base + "-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:8]
+}
+
+// PublishedNote is a published note as served to the public
+type PublishedNote struct {
+	Title string
+	// HTML is the note content rendered to sanitized HTML
+	HTML      string
+	NoIndex   bool
+	UpdatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// AskNotesRequest represents a free-form question about the user's notes
+type AskNotesRequest struct {
+	Question string `json:"question" validate:"required,max=1000"`
+}
+
+// QASource is a note given to the LLM to answer a question. The answer cites
+// sources by index, e.g. [2].
+type QASource struct {
+	Index  int       `json:"index"`
+	NoteID uuid.UUID `json:"note_id"`
+	Title  string    `json:"title"`
+}
+
+// QAAnswer is the complete answer to a question with the sources it cites
+type QAAnswer struct {
+	Answer    string     `json:"answer"`
+	Sources   []QASource `json:"sources"`
+	Citations []QASource `json:"citations"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Recurrence frequencies
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// TimeOfDayLayout is the format of recurrence times
+const TimeOfDayLayout = "15:04"
+
+// Recurrence is a schedule creating a note from a template, such as a daily
+// journal every morning or a weekly review on Fridays. Runs happen at
+// TimeOfDay in TimeZone: every day, on Weekday every week, or on MonthDay
+// every month, or the month's last day when it is shorter.
+type Recurrence struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	TemplateID uuid.UUID `json:"template_id" db:"template_id"`
+	Frequency  string    `json:"frequency" db:"frequency"`
+	// Weekday is the day of weekly runs, 0 for Sunday to 6 for Saturday
+	Weekday int `json:"weekday" db:"weekday"`
+	// MonthDay is the day of monthly runs, 1 to 31
+	MonthDay int `json:"month_day" db:"month_day"`
+	// TimeOfDay is the time of runs as HH:MM
+	TimeOfDay string `json:"time_of_day" db:"time_of_day"`
+	TimeZone  string `json:"time_zone" db:"time_zone"`
+	Paused    bool   `json:"paused" db:"paused"`
+	// NextRunAt is when the next note is created; missed runs are skipped
+	NextRunAt  time.Time  `json:"next_run_at" db:"next_run_at"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastNoteID *uuid.UUID `json:"last_note_id,omitempty" db:"last_note_id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Next returns the first run of the schedule after after
+func (r *Recurrence) Next(after time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time zone %q", r.TimeZone)
+	}
+	clock, err := time.Parse(TimeOfDayLayout, r.TimeOfDay)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day %q, expected HH:MM", r.TimeOfDay)
+	}
+
+	local := after.In(loc)
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+	switch r.Frequency {
+	case RecurrenceDaily:
+		next := at(local.Year(), local.Month(), local.Day())
+		if !next.After(after) {
+			next = at(local.Year(), local.Month(), local.Day()+1)
+		}
+		return next, nil
+	case RecurrenceWeekly:
+		days := (r.Weekday - int(local.Weekday()) + 7) % 7
+		next := at(local.Year(), local.Month(), local.Day()+days)
+		if !next.After(after) {
+			next = at(local.Year(), local.Month(), local.Day()+days+7)
+		}
+		return next, nil
+	case RecurrenceMonthly:
+		monthDay := func(year int, month time.Month) time.Time {
+			last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+			return at(year, month, min(r.MonthDay, last))
+		}
+		next := monthDay(local.Year(), local.Month())
+		if !next.After(after) {
+			next = monthDay(local.Year(), local.Month()+1)
+		}
+		return next, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid frequency %q", r.Frequency)
+}
+
+// CreateRecurrenceRequest represents the request to schedule a template
+type CreateRecurrenceRequest struct {
+	TemplateID string `json:"template_id" validate:"required"`
+	Frequency  string `json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	Weekday    int    `json:"weekday,omitempty" validate:"min=0,max=6"`
+	MonthDay   int    `json:"month_day,omitempty" validate:"min=0,max=31"`
+	TimeOfDay  string `json:"time_of_day" validate:"required"`
+	// TimeZone is an IANA time zone, UTC by default
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// ToRecurrence converts the request to a recurrence, checking the schedule
+func (r *CreateRecurrenceRequest) ToRecurrence(userID uuid.UUID) (*Recurrence, error) {
+	templateID, err := uuid.Parse(r.TemplateID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template_id")
+	}
+	recurrence := &Recurrence{
+		UserID:     userID,
+		TemplateID: templateID,
+		Frequency:  r.Frequency,
+		TimeOfDay:  r.TimeOfDay,
+		TimeZone:   r.TimeZone,
+	}
+	if recurrence.TimeZone == "" {
+		recurrence.TimeZone = "UTC"
+	}
+	switch r.Frequency {
+	case RecurrenceWeekly:
+		recurrence.Weekday = r.Weekday
+	case RecurrenceMonthly:
+		if r.MonthDay < 1 {
+			return nil, fmt.Errorf("month_day is required for monthly recurrences")
+		}
+		recurrence.MonthDay = r.MonthDay
+	}
+	if _, err := recurrence.Next(time.Now()); err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// UpdateRecurrenceRequest represents the request to pause or resume a
+// recurrence
+type UpdateRecurrenceRequest struct {
+	Paused *bool `json:"paused" validate:"required"`
+}
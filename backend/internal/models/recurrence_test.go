@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		after      time.Time
+		want       time.Time
+	}{
+		{"daily later today", Recurrence{Frequency: RecurrenceDaily, TimeOfDay: "07:00", TimeZone: "Europe/Berlin"},
+			time.Date(2024, 3, 4, 6, 0, 0, 0, berlin), time.Date(2024, 3, 4, 7, 0, 0, 0, berlin)},
+		{"daily tomorrow", Recurrence{Frequency: RecurrenceDaily, TimeOfDay: "07:00", TimeZone: "Europe/Berlin"},
+			time.Date(2024, 3, 4, 7, 0, 0, 0, berlin), time.Date(2024, 3, 5, 7, 0, 0, 0, berlin)},
+		{"daily across daylight saving", Recurrence{Frequency: RecurrenceDaily, TimeOfDay: "07:00", TimeZone: "Europe/Berlin"},
+			time.Date(2024, 3, 30, 8, 0, 0, 0, berlin), time.Date(2024, 3, 31, 7, 0, 0, 0, berlin)},
+		{"weekly on friday", Recurrence{Frequency: RecurrenceWeekly, Weekday: int(time.Friday), TimeOfDay: "16:30", TimeZone: "UTC"},
+			time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 8, 16, 30, 0, 0, time.UTC)},
+		{"weekly after this week's run", Recurrence{Frequency: RecurrenceWeekly, Weekday: int(time.Friday), TimeOfDay: "16:30", TimeZone: "UTC"},
+			time.Date(2024, 3, 8, 17, 0, 0, 0, time.UTC), time.Date(2024, 3, 15, 16, 30, 0, 0, time.UTC)},
+		{"monthly on a short month's last day", Recurrence{Frequency: RecurrenceMonthly, MonthDay: 31, TimeOfDay: "09:00", TimeZone: "UTC"},
+			time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.recurrence.Next(tt.after)
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateRecurrenceRequestToRecurrence(t *testing.T) {
+	invalid := []CreateRecurrenceRequest{
+		{TemplateID: "not-a-uuid", Frequency: RecurrenceDaily, TimeOfDay: "07:00"},
+		{TemplateID: "00000000-0000-0000-0000-000000000001", Frequency: RecurrenceDaily, TimeOfDay: "7am"},
+		{TemplateID: "00000000-0000-0000-0000-000000000001", Frequency: RecurrenceDaily, TimeOfDay: "07:00", TimeZone: "Mars/Olympus"},
+		{TemplateID: "00000000-0000-0000-0000-000000000001", Frequency: RecurrenceMonthly, TimeOfDay: "07:00"},
+	}
+	for _, request := range invalid {
+		if _, err := request.ToRecurrence(uuid.New()); err == nil {
+			t.Errorf("ToRecurrence(%+v) error = nil, want an error", request)
+		}
+	}
+
+	request := CreateRecurrenceRequest{TemplateID: "00000000-0000-0000-0000-000000000001", Frequency: RecurrenceDaily, Weekday: 5, TimeOfDay: "07:00"}
+	recurrence, err := request.ToRecurrence(uuid.New())
+	if err != nil {
+		t.Fatalf("ToRecurrence() error = %v", err)
+	}
+	if recurrence.TimeZone != "UTC" || recurrence.Weekday != 0 {
+		t.Errorf("Expected UTC and no weekday on a daily recurrence, got %q and %d", recurrence.TimeZone, recurrence.Weekday)
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+)
+
+// SavedSearch is a stored search rendered by clients as a smart folder
+type SavedSearch struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Query     string    `json:"query" db:"query"`
+	Tags      []string  `json:"tags" db:"tags"`
+	OrderBy   string    `json:"order_by" db:"order_by"`
+	OrderDir  string    `json:"order_dir" db:"order_dir"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ToSearchRequest builds the note search that executes this saved search
+func (s *SavedSearch) ToSearchRequest(limit, offset int) *SearchNotesRequest {
+	return &SearchNotesRequest{
+		Query:    s.Query,
+		Tags:     s.Tags,
+		Limit:    limit,
+		Offset:   offset,
+		OrderBy:  s.OrderBy,
+		OrderDir: s.OrderDir,
+	}
+}
+
+// CombinedQuery returns the query with the tag filters folded in as tag: operators
+func (s *SavedSearch) CombinedQuery() string {
+	parts := make([]string, 0, len(s.Tags)+1)
+	if s.Query != "" {
+		parts = append(parts, s.Query)
+	}
+	for _, tag := range s.Tags {
+		parts = append(parts, "tag:"+tag)
+	}
+	return strings.Join(parts, " ")
+}
+
+// CreateSavedSearchRequest represents the request to save a search
+type CreateSavedSearchRequest struct {
+	Name     string   `json:"name" validate:"required,max=100"`
+	Query    string   `json:"query,omitempty" validate:"max=1000"`
+	Tags     []string `json:"tags,omitempty"`
+	OrderBy  string   `json:"order_by,omitempty" validate:"omitempty,oneof=created_at updated_at title"`
+	OrderDir string   `json:"order_dir,omitempty" validate:"omitempty,oneof=asc desc"`
+}
+
+// Validate validates and normalizes the request, applying default sort order
+func (r *CreateSavedSearchRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Query = strings.TrimSpace(r.Query)
+
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 100 {
+		return fmt.Errorf("name too long (max 100 characters)")
+	}
+	if len(r.Query) > 1000 {
+		return fmt.Errorf("query too long (max 1000 characters)")
+	}
+
+	tags := make([]string, 0, len(r.Tags))
+	seen := make(map[string]bool, len(r.Tags))
+	for _, tag := range r.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		tag = search.NormalizeTag(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	r.Tags = tags
+
+	if r.Query == "" && len(r.Tags) == 0 {
+		return fmt.Errorf("query or tags are required")
+	}
+	if _, err := search.Parse(r.Query); err != nil {
+		return err
+	}
+
+	if r.OrderBy == "" {
+		r.OrderBy = "created_at"
+	}
+	if r.OrderBy != "created_at" && r.OrderBy != "updated_at" && r.OrderBy != "title" {
+		return fmt.Errorf("order_by must be one of created_at, updated_at, title")
+	}
+	if r.OrderDir == "" {
+		r.OrderDir = "desc"
+	}
+	if r.OrderDir != "asc" && r.OrderDir != "desc" {
+		return fmt.Errorf("order_dir must be asc or desc")
+	}
+
+	return nil
+}
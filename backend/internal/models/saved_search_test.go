@@ -0,0 +1,45 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCreateSavedSearchRequestValidate(t *testing.T) {
+	req := CreateSavedSearchRequest{
+		Name:  "  Urgent work  ",
+		Query: "urgent -done",
+		Tags:  []string{"work", "#work", " "},
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+	if req.Name != "Urgent work" {
+		t.Errorf("expected trimmed name, got %q", req.Name)
+	}
+	if !reflect.DeepEqual(req.Tags, []string{"#work"}) {
+		t.Errorf("expected normalized tags [#work], got %v", req.Tags)
+	}
+	if req.OrderBy != "created_at" || req.OrderDir != "desc" {
+		t.Errorf("expected default sort created_at desc, got %s %s", req.OrderBy, req.OrderDir)
+	}
+
+	invalid := []CreateSavedSearchRequest{
+		{Name: "", Query: "x"},
+		{Name: "empty"},
+		{Name: "bad syntax", Query: `"unterminated`},
+		{Name: "bad sort", Query: "x", OrderBy: "version"},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected error for %+v", r)
+		}
+	}
+}
+
+func TestSavedSearchCombinedQuery(t *testing.T) {
+	s := SavedSearch{Query: `"release notes"`, Tags: []string{"#work", "#urgent"}}
+	if got := s.CombinedQuery(); got != `"release notes" tag:#work tag:#urgent` {
+		t.Errorf("unexpected combined query: %s", got)
+	}
+}
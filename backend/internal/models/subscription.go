@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+)
+
+// SearchSubscription is a search query a user is notified about when new notes match it
+type SearchSubscription struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Query     string    `json:"query" db:"query"`
+	// SavedSearchID is set when the subscription follows a saved search; Query
+	// then reflects the saved search's query and tags
+	SavedSearchID *uuid.UUID `json:"saved_search_id,omitempty" db:"saved_search_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateSearchSubscriptionRequest represents the request to subscribe to a search.
+// Either Query or SavedSearchID must be set; Name defaults to the saved search name.
+type CreateSearchSubscriptionRequest struct {
+	Name          string  `json:"name" validate:"required,max=100"`
+	Query         string  `json:"query" validate:"required,max=1000"`
+	SavedSearchID *string `json:"saved_search_id,omitempty"`
+}
+
+// Validate validates the subscription request and returns the parsed query
+func (r *CreateSearchSubscriptionRequest) Validate() (*search.Query, error) {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Query = strings.TrimSpace(r.Query)
+
+	if r.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 100 {
+		return nil, fmt.Errorf("name too long (max 100 characters)")
+	}
+	if r.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if len(r.Query) > 1000 {
+		return nil, fmt.Errorf("query too long (max 1000 characters)")
+	}
+
+	return search.Parse(r.Query)
+}
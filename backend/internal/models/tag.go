@@ -6,36 +6,150 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Tag represents a tag (hashtag) in the system
+// hashtagRegex matches hashtags, including nested ones such as #work/projecta
+var hashtagRegex = regexp.MustCompile(`#\w+(?:/\w+)*`)
+
+// spacedHashtagRegex matches the hashtags extracted from note content:
+// # followed by optional spaces, then word characters with optional
+// "/"-separated nested segments
+var spacedHashtagRegex = regexp.MustCompile(`#\s*\w+(?:/\w+)*`)
+
+// tagNameRegex matches valid tag names; nested tag segments are separated by "/"
+var tagNameRegex = regexp.MustCompile(`^#[a-zA-Z0-9_-]+(?:/[a-zA-Z0-9_-]+)*$`)
+
+// Tag represents a tag (hashtag) of a user. Nested tags such as #work/projecta
+// point at their parent tag.
 type Tag struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	Name      string     `json:"name" db:"name"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 }
 
 // TagResponse is the safe response format for tag data
 type TagResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	NoteCount int       `json:"note_count,omitempty"`
+	ID        uuid.UUID  `json:"id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	NoteCount int        `json:"note_count,omitempty"`
+}
+
+// TagTreeNode is a tag with its nested child tags. NoteCount only counts the
+// notes carrying the tag itself.
+type TagTreeNode struct {
+	TagResponse
+	Children []TagTreeNode `json:"children"`
+}
+
+// Tag graph limits
+const (
+	// DefaultTagGraphNodes is the number of tags in a tag graph
+	DefaultTagGraphNodes = 100
+	// MaxTagGraphNodes is the largest number of tags in a tag graph
+	MaxTagGraphNodes = 500
+)
+
+// TagGraphNode is a tag in the tag co-occurrence graph
+type TagGraphNode struct {
+	Name      string `json:"name"`
+	NoteCount int    `json:"note_count"`
+}
+
+// TagGraphEdge joins two tags used together on Weight notes. Source comes
+// before Target by name.
+type TagGraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// TagGraph is the co-occurrence graph of a user's most used tags
+type TagGraph struct {
+	Nodes []TagGraphNode `json:"nodes"`
+	Edges []TagGraphEdge `json:"edges"`
+}
+
+// Trending tag limits
+const (
+	// DefaultTrendWindow is the window of trending tags
+	DefaultTrendWindow = "7d"
+	// MaxTrendWindowDays is the length of the longest trend window
+	MaxTrendWindowDays = 365
+)
+
+// TrendingTag compares how often a tag was added to notes in the current
+// window and in the window before it
+type TrendingTag struct {
+	Name          string `json:"name"`
+	Count         int    `json:"count"`
+	PreviousCount int    `json:"previous_count"`
+	// GrowthPercent is the change from PreviousCount, nil for tags not used
+	// in the previous window
+	GrowthPercent *float64 `json:"growth_percent"`
+}
+
+// TrendingTags lists the tags used in a window, fastest growing first
+type TrendingTags struct {
+	Window string        `json:"window"`
+	Since  time.Time     `json:"since"`
+	Tags   []TrendingTag `json:"tags"`
+}
+
+// ParseTrendWindow parses a trend window of days such as "7d", or hours
+// such as "24h"
+func ParseTrendWindow(window string) (time.Duration, error) {
+	if len(window) < 2 {
+		return 0, fmt.Errorf("window must be a number of days or hours, such as 7d or 24h")
+	}
+	count, err := strconv.Atoi(window[:len(window)-1])
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("window must be a number of days or hours, such as 7d or 24h")
+	}
+
+	var duration time.Duration
+	switch window[len(window)-1] {
+	case 'd':
+		duration = time.Duration(count) * 24 * time.Hour
+	case 'h':
+		duration = time.Duration(count) * time.Hour
+	default:
+		return 0, fmt.Errorf("window must be a number of days or hours, such as 7d or 24h")
+	}
+	if duration > MaxTrendWindowDays*24*time.Hour {
+		return 0, fmt.Errorf("window too long (max %dd)", MaxTrendWindowDays)
+	}
+	return duration, nil
 }
 
 // ToResponse converts Tag to TagResponse
 func (t *Tag) ToResponse() TagResponse {
 	return TagResponse{
 		ID:        t.ID,
+		ParentID:  t.ParentID,
 		Name:      t.Name,
 		CreatedAt: t.CreatedAt,
 	}
 }
 
+// ParentTagName returns the name of the parent of a nested tag, e.g. #work for
+// #work/projecta, or "" for a top-level tag
+func ParentTagName(name string) string {
+	i := strings.LastIndex(name, "/")
+	if i <= 1 {
+		return ""
+	}
+	return name[:i]
+}
+
 // Validate validates the tag data
 func (t *Tag) Validate() error {
 	if t.Name == "" {
@@ -45,10 +159,10 @@ func (t *Tag) Validate() error {
 		return fmt.Errorf("name too long (max 100 characters)")
 	}
 
-	// Tag must start with # and contain only alphanumeric characters, underscores, and hyphens
-	tagRegex := regexp.MustCompile(`^#[a-zA-Z0-9_-]+$`)
-	if !tagRegex.MatchString(t.Name) {
-		return fmt.Errorf("tag must start with # and contain only alphanumeric characters, underscores, and hyphens")
+	// Tag must start with # and contain only alphanumeric characters, underscores,
+	// and hyphens, with "/" separating nested tags
+	if !tagNameRegex.MatchString(t.Name) {
+		return fmt.Errorf("tag must start with # and contain only alphanumeric characters, underscores, hyphens, and / between nested tags")
 	}
 
 	return nil
@@ -72,9 +186,10 @@ func (t *Tag) SanitizeName() {
 	// Convert to lowercase
 	t.Name = strings.ToLower(t.Name)
 
-	// Remove invalid characters
-	validRegex := regexp.MustCompile(`[^a-zA-Z0-9_#-]`)
+	// Remove invalid characters and empty nested tag segments
+	validRegex := regexp.MustCompile(`[^a-zA-Z0-9_#/-]`)
 	t.Name = validRegex.ReplaceAllString(t.Name, "")
+	t.Name = strings.TrimRight(regexp.MustCompile(`/{2,}`).ReplaceAllString(t.Name, "/"), "/")
 
 	// Ensure it starts with # after sanitization
 	if !strings.HasPrefix(t.Name, "#") && t.Name != "" {
@@ -154,12 +269,10 @@ func (r *CreateTagRequest) ToTag() *Tag {
 	return tag
 }
 
-// ExtractTagsFromContent extracts all hashtags from content
+// ExtractTagsFromContent extracts all hashtags from content, including nested
+// tags such as #work/projecta
 func ExtractTagsFromContent(content string) []string {
-	// Regular expression to match hashtags (including those with spaces)
-	// This regex matches # followed by optional spaces, then word characters
-	hashtagRegex := regexp.MustCompile(`#\s*\w+`)
-	matches := hashtagRegex.FindAllString(content, -1)
+	matches := spacedHashtagRegex.FindAllString(content, -1)
 
 	// Remove duplicates and normalize
 	uniqueTags := make(map[string]bool)
@@ -184,14 +297,12 @@ func ExtractTagsFromContent(content string) []string {
 
 // ValidateTags validates a list of tag names
 func ValidateTags(tagNames []string) error {
-	tagRegex := regexp.MustCompile(`^#[a-zA-Z0-9_-]+$`)
-
 	for _, name := range tagNames {
 		if len(name) > 100 {
 			return fmt.Errorf("tag %s too long (max 100 characters)", name)
 		}
-		if !tagRegex.MatchString(name) {
-			return fmt.Errorf("tag %s must start with # and contain only alphanumeric characters, underscores, and hyphens", name)
+		if !tagNameRegex.MatchString(name) {
+			return fmt.Errorf("tag %s must start with # and contain only alphanumeric characters, underscores, hyphens, and / between nested tags", name)
 		}
 	}
 	return nil
@@ -211,6 +322,22 @@ func GetTagSuggestions(partial string, existingTags []string) []string {
 	return suggestions
 }
 
+// TagSuggestion is a hashtag suggested for a note by the LLM
+type TagSuggestion struct {
+	Tag        string  `json:"tag"`
+	Confidence float64 `json:"confidence"`
+}
+
+// TagSuggestionsResponse represents the tags suggested for a note. Applied
+// lists the suggestions added to the note for users who opted in to
+// auto-applying confident suggestions.
+type TagSuggestionsResponse struct {
+	NoteID      uuid.UUID       `json:"note_id"`
+	Suggestions []TagSuggestion `json:"suggestions"`
+	Applied     []string        `json:"applied"`
+	Note        *NoteResponse   `json:"note,omitempty"`
+}
+
 // MarshalJSON custom JSON marshaling for Tag
 func (t *Tag) MarshalJSON() ([]byte, error) {
 	type Alias Tag
@@ -246,9 +373,13 @@ func (t *Tag) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// UpdateTagRequest represents the request to update a tag
+// UpdateTagRequest represents the request to update a tag. Renaming a tag
+// renames the nested tags below it.
 type UpdateTagRequest struct {
 	Name string `json:"name" validate:"required,max=100"`
+	// RewriteContent replaces the old hashtag in the content of the notes
+	// carrying the tag. Without it only the tag is renamed.
+	RewriteContent bool `json:"rewrite_content"`
 }
 
 // TagAnalytics represents comprehensive analytics for a tag
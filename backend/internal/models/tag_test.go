@@ -0,0 +1,70 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtractHashtagsNested(t *testing.T) {
+	note := &Note{Content: "Plan #work/projectA and #work, see #work/projectA/docs/ and a/#b"}
+
+	want := []string{"#work/projectA", "#work", "#work/projectA/docs", "#b"}
+	if got := note.ExtractHashtags(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if got := ExtractTagsFromContent("#Work/ProjectA # home/kitchen"); !reflect.DeepEqual(got, []string{"#work/projecta", "#home/kitchen"}) {
+		t.Errorf("Expected nested tags to be extracted, got %v", got)
+	}
+}
+
+func TestParentTagName(t *testing.T) {
+	tests := map[string]string{
+		"#work":                "",
+		"#work/projecta":       "#work",
+		"#work/projecta/notes": "#work/projecta",
+	}
+	for name, want := range tests {
+		if got := ParentTagName(name); got != want {
+			t.Errorf("ParentTagName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestValidateNestedTags(t *testing.T) {
+	if err := ValidateTags([]string{"#work/project-a", "#home"}); err != nil {
+		t.Errorf("Expected nested tags to be valid, got %v", err)
+	}
+	for _, name := range []string{"#work/", "#/work", "#work//a"} {
+		if err := ValidateTags([]string{name}); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+
+	tag := (&CreateTagRequest{Name: " Work//Project A/ "}).ToTag()
+	if tag.Name != "#work/projecta" {
+		t.Errorf("Expected sanitized nested tag, got %q", tag.Name)
+	}
+	if err := tag.Validate(); err != nil {
+		t.Errorf("Expected sanitized tag to be valid, got %v", err)
+	}
+}
+
+func TestParseTrendWindow(t *testing.T) {
+	valid := map[string]time.Duration{
+		"7d":   7 * 24 * time.Hour,
+		"24h":  24 * time.Hour,
+		"365d": 365 * 24 * time.Hour,
+	}
+	for window, want := range valid {
+		if got, err := ParseTrendWindow(window); err != nil || got != want {
+			t.Errorf("ParseTrendWindow(%q) = %v, %v, want %v", window, got, err, want)
+		}
+	}
+	for _, window := range []string{"", "d", "7", "0d", "-1d", "2w", "366d"} {
+		if _, err := ParseTrendWindow(window); err == nil {
+			t.Errorf("Expected window %q to be rejected", window)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Task list statuses
+const (
+	TaskStatusOpen = "open"
+	TaskStatusDone = "done"
+	TaskStatusAll  = "all"
+)
+
+// Task is a checklist item of a note, kept in step with the note content
+type Task struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	NoteID    uuid.UUID `json:"note_id" db:"note_id"`
+	NoteTitle string    `json:"note_title" db:"-"`
+	// Line is the 1-based number of the task's line in the note content
+	Line      int       `json:"line" db:"line"`
+	Text      string    `json:"text" db:"text"`
+	Checked   bool      `json:"checked" db:"checked"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TaskFilter selects tasks. An empty Tag matches tasks of every note; Status
+// is one of the task list statuses.
+type TaskFilter struct {
+	Tag    string
+	Status string
+}
+
+// TaskList represents a paginated list of tasks, from the most recently
+// updated notes first and in note order within a note
+type TaskList struct {
+	Tasks   []Task `json:"tasks"`
+	Total   int    `json:"total"`
+	Limit   int    `json:"limit"`
+	Offset  int    `json:"offset"`
+	HasMore bool   `json:"has_more"`
+}
+
+// UpdateTaskRequest represents the request to check or uncheck a task
+type UpdateTaskRequest struct {
+	Checked *bool `json:"checked" validate:"required"`
+}
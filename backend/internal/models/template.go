@@ -0,0 +1,198 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/templates"
+)
+
+// Template limits
+const (
+	// MaxTemplateTags is the number of tags a template may apply
+	MaxTemplateTags = 20
+	// MaxTemplateCategoryLength is the length of a template category
+	MaxTemplateCategoryLength = 50
+)
+
+// Gallery sort orders
+const (
+	GallerySortPopular = "popular"
+	GallerySortRecent  = "recent"
+)
+
+// Template is a reusable starting point for notes. Title and Content may
+// hold placeholders rendered by the templates package; Tags are added to
+// every note created from the template.
+type Template struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description,omitempty" db:"description"`
+	Title       string    `json:"title,omitempty" db:"title"`
+	Content     string    `json:"content" db:"content"`
+	Tags        []string  `json:"tags" db:"tags"`
+	Category    string    `json:"category,omitempty" db:"category"`
+	IsPublic    bool      `json:"is_public" db:"is_public"`
+	// UsageCount is the number of notes created from the template
+	UsageCount int `json:"usage_count" db:"usage_count"`
+	// CloneCount is the number of times other users cloned the template
+	CloneCount int `json:"clone_count" db:"clone_count"`
+	// ClonedFrom is the public template this one was cloned from
+	ClonedFrom *uuid.UUID `json:"cloned_from,omitempty" db:"cloned_from"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// GalleryTemplate is a public template as listed in the gallery, without
+// its owner
+type GalleryTemplate struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Title       string    `json:"title,omitempty"`
+	Content     string    `json:"content"`
+	Tags        []string  `json:"tags"`
+	UsageCount  int       `json:"usage_count"`
+	CloneCount  int       `json:"clone_count"`
+	// Own is set on the caller's own templates
+	Own       bool      `json:"own"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GalleryFilter selects public templates. Query matches names and
+// descriptions; an empty Category matches every category.
+type GalleryFilter struct {
+	Query    string
+	Category string
+	// Sort is one of the gallery sort orders, popular by default
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+// TemplateGallery is a page of public templates
+type TemplateGallery struct {
+	Templates []GalleryTemplate `json:"templates"`
+	Total     int               `json:"total"`
+	Limit     int               `json:"limit"`
+	Offset    int               `json:"offset"`
+	HasMore   bool              `json:"has_more"`
+}
+
+// CloneTemplateRequest represents the request to clone a public template
+type CloneTemplateRequest struct {
+	// Name replaces the name of the template when set
+	Name string `json:"name,omitempty" validate:"max=100"`
+}
+
+// ApplyTags returns content with the template's tags that it does not
+// mention yet appended on a new line
+func (t *Template) ApplyTags(content string) string {
+	present := make(map[string]bool)
+	for _, match := range spacedHashtagRegex.FindAllString(content, -1) {
+		present[strings.ToLower(strings.ReplaceAll(match, " ", ""))] = true
+	}
+
+	var missing []string
+	for _, tag := range t.Tags {
+		if !present[tag] {
+			present[tag] = true
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) == 0 {
+		return content
+	}
+	return strings.TrimRight(content, "\n") + "\n" + strings.Join(missing, " ")
+}
+
+// TemplateRequest represents the request to create or replace a template
+type TemplateRequest struct {
+	Name        string   `json:"name" validate:"required,max=100"`
+	Description string   `json:"description,omitempty" validate:"max=500"`
+	Title       string   `json:"title,omitempty" validate:"max=500"`
+	Content     string   `json:"content" validate:"required,max=10000"`
+	Tags        []string `json:"tags,omitempty"`
+	Category    string   `json:"category,omitempty" validate:"max=50"`
+	IsPublic    bool     `json:"is_public,omitempty"`
+}
+
+// Validate validates and normalizes the request. Tags and the category are
+// lowercased, tags get a leading # when missing, and the title and content
+// must parse as templates.
+func (r *TemplateRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 100 {
+		return fmt.Errorf("name too long (max 100 characters)")
+	}
+	if strings.TrimSpace(r.Content) == "" {
+		return fmt.Errorf("content is required")
+	}
+	r.Category = strings.ToLower(strings.TrimSpace(r.Category))
+	if len(r.Category) > MaxTemplateCategoryLength {
+		return fmt.Errorf("category too long (max %d characters)", MaxTemplateCategoryLength)
+	}
+	if _, err := templates.Parse(r.Title); err != nil {
+		return fmt.Errorf("invalid title: %w", err)
+	}
+	if _, err := templates.Parse(r.Content); err != nil {
+		return fmt.Errorf("invalid content: %w", err)
+	}
+
+	tags := make([]string, 0, len(r.Tags))
+	seen := make(map[string]bool, len(r.Tags))
+	for _, tag := range r.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		tag = search.NormalizeTag(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxTemplateTags {
+		return fmt.Errorf("too many tags (max %d)", MaxTemplateTags)
+	}
+	if err := ValidateTags(tags); err != nil {
+		return err
+	}
+	r.Tags = tags
+	return nil
+}
+
+// ApplyTemplateRequest represents the request to render a template
+type ApplyTemplateRequest struct {
+	// Variables are the values of the template's placeholders
+	Variables map[string]string `json:"variables,omitempty"`
+	// TimeZone is the IANA time zone of the computed date variables, UTC
+	// by default
+	TimeZone string `json:"time_zone,omitempty"`
+	// At is the time the computed date variables are based on, now when
+	// zero
+	At time.Time `json:"-"`
+}
+
+// AppliedTemplate is a rendered template
+type AppliedTemplate struct {
+	Title   string `json:"title,omitempty"`
+	Content string `json:"content"`
+}
+
+// CreateNoteFromTemplateRequest represents the request to create a note from
+// a template
+type CreateNoteFromTemplateRequest struct {
+	ApplyTemplateRequest
+	// Title replaces the template's title when set
+	Title   *string `json:"title,omitempty" validate:"omitempty,max=500"`
+	Private bool    `json:"private,omitempty"`
+}
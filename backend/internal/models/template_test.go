@@ -0,0 +1,57 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateApplyTags(t *testing.T) {
+	template := &Template{Tags: []string{"#journal", "#daily"}}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"appends missing tags", "Dear diary\n", "Dear diary\n#journal #daily"},
+		{"skips tags already present", "Dear diary #Journal", "Dear diary #Journal\n#daily"},
+		{"keeps content with every tag", "#daily #journal", "#daily #journal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := template.ApplyTags(tt.content); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTemplateRequestValidate(t *testing.T) {
+	request := &TemplateRequest{Name: " Daily Journal ", Content: "# {{today}}", Tags: []string{"Journal", " #daily", "", "journal"}, Category: " Daily "}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if request.Name != "Daily Journal" {
+		t.Errorf("Expected trimmed name, got %q", request.Name)
+	}
+	if request.Category != "daily" {
+		t.Errorf("Expected normalized category, got %q", request.Category)
+	}
+	if want := []string{"#journal", "#daily"}; !reflect.DeepEqual(request.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, request.Tags)
+	}
+
+	for _, invalid := range []*TemplateRequest{
+		{Name: "", Content: "text"},
+		{Name: "Empty", Content: "  "},
+		{Name: "Unclosed", Content: "{{#if mood}}text"},
+		{Name: "Bad title", Title: "{{/if}}", Content: "text"},
+		{Name: "Bad tag", Content: "text", Tags: []string{"#not a tag"}},
+		{Name: "Long category", Content: "text", Category: strings.Repeat("c", MaxTemplateCategoryLength+1)},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%q) error = nil, want an error", invalid.Name)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gpd/my-notes/internal/language"
+)
+
+// TranslationSource is the metadata source of translated notes
+const TranslationSource = "translation"
+
+// maxTranslationTitleLength is the title limit of notes
+const maxTranslationTitleLength = 500
+
+// TranslateRequest holds the options of a note translation
+type TranslateRequest struct {
+	// Language is the ISO-639-1 code of the language to translate to
+	Language string
+}
+
+// Validate validates the request and normalizes its language
+func (r *TranslateRequest) Validate() error {
+	r.Language = strings.ToLower(strings.TrimSpace(r.Language))
+	if r.Language == "" {
+		return fmt.Errorf("lang is required")
+	}
+	if language.Code(r.Language).Name() == "" {
+		return fmt.Errorf("unsupported language %q", r.Language)
+	}
+	return nil
+}
+
+// Translation is a note translated by the LLM
+type Translation struct {
+	// SourceLanguages are the ISO-639-1 codes of the languages detected in
+	// the note, more than one for notes mixing languages
+	SourceLanguages []string
+	Title           string
+	Content         string
+}
+
+// TranslatedNote is the note created from a translation
+type TranslatedNote struct {
+	NoteResponse
+	// Language is the language the note was translated to
+	Language string `json:"language"`
+	// SourceLanguages are the languages detected in the original note
+	SourceLanguages []string `json:"source_languages"`
+}
+
+// ToCreateNoteRequest returns the note of a translation of original: the
+// translated content followed by a [[link]] to the original, with the same
+// color and icon. It fails when the translation is too long for a note.
+func (t *Translation) ToCreateNoteRequest(original *Note) (*CreateNoteRequest, error) {
+	content := strings.TrimSpace(t.Content) + "\n\nTranslated from [[" + original.ID.String() + "]]"
+	if utf8.RuneCountInString(content) > MaxContentLength() {
+		return nil, fmt.Errorf("translation too long for a note (max %d characters)", MaxContentLength())
+	}
+
+	return &CreateNoteRequest{
+		Title:    truncateBytes(strings.Join(strings.Fields(t.Title), " "), maxTranslationTitleLength),
+		Content:  content,
+		Metadata: &NoteMetadata{Source: TranslationSource},
+		Color:    original.Color,
+		Icon:     original.Icon,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTranslateRequestValidate(t *testing.T) {
+	request := TranslateRequest{Language: " EN "}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if request.Language != "en" {
+		t.Errorf("Expected the language to be normalized, got %q", request.Language)
+	}
+
+	for _, invalid := range []string{"", "english", "xx", "und"} {
+		if err := (&TranslateRequest{Language: invalid}).Validate(); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestTranslationToCreateNoteRequest(t *testing.T) {
+	original := &Note{ID: uuid.New(), Color: "blue", Icon: "🛒"}
+	translation := Translation{Title: "  Shopping\nlist ", Content: "Buy milk #groceries\n"}
+	note, err := translation.ToCreateNoteRequest(original)
+	if err != nil {
+		t.Fatalf("ToCreateNoteRequest failed: %v", err)
+	}
+
+	want := "Buy milk #groceries\n\nTranslated from [[" + original.ID.String() + "]]"
+	if note.Title != "Shopping list" || note.Content != want {
+		t.Errorf("Unexpected note %q:\n%s", note.Title, note.Content)
+	}
+	if note.Color != "blue" || note.Icon != "🛒" {
+		t.Errorf("Expected the color and icon of the original, got %q, %q", note.Color, note.Icon)
+	}
+	if note.Metadata == nil || note.Metadata.Source != TranslationSource {
+		t.Errorf("Unexpected metadata %+v", note.Metadata)
+	}
+	links := (&Note{Content: note.Content}).ExtractLinks()
+	if len(links) != 1 || links[0].TargetID == nil || *links[0].TargetID != original.ID {
+		t.Errorf("Expected a link to the original, got %+v", links)
+	}
+
+	translation.Content = strings.Repeat("a", 10000)
+	if _, err := translation.ToCreateNoteRequest(original); err == nil {
+		t.Error("Expected translations too long for a note to fail")
+	}
+}
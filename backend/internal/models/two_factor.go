@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TwoFactorStatus describes the two-factor authentication of a user
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorEnrollment is the secret of an enrollment awaiting its first code.
+// OTPAuthURL is the otpauth:// URI to show as a QR code to authenticator
+// apps; Secret is for entering the key by hand.
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app, or a
+// recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// Validate validates and normalizes the request
+func (r *TwoFactorCodeRequest) Validate() error {
+	r.Code = strings.TrimSpace(r.Code)
+	if r.Code == "" {
+		return fmt.Errorf("code is required")
+	}
+	if len(r.Code) > 32 {
+		return fmt.Errorf("code too long")
+	}
+	return nil
+}
+
+// TwoFactorRecoveryCodes are new recovery codes, returned only once
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
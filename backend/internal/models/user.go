@@ -8,36 +8,47 @@ import (
 	"github.com/google/uuid"
 )
 
+// User roles
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 // UserSession represents a user session
 type UserSession struct {
-	ID        string    `json:"id" db:"id"`
-	UserID    string    `json:"user_id" db:"user_id"`
-	IPAddress string    `json:"ip_address" db:"ip_address"`
-	UserAgent string    `json:"user_agent" db:"user_agent"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	LastSeen  time.Time `json:"last_seen" db:"last_seen"`
-	IsActive  bool      `json:"is_active" db:"is_active"`
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	IPAddress  string    `json:"ip_address" db:"ip_address"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	DeviceName string    `json:"device_name" db:"device_name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeen   time.Time `json:"last_seen" db:"last_seen"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
 }
 
 // UserSessionResponse is the safe response format for user session data
 type UserSessionResponse struct {
-	ID        string `json:"id"`
-	IPAddress string `json:"ip_address"`
-	UserAgent string `json:"user_agent"`
-	CreatedAt string `json:"created_at"`
-	LastSeen  string `json:"last_seen"`
-	IsActive  bool   `json:"is_active"`
+	ID         string `json:"id"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	DeviceName string `json:"device_name"`
+	CreatedAt  string `json:"created_at"`
+	LastSeen   string `json:"last_seen"`
+	IsActive   bool   `json:"is_active"`
+	// Current marks the session the request was made with
+	Current bool `json:"current"`
 }
 
 // ToResponse converts UserSession to UserSessionResponse
 func (s *UserSession) ToResponse() UserSessionResponse {
 	return UserSessionResponse{
-		ID:        s.ID,
-		IPAddress: s.IPAddress,
-		UserAgent: s.UserAgent,
-		CreatedAt: s.CreatedAt.Format(time.RFC3339),
-		LastSeen:  s.LastSeen.Format(time.RFC3339),
-		IsActive:  s.IsActive,
+		ID:         s.ID,
+		IPAddress:  s.IPAddress,
+		UserAgent:  s.UserAgent,
+		DeviceName: s.DeviceName,
+		CreatedAt:  s.CreatedAt.Format(time.RFC3339),
+		LastSeen:   s.LastSeen.Format(time.RFC3339),
+		IsActive:   s.IsActive,
 	}
 }
 
@@ -50,6 +61,42 @@ type UserStats struct {
 	LastLoginAt     string `json:"last_login_at"`
 }
 
+// UserSettings represents a user's preferences
+type UserSettings struct {
+	// AutoApplyTagSuggestions adds confident LLM tag suggestions to notes
+	// without asking
+	AutoApplyTagSuggestions bool `json:"auto_apply_tag_suggestions"`
+	// DigestFrequency is how often the user receives a digest email: off,
+	// daily or weekly
+	DigestFrequency string `json:"digest_frequency"`
+	// JournalTemplateID is the template new daily notes are created from
+	JournalTemplateID *uuid.UUID `json:"journal_template_id"`
+}
+
+// UpdateUserSettingsRequest represents the request to update user preferences.
+// Omitted fields are left unchanged.
+type UpdateUserSettingsRequest struct {
+	AutoApplyTagSuggestions *bool   `json:"auto_apply_tag_suggestions,omitempty"`
+	DigestFrequency         *string `json:"digest_frequency,omitempty"`
+	// JournalTemplateID sets the journal template; an empty string clears it
+	JournalTemplateID *string `json:"journal_template_id,omitempty"`
+}
+
+// Validate validates the settings set in the request
+func (r *UpdateUserSettingsRequest) Validate() error {
+	if r.DigestFrequency != nil {
+		if err := ValidateDigestFrequency(*r.DigestFrequency); err != nil {
+			return err
+		}
+	}
+	if r.JournalTemplateID != nil && *r.JournalTemplateID != "" {
+		if _, err := uuid.Parse(*r.JournalTemplateID); err != nil {
+			return fmt.Errorf("journal_template_id must be a template ID")
+		}
+	}
+	return nil
+}
+
 // UserSearchResult represents a user search result
 type UserSearchResult struct {
 	ID        uuid.UUID `json:"id"`
@@ -65,24 +112,58 @@ type User struct {
 	AvatarURL *string   `json:"avatar_url,omitempty" db:"avatar_url"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// ReadOnlyUntil is set while the account is locked after suspicious activity
+	ReadOnlyUntil *time.Time `json:"read_only_until,omitempty" db:"read_only_until"`
+	Role          string     `json:"role" db:"role"`
+	// DisabledAt is set while an administrator has disabled the account
+	DisabledAt *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+	// DeletionScheduledAt is when the account will be purged, set while the
+	// user can still restore it
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" db:"deletion_scheduled_at"`
+	// TwoFactorEnabled is set once the user has enrolled an authenticator app
+	TwoFactorEnabled bool `json:"two_factor_enabled" db:"two_factor_enabled"`
 }
 
 // UserResponse is the safe response format for user data
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	AvatarURL *string   `json:"avatar_url,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	AvatarURL           *string    `json:"avatar_url,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	ReadOnlyUntil       *time.Time `json:"read_only_until,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
 }
 
 // ToResponse converts User to UserResponse (omits sensitive data)
 func (u *User) ToResponse() UserResponse {
-	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		AvatarURL: u.AvatarURL,
-		CreatedAt: u.CreatedAt,
+	response := UserResponse{
+		ID:                  u.ID,
+		Email:               u.Email,
+		AvatarURL:           u.AvatarURL,
+		CreatedAt:           u.CreatedAt,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		TwoFactorEnabled:    u.TwoFactorEnabled,
+	}
+	if u.IsReadOnly() {
+		response.ReadOnlyUntil = u.ReadOnlyUntil
 	}
+	return response
+}
+
+// IsReadOnly reports whether the account is currently locked to read-only access
+func (u *User) IsReadOnly() bool {
+	return u.ReadOnlyUntil != nil && time.Now().Before(*u.ReadOnlyUntil)
+}
+
+// IsAdmin reports whether the user has the administrator role
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// IsDisabled reports whether an administrator has disabled the account
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
 
 // Validate validates the user data
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// VoiceMemoTag is added to every note transcribed from a voice memo
+const VoiceMemoTag = "#voicememo"
+
+// VoiceMemoSource is the metadata source of transcribed notes
+const VoiceMemoSource = "voicememo"
+
+// Voice memo limits
+const (
+	// maxVoiceMemoTitleWords is the number of transcript words a title is
+	// made of when the request has none
+	maxVoiceMemoTitleWords = 8
+	// maxVoiceMemoTitleLength is the title limit of notes
+	maxVoiceMemoTitleLength = 500
+)
+
+// voiceMemoTruncated ends the transcript of memos too long for a note
+const voiceMemoTruncated = "_The transcript was shortened._"
+
+// voiceMemoLanguageRegex matches ISO-639-1 language codes
+var voiceMemoLanguageRegex = regexp.MustCompile(`^[a-z]{2}$`)
+
+// voiceMemoSniffedFormats are the file extensions of the audio types
+// http.DetectContentType recognizes
+var voiceMemoSniffedFormats = map[string]string{
+	"audio/mpeg":      "mp3",
+	"audio/wave":      "wav",
+	"application/ogg": "ogg",
+	"video/webm":      "webm",
+	"video/mp4":       "m4a",
+}
+
+// VoiceMemoFormats are the file extensions of the audio formats accepted,
+// for audio the type of which cannot be told from its content
+var VoiceMemoFormats = map[string]bool{
+	"flac": true, "m4a": true, "mp3": true, "mp4": true, "mpeg": true,
+	"mpga": true, "oga": true, "ogg": true, "wav": true, "webm": true,
+}
+
+// TranscribeRequest holds the options sent with an uploaded voice memo
+type TranscribeRequest struct {
+	// Title is the note title; the first words of the transcript otherwise
+	Title string
+	Tags  []string
+	// Language is the ISO-639-1 code of the spoken language, empty to
+	// detect it
+	Language string
+	// Prettify runs the note through prettify when an LLM is configured
+	Prettify bool
+}
+
+// VoiceMemo is the transcript of a voice memo
+type VoiceMemo struct {
+	Text     string
+	Duration time.Duration
+	// Segments are the timed parts of the text, empty when the backend does
+	// not time them
+	Segments []VoiceMemoSegment
+}
+
+// VoiceMemoSegment is a part of a transcript starting at Start
+type VoiceMemoSegment struct {
+	Start time.Duration
+	Text  string
+}
+
+// TranscribedNote is the note created from a voice memo
+type TranscribedNote struct {
+	NoteResponse
+	// Duration is the length of the memo in seconds
+	Duration float64 `json:"duration"`
+	// Prettified is set when the note was prettified
+	Prettified bool `json:"prettified"`
+}
+
+// Validate validates the request and normalizes its language
+func (r *TranscribeRequest) Validate() error {
+	r.Title = strings.Join(strings.Fields(r.Title), " ")
+	if len(r.Title) > maxVoiceMemoTitleLength {
+		return fmt.Errorf("title too long (max %d characters)", maxVoiceMemoTitleLength)
+	}
+	r.Language = strings.ToLower(strings.TrimSpace(r.Language))
+	if r.Language != "" && !voiceMemoLanguageRegex.MatchString(r.Language) {
+		return fmt.Errorf("language must be a two-letter ISO-639-1 code")
+	}
+	_, err := normalizeCaptureTags(r.Tags)
+	return err
+}
+
+// VoiceMemoFormat returns the file extension of an audio file's format,
+// told from its content or else its name, or "" when it is not accepted
+func VoiceMemoFormat(audio []byte, filename string) string {
+	if format, ok := voiceMemoSniffedFormats[http.DetectContentType(audio)]; ok {
+		return format
+	}
+	format := strings.ToLower(strings.TrimPrefix(path.Ext(filename), "."))
+	if VoiceMemoFormats[format] {
+		return format
+	}
+	return ""
+}
+
+// ToCreateNoteRequest returns the note of a voice memo: its length, then
+// each segment on a line of its own starting with its time, with
+// VoiceMemoTag and the request's tags on the last line. Transcripts too long
+// for a note are shortened at a segment. The request must be valid.
+func (r *TranscribeRequest) ToCreateNoteRequest(memo *VoiceMemo) (*CreateNoteRequest, error) {
+	userTags, err := normalizeCaptureTags(r.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	header := "Voice memo, " + formatMemoTime(memo.Duration)
+	lines := make([]string, 0, len(memo.Segments))
+	for _, segment := range memo.Segments {
+		lines = append(lines, "["+formatMemoTime(segment.Start)+"] "+strings.Join(strings.Fields(segment.Text), " "))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, strings.TrimSpace(memo.Text))
+	}
+	transcript := strings.Join(lines, "\n")
+	tags := missingTags(transcript, append([]string{VoiceMemoTag}, userTags...))
+	footer := strings.Join(tags, " ")
+
+	// Room left for the transcript between the header and the tags
+	room := MaxContentLength() - len(header) - len(footer) - 4
+	if len(transcript) > room {
+		room -= len(voiceMemoTruncated) + 2
+		if room <= 0 {
+			return nil, fmt.Errorf("tags too long for a note")
+		}
+		cut := truncateBytes(transcript, room)
+		if i := strings.LastIndex(cut, "\n"); i > 0 {
+			cut = cut[:i]
+		}
+		transcript = strings.TrimSpace(cut) + "\n\n" + voiceMemoTruncated
+	}
+
+	parts := []string{header, transcript}
+	if footer != "" {
+		parts = append(parts, footer)
+	}
+
+	title := r.Title
+	if title == "" {
+		title = voiceMemoTitle(memo.Text)
+	}
+	return &CreateNoteRequest{
+		Title:    truncateBytes(title, maxVoiceMemoTitleLength),
+		Content:  strings.Join(parts, "\n\n"),
+		Metadata: &NoteMetadata{Source: VoiceMemoSource},
+	}, nil
+}
+
+// voiceMemoTitle returns the first words of a transcript, ending with an
+// ellipsis when there are more
+func voiceMemoTitle(text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return "Voice memo"
+	}
+	if len(words) <= maxVoiceMemoTitleWords {
+		return strings.Join(words, " ")
+	}
+	title := strings.Join(words[:maxVoiceMemoTitleWords], " ")
+	return strings.TrimRight(title, ".,;:!?") + "…"
+}
+
+// formatMemoTime formats a time in a memo as m:ss, or h:mm:ss from an hour
+func formatMemoTime(d time.Duration) string {
+	total := int(d / time.Second)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
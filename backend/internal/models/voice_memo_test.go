@@ -0,0 +1,95 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranscribeRequestValidate(t *testing.T) {
+	request := TranscribeRequest{Title: "  Standup   notes ", Language: " EN ", Tags: []string{"work"}}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if request.Title != "Standup notes" || request.Language != "en" {
+		t.Errorf("Expected the title and language to be normalized, got %+v", request)
+	}
+
+	for _, invalid := range []TranscribeRequest{
+		{Language: "english"},
+		{Title: strings.Repeat("a", maxVoiceMemoTitleLength+1)},
+		{Tags: []string{"not a tag"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestVoiceMemoFormat(t *testing.T) {
+	tests := []struct {
+		audio    string
+		filename string
+		want     string
+	}{
+		{"ID3\x04\x00\x00\x00\x00\x00\x00", "", "mp3"},
+		{"RIFF\x24\x00\x00\x00WAVEfmt ", "memo", "wav"},
+		{"\xff\xfb\x90\x00", "memo.MP3", "mp3"},
+		{"fLaC\x00\x00\x00\x22", "memo.flac", "flac"},
+		{"%PDF-1.7", "memo.pdf", ""},
+		{"\xff\xfb\x90\x00", "", ""},
+	}
+	for _, tt := range tests {
+		if got := VoiceMemoFormat([]byte(tt.audio), tt.filename); got != tt.want {
+			t.Errorf("VoiceMemoFormat(%q, %q) = %q, want %q", tt.audio, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestVoiceMemoToCreateNoteRequest(t *testing.T) {
+	request := TranscribeRequest{Tags: []string{"groceries", "#VoiceMemo"}}
+	note, err := request.ToCreateNoteRequest(&VoiceMemo{
+		Text:     "Buy milk and eggs. Then call Ada about the trip to Lisbon next week.",
+		Duration: 75 * time.Second,
+		Segments: []VoiceMemoSegment{
+			{Start: 0, Text: " Buy milk and eggs."},
+			{Start: 62500 * time.Millisecond, Text: "Then call Ada about\nthe trip to Lisbon next week."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToCreateNoteRequest failed: %v", err)
+	}
+
+	want := "Voice memo, 1:15\n\n[0:00] Buy milk and eggs.\n[1:02] Then call Ada about the trip to Lisbon next week.\n\n#voicememo #groceries"
+	if note.Content != want {
+		t.Errorf("Unexpected content:\n%s", note.Content)
+	}
+	if note.Title != "Buy milk and eggs. Then call Ada about…" {
+		t.Errorf("Expected the first words as the title, got %q", note.Title)
+	}
+	if note.Metadata == nil || note.Metadata.Source != VoiceMemoSource {
+		t.Errorf("Unexpected metadata %+v", note.Metadata)
+	}
+
+	// Transcripts without segments are kept as one paragraph
+	note, err = (&TranscribeRequest{Title: "Idea"}).ToCreateNoteRequest(&VoiceMemo{Text: "Short one.", Duration: 2 * time.Hour})
+	if err != nil {
+		t.Fatalf("ToCreateNoteRequest failed: %v", err)
+	}
+	if note.Title != "Idea" || note.Content != "Voice memo, 2:00:00\n\nShort one.\n\n#voicememo" {
+		t.Errorf("Unexpected note %q:\n%s", note.Title, note.Content)
+	}
+
+	// Long transcripts are shortened at a segment
+	var segments []VoiceMemoSegment
+	for i := 0; i < 200; i++ {
+		segments = append(segments, VoiceMemoSegment{Start: time.Duration(i) * 10 * time.Second, Text: strings.Repeat("word ", 20)})
+	}
+	note, err = (&TranscribeRequest{}).ToCreateNoteRequest(&VoiceMemo{Text: "word", Segments: segments})
+	if err != nil {
+		t.Fatalf("ToCreateNoteRequest failed: %v", err)
+	}
+	if len(note.Content) > MaxContentLength() || !strings.HasSuffix(note.Content, "word\n\n"+voiceMemoTruncated+"\n\n#voicememo") {
+		t.Errorf("Expected the transcript to be shortened at a segment, got %d bytes ending in %q", len(note.Content), note.Content[len(note.Content)-80:])
+	}
+}
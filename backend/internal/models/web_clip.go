@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// WebClipTag is added to every note clipped from a web page
+const WebClipTag = "#webclip"
+
+// WebClipSource is the metadata source of clipped notes
+const WebClipSource = "webclip"
+
+// Web clip limits
+const (
+	// MaxWebClipURLLength is the longest URL that can be clipped
+	MaxWebClipURLLength = 2048
+	// maxWebClipTitleLength is the title limit of notes
+	maxWebClipTitleLength = 500
+)
+
+// webClipTruncated ends the content of articles too long for a note
+const webClipTruncated = "_The article was shortened; the full text is at the source._"
+
+// WebClipRequest is the payload browser extensions send to clip a page,
+// which the server fetches
+type WebClipRequest struct {
+	URL  string   `json:"url" validate:"required,max=2048"`
+	Tags []string `json:"tags,omitempty"`
+	// Summarize adds an LLM summary of the article when an LLM is
+	// configured
+	Summarize bool `json:"summarize,omitempty"`
+}
+
+// WebClip is the readable article of a clipped page
+type WebClip struct {
+	URL    string
+	Title  string
+	Byline string
+	// Content is the article as Markdown
+	Content string
+	// Summary is the LLM summary, if one was requested
+	Summary string
+}
+
+// Validate validates the request and normalizes its URL, which must be an
+// http or https URL without credentials
+func (r *WebClipRequest) Validate() error {
+	raw := strings.TrimSpace(r.URL)
+	if raw == "" {
+		return fmt.Errorf("url is required")
+	}
+	if len(raw) > MaxWebClipURLLength {
+		return fmt.Errorf("url too long (max %d characters)", MaxWebClipURLLength)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	parsed.Fragment = ""
+	r.URL = parsed.String()
+
+	_, err = normalizeCaptureTags(r.Tags)
+	return err
+}
+
+// ToCreateNoteRequest returns the note of a clipped page: the source URL,
+// the byline and the summary above the article, with WebClipTag and the
+// request's tags on the last line. Articles too long for a note are
+// shortened at a paragraph. The request must be valid.
+func (r *WebClipRequest) ToCreateNoteRequest(clip *WebClip) (*CreateNoteRequest, error) {
+	userTags, err := normalizeCaptureTags(r.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	header := "Source: " + clip.URL
+	if clip.Byline != "" {
+		header += "\nBy " + clip.Byline
+	}
+	if clip.Summary != "" {
+		header += "\n\n> **Summary:** " + strings.Join(strings.Fields(clip.Summary), " ")
+	}
+	article := strings.TrimSpace(clip.Content)
+	tags := missingTags(header+"\n\n"+article, append([]string{WebClipTag}, userTags...))
+	footer := strings.Join(tags, " ")
+
+	// Room left for the article between the header and the tags
+	room := MaxContentLength() - len(header) - len(footer) - 4
+	if len(article) > room {
+		room -= len(webClipTruncated) + 2
+		if room <= 0 {
+			return nil, fmt.Errorf("summary and tags too long for a note")
+		}
+		cut := truncateBytes(article, room)
+		if i := strings.LastIndex(cut, "\n\n"); i > 0 {
+			cut = cut[:i]
+		}
+		article = strings.TrimSpace(cut) + "\n\n" + webClipTruncated
+	}
+
+	parts := []string{header}
+	if article != "" {
+		parts = append(parts, article)
+	}
+	if footer != "" {
+		parts = append(parts, footer)
+	}
+
+	title := strings.Join(strings.Fields(clip.Title), " ")
+	if title == "" {
+		title = clip.URL
+	}
+	return &CreateNoteRequest{
+		Title:    truncateBytes(title, maxWebClipTitleLength),
+		Content:  strings.Join(parts, "\n\n"),
+		Metadata: &NoteMetadata{Source: WebClipSource, SourceURL: clip.URL},
+	}, nil
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a character
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
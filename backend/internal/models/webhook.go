@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events delivered to webhooks
+const (
+	WebhookEventNoteCreated = "note.created"
+	WebhookEventNoteUpdated = "note.updated"
+	WebhookEventNoteDeleted = "note.deleted"
+	WebhookEventTagCreated  = "tag.created"
+	// WebhookEventRuleMatched is sent by notification rules delivering
+	// through webhooks
+	WebhookEventRuleMatched = "rule.matched"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventNoteCreated,
+	WebhookEventNoteUpdated,
+	WebhookEventNoteDeleted,
+	WebhookEventTagCreated,
+	WebhookEventRuleMatched,
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook is a URL that receives signed note and tag events of its owner.
+// The secret is never returned by the API.
+type Webhook struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"-" db:"secret"`
+	Events    []string  `json:"events" db:"events"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookDelivery is an event queued for or sent to a webhook
+type WebhookDelivery struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	WebhookID     uuid.UUID       `json:"webhook_id" db:"webhook_id"`
+	Event         string          `json:"event" db:"event"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	ResponseCode  *int            `json:"response_code,omitempty" db:"response_code"`
+	Error         *string         `json:"error,omitempty" db:"error"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// CreateWebhookRequest represents the request to register a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Secret string   `json:"secret" validate:"required,min=16,max=255"`
+	Events []string `json:"events,omitempty"`
+}
+
+// Validate validates and normalizes the request. Without events the webhook
+// subscribes to all of them.
+func (r *CreateWebhookRequest) Validate() error {
+	r.URL = strings.TrimSpace(r.URL)
+	if r.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if len(r.Secret) < 16 {
+		return fmt.Errorf("secret must be at least 16 characters")
+	}
+	if len(r.Secret) > 255 {
+		return fmt.Errorf("secret too long (max 255 characters)")
+	}
+
+	if len(r.Events) == 0 {
+		r.Events = append([]string(nil), WebhookEvents...)
+		return nil
+	}
+
+	events := make([]string, 0, len(r.Events))
+	seen := make(map[string]bool, len(r.Events))
+	for _, event := range r.Events {
+		event = strings.TrimSpace(event)
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("unknown event %q: events must be among %s", event, strings.Join(WebhookEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	r.Events = events
+	return nil
+}
+
+// isWebhookEvent reports whether event is a known webhook event
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestCreateWebhookRequestValidate(t *testing.T) {
+	request := CreateWebhookRequest{URL: " https://example.com/hooks ", Secret: "0123456789abcdef"}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if request.URL != "https://example.com/hooks" {
+		t.Errorf("Expected the URL to be trimmed, got %q", request.URL)
+	}
+	if len(request.Events) != len(WebhookEvents) {
+		t.Errorf("Expected all events by default, got %v", request.Events)
+	}
+
+	request = CreateWebhookRequest{
+		URL:    "https://example.com/hooks",
+		Secret: "0123456789abcdef",
+		Events: []string{"note.deleted", " note.deleted", "tag.created"},
+	}
+	if err := request.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(request.Events) != 2 || request.Events[0] != WebhookEventNoteDeleted || request.Events[1] != WebhookEventTagCreated {
+		t.Errorf("Expected deduplicated events, got %v", request.Events)
+	}
+
+	for _, invalid := range []CreateWebhookRequest{
+		{Secret: "0123456789abcdef"},
+		{URL: "https://example.com", Secret: "short"},
+		{URL: "https://example.com", Secret: "0123456789abcdef", Events: []string{"note.viewed"}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
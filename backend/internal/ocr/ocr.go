@@ -0,0 +1,91 @@
+// Package ocr recognizes the text in images, such as photos of whiteboards
+// and scanned documents, so notes can be found by it. Engines implement
+// Recognizer; Tesseract runs the tesseract command line tool.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// ErrUnavailable is returned when the OCR engine cannot be run
+var ErrUnavailable = errors.New("OCR engine unavailable")
+
+// Recognizer recognizes the text in an image
+type Recognizer interface {
+	// Recognize returns the text in a PNG, JPEG, GIF, WebP or BMP image,
+	// or "" for images without text
+	Recognize(ctx context.Context, image []byte) (string, error)
+}
+
+// Tesseract recognizes text with the tesseract command line tool
+type Tesseract struct {
+	path      string
+	languages string
+}
+
+// NewTesseract creates a Tesseract recognizer running the tool at path, or
+// found in PATH for a bare name, for languages such as "eng" or "eng+deu"
+func NewTesseract(path, languages string) *Tesseract {
+	if path == "" {
+		path = "tesseract"
+	}
+	if languages == "" {
+		languages = "eng"
+	}
+	return &Tesseract{path: path, languages: languages}
+}
+
+// Check reports whether the tool can be run
+func (t *Tesseract) Check() error {
+	if _, err := exec.LookPath(t.path); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Recognize implements Recognizer, passing the image on stdin
+func (t *Tesseract) Recognize(ctx context.Context, image []byte) (string, error) {
+	cmd := exec.CommandContext(ctx, t.path, "stdin", "stdout", "-l", t.languages)
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return Normalize(stdout.String()), nil
+}
+
+// Normalize trims recognized text: lines are trimmed, runs of blank lines
+// become one, and form feeds between pages are dropped
+func Normalize(text string) string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\f", "\n")
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,56 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	got := Normalize("  Sprint goals \r\n\n\n- ship search  \n\f\n\nTODO\n\n")
+	want := "Sprint goals\n\n- ship search\n\nTODO"
+	if got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+	if got := Normalize(" \n\f "); got != "" {
+		t.Errorf("Expected no text, got %q", got)
+	}
+}
+
+func TestTesseract(t *testing.T) {
+	// A stand-in for tesseract echoing its arguments and input
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "fake-tesseract")
+	script := "#!/bin/sh\necho \"args: $*\"\necho\ncat\n"
+	if err := os.WriteFile(tool, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	recognizer := NewTesseract(tool, "eng+deu")
+	if err := recognizer.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	text, err := recognizer.Recognize(context.Background(), []byte("  whiteboard  \n"))
+	if err != nil {
+		t.Fatalf("Recognize failed: %v", err)
+	}
+	if text != "args: stdin stdout -l eng+deu\n\nwhiteboard" {
+		t.Errorf("Unexpected text %q", text)
+	}
+
+	failing := filepath.Join(dir, "failing-tesseract")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\necho 'Error in pixReadStream' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTesseract(failing, "").Recognize(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "pixReadStream") {
+		t.Errorf("Expected the tool's error, got %v", err)
+	}
+
+	missing := NewTesseract(filepath.Join(dir, "missing"), "")
+	if err := missing.Check(); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable for a missing tool, got %v", err)
+	}
+}
@@ -0,0 +1,544 @@
+// Package openapi generates the OpenAPI 3 description of the REST API. The
+// paths come from walking the router, so the spec cannot list routes the
+// server does not serve; request and response shapes come from a registry of
+// typed routes kept next to the handlers.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Auth describes how a route authenticates requests
+type Auth int
+
+const (
+	// AuthUser routes need a bearer access token. Routes accepted by
+	// Options.APIKeyAllowed also take an API key.
+	AuthUser Auth = iota
+	// AuthPublic routes need no credentials
+	AuthPublic
+	// AuthAdmin routes need the access token of an administrator
+	AuthAdmin
+	// AuthAPIKey routes only take an API key
+	AuthAPIKey
+	// AuthTransferToken routes need the token of a migration transfer
+	AuthTransferToken
+)
+
+// Route documents a route registered on the router. Request and Response
+// are values of the types the handler decodes and encodes; only their types
+// are used.
+type Route struct {
+	Summary     string
+	Description string
+	Auth        Auth
+	Query       []Param
+	Headers     []Param
+
+	// Request is the JSON request body. RequestContentType replaces JSON for
+	// routes taking uploads, which are documented as binary.
+	Request            interface{}
+	RequestContentType string
+
+	// Status is the success status, 200 when unset
+	Status int
+	// Response is the data of the {"success": true, "data": ...} envelope.
+	// Raw responses are sent without the envelope; ResponseContentType
+	// replaces JSON for routes sending files.
+	Response            interface{}
+	Raw                 bool
+	ResponseContentType string
+
+	// Errors lists statuses the route returns besides the ones every route
+	// of its kind can: 409, 410, 412, 413, 422 or 428
+	Errors []int
+}
+
+// Param is a query or header parameter
+type Param struct {
+	Name        string
+	Type        string // "string" unless set: "integer", "boolean" or "array" (comma separated strings)
+	Description string
+	Required    bool
+}
+
+// Options configures a generated document
+type Options struct {
+	Title       string
+	Version     string
+	Description string
+	ServerURL   string
+	// APIKeyAllowed reports whether API keys may access a path
+	APIKeyAllowed func(path string) bool
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+// Operation is a method on a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Style       string  `json:"style,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response, or a reference to a shared one
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the shared schemas, responses and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	Responses       map[string]*Response      `json:"responses"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme is a way of authenticating
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Security scheme names
+const (
+	SchemeBearer        = "bearerAuth"
+	SchemeAPIKey        = "apiKeyAuth"
+	SchemeTransferToken = "transferToken"
+)
+
+// Headers of the security schemes
+const (
+	APIKeyHeader        = "X-API-Key"
+	TransferTokenHeader = "X-Migration-Token"
+)
+
+// pathVariable matches a route variable with an optional pattern
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(?::([^}]*))?\}`)
+
+// Build documents every route of router with a method. Routes missing from
+// routes, which is keyed by "METHOD /path" with patterns stripped from path
+// variables, are returned as an error so the registry cannot fall behind.
+func Build(router *mux.Router, routes map[string]Route, opts Options) (*Document, error) {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       opts.Title,
+			Version:     opts.Version,
+			Description: opts.Description,
+		},
+		Paths: make(map[string]PathItem),
+	}
+	if opts.ServerURL != "" {
+		doc.Servers = []Server{{URL: opts.ServerURL}}
+	}
+
+	var undocumented []string
+	tags := make(map[string]bool)
+	operationIDs := make(map[string]string)
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+
+		path := pathVariable.ReplaceAllString(template, "{$1}")
+		for _, method := range methods {
+			key := method + " " + path
+			spec, ok := routes[key]
+			if !ok {
+				undocumented = append(undocumented, key)
+				continue
+			}
+
+			tag, operationID := handlerName(route.GetHandler())
+			if other, taken := operationIDs[operationID]; taken && other != key {
+				operationID = lowerFirst(tag) + upperFirst(operationID)
+			}
+			operationIDs[operationID] = key
+			tags[tag] = true
+
+			op := g.operation(method, path, template, spec, opts)
+			op.OperationID = operationID
+			op.Tags = []string{tag}
+
+			item, ok := doc.Paths[path]
+			if !ok {
+				item = make(PathItem)
+				doc.Paths[path] = item
+			}
+			item[strings.ToLower(method)] = op
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk routes: %w", err)
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("routes missing from the API registry: %s", strings.Join(undocumented, ", "))
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	doc.Components = g.components()
+	return doc, nil
+}
+
+// operation documents a route
+func (g *generator) operation(method, path, template string, spec Route, opts Options) *Operation {
+	op := &Operation{
+		Summary:     spec.Summary,
+		Description: spec.Description,
+		Responses:   make(map[string]*Response),
+	}
+
+	switch spec.Auth {
+	case AuthUser, AuthAdmin:
+		op.Security = []map[string][]string{{SchemeBearer: {}}}
+		if spec.Auth == AuthUser && opts.APIKeyAllowed != nil && opts.APIKeyAllowed(path) {
+			op.Security = append(op.Security, map[string][]string{SchemeAPIKey: {}})
+		}
+	case AuthAPIKey:
+		op.Security = []map[string][]string{{SchemeAPIKey: {}}}
+	case AuthTransferToken:
+		op.Security = []map[string][]string{{SchemeTransferToken: {}}}
+	}
+
+	// Path variables, integers when their pattern only allows digits
+	for _, match := range pathVariable.FindAllStringSubmatch(template, -1) {
+		schema := &Schema{Type: "string"}
+		if match[2] == "[0-9]+" {
+			schema = &Schema{Type: "integer"}
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: schema})
+	}
+	for _, param := range spec.Query {
+		op.Parameters = append(op.Parameters, queryParameter(param))
+	}
+	for _, param := range spec.Headers {
+		parameter := queryParameter(param)
+		parameter.In = "header"
+		op.Parameters = append(op.Parameters, parameter)
+	}
+
+	if spec.RequestContentType != "" {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				spec.RequestContentType: {Schema: &Schema{Type: "string", Format: "binary"}},
+			},
+		}
+	} else if spec.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(spec.Request)}},
+		}
+	}
+
+	status := spec.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[fmt.Sprint(status)] = g.successResponse(status, spec)
+
+	// Errors every route of the kind can return
+	if len(op.Parameters) > 0 || op.RequestBody != nil {
+		op.Responses["400"] = responseRef("BadRequest")
+	}
+	if spec.Auth != AuthPublic {
+		op.Responses["401"] = responseRef("Unauthorized")
+		op.Responses["403"] = responseRef("Forbidden")
+	}
+	if strings.Contains(path, "{") {
+		op.Responses["404"] = responseRef("NotFound")
+	}
+	if (spec.Auth == AuthUser || spec.Auth == AuthAdmin || spec.Auth == AuthAPIKey) && method != http.MethodGet {
+		op.Responses["423"] = responseRef("Locked")
+	}
+	for _, status := range spec.Errors {
+		if name, ok := routeErrors[status]; ok {
+			op.Responses[fmt.Sprint(status)] = responseRef(name)
+		}
+	}
+	op.Responses["429"] = responseRef("TooManyRequests")
+	op.Responses["500"] = responseRef("InternalError")
+	return op
+}
+
+// successResponse documents the success response of a route
+func (g *generator) successResponse(status int, spec Route) *Response {
+	response := &Response{Description: http.StatusText(status)}
+	switch {
+	case strings.HasPrefix(spec.ResponseContentType, "text/"):
+		response.Content = map[string]MediaType{spec.ResponseContentType: {Schema: &Schema{Type: "string"}}}
+	case spec.ResponseContentType != "":
+		response.Content = map[string]MediaType{
+			spec.ResponseContentType: {Schema: &Schema{Type: "string", Format: "binary"}},
+		}
+	case spec.Raw:
+		response.Content = map[string]MediaType{"application/json": {Schema: g.schemaOf(spec.Response)}}
+	default:
+		envelope := &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+			},
+			Required: []string{"success"},
+		}
+		if spec.Response != nil {
+			envelope.Properties["data"] = g.schemaOf(spec.Response)
+			envelope.Required = append(envelope.Required, "data")
+		}
+		response.Content = map[string]MediaType{"application/json": {Schema: envelope}}
+	}
+	return response
+}
+
+// queryParameter documents a query parameter
+func queryParameter(param Param) Parameter {
+	parameter := Parameter{
+		Name:        param.Name,
+		In:          "query",
+		Description: param.Description,
+		Required:    param.Required,
+	}
+	switch param.Type {
+	case "", "string":
+		parameter.Schema = &Schema{Type: "string"}
+	case "array":
+		explode := false
+		parameter.Style = "form"
+		parameter.Explode = &explode
+		parameter.Schema = &Schema{Type: "array", Items: &Schema{Type: "string"}}
+	default:
+		parameter.Schema = &Schema{Type: param.Type}
+	}
+	return parameter
+}
+
+// routeErrors names the shared responses of Route.Errors
+var routeErrors = map[int]string{
+	http.StatusConflict:              "Conflict",
+	http.StatusGone:                  "Gone",
+	http.StatusPreconditionFailed:    "PreconditionFailed",
+	http.StatusRequestEntityTooLarge: "PayloadTooLarge",
+	http.StatusUnprocessableEntity:   "UnprocessableEntity",
+	http.StatusPreconditionRequired:  "ConfirmationRequired",
+}
+
+// responseRef references a shared response
+func responseRef(name string) *Response {
+	return &Response{Ref: "#/components/responses/" + name}
+}
+
+// components returns the shared schemas, error responses and security
+// schemes
+func (g *generator) components() Components {
+	g.schemas["ErrorResponse"] = &Schema{
+		Type:        "object",
+		Description: "Error sent by handlers and middleware",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean", Enum: []interface{}{false}},
+			"error": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"code": {
+						Type:        "string",
+						Description: "Machine-readable code, such as NOTE_NOT_FOUND or NOTE_VERSION_CONFLICT; errors without a specific code use the code of their status, such as BAD_REQUEST or NOT_FOUND",
+					},
+					"message":      {Type: "string"},
+					"details":      {Type: "string"},
+					"position":     {Type: "integer", Description: "Start of the error within a search query"},
+					"length":       {Type: "integer", Description: "Length of the error within a search query"},
+					"confirmation": {Type: "object", Description: "Set when the operation needs a confirmation code"},
+					"current":      {Type: "object", Description: "The stored note, set when a conditional update did not match"},
+					"fields": {
+						Type:        "array",
+						Description: "The invalid fields, set when the request failed validation",
+						Items: &Schema{
+							Type: "object",
+							Properties: map[string]*Schema{
+								"field":   {Type: "string", Description: "Path of the field in the request, such as [1].content"},
+								"rule":    {Type: "string"},
+								"message": {Type: "string"},
+							},
+						},
+					},
+				},
+				Required: []string{"code", "message"},
+			},
+		},
+		Required: []string{"success", "error"},
+	}
+	errorResponse := func(description string) *Response {
+		return &Response{
+			Description: description,
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"}}},
+		}
+	}
+
+	return Components{
+		Schemas: g.schemas,
+		Responses: map[string]*Response{
+			"BadRequest":           errorResponse("The request is invalid"),
+			"Unauthorized":         errorResponse("Credentials are missing, invalid or expired"),
+			"Forbidden":            errorResponse("The account is disabled or lacks the required role"),
+			"NotFound":             errorResponse("The resource does not exist or belongs to another user"),
+			"Conflict":             errorResponse("The request conflicts with the current state, such as a stale version or a legal hold"),
+			"Gone":                 errorResponse("The cursor expired; start again without it"),
+			"PreconditionFailed":   errorResponse("The note changed since the given version; error.current is the stored copy"),
+			"PayloadTooLarge":      errorResponse("The request body is too large"),
+			"UnprocessableEntity":  errorResponse("The request failed validation, with error.fields listing each invalid field, or the Idempotency-Key was already used for a different request"),
+			"ConfirmationRequired": errorResponse("The operation needs the confirmation code sent by email; error.confirmation identifies it"),
+			"Locked":               errorResponse("The account is read-only after unusual activity"),
+			"TooManyRequests":      errorResponse("Rate limit or monthly LLM token budget exceeded"),
+			"InternalError":        errorResponse("Unexpected server error"),
+		},
+		SecuritySchemes: map[string]SecurityScheme{
+			SchemeBearer: {
+				Type:         "http",
+				Scheme:       "bearer",
+				BearerFormat: "JWT",
+				Description:  "Access token from POST /auth/chrome or POST /auth/refresh",
+			},
+			SchemeAPIKey: {
+				Type:        "apiKey",
+				In:          "header",
+				Name:        APIKeyHeader,
+				Description: "API key from POST /api-keys, also accepted as a bearer token",
+			},
+			SchemeTransferToken: {
+				Type:        "apiKey",
+				In:          "header",
+				Name:        TransferTokenHeader,
+				Description: "Token of a migration transfer from POST /migrations/incoming",
+			},
+		},
+	}
+}
+
+// handlerName returns the tag and operation ID of a handler method, such as
+// "SavedSearches" and "listSavedSearches" for SavedSearchesHandler.ListSavedSearches
+func handlerName(handler http.Handler) (string, string) {
+	value := reflect.ValueOf(handler)
+	if value.Kind() != reflect.Func {
+		return "Default", "operation"
+	}
+	name := runtime.FuncForPC(value.Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+
+	method := name[strings.LastIndex(name, ".")+1:]
+	receiver := ""
+	if start := strings.Index(name, "(*"); start >= 0 {
+		if end := strings.Index(name[start:], ")"); end >= 0 {
+			receiver = name[start+2 : start+end]
+		}
+	}
+	receiver = strings.TrimSuffix(receiver, "Handler")
+	if receiver == "" {
+		receiver = "Default"
+	}
+	return receiver, lowerFirst(method)
+}
+
+// lowerFirst lower cases the first letter of s
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// upperFirst upper cases the first letter of s
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type testItemHandler struct{}
+
+func (h *testItemHandler) ListItems(w http.ResponseWriter, r *http.Request) {}
+func (h *testItemHandler) GetItem(w http.ResponseWriter, r *http.Request)   {}
+func (h *testItemHandler) Upload(w http.ResponseWriter, r *http.Request)    {}
+
+type testAudit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testItem struct {
+	testAudit
+	ID       uuid.UUID   `json:"id"`
+	Name     string      `json:"name"`
+	Note     *string     `json:"note"`
+	Tags     []string    `json:"tags,omitempty"`
+	Children []*testItem `json:"children,omitempty"`
+	Secret   string      `json:"-"`
+	internal string
+}
+
+func newTestRouter() *mux.Router {
+	h := &testItemHandler{}
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/items", h.ListItems).Methods("GET")
+	api.HandleFunc("/items/{id}", h.GetItem).Methods("GET")
+	api.HandleFunc("/items/{id}/parts/{part:[0-9]+}", h.Upload).Methods("PUT")
+	return router
+}
+
+var testRoutes = map[string]Route{
+	"GET /api/items": {
+		Summary:  "List items",
+		Query:    []Param{{Name: "limit", Type: "integer"}, {Name: "tags", Type: "array"}},
+		Response: []testItem{},
+	},
+	"GET /api/items/{id}": {
+		Summary:  "Get an item",
+		Auth:     AuthPublic,
+		Response: testItem{},
+		Raw:      true,
+	},
+	"PUT /api/items/{id}/parts/{part}": {
+		Summary:            "Upload a part",
+		Auth:               AuthAdmin,
+		Headers:            []Param{{Name: "Idempotency-Key"}},
+		RequestContentType: "application/octet-stream",
+		Status:             http.StatusCreated,
+		Errors:             []int{http.StatusConflict, http.StatusUnprocessableEntity},
+	},
+}
+
+func TestBuild(t *testing.T) {
+	doc, err := Build(newTestRouter(), testRoutes, Options{
+		Title:         "Test",
+		Version:       "1.0.0",
+		APIKeyAllowed: func(path string) bool { return strings.HasPrefix(path, "/api/items") },
+	})
+	if err != nil {
+		t.Fatalf("Failed to build document: %v", err)
+	}
+
+	list := doc.Paths["/api/items"]["get"]
+	if list == nil {
+		t.Fatalf("Expected GET /api/items, got paths %v", doc.Paths)
+	}
+	if list.OperationID != "listItems" || list.Tags[0] != "testItem" {
+		t.Errorf("Expected operation listItems tagged testItem, got %s %v", list.OperationID, list.Tags)
+	}
+	if len(list.Security) != 2 || list.Security[1][SchemeAPIKey] == nil {
+		t.Errorf("Expected bearer and API key security, got %v", list.Security)
+	}
+	data := list.Responses["200"].Content["application/json"].Schema.Properties["data"]
+	if data == nil || data.Type != "array" || data.Items.Ref != "#/components/schemas/TestItem" {
+		t.Errorf("Expected data wrapped in the envelope, got %+v", list.Responses["200"])
+	}
+	if list.Parameters[1].Schema.Type != "array" || list.Parameters[1].Explode == nil {
+		t.Errorf("Expected a comma separated array parameter, got %+v", list.Parameters[1])
+	}
+	for _, status := range []string{"400", "401", "403", "429", "500"} {
+		if list.Responses[status] == nil {
+			t.Errorf("Expected a %s response", status)
+		}
+	}
+
+	get := doc.Paths["/api/items/{id}"]["get"]
+	if get.Security != nil || get.Responses["401"] != nil {
+		t.Errorf("Expected a public operation, got security %v", get.Security)
+	}
+	if get.Responses["200"].Content["application/json"].Schema.Ref != "#/components/schemas/TestItem" {
+		t.Errorf("Expected the raw item schema, got %+v", get.Responses["200"])
+	}
+	if get.Responses["404"] == nil || get.Parameters[0].In != "path" {
+		t.Errorf("Expected the id path parameter and a 404 response")
+	}
+
+	upload := doc.Paths["/api/items/{id}/parts/{part}"]["put"]
+	if upload.Parameters[1].Schema.Type != "integer" {
+		t.Errorf("Expected a numeric part parameter, got %+v", upload.Parameters[1].Schema)
+	}
+	if upload.Security[0][SchemeBearer] == nil || len(upload.Security) != 1 {
+		t.Errorf("Expected admin routes to need a bearer token only, got %v", upload.Security)
+	}
+	if upload.Parameters[2].In != "header" || upload.Parameters[2].Name != "Idempotency-Key" {
+		t.Errorf("Expected the Idempotency-Key header, got %+v", upload.Parameters[2])
+	}
+	for _, status := range []string{"201", "409", "422", "423"} {
+		if upload.Responses[status] == nil {
+			t.Errorf("Expected a %s response", status)
+		}
+	}
+	if upload.RequestBody.Content["application/octet-stream"].Schema.Format != "binary" {
+		t.Errorf("Expected a binary request body, got %+v", upload.RequestBody)
+	}
+}
+
+func TestBuildRejectsUndocumentedRoutes(t *testing.T) {
+	routes := map[string]Route{"GET /api/items": testRoutes["GET /api/items"]}
+
+	_, err := Build(newTestRouter(), routes, Options{})
+	if err == nil {
+		t.Fatal("Expected an error for undocumented routes")
+	}
+	if !strings.Contains(err.Error(), "GET /api/items/{id}") || !strings.Contains(err.Error(), "PUT /api/items/{id}/parts/{part}") {
+		t.Errorf("Expected the undocumented routes in the error, got %v", err)
+	}
+}
+
+func TestStructSchema(t *testing.T) {
+	g := newGenerator()
+	ref := g.schemaOf(testItem{})
+	if ref.Ref != "#/components/schemas/TestItem" {
+		t.Fatalf("Expected a component reference, got %+v", ref)
+	}
+
+	schema := g.schemas["TestItem"]
+	if got := schema.Properties["created_at"]; got == nil || got.Format != "date-time" {
+		t.Errorf("Expected the embedded created_at timestamp, got %+v", got)
+	}
+	if got := schema.Properties["id"]; got.Format != "uuid" {
+		t.Errorf("Expected a uuid id, got %+v", got)
+	}
+	if got := schema.Properties["note"]; !got.Nullable {
+		t.Errorf("Expected a nullable note, got %+v", got)
+	}
+	if got := schema.Properties["children"]; got.Items.Ref != "#/components/schemas/TestItem" {
+		t.Errorf("Expected children to reference the item schema, got %+v", got)
+	}
+	for _, name := range []string{"Secret", "-", "internal"} {
+		if schema.Properties[name] != nil {
+			t.Errorf("Expected %s to be skipped", name)
+		}
+	}
+
+	required := strings.Join(schema.Required, ",")
+	if required != "created_at,id,name" {
+		t.Errorf("Expected created_at, id and name to be required, got %s", required)
+	}
+}
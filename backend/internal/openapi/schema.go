@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is a JSON schema of the OpenAPI dialect
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// unsafeName matches characters not allowed in component names, such as
+// the brackets of generic types
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// generator turns Go types into schemas. Named structs become shared
+// component schemas so recursive types such as tag trees terminate.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// newGenerator creates a generator without schemas
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of the type of v
+func (g *generator) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+// schema returns the schema of t
+func (g *generator) schema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid", Nullable: nullable}
+	case rawMessageType:
+		return &Schema{Description: "Any JSON value"}
+	}
+
+	// Types with their own encoding are documented as what they most
+	// likely encode to
+	if t.Kind() != reflect.Struct || t.Name() != "" {
+		if reflect.PointerTo(t).Implements(jsonMarshalerType) {
+			return &Schema{Description: "Any JSON value"}
+		}
+		if reflect.PointerTo(t).Implements(textMarshalerType) {
+			return &Schema{Type: "string", Nullable: nullable}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Nullable: nullable}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float", Nullable: nullable}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double", Nullable: nullable}
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: nullable}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: nullable}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: nullable}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	return &Schema{Description: "Any JSON value"}
+}
+
+// component returns the name of the component schema of the named struct
+// t, adding it on first use
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := upperFirst(unsafeName.ReplaceAllString(t.Name(), "_"))
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = upperFirst(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+	}
+
+	// Registered before the fields so recursive references resolve
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+// structSchema returns the object schema of the exported fields of t,
+// following encoding/json: embedded structs are flattened, fields tagged
+// "-" are skipped and fields without omitempty are required
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	if len(schema.Properties) == 0 {
+		schema.Properties = nil
+	}
+	return schema
+}
+
+// addFields adds the JSON fields of the struct t to schema
+func (g *generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if _, exists := schema.Properties[name]; !exists && !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+		if strings.Contains(options, "string") {
+			schema.Properties[name] = &Schema{Type: "string"}
+			continue
+		}
+		schema.Properties[name] = g.schema(field.Type)
+	}
+}
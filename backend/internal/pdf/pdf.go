@@ -0,0 +1,448 @@
+// Package pdf writes text documents as PDF files for printing. A document is
+// a table of contents followed by sections, each starting on a new A4 page
+// with a bold title, a line of details in small grey type and a body of
+// wrapped text. The contents list each section's page and link to it.
+// Sections may carry a watermark, drawn across and at the foot of each of
+// their pages.
+//
+// Sections are written as they are added, so long documents are streamed;
+// only the section titles are kept until the table of contents is written
+// on Close. Text uses the standard Helvetica fonts, which PDF readers
+// provide, so characters outside Windows-1252 are written as "?".
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Page geometry in points: A4 with 2cm margins
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	margin       = 56.69
+	contentWidth = pageWidth - 2*margin
+)
+
+// style is the font, size and leading of a kind of line
+type style struct {
+	font    string
+	size    float64
+	leading float64
+	gray    float64
+	bold    bool
+}
+
+var (
+	titleStyle   = style{font: "F2", size: 16, leading: 22, bold: true}
+	detailsStyle = style{font: "F1", size: 9, leading: 13, gray: 0.4}
+	bodyStyle    = style{font: "F1", size: 11, leading: 15}
+	entryStyle   = style{font: "F1", size: 11, leading: 18}
+	footerStyle  = style{font: "F1", size: 8, leading: 10, gray: 0.4}
+)
+
+// watermarkSize is the largest font size of watermarks drawn across pages
+const watermarkSize = 36
+
+// Section is a part of a document starting on a new page
+type Section struct {
+	Title string
+	// Details is a line shown under the title, such as dates
+	Details string
+	// Body is the text of the section; its lines are kept and long lines
+	// are wrapped
+	Body string
+	// Watermark is drawn on each page of the section when set
+	Watermark string
+}
+
+// Reserved object numbers; the catalog and page tree are written on Close
+const (
+	catalogObject  = 1
+	pagesObject    = 2
+	fontObject     = 3
+	boldFontObject = 4
+)
+
+// entry is a section listed in the table of contents
+type entry struct {
+	title string
+	page  int // index of its first page in Writer.pages
+}
+
+// Writer writes a PDF document to an underlying writer
+type Writer struct {
+	w       *bufio.Writer
+	offset  int64
+	objects []int64 // byte offset of each object, by object number - 1
+	pages   []int   // object numbers of the section pages, in order
+	entries []entry
+	title   string
+	err     error
+
+	// The page being laid out and the watermark of its section
+	content   *bytes.Buffer
+	y         float64
+	watermark string
+}
+
+// NewWriter starts a document titled title on w
+func NewWriter(w io.Writer, title string) *Writer {
+	p := &Writer{w: bufio.NewWriter(w), title: title}
+	p.objects = make([]int64, boldFontObject)
+	// The binary comment marks the file as binary for transfer programs
+	p.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	p.writeObject(fontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.writeObject(boldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	return p
+}
+
+// AddSection writes a section starting on a new page and lists it in the
+// table of contents
+func (p *Writer) AddSection(section Section) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.watermark = section.Watermark
+	defer func() { p.watermark = "" }()
+
+	p.newPage()
+	p.entries = append(p.entries, entry{title: section.Title, page: len(p.pages)})
+	p.writeText(titleStyle, section.Title)
+	if section.Details != "" {
+		p.writeText(detailsStyle, section.Details)
+	}
+	p.y -= bodyStyle.leading / 2
+	p.writeText(bodyStyle, section.Body)
+	p.endPage()
+	return p.err
+}
+
+// Close writes the table of contents and the document structure. It does
+// not close the underlying writer.
+func (p *Writer) Close() error {
+	if p.err != nil {
+		return p.err
+	}
+
+	contents := p.writeContents()
+	kids := make([]string, 0, len(contents)+len(p.pages))
+	for _, page := range append(contents, p.pages...) {
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	p.writeObject(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	p.writeObject(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+	info := p.addObject(fmt.Sprintf("<< /Title %s /Producer (my-notes) /CreationDate (D:%s) >>",
+		pdfString(p.title), time.Now().UTC().Format("20060102150405Z")))
+
+	// Cross-reference table: entries are exactly 20 bytes
+	xref := p.offset
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", len(p.objects)+1)
+	for _, offset := range p.objects {
+		p.printf("%010d 00000 n \n", offset)
+	}
+	p.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(p.objects)+1, catalogObject, info, xref)
+
+	if p.err == nil {
+		p.err = p.w.Flush()
+	}
+	return p.err
+}
+
+// writeContents writes the table of contents pages and returns their object
+// numbers. Each entry links to the first page of its section.
+func (p *Writer) writeContents() []int {
+	perPage := int((pageHeight - 2*margin) / entryStyle.leading)
+	// The heading takes the place of two entries on the first page
+	firstPage := perPage - 2
+	count := 1
+	if len(p.entries) > firstPage {
+		count += (len(p.entries) - firstPage + perPage - 1) / perPage
+	}
+
+	var pages []int
+	entries := p.entries
+	for i := 0; i < count; i++ {
+		p.newPage()
+		capacity := perPage
+		if i == 0 {
+			p.writeText(titleStyle, "Contents")
+			p.y = pageHeight - margin - 2*entryStyle.leading
+			capacity = firstPage
+		}
+
+		var annotations []string
+		for ; capacity > 0 && len(entries) > 0; capacity-- {
+			e := entries[0]
+			entries = entries[1:]
+			number := fmt.Sprintf("%d", count+e.page+1)
+			numberWidth := textWidth(number, entryStyle)
+			title := truncate(e.title, entryStyle, contentWidth-numberWidth-18)
+
+			p.y -= entryStyle.leading
+			p.showText(entryStyle, margin, p.y, title)
+			p.showText(entryStyle, pageWidth-margin-numberWidth, p.y, number)
+			annotations = append(annotations, fmt.Sprintf("%d 0 R", p.addObject(fmt.Sprintf(
+				"<< /Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] /Dest [%d 0 R /XYZ null null null] >>",
+				margin, p.y-4, pageWidth-margin, p.y+entryStyle.size, p.pages[e.page]))))
+		}
+
+		extra := ""
+		if len(annotations) > 0 {
+			extra = fmt.Sprintf(" /Annots [%s]", strings.Join(annotations, " "))
+		}
+		pages = append(pages, p.writePage(extra))
+	}
+	return pages
+}
+
+// newPage starts laying out a page, drawing the watermark first so that
+// text covers it
+func (p *Writer) newPage() {
+	p.content = &bytes.Buffer{}
+	p.y = pageHeight - margin
+	if p.watermark == "" {
+		return
+	}
+
+	// Across the page from the lower left corner, shrunk to fit
+	across := style{font: "F2", size: watermarkSize, gray: 0.9, bold: true}
+	diagonal := math.Hypot(contentWidth, pageHeight-2*margin)
+	if width := textWidth(p.watermark, across); width > diagonal {
+		across.size = math.Floor(across.size * diagonal / width)
+	}
+	width := textWidth(p.watermark, across)
+	fmt.Fprintf(p.content, "BT /%s %g Tf %g g %.4f %.4f %.4f %.4f %.2f %.2f Tm %s Tj ET\n",
+		across.font, across.size, across.gray, math.Sqrt2/2, math.Sqrt2/2, -math.Sqrt2/2, math.Sqrt2/2,
+		pageWidth/2-width/2*math.Sqrt2/2+across.size/2*math.Sqrt2/2, pageHeight/2-width/2*math.Sqrt2/2-across.size/2*math.Sqrt2/2,
+		pdfString(p.watermark))
+
+	p.showText(footerStyle, margin, margin/2, truncate(p.watermark, footerStyle, contentWidth))
+}
+
+// endPage writes the page being laid out as a section page
+func (p *Writer) endPage() {
+	p.pages = append(p.pages, p.writePage(""))
+}
+
+// writeText lays out text in a style, wrapping it to the content width and
+// breaking pages as needed
+func (p *Writer) writeText(s style, text string) {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		// Indented lines, such as code, keep their indentation when wrapped
+		paragraph = strings.ReplaceAll(paragraph, "\t", "    ")
+		indent := paragraph[:len(paragraph)-len(strings.TrimLeft(paragraph, " "))]
+		lines := wrap(paragraph, s, contentWidth-textWidth(indent, s))
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		for _, line := range lines {
+			if line != "" {
+				line = indent + line
+			}
+			if p.y-s.leading < margin {
+				p.endPage()
+				p.newPage()
+			}
+			p.y -= s.leading
+			if line != "" {
+				p.showText(s, margin, p.y, line)
+			}
+		}
+	}
+}
+
+// showText adds a line of text at x, y to the page being laid out
+func (p *Writer) showText(s style, x, y float64, text string) {
+	fmt.Fprintf(p.content, "BT /%s %g Tf %g g %.2f %.2f Td %s Tj ET\n", s.font, s.size, s.gray, x, y, pdfString(text))
+}
+
+// writePage writes the page being laid out with extra page dictionary
+// entries and returns its object number
+func (p *Writer) writePage(extra string) int {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(p.content.Bytes())
+	zw.Close()
+
+	stream := p.addObject(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	return p.addObject(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R%s >>",
+		pagesObject, pageWidth, pageHeight, fontObject, boldFontObject, stream, extra))
+}
+
+// addObject writes an object with the next object number and returns it
+func (p *Writer) addObject(body string) int {
+	p.objects = append(p.objects, 0)
+	number := len(p.objects)
+	p.writeObject(number, body)
+	return number
+}
+
+// writeObject writes an object with a reserved or allocated number
+func (p *Writer) writeObject(number int, body string) {
+	p.objects[number-1] = p.offset
+	p.printf("%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+// printf writes to the document, keeping track of the offset
+func (p *Writer) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.offset += int64(n)
+	p.err = err
+}
+
+// wrap breaks text into lines no wider than width. Words longer than a line
+// are broken between characters.
+func wrap(text string, s style, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if textWidth(candidate, s) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		for textWidth(word, s) > width {
+			cut := fit(word, s, width)
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit returns the byte length of the longest prefix of text, at least one
+// character, no wider than width
+func fit(text string, s style, width float64) int {
+	used := 0.0
+	for i, r := range text {
+		used += runeWidth(r, s)
+		if used > width && i > 0 {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// truncate shortens text to width, ending it with dots
+func truncate(text string, s style, width float64) string {
+	if textWidth(text, s) <= width {
+		return text
+	}
+	cut := fit(text, s, width-textWidth("...", s))
+	return strings.TrimSpace(text[:cut]) + "..."
+}
+
+// textWidth returns the width of text in points
+func textWidth(text string, s style) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += runeWidth(r, s)
+	}
+	return width
+}
+
+// runeWidth returns the width of a character in points. Characters outside
+// printable ASCII are taken to be as wide as a digit.
+func runeWidth(r rune, s style) float64 {
+	widths := &helveticaWidths
+	if s.bold {
+		widths = &helveticaBoldWidths
+	}
+	width := 556
+	if r >= ' ' && r <= '~' {
+		width = widths[r-' ']
+	}
+	return float64(width) * s.size / 1000
+}
+
+// pdfString returns text as a PDF string literal in WinAnsiEncoding
+func pdfString(text string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range text {
+		if r == utf8.RuneError {
+			r = '?'
+		}
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// winAnsi returns the Windows-1252 code of a character
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= ' ' && r <= '~', r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	for i, special := range winAnsiSpecials {
+		if special == r && r != 0 {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
+
+// winAnsiSpecials are the characters of Windows-1252 codes 0x80 to 0x9F;
+// unused codes are 0
+var winAnsiSpecials = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// Character widths of printable ASCII in thousandths of the font size, from
+// the Adobe font metrics of the standard fonts
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
@@ -0,0 +1,199 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// streamRegex matches the compressed content streams of a document
+var streamRegex = regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+
+// pageTexts returns the decompressed content streams of a document in the
+// order they were written
+func pageTexts(t *testing.T, doc []byte) []string {
+	t.Helper()
+	var texts []string
+	for _, match := range streamRegex.FindAllSubmatchIndex(doc, -1) {
+		length, _ := strconv.Atoi(string(doc[match[2]:match[3]]))
+		r, err := zlib.NewReader(bytes.NewReader(doc[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatalf("Invalid content stream: %v", err)
+		}
+		text, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Invalid content stream: %v", err)
+		}
+		texts = append(texts, string(text))
+	}
+	return texts
+}
+
+// checkStructure checks the cross-reference table of a document against
+// the objects it holds and returns the number of pages
+func checkStructure(t *testing.T, doc []byte) int {
+	t.Helper()
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatalf("Document is not framed as a PDF")
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(doc)
+	if match == nil {
+		t.Fatalf("Document has no startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(doc[xref:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref does not point at the cross-reference table")
+	}
+	lines := strings.Split(string(doc[xref:]), "\n")
+	size, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for number := 1; number < size; number++ {
+		entry := lines[2+number]
+		if len(entry)+1 != 20 {
+			t.Fatalf("Cross-reference entry %q is not 20 bytes", entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if !bytes.HasPrefix(doc[offset:], []byte(fmt.Sprintf("%d 0 obj\n", number))) {
+			t.Errorf("Cross-reference entry of object %d does not point at it", number)
+		}
+	}
+
+	count := regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`).FindSubmatch(doc)
+	if count == nil {
+		t.Fatalf("Document has no page tree")
+	}
+	pages, _ := strconv.Atoi(string(count[1]))
+	return pages
+}
+
+func TestWriterWritesContentsAndSections(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "Notes")
+	if err := w.AddSection(Section{Title: "Standup (Monday)", Details: "Created 2024-03-01", Body: "Discussed roadmap #work\n\n    indented code"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.AddSection(Section{Title: "Café notes", Body: strings.Repeat("A long paragraph that wraps over many lines. ", 400)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc := buf.Bytes()
+	pages := checkStructure(t, doc)
+	texts := pageTexts(t, doc)
+	if pages != len(texts) || pages < 4 {
+		t.Fatalf("Expected a contents page and several section pages, got %d pages and %d streams", pages, len(texts))
+	}
+
+	// Sections are written first; the contents page is written last and
+	// lists the sections with the page they start on
+	contents := texts[len(texts)-1]
+	for _, want := range []string{"(Contents)", `(Standup \(Monday\))`, "(2)", `(Caf\351 notes)`, "(3)"} {
+		if !strings.Contains(contents, want) {
+			t.Errorf("Contents page lacks %s:\n%s", want, contents)
+		}
+	}
+	if got := strings.Count(string(doc), "/Subtype /Link"); got != 2 {
+		t.Errorf("Expected a link per section, got %d", got)
+	}
+	if !strings.Contains(texts[0], "(    indented code)") {
+		t.Errorf("Expected indentation to be kept:\n%s", texts[0])
+	}
+}
+
+func TestWriterSplitsLongContents(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "Notes")
+	for i := 0; i < 100; i++ {
+		if err := w.AddSection(Section{Title: fmt.Sprintf("Note %d", i), Body: "body"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc := buf.Bytes()
+	if pages := checkStructure(t, doc); pages != 103 {
+		t.Fatalf("Expected 3 contents pages and 100 section pages, got %d", pages)
+	}
+	texts := pageTexts(t, doc)
+	// The last entry is on the third contents page and points at page 103
+	if last := texts[len(texts)-1]; !strings.Contains(last, "(Note 99)") || !strings.Contains(last, "(103)") {
+		t.Errorf("Expected the last contents page to list Note 99 on page 103:\n%s", last)
+	}
+}
+
+func TestWriterWatermarksSectionPages(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "Notes")
+	watermark := "Exported by ana@example.com on 2024-03-02 10:00 UTC"
+	if err := w.AddSection(Section{Title: "Plan", Body: strings.Repeat("line\n", 60), Watermark: watermark}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.AddSection(Section{Title: "Diary", Body: "private"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc := buf.Bytes()
+	checkStructure(t, doc)
+	texts := pageTexts(t, doc)
+	if len(texts) != 4 {
+		t.Fatalf("Expected 2 pages of Plan, 1 of Diary and the contents, got %d", len(texts))
+	}
+	// Across the page and in the footer of both pages of the section only
+	for i, text := range texts {
+		want := 0
+		if i < 2 {
+			want = 2
+		}
+		if got := strings.Count(text, "("+watermark+")"); got != want {
+			t.Errorf("Expected the watermark %d times on page %d, got %d", want, i, got)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("one two three", bodyStyle, textWidth("one two", bodyStyle))
+	if strings.Join(lines, "|") != "one two|three" {
+		t.Errorf("wrap = %q", lines)
+	}
+
+	word := strings.Repeat("x", 200)
+	lines = wrap(word, bodyStyle, contentWidth)
+	if len(lines) < 2 || strings.Join(lines, "") != word {
+		t.Errorf("Expected a long word to be broken over lines, got %q", lines)
+	}
+	for _, line := range lines {
+		if textWidth(line, bodyStyle) > contentWidth {
+			t.Errorf("Line %q is wider than the page", line)
+		}
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "(plain)"},
+		{`a (b) \c`, `(a \(b\) \\c)`},
+		{"naïve — “quoted”", `(na\357ve \227 \223quoted\224)`},
+		{"日本", "(??)"},
+	}
+
+	for _, tt := range tests {
+		if got := pdfString(tt.text); got != tt.want {
+			t.Errorf("pdfString(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package readability
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// trackingParams are query parameters added to links to track clicks
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true, "igshid": true,
+	"mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true, "mkt_tok": true, "ref_src": true,
+}
+
+// trackingParamPrefixes start the names of tracking query parameters
+var trackingParamPrefixes = []string{"utm_", "oly_", "vero_"}
+
+// Convert returns an HTML document or fragment, such as rich text copied
+// from Google Docs, Word or a web page, as Markdown. Unlike Extract it keeps
+// all of the content but scripts, styles, forms, hidden elements and
+// tracking images. Relative links are resolved against base, which may be
+// nil.
+func Convert(r io.Reader, base *url.URL) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return "", nil
+	}
+
+	var remove []*html.Node
+	walk(body, func(n *html.Node) bool {
+		if n.Type == html.CommentNode ||
+			(n.Type == html.ElementNode && n != body && (removedTags[n.DataAtom] || isHidden(n) || isTrackingImage(n))) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+
+	converter := &markdownWriter{base: base}
+	converter.children(body)
+	return strings.TrimSpace(blankLines.ReplaceAllString(converter.String(), "\n\n")), nil
+}
+
+// isTrackingImage reports whether an element is an image of a single pixel,
+// as emails and pages embed to track readers
+func isTrackingImage(n *html.Node) bool {
+	if n.DataAtom != atom.Img {
+		return false
+	}
+	width, height := strings.TrimSpace(attr(n, "width")), strings.TrimSpace(attr(n, "height"))
+	return (width == "0" || width == "1") && (height == "0" || height == "1")
+}
+
+// untrack returns a link without its tracking query parameters, unwrapping
+// the redirects of Google search results and Outlook safe links
+func untrack(link *url.URL) *url.URL {
+	host := strings.ToLower(link.Hostname())
+	query := link.Query()
+	var target string
+	switch {
+	case (host == "www.google.com" || host == "google.com") && link.Path == "/url":
+		target = firstNonEmpty(query.Get("q"), query.Get("url"))
+	case strings.HasSuffix(host, ".safelinks.protection.outlook.com"):
+		target = query.Get("url")
+	}
+	if target != "" {
+		if unwrapped, err := url.Parse(target); err == nil && (unwrapped.Scheme == "http" || unwrapped.Scheme == "https") {
+			return untrack(unwrapped)
+		}
+	}
+
+	if link.RawQuery == "" {
+		return link
+	}
+	removed := false
+	for name := range query {
+		if isTrackingParam(strings.ToLower(name)) {
+			query.Del(name)
+			removed = true
+		}
+	}
+	if !removed {
+		return link
+	}
+	cleaned := *link
+	cleaned.RawQuery = query.Encode()
+	return &cleaned
+}
+
+// isTrackingParam reports whether a lowercase query parameter name tracks
+// clicks
+func isTrackingParam(name string) bool {
+	if trackingParams[name] {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package readability
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestConvertGoogleDocs(t *testing.T) {
+	paste := `<meta charset="utf-8"><b style="font-weight:normal;" id="docs-internal-guid-1a2b3c"><h1 dir="ltr"><span style="font-size:20pt;">Sprint plan</span></h1>` +
+		`<p dir="ltr"><span style="font-weight:400;">Ship </span><span style="font-weight:700;">search</span><span style="font-weight:400;"> and </span><span style="font-style:italic;">sync</span><span>.</span></p>` +
+		`<ul><li dir="ltr"><p dir="ltr"><span>Fix the </span><a href="https://www.google.com/url?q=https://tracker.example.com/issues/7%3Futm_source%3Ddocs&amp;sa=D"><span>login bug</span></a></p></li></ul>` +
+		`<br></b>`
+
+	markdown, err := Convert(strings.NewReader(paste), nil)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	want := "**Sprint plan**\n\nShip **search** and _sync_.\n\n- Fix the [login bug](https://tracker.example.com/issues/7)"
+	if markdown != want {
+		t.Errorf("Unexpected Markdown:\n%s\nwant:\n%s", markdown, want)
+	}
+}
+
+func TestConvertWord(t *testing.T) {
+	paste := `<html xmlns:o="urn:schemas-microsoft-com:office:office"><head><style>p.MsoNormal {margin:0}</style></head><body>
+<!--StartFragment--><p class=MsoNormal><b>Agenda<o:p></o:p></b></p>
+<p class=MsoListParagraphCxSpFirst style='text-indent:-.25in;mso-list:l0 level1 lfo1'><![if !supportLists]><span style='font-family:Symbol;mso-list:Ignore'>·<span style='font:7.0pt "Times New Roman"'>&nbsp;&nbsp;&nbsp; </span></span><![endif]>Budget<o:p></o:p></p>
+<p class=MsoListParagraphCxSpLast style='text-indent:-.25in;mso-list:l0 level1 lfo1'><![if !supportLists]><span style='font-family:Symbol;mso-list:Ignore'>·<span>&nbsp; </span></span><![endif]>Hiring<o:p></o:p></p>
+<!--EndFragment--></body></html>`
+
+	markdown, err := Convert(strings.NewReader(paste), nil)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if want := "**Agenda**\n\n- Budget\n\n- Hiring"; markdown != want {
+		t.Errorf("Unexpected Markdown:\n%s\nwant:\n%s", markdown, want)
+	}
+}
+
+func TestConvertWebPage(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.com/posts/1")
+	paste := `<p>Read <a href="/posts/2?utm_source=twitter&amp;utm_medium=social&amp;page=2&amp;fbclid=abc">the next post</a>` +
+		`<img src="https://pixel.example.com/t.gif" width="1" height="1"></p><script>track()</script>` +
+		`<p><a href="https://eur01.safelinks.protection.outlook.com/?url=https%3A%2F%2Fexample.com%2Fdoc%3Fgclid%3D1&amp;data=x">Shared doc</a></p>`
+
+	markdown, err := Convert(strings.NewReader(paste), base)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	want := "Read [the next post](https://blog.example.com/posts/2?page=2)\n\n[Shared doc](https://example.com/doc)"
+	if markdown != want {
+		t.Errorf("Unexpected Markdown:\n%s\nwant:\n%s", markdown, want)
+	}
+}
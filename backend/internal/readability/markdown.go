@@ -0,0 +1,344 @@
+package readability
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownWriter converts article elements to Markdown. Headings become
+// bold lines rather than # headings, which notes would take for hashtags.
+type markdownWriter struct {
+	base *url.URL
+	b    strings.Builder
+}
+
+// String returns the Markdown written so far
+func (w *markdownWriter) String() string {
+	return w.b.String()
+}
+
+// paragraph writes a block of text, separated from the previous one by a
+// blank line
+func (w *markdownWriter) paragraph(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if w.b.Len() > 0 {
+		w.b.WriteString("\n\n")
+	}
+	w.b.WriteString(text)
+}
+
+// block writes an element as one or more blocks
+func (w *markdownWriter) block(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.paragraph(normalizeText(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		if text := w.inline(n); text != "" {
+			w.paragraph("**" + text + "**")
+		}
+	case atom.P:
+		if isWordListItem(n) {
+			w.paragraph("- " + strings.ReplaceAll(w.inline(n), "\n", " "))
+		} else {
+			w.paragraph(w.inline(n))
+		}
+	case atom.Ul, atom.Ol:
+		w.paragraph(strings.Join(w.listLines(n, 0), "\n"))
+	case atom.Blockquote:
+		inner := &markdownWriter{base: w.base}
+		inner.children(n)
+		if text := inner.String(); text != "" {
+			w.paragraph("> " + strings.ReplaceAll(text, "\n", "\n> "))
+		}
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if strings.TrimSpace(code) != "" {
+			w.paragraph("```\n" + code + "\n```")
+		}
+	case atom.Hr:
+		w.paragraph("---")
+	case atom.Img:
+		w.paragraph(w.image(n))
+	case atom.Table:
+		w.table(n)
+	case atom.Figcaption:
+		if text := w.inline(n); text != "" {
+			w.paragraph("_" + text + "_")
+		}
+	default:
+		w.children(n)
+	}
+}
+
+// children writes the children of an element, joining runs of inline
+// content into paragraphs
+func (w *markdownWriter) children(n *html.Node) {
+	var run []*html.Node
+	flush := func() {
+		w.paragraph(w.inlineNodes(run))
+		run = nil
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (isBlock(c) || containsBlock(c)) {
+			flush()
+			w.block(c)
+			continue
+		}
+		run = append(run, c)
+	}
+	flush()
+}
+
+// inline returns the children of an element as inline Markdown
+func (w *markdownWriter) inline(n *html.Node) string {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return w.inlineNodes(nodes)
+}
+
+// inlineNodes returns nodes as inline Markdown, with whitespace collapsed
+// and each line trimmed
+func (w *markdownWriter) inlineNodes(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		w.writeInline(&b, n)
+	}
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(whitespace.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// writeInline writes a node as inline Markdown
+func (w *markdownWriter) writeInline(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(strings.NewReplacer("\n", " ", "\t", " ", "\r", " ").Replace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		b.WriteString("\n")
+	case atom.Strong, atom.B:
+		// Google Docs wraps pastes in <b style="font-weight:normal">
+		if fontStyle(n, "font-weight") == "normal" {
+			b.WriteString(w.inline(n))
+		} else {
+			b.WriteString(wrapInline(w.inline(n), "**"))
+		}
+	case atom.Em, atom.I:
+		b.WriteString(wrapInline(w.inline(n), "_"))
+	case atom.Code, atom.Kbd, atom.Samp:
+		if code := normalizeText(textContent(n)); code != "" {
+			b.WriteString("`" + code + "`")
+		}
+	case atom.A:
+		text := w.inline(n)
+		if href := w.resolve(attr(n, "href")); href != "" && text != "" {
+			b.WriteString("[" + text + "](" + href + ")")
+		} else {
+			b.WriteString(text)
+		}
+	case atom.Img:
+		b.WriteString(w.image(n))
+	default:
+		// Editors such as Google Docs style spans rather than use b and i
+		weight, italic := fontStyle(n, "font-weight"), fontStyle(n, "font-style") == "italic"
+		numeric, _ := strconv.Atoi(weight)
+		bold := weight == "bold" || weight == "bolder" || numeric >= 600
+		if bold || italic {
+			text := w.inline(n)
+			if italic {
+				text = wrapInline(text, "_")
+			}
+			if bold {
+				text = wrapInline(text, "**")
+			}
+			b.WriteString(text)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			w.writeInline(b, c)
+		}
+	}
+}
+
+// listLines returns the lines of a list, nested lists indented below their
+// items
+func (w *markdownWriter) listLines(list *html.Node, depth int) []string {
+	indent := strings.Repeat("  ", depth)
+	var lines []string
+	number := 1
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if list.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+
+		var text []*html.Node
+		var nested []string
+		for c := item.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				nested = append(nested, w.listLines(c, depth+1)...)
+				continue
+			}
+			text = append(text, c)
+		}
+		if line := strings.ReplaceAll(w.inlineNodes(text), "\n", " "); line != "" {
+			lines = append(lines, indent+marker+line)
+		}
+		lines = append(lines, nested...)
+	}
+	return lines
+}
+
+// table writes a table as a Markdown table, or its cells as blocks when it
+// only lays out a single column
+func (w *markdownWriter) table(table *html.Node) {
+	var rows [][]*html.Node
+	columns := 0
+	walk(table, func(n *html.Node) bool {
+		if n != table && n.DataAtom == atom.Table {
+			return false
+		}
+		if n.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []*html.Node
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Td || c.DataAtom == atom.Th {
+				cells = append(cells, c)
+			}
+		}
+		rows = append(rows, cells)
+		if len(cells) > columns {
+			columns = len(cells)
+		}
+		return false
+	})
+
+	if columns <= 1 {
+		for _, row := range rows {
+			for _, cell := range row {
+				w.children(cell)
+			}
+		}
+		return
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		cells := make([]string, columns)
+		for j, cell := range row {
+			cells[j] = strings.ReplaceAll(strings.ReplaceAll(w.inline(cell), "\n", " "), "|", `\|`)
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	w.paragraph(strings.Join(lines, "\n"))
+}
+
+// image returns an image as Markdown, or "" without a web URL
+func (w *markdownWriter) image(n *html.Node) string {
+	src := w.resolve(firstNonEmpty(attr(n, "src"), attr(n, "data-src")))
+	if src == "" {
+		return ""
+	}
+	return "![" + normalizeText(attr(n, "alt")) + "](" + src + ")"
+}
+
+// resolve returns a reference resolved against the page URL, or "" unless
+// it is an http or https URL
+func (w *markdownWriter) resolve(ref string) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if w.base != nil {
+		parsed = w.base.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+	parsed = untrack(parsed)
+	return strings.NewReplacer("(", "%28", ")", "%29").Replace(parsed.String())
+}
+
+// isBlock reports whether an element is written as a block
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Main, atom.Figcaption, atom.Li, atom.Dd, atom.Dt, atom.Details, atom.Summary, atom.Img:
+		return true
+	}
+	return blockTags[n.DataAtom]
+}
+
+// containsBlock reports whether an inline element holds blocks, as the
+// element Google Docs wraps pastes in does
+func containsBlock(n *html.Node) bool {
+	found := false
+	walk(n, func(c *html.Node) bool {
+		if c != n && c.Type == html.ElementNode && blockTags[c.DataAtom] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// isWordListItem reports whether a paragraph is an item of a list pasted
+// from Word, which writes lists as styled paragraphs
+func isWordListItem(n *html.Node) bool {
+	return strings.HasPrefix(attr(n, "class"), "MsoListParagraph") ||
+		strings.Contains(strings.ReplaceAll(attr(n, "style"), " ", ""), "mso-list:l")
+}
+
+// fontStyle returns the lowercase value of a font property of an element's
+// style attribute, or ""
+func fontStyle(n *html.Node, property string) string {
+	for _, declaration := range strings.Split(attr(n, "style"), ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), property) {
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
+
+// wrapInline wraps text in an emphasis marker, keeping the surrounding
+// spaces outside of it
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	return marker + trimmed + marker
+}
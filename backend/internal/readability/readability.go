@@ -0,0 +1,469 @@
+// Package readability extracts the readable article of a web page, leaving
+// out navigation, sidebars, comments and ads, and converts it to Markdown.
+// It follows the approach of Mozilla's Readability: paragraphs score the
+// elements containing them by their length and commas, class and id names
+// hint at content or clutter, and elements made mostly of links are
+// penalized. The best scoring element and its related siblings are the
+// article.
+package readability
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoContent is returned for pages without readable text
+var ErrNoContent = errors.New("page has no readable content")
+
+// Article is the readable content of a page
+type Article struct {
+	Title    string
+	Byline   string
+	Excerpt  string
+	SiteName string
+	// Content is the article as Markdown
+	Content string
+}
+
+// Extraction thresholds
+const (
+	// minParagraphLength is the length of the shortest text scored as a
+	// paragraph
+	minParagraphLength = 25
+	// maxCandidates is the number of top scoring elements considered
+	maxCandidates = 5
+)
+
+var (
+	// unlikelyCandidates matches class and id names of page clutter
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote|cookie|newsletter|share`)
+	// maybeCandidate matches class and id names that redeem an unlikely
+	// candidate
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	// positiveNames matches class and id names of content
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	// negativeNames matches class and id names of clutter
+	negativeNames = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|footer|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|widget`)
+	// whitespace matches runs of whitespace
+	whitespace = regexp.MustCompile(`\s+`)
+	// blankLines matches runs of blank lines
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// removedTags are never part of an article
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Svg: true, atom.Canvas: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+	atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Header: true, atom.Dialog: true,
+	atom.Link: true, atom.Meta: true,
+}
+
+// blockTags are elements that hold blocks, so a div containing one is not a
+// paragraph itself
+var blockTags = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Div: true, atom.Dl: true,
+	atom.Figure: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Hr: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Table: true, atom.Ul: true,
+}
+
+// Extract returns the article of an HTML page. Relative links and images
+// are resolved against pageURL.
+func Extract(r io.Reader, pageURL *url.URL) (*Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	article := &Article{}
+	readMetadata(doc, article)
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return nil, ErrNoContent
+	}
+	removeClutter(body)
+
+	top := topCandidate(body)
+	if top == nil {
+		top = body
+	}
+
+	converter := &markdownWriter{base: pageURL}
+	for _, node := range articleNodes(top) {
+		converter.block(node)
+	}
+	article.Content = strings.TrimSpace(blankLines.ReplaceAllString(converter.String(), "\n\n"))
+	if article.Content == "" {
+		return nil, ErrNoContent
+	}
+
+	if article.Title == "" {
+		if h1 := findFirst(body, atom.H1); h1 != nil {
+			article.Title = normalizeText(textContent(h1))
+		}
+	}
+	return article, nil
+}
+
+// readMetadata reads the title, byline, excerpt and site name from the
+// document's head, preferring Open Graph tags
+func readMetadata(doc *html.Node, article *Article) {
+	meta := make(map[string]string)
+	var title string
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = normalizeText(textContent(n))
+			}
+		case atom.Meta:
+			key := strings.ToLower(attr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(n, "name"))
+			}
+			if content := normalizeText(attr(n, "content")); key != "" && content != "" {
+				if _, ok := meta[key]; !ok {
+					meta[key] = content
+				}
+			}
+		case atom.Body:
+			return false
+		}
+		return true
+	})
+
+	article.Title = firstNonEmpty(meta["og:title"], meta["twitter:title"], title)
+	article.Byline = firstNonEmpty(meta["author"], meta["article:author"])
+	article.Excerpt = firstNonEmpty(meta["og:description"], meta["description"], meta["twitter:description"])
+	article.SiteName = meta["og:site_name"]
+	// URLs are not bylines, as with article:author on many sites
+	if strings.HasPrefix(article.Byline, "http://") || strings.HasPrefix(article.Byline, "https://") {
+		article.Byline = ""
+	}
+}
+
+// removeClutter removes the elements that are never part of an article,
+// hidden elements, and those whose names mark them as clutter
+func removeClutter(root *html.Node) {
+	var remove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n.Type == html.CommentNode {
+			remove = append(remove, n)
+			return false
+		}
+		if n.Type != html.ElementNode || n == root {
+			return true
+		}
+		if removedTags[n.DataAtom] || isHidden(n) {
+			remove = append(remove, n)
+			return false
+		}
+		names := attr(n, "class") + " " + attr(n, "id")
+		if n.DataAtom != atom.Article && n.DataAtom != atom.Main && attr(n, "role") != "main" &&
+			unlikelyCandidates.MatchString(names) && !maybeCandidate.MatchString(names) && !hasAncestor(n, atom.Table) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// topCandidate scores the elements containing paragraphs and returns the
+// best one, or nil when the page has no paragraphs
+func topCandidate(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	walk(body, func(n *html.Node) bool {
+		if !isParagraph(n) {
+			return true
+		}
+		text := normalizeText(textContent(n))
+		if len(text) < minParagraphLength {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil && n.Parent != body {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+	if len(order) == 0 {
+		return nil
+	}
+
+	for _, n := range order {
+		scores[n] *= 1 - linkDensity(n)
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	top := order[0]
+	// An ancestor holding several of the top candidates is the article they
+	// are all part of
+	if len(order) > maxCandidates {
+		order = order[:maxCandidates]
+	}
+	for parent := top.Parent; parent != nil && parent != body; parent = parent.Parent {
+		containing := 0
+		for _, candidate := range order[1:] {
+			if scores[candidate] >= scores[top]*0.75 && isAncestor(parent, candidate) {
+				containing++
+			}
+		}
+		if containing >= 2 {
+			top = parent
+			break
+		}
+	}
+	return top
+}
+
+// articleNodes returns the top candidate and those of its siblings that
+// belong to the article, such as an introduction outside of it
+func articleNodes(top *html.Node) []*html.Node {
+	if top.Parent == nil || top.DataAtom == atom.Body {
+		return []*html.Node{top}
+	}
+	threshold := math.Max(10, contentScore(top)*0.2)
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == top {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if contentScore(sibling) >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := normalizeText(textContent(sibling))
+			density := linkDensity(sibling)
+			if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.Contains(text, ". ")) {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+// contentScore returns the score of an element by its own paragraphs, as
+// topCandidate gives its parent
+func contentScore(n *html.Node) float64 {
+	score := initialScore(n)
+	walk(n, func(c *html.Node) bool {
+		if c == n || !isParagraph(c) {
+			return true
+		}
+		text := normalizeText(textContent(c))
+		if len(text) >= minParagraphLength {
+			score += 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		}
+		return false
+	})
+	return score * (1 - linkDensity(n))
+}
+
+// initialScore returns the score of an element by its tag and names
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+// classWeight returns the weight of an element's class and id names
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// isParagraph reports whether an element is scored as a paragraph: a p,
+// pre or td, or a div without block children
+func isParagraph(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td:
+		return true
+	case atom.Div, atom.Section:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockTags[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// linkDensity returns the share of an element's text that is link text
+func linkDensity(n *html.Node) float64 {
+	length := len(normalizeText(textContent(n)))
+	if length == 0 {
+		return 0
+	}
+	var linkLength int
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			linkLength += len(normalizeText(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(linkLength) / float64(length)
+}
+
+// isHidden reports whether an element is hidden from readers
+func isHidden(n *html.Node) bool {
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	// Word marks the bullets of its lists mso-list:Ignore
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") ||
+		strings.Contains(style, "mso-list:ignore")
+}
+
+// walk calls visit for n and its descendants in document order, skipping
+// the descendants of nodes visit returns false for
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		// visit may remove c
+		next := c.NextSibling
+		walk(c, visit)
+		c = next
+	}
+}
+
+// findFirst returns the first element of a tag in n, or nil
+func findFirst(n *html.Node, tag atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && c.DataAtom == tag {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// isAncestor reports whether ancestor contains n
+func isAncestor(ancestor, n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAncestor reports whether n is inside an element of a tag
+func hasAncestor(n *html.Node, tag atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// textContent returns the text of n and its descendants
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// normalizeText collapses whitespace and trims text
+func normalizeText(text string) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// attr returns the value of an attribute of n, or ""
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether n has an attribute
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package readability
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Growing tomatoes | Garden Blog</title>
+  <meta property="og:title" content="Growing tomatoes">
+  <meta property="og:site_name" content="Garden Blog">
+  <meta name="author" content="Ada Gardener">
+  <meta name="description" content="How to grow tomatoes on a balcony.">
+  <script>var tracking = true;</script>
+</head>
+<body>
+  <header><a href="/">Home</a> <a href="/about">About</a></header>
+  <nav class="menu"><ul><li><a href="/a">Archive</a></li><li><a href="/b">Tags</a></li></ul></nav>
+  <div id="main-content">
+    <article class="post">
+      <h1>Growing tomatoes</h1>
+      <p>Tomatoes need sun, water, and patience. A balcony facing south, with at least six hours of light, is enough for a few plants.</p>
+      <h2>Choosing pots</h2>
+      <p>Use pots of at least twenty liters, with drainage holes, and fill them with <strong>fresh soil</strong>, not soil from <a href="/older-post">last year</a>.</p>
+      <ul>
+        <li>Cherry tomatoes grow well in pots</li>
+        <li>Beefsteak tomatoes need support
+          <ol><li>Stakes</li><li>Cages</li></ol>
+        </li>
+      </ul>
+      <img src="/img/tomato.jpg" alt="A ripe tomato">
+      <pre>water: daily
+feed: weekly</pre>
+      <blockquote><p>The best fertilizer is the gardener's shadow.</p></blockquote>
+      <table><tr><th>Variety</th><th>Days</th></tr><tr><td>Cherry</td><td>60</td></tr></table>
+      <p style="display:none">Hidden text that readers never see, even though it is long enough.</p>
+    </article>
+    <div class="comments"><p>Great post, thanks, I will try this with my own plants next spring!</p></div>
+  </div>
+  <aside class="sidebar"><p>Subscribe to our newsletter for more gardening tips, tricks, and offers.</p></aside>
+  <footer>Copyright, all rights reserved, Garden Blog, since forever and ever.</footer>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	pageURL, _ := url.Parse("https://garden.example.com/posts/tomatoes")
+	article, err := Extract(strings.NewReader(articlePage), pageURL)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	if article.Title != "Growing tomatoes" || article.Byline != "Ada Gardener" ||
+		article.SiteName != "Garden Blog" || article.Excerpt != "How to grow tomatoes on a balcony." {
+		t.Errorf("Unexpected metadata %+v", article)
+	}
+
+	for _, want := range []string{
+		"**Growing tomatoes**\n\nTomatoes need sun, water, and patience.",
+		"**Choosing pots**",
+		"with **fresh soil**, not soil from [last year](https://garden.example.com/older-post).",
+		"- Cherry tomatoes grow well in pots\n- Beefsteak tomatoes need support\n  1. Stakes\n  2. Cages",
+		"![A ripe tomato](https://garden.example.com/img/tomato.jpg)",
+		"```\nwater: daily\nfeed: weekly\n```",
+		"> The best fertilizer is the gardener's shadow.",
+		"| Variety | Days |\n| --- | --- |\n| Cherry | 60 |",
+	} {
+		if !strings.Contains(article.Content, want) {
+			t.Errorf("Expected %q in the content:\n%s", want, article.Content)
+		}
+	}
+	for _, unwanted := range []string{"Archive", "Home", "Hidden text", "Great post", "newsletter", "Copyright", "tracking"} {
+		if strings.Contains(article.Content, unwanted) {
+			t.Errorf("Expected %q to be left out of the content:\n%s", unwanted, article.Content)
+		}
+	}
+}
+
+func TestExtractWithoutArticle(t *testing.T) {
+	article, err := Extract(strings.NewReader(`<html><head><title>Note</title></head><body><div>Just a short line</div></body></html>`), nil)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if article.Title != "Note" || article.Content != "Just a short line" {
+		t.Errorf("Expected the whole body without paragraphs, got %+v", article)
+	}
+
+	if _, err := Extract(strings.NewReader(`<html><body><script>app()</script></body></html>`), nil); !errors.Is(err, ErrNoContent) {
+		t.Errorf("Expected ErrNoContent for a page without text, got %v", err)
+	}
+}
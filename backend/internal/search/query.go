@@ -0,0 +1,420 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DateLayout is the date format accepted by the before: and after: operators
+const DateLayout = "2006-01-02"
+
+// MaxRegexLength is the length of a /regex/ term pattern, in characters
+const MaxRegexLength = 200
+
+// Field scopes a text term to part of a note
+type Field string
+
+const (
+	// FieldAny matches title or content
+	FieldAny Field = ""
+	// FieldTitle matches the title only
+	FieldTitle Field = "title"
+)
+
+// Term is a word, quoted phrase or regular expression to match against
+// note text
+type Term struct {
+	Text    string `json:"text"`
+	Phrase  bool   `json:"phrase,omitempty"`
+	Negated bool   `json:"negated,omitempty"`
+	Field   Field  `json:"field,omitempty"`
+	// Regex is set when Text is a case-insensitive regular expression
+	Regex bool `json:"regex,omitempty"`
+}
+
+// Query is the parsed form of a search string such as
+//
+//	"release notes" tag:work -tag:draft title:roadmap -meeting after:2024-01-01 is:untagged
+//
+// Terms are ANDed together; tag, source, color and date filters narrow the result
+// further.
+type Query struct {
+	Terms       []Term     `json:"terms,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	ExcludeTags []string   `json:"exclude_tags,omitempty"`
+	// Sources are the lowercased source apps a note may come from, any of
+	// them; notes from ExcludeSources never match
+	Sources        []string `json:"sources,omitempty"`
+	ExcludeSources []string `json:"exclude_sources,omitempty"`
+	// Colors are lowercased color labels, any of them; notes labeled with
+	// ExcludeColors never match
+	Colors        []string `json:"colors,omitempty"`
+	ExcludeColors []string `json:"exclude_colors,omitempty"`
+	// After is inclusive and Before is exclusive, both on the note creation date
+	After  *time.Time `json:"after,omitempty"`
+	Before *time.Time `json:"before,omitempty"`
+	// Untagged keeps notes without tags when true and notes with tags when
+	// false
+	Untagged *bool `json:"untagged,omitempty"`
+}
+
+// IsEmpty reports whether the query has no terms or filters
+func (q *Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Tags) == 0 && len(q.ExcludeTags) == 0 &&
+		len(q.Sources) == 0 && len(q.ExcludeSources) == 0 && len(q.Colors) == 0 && len(q.ExcludeColors) == 0 &&
+		q.After == nil && q.Before == nil && q.Untagged == nil
+}
+
+// HasTextTerms reports whether the query matches on note text
+func (q *Query) HasTextTerms() bool {
+	return len(q.Terms) > 0
+}
+
+// MatchesText reports whether a title and content satisfy every text term,
+// using the same case-insensitive substring semantics as the SQL fallback
+func (q *Query) MatchesText(title, content string) bool {
+	lowerTitle := strings.ToLower(title)
+	lowerContent := strings.ToLower(content)
+	for _, term := range q.Terms {
+		var found bool
+		if term.Regex {
+			// Patterns are checked by Parse; one that does not compile
+			// matches nothing
+			re, err := CompileRegex(term.Text)
+			found = err == nil && (re.MatchString(title) || term.Field == FieldAny && re.MatchString(content))
+		} else {
+			needle := strings.ToLower(term.Text)
+			found = strings.Contains(lowerTitle, needle)
+			if !found && term.Field == FieldAny {
+				found = strings.Contains(lowerContent, needle)
+			}
+		}
+		if found == term.Negated {
+			return false
+		}
+	}
+	return true
+}
+
+// Document is the in-memory view of a note used by Matches
+type Document struct {
+	Title     string
+	Content   string
+	Tags      []string
+	// Source is the app the note came from, empty when unknown
+	Source    string
+	// Color is the note's color label, empty without one
+	Color     string
+	CreatedAt time.Time
+}
+
+// Matches reports whether a document satisfies every term and filter of the
+// query. It mirrors the SQL search without stemming, so a note can be checked
+// on write without querying the database.
+func (q *Query) Matches(doc Document) bool {
+	if !q.MatchesText(doc.Title, doc.Content) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !containsTag(doc.Tags, tag) {
+			return false
+		}
+	}
+	for _, tag := range q.ExcludeTags {
+		if containsTag(doc.Tags, tag) {
+			return false
+		}
+	}
+	source := strings.ToLower(doc.Source)
+	if len(q.Sources) > 0 && !slices.Contains(q.Sources, source) {
+		return false
+	}
+	if slices.Contains(q.ExcludeSources, source) {
+		return false
+	}
+	color := strings.ToLower(doc.Color)
+	if len(q.Colors) > 0 && !slices.Contains(q.Colors, color) {
+		return false
+	}
+	if slices.Contains(q.ExcludeColors, color) {
+		return false
+	}
+	if q.After != nil && doc.CreatedAt.Before(*q.After) {
+		return false
+	}
+	if q.Before != nil && !doc.CreatedAt.Before(*q.Before) {
+		return false
+	}
+	if q.Untagged != nil && *q.Untagged != (len(doc.Tags) == 0) {
+		return false
+	}
+	return true
+}
+
+// CompileRegex compiles the pattern of a regex term, which matches
+// regardless of case
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// containsTag reports whether tags contains tag, ignoring case
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(NormalizeTag(t), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError describes invalid query syntax. Position and Length are counted
+// in characters (not bytes) so clients can highlight the offending text.
+type ParseError struct {
+	Position int    `json:"position"`
+	Length   int    `json:"length"`
+	Message  string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid search query at position %d: %s", e.Position, e.Message)
+}
+
+// Parse parses a search string into a Query. Supported syntax:
+//
+//	word            notes containing word
+//	"a phrase"      notes containing the exact phrase
+//	/regex/         notes matching a case-insensitive regular expression, written without spaces (use \s)
+//	tag:name        notes tagged #name (or tag:#name)
+//	title:word      word (or title:"a phrase" or title:/regex/) must appear in the title
+//	source:app      notes created from app, such as source:telegram
+//	color:name      notes labeled with a color, such as color:red
+//	after:YYYY-MM-DD, before:YYYY-MM-DD   creation date range
+//	is:untagged     notes without tags
+//	-term           negates a word, phrase, regex, tag:, title:, source:, color: or is: term
+//
+// Unknown prefixes such as "http:" are treated as plain words.
+func Parse(input string) (*Query, error) {
+	p := &parser{input: []rune(input)}
+	query := &Query{}
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+		if err := p.parseTerm(query); err != nil {
+			return nil, err
+		}
+	}
+
+	return query, nil
+}
+
+// parser walks the query one term at a time
+type parser struct {
+	input []rune
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) errorAt(start, end int, format string, args ...any) *ParseError {
+	if end <= start {
+		end = start + 1
+	}
+	return &ParseError{Position: start, Length: end - start, Message: fmt.Sprintf(format, args...)}
+}
+
+// parseTerm parses one whitespace-delimited term, which may be negated,
+// prefixed with an operator and/or quoted
+func (p *parser) parseTerm(query *Query) error {
+	start := p.pos
+	negated := false
+	if p.input[p.pos] == '-' {
+		negated = true
+		p.pos++
+		if p.done() || unicode.IsSpace(p.input[p.pos]) {
+			return p.errorAt(start, p.pos, "'-' must be followed by a term to exclude")
+		}
+	}
+
+	if p.input[p.pos] == '"' {
+		text, err := p.readPhrase()
+		if err != nil {
+			return err
+		}
+		query.Terms = append(query.Terms, Term{Text: text, Phrase: true, Negated: negated})
+		return nil
+	}
+
+	operatorStart := p.pos
+	word := p.readWord()
+	if pattern, ok := regexPattern(word); ok {
+		if err := p.checkRegex(operatorStart, pattern); err != nil {
+			return err
+		}
+		query.Terms = append(query.Terms, Term{Text: pattern, Regex: true, Negated: negated})
+		return nil
+	}
+	key, value, hasOperator := strings.Cut(word, ":")
+	if !hasOperator {
+		query.Terms = append(query.Terms, Term{Text: word, Negated: negated})
+		return nil
+	}
+
+	key = strings.ToLower(key)
+	switch key {
+	case "tag", "title", "source", "color", "before", "after", "is":
+	default:
+		query.Terms = append(query.Terms, Term{Text: word, Negated: negated})
+		return nil
+	}
+
+	valueStart := operatorStart + len([]rune(key)) + 1
+	phrase := false
+	if value == "" && !p.done() && p.input[p.pos] == '"' {
+		// title:"a phrase" — the word stopped at the quote
+		text, err := p.readPhrase()
+		if err != nil {
+			return err
+		}
+		value, phrase = text, true
+	}
+	if value == "" {
+		return p.errorAt(operatorStart, p.pos, "%s: requires a value", key)
+	}
+
+	switch key {
+	case "tag":
+		if phrase {
+			return p.errorAt(valueStart, p.pos, "tag names cannot be quoted")
+		}
+		tag := NormalizeTag(value)
+		if negated {
+			query.ExcludeTags = append(query.ExcludeTags, tag)
+		} else {
+			query.Tags = append(query.Tags, tag)
+		}
+	case "source":
+		source := strings.ToLower(value)
+		if negated {
+			query.ExcludeSources = append(query.ExcludeSources, source)
+		} else {
+			query.Sources = append(query.Sources, source)
+		}
+	case "color":
+		color := strings.ToLower(value)
+		if negated {
+			query.ExcludeColors = append(query.ExcludeColors, color)
+		} else {
+			query.Colors = append(query.Colors, color)
+		}
+	case "title":
+		if pattern, ok := regexPattern(value); ok && !phrase {
+			if err := p.checkRegex(valueStart, pattern); err != nil {
+				return err
+			}
+			query.Terms = append(query.Terms, Term{Text: pattern, Regex: true, Negated: negated, Field: FieldTitle})
+			break
+		}
+		query.Terms = append(query.Terms, Term{Text: value, Phrase: phrase, Negated: negated, Field: FieldTitle})
+	case "is":
+		if strings.ToLower(value) != "untagged" {
+			return p.errorAt(valueStart, p.pos, "unknown filter is:%s (expected is:untagged)", value)
+		}
+		if query.Untagged != nil {
+			return p.errorAt(start, p.pos, "is:untagged can only be used once")
+		}
+		untagged := !negated
+		query.Untagged = &untagged
+	case "before", "after":
+		if negated {
+			return p.errorAt(start, p.pos, "%s: cannot be negated", key)
+		}
+		date, err := time.Parse(DateLayout, value)
+		if err != nil {
+			return p.errorAt(valueStart, p.pos, "invalid date %q for %s: (expected YYYY-MM-DD)", value, key)
+		}
+		if key == "before" {
+			query.Before = &date
+		} else {
+			query.After = &date
+		}
+		if query.After != nil && query.Before != nil && !query.After.Before(*query.Before) {
+			return p.errorAt(start, p.pos, "after: date must be earlier than before: date")
+		}
+	}
+
+	return nil
+}
+
+// regexPattern returns the pattern of a /regex/ word
+func regexPattern(word string) (string, bool) {
+	if len(word) < 3 || !strings.HasPrefix(word, "/") || !strings.HasSuffix(word, "/") {
+		return "", false
+	}
+	return word[1 : len(word)-1], true
+}
+
+// checkRegex checks the pattern of a regex term starting at start, the
+// position of its opening slash
+func (p *parser) checkRegex(start int, pattern string) error {
+	if length := len([]rune(pattern)); length > MaxRegexLength {
+		return p.errorAt(start, p.pos, "regular expression too long (max %d characters)", MaxRegexLength)
+	}
+	if _, err := CompileRegex(pattern); err != nil {
+		return p.errorAt(start, p.pos, "invalid regular expression: %v", err)
+	}
+	return nil
+}
+
+// readWord reads up to the next whitespace or opening quote
+func (p *parser) readWord() string {
+	start := p.pos
+	for !p.done() && !unicode.IsSpace(p.input[p.pos]) && p.input[p.pos] != '"' {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// readPhrase reads a double-quoted phrase starting at the opening quote
+func (p *parser) readPhrase() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	for !p.done() && p.input[p.pos] != '"' {
+		p.pos++
+	}
+	if p.done() {
+		return "", p.errorAt(start, p.pos, "unterminated quoted phrase")
+	}
+	text := strings.TrimSpace(string(p.input[start+1 : p.pos]))
+	p.pos++ // closing quote
+	if text == "" {
+		return "", p.errorAt(start, p.pos, "empty quoted phrase")
+	}
+	if !p.done() && !unicode.IsSpace(p.input[p.pos]) {
+		return "", p.errorAt(p.pos, p.pos+1, "expected a space after the closing quote")
+	}
+	return text, nil
+}
+
+// NormalizeTag returns a tag name in the stored "#name" form
+func NormalizeTag(tag string) string {
+	if !strings.HasPrefix(tag, "#") {
+		return "#" + tag
+	}
+	return tag
+}
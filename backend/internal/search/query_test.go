@@ -0,0 +1,200 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	date := func(s string) *time.Time {
+		d, _ := time.Parse(DateLayout, s)
+		return &d
+	}
+
+	untagged, tagged := true, false
+
+	tests := []struct {
+		name  string
+		input string
+		want  *Query
+	}{
+		{"empty", "   ", &Query{}},
+		{"words", "release notes", &Query{Terms: []Term{{Text: "release"}, {Text: "notes"}}}},
+		{"phrase", `"release notes" draft`, &Query{Terms: []Term{{Text: "release notes", Phrase: true}, {Text: "draft"}}}},
+		{"negation", `-meeting -"stand up"`, &Query{Terms: []Term{{Text: "meeting", Negated: true}, {Text: "stand up", Phrase: true, Negated: true}}}},
+		{"tags", "tag:work -tag:#draft", &Query{Tags: []string{"#work"}, ExcludeTags: []string{"#draft"}}},
+		{"sources", `source:Telegram source:"web" -source:cli`, &Query{Sources: []string{"telegram", "web"}, ExcludeSources: []string{"cli"}}},
+		{"colors", "color:Red -color:gray", &Query{Colors: []string{"red"}, ExcludeColors: []string{"gray"}}},
+		{"title scope", `title:roadmap -title:"q3 plan"`, &Query{Terms: []Term{
+			{Text: "roadmap", Field: FieldTitle},
+			{Text: "q3 plan", Phrase: true, Negated: true, Field: FieldTitle},
+		}}},
+		{"dates", "after:2024-01-01 before:2024-02-01", &Query{After: date("2024-01-01"), Before: date("2024-02-01")}},
+		{"unknown operator is a word", "https://example.com", &Query{Terms: []Term{{Text: "https://example.com"}}}},
+		{"operator is case insensitive", "TAG:work", &Query{Tags: []string{"#work"}}},
+		{"regexes", `/v\d+\.\d+/ -title:/^draft/`, &Query{Terms: []Term{
+			{Text: `v\d+\.\d+`, Regex: true},
+			{Text: "^draft", Regex: true, Negated: true, Field: FieldTitle},
+		}}},
+		{"paths are words", "/usr/bin", &Query{Terms: []Term{{Text: "/usr/bin"}}}},
+		{"untagged", "is:untagged", &Query{Untagged: &untagged}},
+		{"tagged", "-is:Untagged", &Query{Untagged: &tagged}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		position int
+		length   int
+	}{
+		{"unterminated phrase", `notes "release`, 6, 8},
+		{"empty phrase", `"" notes`, 0, 2},
+		{"dangling negation", "notes - draft", 6, 1},
+		{"missing tag value", "tag: work", 0, 4},
+		{"quoted tag", `tag:"work"`, 4, 6},
+		{"invalid date", "after:yesterday", 6, 9},
+		{"negated date", "-before:2024-01-01", 0, 18},
+		{"inverted range", "after:2024-02-01 before:2024-01-01", 17, 17},
+		{"text after phrase", `"release"notes`, 9, 1},
+		{"invalid regex", "notes /a(b/", 6, 5},
+		{"unknown is filter", "is:pinned", 3, 6},
+		{"repeated is filter", "is:untagged -is:untagged", 12, 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse(%q) error = %v, want *ParseError", tt.input, err)
+			}
+			if parseErr.Position != tt.position || parseErr.Length != tt.length {
+				t.Errorf("Parse(%q) error at %d+%d, want %d+%d (%s)",
+					tt.input, parseErr.Position, parseErr.Length, tt.position, tt.length, parseErr.Message)
+			}
+		})
+	}
+}
+
+func TestMatchesText(t *testing.T) {
+	query, err := Parse(`"launch plan" title:q3 -cancelled`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if !query.MatchesText("Q3 goals", "The Launch Plan is ready") {
+		t.Error("expected note to match")
+	}
+	if query.MatchesText("Q3 goals", "launch plan cancelled") {
+		t.Error("expected negated term to exclude note")
+	}
+	if query.MatchesText("Goals", "launch plan for q3") {
+		t.Error("expected title: term to only match the title")
+	}
+
+	query, err = Parse(`/release\sv\d+/ -title:/^draft/`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !query.MatchesText("Notes", "RELEASE v2 is out") {
+		t.Error("expected regex to match regardless of case")
+	}
+	if query.MatchesText("Draft notes", "release v2") {
+		t.Error("expected negated title regex to exclude note")
+	}
+	if query.MatchesText("Notes", "release vX") {
+		t.Error("expected regex not to match")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	query, err := Parse("incident tag:oncall -tag:resolved after:2024-01-01")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		doc  Document
+		want bool
+	}{
+		{"matching", Document{Content: "Incident in payments", Tags: []string{"#oncall"}, CreatedAt: createdAt}, true},
+		{"missing tag", Document{Content: "Incident in payments", CreatedAt: createdAt}, false},
+		{"excluded tag", Document{Content: "Incident", Tags: []string{"#oncall", "#Resolved"}, CreatedAt: createdAt}, false},
+		{"too old", Document{Content: "Incident", Tags: []string{"#oncall"}, CreatedAt: createdAt.AddDate(-1, 0, 0)}, false},
+		{"no text match", Document{Content: "Postmortem", Tags: []string{"#oncall"}, CreatedAt: createdAt}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := query.Matches(tt.doc); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesUntagged(t *testing.T) {
+	tests := []struct {
+		query string
+		tags  []string
+		want  bool
+	}{
+		{"is:untagged", nil, true},
+		{"is:untagged", []string{"#work"}, false},
+		{"-is:untagged", nil, false},
+		{"-is:untagged", []string{"#work"}, true},
+	}
+
+	for _, tt := range tests {
+		query, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.query, err)
+		}
+		if got := query.Matches(Document{Tags: tt.tags}); got != tt.want {
+			t.Errorf("Parse(%q).Matches(tags %v) = %v, want %v", tt.query, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesSource(t *testing.T) {
+	tests := []struct {
+		query  string
+		source string
+		want   bool
+	}{
+		{"source:telegram", "Telegram", true},
+		{"source:telegram", "web", false},
+		{"source:telegram", "", false},
+		{"source:telegram source:web", "web", true},
+		{"-source:web", "web", false},
+		{"-source:web", "", true},
+	}
+
+	for _, tt := range tests {
+		query, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.query, err)
+		}
+		if got := query.Matches(Document{Source: tt.source}); got != tt.want {
+			t.Errorf("Parse(%q).Matches(source %q) = %v, want %v", tt.query, tt.source, got, tt.want)
+		}
+	}
+}
@@ -253,7 +253,7 @@ func (sm *SecurityMonitor) sendAlert(event SecurityEvent, count int) {
 		event.Path,
 		event.IPAddress)
 
-	log.Printf(alertMessage)
+	log.Print(alertMessage)
 
 	// In a real implementation, you might send emails, Slack notifications, etc.
 	// For now, we'll just log the alert
@@ -3,231 +3,81 @@ package server
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/gpd/my-notes/internal/app"
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/auth"
 	"github.com/gpd/my-notes/internal/config"
 	"github.com/gpd/my-notes/internal/handlers"
-	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/lifecycle"
 	"github.com/gpd/my-notes/internal/middleware"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/openapi"
 	"github.com/gpd/my-notes/internal/services"
 	"github.com/gorilla/mux"
-	"github.com/gorilla/sessions"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"google.golang.org/grpc"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	config        *config.Config
-	router        *mux.Router
-	httpServ      *http.Server
-	handlers      *handlers.Handlers
-	db            *sql.DB
-	userService   services.UserServiceInterface
-	tokenService  *auth.TokenService
-	sessionStore  sessions.Store
-	securityMW    *middleware.SecurityMiddleware
-	sessionMW     *middleware.SessionMiddleware
-	rateLimitMW   *middleware.RateLimitingMiddleware
+	config             *config.Config
+	router             *mux.Router
+	httpServ           *http.Server
+	handlers           *handlers.Handlers
+	db                 *sql.DB
+	userService        services.UserServiceInterface
+	tokenService       *auth.TokenService
+	securityMW         *middleware.SecurityMiddleware
+	sessionMW          *middleware.SessionMiddleware
+	rateLimitMW        *middleware.RateLimitingMiddleware
+	apiKeyService      *services.APIKeyService
+	idempotencyService *services.IdempotencyService
+	grpcServ           *grpc.Server
+	jobs               *lifecycle.Manager
 }
 
-// NewServer creates a new server instance
-func NewServer(cfg *config.Config, h *handlers.Handlers, db *sql.DB) *Server {
+// NewServer creates a new server serving the handlers and services of a
+func NewServer(a *app.App) *Server {
 	s := &Server{
-		config:   cfg,
-		router:   mux.NewRouter(),
-		handlers: h,
-		db:       db,
+		config:             a.Config,
+		router:             mux.NewRouter(),
+		handlers:           a.Handlers,
+		db:                 a.DB,
+		userService:        a.Services.Users,
+		tokenService:       a.TokenService,
+		securityMW:         a.SecurityMW,
+		sessionMW:          a.SessionMW,
+		rateLimitMW:        a.RateLimitMW,
+		apiKeyService:      a.Services.APIKeys,
+		idempotencyService: a.Services.Idempotency,
+		grpcServ:           a.GRPC,
+		jobs:               a.Jobs,
 	}
 
-	s.initializeServices()
 	s.setupMiddleware()
 	s.setupRoutes()
 
 	return s
 }
 
-// initializeServices initializes all services needed for middleware
-func (s *Server) initializeServices() {
-	// Initialize user service
-	s.userService = services.NewUserService(s.db)
-
-	// Initialize tag service
-	tagService := services.NewTagService(s.db)
-
-	// Initialize token service
-	tokenSecret := s.config.Auth.JWTSecret
-	if tokenSecret == "" {
-		log.Println("⚠️  Warning: Using default JWT secret key - please set JWT_SECRET in production")
-		tokenSecret = "your-secret-key-change-in-production"
-	}
-	s.tokenService = auth.NewTokenService(
-		tokenSecret,
-		time.Duration(s.config.Auth.TokenExpiry)*time.Hour,
-		time.Duration(s.config.Auth.RefreshExpiry)*time.Hour,
-		"silence-notes",
-		"silence-notes-users",
-	)
-
-	// Initialize blacklist service for token revocation
-	blacklistSvc := services.NewBlacklistService(s.db)
-	s.tokenService.SetBlacklist(blacklistSvc)
-
-	// Start blacklist cleanup goroutine
-	go blacklistCleanupLoop(blacklistSvc, 1*time.Hour)
-
-	// Initialize security configuration
-	var securityConfig *config.SecurityConfig
-	switch s.config.App.Environment {
-	case "development":
-		securityConfig = config.GetDevelopmentSecurityConfig()
-	case "production":
-		securityConfig = config.GetProductionSecurityConfig()
-	default:
-		securityConfig = config.GetDefaultSecurityConfig()
-	}
-
-	// Initialize security middleware
-	s.securityMW = middleware.NewSecurityMiddleware(
-		s.tokenService,
-		s.userService,
-		securityConfig,
-		&securityConfig.CORS,
-	)
-
-	// Initialize session middleware
-	sessionConfig := &middleware.SessionConfig{
-		SessionTimeout:     securityConfig.Session.SessionTimeout,
-		MaxSessions:        securityConfig.Session.MaxSessions,
-		EnableConcurrency:  securityConfig.Session.EnableConcurrency,
-		InactiveTimeout:    securityConfig.Session.InactiveTimeout,
-		RefreshThreshold:   securityConfig.Session.RefreshThreshold,
-	}
-	s.sessionMW = middleware.NewSessionMiddleware(s.userService, s.db, sessionConfig)
-
-	// Initialize rate limiting middleware
-	rateLimitConfig := &middleware.RateLimitConfig{
-		GlobalRequestsPerSecond: securityConfig.RateLimiting.GlobalRequestsPerSecond,
-		GlobalBurstSize:         securityConfig.RateLimiting.GlobalBurstSize,
-		UserRequestsPerMinute:   securityConfig.RateLimiting.UserRequestsPerMinute,
-		UserRequestsPerHour:     securityConfig.RateLimiting.UserRequestsPerHour,
-		UserRequestsPerDay:      securityConfig.RateLimiting.UserRequestsPerDay,
-		AuthRequestsPerMinute:   securityConfig.RateLimiting.AuthRequestsPerMinute,
-		ProfileRequestsPerMinute: securityConfig.RateLimiting.ProfileRequestsPerMinute,
-		SearchRequestsPerMinute: securityConfig.RateLimiting.SearchRequestsPerMinute,
-		WhitelistedIPs:          securityConfig.RateLimiting.WhitelistedIPs,
-		WhitelistedUsers:        securityConfig.RateLimiting.WhitelistedUsers,
-	}
-	s.rateLimitMW = middleware.NewRateLimitingMiddleware(s.userService, s.tokenService, rateLimitConfig)
-
-	// Initialize session store
-	sessionSecret := []byte(s.config.Auth.JWTSecret)
-	if len(sessionSecret) == 0 {
-		sessionSecret = []byte("your-session-secret-change-in-production")
-		log.Println("⚠️  Warning: Using default session secret - please set JWT_SECRET in production")
-	}
-	s.sessionStore = sessions.NewCookieStore(sessionSecret)
-	if cookieStore, ok := s.sessionStore.(*sessions.CookieStore); ok {
-		cookieStore.Options = &sessions.Options{
-			Path:     "/",
-			MaxAge:   int(securityConfig.Session.SessionTimeout.Seconds()),
-			HttpOnly: true,
-			Secure:   s.config.App.Environment == "production",
-			SameSite: http.SameSiteStrictMode,
-		}
-	}
-
-	// Initialize auth handlers
-	authHandler := handlers.NewAuthHandler(
-		s.tokenService,
-		s.userService,
-	)
-	authHandler.SetBlacklist(blacklistSvc)
-
-	// Initialize Chrome extension auth handler
-	chromeAuthHandler := handlers.NewChromeAuthHandler(
-		s.tokenService,
-		s.userService,
-	)
-
-	// Initialize LLM components for semantic search
-	var tokenizer *llm.Tiktoken
-	var resilientLLM *llm.ResilientLLM
-	var semanticSearchService *services.SemanticSearchService
-	var prettifyService *services.PrettifyService
-
-	log.Printf("🔍 Checking LLM configuration...")
-	log.Printf("   LLM Type: %s", s.config.LLM.Type)
-	log.Printf("   API Key configured: %t", s.config.LLM.DeepseekTencentAPIKey != "")
-
-	if s.config.LLM.DeepseekTencentAPIKey != "" {
-		var err error
-		log.Printf("🔧 Creating tokenizer...")
-		tokenizer, err = llm.NewTokenizer()
-		if err != nil {
-			log.Printf("⚠️  Failed to create tokenizer: %v - semantic search disabled", err)
-		} else {
-			log.Printf("🔧 Creating LLM client...")
-			resilientLLM, err = llm.NewResilientLLM(context.Background(), s.config, nil)
-			if err != nil {
-				log.Printf("⚠️  Failed to create LLM client: %v - semantic search disabled", err)
-			} else {
-				noteService := services.NewNoteService(s.db, tagService)
-				log.Printf("🔧 Initializing semantic search service...")
-				semanticSearchService = services.NewSemanticSearchService(
-					resilientLLM,
-					tokenizer,
-					noteService,
-					s.config.LLM.MaxSearchTokenLength,
-				)
-				log.Printf("🔧 Initializing prettify service...")
-				prettifyService = services.NewPrettifyService(
-					resilientLLM,
-					noteService,
-					tagService,
-					s.db,
-				)
-				log.Println("✅ Semantic search enabled")
-				log.Println("✅ Prettify service enabled")
-			}
-		}
-	} else {
-		log.Println("ℹ️  No LLM API key configured - semantic search disabled")
-		log.Println("ℹ️  Prettify service disabled")
-		log.Println("   Set LLM_DEEPSEEK_TENCENT_API_KEY environment variable to enable")
-	}
-
-	// Initialize note service and handler
-	noteService := services.NewNoteService(s.db, tagService)
-	notesHandler := handlers.NewNotesHandler(noteService, semanticSearchService, prettifyService)
-
-	// Initialize tags handler
-	tagsHandler := handlers.NewTagsHandler(tagService)
-
-	// Initialize auth handlers
-	s.handlers.SetAuthHandlers(authHandler, chromeAuthHandler)
-
-	// Initialize notes handler
-	s.handlers.SetNotesHandler(notesHandler)
-
-	// Initialize tags handler
-	s.handlers.SetTagsHandler(tagsHandler)
-
-	log.Printf("✅ Security services initialized")
-	log.Printf("🔒 Security mode: %s", s.config.App.Environment)
-	log.Printf("🚦 Rate limiting: %.0f req/sec global, %d req/min per user",
-		securityConfig.RateLimiting.GlobalRequestsPerSecond,
-		securityConfig.RateLimiting.UserRequestsPerMinute)
-}
-
 // setupMiddleware configures the middleware stack
 func (s *Server) setupMiddleware() {
+	// Trace every request, named by its route template
+	s.router.Use(otelmux.Middleware(s.config.Tracing.ServiceName))
+
 	// Apply core middleware first
 	s.router.Use(middleware.Recovery)
 	s.router.Use(middleware.RequestID)
 	s.router.Use(middleware.Logging)
 	s.router.Use(middleware.ContentType)
+	s.router.Use(middleware.ClientInfo(s.config.Anomaly.CountryHeader))
 
 	// Apply comprehensive security middleware
 	if s.securityMW != nil {
@@ -274,6 +124,32 @@ func (s *Server) setupRoutes() {
 		auth.HandleFunc("/chrome", s.handlers.ChromeAuth.ExchangeChromeToken).Methods("POST")
 	}
 
+	// Migration transfer routes, called by the source deployment with the transfer token
+	if s.handlers.Migrations != nil {
+		transfers := api.PathPrefix("/migrations/incoming/{id}").Subrouter()
+		transfers.HandleFunc("", s.handlers.Migrations.GetTransfer).Methods("GET")
+		transfers.HandleFunc("/chunks/{sequence:[0-9]+}", s.handlers.Migrations.ReceiveChunk).Methods("PUT")
+	}
+
+	// Capture route, authenticated by API key for automation tools and extensions
+	if s.handlers.Capture != nil && s.apiKeyService != nil {
+		capture := api.PathPrefix("/capture").Subrouter()
+		capture.Use(middleware.APIKeyAuth(s.apiKeyService, s.userService))
+		capture.Use(middleware.ReadOnlyLock)
+		capture.HandleFunc("", s.handlers.Capture.Capture).Methods("POST")
+		capture.HandleFunc("/web", s.handlers.Capture.CaptureWeb).Methods("POST")
+	}
+
+	// iCal feed, authenticated by the token in its URL
+	if s.handlers.Calendar != nil {
+		api.HandleFunc("/calendar.ics", s.handlers.Calendar.ServeCalendarFeed).Methods("GET")
+	}
+
+	// Inbound email route, authenticated by the Mailgun signature
+	if s.handlers.InboundEmail != nil {
+		api.HandleFunc("/inbound/mailgun", s.handlers.InboundEmail.ReceiveMailgun).Methods("POST")
+	}
+
 	// Protected routes with authentication and session management
 	protected := api.PathPrefix("/").Subrouter()
 
@@ -287,44 +163,403 @@ func (s *Server) setupRoutes() {
 		protected.Use(s.sessionMW.SessionManager)
 	}
 
+	// Accounts locked after unusual activity can read but not write
+	protected.Use(middleware.ReadOnlyLock)
+
 	// Token management routes
 	if s.handlers.Auth != nil {
 		protected.HandleFunc("/auth/logout", s.handlers.Auth.Logout).Methods("DELETE")
+		protected.HandleFunc("/auth/sessions", s.handlers.Auth.ListSessions).Methods("GET")
+		protected.HandleFunc("/auth/sessions/{id}", s.handlers.Auth.RevokeSession).Methods("DELETE")
+	}
+
+	// Two-factor authentication routes
+	if s.handlers.TwoFactor != nil {
+		protected.HandleFunc("/auth/2fa", s.handlers.TwoFactor.GetStatus).Methods("GET")
+		protected.HandleFunc("/auth/2fa/enroll", s.handlers.TwoFactor.Enroll).Methods("POST")
+		protected.HandleFunc("/auth/2fa/enable", s.handlers.TwoFactor.Enable).Methods("POST")
+		protected.HandleFunc("/auth/2fa/disable", s.handlers.TwoFactor.Disable).Methods("POST")
+		protected.HandleFunc("/auth/2fa/recovery-codes", s.handlers.TwoFactor.RegenerateRecoveryCodes).Methods("POST")
 	}
 
 	// Note routes
 	if s.handlers.Notes != nil {
 		protected.HandleFunc("/notes", s.handlers.Notes.ListNotes).Methods("GET")
-		protected.HandleFunc("/notes", s.handlers.Notes.CreateNote).Methods("POST")
+
+		// Creations and batch writes replay their response to retries with the
+		// same Idempotency-Key. Registered before /notes/{id} so "batch" is not
+		// taken as a note ID.
+		writes := protected.NewRoute().Subrouter()
+		if s.idempotencyService != nil {
+			writes.Use(middleware.Idempotency(s.idempotencyService))
+		}
+		writes.HandleFunc("/notes", s.handlers.Notes.CreateNote).Methods("POST")
+		writes.HandleFunc("/notes/batch", s.handlers.Notes.BatchCreateNotes).Methods("POST")
+		writes.HandleFunc("/notes/batch", s.handlers.Notes.BatchUpdateNotes).Methods("PUT")
+		writes.HandleFunc("/notes/batch/delete", s.handlers.Notes.BatchDeleteNotes).Methods("POST")
+
+		if s.handlers.Links != nil {
+			// Registered before /notes/{id} so "graph" is not taken as a note ID
+			protected.HandleFunc("/notes/graph", s.handlers.Links.GetGraph).Methods("GET")
+			protected.HandleFunc("/notes/{id}/backlinks", s.handlers.Links.GetBacklinks).Methods("GET")
+		}
+		if s.handlers.Paste != nil {
+			// Registered before /notes/{id} so "normalize" is not taken as a note ID
+			protected.HandleFunc("/notes/normalize", s.handlers.Paste.NormalizePaste).Methods("POST")
+		}
+		if s.handlers.Transcription != nil {
+			// Registered before /notes/{id} so "transcribe" is not taken as a note ID
+			protected.HandleFunc("/notes/transcribe", s.handlers.Transcription.TranscribeMemo).Methods("POST")
+		}
+		if s.handlers.QA != nil {
+			protected.HandleFunc("/notes/ask", s.handlers.QA.AskNotes).Methods("POST")
+		}
+		if s.handlers.Tags != nil {
+			protected.HandleFunc("/notes/{id}/tags/suggest", s.handlers.Tags.SuggestTags).Methods("POST")
+		}
+		// Registered before /notes/{id} so they are not taken as note IDs
+		protected.HandleFunc("/notes/untagged", s.handlers.Notes.ListUntaggedNotes).Methods("GET")
+		protected.HandleFunc("/notes/stale", s.handlers.Notes.ListStaleNotes).Methods("GET")
 		protected.HandleFunc("/notes/{id}", s.handlers.Notes.GetNote).Methods("GET")
 		protected.HandleFunc("/notes/{id}", s.handlers.Notes.UpdateNote).Methods("PUT")
 		protected.HandleFunc("/notes/{id}", s.handlers.Notes.DeleteNote).Methods("DELETE")
 		protected.HandleFunc("/notes/{id}/prettify", s.handlers.Notes.PrettifyNote).Methods("POST")
+		if s.handlers.Proofread != nil {
+			protected.HandleFunc("/notes/{id}/proofread", s.handlers.Proofread.ProofreadNote).Methods("POST")
+			protected.HandleFunc("/notes/{id}/proofread/{proposalID}/apply", s.handlers.Proofread.ApplyProofread).Methods("POST")
+			protected.HandleFunc("/notes/{id}/proofread/{proposalID}", s.handlers.Proofread.DiscardProofread).Methods("DELETE")
+		}
+		if s.handlers.Translation != nil {
+			protected.HandleFunc("/notes/{id}/translate", s.handlers.Translation.TranslateNote).Methods("POST")
+		}
+		protected.HandleFunc("/notes/{id}/duplicate", s.handlers.Notes.DuplicateNote).Methods("POST")
+		protected.HandleFunc("/notes/{id}/append", s.handlers.Notes.AppendToNote).Methods("PATCH")
+		protected.HandleFunc("/notes/{id}/prepend", s.handlers.Notes.PrependToNote).Methods("PATCH")
+		if s.handlers.Publications != nil {
+			protected.HandleFunc("/notes/{id}/publish", s.handlers.Publications.GetPublication).Methods("GET")
+			protected.HandleFunc("/notes/{id}/publish", s.handlers.Publications.PublishNote).Methods("POST")
+			protected.HandleFunc("/notes/{id}/publish", s.handlers.Publications.UnpublishNote).Methods("DELETE")
+		}
+		if s.handlers.Comments != nil {
+			protected.HandleFunc("/notes/{id}/comments", s.handlers.Comments.ListComments).Methods("GET")
+			protected.HandleFunc("/notes/{id}/comments", s.handlers.Comments.CreateComment).Methods("POST")
+			protected.HandleFunc("/notes/{id}/comments/{commentID}", s.handlers.Comments.UpdateComment).Methods("PUT")
+			protected.HandleFunc("/notes/{id}/comments/{commentID}", s.handlers.Comments.DeleteComment).Methods("DELETE")
+			protected.HandleFunc("/publications/{slug}/comments", s.handlers.Comments.ListComments).Methods("GET")
+			protected.HandleFunc("/publications/{slug}/comments", s.handlers.Comments.CreateComment).Methods("POST")
+		}
 		protected.HandleFunc("/notes/sync", s.handlers.Notes.SyncNotes).Methods("GET")
-		protected.HandleFunc("/notes/batch", s.handlers.Notes.BatchCreateNotes).Methods("POST")
-		protected.HandleFunc("/notes/batch", s.handlers.Notes.BatchUpdateNotes).Methods("PUT")
 		protected.HandleFunc("/notes/stats", s.handlers.Notes.GetNoteStats).Methods("GET")
-		protected.HandleFunc("/notes/tags/{tag}", s.handlers.Notes.GetNotesByTag).Methods("GET")
+		protected.HandleFunc("/notes/tags/{tag:.+}", s.handlers.Notes.GetNotesByTag).Methods("GET")
 	}
 
 	// Search routes
 	protected.HandleFunc("/search/notes", s.handlers.Notes.SearchNotes).Methods("GET")
 
+	// Change feed routes
+	if s.handlers.Changes != nil {
+		protected.HandleFunc("/changes", s.handlers.Changes.ListChanges).Methods("GET")
+	}
+
+	// Checklist progress routes
+	if s.handlers.Progress != nil {
+		protected.HandleFunc("/progress", s.handlers.Progress.GetTagProgress).Methods("GET")
+	}
+
+	// Note task routes
+	if s.handlers.Tasks != nil {
+		protected.HandleFunc("/tasks", s.handlers.Tasks.ListTasks).Methods("GET")
+		protected.HandleFunc("/tasks/{id}", s.handlers.Tasks.UpdateTask).Methods("PATCH")
+	}
+
+	// Kanban board routes
+	if s.handlers.Boards != nil {
+		protected.HandleFunc("/boards", s.handlers.Boards.ListBoards).Methods("GET")
+		protected.HandleFunc("/boards", s.handlers.Boards.CreateBoard).Methods("POST")
+		protected.HandleFunc("/boards/{id}", s.handlers.Boards.GetBoard).Methods("GET")
+		protected.HandleFunc("/boards/{id}", s.handlers.Boards.DeleteBoard).Methods("DELETE")
+		protected.HandleFunc("/boards/{id}/move", s.handlers.Boards.MoveCard).Methods("POST")
+	}
+
+	// Calendar routes
+	if s.handlers.Calendar != nil {
+		protected.HandleFunc("/calendar", s.handlers.Calendar.GetCalendar).Methods("GET")
+		protected.HandleFunc("/calendar/feed", s.handlers.Calendar.GetCalendarFeed).Methods("GET")
+		protected.HandleFunc("/calendar/feed", s.handlers.Calendar.EnableCalendarFeed).Methods("POST")
+		protected.HandleFunc("/calendar/feed", s.handlers.Calendar.DisableCalendarFeed).Methods("DELETE")
+	}
+
+	// Note template routes
+	if s.handlers.Templates != nil {
+		protected.HandleFunc("/templates", s.handlers.Templates.ListTemplates).Methods("GET")
+		protected.HandleFunc("/templates", s.handlers.Templates.CreateTemplate).Methods("POST")
+		protected.HandleFunc("/templates/gallery", s.handlers.Templates.ListGallery).Methods("GET")
+		protected.HandleFunc("/templates/{id}", s.handlers.Templates.GetTemplate).Methods("GET")
+		protected.HandleFunc("/templates/{id}", s.handlers.Templates.UpdateTemplate).Methods("PUT")
+		protected.HandleFunc("/templates/{id}", s.handlers.Templates.DeleteTemplate).Methods("DELETE")
+		protected.HandleFunc("/templates/{id}/clone", s.handlers.Templates.CloneTemplate).Methods("POST")
+		protected.HandleFunc("/templates/{id}/apply", s.handlers.Templates.ApplyTemplate).Methods("POST")
+		protected.HandleFunc("/templates/{id}/create-note", s.handlers.Templates.CreateNoteFromTemplate).Methods("POST")
+	}
+
+	// Recurring note routes
+	if s.handlers.Recurrences != nil {
+		protected.HandleFunc("/recurrences", s.handlers.Recurrences.ListRecurrences).Methods("GET")
+		protected.HandleFunc("/recurrences", s.handlers.Recurrences.CreateRecurrence).Methods("POST")
+		protected.HandleFunc("/recurrences/{id}", s.handlers.Recurrences.UpdateRecurrence).Methods("PATCH")
+		protected.HandleFunc("/recurrences/{id}", s.handlers.Recurrences.DeleteRecurrence).Methods("DELETE")
+	}
+
+	// Daily note routes
+	if s.handlers.DailyNotes != nil {
+		protected.HandleFunc("/daily/{date}", s.handlers.DailyNotes.GetDailyNote).Methods("GET")
+	}
+
+	// Note property routes
+	if s.handlers.Properties != nil {
+		protected.HandleFunc("/properties", s.handlers.Properties.ListProperties).Methods("GET")
+		protected.HandleFunc("/properties", s.handlers.Properties.CreateProperty).Methods("POST")
+		protected.HandleFunc("/properties/{id}", s.handlers.Properties.DeleteProperty).Methods("DELETE")
+	}
+
+	// Notebook routes
+	if s.handlers.Notebooks != nil {
+		protected.HandleFunc("/notebooks", s.handlers.Notebooks.ListNotebooks).Methods("GET")
+		protected.HandleFunc("/notebooks", s.handlers.Notebooks.CreateNotebook).Methods("POST")
+		protected.HandleFunc("/notebooks/{id}", s.handlers.Notebooks.RenameNotebook).Methods("PUT")
+		protected.HandleFunc("/notebooks/{id}", s.handlers.Notebooks.DeleteNotebook).Methods("DELETE")
+	}
+
+	// Organization routes
+	if s.handlers.Organizations != nil {
+		protected.HandleFunc("/organizations", s.handlers.Organizations.ListOrganizations).Methods("GET")
+		protected.HandleFunc("/organizations", s.handlers.Organizations.CreateOrganization).Methods("POST")
+		protected.HandleFunc("/organizations/{id}", s.handlers.Organizations.UpdateOrganization).Methods("PATCH")
+		protected.HandleFunc("/organizations/{id}", s.handlers.Organizations.DeleteOrganization).Methods("DELETE")
+		protected.HandleFunc("/organizations/{id}/members", s.handlers.Organizations.ListMembers).Methods("GET")
+		protected.HandleFunc("/organizations/{id}/members/{userID}", s.handlers.Organizations.UpdateMember).Methods("PUT")
+		protected.HandleFunc("/organizations/{id}/members/{userID}", s.handlers.Organizations.RemoveMember).Methods("DELETE")
+		protected.HandleFunc("/organizations/{id}/invitations", s.handlers.Organizations.ListInvitations).Methods("GET")
+		protected.HandleFunc("/organizations/{id}/invitations", s.handlers.Organizations.InviteMember).Methods("POST")
+		protected.HandleFunc("/organizations/{id}/notebooks", s.handlers.Organizations.ListNotebooks).Methods("GET")
+		protected.HandleFunc("/organizations/{id}/notebooks", s.handlers.Organizations.CreateNotebook).Methods("POST")
+		protected.HandleFunc("/organizations/{id}/notebooks/{notebookID}", s.handlers.Organizations.DeleteNotebook).Methods("DELETE")
+		protected.HandleFunc("/invitations/{token}/accept", s.handlers.Organizations.AcceptInvitation).Methods("POST")
+	}
+
+	// Activity feed routes
+	if s.handlers.Feed != nil {
+		protected.HandleFunc("/activity", s.handlers.Feed.ListFeed).Methods("GET")
+	}
+
+	// Focus session and time tracking routes
+	if s.handlers.Focus != nil {
+		protected.HandleFunc("/notes/{id}/sessions/start", s.handlers.Focus.StartSession).Methods("POST")
+		protected.HandleFunc("/notes/{id}/sessions/stop", s.handlers.Focus.StopSession).Methods("POST")
+		protected.HandleFunc("/analytics/time", s.handlers.Focus.GetTimeSummary).Methods("GET")
+	}
+
+	// Digest email routes
+	if s.handlers.Digest != nil {
+		protected.HandleFunc("/digest/preview", s.handlers.Digest.PreviewDigest).Methods("GET")
+	}
+
+	// API key routes
+	if s.handlers.APIKeys != nil {
+		protected.HandleFunc("/api-keys", s.handlers.APIKeys.CreateAPIKey).Methods("POST")
+		protected.HandleFunc("/api-keys", s.handlers.APIKeys.ListAPIKeys).Methods("GET")
+		protected.HandleFunc("/api-keys/{id}", s.handlers.APIKeys.RevokeAPIKey).Methods("DELETE")
+	}
+
+	// LLM usage routes
+	if s.handlers.Usage != nil {
+		protected.HandleFunc("/usage/llm", s.handlers.Usage.GetLLMUsage).Methods("GET")
+	}
+
+	// Webhook routes
+	if s.handlers.Webhooks != nil {
+		protected.HandleFunc("/webhooks", s.handlers.Webhooks.CreateWebhook).Methods("POST")
+		protected.HandleFunc("/webhooks", s.handlers.Webhooks.ListWebhooks).Methods("GET")
+		protected.HandleFunc("/webhooks/{id}", s.handlers.Webhooks.DeleteWebhook).Methods("DELETE")
+		protected.HandleFunc("/webhooks/{id}/deliveries", s.handlers.Webhooks.ListDeliveries).Methods("GET")
+	}
+
+	// Audit log routes
+	if s.handlers.Audit != nil {
+		protected.HandleFunc("/audit", s.handlers.Audit.ListAuditLog).Methods("GET")
+	}
+
+	// GraphQL route
+	if s.handlers.GraphQL != nil {
+		protected.HandleFunc("/graphql", s.handlers.GraphQL.Query).Methods("POST")
+	}
+
 	// Tag routes
 	if s.handlers.Tags != nil {
 		protected.HandleFunc("/tags", s.handlers.Tags.GetTags).Methods("GET")
+		protected.HandleFunc("/tags/tree", s.handlers.Tags.GetTagTree).Methods("GET")
+		protected.HandleFunc("/tags/graph", s.handlers.Tags.GetTagGraph).Methods("GET")
+		protected.HandleFunc("/tags/trending", s.handlers.Tags.GetTrendingTags).Methods("GET")
+		protected.HandleFunc("/tags/merge", s.handlers.Tags.MergeTags).Methods("POST")
+		protected.HandleFunc("/tags/{id}", s.handlers.Tags.UpdateTag).Methods("PUT")
+	}
+
+	// Saved search (smart folder) routes
+	if s.handlers.SavedSearches != nil {
+		protected.HandleFunc("/saved-searches", s.handlers.SavedSearches.ListSavedSearches).Methods("GET")
+		protected.HandleFunc("/saved-searches", s.handlers.SavedSearches.CreateSavedSearch).Methods("POST")
+		protected.HandleFunc("/saved-searches/{id}", s.handlers.SavedSearches.GetSavedSearch).Methods("GET")
+		protected.HandleFunc("/saved-searches/{id}", s.handlers.SavedSearches.DeleteSavedSearch).Methods("DELETE")
+		protected.HandleFunc("/saved-searches/{id}/notes", s.handlers.SavedSearches.ExecuteSavedSearch).Methods("GET")
+	}
+
+	// Search subscription routes
+	if s.handlers.Subscriptions != nil {
+		protected.HandleFunc("/subscriptions", s.handlers.Subscriptions.ListSubscriptions).Methods("GET")
+		protected.HandleFunc("/subscriptions", s.handlers.Subscriptions.CreateSubscription).Methods("POST")
+		protected.HandleFunc("/subscriptions/{id}", s.handlers.Subscriptions.DeleteSubscription).Methods("DELETE")
 	}
 
+	// Notification rule routes
+	if s.handlers.NotificationRules != nil {
+		protected.HandleFunc("/notification-rules", s.handlers.NotificationRules.ListRules).Methods("GET")
+		protected.HandleFunc("/notification-rules", s.handlers.NotificationRules.CreateRule).Methods("POST")
+		protected.HandleFunc("/notification-rules/{id}", s.handlers.NotificationRules.DeleteRule).Methods("DELETE")
+	}
+
+	// Note feed routes
+	if s.handlers.NoteFeeds != nil {
+		protected.HandleFunc("/feeds", s.handlers.NoteFeeds.ListFeeds).Methods("GET")
+		protected.HandleFunc("/feeds", s.handlers.NoteFeeds.CreateFeed).Methods("POST")
+		protected.HandleFunc("/feeds/{id}", s.handlers.NoteFeeds.DeleteFeed).Methods("DELETE")
+	}
+
+	// Image text routes
+	if s.handlers.ImageText != nil {
+		protected.HandleFunc("/notes/{id}/image-text", s.handlers.ImageText.AddImage).Methods("POST")
+		protected.HandleFunc("/notes/{id}/image-text", s.handlers.ImageText.ClearImageText).Methods("DELETE")
+	}
+
+	// Inbound email address routes
+	if s.handlers.InboundEmail != nil {
+		protected.HandleFunc("/inbound-email", s.handlers.InboundEmail.GetAddress).Methods("GET")
+		protected.HandleFunc("/inbound-email", s.handlers.InboundEmail.DisableAddress).Methods("DELETE")
+		protected.HandleFunc("/inbound-email/rotate", s.handlers.InboundEmail.RotateAddress).Methods("POST")
+	}
+
+	// Account routes
+	if s.handlers.Account != nil {
+		protected.HandleFunc("/account", s.handlers.Account.DeleteAccount).Methods("DELETE")
+		protected.HandleFunc("/account/restore", s.handlers.Account.RestoreAccount).Methods("POST")
+		protected.HandleFunc("/account/settings", s.handlers.Account.GetSettings).Methods("GET")
+		protected.HandleFunc("/account/settings", s.handlers.Account.UpdateSettings).Methods("PUT")
+	}
+
+	// Import wizard routes
+	if s.handlers.Imports != nil {
+		protected.HandleFunc("/imports", s.handlers.Imports.CreateImport).Methods("POST")
+		protected.HandleFunc("/imports/archive", s.handlers.Imports.ImportArchive).Methods("POST")
+		protected.HandleFunc("/imports/vault", s.handlers.Imports.ImportVault).Methods("POST")
+		protected.HandleFunc("/imports/{id}", s.handlers.Imports.GetImport).Methods("GET")
+		protected.HandleFunc("/imports/{id}/mapping", s.handlers.Imports.SubmitMapping).Methods("POST")
+		protected.HandleFunc("/imports/{id}/execute", s.handlers.Imports.ExecuteImport).Methods("POST")
+	}
+
+	// Export routes
+	if s.handlers.Exports != nil {
+		protected.HandleFunc("/export", s.handlers.Exports.ExportNotes).Methods("GET")
+		protected.HandleFunc("/account/data", s.handlers.Exports.ExportAccountData).Methods("GET")
+	}
+
+	// Data migration routes
+	if s.handlers.Migrations != nil {
+		protected.HandleFunc("/migrations/incoming", s.handlers.Migrations.CreateTransfer).Methods("POST")
+		protected.HandleFunc("/migrations/outgoing", s.handlers.Migrations.StartMigration).Methods("POST")
+		protected.HandleFunc("/migrations/outgoing/{id}", s.handlers.Migrations.GetMigration).Methods("GET")
+		protected.HandleFunc("/migrations/outgoing/{id}/resume", s.handlers.Migrations.ResumeMigration).Methods("POST")
+	}
+
+	// Notification routes
+	if s.handlers.Notifications != nil {
+		protected.HandleFunc("/notifications", s.handlers.Notifications.ListNotifications).Methods("GET")
+		protected.HandleFunc("/notifications/read-all", s.handlers.Notifications.MarkAllRead).Methods("POST")
+		protected.HandleFunc("/notifications/{id}/read", s.handlers.Notifications.MarkRead).Methods("POST")
+	}
+
+	// Administrator routes
+	if s.handlers.Admin != nil {
+		admin := protected.PathPrefix("/admin").Subrouter()
+		admin.Use(middleware.RequireAdmin(s.config.Admin.Emails))
+		admin.HandleFunc("/anomalies", s.handlers.Admin.ListAnomalies).Methods("GET")
+		admin.HandleFunc("/anomalies/{id}/review", s.handlers.Admin.ReviewAnomaly).Methods("POST")
+		admin.HandleFunc("/legal-holds", s.handlers.Admin.ListLegalHolds).Methods("GET")
+		admin.HandleFunc("/legal-holds", s.handlers.Admin.CreateLegalHold).Methods("POST")
+		admin.HandleFunc("/legal-holds/{id}/release", s.handlers.Admin.ReleaseLegalHold).Methods("POST")
+		admin.HandleFunc("/legal-holds/{id}/audit", s.handlers.Admin.GetLegalHoldAudit).Methods("GET")
+		admin.HandleFunc("/users", s.handlers.Admin.ListUsers).Methods("GET")
+		admin.HandleFunc("/users/{id}", s.handlers.Admin.GetUser).Methods("GET")
+		admin.HandleFunc("/users/{id}/disable", s.handlers.Admin.DisableUser).Methods("POST")
+		admin.HandleFunc("/users/{id}/enable", s.handlers.Admin.EnableUser).Methods("POST")
+		admin.HandleFunc("/users/{id}/role", s.handlers.Admin.SetUserRole).Methods("PUT")
+		if s.handlers.Usage != nil {
+			admin.HandleFunc("/users/{id}/llm-budget", s.handlers.Usage.SetUserLLMBudget).Methods("PUT")
+		}
+		if s.handlers.Audit != nil {
+			admin.HandleFunc("/audit", s.handlers.Audit.ListAllAuditLog).Methods("GET")
+		}
+		admin.HandleFunc("/prompts", s.handlers.Admin.ListPrompts).Methods("GET")
+		admin.HandleFunc("/prompts/reload", s.handlers.Admin.ReloadPrompts).Methods("POST")
+		admin.HandleFunc("/maintenance", s.handlers.Admin.ListMaintenanceTasks).Methods("GET")
+		admin.HandleFunc("/maintenance/{task}", s.handlers.Admin.RunMaintenanceTask).Methods("POST")
+	}
+
+	// Published notes, served to anyone without authentication
+	if s.handlers.Publications != nil {
+		s.router.HandleFunc("/p/{slug}", s.handlers.Publications.ServePublishedNote).Methods("GET")
+	}
+
+	// Note feeds, authenticated by the token in their URL
+	if s.handlers.NoteFeeds != nil {
+		s.router.HandleFunc("/feeds/{token}/rss", s.handlers.NoteFeeds.ServeRSS).Methods("GET")
+		s.router.HandleFunc("/feeds/{token}/atom", s.handlers.NoteFeeds.ServeAtom).Methods("GET")
+	}
+
+	// OpenAPI spec and Swagger UI, generated from the routes above
+	s.setupDocsRoutes(api)
+
 	// Static routes for serving assets (if needed)
 	// s.router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
 
 	// Catch-all route for 404
 	s.router.PathPrefix("/").HandlerFunc(s.notFoundHandler)
 
-	log.Printf("✅ Routes configured - Public: /api/v1/health, /api/v1/auth/*")
+	log.Printf("✅ Routes configured - Public: /api/v1/health, /api/v1/auth/*, /p/*, /feeds/*")
 	log.Printf("🔒 Protected routes: /api/v1/* (requires authentication + session)")
 }
 
+// setupDocsRoutes serves the OpenAPI spec of the routes registered so far.
+// The docs stay disabled when a route is missing from handlers.APIRoutes.
+func (s *Server) setupDocsRoutes(api *mux.Router) {
+	doc, err := openapi.Build(s.router, handlers.APIRoutes, openapi.Options{
+		Title:         "Silence Notes API",
+		Version:       "1.0.0",
+		Description:   "REST API of Silence Notes. Successful responses wrap their data in {\"success\": true, \"data\": ...}.",
+		APIKeyAllowed: middleware.APIKeyAllowed,
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to generate OpenAPI spec: %v - API docs disabled", err)
+		return
+	}
+
+	docs, err := handlers.NewDocsHandler(doc)
+	if err != nil {
+		log.Printf("⚠️  Failed to create docs handler: %v - API docs disabled", err)
+		return
+	}
+	api.HandleFunc("/openapi.json", docs.Spec).Methods("GET")
+	api.HandleFunc("/docs", docs.UI).Methods("GET")
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.httpServ = &http.Server{
@@ -338,19 +573,53 @@ func (s *Server) Start() error {
 	return s.httpServ.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server
+// StartGRPC starts the gRPC server on its own port
+func (s *Server) StartGRPC() error {
+	listener, err := net.Listen("tcp", s.config.Server.GRPCAddress())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Server.GRPCAddress(), err)
+	}
+	return s.grpcServ.Serve(listener)
+}
+
+// Shutdown gracefully shuts down the server. Background jobs stop being
+// scheduled right away, then the requests and the jobs in flight are waited
+// for until ctx ends.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.jobs != nil {
+		s.jobs.Stop()
+	}
+
+	if s.grpcServ != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServ.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpcServ.Stop()
+		}
+	}
+
+	var err error
 	if s.httpServ != nil {
-		return s.httpServ.Shutdown(ctx)
+		err = s.httpServ.Shutdown(ctx)
+	}
+	if s.jobs != nil {
+		if drainErr := s.jobs.Drain(ctx); drainErr != nil && err == nil {
+			err = drainErr
+		}
 	}
-	return nil
+	return err
 }
 
 // notFoundHandler handles 404 errors
 func (s *Server) notFoundHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusNotFound)
-	w.Write([]byte(`{"error":"Not found"}`))
+	json.NewEncoder(w).Encode(models.NewAPIErrorResponse(apperrors.CodeNotFound, "Not found", ""))
 }
 
 // GetRouter returns the router (useful for testing)
@@ -374,20 +643,3 @@ func (s *Server) ResetRateLimiters() {
 	// Clear any remaining global rate limiters
 	middleware.ClearUserRateLimiters()
 }
-
-// blacklistCleanupLoop runs periodic cleanup of expired blacklist entries
-func blacklistCleanupLoop(svc *services.BlacklistService, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		rows, err := svc.CleanupExpiredTokens(ctx)
-		if err != nil {
-			log.Printf("ERROR: failed to cleanup expired tokens: %v", err)
-		} else if rows > 0 {
-			log.Printf("Cleaned up %d expired blacklist entries", rows)
-		}
-		cancel()
-	}
-}
\ No newline at end of file
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// activityRetention is how long activity events are kept for country history
+const activityRetention = 90 * 24 * time.Hour
+
+// ActivityServiceInterface defines the interface for recording account activity
+type ActivityServiceInterface interface {
+	Record(ctx context.Context, userID uuid.UUID, eventType string, count int, ipAddress, country string) error
+}
+
+// ActivityService records account activity analyzed by the AnomalyService
+type ActivityService struct {
+	db *sql.DB
+}
+
+// NewActivityService creates a new ActivityService
+func NewActivityService(db *sql.DB) *ActivityService {
+	return &ActivityService{db: db}
+}
+
+// Record stores an activity event. count is the number of items the event
+// covers; country is an ISO 3166 alpha-2 code and may be empty.
+func (s *ActivityService) Record(ctx context.Context, userID uuid.UUID, eventType string, count int, ipAddress, country string) error {
+	if count < 1 {
+		count = 1
+	}
+
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 || country == "XX" {
+		// Unknown or anonymized (e.g. Tor) origins carry no country
+		country = ""
+	}
+	if len(ipAddress) > 45 {
+		ipAddress = ipAddress[:45]
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO activity_events (user_id, event_type, count, ip_address, country)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+	`, userID, eventType, count, ipAddress, country)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+
+	return nil
+}
+
+// CleanupOldEvents removes activity events past the retention period
+func (s *ActivityService) CleanupOldEvents(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM activity_events
+		WHERE created_at < $1
+	`, time.Now().Add(-activityRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup activity events: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// AdminServiceInterface defines the interface for administrator user
+// management and maintenance
+type AdminServiceInterface interface {
+	ListUsers(ctx context.Context, query string, limit, offset int) (*models.AdminUserList, error)
+	GetUser(ctx context.Context, userID string) (*models.AdminUser, error)
+	DisableUser(ctx context.Context, adminID uuid.UUID, userID string, request *models.DisableUserRequest) (*models.AdminUser, error)
+	EnableUser(ctx context.Context, userID string) (*models.AdminUser, error)
+	SetUserRole(ctx context.Context, adminID uuid.UUID, userID string, request *models.SetUserRoleRequest) (*models.AdminUser, error)
+	MaintenanceTasks() []string
+	RunMaintenanceTask(ctx context.Context, task string) (*models.MaintenanceResult, error)
+}
+
+// MaintenanceTask is a cleanup job returning the number of rows it affected
+type MaintenanceTask func(ctx context.Context) (int64, error)
+
+// AdminService manages user accounts on behalf of administrators
+type AdminService struct {
+	db    *sql.DB
+	tasks map[string]MaintenanceTask
+}
+
+// NewAdminService creates a new AdminService
+func NewAdminService(db *sql.DB) *AdminService {
+	return &AdminService{
+		db:    db,
+		tasks: make(map[string]MaintenanceTask),
+	}
+}
+
+// RegisterMaintenanceTask makes a cleanup job available to administrators by name
+func (s *AdminService) RegisterMaintenanceTask(name string, task MaintenanceTask) {
+	s.tasks[name] = task
+}
+
+// adminUserColumns lists the columns scanned by scanAdminUser
+const adminUserColumns = `u.id, u.email, u.role, u.created_at, s.last_seen, u.disabled_at, u.disabled_reason,
+	u.read_only_until, COALESCE(n.note_count, 0), COALESCE(n.storage_bytes, 0), COALESCE(s.active_sessions, 0)`
+
+// adminUserJoins adds the usage of each user to a query on users u
+const adminUserJoins = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS note_count,
+		       SUM(COALESCE(octet_length(title), 0) + octet_length(content)) AS storage_bytes
+		FROM notes WHERE user_id = u.id
+	) n ON true
+	LEFT JOIN LATERAL (
+		SELECT MAX(last_seen) AS last_seen, COUNT(*) FILTER (WHERE is_active) AS active_sessions
+		FROM user_sessions WHERE user_id = u.id
+	) s ON true`
+
+// scanAdminUser scans a row selected with adminUserColumns
+func scanAdminUser(row rowScanner, u *models.AdminUser) error {
+	return row.Scan(&u.ID, &u.Email, &u.Role, &u.CreatedAt, &u.LastSeen, &u.DisabledAt, &u.DisabledReason,
+		&u.ReadOnlyUntil, &u.NoteCount, &u.StorageBytes, &u.ActiveSessions)
+}
+
+// ListUsers returns user accounts with their usage, newest first. A non-empty
+// query filters by email.
+func (s *AdminService) ListUsers(ctx context.Context, query string, limit, offset int) (*models.AdminUserList, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	pattern := ""
+	if query = strings.TrimSpace(query); query != "" {
+		pattern = "%" + query + "%"
+	}
+
+	list := &models.AdminUserList{
+		Users:  []models.AdminUser{},
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users
+		WHERE $1 = '' OR email ILIKE $1
+	`, pattern).Scan(&list.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+adminUserColumns+`
+		FROM users u`+adminUserJoins+`
+		WHERE $1 = '' OR u.email ILIKE $1
+		ORDER BY u.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, pattern, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.AdminUser
+		if err := scanAdminUser(rows, &u); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		list.Users = append(list.Users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	list.HasMore = offset+limit < list.Total
+	return list, nil
+}
+
+// GetUser returns a user account with its usage
+func (s *AdminService) GetUser(ctx context.Context, userID string) (*models.AdminUser, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	var u models.AdminUser
+	err := scanAdminUser(s.db.QueryRowContext(ctx, `
+		SELECT `+adminUserColumns+`
+		FROM users u`+adminUserJoins+`
+		WHERE u.id = $1
+	`, userID), &u)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &u, nil
+}
+
+// DisableUser disables an account and ends all of its sessions. The user
+// cannot sign in again until the account is enabled.
+func (s *AdminService) DisableUser(ctx context.Context, adminID uuid.UUID, userID string, request *models.DisableUserRequest) (*models.AdminUser, error) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if id == adminID {
+		return nil, apperrors.Validation("SELF_MODIFICATION", "cannot disable your own account")
+	}
+
+	var reason *string
+	if r := strings.TrimSpace(request.Reason); r != "" {
+		reason = &r
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Disabling an already disabled account keeps the original time
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET disabled_at = COALESCE(disabled_at, NOW()), disabled_reason = $1
+		WHERE id = $2
+	`, reason, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to disable user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET is_active = false, revoked_at = NOW(), revoked_reason = $1
+		WHERE user_id = $2 AND is_active = true
+	`, SessionRevokedDisabled, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// EnableUser lets a disabled account sign in again
+func (s *AdminService) EnableUser(ctx context.Context, userID string) (*models.AdminUser, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET disabled_at = NULL, disabled_reason = NULL
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// SetUserRole grants or removes the administrator role. Administrators cannot
+// change their own role, so there is always one left to undo a mistake.
+func (s *AdminService) SetUserRole(ctx context.Context, adminID uuid.UUID, userID string, request *models.SetUserRoleRequest) (*models.AdminUser, error) {
+	if request.Role != models.UserRoleUser && request.Role != models.UserRoleAdmin {
+		return nil, apperrors.Validation("INVALID_ROLE", "invalid role")
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if id == adminID {
+		return nil, apperrors.Validation("SELF_MODIFICATION", "cannot change your own role")
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET role = $1 WHERE id = $2`, request.Role, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// MaintenanceTasks returns the names of the registered maintenance tasks
+func (s *AdminService) MaintenanceTasks() []string {
+	names := make([]string, 0, len(s.tasks))
+	for name := range s.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunMaintenanceTask runs a registered maintenance task now rather than
+// waiting for its next scheduled run
+func (s *AdminService) RunMaintenanceTask(ctx context.Context, task string) (*models.MaintenanceResult, error) {
+	run, ok := s.tasks[task]
+	if !ok {
+		return nil, apperrors.NotFound("MAINTENANCE_TASK_NOT_FOUND", "unknown maintenance task")
+	}
+
+	result := &models.MaintenanceResult{Task: task, StartedAt: time.Now()}
+	affected, err := run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance task %s failed: %w", task, err)
+	}
+	result.Affected = affected
+	result.DurationMS = time.Since(result.StartedAt).Milliseconds()
+
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestAdminServiceMaintenanceTasks(t *testing.T) {
+	service := NewAdminService(nil)
+	service.RegisterMaintenanceTask("cleanup_unused_tags", func(ctx context.Context) (int64, error) {
+		return 3, nil
+	})
+	service.RegisterMaintenanceTask("cleanup_broken", func(ctx context.Context) (int64, error) {
+		return 0, errors.New("boom")
+	})
+
+	if got := service.MaintenanceTasks(); !reflect.DeepEqual(got, []string{"cleanup_broken", "cleanup_unused_tags"}) {
+		t.Errorf("MaintenanceTasks() = %v", got)
+	}
+
+	result, err := service.RunMaintenanceTask(context.Background(), "cleanup_unused_tags")
+	if err != nil {
+		t.Fatalf("RunMaintenanceTask: %v", err)
+	}
+	if result.Task != "cleanup_unused_tags" || result.Affected != 3 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	if _, err := service.RunMaintenanceTask(context.Background(), "cleanup_broken"); err == nil {
+		t.Error("Expected the task error to be returned")
+	}
+	if _, err := service.RunMaintenanceTask(context.Background(), "vacuum"); err == nil || err.Error() != "unknown maintenance task" {
+		t.Errorf("Expected unknown maintenance task, got %v", err)
+	}
+}
+
+func TestAdminServiceProtectsOwnAccount(t *testing.T) {
+	// Administrators cannot lock themselves out; checked before the database
+	service := NewAdminService(nil)
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	_, err := service.DisableUser(ctx, adminID, adminID.String(), &models.DisableUserRequest{})
+	if err == nil || err.Error() != "cannot disable your own account" {
+		t.Errorf("DisableUser: expected own account error, got %v", err)
+	}
+
+	_, err = service.SetUserRole(ctx, adminID, adminID.String(), &models.SetUserRoleRequest{Role: models.UserRoleUser})
+	if err == nil || err.Error() != "cannot change your own role" {
+		t.Errorf("SetUserRole: expected own role error, got %v", err)
+	}
+
+	_, err = service.SetUserRole(ctx, adminID, uuid.NewString(), &models.SetUserRoleRequest{Role: "owner"})
+	if err == nil || err.Error() != "invalid role" {
+		t.Errorf("SetUserRole: expected invalid role, got %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/anomaly"
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// AnomalyServiceInterface defines the interface for reviewing flagged accounts
+type AnomalyServiceInterface interface {
+	ListAnomalies(ctx context.Context, status string, limit, offset int) (*models.AccountAnomalyList, error)
+	ReviewAnomaly(ctx context.Context, reviewerID uuid.UUID, anomalyID string, request *models.ReviewAnomalyRequest) (*models.AccountAnomaly, error)
+}
+
+// AnomalyService analyzes recorded activity for unusual patterns, notifies the
+// affected users and optionally locks their accounts read-only until an
+// administrator reviews the finding.
+type AnomalyService struct {
+	db                  *sql.DB
+	notificationService NotificationServiceInterface
+	thresholds          anomaly.Thresholds
+	autoLock            bool
+	lockDuration        time.Duration
+}
+
+// NewAnomalyService creates a new AnomalyService. With autoLock set, high
+// severity findings lock the account read-only for lockDuration.
+func NewAnomalyService(db *sql.DB, notificationService NotificationServiceInterface, thresholds anomaly.Thresholds, autoLock bool, lockDuration time.Duration) *AnomalyService {
+	return &AnomalyService{
+		db:                  db,
+		notificationService: notificationService,
+		thresholds:          thresholds,
+		autoLock:            autoLock,
+		lockDuration:        lockDuration,
+	}
+}
+
+// accountAnomalyColumns lists the columns scanned by scanAccountAnomaly
+const accountAnomalyColumns = "a.id, a.user_id, u.email, a.rule, a.severity, a.summary, a.details, a.status, a.locked_until, a.reviewed_by, a.reviewed_at, a.created_at"
+
+// scanAccountAnomaly scans a row selected with accountAnomalyColumns
+func scanAccountAnomaly(row rowScanner, a *models.AccountAnomaly) error {
+	var details []byte
+	err := row.Scan(&a.ID, &a.UserID, &a.UserEmail, &a.Rule, &a.Severity, &a.Summary, &details,
+		&a.Status, &a.LockedUntil, &a.ReviewedBy, &a.ReviewedAt, &a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	a.Details = details
+	return nil
+}
+
+// Analyze checks every user with activity since the given time and returns the
+// number of anomalies raised. A rule already open for a user is not raised
+// again until it has been reviewed.
+func (s *AnomalyService) Analyze(ctx context.Context, since time.Time) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT user_id FROM activity_events WHERE created_at > $1
+	`, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find active users: %w", err)
+	}
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating active users: %w", err)
+	}
+
+	raised := 0
+	for _, userID := range userIDs {
+		n, err := s.analyzeUser(ctx, userID)
+		if err != nil {
+			log.Printf("ERROR: failed to analyze activity of user %s: %v", userID, err)
+			continue
+		}
+		raised += n
+	}
+
+	return raised, nil
+}
+
+// analyzeUser runs the detector over one user's recent activity
+func (s *AnomalyService) analyzeUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	now := time.Now()
+	windowStart := now.Add(-s.thresholds.Lookback())
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT event_type, count, COALESCE(country, ''), created_at
+		FROM activity_events
+		WHERE user_id = $1 AND created_at > $2
+	`, userID, windowStart)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load activity: %w", err)
+	}
+
+	var events []anomaly.Event
+	for rows.Next() {
+		var event anomaly.Event
+		if err := rows.Scan(&event.Type, &event.Count, &event.Country, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating activity: %w", err)
+	}
+
+	// Countries seen before the analyzed window make up the user's history
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT DISTINCT country
+		FROM activity_events
+		WHERE user_id = $1 AND country IS NOT NULL AND created_at <= $2
+	`, userID, windowStart)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load known countries: %w", err)
+	}
+
+	knownCountries := make(map[string]bool)
+	for rows.Next() {
+		var country string
+		if err := rows.Scan(&country); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan country: %w", err)
+		}
+		knownCountries[strings.ToUpper(country)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating known countries: %w", err)
+	}
+
+	raised := 0
+	for _, finding := range anomaly.Detect(events, knownCountries, now, s.thresholds) {
+		created, err := s.raise(ctx, userID, finding)
+		if err != nil {
+			return raised, err
+		}
+		if created {
+			raised++
+		}
+	}
+
+	return raised, nil
+}
+
+// raise stores a finding, locks the account when configured and notifies the
+// user. It reports false when the same rule is already open for the user.
+func (s *AnomalyService) raise(ctx context.Context, userID uuid.UUID, finding anomaly.Finding) (bool, error) {
+	details, err := json.Marshal(finding.Details)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode anomaly details: %w", err)
+	}
+
+	var lockedUntil *time.Time
+	if s.autoLock && finding.Severity == anomaly.SeverityHigh {
+		until := time.Now().Add(s.lockDuration)
+		lockedUntil = &until
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var anomalyID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO account_anomalies (user_id, rule, severity, summary, details, locked_until)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE NOT EXISTS (
+			SELECT 1 FROM account_anomalies
+			WHERE user_id = $1 AND rule = $2 AND status = 'open'
+		)
+		RETURNING id
+	`, userID, finding.Rule, finding.Severity, finding.Summary, details, lockedUntil).Scan(&anomalyID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store anomaly: %w", err)
+	}
+
+	if lockedUntil != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users
+			SET read_only_until = GREATEST(COALESCE(read_only_until, $2), $2)
+			WHERE id = $1
+		`, userID, *lockedUntil)
+		if err != nil {
+			return false, fmt.Errorf("failed to lock account: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit anomaly: %w", err)
+	}
+
+	body := fmt.Sprintf("We noticed unusual activity on your account: %s. If this wasn't you, sign out of all sessions.", finding.Summary)
+	if lockedUntil != nil {
+		body += " Your account is read-only until the activity has been reviewed."
+	}
+	data := map[string]any{"anomaly_id": anomalyID, "rule": finding.Rule}
+	if lockedUntil != nil {
+		data["read_only_until"] = lockedUntil
+	}
+	if s.notificationService != nil {
+		if _, err := s.notificationService.Notify(ctx, userID, models.NotificationTypeSecurityAlert,
+			"Unusual account activity", body, data); err != nil {
+			log.Printf("ERROR: failed to notify user %s of anomaly %s: %v", userID, anomalyID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// ListAnomalies returns flagged activity, newest first. An empty status lists
+// anomalies of every status.
+func (s *AnomalyService) ListAnomalies(ctx context.Context, status string, limit, offset int) (*models.AccountAnomalyList, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	switch status {
+	case "", models.AnomalyStatusOpen, models.AnomalyStatusConfirmed, models.AnomalyStatusDismissed:
+	default:
+		return nil, apperrors.Validation("INVALID_STATUS", "invalid anomaly status")
+	}
+
+	list := &models.AccountAnomalyList{
+		Anomalies: []models.AccountAnomaly{},
+		Limit:     limit,
+		Offset:    offset,
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM account_anomalies
+		WHERE $1 = '' OR status = $1
+	`, status).Scan(&list.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count anomalies: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+accountAnomalyColumns+`
+		FROM account_anomalies a
+		JOIN users u ON u.id = a.user_id
+		WHERE $1 = '' OR a.status = $1
+		ORDER BY a.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.AccountAnomaly
+		if err := scanAccountAnomaly(rows, &a); err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
+		}
+		list.Anomalies = append(list.Anomalies, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating anomalies: %w", err)
+	}
+
+	list.HasMore = offset+limit < list.Total
+	return list, nil
+}
+
+// ReviewAnomaly records an administrator's decision on an open anomaly and,
+// when requested, lifts the account's read-only lock
+func (s *AnomalyService) ReviewAnomaly(ctx context.Context, reviewerID uuid.UUID, anomalyID string, request *models.ReviewAnomalyRequest) (*models.AccountAnomaly, error) {
+	if request.Status != models.AnomalyStatusConfirmed && request.Status != models.AnomalyStatusDismissed {
+		return nil, apperrors.Validation("INVALID_STATUS", "status must be confirmed or dismissed")
+	}
+
+	id, err := uuid.Parse(anomalyID)
+	if err != nil {
+		return nil, apperrors.NotFound("ANOMALY_NOT_FOUND", "anomaly not found")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var userID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, user_id FROM account_anomalies WHERE id = $1 FOR UPDATE
+	`, id).Scan(&status, &userID)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.NotFound("ANOMALY_NOT_FOUND", "anomaly not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anomaly: %w", err)
+	}
+	if status != models.AnomalyStatusOpen {
+		return nil, apperrors.Conflict("ANOMALY_REVIEWED", "anomaly has already been reviewed")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE account_anomalies
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $1
+	`, id, request.Status, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to review anomaly: %w", err)
+	}
+
+	if request.Unlock {
+		_, err = tx.ExecContext(ctx, `UPDATE users SET read_only_until = NULL WHERE id = $1`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unlock account: %w", err)
+		}
+	}
+
+	var reviewed models.AccountAnomaly
+	err = scanAccountAnomaly(tx.QueryRowContext(ctx, `
+		SELECT `+accountAnomalyColumns+`
+		FROM account_anomalies a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.id = $1
+	`, id), &reviewed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anomaly: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return &reviewed, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, so keys are recognizable in
+// configuration files and secret scanners
+const APIKeyPrefix = "mn_"
+
+// apiKeyDisplayLength is the length of the key start stored in clear
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// maxAPIKeysPerUser limits the active API keys a user can have
+const maxAPIKeysPerUser = 20
+
+// ErrInvalidAPIKey is returned when an API key is unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKeyServiceInterface defines the interface for API key operations
+type APIKeyServiceInterface interface {
+	CreateKey(ctx context.Context, userID string, request *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error)
+	ListKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	RevokeKey(ctx context.Context, userID, keyID string) error
+	Authenticate(ctx context.Context, key string) (string, error)
+}
+
+// APIKeyService manages the API keys integrations authenticate with. Only
+// hashes of the keys are stored.
+type APIKeyService struct {
+	db *sql.DB
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(db *sql.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// CreateKey creates an API key for a user. The key is only returned here.
+func (s *APIKeyService) CreateKey(ctx context.Context, userID string, request *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_API_KEY_REQUEST", err)
+	}
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, apperrors.Validation("API_KEY_LIMIT_REACHED", fmt.Sprintf("API key limit of %d reached", maxAPIKeysPerUser))
+	}
+
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	created := &models.CreatedAPIKey{
+		APIKey: models.APIKey{
+			UserID: uuid.MustParse(userID),
+			Name:   request.Name,
+			Prefix: key[:apiKeyDisplayLength],
+		},
+		Key: key,
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, userID, request.Name, created.Prefix, hashAPIKey(key)).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return created, nil
+}
+
+// ListKeys returns a user's API keys, including revoked ones, newest first
+func (s *APIKeyService) ListKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, name, prefix, last_used_at, created_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix,
+			&key.LastUsedAt, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeKey revokes a user's API key. Revoked keys stay listed so users can
+// see when a key was last used.
+func (s *APIKeyService) RevokeKey(ctx context.Context, userID, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return apperrors.NotFound("API_KEY_NOT_FOUND", "API key not found")
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.NotFound("API_KEY_NOT_FOUND", "API key not found")
+	}
+
+	return nil
+}
+
+// Authenticate returns the ID of the user owning an active API key and
+// records the key as used
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (string, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", ErrInvalidAPIKey
+	}
+
+	var userID string
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING user_id
+	`, hashAPIKey(key)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidAPIKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	return userID, nil
+}
+
+// generateAPIKey returns a new random API key
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// hashAPIKey returns the hex SHA-256 of a key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxAuditUserAgentLength bounds the user agents stored in the audit log
+const maxAuditUserAgentLength = 512
+
+// AuditServiceInterface defines the interface for the audit log
+type AuditServiceInterface interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, filter models.AuditLogFilter, limit, offset int) (*models.AuditLogList, error)
+}
+
+// AuditService records who did what to which note, and lists the records
+// for users and administrators. Entries are kept for the retention period,
+// or longer while the user is under legal hold.
+type AuditService struct {
+	db        *sql.DB
+	retention time.Duration
+}
+
+// NewAuditService creates a new AuditService. A retention of 0 keeps entries
+// forever.
+func NewAuditService(db *sql.DB, retention time.Duration) *AuditService {
+	return &AuditService{
+		db:        db,
+		retention: retention,
+	}
+}
+
+// auditEntryColumns lists the columns scanned by scanAuditEntry
+const auditEntryColumns = "a.id, a.user_id, COALESCE(u.email, ''), a.action, a.resource_type, a.resource_id, a.count, COALESCE(a.ip_address, ''), COALESCE(a.user_agent, ''), a.created_at"
+
+// scanAuditEntry scans a row selected with auditEntryColumns
+func scanAuditEntry(row rowScanner, e *models.AuditEntry) error {
+	return row.Scan(&e.ID, &e.UserID, &e.UserEmail, &e.Action, &e.ResourceType, &e.ResourceID,
+		&e.Count, &e.IPAddress, &e.UserAgent, &e.CreatedAt)
+}
+
+// Record appends an entry to the audit log. ID, UserEmail and CreatedAt are
+// ignored.
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	count := entry.Count
+	if count < 1 {
+		count = 1
+	}
+
+	ipAddress := entry.IPAddress
+	if len(ipAddress) > 45 {
+		ipAddress = ipAddress[:45]
+	}
+	userAgent := entry.UserAgent
+	if len(userAgent) > maxAuditUserAgentLength {
+		userAgent = userAgent[:maxAuditUserAgentLength]
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, count, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+	`, entry.UserID, entry.Action, entry.ResourceType, entry.ResourceID, count, ipAddress, userAgent)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the audit entries matching filter, newest first
+func (s *AuditService) List(ctx context.Context, filter models.AuditLogFilter, limit, offset int) (*models.AuditLogList, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	where, args, err := auditConditions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.AuditLogList{
+		Entries: []models.AuditEntry{},
+		Limit:   limit,
+		Offset:  offset,
+	}
+
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_log a "+where, args...).Scan(&list.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+auditEntryColumns+`
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.user_id
+		%s
+		ORDER BY a.created_at DESC, a.id
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := scanAuditEntry(rows, &entry); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		list.Entries = append(list.Entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", err)
+	}
+
+	list.HasMore = offset+limit < list.Total
+	return list, nil
+}
+
+// auditConditions builds the WHERE clause and arguments selecting the audit
+// entries of filter, with placeholders starting at $1
+func auditConditions(filter models.AuditLogFilter) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != "" {
+		userID, err := uuid.Parse(filter.UserID)
+		if err != nil {
+			return "", nil, apperrors.Validation("INVALID_USER_ID", "invalid user ID")
+		}
+		add("a.user_id = $%d", userID)
+	}
+	if filter.Action != "" {
+		if !models.IsAuditAction(filter.Action) {
+			return "", nil, apperrors.Validation("INVALID_ACTION", "invalid audit action")
+		}
+		add("a.action = $%d", filter.Action)
+	}
+	if filter.Since != nil {
+		add("a.created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		add("a.created_at < $%d", *filter.Until)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// CleanupOldEntries removes audit entries past the retention period, except
+// those of users under an active legal hold
+func (s *AuditService) CleanupOldEntries(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM audit_log a
+		WHERE a.created_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM legal_holds h
+			WHERE h.user_id = a.user_id AND h.released_at IS NULL
+		  )
+	`, time.Now().Add(-s.retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup audit entries: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestAuditConditions(t *testing.T) {
+	where, args, err := auditConditions(models.AuditLogFilter{})
+	if err != nil || where != "" || len(args) != 0 {
+		t.Errorf("Expected no conditions for an empty filter, got %q %v %v", where, args, err)
+	}
+
+	userID := uuid.New()
+	since := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 1, 0)
+	where, args, err = auditConditions(models.AuditLogFilter{
+		UserID: userID.String(),
+		Action: models.AuditActionDelete,
+		Since:  &since,
+		Until:  &until,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantWhere := "WHERE a.user_id = $1 AND a.action = $2 AND a.created_at >= $3 AND a.created_at < $4"
+	if where != wantWhere {
+		t.Errorf("Expected %q, got %q", wantWhere, where)
+	}
+	if wantArgs := []interface{}{userID, models.AuditActionDelete, since, until}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Expected arguments %v, got %v", wantArgs, args)
+	}
+}
+
+func TestAuditConditionsRejectsInvalidFilters(t *testing.T) {
+	if _, _, err := auditConditions(models.AuditLogFilter{UserID: "not-a-uuid"}); err == nil {
+		t.Error("Expected an invalid user ID to be rejected")
+	}
+	if _, _, err := auditConditions(models.AuditLogFilter{Action: "share"}); err == nil {
+		t.Error("Expected an unknown action to be rejected")
+	}
+}
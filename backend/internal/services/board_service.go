@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// BoardServiceInterface defines the interface for kanban board operations
+type BoardServiceInterface interface {
+	CreateBoard(ctx context.Context, userID string, request *models.CreateBoardRequest) (*models.Board, error)
+	ListBoards(ctx context.Context, userID string) ([]models.Board, error)
+	GetBoard(ctx context.Context, userID, boardID string, limit int) (*models.BoardView, error)
+	DeleteBoard(ctx context.Context, userID, boardID string) error
+	MoveCard(ctx context.Context, userID, boardID string, request *models.MoveCardRequest) (*models.Note, error)
+}
+
+// ErrBoardNotFound is returned for boards that do not exist
+var ErrBoardNotFound = apperrors.NotFound("BOARD_NOT_FOUND", "board not found")
+
+// BoardService stores kanban boards and moves notes between their columns.
+// Columns are status tags, so a board is a view over the user's notes and a
+// move is an ordinary note update changing the status tag.
+type BoardService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+}
+
+// NewBoardService creates a new BoardService
+func NewBoardService(db *sql.DB, noteService NoteServiceInterface) *BoardService {
+	return &BoardService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// boardColumns lists the boards columns in scanBoard order
+const boardColumns = "id, user_id, name, columns, created_at, updated_at"
+
+// scanBoard scans a row selected with boardColumns
+func scanBoard(row rowScanner, b *models.Board) error {
+	return row.Scan(&b.ID, &b.UserID, &b.Name, pq.Array(&b.Columns), &b.CreatedAt, &b.UpdatedAt)
+}
+
+// CreateBoard creates a board for a user
+func (s *BoardService) CreateBoard(ctx context.Context, userID string, request *models.CreateBoardRequest) (*models.Board, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_BOARD", err)
+	}
+
+	var board models.Board
+	err := scanBoard(s.db.QueryRowContext(ctx, `
+		INSERT INTO boards (id, user_id, name, columns)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+boardColumns,
+		uuid.New(), userID, request.Name, pq.Array(request.Columns)), &board)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.Conflict("BOARD_EXISTS", "board with this name already exists")
+		}
+		return nil, fmt.Errorf("failed to create board: %w", err)
+	}
+
+	return &board, nil
+}
+
+// ListBoards returns a user's boards ordered by name
+func (s *BoardService) ListBoards(ctx context.Context, userID string) ([]models.Board, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+boardColumns+`
+		FROM boards
+		WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list boards: %w", err)
+	}
+	defer rows.Close()
+
+	boards := []models.Board{}
+	for rows.Next() {
+		var board models.Board
+		if err := scanBoard(rows, &board); err != nil {
+			return nil, fmt.Errorf("failed to scan board: %w", err)
+		}
+		boards = append(boards, board)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating boards: %w", err)
+	}
+
+	return boards, nil
+}
+
+// getBoard retrieves a board by ID for a specific user
+func (s *BoardService) getBoard(ctx context.Context, userID, boardID string) (*models.Board, error) {
+	if _, err := uuid.Parse(boardID); err != nil {
+		return nil, ErrBoardNotFound
+	}
+
+	var board models.Board
+	err := scanBoard(s.db.QueryRowContext(ctx, `
+		SELECT `+boardColumns+`
+		FROM boards
+		WHERE id = $1 AND user_id = $2
+	`, boardID, userID), &board)
+	if err == sql.ErrNoRows {
+		return nil, ErrBoardNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get board: %w", err)
+	}
+
+	return &board, nil
+}
+
+// GetBoard returns a board with up to limit notes per column, most recently
+// updated first. A note with the tags of several columns is a card in the
+// first of them only.
+func (s *BoardService) GetBoard(ctx context.Context, userID, boardID string, limit int) (*models.BoardView, error) {
+	board, err := s.getBoard(ctx, userID, boardID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	view := &models.BoardView{Board: board, Columns: make([]models.BoardColumn, 0, len(board.Columns))}
+	placed := make(map[uuid.UUID]bool)
+	for _, tag := range board.Columns {
+		// Earlier columns may claim some of the notes, so read a page at a time
+		column := models.BoardColumn{Tag: tag, Notes: []models.NoteResponse{}}
+		for offset := 0; ; offset += limit {
+			page, err := s.noteService.GetNotesByTag(ctx, userID, tag, false, limit, offset)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get notes of column %s: %w", tag, err)
+			}
+			for _, note := range page.Notes {
+				if placed[note.ID] {
+					continue
+				}
+				if len(column.Notes) == limit {
+					column.HasMore = true
+					break
+				}
+				placed[note.ID] = true
+				column.Notes = append(column.Notes, note)
+			}
+			if column.HasMore || !page.HasMore || len(page.Notes) == 0 {
+				break
+			}
+		}
+		view.Columns = append(view.Columns, column)
+	}
+
+	return view, nil
+}
+
+// DeleteBoard deletes a board. Its notes and tags are kept.
+func (s *BoardService) DeleteBoard(ctx context.Context, userID, boardID string) error {
+	if _, err := uuid.Parse(boardID); err != nil {
+		return ErrBoardNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM boards WHERE id = $1 AND user_id = $2
+	`, boardID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete board: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrBoardNotFound
+	}
+
+	return nil
+}
+
+// MoveCard moves a note to a column of the board by swapping its status tag.
+// The content and tag associations change in one versioned note update, so
+// a concurrent edit fails with ErrVersionMismatch instead of being lost.
+// Notes not on the board yet are added to the column.
+func (s *BoardService) MoveCard(ctx context.Context, userID, boardID string, request *models.MoveCardRequest) (*models.Note, error) {
+	board, err := s.getBoard(ctx, userID, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	to := search.NormalizeTag(strings.ToLower(strings.TrimSpace(request.Column)))
+	onBoard := false
+	for _, column := range board.Columns {
+		onBoard = onBoard || column == to
+	}
+	if !onBoard {
+		return nil, apperrors.Validation("INVALID_COLUMN", fmt.Sprintf("board has no column %s", to))
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, userID, request.NoteID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Version != nil && *request.Version != note.Version {
+		return nil, ErrVersionMismatch
+	}
+
+	content := models.MoveCard(note.Content, board.Columns, to)
+	if content == note.Content {
+		return note, nil
+	}
+
+	version := note.Version
+	return s.noteService.UpdateNote(ctx, userID, note.ID.String(), &models.UpdateNoteRequest{
+		Content: &content,
+		Version: &version,
+	})
+}
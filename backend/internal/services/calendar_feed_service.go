@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+)
+
+// CalendarFeedServiceInterface defines the interface for iCal feeds
+type CalendarFeedServiceInterface interface {
+	EnableFeed(ctx context.Context, userID string) (*models.CreatedCalendarFeed, error)
+	GetFeed(ctx context.Context, userID string) (*models.CalendarFeed, error)
+	DisableFeed(ctx context.Context, userID string) error
+	GetFeedEvents(ctx context.Context, token string) ([]models.CalendarEvent, error)
+}
+
+// ErrCalendarFeedNotFound is returned for users without a feed and unknown
+// feed tokens
+var ErrCalendarFeedNotFound = apperrors.NotFound("CALENDAR_FEED_NOT_FOUND", "calendar feed not found")
+
+// CalendarFeedService serves a user's due dates, the values of their date
+// properties, and the schedules of their recurring notes as an iCal feed,
+// so they show up in calendar apps
+type CalendarFeedService struct {
+	db                *sql.DB
+	dialect           database.Dialect
+	recurrenceService RecurrenceServiceInterface
+}
+
+// NewCalendarFeedService creates a new CalendarFeedService
+func NewCalendarFeedService(db *sql.DB) *CalendarFeedService {
+	return &CalendarFeedService{
+		db:      db,
+		dialect: database.DialectOf(db),
+	}
+}
+
+// SetRecurrenceService adds the schedules of recurring notes to feeds
+func (s *CalendarFeedService) SetRecurrenceService(recurrenceService RecurrenceServiceInterface) {
+	s.recurrenceService = recurrenceService
+}
+
+// EnableFeed creates the user's feed, or replaces its token so the previous
+// URL stops working. The token is only returned here.
+func (s *CalendarFeedService) EnableFeed(ctx context.Context, userID string) (*models.CreatedCalendarFeed, error) {
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate calendar feed token: %w", err)
+	}
+
+	created := &models.CreatedCalendarFeed{
+		Token: token,
+		Path:  models.CalendarFeedPath + "?token=" + token,
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO calendar_feeds (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_used_at = NULL
+		RETURNING created_at
+	`, userID, hashFeedToken(token)).Scan(&created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable calendar feed: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetFeed returns the user's feed, without its token
+func (s *CalendarFeedService) GetFeed(ctx context.Context, userID string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := s.db.QueryRowContext(ctx, `
+		SELECT created_at, last_used_at FROM calendar_feeds WHERE user_id = $1
+	`, userID).Scan(&feed.CreatedAt, &feed.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+	return &feed, nil
+}
+
+// DisableFeed deletes the user's feed; its URL stops working
+func (s *CalendarFeedService) DisableFeed(ctx context.Context, userID string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM calendar_feeds WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable calendar feed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrCalendarFeedNotFound
+	}
+
+	return nil
+}
+
+// GetFeedEvents returns the events of the feed of a token and records the
+// feed as read: an all-day event per note and date property set on it, and
+// a repeating event per active recurring note. Feeds of disabled accounts
+// are not found.
+func (s *CalendarFeedService) GetFeedEvents(ctx context.Context, token string) ([]models.CalendarEvent, error) {
+	var userID string
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE calendar_feeds SET last_used_at = NOW()
+		WHERE token_hash = $1
+			AND user_id IN (SELECT id FROM users WHERE disabled_at IS NULL)
+		RETURNING user_id
+	`, hashFeedToken(token)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+
+	events, err := s.dueDateEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.recurrenceService != nil {
+		recurrences, err := s.recurrenceService.ListRecurrences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, recurrence := range recurrences {
+			if recurrence.Paused {
+				continue
+			}
+			loc, err := time.LoadLocation(recurrence.TimeZone)
+			if err != nil {
+				loc = time.UTC
+			}
+			events = append(events, models.CalendarEvent{
+				UID:      "recurrence-" + recurrence.ID.String() + "@silence-notes",
+				Summary:  "Recurring note (" + recurrence.Frequency + ")",
+				Start:    recurrence.NextRunAt.In(loc),
+				TimeZone: loc.String(),
+				RRule:    recurrence.RRule(),
+				Stamp:    recurrence.UpdatedAt,
+			})
+		}
+	}
+
+	if len(events) > models.MaxCalendarFeedEvents {
+		events = events[:models.MaxCalendarFeedEvents]
+	}
+	return events, nil
+}
+
+// dueDateEvents returns an all-day event per note of the user and date
+// property set on it, latest dates first
+func (s *CalendarFeedService) dueDateEvents(ctx context.Context, userID string) ([]models.CalendarEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name FROM note_properties WHERE user_id = $1 AND type = $2 ORDER BY name
+	`, userID, models.PropertyDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get date properties: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan date property: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating date properties: %w", err)
+	}
+
+	events := []models.CalendarEvent{}
+	for _, name := range names {
+		// Property names only hold lowercase letters, digits and
+		// underscores, so they are safe to use as JSON keys in SQL
+		value := s.dialect.JSONText("properties", name)
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT id, title, `+value+`, updated_at
+			FROM notes
+			WHERE user_id = $1 AND `+value+` IS NOT NULL AND `+value+` <> ''
+			ORDER BY `+value+` DESC
+			LIMIT $2
+		`, userID, models.MaxCalendarFeedEvents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get due dates: %w", err)
+		}
+
+		for rows.Next() {
+			var noteID, date string
+			var title sql.NullString
+			var updatedAt time.Time
+			if err := rows.Scan(&noteID, &title, &date, &updatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan due date: %w", err)
+			}
+			day, err := time.Parse(search.DateLayout, date)
+			if err != nil {
+				continue
+			}
+			summary := title.String
+			if summary == "" {
+				summary = "Untitled note"
+			}
+			events = append(events, models.CalendarEvent{
+				UID:         "note-" + noteID + "-" + name + "@silence-notes",
+				Summary:     summary,
+				Description: name,
+				Start:       day,
+				AllDay:      true,
+				Stamp:       updatedAt,
+			})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating due dates: %w", err)
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestCalendarFeed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewCalendarFeedService(db)
+	propertyService := NewPropertyService(db)
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	other := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	if _, err := service.GetFeed(ctx, userID); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("Expected no feed before enabling one, got %v", err)
+	}
+
+	for _, u := range []string{userID, other.ID.String()} {
+		if _, err := propertyService.CreateProperty(ctx, u, &models.CreatePropertyRequest{Name: "due", Type: models.PropertyDate}); err != nil {
+			t.Fatalf("Failed to create property: %v", err)
+		}
+	}
+	due, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{
+		Title: "Pay rent", Content: "Monthly", Properties: map[string]any{"due": "2024-03-31"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "No due date"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, other.ID.String(), &models.CreateNoteRequest{
+		Content: "Not mine", Properties: map[string]any{"due": "2024-04-01"},
+	}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	created, err := service.EnableFeed(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to enable feed: %v", err)
+	}
+	if created.Path != "/api/v1/calendar.ics?token="+created.Token {
+		t.Errorf("Unexpected feed %+v", created)
+	}
+
+	events, err := service.GetFeedEvents(ctx, created.Token)
+	if err != nil {
+		t.Fatalf("Failed to get feed events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected the user's due date, got %+v", events)
+	}
+	event := events[0]
+	if event.UID != "note-"+due.ID.String()+"-due@silence-notes" || event.Summary != "Pay rent" || !event.AllDay || event.Start.Format("2006-01-02") != "2024-03-31" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	feed, err := service.GetFeed(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get feed: %v", err)
+	}
+	if feed.LastUsedAt == nil {
+		t.Error("Expected reading the feed to record it as used")
+	}
+
+	// Enabling again replaces the token
+	rotated, err := service.EnableFeed(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to enable feed again: %v", err)
+	}
+	if _, err := service.GetFeedEvents(ctx, created.Token); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("Expected the previous token to stop working, got %v", err)
+	}
+	if _, err := service.GetFeedEvents(ctx, rotated.Token); err != nil {
+		t.Errorf("Failed to get feed events with the new token: %v", err)
+	}
+
+	if err := service.DisableFeed(ctx, userID); err != nil {
+		t.Fatalf("Failed to disable feed: %v", err)
+	}
+	if _, err := service.GetFeedEvents(ctx, rotated.Token); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("Expected a disabled feed not to be found, got %v", err)
+	}
+	if err := service.DisableFeed(ctx, userID); !errors.Is(err, ErrCalendarFeedNotFound) {
+		t.Errorf("Expected disabling twice to fail, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+)
+
+// CalendarServiceInterface defines the interface for the notes calendar
+type CalendarServiceInterface interface {
+	GetCalendar(ctx context.Context, userID string, request *models.CalendarRequest) (*models.Calendar, error)
+}
+
+// CalendarService counts the notes created each day, for calendar and
+// heatmap views
+type CalendarService struct {
+	db *sql.DB
+}
+
+// NewCalendarService creates a new CalendarService
+func NewCalendarService(db *sql.DB) *CalendarService {
+	return &CalendarService{db: db}
+}
+
+// GetCalendar returns the notes created each day of the request's range.
+// Notes are grouped here rather than in SQL so days follow the requested
+// time zone on every database; only IDs, titles and dates are read.
+func (s *CalendarService) GetCalendar(ctx context.Context, userID string, request *models.CalendarRequest) (*models.Calendar, error) {
+	loc := request.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	from := time.Date(request.From.Year(), request.From.Month(), request.From.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(request.To.Year(), request.To.Month(), request.To.Day(), 0, 0, 0, 0, loc)
+	if to.Before(from) {
+		return nil, apperrors.Validation("INVALID_RANGE", "from must not be after to")
+	}
+	if to.After(from.AddDate(0, 0, models.MaxCalendarDays-1)) {
+		return nil, apperrors.Validation("INVALID_RANGE", fmt.Sprintf("range is longer than %d days", models.MaxCalendarDays))
+	}
+	notesPerDay := request.NotesPerDay
+	if notesPerDay < 0 || notesPerDay > models.MaxCalendarNotesPerDay {
+		notesPerDay = models.DefaultCalendarNotesPerDay
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, COALESCE(title, ''), created_at, is_private
+		FROM notes
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at
+	`, userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes for calendar: %w", err)
+	}
+	defer rows.Close()
+
+	calendar := &models.Calendar{
+		From:     from.Format(search.DateLayout),
+		To:       to.Format(search.DateLayout),
+		TimeZone: loc.String(),
+		Days:     []models.CalendarDay{},
+	}
+	for rows.Next() {
+		var note models.CalendarNote
+		if err := rows.Scan(&note.ID, &note.Title, &note.CreatedAt, &note.IsPrivate); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar note: %w", err)
+		}
+
+		date := note.CreatedAt.In(loc).Format(search.DateLayout)
+		if n := len(calendar.Days); n == 0 || calendar.Days[n-1].Date != date {
+			calendar.Days = append(calendar.Days, models.CalendarDay{Date: date, Notes: []models.CalendarNote{}})
+		}
+		day := &calendar.Days[len(calendar.Days)-1]
+		day.Count++
+		if len(day.Notes) < notesPerDay {
+			day.Notes = append(day.Notes, note)
+		} else {
+			day.HasMore = true
+		}
+		calendar.Total++
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating calendar notes: %w", err)
+	}
+
+	return calendar, nil
+}
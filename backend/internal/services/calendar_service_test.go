@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestGetCalendar(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewCalendarService(db)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	for _, createdAt := range []time.Time{
+		time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC),
+		// Still March 1 in New York
+		time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC),
+	} {
+		note := testutil.NewTestNote(t, db, user.ID, "Standup", "Notes")
+		if _, err := db.Exec("UPDATE notes SET created_at = $2 WHERE id = $1", note.ID, createdAt); err != nil {
+			t.Fatalf("Failed to date note: %v", err)
+		}
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+	calendar, err := service.GetCalendar(ctx, user.ID.String(), &models.CalendarRequest{
+		From:        time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		Location:    newYork,
+		NotesPerDay: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get calendar: %v", err)
+	}
+
+	if calendar.Total != 5 || len(calendar.Days) != 2 {
+		t.Fatalf("Expected 5 notes on 2 days, got %+v", calendar)
+	}
+	first := calendar.Days[0]
+	if first.Date != "2024-03-01" || first.Count != 4 || len(first.Notes) != 2 || !first.HasMore {
+		t.Errorf("Unexpected first day %+v", first)
+	}
+	if calendar.Days[1].Date != "2024-03-05" || calendar.Days[1].Count != 1 || calendar.Days[1].HasMore {
+		t.Errorf("Unexpected second day %+v", calendar.Days[1])
+	}
+	if calendar.TimeZone != "America/New_York" || calendar.From != "2024-03-01" || calendar.To != "2024-03-31" {
+		t.Errorf("Unexpected range %s to %s in %s", calendar.From, calendar.To, calendar.TimeZone)
+	}
+
+	// Ranges are limited to a year
+	_, err = service.GetCalendar(ctx, user.ID.String(), &models.CalendarRequest{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err == nil {
+		t.Error("Expected a range over 366 days to be rejected")
+	}
+}
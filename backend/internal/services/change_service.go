@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// changeRetention is how long change records are kept. Clients whose cursor
+// is older must resync from scratch.
+const changeRetention = 30 * 24 * time.Hour
+
+// ErrCursorExpired is returned when changes after a cursor have been purged
+var ErrCursorExpired = apperrors.New(apperrors.ErrGone, "CURSOR_EXPIRED", "cursor expired, resync required")
+
+// ErrInvalidCursor is returned for cursors not issued by the change feed
+var ErrInvalidCursor = apperrors.Validation("INVALID_CURSOR", "invalid cursor")
+
+// ChangeServiceInterface defines the interface for reading the change log
+type ChangeServiceInterface interface {
+	ListChanges(ctx context.Context, userID, since string, limit int) (*models.ChangeFeed, error)
+	ListEvents(ctx context.Context, userID, entityType string, lastSeq int64, limit int) (*models.ChangeEvents, error)
+}
+
+// ChangeService reads the change log written by database triggers. Records
+// are ordered by writing transaction and only returned once every earlier
+// transaction has finished, so a transaction that commits late cannot end up
+// behind a cursor a client already holds. The records of each user are also
+// numbered in commit order, so they can be read after a sequence number.
+type ChangeService struct {
+	db *sql.DB
+}
+
+// NewChangeService creates a new ChangeService
+func NewChangeService(db *sql.DB) *ChangeService {
+	return &ChangeService{db: db}
+}
+
+// changeCursor is the position after a change record
+type changeCursor struct {
+	txID int64
+	id   int64
+}
+
+// encodeChangeCursor returns the opaque form of a cursor
+func encodeChangeCursor(c changeCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.txID, c.id)))
+}
+
+// decodeChangeCursor parses an opaque cursor; the empty cursor is the start of the log
+func decodeChangeCursor(cursor string) (changeCursor, error) {
+	if cursor == "" {
+		return changeCursor{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return changeCursor{}, ErrInvalidCursor
+	}
+	txPart, idPart, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return changeCursor{}, ErrInvalidCursor
+	}
+	txID, err := strconv.ParseInt(txPart, 10, 64)
+	if err != nil || txID < 0 {
+		return changeCursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id < 0 {
+		return changeCursor{}, ErrInvalidCursor
+	}
+
+	return changeCursor{txID: txID, id: id}, nil
+}
+
+// ListChanges returns the user's changes after the since cursor, oldest first
+func (s *ChangeService) ListChanges(ctx context.Context, userID, since string, limit int) (*models.ChangeFeed, error) {
+	cursor, err := decodeChangeCursor(since)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	if since != "" {
+		// Records are purged in log order; a cursor before the oldest record left
+		// may have missed purged changes
+		var oldest sql.NullInt64
+		if err := s.db.QueryRowContext(ctx, `SELECT MIN(id) FROM change_log`).Scan(&oldest); err != nil {
+			return nil, fmt.Errorf("failed to check change log: %w", err)
+		}
+		if oldest.Valid && cursor.id < oldest.Int64-1 {
+			return nil, ErrCursorExpired
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tx_id, id, seq, entity_type, entity_id, operation, version, changed_at
+		FROM change_log
+		WHERE user_id = $1
+		  AND (tx_id, id) > ($2, $3)
+		  AND tx_id < txid_snapshot_xmin(txid_current_snapshot())
+		ORDER BY tx_id, id
+		LIMIT $4
+	`, userID, cursor.txID, cursor.id, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	defer rows.Close()
+
+	feed := &models.ChangeFeed{
+		Changes:    []models.ChangeRecord{},
+		NextCursor: since,
+	}
+	for rows.Next() {
+		if len(feed.Changes) == limit {
+			feed.HasMore = true
+			break
+		}
+
+		var change models.ChangeRecord
+		err := rows.Scan(&cursor.txID, &cursor.id, &change.Seq, &change.EntityType, &change.EntityID,
+			&change.Operation, &change.Version, &change.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+		feed.Changes = append(feed.Changes, change)
+		feed.NextCursor = encodeChangeCursor(cursor)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating changes: %w", err)
+	}
+
+	return feed, nil
+}
+
+// ListEvents returns the user's changes after the lastSeq sequence number,
+// in order, keeping those of entityType when set. Sequence numbers are taken
+// by writers in commit order, so no change can appear behind a sequence
+// number a client already holds.
+func (s *ChangeService) ListEvents(ctx context.Context, userID, entityType string, lastSeq int64, limit int) (*models.ChangeEvents, error) {
+	if lastSeq < 0 {
+		return nil, ErrInvalidCursor
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	if lastSeq > 0 {
+		// Records are purged in log order; when the oldest record left is past
+		// the next one the client needs, changes were purged. Without records,
+		// the next one is the next sequence number of the user.
+		var latest, oldest sql.NullInt64
+		err := s.db.QueryRowContext(ctx, `
+			SELECT
+				(SELECT last_seq FROM change_sequences WHERE user_id = $1),
+				(SELECT MIN(seq) FROM change_log WHERE user_id = $1)
+		`, userID).Scan(&latest, &oldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check change log: %w", err)
+		}
+		if !latest.Valid || lastSeq > latest.Int64 {
+			return nil, ErrInvalidCursor
+		}
+		if !oldest.Valid {
+			oldest.Int64 = latest.Int64 + 1
+		}
+		if lastSeq+1 < oldest.Int64 {
+			return nil, ErrCursorExpired
+		}
+	}
+
+	conditions := database.NewConditions(database.Postgres)
+	conditions.Compare("user_id", "=", userID)
+	conditions.Compare("seq", ">", lastSeq)
+	if entityType != "" {
+		conditions.Compare("entity_type", "=", entityType)
+	}
+	query := fmt.Sprintf(`
+		SELECT seq, entity_type, entity_id, operation, version, changed_at
+		FROM change_log
+		%s
+		ORDER BY seq
+		LIMIT $%d
+	`, conditions.Where(), conditions.Next())
+
+	rows, err := s.db.QueryContext(ctx, query, append(conditions.Args(), limit+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list change events: %w", err)
+	}
+	defer rows.Close()
+
+	events := &models.ChangeEvents{
+		Events:  []models.ChangeRecord{},
+		LastSeq: lastSeq,
+	}
+	for rows.Next() {
+		if len(events.Events) == limit {
+			events.HasMore = true
+			break
+		}
+
+		var change models.ChangeRecord
+		err := rows.Scan(&change.Seq, &change.EntityType, &change.EntityID,
+			&change.Operation, &change.Version, &change.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan change event: %w", err)
+		}
+		events.Events = append(events.Events, change)
+		events.LastSeq = change.Seq
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating change events: %w", err)
+	}
+
+	return events, nil
+}
+
+// CleanupOldChanges removes change records past the retention period
+func (s *ChangeService) CleanupOldChanges(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM change_log
+		WHERE changed_at < $1
+	`, time.Now().Add(-changeRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup change log: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestChangeCursorRoundTrip(t *testing.T) {
+	cursor := changeCursor{txID: 1234567, id: 89}
+	decoded, err := decodeChangeCursor(encodeChangeCursor(cursor))
+	if err != nil {
+		t.Fatalf("Failed to decode cursor: %v", err)
+	}
+	if decoded != cursor {
+		t.Errorf("Expected %+v, got %+v", cursor, decoded)
+	}
+
+	start, err := decodeChangeCursor("")
+	if err != nil || start != (changeCursor{}) {
+		t.Errorf("Expected the empty cursor to start the log, got %+v, %v", start, err)
+	}
+}
+
+func TestDecodeChangeCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	for _, cursor := range []string{"not base64!", encode("12"), encode("a:1"), encode("1:b"), encode("-1:5")} {
+		if _, err := decodeChangeCursor(cursor); err == nil {
+			t.Errorf("Expected cursor %q to be rejected", cursor)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// CommentServiceInterface defines the interface for note comment operations
+type CommentServiceInterface interface {
+	ListComments(ctx context.Context, userID, noteID string, limit, offset int) (*models.CommentList, error)
+	CreateComment(ctx context.Context, userID, noteID string, request *models.CommentRequest) (*models.Comment, error)
+	UpdateComment(ctx context.Context, userID, noteID, commentID string, request *models.CommentRequest) (*models.Comment, error)
+	DeleteComment(ctx context.Context, userID, noteID, commentID string) error
+	ListAuthoredComments(ctx context.Context, userID string) ([]models.Comment, error)
+	NoteIDBySlug(ctx context.Context, slug string) (string, error)
+}
+
+// Comment errors
+var (
+	ErrCommentNotFound    = apperrors.NotFound("COMMENT_NOT_FOUND", "comment not found")
+	errCommentsDisallowed = apperrors.Validation("COMMENTS_UNAVAILABLE", "comments are available on shared and published notes")
+	errCommentForbidden   = apperrors.New(apperrors.ErrForbidden, "COMMENT_FORBIDDEN", "you cannot change this comment")
+)
+
+// codeInvalidComment is the error code of invalid comments
+const codeInvalidComment = "INVALID_COMMENT"
+
+// CommentService manages comments on notes. Members of the organization
+// sharing a note's notebook comment on it, and any user comments on
+// published notes. Users mentioned in a comment are notified in the app and
+// by email.
+type CommentService struct {
+	db                  *sql.DB
+	notificationService NotificationServiceInterface
+	sender              email.Sender
+}
+
+// NewCommentService creates a new CommentService
+func NewCommentService(db *sql.DB, notificationService NotificationServiceInterface, sender email.Sender) *CommentService {
+	return &CommentService{
+		db:                  db,
+		notificationService: notificationService,
+		sender:              sender,
+	}
+}
+
+// commentColumns lists the note_comments columns, with the author's email,
+// in scanComment order. Queries join users as u.
+const commentColumns = "c.id, c.note_id, c.user_id, u.email, c.content, c.created_at, c.updated_at"
+
+// scanComment scans a row selected with commentColumns
+func scanComment(row rowScanner, c *models.Comment) error {
+	return row.Scan(&c.ID, &c.NoteID, &c.UserID, &c.AuthorEmail, &c.Content, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// commentedNote is a note comments are written on
+type commentedNote struct {
+	note   models.Note
+	shared bool
+}
+
+// commentableNote returns a note the user may comment on: a note of a
+// shared notebook they can read, or a published note
+func (s *CommentService) commentableNote(ctx context.Context, userID, noteID string) (*commentedNote, error) {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return nil, ErrNoteNotFound
+	}
+
+	var target commentedNote
+	var published, readable bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT n.id, n.user_id, n.notebook_id, n.title, n.is_private,
+			nb.organization_id IS NOT NULL,
+			EXISTS(SELECT 1 FROM published_notes p WHERE p.note_id = n.id),
+			`+noteAccess("n.", 2)+`
+		FROM notes n
+		JOIN notebooks nb ON nb.id = n.notebook_id
+		WHERE n.id = $1
+	`, noteID, userID).Scan(&target.note.ID, &target.note.UserID, &target.note.NotebookID, &target.note.Title,
+		&target.note.IsPrivate, &target.shared, &published, &readable)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoteNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+
+	switch {
+	case published && !target.note.IsPrivate:
+		return &target, nil
+	case readable && target.shared:
+		return &target, nil
+	case readable:
+		return nil, errCommentsDisallowed
+	default:
+		return nil, ErrNoteNotFound
+	}
+}
+
+// ListComments returns the comments on a note, oldest first
+func (s *CommentService) ListComments(ctx context.Context, userID, noteID string, limit, offset int) (*models.CommentList, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	target, err := s.commentableNote(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.CommentList{
+		Comments: []models.Comment{},
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM note_comments WHERE note_id = $1
+	`, target.note.ID).Scan(&list.Total); err != nil {
+		return nil, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM note_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.note_id = $1
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3
+	`, target.note.ID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment models.Comment
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		list.Comments = append(list.Comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+
+	list.HasMore = offset+limit < list.Total
+	return list, nil
+}
+
+// ListAuthoredComments returns every comment the user wrote, on any note,
+// oldest first
+func (s *CommentService) ListAuthoredComments(ctx context.Context, userID string) ([]models.Comment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM note_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at, c.id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var comment models.Comment
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+
+	return comments, nil
+}
+
+// CreateComment comments on a note and notifies the users it mentions
+func (s *CommentService) CreateComment(ctx context.Context, userID, noteID string, request *models.CommentRequest) (*models.Comment, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidComment, err)
+	}
+
+	target, err := s.commentableNote(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	commentID := uuid.New()
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO note_comments (id, note_id, user_id, content)
+		VALUES ($1, $2, $3, $4)
+	`, commentID, target.note.ID, userID, request.Content); err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	comment, err := s.getComment(ctx, target.note.ID, commentID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyMentions(ctx, target, comment, models.ExtractMentions(comment.Content))
+	return comment, nil
+}
+
+// UpdateComment edits a comment of the user. Users mentioned for the first
+// time are notified.
+func (s *CommentService) UpdateComment(ctx context.Context, userID, noteID, commentID string, request *models.CommentRequest) (*models.Comment, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidComment, err)
+	}
+
+	target, err := s.commentableNote(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.getComment(ctx, target.note.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if current.UserID.String() != userID {
+		return nil, errCommentForbidden
+	}
+
+	comment := *current
+	if err := s.db.QueryRowContext(ctx, `
+		UPDATE note_comments SET content = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING content, updated_at
+	`, request.Content, current.ID).Scan(&comment.Content, &comment.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	previous := make(map[string]bool)
+	for _, mention := range models.ExtractMentions(current.Content) {
+		previous[mention] = true
+	}
+	var added []string
+	for _, mention := range models.ExtractMentions(comment.Content) {
+		if !previous[mention] {
+			added = append(added, mention)
+		}
+	}
+	s.notifyMentions(ctx, target, &comment, added)
+
+	return &comment, nil
+}
+
+// DeleteComment deletes a comment. Authors delete their comments, and the
+// users who can edit the note delete any comment on it.
+func (s *CommentService) DeleteComment(ctx context.Context, userID, noteID, commentID string) error {
+	target, err := s.commentableNote(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	comment, err := s.getComment(ctx, target.note.ID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID.String() != userID {
+		if err := checkNoteWrite(ctx, s.db, userID, &target.note); err != nil {
+			return errCommentForbidden
+		}
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM note_comments WHERE id = $1
+	`, comment.ID); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return nil
+}
+
+// NoteIDBySlug returns the ID of the note published at a slug
+func (s *CommentService) NoteIDBySlug(ctx context.Context, slug string) (string, error) {
+	var noteID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT note_id FROM published_notes WHERE slug = $1
+	`, slug).Scan(&noteID)
+	if err == sql.ErrNoRows {
+		return "", ErrPublicationNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get publication: %w", err)
+	}
+	return noteID, nil
+}
+
+// getComment returns a comment on a note
+func (s *CommentService) getComment(ctx context.Context, noteID uuid.UUID, commentID string) (*models.Comment, error) {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return nil, ErrCommentNotFound
+	}
+
+	var comment models.Comment
+	err := scanComment(s.db.QueryRowContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM note_comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1 AND c.note_id = $2
+	`, commentID, noteID), &comment)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// notifyMentions notifies the users a comment mentions. Only the note's
+// participants can be mentioned: its author, the members of the
+// organization sharing it and its commenters. A mention is a participant's
+// email address or, when no other participant shares it, the part of it
+// before the @. Failures are logged, as the comment is already saved.
+func (s *CommentService) notifyMentions(ctx context.Context, target *commentedNote, comment *models.Comment, mentions []string) {
+	if len(mentions) == 0 {
+		return
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, email FROM users
+		WHERE id = $1
+			OR id IN (
+				SELECT m.user_id FROM organization_members m
+				JOIN notebooks nb ON nb.organization_id = m.organization_id
+				WHERE nb.id = $2
+			)
+			OR id IN (SELECT user_id FROM note_comments WHERE note_id = $3)
+	`, target.note.UserID, target.note.NotebookID, target.note.ID)
+	if err != nil {
+		log.Printf("[CommentService] WARNING: failed to load participants of note %s: %v", target.note.ID, err)
+		return
+	}
+	defer rows.Close()
+
+	byEmail := make(map[string]uuid.UUID)
+	byName := make(map[string][]uuid.UUID)
+	for rows.Next() {
+		var id uuid.UUID
+		var address string
+		if err := rows.Scan(&id, &address); err != nil {
+			log.Printf("[CommentService] WARNING: failed to scan participant: %v", err)
+			return
+		}
+		address = strings.ToLower(address)
+		byEmail[address] = id
+		name, _, _ := strings.Cut(address, "@")
+		byName[name] = append(byName[name], id)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[CommentService] WARNING: failed to load participants of note %s: %v", target.note.ID, err)
+		return
+	}
+	emails := make(map[uuid.UUID]string, len(byEmail))
+	for address, id := range byEmail {
+		emails[id] = address
+	}
+
+	noteTitle := "Untitled note"
+	if target.note.Title != nil && *target.note.Title != "" {
+		noteTitle = *target.note.Title
+	}
+	subject := fmt.Sprintf("%s mentioned you in a comment", comment.AuthorEmail)
+	data := map[string]string{
+		"note_id":    comment.NoteID.String(),
+		"comment_id": comment.ID.String(),
+	}
+
+	notified := map[uuid.UUID]bool{comment.UserID: true}
+	for _, mention := range mentions {
+		id, ok := byEmail[mention]
+		if !ok {
+			if ids := byName[mention]; len(ids) == 1 {
+				id, ok = ids[0], true
+			}
+		}
+		if !ok || notified[id] {
+			continue
+		}
+		notified[id] = true
+
+		if _, err := s.notificationService.Notify(ctx, id, models.NotificationTypeMention, subject, noteTitle, data); err != nil {
+			log.Printf("[CommentService] WARNING: failed to notify mention of user %s: %v", id, err)
+		}
+		if err := s.sender.Send(ctx, email.Message{
+			To:      emails[id],
+			Subject: subject,
+			Body:    fmt.Sprintf("%s\n\nOn %s:\n\n%s\n", subject, noteTitle, comment.Content),
+		}); err != nil {
+			log.Printf("[CommentService] WARNING: failed to email mention to user %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestComments(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	notifier := &recordingNotifier{}
+	sender := &recordingSender{}
+	service := NewCommentService(db, notifier, sender)
+	organizationService := NewOrganizationService(db, email.LogSender{})
+	noteService := NewNoteService(db, NewTagService(db))
+	publicationService := NewPublicationService(db, noteService)
+	ctx := context.Background()
+
+	owner := testutil.NewTestUser(t, db)
+	member := testutil.NewTestUser(t, db)
+	outsider := testutil.NewTestUser(t, db)
+	ownerID, memberID, outsiderID := owner.ID.String(), member.ID.String(), outsider.ID.String()
+	ownerName, _, _ := strings.Cut(owner.Email, "@")
+	memberName, _, _ := strings.Cut(member.Email, "@")
+
+	org, err := organizationService.CreateOrganization(ctx, ownerID, &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	invitation, err := organizationService.InviteMember(ctx, ownerID, org.ID.String(), &models.InviteMemberRequest{Email: member.Email, Role: models.OrgRoleViewer})
+	if err != nil {
+		t.Fatalf("Failed to invite member: %v", err)
+	}
+	if _, err := organizationService.AcceptInvitation(ctx, member, invitation.Token); err != nil {
+		t.Fatalf("Failed to accept invitation: %v", err)
+	}
+	shared, err := organizationService.CreateNotebook(ctx, ownerID, org.ID.String(), &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+	sharedNote, err := noteService.CreateNote(ctx, ownerID, &models.CreateNoteRequest{Title: "Q3 goals", Content: "Ship it", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	personal, err := noteService.CreateNote(ctx, ownerID, &models.CreateNoteRequest{Content: "Diary"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	// Members, even viewers, comment on shared notes; mentions notify
+	comment, err := service.CreateComment(ctx, memberID, sharedNote.ID.String(), &models.CommentRequest{Content: " Looks good @" + strings.ToUpper(ownerName) + " "})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if comment.AuthorEmail != member.Email || comment.Content != "Looks good @"+strings.ToUpper(ownerName) {
+		t.Errorf("Unexpected comment %+v", comment)
+	}
+	if len(notifier.notifications) != 1 || notifier.notifications[0].UserID != owner.ID || notifier.notifications[0].Type != models.NotificationTypeMention {
+		t.Errorf("Expected the owner notified of the mention, got %+v", notifier.notifications)
+	}
+	if len(sender.messages) != 1 || sender.messages[0].To != owner.Email {
+		t.Errorf("Expected the mention emailed to the owner, got %+v", sender.messages)
+	}
+
+	if _, err := service.CreateComment(ctx, outsiderID, sharedNote.ID.String(), &models.CommentRequest{Content: "Hi"}); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected outsiders not to see shared notes, got %v", err)
+	}
+	if _, err := service.CreateComment(ctx, ownerID, personal.ID.String(), &models.CommentRequest{Content: "Hi"}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected no comments on personal notes, got %v", err)
+	}
+	if _, err := service.CreateComment(ctx, memberID, sharedNote.ID.String(), &models.CommentRequest{Content: "  "}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected empty comments rejected, got %v", err)
+	}
+
+	// Only the mentions added by an edit notify
+	if _, err := service.UpdateComment(ctx, ownerID, sharedNote.ID.String(), comment.ID.String(), &models.CommentRequest{Content: "Mine"}); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("Expected only the author to edit, got %v", err)
+	}
+	updated, err := service.UpdateComment(ctx, memberID, sharedNote.ID.String(), comment.ID.String(), &models.CommentRequest{Content: "Looks good @" + ownerName + " and @" + memberName})
+	if err != nil {
+		t.Fatalf("Failed to update comment: %v", err)
+	}
+	if !strings.HasSuffix(updated.Content, memberName) || len(notifier.notifications) != 1 {
+		t.Errorf("Expected no new notification, got %+v, %+v", updated, notifier.notifications)
+	}
+
+	// Anyone comments on published notes, mentioning its participants only
+	publication, err := publicationService.PublishNote(ctx, ownerID, personal.ID.String(), &models.PublishNoteRequest{})
+	if err != nil {
+		t.Fatalf("Failed to publish note: %v", err)
+	}
+	noteID, err := service.NoteIDBySlug(ctx, publication.Slug)
+	if err != nil || noteID != personal.ID.String() {
+		t.Fatalf("Expected the published note, got %s, %v", noteID, err)
+	}
+	outsiderComment, err := service.CreateComment(ctx, outsiderID, noteID, &models.CommentRequest{Content: "Nice @" + memberName + " @" + owner.Email})
+	if err != nil {
+		t.Fatalf("Failed to comment on published note: %v", err)
+	}
+	if len(notifier.notifications) != 2 || notifier.notifications[1].UserID != owner.ID {
+		t.Errorf("Expected only the owner notified, got %+v", notifier.notifications)
+	}
+
+	list, err := service.ListComments(ctx, ownerID, noteID, 1, 0)
+	if err != nil || list.Total != 1 || len(list.Comments) != 1 || list.HasMore {
+		t.Errorf("Unexpected comments %+v, %v", list, err)
+	}
+
+	// Authors and the note's editors delete comments
+	if err := service.DeleteComment(ctx, memberID, sharedNote.ID.String(), outsiderComment.ID.String()); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("Expected comments of other notes not found, got %v", err)
+	}
+	if err := service.DeleteComment(ctx, ownerID, noteID, outsiderComment.ID.String()); err != nil {
+		t.Errorf("Expected the note's owner to delete comments: %v", err)
+	}
+	if err := service.DeleteComment(ctx, memberID, sharedNote.ID.String(), comment.ID.String()); err != nil {
+		t.Errorf("Failed to delete comment: %v", err)
+	}
+}
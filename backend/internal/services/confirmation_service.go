@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxConfirmationAttempts is the number of wrong codes before a confirmation is spent
+const maxConfirmationAttempts = 5
+
+// Errors of confirmation codes that cannot confirm an operation
+var (
+	ErrInvalidConfirmationCode = apperrors.New(apperrors.ErrForbidden, "INVALID_CONFIRMATION_CODE", "invalid confirmation code")
+	ErrConfirmationExpired     = apperrors.New(apperrors.ErrForbidden, "CONFIRMATION_EXPIRED", "confirmation code expired")
+)
+
+// ConfirmationRequiredError is returned when an operation needs a confirmation
+// code. A new code has been sent; the client repeats the request with the
+// confirmation ID and the code.
+type ConfirmationRequiredError struct {
+	Confirmation *models.Confirmation
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("confirmation required for %s", e.Confirmation.Operation)
+}
+
+// ConfirmationSender delivers confirmation codes to users out of band
+type ConfirmationSender interface {
+	SendConfirmationCode(ctx context.Context, userID uuid.UUID, operation, code string, expiresAt time.Time) error
+}
+
+// LogConfirmationSender writes confirmation codes to the server log. It is
+// meant for development, where no email delivery is configured.
+type LogConfirmationSender struct{}
+
+// SendConfirmationCode logs the code
+func (LogConfirmationSender) SendConfirmationCode(ctx context.Context, userID uuid.UUID, operation, code string, expiresAt time.Time) error {
+	log.Printf("[Confirmation] code %s confirms %s for user %s (expires %s)", code, operation, userID, expiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// ConfirmationServiceInterface defines the interface for confirming destructive operations
+type ConfirmationServiceInterface interface {
+	Require(ctx context.Context, userID uuid.UUID, operation, scope string, affected int, confirmationID, code string) error
+}
+
+// ConfirmationService enforces a second factor for destructive operations.
+// Account deletion always needs a code; bulk operations need one when they
+// affect more notes than the configured threshold.
+type ConfirmationService struct {
+	db            *sql.DB
+	sender        ConfirmationSender
+	bulkThreshold int
+	codeTTL       time.Duration
+}
+
+// NewConfirmationService creates a new ConfirmationService
+func NewConfirmationService(db *sql.DB, sender ConfirmationSender, bulkThreshold int, codeTTL time.Duration) *ConfirmationService {
+	return &ConfirmationService{
+		db:            db,
+		sender:        sender,
+		bulkThreshold: bulkThreshold,
+		codeTTL:       codeTTL,
+	}
+}
+
+// Require checks that an operation is confirmed. scope identifies exactly what
+// is being confirmed (e.g. the set of note IDs), so a code cannot be reused for
+// a different request. Without a confirmation ID a new code is sent and a
+// *ConfirmationRequiredError returned.
+func (s *ConfirmationService) Require(ctx context.Context, userID uuid.UUID, operation, scope string, affected int, confirmationID, code string) error {
+	if operation != models.OperationDeleteAccount && affected <= s.bulkThreshold {
+		return nil
+	}
+
+	if confirmationID == "" {
+		confirmation, err := s.issue(ctx, userID, operation, scope, affected)
+		if err != nil {
+			return err
+		}
+		return &ConfirmationRequiredError{Confirmation: confirmation}
+	}
+
+	return s.verify(ctx, userID, operation, scope, confirmationID, code)
+}
+
+// issue creates a confirmation and sends its code
+func (s *ConfirmationService) issue(ctx context.Context, userID uuid.UUID, operation, scope string, affected int) (*models.Confirmation, error) {
+	code, err := generateConfirmationCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate confirmation code: %w", err)
+	}
+
+	confirmation := &models.Confirmation{
+		UserID:    userID,
+		Operation: operation,
+		Affected:  affected,
+		ExpiresAt: time.Now().Add(s.codeTTL),
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO action_confirmations (user_id, operation, scope, affected, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, userID, operation, scope, affected, hashConfirmationCode(code), confirmation.ExpiresAt).
+		Scan(&confirmation.ID, &confirmation.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create confirmation: %w", err)
+	}
+
+	if err := s.sender.SendConfirmationCode(ctx, userID, operation, code, confirmation.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to send confirmation code: %w", err)
+	}
+
+	return confirmation, nil
+}
+
+// verify checks a code against a pending confirmation and spends it on success
+func (s *ConfirmationService) verify(ctx context.Context, userID uuid.UUID, operation, scope, confirmationID, code string) error {
+	if _, err := uuid.Parse(confirmationID); err != nil {
+		return ErrInvalidConfirmationCode
+	}
+
+	var codeHash string
+	var attempts int
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT code_hash, attempts, expires_at
+		FROM action_confirmations
+		WHERE id = $1 AND user_id = $2 AND operation = $3 AND scope = $4 AND used_at IS NULL
+	`, confirmationID, userID, operation, scope).Scan(&codeHash, &attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return ErrInvalidConfirmationCode
+	} else if err != nil {
+		return fmt.Errorf("failed to get confirmation: %w", err)
+	}
+
+	if time.Now().After(expiresAt) || attempts >= maxConfirmationAttempts {
+		return ErrConfirmationExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashConfirmationCode(code))) != 1 {
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE action_confirmations SET attempts = attempts + 1 WHERE id = $1
+		`, confirmationID); err != nil {
+			log.Printf("[ConfirmationService] WARNING: failed to record attempt for confirmation %s: %v", confirmationID, err)
+		}
+		return ErrInvalidConfirmationCode
+	}
+
+	// Spend the confirmation; a concurrent request using the same code loses
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE action_confirmations SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
+	`, confirmationID)
+	if err != nil {
+		return fmt.Errorf("failed to use confirmation: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrInvalidConfirmationCode
+	}
+
+	return nil
+}
+
+// CleanupExpiredConfirmations removes confirmations past their expiry
+func (s *ConfirmationService) CleanupExpiredConfirmations(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM action_confirmations
+		WHERE expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired confirmations: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// generateConfirmationCode returns a random 6-digit code
+func generateConfirmationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashConfirmationCode returns the hex SHA-256 of a code
+func hashConfirmationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestConfirmationNotRequiredBelowThreshold(t *testing.T) {
+	// No database is needed when the operation is under the threshold
+	service := NewConfirmationService(nil, LogConfirmationSender{}, 20, 10*time.Minute)
+
+	for _, affected := range []int{0, 1, 20} {
+		if err := service.Require(context.Background(), uuid.New(), models.OperationBulkDelete, "notes:x", affected, "", ""); err != nil {
+			t.Errorf("Require with %d affected notes returned %v, want nil", affected, err)
+		}
+	}
+}
+
+func TestConfirmationInvalidID(t *testing.T) {
+	service := NewConfirmationService(nil, LogConfirmationSender{}, 20, 10*time.Minute)
+
+	err := service.Require(context.Background(), uuid.New(), models.OperationDeleteAccount, "account", 1, "not-a-uuid", "123456")
+	if err == nil || err.Error() != "invalid confirmation code" {
+		t.Errorf("Expected invalid confirmation code error, got %v", err)
+	}
+}
+
+func TestGenerateConfirmationCode(t *testing.T) {
+	codePattern := regexp.MustCompile(`^\d{6}$`)
+	for i := 0; i < 50; i++ {
+		code, err := generateConfirmationCode()
+		if err != nil {
+			t.Fatalf("generateConfirmationCode returned error: %v", err)
+		}
+		if !codePattern.MatchString(code) {
+			t.Fatalf("Expected 6-digit code, got %q", code)
+		}
+	}
+
+	if hashConfirmationCode("123456") == hashConfirmationCode("123457") {
+		t.Error("Expected different codes to hash differently")
+	}
+}
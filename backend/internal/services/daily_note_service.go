@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+)
+
+// DailyNoteServiceInterface defines the interface for daily notes
+type DailyNoteServiceInterface interface {
+	GetDailyNote(ctx context.Context, userID string, day time.Time) (*models.Note, bool, error)
+	AppendToDailyNote(ctx context.Context, userID string, day time.Time, text string) (*models.Note, error)
+}
+
+// errDayTaken is returned when another note was recorded for a day first
+var errDayTaken = errors.New("day already has a daily note")
+
+// DailyNoteService keeps a journal note per user and day. Daily notes are
+// created on first use, from the user's journal template when they have one,
+// and are ordinary notes otherwise: deleting one frees its day.
+type DailyNoteService struct {
+	db              *sql.DB
+	noteService     NoteServiceInterface
+	templateService TemplateServiceInterface
+}
+
+// NewDailyNoteService creates a new DailyNoteService
+func NewDailyNoteService(db *sql.DB, noteService NoteServiceInterface) *DailyNoteService {
+	return &DailyNoteService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// SetTemplateService sets the service rendering journal templates. Without
+// one, daily notes start with a heading of their date.
+func (s *DailyNoteService) SetTemplateService(templateService TemplateServiceInterface) {
+	s.templateService = templateService
+}
+
+// GetDailyNote returns the note of a day, creating it when the day has none
+// yet. day is midnight of the date in the user's time zone. The returned
+// flag reports whether the note was created.
+func (s *DailyNoteService) GetDailyNote(ctx context.Context, userID string, day time.Time) (*models.Note, bool, error) {
+	date := day.Format(search.DateLayout)
+
+	note, err := s.findDailyNote(ctx, userID, date)
+	if err != nil || note != nil {
+		return note, false, err
+	}
+
+	note, err = s.createDailyNote(ctx, userID, day)
+	if errors.Is(err, errDayTaken) {
+		// Another request created the note of the day first
+		note, err = s.findDailyNote(ctx, userID, date)
+		if err == nil && note == nil {
+			err = fmt.Errorf("daily note of %s was deleted while being created", date)
+		}
+		return note, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return note, true, nil
+}
+
+// AppendToDailyNote appends text on a new line of the note of a day,
+// creating the note when needed. The append is a versioned update, retried
+// once when the note changes meanwhile.
+func (s *DailyNoteService) AppendToDailyNote(ctx context.Context, userID string, day time.Time, text string) (*models.Note, error) {
+	for attempt := 0; ; attempt++ {
+		note, _, err := s.GetDailyNote(ctx, userID, day)
+		if err != nil {
+			return nil, err
+		}
+
+		content := strings.TrimRight(note.Content, "\n") + "\n" + text
+		version := note.Version
+		updated, err := s.noteService.UpdateNote(ctx, userID, note.ID.String(), &models.UpdateNoteRequest{
+			Content: &content,
+			Version: &version,
+		})
+		if errors.Is(err, ErrVersionMismatch) && attempt == 0 {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// findDailyNote returns the note of a date, or nil when it has none
+func (s *DailyNoteService) findDailyNote(ctx context.Context, userID, date string) (*models.Note, error) {
+	var noteID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT note_id FROM daily_notes WHERE user_id = $1 AND day = $2
+	`, userID, date).Scan(&noteID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get daily note: %w", err)
+	}
+
+	return s.noteService.GetNoteByID(ctx, userID, noteID)
+}
+
+// createDailyNote creates the note of a day and records it in the same
+// transaction. It fails with errDayTaken when another note was recorded for
+// the day first.
+func (s *DailyNoteService) createDailyNote(ctx context.Context, userID string, day time.Time) (*models.Note, error) {
+	date := day.Format(search.DateLayout)
+	record := func(ctx context.Context, tx *sql.Tx, note *models.Note) error {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO daily_notes (user_id, day, note_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, day) DO NOTHING
+		`, userID, date, note.ID)
+		if err != nil {
+			return fmt.Errorf("failed to record daily note: %w", err)
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to check rows affected: %w", err)
+		} else if rowsAffected == 0 {
+			return errDayTaken
+		}
+		return nil
+	}
+
+	if s.templateService != nil {
+		var templateID *uuid.UUID
+		err := s.db.QueryRowContext(ctx, `
+			SELECT journal_template_id FROM users WHERE id = $1
+		`, userID).Scan(&templateID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get journal template: %w", err)
+		}
+		if templateID != nil {
+			note, err := s.templateService.CreateNoteFromTemplateInTx(ctx, userID, templateID.String(),
+				&models.CreateNoteFromTemplateRequest{
+					ApplyTemplateRequest: models.ApplyTemplateRequest{TimeZone: day.Location().String(), At: day},
+				}, record)
+			if !errors.Is(err, ErrTemplateNotFound) {
+				return note, err
+			}
+			// The template was deleted meanwhile; start a plain daily note
+		}
+	}
+
+	return s.noteService.CreateNoteInTx(ctx, userID, &models.CreateNoteRequest{
+		Title:   date,
+		Content: "# " + day.Format("Monday, January 2, 2006") + "\n",
+	}, record)
+}
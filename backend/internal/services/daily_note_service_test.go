@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestDailyNotes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewDailyNoteService(db, NewNoteService(db, NewTagService(db)))
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	note, created, err := service.GetDailyNote(ctx, user.ID.String(), day)
+	if err != nil {
+		t.Fatalf("Failed to get daily note: %v", err)
+	}
+	if !created {
+		t.Error("Expected the daily note to be created")
+	}
+	if note.Title == nil || *note.Title != "2024-03-04" || note.Content != "# Monday, March 4, 2024\n" {
+		t.Errorf("Unexpected daily note %q", note.Content)
+	}
+
+	again, created, err := service.GetDailyNote(ctx, user.ID.String(), day)
+	if err != nil {
+		t.Fatalf("Failed to get daily note again: %v", err)
+	}
+	if created || again.ID != note.ID {
+		t.Errorf("Expected the same daily note, got %s (created %v)", again.ID, created)
+	}
+
+	if _, err := service.AppendToDailyNote(ctx, user.ID.String(), day, "- Call the dentist"); err != nil {
+		t.Fatalf("Failed to append: %v", err)
+	}
+	updated, err := service.AppendToDailyNote(ctx, user.ID.String(), day, "- Buy milk #capture")
+	if err != nil {
+		t.Fatalf("Failed to append: %v", err)
+	}
+	if updated.ID != note.ID || !strings.HasSuffix(updated.Content, "2024\n- Call the dentist\n- Buy milk #capture") {
+		t.Errorf("Unexpected appended content %q", updated.Content)
+	}
+
+	// Another day gets its own note
+	next, created, err := service.GetDailyNote(ctx, user.ID.String(), day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get next daily note: %v", err)
+	}
+	if !created || next.ID == note.ID {
+		t.Error("Expected a new note for the next day")
+	}
+}
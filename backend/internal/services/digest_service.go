@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxDigestNotes is the number of notes listed in each section of a digest
+const maxDigestNotes = 20
+
+// digestTodoTag is the tag of the notes listed as open to-dos
+const digestTodoTag = "#todo"
+
+// DigestServiceInterface defines the interface for digest emails
+type DigestServiceInterface interface {
+	BuildDigest(ctx context.Context, userID, frequency string, now time.Time) (*models.Digest, error)
+	SendDueDigests(ctx context.Context, now time.Time) (int, error)
+}
+
+// DigestService compiles daily and weekly digests of new notes and open
+// to-dos and emails them to the users who opted in
+type DigestService struct {
+	db       *sql.DB
+	progress ProgressServiceInterface
+	sender   email.Sender
+	sendHour int
+	llm      TextGenerator
+	cache    LLMCache
+	prompts  *prompts.Registry
+}
+
+// NewDigestService creates a new DigestService. Digests are sent from
+// sendHour (UTC) on.
+func NewDigestService(db *sql.DB, progress ProgressServiceInterface, sender email.Sender, sendHour int) *DigestService {
+	return &DigestService{
+		db:       db,
+		progress: progress,
+		sender:   sender,
+		sendHour: sendHour,
+	}
+}
+
+// SetLLM enables LLM summaries of digests
+func (s *DigestService) SetLLM(llm TextGenerator) {
+	s.llm = llm
+}
+
+// SetCache enables caching of digest summaries, so rebuilding an unchanged
+// digest skips the LLM
+func (s *DigestService) SetCache(cache LLMCache) {
+	s.cache = cache
+}
+
+// SetPrompts sets the registry the summary prompt is rendered from. The
+// built-in prompt is used otherwise.
+func (s *DigestService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// BuildDigest compiles the digest of a user for the period of the given
+// frequency ending at now
+func (s *DigestService) BuildDigest(ctx context.Context, userID, frequency string, now time.Time) (*models.Digest, error) {
+	if frequency != models.DigestFrequencyDaily && frequency != models.DigestFrequencyWeekly {
+		return nil, fmt.Errorf("digest frequency must be daily or weekly")
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	digest := &models.Digest{
+		UserID:      userUUID,
+		Frequency:   frequency,
+		PeriodStart: now.Add(-models.DigestPeriod(frequency)),
+		PeriodEnd:   now,
+		NewNotes:    []models.DigestNote{},
+		OpenTodos:   []models.DigestNote{},
+	}
+
+	if err := s.addNewNotes(ctx, digest); err != nil {
+		return nil, err
+	}
+
+	progress, err := s.progress.GetTagProgress(ctx, userID, digestTodoTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open to-dos: %w", err)
+	}
+	for _, note := range progress.Notes {
+		if note.Unchecked == 0 {
+			continue
+		}
+		if len(digest.OpenTodos) == maxDigestNotes {
+			break
+		}
+		digest.OpenTodos = append(digest.OpenTodos, models.DigestNote{
+			ID:        note.NoteID,
+			Title:     note.Title,
+			Date:      note.UpdatedAt,
+			IsPrivate: note.IsPrivate,
+			Unchecked: note.Unchecked,
+		})
+	}
+
+	if s.llm != nil && !digest.IsEmpty() {
+		prompt, err := buildDigestPrompt(s.prompts, digest)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := s.summarize(ctx, userID, prompt)
+		if err != nil {
+			// The digest is still useful without a summary
+			log.Printf("[DigestService] WARNING: Failed to summarize digest of user %s: %v", userID, err)
+		} else {
+			digest.Summary = strings.TrimSpace(summary)
+		}
+	}
+
+	return digest, nil
+}
+
+// summarize returns the LLM summary for a digest prompt, from the cache when
+// the same prompt was summarized before
+func (s *DigestService) summarize(ctx context.Context, userID, prompt string) (string, error) {
+	hash := ContentHash(prompt)
+	if s.cache != nil {
+		summary, ok, err := s.cache.Get(ctx, userID, LLMFeatureDigest, hash)
+		if err != nil {
+			log.Printf("[DigestService] WARNING: Failed to get cached digest summary: %v", err)
+		} else if ok {
+			return summary, nil
+		}
+	}
+
+	summary, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeatureDigest), prompt)
+	if err != nil {
+		return "", err
+	}
+
+	if s.cache != nil {
+		entry := &LLMCacheEntry{UserID: userID, Feature: LLMFeatureDigest, SourceHash: hash, Response: summary}
+		if err := s.cache.Put(ctx, entry); err != nil {
+			log.Printf("[DigestService] WARNING: Failed to cache digest summary: %v", err)
+		}
+	}
+	return summary, nil
+}
+
+// addNewNotes adds the notes created during the digest period
+func (s *DigestService) addNewNotes(ctx context.Context, digest *models.Digest) error {
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notes
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+	`, digest.UserID, digest.PeriodStart, digest.PeriodEnd).Scan(&digest.NewNotesTotal)
+	if err != nil {
+		return fmt.Errorf("failed to count new notes: %w", err)
+	}
+	if digest.NewNotesTotal == 0 {
+		return nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, COALESCE(title, ''), created_at, is_private
+		FROM notes
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at DESC
+		LIMIT $4
+	`, digest.UserID, digest.PeriodStart, digest.PeriodEnd, maxDigestNotes)
+	if err != nil {
+		return fmt.Errorf("failed to get new notes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var note models.DigestNote
+		if err := rows.Scan(&note.ID, &note.Title, &note.Date, &note.IsPrivate); err != nil {
+			return fmt.Errorf("failed to scan new note: %w", err)
+		}
+		digest.NewNotes = append(digest.NewNotes, note)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating new notes: %w", err)
+	}
+	return nil
+}
+
+// digestRecipient is a user whose digest is due
+type digestRecipient struct {
+	id    uuid.UUID
+	email string
+}
+
+// SendDueDigests emails the digests due at now and returns how many were
+// sent. Digests with nothing to report are skipped but count as sent, so
+// the user is not checked again until the next one is due. Failures for one
+// user are logged and retried on the next run.
+func (s *DigestService) SendDueDigests(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	for _, frequency := range []string{models.DigestFrequencyDaily, models.DigestFrequencyWeekly} {
+		scheduled := models.DigestScheduledAt(frequency, now, s.sendHour)
+		recipients, err := s.dueRecipients(ctx, frequency, scheduled)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, recipient := range recipients {
+			delivered, err := s.sendDigest(ctx, recipient, frequency, now)
+			if err != nil {
+				log.Printf("[DigestService] WARNING: Failed to send %s digest to user %s: %v", frequency, recipient.id, err)
+				continue
+			}
+			if delivered {
+				sent++
+			}
+		}
+	}
+	return sent, nil
+}
+
+// dueRecipients returns the users of a frequency who have not received the
+// digest scheduled at scheduled
+func (s *DigestService) dueRecipients(ctx context.Context, frequency string, scheduled time.Time) ([]digestRecipient, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, email FROM users
+		WHERE digest_frequency = $1 AND disabled_at IS NULL
+		  AND (digest_sent_at IS NULL OR digest_sent_at < $2)
+	`, frequency, scheduled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []digestRecipient
+	for rows.Next() {
+		var recipient digestRecipient
+		if err := rows.Scan(&recipient.id, &recipient.email); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating digest recipients: %w", err)
+	}
+	return recipients, nil
+}
+
+// sendDigest builds and emails the digest of a recipient, then records it as
+// sent. It reports whether an email went out.
+func (s *DigestService) sendDigest(ctx context.Context, recipient digestRecipient, frequency string, now time.Time) (bool, error) {
+	digest, err := s.BuildDigest(ctx, recipient.id.String(), frequency, now)
+	if err != nil {
+		return false, err
+	}
+
+	if !digest.IsEmpty() {
+		subject, body := renderDigestEmail(digest)
+		if err := s.sender.Send(ctx, email.Message{To: recipient.email, Subject: subject, Body: body}); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, "UPDATE users SET digest_sent_at = $2 WHERE id = $1", recipient.id, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to record digest: %w", err)
+	}
+	return !digest.IsEmpty(), nil
+}
+
+// digestNoteTitle returns the title shown for a note in a digest. Titles of
+// private notes are not sent out.
+func digestNoteTitle(note models.DigestNote) string {
+	switch {
+	case note.IsPrivate:
+		return "Private note"
+	case note.Title == "":
+		return "Untitled note"
+	default:
+		return note.Title
+	}
+}
+
+// renderDigestEmail returns the subject and plain text body of a digest email
+func renderDigestEmail(digest *models.Digest) (string, string) {
+	subject := fmt.Sprintf("Your %s notes digest", digest.Frequency)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Here is your %s digest for %s.\n", digest.Frequency, digest.PeriodEnd.UTC().Format("Jan 2, 2006"))
+
+	if digest.Summary != "" {
+		fmt.Fprintf(&body, "\n%s\n", digest.Summary)
+	}
+
+	if digest.NewNotesTotal > 0 {
+		fmt.Fprintf(&body, "\nNew notes (%d)\n", digest.NewNotesTotal)
+		for _, note := range digest.NewNotes {
+			fmt.Fprintf(&body, "- %s (%s)\n", digestNoteTitle(note), note.Date.UTC().Format("Jan 2"))
+		}
+		if more := digest.NewNotesTotal - len(digest.NewNotes); more > 0 {
+			fmt.Fprintf(&body, "...and %d more\n", more)
+		}
+	}
+
+	if len(digest.OpenTodos) > 0 {
+		fmt.Fprintf(&body, "\nOpen to-dos (%d)\n", len(digest.OpenTodos))
+		for _, note := range digest.OpenTodos {
+			items := "items"
+			if note.Unchecked == 1 {
+				items = "item"
+			}
+			fmt.Fprintf(&body, "- %s (%d open %s)\n", digestNoteTitle(note), note.Unchecked, items)
+		}
+	}
+
+	body.WriteString("\nYou receive this email because digests are turned on in your settings. " +
+		"Set the digest frequency to off to stop them.\n")
+	return subject, body.String()
+}
+
+// buildDigestPrompt creates the LLM prompt summarizing a digest. Only titles
+// are sent, and none of private notes.
+func buildDigestPrompt(registry *prompts.Registry, digest *models.Digest) (string, error) {
+	data := prompts.DigestData{Frequency: digest.Frequency, NewNotesTotal: digest.NewNotesTotal}
+	for _, note := range digest.NewNotes {
+		if !note.IsPrivate {
+			data.NewNotes = append(data.NewNotes, digestNoteTitle(note))
+		}
+	}
+	for _, note := range digest.OpenTodos {
+		if !note.IsPrivate {
+			data.OpenTodos = append(data.OpenTodos, prompts.DigestTodo{Title: digestNoteTitle(note), OpenItems: note.Unchecked})
+		}
+	}
+
+	return promptsOrDefault(registry).Render(prompts.Digest, data)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDigest returns a weekly digest with public and private notes
+func testDigest() *models.Digest {
+	end := time.Date(2024, 3, 4, 7, 0, 0, 0, time.UTC)
+	return &models.Digest{
+		Frequency:     models.DigestFrequencyWeekly,
+		PeriodStart:   end.AddDate(0, 0, -7),
+		PeriodEnd:     end,
+		NewNotesTotal: 4,
+		NewNotes: []models.DigestNote{
+			{ID: uuid.New(), Title: "Roadmap", Date: end.AddDate(0, 0, -1)},
+			{ID: uuid.New(), Title: "Diary", Date: end.AddDate(0, 0, -2), IsPrivate: true},
+			{ID: uuid.New(), Date: end.AddDate(0, 0, -3)},
+		},
+		OpenTodos: []models.DigestNote{
+			{ID: uuid.New(), Title: "Groceries", Unchecked: 1},
+			{ID: uuid.New(), Title: "Taxes", Unchecked: 3, IsPrivate: true},
+		},
+	}
+}
+
+func TestRenderDigestEmail(t *testing.T) {
+	digest := testDigest()
+	digest.Summary = "A busy week."
+
+	subject, body := renderDigestEmail(digest)
+
+	assert.Equal(t, "Your weekly notes digest", subject)
+	for _, line := range []string{
+		"Here is your weekly digest for Mar 4, 2024.",
+		"A busy week.",
+		"New notes (4)",
+		"- Roadmap (Mar 3)",
+		"- Private note (Mar 2)",
+		"- Untitled note (Mar 1)",
+		"...and 1 more",
+		"Open to-dos (2)",
+		"- Groceries (1 open item)",
+		"- Private note (3 open items)",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "Diary")
+	assert.NotContains(t, body, "Taxes")
+}
+
+func TestBuildDigestPromptSkipsPrivateNotes(t *testing.T) {
+	prompt, err := buildDigestPrompt(nil, testDigest())
+	assert.NoError(t, err)
+
+	assert.Contains(t, prompt, "weekly notes digest")
+	assert.Contains(t, prompt, "NEW NOTES (4 in total):\n- Roadmap\n- Untitled note\n")
+	assert.Contains(t, prompt, "- Groceries (1 open items)")
+	assert.False(t, strings.Contains(prompt, "Private note") || strings.Contains(prompt, "Diary") || strings.Contains(prompt, "Taxes"))
+}
+
+func TestDigestIsEmpty(t *testing.T) {
+	assert.True(t, (&models.Digest{}).IsEmpty())
+	assert.False(t, testDigest().IsEmpty())
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/pdf"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+)
+
+// exportPageSize is the number of notes read per search page while exporting
+const exportPageSize = 100
+
+// exportCSVColumns is the header row of CSV exports
+var exportCSVColumns = []string{"id", "title", "content", "tags", "private", "color", "icon", "created_at", "updated_at", "watermark"}
+
+// ExportServiceInterface defines the interface for note exports
+type ExportServiceInterface interface {
+	ExportNotes(ctx context.Context, userID, format string, filter *models.ExportFilter, w io.Writer) (int, error)
+	ExportAccountData(ctx context.Context, userID string, w io.Writer) (int, error)
+}
+
+// ExportService writes the notes of a user matching a filter as an archive.
+// Notes are read page by page through note search, so the tag, date and
+// query filters behave exactly like searching.
+type ExportService struct {
+	noteService         NoteServiceInterface
+	userService         UserServiceInterface
+	savedSearchService  SavedSearchServiceInterface
+	subscriptionService SubscriptionServiceInterface
+	auditService        AuditServiceInterface
+	templateService     TemplateServiceInterface
+	notebookService     NotebookServiceInterface
+	recurrenceService   RecurrenceServiceInterface
+	commentService      CommentServiceInterface
+	webhookService      WebhookServiceInterface
+	apiKeyService       APIKeyServiceInterface
+	organizationService OrganizationServiceInterface
+}
+
+// NewExportService creates a new ExportService
+func NewExportService(noteService NoteServiceInterface) *ExportService {
+	return &ExportService{noteService: noteService}
+}
+
+// SetAccountSources sets the services read by account data exports. Only the
+// user service is required; the data of missing services is exported empty.
+func (s *ExportService) SetAccountSources(userService UserServiceInterface, savedSearchService SavedSearchServiceInterface,
+	subscriptionService SubscriptionServiceInterface, auditService AuditServiceInterface) {
+	s.userService = userService
+	s.savedSearchService = savedSearchService
+	s.subscriptionService = subscriptionService
+	s.auditService = auditService
+}
+
+// SetWorkspaceSources sets the services of the templates, notebooks,
+// recurrences, comments, webhooks and API keys written by account data
+// exports. The data of missing services is exported empty.
+func (s *ExportService) SetWorkspaceSources(templateService TemplateServiceInterface, notebookService NotebookServiceInterface,
+	recurrenceService RecurrenceServiceInterface, commentService CommentServiceInterface,
+	webhookService WebhookServiceInterface, apiKeyService APIKeyServiceInterface) {
+	s.templateService = templateService
+	s.notebookService = notebookService
+	s.recurrenceService = recurrenceService
+	s.commentService = commentService
+	s.webhookService = webhookService
+	s.apiKeyService = apiKeyService
+}
+
+// SetWatermarkPolicy sets the service telling which notebooks belong to
+// organizations that watermark exports. Notes of those notebooks are
+// exported with a watermark naming the user, read from the user service,
+// and the export time, whatever the format.
+func (s *ExportService) SetWatermarkPolicy(organizationService OrganizationServiceInterface) {
+	s.organizationService = organizationService
+}
+
+// ExportNotes writes the user's notes matching the filter to w, oldest first,
+// and returns the number of notes written. Nothing is written when the filter
+// is invalid or the first page cannot be read, so the caller can still report
+// the error; later errors leave the archive truncated.
+func (s *ExportService) ExportNotes(ctx context.Context, userID, format string, filter *models.ExportFilter, w io.Writer) (int, error) {
+	if format != models.ExportFormatJSON && format != models.ExportFormatCSV && format != models.ExportFormatPDF {
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	exportedAt := time.Now().UTC()
+	watermark, err := s.watermark(ctx, userID, exportedAt)
+	if err != nil {
+		return 0, err
+	}
+	page, err := s.noteService.SearchNotes(ctx, userID, exportSearchRequest(filter, 0))
+	if err != nil {
+		return 0, err
+	}
+	switch format {
+	case models.ExportFormatCSV:
+		return s.writeCSV(ctx, userID, filter, page, watermark, w)
+	case models.ExportFormatPDF:
+		return s.writePDF(ctx, userID, filter, page, watermark, w)
+	}
+
+	header, err := json.Marshal(struct {
+		ExportedAt time.Time            `json:"exported_at"`
+		Filter     *models.ExportFilter `json:"filter"`
+	}{exportedAt, filter})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode export: %w", err)
+	}
+	return s.writeNotes(ctx, userID, filter, page, watermark, header, w)
+}
+
+// ExportAccountData writes everything stored for the user to w: the account,
+// its settings, sessions, saved searches, subscriptions, audit log,
+// templates, personal notebooks, recurrences, comments, webhooks and API
+// key metadata, followed by every note. It returns the number of notes
+// written. As with ExportNotes, nothing is written when the account data
+// cannot be read.
+func (s *ExportService) ExportAccountData(ctx context.Context, userID string, w io.Writer) (int, error) {
+	if s.userService == nil {
+		return 0, fmt.Errorf("failed to export account: account export is not configured")
+	}
+
+	data := &models.AccountExport{
+		ExportedAt:    time.Now().UTC(),
+		SavedSearches: []models.SavedSearch{},
+		Subscriptions: []models.SearchSubscription{},
+		AuditLog:      []models.AuditEntry{},
+		Templates:     []models.Template{},
+		Notebooks:     []models.Notebook{},
+		Recurrences:   []models.Recurrence{},
+		Comments:      []models.Comment{},
+		Webhooks:      []models.Webhook{},
+		APIKeys:       []models.APIKey{},
+	}
+	var err error
+	if data.Account, err = s.userService.GetByID(ctx, userID); err != nil {
+		return 0, err
+	}
+	if data.Settings, err = s.userService.GetSettings(ctx, userID); err != nil {
+		return 0, err
+	}
+	if data.Sessions, err = s.userService.GetActiveSessions(ctx, userID); err != nil {
+		return 0, err
+	}
+	if s.savedSearchService != nil {
+		if data.SavedSearches, err = s.savedSearchService.ListSavedSearches(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if s.subscriptionService != nil {
+		if data.Subscriptions, err = s.subscriptionService.ListSubscriptions(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if s.auditService != nil {
+		filter := models.AuditLogFilter{UserID: userID}
+		for {
+			list, err := s.auditService.List(ctx, filter, exportPageSize, len(data.AuditLog))
+			if err != nil {
+				return 0, err
+			}
+			data.AuditLog = append(data.AuditLog, list.Entries...)
+			if !list.HasMore || len(list.Entries) == 0 {
+				break
+			}
+		}
+	}
+	if s.templateService != nil {
+		if data.Templates, err = s.templateService.ListTemplates(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if s.notebookService != nil {
+		notebooks, err := s.notebookService.ListNotebooks(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		// Notebooks shared by organizations belong to the organization
+		for _, notebook := range notebooks {
+			if notebook.OrganizationID == nil {
+				data.Notebooks = append(data.Notebooks, notebook)
+			}
+		}
+	}
+	if s.recurrenceService != nil {
+		if data.Recurrences, err = s.recurrenceService.ListRecurrences(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if s.commentService != nil {
+		if data.Comments, err = s.commentService.ListAuthoredComments(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if s.webhookService != nil {
+		if data.Webhooks, err = s.webhookService.ListWebhooks(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if s.apiKeyService != nil {
+		if data.APIKeys, err = s.apiKeyService.ListKeys(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	filter := &models.ExportFilter{}
+	page, err := s.noteService.SearchNotes(ctx, userID, exportSearchRequest(filter, 0))
+	if err != nil {
+		return 0, err
+	}
+
+	watermark, err := s.watermark(ctx, userID, data.ExportedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := json.Marshal(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode export: %w", err)
+	}
+	return s.writeNotes(ctx, userID, filter, page, watermark, header, w)
+}
+
+// writeNotes writes the header object with a notes array appended, holding
+// page and the following pages of notes matching filter
+func (s *ExportService) writeNotes(ctx context.Context, userID string, filter *models.ExportFilter, page *models.NoteList,
+	watermark *exportWatermark, header []byte, w io.Writer) (int, error) {
+	// The notes array is appended to the header object as notes are read
+	if _, err := fmt.Fprintf(w, "%s,\"notes\":[", header[:len(header)-1]); err != nil {
+		return 0, err
+	}
+
+	count, err := s.eachNote(ctx, userID, filter, page, watermark, func(note models.ExportedNote, index int) error {
+		data, err := json.Marshal(note)
+		if err != nil {
+			return fmt.Errorf("failed to encode note %s: %w", note.ID, err)
+		}
+		if index > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return count, err
+}
+
+// writeCSV writes a header row followed by a row for each note of page and
+// the following pages. Tags are joined with spaces into one column.
+func (s *ExportService) writeCSV(ctx context.Context, userID string, filter *models.ExportFilter, page *models.NoteList,
+	watermark *exportWatermark, w io.Writer) (int, error) {
+	out := csv.NewWriter(w)
+	if err := out.Write(exportCSVColumns); err != nil {
+		return 0, err
+	}
+
+	count, err := s.eachNote(ctx, userID, filter, page, watermark, func(note models.ExportedNote, index int) error {
+		return out.Write([]string{
+			note.ID.String(),
+			note.Title,
+			note.Content,
+			strings.Join(note.Tags, " "),
+			strconv.FormatBool(note.Private),
+			note.Color,
+			note.Icon,
+			note.CreatedAt.UTC().Format(time.RFC3339),
+			note.UpdatedAt.UTC().Format(time.RFC3339),
+			note.Watermark,
+		})
+	})
+	out.Flush()
+	if err == nil {
+		err = out.Error()
+	}
+	return count, err
+}
+
+// writePDF writes a document listing the notes of page and the following
+// pages in its table of contents, followed by each note on its own page
+// under its dates and tags. Content is written as plain text.
+func (s *ExportService) writePDF(ctx context.Context, userID string, filter *models.ExportFilter, page *models.NoteList,
+	watermark *exportWatermark, w io.Writer) (int, error) {
+	out := pdf.NewWriter(w, "Notes")
+	count, err := s.eachNote(ctx, userID, filter, page, watermark, func(note models.ExportedNote, index int) error {
+		title := note.Title
+		if title == "" {
+			title = "Untitled"
+		}
+		details := []string{
+			"Created " + note.CreatedAt.UTC().Format("2006-01-02 15:04"),
+			"Updated " + note.UpdatedAt.UTC().Format("2006-01-02 15:04"),
+		}
+		if len(note.Tags) > 0 {
+			details = append(details, strings.Join(note.Tags, " "))
+		}
+		return out.AddSection(pdf.Section{
+			Title:     title,
+			Details:   strings.Join(details, " · "),
+			Body:      note.Content,
+			Watermark: note.Watermark,
+		})
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, out.Close()
+}
+
+// eachNote calls write with each note of page and the following pages of
+// notes matching filter, along with its index, and returns the number of
+// notes written. Notes are watermarked as their notebook requires.
+func (s *ExportService) eachNote(ctx context.Context, userID string, filter *models.ExportFilter, page *models.NoteList,
+	watermark *exportWatermark, write func(note models.ExportedNote, index int) error) (int, error) {
+	count := 0
+	for {
+		for _, response := range page.Notes {
+			note := exportedNote(response)
+			if watermark.notebooks[response.NotebookID] {
+				note.Watermark = watermark.text
+			}
+			if err := write(note, count); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if !page.HasMore || len(page.Notes) == 0 {
+			return count, nil
+		}
+		var err error
+		page, err = s.noteService.SearchNotes(ctx, userID, exportSearchRequest(filter, count))
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// exportWatermark is the watermark of an export and the notebooks whose
+// notes carry it
+type exportWatermark struct {
+	text      string
+	notebooks map[uuid.UUID]bool
+}
+
+// watermark returns the watermark of an export made at exportedAt. It names
+// the user and the time, and is only read when the user has notes in
+// notebooks whose organization watermarks exports.
+func (s *ExportService) watermark(ctx context.Context, userID string, exportedAt time.Time) (*exportWatermark, error) {
+	watermark := &exportWatermark{}
+	if s.organizationService == nil {
+		return watermark, nil
+	}
+
+	var err error
+	if watermark.notebooks, err = s.organizationService.WatermarkedNotebooks(ctx, userID); err != nil {
+		return nil, err
+	}
+	if len(watermark.notebooks) == 0 {
+		return watermark, nil
+	}
+	if s.userService == nil {
+		return nil, fmt.Errorf("failed to export notes: export watermarks are not configured")
+	}
+	user, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	watermark.text = fmt.Sprintf("Exported by %s on %s", user.Email, exportedAt.Format("2006-01-02 15:04 UTC"))
+	return watermark, nil
+}
+
+// exportSearchRequest builds the search request for a page of an export. The
+// date range is appended to the query, so it takes precedence over before:
+// and after: operators in the query itself.
+func exportSearchRequest(filter *models.ExportFilter, offset int) *models.SearchNotesRequest {
+	query := strings.TrimSpace(filter.Query)
+	if filter.CreatedAfter != nil {
+		query += " after:" + filter.CreatedAfter.Format(search.DateLayout)
+	}
+	if filter.CreatedBefore != nil {
+		query += " before:" + filter.CreatedBefore.Format(search.DateLayout)
+	}
+
+	return &models.SearchNotesRequest{
+		Query:      strings.TrimSpace(query),
+		Tags:       filter.Tags,
+		Limit:      exportPageSize,
+		Offset:     offset,
+		OrderBy:    "created_at",
+		OrderDir:   "asc",
+		NotebookID: filter.NotebookID,
+		// Exports hold the user's own notes, not those of other members of
+		// shared notebooks
+		OwnedOnly: true,
+	}
+}
+
+// exportedNote converts a note to its export form
+func exportedNote(response models.NoteResponse) models.ExportedNote {
+	note := models.ExportedNote{
+		ID:        response.ID,
+		Content:   response.Content,
+		Tags:      response.Tags,
+		Private:   response.IsPrivate,
+		Color:     response.Color,
+		Icon:      response.Icon,
+		CreatedAt: response.CreatedAt,
+		UpdatedAt: response.UpdatedAt,
+	}
+	if response.Title != nil {
+		note.Title = *response.Title
+	}
+	if note.Tags == nil {
+		note.Tags = []string{}
+	}
+	return note
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/importer"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// fakeSearchNotes serves search pages from a fixed list of notes, recording
+// the requests it receives
+type fakeSearchNotes struct {
+	NoteServiceInterface
+	notes    []models.NoteResponse
+	requests []models.SearchNotesRequest
+	err      error
+}
+
+func (f *fakeSearchNotes) SearchNotes(ctx context.Context, userID string, request *models.SearchNotesRequest) (*models.NoteList, error) {
+	f.requests = append(f.requests, *request)
+	if f.err != nil {
+		return nil, f.err
+	}
+	start := min(request.Offset, len(f.notes))
+	end := min(request.Offset+request.Limit, len(f.notes))
+	return &models.NoteList{
+		Notes:   f.notes[start:end],
+		Total:   len(f.notes),
+		HasMore: end < len(f.notes),
+	}, nil
+}
+
+func TestExportNotesWritesImportableArchive(t *testing.T) {
+	title := "Standup"
+	notes := make([]models.NoteResponse, 250)
+	for i := range notes {
+		notes[i] = models.NoteResponse{ID: uuid.New(), Content: "Discussed roadmap #work", Tags: []string{"#work"}}
+	}
+	notes[0].Title = &title
+	fake := &fakeSearchNotes{notes: notes}
+	service := NewExportService(fake)
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := &models.ExportFilter{Tags: []string{"work"}, CreatedAfter: &after, Query: "roadmap"}
+
+	var buf bytes.Buffer
+	count, err := service.ExportNotes(context.Background(), uuid.New().String(), models.ExportFormatJSON, filter, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 250 {
+		t.Errorf("Expected 250 notes exported, got %d", count)
+	}
+
+	if len(fake.requests) != 3 || fake.requests[2].Offset != 200 {
+		t.Errorf("Expected 3 pages of 100 notes, got %+v", fake.requests)
+	}
+	if fake.requests[0].Query != "roadmap after:2024-01-01" || fake.requests[0].Tags[0] != "work" {
+		t.Errorf("Expected the filter to become the search request, got %+v", fake.requests[0])
+	}
+
+	var archive struct {
+		Filter models.ExportFilter   `json:"filter"`
+		Notes  []models.ExportedNote `json:"notes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &archive); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	if len(archive.Notes) != 250 || archive.Notes[0].Title != "Standup" || archive.Notes[1].ID != notes[1].ID {
+		t.Errorf("Unexpected exported notes %+v", archive.Notes[:2])
+	}
+	if archive.Filter.Query != "roadmap" {
+		t.Errorf("Expected the filter to be recorded, got %+v", archive.Filter)
+	}
+
+	// The export can be imported again as an archive
+	reader := importer.NewArchiveReader(&buf, models.DefaultMaxContentLength)
+	imported := 0
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read export as an archive: %v", err)
+		}
+		imported++
+	}
+	if imported != 250 {
+		t.Errorf("Expected 250 notes read back, got %d", imported)
+	}
+}
+
+func TestExportNotesWritesSpreadsheet(t *testing.T) {
+	title := "Standup"
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	notes := make([]models.NoteResponse, 150)
+	for i := range notes {
+		notes[i] = models.NoteResponse{ID: uuid.New(), Content: "Discussed roadmap, \"Q3\"\n#work #team", Tags: []string{"#work", "#team"},
+			CreatedAt: created, UpdatedAt: created}
+	}
+	notes[0].Title = &title
+	notes[0].Color = "blue"
+	fake := &fakeSearchNotes{notes: notes}
+	service := NewExportService(fake)
+
+	var buf bytes.Buffer
+	count, err := service.ExportNotes(context.Background(), uuid.New().String(), models.ExportFormatCSV, &models.ExportFilter{}, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 150 || len(fake.requests) != 2 {
+		t.Errorf("Expected 150 notes in 2 pages, got %d in %d", count, len(fake.requests))
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("Export is not valid CSV: %v", err)
+	}
+	if len(rows) != 151 {
+		t.Fatalf("Expected a header and 150 rows, got %d rows", len(rows))
+	}
+	want := []string{notes[0].ID.String(), "Standup", notes[0].Content, "#work #team", "false", "blue", "", "2024-03-01T09:00:00Z", "2024-03-01T09:00:00Z", ""}
+	if !slices.Equal(rows[0], exportCSVColumns) || !slices.Equal(rows[1], want) {
+		t.Errorf("Unexpected rows\n%q\n%q", rows[0], rows[1])
+	}
+
+	// The spreadsheet import reads the export back
+	table, err := importer.ParseCSV(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	records, rowErrors, err := table.Apply(table.SuggestMapping(), models.DefaultMaxContentLength)
+	if err != nil || len(rowErrors) != 0 || len(records) != 150 {
+		t.Fatalf("Expected 150 records read back, got %d, %v, %v", len(records), rowErrors, err)
+	}
+	if records[0].Title != "Standup" || !slices.Equal(records[0].Tags, []string{"#work", "#team"}) || !records[0].CreatedAt.Equal(created) {
+		t.Errorf("Unexpected record %+v", records[0])
+	}
+}
+
+func TestExportNotesWritesPDF(t *testing.T) {
+	title := "Standup"
+	notes := make([]models.NoteResponse, 120)
+	for i := range notes {
+		notes[i] = models.NoteResponse{ID: uuid.New(), Content: "Discussed roadmap #work", Tags: []string{"#work"}}
+	}
+	notes[0].Title = &title
+	fake := &fakeSearchNotes{notes: notes}
+	service := NewExportService(fake)
+
+	var buf bytes.Buffer
+	count, err := service.ExportNotes(context.Background(), uuid.New().String(), models.ExportFormatPDF, &models.ExportFilter{}, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 120 || len(fake.requests) != 2 {
+		t.Errorf("Expected 120 notes in 2 pages, got %d in %d", count, len(fake.requests))
+	}
+
+	doc := buf.String()
+	if !strings.HasPrefix(doc, "%PDF-") || !strings.HasSuffix(doc, "%%EOF\n") {
+		t.Fatalf("Export is not a PDF document")
+	}
+	// 120 contents entries take 4 pages, followed by a page per note
+	if !strings.Contains(doc, "/Count 124 >>") {
+		t.Errorf("Expected 124 pages in the export")
+	}
+	if got := strings.Count(doc, "/Subtype /Link"); got != 120 {
+		t.Errorf("Expected a contents entry per note, got %d", got)
+	}
+}
+
+func TestExportNotesWritesNothingOnEarlyErrors(t *testing.T) {
+	searchErr := errors.New("failed to search notes: connection refused")
+	tests := []struct {
+		name   string
+		format string
+		err    error
+	}{
+		{"unsupported format", "xlsx", nil},
+		{"search error", models.ExportFormatJSON, searchErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewExportService(&fakeSearchNotes{err: tt.err})
+
+			var buf bytes.Buffer
+			_, err := service.ExportNotes(context.Background(), uuid.New().String(), tt.format, &models.ExportFilter{}, &buf)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Expected nothing written, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestExportsHoldOnlyOwnNotes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	organizationService := NewOrganizationService(db, &recordingSender{})
+	noteService := NewNoteService(db, NewTagService(db))
+	service := NewExportService(noteService)
+	service.SetAccountSources(NewUserService(db), nil, nil, nil)
+	ctx := context.Background()
+
+	// Two members write in a notebook their organization shares
+	admin := testutil.NewTestUser(t, db)
+	editor := testutil.NewTestUser(t, db)
+	org, err := organizationService.CreateOrganization(ctx, admin.ID.String(), &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	invitation, err := organizationService.InviteMember(ctx, admin.ID.String(), org.ID.String(), &models.InviteMemberRequest{Email: editor.Email, Role: models.OrgRoleEditor})
+	if err != nil {
+		t.Fatalf("Failed to invite member: %v", err)
+	}
+	if _, err := organizationService.AcceptInvitation(ctx, editor, invitation.Token); err != nil {
+		t.Fatalf("Failed to accept invitation: %v", err)
+	}
+	shared, err := organizationService.CreateNotebook(ctx, admin.ID.String(), org.ID.String(), &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+	own, err := noteService.CreateNote(ctx, admin.ID.String(), &models.CreateNoteRequest{Content: "Q3 goals #planning", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, editor.ID.String(), &models.CreateNoteRequest{Content: "Q4 goals #planning", NotebookID: &shared.ID}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	readNotes := func(data []byte) []models.ExportedNote {
+		t.Helper()
+		var archive struct {
+			Notes []models.ExportedNote `json:"notes"`
+		}
+		if err := json.Unmarshal(data, &archive); err != nil {
+			t.Fatalf("Export is not valid JSON: %v", err)
+		}
+		return archive.Notes
+	}
+
+	var buf bytes.Buffer
+	if _, err := service.ExportNotes(ctx, admin.ID.String(), models.ExportFormatJSON, &models.ExportFilter{NotebookID: &shared.ID}, &buf); err != nil {
+		t.Fatalf("Failed to export notes: %v", err)
+	}
+	if notes := readNotes(buf.Bytes()); len(notes) != 1 || notes[0].ID != own.ID {
+		t.Errorf("Expected only the caller's note in the export, got %+v", notes)
+	}
+
+	buf.Reset()
+	if _, err := service.ExportAccountData(ctx, admin.ID.String(), &buf); err != nil {
+		t.Fatalf("Failed to export account data: %v", err)
+	}
+	if notes := readNotes(buf.Bytes()); len(notes) != 1 || notes[0].ID != own.ID {
+		t.Errorf("Expected only the caller's note in the account export, got %+v", notes)
+	}
+}
+
+// fakeWorkspace serves the templates, recurrences, webhooks and API keys of
+// account exports, whose tables the SQLite test schema leaves out
+type fakeWorkspace struct {
+	TemplateServiceInterface
+	RecurrenceServiceInterface
+	WebhookServiceInterface
+	APIKeyServiceInterface
+	templates   []models.Template
+	recurrences []models.Recurrence
+	webhooks    []models.Webhook
+	keys        []models.APIKey
+}
+
+func (f *fakeWorkspace) ListTemplates(ctx context.Context, userID string) ([]models.Template, error) {
+	return f.templates, nil
+}
+
+func (f *fakeWorkspace) ListRecurrences(ctx context.Context, userID string) ([]models.Recurrence, error) {
+	return f.recurrences, nil
+}
+
+func (f *fakeWorkspace) ListWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	return f.webhooks, nil
+}
+
+func (f *fakeWorkspace) ListKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	return f.keys, nil
+}
+
+func TestExportsWatermarkOrganizationNotes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	organizationService := NewOrganizationService(db, &recordingSender{})
+	noteService := NewNoteService(db, NewTagService(db))
+	service := NewExportService(noteService)
+	service.SetAccountSources(NewUserService(db), nil, nil, nil)
+	service.SetWatermarkPolicy(organizationService)
+	ctx := context.Background()
+
+	admin := testutil.NewTestUser(t, db)
+	editor := testutil.NewTestUser(t, db)
+	org, err := organizationService.CreateOrganization(ctx, admin.ID.String(), &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	invitation, err := organizationService.InviteMember(ctx, admin.ID.String(), org.ID.String(), &models.InviteMemberRequest{Email: editor.Email, Role: models.OrgRoleEditor})
+	if err != nil {
+		t.Fatalf("Failed to invite member: %v", err)
+	}
+	if _, err := organizationService.AcceptInvitation(ctx, editor, invitation.Token); err != nil {
+		t.Fatalf("Failed to accept invitation: %v", err)
+	}
+	shared, err := organizationService.CreateNotebook(ctx, admin.ID.String(), org.ID.String(), &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, editor.ID.String(), &models.CreateNoteRequest{Content: "Q3 goals", NotebookID: &shared.ID}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, editor.ID.String(), &models.CreateNoteRequest{Content: "Groceries"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	// Only admins set the policy
+	enable := true
+	if _, err := organizationService.UpdateOrganization(ctx, editor.ID.String(), org.ID.String(), &models.UpdateOrganizationRequest{WatermarkExports: &enable}); err == nil {
+		t.Fatalf("Expected an editor to be refused changing the organization")
+	}
+	updated, err := organizationService.UpdateOrganization(ctx, admin.ID.String(), org.ID.String(), &models.UpdateOrganizationRequest{WatermarkExports: &enable})
+	if err != nil || !updated.WatermarkExports || updated.Name != "Acme" {
+		t.Fatalf("Failed to enable watermarks: %+v, %v", updated, err)
+	}
+
+	want := "Exported by " + editor.Email + " on "
+	checkNotes := func(notes []models.ExportedNote) {
+		t.Helper()
+		if len(notes) != 2 {
+			t.Fatalf("Expected 2 notes, got %d", len(notes))
+		}
+		for _, note := range notes {
+			marked := strings.HasPrefix(note.Watermark, want)
+			if marked != (note.Content == "Q3 goals") {
+				t.Errorf("Unexpected watermark %q on note %q", note.Watermark, note.Content)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := service.ExportNotes(ctx, editor.ID.String(), models.ExportFormatJSON, &models.ExportFilter{}, &buf); err != nil {
+		t.Fatalf("Failed to export notes: %v", err)
+	}
+	var archive struct {
+		Notes []models.ExportedNote `json:"notes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &archive); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	checkNotes(archive.Notes)
+
+	buf.Reset()
+	if _, err := service.ExportAccountData(ctx, editor.ID.String(), &buf); err != nil {
+		t.Fatalf("Failed to export account data: %v", err)
+	}
+	archive.Notes = nil
+	if err := json.Unmarshal(buf.Bytes(), &archive); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	checkNotes(archive.Notes)
+
+	buf.Reset()
+	if _, err := service.ExportNotes(ctx, editor.ID.String(), models.ExportFormatCSV, &models.ExportFilter{}, &buf); err != nil {
+		t.Fatalf("Failed to export notes: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d rows (%v)", len(rows), err)
+	}
+	for _, row := range rows[1:] {
+		if marked := strings.HasPrefix(row[9], want); marked != (row[2] == "Q3 goals") {
+			t.Errorf("Unexpected watermark %q on row %q", row[9], row[2])
+		}
+	}
+
+	buf.Reset()
+	if _, err := service.ExportNotes(ctx, editor.ID.String(), models.ExportFormatPDF, &models.ExportFilter{}, &buf); err != nil {
+		t.Fatalf("Failed to export notes: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "%PDF-") {
+		t.Errorf("Expected a PDF export")
+	}
+}
+
+func TestExportAccountDataHoldsWorkspaceData(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	notebookService := NewNotebookService(db)
+	commentService := NewCommentService(db, nil, &recordingSender{})
+	workspace := &fakeWorkspace{
+		templates:   []models.Template{{ID: uuid.New(), Name: "Standup"}},
+		recurrences: []models.Recurrence{{ID: uuid.New(), Frequency: "daily"}},
+		webhooks:    []models.Webhook{{ID: uuid.New(), URL: "https://example.com/hook", Secret: "a-webhook-secret-value"}},
+		keys:        []models.APIKey{{ID: uuid.New(), Name: "Shortcuts"}},
+	}
+	service := NewExportService(noteService)
+	service.SetAccountSources(NewUserService(db), nil, nil, nil)
+	service.SetWorkspaceSources(workspace, notebookService, workspace, commentService, workspace, workspace)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+	notebook, err := notebookService.CreateNotebook(ctx, userID, &models.NotebookRequest{Name: "Work"})
+	if err != nil {
+		t.Fatalf("Failed to create notebook: %v", err)
+	}
+
+	// Comments are written on notes of notebooks an organization shares,
+	// which are left out of the exported notebooks
+	organizationService := NewOrganizationService(db, &recordingSender{})
+	org, err := organizationService.CreateOrganization(ctx, userID, &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	shared, err := organizationService.CreateNotebook(ctx, userID, org.ID.String(), &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Q3 goals", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := commentService.CreateComment(ctx, userID, note.ID.String(), &models.CommentRequest{Content: "Looks good"}); err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := service.ExportAccountData(ctx, userID, &buf); err != nil {
+		t.Fatalf("Failed to export account data: %v", err)
+	}
+	var data models.AccountExport
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+
+	if len(data.Templates) != 1 || data.Templates[0].Name != "Standup" {
+		t.Errorf("Expected the template in the export, got %+v", data.Templates)
+	}
+	var names []string
+	for _, exported := range data.Notebooks {
+		if exported.ID == shared.ID {
+			t.Errorf("Expected the shared notebook to be left out of the export")
+		}
+		names = append(names, exported.Name)
+	}
+	if !slices.Contains(names, notebook.Name) {
+		t.Errorf("Expected the personal notebook in the export, got %v", names)
+	}
+	if len(data.Recurrences) != 1 {
+		t.Errorf("Expected 1 recurrence in the export, got %d", len(data.Recurrences))
+	}
+	if len(data.Comments) != 1 || data.Comments[0].Content != "Looks good" {
+		t.Errorf("Expected the comment in the export, got %+v", data.Comments)
+	}
+	if len(data.Webhooks) != 1 || bytes.Contains(buf.Bytes(), []byte("a-webhook-secret-value")) {
+		t.Errorf("Expected the webhook without its secret in the export, got %+v", data.Webhooks)
+	}
+	if len(data.APIKeys) != 1 || data.APIKeys[0].Name != "Shortcuts" {
+		t.Errorf("Expected the API key metadata in the export, got %+v", data.APIKeys)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// FeedServiceInterface defines the interface for reading activity feeds
+type FeedServiceInterface interface {
+	ListFeed(ctx context.Context, userID string, notebookID *uuid.UUID, cursor string, limit int) (*models.Feed, error)
+}
+
+// feedAuditTypes maps audited note actions to activity event types
+var feedAuditTypes = map[string]string{
+	models.AuditActionCreate:   models.FeedNoteCreated,
+	models.AuditActionUpdate:   models.FeedNoteUpdated,
+	models.AuditActionDelete:   models.FeedNoteDeleted,
+	models.AuditActionPrettify: models.FeedNotePrettified,
+}
+
+// FeedService builds activity feeds from the tables recording what
+// happened: the audit log for note writes, note tags for tags added, and
+// organization members and notebooks for shares. A user's feed holds their
+// own note events and the events of the notebooks and organizations shared
+// with them.
+type FeedService struct {
+	db *sql.DB
+}
+
+// NewFeedService creates a new FeedService
+func NewFeedService(db *sql.DB) *FeedService {
+	return &FeedService{db: db}
+}
+
+// feedCursor is the position after an event: its time, and its key
+// ordering events of the same time
+type feedCursor struct {
+	createdAt time.Time
+	key       string
+}
+
+// encodeFeedCursor returns the opaque form of a cursor
+func encodeFeedCursor(c feedCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.createdAt.Format(time.RFC3339Nano) + " " + c.key))
+}
+
+// decodeFeedCursor parses an opaque cursor; nil is the newest event
+func decodeFeedCursor(cursor string) (*feedCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	timePart, key, ok := strings.Cut(string(raw), " ")
+	if !ok || key == "" {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, timePart)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &feedCursor{createdAt: createdAt, key: key}, nil
+}
+
+// ListFeed returns a page of the user's activity feed, newest first,
+// from the events before cursor. With notebookID, only the events of that
+// notebook are returned, with the members joining its organization for
+// shared notebooks.
+func (s *FeedService) ListFeed(ctx context.Context, userID string, notebookID *uuid.UUID, cursor string, limit int) (*models.Feed, error) {
+	after, err := decodeFeedCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	memberOrgs := `SELECT organization_id FROM organization_members WHERE user_id = $1`
+	sharedNotebooks := `SELECT id FROM notebooks WHERE organization_id IN (` + memberOrgs + `)`
+
+	var conditions []string
+	args := []interface{}{userID}
+
+	if notebookID != nil {
+		var organizationID *uuid.UUID
+		err := s.db.QueryRowContext(ctx, `
+			SELECT organization_id FROM notebooks
+			WHERE id = $2 AND ((user_id = $1 AND organization_id IS NULL) OR organization_id IN (`+memberOrgs+`))
+		`, userID, *notebookID).Scan(&organizationID)
+		if err == sql.ErrNoRows {
+			return nil, ErrNotebookNotFound
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get notebook: %w", err)
+		}
+
+		conditions = append(conditions, fmt.Sprintf(
+			"(COALESCE(e.notebook_id, n.notebook_id) = $%d OR (e.type = '%s' AND e.organization_id = $%d))",
+			len(args)+1, models.FeedMemberJoined, len(args)+2))
+		args = append(args, *notebookID, organizationID)
+	}
+
+	if after != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"(e.created_at < $%[1]d OR (e.created_at = $%[1]d AND e.key < $%[2]d))", len(args)+1, len(args)+2))
+		args = append(args, after.createdAt, after.key)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit+1)
+
+	// The first two branches give every column a type, which PostgreSQL
+	// needs to resolve the NULLs of the others
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.type, e.key, e.actor_id, COALESCE(u.email, ''), e.note_id, COALESCE(n.title, ''),
+			COALESCE(e.notebook_id, n.notebook_id), e.organization_id, e.tag, e.count, e.created_at
+		FROM (
+			SELECT '`+models.FeedTagAdded+`' AS type,
+				CAST(nt.note_id AS TEXT) || ':' || CAST(nt.tag_id AS TEXT) AS key,
+				tn.user_id AS actor_id, nt.note_id AS note_id, tn.notebook_id AS notebook_id,
+				NULL AS organization_id, t.name AS tag, 1 AS count, nt.created_at AS created_at
+			FROM note_tags nt
+			JOIN notes tn ON tn.id = nt.note_id
+			JOIN tags t ON t.id = nt.tag_id
+			WHERE `+noteAccess("tn.", 1)+`
+			UNION ALL
+			SELECT '`+models.FeedNotebookShared+`', CAST(nb.id AS TEXT), nb.user_id, NULL, nb.id,
+				nb.organization_id, '', 1, nb.created_at
+			FROM notebooks nb
+			WHERE nb.organization_id IN (`+memberOrgs+`)
+			UNION ALL
+			SELECT a.action, CAST(a.id AS TEXT), a.user_id, a.resource_id, NULL, NULL, '', a.count, a.created_at
+			FROM audit_log a
+			WHERE a.resource_type = '`+models.AuditResourceNote+`'
+				AND (a.user_id = $1 OR a.resource_id IN (SELECT id FROM notes WHERE notebook_id IN (`+sharedNotebooks+`)))
+			UNION ALL
+			SELECT '`+models.FeedMemberJoined+`', CAST(m.organization_id AS TEXT) || ':' || CAST(m.user_id AS TEXT),
+				m.user_id, NULL, NULL, m.organization_id, '', 1, m.created_at
+			FROM organization_members m
+			WHERE m.organization_id IN (`+memberOrgs+`)
+		) e
+		LEFT JOIN users u ON u.id = e.actor_id
+		LEFT JOIN notes n ON n.id = e.note_id
+		`+where+`
+		ORDER BY e.created_at DESC, e.key DESC
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	defer rows.Close()
+
+	feed := &models.Feed{Events: []models.FeedEvent{}}
+	var last feedCursor
+	for rows.Next() {
+		if len(feed.Events) == limit {
+			feed.HasMore = true
+			feed.NextCursor = encodeFeedCursor(last)
+			break
+		}
+
+		var event models.FeedEvent
+		if err := rows.Scan(&event.Type, &last.key, &event.ActorID, &event.ActorEmail, &event.NoteID, &event.NoteTitle,
+			&event.NotebookID, &event.OrganizationID, &event.Tag, &event.Count, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if eventType, ok := feedAuditTypes[event.Type]; ok {
+			event.Type = eventType
+		}
+		last.createdAt = event.CreatedAt
+		feed.Events = append(feed.Events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating activity: %w", err)
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestListFeed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewFeedService(db)
+	organizationService := NewOrganizationService(db, email.LogSender{})
+	noteService := NewNoteService(db, NewTagService(db))
+	auditService := NewAuditService(db, 0)
+	ctx := context.Background()
+
+	owner := testutil.NewTestUser(t, db)
+	member := testutil.NewTestUser(t, db)
+	outsider := testutil.NewTestUser(t, db)
+	ownerID, memberID := owner.ID.String(), member.ID.String()
+
+	org, err := organizationService.CreateOrganization(ctx, ownerID, &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	invitation, err := organizationService.InviteMember(ctx, ownerID, org.ID.String(), &models.InviteMemberRequest{Email: member.Email, Role: models.OrgRoleEditor})
+	if err != nil {
+		t.Fatalf("Failed to invite member: %v", err)
+	}
+	if _, err := organizationService.AcceptInvitation(ctx, member, invitation.Token); err != nil {
+		t.Fatalf("Failed to accept invitation: %v", err)
+	}
+	shared, err := organizationService.CreateNotebook(ctx, ownerID, org.ID.String(), &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+
+	note, err := noteService.CreateNote(ctx, memberID, &models.CreateNoteRequest{Title: "Q3 goals", Content: "Ship it #planning", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if err := auditService.Record(ctx, &models.AuditEntry{UserID: member.ID, Action: models.AuditActionCreate, ResourceType: models.AuditResourceNote, ResourceID: &note.ID, Count: 1}); err != nil {
+		t.Fatalf("Failed to record audit entry: %v", err)
+	}
+	// The member's personal notes stay out of the owner's feed
+	if _, err := noteService.CreateNote(ctx, memberID, &models.CreateNoteRequest{Content: "Diary #private"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	// Paging through the feed returns every event once, newest first
+	var events []models.FeedEvent
+	cursor := ""
+	for page := 0; ; page++ {
+		feed, err := service.ListFeed(ctx, ownerID, nil, cursor, 2)
+		if err != nil {
+			t.Fatalf("Failed to list feed: %v", err)
+		}
+		events = append(events, feed.Events...)
+		if !feed.HasMore {
+			break
+		}
+		if page > 5 {
+			t.Fatalf("Feed does not end")
+		}
+		cursor = feed.NextCursor
+	}
+
+	counts := map[string]int{}
+	for i, event := range events {
+		counts[event.Type]++
+		if i > 0 && event.CreatedAt.After(events[i-1].CreatedAt) {
+			t.Errorf("Expected events newest first, got %v after %v", event.CreatedAt, events[i-1].CreatedAt)
+		}
+		if event.Type == models.FeedTagAdded && event.Tag != "#planning" {
+			t.Errorf("Expected only the shared note's tag, got %s", event.Tag)
+		}
+		if event.Type == models.FeedNoteCreated && (event.ActorEmail != member.Email || event.NoteTitle != "Q3 goals") {
+			t.Errorf("Unexpected note event %+v", event)
+		}
+	}
+	expected := map[string]int{
+		models.FeedMemberJoined:   2,
+		models.FeedNotebookShared: 1,
+		models.FeedNoteCreated:    1,
+		models.FeedTagAdded:       1,
+	}
+	for eventType, count := range expected {
+		if counts[eventType] != count {
+			t.Errorf("Expected %d %s events, got %d (%v)", count, eventType, counts[eventType], counts)
+		}
+	}
+
+	// A notebook's feed has its notes and the members of its organization
+	feed, err := service.ListFeed(ctx, memberID, &shared.ID, "", 50)
+	if err != nil {
+		t.Fatalf("Failed to list notebook feed: %v", err)
+	}
+	if len(feed.Events) != len(events) {
+		t.Errorf("Expected %d notebook events, got %+v", len(events), feed.Events)
+	}
+
+	if _, err := service.ListFeed(ctx, outsider.ID.String(), &shared.ID, "", 50); !errors.Is(err, ErrNotebookNotFound) {
+		t.Errorf("Expected outsiders not to see the notebook, got %v", err)
+	}
+	feed, err = service.ListFeed(ctx, outsider.ID.String(), nil, "", 50)
+	if err != nil || len(feed.Events) != 0 {
+		t.Errorf("Expected an empty feed for outsiders, got %+v, %v", feed, err)
+	}
+	if _, err := service.ListFeed(ctx, ownerID, nil, "not a cursor", 50); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected an invalid cursor error, got %v", err)
+	}
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxFocusSession caps sessions that were never stopped, e.g. because the
+// browser was closed, so they do not fill the timesheet with days of work
+const maxFocusSession = 12 * time.Hour
+
+// maxTimeSummaryDays is the longest range a time summary covers
+const maxTimeSummaryDays = 366
+
+// FocusServiceInterface defines the interface for focus session tracking
+type FocusServiceInterface interface {
+	StartSession(ctx context.Context, userID, noteID string) (*models.StartFocusSessionResponse, error)
+	StopSession(ctx context.Context, userID, noteID string) (*models.FocusSession, error)
+	GetTimeSummary(ctx context.Context, userID string, request *models.TimeSummaryRequest) (*models.TimeSummary, error)
+	TotalTimes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID]int64, error)
+}
+
+// FocusTimeSource provides the total time spent on notes
+type FocusTimeSource interface {
+	TotalTimes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID]int64, error)
+}
+
+// ErrNoFocusSession is returned when stopping a focus session while none is
+// running
+var ErrNoFocusSession = apperrors.NotFound("FOCUS_SESSION_NOT_FOUND", "no focus session is running for this note")
+
+// FocusService records focus sessions against notes. A user focuses on one
+// note at a time: starting a session stops the one running on another note.
+type FocusService struct {
+	db *sql.DB
+}
+
+// NewFocusService creates a new FocusService
+func NewFocusService(db *sql.DB) *FocusService {
+	return &FocusService{db: db}
+}
+
+// focusSessionColumns lists the columns scanned by scanFocusSession
+const focusSessionColumns = "id, note_id, started_at, ended_at"
+
+// scanFocusSession scans a row selected with focusSessionColumns
+func scanFocusSession(row rowScanner, session *models.FocusSession) error {
+	if err := row.Scan(&session.ID, &session.NoteID, &session.StartedAt, &session.EndedAt); err != nil {
+		return err
+	}
+	session.DurationSeconds = int64(focusSessionEnd(session.StartedAt, session.EndedAt, time.Now()).Sub(session.StartedAt).Seconds())
+	return nil
+}
+
+// focusSessionEnd returns when a session ended, counting running sessions up
+// to now and capping both at maxFocusSession
+func focusSessionEnd(startedAt time.Time, endedAt *time.Time, now time.Time) time.Time {
+	end := now
+	if endedAt != nil {
+		end = *endedAt
+	}
+	if limit := startedAt.Add(maxFocusSession); end.After(limit) {
+		end = limit
+	}
+	return end
+}
+
+// StartSession starts a focus session on a note. Starting a session on the
+// note already being focused on returns the running session.
+func (s *FocusService) StartSession(ctx context.Context, userID, noteID string) (*models.StartFocusSessionResponse, error) {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return nil, ErrNoteNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM notes WHERE id = $1 AND user_id = $2)`,
+		noteID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check note: %w", err)
+	}
+	if !exists {
+		return nil, ErrNoteNotFound
+	}
+
+	response := &models.StartFocusSessionResponse{}
+
+	var running models.FocusSession
+	err = scanFocusSession(tx.QueryRowContext(ctx, `
+		SELECT `+focusSessionColumns+`
+		FROM focus_sessions
+		WHERE user_id = $1 AND ended_at IS NULL
+		FOR UPDATE
+	`, userID), &running)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, fmt.Errorf("failed to get running focus session: %w", err)
+	case running.NoteID.String() == noteID:
+		response.Session = &running
+		return response, nil
+	default:
+		stopped, err := stopFocusSession(ctx, tx, &running)
+		if err != nil {
+			return nil, err
+		}
+		response.Stopped = stopped
+	}
+
+	var session models.FocusSession
+	err = scanFocusSession(tx.QueryRowContext(ctx, `
+		INSERT INTO focus_sessions (user_id, note_id)
+		VALUES ($1, $2)
+		RETURNING `+focusSessionColumns, userID, noteID), &session)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.Conflict("FOCUS_SESSION_RUNNING", "another focus session was started at the same time")
+		}
+		return nil, fmt.Errorf("failed to start focus session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	response.Session = &session
+	return response, nil
+}
+
+// StopSession stops the session running on a note
+func (s *FocusService) StopSession(ctx context.Context, userID, noteID string) (*models.FocusSession, error) {
+	if _, err := uuid.Parse(noteID); err != nil {
+		return nil, ErrNoFocusSession
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var running models.FocusSession
+	err = scanFocusSession(tx.QueryRowContext(ctx, `
+		SELECT `+focusSessionColumns+`
+		FROM focus_sessions
+		WHERE user_id = $1 AND note_id = $2 AND ended_at IS NULL
+		FOR UPDATE
+	`, userID, noteID), &running)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoFocusSession
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get running focus session: %w", err)
+	}
+
+	stopped, err := stopFocusSession(ctx, tx, &running)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stopped, nil
+}
+
+// stopFocusSession ends a running session now, or at maxFocusSession for
+// sessions left running too long
+func stopFocusSession(ctx context.Context, tx *sql.Tx, running *models.FocusSession) (*models.FocusSession, error) {
+	endedAt := focusSessionEnd(running.StartedAt, nil, time.Now())
+
+	var stopped models.FocusSession
+	err := scanFocusSession(tx.QueryRowContext(ctx, `
+		UPDATE focus_sessions
+		SET ended_at = $1
+		WHERE id = $2
+		RETURNING `+focusSessionColumns, endedAt, running.ID), &stopped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop focus session: %w", err)
+	}
+	return &stopped, nil
+}
+
+// GetTimeSummary returns the time spent on notes per day or week between the
+// request's first and last day. Weeks start on Monday; a week period covers
+// whole weeks around the requested days.
+func (s *FocusService) GetTimeSummary(ctx context.Context, userID string, request *models.TimeSummaryRequest) (*models.TimeSummary, error) {
+	if request.Period != models.TimePeriodDay && request.Period != models.TimePeriodWeek {
+		return nil, apperrors.Validation("INVALID_PERIOD", "period must be day or week")
+	}
+	loc := request.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	from := startOfDay(request.From, loc)
+	to := startOfDay(request.To, loc)
+	if to.Before(from) {
+		return nil, apperrors.Validation("INVALID_PERIOD", "from must not be after to")
+	}
+	if request.Period == models.TimePeriodWeek {
+		from = startOfWeek(from)
+		to = startOfWeek(to).AddDate(0, 0, 6)
+	}
+	if to.Sub(from) > maxTimeSummaryDays*24*time.Hour {
+		return nil, apperrors.Validation("INVALID_PERIOD", "date range cannot exceed 366 days")
+	}
+	end := to.AddDate(0, 0, 1)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.note_id, COALESCE(n.title, ''), f.started_at, f.ended_at
+		FROM focus_sessions f
+		JOIN notes n ON n.id = f.note_id
+		WHERE f.user_id = $1
+		  AND f.started_at < $3
+		  AND f.started_at > $4
+		  AND (f.ended_at IS NULL OR f.ended_at > $2)
+		ORDER BY f.started_at
+	`, userID, from, end, from.Add(-maxFocusSession))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get focus sessions: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var intervals []focusInterval
+	for rows.Next() {
+		var interval focusInterval
+		var endedAt *time.Time
+		if err := rows.Scan(&interval.noteID, &interval.title, &interval.start, &endedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan focus session: %w", err)
+		}
+		interval.end = focusSessionEnd(interval.start, endedAt, now)
+		intervals = append(intervals, interval)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating focus sessions: %w", err)
+	}
+
+	summary := &models.TimeSummary{
+		Period:   request.Period,
+		TimeZone: loc.String(),
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Buckets:  bucketFocusTime(intervals, request.Period, from, end),
+	}
+	for _, bucket := range summary.Buckets {
+		summary.TotalSeconds += bucket.TotalSeconds
+	}
+
+	return summary, nil
+}
+
+// TotalTimes returns the total time spent on each of the notes, in seconds.
+// Notes without sessions are left out.
+func (s *FocusService) TotalTimes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	totals := make(map[uuid.UUID]int64)
+	if len(noteIDs) == 0 {
+		return totals, nil
+	}
+
+	ids := make([]string, len(noteIDs))
+	for i, id := range noteIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT note_id,
+		       SUM(EXTRACT(EPOCH FROM LEAST(COALESCE(ended_at, NOW()), started_at + $2 * INTERVAL '1 second') - started_at))::bigint
+		FROM focus_sessions
+		WHERE note_id = ANY($1::uuid[])
+		GROUP BY note_id
+	`, pq.Array(ids), maxFocusSession.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note times: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var noteID uuid.UUID
+		var seconds int64
+		if err := rows.Scan(&noteID, &seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan note time: %w", err)
+		}
+		totals[noteID] = seconds
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating note times: %w", err)
+	}
+
+	return totals, nil
+}
+
+// focusInterval is the time spent on a note in one session
+type focusInterval struct {
+	noteID uuid.UUID
+	title  string
+	start  time.Time
+	end    time.Time
+}
+
+// bucketFocusTime splits intervals into day or week buckets from from up to
+// end, both midnight in the summary's location. Sessions crossing midnight
+// count towards both days.
+func bucketFocusTime(intervals []focusInterval, period string, from, end time.Time) []models.TimeBucket {
+	var starts []time.Time
+	for start := from; start.Before(end); {
+		starts = append(starts, start)
+		if period == models.TimePeriodWeek {
+			start = start.AddDate(0, 0, 7)
+		} else {
+			start = start.AddDate(0, 0, 1)
+		}
+	}
+
+	durations := make([]map[uuid.UUID]time.Duration, len(starts))
+	titles := make(map[uuid.UUID]string)
+	for _, interval := range intervals {
+		titles[interval.noteID] = interval.title
+		for i, start := range starts {
+			bucketEnd := end
+			if i+1 < len(starts) {
+				bucketEnd = starts[i+1]
+			}
+
+			overlap := minTime(interval.end, bucketEnd).Sub(maxTime(interval.start, start))
+			if overlap <= 0 {
+				continue
+			}
+			if durations[i] == nil {
+				durations[i] = make(map[uuid.UUID]time.Duration)
+			}
+			durations[i][interval.noteID] += overlap
+		}
+	}
+
+	buckets := make([]models.TimeBucket, len(starts))
+	for i, start := range starts {
+		bucket := models.TimeBucket{Start: start.Format("2006-01-02"), Notes: []models.NoteTime{}}
+		for noteID, duration := range durations[i] {
+			seconds := int64(duration.Seconds())
+			bucket.TotalSeconds += seconds
+			bucket.Notes = append(bucket.Notes, models.NoteTime{NoteID: noteID, Title: titles[noteID], Seconds: seconds})
+		}
+		// Most time first, then by title for a stable order
+		sort.Slice(bucket.Notes, func(a, b int) bool {
+			if bucket.Notes[a].Seconds != bucket.Notes[b].Seconds {
+				return bucket.Notes[a].Seconds > bucket.Notes[b].Seconds
+			}
+			return bucket.Notes[a].Title < bucket.Notes[b].Title
+		})
+		buckets[i] = bucket
+	}
+	return buckets
+}
+
+// startOfDay returns midnight of t's day in loc
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// startOfWeek returns the Monday on or before day, which is at midnight
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestBucketFocusTimeByDay(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	meeting, project := uuid.New(), uuid.New()
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, loc)
+	end := from.AddDate(0, 0, 3)
+	intervals := []focusInterval{
+		// One hour on the first day
+		{meeting, "Standup", from.Add(9 * time.Hour), from.Add(10 * time.Hour)},
+		// 23:30 to 00:30 is split across the first and second day
+		{project, "Launch", from.Add(23*time.Hour + 30*time.Minute), from.Add(24*time.Hour + 30*time.Minute)},
+		// Started before the range; only the part inside counts
+		{project, "Launch", from.Add(-time.Hour), from.Add(15 * time.Minute)},
+	}
+
+	buckets := bucketFocusTime(intervals, models.TimePeriodDay, from, end)
+	if len(buckets) != 3 {
+		t.Fatalf("Expected 3 buckets, got %d", len(buckets))
+	}
+
+	if buckets[0].Start != "2024-03-04" || buckets[0].TotalSeconds != 6300 {
+		t.Errorf("Unexpected first day %+v", buckets[0])
+	}
+	if len(buckets[0].Notes) != 2 || buckets[0].Notes[0].NoteID != meeting || buckets[0].Notes[1].Seconds != 2700 {
+		t.Errorf("Expected the standup first, then 45 minutes of launch work, got %+v", buckets[0].Notes)
+	}
+	if buckets[1].TotalSeconds != 1800 || buckets[1].Notes[0].Title != "Launch" {
+		t.Errorf("Unexpected second day %+v", buckets[1])
+	}
+	if buckets[2].TotalSeconds != 0 || buckets[2].Notes == nil {
+		t.Errorf("Expected an empty third day, got %+v", buckets[2])
+	}
+}
+
+func TestBucketFocusTimeByWeek(t *testing.T) {
+	from := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC) // Monday
+	end := from.AddDate(0, 0, 14)
+	noteID := uuid.New()
+	intervals := []focusInterval{
+		{noteID, "Report", from.AddDate(0, 0, 2), from.AddDate(0, 0, 2).Add(2 * time.Hour)},
+		{noteID, "Report", from.AddDate(0, 0, 9), from.AddDate(0, 0, 9).Add(time.Hour)},
+	}
+
+	buckets := bucketFocusTime(intervals, models.TimePeriodWeek, from, end)
+	if len(buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", len(buckets))
+	}
+	if buckets[0].Start != "2024-03-04" || buckets[0].TotalSeconds != 7200 {
+		t.Errorf("Unexpected first week %+v", buckets[0])
+	}
+	if buckets[1].Start != "2024-03-11" || buckets[1].TotalSeconds != 3600 {
+		t.Errorf("Unexpected second week %+v", buckets[1])
+	}
+}
+
+func TestFocusSessionEnd(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	now := start.Add(2 * time.Hour)
+
+	if got := focusSessionEnd(start, nil, now); !got.Equal(now) {
+		t.Errorf("Running session should end now, got %v", got)
+	}
+
+	ended := start.Add(time.Hour)
+	if got := focusSessionEnd(start, &ended, now); !got.Equal(ended) {
+		t.Errorf("Stopped session should end when stopped, got %v", got)
+	}
+
+	if got := focusSessionEnd(start, nil, start.Add(48*time.Hour)); !got.Equal(start.Add(maxFocusSession)) {
+		t.Errorf("Forgotten session should be capped, got %v", got)
+	}
+}
+
+func TestStartOfWeek(t *testing.T) {
+	monday := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	for offset := 0; offset < 7; offset++ {
+		day := monday.AddDate(0, 0, offset)
+		if got := startOfWeek(day); !got.Equal(monday) {
+			t.Errorf("startOfWeek(%s) = %s, want %s", day.Weekday(), got, monday)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// IdempotencyKeyTTL is how long responses are replayed for a key
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyClaimTimeout is how long a key stays claimed without a
+// response, after which the request is assumed lost, e.g. in a crash
+const idempotencyClaimTimeout = 5 * time.Minute
+
+var (
+	// ErrIdempotencyKeyInProgress is returned while the first request with a
+	// key is still being handled
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+	// ErrIdempotencyKeyReused is returned when a key is sent with a
+	// different request than the one it was first used for
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+)
+
+// IdempotencyServiceInterface defines the interface for idempotency key operations
+type IdempotencyServiceInterface interface {
+	Begin(ctx context.Context, userID, key, method, path, requestHash string) (*models.IdempotentResponse, error)
+	Complete(ctx context.Context, userID, key string, response *models.IdempotentResponse) error
+	Release(ctx context.Context, userID, key string) error
+}
+
+// IdempotencyService stores the responses of requests sent with an
+// Idempotency-Key header, so retries of a request that already succeeded
+// get the original response instead of running again
+type IdempotencyService struct {
+	db *sql.DB
+}
+
+// NewIdempotencyService creates a new IdempotencyService
+func NewIdempotencyService(db *sql.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// Begin claims a key for a request. It returns nil when the request should
+// be handled, followed by Complete or Release, and the stored response when
+// the key was already used for the same request.
+func (s *IdempotencyService) Begin(ctx context.Context, userID, key, method, path, requestHash string) (*models.IdempotentResponse, error) {
+	// Expired keys are claimed again as if they were new, and abandoned
+	// claims by a retry of the same request
+	now := time.Now()
+	var claimed bool
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET method = EXCLUDED.method, path = EXCLUDED.path, request_hash = EXCLUDED.request_hash,
+			status_code = NULL, response_body = NULL, created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= $7
+				AND idempotency_keys.request_hash = EXCLUDED.request_hash)
+		RETURNING true
+	`, userID, key, method, path, requestHash, now.Add(IdempotencyKeyTTL), now.Add(-idempotencyClaimTimeout)).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var storedMethod, storedPath, storedHash string
+	var statusCode sql.NullInt64
+	var body []byte
+	err = s.db.QueryRowContext(ctx, `
+		SELECT method, path, request_hash, status_code, response_body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key).Scan(&storedMethod, &storedPath, &storedHash, &statusCode, &body)
+	if err == sql.ErrNoRows {
+		// Released between the two queries
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if storedMethod != method || storedPath != path || storedHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !statusCode.Valid {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	return &models.IdempotentResponse{StatusCode: int(statusCode.Int64), Body: body}, nil
+}
+
+// Complete stores the response of a request claimed by Begin
+func (s *IdempotencyService) Complete(ctx context.Context, userID, key string, response *models.IdempotentResponse) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, response_body = $4
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, key, response.StatusCode, response.Body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release frees a key claimed by Begin without storing a response, so the
+// request can be retried
+func (s *IdempotencyService) Release(ctx context.Context, userID, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// CleanupExpiredKeys removes keys past their expiry
+func (s *IdempotencyService) CleanupExpiredKeys(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired idempotency keys: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/ocr"
+)
+
+// Image text recognition limits
+const (
+	// imageTextTimeout bounds the recognition of one image
+	imageTextTimeout = 60 * time.Second
+	// maxConcurrentImageText is the number of images recognized at once;
+	// recognition is CPU bound
+	maxConcurrentImageText = 2
+)
+
+// Image text error codes
+const (
+	codeInvalidImage      = "INVALID_IMAGE"
+	codeImageTextFailed   = "IMAGE_TEXT_FAILED"
+	codeImageTextPrivate  = "IMAGE_TEXT_PRIVATE_NOTE"
+	codeImageTextTooLarge = "IMAGE_TEXT_TOO_LARGE"
+)
+
+// ImageTextServiceInterface defines the interface for recognizing the text
+// of images uploaded to notes
+type ImageTextServiceInterface interface {
+	AddImage(ctx context.Context, userID, noteID string, image []byte) (*models.ImageTextResponse, error)
+	ClearImageText(ctx context.Context, userID, noteID string) (*models.Note, error)
+}
+
+// ImageTextService recognizes the text in images uploaded to notes, such as
+// photos of whiteboards, and appends it to the note's image text so search
+// finds it. The images themselves are not stored.
+type ImageTextService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+	recognizer  ocr.Recognizer
+	slots       chan struct{}
+}
+
+// NewImageTextService creates a new ImageTextService
+func NewImageTextService(db *sql.DB, noteService NoteServiceInterface, recognizer ocr.Recognizer) *ImageTextService {
+	return &ImageTextService{
+		db:          db,
+		noteService: noteService,
+		recognizer:  recognizer,
+		slots:       make(chan struct{}, maxConcurrentImageText),
+	}
+}
+
+// AddImage recognizes the text in an image and appends it to the image text
+// of a note the user may change. Image text is stored unencrypted, so images
+// cannot be added to private notes.
+func (s *ImageTextService) AddImage(ctx context.Context, userID, noteID string, image []byte) (*models.ImageTextResponse, error) {
+	note, err := writableNote(ctx, s.db, s.noteService, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.IsPrivate {
+		return nil, apperrors.Validation(codeImageTextPrivate, "text is not recognized in images of private notes")
+	}
+	if models.DetectImageType(image) == "" {
+		return nil, apperrors.Validation(codeInvalidImage, "image must be PNG, JPEG, GIF, WebP or BMP")
+	}
+
+	text, err := s.recognize(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return &models.ImageTextResponse{Note: note.ToResponse()}, nil
+	}
+
+	current := ""
+	if note.ImageText != nil {
+		current = *note.ImageText
+	}
+	if len(current)+len(text)+2 > models.MaxNoteImageTextLength {
+		return nil, apperrors.New(apperrors.ErrTooLarge, codeImageTextTooLarge,
+			fmt.Sprintf("note image text is limited to %d bytes", models.MaxNoteImageTextLength))
+	}
+
+	// The version is kept: the note as written by its author is unchanged
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notes
+		SET image_text = CASE WHEN image_text IS NULL OR image_text = '' THEN $1 ELSE image_text || $2 || $1 END
+		WHERE id = $3 AND NOT is_private
+	`, text, "\n\n", noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store image text: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return nil, ErrNoteNotFound
+	}
+
+	note, err = s.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ImageTextResponse{Text: text, Note: note.ToResponse()}, nil
+}
+
+// ClearImageText removes the text recognized in a note's images
+func (s *ImageTextService) ClearImageText(ctx context.Context, userID, noteID string) (*models.Note, error) {
+	if _, err := writableNote(ctx, s.db, s.noteService, userID, noteID); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE notes SET image_text = NULL WHERE id = $1`, noteID); err != nil {
+		return nil, fmt.Errorf("failed to clear image text: %w", err)
+	}
+	return s.noteService.GetNoteByID(ctx, userID, noteID)
+}
+
+// recognize returns the text in an image, waiting for a free slot
+func (s *ImageTextService) recognize(ctx context.Context, image []byte) (string, error) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageTextTimeout)
+	defer cancel()
+
+	text, err := s.recognizer.Recognize(ctx, image)
+	switch {
+	case err == nil:
+		return text, nil
+	case errors.Is(err, ocr.ErrUnavailable):
+		log.Printf("[ImageTextService] ERROR: %v", err)
+		return "", apperrors.New(apperrors.ErrUnavailable, codeImageTextFailed, "text recognition is unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return "", apperrors.New(apperrors.ErrUnavailable, codeImageTextFailed, "text recognition timed out")
+	default:
+		log.Printf("[ImageTextService] WARNING: Failed to recognize image text: %v", err)
+		return "", apperrors.New(apperrors.ErrValidation, codeImageTextFailed, "failed to read the image")
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/ocr"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+// fakeRecognizer answers every image with a fixed text
+type fakeRecognizer struct {
+	text string
+	err  error
+}
+
+func (f *fakeRecognizer) Recognize(ctx context.Context, image []byte) (string, error) {
+	return f.text, f.err
+}
+
+// testPNG is the start of a PNG image, enough to be detected as one
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestImageText(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	keys, err := encryption.NewStaticKeyProvider(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noteService.SetEncryptor(encryption.NewNoteEncryptor(keys))
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	recognizer := &fakeRecognizer{text: "Q3 roadmap\n- migrate billing"}
+	service := NewImageTextService(db, noteService, recognizer)
+
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Title: "Planning", Content: "Photos of the whiteboard"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	noteID := note.ID.String()
+
+	response, err := service.AddImage(ctx, userID, noteID, testPNG)
+	if err != nil {
+		t.Fatalf("Failed to add image: %v", err)
+	}
+	if response.Text != recognizer.text || response.Note.ImageText == nil || *response.Note.ImageText != recognizer.text {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	if response.Note.Version != note.Version {
+		t.Errorf("Expected the version to be kept, got %d", response.Note.Version)
+	}
+
+	recognizer.text = "Retro: fewer meetings"
+	response, err = service.AddImage(ctx, userID, noteID, testPNG)
+	if err != nil {
+		t.Fatalf("Failed to add image: %v", err)
+	}
+	if want := "Q3 roadmap\n- migrate billing\n\nRetro: fewer meetings"; *response.Note.ImageText != want {
+		t.Errorf("Expected the text to be appended, got %q", *response.Note.ImageText)
+	}
+
+	// Search finds the note by the text of its images
+	list, err := noteService.SearchNotes(ctx, userID, &models.SearchNotesRequest{Query: "billing"})
+	if err != nil {
+		t.Fatalf("Failed to search notes: %v", err)
+	}
+	if len(list.Notes) != 1 || list.Notes[0].ID != note.ID {
+		t.Errorf("Expected the note to be found by its image text, got %+v", list.Notes)
+	}
+
+	// Images without text leave the note unchanged
+	recognizer.text = ""
+	response, err = service.AddImage(ctx, userID, noteID, testPNG)
+	if err != nil || response.Text != "" || !strings.HasSuffix(*response.Note.ImageText, "fewer meetings") {
+		t.Errorf("Expected no text to be added, got %+v, %v", response, err)
+	}
+
+	failures := []struct {
+		name  string
+		image []byte
+		err   error
+		kind  error
+	}{
+		{"not an image", []byte("%PDF-1.7"), nil, apperrors.ErrValidation},
+		{"unreadable image", testPNG, errors.New("tesseract failed"), apperrors.ErrValidation},
+		{"engine unavailable", testPNG, ocr.ErrUnavailable, apperrors.ErrUnavailable},
+	}
+	for _, failure := range failures {
+		recognizer.err = failure.err
+		if _, err := service.AddImage(ctx, userID, noteID, failure.image); !errors.Is(err, failure.kind) {
+			t.Errorf("%s: expected %v, got %v", failure.name, failure.kind, err)
+		}
+	}
+	recognizer.err = nil
+
+	other := testutil.NewTestUser(t, db)
+	if _, err := service.AddImage(ctx, other.ID.String(), noteID, testPNG); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected other users' notes to be refused, got %v", err)
+	}
+
+	// Making the note private removes its unencrypted image text
+	private := true
+	note, err = noteService.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{Private: &private})
+	if err != nil {
+		t.Fatalf("Failed to make the note private: %v", err)
+	}
+	if note.ImageText != nil {
+		t.Errorf("Expected the image text to be removed, got %q", *note.ImageText)
+	}
+	if _, err := service.AddImage(ctx, userID, noteID, testPNG); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected images of private notes to be refused, got %v", err)
+	}
+
+	// Image text can be removed
+	public := false
+	if _, err := noteService.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{Private: &public}); err != nil {
+		t.Fatalf("Failed to make the note public: %v", err)
+	}
+	recognizer.text = "Sketch"
+	if _, err := service.AddImage(ctx, userID, noteID, testPNG); err != nil {
+		t.Fatalf("Failed to add image: %v", err)
+	}
+	note, err = service.ClearImageText(ctx, userID, noteID)
+	if err != nil {
+		t.Fatalf("Failed to clear image text: %v", err)
+	}
+	if note.ImageText != nil {
+		t.Errorf("Expected no image text, got %q", *note.ImageText)
+	}
+}
@@ -0,0 +1,551 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/importer"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// importBatchSize is the number of notes created per BatchCreateNotes call
+const importBatchSize = 50
+
+// importSampleRows is the number of rows returned to preview a session
+const importSampleRows = 5
+
+// Default limits for streamed archive imports
+const (
+	defaultArchiveMaxBytes = 50 << 20
+	defaultArchiveMaxNotes = 10000
+)
+
+// errArchiveTooLarge is returned by archiveSizeReader once the limit is passed
+var errArchiveTooLarge = errors.New("archive too large")
+
+// ImportServiceInterface defines the interface for the import wizard
+type ImportServiceInterface interface {
+	CreateImportSession(ctx context.Context, userID, filename string, data []byte) (*models.ImportSession, error)
+	GetImportSession(ctx context.Context, userID, sessionID string) (*models.ImportSession, error)
+	SubmitImportMapping(ctx context.Context, userID, sessionID string, mapping *importer.Mapping) (*models.ImportSession, error)
+	ExecuteImport(ctx context.Context, userID, sessionID string) (*models.ImportSession, error)
+	ImportArchive(ctx context.Context, userID, filename string, r io.Reader) (*models.ImportSession, error)
+	ImportVault(ctx context.Context, userID, filename string, r io.Reader) (*models.ImportSession, error)
+}
+
+// ImportService imports spreadsheet dumps as notes in three steps: the upload
+// is parsed and previewed, the client maps columns to note fields, and the
+// validated rows are created as notes. JSON archives and Markdown vaults skip
+// the mapping and are imported in a single step.
+type ImportService struct {
+	db              *sql.DB
+	noteService     NoteServiceInterface
+	linkListener    NoteWriteListener
+	archiveMaxBytes int64
+	archiveMaxNotes int
+	// maxContentLength is the content limit of imported notes, in characters
+	maxContentLength int
+}
+
+// NewImportService creates a new ImportService
+func NewImportService(db *sql.DB, noteService NoteServiceInterface) *ImportService {
+	return &ImportService{
+		db:               db,
+		noteService:      noteService,
+		archiveMaxBytes:  defaultArchiveMaxBytes,
+		archiveMaxNotes:  defaultArchiveMaxNotes,
+		maxContentLength: models.DefaultMaxContentLength,
+	}
+}
+
+// SetArchiveLimits sets the maximum size in bytes and note count of archive imports
+func (s *ImportService) SetArchiveLimits(maxBytes int64, maxNotes int) {
+	s.archiveMaxBytes = maxBytes
+	s.archiveMaxNotes = maxNotes
+}
+
+// SetMaxContentLength sets the content limit of imported notes, in
+// characters, matching the limit of notes created through the API
+func (s *ImportService) SetMaxContentLength(n int) {
+	s.maxContentLength = n
+}
+
+// SetLinkListener sets the listener that rebuilds the links of imported vault
+// notes once all of them exist
+func (s *ImportService) SetLinkListener(listener NoteWriteListener) {
+	s.linkListener = listener
+}
+
+// importSessionColumns lists the import_sessions columns in scanImportSession order
+const importSessionColumns = "id, user_id, status, filename, format, content, columns, row_count, mapping, result, created_at, updated_at, expires_at"
+
+// scanImportSession scans a row selected with importSessionColumns, returning the raw file content
+func scanImportSession(row rowScanner, session *models.ImportSession) (string, error) {
+	var content string
+	var mapping, result []byte
+	err := row.Scan(&session.ID, &session.UserID, &session.Status, &session.Filename, &session.Format,
+		&content, pq.Array(&session.Columns), &session.RowCount, &mapping, &result,
+		&session.CreatedAt, &session.UpdatedAt, &session.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	if mapping != nil {
+		if err := json.Unmarshal(mapping, &session.Mapping); err != nil {
+			return "", fmt.Errorf("failed to decode import mapping: %w", err)
+		}
+	}
+	if result != nil {
+		if err := json.Unmarshal(result, &session.Result); err != nil {
+			return "", fmt.Errorf("failed to decode import result: %w", err)
+		}
+	}
+	return content, nil
+}
+
+// CreateImportSession parses an uploaded file and stores it until the client
+// submits a mapping. The session is returned with sample rows and a suggested mapping.
+func (s *ImportService) CreateImportSession(ctx context.Context, userID, filename string, data []byte) (*models.ImportSession, error) {
+	format, err := detectImportFormat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := importer.ParseCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.ImportSession{}
+	query := `
+		INSERT INTO import_sessions (user_id, status, filename, format, content, columns, row_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + importSessionColumns
+
+	_, err = scanImportSession(s.db.QueryRowContext(ctx, query,
+		userID, models.ImportStatusPending, filepath.Base(filename), format, string(data),
+		pq.Array(table.Columns), len(table.Rows)), session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import session: %w", err)
+	}
+
+	addImportPreview(session, table)
+	return session, nil
+}
+
+// GetImportSession retrieves an unexpired import session for a specific user
+func (s *ImportService) GetImportSession(ctx context.Context, userID, sessionID string) (*models.ImportSession, error) {
+	session, table, err := s.loadImportSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if table != nil {
+		addImportPreview(session, table)
+	}
+	return session, nil
+}
+
+// SubmitImportMapping validates a column mapping against every row and stores
+// it. Row problems are reported in the validation without rejecting the mapping;
+// those rows are skipped when the import is executed.
+func (s *ImportService) SubmitImportMapping(ctx context.Context, userID, sessionID string, mapping *importer.Mapping) (*models.ImportSession, error) {
+	session, table, err := s.loadImportSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.ImportStatusCompleted || session.Status == models.ImportStatusFailed {
+		return nil, fmt.Errorf("import has already been executed")
+	}
+
+	records, rowErrors, err := table.Apply(*mapping, s.maxContentLength)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no rows can be imported with this mapping")
+	}
+
+	payload, err := json.Marshal(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import mapping: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE import_sessions SET status = $1, mapping = $2
+		WHERE id = $3 AND user_id = $4
+	`, models.ImportStatusMapped, payload, sessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save import mapping: %w", err)
+	}
+
+	session.Status = models.ImportStatusMapped
+	session.Mapping = mapping
+	session.Validation = &models.ImportValidation{
+		ValidRows: len(records),
+		Errors:    nonNilRowErrors(rowErrors),
+		Preview:   records[:min(len(records), importSampleRows)],
+	}
+	return session, nil
+}
+
+// ExecuteImport creates notes from the valid rows of a mapped session. Notes
+// are created in batches; if a batch fails, the notes from earlier batches are
+// kept and the session is marked failed. The uploaded file is discarded afterwards.
+func (s *ImportService) ExecuteImport(ctx context.Context, userID, sessionID string) (*models.ImportSession, error) {
+	session, table, err := s.loadImportSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	switch session.Status {
+	case models.ImportStatusPending:
+		return nil, fmt.Errorf("import session has no mapping")
+	case models.ImportStatusCompleted, models.ImportStatusFailed:
+		return nil, fmt.Errorf("import has already been executed")
+	}
+
+	records, rowErrors, err := table.Apply(*session.Mapping, s.maxContentLength)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportResult{
+		Skipped: len(rowErrors),
+		Errors:  nonNilRowErrors(rowErrors),
+	}
+	status := models.ImportStatusCompleted
+
+	for start := 0; start < len(records); start += importBatchSize {
+		batch := records[start:min(start+importBatchSize, len(records))]
+		requests := make([]*models.CreateNoteRequest, len(batch))
+		for i, record := range batch {
+			requests[i] = &models.CreateNoteRequest{
+				Title:     record.Title,
+				Content:   record.Content,
+				CreatedAt: record.CreatedAt,
+			}
+		}
+
+		if _, err := s.noteService.BatchCreateNotes(ctx, userID, requests); err != nil {
+			status = models.ImportStatusFailed
+			result.Message = fmt.Sprintf("import stopped at row %d: %v", batch[0].Row, err)
+			break
+		}
+		result.Created += len(batch)
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import result: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE import_sessions SET status = $1, result = $2, content = ''
+		WHERE id = $3 AND user_id = $4
+	`, status, payload, sessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save import result: %w", err)
+	}
+
+	session.Status = status
+	session.Result = result
+	return session, nil
+}
+
+// ImportArchive streams notes out of a JSON archive and creates them in
+// batches as they are read, so the archive is never held in memory. Invalid
+// notes are skipped and reported. When the archive is larger than the size
+// or note limit, or turns out to be malformed, the import stops early: notes
+// read up to that point are kept and the session is marked failed with a
+// message saying where it stopped.
+func (s *ImportService) ImportArchive(ctx context.Context, userID, filename string, r io.Reader) (*models.ImportSession, error) {
+	reader := importer.NewArchiveReader(&archiveSizeReader{r: r, remaining: s.archiveMaxBytes}, s.maxContentLength)
+	result := &models.ImportResult{Errors: []importer.RowError{}}
+	status := models.ImportStatusCompleted
+
+	var batch []importer.Record
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		requests := make([]*models.CreateNoteRequest, len(batch))
+		for i, record := range batch {
+			requests[i] = &models.CreateNoteRequest{
+				Title:     record.Title,
+				Content:   record.Content,
+				Color:     record.Color,
+				Icon:      record.Icon,
+				CreatedAt: record.CreatedAt,
+			}
+		}
+
+		first := batch[0].Row
+		created := len(batch)
+		batch = batch[:0]
+		if _, err := s.noteService.BatchCreateNotes(ctx, userID, requests); err != nil {
+			return fmt.Errorf("import stopped at note %d: %v", first, err)
+		}
+		result.Created += created
+		return nil
+	}
+
+	var stopErr error
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if reader.Count() > s.archiveMaxNotes {
+			stopErr = fmt.Errorf("import stopped at note %d: archives are limited to %d notes", reader.Count(), s.archiveMaxNotes)
+			break
+		}
+		if err == nil {
+			err = checkNoteStyle(record)
+		}
+
+		var rowErr importer.RowError
+		if errors.As(err, &rowErr) {
+			result.Skipped++
+			result.Errors = append(result.Errors, rowErr)
+			continue
+		}
+		if errors.Is(err, errArchiveTooLarge) {
+			err = fmt.Errorf("archive exceeds the %dMB limit", s.archiveMaxBytes>>20)
+		}
+		if err != nil {
+			// An archive that fails before its first note is rejected outright
+			if reader.Count() <= 1 && len(batch) == 0 && result.Skipped == 0 {
+				return nil, err
+			}
+			stopErr = fmt.Errorf("import stopped at note %d: %v", reader.Count(), err)
+			break
+		}
+
+		batch = append(batch, record)
+		if len(batch) == importBatchSize {
+			if stopErr = flush(); stopErr != nil {
+				break
+			}
+		}
+	}
+	// Notes read before the import stopped early are still created
+	if err := flush(); err != nil && stopErr == nil {
+		stopErr = err
+	}
+	if stopErr != nil {
+		status = models.ImportStatusFailed
+		result.Message = stopErr.Error()
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import result: %w", err)
+	}
+
+	session := &models.ImportSession{}
+	query := `
+		INSERT INTO import_sessions (user_id, status, filename, format, content, row_count, result)
+		VALUES ($1, $2, $3, $4, '', $5, $6)
+		RETURNING ` + importSessionColumns
+
+	_, err = scanImportSession(s.db.QueryRowContext(ctx, query,
+		userID, status, filepath.Base(filename), models.ImportFormatJSON, reader.Count(), payload), session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save import result: %w", err)
+	}
+
+	return session, nil
+}
+
+// ImportVault imports a ZIP of Markdown files, such as an Obsidian vault.
+// The archive is held in memory, so it is subject to the archive size and
+// note limits. Notes are created in batches with the IDs their links were
+// rewritten to; if a batch fails, notes from earlier batches are kept and the
+// session is marked failed.
+func (s *ImportService) ImportVault(ctx context.Context, userID, filename string, r io.Reader) (*models.ImportSession, error) {
+	data, err := io.ReadAll(&archiveSizeReader{r: r, remaining: s.archiveMaxBytes})
+	if errors.Is(err, errArchiveTooLarge) {
+		return nil, fmt.Errorf("archive exceeds the %dMB limit", s.archiveMaxBytes>>20)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read vault archive: %w", err)
+	}
+
+	notes, rowErrors, err := importer.ReadVault(bytes.NewReader(data), int64(len(data)), s.archiveMaxNotes, s.maxContentLength)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportResult{
+		Skipped: len(rowErrors),
+		Errors:  nonNilRowErrors(rowErrors),
+	}
+	status := models.ImportStatusCompleted
+
+	var linked []*models.Note
+	for start := 0; start < len(notes); start += importBatchSize {
+		batch := notes[start:min(start+importBatchSize, len(notes))]
+		requests := make([]*models.CreateNoteRequest, len(batch))
+		for i, note := range batch {
+			requests[i] = &models.CreateNoteRequest{
+				Title:     note.Title,
+				Content:   note.Content,
+				CreatedAt: note.CreatedAt,
+				ID:        &note.ID,
+			}
+		}
+
+		created, err := s.noteService.BatchCreateNotes(ctx, userID, requests)
+		if err != nil {
+			status = models.ImportStatusFailed
+			result.Message = fmt.Sprintf("import stopped at %s: %v", batch[0].Path, err)
+			break
+		}
+		result.Created += len(batch)
+		for i := range created {
+			if strings.Contains(created[i].Content, "note://") {
+				linked = append(linked, &created[i])
+			}
+		}
+	}
+
+	// Links are only stored when their target exists, so links to notes of
+	// later batches are rebuilt now that every note has been created
+	if s.linkListener != nil && len(notes) > importBatchSize {
+		for _, note := range linked {
+			s.linkListener.NoteWritten(ctx, note)
+		}
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import result: %w", err)
+	}
+
+	session := &models.ImportSession{}
+	query := `
+		INSERT INTO import_sessions (user_id, status, filename, format, content, row_count, result)
+		VALUES ($1, $2, $3, $4, '', $5, $6)
+		RETURNING ` + importSessionColumns
+
+	_, err = scanImportSession(s.db.QueryRowContext(ctx, query,
+		userID, status, filepath.Base(filename), models.ImportFormatVault, len(notes)+len(rowErrors), payload), session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save import result: %w", err)
+	}
+
+	return session, nil
+}
+
+// CleanupExpiredSessions removes import sessions past their expiry
+func (s *ImportService) CleanupExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM import_sessions
+		WHERE expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired import sessions: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// loadImportSession fetches a session and re-parses its file. The table is nil
+// once the import has been executed and the file discarded.
+func (s *ImportService) loadImportSession(ctx context.Context, userID, sessionID string) (*models.ImportSession, *importer.Table, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, nil, fmt.Errorf("import session not found")
+	}
+
+	session := &models.ImportSession{}
+	content, err := scanImportSession(s.db.QueryRowContext(ctx, `
+		SELECT `+importSessionColumns+`
+		FROM import_sessions
+		WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+	`, sessionID, userID), session)
+
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("import session not found")
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to get import session: %w", err)
+	}
+
+	if content == "" {
+		return session, nil, nil
+	}
+
+	table, err := importer.ParseCSV([]byte(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse import file: %w", err)
+	}
+	return session, table, nil
+}
+
+// detectImportFormat returns the import format for a filename. Files without
+// an extension are treated as CSV.
+func detectImportFormat(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case "", ".csv", ".tsv", ".txt":
+		return models.ImportFormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported import format %q: only CSV and TSV files are supported", filepath.Ext(filename))
+	}
+}
+
+// addImportPreview fills the sample rows and suggested mapping of a session
+func addImportPreview(session *models.ImportSession, table *importer.Table) {
+	session.SampleRows = table.Sample(importSampleRows)
+	suggested := table.SuggestMapping()
+	session.SuggestedMapping = &suggested
+}
+
+// nonNilRowErrors returns an empty slice instead of nil so row errors encode as []
+func nonNilRowErrors(rowErrors []importer.RowError) []importer.RowError {
+	if rowErrors == nil {
+		return []importer.RowError{}
+	}
+	return rowErrors
+}
+
+// archiveSizeReader fails with errArchiveTooLarge once more than remaining
+// bytes have been read
+type archiveSizeReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *archiveSizeReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, errArchiveTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, errArchiveTooLarge
+	}
+	return n, err
+}
+
+// checkNoteStyle reports an archived note whose color or icon fails note
+// validation, so the note is skipped rather than failing its whole batch
+func checkNoteStyle(record importer.Record) error {
+	if err := models.ValidateNoteColor(models.NormalizeNoteColor(record.Color)); err != nil {
+		return importer.RowError{Row: record.Row, Column: "color", Message: err.Error()}
+	}
+	if err := models.ValidateNoteIcon(record.Icon); err != nil {
+		return importer.RowError{Row: record.Row, Column: "icon", Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// InboundEmailServiceInterface defines the interface for turning emails into
+// notes
+type InboundEmailServiceInterface interface {
+	GetAddress(ctx context.Context, userID string) (*models.InboundAddress, error)
+	RotateAddress(ctx context.Context, userID string) (*models.InboundAddress, error)
+	DisableAddress(ctx context.Context, userID string) error
+	ReceiveEmail(ctx context.Context, email *models.InboundEmail) (*models.Note, error)
+}
+
+// Inbound email errors
+var (
+	ErrInboundAddressNotFound = apperrors.NotFound("INBOUND_ADDRESS_NOT_FOUND", "no inbound address matches the recipients")
+	errInboundAccountLocked   = apperrors.New(apperrors.ErrForbidden, "ACCOUNT_READ_ONLY", "account is temporarily read-only")
+	errInboundEmailDisabled   = apperrors.Validation("INBOUND_EMAIL_DISABLED", "email-to-note is not configured on this server")
+)
+
+// codeInvalidEmail is the error code of emails that cannot become notes
+const codeInvalidEmail = "INVALID_EMAIL"
+
+// InboundEmailService gives users a secret address at the inbound domain.
+// Emails the mail provider receives for an address become notes of its user:
+// the subject is the title and the body the content.
+type InboundEmailService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+	domain      string
+}
+
+// NewInboundEmailService creates a new InboundEmailService. An empty domain
+// disables inbound addresses.
+func NewInboundEmailService(db *sql.DB, noteService NoteServiceInterface, domain string) *InboundEmailService {
+	return &InboundEmailService{
+		db:          db,
+		noteService: noteService,
+		domain:      strings.ToLower(domain),
+	}
+}
+
+// GetAddress returns the user's inbound address, creating it on first use
+func (s *InboundEmailService) GetAddress(ctx context.Context, userID string) (*models.InboundAddress, error) {
+	if s.domain == "" {
+		return nil, errInboundEmailDisabled
+	}
+
+	token, err := generateInboundToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate inbound address: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO inbound_email_addresses (user_id, token) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID, token); err != nil {
+		return nil, fmt.Errorf("failed to create inbound address: %w", err)
+	}
+
+	return s.getAddress(ctx, userID)
+}
+
+// RotateAddress replaces the user's inbound address with a new one; mail to
+// the old address is no longer accepted
+func (s *InboundEmailService) RotateAddress(ctx context.Context, userID string) (*models.InboundAddress, error) {
+	if s.domain == "" {
+		return nil, errInboundEmailDisabled
+	}
+
+	token, err := generateInboundToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate inbound address: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO inbound_email_addresses (user_id, token) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = NOW()
+	`, userID, token); err != nil {
+		return nil, fmt.Errorf("failed to rotate inbound address: %w", err)
+	}
+
+	return s.getAddress(ctx, userID)
+}
+
+// DisableAddress removes the user's inbound address. GetAddress creates a
+// new one.
+func (s *InboundEmailService) DisableAddress(ctx context.Context, userID string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM inbound_email_addresses WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable inbound address: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrInboundAddressNotFound
+	}
+	return nil
+}
+
+// getAddress returns the stored inbound address of a user
+func (s *InboundEmailService) getAddress(ctx context.Context, userID string) (*models.InboundAddress, error) {
+	var token string
+	var address models.InboundAddress
+	err := s.db.QueryRowContext(ctx, `
+		SELECT token, created_at FROM inbound_email_addresses WHERE user_id = $1
+	`, userID).Scan(&token, &address.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInboundAddressNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound address: %w", err)
+	}
+
+	address.Address = token + "@" + s.domain
+	return &address, nil
+}
+
+// ReceiveEmail creates a note from an email for the first recipient that is
+// an inbound address. Subaddresses, such as token+work@domain, reach the
+// same user.
+func (s *InboundEmailService) ReceiveEmail(ctx context.Context, email *models.InboundEmail) (*models.Note, error) {
+	if s.domain == "" {
+		return nil, errInboundEmailDisabled
+	}
+
+	userID, err := s.recipientUser(ctx, email.Recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := email.ToCreateNoteRequest()
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidEmail, err)
+	}
+
+	return s.noteService.CreateNote(ctx, userID, request)
+}
+
+// recipientUser returns the user of the first recipient that is an inbound
+// address of a writable account
+func (s *InboundEmailService) recipientUser(ctx context.Context, recipients []string) (string, error) {
+	for _, recipient := range recipients {
+		addresses, err := mail.ParseAddressList(recipient)
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			token, ok := s.inboundToken(address.Address)
+			if !ok {
+				continue
+			}
+
+			var userID string
+			var readOnlyUntil sql.NullTime
+			err := s.db.QueryRowContext(ctx, `
+				SELECT u.id, u.read_only_until
+				FROM inbound_email_addresses a
+				JOIN users u ON u.id = a.user_id
+				WHERE a.token = $1 AND u.disabled_at IS NULL
+			`, token).Scan(&userID, &readOnlyUntil)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to find inbound address: %w", err)
+			}
+			if readOnlyUntil.Valid && readOnlyUntil.Time.After(time.Now()) {
+				return "", errInboundAccountLocked
+			}
+			return userID, nil
+		}
+	}
+	return "", ErrInboundAddressNotFound
+}
+
+// inboundToken returns the token of an address at the inbound domain
+func (s *InboundEmailService) inboundToken(address string) (string, bool) {
+	local, domain, ok := strings.Cut(strings.ToLower(address), "@")
+	if !ok || domain != s.domain {
+		return "", false
+	}
+	local, _, _ = strings.Cut(local, "+")
+	return local, local != ""
+}
+
+// generateInboundToken returns the random local part of a new inbound
+// address
+func generateInboundToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
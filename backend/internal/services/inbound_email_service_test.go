@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestInboundEmail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	service := NewInboundEmailService(db, noteService, "In.Example.com")
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	other := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	address, err := service.GetAddress(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to get inbound address: %v", err)
+	}
+	if !strings.HasSuffix(address.Address, "@in.example.com") {
+		t.Errorf("Unexpected address %s", address.Address)
+	}
+	again, err := service.GetAddress(ctx, userID)
+	if err != nil || again.Address != address.Address {
+		t.Errorf("Expected the same address, got %+v, %v", again, err)
+	}
+
+	// Display names, case and subaddresses do not matter
+	token, _, _ := strings.Cut(address.Address, "@")
+	note, err := service.ReceiveEmail(ctx, &models.InboundEmail{
+		Recipients: []string{"someone@example.com, Notes <" + strings.ToUpper(token) + "+Work@IN.example.com>"},
+		Subject:    "Groceries",
+		Body:       "Milk\nEggs",
+	})
+	if err != nil {
+		t.Fatalf("Failed to receive email: %v", err)
+	}
+	if note.UserID != user.ID || note.Title == nil || *note.Title != "Groceries" || note.Content != "Milk\nEggs" {
+		t.Errorf("Unexpected note %+v", note)
+	}
+
+	if _, err := service.ReceiveEmail(ctx, &models.InboundEmail{Recipients: []string{token + "@example.com"}, Body: "Hi"}); !errors.Is(err, ErrInboundAddressNotFound) {
+		t.Errorf("Expected other domains not found, got %v", err)
+	}
+	if _, err := service.ReceiveEmail(ctx, &models.InboundEmail{Recipients: []string{address.Address}}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected empty emails rejected, got %v", err)
+	}
+
+	// Rotating stops the old address
+	rotated, err := service.RotateAddress(ctx, userID)
+	if err != nil || rotated.Address == address.Address {
+		t.Fatalf("Expected a new address, got %+v, %v", rotated, err)
+	}
+	if _, err := service.ReceiveEmail(ctx, &models.InboundEmail{Recipients: []string{address.Address}, Body: "Hi"}); !errors.Is(err, ErrInboundAddressNotFound) {
+		t.Errorf("Expected the old address not found, got %v", err)
+	}
+
+	// Read-only accounts take no mail
+	if _, err := db.ExecContext(ctx, "UPDATE users SET read_only_until = $1 WHERE id = $2", time.Now().Add(time.Hour), userID); err != nil {
+		t.Fatalf("Failed to lock account: %v", err)
+	}
+	if _, err := service.ReceiveEmail(ctx, &models.InboundEmail{Recipients: []string{rotated.Address}, Body: "Hi"}); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("Expected locked accounts rejected, got %v", err)
+	}
+
+	if err := service.DisableAddress(ctx, userID); err != nil {
+		t.Fatalf("Failed to disable address: %v", err)
+	}
+	if err := service.DisableAddress(ctx, other.ID.String()); !errors.Is(err, ErrInboundAddressNotFound) {
+		t.Errorf("Expected no address to disable, got %v", err)
+	}
+	if _, err := NewInboundEmailService(db, noteService, "").GetAddress(ctx, userID); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected inbound email disabled without a domain, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrLegalHold is returned when deleting data that is under legal hold
+var ErrLegalHold = apperrors.Conflict("LEGAL_HOLD", "under legal hold")
+
+// ErrLegalHoldNotFound is returned for legal holds that do not exist
+var ErrLegalHoldNotFound = apperrors.NotFound("LEGAL_HOLD_NOT_FOUND", "legal hold not found")
+
+// LegalHoldChecker reports which data is protected by active legal holds
+type LegalHoldChecker interface {
+	// HeldNoteIDs returns the given notes of the user that are under hold
+	HeldNoteIDs(ctx context.Context, userID string, noteIDs []string) ([]string, error)
+	// HasActiveHold reports whether any of the user's data is under hold
+	HasActiveHold(ctx context.Context, userID string) (bool, error)
+}
+
+// LegalHoldServiceInterface defines the interface for managing legal holds
+type LegalHoldServiceInterface interface {
+	LegalHoldChecker
+	ApplyHold(ctx context.Context, actorID uuid.UUID, request *models.CreateLegalHoldRequest) (*models.LegalHold, error)
+	ReleaseHold(ctx context.Context, actorID uuid.UUID, holdID string, request *models.ReleaseLegalHoldRequest) (*models.LegalHold, error)
+	ListHolds(ctx context.Context, userID string, activeOnly bool) ([]models.LegalHold, error)
+	GetAuditLog(ctx context.Context, holdID string) ([]models.LegalHoldAuditEntry, error)
+}
+
+// LegalHoldService manages legal holds. Every change is recorded in an
+// append-only audit log.
+type LegalHoldService struct {
+	db *sql.DB
+}
+
+// NewLegalHoldService creates a new LegalHoldService
+func NewLegalHoldService(db *sql.DB) *LegalHoldService {
+	return &LegalHoldService{db: db}
+}
+
+// legalHoldColumns lists the columns scanned by scanLegalHold
+const legalHoldColumns = "id, user_id, note_id, reason, created_by, created_at, released_by, released_at"
+
+// scanLegalHold scans a row selected with legalHoldColumns
+func scanLegalHold(row rowScanner, h *models.LegalHold) error {
+	return row.Scan(&h.ID, &h.UserID, &h.NoteID, &h.Reason, &h.CreatedBy, &h.CreatedAt, &h.ReleasedBy, &h.ReleasedAt)
+}
+
+// ApplyHold places a user's notes, or one note, under legal hold
+func (s *LegalHoldService) ApplyHold(ctx context.Context, actorID uuid.UUID, request *models.CreateLegalHoldRequest) (*models.LegalHold, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, apperrors.Validation("INVALID_LEGAL_HOLD", "reason is required")
+	}
+
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	var noteID *uuid.UUID
+	if request.NoteID != nil && *request.NoteID != "" {
+		id, err := uuid.Parse(*request.NoteID)
+		if err != nil {
+			return nil, ErrNoteNotFound
+		}
+		noteID = &id
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if noteID != nil {
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM notes WHERE id = $1 AND user_id = $2)`,
+			*noteID, userID).Scan(&exists)
+	} else {
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check hold target: %w", err)
+	}
+	if !exists {
+		if noteID != nil {
+			return nil, ErrNoteNotFound
+		}
+		return nil, ErrUserNotFound
+	}
+
+	var hold models.LegalHold
+	err = scanLegalHold(tx.QueryRowContext(ctx, `
+		INSERT INTO legal_holds (user_id, note_id, reason, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+legalHoldColumns, userID, noteID, reason, actorID), &hold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create legal hold: %w", err)
+	}
+
+	if err := s.audit(ctx, tx, hold.ID, models.LegalHoldActionApplied, actorID, reason); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit legal hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// ReleaseHold releases an active legal hold
+func (s *LegalHoldService) ReleaseHold(ctx context.Context, actorID uuid.UUID, holdID string, request *models.ReleaseLegalHoldRequest) (*models.LegalHold, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, apperrors.Validation("INVALID_LEGAL_HOLD", "reason is required")
+	}
+
+	id, err := uuid.Parse(holdID)
+	if err != nil {
+		return nil, ErrLegalHoldNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hold models.LegalHold
+	err = scanLegalHold(tx.QueryRowContext(ctx, `
+		SELECT `+legalHoldColumns+` FROM legal_holds WHERE id = $1 FOR UPDATE
+	`, id), &hold)
+	if err == sql.ErrNoRows {
+		return nil, ErrLegalHoldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	if !hold.IsActive() {
+		return nil, apperrors.Conflict("LEGAL_HOLD_RELEASED", "legal hold has already been released")
+	}
+
+	err = scanLegalHold(tx.QueryRowContext(ctx, `
+		UPDATE legal_holds
+		SET released_by = $2, released_at = NOW()
+		WHERE id = $1
+		RETURNING `+legalHoldColumns, id, actorID), &hold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	if err := s.audit(ctx, tx, hold.ID, models.LegalHoldActionReleased, actorID, reason); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit legal hold release: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// audit appends an entry to the legal hold audit log
+func (s *LegalHoldService) audit(ctx context.Context, tx *sql.Tx, holdID uuid.UUID, action string, actorID uuid.UUID, reason string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO legal_hold_audit (hold_id, action, actor_id, reason)
+		VALUES ($1, $2, $3, $4)
+	`, holdID, action, actorID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record legal hold audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListHolds returns legal holds, newest first, optionally for one user only
+func (s *LegalHoldService) ListHolds(ctx context.Context, userID string, activeOnly bool) ([]models.LegalHold, error) {
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return nil, apperrors.Validation("INVALID_USER_ID", "invalid user ID")
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+legalHoldColumns+`
+		FROM legal_holds
+		WHERE ($1 = '' OR user_id = NULLIF($1, '')::uuid)
+		  AND (NOT $2::boolean OR released_at IS NULL)
+		ORDER BY created_at DESC
+	`, userID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := []models.LegalHold{}
+	for rows.Next() {
+		var hold models.LegalHold
+		if err := scanLegalHold(rows, &hold); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold: %w", err)
+		}
+		holds = append(holds, hold)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating legal holds: %w", err)
+	}
+
+	return holds, nil
+}
+
+// GetAuditLog returns the audit entries of a legal hold, oldest first
+func (s *LegalHoldService) GetAuditLog(ctx context.Context, holdID string) ([]models.LegalHoldAuditEntry, error) {
+	id, err := uuid.Parse(holdID)
+	if err != nil {
+		return nil, ErrLegalHoldNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, hold_id, action, actor_id, reason, created_at
+		FROM legal_hold_audit
+		WHERE hold_id = $1
+		ORDER BY created_at ASC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LegalHoldAuditEntry{}
+	for rows.Next() {
+		var entry models.LegalHoldAuditEntry
+		if err := rows.Scan(&entry.ID, &entry.HoldID, &entry.Action, &entry.ActorID, &entry.Reason, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating legal hold audit log: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrLegalHoldNotFound
+	}
+
+	return entries, nil
+}
+
+// HeldNoteIDs returns the given notes of the user that are under hold, either
+// individually or through a hold on all of the user's notes
+func (s *LegalHoldService) HeldNoteIDs(ctx context.Context, userID string, noteIDs []string) ([]string, error) {
+	if len(noteIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT n.id::text
+		FROM unnest($2::uuid[]) AS n(id)
+		WHERE EXISTS (
+			SELECT 1 FROM legal_holds h
+			WHERE h.user_id = $1 AND h.released_at IS NULL
+			  AND (h.note_id IS NULL OR h.note_id = n.id)
+		)
+	`, userID, pq.Array(noteIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	var held []string
+	for rows.Next() {
+		var noteID string
+		if err := rows.Scan(&noteID); err != nil {
+			return nil, fmt.Errorf("failed to scan held note: %w", err)
+		}
+		held = append(held, noteID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating held notes: %w", err)
+	}
+
+	return held, nil
+}
+
+// HasActiveHold reports whether any of the user's data is under hold
+func (s *LegalHoldService) HasActiveHold(ctx context.Context, userID string) (bool, error) {
+	var held bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM legal_holds WHERE user_id = $1 AND released_at IS NULL)
+	`, userID).Scan(&held)
+	if err != nil {
+		return false, fmt.Errorf("failed to check legal holds: %w", err)
+	}
+	return held, nil
+}
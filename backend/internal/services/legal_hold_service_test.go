@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeLegalHolds holds the listed notes, or every note of a held user
+type fakeLegalHolds struct {
+	heldNotes map[string]bool
+	heldUser  bool
+}
+
+func (f fakeLegalHolds) HeldNoteIDs(ctx context.Context, userID string, noteIDs []string) ([]string, error) {
+	var held []string
+	for _, noteID := range noteIDs {
+		if f.heldUser || f.heldNotes[noteID] {
+			held = append(held, noteID)
+		}
+	}
+	return held, nil
+}
+
+func (f fakeLegalHolds) HasActiveHold(ctx context.Context, userID string) (bool, error) {
+	return f.heldUser || len(f.heldNotes) > 0, nil
+}
+
+func TestBatchDeleteNotesRejectsHeldNotes(t *testing.T) {
+	held := uuid.New().String()
+
+	// The hold check runs before the database is touched
+	service := NewNoteService(nil, nil)
+	service.SetLegalHolds(fakeLegalHolds{heldNotes: map[string]bool{held: true}})
+
+	deleted, err := service.BatchDeleteNotes(context.Background(), uuid.New().String(), []string{uuid.New().String(), held})
+	if !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("Expected legal hold error, got %v", err)
+	}
+	if err.Error() != "1 of the notes are under legal hold" {
+		t.Errorf("Unexpected error message %q", err.Error())
+	}
+	if deleted != 0 {
+		t.Errorf("Expected no notes deleted, got %d", deleted)
+	}
+}
+
+func TestDeleteAccountRejectedUnderLegalHold(t *testing.T) {
+	service := NewUserService(nil)
+	service.SetLegalHolds(fakeLegalHolds{heldUser: true})
+
+	err := service.Delete(context.Background(), uuid.New().String())
+	if !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("Expected legal hold error, got %v", err)
+	}
+	if err.Error() != "account is under legal hold" {
+		t.Errorf("Unexpected error message %q", err.Error())
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// LinkServiceInterface defines the interface for note link operations
+type LinkServiceInterface interface {
+	GetBacklinks(ctx context.Context, userID, noteID string) ([]models.Backlink, error)
+	GetGraph(ctx context.Context, userID string, includeOrphans bool) (*models.NoteGraph, error)
+}
+
+// LinkService maintains the links between notes. Links are rebuilt from note
+// content whenever a note is written; [[wiki]] links are stored by title and
+// resolved when read, so they follow renames and start working once a note
+// with the title exists.
+type LinkService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+}
+
+// NewLinkService creates a new LinkService
+func NewLinkService(db *sql.DB, noteService NoteServiceInterface) *LinkService {
+	return &LinkService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// NoteWritten implements NoteWriteListener by replacing the note's outgoing
+// links. Private notes keep no links so their content is not exposed.
+func (s *LinkService) NoteWritten(ctx context.Context, note *models.Note) {
+	if err := s.replaceLinks(ctx, note); err != nil {
+		log.Printf("[LinkService] WARNING: failed to update links for note %s: %v", note.ID, err)
+	}
+}
+
+// replaceLinks stores the links found in a note's content
+func (s *LinkService) replaceLinks(ctx context.Context, note *models.Note) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM note_links WHERE source_note_id = $1`, note.ID); err != nil {
+		return fmt.Errorf("failed to clear links: %w", err)
+	}
+
+	var targetIDs []string
+	var targetTitles []string
+	if !note.IsPrivate && !note.Locked {
+		for _, link := range note.ExtractLinks() {
+			if link.TargetID != nil {
+				targetIDs = append(targetIDs, link.TargetID.String())
+			} else {
+				targetTitles = append(targetTitles, link.TargetTitle)
+			}
+		}
+	}
+
+	// Links by ID only count when the target belongs to the same user
+	if len(targetIDs) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO note_links (source_note_id, target_note_id)
+			SELECT $1, id FROM notes
+			WHERE user_id = $2 AND id = ANY($3::uuid[])
+		`, note.ID, note.UserID, pq.Array(targetIDs))
+		if err != nil {
+			return fmt.Errorf("failed to store links: %w", err)
+		}
+	}
+
+	if len(targetTitles) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO note_links (source_note_id, target_title)
+			SELECT $1, UNNEST($2::text[])
+		`, note.ID, pq.Array(targetTitles))
+		if err != nil {
+			return fmt.Errorf("failed to store links: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBacklinks returns the notes linking to a note, most recently updated first
+func (s *LinkService) GetBacklinks(ctx context.Context, userID, noteID string) ([]models.Backlink, error) {
+	note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT n.id, n.title, n.updated_at
+		FROM note_links l
+		JOIN notes n ON n.id = l.source_note_id
+		WHERE n.user_id = $1 AND n.id <> $2
+			AND (l.target_note_id = $2 OR LOWER(l.target_title) = LOWER($3::text))
+		ORDER BY n.updated_at DESC
+	`, userID, note.ID, note.Title)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backlinks: %w", err)
+	}
+	defer rows.Close()
+
+	backlinks := []models.Backlink{}
+	for rows.Next() {
+		var backlink models.Backlink
+		if err := rows.Scan(&backlink.NoteID, &backlink.Title, &backlink.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan backlink: %w", err)
+		}
+		backlinks = append(backlinks, backlink)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating backlinks: %w", err)
+	}
+
+	return backlinks, nil
+}
+
+// GetGraph returns a user's link graph. Notes without links are only
+// included when includeOrphans is set.
+func (s *LinkService) GetGraph(ctx context.Context, userID string, includeOrphans bool) (*models.NoteGraph, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT l.source_note_id, COALESCE(l.target_note_id, t.id)
+		FROM note_links l
+		JOIN notes src ON src.id = l.source_note_id AND src.user_id = $1
+		LEFT JOIN notes t ON l.target_note_id IS NULL
+			AND t.user_id = $1 AND LOWER(t.title) = LOWER(l.target_title)
+		WHERE COALESCE(l.target_note_id, t.id) IS NOT NULL
+			AND COALESCE(l.target_note_id, t.id) <> l.source_note_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link graph: %w", err)
+	}
+	defer rows.Close()
+
+	graph := &models.NoteGraph{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	linked := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var edge models.GraphEdge
+		if err := rows.Scan(&edge.Source, &edge.Target); err != nil {
+			return nil, fmt.Errorf("failed to scan link: %w", err)
+		}
+		graph.Edges = append(graph.Edges, edge)
+		linked[edge.Source] = true
+		linked[edge.Target] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating links: %w", err)
+	}
+
+	nodeIDs := make([]string, 0, len(linked))
+	for id := range linked {
+		nodeIDs = append(nodeIDs, id.String())
+	}
+
+	nodeRows, err := s.db.QueryContext(ctx, `
+		SELECT id, title
+		FROM notes
+		WHERE user_id = $1 AND ($2::boolean OR id = ANY($3::uuid[]))
+		ORDER BY created_at
+	`, userID, includeOrphans, pq.Array(nodeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph notes: %w", err)
+	}
+	defer nodeRows.Close()
+
+	for nodeRows.Next() {
+		var node models.GraphNode
+		if err := nodeRows.Scan(&node.ID, &node.Title); err != nil {
+			return nil, fmt.Errorf("failed to scan graph note: %w", err)
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	if err = nodeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph notes: %w", err)
+	}
+
+	return graph, nil
+}
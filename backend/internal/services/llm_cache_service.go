@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// LLMCache stores LLM responses by a hash of the content they were generated
+// from
+type LLMCache interface {
+	Get(ctx context.Context, userID, feature, contentHash string) (string, bool, error)
+	Put(ctx context.Context, entry *LLMCacheEntry) error
+}
+
+// LLMCacheEntry is an LLM response and the content it was generated from
+type LLMCacheEntry struct {
+	UserID     string
+	Feature    string
+	NoteID     *uuid.UUID // note the response was generated from, if any
+	SourceHash string     // ContentHash of the content sent to the LLM
+	ResultHash string     // ContentHash of the note content saved from the response, if any
+	Response   string
+}
+
+// ContentHash returns the hex SHA-256 of the given parts. Parts are length
+// prefixed, so moving text from one part to the next changes the hash.
+func ContentHash(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// noteContentHash returns the ContentHash of a note's title and content
+func noteContentHash(title *string, content string) string {
+	if title == nil {
+		return ContentHash("", content)
+	}
+	return ContentHash(*title, content)
+}
+
+// LLMCacheService caches LLM responses in the database for ttl. A response
+// is returned for content matching either the content it was generated from
+// or the note content saved from it, so repeating an operation on content it
+// already produced is answered from the cache too. Edits to a note invalidate
+// the responses generated from it.
+type LLMCacheService struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewLLMCacheService creates a new LLMCacheService
+func NewLLMCacheService(db *sql.DB, ttl time.Duration) *LLMCacheService {
+	return &LLMCacheService{db: db, ttl: ttl}
+}
+
+// Get returns the cached response for content with the given hash
+func (s *LLMCacheService) Get(ctx context.Context, userID, feature, contentHash string) (string, bool, error) {
+	var response string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT response FROM llm_cache
+		WHERE user_id = $1 AND feature = $2 AND (source_hash = $3 OR result_hash = $3) AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		userID, feature, contentHash).Scan(&response)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get cached LLM response: %w", err)
+	}
+	return response, true, nil
+}
+
+// Put caches a response, replacing the one generated from the same content
+func (s *LLMCacheService) Put(ctx context.Context, entry *LLMCacheEntry) error {
+	var resultHash sql.NullString
+	if entry.ResultHash != "" {
+		resultHash = sql.NullString{String: entry.ResultHash, Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO llm_cache (user_id, feature, note_id, source_hash, result_hash, response, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, feature, source_hash) DO UPDATE SET
+			note_id = EXCLUDED.note_id,
+			result_hash = EXCLUDED.result_hash,
+			response = EXCLUDED.response,
+			created_at = NOW(),
+			expires_at = EXCLUDED.expires_at`,
+		entry.UserID, entry.Feature, entry.NoteID, entry.SourceHash, resultHash, entry.Response, time.Now().Add(s.ttl))
+	if err != nil {
+		return fmt.Errorf("failed to cache LLM response: %w", err)
+	}
+	return nil
+}
+
+// NoteWritten invalidates the responses generated from a note once its
+// content no longer matches them. Responses of private notes are dropped, as
+// they hold the note's text unencrypted.
+func (s *LLMCacheService) NoteWritten(ctx context.Context, note *models.Note) {
+	var err error
+	if note.IsPrivate {
+		_, err = s.db.ExecContext(ctx, "DELETE FROM llm_cache WHERE note_id = $1", note.ID)
+	} else {
+		_, err = s.db.ExecContext(ctx, `
+			DELETE FROM llm_cache
+			WHERE note_id = $1 AND source_hash <> $2 AND (result_hash IS NULL OR result_hash <> $2)`,
+			note.ID, noteContentHash(note.Title, note.Content))
+	}
+	if err != nil {
+		log.Printf("[LLMCacheService] WARNING: Failed to invalidate cached responses of note %s: %v", note.ID, err)
+	}
+}
+
+// InvalidateFeatures removes the cached responses of the given features, for
+// when their prompts change
+func (s *LLMCacheService) InvalidateFeatures(ctx context.Context, features ...string) (int64, error) {
+	if len(features) == 0 {
+		return 0, nil
+	}
+	result, err := s.db.ExecContext(ctx, "DELETE FROM llm_cache WHERE feature = ANY($1)", pq.Array(features))
+	if err != nil {
+		return 0, fmt.Errorf("failed to invalidate LLM cache: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// CleanupExpired removes expired responses
+func (s *LLMCacheService) CleanupExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM llm_cache WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup LLM cache: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestContentHash(t *testing.T) {
+	if ContentHash("title", "content") != ContentHash("title", "content") {
+		t.Error("Expected equal content to hash equally")
+	}
+	if ContentHash("title", "content") == ContentHash("title", "content!") {
+		t.Error("Expected different content to hash differently")
+	}
+	// Moving text between the title and the content is a different note
+	if ContentHash("ab", "c") == ContentHash("a", "bc") {
+		t.Error("Expected parts to be hashed separately")
+	}
+}
+
+func TestNoteContentHash(t *testing.T) {
+	title := ""
+	if noteContentHash(nil, "content") != noteContentHash(&title, "content") {
+		t.Error("Expected a missing title to hash as an empty one")
+	}
+}
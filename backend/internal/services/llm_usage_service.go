@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// Features LLM usage is recorded under
+const (
+	LLMFeaturePrettify       = "prettify"
+	LLMFeatureTagSuggestions = "tag_suggestions"
+	LLMFeatureSemanticSearch = "semantic_search"
+	LLMFeatureQA             = "qa"
+	LLMFeatureDigest         = "digest"
+	LLMFeatureWebClip        = "web_clip"
+	LLMFeatureProofread      = "proofread"
+	LLMFeatureTranslate      = "translate"
+	LLMFeatureTitle          = "title"
+)
+
+// ErrLLMBudgetExceeded is returned for LLM calls of users who have used up
+// their monthly token budget
+var ErrLLMBudgetExceeded = apperrors.New(apperrors.ErrRateLimited, "LLM_BUDGET_EXCEEDED",
+	"monthly LLM token budget exceeded")
+
+// LLMUsageServiceInterface defines the interface for LLM usage accounting
+type LLMUsageServiceInterface interface {
+	CheckBudget(ctx context.Context, userID string) error
+	RecordUsage(ctx context.Context, usage llm.Usage) error
+	GetUsage(ctx context.Context, userID string) (*models.LLMUsage, error)
+	SetBudget(ctx context.Context, userID string, monthlyTokens *int64) (*models.LLMUsage, error)
+}
+
+// LLMUsageService records the tokens users consume through LLM features and
+// enforces monthly token budgets. Budgets are checked before each call, so a
+// call in progress may take a user slightly over budget.
+type LLMUsageService struct {
+	db            *sql.DB
+	defaultBudget int64
+}
+
+// NewLLMUsageService creates a new LLMUsageService. Users without a budget of
+// their own get defaultBudget tokens a month; 0 is unlimited.
+func NewLLMUsageService(db *sql.DB, defaultBudget int) *LLMUsageService {
+	return &LLMUsageService{db: db, defaultBudget: int64(defaultBudget)}
+}
+
+// monthRange returns the calendar month containing t, in UTC
+func monthRange(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// remainingTokens returns the tokens left of a budget, or nil when unlimited
+func remainingTokens(budget *int64, used int64) *int64 {
+	if budget == nil {
+		return nil
+	}
+	remaining := *budget - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+// budget returns the monthly token budget of a user, or nil when unlimited
+func (s *LLMUsageService) budget(ctx context.Context, userID string) (*int64, error) {
+	var budget sql.NullInt64
+	err := s.db.QueryRowContext(ctx, "SELECT llm_token_budget FROM users WHERE id = $1", userID).Scan(&budget)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM token budget: %w", err)
+	}
+
+	if budget.Valid {
+		return &budget.Int64, nil
+	}
+	if s.defaultBudget > 0 {
+		return &s.defaultBudget, nil
+	}
+	return nil, nil
+}
+
+// CheckBudget returns ErrLLMBudgetExceeded when the user has used up their
+// token budget for the month
+func (s *LLMUsageService) CheckBudget(ctx context.Context, userID string) error {
+	budget, err := s.budget(ctx, userID)
+	if err != nil || budget == nil {
+		return err
+	}
+
+	start, _ := monthRange(time.Now())
+	var used int64
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(prompt_tokens + completion_tokens), 0)
+		FROM llm_usage
+		WHERE user_id = $1 AND created_at >= $2`,
+		userID, start).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to get LLM token usage: %w", err)
+	}
+
+	if used >= *budget {
+		return ErrLLMBudgetExceeded
+	}
+	return nil
+}
+
+// RecordUsage stores the tokens of one LLM call
+func (s *LLMUsageService) RecordUsage(ctx context.Context, usage llm.Usage) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO llm_usage (user_id, feature, provider, model, prompt_tokens, completion_tokens, cost_usd)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		usage.UserID, usage.Feature, usage.Provider, usage.Model,
+		usage.PromptTokens, usage.CompletionTokens, usage.CostUSD)
+	if err != nil {
+		return fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsage returns the user's token usage this month, by feature, and their
+// budget
+func (s *LLMUsageService) GetUsage(ctx context.Context, userID string) (*models.LLMUsage, error) {
+	budget, err := s.budget(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := monthRange(time.Now())
+	usage := &models.LLMUsage{
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Budget:      budget,
+		Features:    []models.LLMFeatureUsage{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT feature, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(cost_usd)
+		FROM llm_usage
+		WHERE user_id = $1 AND created_at >= $2
+		GROUP BY feature
+		ORDER BY feature`,
+		userID, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var feature models.LLMFeatureUsage
+		var promptTokens, completionTokens int64
+		if err := rows.Scan(&feature.Feature, &feature.Calls, &promptTokens, &completionTokens, &feature.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan LLM usage: %w", err)
+		}
+		feature.TotalTokens = promptTokens + completionTokens
+		usage.Features = append(usage.Features, feature)
+
+		usage.Calls += feature.Calls
+		usage.PromptTokens += promptTokens
+		usage.CompletionTokens += completionTokens
+		usage.CostUSD += feature.CostUSD
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	usage.Remaining = remainingTokens(budget, usage.TotalTokens)
+	return usage, nil
+}
+
+// SetBudget sets the monthly token budget of a user; nil restores the
+// default. It returns the user's usage under the new budget.
+func (s *LLMUsageService) SetBudget(ctx context.Context, userID string, monthlyTokens *int64) (*models.LLMUsage, error) {
+	if monthlyTokens != nil && *monthlyTokens < 0 {
+		return nil, apperrors.Validation("INVALID_LLM_BUDGET", "monthly token budget cannot be negative")
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, "UPDATE users SET llm_token_budget = $1 WHERE id = $2", monthlyTokens, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set LLM token budget: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return s.GetUsage(ctx, userID)
+}
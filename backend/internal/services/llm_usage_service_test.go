@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonthRange(t *testing.T) {
+	// 23:30 on the last day of January in New York is already February in UTC
+	loc := time.FixedZone("EST", -5*60*60)
+	start, end := monthRange(time.Date(2026, 1, 31, 23, 30, 0, 0, loc))
+
+	if want := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Expected the month to start %v, got %v", want, start)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("Expected the month to end %v, got %v", want, end)
+	}
+}
+
+func TestRemainingTokens(t *testing.T) {
+	if remainingTokens(nil, 500) != nil {
+		t.Error("Expected no remaining tokens without a budget")
+	}
+
+	budget := int64(1000)
+	if remaining := remainingTokens(&budget, 400); remaining == nil || *remaining != 600 {
+		t.Errorf("Expected 600 tokens left, got %v", remaining)
+	}
+	// A call in progress may take a user over budget
+	if remaining := remainingTokens(&budget, 1200); remaining == nil || *remaining != 0 {
+		t.Errorf("Expected 0 tokens left, got %v", remaining)
+	}
+}
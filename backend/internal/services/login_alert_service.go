@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// loginAlertHistory is how many of the user's latest sessions a sign-in is
+// compared against
+const loginAlertHistory = 100
+
+// LoginAlertServiceInterface defines the interface for reporting sign-ins
+// from new devices and locations
+type LoginAlertServiceInterface interface {
+	CheckSignIn(ctx context.Context, userID uuid.UUID, sessionID, ipAddress, userAgent, country string) (bool, error)
+}
+
+// LoginAlertService compares new sessions with the earlier sessions of the
+// user and sends a security alert when one signs in from a device or
+// location the user has not used before.
+type LoginAlertService struct {
+	db                  *sql.DB
+	notificationService NotificationServiceInterface
+}
+
+// NewLoginAlertService creates a new LoginAlertService
+func NewLoginAlertService(db *sql.DB, notificationService NotificationServiceInterface) *LoginAlertService {
+	return &LoginAlertService{
+		db:                  db,
+		notificationService: notificationService,
+	}
+}
+
+// CheckSignIn records the country of a new session and notifies the user
+// when the session signed in from a new device or location, returning
+// whether it did. Devices are compared by browser and OS, so that browser
+// updates are not reported. Locations are compared by country when it is
+// known, by IP address otherwise. The first session of a user is not
+// reported, as there is nothing to compare it with.
+func (s *LoginAlertService) CheckSignIn(ctx context.Context, userID uuid.UUID, sessionID, ipAddress, userAgent, country string) (bool, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		// Fallback sessions are not stored
+		return false, nil
+	}
+
+	country = strings.ToUpper(country)
+	if country != "" {
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE user_sessions SET country = $1 WHERE id = $2
+		`, country, sessionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to record session country: %w", err)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ip_address, user_agent, country
+		FROM user_sessions
+		WHERE user_id = $1 AND id <> $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, userID, sessionID, loginAlertHistory)
+	if err != nil {
+		return false, fmt.Errorf("failed to get earlier sessions: %w", err)
+	}
+	defer rows.Close()
+
+	device := describeDevice(userAgent)
+	earlier, knownDevice, knownLocation := 0, false, false
+	for rows.Next() {
+		var sessionIP, sessionAgent, sessionCountry string
+		if err := rows.Scan(&sessionIP, &sessionAgent, &sessionCountry); err != nil {
+			return false, fmt.Errorf("failed to scan session: %w", err)
+		}
+		earlier++
+		if describeDevice(sessionAgent) == device {
+			knownDevice = true
+		}
+		if (country != "" && sessionCountry == country) || sameIP(sessionIP, ipAddress) {
+			knownLocation = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	if earlier == 0 || (knownDevice && knownLocation) {
+		return false, nil
+	}
+
+	location := ipAddress
+	if country != "" {
+		location = fmt.Sprintf("%s (%s)", ipAddress, country)
+	}
+	body := fmt.Sprintf("Your account was signed in from %s at %s. If this wasn't you, revoke the session and sign out of all sessions.", device, location)
+	data := map[string]any{
+		"session_id":   sessionID,
+		"device":       device,
+		"ip_address":   ipAddress,
+		"new_device":   !knownDevice,
+		"new_location": !knownLocation,
+	}
+	if country != "" {
+		data["country"] = country
+	}
+	if s.notificationService != nil {
+		if _, err := s.notificationService.Notify(ctx, userID, models.NotificationTypeSecurityAlert,
+			"New sign-in to your account", body, data); err != nil {
+			log.Printf("ERROR: failed to notify user %s of sign-in from session %s: %v", userID, sessionID, err)
+		}
+	}
+	return true, nil
+}
+
+// sameIP reports whether two IP addresses are equal. PostgreSQL may return
+// the addresses of INET columns with a prefix length.
+func sameIP(stored, ipAddress string) bool {
+	stored, _, _ = strings.Cut(stored, "/")
+	return stored == ipAddress
+}
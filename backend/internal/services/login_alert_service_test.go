@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+const (
+	macChrome     = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	macChromeNext = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	linuxFirefox  = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+)
+
+func TestLoginAlerts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	notifier := &recordingNotifier{}
+	service := NewLoginAlertService(db, notifier)
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+
+	signIn := func(ipAddress, userAgent, country string) bool {
+		t.Helper()
+		sessionID := uuid.New().String()
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO user_sessions (id, user_id, ip_address, user_agent, created_at, last_seen, is_active)
+			VALUES ($1, $2, $3, $4, NOW(), NOW(), true)
+		`, sessionID, user.ID, ipAddress, userAgent)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		alerted, err := service.CheckSignIn(ctx, user.ID, sessionID, ipAddress, userAgent, country)
+		if err != nil {
+			t.Fatalf("CheckSignIn failed: %v", err)
+		}
+		return alerted
+	}
+
+	tests := []struct {
+		name      string
+		ipAddress string
+		userAgent string
+		country   string
+		want      bool
+	}{
+		{"first sign-in", "203.0.113.7", macChrome, "de", false},
+		{"same device after a browser update", "203.0.113.8", macChromeNext, "DE", false},
+		{"new device", "203.0.113.9", linuxFirefox, "DE", true},
+		{"known device from a new country", "198.51.100.4", macChrome, "BR", true},
+		{"known device and address without a country", "203.0.113.7", macChrome, "", false},
+	}
+
+	for _, tt := range tests {
+		before := len(notifier.notifications)
+		if alerted := signIn(tt.ipAddress, tt.userAgent, tt.country); alerted != tt.want {
+			t.Errorf("%s: expected alert %v, got %v", tt.name, tt.want, !tt.want)
+		}
+		if tt.want {
+			if len(notifier.notifications) != before+1 {
+				t.Fatalf("%s: expected a notification", tt.name)
+			}
+			if got := notifier.notifications[before].Type; got != models.NotificationTypeSecurityAlert {
+				t.Errorf("%s: expected a security alert, got %s", tt.name, got)
+			}
+		}
+	}
+
+	// Fallback sessions are not stored and have nothing to compare
+	alerted, err := service.CheckSignIn(ctx, user.ID, "chrome-session-"+user.ID.String(), "192.0.2.1", linuxFirefox, "FR")
+	if err != nil || alerted {
+		t.Errorf("Expected no alert for a fallback session, got %v, %v", alerted, err)
+	}
+
+	// The country is recorded on the session for later sign-ins
+	var countries int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_sessions WHERE user_id = $1 AND country = 'BR'`, user.ID).Scan(&countries); err != nil {
+		t.Fatalf("Failed to count sessions: %v", err)
+	}
+	if countries != 1 {
+		t.Errorf("Expected one session from BR, got %d", countries)
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// Retry policy for pushing a chunk to the destination
+const (
+	migrationPushAttempts = 5
+	migrationRetryDelay   = time.Second
+	migrationMaxDelay     = 30 * time.Second
+)
+
+// MigrationTokenHeader carries the transfer token on requests between deployments
+const MigrationTokenHeader = "X-Migration-Token"
+
+// errDestinationNotAllowed is returned when the destination resolves to a
+// loopback, private or otherwise internal address
+var errDestinationNotAllowed = errors.New("destination address is not allowed")
+
+// migrationClient talks to the transfer endpoints of a destination deployment
+type migrationClient struct {
+	httpClient *http.Client
+	userAgent  string
+}
+
+// newMigrationClient creates a migrationClient. Unless allowInsecure is set,
+// connections to internal addresses are refused when dialing, which also
+// covers hostnames that resolve to them.
+func newMigrationClient(allowInsecure bool) *migrationClient {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowInsecure {
+		dialer.Control = denyInternalAddresses
+	}
+
+	return &migrationClient{
+		httpClient: &http.Client{
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			// The token must only ever be sent to the URL the user gave
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		userAgent: "my-notes-migration/1.0",
+	}
+}
+
+// denyInternalAddresses is a dialer control refusing connections to
+// loopback, private and otherwise internal addresses
+func denyInternalAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errDestinationNotAllowed
+	}
+	return nil
+}
+
+// normalizeDestinationURL validates the base URL of a destination deployment
+func normalizeDestinationURL(raw string, allowInsecure bool) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid destination URL")
+	}
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && allowInsecure) {
+		return "", fmt.Errorf("destination URL must use https")
+	}
+	if parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("invalid destination URL")
+	}
+
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	parsed.RawPath = ""
+	return parsed.String(), nil
+}
+
+// migrationResponse is the API response envelope returned by the destination
+type migrationResponse struct {
+	Data  *models.MigrationTransfer `json:"data"`
+	Error *models.APIError          `json:"error"`
+}
+
+// migrationStatusError is an error response from the destination
+type migrationStatusError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *migrationStatusError) Error() string {
+	return fmt.Sprintf("destination responded %d: %s", e.StatusCode, e.Message)
+}
+
+// retryable reports whether the request may succeed when repeated
+func (e *migrationStatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// getTransfer fetches the destination's transfer, verifying the token
+func (c *migrationClient) getTransfer(ctx context.Context, destinationURL string, transferID uuid.UUID, token string) (*models.MigrationTransfer, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/migrations/incoming/%s", destinationURL, transferID)
+	return c.do(ctx, http.MethodGet, endpoint, token, nil)
+}
+
+// pushChunk sends a chunk to the destination, retrying network failures,
+// rate limiting and server errors with exponential backoff. Chunks are
+// idempotent on the destination, so a chunk whose acknowledgement was lost
+// can safely be sent again.
+func (c *migrationClient) pushChunk(ctx context.Context, destinationURL string, transferID uuid.UUID, token string, chunk *models.MigrationChunk) (*models.MigrationTransfer, error) {
+	// Escaping HTML would grow notes full of markup past the chunk size limit
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(chunk); err != nil {
+		return nil, fmt.Errorf("failed to encode migration chunk: %w", err)
+	}
+	body := buf.Bytes()
+	endpoint := fmt.Sprintf("%s/api/v1/migrations/incoming/%s/chunks/%d", destinationURL, transferID, chunk.Sequence)
+
+	delay := migrationRetryDelay
+	for attempt := 1; ; attempt++ {
+		transfer, err := c.do(ctx, http.MethodPut, endpoint, token, body)
+		if err == nil {
+			return transfer, nil
+		}
+
+		var statusErr *migrationStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return nil, err
+		}
+		if errors.Is(err, errDestinationNotAllowed) || attempt == migrationPushAttempts {
+			return nil, err
+		}
+
+		wait := delay
+		if statusErr != nil && statusErr.RetryAfter > 0 {
+			wait = statusErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(wait, migrationMaxDelay)):
+		}
+		delay *= 2
+	}
+}
+
+// do performs a request against the destination and decodes the transfer
+func (c *migrationClient) do(ctx context.Context, method, endpoint, token string, body []byte) (*models.MigrationTransfer, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(MigrationTokenHeader, token)
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach destination: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope migrationResponse
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope)
+
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &migrationStatusError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if decodeErr == nil && envelope.Error != nil {
+			statusErr.Message = envelope.Error.Message
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, statusErr
+	}
+	if decodeErr != nil || envelope.Data == nil {
+		return nil, fmt.Errorf("invalid response from destination")
+	}
+
+	return envelope.Data, nil
+}
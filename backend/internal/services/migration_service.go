@@ -0,0 +1,850 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// migrationChunkNotes is the number of notes a source sends per chunk. At the
+// default content limit a chunk stays well below the 1MB request limit.
+const migrationChunkNotes = 25
+
+// maxMigrationChunkNotes is the most notes a destination accepts in one chunk
+const maxMigrationChunkNotes = 100
+
+// MigrationServiceInterface defines the interface for migrating a user's data
+// between deployments
+type MigrationServiceInterface interface {
+	// Destination side
+	CreateTransfer(ctx context.Context, userID uuid.UUID) (*models.MigrationTransfer, error)
+	GetTransfer(ctx context.Context, transferID, token string) (*models.MigrationTransfer, error)
+	ReceiveChunk(ctx context.Context, transferID, token string, chunk *models.MigrationChunk) (*models.MigrationTransfer, error)
+	// Source side
+	StartMigration(ctx context.Context, userID uuid.UUID, request *models.StartMigrationRequest) (*models.OutgoingMigration, error)
+	GetMigration(ctx context.Context, userID uuid.UUID, migrationID string) (*models.OutgoingMigration, error)
+	ResumeMigration(ctx context.Context, userID uuid.UUID, migrationID string) (*models.OutgoingMigration, error)
+}
+
+// MigrationService moves a user's complete data to another deployment. The
+// destination creates a transfer and hands its token to the user; the source
+// then pushes numbered chunks to it in the background. Chunks are idempotent,
+// so a failed migration resumes from the last acknowledged chunk, and notes
+// keep their IDs so that links between them survive the move.
+type MigrationService struct {
+	db                  *sql.DB
+	noteService         NoteServiceInterface
+	userService         UserServiceInterface
+	savedSearchService  SavedSearchServiceInterface
+	subscriptionService SubscriptionServiceInterface
+	templateService     TemplateServiceInterface
+	linkListener        NoteWriteListener
+	client              *migrationClient
+	transferTTL         time.Duration
+	allowInsecure       bool
+	jobs                JobRunner
+
+	mu      sync.Mutex
+	running map[uuid.UUID]bool
+}
+
+// NewMigrationService creates a new MigrationService. allowInsecure permits
+// plain HTTP destinations on internal addresses, for development.
+func NewMigrationService(db *sql.DB, noteService NoteServiceInterface, userService UserServiceInterface,
+	savedSearchService SavedSearchServiceInterface, subscriptionService SubscriptionServiceInterface,
+	transferTTL time.Duration, allowInsecure bool) *MigrationService {
+	return &MigrationService{
+		db:                  db,
+		noteService:         noteService,
+		userService:         userService,
+		savedSearchService:  savedSearchService,
+		subscriptionService: subscriptionService,
+		client:              newMigrationClient(allowInsecure),
+		transferTTL:         transferTTL,
+		allowInsecure:       allowInsecure,
+		running:             make(map[uuid.UUID]bool),
+	}
+}
+
+// SetTemplateService sets the service templates are migrated with. Without
+// it, templates are neither sent nor received.
+func (s *MigrationService) SetTemplateService(templateService TemplateServiceInterface) {
+	s.templateService = templateService
+}
+
+// SetLinkListener sets the listener that rebuilds note links once all notes
+// of a transfer have arrived, for links to notes received later
+func (s *MigrationService) SetLinkListener(listener NoteWriteListener) {
+	s.linkListener = listener
+}
+
+// JobRunner runs background jobs, refusing new ones once the server is
+// shutting down
+type JobRunner interface {
+	Go(name string, job func(ctx context.Context)) error
+}
+
+// SetJobRunner sets the runner of migration pushes, so that shutdown waits
+// for them. Without one, pushes run until the process exits.
+func (s *MigrationService) SetJobRunner(jobs JobRunner) {
+	s.jobs = jobs
+}
+
+// transferColumns lists the columns scanned by scanTransfer
+const transferColumns = "id, user_id, status, next_chunk, notes_received, created_at, expires_at, completed_at"
+
+// scanTransfer scans a row selected with transferColumns followed by token_hash
+func scanTransfer(row rowScanner, t *models.MigrationTransfer, tokenHash *string) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Status, &t.NextChunk, &t.NotesReceived, &t.CreatedAt, &t.ExpiresAt, &t.CompletedAt, tokenHash)
+}
+
+// outgoingMigrationColumns lists the columns scanned by scanOutgoingMigration
+const outgoingMigrationColumns = "id, user_id, destination_url, transfer_id, status, chunks_sent, notes_sent, notes_skipped, error, created_at, updated_at, completed_at"
+
+// scanOutgoingMigration scans a row selected with outgoingMigrationColumns
+func scanOutgoingMigration(row rowScanner, m *models.OutgoingMigration) error {
+	return row.Scan(&m.ID, &m.UserID, &m.DestinationURL, &m.TransferID, &m.Status, &m.ChunksSent,
+		&m.NotesSent, &m.NotesSkipped, &m.Error, &m.CreatedAt, &m.UpdatedAt, &m.CompletedAt)
+}
+
+// CreateTransfer creates a transfer accepting the user's data from another
+// deployment. The token is only returned here.
+func (s *MigrationService) CreateTransfer(ctx context.Context, userID uuid.UUID) (*models.MigrationTransfer, error) {
+	token, err := generateMigrationToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate migration token: %w", err)
+	}
+
+	var transfer models.MigrationTransfer
+	var tokenHash string
+	err = scanTransfer(s.db.QueryRowContext(ctx, `
+		INSERT INTO migration_transfers (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING `+transferColumns+`, token_hash
+	`, userID, hashConfirmationCode(token), time.Now().Add(s.transferTTL)), &transfer, &tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration transfer: %w", err)
+	}
+
+	transfer.Token = token
+	return &transfer, nil
+}
+
+// GetTransfer returns an unexpired transfer if the token matches
+func (s *MigrationService) GetTransfer(ctx context.Context, transferID, token string) (*models.MigrationTransfer, error) {
+	return s.loadTransfer(ctx, s.db, transferID, token, false)
+}
+
+// loadTransfer fetches an unexpired transfer and verifies its token,
+// optionally locking the row for the rest of the transaction
+func (s *MigrationService) loadTransfer(ctx context.Context, q queryRower, transferID, token string, forUpdate bool) (*models.MigrationTransfer, error) {
+	if _, err := uuid.Parse(transferID); err != nil {
+		return nil, fmt.Errorf("migration transfer not found")
+	}
+
+	query := `SELECT ` + transferColumns + `, token_hash FROM migration_transfers WHERE id = $1 AND expires_at > NOW()`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var transfer models.MigrationTransfer
+	var tokenHash string
+	err := scanTransfer(q.QueryRowContext(ctx, query, transferID), &transfer, &tokenHash)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("migration transfer not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration transfer: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashConfirmationCode(token)), []byte(tokenHash)) != 1 {
+		return nil, fmt.Errorf("invalid migration token")
+	}
+
+	return &transfer, nil
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// ReceiveChunk applies a chunk pushed by the source deployment. Chunks must
+// arrive in order; a chunk already received is applied again, which creates
+// nothing new, so sources can resend chunks whose acknowledgement was lost.
+func (s *MigrationService) ReceiveChunk(ctx context.Context, transferID, token string, chunk *models.MigrationChunk) (*models.MigrationTransfer, error) {
+	if len(chunk.Notes) > maxMigrationChunkNotes {
+		return nil, fmt.Errorf("too many notes in chunk (max %d)", maxMigrationChunkNotes)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The row lock serializes chunks of the same transfer
+	transfer, err := s.loadTransfer(ctx, tx, transferID, token, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if chunk.Sequence < 0 || chunk.Sequence > transfer.NextChunk {
+		return nil, fmt.Errorf("expected chunk %d", transfer.NextChunk)
+	}
+	if transfer.Status == models.TransferStatusCompleted {
+		if chunk.Sequence == transfer.NextChunk {
+			return nil, fmt.Errorf("migration transfer has already completed")
+		}
+		return transfer, nil
+	}
+
+	received, err := s.applyChunk(ctx, transfer.UserID, chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	if chunk.Sequence < transfer.NextChunk {
+		return transfer, nil
+	}
+
+	status := models.TransferStatusReceiving
+	if chunk.Final {
+		status = models.TransferStatusCompleted
+	}
+
+	var tokenHash string
+	err = scanTransfer(tx.QueryRowContext(ctx, `
+		UPDATE migration_transfers
+		SET next_chunk = next_chunk + 1, notes_received = notes_received + $2, status = $3,
+		    completed_at = CASE WHEN $3 = 'completed' THEN NOW() END
+		WHERE id = $1
+		RETURNING `+transferColumns+`, token_hash
+	`, transfer.ID, received, status), transfer, &tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update migration transfer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migration chunk: %w", err)
+	}
+
+	if transfer.Status == models.TransferStatusCompleted {
+		s.relinkNotes(ctx, transfer.UserID.String())
+	}
+
+	return transfer, nil
+}
+
+// applyChunk stores the data of a chunk for the user, skipping anything that
+// already exists, and returns the number of notes created
+func (s *MigrationService) applyChunk(ctx context.Context, userID uuid.UUID, chunk *models.MigrationChunk) (int, error) {
+	if chunk.Settings != nil {
+		request := &models.UpdateUserSettingsRequest{
+			AutoApplyTagSuggestions: &chunk.Settings.AutoApplyTagSuggestions,
+		}
+		// Deployments without digests leave the frequency out
+		if chunk.Settings.DigestFrequency != "" {
+			request.DigestFrequency = &chunk.Settings.DigestFrequency
+		}
+		_, err := s.userService.UpdateSettings(ctx, userID.String(), request)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.applySavedSearches(ctx, userID.String(), chunk.SavedSearches); err != nil {
+		return 0, err
+	}
+	if err := s.applySubscriptions(ctx, userID.String(), chunk.Subscriptions); err != nil {
+		return 0, err
+	}
+	if err := s.applyTemplates(ctx, userID.String(), chunk.Templates); err != nil {
+		return 0, err
+	}
+
+	return s.applyNotes(ctx, userID, chunk.Notes)
+}
+
+// applySavedSearches creates saved searches whose name is not taken yet
+func (s *MigrationService) applySavedSearches(ctx context.Context, userID string, searches []models.MigrationSavedSearch) error {
+	for _, search := range searches {
+		_, err := s.savedSearchService.CreateSavedSearch(ctx, userID, &models.CreateSavedSearchRequest{
+			Name:     search.Name,
+			Query:    search.Query,
+			Tags:     search.Tags,
+			OrderBy:  search.OrderBy,
+			OrderDir: search.OrderDir,
+		})
+		if err != nil && err.Error() != "saved search with this name already exists" {
+			return fmt.Errorf("saved search %q: %w", search.Name, err)
+		}
+	}
+	return nil
+}
+
+// applySubscriptions creates subscriptions the user does not have yet,
+// following saved searches by name
+func (s *MigrationService) applySubscriptions(ctx context.Context, userID string, subscriptions []models.MigrationSubscription) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	existing, err := s.subscriptionService.ListSubscriptions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, subscription := range existing {
+		exists[subscription.Name+"\x00"+subscription.Query] = true
+	}
+
+	savedSearches, err := s.savedSearchService.ListSavedSearches(ctx, userID)
+	if err != nil {
+		return err
+	}
+	savedSearchIDs := make(map[string]string, len(savedSearches))
+	for _, saved := range savedSearches {
+		savedSearchIDs[saved.Name] = saved.ID.String()
+	}
+
+	for _, subscription := range subscriptions {
+		if exists[subscription.Name+"\x00"+subscription.Query] {
+			continue
+		}
+
+		request := &models.CreateSearchSubscriptionRequest{
+			Name:  subscription.Name,
+			Query: subscription.Query,
+		}
+		if id, ok := savedSearchIDs[subscription.SavedSearch]; ok && subscription.SavedSearch != "" {
+			request.SavedSearchID = &id
+		}
+
+		if _, err := s.subscriptionService.CreateSubscription(ctx, userID, request); err != nil {
+			return fmt.Errorf("subscription %q: %w", subscription.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyTemplates creates templates whose name is not taken yet
+func (s *MigrationService) applyTemplates(ctx context.Context, userID string, templates []models.MigrationTemplate) error {
+	if s.templateService == nil {
+		return nil
+	}
+	for _, template := range templates {
+		_, err := s.templateService.CreateTemplate(ctx, userID, &models.TemplateRequest{
+			Name:        template.Name,
+			Description: template.Description,
+			Title:       template.Title,
+			Content:     template.Content,
+			Tags:        template.Tags,
+			Category:    template.Category,
+			IsPublic:    template.Public,
+		})
+		if err != nil && apperrors.Code(err) != "TEMPLATE_EXISTS" {
+			return fmt.Errorf("template %q: %w", template.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyNotes creates the notes the user does not have yet. Notes keep their
+// ID unless another user's note already has it.
+func (s *MigrationService) applyNotes(ctx context.Context, userID uuid.UUID, notes []models.MigrationNote) (int, error) {
+	if len(notes) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID.String()
+	}
+
+	conditions := database.NewConditions(database.DialectOf(s.db))
+	conditions.AnyOf("id", ids, "uuid")
+	rows, err := s.db.QueryContext(ctx, "SELECT id, user_id FROM notes "+conditions.Where(), conditions.Args()...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check existing notes: %w", err)
+	}
+	defer rows.Close()
+
+	owners := make(map[uuid.UUID]uuid.UUID)
+	for rows.Next() {
+		var id, owner uuid.UUID
+		if err := rows.Scan(&id, &owner); err != nil {
+			return 0, fmt.Errorf("failed to scan existing note: %w", err)
+		}
+		owners[id] = owner
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating existing notes: %w", err)
+	}
+
+	var requests []*models.CreateNoteRequest
+	for i := range notes {
+		note := notes[i]
+		owner, taken := owners[note.ID]
+		if taken && owner == userID {
+			// Received before
+			continue
+		}
+
+		request := &models.CreateNoteRequest{
+			Title:     note.Title,
+			Content:   note.Content,
+			Private:   note.Private,
+			CreatedAt: &note.CreatedAt,
+		}
+		if !taken {
+			request.ID = &note.ID
+		}
+		requests = append(requests, request)
+	}
+
+	if len(requests) == 0 {
+		return 0, nil
+	}
+	if _, err := s.noteService.BatchCreateNotes(ctx, userID.String(), requests); err != nil {
+		return 0, err
+	}
+	return len(requests), nil
+}
+
+// relinkNotes rebuilds the ID links of the user's notes. Links are only stored
+// when their target exists, so links to notes received in a later chunk are
+// missing until the transfer completes.
+func (s *MigrationService) relinkNotes(ctx context.Context, userID string) {
+	if s.linkListener == nil {
+		return
+	}
+
+	for offset := 0; ; offset += 100 {
+		list, err := s.noteService.ListNotes(ctx, userID, 100, offset, "created_at", "asc")
+		if err != nil {
+			log.Printf("[MigrationService] WARNING: failed to list notes to relink for user %s: %v", userID, err)
+			return
+		}
+
+		for _, response := range list.Notes {
+			if response.IsPrivate || (!strings.Contains(response.Content, "[[") && !strings.Contains(response.Content, "note://")) {
+				continue
+			}
+			note, err := s.noteService.GetNoteByID(ctx, userID, response.ID.String())
+			if err != nil {
+				continue
+			}
+			s.linkListener.NoteWritten(ctx, note)
+		}
+
+		if len(list.Notes) < 100 {
+			return
+		}
+	}
+}
+
+// CleanupExpiredTransfers removes transfers past their expiry. Data already
+// received is kept.
+func (s *MigrationService) CleanupExpiredTransfers(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM migration_transfers
+		WHERE expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired migration transfers: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// StartMigration verifies the transfer with the destination and starts pushing
+// the user's data to it in the background
+func (s *MigrationService) StartMigration(ctx context.Context, userID uuid.UUID, request *models.StartMigrationRequest) (*models.OutgoingMigration, error) {
+	destinationURL, err := normalizeDestinationURL(request.DestinationURL, s.allowInsecure)
+	if err != nil {
+		return nil, err
+	}
+	transferID, err := uuid.Parse(request.TransferID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transfer ID")
+	}
+	token := strings.TrimSpace(request.Token)
+	if token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+
+	transfer, err := s.client.getTransfer(ctx, destinationURL, transferID, token)
+	if err != nil {
+		return nil, fmt.Errorf("destination rejected the transfer: %w", err)
+	}
+	if transfer.Status == models.TransferStatusCompleted {
+		return nil, fmt.Errorf("destination rejected the transfer: already completed")
+	}
+
+	var migration models.OutgoingMigration
+	err = scanOutgoingMigration(s.db.QueryRowContext(ctx, `
+		INSERT INTO outgoing_migrations (user_id, destination_url, transfer_id, token)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+outgoingMigrationColumns, userID, destinationURL, transferID, token), &migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration: %w", err)
+	}
+
+	s.launch(migration.ID)
+	return &migration, nil
+}
+
+// GetMigration retrieves an outgoing migration of the user
+func (s *MigrationService) GetMigration(ctx context.Context, userID uuid.UUID, migrationID string) (*models.OutgoingMigration, error) {
+	if _, err := uuid.Parse(migrationID); err != nil {
+		return nil, fmt.Errorf("migration not found")
+	}
+
+	var migration models.OutgoingMigration
+	err := scanOutgoingMigration(s.db.QueryRowContext(ctx, `
+		SELECT `+outgoingMigrationColumns+` FROM outgoing_migrations WHERE id = $1 AND user_id = $2
+	`, migrationID, userID), &migration)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("migration not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration: %w", err)
+	}
+
+	return &migration, nil
+}
+
+// ResumeMigration restarts a migration that failed or was interrupted by a
+// server restart, continuing after the last acknowledged chunk
+func (s *MigrationService) ResumeMigration(ctx context.Context, userID uuid.UUID, migrationID string) (*models.OutgoingMigration, error) {
+	migration, err := s.GetMigration(ctx, userID, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	if migration.Status == models.MigrationStatusCompleted {
+		return nil, fmt.Errorf("migration has already completed")
+	}
+	if s.isRunning(migration.ID) {
+		return nil, fmt.Errorf("migration is already running")
+	}
+
+	err = scanOutgoingMigration(s.db.QueryRowContext(ctx, `
+		UPDATE outgoing_migrations SET status = $2, error = NULL
+		WHERE id = $1
+		RETURNING `+outgoingMigrationColumns, migration.ID, models.MigrationStatusRunning), migration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume migration: %w", err)
+	}
+
+	s.launch(migration.ID)
+	return migration, nil
+}
+
+// isRunning reports whether a migration is being pushed by this server
+func (s *MigrationService) isRunning(migrationID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running[migrationID]
+}
+
+// launch pushes a migration in the background unless it is already running.
+// A push cut short by shutdown is left interrupted, to be resumed by
+// ResumeInterruptedMigrations on the next start.
+func (s *MigrationService) launch(migrationID uuid.UUID) {
+	s.mu.Lock()
+	if s.running[migrationID] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[migrationID] = true
+	s.mu.Unlock()
+
+	done := func() {
+		s.mu.Lock()
+		delete(s.running, migrationID)
+		s.mu.Unlock()
+	}
+	push := func(ctx context.Context) {
+		defer done()
+
+		err := s.push(ctx, migrationID)
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			log.Printf("[MigrationService] migration %s interrupted by shutdown: %v", migrationID, err)
+			s.setMigrationStatus(migrationID, models.MigrationStatusInterrupted, nil)
+			return
+		}
+		log.Printf("[MigrationService] migration %s failed: %v", migrationID, err)
+		message := err.Error()
+		s.setMigrationStatus(migrationID, models.MigrationStatusFailed, &message)
+	}
+
+	if s.jobs == nil {
+		go push(context.Background())
+		return
+	}
+	if err := s.jobs.Go("migration "+migrationID.String(), push); err != nil {
+		done()
+		s.setMigrationStatus(migrationID, models.MigrationStatusInterrupted, nil)
+	}
+}
+
+// setMigrationStatus records the status a push ended with. It runs without
+// the context of the push, which may be cancelled.
+func (s *MigrationService) setMigrationStatus(migrationID uuid.UUID, status string, message *string) {
+	_, err := s.db.Exec(`
+		UPDATE outgoing_migrations SET status = $2, error = $3 WHERE id = $1
+	`, migrationID, status, message)
+	if err != nil {
+		log.Printf("[MigrationService] WARNING: failed to record migration %s as %s: %v", migrationID, status, err)
+	}
+}
+
+// ResumeInterruptedMigrations resumes the migrations interrupted by the last
+// shutdown. Each is claimed by one server, so replicas do not push it twice.
+func (s *MigrationService) ResumeInterruptedMigrations(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE outgoing_migrations SET status = $1
+		WHERE status = $2
+		RETURNING id
+	`, models.MigrationStatusRunning, models.MigrationStatusInterrupted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim interrupted migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating migrations: %w", err)
+	}
+
+	for _, id := range ids {
+		s.launch(id)
+	}
+	return len(ids), nil
+}
+
+// push sends the remaining chunks of a migration, recording each chunk the
+// destination acknowledges
+func (s *MigrationService) push(ctx context.Context, migrationID uuid.UUID) error {
+	var userID, transferID uuid.UUID
+	var destinationURL, token string
+	var sequence int
+	var cursorCreatedAt *time.Time
+	var cursorNoteID *uuid.UUID
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, destination_url, transfer_id, token, chunks_sent, cursor_created_at, cursor_note_id
+		FROM outgoing_migrations WHERE id = $1
+	`, migrationID).Scan(&userID, &destinationURL, &transferID, &token, &sequence, &cursorCreatedAt, &cursorNoteID)
+	if err != nil {
+		return fmt.Errorf("failed to load migration: %w", err)
+	}
+
+	for {
+		chunk, cursor, skipped, err := s.buildChunk(ctx, userID.String(), sequence, cursorCreatedAt, cursorNoteID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.client.pushChunk(ctx, destinationURL, transferID, token, chunk); err != nil {
+			return fmt.Errorf("chunk %d: %w", sequence, err)
+		}
+
+		if cursor != nil {
+			cursorCreatedAt, cursorNoteID = &cursor.CreatedAt, &cursor.ID
+		}
+		status := models.MigrationStatusRunning
+		if chunk.Final {
+			status = models.MigrationStatusCompleted
+		}
+
+		// The token is no longer needed once the destination has everything
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE outgoing_migrations
+			SET chunks_sent = $2, notes_sent = notes_sent + $3, notes_skipped = notes_skipped + $4,
+			    cursor_created_at = $5, cursor_note_id = $6, status = $7,
+			    token = CASE WHEN $7 = 'completed' THEN '' ELSE token END,
+			    completed_at = CASE WHEN $7 = 'completed' THEN NOW() END
+			WHERE id = $1
+		`, migrationID, sequence+1, len(chunk.Notes), skipped, cursorCreatedAt, cursorNoteID, status)
+		if err != nil {
+			return fmt.Errorf("failed to record migration progress: %w", err)
+		}
+
+		if chunk.Final {
+			return nil
+		}
+		sequence++
+	}
+}
+
+// buildChunk builds the chunk with the given sequence number. Chunk 0 carries
+// the account data; later chunks carry the notes after the cursor in creation
+// order. It returns the last note read, to advance the cursor, and the number
+// of private notes skipped because they cannot be decrypted.
+func (s *MigrationService) buildChunk(ctx context.Context, userID string, sequence int, cursorCreatedAt *time.Time, cursorNoteID *uuid.UUID) (*models.MigrationChunk, *models.Note, int, error) {
+	chunk := &models.MigrationChunk{Sequence: sequence}
+
+	if sequence == 0 {
+		if err := s.addAccountData(ctx, userID, chunk); err != nil {
+			return nil, nil, 0, err
+		}
+		return chunk, nil, 0, nil
+	}
+
+	conditions := database.NewConditions(database.DialectOf(s.db))
+	conditions.Compare("user_id", "=", userID)
+	if cursorCreatedAt != nil && cursorNoteID != nil {
+		next := conditions.Next()
+		conditions.Add(fmt.Sprintf("(created_at, id) > ($%d, $%d)", next, next+1), *cursorCreatedAt, *cursorNoteID)
+	}
+	query := fmt.Sprintf(`
+		SELECT id FROM notes
+		%s
+		ORDER BY created_at, id
+		LIMIT $%d
+	`, conditions.Where(), conditions.Next())
+
+	rows, err := s.db.QueryContext(ctx, query, append(conditions.Args(), migrationChunkNotes)...)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to list notes to migrate: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to scan note to migrate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, 0, fmt.Errorf("error iterating notes to migrate: %w", err)
+	}
+	rows.Close()
+
+	chunk.Final = len(ids) < migrationChunkNotes
+
+	var last *models.Note
+	skipped := 0
+	for _, id := range ids {
+		note, err := s.noteService.GetNoteByID(ctx, userID, id)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to read note %s: %w", id, err)
+		}
+		last = note
+
+		if note.Locked {
+			skipped++
+			continue
+		}
+
+		migrated := models.MigrationNote{
+			ID:        note.ID,
+			Content:   note.Content,
+			Private:   note.IsPrivate,
+			CreatedAt: note.CreatedAt,
+		}
+		if note.Title != nil {
+			migrated.Title = *note.Title
+		}
+		chunk.Notes = append(chunk.Notes, migrated)
+	}
+
+	return chunk, last, skipped, nil
+}
+
+// addAccountData adds the user's settings, saved searches, subscriptions and
+// templates to a chunk
+func (s *MigrationService) addAccountData(ctx context.Context, userID string, chunk *models.MigrationChunk) error {
+	settings, err := s.userService.GetSettings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	chunk.Settings = settings
+
+	savedSearches, err := s.savedSearchService.ListSavedSearches(ctx, userID)
+	if err != nil {
+		return err
+	}
+	names := make(map[uuid.UUID]string, len(savedSearches))
+	for _, saved := range savedSearches {
+		names[saved.ID] = saved.Name
+		chunk.SavedSearches = append(chunk.SavedSearches, models.MigrationSavedSearch{
+			Name:     saved.Name,
+			Query:    saved.Query,
+			Tags:     saved.Tags,
+			OrderBy:  saved.OrderBy,
+			OrderDir: saved.OrderDir,
+		})
+	}
+
+	subscriptions, err := s.subscriptionService.ListSubscriptions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		migrated := models.MigrationSubscription{
+			Name:  subscription.Name,
+			Query: subscription.Query,
+		}
+		if subscription.SavedSearchID != nil {
+			migrated.SavedSearch = names[*subscription.SavedSearchID]
+		}
+		chunk.Subscriptions = append(chunk.Subscriptions, migrated)
+	}
+
+	if s.templateService == nil {
+		return nil
+	}
+	templates, err := s.templateService.ListTemplates(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		chunk.Templates = append(chunk.Templates, models.MigrationTemplate{
+			Name:        template.Name,
+			Description: template.Description,
+			Title:       template.Title,
+			Content:     template.Content,
+			Tags:        template.Tags,
+			Category:    template.Category,
+			Public:      template.IsPublic,
+		})
+	}
+
+	return nil
+}
+
+// generateMigrationToken returns a random 256-bit token
+func generateMigrationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// fakeMigrationAccount serves the account data of chunk 0 and records the
+// templates it creates, rejecting names that are taken
+type fakeMigrationAccount struct {
+	UserServiceInterface
+	SavedSearchServiceInterface
+	SubscriptionServiceInterface
+	TemplateServiceInterface
+	templates []models.Template
+	created   []string
+}
+
+func (f *fakeMigrationAccount) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	return &models.UserSettings{}, nil
+}
+
+func (f *fakeMigrationAccount) UpdateSettings(ctx context.Context, userID string, request *models.UpdateUserSettingsRequest) (*models.UserSettings, error) {
+	return &models.UserSettings{}, nil
+}
+
+func (f *fakeMigrationAccount) ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error) {
+	return nil, nil
+}
+
+func (f *fakeMigrationAccount) ListSubscriptions(ctx context.Context, userID string) ([]models.SearchSubscription, error) {
+	return nil, nil
+}
+
+func (f *fakeMigrationAccount) ListTemplates(ctx context.Context, userID string) ([]models.Template, error) {
+	return f.templates, nil
+}
+
+func (f *fakeMigrationAccount) CreateTemplate(ctx context.Context, userID string, request *models.TemplateRequest) (*models.Template, error) {
+	for _, template := range f.templates {
+		if template.Name == request.Name {
+			return nil, apperrors.Conflict("TEMPLATE_EXISTS", "template with this name already exists")
+		}
+	}
+	f.created = append(f.created, request.Name)
+	return &models.Template{Name: request.Name}, nil
+}
+
+func TestMigrationCarriesTemplates(t *testing.T) {
+	source := &fakeMigrationAccount{templates: []models.Template{
+		{Name: "Standup", Content: "Yesterday, today, blockers", Tags: []string{"#work"}},
+		{Name: "Retro", Content: "Went well, to improve", IsPublic: true},
+	}}
+	service := NewMigrationService(nil, nil, source, source, source, 0, false)
+	service.SetTemplateService(source)
+
+	chunk, _, _, err := service.buildChunk(context.Background(), uuid.New().String(), 0, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chunk.Templates) != 2 || chunk.Templates[0].Name != "Standup" || !chunk.Templates[1].Public {
+		t.Fatalf("Expected both templates in chunk 0, got %+v", chunk.Templates)
+	}
+
+	// The destination already has a template named Retro
+	destination := &fakeMigrationAccount{templates: []models.Template{{Name: "Retro"}}}
+	receiver := NewMigrationService(nil, nil, destination, destination, destination, 0, false)
+	receiver.SetTemplateService(destination)
+	if _, err := receiver.applyChunk(context.Background(), uuid.New(), chunk); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(destination.created) != 1 || destination.created[0] != "Standup" {
+		t.Errorf("Expected only the Standup template to be created, got %v", destination.created)
+	}
+}
+
+func TestMigrationNotesRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	service := NewMigrationService(db, noteService, nil, nil, nil, 0, false)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	first := testutil.NewTestNote(t, db, user.ID, "First", "first note")
+	second := testutil.NewTestNote(t, db, user.ID, "Second", "second note")
+	if _, err := db.Exec("UPDATE notes SET created_at = $1 WHERE id = $2", first.CreatedAt.Add(time.Minute), second.ID); err != nil {
+		t.Fatalf("Failed to date note: %v", err)
+	}
+
+	chunk, last, _, err := service.buildChunk(ctx, user.ID.String(), 1, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chunk.Notes) != 2 || !chunk.Final || last == nil {
+		t.Fatalf("Expected both notes in the first notes chunk, got %+v", chunk.Notes)
+	}
+
+	chunk, _, _, err = service.buildChunk(ctx, user.ID.String(), 2, &first.CreatedAt, &first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chunk.Notes) != 1 || chunk.Notes[0].ID != second.ID {
+		t.Errorf("Expected only the note after the cursor, got %+v", chunk.Notes)
+	}
+
+	// Applying a chunk again creates nothing; notes whose ID another user
+	// has are created with a new one
+	if received, err := service.applyNotes(ctx, user.ID, chunk.Notes); err != nil || received != 0 {
+		t.Errorf("Expected no notes created for the owner, got %d (%v)", received, err)
+	}
+	other := testutil.NewTestUser(t, db)
+	if received, err := service.applyNotes(ctx, other.ID, chunk.Notes); err != nil || received != 1 {
+		t.Errorf("Expected the note created for another user, got %d (%v)", received, err)
+	}
+}
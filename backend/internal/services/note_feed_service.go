@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/markdown"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxNoteFeedsPerUser limits the feeds a user can have
+const maxNoteFeedsPerUser = 20
+
+// NoteFeedServiceInterface defines the interface for note feed operations
+type NoteFeedServiceInterface interface {
+	CreateFeed(ctx context.Context, userID string, request *models.CreateNoteFeedRequest) (*models.CreatedNoteFeed, error)
+	ListFeeds(ctx context.Context, userID string) ([]models.NoteFeed, error)
+	DeleteFeed(ctx context.Context, userID, feedID string) error
+	GetFeedContent(ctx context.Context, token string) (*models.NoteFeedContent, error)
+}
+
+// ErrNoteFeedNotFound is returned for unknown feeds and feed tokens
+var ErrNoteFeedNotFound = apperrors.NotFound("FEED_NOT_FOUND", "feed not found")
+
+// codeInvalidFeed is the error code of invalid feeds
+const codeInvalidFeed = "INVALID_FEED"
+
+// NoteFeedService manages RSS and Atom feeds of recently created or
+// published notes. Private notes, whose content is encrypted, are left out,
+// and notebook access is checked again each time a feed is read.
+type NoteFeedService struct {
+	db *sql.DB
+}
+
+// NewNoteFeedService creates a new NoteFeedService
+func NewNoteFeedService(db *sql.DB) *NoteFeedService {
+	return &NoteFeedService{db: db}
+}
+
+// noteFeedColumns lists the note_feeds columns in scanNoteFeed order
+const noteFeedColumns = "id, user_id, name, kind, notebook_id, last_used_at, created_at"
+
+// scanNoteFeed scans a row selected with noteFeedColumns
+func scanNoteFeed(row rowScanner, f *models.NoteFeed) error {
+	return row.Scan(&f.ID, &f.UserID, &f.Name, &f.Kind, &f.NotebookID, &f.LastUsedAt, &f.CreatedAt)
+}
+
+// CreateFeed creates a feed for a user, limited to a notebook they can read
+// when one is given. The token is only returned here.
+func (s *NoteFeedService) CreateFeed(ctx context.Context, userID string, request *models.CreateNoteFeedRequest) (*models.CreatedNoteFeed, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidFeed, err)
+	}
+	if request.NotebookID != nil {
+		if err := s.checkNotebookReadable(ctx, userID, *request.NotebookID); err != nil {
+			return nil, err
+		}
+	}
+
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM note_feeds WHERE user_id = $1", userID).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feeds: %w", err)
+	}
+	if count >= maxNoteFeedsPerUser {
+		return nil, apperrors.Validation("FEED_LIMIT_REACHED", fmt.Sprintf("feed limit of %d reached", maxNoteFeedsPerUser))
+	}
+
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate feed token: %w", err)
+	}
+
+	created := &models.CreatedNoteFeed{
+		Token:    token,
+		RSSPath:  models.NoteFeedPath(token, models.NoteFeedRSS),
+		AtomPath: models.NoteFeedPath(token, models.NoteFeedAtom),
+	}
+	err = scanNoteFeed(s.db.QueryRowContext(ctx, `
+		INSERT INTO note_feeds (id, user_id, name, kind, notebook_id, token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+noteFeedColumns,
+		uuid.New(), userID, request.Name, request.Kind, request.NotebookID, hashFeedToken(token)), &created.NoteFeed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed: %w", err)
+	}
+
+	return created, nil
+}
+
+// ListFeeds returns a user's feeds, newest first
+func (s *NoteFeedService) ListFeeds(ctx context.Context, userID string) ([]models.NoteFeed, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+noteFeedColumns+` FROM note_feeds
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feeds: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []models.NoteFeed{}
+	for rows.Next() {
+		var feed models.NoteFeed
+		if err := scanNoteFeed(rows, &feed); err != nil {
+			return nil, fmt.Errorf("failed to scan feed: %w", err)
+		}
+		feeds = append(feeds, feed)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feeds: %w", err)
+	}
+
+	return feeds, nil
+}
+
+// DeleteFeed deletes a user's feed; its URLs stop working
+func (s *NoteFeedService) DeleteFeed(ctx context.Context, userID, feedID string) error {
+	if _, err := uuid.Parse(feedID); err != nil {
+		return ErrNoteFeedNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM note_feeds WHERE id = $1 AND user_id = $2", feedID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNoteFeedNotFound
+	}
+
+	return nil
+}
+
+// GetFeedContent returns the feed of a token with its latest notes and
+// records the feed as read. Feeds of disabled accounts, and of notebooks
+// the user can no longer read, are not found.
+func (s *NoteFeedService) GetFeedContent(ctx context.Context, token string) (*models.NoteFeedContent, error) {
+	var content models.NoteFeedContent
+	err := scanNoteFeed(s.db.QueryRowContext(ctx, `
+		UPDATE note_feeds SET last_used_at = NOW()
+		WHERE token_hash = $1
+			AND user_id IN (SELECT id FROM users WHERE disabled_at IS NULL)
+		RETURNING `+noteFeedColumns,
+		hashFeedToken(token)), &content.Feed)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoteFeedNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+	feed := &content.Feed
+
+	// Own notes, or the readable notes of the notebook
+	args := []any{feed.UserID}
+	condition := "n.user_id = $1"
+	if feed.NotebookID != nil {
+		if err := s.checkNotebookReadable(ctx, feed.UserID.String(), *feed.NotebookID); err != nil {
+			if err == ErrNotebookNotFound {
+				return nil, ErrNoteFeedNotFound
+			}
+			return nil, err
+		}
+		args = append(args, *feed.NotebookID)
+		condition = noteAccess("n.", 1) + " AND n.notebook_id = $2"
+	}
+
+	query := `
+		SELECT n.id, n.title, n.content, n.created_at, n.updated_at, p.slug
+		FROM notes n
+		LEFT JOIN published_notes p ON p.note_id = n.id
+		WHERE ` + condition + ` AND NOT n.is_private
+		ORDER BY n.created_at DESC`
+	if feed.Kind == models.NoteFeedPublished {
+		query = `
+		SELECT n.id, n.title, n.content, p.published_at, n.updated_at, p.slug
+		FROM notes n
+		JOIN published_notes p ON p.note_id = n.id
+		WHERE ` + condition + ` AND NOT n.is_private
+		ORDER BY p.published_at DESC`
+	}
+	args = append(args, models.NoteFeedItemLimit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed notes: %w", err)
+	}
+	defer rows.Close()
+
+	content.Items = []models.NoteFeedItem{}
+	for rows.Next() {
+		var item models.NoteFeedItem
+		var title, slug sql.NullString
+		var body string
+		if err := rows.Scan(&item.NoteID, &title, &body, &item.PublishedAt, &item.UpdatedAt, &slug); err != nil {
+			return nil, fmt.Errorf("failed to scan feed note: %w", err)
+		}
+		item.Title = title.String
+		item.HTML = markdown.ToHTML(body)
+		if slug.Valid {
+			item.Path = models.PublicationPath(slug.String)
+		}
+		content.Items = append(content.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feed notes: %w", err)
+	}
+
+	return &content, nil
+}
+
+// checkNotebookReadable checks that the user owns the notebook or that an
+// organization shares it with them
+func (s *NoteFeedService) checkNotebookReadable(ctx context.Context, userID string, notebookID uuid.UUID) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT TRUE FROM notebooks
+		WHERE id = $1 AND (
+			(user_id = $2 AND organization_id IS NULL)
+			OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $2)
+		)
+	`, notebookID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrNotebookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get notebook: %w", err)
+	}
+	return nil
+}
+
+// generateFeedToken returns a new random feed token
+func generateFeedToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashFeedToken returns the hex SHA-256 of a token
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestNoteFeeds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewNoteFeedService(db)
+	noteService := NewNoteService(db, NewTagService(db))
+	publicationService := NewPublicationService(db, noteService)
+	organizationService := NewOrganizationService(db, email.LogSender{})
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	other := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	first, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Title: "First", Content: "**Hello**"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Second"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, other.ID.String(), &models.CreateNoteRequest{Content: "Not mine"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	created, err := service.CreateFeed(ctx, userID, &models.CreateNoteFeedRequest{Name: " Everything "})
+	if err != nil {
+		t.Fatalf("Failed to create feed: %v", err)
+	}
+	if created.Name != "Everything" || created.Kind != models.NoteFeedRecent || created.RSSPath != "/feeds/"+created.Token+"/rss" {
+		t.Errorf("Unexpected feed %+v", created)
+	}
+
+	content, err := service.GetFeedContent(ctx, created.Token)
+	if err != nil {
+		t.Fatalf("Failed to get feed: %v", err)
+	}
+	if len(content.Items) != 2 {
+		t.Fatalf("Expected the user's 2 notes, got %+v", content.Items)
+	}
+	item := content.Items[1]
+	if item.NoteID != first.ID || item.Title != "First" || !strings.Contains(item.HTML, "<strong>Hello</strong>") || item.Path != "" {
+		t.Errorf("Unexpected item %+v", item)
+	}
+
+	// Feeds of published notes link to their pages
+	publication, err := publicationService.PublishNote(ctx, userID, first.ID.String(), &models.PublishNoteRequest{})
+	if err != nil {
+		t.Fatalf("Failed to publish note: %v", err)
+	}
+	published, err := service.CreateFeed(ctx, userID, &models.CreateNoteFeedRequest{Name: "Blog", Kind: "Published"})
+	if err != nil {
+		t.Fatalf("Failed to create feed: %v", err)
+	}
+	content, err = service.GetFeedContent(ctx, published.Token)
+	if err != nil || len(content.Items) != 1 || content.Items[0].Path != publication.Path {
+		t.Errorf("Expected the published note, got %+v, %v", content, err)
+	}
+
+	if _, err := service.CreateFeed(ctx, userID, &models.CreateNoteFeedRequest{Name: "Bad", Kind: "popular"}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected an invalid kind, got %v", err)
+	}
+
+	// Notebook feeds hold the notebook's notes while the user can read it
+	org, err := organizationService.CreateOrganization(ctx, other.ID.String(), &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	notebook, err := organizationService.CreateNotebook(ctx, other.ID.String(), org.ID.String(), &models.NotebookRequest{Name: "Team"})
+	if err != nil {
+		t.Fatalf("Failed to create notebook: %v", err)
+	}
+	if _, err := service.CreateFeed(ctx, userID, &models.CreateNoteFeedRequest{Name: "Team", NotebookID: &notebook.ID}); !errors.Is(err, ErrNotebookNotFound) {
+		t.Errorf("Expected other notebooks not found, got %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, other.ID.String(), &models.CreateNoteRequest{Content: "Team note", NotebookID: &notebook.ID}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	teamFeed, err := service.CreateFeed(ctx, other.ID.String(), &models.CreateNoteFeedRequest{Name: "Team", NotebookID: &notebook.ID})
+	if err != nil {
+		t.Fatalf("Failed to create notebook feed: %v", err)
+	}
+	content, err = service.GetFeedContent(ctx, teamFeed.Token)
+	if err != nil || len(content.Items) != 1 || !strings.Contains(content.Items[0].HTML, "Team note") {
+		t.Errorf("Expected the team note, got %+v, %v", content, err)
+	}
+
+	feeds, err := service.ListFeeds(ctx, userID)
+	if err != nil || len(feeds) != 2 || feeds[0].LastUsedAt == nil {
+		t.Errorf("Expected 2 used feeds, got %+v, %v", feeds, err)
+	}
+
+	if err := service.DeleteFeed(ctx, other.ID.String(), created.ID.String()); !errors.Is(err, ErrNoteFeedNotFound) {
+		t.Errorf("Expected other users' feeds not found, got %v", err)
+	}
+	if err := service.DeleteFeed(ctx, userID, created.ID.String()); err != nil {
+		t.Fatalf("Failed to delete feed: %v", err)
+	}
+	if _, err := service.GetFeedContent(ctx, created.Token); !errors.Is(err, ErrNoteFeedNotFound) {
+		t.Errorf("Expected the deleted feed not found, got %v", err)
+	}
+}
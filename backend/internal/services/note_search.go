@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+)
+
+// noteSearch is a search of a user's notes, from which the SQL conditions
+// and ordering of the notes it matches are built
+type noteSearch struct {
+	userID  string
+	request *models.SearchNotesRequest
+	query   *search.Query
+	// searchPrivate tells whether private notes are searched. Their content
+	// is encrypted, so privateIDs are the ones matching the text terms,
+	// matched in memory.
+	searchPrivate bool
+	privateIDs    []string
+	// schema holds the user's property definitions, loaded for property
+	// filters and sorting
+	schema models.PropertySchema
+}
+
+// conditions returns the WHERE clause of the notes the search matches
+func (n *noteSearch) conditions(dialect database.Dialect) (*database.Conditions, error) {
+	conditions := database.NewConditions(dialect)
+
+	// Always include user filter, with notes shared by their organizations
+	// unless the search is limited to the user's own notes
+	if n.request.OwnedOnly {
+		conditions.Compare("user_id", "=", n.userID)
+	} else {
+		conditions.Add(noteAccess("", conditions.Next()), n.userID)
+	}
+
+	// Add text terms: full-text match using every language analyzer, plus
+	// substring match for partial words. Encrypted content cannot be matched
+	// in SQL, so private notes are matched in memory when the key is available
+	// and excluded otherwise.
+	if n.query.HasTextTerms() {
+		termConditions := make([]string, 0, len(n.query.Terms))
+		var args []interface{}
+		for _, term := range n.query.Terms {
+			condition, termArgs := textTermCondition(dialect, term, conditions.Next()+len(args))
+			termConditions = append(termConditions, condition)
+			args = append(args, termArgs...)
+		}
+		textMatch := "(NOT is_private AND " + strings.Join(termConditions, " AND ") + ")"
+
+		if n.searchPrivate {
+			textMatch = fmt.Sprintf("(%s OR %s)", textMatch, dialect.AnyOf("id", conditions.Next()+len(args), "uuid"))
+			args = append(args, dialect.Array(n.privateIDs))
+		}
+		conditions.Add(textMatch, args...)
+	} else if !n.searchPrivate {
+		conditions.Add("NOT is_private")
+	}
+
+	// Add tag filter from the request and tag: operators; notes must have every
+	// tag. Tag names ignore case.
+	if includeTags := uniqueTags(append(append([]string{}, n.request.Tags...), n.query.Tags...)); len(includeTags) > 0 {
+		conditions.Add(fmt.Sprintf(`id IN (
+			SELECT nt.note_id FROM note_tags nt
+			JOIN tags t ON nt.tag_id = t.id
+			WHERE %s
+			GROUP BY nt.note_id
+			HAVING COUNT(DISTINCT t.id) = $%d
+		)`, dialect.AnyOfIgnoringCase("t.name", conditions.Next()), conditions.Next()+1),
+			dialect.Array(includeTags), len(includeTags))
+	}
+
+	// Exclude notes carrying any -tag: operator tag
+	if excludeTags := uniqueTags(n.query.ExcludeTags); len(excludeTags) > 0 {
+		conditions.Add(fmt.Sprintf(`id NOT IN (
+			SELECT nt.note_id FROM note_tags nt
+			JOIN tags t ON nt.tag_id = t.id
+			WHERE %s
+		)`, dialect.AnyOfIgnoringCase("t.name", conditions.Next())), dialect.Array(excludeTags))
+	}
+
+	// Keep notes without tags for is:untagged, and with tags for -is:untagged
+	if n.query.Untagged != nil {
+		if *n.query.Untagged {
+			conditions.Add("id NOT IN (SELECT note_id FROM note_tags)")
+		} else {
+			conditions.Add("id IN (SELECT note_id FROM note_tags)")
+		}
+	}
+
+	// Keep notes from any source: operator source, dropping -source: ones
+	source := dialect.JSONText("metadata", "source")
+	if len(n.query.Sources) > 0 {
+		conditions.AnyOf(source, n.query.Sources, "")
+	}
+	if len(n.query.ExcludeSources) > 0 {
+		conditions.NoneOf(source, n.query.ExcludeSources, "")
+	}
+
+	// Keep notes with any color: operator color, dropping -color: ones.
+	// Notes without a color label store ''.
+	if len(n.query.Colors) > 0 {
+		conditions.AnyOf("color", n.query.Colors, "")
+	}
+	if len(n.query.ExcludeColors) > 0 {
+		conditions.Add("NOT "+dialect.AnyOf("color", conditions.Next(), ""), dialect.Array(n.query.ExcludeColors))
+	}
+
+	if n.request.NotebookID != nil {
+		conditions.Compare("notebook_id", "=", *n.request.NotebookID)
+	}
+	if n.request.UpdatedBefore != nil {
+		conditions.Compare("updated_at", "<", *n.request.UpdatedBefore)
+	}
+
+	// Add creation date range from after: (inclusive) and before: (exclusive)
+	if n.query.After != nil {
+		conditions.Compare("created_at", ">=", *n.query.After)
+	}
+	if n.query.Before != nil {
+		conditions.Compare("created_at", "<", *n.query.Before)
+	}
+
+	// Add property filters, checked against the user's property definitions
+	for _, filter := range n.request.Properties {
+		definition, value, err := n.schema.Argument(filter)
+		if err != nil {
+			return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidProperty, err)
+		}
+		conditions.Add(propertyCondition(dialect, definition, filter.Op, conditions.Next()), value)
+	}
+
+	return conditions, nil
+}
+
+// orderBy returns the ORDER BY expression of the search, by a note column
+// or by a property of the user
+func (n *noteSearch) orderBy(dialect database.Dialect) (string, error) {
+	if n.request.SortProperty == "" {
+		return n.request.OrderBy + " " + n.request.OrderDir, nil
+	}
+	definition, ok := n.schema[n.request.SortProperty]
+	if !ok {
+		return "", apperrors.Validation(codeInvalidProperty, fmt.Sprintf("unknown property %q", n.request.SortProperty))
+	}
+	value := propertyValue(dialect, &definition)
+	return fmt.Sprintf("%s IS NULL, %s %s, created_at DESC", value, value, n.request.OrderDir), nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+// compactSQL collapses the whitespace of generated SQL
+func compactSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	return strings.NewReplacer("( ", "(", " )", ")").Replace(sql)
+}
+
+func TestNoteSearchConditions(t *testing.T) {
+	parsed, err := search.Parse(`plan tag:work -tag:draft -source:telegram color:red after:2026-01-01 before:2026-02-01`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	filter := &noteSearch{
+		userID:  "user",
+		request: &models.SearchNotesRequest{Tags: []string{"team", "#work"}, OrderBy: "created_at", OrderDir: "desc"},
+		query:   parsed,
+	}
+
+	conditions, err := filter.conditions(database.Postgres)
+	if err != nil {
+		t.Fatalf("conditions failed: %v", err)
+	}
+	where := compactSQL(conditions.Where())
+	for _, want := range []string{
+		"WHERE (user_id = $1 OR notebook_id IN (",
+		"(NOT is_private AND COALESCE(search_vector @@ ",
+		"title ILIKE $3 OR content ILIKE $3 OR image_text ILIKE $3, false))",
+		"id IN (SELECT nt.note_id FROM note_tags nt JOIN tags t ON nt.tag_id = t.id WHERE LOWER(t.name) IN (SELECT LOWER(value) FROM unnest($4::text[]) AS value) GROUP BY nt.note_id HAVING COUNT(DISTINCT t.id) = $5)",
+		"id NOT IN (SELECT nt.note_id FROM note_tags nt JOIN tags t ON nt.tag_id = t.id WHERE LOWER(t.name) IN (SELECT LOWER(value) FROM unnest($6::text[]) AS value))",
+		"((metadata->>'source') IS NULL OR NOT (metadata->>'source') = ANY($7))",
+		"color = ANY($8)",
+		"created_at >= $9 AND created_at < $10",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("Expected %q in:\n%s", want, where)
+		}
+	}
+
+	args := conditions.Args()
+	if len(args) != 10 || args[0] != "user" || args[2] != "%plan%" || args[4] != 2 {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+	if after, ok := args[8].(time.Time); !ok || after.Format("2006-01-02") != "2026-01-01" {
+		t.Errorf("Expected the after: date as argument 9, got %v", args[8])
+	}
+
+	// SQLite matches arrays with json_each, and private notes are searched
+	// by ID when they can be decrypted
+	filter.searchPrivate = true
+	filter.privateIDs = []string{"00000000-0000-0000-0000-000000000001"}
+	conditions, err = filter.conditions(database.SQLite)
+	if err != nil {
+		t.Fatalf("conditions failed: %v", err)
+	}
+	where = compactSQL(conditions.Where())
+	for _, want := range []string{
+		"(NOT is_private AND COALESCE(title LIKE $2 OR content LIKE $2 OR image_text LIKE $2, false)) OR id IN (SELECT value FROM json_each($3)))",
+		"WHERE LOWER(t.name) IN (SELECT LOWER(value) FROM json_each($4)) GROUP BY nt.note_id HAVING COUNT(DISTINCT t.id) = $5)",
+		"created_at >= $9 AND created_at < $10",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("Expected %q in:\n%s", want, where)
+		}
+	}
+	if args := conditions.Args(); args[3] != `["#team","#work"]` {
+		t.Errorf("Expected the tags as a JSON array, got %v", args[3])
+	}
+}
+
+func TestNoteSearchPrivateNotes(t *testing.T) {
+	filter := &noteSearch{userID: "user", request: &models.SearchNotesRequest{}, query: &search.Query{}}
+
+	conditions, err := filter.conditions(database.Postgres)
+	if err != nil {
+		t.Fatalf("conditions failed: %v", err)
+	}
+	if !strings.HasSuffix(conditions.Where(), " AND NOT is_private") || len(conditions.Args()) != 1 {
+		t.Errorf("Expected private notes to be excluded when they cannot be searched, got %s", conditions.Where())
+	}
+
+	filter.searchPrivate = true
+	conditions, err = filter.conditions(database.Postgres)
+	if err != nil {
+		t.Fatalf("conditions failed: %v", err)
+	}
+	if strings.Contains(conditions.Where(), "is_private") {
+		t.Errorf("Expected private notes without text terms, got %s", conditions.Where())
+	}
+}
+
+func TestSearchDecryptsRecentPrivateNotes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	keys, err := encryption.NewStaticKeyProvider(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noteService.SetEncryptor(encryption.NewNoteEncryptor(keys))
+	noteService.maxPrivateSearch = 2*privateSearchPageSize + 10
+	ctx := context.Background()
+	userID := testutil.NewTestUser(t, db).ID.String()
+
+	// The oldest private notes are past the limit
+	created := make([]string, 2*privateSearchPageSize+20)
+	for i := range created {
+		note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: fmt.Sprintf("Secret plan %d", i), Private: true})
+		if err != nil {
+			t.Fatalf("Failed to create note: %v", err)
+		}
+		created[i] = note.ID.String()
+		// Order the notes by update time on every database
+		if _, err := db.ExecContext(ctx, "UPDATE notes SET updated_at = $1 WHERE id = $2",
+			time.Now().Add(time.Duration(i-len(created))*time.Minute), created[i]); err != nil {
+			t.Fatalf("Failed to age note: %v", err)
+		}
+	}
+
+	list, err := noteService.SearchNotes(ctx, userID, &models.SearchNotesRequest{Query: "secret", Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to search notes: %v", err)
+	}
+	if list.Total != noteService.maxPrivateSearch {
+		t.Errorf("Expected the %d most recent private notes to match, got %d", noteService.maxPrivateSearch, list.Total)
+	}
+
+	old, err := noteService.SearchNotes(ctx, userID, &models.SearchNotesRequest{Query: `"plan 0"`, Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to search notes: %v", err)
+	}
+	recent, err := noteService.SearchNotes(ctx, userID, &models.SearchNotesRequest{Query: fmt.Sprintf(`"plan %d"`, len(created)-1), Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to search notes: %v", err)
+	}
+	if old.Total != 0 || recent.Total != 1 || recent.Notes[0].ID.String() != created[len(created)-1] {
+		t.Errorf("Expected only recent private notes to match, got %d old and %d recent", old.Total, recent.Total)
+	}
+}
+
+func TestNoteSearchOrderBy(t *testing.T) {
+	filter := &noteSearch{request: &models.SearchNotesRequest{OrderBy: "updated_at", OrderDir: "asc"}}
+	if orderBy, err := filter.orderBy(database.Postgres); err != nil || orderBy != "updated_at asc" {
+		t.Errorf("orderBy() = %q, %v", orderBy, err)
+	}
+
+	filter.request.SortProperty = "priority"
+	if _, err := filter.orderBy(database.Postgres); err == nil {
+		t.Error("Expected an error sorting by an unknown property")
+	}
+
+	filter.schema = models.PropertySchema{"priority": {Name: "priority", Type: models.PropertyNumber}}
+	orderBy, err := filter.orderBy(database.Postgres)
+	if err != nil {
+		t.Fatalf("orderBy failed: %v", err)
+	}
+	want := "CAST((properties->>'priority') AS NUMERIC) IS NULL, CAST((properties->>'priority') AS NUMERIC) asc, created_at DESC"
+	if orderBy != want {
+		t.Errorf("orderBy() = %q, want %q", orderBy, want)
+	}
+}
@@ -3,107 +3,261 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/language"
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrNoteNotFound is returned for notes that do not exist or belong to
+	// another user
+	ErrNoteNotFound = apperrors.NotFound("NOTE_NOT_FOUND", "note not found")
+	// ErrVersionMismatch is returned when an update names a version of the
+	// note other than the current one, or another update wins a race
+	ErrVersionMismatch = apperrors.Conflict("NOTE_VERSION_CONFLICT", "note has been modified by another process (version mismatch)")
+)
+
+// Note validation error codes
+const (
+	// codeInvalidNote is the code of notes failing validation
+	codeInvalidNote = "INVALID_NOTE"
+	// codeNoteTooLarge is the code of notes whose content is longer than
+	// models.MaxContentLength
+	codeNoteTooLarge = "NOTE_TOO_LARGE"
+)
+
 // NoteServiceInterface defines the interface for note service operations
 type NoteServiceInterface interface {
-	CreateNote(userID string, request *models.CreateNoteRequest) (*models.Note, error)
-	GetNoteByID(userID, noteID string) (*models.Note, error)
-	UpdateNote(userID, noteID string, request *models.UpdateNoteRequest) (*models.Note, error)
-	DeleteNote(userID, noteID string) error
-	ListNotes(userID string, limit, offset int, orderBy, orderDir string) (*models.NoteList, error)
-	SearchNotes(userID string, request *models.SearchNotesRequest) (*models.NoteList, error)
-	GetNotesByTag(userID, tag string, limit, offset int) (*models.NoteList, error)
-	GetNotesWithTimestamp(userID string, since time.Time) ([]models.Note, error)
-	BatchCreateNotes(userID string, requests []*models.CreateNoteRequest) ([]models.Note, error)
-	BatchUpdateNotes(userID string, requests []struct {
+	CreateNote(ctx context.Context, userID string, request *models.CreateNoteRequest) (*models.Note, error)
+	CreateNoteInTx(ctx context.Context, userID string, request *models.CreateNoteRequest, inTx func(ctx context.Context, tx *sql.Tx, note *models.Note) error) (*models.Note, error)
+	GetNoteByID(ctx context.Context, userID, noteID string) (*models.Note, error)
+	UpdateNote(ctx context.Context, userID, noteID string, request *models.UpdateNoteRequest) (*models.Note, error)
+	InsertContent(ctx context.Context, userID, noteID, text string, prepend bool) (*models.Note, error)
+	DuplicateNote(ctx context.Context, userID, noteID string, request *models.DuplicateNoteRequest) (*models.Note, error)
+	DeleteNote(ctx context.Context, userID, noteID string) error
+	BatchDeleteNotes(ctx context.Context, userID string, noteIDs []string) (int, error)
+	ListNotes(ctx context.Context, userID string, limit, offset int, orderBy, orderDir string) (*models.NoteList, error)
+	SearchNotes(ctx context.Context, userID string, request *models.SearchNotesRequest) (*models.NoteList, error)
+	GetNotesByTag(ctx context.Context, userID, tag string, includeChildren bool, limit, offset int) (*models.NoteList, error)
+	GetNotesWithTimestamp(ctx context.Context, userID string, since time.Time) ([]models.Note, error)
+	BatchCreateNotes(ctx context.Context, userID string, requests []*models.CreateNoteRequest) ([]models.Note, error)
+	BatchUpdateNotes(ctx context.Context, userID string, requests []struct {
 		NoteID  string
 		Request *models.UpdateNoteRequest
 	}) ([]models.Note, error)
-	IncrementVersion(noteID string) error
-	GetNotesForSync(userID string, limit, offset int, since *time.Time, includeDeleted bool) ([]models.Note, int, error)
-	DetectConflicts(userID string, notes []models.Note) ([]models.NoteConflict, error)
+	MergeTags(ctx context.Context, userID, source, target string) (int, error)
+	RenameTag(ctx context.Context, userID, tagID string, request *models.UpdateTagRequest) (*models.Tag, int, error)
+	IncrementVersion(ctx context.Context, noteID string) error
+	GetNotesForSync(ctx context.Context, userID string, limit, offset int, since *time.Time, includeDeleted bool) ([]models.Note, int, error)
+	GetDeletedNoteIDs(ctx context.Context, userID string, since time.Time) ([]uuid.UUID, error)
+	GetTagsForNotes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+	GetNotesByIDs(ctx context.Context, userID string, noteIDs []uuid.UUID) ([]models.Note, error)
+	DetectConflicts(ctx context.Context, userID string, notes []models.Note) ([]models.NoteConflict, error)
+}
+
+// NoteWriteListener is notified after a note has been created or updated.
+// Listeners run synchronously and must handle their own errors.
+type NoteWriteListener interface {
+	NoteWritten(ctx context.Context, note *models.Note)
+}
+
+// NoteTitleGenerator writes titles for notes created without one
+type NoteTitleGenerator interface {
+	GenerateTitle(ctx context.Context, userID, content string) (string, error)
 }
 
 // NoteService handles note-related operations
 type NoteService struct {
-	db         *sql.DB
-	tagService TagServiceInterface
+	db             *sql.DB
+	dialect        database.Dialect
+	tagService     TagServiceInterface
+	encryptor      *encryption.NoteEncryptor
+	writeListeners []NoteWriteListener
+	legalHolds     LegalHoldChecker
+	focusTimes     FocusTimeSource
+	titles         NoteTitleGenerator
+	titleTimeout   time.Duration
+	// maxPrivateSearch is the number of private notes decrypted to match the
+	// text terms of a search
+	maxPrivateSearch int
 }
 
 // NewNoteService creates a new NoteService instance
 func NewNoteService(db *sql.DB, tagService TagServiceInterface) *NoteService {
 	return &NoteService{
-		db:         db,
-		tagService: tagService,
+		db:               db,
+		dialect:          database.DialectOf(db),
+		tagService:       tagService,
+		maxPrivateSearch: defaultMaxPrivateSearch,
 	}
 }
 
-// CreateNote creates a new note for a user
-func (s *NoteService) CreateNote(userID string, request *models.CreateNoteRequest) (*models.Note, error) {
-	ctx := context.Background()
+// SetEncryptor sets the encryptor used for private notes. Without one, private
+// notes cannot be written and are returned locked and excluded from search.
+func (s *NoteService) SetEncryptor(encryptor *encryption.NoteEncryptor) {
+	s.encryptor = encryptor
+}
+
+// SetLegalHolds sets the checker preventing deletion of notes under legal hold
+func (s *NoteService) SetLegalHolds(legalHolds LegalHoldChecker) {
+	s.legalHolds = legalHolds
+}
 
+// SetFocusTimes sets the source of the time spent on notes, which listed
+// notes include as total_time_seconds
+func (s *NoteService) SetFocusTimes(focusTimes FocusTimeSource) {
+	s.focusTimes = focusTimes
+}
+
+// SetTitleGenerator sets the generator writing the titles of notes created
+// without one. Titles taking longer than timeout fall back to the first line
+// of the note.
+func (s *NoteService) SetTitleGenerator(titles NoteTitleGenerator, timeout time.Duration) {
+	s.titles = titles
+	s.titleTimeout = timeout
+}
+
+// AddWriteListener registers a listener called after every note create or update
+func (s *NoteService) AddWriteListener(listener NoteWriteListener) {
+	s.writeListeners = append(s.writeListeners, listener)
+}
+
+// CreateNote creates a new note for a user. Notes without a title are
+// titled by the title generator, when set, or after their first line.
+func (s *NoteService) CreateNote(ctx context.Context, userID string, request *models.CreateNoteRequest) (*models.Note, error) {
+	if s.titles != nil && request.Title == "" {
+		request = s.withGeneratedTitle(ctx, userID, request)
+	}
+	return s.CreateNoteInTx(ctx, userID, request, nil)
+}
+
+// withGeneratedTitle returns a copy of the request with a generated title.
+// Private notes are not sent to the LLM, and notes of a single short line
+// are their own title; the request is returned as it is for those and when
+// generating fails.
+func (s *NoteService) withGeneratedTitle(ctx context.Context, userID string, request *models.CreateNoteRequest) *models.CreateNoteRequest {
+	content := strings.TrimSpace(request.Content)
+	if request.Private || content == "" || models.CheckContentLength(request.Content) != nil ||
+		(!strings.Contains(content, "\n") && len(content) <= maxOwnTitleLength) {
+		return request
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.titleTimeout)
+	defer cancel()
+	title, err := s.titles.GenerateTitle(ctx, userID, content)
+	if err != nil {
+		log.Printf("[NoteService] WARNING: Failed to generate a title for user %s, using the first line: %v", userID, err)
+		return request
+	}
+	titled := *request
+	titled.Title = title
+	return &titled
+}
+
+// CreateNoteInTx creates a new note for a user and calls inTx, when set, with
+// the note in the transaction writing it. An error from inTx rolls the note
+// back.
+func (s *NoteService) CreateNoteInTx(ctx context.Context, userID string, request *models.CreateNoteRequest, inTx func(ctx context.Context, tx *sql.Tx, note *models.Note) error) (*models.Note, error) {
 	// Convert request to note model
 	note := request.ToNote(uuid.MustParse(userID))
 
 	// Validate note
 	if err := note.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid note: %w", err)
+		return nil, invalidNote(err, fmt.Sprintf("invalid note: %v", err))
+	}
+	properties, err := s.normalizeProperties(ctx, userID, note.Properties)
+	if err != nil {
+		return nil, err
+	}
+	note.Properties = properties
+
+	// Detect content language for full-text search stemming
+	note.DetectLanguage()
+
+	// Encrypt content of private notes before it reaches the database
+	storedContent, err := s.sealContent(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+
+	// The note and its tags are written in one transaction, so a note is
+	// never left without its tags
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = checkNoteNotebook(ctx, tx, userID, note); err != nil {
+		return nil, err
 	}
 
 	// Insert note into database
 	query := `
-		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, title, content, created_at, updated_at, version
+		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version, language, is_private, metadata, properties, color, icon, notebook_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + noteColumns + `
 	`
 
-	err := s.db.QueryRowContext(ctx, query,
-		note.ID, note.UserID, note.Title, note.Content,
-		note.CreatedAt, note.UpdatedAt, note.Version).Scan(
-		&note.ID, &note.UserID, &note.Title, &note.Content,
-		&note.CreatedAt, &note.UpdatedAt, &note.Version)
+	err = s.readNote(ctx, tx.QueryRowContext(ctx, query,
+		note.ID, note.UserID, note.Title, storedContent,
+		note.CreatedAt, note.UpdatedAt, note.Version, note.Language, note.IsPrivate, note.Metadata, note.Properties, note.Color, note.Icon, note.NotebookID), note)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create note: %w", err)
 	}
 
-	// Extract and process hashtags using TagService
+	// Extract and process hashtags
 	tags := s.tagService.ExtractTagsFromContent(note.Content)
-	if len(tags) > 0 {
-		if err := s.tagService.ProcessTagsForNote(note.ID.String(), tags); err != nil {
-			// Log error but don't fail note creation
-			fmt.Printf("Warning: failed to process tags for note %s: %v\n", note.ID, err)
+	if err := s.tagService.AddNoteTags(ctx, tx, userID, note.ID.String(), tags); err != nil {
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	if inTx != nil {
+		if err := inTx(ctx, tx, note); err != nil {
+			return nil, err
 		}
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit note creation: %w", err)
+	}
+
+	s.notifyWrite(ctx, note)
+
 	return note, nil
 }
 
-// GetNoteByID retrieves a note by ID for a specific user
-func (s *NoteService) GetNoteByID(userID, noteID string) (*models.Note, error) {
-	ctx := context.Background()
+// GetNoteByID retrieves a note by ID for a specific user, who may read their
+// own notes and those in notebooks their organizations share
+func (s *NoteService) GetNoteByID(ctx context.Context, userID, noteID string) (*models.Note, error) {
+	// Malformed IDs cannot name a note
+	if _, err := uuid.Parse(noteID); err != nil {
+		return nil, ErrNoteNotFound
+	}
 
 	var note models.Note
 	query := `
-		SELECT id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SELECT ` + noteColumns + `
 		FROM notes
-		WHERE id = $1 AND user_id = $2
+		WHERE id = $1 AND ` + noteAccess("", 2) + `
 	`
 
-	err := s.db.QueryRowContext(ctx, query, noteID, userID).Scan(
-		&note.ID, &note.UserID, &note.Title, &note.Content,
-		&note.CreatedAt, &note.UpdatedAt, &note.Version,
-		&note.PrettifiedAt, &note.AIImproved)
+	err := s.readNote(ctx, s.db.QueryRowContext(ctx, query, noteID, userID), &note)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("note not found")
+		return nil, ErrNoteNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get note: %w", err)
 	}
@@ -112,28 +266,42 @@ func (s *NoteService) GetNoteByID(userID, noteID string) (*models.Note, error) {
 }
 
 // UpdateNote updates an existing note with optimistic locking
-func (s *NoteService) UpdateNote(userID, noteID string, request *models.UpdateNoteRequest) (*models.Note, error) {
-	ctx := context.Background()
-
+func (s *NoteService) UpdateNote(ctx context.Context, userID, noteID string, request *models.UpdateNoteRequest) (*models.Note, error) {
 	// Get current note first
-	currentNote, err := s.GetNoteByID(userID, noteID)
+	currentNote, err := s.GetNoteByID(ctx, userID, noteID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkNoteWrite(ctx, s.db, userID, currentNote); err != nil {
+		return nil, err
+	}
+
+	// Locked private notes cannot be re-encrypted without the key
+	if currentNote.Locked {
+		return nil, fmt.Errorf("cannot update private note: %w", encryption.ErrKeyUnavailable)
+	}
+
 	// Check version if provided
 	if request.Version != nil && *request.Version != currentNote.Version {
-		return nil, fmt.Errorf("note has been modified by another process (version mismatch)")
+		return nil, ErrVersionMismatch
 	}
 
 	// Apply updates
 	if !request.ApplyUpdates(currentNote) {
-		return nil, fmt.Errorf("no updates provided")
+		return nil, apperrors.Validation(codeInvalidNote, "no updates provided")
 	}
 
 	// Validate updated note
 	if err := currentNote.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid updated note: %w", err)
+		return nil, invalidNote(err, fmt.Sprintf("invalid updated note: %v", err))
+	}
+	// Properties and tags are the author's, also when a member edits
+	authorID := currentNote.UserID.String()
+	if len(request.Properties) > 0 {
+		if currentNote.Properties, err = s.normalizeProperties(ctx, authorID, currentNote.Properties); err != nil {
+			return nil, err
+		}
 	}
 
 	// Increment version for optimistic locking
@@ -143,57 +311,170 @@ func (s *NoteService) UpdateNote(userID, noteID string, request *models.UpdateNo
 	currentNote.AIImproved = false
 	currentNote.PrettifiedAt = nil
 
-	// Update in database
+	// Re-detect language since content may have changed
+	currentNote.DetectLanguage()
+
+	// Encrypt content of private notes before it reaches the database
+	storedContent, err := s.sealContent(ctx, currentNote)
+	if err != nil {
+		return nil, err
+	}
+
+	// The note and its tags are written in one transaction
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if request.NotebookID != nil || request.Private != nil {
+		if err := checkNoteNotebook(ctx, tx, userID, currentNote); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update in database. Image text is stored unencrypted, so it is
+	// dropped when a note becomes private.
 	query := `
 		UPDATE notes
-		SET title = $1, content = $2, updated_at = $3, version = $4, prettified_at = $5, ai_improved = $6
-		WHERE id = $7 AND user_id = $8 AND version = $9 - 1
-		RETURNING id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SET title = $1, content = $2, updated_at = $3, version = $4, prettified_at = $5, ai_improved = $6, language = $7, is_private = $8, properties = $9, color = $10, icon = $11, notebook_id = $12,
+			image_text = CASE WHEN $8 THEN NULL ELSE image_text END
+		WHERE id = $13 AND user_id = $14 AND version = $15 - 1
+		RETURNING ` + noteColumns + `
 	`
 
-	err = s.db.QueryRowContext(ctx, query,
-		currentNote.Title, currentNote.Content, currentNote.UpdatedAt,
-		currentNote.Version, currentNote.PrettifiedAt, currentNote.AIImproved,
-		currentNote.ID, currentNote.UserID, currentNote.Version).Scan(
-		&currentNote.ID, &currentNote.UserID, &currentNote.Title, &currentNote.Content,
-		&currentNote.CreatedAt, &currentNote.UpdatedAt, &currentNote.Version,
-		&currentNote.PrettifiedAt, &currentNote.AIImproved)
+	err = s.readNote(ctx, tx.QueryRowContext(ctx, query,
+		currentNote.Title, storedContent, currentNote.UpdatedAt,
+		currentNote.Version, currentNote.PrettifiedAt, currentNote.AIImproved, currentNote.Language, currentNote.IsPrivate,
+		currentNote.Properties, currentNote.Color, currentNote.Icon, currentNote.NotebookID, currentNote.ID, currentNote.UserID, currentNote.Version), currentNote)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("note has been modified by another process (concurrent update)")
+			return nil, ErrVersionMismatch
 		}
 		return nil, fmt.Errorf("failed to update note: %w", err)
 	}
 
-	// Process hashtags for updated content using TagService
+	// Process hashtags for updated content
 	tags := s.tagService.ExtractTagsFromContent(currentNote.Content)
-	if err := s.tagService.UpdateTagsForNote(currentNote.ID.String(), tags); err != nil {
-		// Log error but don't fail note update
-		fmt.Printf("Warning: failed to update tags for note %s: %v\n", currentNote.ID, err)
+	if err := s.tagService.ReplaceNoteTags(ctx, tx, authorID, currentNote.ID.String(), tags); err != nil {
+		return nil, fmt.Errorf("failed to update note: %w", err)
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit note update: %w", err)
+	}
+
+	s.notifyWrite(ctx, currentNote)
+
 	return currentNote, nil
 }
 
-// DeleteNote soft deletes a note by moving it to trash (or hard delete if preferred)
-func (s *NoteService) DeleteNote(userID, noteID string) error {
-	ctx := context.Background()
+// maxInsertAttempts is how often InsertContent tries its update when the
+// note keeps changing meanwhile
+const maxInsertAttempts = 3
+
+// InsertContent appends text on a new line of a note, or prepends it when
+// prepend is set. The change is made on the stored content as a versioned
+// update, retried when the note changes meanwhile, so clients need not send
+// the whole content or its version.
+func (s *NoteService) InsertContent(ctx context.Context, userID, noteID, text string, prepend bool) (*models.Note, error) {
+	for attempt := 1; ; attempt++ {
+		note, err := s.GetNoteByID(ctx, userID, noteID)
+		if err != nil {
+			return nil, err
+		}
+
+		content := models.InsertContent(note.Content, text, prepend)
+		version := note.Version
+		updated, err := s.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{
+			Content: &content,
+			Version: &version,
+		})
+		if errors.Is(err, ErrVersionMismatch) && attempt < maxInsertAttempts {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// DuplicateNote copies a note the user can read into a new note of theirs,
+// with the original's tags. The copy goes to the original's notebook when
+// the user can write there, and to their default notebook otherwise, unless
+// the request names a notebook.
+func (s *NoteService) DuplicateNote(ctx context.Context, userID, noteID string, request *models.DuplicateNoteRequest) (*models.Note, error) {
+	source, err := s.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locked private notes cannot be copied without the key
+	if source.Locked {
+		return nil, fmt.Errorf("cannot duplicate private note: %w", encryption.ErrKeyUnavailable)
+	}
+
+	tags, err := s.GetTagsForNotes(ctx, []uuid.UUID{source.ID})
+	if err != nil {
+		return nil, err
+	}
 
-	// Verify note exists and belongs to user
-	_, err := s.GetNoteByID(userID, noteID)
+	duplicate := source.DuplicateRequest(request, source.UserID.String() == userID)
+	if duplicate.NotebookID == nil {
+		target := &models.Note{UserID: uuid.MustParse(userID), NotebookID: source.NotebookID, IsPrivate: source.IsPrivate}
+		err := checkNoteNotebook(ctx, s.db, userID, target)
+		if err == nil {
+			duplicate.NotebookID = &source.NotebookID
+		} else if !errors.Is(err, apperrors.ErrValidation) && !errors.Is(err, apperrors.ErrForbidden) {
+			return nil, err
+		}
+	}
+
+	// Tags not written in the content, such as those added through the tags
+	// API, are copied too
+	return s.CreateNoteInTx(ctx, userID, duplicate, func(ctx context.Context, tx *sql.Tx, note *models.Note) error {
+		return s.tagService.AddNoteTags(ctx, tx, userID, note.ID.String(), tags[source.ID])
+	})
+}
+
+// DeleteNote soft deletes a note by moving it to trash (or hard delete if preferred)
+func (s *NoteService) DeleteNote(ctx context.Context, userID, noteID string) error {
+	// Verify note exists and the user may write it
+	note, err := s.GetNoteByID(ctx, userID, noteID)
 	if err != nil {
 		return err
 	}
+	if err := checkNoteWrite(ctx, s.db, userID, note); err != nil {
+		return err
+	}
+	authorID := note.UserID.String()
+
+	if s.legalHolds != nil {
+		held, err := s.legalHolds.HeldNoteIDs(ctx, authorID, []string{noteID})
+		if err != nil {
+			return err
+		}
+		if len(held) > 0 {
+			return fmt.Errorf("note is %w", ErrLegalHold)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	// Delete note tags first
-	if err := s.deleteAllNoteTags(ctx, noteID); err != nil {
-		fmt.Printf("Warning: failed to delete tags for note %s: %v\n", noteID, err)
+	if err := s.tagService.DeleteNoteTags(ctx, tx, noteID); err != nil {
+		return err
 	}
 
-	// Delete the note
+	// Leave a tombstone for sync, then delete the note
+	if err := s.addTombstones(ctx, tx, authorID, []string{noteID}); err != nil {
+		return err
+	}
 	query := `DELETE FROM notes WHERE id = $1 AND user_id = $2`
-	result, err := s.db.ExecContext(ctx, query, noteID, userID)
+	result, err := tx.ExecContext(ctx, query, noteID, authorID)
 	if err != nil {
 		return fmt.Errorf("failed to delete note: %w", err)
 	}
@@ -204,16 +485,68 @@ func (s *NoteService) DeleteNote(userID, noteID string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("note not found")
+		return ErrNoteNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note deletion: %w", err)
 	}
 
 	return nil
 }
 
-// ListNotes retrieves a paginated list of notes for a user
-func (s *NoteService) ListNotes(userID string, limit, offset int, orderBy, orderDir string) (*models.NoteList, error) {
-	ctx := context.Background()
+// BatchDeleteNotes deletes several notes in a single transaction and returns
+// how many were deleted. IDs of notes the user does not own are ignored.
+func (s *NoteService) BatchDeleteNotes(ctx context.Context, userID string, noteIDs []string) (int, error) {
+	for _, noteID := range noteIDs {
+		if _, err := uuid.Parse(noteID); err != nil {
+			return 0, apperrors.Validation(codeInvalidNote, fmt.Sprintf("invalid note ID %q", noteID))
+		}
+	}
+
+	// A batch containing held notes is rejected as a whole
+	if s.legalHolds != nil {
+		held, err := s.legalHolds.HeldNoteIDs(ctx, userID, noteIDs)
+		if err != nil {
+			return 0, err
+		}
+		if len(held) > 0 {
+			return 0, fmt.Errorf("%d of the notes are %w", len(held), ErrLegalHold)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
+	if err := s.addTombstones(ctx, tx, userID, noteIDs); err != nil {
+		return 0, err
+	}
+
+	// note_tags rows are removed by ON DELETE CASCADE
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM notes WHERE `+s.dialect.AnyOf("id", 1, "uuid")+` AND user_id = $2
+	`, s.dialect.Array(noteIDs), userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notes in batch: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit batch delete: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// ListNotes retrieves a paginated list of notes for a user
+func (s *NoteService) ListNotes(ctx context.Context, userID string, limit, offset int, orderBy, orderDir string) (*models.NoteList, error) {
 	// Validate pagination parameters
 	if limit <= 0 || limit > 100 {
 		limit = 20
@@ -245,16 +578,16 @@ func (s *NoteService) ListNotes(userID string, limit, offset int, orderBy, order
 
 	// Get total count
 	var total int
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes WHERE user_id = $1", userID).Scan(&total)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes WHERE "+noteAccess("", 1), userID).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total notes count: %w", err)
 	}
 
 	// Get notes with pagination
 	query := fmt.Sprintf(`
-		SELECT id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SELECT ` + noteColumns + `
 		FROM notes
-		WHERE user_id = $1
+		WHERE ` + noteAccess("", 1) + `
 		ORDER BY %s %s
 		LIMIT $2 OFFSET $3
 	`, orderBy, orderDir)
@@ -268,29 +601,19 @@ func (s *NoteService) ListNotes(userID string, limit, offset int, orderBy, order
 	var notes []models.NoteResponse
 	for rows.Next() {
 		var note models.Note
-		err := rows.Scan(&note.ID, &note.UserID, &note.Title, &note.Content,
-			&note.CreatedAt, &note.UpdatedAt, &note.Version,
-			&note.PrettifiedAt, &note.AIImproved)
+		err := s.readNote(ctx, rows, &note)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan note: %w", err)
 		}
 
-		// Get tags for this note
-		tags, err := s.getNoteTags(ctx, note.ID.String())
-		if err != nil {
-			// Log error but continue without tags
-			fmt.Printf("Warning: failed to get tags for note %s: %v\n", note.ID, err)
-			tags = []string{}
-		}
-
-		noteResponse := note.ToResponse()
-		noteResponse.Tags = tags
-		notes = append(notes, noteResponse)
+		notes = append(notes, note.ToResponse())
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating notes: %w", err)
 	}
+	s.addNoteTags(ctx, notes)
+	s.addTotalTimes(ctx, notes)
 
 	// Calculate pagination info
 	page := (offset / limit) + 1
@@ -306,69 +629,63 @@ func (s *NoteService) ListNotes(userID string, limit, offset int, orderBy, order
 }
 
 // SearchNotes searches notes by content, title, and tags
-func (s *NoteService) SearchNotes(userID string, request *models.SearchNotesRequest) (*models.NoteList, error) {
-	ctx := context.Background()
-
+func (s *NoteService) SearchNotes(ctx context.Context, userID string, request *models.SearchNotesRequest) (*models.NoteList, error) {
 	// Validate request manually
 	if err := request.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid search request: %w", err)
-	}
-
-	// Build search query
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	// Always include user filter
-	conditions = append(conditions, fmt.Sprintf("user_id = $%d", argIndex))
-	args = append(args, userID)
-	argIndex++
-
-	// Add text search if query provided
-	if request.Query != "" {
-		conditions = append(conditions, fmt.Sprintf("(title ILIKE $%d OR content ILIKE $%d)", argIndex, argIndex+1))
-		args = append(args, "%"+request.Query+"%", "%"+request.Query+"%")
-		argIndex += 2
-	}
-
-	// Add tag filter if tags provided
-	if len(request.Tags) > 0 {
-		// Join with note_tags and tags tables
-		conditions = append(conditions, fmt.Sprintf(`
-			id IN (
-				SELECT note_id FROM note_tags nt
-				JOIN tags t ON nt.tag_id = t.id
-				WHERE t.name IN (%s)
-				GROUP BY note_id
-				HAVING COUNT(DISTINCT t.id) = $%d
-			)
-		`, strings.Repeat("?,", len(request.Tags)-1)+"?", argIndex))
+		return nil, apperrors.Validation("INVALID_SEARCH", fmt.Sprintf("invalid search request: %v", err))
+	}
+
+	// Parse the query language (phrases, regexes, tag:, title:, is:, -negation, dates)
+	parsed, err := search.Parse(request.Query)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, tag := range request.Tags {
-			args = append(args, tag)
+	// Private notes are searched when their content can be decrypted
+	filter := &noteSearch{
+		userID:        userID,
+		request:       request,
+		query:         parsed,
+		searchPrivate: s.encryptor.Available(),
+	}
+	if filter.searchPrivate && parsed.HasTextTerms() {
+		if filter.privateIDs, err = s.matchPrivateNotes(ctx, userID, parsed); err != nil {
+			return nil, err
+		}
+	}
+	if len(request.Properties) > 0 || request.SortProperty != "" {
+		if filter.schema, err = loadPropertySchema(ctx, s.db, userID); err != nil {
+			return nil, err
 		}
-		argIndex++
 	}
 
-	// Combine conditions
-	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+	conditions, err := filter.conditions(s.dialect)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := filter.orderBy(s.dialect)
+	if err != nil {
+		return nil, err
+	}
+	whereClause := conditions.Where()
+	args := conditions.Args()
 
 	// Get total count
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM notes %s", whereClause)
 	var total int
-	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	err = s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get search results count: %w", err)
 	}
 
 	// Build the main query
 	query := fmt.Sprintf(`
-		SELECT DISTINCT id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SELECT ` + noteColumns + `
 		FROM notes
 		%s
-		ORDER BY %s %s
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, whereClause, request.OrderBy, request.OrderDir, argIndex, argIndex+1)
+	`, whereClause, orderBy, conditions.Next(), conditions.Next()+1)
 
 	args = append(args, request.Limit, request.Offset)
 
@@ -382,28 +699,19 @@ func (s *NoteService) SearchNotes(userID string, request *models.SearchNotesRequ
 	var notes []models.NoteResponse
 	for rows.Next() {
 		var note models.Note
-		err := rows.Scan(&note.ID, &note.UserID, &note.Title, &note.Content,
-			&note.CreatedAt, &note.UpdatedAt, &note.Version,
-			&note.PrettifiedAt, &note.AIImproved)
+		err := s.readNote(ctx, rows, &note)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan note: %w", err)
 		}
 
-		// Get tags for this note
-		tags, err := s.getNoteTags(ctx, note.ID.String())
-		if err != nil {
-			fmt.Printf("Warning: failed to get tags for note %s: %v\n", note.ID, err)
-			tags = []string{}
-		}
-
-		noteResponse := note.ToResponse()
-		noteResponse.Tags = tags
-		notes = append(notes, noteResponse)
+		notes = append(notes, note.ToResponse())
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating search results: %w", err)
 	}
+	s.addNoteTags(ctx, notes)
+	s.addTotalTimes(ctx, notes)
 
 	// Calculate pagination info
 	page := (request.Offset / request.Limit) + 1
@@ -418,10 +726,10 @@ func (s *NoteService) SearchNotes(userID string, request *models.SearchNotesRequ
 	}, nil
 }
 
-// GetNotesByTag retrieves notes filtered by a specific tag
-func (s *NoteService) GetNotesByTag(userID, tag string, limit, offset int) (*models.NoteList, error) {
-	ctx := context.Background()
-
+// GetNotesByTag retrieves notes filtered by a specific tag. With
+// includeChildren, notes carrying a tag nested below it match as well, e.g.
+// #work/projecta when filtering by #work.
+func (s *NoteService) GetNotesByTag(ctx context.Context, userID, tag string, includeChildren bool, limit, offset int) (*models.NoteList, error) {
 	// Validate pagination parameters
 	if limit <= 0 || limit > 100 {
 		limit = 20
@@ -430,26 +738,40 @@ func (s *NoteService) GetNotesByTag(userID, tag string, limit, offset int) (*mod
 		offset = 0
 	}
 
+	// Shared notes carry their authors' tags, so tags match by name, which
+	// ignores case
+	tagCondition := "LOWER(t.name) = LOWER($2)"
+	if includeChildren {
+		tagCondition = `t.id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM tags WHERE LOWER(name) = LOWER($2)
+				UNION ALL
+				SELECT c.id FROM tags c JOIN subtree st ON c.parent_id = st.id
+			)
+			SELECT id FROM subtree
+		)`
+	}
+	tagFilter := `n.id IN (
+		SELECT nt.note_id FROM note_tags nt
+		JOIN tags t ON nt.tag_id = t.id
+		WHERE ` + tagCondition + `
+	)`
+
 	// Get total count
 	var total int
 	err := s.db.QueryRowContext(ctx, `
-		SELECT COUNT(DISTINCT n.id)
+		SELECT COUNT(*)
 		FROM notes n
-		JOIN note_tags nt ON n.id = nt.note_id
-		JOIN tags t ON nt.tag_id = t.id
-		WHERE n.user_id = $1 AND t.name = $2
-	`, userID, tag).Scan(&total)
+		WHERE `+noteAccess("n.", 1)+` AND `+tagFilter, userID, tag).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total notes count for tag: %w", err)
 	}
 
 	// Get notes with tag filter
 	query := `
-		SELECT n.id, n.user_id, n.title, n.content, n.created_at, n.updated_at, n.version, n.prettified_at, n.ai_improved
+		SELECT ` + qualifiedNoteColumns("n") + `
 		FROM notes n
-		JOIN note_tags nt ON n.id = nt.note_id
-		JOIN tags t ON nt.tag_id = t.id
-		WHERE n.user_id = $1 AND t.name = $2
+		WHERE ` + noteAccess("n.", 1) + ` AND ` + tagFilter + `
 		ORDER BY n.updated_at DESC
 		LIMIT $3 OFFSET $4
 	`
@@ -463,28 +785,19 @@ func (s *NoteService) GetNotesByTag(userID, tag string, limit, offset int) (*mod
 	var notes []models.NoteResponse
 	for rows.Next() {
 		var note models.Note
-		err := rows.Scan(&note.ID, &note.UserID, &note.Title, &note.Content,
-			&note.CreatedAt, &note.UpdatedAt, &note.Version,
-			&note.PrettifiedAt, &note.AIImproved)
+		err := s.readNote(ctx, rows, &note)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan note: %w", err)
 		}
 
-		// Get all tags for this note
-		tags, err := s.getNoteTags(ctx, note.ID.String())
-		if err != nil {
-			fmt.Printf("Warning: failed to get tags for note %s: %v\n", note.ID, err)
-			tags = []string{}
-		}
-
-		noteResponse := note.ToResponse()
-		noteResponse.Tags = tags
-		notes = append(notes, noteResponse)
+		notes = append(notes, note.ToResponse())
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating notes by tag: %w", err)
 	}
+	s.addNoteTags(ctx, notes)
+	s.addTotalTimes(ctx, notes)
 
 	// Calculate pagination info
 	page := (offset / limit) + 1
@@ -500,11 +813,9 @@ func (s *NoteService) GetNotesByTag(userID, tag string, limit, offset int) (*mod
 }
 
 // GetNotesWithTimestamp retrieves notes updated since a given timestamp (for sync)
-func (s *NoteService) GetNotesWithTimestamp(userID string, since time.Time) ([]models.Note, error) {
-	ctx := context.Background()
-
+func (s *NoteService) GetNotesWithTimestamp(ctx context.Context, userID string, since time.Time) ([]models.Note, error) {
 	query := `
-		SELECT id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SELECT ` + noteColumns + `
 		FROM notes
 		WHERE user_id = $1 AND updated_at > $2
 		ORDER BY updated_at ASC
@@ -519,9 +830,7 @@ func (s *NoteService) GetNotesWithTimestamp(userID string, since time.Time) ([]m
 	var notes []models.Note
 	for rows.Next() {
 		var note models.Note
-		err := rows.Scan(&note.ID, &note.UserID, &note.Title, &note.Content,
-			&note.CreatedAt, &note.UpdatedAt, &note.Version,
-			&note.PrettifiedAt, &note.AIImproved)
+		err := s.readNote(ctx, rows, &note)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan note: %w", err)
 		}
@@ -536,9 +845,7 @@ func (s *NoteService) GetNotesWithTimestamp(userID string, since time.Time) ([]m
 }
 
 // BatchCreateNotes creates multiple notes in a single transaction
-func (s *NoteService) BatchCreateNotes(userID string, requests []*models.CreateNoteRequest) ([]models.Note, error) {
-	ctx := context.Background()
-
+func (s *NoteService) BatchCreateNotes(ctx context.Context, userID string, requests []*models.CreateNoteRequest) ([]models.Note, error) {
 	// Start transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -551,13 +858,13 @@ func (s *NoteService) BatchCreateNotes(userID string, requests []*models.CreateN
 	for i, request := range requests {
 		// Validate request manually
 		if request.Content == "" {
-			return nil, fmt.Errorf("invalid request in batch at index %d: content is required", i)
+			return nil, apperrors.Validation(codeInvalidNote, fmt.Sprintf("invalid request in batch at index %d: content is required", i))
 		}
-		if len(request.Content) > 10000 {
-			return nil, fmt.Errorf("invalid request in batch at index %d: content too long (max 10000 characters)", i)
+		if err := models.CheckContentLength(request.Content); err != nil {
+			return nil, invalidNote(err, fmt.Sprintf("invalid request in batch at index %d: %v", i, err))
 		}
 		if len(request.Title) > 500 {
-			return nil, fmt.Errorf("invalid request in batch at index %d: title too long (max 500 characters)", i)
+			return nil, apperrors.Validation(codeInvalidNote, fmt.Sprintf("invalid request in batch at index %d: title too long (max 500 characters)", i))
 		}
 
 		// Convert to note model
@@ -565,26 +872,44 @@ func (s *NoteService) BatchCreateNotes(userID string, requests []*models.CreateN
 
 		// Validate note
 		if err := note.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid note in batch: %w", err)
+			return nil, invalidNote(err, fmt.Sprintf("invalid note in batch: %v", err))
+		}
+		if note.Properties, err = s.normalizeProperties(ctx, userID, note.Properties); err != nil {
+			return nil, err
+		}
+
+		// Detect content language for full-text search stemming
+		note.DetectLanguage()
+
+		// Encrypt content of private notes before it reaches the database
+		storedContent, err := s.sealContent(ctx, note)
+		if err != nil {
+			return nil, fmt.Errorf("invalid note in batch at index %d: %w", i, err)
+		}
+
+		if err = checkNoteNotebook(ctx, tx, userID, note); err != nil {
+			return nil, err
 		}
 
 		// Insert note
 		query := `
-			INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-			RETURNING id, user_id, title, content, created_at, updated_at, version
+			INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version, language, is_private, metadata, properties, color, icon, notebook_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING ` + noteColumns + `
 		`
 
-		err := tx.QueryRowContext(ctx, query,
-			note.ID, note.UserID, note.Title, note.Content,
-			note.CreatedAt, note.UpdatedAt, note.Version).Scan(
-			&note.ID, &note.UserID, &note.Title, &note.Content,
-			&note.CreatedAt, &note.UpdatedAt, &note.Version)
+		err = s.readNote(ctx, tx.QueryRowContext(ctx, query,
+			note.ID, note.UserID, note.Title, storedContent,
+			note.CreatedAt, note.UpdatedAt, note.Version, note.Language, note.IsPrivate, note.Metadata, note.Properties, note.Color, note.Icon, note.NotebookID), note)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to create note in batch: %w", err)
 		}
 
+		if err := s.tagService.AddNoteTags(ctx, tx, userID, note.ID.String(), note.ExtractHashtags()); err != nil {
+			return nil, fmt.Errorf("failed to create note in batch: %w", err)
+		}
+
 		notes = append(notes, *note)
 	}
 
@@ -593,26 +918,18 @@ func (s *NoteService) BatchCreateNotes(userID string, requests []*models.CreateN
 		return nil, fmt.Errorf("failed to commit batch create: %w", err)
 	}
 
-	// Process tags for all notes (outside transaction to avoid blocking)
-	for _, note := range notes {
-		tags := note.ExtractHashtags()
-		if len(tags) > 0 {
-			if err := s.processNoteTags(context.Background(), note.ID.String(), tags); err != nil {
-				fmt.Printf("Warning: failed to process tags for note %s: %v\n", note.ID, err)
-			}
-		}
+	for i := range notes {
+		s.notifyWrite(ctx, &notes[i])
 	}
 
 	return notes, nil
 }
 
 // BatchUpdateNotes updates multiple notes in a single transaction
-func (s *NoteService) BatchUpdateNotes(userID string, requests []struct {
+func (s *NoteService) BatchUpdateNotes(ctx context.Context, userID string, requests []struct {
 	NoteID  string
 	Request *models.UpdateNoteRequest
 }) ([]models.Note, error) {
-	ctx := context.Background()
-
 	// Start transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -620,77 +937,118 @@ func (s *NoteService) BatchUpdateNotes(userID string, requests []struct {
 	}
 	defer tx.Rollback()
 
+	notes, err := s.updateNotesInTx(ctx, tx, userID, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch update: %w", err)
+	}
+
+	for i := range notes {
+		s.notifyWrite(ctx, &notes[i])
+	}
+
+	return notes, nil
+}
+
+// updateNotesInTx applies the updates of a batch in tx. Write listeners are
+// left to the caller, to be notified once tx is committed.
+func (s *NoteService) updateNotesInTx(ctx context.Context, tx *sql.Tx, userID string, requests []struct {
+	NoteID  string
+	Request *models.UpdateNoteRequest
+}) ([]models.Note, error) {
 	var notes []models.Note
 
 	for _, req := range requests {
 		// Get current note
-		currentNote, err := s.GetNoteByID(userID, req.NoteID)
+		currentNote, err := s.GetNoteByID(ctx, userID, req.NoteID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get note %s in batch: %w", req.NoteID, err)
 		}
 
+		if err := checkNoteWrite(ctx, tx, userID, currentNote); err != nil {
+			return nil, fmt.Errorf("note %s: %w", req.NoteID, err)
+		}
+
+		// Locked private notes cannot be re-encrypted without the key
+		if currentNote.Locked {
+			return nil, fmt.Errorf("cannot update private note %s: %w", req.NoteID, encryption.ErrKeyUnavailable)
+		}
+
 		// Check version if provided
 		if req.Request.Version != nil && *req.Request.Version != currentNote.Version {
-			return nil, fmt.Errorf("note %s has been modified by another process", req.NoteID)
+			return nil, fmt.Errorf("note %s: %w", req.NoteID, ErrVersionMismatch)
 		}
 
 		// Apply updates
 		if !req.Request.ApplyUpdates(currentNote) {
-			return nil, fmt.Errorf("no updates provided for note %s", req.NoteID)
+			return nil, apperrors.Validation(codeInvalidNote, fmt.Sprintf("no updates provided for note %s", req.NoteID))
 		}
 
 		// Validate updated note
 		if err := currentNote.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid updated note %s: %w", req.NoteID, err)
+			return nil, invalidNote(err, fmt.Sprintf("invalid updated note %s: %v", req.NoteID, err))
+		}
+		authorID := currentNote.UserID.String()
+		if len(req.Request.Properties) > 0 {
+			if currentNote.Properties, err = s.normalizeProperties(ctx, authorID, currentNote.Properties); err != nil {
+				return nil, err
+			}
 		}
 
 		// Increment version
 		currentNote.Version++
 
+		// Re-detect language since content may have changed
+		currentNote.DetectLanguage()
+
+		// Encrypt content of private notes before it reaches the database
+		storedContent, err := s.sealContent(ctx, currentNote)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update note %s in batch: %w", req.NoteID, err)
+		}
+
+		if req.Request.NotebookID != nil || req.Request.Private != nil {
+			if err := checkNoteNotebook(ctx, tx, userID, currentNote); err != nil {
+				return nil, err
+			}
+		}
+
 		// Update in database
 		query := `
 			UPDATE notes
-			SET title = $1, content = $2, updated_at = $3, version = $4
-			WHERE id = $5 AND user_id = $6 AND version = $7 - 1
-			RETURNING id, user_id, title, content, created_at, updated_at, version
+			SET title = $1, content = $2, updated_at = $3, version = $4, language = $5, is_private = $6, properties = $7, color = $8, icon = $9, notebook_id = $10
+			WHERE id = $11 AND user_id = $12 AND version = $13 - 1
+			RETURNING ` + noteColumns + `
 		`
 
-		err = tx.QueryRowContext(ctx, query,
-			currentNote.Title, currentNote.Content, currentNote.UpdatedAt,
-			currentNote.Version, currentNote.ID, currentNote.UserID, currentNote.Version).Scan(
-			&currentNote.ID, &currentNote.UserID, &currentNote.Title, &currentNote.Content,
-			&currentNote.CreatedAt, &currentNote.UpdatedAt, &currentNote.Version)
+		err = s.readNote(ctx, tx.QueryRowContext(ctx, query,
+			currentNote.Title, storedContent, currentNote.UpdatedAt,
+			currentNote.Version, currentNote.Language, currentNote.IsPrivate,
+			currentNote.Properties, currentNote.Color, currentNote.Icon, currentNote.NotebookID, currentNote.ID, currentNote.UserID, currentNote.Version), currentNote)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
-				return nil, fmt.Errorf("note %s has been modified by another process", req.NoteID)
+				return nil, fmt.Errorf("note %s: %w", req.NoteID, ErrVersionMismatch)
 			}
 			return nil, fmt.Errorf("failed to update note %s in batch: %w", req.NoteID, err)
 		}
 
-		notes = append(notes, *currentNote)
-	}
-
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit batch update: %w", err)
-	}
-
-	// Process tags for all updated notes
-	for _, note := range notes {
-		tags := note.ExtractHashtags()
-		if err := s.updateNoteTags(context.Background(), note.ID.String(), tags); err != nil {
-			fmt.Printf("Warning: failed to update tags for note %s: %v\n", note.ID, err)
+		if err := s.tagService.ReplaceNoteTags(ctx, tx, authorID, currentNote.ID.String(), currentNote.ExtractHashtags()); err != nil {
+			return nil, fmt.Errorf("failed to update note %s in batch: %w", req.NoteID, err)
 		}
+
+		notes = append(notes, *currentNote)
 	}
 
 	return notes, nil
 }
 
 // IncrementVersion increments the version of a note (for conflict resolution)
-func (s *NoteService) IncrementVersion(noteID string) error {
-	ctx := context.Background()
-
+func (s *NoteService) IncrementVersion(ctx context.Context, noteID string) error {
 	query := `UPDATE notes SET version = version + 1 WHERE id = $1`
 	_, err := s.db.ExecContext(ctx, query, noteID)
 	if err != nil {
@@ -700,104 +1058,589 @@ func (s *NoteService) IncrementVersion(noteID string) error {
 	return nil
 }
 
-// Private helper methods for tag management
+// hashtagPattern matches a complete hashtag such as #work
+var hashtagPattern = regexp.MustCompile(`^#\w+$`)
+
+// MergeTags replaces the source hashtag with the target hashtag in every note
+// of the user tagged with source, returning how many notes were rewritten.
+// Notes are updated like regular edits, so versions, tags and listeners follow.
+func (s *NoteService) MergeTags(ctx context.Context, userID, source, target string) (int, error) {
+	source = search.NormalizeTag(strings.TrimSpace(source))
+	target = search.NormalizeTag(strings.TrimSpace(target))
+	if !hashtagPattern.MatchString(source) || !hashtagPattern.MatchString(target) {
+		return 0, apperrors.Validation("INVALID_TAG", "invalid tag name")
+	}
+	if source == target {
+		return 0, apperrors.Validation("INVALID_TAG", "source and target tags must differ")
+	}
 
-// processNoteTags creates tags and associations for a note
-func (s *NoteService) processNoteTags(ctx context.Context, noteID string, tags []string) error {
-	for _, tagName := range tags {
-		// Create or get tag
-		tagID, err := s.getOrCreateTag(ctx, tagName)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT n.id
+		FROM notes n
+		JOIN note_tags nt ON n.id = nt.note_id
+		JOIN tags t ON nt.tag_id = t.id
+		WHERE n.user_id = $1 AND LOWER(t.name) = LOWER($2)
+	`, userID, source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find notes with tag: %w", err)
+	}
+	var noteIDs []string
+	for rows.Next() {
+		var noteID string
+		if err := rows.Scan(&noteID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan note ID: %w", err)
+		}
+		noteIDs = append(noteIDs, noteID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating notes with tag: %w", err)
+	}
+
+	// Tag names ignore case, so #Work is rewritten when merging #work
+	sourcePattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(source) + `\b`)
+	var requests []struct {
+		NoteID  string
+		Request *models.UpdateNoteRequest
+	}
+	for _, noteID := range noteIDs {
+		note, err := s.GetNoteByID(ctx, userID, noteID)
 		if err != nil {
-			return fmt.Errorf("failed to get or create tag %s: %w", tagName, err)
+			return 0, err
+		}
+		if note.Locked {
+			return 0, fmt.Errorf("cannot merge tags in private note %s: %w", noteID, encryption.ErrKeyUnavailable)
 		}
 
-		// Associate tag with note
-		if err := s.associateNoteWithTag(ctx, noteID, tagID); err != nil {
-			return fmt.Errorf("failed to associate note with tag %s: %w", tagName, err)
+		content := sourcePattern.ReplaceAllString(note.Content, target)
+		version := note.Version
+		requests = append(requests, struct {
+			NoteID  string
+			Request *models.UpdateNoteRequest
+		}{NoteID: noteID, Request: &models.UpdateNoteRequest{Title: note.Title, Content: &content, Version: &version}})
+	}
+
+	if len(requests) == 0 {
+		return 0, nil
+	}
+
+	notes, err := s.BatchUpdateNotes(ctx, userID, requests)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return len(notes), nil
+}
+
+// RenameTag renames a tag of the user, together with the nested tags below
+// it. With RewriteContent, the old hashtag is replaced with the new one in
+// the user's notes carrying the tag in the same transaction, bumping their
+// versions. It returns the renamed tag and how many notes were rewritten.
+func (s *NoteService) RenameTag(ctx context.Context, userID, tagID string, request *models.UpdateTagRequest) (*models.Tag, int, error) {
+	tag, err := s.tagService.GetTagByID(ctx, userID, tagID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var requests []struct {
+		NoteID  string
+		Request *models.UpdateNoteRequest
+	}
+	if request.RewriteContent {
+		// Nested tags are matched too, so #work/projecta becomes #job/projecta
+		prefix := strings.ToLower(tag.Name) + "/"
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT DISTINCT n.id
+			FROM notes n
+			JOIN note_tags nt ON n.id = nt.note_id
+			JOIN tags t ON nt.tag_id = t.id
+			WHERE n.user_id = $1 AND (t.id = $2 OR LOWER(SUBSTR(t.name, 1, $3)) = $4)
+		`, userID, tag.ID, len(prefix), prefix)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to find notes with tag: %w", err)
+		}
+		var noteIDs []string
+		for rows.Next() {
+			var noteID string
+			if err := rows.Scan(&noteID); err != nil {
+				rows.Close()
+				return nil, 0, fmt.Errorf("failed to scan note ID: %w", err)
+			}
+			noteIDs = append(noteIDs, noteID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, 0, fmt.Errorf("error iterating notes with tag: %w", err)
+		}
+
+		// Tag names ignore case, so #Work is rewritten when renaming #work
+		renamed := (&models.CreateTagRequest{Name: request.Name}).ToTag()
+		pattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(tag.Name) + `\b`)
+		for _, noteID := range noteIDs {
+			note, err := s.GetNoteByID(ctx, userID, noteID)
+			if err != nil {
+				return nil, 0, err
+			}
+			if note.Locked {
+				return nil, 0, fmt.Errorf("cannot rename tags in private note %s: %w", noteID, encryption.ErrKeyUnavailable)
+			}
+
+			content := pattern.ReplaceAllLiteralString(note.Content, renamed.Name)
+			if content == note.Content {
+				continue
+			}
+			version := note.Version
+			requests = append(requests, struct {
+				NoteID  string
+				Request *models.UpdateNoteRequest
+			}{NoteID: noteID, Request: &models.UpdateNoteRequest{Title: note.Title, Content: &content, Version: &version}})
 		}
 	}
-	return nil
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The tag is renamed first, so the rewritten notes are tagged with it
+	// rather than with a new tag of the same name
+	tag, err = s.tagService.RenameTag(ctx, tx, userID, tag, request.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	notes, err := s.updateNotesInTx(ctx, tx, userID, requests)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit tag rename: %w", err)
+	}
+
+	for i := range notes {
+		s.notifyWrite(ctx, &notes[i])
+	}
+
+	return tag, len(notes), nil
 }
 
-// updateNoteTags updates tags for a note (replaces all existing tags)
-func (s *NoteService) updateNoteTags(ctx context.Context, noteID string, tags []string) error {
-	// Delete existing tag associations
-	if err := s.deleteAllNoteTags(ctx, noteID); err != nil {
-		return err
+// notifyWrite passes a written note to every registered write listener
+func (s *NoteService) notifyWrite(ctx context.Context, note *models.Note) {
+	for _, listener := range s.writeListeners {
+		listener.NoteWritten(ctx, note)
+	}
+}
+
+// addTotalTimes sets the time spent on each of the notes. Notes are listed
+// without it when the times cannot be read.
+func (s *NoteService) addTotalTimes(ctx context.Context, notes []models.NoteResponse) {
+	if s.focusTimes == nil || len(notes) == 0 {
+		return
 	}
 
-	// Process new tags
-	return s.processNoteTags(ctx, noteID, tags)
+	ids := make([]uuid.UUID, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+	totals, err := s.focusTimes.TotalTimes(ctx, ids)
+	if err != nil {
+		fmt.Printf("Warning: failed to get note times: %v\n", err)
+		return
+	}
+	for i := range notes {
+		notes[i].TotalTimeSeconds = totals[notes[i].ID]
+	}
+}
+
+// addNoteTags fills in the tags of a page of notes, fetched with a single
+// query rather than one per note
+func (s *NoteService) addNoteTags(ctx context.Context, notes []models.NoteResponse) {
+	if len(notes) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(notes))
+	for i, note := range notes {
+		ids[i] = note.ID
+	}
+	tags, err := s.GetTagsForNotes(ctx, ids)
+	if err != nil {
+		// Log error but continue without tags
+		fmt.Printf("Warning: failed to get note tags: %v\n", err)
+		for i := range notes {
+			notes[i].Tags = []string{}
+		}
+		return
+	}
+	for i := range notes {
+		notes[i].Tags = tags[notes[i].ID]
+	}
 }
 
-// getOrCreateTag gets an existing tag or creates a new one
-func (s *NoteService) getOrCreateTag(ctx context.Context, tagName string) (uuid.UUID, error) {
-	var tagID uuid.UUID
+// Private helper methods for note scanning
+
+// noteColumns lists the notes columns read by note queries, in scanNote order
+const noteColumns = "id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved, language, is_private, metadata, properties, color, icon, notebook_id, image_text"
 
-	// Try to get existing tag
-	err := s.db.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = $1", tagName).Scan(&tagID)
-	if err == nil {
-		return tagID, nil
+// qualifiedNoteColumns returns noteColumns prefixed with a table alias
+func qualifiedNoteColumns(alias string) string {
+	columns := strings.Split(noteColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
 	}
+	return strings.Join(columns, ", ")
+}
 
-	if err != sql.ErrNoRows {
-		return uuid.Nil, fmt.Errorf("failed to query tag: %w", err)
+// propertyValue returns the SQL value of a note property, NULL when unset.
+// Names only hold lowercase letters, digits and underscores, so they are safe
+// to use as JSON keys in SQL.
+func propertyValue(dialect database.Dialect, definition *models.PropertyDefinition) string {
+	value := dialect.JSONText("properties", definition.Name)
+	if definition.Type == models.PropertyNumber {
+		return "CAST(" + value + " AS NUMERIC)"
 	}
+	return value
+}
+
+// propertyCondition returns the condition of a property filter comparing
+// with the parameter at argIndex. Strings are compared ignoring case.
+func propertyCondition(dialect database.Dialect, definition *models.PropertyDefinition, op string, argIndex int) string {
+	value := propertyValue(dialect, definition)
+	switch {
+	case op == models.PropertyFilterMin:
+		return fmt.Sprintf("%s >= $%d", value, argIndex)
+	case op == models.PropertyFilterMax:
+		return fmt.Sprintf("%s <= $%d", value, argIndex)
+	case definition.Type == models.PropertyString:
+		return fmt.Sprintf("LOWER(%s) = LOWER($%d)", value, argIndex)
+	}
+	return fmt.Sprintf("%s = $%d", value, argIndex)
+}
 
-	// Create new tag
-	tagID = uuid.New()
-	query := "INSERT INTO tags (id, name, created_at) VALUES ($1, $2, $3)"
-	_, err = s.db.ExecContext(ctx, query, tagID, tagName, time.Now())
+// normalizeProperties checks property values against the user's property
+// definitions and returns them in stored form
+func (s *NoteService) normalizeProperties(ctx context.Context, userID string, values models.NoteProperties) (models.NoteProperties, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	schema, err := loadPropertySchema(ctx, s.db, userID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create tag: %w", err)
+		return nil, err
 	}
+	properties, err := schema.Normalize(values)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidProperty, err)
+	}
+	return properties, nil
+}
 
-	return tagID, nil
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-// associateNoteWithTag creates an association between a note and a tag
-func (s *NoteService) associateNoteWithTag(ctx context.Context, noteID string, tagID uuid.UUID) error {
-	query := "INSERT INTO note_tags (note_id, tag_id, created_at) VALUES ($1, $2, $3)"
-	_, err := s.db.ExecContext(ctx, query, noteID, tagID, time.Now())
+// scanNote scans a row selected with noteColumns into a note
+func scanNote(row rowScanner, note *models.Note) error {
+	return row.Scan(&note.ID, &note.UserID, &note.Title, &note.Content,
+		&note.CreatedAt, &note.UpdatedAt, &note.Version,
+		&note.PrettifiedAt, &note.AIImproved, &note.Language, &note.IsPrivate, &note.Metadata, &note.Properties, &note.Color, &note.Icon, &note.NotebookID, &note.ImageText)
+}
+
+// readNote scans a row selected with noteColumns and decrypts private note content
+func (s *NoteService) readNote(ctx context.Context, row rowScanner, note *models.Note) error {
+	if err := scanNote(row, note); err != nil {
+		return err
+	}
+	return s.openContent(ctx, note)
+}
+
+// sealContent returns the content to store for a note, encrypting it for private notes
+func (s *NoteService) sealContent(ctx context.Context, note *models.Note) (string, error) {
+	if !note.IsPrivate {
+		return note.Content, nil
+	}
+	if !s.encryptor.Available() {
+		return "", fmt.Errorf("cannot save private note: %w", encryption.ErrKeyUnavailable)
+	}
+	ciphertext, err := s.encryptor.Encrypt(ctx, note.UserID, note.Content)
 	if err != nil {
-		return fmt.Errorf("failed to associate note with tag: %w", err)
+		return "", fmt.Errorf("failed to encrypt note: %w", err)
 	}
-	return nil
+	return ciphertext, nil
 }
 
-// deleteAllNoteTags deletes all tag associations for a note
-func (s *NoteService) deleteAllNoteTags(ctx context.Context, noteID string) error {
-	query := "DELETE FROM note_tags WHERE note_id = $1"
-	_, err := s.db.ExecContext(ctx, query, noteID)
+// openContent decrypts private note content in place. Without the key the note
+// is marked locked and its content cleared instead of returning ciphertext.
+func (s *NoteService) openContent(ctx context.Context, note *models.Note) error {
+	if !note.IsPrivate || !encryption.IsEncrypted(note.Content) {
+		return nil
+	}
+	if !s.encryptor.Available() {
+		note.Content = ""
+		note.Locked = true
+		return nil
+	}
+	plaintext, err := s.encryptor.Decrypt(ctx, note.UserID, note.Content)
 	if err != nil {
-		return fmt.Errorf("failed to delete note tags: %w", err)
+		return fmt.Errorf("failed to decrypt note %s: %w", note.ID, err)
 	}
+	note.Content = plaintext
 	return nil
 }
 
-// getNoteTags retrieves all tags for a note
-func (s *NoteService) getNoteTags(ctx context.Context, noteID string) ([]string, error) {
+// languageDetectPageSize is the number of notes read per page when detecting
+// the language of notes written before it was detected
+const languageDetectPageSize = 500
+
+// DetectLanguages detects the language of the notes written before it was
+// detected on write, whose language is still "und". Setting the language
+// rebuilds their search vector with the matching search configuration. The
+// content of private notes is encrypted and not indexed, so they are left
+// out. It returns the number of notes given a language.
+func (s *NoteService) DetectLanguages(ctx context.Context) (int64, error) {
+	var detected int64
+	after := uuid.Nil
+	for {
+		notes, err := s.undetectedLanguagePage(ctx, after)
+		if err != nil {
+			return detected, err
+		}
+		for i := range notes {
+			notes[i].DetectLanguage()
+			if notes[i].Language == string(language.Unknown) {
+				continue
+			}
+			// Notes written in the meantime were detected already
+			result, err := s.db.ExecContext(ctx,
+				"UPDATE notes SET language = $1 WHERE id = $2 AND language = $3",
+				notes[i].Language, notes[i].ID, string(language.Unknown))
+			if err != nil {
+				return detected, fmt.Errorf("failed to set language of note %s: %w", notes[i].ID, err)
+			}
+			updated, _ := result.RowsAffected()
+			detected += updated
+		}
+		if len(notes) < languageDetectPageSize {
+			return detected, nil
+		}
+		after = notes[len(notes)-1].ID
+	}
+}
+
+// undetectedLanguagePage reads a page of the non-private notes without a
+// detected language, ordered by ID
+func (s *NoteService) undetectedLanguagePage(ctx context.Context, after uuid.UUID) ([]models.Note, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, title, content
+		FROM notes
+		WHERE id > $1 AND language = $2 AND NOT is_private
+		ORDER BY id
+		LIMIT $3
+	`, after, string(language.Unknown), languageDetectPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes without language: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.Title, &note.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notes: %w", err)
+	}
+
+	return notes, nil
+}
+
+// Limits of matching private notes against the text terms of a search
+const (
+	// privateSearchPageSize is the number of private notes decrypted at a time
+	privateSearchPageSize = 100
+	// defaultMaxPrivateSearch is the number of most recently updated private
+	// notes a search decrypts
+	defaultMaxPrivateSearch = 1000
+)
+
+// matchPrivateNotes returns the IDs of the user's private notes whose decrypted
+// title and content satisfy the query text terms. Encrypted content is not
+// indexed, so private notes are matched in memory and only when the key is
+// available. Notes are decrypted a page at a time, most recently updated
+// first, up to maxPrivateSearch notes; older private notes are not matched.
+func (s *NoteService) matchPrivateNotes(ctx context.Context, userID string, query *search.Query) ([]string, error) {
+	ids := []string{}
+	for offset := 0; offset < s.maxPrivateSearch; offset += privateSearchPageSize {
+		read, err := s.matchPrivateNotesPage(ctx, userID, query, offset, min(privateSearchPageSize, s.maxPrivateSearch-offset), &ids)
+		if err != nil {
+			return nil, err
+		}
+		if read < privateSearchPageSize {
+			break
+		}
+	}
+	return ids, nil
+}
+
+// matchPrivateNotesPage matches a page of the user's private notes, appending
+// the IDs of the matching ones to ids, and returns the number of notes read
+func (s *NoteService) matchPrivateNotesPage(ctx context.Context, userID string, query *search.Query, offset, limit int, ids *[]string) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+noteColumns+`
+		FROM notes
+		WHERE user_id = $1 AND is_private
+		ORDER BY updated_at DESC, id
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query private notes: %w", err)
+	}
+	defer rows.Close()
+
+	read := 0
+	for rows.Next() {
+		var note models.Note
+		if err := s.readNote(ctx, rows, &note); err != nil {
+			return read, fmt.Errorf("failed to read private note: %w", err)
+		}
+		read++
+		title := ""
+		if note.Title != nil {
+			title = *note.Title
+		}
+		if query.MatchesText(title, note.Content) {
+			*ids = append(*ids, note.ID.String())
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return read, fmt.Errorf("error iterating private notes: %w", err)
+	}
+	return read, nil
+}
+
+// fullTextQuery builds a tsquery expression for the query parameter at argIndex
+// that is analyzed with every supported language configuration, so English and
+// Indonesian notes are both matched by their own stems
+func fullTextQuery(argIndex int) string {
+	configs := language.SearchConfigs()
+	parts := make([]string, len(configs))
+	for i, config := range configs {
+		parts[i] = fmt.Sprintf("websearch_to_tsquery('%s', $%d)", config, argIndex)
+	}
+	return "(" + strings.Join(parts, " || ") + ")"
+}
+
+// textTermCondition builds the SQL condition and arguments for one search term,
+// with placeholders starting at argIndex. Terms match the title, the content
+// and the text of uploaded images. Without full-text search the term is
+// matched as a substring only.
+func textTermCondition(dialect database.Dialect, term search.Term, argIndex int) (string, []interface{}) {
+	if term.Regex {
+		match := dialect.Regexp("title", argIndex) + " OR " + dialect.Regexp("content", argIndex) + " OR " + dialect.Regexp("image_text", argIndex)
+		if term.Field == search.FieldTitle {
+			match = dialect.Regexp("title", argIndex)
+		}
+		condition := fmt.Sprintf("COALESCE(%s, false)", match)
+		if term.Negated {
+			condition = "NOT " + condition
+		}
+		return condition, []interface{}{term.Text}
+	}
+
+	pattern := "%" + term.Text + "%"
+	if !dialect.HasFullTextSearch() {
+		substring := dialect.ILike("title", argIndex) + " OR " + dialect.ILike("content", argIndex) + " OR " + dialect.ILike("image_text", argIndex)
+		if term.Field == search.FieldTitle {
+			substring = dialect.ILike("title", argIndex)
+		}
+		condition := fmt.Sprintf("COALESCE(%s, false)", substring)
+		if term.Negated {
+			condition = "NOT " + condition
+		}
+		return condition, []interface{}{pattern}
+	}
+
+	// websearch_to_tsquery treats a quoted string as a phrase
+	tsText := term.Text
+	if term.Phrase {
+		tsText = `"` + term.Text + `"`
+	}
+
+	vector := "search_vector"
+	substring := fmt.Sprintf("title ILIKE $%d OR content ILIKE $%d OR image_text ILIKE $%d", argIndex+1, argIndex+1, argIndex+1)
+	if term.Field == search.FieldTitle {
+		// Title is indexed with weight A
+		vector = "ts_filter(search_vector, '{a}')"
+		substring = fmt.Sprintf("title ILIKE $%d", argIndex+1)
+	}
+
+	// COALESCE keeps NULL titles/vectors from turning a negated term into NULL
+	condition := fmt.Sprintf("COALESCE(%s @@ %s OR %s, false)", vector, fullTextQuery(argIndex), substring)
+	if term.Negated {
+		condition = "NOT " + condition
+	}
+	return condition, []interface{}{tsText, pattern}
+}
+
+// invalidNote returns the error of a note failing validation with err.
+// Content longer than the limit is too large rather than invalid.
+func invalidNote(err error, message string) error {
+	if errors.Is(err, models.ErrContentTooLong) {
+		return apperrors.New(apperrors.ErrTooLarge, codeNoteTooLarge, message)
+	}
+	return apperrors.Validation(codeInvalidNote, message)
+}
+
+// uniqueTags normalizes tag names to their stored "#name" form and removes
+// duplicates, which ignore case like stored tags
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		tag = search.NormalizeTag(tag)
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// GetTagsForNotes retrieves the tags of several notes with a single query,
+// keyed by note ID. Notes without tags have no entry.
+func (s *NoteService) GetTagsForNotes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
 	query := `
-		SELECT t.name
+		SELECT nt.note_id, t.name
 		FROM tags t
 		JOIN note_tags nt ON t.id = nt.tag_id
-		WHERE nt.note_id = $1
-		ORDER BY t.name
+		WHERE ` + s.dialect.AnyOf("nt.note_id", 1, "") + `
+		ORDER BY nt.note_id, t.name
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, noteID)
+	rows, err := s.db.QueryContext(ctx, query, s.dialect.Array(noteIDs))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get note tags: %w", err)
 	}
 	defer rows.Close()
 
-	var tags []string
+	tags := make(map[uuid.UUID][]string)
 	for rows.Next() {
+		var noteID uuid.UUID
 		var tagName string
-		if err := rows.Scan(&tagName); err != nil {
+		if err := rows.Scan(&noteID, &tagName); err != nil {
 			return nil, fmt.Errorf("failed to scan tag: %w", err)
 		}
-		tags = append(tags, tagName)
+		tags[noteID] = append(tags[noteID], tagName)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -807,10 +1650,9 @@ func (s *NoteService) getNoteTags(ctx context.Context, noteID string) ([]string,
 	return tags, nil
 }
 
-// GetNotesForSync retrieves notes for synchronization with filtering options
-func (s *NoteService) GetNotesForSync(userID string, limit, offset int, since *time.Time, includeDeleted bool) ([]models.Note, int, error) {
-	ctx := context.Background()
-
+// GetNotesForSync retrieves notes for synchronization with filtering options.
+// Notes whose tags changed since are returned too.
+func (s *NoteService) GetNotesForSync(ctx context.Context, userID string, limit, offset int, since *time.Time, includeDeleted bool) ([]models.Note, int, error) {
 	// Convert userID to UUID
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
@@ -819,7 +1661,7 @@ func (s *NoteService) GetNotesForSync(userID string, limit, offset int, since *t
 
 	// Build base query
 	baseQuery := `
-		SELECT id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SELECT ` + noteColumns + `
 		FROM notes
 		WHERE user_id = $1
 	`
@@ -831,8 +1673,8 @@ func (s *NoteService) GetNotesForSync(userID string, limit, offset int, since *t
 
 	// Add timestamp filter if provided
 	if since != nil {
-		baseQuery += fmt.Sprintf(" AND updated_at > $%d", argIndex)
-		countQuery += fmt.Sprintf(" AND updated_at > $%d", argIndex)
+		baseQuery += fmt.Sprintf(" AND (updated_at > $%d OR tags_changed_at > $%d)", argIndex, argIndex)
+		countQuery += fmt.Sprintf(" AND (updated_at > $%d OR tags_changed_at > $%d)", argIndex, argIndex)
 		args = append(args, *since)
 		argIndex++
 	}
@@ -858,17 +1700,7 @@ func (s *NoteService) GetNotesForSync(userID string, limit, offset int, since *t
 	var notes []models.Note
 	for rows.Next() {
 		var note models.Note
-		err := rows.Scan(
-			&note.ID,
-			&note.UserID,
-			&note.Title,
-			&note.Content,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-			&note.Version,
-			&note.PrettifiedAt,
-			&note.AIImproved,
-		)
+		err := s.readNote(ctx, rows, &note)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan note: %w", err)
 		}
@@ -883,9 +1715,7 @@ func (s *NoteService) GetNotesForSync(userID string, limit, offset int, since *t
 }
 
 // DetectConflicts detects conflicts between local and remote note versions
-func (s *NoteService) DetectConflicts(userID string, notes []models.Note) ([]models.NoteConflict, error) {
-	ctx := context.Background()
-
+func (s *NoteService) DetectConflicts(ctx context.Context, userID string, notes []models.Note) ([]models.NoteConflict, error) {
 	if len(notes) == 0 {
 		return []models.NoteConflict{}, nil
 	}
@@ -919,7 +1749,7 @@ func (s *NoteService) DetectConflicts(userID string, notes []models.Note) ([]mod
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, user_id, title, content, created_at, updated_at, version, prettified_at, ai_improved
+		SELECT ` + noteColumns + `
 		FROM notes
 		WHERE user_id = $1 AND id IN (%s)
 	`, strings.Join(placeholders, ","))
@@ -933,17 +1763,7 @@ func (s *NoteService) DetectConflicts(userID string, notes []models.Note) ([]mod
 	var conflicts []models.NoteConflict
 	for rows.Next() {
 		var remoteNote models.Note
-		err := rows.Scan(
-			&remoteNote.ID,
-			&remoteNote.UserID,
-			&remoteNote.Title,
-			&remoteNote.Content,
-			&remoteNote.CreatedAt,
-			&remoteNote.UpdatedAt,
-			&remoteNote.Version,
-			&remoteNote.PrettifiedAt,
-			&remoteNote.AIImproved,
-		)
+		err := s.readNote(ctx, rows, &remoteNote)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan remote note: %w", err)
 		}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/config"
-	"github.com/gpd/my-notes/internal/database"
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,7 +26,6 @@ type NoteServiceTestSuite struct {
 	service    *NoteService
 	tagService *TagService
 	userID     string
-	cleanupDB  func()
 }
 
 // SetupSuite runs once before all tests
@@ -34,37 +35,14 @@ func (suite *NoteServiceTestSuite) SetupSuite() {
 		suite.T().Skip("Skipping integration tests in short mode")
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig("")
-	require.NoError(suite.T(), err, "Failed to load config")
-
-	// Create test database
-	db, err := database.CreateTestDatabase(cfg.Database)
-	require.NoError(suite.T(), err, "Failed to create test database")
+	// Create a migrated test database, dropped after the suite
+	db := testutil.NewTestDB(suite.T(), config.GetTestDatabaseConfig(), "../../migrations")
 	suite.db = db
 	suite.tagService = NewTagService(db)
 	suite.service = NewNoteService(db, suite.tagService)
-	suite.userID = uuid.New().String()
-
-	// Run migrations on the test database
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(suite.T(), err, "Failed to run migrations")
-
-	suite.cleanupDB = func() {
-		db.Close()
-	}
 
 	// Create test user
-	err = suite.createTestUser()
-	require.NoError(suite.T(), err, "Failed to create test user")
-}
-
-// TearDownSuite runs once after all tests
-func (suite *NoteServiceTestSuite) TearDownSuite() {
-	if suite.cleanupDB != nil {
-		suite.cleanupDB()
-	}
+	suite.userID = testutil.NewTestUser(suite.T(), db).ID.String()
 }
 
 // SetupTest runs before each test
@@ -103,23 +81,12 @@ func (suite *NoteServiceTestSuite) cleanupTestData() {
 	if err != nil {
 		suite.T().Logf("Warning: failed to cleanup notes: %v", err)
 	}
-}
 
-// createTestUser creates a test user for the tests
-func (suite *NoteServiceTestSuite) createTestUser() error {
-	if suite.db == nil {
-		return fmt.Errorf("database not initialized")
+	_, err = suite.db.ExecContext(context.Background(),
+		"DELETE FROM note_tombstones WHERE user_id = $1", suite.userID)
+	if err != nil {
+		suite.T().Logf("Warning: failed to cleanup note tombstones: %v", err)
 	}
-
-	query := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`
-	userUUID := uuid.MustParse(suite.userID)
-	_, err := suite.db.ExecContext(context.Background(), query,
-		userUUID, "google_"+suite.userID, "test@example.com",
-		time.Now(), time.Now())
-	return err
 }
 
 // TestCreateNote tests the CreateNote method
@@ -184,7 +151,7 @@ func (suite *NoteServiceTestSuite) TestCreateNote() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			note, err := suite.service.CreateNote(suite.userID, tt.request)
+			note, err := suite.service.CreateNote(context.Background(), suite.userID, tt.request)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -213,6 +180,31 @@ func (suite *NoteServiceTestSuite) TestCreateNote() {
 	}
 }
 
+// TestCreateNoteWritesTagsInTransaction tests that a note is only created
+// together with its tags
+func (suite *NoteServiceTestSuite) TestCreateNoteWritesTagsInTransaction() {
+	note, err := suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{
+		Content: "Planning #roadmap and #release",
+	})
+	require.NoError(suite.T(), err)
+
+	var tagCount int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM note_tags WHERE note_id = $1", note.ID).Scan(&tagCount)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, tagCount)
+
+	// Tag names are limited to 100 characters, so saving this tag fails and
+	// the note insert must be rolled back with it
+	content := "Rolled back #" + strings.Repeat("x", 120)
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: content})
+	assert.Error(suite.T(), err)
+
+	var noteCount int
+	err = suite.db.QueryRow("SELECT COUNT(*) FROM notes WHERE user_id = $1 AND content = $2", suite.userID, content).Scan(&noteCount)
+	require.NoError(suite.T(), err)
+	assert.Zero(suite.T(), noteCount)
+}
+
 // TestGetNoteByID tests the GetNoteByID method
 func (suite *NoteServiceTestSuite) TestGetNoteByID() {
 	// Create a test note first
@@ -220,7 +212,7 @@ func (suite *NoteServiceTestSuite) TestGetNoteByID() {
 		Title:   "Test Note for Get",
 		Content: "This is a test note for retrieval testing.",
 	}
-	createdNote, err := suite.service.CreateNote(suite.userID, request)
+	createdNote, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 	require.NoError(suite.T(), err)
 	require.NotNil(suite.T(), createdNote)
 
@@ -255,7 +247,7 @@ func (suite *NoteServiceTestSuite) TestGetNoteByID() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			note, err := suite.service.GetNoteByID(tt.userID, tt.noteID)
+			note, err := suite.service.GetNoteByID(context.Background(), tt.userID, tt.noteID)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -281,7 +273,7 @@ func (suite *NoteServiceTestSuite) TestUpdateNote() {
 		Title:   "Original Title",
 		Content: "Original content for testing updates.",
 	}
-	createdNote, err := suite.service.CreateNote(suite.userID, request)
+	createdNote, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 	require.NoError(suite.T(), err)
 	require.NotNil(suite.T(), createdNote)
 
@@ -360,7 +352,7 @@ func (suite *NoteServiceTestSuite) TestUpdateNote() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			updatedNote, err := suite.service.UpdateNote(tt.userID, tt.noteID, tt.request)
+			updatedNote, err := suite.service.UpdateNote(context.Background(), tt.userID, tt.noteID, tt.request)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -393,7 +385,7 @@ func (suite *NoteServiceTestSuite) TestDeleteNote() {
 		Title:   "Note to Delete",
 		Content: "This note will be deleted for testing.",
 	}
-	createdNote, err := suite.service.CreateNote(suite.userID, request)
+	createdNote, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 	require.NoError(suite.T(), err)
 	require.NotNil(suite.T(), createdNote)
 
@@ -428,7 +420,7 @@ func (suite *NoteServiceTestSuite) TestDeleteNote() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			err := suite.service.DeleteNote(tt.userID, tt.noteID)
+			err := suite.service.DeleteNote(context.Background(), tt.userID, tt.noteID)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -439,7 +431,7 @@ func (suite *NoteServiceTestSuite) TestDeleteNote() {
 				assert.NoError(suite.T(), err)
 
 				// Verify note is actually deleted
-				_, err := suite.service.GetNoteByID(tt.userID, tt.noteID)
+				_, err := suite.service.GetNoteByID(context.Background(), tt.userID, tt.noteID)
 				assert.Error(suite.T(), err)
 				assert.Contains(suite.T(), err.Error(), "note not found")
 			}
@@ -447,6 +439,72 @@ func (suite *NoteServiceTestSuite) TestDeleteNote() {
 	}
 }
 
+// TestDeleteNoteTombstones tests that deleted notes are returned to sync
+func (suite *NoteServiceTestSuite) TestDeleteNoteTombstones() {
+	ctx := context.Background()
+	since := time.Now().Add(-time.Minute)
+
+	notes := make([]*models.Note, 3)
+	for i := range notes {
+		note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{
+			Title:   fmt.Sprintf("Tombstone %d", i),
+			Content: "Deleted for sync #sync",
+		})
+		require.NoError(suite.T(), err)
+		notes[i] = note
+	}
+
+	require.NoError(suite.T(), suite.service.DeleteNote(ctx, suite.userID, notes[0].ID.String()))
+	deleted, err := suite.service.BatchDeleteNotes(ctx, suite.userID, []string{notes[1].ID.String(), uuid.New().String()})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, deleted)
+
+	ids, err := suite.service.GetDeletedNoteIDs(ctx, suite.userID, since)
+	require.NoError(suite.T(), err)
+	assert.ElementsMatch(suite.T(), []uuid.UUID{notes[0].ID, notes[1].ID}, ids)
+
+	// Deletions before the cursor and of other users are not returned
+	ids, err = suite.service.GetDeletedNoteIDs(ctx, suite.userID, time.Now().Add(time.Minute))
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), ids)
+	ids, err = suite.service.GetDeletedNoteIDs(ctx, uuid.New().String(), since)
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), ids)
+}
+
+// TestGetNotesForSyncTagChanges tests that notes whose tags changed
+// without the note are returned to sync
+func (suite *NoteServiceTestSuite) TestGetNotesForSyncTagChanges() {
+	ctx := context.Background()
+	note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{
+		Title:   "Tagged later",
+		Content: "Content #first",
+	})
+	require.NoError(suite.T(), err)
+
+	since := time.Now()
+	notes, _, err := suite.service.GetNotesForSync(ctx, suite.userID, 100, 0, &since, false)
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), notes)
+
+	// Tags kept as they are do not count as a change
+	require.NoError(suite.T(), suite.tagService.UpdateTagsForNote(ctx, suite.userID, note.ID.String(), []string{"#first"}))
+	notes, _, err = suite.service.GetNotesForSync(ctx, suite.userID, 100, 0, &since, false)
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), notes)
+
+	require.NoError(suite.T(), suite.tagService.UpdateTagsForNote(ctx, suite.userID, note.ID.String(), []string{"#second"}))
+	notes, total, err := suite.service.GetNotesForSync(ctx, suite.userID, 100, 0, &since, false)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), notes, 1)
+	assert.Equal(suite.T(), 1, total)
+	assert.Equal(suite.T(), note.ID, notes[0].ID)
+
+	tags, err := suite.service.GetTagsForNotes(ctx, []uuid.UUID{note.ID})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"#second"}, tags[note.ID])
+}
+
 // TestListNotes tests the ListNotes method
 func (suite *NoteServiceTestSuite) TestListNotes() {
 	// Create multiple test notes
@@ -456,7 +514,7 @@ func (suite *NoteServiceTestSuite) TestListNotes() {
 			Title:   fmt.Sprintf("Test Note %d", i+1),
 			Content: fmt.Sprintf("This is test note number %d.", i+1),
 		}
-		note, err := suite.service.CreateNote(suite.userID, request)
+		note, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 		require.NoError(suite.T(), err)
 		notes[i] = note
 	}
@@ -528,7 +586,7 @@ func (suite *NoteServiceTestSuite) TestListNotes() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			noteList, err := suite.service.ListNotes(suite.userID, tt.limit, tt.offset, tt.orderBy, tt.orderDir)
+			noteList, err := suite.service.ListNotes(context.Background(), suite.userID, tt.limit, tt.offset, tt.orderBy, tt.orderDir)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -573,7 +631,7 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 			Title:   n.title,
 			Content: n.content,
 		}
-		_, err := suite.service.CreateNote(suite.userID, request)
+		_, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 		require.NoError(suite.T(), err)
 	}
 
@@ -582,7 +640,6 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 		request   *models.SearchNotesRequest
 		wantErr   bool
 		wantCount int
-		skipTest  bool
 	}{
 		{
 			name: "search by content text",
@@ -619,7 +676,6 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 			},
 			wantErr:   false,
 			wantCount: 3,
-			skipTest: true, // Skip due to SQL syntax error in SearchNotes with tags
 		},
 		{
 			name: "search by multiple tags",
@@ -632,7 +688,6 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 			},
 			wantErr:   false,
 			wantCount: 1, // Only "Meeting Notes" has both tags
-			skipTest: true, // Skip due to SQL syntax error in SearchNotes with tags
 		},
 		{
 			name: "search by text and tag",
@@ -646,7 +701,6 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 			},
 			wantErr:   false,
 			wantCount: 1,
-			skipTest: true, // Skip due to SQL syntax error in SearchNotes with tags
 		},
 		{
 			name: "search with no results",
@@ -664,11 +718,7 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			if tt.skipTest {
-				suite.T().Skip("Skipping due to pre-existing SQL bug in SearchNotes with tags")
-			}
-
-			noteList, err := suite.service.SearchNotes(suite.userID, tt.request)
+			noteList, err := suite.service.SearchNotes(context.Background(), suite.userID, tt.request)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -687,6 +737,264 @@ func (suite *NoteServiceTestSuite) TestSearchNotes() {
 	}
 }
 
+// TestSearchNotesBySource tests note metadata and the source: operator
+func (suite *NoteServiceTestSuite) TestSearchNotesBySource() {
+	latitude, longitude := 52.52, 13.405
+	for _, request := range []*models.CreateNoteRequest{
+		{Content: "Groceries from the bot", Metadata: &models.NoteMetadata{Source: "Telegram", Latitude: &latitude, Longitude: &longitude}},
+		{Content: "Groceries from the browser", Metadata: &models.NoteMetadata{Source: "web", Device: "Firefox"}},
+		{Content: "Groceries without metadata"},
+	} {
+		_, err := suite.service.CreateNote(context.Background(), suite.userID, request)
+		require.NoError(suite.T(), err)
+	}
+
+	search := func(query string) []models.NoteResponse {
+		noteList, err := suite.service.SearchNotes(context.Background(), suite.userID, &models.SearchNotesRequest{Query: query})
+		require.NoError(suite.T(), err)
+		return noteList.Notes
+	}
+
+	notes := search("groceries source:telegram")
+	require.Len(suite.T(), notes, 1)
+	require.NotNil(suite.T(), notes[0].Metadata)
+	assert.Equal(suite.T(), "telegram", notes[0].Metadata.Source)
+	assert.Equal(suite.T(), latitude, *notes[0].Metadata.Latitude)
+
+	assert.Len(suite.T(), search("source:telegram source:web"), 2)
+	assert.Len(suite.T(), search("groceries -source:web"), 2)
+
+	_, err := suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{
+		Content:  "Somewhere",
+		Metadata: &models.NoteMetadata{Latitude: &latitude},
+	})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *NoteServiceTestSuite) TestSearchNotesByColor() {
+	red, err := suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: "Red note", Color: "Red", Icon: "📌"})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "red", red.Color)
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: "Blue note", Color: "blue"})
+	require.NoError(suite.T(), err)
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: "Plain note"})
+	require.NoError(suite.T(), err)
+
+	search := func(query string) []models.NoteResponse {
+		noteList, err := suite.service.SearchNotes(context.Background(), suite.userID, &models.SearchNotesRequest{Query: query})
+		require.NoError(suite.T(), err)
+		return noteList.Notes
+	}
+
+	notes := search("color:red")
+	require.Len(suite.T(), notes, 1)
+	assert.Equal(suite.T(), "red", notes[0].Color)
+	assert.Equal(suite.T(), "📌", notes[0].Icon)
+	assert.Len(suite.T(), search("color:red color:blue"), 2)
+	assert.Len(suite.T(), search("note -color:blue"), 2)
+
+	none := ""
+	updated, err := suite.service.UpdateNote(context.Background(), suite.userID, red.ID.String(), &models.UpdateNoteRequest{Color: &none})
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), updated.Color)
+	assert.Equal(suite.T(), "📌", updated.Icon)
+	assert.Empty(suite.T(), search("color:red"))
+
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: "Magenta note", Color: "magenta"})
+	assert.Error(suite.T(), err)
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: "Lettered note", Icon: "ab"})
+	assert.Error(suite.T(), err)
+}
+
+// TestSearchNotesByRegexAndTagging tests /regex/ terms and is:untagged
+func (suite *NoteServiceTestSuite) TestSearchNotesByRegexAndTagging() {
+	ctx := context.Background()
+	_, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Title: "Release", Content: "Shipped v2.10 #work"})
+	require.NoError(suite.T(), err)
+	_, err = suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Title: "Draft release", Content: "Planning V3.0"})
+	require.NoError(suite.T(), err)
+
+	search := func(query string) []models.NoteResponse {
+		noteList, err := suite.service.SearchNotes(ctx, suite.userID, &models.SearchNotesRequest{Query: query})
+		require.NoError(suite.T(), err)
+		return noteList.Notes
+	}
+
+	assert.Len(suite.T(), search(`/v\d+\.\d+/`), 2)
+	assert.Len(suite.T(), search(`/v\d+\.\d{2}/`), 1)
+	assert.Len(suite.T(), search(`/v\d/ -title:/^draft/`), 1)
+	assert.Empty(suite.T(), search(`title:/^v\d/`))
+
+	untagged := search("is:untagged")
+	require.Len(suite.T(), untagged, 1)
+	assert.Equal(suite.T(), "Draft release", *untagged[0].Title)
+	tagged := search("release -is:untagged")
+	require.Len(suite.T(), tagged, 1)
+	assert.Equal(suite.T(), "Release", *tagged[0].Title)
+
+	_, err = suite.service.SearchNotes(ctx, suite.userID, &models.SearchNotesRequest{Query: "/a(b/"})
+	assert.Error(suite.T(), err)
+}
+
+// TestTagReadsIgnoreCase tests that tag lookups match tags stored in another case
+func (suite *NoteServiceTestSuite) TestTagReadsIgnoreCase() {
+	ctx := context.Background()
+	work, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Title: "Standup", Content: "Notes #Work"})
+	require.NoError(suite.T(), err)
+	_, err = suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Title: "Alpha", Content: "Kickoff #Work/Alpha"})
+	require.NoError(suite.T(), err)
+	_, err = suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Title: "Groceries", Content: "Milk #home"})
+	require.NoError(suite.T(), err)
+
+	tagged, err := suite.service.GetNotesByTag(ctx, suite.userID, "#work", false, 10, 0)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), 1, tagged.Total)
+	assert.Equal(suite.T(), work.ID, tagged.Notes[0].ID)
+
+	tagged, err = suite.service.GetNotesByTag(ctx, suite.userID, "#WORK", true, 10, 0)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, tagged.Total)
+
+	search := func(request *models.SearchNotesRequest) []models.NoteResponse {
+		noteList, err := suite.service.SearchNotes(ctx, suite.userID, request)
+		require.NoError(suite.T(), err)
+		return noteList.Notes
+	}
+	found := search(&models.SearchNotesRequest{Query: "tag:#work"})
+	require.Len(suite.T(), found, 1)
+	assert.Equal(suite.T(), work.ID, found[0].ID)
+	// Tags differing in case only are one tag
+	assert.Len(suite.T(), search(&models.SearchNotesRequest{Query: "tag:#work tag:#WORK"}), 1)
+	assert.Len(suite.T(), search(&models.SearchNotesRequest{Tags: []string{"#wOrK"}}), 1)
+	assert.Len(suite.T(), search(&models.SearchNotesRequest{Query: "-tag:#work"}), 2)
+}
+
+// TestMergeTagsIgnoresCase tests that merging rewrites the source tag in any case
+func (suite *NoteServiceTestSuite) TestMergeTagsIgnoresCase() {
+	ctx := context.Background()
+	note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Ship it #Work and #WORKSHOP"})
+	require.NoError(suite.T(), err)
+
+	merged, err := suite.service.MergeTags(ctx, suite.userID, "#work", "#job")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, merged)
+
+	updated, err := suite.service.GetNoteByID(ctx, suite.userID, note.ID.String())
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Ship it #job and #WORKSHOP", updated.Content)
+	tagged, err := suite.service.GetNotesByTag(ctx, suite.userID, "#job", false, 10, 0)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, tagged.Total)
+}
+
+// TestRenameTagRewritesContent tests that renaming a tag with RewriteContent
+// rewrites the hashtag and its nested tags in the notes carrying it
+func (suite *NoteServiceTestSuite) TestRenameTagRewritesContent() {
+	ctx := context.Background()
+	note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Plan #Roadmap and #roadmap/q3, not #roadmapping"})
+	require.NoError(suite.T(), err)
+	other, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Unrelated #errands"})
+	require.NoError(suite.T(), err)
+	tag, err := suite.tagService.GetTagByName(ctx, suite.userID, "#roadmap")
+	require.NoError(suite.T(), err)
+
+	renamed, rewritten, err := suite.service.RenameTag(ctx, suite.userID, tag.ID.String(),
+		&models.UpdateTagRequest{Name: "plans", RewriteContent: true})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, rewritten)
+	assert.Equal(suite.T(), tag.ID, renamed.ID)
+	assert.Equal(suite.T(), "#plans", renamed.Name)
+
+	updated, err := suite.service.GetNoteByID(ctx, suite.userID, note.ID.String())
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Plan #plans and #plans/q3, not #roadmapping", updated.Content)
+	assert.Equal(suite.T(), note.Version+1, updated.Version)
+	unchanged, err := suite.service.GetNoteByID(ctx, suite.userID, other.ID.String())
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), other.Version, unchanged.Version)
+
+	// The note keeps the renamed tags rather than getting new ones
+	tags, err := suite.service.GetTagsForNotes(ctx, []uuid.UUID{note.ID})
+	require.NoError(suite.T(), err)
+	assert.ElementsMatch(suite.T(), []string{"#plans", "#plans/q3", "#roadmapping"}, tags[note.ID])
+	child, err := suite.tagService.GetTagByName(ctx, suite.userID, "#plans/q3")
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), child.ParentID)
+	assert.Equal(suite.T(), tag.ID, *child.ParentID)
+	_, err = suite.tagService.GetTagByName(ctx, suite.userID, "#roadmap")
+	assert.ErrorIs(suite.T(), err, ErrTagNotFound)
+}
+
+// TestRenameTagKeepsContent tests that renaming a tag leaves note content
+// alone without RewriteContent, and that a tag cannot take another's name
+func (suite *NoteServiceTestSuite) TestRenameTagKeepsContent() {
+	ctx := context.Background()
+	note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Draft #outline for #essay"})
+	require.NoError(suite.T(), err)
+	tag, err := suite.tagService.GetTagByName(ctx, suite.userID, "#outline")
+	require.NoError(suite.T(), err)
+
+	_, _, err = suite.service.RenameTag(ctx, suite.userID, tag.ID.String(), &models.UpdateTagRequest{Name: "#Essay", RewriteContent: true})
+	assert.ErrorIs(suite.T(), err, ErrTagExists)
+
+	renamed, rewritten, err := suite.service.RenameTag(ctx, suite.userID, tag.ID.String(), &models.UpdateTagRequest{Name: "#structure"})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, rewritten)
+	assert.Equal(suite.T(), "#structure", renamed.Name)
+
+	unchanged, err := suite.service.GetNoteByID(ctx, suite.userID, note.ID.String())
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Draft #outline for #essay", unchanged.Content)
+	assert.Equal(suite.T(), note.Version, unchanged.Version)
+}
+
+// TestDetectLanguages tests that notes written before languages were
+// detected are given their language, leaving detected notes alone
+func (suite *NoteServiceTestSuite) TestDetectLanguages() {
+	ctx := context.Background()
+	english, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "The meeting with the team is on Monday and we will review the plan for the release"})
+	require.NoError(suite.T(), err)
+	indonesian, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Saya akan pergi ke pasar dengan ibu untuk membeli sayuran dan buah yang segar"})
+	require.NoError(suite.T(), err)
+	_, err = suite.db.ExecContext(ctx, "UPDATE notes SET language = 'und' WHERE user_id = $1", suite.userID)
+	require.NoError(suite.T(), err)
+
+	detected, err := suite.service.DetectLanguages(ctx)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), detected)
+
+	for id, want := range map[uuid.UUID]string{english.ID: "en", indonesian.ID: "id"} {
+		note, err := suite.service.GetNoteByID(ctx, suite.userID, id.String())
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), want, note.Language)
+	}
+
+	detected, err = suite.service.DetectLanguages(ctx)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), detected)
+}
+
+// TestSearchNotesUpdatedBefore tests the UpdatedBefore filter of stale notes
+func (suite *NoteServiceTestSuite) TestSearchNotesUpdatedBefore() {
+	ctx := context.Background()
+	old, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Old idea"})
+	require.NoError(suite.T(), err)
+	_, err = suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "New idea"})
+	require.NoError(suite.T(), err)
+	_, err = suite.db.ExecContext(ctx, "UPDATE notes SET updated_at = $1 WHERE id = $2", time.Now().AddDate(0, 0, -100), old.ID)
+	require.NoError(suite.T(), err)
+
+	updatedBefore := time.Now().AddDate(0, 0, -models.DefaultStaleDays)
+	noteList, err := suite.service.SearchNotes(ctx, suite.userID, &models.SearchNotesRequest{
+		UpdatedBefore: &updatedBefore,
+		OrderBy:       "updated_at",
+		OrderDir:      "asc",
+	})
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), noteList.Notes, 1)
+	assert.Equal(suite.T(), old.ID, noteList.Notes[0].ID)
+}
+
 // TestGetNotesByTag tests the GetNotesByTag method
 func (suite *NoteServiceTestSuite) TestGetNotesByTag() {
 	// Create notes with specific tags
@@ -704,7 +1012,7 @@ func (suite *NoteServiceTestSuite) TestGetNotesByTag() {
 			Title:   n.title,
 			Content: n.content,
 		}
-		_, err := suite.service.CreateNote(suite.userID, request)
+		_, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 		require.NoError(suite.T(), err)
 	}
 
@@ -752,7 +1060,7 @@ func (suite *NoteServiceTestSuite) TestGetNotesByTag() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			noteList, err := suite.service.GetNotesByTag(suite.userID, tt.tag, tt.limit, tt.offset)
+			noteList, err := suite.service.GetNotesByTag(context.Background(), suite.userID, tt.tag, false, tt.limit, tt.offset)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -778,7 +1086,7 @@ func (suite *NoteServiceTestSuite) TestGetNotesWithTimestamp() {
 		Title:   "Initial Note",
 		Content: "Created before timestamp test.",
 	}
-	_, err := suite.service.CreateNote(suite.userID, request1)
+	_, err := suite.service.CreateNote(context.Background(), suite.userID, request1)
 	require.NoError(suite.T(), err)
 
 	// Wait a bit to ensure different timestamp
@@ -795,7 +1103,7 @@ func (suite *NoteServiceTestSuite) TestGetNotesWithTimestamp() {
 		Title:   "Later Note",
 		Content: "Created after timestamp test.",
 	}
-	_, err = suite.service.CreateNote(suite.userID, request2)
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, request2)
 	require.NoError(suite.T(), err)
 
 	tests := []struct {
@@ -826,7 +1134,7 @@ func (suite *NoteServiceTestSuite) TestGetNotesWithTimestamp() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			notes, err := suite.service.GetNotesWithTimestamp(suite.userID, tt.timestamp)
+			notes, err := suite.service.GetNotesWithTimestamp(context.Background(), suite.userID, tt.timestamp)
 
 			if tt.wantErr {
 				assert.Error(suite.T(), err)
@@ -866,7 +1174,7 @@ func (suite *NoteServiceTestSuite) TestBatchCreateNotes() {
 	}
 
 	// Test successful batch creation
-	notes, err := suite.service.BatchCreateNotes(suite.userID, requests)
+	notes, err := suite.service.BatchCreateNotes(context.Background(), suite.userID, requests)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 3, len(notes))
 
@@ -899,10 +1207,24 @@ func (suite *NoteServiceTestSuite) TestBatchCreateNotes() {
 		},
 	}
 
-	notes, err = suite.service.BatchCreateNotes(suite.userID, invalidRequests)
+	notes, err = suite.service.BatchCreateNotes(context.Background(), suite.userID, invalidRequests)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), notes)
 	assert.Contains(suite.T(), err.Error(), "invalid request in batch")
+
+	// Content over the configured limit is too large, in batches and single
+	// notes alike
+	large := []*models.CreateNoteRequest{{Content: strings.Repeat("a", models.MaxContentLength()+1)}}
+	_, err = suite.service.BatchCreateNotes(context.Background(), suite.userID, large)
+	assert.ErrorIs(suite.T(), err, apperrors.ErrTooLarge)
+
+	models.SetMaxContentLength(1 << 20)
+	defer models.SetMaxContentLength(models.DefaultMaxContentLength)
+	notes, err = suite.service.BatchCreateNotes(context.Background(), suite.userID, large)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), notes[0].Content, models.DefaultMaxContentLength+1)
+	_, err = suite.service.CreateNote(context.Background(), suite.userID, &models.CreateNoteRequest{Content: strings.Repeat("a", 1<<20+1)})
+	assert.ErrorIs(suite.T(), err, apperrors.ErrTooLarge)
 }
 
 // TestBatchUpdateNotes tests the BatchUpdateNotes method
@@ -914,7 +1236,7 @@ func (suite *NoteServiceTestSuite) TestBatchUpdateNotes() {
 			Title:   fmt.Sprintf("Original Note %d", i+1),
 			Content: fmt.Sprintf("Original content %d.", i+1),
 		}
-		note, err := suite.service.CreateNote(suite.userID, request)
+		note, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 		require.NoError(suite.T(), err)
 		notes[i] = note
 	}
@@ -949,7 +1271,7 @@ func (suite *NoteServiceTestSuite) TestBatchUpdateNotes() {
 	}
 
 	// Test successful batch update
-	updatedNotes, err := suite.service.BatchUpdateNotes(suite.userID, updateRequests)
+	updatedNotes, err := suite.service.BatchUpdateNotes(context.Background(), suite.userID, updateRequests)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 3, len(updatedNotes))
 
@@ -981,12 +1303,80 @@ func (suite *NoteServiceTestSuite) TestBatchUpdateNotes() {
 		},
 	}
 
-	updatedNotes, err = suite.service.BatchUpdateNotes(suite.userID, conflictRequests)
+	updatedNotes, err = suite.service.BatchUpdateNotes(context.Background(), suite.userID, conflictRequests)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), updatedNotes)
 	assert.Contains(suite.T(), err.Error(), "has been modified")
 }
 
+// TestInsertContent tests the InsertContent method
+func (suite *NoteServiceTestSuite) TestInsertContent() {
+	ctx := context.Background()
+	note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{Content: "Inbox\n"})
+	require.NoError(suite.T(), err)
+
+	updated, err := suite.service.InsertContent(ctx, suite.userID, note.ID.String(), "call the bank #todo", false)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Inbox\ncall the bank #todo", updated.Content)
+	assert.Equal(suite.T(), note.Version+1, updated.Version)
+
+	updated, err = suite.service.InsertContent(ctx, suite.userID, note.ID.String(), "Urgent", true)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Urgent\nInbox\ncall the bank #todo", updated.Content)
+
+	tagged, err := suite.service.GetNotesByTag(ctx, suite.userID, "#todo", false, 10, 0)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, tagged.Total)
+
+	_, err = suite.service.InsertContent(ctx, suite.userID, note.ID.String(), strings.Repeat("a", 10000), false)
+	assert.ErrorIs(suite.T(), err, apperrors.ErrTooLarge)
+	_, err = suite.service.InsertContent(ctx, uuid.New().String(), note.ID.String(), "Theirs", false)
+	assert.ErrorIs(suite.T(), err, ErrNoteNotFound)
+}
+
+// TestDuplicateNote tests the DuplicateNote method
+func (suite *NoteServiceTestSuite) TestDuplicateNote() {
+	ctx := context.Background()
+	note, err := suite.service.CreateNote(ctx, suite.userID, &models.CreateNoteRequest{
+		Title:   "Weekly review",
+		Content: "Goals for the week #review",
+		Color:   "blue",
+	})
+	require.NoError(suite.T(), err)
+	// Tags added outside the content are copied too
+	require.NoError(suite.T(), suite.tagService.ProcessTagsForNote(ctx, suite.userID, note.ID.String(), []string{"#extra"}))
+
+	duplicate, err := suite.service.DuplicateNote(ctx, suite.userID, note.ID.String(), &models.DuplicateNoteRequest{PrefixTitle: true})
+	require.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), note.ID, duplicate.ID)
+	require.NotNil(suite.T(), duplicate.Title)
+	assert.Equal(suite.T(), "Copy of Weekly review", *duplicate.Title)
+	assert.Equal(suite.T(), note.Content, duplicate.Content)
+	assert.Equal(suite.T(), note.Color, duplicate.Color)
+	assert.Equal(suite.T(), note.NotebookID, duplicate.NotebookID)
+	assert.Equal(suite.T(), 1, duplicate.Version)
+
+	for _, tag := range []string{"#review", "#extra"} {
+		tagged, err := suite.service.GetNotesByTag(ctx, suite.userID, tag, false, 10, 0)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 2, tagged.Total, tag)
+	}
+
+	// Without a prefix the copy keeps the title
+	duplicate, err = suite.service.DuplicateNote(ctx, suite.userID, note.ID.String(), &models.DuplicateNoteRequest{})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Weekly review", *duplicate.Title)
+
+	// Other users' notes and notebooks are not found
+	other := testutil.NewTestUser(suite.T(), suite.db).ID.String()
+	_, err = suite.service.DuplicateNote(ctx, other, note.ID.String(), &models.DuplicateNoteRequest{})
+	assert.ErrorIs(suite.T(), err, ErrNoteNotFound)
+	otherNote, err := suite.service.CreateNote(ctx, other, &models.CreateNoteRequest{Content: "Theirs"})
+	require.NoError(suite.T(), err)
+	_, err = suite.service.DuplicateNote(ctx, suite.userID, note.ID.String(), &models.DuplicateNoteRequest{NotebookID: &otherNote.NotebookID})
+	assert.ErrorIs(suite.T(), err, apperrors.ErrValidation)
+}
+
 // TestIncrementVersion tests the IncrementVersion method
 func (suite *NoteServiceTestSuite) TestIncrementVersion() {
 	// Create a test note
@@ -994,16 +1384,16 @@ func (suite *NoteServiceTestSuite) TestIncrementVersion() {
 		Title:   "Test Note",
 		Content: "Test content for version increment.",
 	}
-	note, err := suite.service.CreateNote(suite.userID, request)
+	note, err := suite.service.CreateNote(context.Background(), suite.userID, request)
 	require.NoError(suite.T(), err)
 	originalVersion := note.Version
 
 	// Increment version
-	err = suite.service.IncrementVersion(note.ID.String())
+	err = suite.service.IncrementVersion(context.Background(), note.ID.String())
 	assert.NoError(suite.T(), err)
 
 	// Verify version was incremented
-	updatedNote, err := suite.service.GetNoteByID(suite.userID, note.ID.String())
+	updatedNote, err := suite.service.GetNoteByID(context.Background(), suite.userID, note.ID.String())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), originalVersion+1, updatedNote.Version)
 }
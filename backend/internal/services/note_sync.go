@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// tombstoneRetention is how long deleted notes are reported to sync
+// clients. Clients whose cursor is older must resync from scratch.
+const tombstoneRetention = changeRetention
+
+// syncCursorOverlap is how far before the sync a cursor points, so that notes
+// written by transactions still running during the sync are returned by the
+// next one. Notes written during the overlap are returned twice.
+const syncCursorOverlap = 5 * time.Second
+
+// NewSyncCursor returns the cursor of a sync run at now, passed by the
+// client to its next sync to get the changes since this one
+func NewSyncCursor(now time.Time) string {
+	since := now.Add(-syncCursorOverlap).UnixNano()
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(since, 10)))
+}
+
+// ParseSyncCursor returns the time a sync cursor points to. Cursors past the
+// tombstone retention return ErrCursorExpired, as deletions may be missing.
+func ParseSyncCursor(cursor string, now time.Time) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || nanos <= 0 {
+		return time.Time{}, ErrInvalidCursor
+	}
+	since := time.Unix(0, nanos)
+	if since.After(now) {
+		return time.Time{}, ErrInvalidCursor
+	}
+	if since.Before(now.Add(-tombstoneRetention)) {
+		return time.Time{}, ErrCursorExpired
+	}
+	return since, nil
+}
+
+// addTombstones records in q the deletion of the notes of userID among
+// noteIDs, before they are deleted, so that sync returns them as deleted
+func (s *NoteService) addTombstones(ctx context.Context, q QueryExecer, userID string, noteIDs []string) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO note_tombstones (note_id, user_id, deleted_at)
+		SELECT id, user_id, $3 FROM notes
+		WHERE `+s.dialect.AnyOf("id", 1, "uuid")+` AND user_id = $2
+		ON CONFLICT (note_id) DO UPDATE SET deleted_at = excluded.deleted_at
+	`, s.dialect.Array(noteIDs), userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record deleted notes: %w", err)
+	}
+	return nil
+}
+
+// GetDeletedNoteIDs returns the IDs of the notes of a user deleted after
+// since, oldest first
+func (s *NoteService) GetDeletedNoteIDs(ctx context.Context, userID string, since time.Time) ([]uuid.UUID, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT note_id FROM note_tombstones
+		WHERE user_id = $1 AND deleted_at > $2
+		ORDER BY deleted_at ASC
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted notes: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted note: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deleted notes: %w", err)
+	}
+	return ids, nil
+}
+
+// CleanupOldTombstones removes deleted notes past the retention period
+func (s *NoteService) CleanupOldTombstones(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM note_tombstones
+		WHERE deleted_at < $1
+	`, time.Now().Add(-tombstoneRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup note tombstones: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// GetNotesByIDs returns the notes of a user among noteIDs. Notes that do
+// not exist or belong to other users are left out.
+func (s *NoteService) GetNotesByIDs(ctx context.Context, userID string, noteIDs []uuid.UUID) ([]models.Note, error) {
+	notes := []models.Note{}
+	if len(noteIDs) == 0 {
+		return notes, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+noteColumns+`
+		FROM notes
+		WHERE user_id = $1 AND `+s.dialect.AnyOf("id", 2, "uuid")+`
+		ORDER BY updated_at ASC
+	`, userID, s.dialect.Array(noteIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var note models.Note
+		if err := s.readNote(ctx, rows, &note); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notes: %w", err)
+	}
+	return notes, nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncCursor(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	cursor := NewSyncCursor(now)
+	since, err := ParseSyncCursor(cursor, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ParseSyncCursor failed: %v", err)
+	}
+	if want := now.Add(-syncCursorOverlap); !since.Equal(want) {
+		t.Errorf("Expected the cursor to point to %v, got %v", want, since)
+	}
+
+	for _, invalid := range []string{"not base64!", "YWJj", NewSyncCursor(now.Add(time.Hour))} {
+		if _, err := ParseSyncCursor(invalid, now); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ParseSyncCursor(%q) = %v, want ErrInvalidCursor", invalid, err)
+		}
+	}
+
+	if _, err := ParseSyncCursor(cursor, now.Add(tombstoneRetention+time.Hour)); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("Expected a cursor past the tombstone retention to expire, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// tagsPerNote is the number of tags the fake database returns for each note
+const tagsPerNote = 3
+
+// tagQueryDriver is a database/sql driver answering note tag queries. Every
+// query costs a fixed round trip, so per-note and batched lookups can be
+// compared without a PostgreSQL server.
+type tagQueryDriver struct {
+	roundTrip time.Duration
+	queries   atomic.Int64
+}
+
+var tagQueryDrivers atomic.Int64
+
+// openTagQueryDB returns a database backed by a new tagQueryDriver
+func openTagQueryDB(tb testing.TB, roundTrip time.Duration) (*sql.DB, *tagQueryDriver) {
+	d := &tagQueryDriver{roundTrip: roundTrip}
+	name := fmt.Sprintf("note-tags-%d", tagQueryDrivers.Add(1))
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		tb.Fatalf("Failed to open fake database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func (d *tagQueryDriver) Open(string) (driver.Conn, error) {
+	return &tagQueryConn{driver: d}, nil
+}
+
+type tagQueryConn struct {
+	driver *tagQueryDriver
+}
+
+func (c *tagQueryConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *tagQueryConn) Close() error { return nil }
+
+func (c *tagQueryConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions are not supported")
+}
+
+// QueryContext returns tagsPerNote tags for every note ID in the array argument
+func (c *tagQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.queries.Add(1)
+	time.Sleep(c.driver.roundTrip)
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected one argument, got %d", len(args))
+	}
+	array, ok := args[0].Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected an array argument, got %T", args[0].Value)
+	}
+
+	rows := &tagQueryRows{}
+	for _, id := range strings.Split(strings.Trim(array, "{}"), ",") {
+		id = strings.Trim(id, `"`)
+		for i := 1; i <= tagsPerNote; i++ {
+			rows.values = append(rows.values, []driver.Value{id, fmt.Sprintf("#tag%d", i)})
+		}
+	}
+	return rows, nil
+}
+
+type tagQueryRows struct {
+	values [][]driver.Value
+}
+
+func (r *tagQueryRows) Columns() []string { return []string{"note_id", "name"} }
+
+func (r *tagQueryRows) Close() error { return nil }
+
+func (r *tagQueryRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// notePage returns a page of notes without tags
+func notePage(size int) []models.NoteResponse {
+	notes := make([]models.NoteResponse, size)
+	for i := range notes {
+		notes[i].ID = uuid.New()
+	}
+	return notes
+}
+
+func TestAddNoteTagsUsesOneQueryPerPage(t *testing.T) {
+	db, d := openTagQueryDB(t, 0)
+	service := &NoteService{db: db}
+
+	notes := notePage(20)
+	service.addNoteTags(context.Background(), notes)
+
+	if got := d.queries.Load(); got != 1 {
+		t.Errorf("Expected 1 tag query for a page of 20 notes, got %d", got)
+	}
+	for _, note := range notes {
+		if len(note.Tags) != tagsPerNote || note.Tags[0] != "#tag1" {
+			t.Errorf("Expected %d tags for note %s, got %v", tagsPerNote, note.ID, note.Tags)
+		}
+	}
+}
+
+func TestAddNoteTagsEmptyPage(t *testing.T) {
+	db, d := openTagQueryDB(t, 0)
+	service := &NoteService{db: db}
+
+	service.addNoteTags(context.Background(), nil)
+
+	if got := d.queries.Load(); got != 0 {
+		t.Errorf("Expected no tag query for an empty page, got %d", got)
+	}
+}
+
+// BenchmarkNoteTags compares looking up the tags of a page of notes one note
+// at a time with the single batched query used by the list endpoints
+func BenchmarkNoteTags(b *testing.B) {
+	const pageSize = 50
+	const roundTrip = 100 * time.Microsecond
+	ctx := context.Background()
+
+	b.Run("per_note", func(b *testing.B) {
+		db, d := openTagQueryDB(b, roundTrip)
+		service := &NoteService{db: db}
+		notes := notePage(pageSize)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for j := range notes {
+				tags, err := service.GetTagsForNotes(ctx, []uuid.UUID{notes[j].ID})
+				if err != nil {
+					b.Fatal(err)
+				}
+				notes[j].Tags = tags[notes[j].ID]
+			}
+		}
+		b.ReportMetric(float64(d.queries.Load())/float64(b.N), "queries/op")
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		db, d := openTagQueryDB(b, roundTrip)
+		service := &NoteService{db: db}
+		notes := notePage(pageSize)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			service.addNoteTags(ctx, notes)
+		}
+		b.ReportMetric(float64(d.queries.Load())/float64(b.N), "queries/op")
+	})
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// NotebookServiceInterface defines the interface for notebook operations
+type NotebookServiceInterface interface {
+	CreateNotebook(ctx context.Context, userID string, request *models.NotebookRequest) (*models.Notebook, error)
+	ListNotebooks(ctx context.Context, userID string) ([]models.Notebook, error)
+	RenameNotebook(ctx context.Context, userID, notebookID string, request *models.NotebookRequest) (*models.Notebook, error)
+	DeleteNotebook(ctx context.Context, userID, notebookID string) error
+}
+
+// Notebook errors
+var (
+	ErrNotebookNotFound = apperrors.NotFound("NOTEBOOK_NOT_FOUND", "notebook not found")
+	errNotebookExists   = apperrors.Conflict("NOTEBOOK_EXISTS", "notebook with this name already exists")
+)
+
+// codeInvalidNotebook is the error code of invalid notebooks
+const codeInvalidNotebook = "INVALID_NOTEBOOK"
+
+// NotebookService manages the notebooks that group a user's notes. Each
+// user has a default notebook, created on first use, which holds notes
+// created without a notebook and the notes of deleted notebooks.
+type NotebookService struct {
+	db *sql.DB
+}
+
+// NewNotebookService creates a new NotebookService
+func NewNotebookService(db *sql.DB) *NotebookService {
+	return &NotebookService{
+		db: db,
+	}
+}
+
+// notebookColumns lists the notebooks columns in scanNotebook order
+const notebookColumns = "id, user_id, name, is_default, organization_id, created_at, updated_at"
+
+// scanNotebook scans a row selected with notebookColumns
+func scanNotebook(row rowScanner, n *models.Notebook) error {
+	return row.Scan(&n.ID, &n.UserID, &n.Name, &n.IsDefault, &n.OrganizationID, &n.CreatedAt, &n.UpdatedAt)
+}
+
+// scanNotebookCounts scans notebooks selected with notebookColumns followed
+// by their note count
+func scanNotebookCounts(rows *sql.Rows) ([]models.Notebook, error) {
+	notebooks := []models.Notebook{}
+	for rows.Next() {
+		var n models.Notebook
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Name, &n.IsDefault, &n.OrganizationID,
+			&n.CreatedAt, &n.UpdatedAt, &n.NoteCount); err != nil {
+			return nil, fmt.Errorf("failed to scan notebook: %w", err)
+		}
+		notebooks = append(notebooks, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notebooks: %w", err)
+	}
+
+	return notebooks, nil
+}
+
+// CreateNotebook creates a notebook for a user
+func (s *NotebookService) CreateNotebook(ctx context.Context, userID string, request *models.NotebookRequest) (*models.Notebook, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidNotebook, err)
+	}
+
+	// The default notebook comes first, so it keeps its name
+	if _, err := defaultNotebook(ctx, s.db, userID); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notebooks WHERE user_id = $1
+	`, userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count notebooks: %w", err)
+	}
+	if count >= models.MaxNotebooks {
+		return nil, apperrors.Validation(codeInvalidNotebook, fmt.Sprintf("too many notebooks (max %d)", models.MaxNotebooks))
+	}
+
+	var notebook models.Notebook
+	err := scanNotebook(s.db.QueryRowContext(ctx, `
+		INSERT INTO notebooks (id, user_id, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING `+notebookColumns,
+		uuid.New(), userID, request.Name), &notebook)
+	if err == sql.ErrNoRows {
+		return nil, errNotebookExists
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create notebook: %w", err)
+	}
+
+	return &notebook, nil
+}
+
+// ListNotebooks returns a user's notebooks and those shared with them by
+// their organizations, with their note counts: the default notebook first,
+// then personal notebooks and shared ones, each by name
+func (s *NotebookService) ListNotebooks(ctx context.Context, userID string) ([]models.Notebook, error) {
+	if _, err := defaultNotebook(ctx, s.db, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+notebookColumns+`,
+			(SELECT COUNT(*) FROM notes WHERE notes.notebook_id = notebooks.id)
+		FROM notebooks
+		WHERE (user_id = $1 AND organization_id IS NULL)
+			OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $1)
+		ORDER BY is_default DESC, organization_id IS NOT NULL, name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notebooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotebookCounts(rows)
+}
+
+// RenameNotebook renames one of a user's personal notebooks
+func (s *NotebookService) RenameNotebook(ctx context.Context, userID, notebookID string, request *models.NotebookRequest) (*models.Notebook, error) {
+	if _, err := uuid.Parse(notebookID); err != nil {
+		return nil, ErrNotebookNotFound
+	}
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidNotebook, err)
+	}
+
+	var taken bool
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM notebooks WHERE user_id = $1 AND name = $2 AND id <> $3)
+	`, userID, request.Name, notebookID).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to check notebook name: %w", err)
+	}
+	if taken {
+		return nil, errNotebookExists
+	}
+
+	var notebook models.Notebook
+	err := scanNotebook(s.db.QueryRowContext(ctx, `
+		UPDATE notebooks SET name = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3 AND organization_id IS NULL
+		RETURNING `+notebookColumns,
+		request.Name, notebookID, userID), &notebook)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotebookNotFound
+	} else if err != nil {
+		if isUniqueViolation(err) {
+			return nil, errNotebookExists
+		}
+		return nil, fmt.Errorf("failed to rename notebook: %w", err)
+	}
+
+	return &notebook, nil
+}
+
+// DeleteNotebook deletes a personal notebook and moves its notes to the
+// user's default notebook, which cannot be deleted itself. Moved notes get a new
+// version, so clients syncing them see the move.
+func (s *NotebookService) DeleteNotebook(ctx context.Context, userID, notebookID string) error {
+	if _, err := uuid.Parse(notebookID); err != nil {
+		return ErrNotebookNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var isDefault bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT is_default FROM notebooks WHERE id = $1 AND user_id = $2 AND organization_id IS NULL
+	`, notebookID, userID).Scan(&isDefault)
+	if err == sql.ErrNoRows {
+		return ErrNotebookNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get notebook: %w", err)
+	}
+	if isDefault {
+		return apperrors.Conflict("DEFAULT_NOTEBOOK", "the default notebook cannot be deleted")
+	}
+
+	defaultID, err := defaultNotebook(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE notes SET notebook_id = $1, version = version + 1, updated_at = NOW()
+		WHERE notebook_id = $2 AND user_id = $3
+	`, defaultID, notebookID, userID); err != nil {
+		return fmt.Errorf("failed to move notes to the default notebook: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM notebooks WHERE id = $1 AND user_id = $2
+	`, notebookID, userID); err != nil {
+		return fmt.Errorf("failed to delete notebook: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notebook deletion: %w", err)
+	}
+
+	return nil
+}
+
+// defaultNotebook returns the ID of a user's default notebook, creating it
+// on first use
+func defaultNotebook(ctx context.Context, q QueryExecer, userID string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := q.QueryRowContext(ctx, `
+		SELECT id FROM notebooks WHERE user_id = $1 AND is_default = TRUE
+	`, userID).Scan(&id)
+	if err == nil {
+		return id, nil
+	} else if err != sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("failed to get default notebook: %w", err)
+	}
+
+	// Concurrent requests may both get here; the unique index keeps one
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO notebooks (id, user_id, name, is_default)
+		VALUES ($1, $2, $3, TRUE)
+		ON CONFLICT DO NOTHING
+	`, uuid.New(), userID, models.DefaultNotebookName); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create default notebook: %w", err)
+	}
+
+	if err := q.QueryRowContext(ctx, `
+		SELECT id FROM notebooks WHERE user_id = $1 AND is_default = TRUE
+	`, userID).Scan(&id); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get default notebook: %w", err)
+	}
+	return id, nil
+}
+
+// checkNoteNotebook checks the notebook a user creates a note in or moves it
+// to, setting the author's default notebook when it has none. Authors write
+// to their personal notebooks and, as editors or admins, members write to the
+// notebooks of their organizations; private notes stay out of shared
+// notebooks, as other members could not decrypt them.
+func checkNoteNotebook(ctx context.Context, q QueryExecer, userID string, note *models.Note) error {
+	if note.NotebookID == uuid.Nil {
+		notebookID, err := defaultNotebook(ctx, q, note.UserID.String())
+		if err != nil {
+			return err
+		}
+		note.NotebookID = notebookID
+		return nil
+	}
+
+	var owner uuid.UUID
+	var organizationID *uuid.UUID
+	err := q.QueryRowContext(ctx, `
+		SELECT user_id, organization_id FROM notebooks WHERE id = $1
+	`, note.NotebookID).Scan(&owner, &organizationID)
+	if err == sql.ErrNoRows {
+		return apperrors.Validation(codeInvalidNotebook, "notebook not found")
+	} else if err != nil {
+		return fmt.Errorf("failed to get notebook: %w", err)
+	}
+
+	if organizationID == nil {
+		if owner != note.UserID || owner.String() != userID {
+			return apperrors.Validation(codeInvalidNotebook, "notebook not found")
+		}
+		return nil
+	}
+
+	role, err := notebookRole(ctx, q, userID, note.NotebookID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return apperrors.Validation(codeInvalidNotebook, "notebook not found")
+	}
+	if !models.OrgRoleCanEdit(role) {
+		return errOrgForbidden
+	}
+	if note.IsPrivate {
+		return apperrors.Validation(codeInvalidNotebook, "private notes cannot be in shared notebooks")
+	}
+	return nil
+}
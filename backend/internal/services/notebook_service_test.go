@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestNotebooks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewNotebookService(db)
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	// Notes created without a notebook land in the default notebook
+	personal, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Groceries"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	notebooks, err := service.ListNotebooks(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to list notebooks: %v", err)
+	}
+	if len(notebooks) != 1 || !notebooks[0].IsDefault || notebooks[0].Name != models.DefaultNotebookName {
+		t.Fatalf("Expected only the default notebook, got %+v", notebooks)
+	}
+	defaultID := notebooks[0].ID
+	if personal.NotebookID != defaultID {
+		t.Errorf("Expected the note in the default notebook, got %s", personal.NotebookID)
+	}
+
+	work, err := service.CreateNotebook(ctx, userID, &models.NotebookRequest{Name: " Work "})
+	if err != nil {
+		t.Fatalf("Failed to create notebook: %v", err)
+	}
+	if work.Name != "Work" || work.IsDefault {
+		t.Errorf("Unexpected notebook %+v", work)
+	}
+	if _, err := service.CreateNotebook(ctx, userID, &models.NotebookRequest{Name: "Work"}); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("Expected a conflict for a duplicate name, got %v", err)
+	}
+
+	standup, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Standup", NotebookID: &work.ID})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	other := testutil.NewTestUser(t, db)
+	if _, err := noteService.CreateNote(ctx, other.ID.String(), &models.CreateNoteRequest{Content: "Standup", NotebookID: &work.ID}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected another user's notebook to be rejected, got %v", err)
+	}
+
+	inNotebook := func(notebookID uuid.UUID) []models.NoteResponse {
+		t.Helper()
+		list, err := noteService.SearchNotes(ctx, userID, &models.SearchNotesRequest{NotebookID: &notebookID})
+		if err != nil {
+			t.Fatalf("Failed to search notes: %v", err)
+		}
+		return list.Notes
+	}
+	if notes := inNotebook(work.ID); len(notes) != 1 || notes[0].ID != standup.ID {
+		t.Errorf("Expected only the standup note in Work, got %+v", notes)
+	}
+
+	// Moving a note changes its notebook
+	if _, err := noteService.UpdateNote(ctx, userID, personal.ID.String(), &models.UpdateNoteRequest{NotebookID: &work.ID}); err != nil {
+		t.Fatalf("Failed to move note: %v", err)
+	}
+	if notes := inNotebook(work.ID); len(notes) != 2 {
+		t.Errorf("Expected 2 notes in Work, got %d", len(notes))
+	}
+
+	if _, err := service.RenameNotebook(ctx, userID, work.ID.String(), &models.NotebookRequest{Name: models.DefaultNotebookName}); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("Expected a conflict renaming to a taken name, got %v", err)
+	}
+	renamed, err := service.RenameNotebook(ctx, userID, work.ID.String(), &models.NotebookRequest{Name: "Project X"})
+	if err != nil || renamed.Name != "Project X" {
+		t.Fatalf("Failed to rename notebook: %+v, %v", renamed, err)
+	}
+
+	// Deleting a notebook moves its notes to the default notebook
+	if err := service.DeleteNotebook(ctx, userID, defaultID.String()); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("Expected the default notebook to be kept, got %v", err)
+	}
+	if err := service.DeleteNotebook(ctx, userID, work.ID.String()); err != nil {
+		t.Fatalf("Failed to delete notebook: %v", err)
+	}
+	if notes := inNotebook(defaultID); len(notes) != 2 {
+		t.Errorf("Expected both notes in the default notebook, got %d", len(notes))
+	}
+	moved, err := noteService.GetNoteByID(ctx, userID, standup.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if moved.NotebookID != defaultID || moved.Version != standup.Version+1 {
+		t.Errorf("Expected the note moved with a new version, got notebook %s version %d", moved.NotebookID, moved.Version)
+	}
+	if err := service.DeleteNotebook(ctx, userID, work.ID.String()); !errors.Is(err, ErrNotebookNotFound) {
+		t.Errorf("Expected a deleted notebook to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// NotificationRuleServiceInterface defines the interface for notification rule operations
+type NotificationRuleServiceInterface interface {
+	CreateRule(ctx context.Context, userID string, request *models.CreateNotificationRuleRequest) (*models.NotificationRule, error)
+	ListRules(ctx context.Context, userID string) ([]models.NotificationRule, error)
+	DeleteRule(ctx context.Context, userID, ruleID string) error
+}
+
+// errNotificationRuleNotFound is returned for rules missing or of other users
+var errNotificationRuleNotFound = apperrors.NotFound("NOTIFICATION_RULE_NOT_FOUND", "notification rule not found")
+
+// NotificationRuleService notifies users when notes with the tags of their
+// rules are created or shared with them. Rules are evaluated as notes are
+// written and deliver in the app, by email and to the user's webhooks
+// subscribed to rule.matched.
+type NotificationRuleService struct {
+	db                  *sql.DB
+	notificationService NotificationServiceInterface
+	sender              email.Sender
+}
+
+// NewNotificationRuleService creates a new NotificationRuleService
+func NewNotificationRuleService(db *sql.DB, notificationService NotificationServiceInterface, sender email.Sender) *NotificationRuleService {
+	return &NotificationRuleService{
+		db:                  db,
+		notificationService: notificationService,
+		sender:              sender,
+	}
+}
+
+// notificationRuleColumns lists the notification_rules columns in
+// scanNotificationRule order
+const notificationRuleColumns = "id, user_id, tag, events, channels, created_at"
+
+// scanNotificationRule scans a row selected with notificationRuleColumns
+func scanNotificationRule(row rowScanner, r *models.NotificationRule) error {
+	return row.Scan(&r.ID, &r.UserID, &r.Tag, pq.Array(&r.Events), pq.Array(&r.Channels), &r.CreatedAt)
+}
+
+// CreateRule creates a notification rule for a user
+func (s *NotificationRuleService) CreateRule(ctx context.Context, userID string, request *models.CreateNotificationRuleRequest) (*models.NotificationRule, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_NOTIFICATION_RULE", err)
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notification_rules WHERE user_id = $1
+	`, userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count notification rules: %w", err)
+	}
+	if count >= models.MaxNotificationRules {
+		return nil, apperrors.Validation("INVALID_NOTIFICATION_RULE", fmt.Sprintf("too many notification rules (max %d)", models.MaxNotificationRules))
+	}
+
+	var rule models.NotificationRule
+	err := scanNotificationRule(s.db.QueryRowContext(ctx, `
+		INSERT INTO notification_rules (id, user_id, tag, events, channels)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+notificationRuleColumns,
+		uuid.New(), userID, request.Tag, pq.Array(request.Events), pq.Array(request.Channels)), &rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification rule: %w", err)
+	}
+
+	return &rule, nil
+}
+
+// ListRules returns a user's notification rules, newest first
+func (s *NotificationRuleService) ListRules(ctx context.Context, userID string) ([]models.NotificationRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+notificationRuleColumns+`
+		FROM notification_rules
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification rules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotificationRules(rows)
+}
+
+// DeleteRule removes a notification rule and its recorded matches
+func (s *NotificationRuleService) DeleteRule(ctx context.Context, userID, ruleID string) error {
+	if _, err := uuid.Parse(ruleID); err != nil {
+		return errNotificationRuleNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM notification_rules WHERE id = $1 AND user_id = $2
+	`, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return errNotificationRuleNotFound
+	}
+
+	return nil
+}
+
+// scanNotificationRules scans rows selected with notificationRuleColumns
+func scanNotificationRules(rows *sql.Rows) ([]models.NotificationRule, error) {
+	rules := []models.NotificationRule{}
+	for rows.Next() {
+		var rule models.NotificationRule
+		if err := scanNotificationRule(rows, &rule); err != nil {
+			return nil, fmt.Errorf("failed to scan notification rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// NoteWritten implements NoteWriteListener. A newly created note triggers
+// its author's note_created rules, and a note in a shared notebook triggers
+// the note_shared rules of the organization's other members. Each rule
+// notifies once per note: tagging a shared note later notifies members,
+// further edits of it do not.
+func (s *NotificationRuleService) NoteWritten(ctx context.Context, note *models.Note) {
+	tags := note.ExtractHashtags()
+	if len(tags) == 0 {
+		return
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+notificationRuleColumns+`
+		FROM notification_rules
+		WHERE user_id = $1
+			OR user_id IN (
+				SELECT m.user_id FROM organization_members m
+				JOIN notebooks nb ON nb.organization_id = m.organization_id
+				WHERE nb.id = $2
+			)
+	`, note.UserID, note.NotebookID)
+	if err != nil {
+		log.Printf("[NotificationRuleService] WARNING: failed to load notification rules for note %s: %v", note.ID, err)
+		return
+	}
+	defer rows.Close()
+	rules, err := scanNotificationRules(rows)
+	if err != nil {
+		log.Printf("[NotificationRuleService] WARNING: failed to load notification rules for note %s: %v", note.ID, err)
+		return
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		event := models.RuleEventNoteShared
+		if rule.UserID == note.UserID {
+			// Authors are not notified of sharing their own notes, and
+			// edits are not creations
+			if note.Version != 1 {
+				continue
+			}
+			event = models.RuleEventNoteCreated
+		}
+		if !rule.Matches(event, tags) {
+			continue
+		}
+
+		isNew, err := s.recordMatch(ctx, rule.ID, note.ID)
+		if err != nil {
+			log.Printf("[NotificationRuleService] WARNING: failed to record match for rule %s: %v", rule.ID, err)
+			continue
+		}
+		if isNew {
+			s.deliver(ctx, rule, event, note)
+		}
+	}
+}
+
+// recordMatch stores a rule match and reports whether it is new
+func (s *NotificationRuleService) recordMatch(ctx context.Context, ruleID, noteID uuid.UUID) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO notification_rule_matches (rule_id, note_id)
+		VALUES ($1, $2)
+		ON CONFLICT (rule_id, note_id) DO NOTHING
+	`, ruleID, noteID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ruleMatchData is the data of the notifications and webhook deliveries of
+// a rule match. Titles of private notes are not sent out.
+type ruleMatchData struct {
+	RuleID     uuid.UUID `json:"rule_id"`
+	Tag        string    `json:"tag"`
+	Event      string    `json:"event"`
+	NoteID     uuid.UUID `json:"note_id"`
+	Title      *string   `json:"title"`
+	NotebookID uuid.UUID `json:"notebook_id"`
+}
+
+// deliver sends a rule match through each of the rule's channels. A channel
+// failing is logged and does not keep the others from delivering.
+func (s *NotificationRuleService) deliver(ctx context.Context, rule *models.NotificationRule, event string, note *models.Note) {
+	data := ruleMatchData{
+		RuleID:     rule.ID,
+		Tag:        rule.Tag,
+		Event:      event,
+		NoteID:     note.ID,
+		NotebookID: note.NotebookID,
+	}
+	noteTitle := "Untitled note"
+	if note.Title != nil && *note.Title != "" && !note.IsPrivate {
+		data.Title = note.Title
+		noteTitle = *note.Title
+	}
+
+	subject := fmt.Sprintf("New note tagged %s", rule.Tag)
+	if event == models.RuleEventNoteShared {
+		subject = fmt.Sprintf("Note tagged %s shared with you", rule.Tag)
+	}
+
+	for _, channel := range rule.Channels {
+		var err error
+		switch channel {
+		case models.NotificationChannelInApp:
+			_, err = s.notificationService.Notify(ctx, rule.UserID, models.NotificationTypeTagRule, subject, noteTitle, data)
+		case models.NotificationChannelEmail:
+			err = s.sendEmail(ctx, rule.UserID, subject, noteTitle)
+		case models.NotificationChannelWebhook:
+			err = s.queueWebhooks(ctx, rule.UserID, data)
+		}
+		if err != nil {
+			log.Printf("[NotificationRuleService] WARNING: failed to deliver rule %s through %s: %v", rule.ID, channel, err)
+		}
+	}
+}
+
+// sendEmail emails a rule match to the rule's owner
+func (s *NotificationRuleService) sendEmail(ctx context.Context, userID uuid.UUID, subject, noteTitle string) error {
+	var address string
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT email FROM users WHERE id = $1
+	`, userID).Scan(&address); err != nil {
+		return fmt.Errorf("failed to get user email: %w", err)
+	}
+
+	return s.sender.Send(ctx, email.Message{
+		To:      address,
+		Subject: subject,
+		Body:    fmt.Sprintf("%s\n\n%s\n", subject, noteTitle),
+	})
+}
+
+// queueWebhooks queues a rule.matched delivery to every active webhook of
+// the rule's owner that subscribed to it, as the triggers do for note events
+func (s *NotificationRuleService) queueWebhooks(ctx context.Context, userID uuid.UUID, data ruleMatchData) error {
+	payload, err := json.Marshal(map[string]any{
+		"event":      models.WebhookEventRuleMatched,
+		"created_at": time.Now().UTC(),
+		"data":       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT w.id, $1, $2
+		FROM webhooks w
+		WHERE w.user_id = $3 AND w.active AND $4 = ANY(w.events)
+	`, models.WebhookEventRuleMatched, payload, userID, models.WebhookEventRuleMatched); err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
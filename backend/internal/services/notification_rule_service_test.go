@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/google/uuid"
+)
+
+// recordingNotifier keeps the notifications it is asked to create
+type recordingNotifier struct {
+	NotificationServiceInterface
+	notifications []models.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, userID uuid.UUID, notificationType, title, body string, data any) (*models.Notification, error) {
+	notification := models.Notification{UserID: userID, Type: notificationType, Title: title, Body: body}
+	n.notifications = append(n.notifications, notification)
+	return &notification, nil
+}
+
+func TestNotificationRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	notifier := &recordingNotifier{}
+	sender := &recordingSender{}
+	service := NewNotificationRuleService(db, notifier, sender)
+	organizationService := NewOrganizationService(db, email.LogSender{})
+	noteService := NewNoteService(db, NewTagService(db))
+	noteService.AddWriteListener(service)
+	ctx := context.Background()
+
+	owner := testutil.NewTestUser(t, db)
+	member := testutil.NewTestUser(t, db)
+	ownerID, memberID := owner.ID.String(), member.ID.String()
+
+	if _, err := service.CreateRule(ctx, ownerID, &models.CreateNotificationRuleRequest{Tag: "urgent", Events: []string{"note_deleted"}}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected unknown events rejected, got %v", err)
+	}
+	ownerRule, err := service.CreateRule(ctx, ownerID, &models.CreateNotificationRuleRequest{Tag: " Urgent ", Channels: []string{"in_app", "email", "in_app"}})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if ownerRule.Tag != "#urgent" || len(ownerRule.Events) != 2 || len(ownerRule.Channels) != 2 {
+		t.Errorf("Unexpected rule %+v", ownerRule)
+	}
+	if _, err := service.CreateRule(ctx, memberID, &models.CreateNotificationRuleRequest{Tag: "#urgent", Events: []string{models.RuleEventNoteShared}}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Creating a tagged note notifies its author once, in the app and by email
+	note, err := noteService.CreateNote(ctx, ownerID, &models.CreateNoteRequest{Title: "Outage", Content: "Payments down #URGENT"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if len(notifier.notifications) != 1 || notifier.notifications[0].UserID != owner.ID || notifier.notifications[0].Body != "Outage" {
+		t.Errorf("Expected the author notified in the app, got %+v", notifier.notifications)
+	}
+	if len(sender.messages) != 1 || sender.messages[0].To != owner.Email {
+		t.Errorf("Expected the author notified by email, got %+v", sender.messages)
+	}
+	content := "Payments back #urgent"
+	if _, err := noteService.UpdateNote(ctx, ownerID, note.ID.String(), &models.UpdateNoteRequest{Content: &content}); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if len(notifier.notifications) != 1 {
+		t.Errorf("Expected edits not to notify again, got %+v", notifier.notifications)
+	}
+
+	// Notes tagged in a shared notebook notify the other members
+	org, err := organizationService.CreateOrganization(ctx, ownerID, &models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	invitation, err := organizationService.InviteMember(ctx, ownerID, org.ID.String(), &models.InviteMemberRequest{Email: member.Email, Role: models.OrgRoleEditor})
+	if err != nil {
+		t.Fatalf("Failed to invite member: %v", err)
+	}
+	if _, err := organizationService.AcceptInvitation(ctx, member, invitation.Token); err != nil {
+		t.Fatalf("Failed to accept invitation: %v", err)
+	}
+	shared, err := organizationService.CreateNotebook(ctx, ownerID, org.ID.String(), &models.NotebookRequest{Name: "Ops"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+
+	sharedNote, err := noteService.CreateNote(ctx, ownerID, &models.CreateNoteRequest{Content: "Rotate keys", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if len(notifier.notifications) != 1 {
+		t.Errorf("Expected untagged notes not to notify, got %+v", notifier.notifications)
+	}
+	content = "Rotate keys #urgent"
+	if _, err := noteService.UpdateNote(ctx, ownerID, sharedNote.ID.String(), &models.UpdateNoteRequest{Content: &content}); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if len(notifier.notifications) != 2 || notifier.notifications[1].UserID != member.ID || notifier.notifications[1].Type != models.NotificationTypeTagRule {
+		t.Errorf("Expected the member notified of the shared note, got %+v", notifier.notifications)
+	}
+	if len(sender.messages) != 1 {
+		t.Errorf("Expected no email for in-app rules, got %+v", sender.messages)
+	}
+
+	rules, err := service.ListRules(ctx, ownerID)
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("Expected 1 rule, got %+v, %v", rules, err)
+	}
+	if err := service.DeleteRule(ctx, memberID, ownerRule.ID.String()); !errors.Is(err, errNotificationRuleNotFound) {
+		t.Errorf("Expected other users' rules not found, got %v", err)
+	}
+	if err := service.DeleteRule(ctx, ownerID, ownerRule.ID.String()); err != nil {
+		t.Errorf("Failed to delete rule: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// NotificationServiceInterface defines the interface for notification operations
+type NotificationServiceInterface interface {
+	Notify(ctx context.Context, userID uuid.UUID, notificationType, title, body string, data any) (*models.Notification, error)
+	ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset int) (*models.NotificationList, error)
+	MarkRead(ctx context.Context, userID, notificationID string) error
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+}
+
+// NotificationService stores and serves in-app notifications
+type NotificationService struct {
+	db *sql.DB
+}
+
+// NewNotificationService creates a new NotificationService
+func NewNotificationService(db *sql.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Notify creates a notification for a user; data is stored as JSON
+func (s *NotificationService) Notify(ctx context.Context, userID uuid.UUID, notificationType, title, body string, data any) (*models.Notification, error) {
+	payload := []byte("{}")
+	if data != nil {
+		var err error
+		payload, err = json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode notification data: %w", err)
+		}
+	}
+
+	notification := &models.Notification{
+		UserID: userID,
+		Type:   notificationType,
+		Title:  title,
+		Body:   body,
+		Data:   payload,
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, type, title, body, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := s.db.QueryRowContext(ctx, query, userID, notificationType, title, body, payload).
+		Scan(&notification.ID, &notification.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return notification, nil
+}
+
+// ListNotifications returns a user's notifications, newest first
+func (s *NotificationService) ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit, offset int) (*models.NotificationList, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := "user_id = $1"
+	if unreadOnly {
+		filter += " AND read_at IS NULL"
+	}
+
+	list := &models.NotificationList{
+		Notifications: []models.Notification{},
+		Limit:         limit,
+		Offset:        offset,
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications
+		WHERE `+filter, userID).Scan(&list.Total, &list.Unread)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, type, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE `+filter+`
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n models.Notification
+		var data []byte
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &data, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		n.Data = data
+		list.Notifications = append(list.Notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	list.HasMore = offset+limit < list.Total
+	return list, nil
+}
+
+// MarkRead marks a single notification as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.NotFound("NOTIFICATION_NOT_FOUND", "notification not found")
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user as read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
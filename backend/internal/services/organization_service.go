@@ -0,0 +1,793 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// OrganizationServiceInterface defines the interface for organizations,
+// their members and shared notebooks
+type OrganizationServiceInterface interface {
+	CreateOrganization(ctx context.Context, userID string, request *models.CreateOrganizationRequest) (*models.Organization, error)
+	ListOrganizations(ctx context.Context, userID string) ([]models.Organization, error)
+	UpdateOrganization(ctx context.Context, userID, orgID string, request *models.UpdateOrganizationRequest) (*models.Organization, error)
+	DeleteOrganization(ctx context.Context, userID, orgID string) error
+	ListMembers(ctx context.Context, userID, orgID string) ([]models.OrgMember, error)
+	UpdateMember(ctx context.Context, userID, orgID, memberID string, request *models.UpdateMemberRequest) (*models.OrgMember, error)
+	RemoveMember(ctx context.Context, userID, orgID, memberID string) error
+	InviteMember(ctx context.Context, userID, orgID string, request *models.InviteMemberRequest) (*models.OrgInvitation, error)
+	ListInvitations(ctx context.Context, userID, orgID string) ([]models.OrgInvitation, error)
+	AcceptInvitation(ctx context.Context, user *models.User, token string) (*models.Organization, error)
+	CreateNotebook(ctx context.Context, userID, orgID string, request *models.NotebookRequest) (*models.Notebook, error)
+	ListNotebooks(ctx context.Context, userID, orgID string) ([]models.Notebook, error)
+	DeleteNotebook(ctx context.Context, userID, orgID, notebookID string) error
+	WatermarkedNotebooks(ctx context.Context, userID string) (map[uuid.UUID]bool, error)
+}
+
+// Organization errors
+var (
+	ErrOrganizationNotFound = apperrors.NotFound("ORGANIZATION_NOT_FOUND", "organization not found")
+	ErrOrgMemberNotFound    = apperrors.NotFound("MEMBER_NOT_FOUND", "member not found")
+	ErrInvitationNotFound   = apperrors.NotFound("INVITATION_NOT_FOUND", "invitation not found or expired")
+	errOrgForbidden         = apperrors.New(apperrors.ErrForbidden, "ORGANIZATION_FORBIDDEN", "your role in the organization does not allow this")
+	errLastOrgAdmin         = apperrors.Conflict("LAST_ADMIN", "an organization needs at least one admin")
+)
+
+// codeInvalidOrganization is the error code of invalid organization requests
+const codeInvalidOrganization = "INVALID_ORGANIZATION"
+
+// OrganizationService manages organizations: teams whose members share
+// notebooks. Notes in a shared notebook keep their author as owner; members
+// read them through the note service, which also checks their role before
+// writes.
+type OrganizationService struct {
+	db     *sql.DB
+	sender email.Sender
+}
+
+// NewOrganizationService creates a new OrganizationService sending
+// invitations with sender
+func NewOrganizationService(db *sql.DB, sender email.Sender) *OrganizationService {
+	return &OrganizationService{
+		db:     db,
+		sender: sender,
+	}
+}
+
+// organizationColumns lists the organizations columns in scanOrganization order
+const organizationColumns = "id, name, created_by, created_at, updated_at, watermark_exports"
+
+// scanOrganization scans a row selected with organizationColumns
+func scanOrganization(row rowScanner, o *models.Organization) error {
+	return row.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.UpdatedAt, &o.WatermarkExports)
+}
+
+// invitationColumns lists the organization_invitations columns in
+// scanInvitation order
+const invitationColumns = "id, organization_id, email, role, invited_by, created_at, expires_at, accepted_at"
+
+// scanInvitation scans a row selected with invitationColumns
+func scanInvitation(row rowScanner, i *models.OrgInvitation) error {
+	return row.Scan(&i.ID, &i.OrganizationID, &i.Email, &i.Role, &i.InvitedBy, &i.CreatedAt, &i.ExpiresAt, &i.AcceptedAt)
+}
+
+// CreateOrganization creates an organization with the user as its admin
+func (s *OrganizationService) CreateOrganization(ctx context.Context, userID string, request *models.CreateOrganizationRequest) (*models.Organization, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidOrganization, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var organization models.Organization
+	err = scanOrganization(tx.QueryRowContext(ctx, `
+		INSERT INTO organizations (id, name, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING `+organizationColumns,
+		uuid.New(), request.Name, userID), &organization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+	`, organization.ID, userID, models.OrgRoleAdmin); err != nil {
+		return nil, fmt.Errorf("failed to add organization admin: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit organization creation: %w", err)
+	}
+
+	organization.Role = models.OrgRoleAdmin
+	return &organization, nil
+}
+
+// ListOrganizations returns the organizations a user is a member of, with
+// their role, ordered by name
+func (s *OrganizationService) ListOrganizations(ctx context.Context, userID string) ([]models.Organization, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at, o.watermark_exports, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	organizations := []models.Organization{}
+	for rows.Next() {
+		var o models.Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.UpdatedAt, &o.WatermarkExports, &o.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		organizations = append(organizations, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organizations: %w", err)
+	}
+
+	return organizations, nil
+}
+
+// UpdateOrganization renames an organization or changes its export
+// watermark policy. Only admins change organizations.
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, userID, orgID string, request *models.UpdateOrganizationRequest) (*models.Organization, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidOrganization, err)
+	}
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	var organization models.Organization
+	err := scanOrganization(s.db.QueryRowContext(ctx, `
+		UPDATE organizations
+		SET name = COALESCE($1, name), watermark_exports = COALESCE($2, watermark_exports), updated_at = NOW()
+		WHERE id = $3
+		RETURNING `+organizationColumns,
+		request.Name, request.WatermarkExports, orgID), &organization)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	organization.Role = models.OrgRoleAdmin
+	return &organization, nil
+}
+
+// DeleteOrganization deletes an organization. The notes of its notebooks
+// move to the default notebooks of their authors.
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, userID, orgID string) error {
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := releaseNotebooks(ctx, tx, "organization_id = $1", orgID); err != nil {
+		return err
+	}
+
+	// Members, invitations and notebooks are deleted with the organization
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, orgID); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit organization deletion: %w", err)
+	}
+
+	return nil
+}
+
+// ListMembers returns the members of an organization ordered by email
+func (s *OrganizationService) ListMembers(ctx context.Context, userID, orgID string) ([]models.OrgMember, error) {
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.organization_id, m.user_id, u.email, m.role, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY u.email
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.OrgMember{}
+	for rows.Next() {
+		var m models.OrgMember
+		if err := rows.Scan(&m.OrganizationID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members = append(members, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating members: %w", err)
+	}
+
+	return members, nil
+}
+
+// UpdateMember changes the role of a member. Only admins change roles, and
+// the last admin cannot step down.
+func (s *OrganizationService) UpdateMember(ctx context.Context, userID, orgID, memberID string, request *models.UpdateMemberRequest) (*models.OrgMember, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidOrganization, err)
+	}
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(memberID); err != nil {
+		return nil, ErrOrgMemberNotFound
+	}
+
+	if request.Role != models.OrgRoleAdmin {
+		if err := s.checkOtherAdmin(ctx, orgID, memberID); err != nil {
+			return nil, err
+		}
+	}
+
+	var member models.OrgMember
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE organization_members SET role = $1
+		WHERE organization_id = $2 AND user_id = $3
+		RETURNING organization_id, user_id, role, created_at
+	`, request.Role, orgID, memberID).Scan(&member.OrganizationID, &member.UserID, &member.Role, &member.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrgMemberNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update member: %w", err)
+	}
+
+	if err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1`, memberID).Scan(&member.Email); err != nil {
+		return nil, fmt.Errorf("failed to get member email: %w", err)
+	}
+
+	return &member, nil
+}
+
+// RemoveMember removes a member from an organization. Admins remove anyone
+// and members may leave; the last admin cannot. Notes the member wrote in
+// shared notebooks stay there.
+func (s *OrganizationService) RemoveMember(ctx context.Context, userID, orgID, memberID string) error {
+	required := models.OrgRoleAdmin
+	if memberID == userID {
+		required = models.OrgRoleViewer
+	}
+	if err := s.requireRole(ctx, userID, orgID, required); err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(memberID); err != nil {
+		return ErrOrgMemberNotFound
+	}
+	if err := s.checkOtherAdmin(ctx, orgID, memberID); err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2
+	`, orgID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	} else if rows == 0 {
+		return ErrOrgMemberNotFound
+	}
+
+	return nil
+}
+
+// InviteMember invites an email address to join an organization with a
+// role and emails the invitation token. The token is also returned, so
+// admins can pass it on when email is not set up.
+func (s *OrganizationService) InviteMember(ctx context.Context, userID, orgID string, request *models.InviteMemberRequest) (*models.OrgInvitation, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidOrganization, err)
+	}
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	var name string
+	var members, member int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT o.name,
+			(SELECT COUNT(*) FROM organization_members WHERE organization_id = o.id) +
+			(SELECT COUNT(*) FROM organization_invitations
+				WHERE organization_id = o.id AND accepted_at IS NULL AND expires_at > NOW()),
+			(SELECT COUNT(*) FROM organization_members m JOIN users u ON u.id = m.user_id
+				WHERE m.organization_id = o.id AND LOWER(u.email) = $2)
+		FROM organizations o
+		WHERE o.id = $1
+	`, orgID, request.Email).Scan(&name, &members, &member); err != nil {
+		return nil, fmt.Errorf("failed to count members: %w", err)
+	}
+	if member > 0 {
+		return nil, apperrors.Conflict("ALREADY_MEMBER", "this email already belongs to a member")
+	}
+	if members >= models.MaxOrganizationMembers {
+		return nil, apperrors.Validation(codeInvalidOrganization, fmt.Sprintf("too many members and invitations (max %d)", models.MaxOrganizationMembers))
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+
+	var invitation models.OrgInvitation
+	err = scanInvitation(s.db.QueryRowContext(ctx, `
+		INSERT INTO organization_invitations (id, organization_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+invitationColumns,
+		uuid.New(), orgID, request.Email, request.Role, hashInvitationToken(token), userID,
+		time.Now().Add(models.OrgInvitationTTL)), &invitation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+	invitation.Token = token
+
+	message := email.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You're invited to join %s", name),
+		Body: fmt.Sprintf("You have been invited to join %s as %s.\n\n"+
+			"Sign in with this email address and accept the invitation with the code:\n\n%s\n\n"+
+			"The invitation expires on %s.\n",
+			name, invitation.Role, token, invitation.ExpiresAt.UTC().Format("January 2, 2006")),
+	}
+	if err := s.sender.Send(ctx, message); err != nil {
+		log.Printf("[OrganizationService] WARNING: failed to email invitation %s: %v", invitation.ID, err)
+	}
+
+	return &invitation, nil
+}
+
+// ListInvitations returns the pending invitations of an organization,
+// newest first
+func (s *OrganizationService) ListInvitations(ctx context.Context, userID, orgID string) ([]models.OrgInvitation, error) {
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+invitationColumns+`
+		FROM organization_invitations
+		WHERE organization_id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []models.OrgInvitation{}
+	for rows.Next() {
+		var invitation models.OrgInvitation
+		if err := scanInvitation(rows, &invitation); err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// AcceptInvitation makes the user a member of the organization that
+// invited their email address
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, user *models.User, token string) (*models.Organization, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Invitations are for one address; accepting marks them used
+	var invitation models.OrgInvitation
+	err = scanInvitation(tx.QueryRowContext(ctx, `
+		UPDATE organization_invitations SET accepted_at = NOW()
+		WHERE token_hash = $1 AND email = $2 AND accepted_at IS NULL AND expires_at > NOW()
+		RETURNING `+invitationColumns,
+		hashInvitationToken(strings.TrimSpace(token)), strings.ToLower(user.Email)), &invitation)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvitationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, invitation.OrganizationID, user.ID, invitation.Role); err != nil {
+		return nil, fmt.Errorf("failed to add member: %w", err)
+	}
+
+	var organization models.Organization
+	err = scanOrganization(tx.QueryRowContext(ctx, `
+		SELECT `+organizationColumns+` FROM organizations WHERE id = $1
+	`, invitation.OrganizationID), &organization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2
+	`, invitation.OrganizationID, user.ID).Scan(&organization.Role); err != nil {
+		return nil, fmt.Errorf("failed to get member role: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invitation: %w", err)
+	}
+
+	return &organization, nil
+}
+
+// CreateNotebook creates a notebook shared with the organization
+func (s *OrganizationService) CreateNotebook(ctx context.Context, userID, orgID string, request *models.NotebookRequest) (*models.Notebook, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidNotebook, err)
+	}
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	var notebook models.Notebook
+	err := scanNotebook(s.db.QueryRowContext(ctx, `
+		INSERT INTO notebooks (id, user_id, name, organization_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING `+notebookColumns,
+		uuid.New(), userID, request.Name, orgID), &notebook)
+	if err == sql.ErrNoRows {
+		return nil, errNotebookExists
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create notebook: %w", err)
+	}
+
+	return &notebook, nil
+}
+
+// ListNotebooks returns the notebooks of an organization with their note
+// counts, ordered by name
+func (s *OrganizationService) ListNotebooks(ctx context.Context, userID, orgID string) ([]models.Notebook, error) {
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleViewer); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+notebookColumns+`,
+			(SELECT COUNT(*) FROM notes WHERE notes.notebook_id = notebooks.id)
+		FROM notebooks
+		WHERE organization_id = $1
+		ORDER BY name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notebooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotebookCounts(rows)
+}
+
+// DeleteNotebook deletes a shared notebook. Its notes move to the default
+// notebooks of their authors.
+func (s *OrganizationService) DeleteNotebook(ctx context.Context, userID, orgID, notebookID string) error {
+	if err := s.requireRole(ctx, userID, orgID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(notebookID); err != nil {
+		return ErrNotebookNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM notebooks WHERE id = $1 AND organization_id = $2)
+	`, notebookID, orgID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get notebook: %w", err)
+	}
+	if !exists {
+		return ErrNotebookNotFound
+	}
+
+	if err := releaseNotebooks(ctx, tx, "id = $1", notebookID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notebooks WHERE id = $1`, notebookID); err != nil {
+		return fmt.Errorf("failed to delete notebook: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notebook deletion: %w", err)
+	}
+
+	return nil
+}
+
+// requireRole checks that a user is a member of an organization with at
+// least the given role. Non-members get ErrOrganizationNotFound, so they
+// cannot tell which organizations exist.
+func (s *OrganizationService) requireRole(ctx context.Context, userID, orgID, required string) error {
+	if _, err := uuid.Parse(orgID); err != nil {
+		return ErrOrganizationNotFound
+	}
+
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2
+	`, orgID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return ErrOrganizationNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get member role: %w", err)
+	}
+
+	switch required {
+	case models.OrgRoleAdmin:
+		if role != models.OrgRoleAdmin {
+			return errOrgForbidden
+		}
+	case models.OrgRoleEditor:
+		if !models.OrgRoleCanEdit(role) {
+			return errOrgForbidden
+		}
+	}
+	return nil
+}
+
+// checkOtherAdmin fails when memberID is the only admin of the organization
+func (s *OrganizationService) checkOtherAdmin(ctx context.Context, orgID, memberID string) error {
+	var others int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM organization_members
+		WHERE organization_id = $1 AND role = $2 AND user_id <> $3
+	`, orgID, models.OrgRoleAdmin, memberID).Scan(&others); err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if others == 0 {
+		var role string
+		err := s.db.QueryRowContext(ctx, `
+			SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2
+		`, orgID, memberID).Scan(&role)
+		if err == nil && role == models.OrgRoleAdmin {
+			return errLastOrgAdmin
+		}
+	}
+	return nil
+}
+
+// releaseNotebooks moves the notes of the notebooks matching condition to
+// the default notebooks of their authors, giving them new versions
+func releaseNotebooks(ctx context.Context, tx *sql.Tx, condition string, args ...any) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT user_id, notebook_id FROM notes
+		WHERE notebook_id IN (SELECT id FROM notebooks WHERE `+condition+`)
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get notebook authors: %w", err)
+	}
+	type authorNotebook struct{ userID, notebookID string }
+	var moves []authorNotebook
+	for rows.Next() {
+		var move authorNotebook
+		if err := rows.Scan(&move.userID, &move.notebookID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan notebook author: %w", err)
+		}
+		moves = append(moves, move)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating notebook authors: %w", err)
+	}
+
+	for _, move := range moves {
+		defaultID, err := defaultNotebook(ctx, tx, move.userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE notes SET notebook_id = $1, version = version + 1, updated_at = NOW()
+			WHERE notebook_id = $2 AND user_id = $3
+		`, defaultID, move.notebookID, move.userID); err != nil {
+			return fmt.Errorf("failed to move notes to the default notebook: %w", err)
+		}
+	}
+	return nil
+}
+
+// notebookRole returns the role of a user in the organization sharing a
+// notebook, "" when the notebook is not shared with them
+func notebookRole(ctx context.Context, q QueryExecer, userID string, notebookID uuid.UUID) (string, error) {
+	var role string
+	err := q.QueryRowContext(ctx, `
+		SELECT m.role FROM notebooks nb
+		JOIN organization_members m ON m.organization_id = nb.organization_id
+		WHERE nb.id = $1 AND m.user_id = $2
+	`, notebookID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get notebook role: %w", err)
+	}
+	return role, nil
+}
+
+// checkNoteWrite checks that a user may change a note: its author, or an
+// editor or admin of the organization sharing its notebook
+func checkNoteWrite(ctx context.Context, q QueryExecer, userID string, note *models.Note) error {
+	if note.UserID.String() == userID {
+		return nil
+	}
+	role, err := notebookRole(ctx, q, userID, note.NotebookID)
+	if err != nil {
+		return err
+	}
+	if !models.OrgRoleCanEdit(role) {
+		return errOrgForbidden
+	}
+	return nil
+}
+
+// writableNote returns a note the user may change
+func writableNote(ctx context.Context, q QueryExecer, noteService NoteServiceInterface, userID, noteID string) (*models.Note, error) {
+	note, err := noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNoteWrite(ctx, q, userID, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// leaveOrganizations removes a user being deleted from their organizations.
+// Organizations left without members are deleted, those left without an admin
+// get their longest-standing member as admin, and the shared notebooks the
+// user created pass to another admin.
+func leaveOrganizations(ctx context.Context, tx *sql.Tx, userID string) error {
+	const soleMember = `organization_id IN (
+		SELECT organization_id FROM organization_members WHERE user_id = $1
+	) AND organization_id NOT IN (
+		SELECT organization_id FROM organization_members WHERE user_id <> $1
+	)`
+	if err := releaseNotebooks(ctx, tx, soleMember, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM organizations WHERE id IN (
+			SELECT organization_id FROM organization_members WHERE `+soleMember+`
+		)
+	`, userID); err != nil {
+		return fmt.Errorf("failed to delete organizations: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE organization_members SET role = $2
+		WHERE user_id = (
+			SELECT m.user_id FROM organization_members m
+			WHERE m.organization_id = organization_members.organization_id AND m.user_id <> $1
+			ORDER BY m.created_at, m.user_id
+			LIMIT 1
+		) AND organization_id IN (
+			SELECT organization_id FROM organization_members WHERE user_id = $1 AND role = $2
+		) AND organization_id NOT IN (
+			SELECT organization_id FROM organization_members WHERE user_id <> $1 AND role = $2
+		)
+	`, userID, models.OrgRoleAdmin); err != nil {
+		return fmt.Errorf("failed to promote organization admins: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE notebooks SET user_id = (
+			SELECT m.user_id FROM organization_members m
+			WHERE m.organization_id = notebooks.organization_id AND m.user_id <> $1 AND m.role = $2
+			ORDER BY m.created_at, m.user_id
+			LIMIT 1
+		)
+		WHERE user_id = $1 AND organization_id IS NOT NULL
+	`, userID, models.OrgRoleAdmin); err != nil {
+		return fmt.Errorf("failed to hand over shared notebooks: %w", err)
+	}
+	return nil
+}
+
+// noteAccess returns the condition selecting the notes a user can read: their
+// own and those in notebooks shared with them by their organizations. prefix
+// qualifies the note columns, and the user is placeholder arg.
+func noteAccess(prefix string, arg int) string {
+	return fmt.Sprintf(`(%[1]suser_id = $%[2]d OR %[1]snotebook_id IN (
+		SELECT nb.id FROM notebooks nb
+		JOIN organization_members m ON m.organization_id = nb.organization_id
+		WHERE m.user_id = $%[2]d
+	))`, prefix, arg)
+}
+
+// generateInvitationToken returns a new random invitation token
+func generateInvitationToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashInvitationToken returns the hex SHA-256 of a token
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// WatermarkedNotebooks returns the notebooks holding notes of the user whose
+// organization watermarks exports. Notes the user wrote there before leaving
+// the organization are included.
+func (s *OrganizationService) WatermarkedNotebooks(ctx context.Context, userID string) (map[uuid.UUID]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT nb.id
+		FROM notebooks nb
+		JOIN organizations o ON o.id = nb.organization_id
+		WHERE o.watermark_exports AND nb.id IN (SELECT notebook_id FROM notes WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watermarked notebooks: %w", err)
+	}
+	defer rows.Close()
+
+	notebooks := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan notebook: %w", err)
+		}
+		notebooks[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notebooks: %w", err)
+	}
+
+	return notebooks, nil
+}
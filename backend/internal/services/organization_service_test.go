@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/email"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+// recordingSender keeps the emails it is asked to send
+type recordingSender struct {
+	messages []email.Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, message email.Message) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func TestOrganizations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	sender := &recordingSender{}
+	service := NewOrganizationService(db, sender)
+	noteService := NewNoteService(db, NewTagService(db))
+	notebookService := NewNotebookService(db)
+	ctx := context.Background()
+
+	admin := testutil.NewTestUser(t, db)
+	editor := testutil.NewTestUser(t, db)
+	viewer := testutil.NewTestUser(t, db)
+	outsider := testutil.NewTestUser(t, db)
+	adminID, editorID, viewerID := admin.ID.String(), editor.ID.String(), viewer.ID.String()
+
+	org, err := service.CreateOrganization(ctx, adminID, &models.CreateOrganizationRequest{Name: " Acme "})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	if org.Name != "Acme" || org.Role != models.OrgRoleAdmin {
+		t.Errorf("Unexpected organization %+v", org)
+	}
+	orgID := org.ID.String()
+
+	join := func(user *models.User, role string) {
+		t.Helper()
+		invitation, err := service.InviteMember(ctx, adminID, orgID, &models.InviteMemberRequest{Email: strings.ToUpper(user.Email), Role: role})
+		if err != nil {
+			t.Fatalf("Failed to invite member: %v", err)
+		}
+		sent := sender.messages[len(sender.messages)-1]
+		if sent.To != user.Email || !strings.Contains(sent.Body, invitation.Token) {
+			t.Errorf("Expected the token emailed to %s, got %+v", user.Email, sent)
+		}
+		if _, err := service.AcceptInvitation(ctx, outsider, invitation.Token); !errors.Is(err, ErrInvitationNotFound) {
+			t.Errorf("Expected the invitation to be for its email only, got %v", err)
+		}
+		joined, err := service.AcceptInvitation(ctx, user, invitation.Token)
+		if err != nil || joined.Role != role {
+			t.Fatalf("Failed to accept invitation: %+v, %v", joined, err)
+		}
+		if _, err := service.AcceptInvitation(ctx, user, invitation.Token); !errors.Is(err, ErrInvitationNotFound) {
+			t.Errorf("Expected an accepted invitation to be used up, got %v", err)
+		}
+	}
+	join(editor, models.OrgRoleEditor)
+	join(viewer, models.OrgRoleViewer)
+
+	if _, err := service.InviteMember(ctx, editorID, orgID, &models.InviteMemberRequest{Email: outsider.Email, Role: models.OrgRoleViewer}); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("Expected editors not to invite, got %v", err)
+	}
+	if _, err := service.ListMembers(ctx, outsider.ID.String(), orgID); !errors.Is(err, ErrOrganizationNotFound) {
+		t.Errorf("Expected outsiders not to see the organization, got %v", err)
+	}
+	members, err := service.ListMembers(ctx, viewerID, orgID)
+	if err != nil || len(members) != 3 {
+		t.Fatalf("Expected 3 members, got %+v, %v", members, err)
+	}
+
+	shared, err := service.CreateNotebook(ctx, adminID, orgID, &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+	// Shared notebook names do not clash with personal ones
+	if _, err := notebookService.CreateNotebook(ctx, adminID, &models.NotebookRequest{Name: "Roadmap"}); err != nil {
+		t.Errorf("Failed to create personal notebook: %v", err)
+	}
+
+	// Editors write in shared notebooks, viewers only read them
+	note, err := noteService.CreateNote(ctx, editorID, &models.CreateNoteRequest{Content: "Q3 goals #planning", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create shared note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, viewerID, &models.CreateNoteRequest{Content: "Q4", NotebookID: &shared.ID}); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("Expected viewers not to write, got %v", err)
+	}
+
+	if _, err := noteService.GetNoteByID(ctx, viewerID, note.ID.String()); err != nil {
+		t.Errorf("Expected viewers to read shared notes: %v", err)
+	}
+	if _, err := noteService.GetNoteByID(ctx, outsider.ID.String(), note.ID.String()); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected outsiders not to read shared notes, got %v", err)
+	}
+	tagged, err := noteService.GetNotesByTag(ctx, viewerID, "#planning", false, 10, 0)
+	if err != nil || tagged.Total != 1 {
+		t.Errorf("Expected the shared note by its tag, got %+v, %v", tagged, err)
+	}
+
+	content := "Q3 goals, revised #planning"
+	if _, err := noteService.UpdateNote(ctx, viewerID, note.ID.String(), &models.UpdateNoteRequest{Content: &content}); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("Expected viewers not to update, got %v", err)
+	}
+	updated, err := noteService.UpdateNote(ctx, adminID, note.ID.String(), &models.UpdateNoteRequest{Content: &content})
+	if err != nil {
+		t.Fatalf("Failed to update shared note: %v", err)
+	}
+	if updated.UserID != editor.ID {
+		t.Errorf("Expected the author to stay the owner, got %s", updated.UserID)
+	}
+
+	// Demoting a member takes write access away
+	if _, err := service.UpdateMember(ctx, adminID, orgID, editorID, &models.UpdateMemberRequest{Role: models.OrgRoleViewer}); err != nil {
+		t.Fatalf("Failed to update member: %v", err)
+	}
+	if err := noteService.DeleteNote(ctx, adminID, note.ID.String()); err != nil {
+		t.Errorf("Expected admins to delete shared notes: %v", err)
+	}
+	if err := service.RemoveMember(ctx, adminID, orgID, adminID); !errors.Is(err, errLastOrgAdmin) {
+		t.Errorf("Expected the last admin to stay, got %v", err)
+	}
+
+	// Deleting the organization moves its notes to their authors' default
+	// notebooks and ends the sharing
+	kept, err := noteService.CreateNote(ctx, adminID, &models.CreateNoteRequest{Content: "Launch", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create shared note: %v", err)
+	}
+	if err := service.DeleteOrganization(ctx, editorID, orgID); !errors.Is(err, apperrors.ErrForbidden) {
+		t.Errorf("Expected only admins to delete the organization, got %v", err)
+	}
+	if err := service.DeleteOrganization(ctx, adminID, orgID); err != nil {
+		t.Fatalf("Failed to delete organization: %v", err)
+	}
+	moved, err := noteService.GetNoteByID(ctx, adminID, kept.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if moved.NotebookID == shared.ID {
+		t.Errorf("Expected the note out of the deleted notebook")
+	}
+	if _, err := noteService.GetNoteByID(ctx, viewerID, kept.ID.String()); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected the note no longer shared, got %v", err)
+	}
+
+	// Deleting the account of the last admin hands the organization and
+	// its notebooks over to the remaining members
+	org, err = service.CreateOrganization(ctx, adminID, &models.CreateOrganizationRequest{Name: "Acme 2"})
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	orgID = org.ID.String()
+	join(editor, models.OrgRoleEditor)
+	shared, err = service.CreateNotebook(ctx, adminID, orgID, &models.NotebookRequest{Name: "Roadmap"})
+	if err != nil {
+		t.Fatalf("Failed to create shared notebook: %v", err)
+	}
+	note, err = noteService.CreateNote(ctx, editorID, &models.CreateNoteRequest{Content: "Q1", NotebookID: &shared.ID})
+	if err != nil {
+		t.Fatalf("Failed to create shared note: %v", err)
+	}
+	if err := NewUserService(db).Delete(ctx, adminID); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	members, err = service.ListMembers(ctx, editorID, orgID)
+	if err != nil || len(members) != 1 || members[0].Role != models.OrgRoleAdmin {
+		t.Errorf("Expected the editor promoted to admin, got %+v, %v", members, err)
+	}
+	if _, err := noteService.GetNoteByID(ctx, editorID, note.ID.String()); err != nil {
+		t.Errorf("Expected the shared note kept: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/readability"
+)
+
+// codeInvalidPaste is the error code of pastes that cannot become a note
+const codeInvalidPaste = "INVALID_PASTE"
+
+// PasteServiceInterface defines the interface for normalizing pasted content
+type PasteServiceInterface interface {
+	NormalizePaste(ctx context.Context, request *models.NormalizePasteRequest) (*models.NormalizedPaste, error)
+}
+
+// PasteService converts rich text pasted from editors and web pages to
+// clean Markdown note content
+type PasteService struct{}
+
+// NewPasteService creates a new PasteService
+func NewPasteService() *PasteService {
+	return &PasteService{}
+}
+
+// NormalizePaste returns pasted HTML as Markdown, or the pasted plain text
+// when there is no HTML or it has no text, cleaned of invisible characters
+// and tracking parameters. The content is not stored.
+func (s *PasteService) NormalizePaste(ctx context.Context, request *models.NormalizePasteRequest) (*models.NormalizedPaste, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidPaste, err)
+	}
+
+	var content string
+	if strings.TrimSpace(request.HTML) != "" {
+		var base *url.URL
+		if request.BaseURL != "" {
+			base, _ = url.Parse(request.BaseURL)
+		}
+		markdown, err := readability.Convert(strings.NewReader(request.HTML), base)
+		if err != nil {
+			return nil, apperrors.Validation(codeInvalidPaste, "pasted html could not be read")
+		}
+		content = models.NormalizePastedText(markdown)
+	}
+	if content == "" {
+		content = models.NormalizePastedText(request.Text)
+	}
+
+	if content == "" {
+		return nil, apperrors.Validation(codeInvalidPaste, "pasted content has no text")
+	}
+	if utf8.RuneCountInString(content) > models.MaxContentLength() {
+		return nil, apperrors.New(apperrors.ErrTooLarge, "PASTE_TOO_LONG",
+			fmt.Sprintf("pasted content too long for a note (max %d characters)", models.MaxContentLength()))
+	}
+	return &models.NormalizedPaste{Content: content}, nil
+}
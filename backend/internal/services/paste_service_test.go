@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+func TestNormalizePaste(t *testing.T) {
+	service := NewPasteService()
+	ctx := context.Background()
+
+	paste, err := service.NormalizePaste(ctx, &models.NormalizePasteRequest{
+		HTML:    `<p>Meeting notes</p><ul><li>Ship <b>v2</b></li><li><a href="/plan?utm_campaign=x">Plan</a></li></ul>`,
+		Text:    "Meeting notes\n* Ship v2\n* Plan",
+		BaseURL: "https://wiki.example.com/team/",
+	})
+	if err != nil {
+		t.Fatalf("NormalizePaste failed: %v", err)
+	}
+	if want := "Meeting notes\n\n- Ship **v2**\n- [Plan](https://wiki.example.com/plan)"; paste.Content != want {
+		t.Errorf("Unexpected content:\n%s", paste.Content)
+	}
+
+	// The plain text is used when the HTML has no text
+	paste, err = service.NormalizePaste(ctx, &models.NormalizePasteRequest{HTML: "<img src=x>", Text: "• one\r\n• two"})
+	if err != nil {
+		t.Fatalf("NormalizePaste failed: %v", err)
+	}
+	if paste.Content != "- one\n- two" {
+		t.Errorf("Expected the plain text, got %q", paste.Content)
+	}
+
+	failures := []struct {
+		request models.NormalizePasteRequest
+		kind    error
+	}{
+		{models.NormalizePasteRequest{}, apperrors.ErrValidation},
+		{models.NormalizePasteRequest{HTML: "<script>x()</script>"}, apperrors.ErrValidation},
+		{models.NormalizePasteRequest{Text: strings.Repeat("word ", 3000)}, apperrors.ErrTooLarge},
+	}
+	for _, failure := range failures {
+		if _, err := service.NormalizePaste(ctx, &failure.request); !errors.Is(err, failure.kind) {
+			t.Errorf("Expected %v for %+v, got %v", failure.kind, failure.request, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// maxPrettifyAttempts is how often prettify asks the LLM for output that
+// keeps the note's content before leaving the note unchanged
+const maxPrettifyAttempts = 2
+
+var (
+	guardURLPattern       = regexp.MustCompile(`https?://\S+`)
+	guardCodeBlockPattern = regexp.MustCompile("(?s)```[^\n]*\n(.*?)```")
+)
+
+// lostContent returns the URLs, hashtags and fenced code blocks of input
+// missing from output. Code blocks may be reindented, so whitespace is
+// ignored when comparing them.
+func lostContent(input, output string) []string {
+	var lost []string
+	seen := make(map[string]bool)
+	addLost := func(item string) {
+		if !seen[item] {
+			seen[item] = true
+			lost = append(lost, item)
+		}
+	}
+
+	for _, url := range guardURLPattern.FindAllString(input, -1) {
+		// Punctuation after a URL usually ends the sentence
+		url = strings.TrimRight(url, `.,;:!?)]}>"'`)
+		if !strings.Contains(output, url) {
+			addLost(url)
+		}
+	}
+
+	outputTags := make(map[string]bool)
+	for _, tag := range models.ExtractTagsFromContent(output) {
+		outputTags[tag] = true
+	}
+	for _, tag := range models.ExtractTagsFromContent(input) {
+		if !outputTags[tag] {
+			addLost(tag)
+		}
+	}
+
+	compactOutput := removeWhitespace(output)
+	for _, match := range guardCodeBlockPattern.FindAllStringSubmatch(input, -1) {
+		code := removeWhitespace(match[1])
+		if code != "" && !strings.Contains(compactOutput, code) {
+			addLost(strings.TrimSpace(match[0]))
+		}
+	}
+	return lost
+}
+
+// removeWhitespace removes all whitespace from s
+func removeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// lostIn returns the lost items that occur in chunk, so retries can be
+// limited to the chunks that lost content
+func lostIn(chunk string, lost []string) []string {
+	var found []string
+	lowerChunk := strings.ToLower(chunk)
+	for _, item := range lost {
+		// Hashtags are compared in lower case
+		if strings.Contains(lowerChunk, strings.ToLower(item)) {
+			found = append(found, item)
+		}
+	}
+	return found
+}
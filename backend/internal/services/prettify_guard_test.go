@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLostContent(t *testing.T) {
+	input := "TODO 12/11/2025\n" +
+		"See https://wiki.example.com/display/AD/Tool+Guide.\n" +
+		"```go\ntype Note struct {\n\tID string\n}\n```\n" +
+		"#todo #work/project"
+
+	tests := []struct {
+		name   string
+		output string
+		lost   []string
+	}{
+		{
+			name:   "everything kept",
+			output: "- See [guide](https://wiki.example.com/display/AD/Tool+Guide)\n```go\ntype Note struct {\n    ID string\n}\n```\n#TODO #work/project",
+		},
+		{
+			name:   "URL changed",
+			output: "- See https://wiki.example.com/display/AD/Tool Guide\n```\ntype Note struct { ID string }\n```\n#todo #work/project",
+			lost:   []string{"https://wiki.example.com/display/AD/Tool+Guide"},
+		},
+		{
+			name:   "hashtag and code lost",
+			output: "- See https://wiki.example.com/display/AD/Tool+Guide\n- Note has an ID\n#todo",
+			lost:   []string{"#work/project", "```go\ntype Note struct {\n\tID string\n}\n```"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.lost, lostContent(input, tt.output))
+		})
+	}
+}
+
+func TestAppendMissingTags(t *testing.T) {
+	assert.Equal(t, "- Pack the tent #Travel\n\n#todo",
+		appendMissingTags("- Pack the tent #Travel", []string{"#travel", "#todo"}))
+	assert.Equal(t, "- Pack the tent #todo", appendMissingTags("- Pack the tent #todo", []string{"#todo"}))
+}
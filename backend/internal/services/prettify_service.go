@@ -10,7 +10,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
 	"github.com/gpd/my-notes/internal/models"
 )
 
@@ -20,6 +22,10 @@ type PrettifyService struct {
 	noteService NoteServiceInterface
 	tagService  TagServiceInterface
 	db          *sql.DB
+	cache       LLMCache
+	prompts     *prompts.Registry
+	tokenizer   llm.TokenCounter
+	chunkTokens int
 }
 
 // NewPrettifyService creates a new prettify service
@@ -37,6 +43,25 @@ func NewPrettifyService(
 	}
 }
 
+// SetCache enables caching of LLM responses, so prettifying unchanged
+// content skips the LLM
+func (s *PrettifyService) SetCache(cache LLMCache) {
+	s.cache = cache
+}
+
+// SetPrompts sets the registry the prettify prompt is rendered from. The
+// built-in prompt is used otherwise.
+func (s *PrettifyService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// SetTokenizer enables prettifying notes longer than chunkTokens tokens in
+// chunks, so long notes fit the LLM context with the prompt
+func (s *PrettifyService) SetTokenizer(tokenizer llm.TokenCounter, chunkTokens int) {
+	s.tokenizer = tokenizer
+	s.chunkTokens = chunkTokens
+}
+
 // prettifyLLMResponse represents the expected LLM JSON response
 type prettifyLLMResponse struct {
 	DetectedLanguage  string   `json:"detected_language"`
@@ -52,20 +77,26 @@ func (s *PrettifyService) PrettifyNote(ctx context.Context, userID, noteID strin
 	log.Printf("[PrettifyService] Starting PrettifyNote for note: %s, user: %s", noteID, userID)
 
 	// 1. Get the note
-	note, err := s.noteService.GetNoteByID(userID, noteID)
+	note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
 	if err != nil {
 		log.Printf("[PrettifyService] ERROR: Failed to get note: %v", err)
 		return nil, fmt.Errorf("failed to get note: %w", err)
 	}
 	log.Printf("[PrettifyService] Retrieved note: title='%v', content_length=%d", note.Title, len(note.Content))
 
+	// Private notes are encrypted at rest and must not be sent to the LLM
+	if note.IsPrivate {
+		log.Printf("[PrettifyService] ERROR: Note %s is private", noteID)
+		return nil, apperrors.Validation("PRIVATE_NOTE", "private notes cannot be prettified")
+	}
+
 	// 2. Validate minimum word count (excluding hashtags)
 	contentWithoutTags := s.removeHashtags(note.Content)
 	wordCount := s.countWords(contentWithoutTags)
 	log.Printf("[PrettifyService] Word count (excluding hashtags): %d", wordCount)
 	if wordCount < 5 {
 		log.Printf("[PrettifyService] ERROR: Note too short (%d words, minimum 5)", wordCount)
-		return nil, fmt.Errorf("note content too short (minimum 5 words excluding hashtags, got %d)", wordCount)
+		return nil, apperrors.Validation("NOTE_TOO_SHORT", fmt.Sprintf("note content too short (minimum 5 words excluding hashtags, got %d)", wordCount))
 	}
 
 	// 3. Check if already prettified and not manually edited
@@ -76,7 +107,7 @@ func (s *PrettifyService) PrettifyNote(ctx context.Context, userID, noteID strin
 	}
 
 	// 4. Get user's existing tags for context
-	tagList, err := s.tagService.GetAllTags(userID, 100, 0)
+	tagList, err := s.tagService.GetAllTags(ctx, userID, 100, 0)
 	if err != nil {
 		// Log but don't fail - tag context is optional
 		log.Printf("[PrettifyService] WARNING: Failed to get user tags: %v", err)
@@ -84,62 +115,39 @@ func (s *PrettifyService) PrettifyNote(ctx context.Context, userID, noteID strin
 	}
 	log.Printf("[PrettifyService] User has %d existing tags for context", len(tagList.Tags))
 
-	// 5. Build the LLM prompt with user tags
-	prompt := s.buildPrettifyPrompt(note, tagList.Tags)
-	log.Printf("[PrettifyService] Built LLM prompt (length: %d chars)", len(prompt))
-
-	// 6. Call LLM
-	log.Printf("[PrettifyService] Calling LLM...")
-	llmStart := time.Now()
-	response, err := s.llm.GenerateFromSinglePrompt(ctx, prompt)
-	llmDuration := time.Since(llmStart)
-	log.Printf("[PrettifyService] LLM call duration: %v", llmDuration)
-
-	if err != nil {
-		log.Printf("[PrettifyService] ERROR: LLM prettification failed")
-		log.Printf("[PrettifyService]   Error: %v", err)
-		log.Printf("[PrettifyService]   Error type: %T", err)
-		log.Printf("[PrettifyService]   Context error: %v", ctx.Err())
-		return nil, fmt.Errorf("LLM prettification failed: %w", err)
+	tagNames := make([]string, len(tagList.Tags))
+	for i, tag := range tagList.Tags {
+		tagNames[i] = tag.Name
 	}
-	log.Printf("[PrettifyService] LLM call successful, response length: %d chars", len(response))
 
-	// 7. Parse LLM response
-	var llmResult prettifyLLMResponse
-	if err := s.parseLLMResponse(response, &llmResult); err != nil {
-		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
-	}
-
-	// 8. Handle tags - merge existing with suggested
+	// 5-8. Prettify with the LLM, unless the content was prettified before,
+	// and check the prettified content keeps the note's URLs, hashtags and code
+	sourceHash := noteContentHash(note.Title, note.Content)
 	existingTags := note.ExtractHashtags()
-	allTags := s.mergeTags(existingTags, llmResult.SuggestedTags)
-
-	// NEW: Append tags to prettified content if not already present
-	prettifiedContent := llmResult.PrettifiedContent
-	if len(allTags) > 0 {
-		// Check if tags are already in the content
-		contentTags := models.ExtractTagsFromContent(prettifiedContent)
-		missingTags := []string{}
-		for _, tag := range allTags {
-			found := false
-			for _, contentTag := range contentTags {
-				if strings.EqualFold(tag, contentTag) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				missingTags = append(missingTags, tag)
-			}
-		}
-
-		// Append missing tags to content
-		if len(missingTags) > 0 {
-			// Add double newline before tags if content doesn't end with one
-			separator := "\n\n"
-			prettifiedContent += separator + strings.Join(missingTags, " ")
+	result := s.cachedResult(ctx, userID, sourceHash, note.Content, existingTags)
+	if result == nil {
+		result, err = s.prettifyChunks(ctx, userID, note, existingTags, tagNames)
+		if err != nil {
+			return nil, err
 		}
 	}
+	llmResult := result.llm
+	allTags := result.tags
+	prettifiedContent := result.content
+
+	// Keep the note as it is rather than persisting a lossy result
+	if len(result.lost) > 0 {
+		log.Printf("[PrettifyService] Keeping note %s unchanged, prettified content lost: %v", noteID, result.lost)
+		noteResponse := note.ToResponse()
+		noteResponse.Tags = existingTags
+		return &models.PrettifyNoteResponse{
+			NoteResponse:  noteResponse,
+			SuggestedTags: llmResult.SuggestedTags,
+			ChangesMade:   []string{},
+			Unchanged:     true,
+			LostContent:   result.lost,
+		}, nil
+	}
 
 	// 9. Update the note with prettified content (now including tags)
 	now := time.Now()
@@ -149,18 +157,33 @@ func (s *PrettifyService) PrettifyNote(ctx context.Context, userID, noteID strin
 		Version: &note.Version,
 	}
 
-	updatedNote, err := s.noteService.UpdateNote(userID, noteID, updateRequest)
+	updatedNote, err := s.noteService.UpdateNote(ctx, userID, noteID, updateRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update note: %w", err)
 	}
 
+	// Cache the result for both the original and the prettified content,
+	// so prettifying either again is answered without the LLM
+	response, err := json.Marshal(llmResult)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode prettify result: %w", err)
+	}
+	s.cacheResponse(ctx, &LLMCacheEntry{
+		UserID:     userID,
+		Feature:    LLMFeaturePrettify,
+		NoteID:     &note.ID,
+		SourceHash: sourceHash,
+		ResultHash: noteContentHash(updatedNote.Title, updatedNote.Content),
+		Response:   string(response),
+	})
+
 	// 10. Set prettify flags directly in database (after UpdateNote which clears them)
 	if err := s.setPrettifyFlags(ctx, noteID, now); err != nil {
 		return nil, fmt.Errorf("failed to set prettify flags: %w", err)
 	}
 
 	// 11. Update tags with suggested ones
-	if err := s.tagService.UpdateTagsForNote(noteID, allTags); err != nil {
+	if err := s.tagService.UpdateTagsForNote(ctx, userID, noteID, allTags); err != nil {
 		// Log error but don't fail - the note content is already updated
 		log.Printf("[PrettifyService] WARNING: Failed to update tags: %v", err)
 	}
@@ -186,75 +209,195 @@ func (s *PrettifyService) PrettifyNote(ctx context.Context, userID, noteID strin
 	}, nil
 }
 
-// buildPrettifyPrompt creates the LLM prompt for prettification
-func (s *PrettifyService) buildPrettifyPrompt(note *models.Note, userTags []models.TagResponse) string {
+// prettifyResult is a prettification checked against the note
+type prettifyResult struct {
+	llm     prettifyLLMResponse
+	tags    []string // existing and suggested tags
+	content string   // prettified content including the tags
+	lost    []string // URLs, hashtags and code blocks of the note the content lost
+}
+
+// checkPrettified merges the tags into a prettification and checks it
+// against the note content
+func (s *PrettifyService) checkPrettified(content string, existingTags []string, llmResult prettifyLLMResponse) *prettifyResult {
+	result := &prettifyResult{llm: llmResult}
+	result.tags = s.mergeTags(existingTags, llmResult.SuggestedTags)
+	result.content = appendMissingTags(llmResult.PrettifiedContent, result.tags)
+	result.lost = lostContent(content, result.content)
+	return result
+}
+
+// cachedResult returns the cached prettification of content with sourceHash,
+// or nil when there is none or it fails the check
+func (s *PrettifyService) cachedResult(ctx context.Context, userID, sourceHash, content string, existingTags []string) *prettifyResult {
+	response, ok := s.cachedResponse(ctx, userID, sourceHash)
+	if !ok {
+		return nil
+	}
+	var llmResult prettifyLLMResponse
+	if err := s.parseLLMResponse(response, &llmResult); err != nil {
+		log.Printf("[PrettifyService] WARNING: Ignoring unreadable cached response: %v", err)
+		return nil
+	}
+	result := s.checkPrettified(content, existingTags, llmResult)
+	if len(result.lost) > 0 {
+		return nil
+	}
+	log.Printf("[PrettifyService] Using cached LLM response for unchanged content")
+	return result
+}
+
+// prettifyChunks prettifies the note with the LLM. Notes over the chunk
+// budget are split between paragraphs and the chunks prettified one by one.
+// Chunks whose content the result lost are retried with a corrective prompt.
+func (s *PrettifyService) prettifyChunks(ctx context.Context, userID string, note *models.Note, existingTags, userTags []string) (*prettifyResult, error) {
 	title := ""
 	if note.Title != nil {
 		title = *note.Title
 	}
+	chunks := []string{note.Content}
+	if s.tokenizer != nil {
+		chunks = llm.Chunk(note.Content, s.chunkTokens, s.tokenizer)
+	}
+	if len(chunks) > 1 {
+		log.Printf("[PrettifyService] Prettifying note in %d chunks", len(chunks))
+	}
+
+	responses := make([]prettifyLLMResponse, len(chunks))
+	var result *prettifyResult
+	for attempt := 1; attempt <= maxPrettifyAttempts; attempt++ {
+		for i, chunk := range chunks {
+			var lost []string
+			if result != nil {
+				if lost = lostIn(chunk, result.lost); len(lost) == 0 {
+					continue
+				}
+			}
+
+			prompt, err := s.buildPrettifyPrompt(title, chunk, userTags, lost)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("[PrettifyService] Built LLM prompt (length: %d chars)", len(prompt))
+
+			response, err := s.generate(ctx, userID, prompt)
+			if err != nil {
+				return nil, err
+			}
+			responses[i] = prettifyLLMResponse{}
+			if err := s.parseLLMResponse(response, &responses[i]); err != nil {
+				return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+			}
+		}
+
+		result = s.checkPrettified(note.Content, existingTags, combinePrettified(responses))
+		if len(result.lost) == 0 {
+			break
+		}
+		log.Printf("[PrettifyService] WARNING: Attempt %d lost content of the note: %v", attempt, result.lost)
+	}
+	return result, nil
+}
 
-	// Build user tag list for prompt
-	userTagList := ""
-	if len(userTags) > 0 {
-		tagNames := make([]string, len(userTags))
-		for i, tag := range userTags {
-			tagNames[i] = tag.Name
+// combinePrettified reassembles the prettified chunks of a note in order.
+// The title and language come from the first chunk.
+func combinePrettified(responses []prettifyLLMResponse) prettifyLLMResponse {
+	combined := prettifyLLMResponse{
+		DetectedLanguage: responses[0].DetectedLanguage,
+		PrettifiedTitle:  responses[0].PrettifiedTitle,
+		SuggestedTags:    []string{},
+		ChangesMade:      []string{},
+	}
+	contents := make([]string, len(responses))
+	seenTags := make(map[string]bool)
+	seenChanges := make(map[string]bool)
+	for i, response := range responses {
+		contents[i] = response.PrettifiedContent
+		for _, tag := range response.SuggestedTags {
+			if key := strings.ToLower(tag); !seenTags[key] {
+				seenTags[key] = true
+				combined.SuggestedTags = append(combined.SuggestedTags, tag)
+			}
 		}
-		userTagList = strings.Join(tagNames, ", ")
-	}
-
-	prompt := fmt.Sprintf(`You are a note editing assistant. Prettify the following note according to these rules:
-
-CURRENT NOTE:
-Title: %s
-Content: %s
-
-YOUR EXISTING TAGS (prefer these when relevant):
-%s
-
-PRETTIFY RULES:
-1. Detect the language of the content first
-2. Fix all typos using language-specific corrections
-3. Remove excess spacing, tabs, dots, commas
-4. Detect content type and handle appropriately:
-   a) If content contains JSON (with curly braces { } and "key": "value" format):
-      - Keep it as valid JSON
-      - Fix any broken JSON syntax (missing braces, quotes, commas)
-      - Prettify with proper indentation (2 spaces per level)
-      - Do NOT convert to bullet lists
-   b) If content contains Go struct definitions (type X struct):
-      - Keep it as valid Go code
-      - Fix any broken struct syntax
-      - Prettify with proper indentation (tabs or spaces)
-      - Do NOT convert to bullet lists
-   c) For regular text content:
-      - Remove markdown headers and convert to bullets (use "-" for bullets)
-      - Convert markdown tables to simple bullet lists
-      - Simplify formatting - use bullet points only
-5. Remove all emoticons
-6. Preserve URLs exactly as they appear
-7. If current title is empty, generate a title based on content (max 50 chars)
-8. Suggest 2-3 relevant tags based on content (start with #, e.g., #tag1)
-9. When suggesting tags, prefer using tags from "YOUR EXISTING TAGS" list if they are relevant to the content
-
-IMPORTANT:
-- Return valid JSON only
-- Keep the content meaning but make it cleaner and more readable
-- For JSON and Go structs: preserve the format, just fix and indent properly
-- For regular text: convert to bullet lists
-- Preserve hashtags in content
-- Remove markdown table syntax (|, ---, +) entirely from non-code content
-
-Response format (JSON):
-{
-  "detected_language": "en",
-  "prettified_title": "Clean or Generated Title",
-  "prettified_content": "Cleaned content with bullets only",
-  "suggested_tags": ["#tag1", "#tag2", "#tag3"],
-  "changes_made": ["fixed typos", "removed markdown tables", "suggested tags"]
-}`, title, note.Content, userTagList)
-
-	return prompt
+		for _, change := range response.ChangesMade {
+			if !seenChanges[change] {
+				seenChanges[change] = true
+				combined.ChangesMade = append(combined.ChangesMade, change)
+			}
+		}
+	}
+	if len(responses) == 1 {
+		combined.PrettifiedContent = responses[0].PrettifiedContent
+	} else {
+		combined.PrettifiedContent = llm.JoinChunks(contents)
+	}
+	return combined
+}
+
+// generate returns the LLM response to prompt
+func (s *PrettifyService) generate(ctx context.Context, userID, prompt string) (string, error) {
+	log.Printf("[PrettifyService] Calling LLM...")
+	llmStart := time.Now()
+	response, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeaturePrettify), prompt)
+	llmDuration := time.Since(llmStart)
+	log.Printf("[PrettifyService] LLM call duration: %v", llmDuration)
+
+	if err != nil {
+		log.Printf("[PrettifyService] ERROR: LLM prettification failed")
+		log.Printf("[PrettifyService]   Error: %v", err)
+		log.Printf("[PrettifyService]   Error type: %T", err)
+		log.Printf("[PrettifyService]   Context error: %v", ctx.Err())
+		return "", fmt.Errorf("LLM prettification failed: %w", err)
+	}
+	log.Printf("[PrettifyService] LLM call successful, response length: %d chars", len(response))
+	return response, nil
+}
+
+// cachedResponse returns the cached LLM response for content with the given
+// hash. Cache failures are logged and treated as misses.
+func (s *PrettifyService) cachedResponse(ctx context.Context, userID, contentHash string) (string, bool) {
+	if s.cache == nil {
+		return "", false
+	}
+	response, ok, err := s.cache.Get(ctx, userID, LLMFeaturePrettify, contentHash)
+	if err != nil {
+		log.Printf("[PrettifyService] WARNING: Failed to get cached LLM response: %v", err)
+		return "", false
+	}
+	return response, ok
+}
+
+// cacheResponse caches an LLM response, logging failures
+func (s *PrettifyService) cacheResponse(ctx context.Context, entry *LLMCacheEntry) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Put(ctx, entry); err != nil {
+		log.Printf("[PrettifyService] WARNING: Failed to cache LLM response: %v", err)
+	}
+}
+
+// buildPrettifyPrompt creates the LLM prompt for prettification. When lost
+// lists content a previous response dropped, the prompt asks to keep it.
+func (s *PrettifyService) buildPrettifyPrompt(title, content string, userTags, lost []string) (string, error) {
+	registry := promptsOrDefault(s.prompts)
+	prompt, err := registry.Render(prompts.Prettify, prompts.PrettifyData{
+		Title:    title,
+		Content:  content,
+		UserTags: userTags,
+	})
+	if err != nil || len(lost) == 0 {
+		return prompt, err
+	}
+	return registry.Render(prompts.PrettifyCorrection, prompts.PrettifyCorrectionData{Prompt: prompt, Lost: lost})
+}
+
+// promptsOrDefault returns registry, or the built-in prompts when it is nil
+func promptsOrDefault(registry *prompts.Registry) *prompts.Registry {
+	if registry == nil {
+		return prompts.Default()
+	}
+	return registry
 }
 
 // parseLLMResponse extracts and parses JSON from LLM response
@@ -324,7 +467,7 @@ func (s *PrettifyService) parseLLMResponse(response string, result *prettifyLLMR
 
 // removeHashtags removes hashtags from content
 func (s *PrettifyService) removeHashtags(content string) string {
-	hashtagRegex := regexp.MustCompile(`#\w+`)
+	hashtagRegex := regexp.MustCompile(`#\w+(?:/\w+)*`)
 	return hashtagRegex.ReplaceAllString(content, "")
 }
 
@@ -334,25 +477,51 @@ func (s *PrettifyService) countWords(content string) int {
 	return len(words)
 }
 
-// mergeTags merges existing tags with suggested tags, removing duplicates
+// mergeTags merges existing tags with suggested tags, removing duplicates.
+// Existing tags come first, in order.
 func (s *PrettifyService) mergeTags(existing, suggested []string) []string {
-	tagMap := make(map[string]bool)
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(existing)+len(suggested))
 	for _, tag := range existing {
-		tagMap[tag] = true
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
 	}
 	for _, tag := range suggested {
 		// Ensure tag starts with #
 		if !strings.HasPrefix(tag, "#") {
 			tag = "#" + tag
 		}
-		tagMap[tag] = true
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
 	}
+	return result
+}
 
-	result := make([]string, 0, len(tagMap))
-	for tag := range tagMap {
-		result = append(result, tag)
+// appendMissingTags appends the tags missing from content to it
+func appendMissingTags(content string, tags []string) string {
+	contentTags := models.ExtractTagsFromContent(content)
+	missingTags := []string{}
+	for _, tag := range tags {
+		found := false
+		for _, contentTag := range contentTags {
+			if strings.EqualFold(tag, contentTag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missingTags = append(missingTags, tag)
+		}
 	}
-	return result
+
+	if len(missingTags) == 0 {
+		return content
+	}
+	return content + "\n\n" + strings.Join(missingTags, " ")
 }
 
 // setPrettifyFlags sets the prettification flags on a note
@@ -3,15 +3,14 @@ package services
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/gpd/my-notes/internal/config"
-	"github.com/gpd/my-notes/internal/database"
 	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,25 +31,11 @@ func TestPrettifyOnContentWithHashtag(t *testing.T) {
 	// Use test database config for creating test database
 	testDBConfig := config.GetTestDatabaseConfig()
 
-	// Create test database
-	db, err := database.CreateTestDatabase(testDBConfig)
-	require.NoError(t, err, "Failed to create test database")
-	defer database.DropTestDatabase(db)
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(t, err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the test
+	db := testutil.NewTestDB(t, testDBConfig, "../../migrations")
 
 	// Create test user
-	userID := uuid.New().String()
-	googleID := fmt.Sprintf("google_%s", userID)
-	userQuery := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = db.ExecContext(context.Background(), userQuery, userID, googleID, "prettify@example.com")
-	require.NoError(t, err, "Failed to create test user")
+	userID := testutil.NewTestUser(t, db).ID.String()
 
 	// Create note with content containing #todos hashtag
 	inputContent := `- update run_migration.sh and its dependencies to migrate all these:
@@ -61,13 +46,7 @@ sessions
 #todos
 `
 
-	noteID := uuid.New()
-	noteQuery := `
-		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, NOW(), NOW(), 1)
-	`
-	_, err = db.ExecContext(context.Background(), noteQuery, noteID, userID, "Test Note", inputContent)
-	require.NoError(t, err, "Failed to create test note")
+	noteID := testutil.NewTestNote(t, db, uuid.MustParse(userID), "Test Note", inputContent).ID
 
 	// Setup services
 	llmClient, err := llm.NewResilientLLM(context.Background(), cfg, nil)
@@ -108,25 +87,11 @@ func TestPrettifyOnContentWithURL(t *testing.T) {
 	// Use test database config for creating test database
 	testDBConfig := config.GetTestDatabaseConfig()
 
-	// Create test database
-	db, err := database.CreateTestDatabase(testDBConfig)
-	require.NoError(t, err, "Failed to create test database")
-	defer database.DropTestDatabase(db)
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(t, err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the test
+	db := testutil.NewTestDB(t, testDBConfig, "../../migrations")
 
 	// Create test user
-	userID := uuid.New().String()
-	googleID := fmt.Sprintf("google_%s", userID)
-	userQuery := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = db.ExecContext(context.Background(), userQuery, userID, googleID, "prettify@example.com")
-	require.NoError(t, err, "Failed to create test user")
+	userID := testutil.NewTestUser(t, db).ID.String()
 
 	// Create note with content containing URL
 	inputContent := `TODO 12/11/2025
@@ -139,13 +104,7 @@ remove "median fair value"
 #todo
 `
 
-	noteID := uuid.New()
-	noteQuery := `
-		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, NOW(), NOW(), 1)
-	`
-	_, err = db.ExecContext(context.Background(), noteQuery, noteID, userID, "Test Note", inputContent)
-	require.NoError(t, err, "Failed to create test note")
+	noteID := testutil.NewTestNote(t, db, uuid.MustParse(userID), "Test Note", inputContent).ID
 
 	// Setup services
 	llmClient, err := llm.NewResilientLLM(context.Background(), cfg, nil)
@@ -194,36 +153,16 @@ func TestPrettifyOnContentWithJSON(t *testing.T) {
 	// Use test database config for creating test database
 	testDBConfig := config.GetTestDatabaseConfig()
 
-	// Create test database
-	db, err := database.CreateTestDatabase(testDBConfig)
-	require.NoError(t, err, "Failed to create test database")
-	defer database.DropTestDatabase(db)
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(t, err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the test
+	db := testutil.NewTestDB(t, testDBConfig, "../../migrations")
 
 	// Create test user
-	userID := uuid.New().String()
-	googleID := fmt.Sprintf("google_%s", userID)
-	userQuery := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = db.ExecContext(context.Background(), userQuery, userID, googleID, "prettify@example.com")
-	require.NoError(t, err, "Failed to create test user")
+	userID := testutil.NewTestUser(t, db).ID.String()
 
 	// Create note with valid JSON content (single line)
 	inputContent := `429 {"type":"error","error":{"type":"1308","message":"Usage limit reached for 5 hour. Your limit will reset at 2025-11-10 15:47:02"},"request_id":"20251110131106ad01ca8eb00144df"}`
 
-	noteID := uuid.New()
-	noteQuery := `
-		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, NOW(), NOW(), 1)
-	`
-	_, err = db.ExecContext(context.Background(), noteQuery, noteID, userID, "Test Note", inputContent)
-	require.NoError(t, err, "Failed to create test note")
+	noteID := testutil.NewTestNote(t, db, uuid.MustParse(userID), "Test Note", inputContent).ID
 
 	// Setup services
 	llmClient, err := llm.NewResilientLLM(context.Background(), cfg, nil)
@@ -284,25 +223,11 @@ func TestPrettifyOnContentWithBrokenJSON(t *testing.T) {
 	// Use test database config for creating test database
 	testDBConfig := config.GetTestDatabaseConfig()
 
-	// Create test database
-	db, err := database.CreateTestDatabase(testDBConfig)
-	require.NoError(t, err, "Failed to create test database")
-	defer database.DropTestDatabase(db)
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(t, err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the test
+	db := testutil.NewTestDB(t, testDBConfig, "../../migrations")
 
 	// Create test user
-	userID := uuid.New().String()
-	googleID := fmt.Sprintf("google_%s", userID)
-	userQuery := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = db.ExecContext(context.Background(), userQuery, userID, googleID, "prettify@example.com")
-	require.NoError(t, err, "Failed to create test user")
+	userID := testutil.NewTestUser(t, db).ID.String()
 
 	// Define broken JSON scenarios
 	brokenJSONScenarios := []struct {
@@ -396,25 +321,11 @@ func TestPrettifyOnContentWithGolangStruct(t *testing.T) {
 	// Use test database config for creating test database
 	testDBConfig := config.GetTestDatabaseConfig()
 
-	// Create test database
-	db, err := database.CreateTestDatabase(testDBConfig)
-	require.NoError(t, err, "Failed to create test database")
-	defer database.DropTestDatabase(db)
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(t, err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the test
+	db := testutil.NewTestDB(t, testDBConfig, "../../migrations")
 
 	// Create test user
-	userID := uuid.New().String()
-	googleID := fmt.Sprintf("google_%s", userID)
-	userQuery := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = db.ExecContext(context.Background(), userQuery, userID, googleID, "prettify@example.com")
-	require.NoError(t, err, "Failed to create test user")
+	userID := testutil.NewTestUser(t, db).ID.String()
 
 	// Create note with Golang struct content (poorly indented)
 	inputContent := `
@@ -438,13 +349,7 @@ EnableParallelMap bool ` + "`" + `json:"enable_parallel_map,omitempty"` + "`" +
 }
 `
 
-	noteID := uuid.New()
-	noteQuery := `
-		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, NOW(), NOW(), 1)
-	`
-	_, err = db.ExecContext(context.Background(), noteQuery, noteID, userID, "Test Note", inputContent)
-	require.NoError(t, err, "Failed to create test note")
+	noteID := testutil.NewTestNote(t, db, uuid.MustParse(userID), "Test Note", inputContent).ID
 
 	// Setup services
 	llmClient, err := llm.NewResilientLLM(context.Background(), cfg, nil)
@@ -499,25 +404,11 @@ func TestPrettifyOnContentWithBrokenGolangStruct(t *testing.T) {
 	// Use test database config for creating test database
 	testDBConfig := config.GetTestDatabaseConfig()
 
-	// Create test database
-	db, err := database.CreateTestDatabase(testDBConfig)
-	require.NoError(t, err, "Failed to create test database")
-	defer database.DropTestDatabase(db)
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(t, err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the test
+	db := testutil.NewTestDB(t, testDBConfig, "../../migrations")
 
 	// Create test user
-	userID := uuid.New().String()
-	googleID := fmt.Sprintf("google_%s", userID)
-	userQuery := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = db.ExecContext(context.Background(), userQuery, userID, googleID, "prettify@example.com")
-	require.NoError(t, err, "Failed to create test user")
+	userID := testutil.NewTestUser(t, db).ID.String()
 
 	// Define broken Golang struct scenarios
 	brokenStructScenarios := []struct {
@@ -588,3 +479,34 @@ func TestPrettifyOnContentWithBrokenGolangStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestCombinePrettified(t *testing.T) {
+	combined := combinePrettified([]prettifyLLMResponse{
+		{
+			DetectedLanguage:  "en",
+			PrettifiedTitle:   "Trip plan",
+			PrettifiedContent: "- Book the flight\n",
+			SuggestedTags:     []string{"#travel", "#todo"},
+			ChangesMade:       []string{"fixed typos"},
+		},
+		{
+			DetectedLanguage:  "id",
+			PrettifiedTitle:   "Ignored",
+			PrettifiedContent: "- Pack the tent",
+			SuggestedTags:     []string{"#Travel", "#camping"},
+			ChangesMade:       []string{"fixed typos", "converted to bullets"},
+		},
+	})
+
+	assert.Equal(t, "en", combined.DetectedLanguage)
+	assert.Equal(t, "Trip plan", combined.PrettifiedTitle)
+	assert.Equal(t, "- Book the flight\n\n- Pack the tent", combined.PrettifiedContent)
+	assert.Equal(t, []string{"#travel", "#todo", "#camping"}, combined.SuggestedTags)
+	assert.Equal(t, []string{"fixed typos", "converted to bullets"}, combined.ChangesMade)
+}
+
+func TestMergeTagsKeepsOrder(t *testing.T) {
+	s := &PrettifyService{}
+	assert.Equal(t, []string{"#todo", "#work", "#travel"},
+		s.mergeTags([]string{"#todo", "#work"}, []string{"travel", "#todo"}))
+}
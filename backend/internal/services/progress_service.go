@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ProgressServiceInterface defines the interface for checklist progress rollups
+type ProgressServiceInterface interface {
+	GetTagProgress(ctx context.Context, userID, tag string) (*models.TagProgress, error)
+}
+
+// ProgressService aggregates checklist items across the notes of a tag.
+// Counts are parsed from note content once per note version and cached, so a
+// rollup only reads the content of notes edited since the last one.
+type ProgressService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+}
+
+// NewProgressService creates a new ProgressService
+func NewProgressService(db *sql.DB, noteService NoteServiceInterface) *ProgressService {
+	return &ProgressService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// progressEntry is a note with a tag and its cached counts, if current
+type progressEntry struct {
+	id        uuid.UUID
+	title     string
+	version   int
+	updatedAt time.Time
+	isPrivate bool
+	content   sql.NullString // only selected when the cache is stale
+	cached    bool
+	counts    models.ChecklistCounts
+}
+
+// GetTagProgress returns the checked and unchecked checklist items of every
+// note with the tag. Private notes are decrypted to be counted; without the
+// key they are reported as locked.
+func (s *ProgressService) GetTagProgress(ctx context.Context, userID, tag string) (*models.TagProgress, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT n.id, n.title, n.version, n.updated_at, n.is_private,
+		       CASE WHEN p.version = n.version THEN NULL ELSE n.content END,
+		       p.version = n.version AS cached, COALESCE(p.checked, 0), COALESCE(p.unchecked, 0)
+		FROM notes n
+		JOIN note_tags nt ON n.id = nt.note_id
+		JOIN tags t ON nt.tag_id = t.id
+		LEFT JOIN note_checklist_progress p ON p.note_id = n.id
+		WHERE n.user_id = $1 AND t.name = $2
+		ORDER BY n.updated_at DESC
+	`, userID, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes for progress: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []progressEntry
+	for rows.Next() {
+		var entry progressEntry
+		var cached sql.NullBool
+		err := rows.Scan(&entry.id, &entry.title, &entry.version, &entry.updatedAt, &entry.isPrivate,
+			&entry.content, &cached, &entry.counts.Checked, &entry.counts.Unchecked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note progress: %w", err)
+		}
+		entry.cached = cached.Valid && cached.Bool
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating note progress: %w", err)
+	}
+	rows.Close()
+
+	progress := &models.TagProgress{Tag: tag, Notes: []models.NoteProgress{}}
+	var stale []progressEntry
+	for _, entry := range entries {
+		if !entry.cached {
+			note := &models.Note{Content: entry.content.String}
+			if entry.isPrivate {
+				// Stored content is encrypted; read it through the note service
+				decrypted, err := s.noteService.GetNoteByID(ctx, userID, entry.id.String())
+				if errors.Is(err, ErrNoteNotFound) {
+					// Deleted since the notes were listed
+					continue
+				} else if err != nil {
+					return nil, fmt.Errorf("failed to read note %s: %w", entry.id, err)
+				}
+				if decrypted.Locked {
+					progress.LockedNotes++
+					continue
+				}
+				note = decrypted
+				entry.version = decrypted.Version
+			}
+			entry.counts = note.CountChecklist()
+			stale = append(stale, entry)
+		}
+
+		if entry.counts.Total() == 0 {
+			continue
+		}
+		progress.Checked += entry.counts.Checked
+		progress.Unchecked += entry.counts.Unchecked
+		progress.Notes = append(progress.Notes, models.NoteProgress{
+			NoteID:    entry.id,
+			Title:     entry.title,
+			Checked:   entry.counts.Checked,
+			Unchecked: entry.counts.Unchecked,
+			UpdatedAt: entry.updatedAt,
+			IsPrivate: entry.isPrivate,
+		})
+	}
+
+	progress.Total = progress.Checked + progress.Unchecked
+	if progress.Total > 0 {
+		progress.PercentComplete = math.Round(float64(progress.Checked)/float64(progress.Total)*1000) / 10
+	}
+
+	// A failed cache write only means the counts are parsed again next time
+	if err := s.cacheCounts(ctx, stale); err != nil {
+		log.Printf("[ProgressService] WARNING: failed to cache checklist counts: %v", err)
+	}
+
+	return progress, nil
+}
+
+// cacheCounts stores freshly parsed counts against the note versions they were parsed from
+func (s *ProgressService) cacheCounts(ctx context.Context, entries []progressEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(entries))
+	versions := make([]int64, len(entries))
+	checked := make([]int64, len(entries))
+	unchecked := make([]int64, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.id.String()
+		versions[i] = int64(entry.version)
+		checked[i] = int64(entry.counts.Checked)
+		unchecked[i] = int64(entry.counts.Unchecked)
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO note_checklist_progress (note_id, version, checked, unchecked)
+		SELECT * FROM unnest($1::uuid[], $2::int[], $3::int[], $4::int[])
+		ON CONFLICT (note_id) DO UPDATE
+		SET version = EXCLUDED.version, checked = EXCLUDED.checked,
+		    unchecked = EXCLUDED.unchecked, computed_at = NOW()
+	`, pq.Array(ids), pq.Array(versions), pq.Array(checked), pq.Array(unchecked))
+	return err
+}
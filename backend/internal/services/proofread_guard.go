@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+var (
+	// proofreadPrefixPattern matches the Markdown formatting that starts a
+	// line: indentation, list markers, checkboxes, quotes and headings
+	proofreadPrefixPattern = regexp.MustCompile(`^(?:\s*(?:[-*+]\s+(?:\[[ xX]\]\s+)?|\d+[.)]\s+|>\s*|#{1,6}\s+))*\s*`)
+	// proofreadProtectedPattern matches the parts of a line a proofread must
+	// not change: URLs, hashtags, [[links]] and inline code
+	proofreadProtectedPattern = regexp.MustCompile("https?://\\S+|#[\\p{L}\\p{N}_/-]+|\\[\\[[^\\]]*\\]\\]|`[^`]*`")
+)
+
+// errProofreadLineCount is returned for proofreads that added or removed
+// lines, which cannot be compared line by line
+var errProofreadLineCount = errors.New("proofread changed the number of lines")
+
+// mergeProofread merges a proofread into the original content line by line.
+// Corrections are kept only where they leave a line's formatting and
+// protected parts as they were; fenced code blocks are never changed. It
+// returns the merged content, the corrected lines and the 1-based numbers of
+// the lines kept as they were despite a correction.
+func mergeProofread(original, proofread string) (string, []models.ProofreadChange, []int, error) {
+	trimmed := strings.TrimRight(original, "\n")
+	originalLines := strings.Split(trimmed, "\n")
+	proofreadLines := strings.Split(strings.TrimRight(proofread, "\n"), "\n")
+	if len(originalLines) != len(proofreadLines) {
+		return "", nil, nil, errProofreadLineCount
+	}
+
+	var changes []models.ProofreadChange
+	var kept []int
+	inFence := false
+	merged := make([]string, len(originalLines))
+	for i, line := range originalLines {
+		merged[i] = line
+		fence := strings.HasPrefix(strings.TrimSpace(line), "```")
+		if inFence || fence {
+			if fence {
+				inFence = !inFence
+			}
+			if proofreadLines[i] != line {
+				kept = append(kept, i+1)
+			}
+			continue
+		}
+
+		corrected := strings.TrimRight(proofreadLines[i], " \t")
+		if corrected == strings.TrimRight(line, " \t") {
+			continue
+		}
+		if !proofreadLineAllowed(line, corrected) {
+			kept = append(kept, i+1)
+			continue
+		}
+		merged[i] = corrected
+		changes = append(changes, models.ProofreadChange{
+			Field:     models.ProofreadFieldContent,
+			Line:      i + 1,
+			Original:  line,
+			Proofread: corrected,
+		})
+	}
+	return strings.Join(merged, "\n") + original[len(trimmed):], changes, kept, nil
+}
+
+// proofreadLineAllowed reports whether a corrected line keeps the
+// formatting and protected parts of the original line
+func proofreadLineAllowed(original, corrected string) bool {
+	if strings.TrimSpace(original) == "" || strings.TrimSpace(corrected) == "" {
+		return false
+	}
+	if proofreadPrefixPattern.FindString(original) != proofreadPrefixPattern.FindString(corrected) {
+		return false
+	}
+	before := proofreadProtectedPattern.FindAllString(original, -1)
+	after := proofreadProtectedPattern.FindAllString(corrected, -1)
+	if len(before) != len(after) {
+		return false
+	}
+	for i := range before {
+		if before[i] != after[i] {
+			return false
+		}
+	}
+	return true
+}
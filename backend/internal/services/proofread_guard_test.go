@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeProofread(t *testing.T) {
+	original := "Plan for teh week\n\n" +
+		"- [ ] recieve the parcel #errands\n" +
+		"- read https://example.com/recieve-guide\n" +
+		"> qoute of the day\n" +
+		"```\nfmt.Println(\"helo\")\n```\n" +
+		"Call [[Ada Lovlace]] tommorow\n"
+	proofread := "Plan for the week\n\n" +
+		"- [ ] receive the parcel #errands\n" +
+		"- read https://example.com/receive-guide\n" +
+		"quote of the day\n" +
+		"```\nfmt.Println(\"hello\")\n```\n" +
+		"Call [[Ada Lovlace]] tomorrow"
+
+	merged, changes, kept, err := mergeProofread(original, proofread)
+	assert.NoError(t, err)
+	assert.Equal(t, "Plan for the week\n\n"+
+		"- [ ] receive the parcel #errands\n"+
+		"- read https://example.com/recieve-guide\n"+
+		"> qoute of the day\n"+
+		"```\nfmt.Println(\"helo\")\n```\n"+
+		"Call [[Ada Lovlace]] tomorrow\n", merged)
+	assert.Equal(t, []models.ProofreadChange{
+		{Field: models.ProofreadFieldContent, Line: 1, Original: "Plan for teh week", Proofread: "Plan for the week"},
+		{Field: models.ProofreadFieldContent, Line: 3, Original: "- [ ] recieve the parcel #errands", Proofread: "- [ ] receive the parcel #errands"},
+		{Field: models.ProofreadFieldContent, Line: 9, Original: "Call [[Ada Lovlace]] tommorow", Proofread: "Call [[Ada Lovlace]] tomorrow"},
+	}, changes)
+	// The URL, the quote marker and the code were corrected too, so those
+	// lines are kept
+	assert.Equal(t, []int{4, 5, 7}, kept)
+
+	_, _, _, err = mergeProofread("one\ntwo", "one two")
+	assert.ErrorIs(t, err, errProofreadLineCount)
+}
+
+func TestProofreadLineAllowed(t *testing.T) {
+	tests := []struct {
+		original, corrected string
+		allowed             bool
+	}{
+		{"1. frist step", "1. first step", true},
+		{"  * nested itme", "  * nested item", true},
+		{"1. frist step", "1) first step", false},
+		{"tagged #Recipe", "tagged #recipe", false},
+		{"run `go tset` now", "run `go test` now", false},
+		{"", "added", false},
+		{"removed", " ", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, proofreadLineAllowed(tt.original, tt.corrected), "%q -> %q", tt.original, tt.corrected)
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// proofreadProposalTTL is how long a proofread can be applied
+const proofreadProposalTTL = time.Hour
+
+// codeProofreadFailed is the error code of proofreads the LLM failed
+const codeProofreadFailed = "PROOFREAD_FAILED"
+
+// Errors of proofread proposals that cannot be applied
+var (
+	ErrProofreadNotFound = apperrors.NotFound("PROOFREAD_NOT_FOUND", "proofread not found or expired")
+	ErrProofreadOutdated = apperrors.Conflict("PROOFREAD_OUTDATED", "the note changed since it was proofread")
+)
+
+// ProofreadServiceInterface defines the interface for proofreading notes
+type ProofreadServiceInterface interface {
+	ProofreadNote(ctx context.Context, userID, noteID string) (*models.ProofreadProposal, error)
+	ApplyProofread(ctx context.Context, userID, noteID, proposalID string) (*models.Note, error)
+	DiscardProofread(ctx context.Context, userID, noteID, proposalID string) error
+}
+
+// ProofreadService corrects the spelling and grammar of notes with the LLM.
+// Unlike prettify it leaves the formatting alone, and its corrections are
+// proposed as a diff the user applies.
+type ProofreadService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+	llm         TextGenerator
+	prompts     *prompts.Registry
+}
+
+// NewProofreadService creates a new ProofreadService
+func NewProofreadService(db *sql.DB, noteService NoteServiceInterface, llm TextGenerator) *ProofreadService {
+	return &ProofreadService{
+		db:          db,
+		noteService: noteService,
+		llm:         llm,
+	}
+}
+
+// SetPrompts sets the registry the proofread prompt is rendered from. The
+// built-in prompt is used otherwise.
+func (s *ProofreadService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// proofreadLLMResponse is the JSON the proofread prompt asks for
+type proofreadLLMResponse struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// ProofreadNote proofreads a note the user may change and returns the
+// corrections as a proposal, without changing the note. Corrections that
+// would change a line's formatting, URLs, hashtags or code are dropped.
+// Proposals replace the user's previous one for the note.
+func (s *ProofreadService) ProofreadNote(ctx context.Context, userID, noteID string) (*models.ProofreadProposal, error) {
+	note, err := writableNote(ctx, s.db, s.noteService, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	// Private notes are encrypted at rest and must not be sent to the LLM
+	if note.IsPrivate {
+		return nil, apperrors.Validation("PRIVATE_NOTE", "private notes cannot be proofread")
+	}
+	if strings.TrimSpace(note.Content) == "" {
+		return nil, apperrors.Validation("NOTE_EMPTY", "note has no content to proofread")
+	}
+
+	title := ""
+	if note.Title != nil {
+		title = *note.Title
+	}
+	prompt, err := promptsOrDefault(s.prompts).Render(prompts.Proofread, prompts.ProofreadData{Title: title, Content: note.Content})
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeatureProofread), prompt)
+	if err != nil {
+		return nil, fmt.Errorf("LLM proofread failed: %w", err)
+	}
+	var result proofreadLLMResponse
+	if err := parseProofreadResponse(response, &result); err != nil {
+		log.Printf("[ProofreadService] WARNING: Invalid proofread of note %s: %v", noteID, err)
+		return nil, apperrors.New(apperrors.ErrUnavailable, codeProofreadFailed, "the proofread could not be read, try again")
+	}
+
+	content, changes, kept, err := mergeProofread(note.Content, result.Content)
+	if errors.Is(err, errProofreadLineCount) {
+		log.Printf("[ProofreadService] WARNING: Proofread of note %s changed its lines", noteID)
+		return nil, apperrors.New(apperrors.ErrUnavailable, codeProofreadFailed, "the proofread changed the lines of the note, try again")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := &models.ProofreadProposal{
+		NoteID:    note.ID,
+		Version:   note.Version,
+		Title:     note.Title,
+		Content:   content,
+		Changes:   []models.ProofreadChange{},
+		KeptLines: kept,
+	}
+	correctedTitle := strings.TrimSpace(result.Title)
+	if title != "" && correctedTitle != title && proofreadLineAllowed(title, correctedTitle) {
+		proposal.Title = &correctedTitle
+		proposal.Changes = append(proposal.Changes, models.ProofreadChange{
+			Field:     models.ProofreadFieldTitle,
+			Original:  title,
+			Proofread: correctedTitle,
+		})
+	}
+	proposal.Changes = append(proposal.Changes, changes...)
+	if len(proposal.Changes) == 0 {
+		return proposal, nil
+	}
+
+	if err := s.saveProposal(ctx, userID, proposal); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// saveProposal stores a proposal, replacing the user's previous one for the
+// note and removing expired ones
+func (s *ProofreadService) saveProposal(ctx context.Context, userID string, proposal *models.ProofreadProposal) error {
+	now := time.Now().UTC()
+	id := uuid.New()
+	expiresAt := now.Add(proofreadProposalTTL)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM proofread_proposals
+		WHERE (note_id = $1 AND user_id = $2) OR expires_at < $3
+	`, proposal.NoteID, userID, now); err != nil {
+		return fmt.Errorf("failed to replace proofread: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO proofread_proposals (id, note_id, user_id, note_version, title, content, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, id, proposal.NoteID, userID, proposal.Version, proposal.Title, proposal.Content, now, expiresAt); err != nil {
+		return fmt.Errorf("failed to save proofread: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit proofread: %w", err)
+	}
+
+	proposal.ID = &id
+	proposal.ExpiresAt = &expiresAt
+	return nil
+}
+
+// ApplyProofread applies a proposal to its note, which must not have changed
+// since it was proofread
+func (s *ProofreadService) ApplyProofread(ctx context.Context, userID, noteID, proposalID string) (*models.Note, error) {
+	if _, err := uuid.Parse(proposalID); err != nil {
+		return nil, ErrProofreadNotFound
+	}
+	var version int
+	var title *string
+	var content string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT note_version, title, content
+		FROM proofread_proposals
+		WHERE id = $1 AND note_id = $2 AND user_id = $3 AND expires_at > $4
+	`, proposalID, noteID, userID, time.Now().UTC()).Scan(&version, &title, &content)
+	if err == sql.ErrNoRows {
+		return nil, ErrProofreadNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get proofread: %w", err)
+	}
+
+	note, err := writableNote(ctx, s.db, s.noteService, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Version != version {
+		return nil, ErrProofreadOutdated
+	}
+
+	updated, err := s.noteService.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{
+		Title:   title,
+		Content: &content,
+		Version: &version,
+	})
+	if errors.Is(err, ErrVersionMismatch) {
+		return nil, ErrProofreadOutdated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DiscardProofread(ctx, userID, noteID, proposalID); err != nil {
+		log.Printf("[ProofreadService] WARNING: Failed to remove applied proofread %s: %v", proposalID, err)
+	}
+	return updated, nil
+}
+
+// DiscardProofread removes a proposal without applying it
+func (s *ProofreadService) DiscardProofread(ctx context.Context, userID, noteID, proposalID string) error {
+	if _, err := uuid.Parse(proposalID); err != nil {
+		return ErrProofreadNotFound
+	}
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM proofread_proposals WHERE id = $1 AND note_id = $2 AND user_id = $3
+	`, proposalID, noteID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to discard proofread: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ErrProofreadNotFound
+	}
+	return nil
+}
+
+// parseProofreadResponse extracts the JSON object of an LLM response, which
+// may be wrapped in text or a code block
+func parseProofreadResponse(response string, result *proofreadLLMResponse) error {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return fmt.Errorf("no JSON found in response")
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), result); err != nil {
+		return err
+	}
+	if strings.TrimSpace(result.Content) == "" {
+		return fmt.Errorf("response has no content")
+	}
+	return nil
+}
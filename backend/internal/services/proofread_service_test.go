@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestProofreadNote(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	generator := &fakeSummarizer{summary: "```json\n" +
+		`{"title": "Shopping", "content": "Buy milk for the week\n- eggs #groceries\n- see https://shop.example.com/offers"}` + "\n```"}
+	service := NewProofreadService(db, noteService, generator)
+
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{
+		Title:   "Shoping",
+		Content: "Buy mlk for teh week\n- eggs #groceries\n- see https://shop.example.com/ofers",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	noteID := note.ID.String()
+
+	proposal, err := service.ProofreadNote(ctx, userID, noteID)
+	if err != nil {
+		t.Fatalf("Failed to proofread note: %v", err)
+	}
+	if proposal.ID == nil || proposal.Version != note.Version {
+		t.Fatalf("Expected a stored proposal of version %d, got %+v", note.Version, proposal)
+	}
+	want := []models.ProofreadChange{
+		{Field: models.ProofreadFieldTitle, Original: "Shoping", Proofread: "Shopping"},
+		{Field: models.ProofreadFieldContent, Line: 1, Original: "Buy mlk for teh week", Proofread: "Buy milk for the week"},
+	}
+	if len(proposal.Changes) != len(want) || proposal.Changes[0] != want[0] || proposal.Changes[1] != want[1] {
+		t.Errorf("Unexpected changes %+v", proposal.Changes)
+	}
+	// The URL was changed, so its line is kept
+	if len(proposal.KeptLines) != 1 || proposal.KeptLines[0] != 3 {
+		t.Errorf("Expected line 3 to be kept, got %v", proposal.KeptLines)
+	}
+
+	// The note is unchanged until the proposal is applied
+	current, err := noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if current.Content != note.Content {
+		t.Errorf("Expected the note to be unchanged, got %q", current.Content)
+	}
+
+	applied, err := service.ApplyProofread(ctx, userID, noteID, proposal.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to apply proofread: %v", err)
+	}
+	if *applied.Title != "Shopping" || applied.Content != "Buy milk for the week\n- eggs #groceries\n- see https://shop.example.com/ofers" {
+		t.Errorf("Unexpected note %q:\n%s", *applied.Title, applied.Content)
+	}
+	if _, err := service.ApplyProofread(ctx, userID, noteID, proposal.ID.String()); !errors.Is(err, ErrProofreadNotFound) {
+		t.Errorf("Expected an applied proposal to be gone, got %v", err)
+	}
+
+	// Proposals do not apply once the note changed
+	generator.summary = `{"title": "Shopping", "content": "Buy milk for the week\n- Eggs #groceries\n- see https://shop.example.com/ofers"}`
+	proposal, err = service.ProofreadNote(ctx, userID, noteID)
+	if err != nil || proposal.ID == nil {
+		t.Fatalf("Failed to proofread note: %+v, %v", proposal, err)
+	}
+	content := applied.Content + "\n- bread"
+	if _, err := noteService.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{Title: applied.Title, Content: &content}); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if _, err := service.ApplyProofread(ctx, userID, noteID, proposal.ID.String()); !errors.Is(err, ErrProofreadOutdated) {
+		t.Errorf("Expected ErrProofreadOutdated, got %v", err)
+	}
+	if err := service.DiscardProofread(ctx, userID, noteID, proposal.ID.String()); err != nil {
+		t.Errorf("Failed to discard proofread: %v", err)
+	}
+
+	// Notes without mistakes get no proposal
+	generator.summary = `{"title": "Shopping", "content": "Buy milk for the week\n- eggs #groceries\n- see https://shop.example.com/ofers\n- bread"}`
+	proposal, err = service.ProofreadNote(ctx, userID, noteID)
+	if err != nil {
+		t.Fatalf("Failed to proofread note: %v", err)
+	}
+	if proposal.ID != nil || len(proposal.Changes) != 0 {
+		t.Errorf("Expected no proposal, got %+v", proposal)
+	}
+
+	// Proofreads merging or splitting lines cannot be shown line by line
+	generator.summary = `{"title": "Shopping", "content": "Buy milk for the week, eggs and bread"}`
+	if _, err := service.ProofreadNote(ctx, userID, noteID); !errors.Is(err, apperrors.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+
+	other := testutil.NewTestUser(t, db)
+	if _, err := service.ProofreadNote(ctx, other.ID.String(), noteID); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected other users' notes to be refused, got %v", err)
+	}
+}
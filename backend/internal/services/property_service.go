@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// PropertyServiceInterface defines the interface for custom note properties
+type PropertyServiceInterface interface {
+	CreateProperty(ctx context.Context, userID string, request *models.CreatePropertyRequest) (*models.PropertyDefinition, error)
+	ListProperties(ctx context.Context, userID string) ([]models.PropertyDefinition, error)
+	DeleteProperty(ctx context.Context, userID, propertyID string) error
+}
+
+// ErrPropertyNotFound is returned for properties that do not exist
+var ErrPropertyNotFound = apperrors.NotFound("PROPERTY_NOT_FOUND", "property not found")
+
+// codeInvalidProperty is the error code of invalid properties and values
+const codeInvalidProperty = "INVALID_PROPERTY"
+
+// PropertyService manages the typed properties users define for their
+// notes. Values are stored on the notes and checked by NoteService.
+type PropertyService struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewPropertyService creates a new PropertyService
+func NewPropertyService(db *sql.DB) *PropertyService {
+	return &PropertyService{
+		db:      db,
+		dialect: database.DialectOf(db),
+	}
+}
+
+// propertyColumns lists the note_properties columns in scanProperty order
+const propertyColumns = "id, user_id, name, type, options, created_at"
+
+// scanProperty scans a row selected with propertyColumns
+func scanProperty(row rowScanner, p *models.PropertyDefinition) error {
+	return row.Scan(&p.ID, &p.UserID, &p.Name, &p.Type, &p.Options, &p.CreatedAt)
+}
+
+// CreateProperty defines a property for a user's notes
+func (s *PropertyService) CreateProperty(ctx context.Context, userID string, request *models.CreatePropertyRequest) (*models.PropertyDefinition, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidProperty, err)
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM note_properties WHERE user_id = $1
+	`, userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count properties: %w", err)
+	}
+	if count >= models.MaxProperties {
+		return nil, apperrors.Validation(codeInvalidProperty, fmt.Sprintf("too many properties (max %d)", models.MaxProperties))
+	}
+
+	var options models.PropertyOptions
+	if request.Type == models.PropertySelect {
+		options = request.Options
+	}
+	var property models.PropertyDefinition
+	err := scanProperty(s.db.QueryRowContext(ctx, `
+		INSERT INTO note_properties (id, user_id, name, type, options)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING `+propertyColumns,
+		uuid.New(), userID, request.Name, request.Type, options), &property)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.Conflict("PROPERTY_EXISTS", "property with this name already exists")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to create property: %w", err)
+	}
+
+	return &property, nil
+}
+
+// ListProperties returns a user's properties ordered by name
+func (s *PropertyService) ListProperties(ctx context.Context, userID string) ([]models.PropertyDefinition, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+propertyColumns+`
+		FROM note_properties
+		WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list properties: %w", err)
+	}
+	defer rows.Close()
+
+	properties := []models.PropertyDefinition{}
+	for rows.Next() {
+		var property models.PropertyDefinition
+		if err := scanProperty(rows, &property); err != nil {
+			return nil, fmt.Errorf("failed to scan property: %w", err)
+		}
+		properties = append(properties, property)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating properties: %w", err)
+	}
+
+	return properties, nil
+}
+
+// DeleteProperty deletes a property and removes its values from the user's
+// notes, without changing their versions
+func (s *PropertyService) DeleteProperty(ctx context.Context, userID, propertyID string) error {
+	if _, err := uuid.Parse(propertyID); err != nil {
+		return ErrPropertyNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM note_properties WHERE id = $1 AND user_id = $2
+		RETURNING name
+	`, propertyID, userID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return ErrPropertyNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete property: %w", err)
+	}
+
+	// Names only hold lowercase letters, digits and underscores, so they
+	// are safe to use as JSON keys in SQL
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE notes SET properties = NULLIF(%s, '{}')
+		WHERE user_id = $1 AND %s IS NOT NULL
+	`, s.dialect.JSONRemove("properties", name), s.dialect.JSONText("properties", name)), userID); err != nil {
+		return fmt.Errorf("failed to remove property values: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit property deletion: %w", err)
+	}
+
+	return nil
+}
+
+// loadPropertySchema returns a user's property definitions by name
+func loadPropertySchema(ctx context.Context, db *sql.DB, userID string) (models.PropertySchema, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+propertyColumns+`
+		FROM note_properties
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get properties: %w", err)
+	}
+	defer rows.Close()
+
+	schema := make(models.PropertySchema)
+	for rows.Next() {
+		var property models.PropertyDefinition
+		if err := scanProperty(rows, &property); err != nil {
+			return nil, fmt.Errorf("failed to scan property: %w", err)
+		}
+		schema[property.Name] = property
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating properties: %w", err)
+	}
+
+	return schema, nil
+}
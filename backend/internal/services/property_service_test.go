@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestNoteProperties(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewPropertyService(db)
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	status, err := service.CreateProperty(ctx, userID, &models.CreatePropertyRequest{
+		Name: "status", Type: models.PropertySelect, Options: []string{"Todo", "Done"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create property: %v", err)
+	}
+	if _, err := service.CreateProperty(ctx, userID, &models.CreatePropertyRequest{Name: "estimate", Type: models.PropertyNumber}); err != nil {
+		t.Fatalf("Failed to create property: %v", err)
+	}
+	if _, err := service.CreateProperty(ctx, userID, &models.CreatePropertyRequest{Name: "Status", Type: models.PropertyString}); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("Expected a conflict for a duplicate name, got %v", err)
+	}
+
+	for _, values := range []map[string]any{
+		{"status": "todo", "estimate": 3.0},
+		{"status": "Done", "estimate": 1.0},
+		{"estimate": 8.0},
+		nil,
+	} {
+		if _, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Task", Properties: values}); err != nil {
+			t.Fatalf("Failed to create note: %v", err)
+		}
+	}
+	if _, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{
+		Content: "Task", Properties: map[string]any{"status": "Blocked"},
+	}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected an invalid option to fail validation, got %v", err)
+	}
+
+	search := func(request *models.SearchNotesRequest) []models.NoteResponse {
+		t.Helper()
+		list, err := noteService.SearchNotes(ctx, userID, request)
+		if err != nil {
+			t.Fatalf("Failed to search notes: %v", err)
+		}
+		return list.Notes
+	}
+
+	notes := search(&models.SearchNotesRequest{Properties: []models.PropertyFilter{{Name: "status", Op: models.PropertyFilterEq, Value: "TODO"}}})
+	if len(notes) != 1 || notes[0].Properties["status"] != "Todo" || notes[0].Properties["estimate"] != 3.0 {
+		t.Errorf("Unexpected notes with status Todo: %+v", notes)
+	}
+
+	notes = search(&models.SearchNotesRequest{Properties: []models.PropertyFilter{{Name: "estimate", Op: models.PropertyFilterMin, Value: "2"}}})
+	if len(notes) != 2 {
+		t.Errorf("Expected 2 notes with an estimate of at least 2, got %d", len(notes))
+	}
+
+	notes = search(&models.SearchNotesRequest{SortProperty: "estimate", OrderDir: "asc"})
+	if len(notes) != 4 || notes[0].Properties["estimate"] != 1.0 || notes[2].Properties["estimate"] != 8.0 || notes[3].Properties != nil {
+		t.Errorf("Expected notes by ascending estimate with the unset one last, got %+v", notes)
+	}
+
+	// Updates merge values, and null removes one
+	updated, err := noteService.UpdateNote(ctx, userID, notes[0].ID.String(), &models.UpdateNoteRequest{
+		Properties: map[string]any{"status": "Todo", "estimate": nil},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update properties: %v", err)
+	}
+	if len(updated.Properties) != 1 || updated.Properties["status"] != "Todo" {
+		t.Errorf("Unexpected updated properties %v", updated.Properties)
+	}
+
+	// Deleting a property removes its values
+	if err := service.DeleteProperty(ctx, userID, status.ID.String()); err != nil {
+		t.Fatalf("Failed to delete property: %v", err)
+	}
+	note, err := noteService.GetNoteByID(ctx, userID, updated.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if note.Properties != nil {
+		t.Errorf("Expected the property values to be removed, got %v", note.Properties)
+	}
+	if _, err := noteService.SearchNotes(ctx, userID, &models.SearchNotesRequest{SortProperty: "status"}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected sorting by a deleted property to fail validation, got %v", err)
+	}
+}
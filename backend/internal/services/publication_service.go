@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/markdown"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// PublicationServiceInterface defines the interface for publishing notes
+type PublicationServiceInterface interface {
+	PublishNote(ctx context.Context, userID, noteID string, request *models.PublishNoteRequest) (*models.Publication, error)
+	GetPublication(ctx context.Context, userID, noteID string) (*models.Publication, error)
+	UnpublishNote(ctx context.Context, userID, noteID string) error
+	GetPublishedNote(ctx context.Context, slug string) (*models.PublishedNote, error)
+}
+
+// Publication errors
+var (
+	ErrPublicationNotFound = apperrors.NotFound("PUBLICATION_NOT_FOUND", "publication not found")
+	errSlugTaken           = apperrors.Conflict("SLUG_TAKEN", "slug is already used by another note")
+)
+
+// codeInvalidPublication is the error code of invalid publications
+const codeInvalidPublication = "INVALID_PUBLICATION"
+
+// PublicationService publishes notes read-only at public slugs. Published
+// notes are rendered from their current content, so edits show on the page
+// right away; notes made private stop being served.
+type PublicationService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+}
+
+// NewPublicationService creates a new PublicationService
+func NewPublicationService(db *sql.DB, noteService NoteServiceInterface) *PublicationService {
+	return &PublicationService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// publicationColumns lists the published_notes columns in scanPublication order
+const publicationColumns = "note_id, slug, noindex, published_at, updated_at"
+
+// scanPublication scans a row selected with publicationColumns
+func scanPublication(row rowScanner, p *models.Publication) error {
+	if err := row.Scan(&p.NoteID, &p.Slug, &p.NoIndex, &p.PublishedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+	p.Path = models.PublicationPath(p.Slug)
+	return nil
+}
+
+// PublishNote publishes a note its user can edit, or changes the slug or
+// noindex option of its publication. Private notes cannot be published.
+func (s *PublicationService) PublishNote(ctx context.Context, userID, noteID string, request *models.PublishNoteRequest) (*models.Publication, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidPublication, err)
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNoteWrite(ctx, s.db, userID, note); err != nil {
+		return nil, err
+	}
+	if note.IsPrivate {
+		return nil, apperrors.Validation(codeInvalidPublication, "private notes cannot be published")
+	}
+
+	current, err := s.getPublication(ctx, noteID)
+	if err != nil && err != ErrPublicationNotFound {
+		return nil, err
+	}
+
+	slug, noIndex := request.Slug, false
+	if current != nil {
+		noIndex = current.NoIndex
+		if slug == "" {
+			slug = current.Slug
+		}
+	}
+	if slug == "" {
+		title := ""
+		if note.Title != nil {
+			title = *note.Title
+		}
+		slug = models.GenerateSlug(title)
+	}
+	if request.NoIndex != nil {
+		noIndex = *request.NoIndex
+	}
+
+	var taken bool
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM published_notes WHERE slug = $1 AND note_id <> $2)
+	`, slug, note.ID).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to check slug: %w", err)
+	}
+	if taken {
+		return nil, errSlugTaken
+	}
+
+	var publication models.Publication
+	err = scanPublication(s.db.QueryRowContext(ctx, `
+		INSERT INTO published_notes (note_id, slug, noindex)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (note_id) DO UPDATE SET slug = EXCLUDED.slug, noindex = EXCLUDED.noindex, updated_at = NOW()
+		RETURNING `+publicationColumns,
+		note.ID, slug, noIndex), &publication)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, errSlugTaken
+		}
+		return nil, fmt.Errorf("failed to publish note: %w", err)
+	}
+
+	return &publication, nil
+}
+
+// GetPublication returns the publication of a note the user can read
+func (s *PublicationService) GetPublication(ctx context.Context, userID, noteID string) (*models.Publication, error) {
+	if _, err := s.noteService.GetNoteByID(ctx, userID, noteID); err != nil {
+		return nil, err
+	}
+	return s.getPublication(ctx, noteID)
+}
+
+// getPublication returns the publication of a note
+func (s *PublicationService) getPublication(ctx context.Context, noteID string) (*models.Publication, error) {
+	var publication models.Publication
+	err := scanPublication(s.db.QueryRowContext(ctx, `
+		SELECT `+publicationColumns+` FROM published_notes WHERE note_id = $1
+	`, noteID), &publication)
+	if err == sql.ErrNoRows {
+		return nil, ErrPublicationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get publication: %w", err)
+	}
+	return &publication, nil
+}
+
+// UnpublishNote takes a note the user can edit off its public page. Its
+// slug becomes free for other notes.
+func (s *PublicationService) UnpublishNote(ctx context.Context, userID, noteID string) error {
+	note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	if err := checkNoteWrite(ctx, s.db, userID, note); err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM published_notes WHERE note_id = $1
+	`, note.ID)
+	if err != nil {
+		return fmt.Errorf("failed to unpublish note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrPublicationNotFound
+	}
+
+	return nil
+}
+
+// GetPublishedNote returns the note published at a slug, rendered to HTML,
+// for anyone to read
+func (s *PublicationService) GetPublishedNote(ctx context.Context, slug string) (*models.PublishedNote, error) {
+	var published models.PublishedNote
+	var title sql.NullString
+	var content string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT n.title, n.content, n.updated_at, p.noindex
+		FROM published_notes p
+		JOIN notes n ON n.id = p.note_id
+		WHERE p.slug = $1 AND NOT n.is_private
+	`, slug).Scan(&title, &content, &published.UpdatedAt, &published.NoIndex)
+	if err == sql.ErrNoRows {
+		return nil, ErrPublicationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get published note: %w", err)
+	}
+
+	published.Title = title.String
+	published.HTML = markdown.ToHTML(content)
+	return &published, nil
+}
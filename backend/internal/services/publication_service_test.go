@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestPublishNote(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	service := NewPublicationService(db, noteService)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	other := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	title := "Trip Plan: Kyoto!"
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Title: title, Content: "# Day 1\n- [x] Fushimi Inari\n<script>alert(1)</script>"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	noteID := note.ID.String()
+
+	if _, err := service.GetPublication(ctx, userID, noteID); !errors.Is(err, ErrPublicationNotFound) {
+		t.Errorf("Expected no publication yet, got %v", err)
+	}
+	if _, err := service.PublishNote(ctx, other.ID.String(), noteID, &models.PublishNoteRequest{}); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected other users not to publish the note, got %v", err)
+	}
+
+	// Without a slug, the note gets one from its title
+	publication, err := service.PublishNote(ctx, userID, noteID, &models.PublishNoteRequest{})
+	if err != nil {
+		t.Fatalf("Failed to publish note: %v", err)
+	}
+	if !strings.HasPrefix(publication.Slug, "trip-plan-kyoto-") || publication.Path != "/p/"+publication.Slug || publication.NoIndex {
+		t.Errorf("Unexpected publication %+v", publication)
+	}
+
+	published, err := service.GetPublishedNote(ctx, publication.Slug)
+	if err != nil {
+		t.Fatalf("Failed to get published note: %v", err)
+	}
+	if published.Title != title || !strings.Contains(published.HTML, "<h1>Day 1</h1>") || strings.Contains(published.HTML, "<script>") {
+		t.Errorf("Unexpected published note %+v", published)
+	}
+
+	// Changing the slug keeps the noindex option unless it is set
+	noIndex := true
+	if _, err := service.PublishNote(ctx, userID, noteID, &models.PublishNoteRequest{NoIndex: &noIndex}); err != nil {
+		t.Fatalf("Failed to update publication: %v", err)
+	}
+	publication, err = service.PublishNote(ctx, userID, noteID, &models.PublishNoteRequest{Slug: "Kyoto-2024"})
+	if err != nil {
+		t.Fatalf("Failed to update publication: %v", err)
+	}
+	if publication.Slug != "kyoto-2024" || !publication.NoIndex {
+		t.Errorf("Unexpected publication %+v", publication)
+	}
+	if _, err := service.GetPublishedNote(ctx, "trip-plan"); !errors.Is(err, ErrPublicationNotFound) {
+		t.Errorf("Expected unknown slugs not found, got %v", err)
+	}
+
+	otherNote, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Packing list"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := service.PublishNote(ctx, userID, otherNote.ID.String(), &models.PublishNoteRequest{Slug: "kyoto-2024"}); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("Expected the slug taken, got %v", err)
+	}
+	if _, err := service.PublishNote(ctx, userID, otherNote.ID.String(), &models.PublishNoteRequest{Slug: "no spaces"}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected an invalid slug, got %v", err)
+	}
+
+	if err := service.UnpublishNote(ctx, userID, noteID); err != nil {
+		t.Fatalf("Failed to unpublish note: %v", err)
+	}
+	if _, err := service.GetPublishedNote(ctx, "kyoto-2024"); !errors.Is(err, ErrPublicationNotFound) {
+		t.Errorf("Expected the unpublished note gone, got %v", err)
+	}
+	if err := service.UnpublishNote(ctx, userID, noteID); !errors.Is(err, ErrPublicationNotFound) {
+		t.Errorf("Expected unpublishing twice not found, got %v", err)
+	}
+}
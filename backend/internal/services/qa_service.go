@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// Retrieval limits for question answering
+const (
+	maxQuestionKeywords = 8
+	qaCandidatesPerTerm = 20
+	maxQACandidates     = 20
+)
+
+// noNotesAnswer is the answer given when no note relates to the question
+const noNotesAnswer = "I couldn't find any notes related to your question."
+
+// TokenCounter counts the LLM tokens in a text
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// TextStreamer streams an LLM completion for a single prompt
+type TextStreamer interface {
+	Stream(ctx context.Context, prompt string, streamingFunc func(context.Context, []byte) error) error
+}
+
+// QAServiceInterface defines the interface for answering questions over notes
+type QAServiceInterface interface {
+	PrepareAnswer(ctx context.Context, userID, question string) (*QAContext, error)
+	StreamAnswer(ctx context.Context, qa *QAContext, onChunk func(chunk string) error) (*models.QAAnswer, error)
+}
+
+// QAContext holds the notes retrieved for a question. Sources are known before
+// the answer is generated, so they can be sent to the client first.
+type QAContext struct {
+	Question string
+	Sources  []models.QASource
+	prompt   string
+	userID   string
+}
+
+// QAService answers free-form questions using the user's notes as context.
+// Candidate notes are retrieved by keyword and tag search, fitted into the
+// token budget and passed to the LLM, which cites them by index.
+type QAService struct {
+	llm           TextStreamer
+	tokenizer     TokenCounter
+	noteService   NoteServiceInterface
+	contextTokens int
+}
+
+// NewQAService creates a new QAService. contextTokens is the token budget for
+// the prompt including the notes.
+func NewQAService(llmClient TextStreamer, tokenizer TokenCounter, noteService NoteServiceInterface, contextTokens int) *QAService {
+	return &QAService{
+		llm:           llmClient,
+		tokenizer:     tokenizer,
+		noteService:   noteService,
+		contextTokens: contextTokens,
+	}
+}
+
+// qaCandidate is a note scored by how many question terms it matches
+type qaCandidate struct {
+	note  models.NoteResponse
+	score int
+}
+
+// PrepareAnswer retrieves the notes relevant to a question and builds the prompt
+func (s *QAService) PrepareAnswer(ctx context.Context, userID, question string) (*QAContext, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	if len(question) > 1000 {
+		return nil, fmt.Errorf("question too long (max 1000 characters)")
+	}
+
+	keywords, tags := questionTerms(question)
+	candidates := make(map[string]*qaCandidate)
+	collect := func(request *models.SearchNotesRequest, weight int) error {
+		// Questions are answered from the user's own notes only
+		request.OwnedOnly = true
+		list, err := s.noteService.SearchNotes(ctx, userID, request)
+		if err != nil {
+			return fmt.Errorf("failed to search notes: %w", err)
+		}
+		for _, note := range list.Notes {
+			// Private notes are encrypted at rest and must not be sent to the LLM
+			if note.IsPrivate || note.Locked {
+				continue
+			}
+			id := note.ID.String()
+			if candidates[id] == nil {
+				candidates[id] = &qaCandidate{note: note}
+			}
+			candidates[id].score += weight
+		}
+		return nil
+	}
+
+	for _, keyword := range keywords {
+		if err := collect(&models.SearchNotesRequest{Query: keyword, Limit: qaCandidatesPerTerm, OrderBy: "updated_at", OrderDir: "desc"}, 1); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range tags {
+		if err := collect(&models.SearchNotesRequest{Tags: []string{tag}, Limit: qaCandidatesPerTerm, OrderBy: "updated_at", OrderDir: "desc"}, 2); err != nil {
+			return nil, err
+		}
+	}
+
+	ranked := make([]*qaCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		ranked = append(ranked, candidate)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].note.UpdatedAt.After(ranked[j].note.UpdatedAt)
+	})
+	if len(ranked) > maxQACandidates {
+		ranked = ranked[:maxQACandidates]
+	}
+
+	notes := make([]models.NoteResponse, len(ranked))
+	for i, candidate := range ranked {
+		notes[i] = candidate.note
+	}
+
+	sources, prompt := buildQAPrompt(question, notes, s.tokenizer, s.contextTokens)
+	return &QAContext{Question: question, Sources: sources, prompt: prompt, userID: userID}, nil
+}
+
+// StreamAnswer generates the answer, passing each chunk to onChunk as it
+// arrives, and returns the complete answer with the sources it cites
+func (s *QAService) StreamAnswer(ctx context.Context, qa *QAContext, onChunk func(chunk string) error) (*models.QAAnswer, error) {
+	answer := &models.QAAnswer{
+		Sources:   qa.Sources,
+		Citations: []models.QASource{},
+	}
+
+	if len(qa.Sources) == 0 {
+		answer.Answer = noNotesAnswer
+		if err := onChunk(noNotesAnswer); err != nil {
+			return nil, err
+		}
+		return answer, nil
+	}
+
+	var text strings.Builder
+	err := s.llm.Stream(llm.WithCaller(ctx, qa.userID, LLMFeatureQA), qa.prompt, func(ctx context.Context, chunk []byte) error {
+		text.Write(chunk)
+		return onChunk(string(chunk))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+
+	answer.Answer = text.String()
+	answer.Citations = citedSources(answer.Answer, qa.Sources)
+	return answer, nil
+}
+
+// questionStopWords are common words that make poor search keywords
+var questionStopWords = map[string]bool{
+	"about": true, "all": true, "and": true, "any": true, "are": true, "can": true, "did": true,
+	"does": true, "for": true, "from": true, "had": true, "has": true, "have": true, "how": true,
+	"into": true, "many": true, "much": true, "not": true, "notes": true, "that": true, "the": true,
+	"their": true, "there": true, "these": true, "this": true, "was": true, "were": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true, "will": true, "with": true,
+	"you": true, "your": true, "note": true, "tell": true,
+}
+
+var (
+	questionTagPattern  = regexp.MustCompile(`#\w+`)
+	questionWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	citationPattern     = regexp.MustCompile(`\[(\d+)\]`)
+)
+
+// questionTerms extracts search keywords and hashtags from a question
+func questionTerms(question string) ([]string, []string) {
+	var tags []string
+	seenTags := make(map[string]bool)
+	for _, tag := range questionTagPattern.FindAllString(question, -1) {
+		tag = strings.ToLower(tag)
+		if !seenTags[tag] {
+			seenTags[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	var keywords []string
+	seen := make(map[string]bool)
+	withoutTags := questionTagPattern.ReplaceAllString(question, " ")
+	for _, word := range questionWordPattern.FindAllString(withoutTags, -1) {
+		word = strings.ToLower(word)
+		if len([]rune(word)) < 3 || questionStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+		if len(keywords) == maxQuestionKeywords {
+			break
+		}
+	}
+
+	return keywords, tags
+}
+
+// buildQAPrompt numbers the notes as sources and adds them to the prompt in
+// order while they fit the token budget
+func buildQAPrompt(question string, notes []models.NoteResponse, tokenizer TokenCounter, contextTokens int) ([]models.QASource, string) {
+	header := `You answer questions using only the user's notes below. Each note is numbered like [1].
+
+RULES:
+1. Answer in the language of the question
+2. Use only information from the notes; if they do not contain the answer, say so
+3. Cite the notes you use with their number in square brackets, e.g. [1] or [2][3]
+
+NOTES:
+`
+	footer := "\nQUESTION: " + question + "\n\nANSWER:"
+
+	budget := contextTokens - tokenizer.CountTokens(header) - tokenizer.CountTokens(footer)
+	sources := []models.QASource{}
+
+	var prompt strings.Builder
+	prompt.WriteString(header)
+	for _, note := range notes {
+		title := ""
+		if note.Title != nil {
+			title = strings.TrimSpace(*note.Title)
+		}
+
+		index := len(sources) + 1
+		entry := fmt.Sprintf("\n[%d] Title: %s\n%s\n", index, title, strings.TrimSpace(note.Content))
+		tokens := tokenizer.CountTokens(entry)
+		if tokens > budget {
+			// A smaller note further down may still fit
+			continue
+		}
+		budget -= tokens
+
+		prompt.WriteString(entry)
+		sources = append(sources, models.QASource{Index: index, NoteID: note.ID, Title: title})
+	}
+	prompt.WriteString(footer)
+
+	return sources, prompt.String()
+}
+
+// citedSources returns the sources cited in an answer, in citation order
+func citedSources(answer string, sources []models.QASource) []models.QASource {
+	cited := []models.QASource{}
+	seen := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		index, err := strconv.Atoi(match[1])
+		if err != nil || index < 1 || index > len(sources) || seen[index] {
+			continue
+		}
+		seen[index] = true
+		cited = append(cited, sources[index-1])
+	}
+	return cited
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// wordCounter counts whitespace-separated words as tokens
+type wordCounter struct{}
+
+func (wordCounter) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestQuestionTerms(t *testing.T) {
+	keywords, tags := questionTerms("What did I write about the Berlin trip? #Travel #travel #work")
+
+	if want := []string{"write", "berlin", "trip"}; !reflect.DeepEqual(keywords, want) {
+		t.Errorf("Expected keywords %v, got %v", want, keywords)
+	}
+	if want := []string{"#travel", "#work"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, tags)
+	}
+
+	keywords, _ = questionTerms("one two three four five six seven eight nine ten eleven twelve")
+	if len(keywords) != maxQuestionKeywords {
+		t.Errorf("Expected %d keywords, got %d", maxQuestionKeywords, len(keywords))
+	}
+}
+
+func TestBuildQAPrompt(t *testing.T) {
+	title := "Trip"
+	notes := []models.NoteResponse{
+		{ID: uuid.New(), Title: &title, Content: "Flight to Berlin on Monday"},
+		{ID: uuid.New(), Content: strings.Repeat("word ", 500)},
+		{ID: uuid.New(), Content: "Hotel near the station"},
+	}
+
+	sources, prompt := buildQAPrompt("When is the flight?", notes, wordCounter{}, 200)
+
+	if len(sources) != 2 {
+		t.Fatalf("Expected the oversized note to be skipped, got %d sources", len(sources))
+	}
+	if sources[0].NoteID != notes[0].ID || sources[0].Index != 1 || sources[0].Title != "Trip" {
+		t.Errorf("Unexpected first source %+v", sources[0])
+	}
+	if sources[1].NoteID != notes[2].ID || sources[1].Index != 2 {
+		t.Errorf("Unexpected second source %+v", sources[1])
+	}
+	if !strings.Contains(prompt, "[2] Title: \nHotel near the station") {
+		t.Errorf("Expected the third note to be numbered [2], got prompt:\n%s", prompt)
+	}
+	if !strings.HasSuffix(prompt, "QUESTION: When is the flight?\n\nANSWER:") {
+		t.Errorf("Expected the prompt to end with the question, got:\n%s", prompt)
+	}
+}
+
+func TestCitedSources(t *testing.T) {
+	sources := []models.QASource{
+		{Index: 1, NoteID: uuid.New()},
+		{Index: 2, NoteID: uuid.New()},
+	}
+
+	cited := citedSources("The flight is on Monday [2][1], see also [2] and [7].", sources)
+	if len(cited) != 2 || cited[0].Index != 2 || cited[1].Index != 1 {
+		t.Errorf("Expected citations [2 1], got %+v", cited)
+	}
+
+	if cited := citedSources("No citations here.", sources); len(cited) != 0 {
+		t.Errorf("Expected no citations, got %+v", cited)
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// RecurrenceServiceInterface defines the interface for recurring notes
+type RecurrenceServiceInterface interface {
+	CreateRecurrence(ctx context.Context, userID string, request *models.CreateRecurrenceRequest) (*models.Recurrence, error)
+	ListRecurrences(ctx context.Context, userID string) ([]models.Recurrence, error)
+	SetRecurrencePaused(ctx context.Context, userID, recurrenceID string, paused bool) (*models.Recurrence, error)
+	DeleteRecurrence(ctx context.Context, userID, recurrenceID string) error
+}
+
+// ErrRecurrenceNotFound is returned for recurrences that do not exist
+var ErrRecurrenceNotFound = apperrors.NotFound("RECURRENCE_NOT_FOUND", "recurrence not found")
+
+// RecurrenceService schedules notes created from templates, such as a daily
+// journal. Deleting the template deletes its recurrences.
+type RecurrenceService struct {
+	db              *sql.DB
+	templateService TemplateServiceInterface
+}
+
+// NewRecurrenceService creates a new RecurrenceService
+func NewRecurrenceService(db *sql.DB, templateService TemplateServiceInterface) *RecurrenceService {
+	return &RecurrenceService{
+		db:              db,
+		templateService: templateService,
+	}
+}
+
+// recurrenceColumns lists the recurrences columns in scanRecurrence order
+const recurrenceColumns = "id, user_id, template_id, frequency, weekday, month_day, time_of_day, time_zone, paused, next_run_at, last_run_at, last_note_id, created_at, updated_at"
+
+// scanRecurrence scans a row selected with recurrenceColumns
+func scanRecurrence(row rowScanner, r *models.Recurrence) error {
+	return row.Scan(&r.ID, &r.UserID, &r.TemplateID, &r.Frequency, &r.Weekday, &r.MonthDay, &r.TimeOfDay,
+		&r.TimeZone, &r.Paused, &r.NextRunAt, &r.LastRunAt, &r.LastNoteID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// CreateRecurrence schedules one of the user's templates
+func (s *RecurrenceService) CreateRecurrence(ctx context.Context, userID string, request *models.CreateRecurrenceRequest) (*models.Recurrence, error) {
+	recurrence, err := request.ToRecurrence(uuid.MustParse(userID))
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_RECURRENCE", err)
+	}
+	if _, err := s.templateService.GetTemplate(ctx, userID, request.TemplateID); err != nil {
+		return nil, err
+	}
+	if recurrence.NextRunAt, err = recurrence.Next(time.Now()); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_RECURRENCE", err)
+	}
+
+	err = scanRecurrence(s.db.QueryRowContext(ctx, `
+		INSERT INTO recurrences (id, user_id, template_id, frequency, weekday, month_day, time_of_day, time_zone, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+recurrenceColumns,
+		uuid.New(), recurrence.UserID, recurrence.TemplateID, recurrence.Frequency, recurrence.Weekday,
+		recurrence.MonthDay, recurrence.TimeOfDay, recurrence.TimeZone, recurrence.NextRunAt), recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recurrence: %w", err)
+	}
+
+	return recurrence, nil
+}
+
+// ListRecurrences returns a user's recurrences, next due first
+func (s *RecurrenceService) ListRecurrences(ctx context.Context, userID string) ([]models.Recurrence, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+recurrenceColumns+`
+		FROM recurrences
+		WHERE user_id = $1
+		ORDER BY paused, next_run_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurrences: %w", err)
+	}
+	defer rows.Close()
+
+	recurrences := []models.Recurrence{}
+	for rows.Next() {
+		var recurrence models.Recurrence
+		if err := scanRecurrence(rows, &recurrence); err != nil {
+			return nil, fmt.Errorf("failed to scan recurrence: %w", err)
+		}
+		recurrences = append(recurrences, recurrence)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recurrences: %w", err)
+	}
+
+	return recurrences, nil
+}
+
+// SetRecurrencePaused pauses or resumes a recurrence. Resuming schedules the
+// next run from now, so runs missed while paused are skipped.
+func (s *RecurrenceService) SetRecurrencePaused(ctx context.Context, userID, recurrenceID string, paused bool) (*models.Recurrence, error) {
+	if _, err := uuid.Parse(recurrenceID); err != nil {
+		return nil, ErrRecurrenceNotFound
+	}
+
+	var recurrence models.Recurrence
+	err := scanRecurrence(s.db.QueryRowContext(ctx, `
+		SELECT `+recurrenceColumns+`
+		FROM recurrences
+		WHERE id = $1 AND user_id = $2
+	`, recurrenceID, userID), &recurrence)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecurrenceNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get recurrence: %w", err)
+	}
+
+	nextRunAt := recurrence.NextRunAt
+	if recurrence.Paused && !paused {
+		if nextRunAt, err = recurrence.Next(time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to schedule recurrence: %w", err)
+		}
+	}
+
+	err = scanRecurrence(s.db.QueryRowContext(ctx, `
+		UPDATE recurrences
+		SET paused = $1, next_run_at = $2, updated_at = NOW()
+		WHERE id = $3 AND user_id = $4
+		RETURNING `+recurrenceColumns,
+		paused, nextRunAt, recurrenceID, userID), &recurrence)
+	if err == sql.ErrNoRows {
+		return nil, ErrRecurrenceNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update recurrence: %w", err)
+	}
+
+	return &recurrence, nil
+}
+
+// DeleteRecurrence deletes a recurrence. Notes it created are kept.
+func (s *RecurrenceService) DeleteRecurrence(ctx context.Context, userID, recurrenceID string) error {
+	if _, err := uuid.Parse(recurrenceID); err != nil {
+		return ErrRecurrenceNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM recurrences WHERE id = $1 AND user_id = $2
+	`, recurrenceID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recurrence: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrRecurrenceNotFound
+	}
+
+	return nil
+}
+
+// RunDueRecurrences creates the notes of recurrences due at now and returns
+// how many were created. Each recurrence is claimed by moving its next run
+// forward before its note is created, so concurrent runners never create a
+// note twice; a run that fails is skipped rather than retried. Recurrences
+// overdue by several runs create a single note.
+func (s *RecurrenceService) RunDueRecurrences(ctx context.Context, now time.Time) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+recurrenceColumns+`
+		FROM recurrences
+		WHERE NOT paused AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT 500
+	`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due recurrences: %w", err)
+	}
+	var due []models.Recurrence
+	for rows.Next() {
+		var recurrence models.Recurrence
+		if err := scanRecurrence(rows, &recurrence); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan recurrence: %w", err)
+		}
+		due = append(due, recurrence)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating due recurrences: %w", err)
+	}
+
+	created := 0
+	for _, recurrence := range due {
+		ok, err := s.run(ctx, &recurrence, now)
+		if err != nil {
+			log.Printf("WARNING: recurrence %s failed: %v", recurrence.ID, err)
+			continue
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// run claims a due recurrence and creates its note. It returns false when
+// another runner claimed the recurrence first.
+func (s *RecurrenceService) run(ctx context.Context, recurrence *models.Recurrence, now time.Time) (bool, error) {
+	nextRunAt, err := recurrence.Next(now)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE recurrences SET next_run_at = $1, last_run_at = $2
+		WHERE id = $3 AND next_run_at = $4 AND NOT paused
+	`, nextRunAt, now, recurrence.ID, recurrence.NextRunAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim recurrence: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	} else if rowsAffected == 0 {
+		return false, nil
+	}
+
+	note, err := s.templateService.CreateNoteFromTemplate(ctx, recurrence.UserID.String(), recurrence.TemplateID.String(),
+		&models.CreateNoteFromTemplateRequest{
+			ApplyTemplateRequest: models.ApplyTemplateRequest{TimeZone: recurrence.TimeZone},
+		})
+	if err != nil {
+		return false, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE recurrences SET last_note_id = $1 WHERE id = $2
+	`, note.ID, recurrence.ID); err != nil {
+		return true, fmt.Errorf("failed to record recurrence note: %w", err)
+	}
+	return true, nil
+}
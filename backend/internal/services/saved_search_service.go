@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SavedSearchServiceInterface defines the interface for saved search operations
+type SavedSearchServiceInterface interface {
+	CreateSavedSearch(ctx context.Context, userID string, request *models.CreateSavedSearchRequest) (*models.SavedSearch, error)
+	ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error)
+	GetSavedSearch(ctx context.Context, userID, savedSearchID string) (*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, userID, savedSearchID string) error
+	ExecuteSavedSearch(ctx context.Context, userID, savedSearchID string, limit, offset int) (*models.SavedSearch, *models.NoteList, error)
+}
+
+// ErrSavedSearchNotFound is returned for saved searches that do not exist
+var ErrSavedSearchNotFound = apperrors.NotFound("SAVED_SEARCH_NOT_FOUND", "saved search not found")
+
+// SavedSearchService stores per-user searches and runs them through NoteService
+type SavedSearchService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+}
+
+// NewSavedSearchService creates a new SavedSearchService
+func NewSavedSearchService(db *sql.DB, noteService NoteServiceInterface) *SavedSearchService {
+	return &SavedSearchService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// savedSearchColumns lists the saved_searches columns in scanSavedSearch order
+const savedSearchColumns = "id, user_id, name, query, tags, order_by, order_dir, created_at, updated_at"
+
+// scanSavedSearch scans a row selected with savedSearchColumns
+func scanSavedSearch(row rowScanner, s *models.SavedSearch) error {
+	return row.Scan(&s.ID, &s.UserID, &s.Name, &s.Query, pq.Array(&s.Tags),
+		&s.OrderBy, &s.OrderDir, &s.CreatedAt, &s.UpdatedAt)
+}
+
+// CreateSavedSearch saves a search for a user
+func (s *SavedSearchService) CreateSavedSearch(ctx context.Context, userID string, request *models.CreateSavedSearchRequest) (*models.SavedSearch, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_SAVED_SEARCH", err)
+	}
+
+	var saved models.SavedSearch
+	query := `
+		INSERT INTO saved_searches (id, user_id, name, query, tags, order_by, order_dir)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + savedSearchColumns
+
+	err := scanSavedSearch(s.db.QueryRowContext(ctx, query,
+		uuid.New(), userID, request.Name, request.Query, pq.Array(request.Tags),
+		request.OrderBy, request.OrderDir), &saved)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.Conflict("SAVED_SEARCH_EXISTS", "saved search with this name already exists")
+		}
+		return nil, fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// ListSavedSearches returns a user's saved searches ordered by name
+func (s *SavedSearchService) ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+savedSearchColumns+`
+		FROM saved_searches
+		WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		var saved models.SavedSearch
+		if err := scanSavedSearch(rows, &saved); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, saved)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved searches: %w", err)
+	}
+
+	return searches, nil
+}
+
+// GetSavedSearch retrieves a saved search by ID for a specific user
+func (s *SavedSearchService) GetSavedSearch(ctx context.Context, userID, savedSearchID string) (*models.SavedSearch, error) {
+	var saved models.SavedSearch
+	err := scanSavedSearch(s.db.QueryRowContext(ctx, `
+		SELECT `+savedSearchColumns+`
+		FROM saved_searches
+		WHERE id = $1 AND user_id = $2
+	`, savedSearchID, userID), &saved)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSavedSearchNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// DeleteSavedSearch deletes a saved search and any subscriptions following it
+func (s *SavedSearchService) DeleteSavedSearch(ctx context.Context, userID, savedSearchID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM saved_searches WHERE id = $1 AND user_id = $2
+	`, savedSearchID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrSavedSearchNotFound
+	}
+
+	return nil
+}
+
+// ExecuteSavedSearch runs a saved search with its stored query, tags and sort order
+func (s *SavedSearchService) ExecuteSavedSearch(ctx context.Context, userID, savedSearchID string, limit, offset int) (*models.SavedSearch, *models.NoteList, error) {
+	saved, err := s.GetSavedSearch(ctx, userID, savedSearchID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	noteList, err := s.noteService.SearchNotes(ctx, userID, saved.ToSearchRequest(limit, offset))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute saved search: %w", err)
+	}
+
+	return saved, noteList, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
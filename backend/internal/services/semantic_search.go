@@ -44,8 +44,14 @@ func (s *SemanticSearchService) Search(ctx context.Context, userID, query string
 
 	startTime := time.Now()
 
+	// Every cluster is sent to the LLM, so check the budget once up front
+	ctx = llm.WithCaller(ctx, userID, LLMFeatureSemanticSearch)
+	if err := s.llm.CheckBudget(ctx); err != nil {
+		return nil, 0, err
+	}
+
 	// 1. Fetch all user notes (use high limit to get all)
-	noteList, err := s.noteService.ListNotes(userID, 10000, 0, "created_at", "desc")
+	noteList, err := s.noteService.ListNotes(ctx, userID, 10000, 0, "created_at", "desc")
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to fetch notes: %w", err)
 	}
@@ -92,7 +98,7 @@ func (s *SemanticSearchService) Search(ctx context.Context, userID, query string
 			continue
 		}
 		// Use GetNoteByID to get individual notes
-		note, err := s.noteService.GetNoteByID(userID, noteID.String())
+		note, err := s.noteService.GetNoteByID(ctx, userID, noteID.String())
 		if err == nil && note != nil {
 			resultNotes = append(resultNotes, *note)
 		}
@@ -107,6 +113,10 @@ func (s *SemanticSearchService) Search(ctx context.Context, userID, query string
 func (s *SemanticSearchService) convertNotesResponseToNotes(noteResponses []models.NoteResponse, userID string) []models.Note {
 	notes := make([]models.Note, 0, len(noteResponses))
 	for _, resp := range noteResponses {
+		// Private notes are never sent to the LLM
+		if resp.IsPrivate {
+			continue
+		}
 		note := s.convertNoteResponseToNote(&resp, userID)
 		notes = append(notes, *note)
 	}
@@ -123,6 +133,8 @@ func (s *SemanticSearchService) convertNoteResponseToNote(resp *models.NoteRespo
 		CreatedAt: resp.CreatedAt,
 		UpdatedAt: resp.UpdatedAt,
 		Version:   resp.Version,
+		Language:  resp.Language,
+		IsPrivate: resp.IsPrivate,
 	}
 }
 
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// Reasons recorded when a session ends
+const (
+	SessionRevokedLogout       = "logout"
+	SessionRevokedByUser       = "revoked"
+	SessionRevokedRefreshReuse = "refresh_token_reuse"
+	SessionRevokedDisabled     = "account_disabled"
+)
+
+var (
+	// ErrSessionNotFound is returned for sessions that do not exist or have ended
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrRefreshTokenReused is returned when a refresh token that was already
+	// rotated is presented again. The session is revoked, since either the
+	// client or an attacker holds a stolen token.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// SessionServiceInterface defines the interface for session management and
+// refresh token rotation
+type SessionServiceInterface interface {
+	ListSessions(ctx context.Context, userID string) ([]models.UserSession, error)
+	RevokeSession(ctx context.Context, userID, sessionID, reason string) error
+	IssueRefreshToken(ctx context.Context, sessionID, tokenID, ipAddress, userAgent string) error
+	RotateRefreshToken(ctx context.Context, userID, sessionID, presentedID, nextID string) error
+}
+
+// SessionService manages sign-in sessions. Each session accepts a single
+// refresh token at a time: refreshing replaces it, and presenting a replaced
+// token revokes the whole session.
+type SessionService struct {
+	db *sql.DB
+}
+
+// NewSessionService creates a new SessionService
+func NewSessionService(db *sql.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *SessionService) ListSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, ip_address, user_agent, device_name, created_at, last_seen, is_active
+		FROM user_sessions
+		WHERE user_id = $1 AND is_active = true
+		ORDER BY last_seen DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.UserSession{}
+	for rows.Next() {
+		var session models.UserSession
+		err := rows.Scan(&session.ID, &session.UserID, &session.IPAddress, &session.UserAgent,
+			&session.DeviceName, &session.CreatedAt, &session.LastSeen, &session.IsActive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions. Access tokens of the session
+// stop working on their next request and its refresh token is no longer accepted.
+func (s *SessionService) RevokeSession(ctx context.Context, userID, sessionID, reason string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET is_active = false, revoked_at = NOW(), revoked_reason = $1
+		WHERE id = $2 AND user_id = $3 AND is_active = true
+	`, reason, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// IssueRefreshToken records the refresh token issued when signing in, along
+// with the device it was issued to
+func (s *SessionService) IssueRefreshToken(ctx context.Context, sessionID, tokenID, ipAddress, userAgent string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET refresh_token_id = $1, refreshed_at = NOW(), ip_address = $2, user_agent = $3, device_name = $4
+		WHERE id = $5 AND is_active = true
+	`, tokenID, ipAddress, userAgent, describeDevice(userAgent), sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to record refresh token: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RotateRefreshToken replaces the session's refresh token presentedID with
+// nextID. Sessions created before rotation have no recorded token and accept
+// any refresh token of the session once. If presentedID was already replaced,
+// the session is revoked and ErrRefreshTokenReused is returned; clients must
+// therefore not refresh concurrently with the same token.
+func (s *SessionService) RotateRefreshToken(ctx context.Context, userID, sessionID, presentedID, nextID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET refresh_token_id = $1, refreshed_at = NOW()
+		WHERE id = $2 AND user_id = $3 AND is_active = true
+		  AND (refresh_token_id = $4 OR refresh_token_id IS NULL)
+	`, nextID, sessionID, userID, presentedID)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil
+	}
+
+	// The token was not accepted: either the session has ended or the token
+	// was replaced by an earlier refresh
+	result, err = s.db.ExecContext(ctx, `
+		UPDATE user_sessions
+		SET is_active = false, revoked_at = NOW(), revoked_reason = $1
+		WHERE id = $2 AND user_id = $3 AND is_active = true
+	`, SessionRevokedRefreshReuse, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return ErrRefreshTokenReused
+	}
+	return ErrSessionNotFound
+}
+
+// describeDevice summarises a user agent as "<browser> on <OS>"
+func describeDevice(userAgent string) string {
+	browser := ""
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+
+	// Unrecognised clients such as API tools are shown by their product name
+	product, _, _ := strings.Cut(userAgent, " ")
+	product, _, _ = strings.Cut(product, "/")
+	if len(product) > 100 {
+		product = product[:100]
+	}
+	return product
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDescribeDevice(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on macOS"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"curl/8.4.0", "curl"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := describeDevice(tt.userAgent); got != tt.want {
+			t.Errorf("describeDevice(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestSessionServiceRejectsInvalidSessionIDs(t *testing.T) {
+	// Session IDs from tokens issued without a stored session are not UUIDs
+	// and must be rejected before reaching the database
+	service := NewSessionService(nil)
+	ctx := context.Background()
+
+	if err := service.RotateRefreshToken(ctx, "user", "chrome-session-user", "a", "b"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("RotateRefreshToken: expected ErrSessionNotFound, got %v", err)
+	}
+	if err := service.RevokeSession(ctx, "user", "not-a-uuid", SessionRevokedByUser); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("RevokeSession: expected ErrSessionNotFound, got %v", err)
+	}
+}
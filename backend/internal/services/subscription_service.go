@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/search"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SubscriptionServiceInterface defines the interface for search subscription operations
+type SubscriptionServiceInterface interface {
+	CreateSubscription(ctx context.Context, userID string, request *models.CreateSearchSubscriptionRequest) (*models.SearchSubscription, error)
+	ListSubscriptions(ctx context.Context, userID string) ([]models.SearchSubscription, error)
+	DeleteSubscription(ctx context.Context, userID, subscriptionID string) error
+}
+
+// SubscriptionService notifies users when notes start matching their subscribed searches.
+// Matching is incremental: each written note is checked in memory against the
+// owner's subscriptions instead of re-running every search.
+type SubscriptionService struct {
+	db                  *sql.DB
+	noteService         NoteServiceInterface
+	notificationService NotificationServiceInterface
+	savedSearchService  SavedSearchServiceInterface
+}
+
+// NewSubscriptionService creates a new SubscriptionService
+func NewSubscriptionService(db *sql.DB, noteService NoteServiceInterface, notificationService NotificationServiceInterface, savedSearchService SavedSearchServiceInterface) *SubscriptionService {
+	return &SubscriptionService{
+		db:                  db,
+		noteService:         noteService,
+		notificationService: notificationService,
+		savedSearchService:  savedSearchService,
+	}
+}
+
+// CreateSubscription subscribes a user to a search. Notes that already match
+// are recorded so that only notes matching from now on trigger notifications.
+func (s *SubscriptionService) CreateSubscription(ctx context.Context, userID string, request *models.CreateSearchSubscriptionRequest) (*models.SearchSubscription, error) {
+	// Following a saved search subscribes to its query and tag filters
+	var savedSearchID *uuid.UUID
+	if request.SavedSearchID != nil {
+		saved, err := s.savedSearchService.GetSavedSearch(ctx, userID, *request.SavedSearchID)
+		if err != nil {
+			return nil, err
+		}
+		if request.Name == "" {
+			request.Name = saved.Name
+		}
+		request.Query = saved.CombinedQuery()
+		savedSearchID = &saved.ID
+	}
+
+	if _, err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_SUBSCRIPTION", err)
+	}
+
+	subscription := &models.SearchSubscription{
+		UserID:        uuid.MustParse(userID),
+		Name:          request.Name,
+		Query:         request.Query,
+		SavedSearchID: savedSearchID,
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO search_subscriptions (user_id, name, query, saved_search_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, userID, request.Name, request.Query, savedSearchID).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	if err := s.seedMatches(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// ListSubscriptions returns a user's subscriptions. Subscriptions following a
+// saved search report the saved search's current query and tags.
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context, userID string) ([]models.SearchSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sub.id, sub.user_id, sub.name, sub.query, sub.saved_search_id,
+			ss.query, ss.tags, sub.created_at, sub.updated_at
+		FROM search_subscriptions sub
+		LEFT JOIN saved_searches ss ON ss.id = sub.saved_search_id
+		WHERE sub.user_id = $1
+		ORDER BY sub.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.SearchSubscription{}
+	for rows.Next() {
+		var sub models.SearchSubscription
+		var savedQuery sql.NullString
+		var savedTags []string
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Name, &sub.Query, &sub.SavedSearchID,
+			&savedQuery, pq.Array(&savedTags), &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		if savedQuery.Valid {
+			saved := models.SavedSearch{Query: savedQuery.String, Tags: savedTags}
+			sub.Query = saved.CombinedQuery()
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes a subscription and its recorded matches
+func (s *SubscriptionService) DeleteSubscription(ctx context.Context, userID, subscriptionID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM search_subscriptions WHERE id = $1 AND user_id = $2
+	`, subscriptionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.NotFound("SUBSCRIPTION_NOT_FOUND", "subscription not found")
+	}
+
+	return nil
+}
+
+// NoteWritten implements NoteWriteListener. It checks the note against the
+// owner's subscriptions, notifies on new matches and forgets notes that no
+// longer match so they can notify again later.
+func (s *SubscriptionService) NoteWritten(ctx context.Context, note *models.Note) {
+	subscriptions, err := s.ListSubscriptions(ctx, note.UserID.String())
+	if err != nil {
+		log.Printf("[SubscriptionService] WARNING: failed to load subscriptions for user %s: %v", note.UserID, err)
+		return
+	}
+
+	title := ""
+	if note.Title != nil {
+		title = *note.Title
+	}
+	doc := search.Document{
+		Title:     title,
+		Content:   note.Content,
+		Tags:      note.ExtractHashtags(),
+		Color:     note.Color,
+		CreatedAt: note.CreatedAt,
+	}
+	if note.Metadata != nil {
+		doc.Source = note.Metadata.Source
+	}
+
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		query, err := search.Parse(sub.Query)
+		if err != nil {
+			log.Printf("[SubscriptionService] WARNING: subscription %s has invalid query: %v", sub.ID, err)
+			continue
+		}
+
+		if !query.Matches(doc) {
+			if _, err := s.db.ExecContext(ctx, `
+				DELETE FROM search_subscription_matches WHERE subscription_id = $1 AND note_id = $2
+			`, sub.ID, note.ID); err != nil {
+				log.Printf("[SubscriptionService] WARNING: failed to clear match for subscription %s: %v", sub.ID, err)
+			}
+			continue
+		}
+
+		isNew, err := s.recordMatch(ctx, sub.ID, note.ID)
+		if err != nil {
+			log.Printf("[SubscriptionService] WARNING: failed to record match for subscription %s: %v", sub.ID, err)
+			continue
+		}
+		if isNew {
+			s.notifyMatch(ctx, sub, note, title)
+		}
+	}
+}
+
+// recordMatch stores a subscription match and reports whether it is new
+func (s *SubscriptionService) recordMatch(ctx context.Context, subscriptionID, noteID uuid.UUID) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO search_subscription_matches (subscription_id, note_id)
+		VALUES ($1, $2)
+		ON CONFLICT (subscription_id, note_id) DO NOTHING
+	`, subscriptionID, noteID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// notifyMatch sends the notification for a note newly matching a subscription
+func (s *SubscriptionService) notifyMatch(ctx context.Context, sub *models.SearchSubscription, note *models.Note, noteTitle string) {
+	if noteTitle == "" {
+		noteTitle = "Untitled note"
+	}
+	data := map[string]string{
+		"subscription_id": sub.ID.String(),
+		"note_id":         note.ID.String(),
+		"query":           sub.Query,
+	}
+
+	_, err := s.notificationService.Notify(ctx, note.UserID, models.NotificationTypeSearchMatch,
+		fmt.Sprintf("New note matches \"%s\"", sub.Name), noteTitle, data)
+	if err != nil {
+		log.Printf("[SubscriptionService] WARNING: failed to notify match for subscription %s: %v", sub.ID, err)
+	}
+}
+
+// seedMatches records the notes that already match a new subscription
+func (s *SubscriptionService) seedMatches(ctx context.Context, sub *models.SearchSubscription) error {
+	// Only the user's own notes are checked as they are written
+	request := &models.SearchNotesRequest{Query: sub.Query, Limit: 100, OwnedOnly: true}
+	for {
+		noteList, err := s.noteService.SearchNotes(ctx, sub.UserID.String(), request)
+		if err != nil {
+			return fmt.Errorf("failed to find notes matching subscription: %w", err)
+		}
+
+		for _, note := range noteList.Notes {
+			if _, err := s.recordMatch(ctx, sub.ID, note.ID); err != nil {
+				return fmt.Errorf("failed to record existing match: %w", err)
+			}
+		}
+
+		if !noteList.HasMore {
+			return nil
+		}
+		request.Offset += request.Limit
+	}
+}
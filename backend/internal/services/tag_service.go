@@ -3,100 +3,122 @@ package services
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/google/uuid"
 )
 
+// QueryExecer is implemented by *sql.DB and *sql.Tx, so tags can be written
+// in the transaction that writes their note
+type QueryExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // TagServiceInterface defines the interface for tag service operations
 type TagServiceInterface interface {
-	CreateTag(request *models.CreateTagRequest) (*models.Tag, error)
-	GetTagByID(tagID string) (*models.Tag, error)
-	GetTagByName(tagName string) (*models.Tag, error)
-	GetAllTags(userID string, limit int, offset int) (*models.TagList, error)
+	CreateTag(ctx context.Context, userID string, request *models.CreateTagRequest) (*models.Tag, error)
+	GetTagByID(ctx context.Context, userID, tagID string) (*models.Tag, error)
+	GetTagByName(ctx context.Context, userID, tagName string) (*models.Tag, error)
+	GetAllTags(ctx context.Context, userID string, limit int, offset int) (*models.TagList, error)
+	GetTagTree(ctx context.Context, userID, tagName string) ([]models.TagTreeNode, error)
+	GetTagGraph(ctx context.Context, userID string, limit int) (*models.TagGraph, error)
+	GetTrendingTags(ctx context.Context, userID, window string, limit int) (*models.TrendingTags, error)
 	ExtractTagsFromContent(content string) []string
-	ProcessTagsForNote(noteID string, tags []string) error
-	UpdateTagsForNote(noteID string, tags []string) error
+	ProcessTagsForNote(ctx context.Context, userID, noteID string, tags []string) error
+	UpdateTagsForNote(ctx context.Context, userID, noteID string, tags []string) error
+	// AddNoteTags, ReplaceNoteTags and DeleteNoteTags write the tags of a
+	// note in q, the transaction that writes the note
+	AddNoteTags(ctx context.Context, q QueryExecer, userID, noteID string, tags []string) error
+	ReplaceNoteTags(ctx context.Context, q QueryExecer, userID, noteID string, tags []string) error
+	DeleteNoteTags(ctx context.Context, q QueryExecer, noteID string) error
+	// RenameTag renames a tag in q, the transaction that rewrites the notes
+	// carrying it
+	RenameTag(ctx context.Context, q QueryExecer, userID string, tag *models.Tag, name string) (*models.Tag, error)
 	ValidateTagNames(tagNames []string) error
+	SuggestTagsForNote(ctx context.Context, userID, noteID string) ([]models.TagSuggestion, error)
+}
+
+// TextGenerator generates text from a single prompt, e.g. an LLM client
+type TextGenerator interface {
+	GenerateFromSinglePrompt(ctx context.Context, prompt string) (string, error)
 }
 
+// ErrTagNotFound is returned for tags that do not exist
+var ErrTagNotFound = apperrors.NotFound("TAG_NOT_FOUND", "tag not found")
+
 // TagService handles tag-related operations
 type TagService struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect database.Dialect
+	llm     TextGenerator
+	prompts *prompts.Registry
 }
 
 // NewTagService creates a new TagService instance
 func NewTagService(db *sql.DB) *TagService {
 	return &TagService{
-		db: db,
+		db:      db,
+		dialect: database.DialectOf(db),
 	}
 }
 
-// CreateTag creates a new tag with deduplication
-func (s *TagService) CreateTag(request *models.CreateTagRequest) (*models.Tag, error) {
-	ctx := context.Background()
+// SetLLM enables LLM tag suggestions
+func (s *TagService) SetLLM(llm TextGenerator) {
+	s.llm = llm
+}
+
+// SetPrompts sets the registry the tag suggestion prompt is rendered from.
+// The built-in prompt is used otherwise.
+func (s *TagService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// CreateTag creates a new tag of the user with deduplication
+func (s *TagService) CreateTag(ctx context.Context, userID string, request *models.CreateTagRequest) (*models.Tag, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
 
 	// Convert request to tag model
 	tag := request.ToTag()
+	tag.UserID = userUUID
 
 	// Validate tag
 	if err := tag.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid tag: %w", err)
 	}
 
-	// Check if tag already exists (case-insensitive)
-	var existingTag models.Tag
-	err := s.db.QueryRowContext(ctx,
-		"SELECT id, name, created_at FROM tags WHERE LOWER(name) = LOWER($1)",
-		tag.Name).Scan(&existingTag.ID, &existingTag.Name, &existingTag.CreatedAt)
-
-	if err == nil {
-		// Tag already exists, return existing tag
-		return &existingTag, nil
-	}
-
-	if err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to check for existing tag: %w", err)
-	}
-
-	// Create new tag
-	tag.ID = uuid.New()
-	query := `
-		INSERT INTO tags (id, name, created_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, name, created_at
-	`
-
-	err = s.db.QueryRowContext(ctx, query,
-		tag.ID, tag.Name, tag.CreatedAt).Scan(
-		&tag.ID, &tag.Name, &tag.CreatedAt)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create tag: %w", err)
-	}
-
-	return tag, nil
+	// Existing tags are returned as they are, whatever their case
+	return upsertTag(ctx, s.db, userID, tag.Name)
 }
 
-// GetTagByID retrieves a tag by ID
-func (s *TagService) GetTagByID(tagID string) (*models.Tag, error) {
-	ctx := context.Background()
-
+// GetTagByID retrieves a tag of the user by ID
+func (s *TagService) GetTagByID(ctx context.Context, userID, tagID string) (*models.Tag, error) {
 	var tag models.Tag
 	query := `
-		SELECT id, name, created_at
+		SELECT id, user_id, parent_id, name, created_at
 		FROM tags
-		WHERE id = $1
+		WHERE id = $1 AND user_id = $2
 	`
 
-	err := s.db.QueryRowContext(ctx, query, tagID).Scan(
-		&tag.ID, &tag.Name, &tag.CreatedAt)
+	err := s.db.QueryRowContext(ctx, query, tagID, userID).Scan(
+		&tag.ID, &tag.UserID, &tag.ParentID, &tag.Name, &tag.CreatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("tag not found")
+			return nil, ErrTagNotFound
 		}
 		return nil, fmt.Errorf("failed to get tag: %w", err)
 	}
@@ -104,23 +126,21 @@ func (s *TagService) GetTagByID(tagID string) (*models.Tag, error) {
 	return &tag, nil
 }
 
-// GetTagByName retrieves a tag by name (case-insensitive)
-func (s *TagService) GetTagByName(tagName string) (*models.Tag, error) {
-	ctx := context.Background()
-
+// GetTagByName retrieves a tag of the user by name (case-insensitive)
+func (s *TagService) GetTagByName(ctx context.Context, userID, tagName string) (*models.Tag, error) {
 	var tag models.Tag
 	query := `
-		SELECT id, name, created_at
+		SELECT id, user_id, parent_id, name, created_at
 		FROM tags
-		WHERE LOWER(name) = LOWER($1)
+		WHERE user_id = $1 AND LOWER(name) = LOWER($2)
 	`
 
-	err := s.db.QueryRowContext(ctx, query, tagName).Scan(
-		&tag.ID, &tag.Name, &tag.CreatedAt)
+	err := s.db.QueryRowContext(ctx, query, userID, tagName).Scan(
+		&tag.ID, &tag.UserID, &tag.ParentID, &tag.Name, &tag.CreatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("tag not found")
+			return nil, ErrTagNotFound
 		}
 		return nil, fmt.Errorf("failed to get tag: %w", err)
 	}
@@ -134,36 +154,147 @@ func (s *TagService) ExtractTagsFromContent(content string) []string {
 	return models.ExtractTagsFromContent(content)
 }
 
-// ProcessTagsForNote creates tags and associations for a note
-func (s *TagService) ProcessTagsForNote(noteID string, tags []string) error {
-	ctx := context.Background()
+// ProcessTagsForNote creates the user's tags and associations for a note
+func (s *TagService) ProcessTagsForNote(ctx context.Context, userID, noteID string, tags []string) error {
+	return s.AddNoteTags(ctx, s.db, userID, noteID, tags)
+}
 
+// UpdateTagsForNote updates tags for a note (replaces all existing tags)
+func (s *TagService) UpdateTagsForNote(ctx context.Context, userID, noteID string, tags []string) error {
+	return s.ReplaceNoteTags(ctx, s.db, userID, noteID, tags)
+}
+
+// AddNoteTags creates the user's tags and associations for a note in q
+func (s *TagService) AddNoteTags(ctx context.Context, q QueryExecer, userID, noteID string, tags []string) error {
 	for _, tagName := range tags {
 		// Create or get tag
-		tag, err := s.getOrCreateTagByName(ctx, tagName)
+		tag, err := upsertTag(ctx, q, userID, tagName)
 		if err != nil {
 			return fmt.Errorf("failed to get or create tag %s: %w", tagName, err)
 		}
 
 		// Associate tag with note
-		if err := s.associateNoteWithTag(ctx, noteID, tag.ID); err != nil {
+		if _, err := associateNoteWithTag(ctx, q, noteID, tag.ID); err != nil {
 			return fmt.Errorf("failed to associate note with tag %s: %w", tagName, err)
 		}
 	}
 	return nil
 }
 
-// UpdateTagsForNote updates tags for a note (replaces all existing tags)
-func (s *TagService) UpdateTagsForNote(noteID string, tags []string) error {
-	ctx := context.Background()
+// ReplaceNoteTags replaces the tags of a note in q. Tags the note keeps keep
+// their association, whose created_at tells when the tag was added. When
+// the tags change, the note's tags_changed_at is set so sync returns it.
+func (s *TagService) ReplaceNoteTags(ctx context.Context, q QueryExecer, userID, noteID string, tags []string) error {
+	tagIDs := make([]uuid.UUID, 0, len(tags))
+	keep := make([]string, 0, len(tags))
+	for _, tagName := range tags {
+		tag, err := upsertTag(ctx, q, userID, tagName)
+		if err != nil {
+			return fmt.Errorf("failed to get or create tag %s: %w", tagName, err)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+		keep = append(keep, tag.ID.String())
+	}
 
-	// Delete existing tag associations
-	if err := s.deleteAllNoteTags(ctx, noteID); err != nil {
-		return err
+	// Remove the associations of tags no longer in the note
+	result, err := q.ExecContext(ctx, `
+		DELETE FROM note_tags WHERE note_id = $1 AND NOT `+s.dialect.AnyOf("tag_id", 2, "uuid"),
+		noteID, s.dialect.Array(keep))
+	if err != nil {
+		return fmt.Errorf("failed to delete removed note tags: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
 	}
+	changed := removed > 0
 
-	// Process new tags
-	return s.ProcessTagsForNote(noteID, tags)
+	for i, tagID := range tagIDs {
+		added, err := associateNoteWithTag(ctx, q, noteID, tagID)
+		if err != nil {
+			return fmt.Errorf("failed to associate note with tag %s: %w", tags[i], err)
+		}
+		changed = changed || added
+	}
+
+	if changed {
+		if _, err := q.ExecContext(ctx, "UPDATE notes SET tags_changed_at = $1 WHERE id = $2", time.Now(), noteID); err != nil {
+			return fmt.Errorf("failed to record note tags change: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteNoteTags deletes all tag associations for a note in q
+func (s *TagService) DeleteNoteTags(ctx context.Context, q QueryExecer, noteID string) error {
+	query := "DELETE FROM note_tags WHERE note_id = $1"
+	_, err := q.ExecContext(ctx, query, noteID)
+	if err != nil {
+		return fmt.Errorf("failed to delete note tags: %w", err)
+	}
+	return nil
+}
+
+// ErrTagExists is returned when a tag is renamed to the name of another tag
+var ErrTagExists = apperrors.Conflict("TAG_EXISTS", "a tag with this name already exists; merge the tags instead")
+
+// RenameTag renames a tag of the user in q, together with the nested tags
+// below it, and moves it below the parent of its new name. Names are
+// compared regardless of case, so a tag can be renamed to another case.
+func (s *TagService) RenameTag(ctx context.Context, q QueryExecer, userID string, tag *models.Tag, name string) (*models.Tag, error) {
+	renamed := (&models.CreateTagRequest{Name: name}).ToTag()
+	if err := renamed.Validate(); err != nil {
+		return nil, apperrors.Validation("INVALID_TAG", err.Error())
+	}
+	if renamed.Name == tag.Name {
+		return tag, nil
+	}
+	oldPrefix := strings.ToLower(tag.Name) + "/"
+	newPrefix := strings.ToLower(renamed.Name) + "/"
+	if strings.HasPrefix(newPrefix, oldPrefix) {
+		return nil, apperrors.Validation("INVALID_TAG", "a tag cannot be moved below itself")
+	}
+
+	// Tags already using the new names, other than the renamed ones, would
+	// be merged into them
+	var taken int
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tags
+		WHERE user_id = $1
+		AND (LOWER(name) = LOWER($2) OR LOWER(SUBSTR(name, 1, $3)) = $4)
+		AND NOT (LOWER(name) = LOWER($5) OR LOWER(SUBSTR(name, 1, $6)) = $7)
+	`, userID, renamed.Name, len(newPrefix), newPrefix, tag.Name, len(oldPrefix), oldPrefix).Scan(&taken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tag names: %w", err)
+	}
+	if taken > 0 {
+		return nil, ErrTagExists
+	}
+
+	var parentID *uuid.UUID
+	if parentName := models.ParentTagName(renamed.Name); parentName != "" {
+		parent, err := upsertTag(ctx, q, userID, parentName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get or create parent tag %s: %w", parentName, err)
+		}
+		parentID = &parent.ID
+	}
+
+	_, err = q.ExecContext(ctx, `
+		UPDATE tags SET name = $1 || SUBSTR(name, $2)
+		WHERE user_id = $3 AND (id = $4 OR LOWER(SUBSTR(name, 1, $5)) = $6)
+	`, renamed.Name, len(tag.Name)+1, userID, tag.ID, len(oldPrefix), oldPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE tags SET parent_id = $1 WHERE id = $2", parentID, tag.ID); err != nil {
+		return nil, fmt.Errorf("failed to move tag: %w", err)
+	}
+
+	result := *tag
+	result.Name = renamed.Name
+	result.ParentID = parentID
+	return &result, nil
 }
 
 // ValidateTagNames validates a list of tag names
@@ -173,60 +304,64 @@ func (s *TagService) ValidateTagNames(tagNames []string) error {
 
 // Private helper methods
 
-// getOrCreateTagByName gets an existing tag by name or creates a new one
-func (s *TagService) getOrCreateTagByName(ctx context.Context, tagName string) (*models.Tag, error) {
-	// Try to get existing tag
+// upsertTag gets the user's tag with a name, compared regardless of case,
+// or creates it below its parent, which is created as needed. Tags are
+// created only through it, in the transaction writing a note or by
+// CreateTag. The insert is an upsert so a tag created concurrently
+// does not abort the transaction.
+func upsertTag(ctx context.Context, q QueryExecer, userID, tagName string) (*models.Tag, error) {
 	var tag models.Tag
-	err := s.db.QueryRowContext(ctx,
-		"SELECT id, name, created_at FROM tags WHERE LOWER(name) = LOWER($1)",
-		tagName).Scan(&tag.ID, &tag.Name, &tag.CreatedAt)
-
+	err := q.QueryRowContext(ctx,
+		"SELECT id, user_id, parent_id, name, created_at FROM tags WHERE user_id = $1 AND LOWER(name) = LOWER($2)",
+		userID, tagName).Scan(&tag.ID, &tag.UserID, &tag.ParentID, &tag.Name, &tag.CreatedAt)
 	if err == nil {
 		return &tag, nil
 	}
-
 	if err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to query tag: %w", err)
 	}
 
-	// Create new tag
-	tag.ID = uuid.New()
-	tag.Name = tagName
-	tag.CreatedAt = time.Now()
+	// Nested tags are created below their parent
+	var parentID *uuid.UUID
+	if parentName := models.ParentTagName(tagName); parentName != "" {
+		parent, err := upsertTag(ctx, q, userID, parentName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get or create parent tag %s: %w", parentName, err)
+		}
+		parentID = &parent.ID
+	}
 
-	insertQuery := "INSERT INTO tags (id, name, created_at) VALUES ($1, $2, $3)"
-	_, err = s.db.ExecContext(ctx, insertQuery, tag.ID, tag.Name, tag.CreatedAt)
+	// A tag created concurrently is returned with its own name
+	query := `
+		INSERT INTO tags (id, user_id, parent_id, name, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, LOWER(name)) DO UPDATE SET name = tags.name
+		RETURNING id, user_id, parent_id, name, created_at
+	`
+	err = q.QueryRowContext(ctx, query, uuid.New(), userID, parentID, tagName, time.Now()).Scan(
+		&tag.ID, &tag.UserID, &tag.ParentID, &tag.Name, &tag.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tag: %w", err)
 	}
-
 	return &tag, nil
 }
 
-// associateNoteWithTag creates an association between a note and a tag
-func (s *TagService) associateNoteWithTag(ctx context.Context, noteID string, tagID uuid.UUID) error {
+// associateNoteWithTag creates an association between a note and a tag and
+// tells whether the note did not have the tag yet
+func associateNoteWithTag(ctx context.Context, q QueryExecer, noteID string, tagID uuid.UUID) (bool, error) {
 	query := "INSERT INTO note_tags (note_id, tag_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING"
-	_, err := s.db.ExecContext(ctx, query, noteID, tagID, time.Now())
+	result, err := q.ExecContext(ctx, query, noteID, tagID, time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to associate note with tag: %w", err)
+		return false, fmt.Errorf("failed to associate note with tag: %w", err)
 	}
-	return nil
-}
-
-// deleteAllNoteTags deletes all tag associations for a note
-func (s *TagService) deleteAllNoteTags(ctx context.Context, noteID string) error {
-	query := "DELETE FROM note_tags WHERE note_id = $1"
-	_, err := s.db.ExecContext(ctx, query, noteID)
+	added, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to delete note tags: %w", err)
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
 	}
-	return nil
+	return added > 0, nil
 }
 
 // GetAllTags retrieves all tags for the current user with pagination
-func (s *TagService) GetAllTags(userID string, limit int, offset int) (*models.TagList, error) {
-	ctx := context.Background()
-
+func (s *TagService) GetAllTags(ctx context.Context, userID string, limit int, offset int) (*models.TagList, error) {
 	// Set defaults
 	if limit <= 0 {
 		limit = 100
@@ -238,19 +373,18 @@ func (s *TagService) GetAllTags(userID string, limit int, offset int) (*models.T
 		offset = 0
 	}
 
-	// Query to get all tags with their note counts for this user
-	// Note: Tags are global (not per-user), but we only want tags used by this user's notes
+	// Query to get the user's tags in use with their note counts
 	query := `
 		SELECT DISTINCT
 			t.id,
+			t.parent_id,
 			t.name,
 			t.created_at,
 			COUNT(nt.note_id) as note_count
 		FROM tags t
 		INNER JOIN note_tags nt ON t.id = nt.tag_id
-		INNER JOIN notes n ON nt.note_id = n.id
-		WHERE n.user_id = $1
-		GROUP BY t.id, t.name, t.created_at
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.parent_id, t.name, t.created_at
 		ORDER BY t.name ASC
 		LIMIT $2 OFFSET $3
 	`
@@ -264,7 +398,7 @@ func (s *TagService) GetAllTags(userID string, limit int, offset int) (*models.T
 	var tags []models.TagResponse
 	for rows.Next() {
 		var tag models.TagResponse
-		err := rows.Scan(&tag.ID, &tag.Name, &tag.CreatedAt, &tag.NoteCount)
+		err := rows.Scan(&tag.ID, &tag.ParentID, &tag.Name, &tag.CreatedAt, &tag.NoteCount)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan tag: %w", err)
 		}
@@ -281,8 +415,7 @@ func (s *TagService) GetAllTags(userID string, limit int, offset int) (*models.T
 		SELECT COUNT(DISTINCT t.id)
 		FROM tags t
 		INNER JOIN note_tags nt ON t.id = nt.tag_id
-		INNER JOIN notes n ON nt.note_id = n.id
-		WHERE n.user_id = $1
+		WHERE t.user_id = $1
 	`
 	err = s.db.QueryRowContext(ctx, countQuery, userID).Scan(&total)
 	if err != nil {
@@ -296,4 +429,390 @@ func (s *TagService) GetAllTags(userID string, limit int, offset int) (*models.T
 		Offset: offset,
 		HasMore: offset + limit < total,
 	}, nil
-}
\ No newline at end of file
+}
+
+// GetTagTree retrieves the user's tags as a tree of nested tags. With a tag
+// name, only the subtree below that tag is returned; otherwise every top-level
+// tag is a root.
+func (s *TagService) GetTagTree(ctx context.Context, userID, tagName string) ([]models.TagTreeNode, error) {
+	query := `
+		SELECT t.id, t.parent_id, t.name, t.created_at, COUNT(nt.note_id)
+		FROM tags t
+		LEFT JOIN note_tags nt ON t.id = nt.tag_id
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.parent_id, t.name, t.created_at
+		ORDER BY t.name ASC
+	`
+	args := []interface{}{userID}
+	if tagName != "" {
+		query = `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM tags WHERE user_id = $1 AND LOWER(name) = LOWER($2)
+				UNION ALL
+				SELECT c.id FROM tags c JOIN subtree st ON c.parent_id = st.id
+			)
+			SELECT t.id, t.parent_id, t.name, t.created_at, COUNT(nt.note_id)
+			FROM tags t
+			JOIN subtree st ON t.id = st.id
+			LEFT JOIN note_tags nt ON t.id = nt.tag_id
+			GROUP BY t.id, t.parent_id, t.name, t.created_at
+			ORDER BY t.name ASC
+		`
+		args = append(args, tagName)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag tree: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []models.TagResponse
+	for rows.Next() {
+		var tag models.TagResponse
+		if err := rows.Scan(&tag.ID, &tag.ParentID, &tag.Name, &tag.CreatedAt, &tag.NoteCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+
+	if tagName != "" && len(tags) == 0 {
+		return nil, ErrTagNotFound
+	}
+	return buildTagTree(tags), nil
+}
+
+// GetTagGraph returns how often the user's most used tags, up to limit of
+// them, are used together on the same notes. Tags never used together have
+// no edge.
+func (s *TagService) GetTagGraph(ctx context.Context, userID string, limit int) (*models.TagGraph, error) {
+	if limit <= 0 {
+		limit = models.DefaultTagGraphNodes
+	}
+	if limit > models.MaxTagGraphNodes {
+		limit = models.MaxTagGraphNodes
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name, COUNT(nt.note_id)
+		FROM tags t
+		INNER JOIN note_tags nt ON t.id = nt.tag_id
+		WHERE t.user_id = $1
+		GROUP BY t.name
+		ORDER BY COUNT(nt.note_id) DESC, t.name ASC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	graph := &models.TagGraph{Nodes: []models.TagGraphNode{}, Edges: []models.TagGraphEdge{}}
+	names := []string{}
+	for rows.Next() {
+		var node models.TagGraphNode
+		if err := rows.Scan(&node.Name, &node.NoteCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag graph node: %w", err)
+		}
+		graph.Nodes = append(graph.Nodes, node)
+		names = append(names, node.Name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag graph nodes: %w", err)
+	}
+	if len(names) < 2 {
+		return graph, nil
+	}
+
+	// Each pair of tags on a note is counted once, from the tag first by name
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT a.name, b.name, COUNT(DISTINCT na.note_id)
+		FROM note_tags na
+		INNER JOIN tags a ON a.id = na.tag_id
+		INNER JOIN note_tags nb ON nb.note_id = na.note_id
+		INNER JOIN tags b ON b.id = nb.tag_id
+		WHERE a.user_id = $1 AND b.user_id = $1 AND a.name < b.name
+			AND `+s.dialect.AnyOf("a.name", 2, "")+` AND `+s.dialect.AnyOf("b.name", 2, "")+`
+		GROUP BY a.name, b.name
+		ORDER BY COUNT(DISTINCT na.note_id) DESC, a.name ASC, b.name ASC
+	`, userID, s.dialect.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag graph edges: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var edge models.TagGraphEdge
+		if err := rows.Scan(&edge.Source, &edge.Target, &edge.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan tag graph edge: %w", err)
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag graph edges: %w", err)
+	}
+
+	return graph, nil
+}
+
+// GetTrendingTags compares how often the user added each tag to notes in
+// the last window, such as "7d", with the window before it. Tags used in the
+// last window are returned up to limit, new tags first, then by growth and
+// use.
+func (s *TagService) GetTrendingTags(ctx context.Context, userID, window string, limit int) (*models.TrendingTags, error) {
+	if window == "" {
+		window = models.DefaultTrendWindow
+	}
+	duration, err := models.ParseTrendWindow(window)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_WINDOW", err)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	now := time.Now()
+	since := now.Add(-duration)
+
+	// Both windows are counted in one pass over the tags added since the
+	// start of the previous window
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name,
+			SUM(CASE WHEN nt.created_at >= $2 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN nt.created_at < $2 THEN 1 ELSE 0 END)
+		FROM note_tags nt
+		INNER JOIN tags t ON t.id = nt.tag_id
+		WHERE t.user_id = $1 AND nt.created_at >= $3
+		GROUP BY t.name
+		HAVING SUM(CASE WHEN nt.created_at >= $2 THEN 1 ELSE 0 END) > 0
+	`, userID, since, since.Add(-duration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trending tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []models.TrendingTag{}
+	for rows.Next() {
+		var tag models.TrendingTag
+		if err := rows.Scan(&tag.Name, &tag.Count, &tag.PreviousCount); err != nil {
+			return nil, fmt.Errorf("failed to scan trending tag: %w", err)
+		}
+		if tag.PreviousCount > 0 {
+			growth := float64(tag.Count-tag.PreviousCount) * 100 / float64(tag.PreviousCount)
+			tag.GrowthPercent = &growth
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trending tags: %w", err)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := tags[i], tags[j]
+		if (a.GrowthPercent == nil) != (b.GrowthPercent == nil) {
+			return a.GrowthPercent == nil
+		}
+		if a.GrowthPercent != nil && *a.GrowthPercent != *b.GrowthPercent {
+			return *a.GrowthPercent > *b.GrowthPercent
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return &models.TrendingTags{Window: window, Since: since, Tags: tags}, nil
+}
+
+// buildTagTree nests tags below their parents. Tags whose parent is not in
+// the list become roots; the order of the list is kept among siblings.
+func buildTagTree(tags []models.TagResponse) []models.TagTreeNode {
+	present := make(map[uuid.UUID]bool, len(tags))
+	for _, tag := range tags {
+		present[tag.ID] = true
+	}
+
+	children := make(map[uuid.UUID][]models.TagResponse)
+	var roots []models.TagResponse
+	for _, tag := range tags {
+		if tag.ParentID != nil && present[*tag.ParentID] {
+			children[*tag.ParentID] = append(children[*tag.ParentID], tag)
+		} else {
+			roots = append(roots, tag)
+		}
+	}
+
+	var build func(tag models.TagResponse) models.TagTreeNode
+	build = func(tag models.TagResponse) models.TagTreeNode {
+		node := models.TagTreeNode{TagResponse: tag, Children: []models.TagTreeNode{}}
+		for _, child := range children[tag.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+
+	tree := make([]models.TagTreeNode, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+	return tree
+}
+
+// unusedTagGracePeriod keeps newly created tags from being removed before the
+// note that created them is linked to them
+const unusedTagGracePeriod = time.Hour
+
+// CleanupUnusedTags removes tags no longer used by any note. Parents of
+// nested tags are kept until their children are removed.
+func (s *TagService) CleanupUnusedTags(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM tags t
+		WHERE t.created_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM note_tags nt WHERE nt.tag_id = t.id)
+		  AND NOT EXISTS (SELECT 1 FROM tags c WHERE c.parent_id = t.id)
+	`, time.Now().Add(-unusedTagGracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup unused tags: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// maxTagSuggestions is the number of suggestions returned for a note
+const maxTagSuggestions = 5
+
+// AutoApplyConfidence is the confidence from which suggestions are applied
+// automatically for users who opted in
+const AutoApplyConfidence = 0.8
+
+// SuggestTagsForNote asks the LLM for hashtags describing a note. Tags already
+// in the note are left out; the user's existing tags are preferred.
+func (s *TagService) SuggestTagsForNote(ctx context.Context, userID, noteID string) ([]models.TagSuggestion, error) {
+	if s.llm == nil {
+		return nil, apperrors.New(apperrors.ErrUnavailable, "TAG_SUGGESTIONS_UNAVAILABLE", "tag suggestions are not available")
+	}
+
+	var title sql.NullString
+	var content string
+	var isPrivate bool
+	err := s.db.QueryRowContext(ctx,
+		"SELECT title, content, is_private FROM notes WHERE id = $1 AND user_id = $2",
+		noteID, userID).Scan(&title, &content, &isPrivate)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note: %w", err)
+	}
+
+	// Private notes are encrypted at rest and must not be sent to the LLM
+	if isPrivate {
+		return nil, apperrors.Validation("PRIVATE_NOTE", "private notes cannot be tagged automatically")
+	}
+
+	// The user's tags give the LLM a vocabulary to reuse
+	var userTags []string
+	tagList, err := s.GetAllTags(ctx, userID, 100, 0)
+	if err == nil {
+		for _, tag := range tagList.Tags {
+			userTags = append(userTags, tag.Name)
+		}
+	}
+
+	prompt, err := buildTagSuggestionPrompt(s.prompts, title.String, content, userTags)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeatureTagSuggestions), prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest tags: %w", err)
+	}
+
+	suggestions, err := parseTagSuggestions(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag suggestions: %w", err)
+	}
+
+	return filterTagSuggestions(suggestions, models.ExtractTagsFromContent(content)), nil
+}
+
+// buildTagSuggestionPrompt creates the LLM prompt for tag suggestions
+func buildTagSuggestionPrompt(registry *prompts.Registry, title, content string, userTags []string) (string, error) {
+	return promptsOrDefault(registry).Render(prompts.TagSuggestions, prompts.TagSuggestionsData{
+		MaxSuggestions: maxTagSuggestions,
+		Title:          title,
+		Content:        content,
+		UserTags:       userTags,
+	})
+}
+
+// parseTagSuggestions extracts suggestions from the LLM response, which may
+// wrap the JSON in text or code fences
+func parseTagSuggestions(response string) ([]models.TagSuggestion, error) {
+	jsonStart := strings.Index(response, "{")
+	jsonEnd := strings.LastIndex(response, "}")
+	if jsonStart == -1 || jsonEnd < jsonStart {
+		return nil, fmt.Errorf("no valid JSON found in response")
+	}
+
+	var result struct {
+		Suggestions []models.TagSuggestion `json:"suggestions"`
+	}
+	if err := json.Unmarshal([]byte(response[jsonStart:jsonEnd+1]), &result); err != nil {
+		return nil, err
+	}
+	return result.Suggestions, nil
+}
+
+// invalidTagChars matches characters not allowed in suggested tags
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// filterTagSuggestions normalizes suggestions to lowercase hashtags, drops
+// invalid ones and those already in the note, and keeps the most confident
+func filterTagSuggestions(suggestions []models.TagSuggestion, present []string) []models.TagSuggestion {
+	seen := make(map[string]bool, len(present))
+	for _, tag := range present {
+		seen[strings.ToLower(tag)] = true
+	}
+
+	filtered := []models.TagSuggestion{}
+	for _, suggestion := range suggestions {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(suggestion.Tag), "#")))
+		name = strings.Trim(invalidTagChars.ReplaceAllString(name, "_"), "_")
+		if name == "" || len(name) > 99 {
+			continue
+		}
+
+		tag := "#" + name
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+
+		confidence := suggestion.Confidence
+		if confidence < 0 {
+			confidence = 0
+		} else if confidence > 1 {
+			confidence = 1
+		}
+		filtered = append(filtered, models.TagSuggestion{Tag: tag, Confidence: confidence})
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Confidence > filtered[j].Confidence
+	})
+	if len(filtered) > maxTagSuggestions {
+		filtered = filtered[:maxTagSuggestions]
+	}
+	return filtered
+}
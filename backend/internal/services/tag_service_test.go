@@ -1,14 +1,16 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/config"
-	"github.com/gpd/my-notes/internal/database"
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,10 +21,9 @@ import (
 // The frontend extracts tags client-side, so we only test backend methods used by NoteService
 type TagServiceTestSuite struct {
 	suite.Suite
-	db        *sql.DB
-	service   *TagService
-	userID    uuid.UUID
-	cleanupDB func()
+	db      *sql.DB
+	service *TagService
+	userID  uuid.UUID
 }
 
 // SetupSuite runs once before all tests
@@ -31,30 +32,11 @@ func (suite *TagServiceTestSuite) SetupSuite() {
 		suite.T().Skip("Skipping integration tests in short mode")
 	}
 
-	cfg, err := config.LoadConfig("")
-	require.NoError(suite.T(), err, "Failed to load config")
-
-	db, err := database.CreateTestDatabase(cfg.Database)
-	require.NoError(suite.T(), err, "Failed to create test database")
+	db := testutil.NewTestDB(suite.T(), config.GetTestDatabaseConfig(), "../../migrations")
 	suite.db = db
 
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(suite.T(), err, "Failed to run migrations")
-
 	suite.service = NewTagService(db)
-	suite.userID = uuid.New()
-	suite.cleanupDB = func() { db.Close() }
-
-	err = suite.createTestUser()
-	require.NoError(suite.T(), err, "Failed to create test user")
-}
-
-// TearDownSuite runs once after all tests
-func (suite *TagServiceTestSuite) TearDownSuite() {
-	if suite.cleanupDB != nil {
-		suite.cleanupDB()
-	}
+	suite.userID = testutil.NewTestUser(suite.T(), db).ID
 }
 
 // SetupTest runs before each test
@@ -62,17 +44,6 @@ func (suite *TagServiceTestSuite) SetupTest() {
 	suite.cleanupTestData()
 }
 
-// createTestUser creates a test user for the tests
-func (suite *TagServiceTestSuite) createTestUser() error {
-	query := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-	`
-	_, err := suite.db.Exec(query, suite.userID, "google_"+suite.userID.String(),
-		"test@example.com", time.Now(), time.Now())
-	return err
-}
-
 // cleanupTestData cleans up test data between tests
 func (suite *TagServiceTestSuite) cleanupTestData() {
 	_, err := suite.db.Exec("DELETE FROM notes WHERE user_id = $1", suite.userID)
@@ -142,9 +113,9 @@ func (suite *TagServiceTestSuite) TestExtractTagsFromContent() {
 // This is used by NoteService when creating notes to associate extracted hashtags
 func (suite *TagServiceTestSuite) TestProcessTagsForNote() {
 	// Create test tags
-	_, err := suite.service.CreateTag(&models.CreateTagRequest{Name: "#tag1"})
+	_, err := suite.service.CreateTag(context.Background(), suite.userID.String(), &models.CreateTagRequest{Name: "#tag1"})
 	require.NoError(suite.T(), err)
-	_, err = suite.service.CreateTag(&models.CreateTagRequest{Name: "#tag2"})
+	_, err = suite.service.CreateTag(context.Background(), suite.userID.String(), &models.CreateTagRequest{Name: "#tag2"})
 	require.NoError(suite.T(), err)
 
 	tests := []struct {
@@ -167,13 +138,9 @@ func (suite *TagServiceTestSuite) TestProcessTagsForNote() {
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
 			// Create a real note in the database first (required by foreign key constraint)
-			noteID := uuid.New()
-			_, err := suite.db.Exec(
-				"INSERT INTO notes (id, user_id, title, content, created_at, updated_at) VALUES ($1, $2, $3, $4, NOW(), NOW())",
-				noteID, suite.userID, "Test Note", "Test content")
-			require.NoError(suite.T(), err)
+			noteID := testutil.NewTestNote(suite.T(), suite.db, suite.userID, "Test Note", "Test content").ID
 
-			err = suite.service.ProcessTagsForNote(noteID.String(), tt.tags)
+			err := suite.service.ProcessTagsForNote(context.Background(), suite.userID.String(), noteID.String(), tt.tags)
 
 			if tt.expectError {
 				assert.Error(suite.T(), err)
@@ -196,20 +163,16 @@ func (suite *TagServiceTestSuite) TestProcessTagsForNote() {
 // This is used by NoteService when updating notes
 func (suite *TagServiceTestSuite) TestUpdateTagsForNote() {
 	// Create a real note in the database first (required by foreign key constraint)
-	noteID := uuid.New()
-	_, err := suite.db.Exec(
-		"INSERT INTO notes (id, user_id, title, content, created_at, updated_at) VALUES ($1, $2, $3, $4, NOW(), NOW())",
-		noteID, suite.userID, "Test Note", "Test content with #tag1 and #tag2")
-	require.NoError(suite.T(), err)
+	noteID := testutil.NewTestNote(suite.T(), suite.db, suite.userID, "Test Note", "Test content with #tag1 and #tag2").ID
 
 	// Extract and associate initial tags from content
 	initialTags := []string{"#tag1", "#tag2"}
-	err = suite.service.ProcessTagsForNote(noteID.String(), initialTags)
+	err := suite.service.ProcessTagsForNote(context.Background(), suite.userID.String(), noteID.String(), initialTags)
 	require.NoError(suite.T(), err)
 
 	// Update tags
 	updatedTags := []string{"#tag1", "#newtag"}
-	err = suite.service.UpdateTagsForNote(noteID.String(), updatedTags)
+	err = suite.service.UpdateTagsForNote(context.Background(), suite.userID.String(), noteID.String(), updatedTags)
 	assert.NoError(suite.T(), err)
 
 	// Verify the tag associations were updated
@@ -224,7 +187,7 @@ func (suite *TagServiceTestSuite) TestUpdateTagsForNote() {
 func (suite *TagServiceTestSuite) TestGetTagByName() {
 	// Create a test tag
 	createReq := &models.CreateTagRequest{Name: "#byname"}
-	createdTag, err := suite.service.CreateTag(createReq)
+	createdTag, err := suite.service.CreateTag(context.Background(), suite.userID.String(), createReq)
 	require.NoError(suite.T(), err)
 
 	tests := []struct {
@@ -251,7 +214,7 @@ func (suite *TagServiceTestSuite) TestGetTagByName() {
 
 	for _, tt := range tests {
 		suite.Run(tt.name, func() {
-			tag, err := suite.service.GetTagByName(tt.tagName)
+			tag, err := suite.service.GetTagByName(context.Background(), suite.userID.String(), tt.tagName)
 
 			if tt.expectError {
 				assert.Error(suite.T(), err)
@@ -307,7 +270,215 @@ func (suite *TagServiceTestSuite) TestValidateTagNames() {
 	}
 }
 
+// TestGetTagGraph tests the tag co-occurrence graph
+func (suite *TagServiceTestSuite) TestGetTagGraph() {
+	ctx := context.Background()
+	noteService := NewNoteService(suite.db, suite.service)
+	userID := suite.userID.String()
+	for _, content := range []string{"#work #urgent", "#work #urgent #home", "#work", "#home"} {
+		_, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: content})
+		require.NoError(suite.T(), err)
+	}
+	// Another user's tags stay out of the graph
+	_, err := noteService.CreateNote(ctx, testutil.NewTestUser(suite.T(), suite.db).ID.String(), &models.CreateNoteRequest{Content: "#work #other"})
+	require.NoError(suite.T(), err)
+
+	graph, err := suite.service.GetTagGraph(ctx, userID, 0)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.TagGraphNode{
+		{Name: "#work", NoteCount: 3},
+		{Name: "#home", NoteCount: 2},
+		{Name: "#urgent", NoteCount: 2},
+	}, graph.Nodes)
+	assert.Equal(suite.T(), []models.TagGraphEdge{
+		{Source: "#urgent", Target: "#work", Weight: 2},
+		{Source: "#home", Target: "#urgent", Weight: 1},
+		{Source: "#home", Target: "#work", Weight: 1},
+	}, graph.Edges)
+
+	// Edges only join the tags in the graph
+	graph, err = suite.service.GetTagGraph(ctx, userID, 2)
+	require.NoError(suite.T(), err)
+	assert.Len(suite.T(), graph.Nodes, 2)
+	assert.Equal(suite.T(), []models.TagGraphEdge{{Source: "#home", Target: "#work", Weight: 1}}, graph.Edges)
+}
+
+// TestGetTrendingTags tests comparing tag use between windows
+func (suite *TagServiceTestSuite) TestGetTrendingTags() {
+	ctx := context.Background()
+	noteService := NewNoteService(suite.db, suite.service)
+	userID := suite.userID.String()
+	for _, content := range []string{"#work", "#work #home", "#work #home #new", "#home #old"} {
+		_, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: content})
+		require.NoError(suite.T(), err)
+	}
+	// Move some uses to the previous window: #work was used once, #home
+	// twice, and #old only then
+	age := func(tag string, count int) {
+		_, err := suite.db.ExecContext(ctx, `
+			UPDATE note_tags SET created_at = $1
+			WHERE note_id IN (SELECT note_id FROM note_tags nt JOIN tags t ON t.id = nt.tag_id WHERE t.name = $2 AND t.user_id = $3 ORDER BY nt.note_id LIMIT $4)
+				AND tag_id = (SELECT id FROM tags WHERE name = $2 AND user_id = $3)
+		`, time.Now().AddDate(0, 0, -10), tag, suite.userID, count)
+		require.NoError(suite.T(), err)
+	}
+	age("#work", 1)
+	age("#home", 2)
+	age("#old", 1)
+
+	trending, err := suite.service.GetTrendingTags(ctx, userID, "", 0)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.DefaultTrendWindow, trending.Window)
+	require.Len(suite.T(), trending.Tags, 3)
+	assert.Equal(suite.T(), "#new", trending.Tags[0].Name)
+	assert.Nil(suite.T(), trending.Tags[0].GrowthPercent)
+	assert.Equal(suite.T(), "#work", trending.Tags[1].Name)
+	assert.Equal(suite.T(), 2, trending.Tags[1].Count)
+	assert.Equal(suite.T(), 1, trending.Tags[1].PreviousCount)
+	assert.InDelta(suite.T(), 100.0, *trending.Tags[1].GrowthPercent, 0.001)
+	assert.Equal(suite.T(), "#home", trending.Tags[2].Name)
+	assert.InDelta(suite.T(), -50.0, *trending.Tags[2].GrowthPercent, 0.001)
+
+	_, err = suite.service.GetTrendingTags(ctx, userID, "1y", 0)
+	assert.ErrorIs(suite.T(), err, apperrors.ErrValidation)
+}
+
+// TestTagNamesIgnoreCase tests that tags differing only in case are one tag,
+// however they are created
+func (suite *TagServiceTestSuite) TestTagNamesIgnoreCase() {
+	ctx := context.Background()
+	noteService := NewNoteService(suite.db, suite.service)
+	user := testutil.NewTestUser(suite.T(), suite.db)
+	userID := user.ID.String()
+
+	// A tag created before names were lowercased
+	legacyID := testutil.NewTestTag(suite.T(), suite.db, user.ID, "#Work")
+
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Plan #work #work/q3"})
+	require.NoError(suite.T(), err)
+	var tagged []uuid.UUID
+	rows, err := suite.db.Query("SELECT t.id FROM note_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.note_id = $1 AND t.parent_id IS NULL", note.ID)
+	require.NoError(suite.T(), err)
+	for rows.Next() {
+		var id uuid.UUID
+		require.NoError(suite.T(), rows.Scan(&id))
+		tagged = append(tagged, id)
+	}
+	require.NoError(suite.T(), rows.Close())
+	assert.Equal(suite.T(), []uuid.UUID{legacyID}, tagged)
+
+	child, err := suite.service.GetTagByName(ctx, userID, "#work/q3")
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), child.ParentID)
+	assert.Equal(suite.T(), legacyID, *child.ParentID)
+
+	tag, err := suite.service.CreateTag(ctx, userID, &models.CreateTagRequest{Name: "#WORK"})
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), legacyID, tag.ID)
+	assert.Equal(suite.T(), "#Work", tag.Name)
+
+	var count int
+	require.NoError(suite.T(), suite.db.QueryRow("SELECT COUNT(*) FROM tags WHERE user_id = $1", userID).Scan(&count))
+	assert.Equal(suite.T(), 2, count)
+
+	// The database refuses case duplicates written around the services
+	_, err = suite.db.Exec("INSERT INTO tags (id, user_id, name) VALUES ($1, $2, $3)", uuid.New(), userID, "#wORK")
+	assert.Error(suite.T(), err)
+}
+
+// TestNoteWritesGoThroughTagService tests that the tags NoteService writes
+// with a note are the ones TagService reads
+func (suite *TagServiceTestSuite) TestNoteWritesGoThroughTagService() {
+	ctx := context.Background()
+	noteService := NewNoteService(suite.db, suite.service)
+	userID := suite.userID.String()
+
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Kickoff #project/alpha #meeting"})
+	require.NoError(suite.T(), err)
+	tags, err := noteService.GetTagsForNotes(ctx, []uuid.UUID{note.ID})
+	require.NoError(suite.T(), err)
+	assert.ElementsMatch(suite.T(), []string{"#project/alpha", "#meeting"}, tags[note.ID])
+
+	alpha, err := suite.service.GetTagByName(ctx, userID, "#project/alpha")
+	require.NoError(suite.T(), err)
+	parent, err := suite.service.GetTagByName(ctx, userID, "#project")
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), alpha.ParentID)
+	assert.Equal(suite.T(), parent.ID, *alpha.ParentID)
+
+	content := "Kickoff moved #project/alpha #followup"
+	_, err = noteService.UpdateNote(ctx, userID, note.ID.String(), &models.UpdateNoteRequest{Content: &content, Version: &note.Version})
+	require.NoError(suite.T(), err)
+
+	_, err = suite.service.GetTagByName(ctx, userID, "#followup")
+	assert.NoError(suite.T(), err)
+	tags, err = noteService.GetTagsForNotes(ctx, []uuid.UUID{note.ID})
+	require.NoError(suite.T(), err)
+	assert.ElementsMatch(suite.T(), []string{"#project/alpha", "#followup"}, tags[note.ID])
+}
+
 // TestTagService runs the complete test suite
 func TestTagService(t *testing.T) {
 	suite.Run(t, new(TagServiceTestSuite))
 }
+
+func TestParseTagSuggestions(t *testing.T) {
+	llmResponse := "```json\n{\"suggestions\": [{\"tag\": \"#golang\", \"confidence\": 0.92}, {\"tag\": \"backend\", \"confidence\": 0.6}]}\n```"
+
+	suggestions, err := parseTagSuggestions(llmResponse)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TagSuggestion{
+		{Tag: "#golang", Confidence: 0.92},
+		{Tag: "backend", Confidence: 0.6},
+	}, suggestions)
+
+	_, err = parseTagSuggestions("no tags here")
+	assert.Error(t, err)
+}
+
+func TestFilterTagSuggestions(t *testing.T) {
+	suggestions := []models.TagSuggestion{
+		{Tag: "#Meeting", Confidence: 0.5},
+		{Tag: "#work", Confidence: 0.99},
+		{Tag: "Project Plan", Confidence: 0.85},
+		{Tag: "#meeting", Confidence: 0.4},
+		{Tag: "#", Confidence: 0.9},
+		{Tag: "#café", Confidence: 1.7},
+		{Tag: "#a", Confidence: 0.3},
+		{Tag: "#b", Confidence: 0.2},
+	}
+
+	filtered := filterTagSuggestions(suggestions, []string{"#work"})
+
+	assert.Equal(t, []models.TagSuggestion{
+		{Tag: "#caf", Confidence: 1},
+		{Tag: "#project_plan", Confidence: 0.85},
+		{Tag: "#meeting", Confidence: 0.5},
+		{Tag: "#a", Confidence: 0.3},
+		{Tag: "#b", Confidence: 0.2},
+	}, filtered)
+}
+
+func TestBuildTagTree(t *testing.T) {
+	work := models.TagResponse{ID: uuid.New(), Name: "#work", NoteCount: 1}
+	projectA := models.TagResponse{ID: uuid.New(), ParentID: &work.ID, Name: "#work/projecta", NoteCount: 2}
+	docs := models.TagResponse{ID: uuid.New(), ParentID: &projectA.ID, Name: "#work/projecta/docs", NoteCount: 3}
+	home := models.TagResponse{ID: uuid.New(), Name: "#home"}
+
+	tree := buildTagTree([]models.TagResponse{home, work, projectA, docs})
+
+	require.Len(t, tree, 2)
+	assert.Equal(t, "#home", tree[0].Name)
+	assert.Empty(t, tree[0].Children)
+	assert.Equal(t, "#work", tree[1].Name)
+	require.Len(t, tree[1].Children, 1)
+	assert.Equal(t, "#work/projecta", tree[1].Children[0].Name)
+	require.Len(t, tree[1].Children[0].Children, 1)
+	assert.Equal(t, 3, tree[1].Children[0].Children[0].NoteCount)
+
+	// A subtree is rooted at the requested tag even though it has a parent
+	subtree := buildTagTree([]models.TagResponse{projectA, docs})
+	require.Len(t, subtree, 1)
+	assert.Equal(t, "#work/projecta", subtree[0].Name)
+	assert.Len(t, subtree[0].Children, 1)
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// taskRebuildPageSize is the number of notes parsed per page when rebuilding tasks
+const taskRebuildPageSize = 500
+
+// TaskServiceInterface defines the interface for note task operations
+type TaskServiceInterface interface {
+	ListTasks(ctx context.Context, userID string, filter models.TaskFilter, limit, offset int) (*models.TaskList, error)
+	SetTaskChecked(ctx context.Context, userID, taskID string, checked bool) (*models.Task, error)
+}
+
+var (
+	// ErrTaskNotFound is returned for tasks that do not exist or belong to
+	// another user
+	ErrTaskNotFound = apperrors.NotFound("TASK_NOT_FOUND", "task not found")
+	// ErrTaskChanged is returned when the line of a task no longer holds it
+	ErrTaskChanged = apperrors.Conflict("TASK_CHANGED", "the note has changed since the task was listed")
+)
+
+// TaskService maintains the checklist items of notes as tasks. Tasks are
+// rebuilt from note content whenever a note is written and keep their ID
+// while their line does not move. Private notes keep no tasks so their
+// content is not exposed.
+type TaskService struct {
+	db          *sql.DB
+	dialect     database.Dialect
+	noteService NoteServiceInterface
+}
+
+// NewTaskService creates a new TaskService
+func NewTaskService(db *sql.DB, noteService NoteServiceInterface) *TaskService {
+	return &TaskService{
+		db:          db,
+		dialect:     database.DialectOf(db),
+		noteService: noteService,
+	}
+}
+
+// NoteWritten implements NoteWriteListener by replacing the note's tasks
+func (s *TaskService) NoteWritten(ctx context.Context, note *models.Note) {
+	if err := s.replaceTasks(ctx, note); err != nil {
+		log.Printf("[TaskService] WARNING: failed to update tasks for note %s: %v", note.ID, err)
+	}
+}
+
+// replaceTasks stores the checklist items found in a note's content
+func (s *TaskService) replaceTasks(ctx context.Context, note *models.Note) error {
+	var items []models.ChecklistItem
+	if !note.IsPrivate && !note.Locked {
+		items = note.ChecklistItems()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	lines := make([]int, len(items))
+	for i, item := range items {
+		lines[i] = item.Line
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO note_tasks (note_id, user_id, line, text, checked)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (note_id, line) DO UPDATE
+			SET text = EXCLUDED.text, checked = EXCLUDED.checked, updated_at = NOW()
+			WHERE note_tasks.text <> EXCLUDED.text OR note_tasks.checked <> EXCLUDED.checked
+		`, note.ID, note.UserID, item.Line, item.Text, item.Checked)
+		if err != nil {
+			return fmt.Errorf("failed to store task: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM note_tasks WHERE note_id = $1 AND NOT (`+
+		s.dialect.AnyOf("line", 2, "int")+`)`, note.ID, s.dialect.Array(lines))
+	if err != nil {
+		return fmt.Errorf("failed to clear tasks: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ListTasks returns the user's tasks matching filter. Open tasks are listed
+// unless the filter asks for done or all tasks.
+func (s *TaskService) ListTasks(ctx context.Context, userID string, filter models.TaskFilter, limit, offset int) (*models.TaskList, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	conditions := []string{"t.user_id = $1"}
+	args := []interface{}{userID}
+	switch filter.Status {
+	case "", models.TaskStatusOpen:
+		conditions = append(conditions, "t.checked = FALSE")
+	case models.TaskStatusDone:
+		conditions = append(conditions, "t.checked = TRUE")
+	case models.TaskStatusAll:
+	default:
+		return nil, apperrors.Validation("INVALID_STATUS", "status must be open, done or all")
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM note_tags nt JOIN tags tg ON tg.id = nt.tag_id
+			WHERE nt.note_id = t.note_id AND tg.name = $%d
+		)`, len(args)))
+	}
+	where := "WHERE " + strings.Join(conditions, " AND ")
+
+	list := &models.TaskList{
+		Tasks:  []models.Task{},
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM note_tasks t "+where, args...).Scan(&list.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.note_id, COALESCE(n.title, ''), t.line, t.text, t.checked, t.updated_at
+		FROM note_tasks t
+		JOIN notes n ON n.id = t.note_id
+		%s
+		ORDER BY n.updated_at DESC, t.note_id, t.line
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task models.Task
+		err := rows.Scan(&task.ID, &task.NoteID, &task.NoteTitle, &task.Line, &task.Text, &task.Checked, &task.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		list.Tasks = append(list.Tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tasks: %w", err)
+	}
+
+	list.HasMore = offset+limit < list.Total
+	return list, nil
+}
+
+// SetTaskChecked checks or unchecks a task by rewriting its line in the note
+// content. The note is updated against the version the line was read from,
+// so a concurrent edit fails with ErrVersionMismatch instead of being lost.
+func (s *TaskService) SetTaskChecked(ctx context.Context, userID, taskID string, checked bool) (*models.Task, error) {
+	task, err := s.getTask(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, userID, task.NoteID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var item *models.ChecklistItem
+	for _, candidate := range note.ChecklistItems() {
+		if candidate.Line == task.Line {
+			item = &candidate
+			break
+		}
+	}
+	if item == nil || item.Text != task.Text {
+		return nil, ErrTaskChanged
+	}
+	if item.Checked == checked {
+		return task, nil
+	}
+
+	note.SetChecklistItem(task.Line, checked)
+	version := note.Version
+	_, err = s.noteService.UpdateNote(ctx, userID, note.ID.String(), &models.UpdateNoteRequest{
+		Content: &note.Content,
+		Version: &version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The write listener has stored the task again
+	return s.getTask(ctx, userID, taskID)
+}
+
+// getTask returns one of the user's tasks
+func (s *TaskService) getTask(ctx context.Context, userID, taskID string) (*models.Task, error) {
+	if _, err := uuid.Parse(taskID); err != nil {
+		return nil, ErrTaskNotFound
+	}
+
+	var task models.Task
+	err := s.db.QueryRowContext(ctx, `
+		SELECT t.id, t.note_id, COALESCE(n.title, ''), t.line, t.text, t.checked, t.updated_at
+		FROM note_tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE t.id = $1 AND t.user_id = $2
+	`, taskID, userID).Scan(&task.ID, &task.NoteID, &task.NoteTitle, &task.Line, &task.Text, &task.Checked, &task.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// RebuildTasks parses the tasks of every note again, for notes written
+// before tasks were tracked. It returns the number of notes parsed.
+func (s *TaskService) RebuildTasks(ctx context.Context) (int64, error) {
+	var parsed int64
+	after := uuid.Nil
+	for {
+		notes, err := s.rebuildPage(ctx, after)
+		if err != nil {
+			return parsed, err
+		}
+		for i := range notes {
+			if err := s.replaceTasks(ctx, &notes[i]); err != nil {
+				return parsed, fmt.Errorf("failed to rebuild tasks for note %s: %w", notes[i].ID, err)
+			}
+			parsed++
+		}
+		if len(notes) < taskRebuildPageSize {
+			return parsed, nil
+		}
+		after = notes[len(notes)-1].ID
+	}
+}
+
+// rebuildPage reads a page of notes ordered by ID. The content of private
+// notes is not read since they keep no tasks.
+func (s *TaskService) rebuildPage(ctx context.Context, after uuid.UUID) ([]models.Note, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, CASE WHEN is_private THEN '' ELSE content END, is_private
+		FROM notes
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, after, taskRebuildPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes for tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.UserID, &note.Content, &note.IsPrivate); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notes: %w", err)
+	}
+
+	return notes, nil
+}
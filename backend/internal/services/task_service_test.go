@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestTaskService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	service := NewTaskService(db, noteService)
+	noteService.AddWriteListener(service)
+	ctx := context.Background()
+
+	userID := testutil.NewTestUser(t, db).ID.String()
+	launch, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{
+		Title:   "Launch",
+		Content: "#projectX\n- [ ] Write spec\n```\n- [ ] example\n```\n- [x] Book venue",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "#home\n- [ ] Fix sink"}); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	// Open tasks are listed by default, and can be narrowed to a tag
+	list, err := service.ListTasks(ctx, userID, models.TaskFilter{}, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if list.Total != 2 {
+		t.Fatalf("Expected 2 open tasks, got %+v", list)
+	}
+	list, err = service.ListTasks(ctx, userID, models.TaskFilter{Tag: "#projectx", Status: models.TaskStatusAll}, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if list.Total != 2 || list.Tasks[0].Text != "Write spec" || list.Tasks[0].Line != 2 || list.Tasks[0].NoteTitle != "Launch" {
+		t.Fatalf("Unexpected tasks of #projectX: %+v", list.Tasks)
+	}
+	task := list.Tasks[0]
+
+	// Checking a task rewrites its line and keeps its ID
+	checked, err := service.SetTaskChecked(ctx, userID, task.ID.String(), true)
+	if err != nil {
+		t.Fatalf("Failed to check task: %v", err)
+	}
+	if checked.ID != task.ID || !checked.Checked {
+		t.Errorf("Expected the same task checked, got %+v", checked)
+	}
+	note, err := noteService.GetNoteByID(ctx, userID, launch.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if want := "#projectX\n- [x] Write spec\n```\n- [ ] example\n```\n- [x] Book venue"; note.Content != want {
+		t.Errorf("Expected content %q, got %q", want, note.Content)
+	}
+	if note.Version != launch.Version+1 {
+		t.Errorf("Expected the note version to increase, got %d", note.Version)
+	}
+
+	// Tasks follow edits of the note, keeping their ID on the same line
+	content := "#projectX\n- [ ] Write the spec"
+	if _, err := noteService.UpdateNote(ctx, userID, launch.ID.String(), &models.UpdateNoteRequest{Content: &content}); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	list, err = service.ListTasks(ctx, userID, models.TaskFilter{Tag: "#projectx", Status: models.TaskStatusAll}, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if list.Total != 1 || list.Tasks[0].ID != task.ID || list.Tasks[0].Text != "Write the spec" || list.Tasks[0].Checked {
+		t.Errorf("Expected the edited task only, got %+v", list.Tasks)
+	}
+
+	if _, err := service.SetTaskChecked(ctx, testutil.NewTestUser(t, db).ID.String(), task.ID.String(), false); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected tasks of other users to be hidden, got %v", err)
+	}
+	if _, err := service.ListTasks(ctx, userID, models.TaskFilter{Status: "later"}, 0, 0); err == nil {
+		t.Error("Expected an unknown status to be rejected")
+	}
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/templates"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// TemplateServiceInterface defines the interface for note template operations
+type TemplateServiceInterface interface {
+	CreateTemplate(ctx context.Context, userID string, request *models.TemplateRequest) (*models.Template, error)
+	ListTemplates(ctx context.Context, userID string) ([]models.Template, error)
+	GetTemplate(ctx context.Context, userID, templateID string) (*models.Template, error)
+	UpdateTemplate(ctx context.Context, userID, templateID string, request *models.TemplateRequest) (*models.Template, error)
+	DeleteTemplate(ctx context.Context, userID, templateID string) error
+	ApplyTemplate(ctx context.Context, userID, templateID string, request *models.ApplyTemplateRequest) (*models.AppliedTemplate, error)
+	CreateNoteFromTemplate(ctx context.Context, userID, templateID string, request *models.CreateNoteFromTemplateRequest) (*models.Note, error)
+	CreateNoteFromTemplateInTx(ctx context.Context, userID, templateID string, request *models.CreateNoteFromTemplateRequest, inTx func(ctx context.Context, tx *sql.Tx, note *models.Note) error) (*models.Note, error)
+	ListGallery(ctx context.Context, userID string, filter *models.GalleryFilter) (*models.TemplateGallery, error)
+	CloneTemplate(ctx context.Context, userID, templateID string, request *models.CloneTemplateRequest) (*models.Template, error)
+}
+
+// ErrTemplateNotFound is returned for templates that do not exist
+var ErrTemplateNotFound = apperrors.NotFound("TEMPLATE_NOT_FOUND", "template not found")
+
+// codeInvalidTemplate is the code of templates failing validation
+const codeInvalidTemplate = "INVALID_TEMPLATE"
+
+// TemplateService stores note templates and renders them into notes
+type TemplateService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+}
+
+// NewTemplateService creates a new TemplateService
+func NewTemplateService(db *sql.DB, noteService NoteServiceInterface) *TemplateService {
+	return &TemplateService{
+		db:          db,
+		noteService: noteService,
+	}
+}
+
+// templateColumns lists the templates columns in scanTemplate order
+const templateColumns = "id, user_id, name, description, title, content, tags, category, is_public, usage_count, clone_count, cloned_from, created_at, updated_at"
+
+// scanTemplate scans a row selected with templateColumns
+func scanTemplate(row rowScanner, t *models.Template) error {
+	return row.Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &t.Title, &t.Content,
+		pq.Array(&t.Tags), &t.Category, &t.IsPublic, &t.UsageCount, &t.CloneCount, &t.ClonedFrom, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// CreateTemplate creates a template for a user
+func (s *TemplateService) CreateTemplate(ctx context.Context, userID string, request *models.TemplateRequest) (*models.Template, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidTemplate, err)
+	}
+
+	var template models.Template
+	err := scanTemplate(s.db.QueryRowContext(ctx, `
+		INSERT INTO templates (id, user_id, name, description, title, content, tags, category, is_public)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+templateColumns,
+		uuid.New(), userID, request.Name, request.Description, request.Title, request.Content,
+		pq.Array(request.Tags), request.Category, request.IsPublic), &template)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.Conflict("TEMPLATE_EXISTS", "template with this name already exists")
+		}
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// ListTemplates returns a user's templates ordered by name
+func (s *TemplateService) ListTemplates(ctx context.Context, userID string) ([]models.Template, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	list := []models.Template{}
+	for rows.Next() {
+		var template models.Template
+		if err := scanTemplate(rows, &template); err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		list = append(list, template)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating templates: %w", err)
+	}
+
+	return list, nil
+}
+
+// GetTemplate retrieves a template by ID for a specific user
+func (s *TemplateService) GetTemplate(ctx context.Context, userID, templateID string) (*models.Template, error) {
+	if _, err := uuid.Parse(templateID); err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	var template models.Template
+	err := scanTemplate(s.db.QueryRowContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE id = $1 AND user_id = $2
+	`, templateID, userID), &template)
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// UpdateTemplate replaces a template's fields. Its usage count is kept.
+func (s *TemplateService) UpdateTemplate(ctx context.Context, userID, templateID string, request *models.TemplateRequest) (*models.Template, error) {
+	if _, err := uuid.Parse(templateID); err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidTemplate, err)
+	}
+
+	var template models.Template
+	err := scanTemplate(s.db.QueryRowContext(ctx, `
+		UPDATE templates
+		SET name = $1, description = $2, title = $3, content = $4, tags = $5, category = $6, is_public = $7, updated_at = NOW()
+		WHERE id = $8 AND user_id = $9
+		RETURNING `+templateColumns,
+		request.Name, request.Description, request.Title, request.Content,
+		pq.Array(request.Tags), request.Category, request.IsPublic, templateID, userID), &template)
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	} else if err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.Conflict("TEMPLATE_EXISTS", "template with this name already exists")
+		}
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// DeleteTemplate deletes a template. Notes created from it are kept.
+func (s *TemplateService) DeleteTemplate(ctx context.Context, userID, templateID string) error {
+	if _, err := uuid.Parse(templateID); err != nil {
+		return ErrTemplateNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM templates WHERE id = $1 AND user_id = $2
+	`, templateID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+// ListGallery returns a page of public templates of every user. Popular
+// templates are those most used and cloned.
+func (s *TemplateService) ListGallery(ctx context.Context, userID string, filter *models.GalleryFilter) (*models.TemplateGallery, error) {
+	limit, offset := filter.Limit, filter.Offset
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	orderBy := "usage_count + clone_count DESC, name"
+	switch filter.Sort {
+	case "", models.GallerySortPopular:
+	case models.GallerySortRecent:
+		orderBy = "updated_at DESC"
+	default:
+		return nil, apperrors.Validation("INVALID_SORT", fmt.Sprintf("sort must be %s or %s", models.GallerySortPopular, models.GallerySortRecent))
+	}
+
+	pattern := ""
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		pattern = "%" + query + "%"
+	}
+	category := strings.ToLower(strings.TrimSpace(filter.Category))
+	where := `
+		WHERE is_public
+		AND ($1 = '' OR name ILIKE $1 OR description ILIKE $1)
+		AND ($2 = '' OR category = $2)
+	`
+
+	gallery := &models.TemplateGallery{
+		Templates: []models.GalleryTemplate{},
+		Limit:     limit,
+		Offset:    offset,
+	}
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM templates`+where, pattern, category).Scan(&gallery.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count gallery templates: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, description, category, title, content, tags, usage_count, clone_count, user_id = $3, updated_at
+		FROM templates`+where+`
+		ORDER BY `+orderBy+`
+		LIMIT $4 OFFSET $5
+	`, pattern, category, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gallery templates: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.GalleryTemplate
+		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.Category, &t.Title, &t.Content,
+			pq.Array(&t.Tags), &t.UsageCount, &t.CloneCount, &t.Own, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan gallery template: %w", err)
+		}
+		gallery.Templates = append(gallery.Templates, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating gallery templates: %w", err)
+	}
+
+	gallery.HasMore = offset+limit < gallery.Total
+	return gallery, nil
+}
+
+// CloneTemplate copies a public template into the user's templates. The copy
+// is private and starts unused; cloning another user's template counts
+// towards its clone count in the same transaction.
+func (s *TemplateService) CloneTemplate(ctx context.Context, userID, templateID string, request *models.CloneTemplateRequest) (*models.Template, error) {
+	if _, err := uuid.Parse(templateID); err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var source models.Template
+	err = scanTemplate(tx.QueryRowContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM templates
+		WHERE id = $1 AND (is_public OR user_id = $2)
+	`, templateID, userID), &source)
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	name := source.Name
+	if request.Name != "" {
+		name = strings.TrimSpace(request.Name)
+	}
+
+	var template models.Template
+	err = scanTemplate(tx.QueryRowContext(ctx, `
+		INSERT INTO templates (id, user_id, name, description, title, content, tags, category, cloned_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+templateColumns,
+		uuid.New(), userID, name, source.Description, source.Title, source.Content,
+		pq.Array(source.Tags), source.Category, source.ID), &template)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, apperrors.Conflict("TEMPLATE_EXISTS", "template with this name already exists, clone it under another name")
+		}
+		return nil, fmt.Errorf("failed to clone template: %w", err)
+	}
+
+	if source.UserID.String() != userID {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE templates SET clone_count = clone_count + 1 WHERE id = $1
+		`, source.ID); err != nil {
+			return nil, fmt.Errorf("failed to count template clone: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit template clone: %w", err)
+	}
+
+	return &template, nil
+}
+
+// ApplyTemplate renders a template's title and content without creating a
+// note. The template's tags are added to the content.
+func (s *TemplateService) ApplyTemplate(ctx context.Context, userID, templateID string, request *models.ApplyTemplateRequest) (*models.AppliedTemplate, error) {
+	template, err := s.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(ctx, userID, template, template.Title, request)
+}
+
+// CreateNoteFromTemplate renders a template and creates a note from it. The
+// note is written and the template's usage count incremented in one
+// transaction, so a template deleted meanwhile creates no note.
+func (s *TemplateService) CreateNoteFromTemplate(ctx context.Context, userID, templateID string, request *models.CreateNoteFromTemplateRequest) (*models.Note, error) {
+	return s.CreateNoteFromTemplateInTx(ctx, userID, templateID, request, nil)
+}
+
+// CreateNoteFromTemplateInTx creates a note from a template like
+// CreateNoteFromTemplate and calls inTx, when set, with the note in the
+// transaction writing it
+func (s *TemplateService) CreateNoteFromTemplateInTx(ctx context.Context, userID, templateID string, request *models.CreateNoteFromTemplateRequest, inTx func(ctx context.Context, tx *sql.Tx, note *models.Note) error) (*models.Note, error) {
+	template, err := s.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	title := template.Title
+	if request.Title != nil {
+		title = *request.Title
+	}
+	applied, err := s.render(ctx, userID, template, title, &request.ApplyTemplateRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.noteService.CreateNoteInTx(ctx, userID, &models.CreateNoteRequest{
+		Title:   applied.Title,
+		Content: applied.Content,
+		Private: request.Private,
+	}, func(ctx context.Context, tx *sql.Tx, note *models.Note) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE templates SET usage_count = usage_count + 1 WHERE id = $1 AND user_id = $2
+		`, template.ID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to count template use: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return ErrTemplateNotFound
+		}
+		if inTx != nil {
+			return inTx(ctx, tx, note)
+		}
+		return nil
+	})
+}
+
+// render renders a title and the template's content for a user. The user's
+// name is the part of their email before the @, as accounts have no display
+// name.
+func (s *TemplateService) render(ctx context.Context, userID string, template *models.Template, title string, request *models.ApplyTemplateRequest) (*models.AppliedTemplate, error) {
+	loc := time.UTC
+	if request.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(request.TimeZone); err != nil {
+			return nil, apperrors.Validation("INVALID_TIME_ZONE", "invalid time zone")
+		}
+	}
+
+	var email string
+	if err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email); err != nil {
+		return nil, fmt.Errorf("failed to get user for template: %w", err)
+	}
+	name, _, _ := strings.Cut(email, "@")
+
+	now := request.At
+	if now.IsZero() {
+		now = time.Now()
+	}
+	data := templates.Context{
+		Now:       now.In(loc),
+		UserName:  name,
+		UserEmail: email,
+		Vars:      request.Variables,
+	}
+	renderedTitle, err := templates.Render(title, data)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidTemplate, fmt.Errorf("invalid title: %w", err))
+	}
+	content, err := templates.Render(template.Content, data)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidTemplate, fmt.Errorf("invalid content: %w", err))
+	}
+
+	return &models.AppliedTemplate{
+		Title:   strings.TrimSpace(renderedTitle),
+		Content: template.ApplyTags(content),
+	}, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+)
+
+// Generated title limits
+const (
+	// maxTitlePromptLength is the length of the note content sent to the
+	// LLM for a title, in bytes
+	maxTitlePromptLength = 4000
+	// maxGeneratedTitleLength is the length of generated titles, in bytes
+	maxGeneratedTitleLength = 80
+	// maxOwnTitleLength is the length of notes of a single line that are
+	// titled after themselves rather than by the LLM, the length of titles
+	// derived from the first line
+	maxOwnTitleLength = 50
+)
+
+// TitleService writes titles for notes with the LLM
+type TitleService struct {
+	llm     TextGenerator
+	prompts *prompts.Registry
+}
+
+// NewTitleService creates a new TitleService
+func NewTitleService(llm TextGenerator) *TitleService {
+	return &TitleService{llm: llm}
+}
+
+// SetPrompts sets the registry the title prompt is rendered from. The
+// built-in prompt is used otherwise.
+func (s *TitleService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// GenerateTitle implements NoteTitleGenerator
+func (s *TitleService) GenerateTitle(ctx context.Context, userID, content string) (string, error) {
+	if len(content) > maxTitlePromptLength {
+		content = content[:maxTitlePromptLength]
+	}
+	prompt, err := promptsOrDefault(s.prompts).Render(prompts.Title, prompts.TitleData{
+		Content: strings.ToValidUTF8(content, ""),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeatureTitle), prompt)
+	if err != nil {
+		return "", err
+	}
+	title := cleanGeneratedTitle(response)
+	if title == "" {
+		return "", fmt.Errorf("LLM returned no title")
+	}
+	return title, nil
+}
+
+// cleanGeneratedTitle returns the first line of an LLM title without a
+// "Title:" label, quotes or Markdown, shortened at a word when longer than
+// maxGeneratedTitleLength
+func cleanGeneratedTitle(response string) string {
+	title := ""
+	for _, line := range strings.Split(response, "\n") {
+		if title = strings.TrimSpace(line); title != "" {
+			break
+		}
+	}
+	if len(title) >= 6 && strings.EqualFold(title[:6], "title:") {
+		title = title[6:]
+	}
+	title = strings.Trim(title, " \t\"'`*_#“”‘’")
+	title = strings.Join(strings.Fields(title), " ")
+
+	if len(title) > maxGeneratedTitleLength {
+		cut := title[:maxGeneratedTitleLength]
+		for !utf8.ValidString(cut) {
+			cut = cut[:len(cut)-1]
+		}
+		if i := strings.LastIndex(cut, " "); i > 0 {
+			cut = cut[:i]
+		}
+		title = strings.TrimRight(cut, ".,;:!?") + "…"
+	}
+	return title
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestCleanGeneratedTitle(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{"Sprint planning for Q3", "Sprint planning for Q3"},
+		{"\n  Title: \"Sprint   planning\"\nThis note covers...", "Sprint planning"},
+		{"**# Rencana liburan ke Bali**", "Rencana liburan ke Bali"},
+		{"  \n", ""},
+		{strings.Repeat("word ", 20), strings.TrimSpace(strings.Repeat("word ", 16)) + "…"},
+	}
+	for _, tt := range tests {
+		if got := cleanGeneratedTitle(tt.response); got != tt.want {
+			t.Errorf("cleanGeneratedTitle(%q) = %q, want %q", tt.response, got, tt.want)
+		}
+	}
+}
+
+// blockingTitles generates no title until the context is done
+type blockingTitles struct{}
+
+func (blockingTitles) GenerateTitle(ctx context.Context, userID, content string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestCreateNoteGeneratedTitle(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	generator := &fakeSummarizer{summary: "Title: Groceries for the weekend"}
+	noteService.SetTitleGenerator(NewTitleService(generator), time.Second)
+
+	content := "need to buy\n- milk\n- eggs"
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: content})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if note.Title == nil || *note.Title != "Groceries for the weekend" {
+		t.Errorf("Expected the generated title, got %v", note.Title)
+	}
+	if len(generator.prompts) != 1 || !strings.Contains(generator.prompts[0], content) {
+		t.Errorf("Expected one prompt with the content, got %v", generator.prompts)
+	}
+
+	// Titled notes and notes of a single short line are not sent to the LLM
+	titled, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Title: "Shopping", Content: content})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	short, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: "Call the dentist"})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if *titled.Title != "Shopping" || *short.Title != "Call the dentist" {
+		t.Errorf("Unexpected titles %q, %q", *titled.Title, *short.Title)
+	}
+	if len(generator.prompts) != 1 {
+		t.Errorf("Expected no more prompts, got %d", len(generator.prompts))
+	}
+
+	// Failing and slow generators fall back to the first line
+	generator.err = errors.New("provider down")
+	note, err = noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{Content: content})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if *note.Title != "need to buy" {
+		t.Errorf("Expected the first line as the title, got %q", *note.Title)
+	}
+
+	noteService.SetTitleGenerator(blockingTitles{}, 10*time.Millisecond)
+	request := &models.CreateNoteRequest{Content: content}
+	note, err = noteService.CreateNote(ctx, userID, request)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if *note.Title != "need to buy" || request.Title != "" {
+		t.Errorf("Expected the first line as the title and the request unchanged, got %q", *note.Title)
+	}
+}
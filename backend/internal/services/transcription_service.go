@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/transcription"
+)
+
+// Transcription error codes
+const (
+	codeInvalidVoiceMemo    = "INVALID_VOICE_MEMO"
+	codeTranscriptionFailed = "TRANSCRIPTION_FAILED"
+)
+
+// NotePrettifier prettifies notes with the LLM
+type NotePrettifier interface {
+	PrettifyNote(ctx context.Context, userID, noteID string) (*models.PrettifyNoteResponse, error)
+}
+
+// TranscriptionServiceInterface defines the interface for transcribing voice
+// memos into notes
+type TranscriptionServiceInterface interface {
+	TranscribeMemo(ctx context.Context, userID string, audio []byte, filename string, request *models.TranscribeRequest) (*models.TranscribedNote, error)
+}
+
+// TranscriptionService sends voice memos to a speech-to-text backend and
+// creates notes of their transcripts. The audio is not stored.
+type TranscriptionService struct {
+	noteService NoteServiceInterface
+	transcriber transcription.Transcriber
+	prettifier  NotePrettifier
+}
+
+// NewTranscriptionService creates a new TranscriptionService
+func NewTranscriptionService(noteService NoteServiceInterface, transcriber transcription.Transcriber) *TranscriptionService {
+	return &TranscriptionService{
+		noteService: noteService,
+		transcriber: transcriber,
+	}
+}
+
+// SetPrettifier enables prettifying transcribed notes on request
+func (s *TranscriptionService) SetPrettifier(prettifier NotePrettifier) {
+	s.prettifier = prettifier
+}
+
+// TranscribeMemo transcribes a voice memo and creates a note of it, tagged
+// models.VoiceMemoTag. When requested and available the note is prettified;
+// it is still created when prettifying fails.
+func (s *TranscriptionService) TranscribeMemo(ctx context.Context, userID string, audio []byte, filename string, request *models.TranscribeRequest) (*models.TranscribedNote, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidVoiceMemo, err)
+	}
+	format := models.VoiceMemoFormat(audio, filename)
+	if format == "" {
+		return nil, apperrors.Validation(codeInvalidVoiceMemo, "audio must be MP3, MP4, M4A, WAV, WebM, Ogg or FLAC")
+	}
+
+	transcript, err := s.transcriber.Transcribe(ctx, audio, "memo."+format, request.Language)
+	if err != nil {
+		return nil, transcriptionError(err)
+	}
+	if strings.TrimSpace(transcript.Text) == "" {
+		return nil, apperrors.Validation(codeTranscriptionFailed, "no speech was recognized in the audio")
+	}
+
+	memo := &models.VoiceMemo{Text: transcript.Text, Duration: transcript.Duration}
+	for _, segment := range transcript.Segments {
+		memo.Segments = append(memo.Segments, models.VoiceMemoSegment{Start: segment.Start, Text: segment.Text})
+	}
+	noteRequest, err := request.ToCreateNoteRequest(memo)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidVoiceMemo, err)
+	}
+	note, err := s.noteService.CreateNote(ctx, userID, noteRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TranscribedNote{Duration: transcript.Duration.Seconds()}
+	if request.Prettify && s.prettifier != nil {
+		prettified, err := s.prettifier.PrettifyNote(ctx, userID, note.ID.String())
+		if err != nil {
+			log.Printf("[TranscriptionService] WARNING: Failed to prettify note %s: %v", note.ID, err)
+		} else if !prettified.Unchanged {
+			result.NoteResponse = prettified.NoteResponse
+			result.Prettified = true
+			return result, nil
+		}
+	}
+
+	result.NoteResponse = note.ToResponse()
+	result.Tags = note.ExtractHashtags()
+	return result, nil
+}
+
+// transcriptionError maps a transcriber error to the error returned to the
+// user, logging backend failures
+func transcriptionError(err error) error {
+	switch {
+	case errors.Is(err, transcription.ErrInvalidAudio):
+		log.Printf("[TranscriptionService] WARNING: %v", err)
+		return apperrors.Validation(codeTranscriptionFailed, "the audio could not be transcribed")
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return apperrors.New(apperrors.ErrUnavailable, codeTranscriptionFailed, "transcription timed out")
+	default:
+		log.Printf("[TranscriptionService] ERROR: %v", err)
+		return apperrors.New(apperrors.ErrUnavailable, codeTranscriptionFailed, "transcription is unavailable")
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/gpd/my-notes/internal/transcription"
+)
+
+// fakeTranscriber answers every recording with a fixed transcript,
+// recording the file names and languages it was sent
+type fakeTranscriber struct {
+	transcript *transcription.Transcript
+	err        error
+	filenames  []string
+	languages  []string
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audio []byte, filename, language string) (*transcription.Transcript, error) {
+	f.filenames = append(f.filenames, filename)
+	f.languages = append(f.languages, language)
+	return f.transcript, f.err
+}
+
+// fakePrettifier prettifies notes by upper-casing their titles
+type fakePrettifier struct {
+	noteService NoteServiceInterface
+	err         error
+}
+
+func (f *fakePrettifier) PrettifyNote(ctx context.Context, userID, noteID string) (*models.PrettifyNoteResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	note, err := f.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	title := strings.ToUpper(*note.Title)
+	note, err = f.noteService.UpdateNote(ctx, userID, noteID, &models.UpdateNoteRequest{Title: &title})
+	if err != nil {
+		return nil, err
+	}
+	return &models.PrettifyNoteResponse{NoteResponse: note.ToResponse()}, nil
+}
+
+func TestTranscribeMemo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	transcriber := &fakeTranscriber{transcript: &transcription.Transcript{
+		Text:     "Remember to water the tomatoes.",
+		Duration: 4 * time.Second,
+		Segments: []transcription.Segment{{Start: 0, End: 4 * time.Second, Text: "Remember to water the tomatoes."}},
+	}}
+	service := NewTranscriptionService(noteService, transcriber)
+	mp3 := []byte("ID3\x04\x00\x00\x00\x00\x00\x00")
+
+	result, err := service.TranscribeMemo(ctx, userID, mp3, "recording.bin", &models.TranscribeRequest{Tags: []string{"garden"}, Language: "EN", Prettify: true})
+	if err != nil {
+		t.Fatalf("Failed to transcribe memo: %v", err)
+	}
+	if want := "Voice memo, 0:04\n\n[0:00] Remember to water the tomatoes.\n\n#voicememo #garden"; result.Content != want {
+		t.Errorf("Unexpected content:\n%s", result.Content)
+	}
+	if result.Duration != 4 || result.Prettified || len(result.Tags) != 2 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if transcriber.filenames[0] != "memo.mp3" || transcriber.languages[0] != "en" {
+		t.Errorf("Expected the detected format and language to be sent, got %v %v", transcriber.filenames, transcriber.languages)
+	}
+
+	// Notes are prettified when asked and possible, and created either way
+	prettifier := &fakePrettifier{noteService: noteService}
+	service.SetPrettifier(prettifier)
+	result, err = service.TranscribeMemo(ctx, userID, mp3, "", &models.TranscribeRequest{Prettify: true})
+	if err != nil {
+		t.Fatalf("Failed to transcribe memo: %v", err)
+	}
+	if !result.Prettified || result.Title == nil || *result.Title != "REMEMBER TO WATER THE TOMATOES." {
+		t.Errorf("Expected a prettified note, got %+v", result)
+	}
+	prettifier.err = errors.New("provider down")
+	result, err = service.TranscribeMemo(ctx, userID, mp3, "", &models.TranscribeRequest{Prettify: true})
+	if err != nil {
+		t.Fatalf("Failed to transcribe memo: %v", err)
+	}
+	if result.Prettified {
+		t.Errorf("Expected the note without prettifying, got %+v", result)
+	}
+
+	failures := []struct {
+		name       string
+		audio      []byte
+		request    models.TranscribeRequest
+		transcript *transcription.Transcript
+		err        error
+		kind       error
+	}{
+		{"not audio", []byte("%PDF-1.7"), models.TranscribeRequest{}, transcriber.transcript, nil, apperrors.ErrValidation},
+		{"invalid language", mp3, models.TranscribeRequest{Language: "english"}, transcriber.transcript, nil, apperrors.ErrValidation},
+		{"silence", mp3, models.TranscribeRequest{}, &transcription.Transcript{Text: " "}, nil, apperrors.ErrValidation},
+		{"undecodable audio", mp3, models.TranscribeRequest{}, nil, transcription.ErrInvalidAudio, apperrors.ErrValidation},
+		{"backend down", mp3, models.TranscribeRequest{}, nil, transcription.ErrUnavailable, apperrors.ErrUnavailable},
+	}
+	for _, failure := range failures {
+		transcriber.transcript, transcriber.err = failure.transcript, failure.err
+		if _, err := service.TranscribeMemo(ctx, userID, failure.audio, "", &failure.request); !errors.Is(err, failure.kind) {
+			t.Errorf("%s: expected %v, got %v", failure.name, failure.kind, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/language"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// Translation error codes
+const (
+	codeInvalidTranslation = "INVALID_TRANSLATION"
+	codeTranslationFailed  = "TRANSLATION_FAILED"
+)
+
+// TranslationServiceInterface defines the interface for translating notes
+type TranslationServiceInterface interface {
+	TranslateNote(ctx context.Context, userID, noteID string, request *models.TranslateRequest) (*models.TranslatedNote, error)
+}
+
+// TranslationService translates notes with the LLM. Translations become new
+// notes linking to the original, so both stay searchable and editable.
+type TranslationService struct {
+	db          *sql.DB
+	noteService NoteServiceInterface
+	llm         TextGenerator
+	prompts     *prompts.Registry
+}
+
+// NewTranslationService creates a new TranslationService
+func NewTranslationService(db *sql.DB, noteService NoteServiceInterface, llm TextGenerator) *TranslationService {
+	return &TranslationService{
+		db:          db,
+		noteService: noteService,
+		llm:         llm,
+	}
+}
+
+// SetPrompts sets the registry the translation prompt is rendered from. The
+// built-in prompt is used otherwise.
+func (s *TranslationService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// translationLLMResponse is the JSON the translation prompt asks for
+type translationLLMResponse struct {
+	SourceLanguages []string `json:"source_languages"`
+	Title           string   `json:"title"`
+	Content         string   `json:"content"`
+}
+
+// TranslateNote translates a note the user can read to the request's
+// language and stores the translation as a new note of theirs, in the
+// original's notebook when they can write there. The languages of the note
+// are detected by the LLM; parts already in the requested language are kept.
+// Translations that lose URLs, hashtags or code are refused.
+func (s *TranslationService) TranslateNote(ctx context.Context, userID, noteID string, request *models.TranslateRequest) (*models.TranslatedNote, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidTranslation, err)
+	}
+
+	note, err := s.noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	// Private notes are encrypted at rest and must not be sent to the LLM
+	if note.IsPrivate {
+		return nil, apperrors.Validation("PRIVATE_NOTE", "private notes cannot be translated")
+	}
+	if strings.TrimSpace(note.Content) == "" {
+		return nil, apperrors.Validation("NOTE_EMPTY", "note has no content to translate")
+	}
+
+	title := ""
+	if note.Title != nil {
+		title = *note.Title
+	}
+	prompt, err := promptsOrDefault(s.prompts).Render(prompts.Translate, prompts.TranslateData{
+		Language: language.Code(request.Language).Name(),
+		Title:    title,
+		Content:  note.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeatureTranslate), prompt)
+	if err != nil {
+		return nil, fmt.Errorf("LLM translation failed: %w", err)
+	}
+	var result translationLLMResponse
+	if err := parseTranslationResponse(response, &result); err != nil {
+		log.Printf("[TranslationService] WARNING: Invalid translation of note %s: %v", noteID, err)
+		return nil, apperrors.New(apperrors.ErrUnavailable, codeTranslationFailed, "the translation could not be read, try again")
+	}
+
+	sourceLanguages := normalizeLanguages(result.SourceLanguages)
+	if len(sourceLanguages) == 1 && sourceLanguages[0] == request.Language {
+		return nil, apperrors.Validation("NOTE_ALREADY_IN_LANGUAGE",
+			fmt.Sprintf("note is already in %s", language.Code(request.Language).Name()))
+	}
+	if lost := lostContent(note.Content, result.Content); len(lost) > 0 {
+		log.Printf("[TranslationService] WARNING: Translation of note %s lost %d items", noteID, len(lost))
+		return nil, apperrors.New(apperrors.ErrUnavailable, codeTranslationFailed, "the translation lost URLs, hashtags or code, try again")
+	}
+
+	translation := &models.Translation{
+		SourceLanguages: sourceLanguages,
+		Title:           result.Title,
+		Content:         result.Content,
+	}
+	noteRequest, err := translation.ToCreateNoteRequest(note)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrTooLarge, "TRANSLATION_TOO_LONG", err)
+	}
+
+	// The translation goes to the original's notebook when the user can
+	// write there, and to their default notebook otherwise
+	target := &models.Note{UserID: uuid.MustParse(userID), NotebookID: note.NotebookID}
+	err = checkNoteNotebook(ctx, s.db, userID, target)
+	if err == nil {
+		noteRequest.NotebookID = &note.NotebookID
+	} else if !errors.Is(err, apperrors.ErrValidation) && !errors.Is(err, apperrors.ErrForbidden) {
+		return nil, err
+	}
+
+	translated, err := s.noteService.CreateNote(ctx, userID, noteRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TranslatedNote{
+		NoteResponse:    translated.ToResponse(),
+		Language:        request.Language,
+		SourceLanguages: sourceLanguages,
+	}, nil
+}
+
+// normalizeLanguages lowercases the language codes the LLM detected,
+// dropping duplicates and anything that is not a two-letter code
+func normalizeLanguages(codes []string) []string {
+	languages := []string{}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if len(code) != 2 || seen[code] {
+			continue
+		}
+		seen[code] = true
+		languages = append(languages, code)
+	}
+	return languages
+}
+
+// parseTranslationResponse extracts the JSON object of an LLM response,
+// which may be wrapped in text or a code block
+func parseTranslationResponse(response string, result *translationLLMResponse) error {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return fmt.Errorf("no JSON found in response")
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), result); err != nil {
+		return err
+	}
+	if strings.TrimSpace(result.Content) == "" {
+		return fmt.Errorf("response has no content")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestTranslateNote(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	generator := &fakeSummarizer{summary: "```json\n" +
+		`{"source_languages": ["ID", "en", "id"], "title": "Meeting notes", "content": "We need to send the report tomorrow.\nAgenda: https://example.com/agenda #work"}` + "\n```"}
+	service := NewTranslationService(db, noteService, generator)
+
+	note, err := noteService.CreateNote(ctx, userID, &models.CreateNoteRequest{
+		Title:   "Catatan rapat",
+		Content: "Kita harus kirim report besok.\nAgenda: https://example.com/agenda #work",
+		Color:   "green",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	noteID := note.ID.String()
+
+	translated, err := service.TranslateNote(ctx, userID, noteID, &models.TranslateRequest{Language: "EN"})
+	if err != nil {
+		t.Fatalf("Failed to translate note: %v", err)
+	}
+	if translated.ID == note.ID || *translated.Title != "Meeting notes" || translated.Color != "green" {
+		t.Errorf("Unexpected translation %+v", translated.NoteResponse)
+	}
+	if !strings.HasPrefix(translated.Content, "We need to send the report tomorrow.") ||
+		!strings.HasSuffix(translated.Content, "Translated from [["+noteID+"]]") {
+		t.Errorf("Unexpected content:\n%s", translated.Content)
+	}
+	if translated.Language != "en" || strings.Join(translated.SourceLanguages, ",") != "id,en" {
+		t.Errorf("Unexpected languages %q from %v", translated.Language, translated.SourceLanguages)
+	}
+	if len(generator.prompts) != 1 || !strings.Contains(generator.prompts[0], "to English") {
+		t.Errorf("Expected one prompt translating to English, got %v", generator.prompts)
+	}
+
+	original, err := noteService.GetNoteByID(ctx, userID, noteID)
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if original.Content != note.Content || original.Version != note.Version {
+		t.Errorf("Expected the original to be unchanged, got %q", original.Content)
+	}
+
+	generator.summary = `{"source_languages": ["en"], "title": "Catatan rapat", "content": "Same"}`
+	if _, err := service.TranslateNote(ctx, userID, noteID, &models.TranslateRequest{Language: "en"}); err == nil ||
+		!strings.Contains(err.Error(), "already in English") {
+		t.Errorf("Expected notes already in the language to be refused, got %v", err)
+	}
+
+	// Translations losing URLs or hashtags are refused
+	generator.summary = `{"source_languages": ["id"], "title": "Meeting notes", "content": "We need to send the report tomorrow.\nAgenda #pekerjaan"}`
+	if _, err := service.TranslateNote(ctx, userID, noteID, &models.TranslateRequest{Language: "en"}); !errors.Is(err, apperrors.ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+
+	if _, err := service.TranslateNote(ctx, userID, noteID, &models.TranslateRequest{Language: "klingon"}); !errors.Is(err, apperrors.ErrValidation) {
+		t.Errorf("Expected unsupported languages to be refused, got %v", err)
+	}
+	other := testutil.NewTestUser(t, db)
+	if _, err := service.TranslateNote(ctx, other.ID.String(), noteID, &models.TranslateRequest{Language: "en"}); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("Expected other users' notes to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/totp"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued at a time
+	recoveryCodeCount = 10
+	// recoveryCodeLength is the length of a recovery code, without the dash
+	// it is shown with
+	recoveryCodeLength = 10
+	// recoveryCodeAlphabet leaves out characters that are easily confused
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	// twoFactorSkew is the number of time steps a code may be off by, to
+	// allow for the clock of the phone
+	twoFactorSkew = 1
+)
+
+// Errors of two-factor authentication
+var (
+	ErrTwoFactorRequired     = apperrors.New(apperrors.ErrUnauthorized, "TWO_FACTOR_REQUIRED", "two-factor code required")
+	ErrInvalidTwoFactorCode  = apperrors.New(apperrors.ErrForbidden, "INVALID_TWO_FACTOR_CODE", "invalid two-factor code")
+	ErrTwoFactorEnabled      = apperrors.New(apperrors.ErrConflict, "TWO_FACTOR_ENABLED", "two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled   = apperrors.New(apperrors.ErrConflict, "TWO_FACTOR_NOT_ENABLED", "two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolling = apperrors.New(apperrors.ErrConflict, "TWO_FACTOR_NOT_ENROLLING", "no enrollment to confirm, start one first")
+)
+
+// TwoFactorServiceInterface defines the interface for TOTP two-factor
+// authentication
+type TwoFactorServiceInterface interface {
+	GetStatus(ctx context.Context, userID uuid.UUID) (*models.TwoFactorStatus, error)
+	BeginEnrollment(ctx context.Context, user *models.User) (*models.TwoFactorEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	Disable(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// TwoFactorService manages optional TOTP two-factor authentication. Users
+// enroll an authenticator app with a secret, enable it with a first code and
+// then need a code, or one of their single-use recovery codes, to sign in.
+// Recovery codes are stored hashed; secrets are encrypted when a master key
+// is configured.
+type TwoFactorService struct {
+	db        *sql.DB
+	issuer    string
+	encryptor *encryption.NoteEncryptor
+	now       func() time.Time
+}
+
+// NewTwoFactorService creates a new TwoFactorService. issuer names the
+// service in authenticator apps.
+func NewTwoFactorService(db *sql.DB, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		db:     db,
+		issuer: issuer,
+		now:    time.Now,
+	}
+}
+
+// SetEncryptor sets the encryptor of stored secrets. Without an available
+// key secrets are stored in clear.
+func (s *TwoFactorService) SetEncryptor(encryptor *encryption.NoteEncryptor) {
+	s.encryptor = encryptor
+}
+
+// twoFactorCredential is the stored secret of a user
+type twoFactorCredential struct {
+	secret       string
+	enabledAt    *time.Time
+	lastUsedStep int64
+}
+
+// getCredential returns the credential of a user, nil when there is none
+func (s *TwoFactorService) getCredential(ctx context.Context, userID uuid.UUID) (*twoFactorCredential, error) {
+	var credential twoFactorCredential
+	err := s.db.QueryRowContext(ctx, `
+		SELECT secret, enabled_at, last_used_step FROM two_factor_credentials WHERE user_id = $1
+	`, userID).Scan(&credential.secret, &credential.enabledAt, &credential.lastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor credential: %w", err)
+	}
+
+	if encryption.IsEncrypted(credential.secret) {
+		credential.secret, err = s.encryptor.Decrypt(ctx, userID, credential.secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt two-factor secret: %w", err)
+		}
+	}
+	return &credential, nil
+}
+
+// GetStatus returns whether the user has enabled two-factor authentication
+// and how many recovery codes are left
+func (s *TwoFactorService) GetStatus(ctx context.Context, userID uuid.UUID) (*models.TwoFactorStatus, error) {
+	status := &models.TwoFactorStatus{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT enabled_at FROM two_factor_credentials WHERE user_id = $1
+	`, userID).Scan(&status.EnabledAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get two-factor status: %w", err)
+	}
+	status.Enabled = status.EnabledAt != nil
+	if !status.Enabled {
+		return status, nil
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&status.RecoveryCodesRemaining)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a new secret for the user, replacing that of an
+// enrollment not confirmed yet. Two-factor authentication is enabled once
+// ConfirmEnrollment gets a code of the secret.
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, user *models.User) (*models.TwoFactorEnrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := secret
+	if s.encryptor.Available() {
+		stored, err = s.encryptor.Encrypt(ctx, user.ID, secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt two-factor secret: %w", err)
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO two_factor_credentials (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = excluded.secret, last_used_step = 0, created_at = excluded.created_at
+		WHERE two_factor_credentials.enabled_at IS NULL
+	`, user.ID, stored, s.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to store two-factor secret: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURL: totp.ProvisioningURI(secret, s.issuer, user.Email),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user enters a
+// code of the enrolled secret, and returns the recovery codes
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	credential, err := s.getCredential(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+	if credential.enabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	step, ok := totp.Validate(credential.secret, code, s.now(), twoFactorSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE two_factor_credentials SET enabled_at = $1, last_used_step = $2
+		WHERE user_id = $3 AND enabled_at IS NULL
+	`, s.now(), step, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, ErrTwoFactorEnabled
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET two_factor_enabled = true WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit two-factor enrollment: %w", err)
+	}
+	return codes, nil
+}
+
+// Verify checks a code from the authenticator app or an unused recovery
+// code, which is then spent. A code of the app is only accepted once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	credential, err := s.getCredential(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if credential == nil || credential.enabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := totp.Validate(credential.secret, code, s.now(), twoFactorSkew); ok {
+		// Codes at or before the last step accepted are replays
+		result, err := s.db.ExecContext(ctx, `
+			UPDATE two_factor_credentials SET last_used_step = $1
+			WHERE user_id = $2 AND last_used_step < $1
+		`, step, userID)
+		if err != nil {
+			return fmt.Errorf("failed to record two-factor code: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != recoveryCodeLength {
+		return ErrInvalidTwoFactorCode
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE two_factor_recovery_codes SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+	`, s.now(), userID, hashRecoveryCode(normalized))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	log.Printf("Recovery code used by user %s", userID)
+	return nil
+}
+
+// Disable turns two-factor authentication off after checking a code, and
+// forgets the secret and the recovery codes
+func (s *TwoFactorService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM two_factor_credentials WHERE user_id = $1`,
+		`UPDATE users SET two_factor_enabled = false WHERE id = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit disabling two-factor authentication: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after
+// checking a code, and returns the new ones
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	codes, err := s.replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// replaceRecoveryCodes stores new recovery codes for the user in tx in place
+// of the old ones, and returns them formatted for display
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO two_factor_recovery_codes (id, user_id, code_hash, created_at)
+			VALUES ($1, $2, $3, $4)
+		`, uuid.New(), userID, hashRecoveryCode(code), s.now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random recovery code, without its dash
+func generateRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	code := make([]byte, recoveryCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeRecoveryCode lowercases a recovery code and removes the dashes
+// and spaces users may type it with
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashRecoveryCode returns the hex SHA-256 of a normalized recovery code
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
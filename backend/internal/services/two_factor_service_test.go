@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/gpd/my-notes/internal/totp"
+)
+
+func TestTwoFactor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewTwoFactorService(db, "Silence Notes")
+	keyProvider, err := encryption.NewStaticKeyProvider("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	service.SetEncryptor(encryption.NewTwoFactorEncryptor(keyProvider))
+	userService := NewUserService(db)
+	ctx := context.Background()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	user := testutil.NewTestUser(t, db)
+	if err := service.Verify(ctx, user.ID, "123456"); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Errorf("Expected ErrTwoFactorNotEnabled before enrolling, got %v", err)
+	}
+	if _, err := service.ConfirmEnrollment(ctx, user.ID, "123456"); !errors.Is(err, ErrTwoFactorNotEnrolling) {
+		t.Errorf("Expected ErrTwoFactorNotEnrolling, got %v", err)
+	}
+
+	enrollment, err := service.BeginEnrollment(ctx, user)
+	if err != nil {
+		t.Fatalf("BeginEnrollment failed: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.OTPAuthURL, "otpauth://totp/Silence%20Notes:") || !strings.Contains(enrollment.OTPAuthURL, enrollment.Secret) {
+		t.Errorf("Unexpected provisioning URI %s", enrollment.OTPAuthURL)
+	}
+
+	// The secret is not stored in clear
+	var stored string
+	if err := db.QueryRow("SELECT secret FROM two_factor_credentials WHERE user_id = $1", user.ID).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read secret: %v", err)
+	}
+	if !encryption.IsEncrypted(stored) {
+		t.Error("Expected the secret to be encrypted")
+	}
+
+	if _, err := service.ConfirmEnrollment(ctx, user.ID, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+	code, _ := totp.Code(enrollment.Secret, now)
+	recoveryCodes, err := service.ConfirmEnrollment(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("ConfirmEnrollment failed: %v", err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", recoveryCodeCount, len(recoveryCodes))
+	}
+	if enabled, _ := userService.GetByID(ctx, user.ID.String()); !enabled.TwoFactorEnabled {
+		t.Error("Expected the user to have two-factor authentication enabled")
+	}
+	if _, err := service.BeginEnrollment(ctx, user); !errors.Is(err, ErrTwoFactorEnabled) {
+		t.Errorf("Expected ErrTwoFactorEnabled when enrolling again, got %v", err)
+	}
+
+	// The code that enabled it cannot be replayed, the next one is accepted
+	if err := service.Verify(ctx, user.ID, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+	now = now.Add(totp.Period)
+	code, _ = totp.Code(enrollment.Secret, now)
+	if err := service.Verify(ctx, user.ID, code); err != nil {
+		t.Errorf("Expected the next code to be accepted, got %v", err)
+	}
+
+	// Recovery codes work once, typed in any case and without the dash
+	recoveryCode := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", ""))
+	if err := service.Verify(ctx, user.ID, recoveryCode); err != nil {
+		t.Errorf("Expected the recovery code to be accepted, got %v", err)
+	}
+	if err := service.Verify(ctx, user.ID, recoveryCodes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+	status, err := service.GetStatus(ctx, user.ID)
+	if err != nil || !status.Enabled || status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Errorf("Expected %d recovery codes left, got %+v, %v", recoveryCodeCount-1, status, err)
+	}
+
+	regenerated, err := service.RegenerateRecoveryCodes(ctx, user.ID, recoveryCodes[1])
+	if err != nil || len(regenerated) != recoveryCodeCount {
+		t.Fatalf("RegenerateRecoveryCodes failed: %v", err)
+	}
+	if err := service.Verify(ctx, user.ID, recoveryCodes[2]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected replaced recovery codes to be rejected, got %v", err)
+	}
+
+	if err := service.Disable(ctx, user.ID, regenerated[0]); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	status, err = service.GetStatus(ctx, user.ID)
+	if err != nil || status.Enabled {
+		t.Errorf("Expected two-factor authentication disabled, got %+v, %v", status, err)
+	}
+	if disabled, _ := userService.GetByID(ctx, user.ID.String()); disabled.TwoFactorEnabled {
+		t.Error("Expected the user to have two-factor authentication disabled")
+	}
+}
@@ -3,53 +3,80 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/gpd/my-notes/internal/apperrors"
 	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/database"
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/google/uuid"
 )
 
 // UserServiceInterface defines the interface for user service operations
 type UserServiceInterface interface {
-	CreateOrUpdateFromGoogle(userInfo *auth.GoogleUserInfo) (*models.User, error)
-	GetByID(userID string) (*models.User, error)
-	Update(user *models.User) (*models.User, error)
-	Delete(userID string) error
-	CreateSession(userID, ipAddress, userAgent string) (*models.UserSession, error)
-	UpdateSessionActivity(sessionID, ipAddress, userAgent string) error
-	GetActiveSessions(userID string) ([]models.UserSession, error)
-	DeleteSession(sessionID, userID string) error
-	DeleteAllSessions(userID string) error
-	GetUserStats(userID string) (*models.UserStats, error)
-	SearchUsers(query string, page, limit int) ([]models.User, int, error)
+	CreateOrUpdateFromGoogle(ctx context.Context, userInfo *auth.GoogleUserInfo) (*models.User, error)
+	GetByID(ctx context.Context, userID string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) (*models.User, error)
+	Delete(ctx context.Context, userID string) error
+	ScheduleDeletion(ctx context.Context, userID string) (*time.Time, error)
+	CancelDeletion(ctx context.Context, userID string) error
+	CreateSession(ctx context.Context, userID, ipAddress, userAgent string) (*models.UserSession, error)
+	UpdateSessionActivity(ctx context.Context, sessionID, ipAddress, userAgent string) error
+	GetActiveSessions(ctx context.Context, userID string) ([]models.UserSession, error)
+	DeleteSession(ctx context.Context, sessionID, userID string) error
+	DeleteAllSessions(ctx context.Context, userID string) error
+	GetUserStats(ctx context.Context, userID string) (*models.UserStats, error)
+	GetSettings(ctx context.Context, userID string) (*models.UserSettings, error)
+	UpdateSettings(ctx context.Context, userID string, request *models.UpdateUserSettingsRequest) (*models.UserSettings, error)
+	SearchUsers(ctx context.Context, query string, page, limit int) ([]models.User, int, error)
 }
 
+// ErrUserNotFound is returned for users that do not exist
+var ErrUserNotFound = apperrors.NotFound("USER_NOT_FOUND", "user not found")
+
+// ErrDeletionNotScheduled is returned when restoring an account that is not
+// scheduled for deletion
+var ErrDeletionNotScheduled = apperrors.Conflict("DELETION_NOT_SCHEDULED", "account is not scheduled for deletion")
+
 // UserService handles user-related operations
 type UserService struct {
-	db *sql.DB
+	db            *sql.DB
+	dialect       database.Dialect
+	legalHolds    LegalHoldChecker
+	deletionGrace time.Duration
 }
 
 // NewUserService creates a new UserService instance
 func NewUserService(db *sql.DB) *UserService {
 	return &UserService{
-		db: db,
+		db:      db,
+		dialect: database.DialectOf(db),
 	}
 }
 
-// CreateOrUpdateFromGoogle creates a new user or updates an existing one from Google OAuth info
-func (s *UserService) CreateOrUpdateFromGoogle(userInfo *auth.GoogleUserInfo) (*models.User, error) {
-	ctx := context.Background()
+// SetLegalHolds sets the checker preventing deletion of accounts under legal hold
+func (s *UserService) SetLegalHolds(legalHolds LegalHoldChecker) {
+	s.legalHolds = legalHolds
+}
+
+// SetDeletionGracePeriod sets how long accounts scheduled for deletion can be
+// restored before they are purged. Without one, accounts are purged at once.
+func (s *UserService) SetDeletionGracePeriod(grace time.Duration) {
+	s.deletionGrace = grace
+}
 
+// CreateOrUpdateFromGoogle creates a new user or updates an existing one from Google OAuth info
+func (s *UserService) CreateOrUpdateFromGoogle(ctx context.Context, userInfo *auth.GoogleUserInfo) (*models.User, error) {
 	// Check if user exists
 	var user models.User
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, google_id, email, avatar_url, created_at, updated_at
+		`SELECT id, google_id, email, avatar_url, created_at, updated_at, role, disabled_at
 		 FROM users WHERE google_id = $1`,
 		userInfo.ID).Scan(
 		&user.ID, &user.GoogleID, &user.Email, &user.AvatarURL,
-		&user.CreatedAt, &user.UpdatedAt)
+		&user.CreatedAt, &user.UpdatedAt, &user.Role, &user.DisabledAt)
 
 	if err == sql.ErrNoRows {
 		// Create new user
@@ -60,6 +87,7 @@ func (s *UserService) CreateOrUpdateFromGoogle(userInfo *auth.GoogleUserInfo) (*
 			AvatarURL: &userInfo.Picture,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
+			Role:      models.UserRoleUser,
 		}
 
 		err = s.createUser(ctx, &user)
@@ -83,19 +111,19 @@ func (s *UserService) CreateOrUpdateFromGoogle(userInfo *auth.GoogleUserInfo) (*
 }
 
 // GetByID retrieves a user by ID
-func (s *UserService) GetByID(userID string) (*models.User, error) {
-	ctx := context.Background()
-
+func (s *UserService) GetByID(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, google_id, email, avatar_url, created_at, updated_at
+		`SELECT id, google_id, email, avatar_url, created_at, updated_at, read_only_until, role, disabled_at,
+		        deletion_scheduled_at, two_factor_enabled
 		 FROM users WHERE id = $1`,
 		userID).Scan(
 		&user.ID, &user.GoogleID, &user.Email, &user.AvatarURL,
-		&user.CreatedAt, &user.UpdatedAt)
+		&user.CreatedAt, &user.UpdatedAt, &user.ReadOnlyUntil, &user.Role, &user.DisabledAt,
+		&user.DeletionScheduledAt, &user.TwoFactorEnabled)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
@@ -104,19 +132,19 @@ func (s *UserService) GetByID(userID string) (*models.User, error) {
 }
 
 // GetByEmail retrieves a user by email
-func (s *UserService) GetByEmail(email string) (*models.User, error) {
-	ctx := context.Background()
-
+func (s *UserService) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, google_id, email, avatar_url, created_at, updated_at
+		`SELECT id, google_id, email, avatar_url, created_at, updated_at, read_only_until, role, disabled_at,
+		        deletion_scheduled_at, two_factor_enabled
 		 FROM users WHERE email = $1`,
 		email).Scan(
 		&user.ID, &user.GoogleID, &user.Email, &user.AvatarURL,
-		&user.CreatedAt, &user.UpdatedAt)
+		&user.CreatedAt, &user.UpdatedAt, &user.ReadOnlyUntil, &user.Role, &user.DisabledAt,
+		&user.DeletionScheduledAt, &user.TwoFactorEnabled)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
@@ -125,9 +153,7 @@ func (s *UserService) GetByEmail(email string) (*models.User, error) {
 }
 
 // CreateSession creates a new user session
-func (s *UserService) CreateSession(userID, ipAddress, userAgent string) (*models.UserSession, error) {
-	ctx := context.Background()
-
+func (s *UserService) CreateSession(ctx context.Context, userID, ipAddress, userAgent string) (*models.UserSession, error) {
 	session := &models.UserSession{
 		ID:        uuid.New().String(),
 		UserID:    userID,
@@ -155,22 +181,20 @@ func (s *UserService) CreateSession(userID, ipAddress, userAgent string) (*model
 }
 
 // Update updates an existing user
-func (s *UserService) Update(user *models.User) (*models.User, error) {
-	ctx := context.Background()
-
+func (s *UserService) Update(ctx context.Context, user *models.User) (*models.User, error) {
 	user.UpdatedAt = time.Now()
 
 	query := `
 		UPDATE users
 		SET avatar_url = $1, updated_at = $2
 		WHERE id = $3
-		RETURNING id, google_id, email, avatar_url, created_at, updated_at
+		RETURNING id, google_id, email, avatar_url, created_at, updated_at, read_only_until
 	`
 
 	err := s.db.QueryRowContext(ctx, query,
 		user.AvatarURL, user.UpdatedAt, user.ID).Scan(
 		&user.ID, &user.GoogleID, &user.Email, &user.AvatarURL,
-		&user.CreatedAt, &user.UpdatedAt)
+		&user.CreatedAt, &user.UpdatedAt, &user.ReadOnlyUntil)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
@@ -180,8 +204,10 @@ func (s *UserService) Update(user *models.User) (*models.User, error) {
 }
 
 // Delete deletes a user and all associated data
-func (s *UserService) Delete(userID string) error {
-	ctx := context.Background()
+func (s *UserService) Delete(ctx context.Context, userID string) error {
+	if err := s.checkLegalHold(ctx, userID); err != nil {
+		return err
+	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -195,9 +221,20 @@ func (s *UserService) Delete(userID string) error {
 		return fmt.Errorf("failed to delete user sessions: %w", err)
 	}
 
-	// TODO: Delete user's notes and tags when those tables are created
+	// Tables without a foreign key to users are purged explicitly. Legal holds
+	// are kept, and change log records only hold IDs and expire on their own.
+	for _, table := range []string{"blacklisted_tokens", "audit_log"} {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = $1", userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete user data from %s: %w", table, err)
+		}
+	}
 
-	// Delete the user
+	if err := leaveOrganizations(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	// Delete the user; notes, tags and the rest of the user's data cascade
 	_, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
@@ -211,10 +248,109 @@ func (s *UserService) Delete(userID string) error {
 	return nil
 }
 
-// UpdateSessionActivity updates the last seen time for a session
-func (s *UserService) UpdateSessionActivity(sessionID, ipAddress, userAgent string) error {
-	ctx := context.Background()
+// checkLegalHold returns ErrLegalHold when the account is under legal hold
+func (s *UserService) checkLegalHold(ctx context.Context, userID string) error {
+	if s.legalHolds == nil {
+		return nil
+	}
+	held, err := s.legalHolds.HasActiveHold(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if held {
+		return fmt.Errorf("account is %w", ErrLegalHold)
+	}
+	return nil
+}
+
+// ScheduleDeletion schedules the account to be purged after the grace period
+// and returns when. Scheduling an account again keeps the original time.
+// Without a grace period the account is purged at once and nil is returned.
+func (s *UserService) ScheduleDeletion(ctx context.Context, userID string) (*time.Time, error) {
+	if s.deletionGrace <= 0 {
+		return nil, s.Delete(ctx, userID)
+	}
+	if err := s.checkLegalHold(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var scheduledAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2)
+		WHERE id = $1
+		RETURNING deletion_scheduled_at
+	`, userID, time.Now().Add(s.deletionGrace)).Scan(&scheduledAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+
+	return &scheduledAt, nil
+}
+
+// CancelDeletion restores an account scheduled for deletion
+func (s *UserService) CancelDeletion(ctx context.Context, userID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users SET deletion_scheduled_at = NULL
+		WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrDeletionNotScheduled
+	}
+
+	return nil
+}
+
+// PurgeScheduledDeletions deletes the accounts whose grace period has ended.
+// Accounts under legal hold stay scheduled and are purged once released.
+func (s *UserService) PurgeScheduledDeletions(ctx context.Context) (int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM users
+		WHERE deletion_scheduled_at <= $1
+	`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list accounts due for deletion: %w", err)
+	}
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan account due for deletion: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating accounts due for deletion: %w", err)
+	}
+
+	var purged int64
+	for _, userID := range userIDs {
+		if err := s.Delete(ctx, userID); err != nil {
+			if errors.Is(err, ErrLegalHold) {
+				continue
+			}
+			return purged, fmt.Errorf("failed to purge account %s: %w", userID, err)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
 
+// UpdateSessionActivity updates the last seen time for a session
+func (s *UserService) UpdateSessionActivity(ctx context.Context, sessionID, ipAddress, userAgent string) error {
 	query := `
 		UPDATE user_sessions
 		SET last_seen = $1, ip_address = $2, user_agent = $3
@@ -230,9 +366,7 @@ func (s *UserService) UpdateSessionActivity(sessionID, ipAddress, userAgent stri
 }
 
 // GetActiveSessions retrieves all active sessions for a user
-func (s *UserService) GetActiveSessions(userID string) ([]models.UserSession, error) {
-	ctx := context.Background()
-
+func (s *UserService) GetActiveSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
 	query := `
 		SELECT id, user_id, ip_address, user_agent, created_at, last_seen, is_active
 		FROM user_sessions
@@ -265,9 +399,7 @@ func (s *UserService) GetActiveSessions(userID string) ([]models.UserSession, er
 }
 
 // DeleteSession deletes a specific session for a user
-func (s *UserService) DeleteSession(sessionID, userID string) error {
-	ctx := context.Background()
-
+func (s *UserService) DeleteSession(ctx context.Context, sessionID, userID string) error {
 	query := `UPDATE user_sessions SET is_active = false WHERE id = $1 AND user_id = $2`
 	_, err := s.db.ExecContext(ctx, query, sessionID, userID)
 	if err != nil {
@@ -278,9 +410,7 @@ func (s *UserService) DeleteSession(sessionID, userID string) error {
 }
 
 // DeleteAllSessions deletes all active sessions for a user
-func (s *UserService) DeleteAllSessions(userID string) error {
-	ctx := context.Background()
-
+func (s *UserService) DeleteAllSessions(ctx context.Context, userID string) error {
 	query := `UPDATE user_sessions SET is_active = false WHERE user_id = $1`
 	_, err := s.db.ExecContext(ctx, query, userID)
 	if err != nil {
@@ -291,9 +421,7 @@ func (s *UserService) DeleteAllSessions(userID string) error {
 }
 
 // GetUserStats retrieves user statistics
-func (s *UserService) GetUserStats(userID string) (*models.UserStats, error) {
-	ctx := context.Background()
-
+func (s *UserService) GetUserStats(ctx context.Context, userID string) (*models.UserStats, error) {
 	stats := &models.UserStats{}
 
 	// Get user info for account age
@@ -344,17 +472,90 @@ func (s *UserService) GetUserStats(userID string) (*models.UserStats, error) {
 	return stats, nil
 }
 
-// SearchUsers searches for users by email
-func (s *UserService) SearchUsers(query string, page, limit int) ([]models.User, int, error) {
-	ctx := context.Background()
+// GetSettings retrieves a user's preferences
+func (s *UserService) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := s.db.QueryRowContext(ctx,
+		"SELECT auto_apply_tag_suggestions, digest_frequency, journal_template_id FROM users WHERE id = $1",
+		userID).Scan(&settings.AutoApplyTagSuggestions, &settings.DigestFrequency, &settings.JournalTemplateID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	return &settings, nil
+}
 
+// UpdateSettings updates the preferences set in the request
+func (s *UserService) UpdateSettings(ctx context.Context, userID string, request *models.UpdateUserSettingsRequest) (*models.UserSettings, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The journal template is set on its own, as COALESCE cannot clear it
+	if request.JournalTemplateID != nil {
+		var templateID *string
+		if *request.JournalTemplateID != "" {
+			templateID = request.JournalTemplateID
+			var owned bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT EXISTS (SELECT 1 FROM templates WHERE id = $1 AND user_id = $2)
+			`, *templateID, userID).Scan(&owned)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check journal template: %w", err)
+			}
+			if !owned {
+				return nil, apperrors.Validation("INVALID_JOURNAL_TEMPLATE", "journal template not found")
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users SET journal_template_id = $2 WHERE id = $1
+		`, userID, templateID); err != nil {
+			return nil, fmt.Errorf("failed to update user settings: %w", err)
+		}
+	}
+
+	var settings models.UserSettings
+	err = tx.QueryRowContext(ctx, `
+		UPDATE users
+		SET auto_apply_tag_suggestions = COALESCE($2, auto_apply_tag_suggestions),
+		    digest_frequency = COALESCE($3, digest_frequency),
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING auto_apply_tag_suggestions, digest_frequency, journal_template_id
+	`, userID, request.AutoApplyTagSuggestions, request.DigestFrequency).Scan(
+		&settings.AutoApplyTagSuggestions, &settings.DigestFrequency, &settings.JournalTemplateID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update user settings: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// SearchUsers searches for users by email
+func (s *UserService) SearchUsers(ctx context.Context, query string, page, limit int) ([]models.User, int, error) {
 	offset := (page - 1) * limit
 
 	// Get total count
 	var total int
 	err := s.db.QueryRowContext(ctx,
 		`SELECT COUNT(*) FROM users
-		 WHERE email ILIKE $1`,
+		 WHERE `+s.dialect.ILike("email", 1),
 		"%"+query+"%").Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get total users count: %w", err)
@@ -364,7 +565,7 @@ func (s *UserService) SearchUsers(query string, page, limit int) ([]models.User,
 	dbQuery := `
 		SELECT id, google_id, email, avatar_url, created_at, updated_at
 		FROM users
-		WHERE email ILIKE $1
+		WHERE ` + s.dialect.ILike("email", 1) + `
 		ORDER BY email
 		LIMIT $2 OFFSET $3
 	`
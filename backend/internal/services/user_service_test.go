@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+func TestAccountDeletionGracePeriod(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewUserService(db)
+	service.SetLegalHolds(NewLegalHoldService(db))
+	service.SetDeletionGracePeriod(time.Hour)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+	testutil.NewTestNoteWithTags(t, db, user.ID, "Quarterly plan #work", "#work")
+
+	// Restoring requires a scheduled deletion
+	if err := service.CancelDeletion(ctx, userID); !errors.Is(err, ErrDeletionNotScheduled) {
+		t.Fatalf("Expected ErrDeletionNotScheduled, got %v", err)
+	}
+
+	scheduledAt, err := service.ScheduleDeletion(ctx, userID)
+	if err != nil {
+		t.Fatalf("Failed to schedule deletion: %v", err)
+	}
+	if scheduledAt == nil || scheduledAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("Expected deletion scheduled after the grace period, got %v", scheduledAt)
+	}
+
+	// The account is kept, and restorable, during the grace period
+	if purged, err := service.PurgeScheduledDeletions(ctx); err != nil || purged != 0 {
+		t.Fatalf("Expected nothing purged during the grace period, got %d %v", purged, err)
+	}
+	if err := service.CancelDeletion(ctx, userID); err != nil {
+		t.Fatalf("Failed to restore account: %v", err)
+	}
+	if restored, err := service.GetByID(ctx, userID); err != nil || restored.DeletionScheduledAt != nil {
+		t.Fatalf("Expected a restored account, got %+v %v", restored, err)
+	}
+
+	// Once the grace period has ended, the account and its data are purged
+	if _, err := db.Exec("UPDATE users SET deletion_scheduled_at = $2 WHERE id = $1", user.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Failed to end the grace period: %v", err)
+	}
+	if purged, err := service.PurgeScheduledDeletions(ctx); err != nil || purged != 1 {
+		t.Fatalf("Expected one account purged, got %d %v", purged, err)
+	}
+	if _, err := service.GetByID(ctx, userID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected the account to be deleted, got %v", err)
+	}
+	for _, table := range []string{"notes", "tags"} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id = $1", user.ID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("Expected the user's %s to be purged, %d left", table, count)
+		}
+	}
+}
+
+func TestAccountDeletionRespectsLegalHolds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	service := NewUserService(db)
+	service.SetLegalHolds(NewLegalHoldService(db))
+	service.SetDeletionGracePeriod(time.Hour)
+	ctx := context.Background()
+
+	user := testutil.NewTestUser(t, db)
+	if _, err := db.Exec("UPDATE users SET deletion_scheduled_at = $2 WHERE id = $1", user.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Failed to schedule deletion: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO legal_holds (user_id, reason, created_by) VALUES ($1, $2, $1)", user.ID, "litigation"); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+
+	if _, err := service.ScheduleDeletion(ctx, user.ID.String()); !errors.Is(err, ErrLegalHold) {
+		t.Errorf("Expected ErrLegalHold, got %v", err)
+	}
+	if purged, err := service.PurgeScheduledDeletions(ctx); err != nil || purged != 0 {
+		t.Errorf("Expected held accounts to be skipped, got %d %v", purged, err)
+	}
+	if _, err := service.GetByID(ctx, user.ID.String()); err != nil {
+		t.Errorf("Expected the held account to be kept, got %v", err)
+	}
+}
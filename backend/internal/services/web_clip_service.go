@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/llm"
+	"github.com/gpd/my-notes/internal/llm/prompts"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/readability"
+	"golang.org/x/net/html/charset"
+)
+
+// Web clip fetch limits
+const (
+	// maxWebClipRedirects is the number of redirects followed
+	maxWebClipRedirects = 5
+	// maxWebClipPromptLength is the length of the article text sent to the
+	// LLM for a summary, in bytes
+	maxWebClipPromptLength = 12000
+)
+
+// webClipUserAgent identifies the server to the websites it fetches
+const webClipUserAgent = "Mozilla/5.0 (compatible; my-notes-webclip/1.0)"
+
+// Web clip error codes
+const (
+	codeInvalidWebClip = "INVALID_WEB_CLIP"
+	codeWebClipFailed  = "WEB_CLIP_FAILED"
+)
+
+// WebClipServiceInterface defines the interface for clipping web pages
+type WebClipServiceInterface interface {
+	ClipPage(ctx context.Context, userID string, request *models.WebClipRequest) (*models.Note, error)
+}
+
+// WebClipService fetches web pages browser extensions clip, extracts their
+// readable article and stores it as a note
+type WebClipService struct {
+	noteService NoteServiceInterface
+	httpClient  *http.Client
+	maxPageSize int64
+	llm         TextGenerator
+	prompts     *prompts.Registry
+}
+
+// NewWebClipService creates a new WebClipService. Pages larger than
+// maxPageSize bytes are refused. Unless allowInsecure is set, pages on
+// internal addresses cannot be fetched.
+func NewWebClipService(noteService NoteServiceInterface, maxPageSize int64, allowInsecure bool) *WebClipService {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowInsecure {
+		dialer.Control = denyInternalAddresses
+	}
+
+	return &WebClipService{
+		noteService: noteService,
+		httpClient: &http.Client{
+			Timeout: 20 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			// Each redirect is dialed again, so internal addresses stay
+			// refused
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxWebClipRedirects {
+					return fmt.Errorf("too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to unsupported scheme %s", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		maxPageSize: maxPageSize,
+	}
+}
+
+// SetLLM enables LLM summaries of clipped articles
+func (s *WebClipService) SetLLM(llm TextGenerator) {
+	s.llm = llm
+}
+
+// SetPrompts sets the registry the summary prompt is rendered from. The
+// built-in prompt is used otherwise.
+func (s *WebClipService) SetPrompts(registry *prompts.Registry) {
+	s.prompts = registry
+}
+
+// ClipPage fetches the page of the request's URL and creates a note of its
+// article, tagged models.WebClipTag. A summary is added when requested and
+// an LLM is configured; the page is still clipped when summarizing fails.
+func (s *WebClipService) ClipPage(ctx context.Context, userID string, request *models.WebClipRequest) (*models.Note, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidWebClip, err)
+	}
+
+	clip, err := s.fetchArticle(ctx, request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Summarize && s.llm != nil {
+		summary, err := s.summarize(ctx, userID, clip)
+		if err != nil {
+			log.Printf("[WebClipService] WARNING: Failed to summarize %s for user %s: %v", clip.URL, userID, err)
+		} else {
+			clip.Summary = summary
+		}
+	}
+
+	noteRequest, err := request.ToCreateNoteRequest(clip)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, codeInvalidWebClip, err)
+	}
+	return s.noteService.CreateNote(ctx, userID, noteRequest)
+}
+
+// fetchArticle fetches a page and extracts its article
+func (s *WebClipService) fetchArticle(ctx context.Context, pageURL string) (*models.WebClip, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrValidation, codeInvalidWebClip, "invalid url")
+	}
+	req.Header.Set("User-Agent", webClipUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, errDestinationNotAllowed) {
+			return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed, "page address is not allowed")
+		}
+		log.Printf("[WebClipService] Failed to fetch %s: %v", pageURL, err)
+		return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed, "failed to fetch page")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed,
+			fmt.Sprintf("page returned status %d", resp.StatusCode))
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); contentType != "" &&
+		(err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml")) {
+		return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed, "page is not HTML")
+	}
+	if resp.ContentLength > s.maxPageSize {
+		return nil, s.tooLarge()
+	}
+
+	// One byte more than allowed tells a page that is too large apart
+	body, err := io.ReadAll(io.LimitReader(resp.Body, s.maxPageSize+1))
+	if err != nil {
+		log.Printf("[WebClipService] Failed to read %s: %v", pageURL, err)
+		return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed, "failed to fetch page")
+	}
+	if int64(len(body)) > s.maxPageSize {
+		return nil, s.tooLarge()
+	}
+
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed, "unsupported page encoding")
+	}
+	// Links are resolved against the page redirects led to
+	article, err := readability.Extract(reader, resp.Request.URL)
+	if errors.Is(err, readability.ErrNoContent) {
+		return nil, apperrors.New(apperrors.ErrValidation, codeWebClipFailed, "page has no readable content")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract article: %w", err)
+	}
+
+	return &models.WebClip{
+		URL:     pageURL,
+		Title:   article.Title,
+		Byline:  article.Byline,
+		Content: article.Content,
+	}, nil
+}
+
+// tooLarge returns the error of pages larger than the limit
+func (s *WebClipService) tooLarge() error {
+	return apperrors.New(apperrors.ErrTooLarge, "WEB_CLIP_TOO_LARGE",
+		fmt.Sprintf("page too large (max %d MB)", s.maxPageSize>>20))
+}
+
+// summarize returns the LLM summary of a clipped article
+func (s *WebClipService) summarize(ctx context.Context, userID string, clip *models.WebClip) (string, error) {
+	content := clip.Content
+	if len(content) > maxWebClipPromptLength {
+		content = content[:maxWebClipPromptLength]
+	}
+	prompt, err := promptsOrDefault(s.prompts).Render(prompts.WebClip, prompts.WebClipData{
+		Title:   clip.Title,
+		Content: strings.ToValidUTF8(content, ""),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	summary, err := s.llm.GenerateFromSinglePrompt(llm.WithCaller(ctx, userID, LLMFeatureWebClip), prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
+)
+
+// fakeSummarizer answers every prompt with a fixed summary, recording the
+// prompts
+type fakeSummarizer struct {
+	summary string
+	err     error
+	prompts []string
+}
+
+func (f *fakeSummarizer) GenerateFromSinglePrompt(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	return f.summary, f.err
+}
+
+func TestWebClips(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	noteService := NewNoteService(db, NewTagService(db))
+	ctx := context.Background()
+	user := testutil.NewTestUser(t, db)
+	userID := user.ID.String()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Tomatoes</title><meta name="author" content="Ada"></head><body>
+			<nav><a href="/">Home</a></nav>
+			<article><p>Tomatoes need sun, water, and patience, and a balcony facing south is enough.</p>
+			<p>Read the <a href="/older">older post</a> for more, including how to choose pots.</p></article>
+			</body></html>`))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article", http.StatusFound)
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>" + strings.Repeat("a", 2048) + "</p>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service := NewWebClipService(noteService, 1024, true)
+	summarizer := &fakeSummarizer{summary: "Tomatoes grow on balconies."}
+	service.SetLLM(summarizer)
+
+	note, err := service.ClipPage(ctx, userID, &models.WebClipRequest{URL: server.URL + "/moved", Tags: []string{"garden"}, Summarize: true})
+	if err != nil {
+		t.Fatalf("Failed to clip page: %v", err)
+	}
+	for _, want := range []string{
+		"Source: " + server.URL + "/moved\nBy Ada",
+		"> **Summary:** Tomatoes grow on balconies.",
+		"Tomatoes need sun, water, and patience",
+		"[older post](" + server.URL + "/older)",
+		"#webclip #garden",
+	} {
+		if !strings.Contains(note.Content, want) {
+			t.Errorf("Expected %q in the note:\n%s", want, note.Content)
+		}
+	}
+	if strings.Contains(note.Content, "Home") {
+		t.Errorf("Expected the navigation to be left out:\n%s", note.Content)
+	}
+	if note.Title == nil || *note.Title != "Tomatoes" {
+		t.Errorf("Expected the page title as the note title, got %v", note.Title)
+	}
+	if note.Metadata == nil || note.Metadata.Source != models.WebClipSource || note.Metadata.SourceURL != server.URL+"/moved" {
+		t.Errorf("Unexpected metadata %+v", note.Metadata)
+	}
+	if len(summarizer.prompts) != 1 || !strings.Contains(summarizer.prompts[0], "Title: Tomatoes") {
+		t.Errorf("Expected one summary prompt of the article, got %v", summarizer.prompts)
+	}
+
+	// The page is clipped without a summary when summarizing fails or was
+	// not asked for
+	summarizer.err = errors.New("provider down")
+	note, err = service.ClipPage(ctx, userID, &models.WebClipRequest{URL: server.URL + "/article", Summarize: true})
+	if err != nil {
+		t.Fatalf("Failed to clip page: %v", err)
+	}
+	if strings.Contains(note.Content, "Summary") {
+		t.Errorf("Expected no summary, got:\n%s", note.Content)
+	}
+	if _, err := service.ClipPage(ctx, userID, &models.WebClipRequest{URL: server.URL + "/article"}); err != nil {
+		t.Fatalf("Failed to clip page: %v", err)
+	}
+	if len(summarizer.prompts) != 2 {
+		t.Errorf("Expected no summary without summarize, got %d prompts", len(summarizer.prompts))
+	}
+
+	failures := []struct {
+		url  string
+		kind error
+	}{
+		{"javascript:alert(1)", apperrors.ErrValidation},
+		{server.URL + "/missing", apperrors.ErrValidation},
+		{server.URL + "/image", apperrors.ErrValidation},
+		{server.URL + "/large", apperrors.ErrTooLarge},
+	}
+	for _, failure := range failures {
+		if _, err := service.ClipPage(ctx, userID, &models.WebClipRequest{URL: failure.url}); !errors.Is(err, failure.kind) {
+			t.Errorf("Expected %v clipping %s, got %v", failure.kind, failure.url, err)
+		}
+	}
+
+	// Internal addresses are refused unless insecure pages are allowed
+	secure := NewWebClipService(noteService, 1024, false)
+	_, err = secure.ClipPage(ctx, userID, &models.WebClipRequest{URL: server.URL + "/article"})
+	var appErr *apperrors.Error
+	if !errors.As(err, &appErr) || appErr.Message != "page address is not allowed" {
+		t.Errorf("Expected the internal address to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gpd/my-notes/internal/apperrors"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Retry policy for webhook deliveries. Failed attempts are retried with
+// exponential backoff until webhookMaxAttempts is reached.
+const (
+	webhookMaxAttempts = 8
+	webhookRetryDelay  = 30 * time.Second
+	webhookMaxDelay    = 6 * time.Hour
+)
+
+const (
+	// maxWebhooksPerUser limits the webhooks a user can register
+	maxWebhooksPerUser = 10
+	// webhookBatchSize is the number of deliveries attempted per run
+	webhookBatchSize = 20
+	// webhookLease keeps a claimed delivery from being picked up by another
+	// run while it is being attempted
+	webhookLease = 10 * time.Minute
+	// webhookDeliveryRetention is how long delivery logs are kept
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+	// maxWebhookErrorLength caps the response excerpt stored with a failure
+	maxWebhookErrorLength = 500
+)
+
+// WebhookServiceInterface defines the interface for webhook operations
+type WebhookServiceInterface interface {
+	CreateWebhook(ctx context.Context, userID string, request *models.CreateWebhookRequest) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context, userID string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, webhookID string) error
+	ListDeliveries(ctx context.Context, userID, webhookID string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// ErrWebhookNotFound is returned for webhooks that do not exist
+var ErrWebhookNotFound = apperrors.NotFound("WEBHOOK_NOT_FOUND", "webhook not found")
+
+// WebhookService manages outbound webhooks and sends their deliveries.
+// Deliveries are queued by database triggers in the transaction writing the
+// note or tag, so an event is only ever sent for a committed change.
+type WebhookService struct {
+	db            *sql.DB
+	httpClient    *http.Client
+	allowInsecure bool
+}
+
+// NewWebhookService creates a new WebhookService. Unless allowInsecure is
+// set, webhooks must use https and cannot reach internal addresses.
+func NewWebhookService(db *sql.DB, allowInsecure bool) *WebhookService {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowInsecure {
+		dialer.Control = denyInternalAddresses
+	}
+
+	return &WebhookService{
+		db: db,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			// A redirect could lead the payload to an address that was not checked
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowInsecure: allowInsecure,
+	}
+}
+
+// normalizeWebhookURL validates the URL deliveries are posted to
+func normalizeWebhookURL(raw string, allowInsecure bool) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return "", apperrors.Validation("INVALID_WEBHOOK_URL", "invalid webhook URL")
+	}
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && allowInsecure) {
+		return "", apperrors.Validation("INVALID_WEBHOOK_URL", "webhook URL must use https")
+	}
+	if parsed.User != nil || parsed.Fragment != "" {
+		return "", apperrors.Validation("INVALID_WEBHOOK_URL", "invalid webhook URL")
+	}
+	return parsed.String(), nil
+}
+
+// CreateWebhook registers a webhook for a user
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID string, request *models.CreateWebhookRequest) (*models.Webhook, error) {
+	if err := request.Validate(); err != nil {
+		return nil, apperrors.Wrap(apperrors.ErrValidation, "INVALID_WEBHOOK", err)
+	}
+	endpoint, err := normalizeWebhookURL(request.URL, s.allowInsecure)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhooks WHERE user_id = $1", userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, apperrors.Validation("WEBHOOK_LIMIT_REACHED", fmt.Sprintf("webhook limit of %d reached", maxWebhooksPerUser))
+	}
+
+	webhook := &models.Webhook{
+		UserID: uuid.MustParse(userID),
+		URL:    endpoint,
+		Secret: request.Secret,
+		Events: request.Events,
+		Active: true,
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, userID, endpoint, request.Secret, pq.Array(request.Events)).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns a user's webhooks, newest first
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, url, events, active, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, pq.Array(&webhook.Events),
+			&webhook.Active, &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook together with its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, webhookID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM webhooks WHERE id = $1 AND user_id = $2
+	`, webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a user's webhook
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)
+	`, webhookID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, webhook_id, event, payload, status, attempts, response_code, error,
+			next_attempt_at, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var nextAttemptAt time.Time
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload,
+			&delivery.Status, &delivery.Attempts, &delivery.ResponseCode, &delivery.Error,
+			&nextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if delivery.Status == models.WebhookDeliveryPending {
+			delivery.NextAttemptAt = &nextAttemptAt
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// pendingDelivery is a claimed delivery together with its webhook
+type pendingDelivery struct {
+	id       uuid.UUID
+	event    string
+	payload  []byte
+	attempts int
+	url      string
+	secret   string
+}
+
+// DeliverPending attempts the deliveries that are due and returns how many
+// succeeded. Deliveries are claimed before they are sent, so concurrent runs
+// never attempt the same delivery.
+func (s *WebhookService) DeliverPending(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := s.claimDeliveries(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		code, sendErr := s.send(ctx, delivery)
+		if err := s.recordAttempt(ctx, delivery, code, sendErr, time.Now()); err != nil {
+			log.Printf("[WebhookService] WARNING: Failed to record delivery %s: %v", delivery.id, err)
+			continue
+		}
+		if sendErr == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// claimDeliveries leases the pending deliveries due at now
+func (s *WebhookService) claimDeliveries(ctx context.Context, now time.Time) ([]pendingDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM webhooks w
+		WHERE w.id = d.webhook_id
+		  AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		  )
+		RETURNING d.id, d.event, d.payload, d.attempts, w.url, w.secret
+	`, now, now.Add(webhookLease), webhookBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []pendingDelivery
+	for rows.Next() {
+		var delivery pendingDelivery
+		if err := rows.Scan(&delivery.id, &delivery.event, &delivery.payload, &delivery.attempts,
+			&delivery.url, &delivery.secret); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// send posts a delivery to its webhook. Any response other than 2xx is a
+// failure; the status code is returned when a response was received.
+func (s *WebhookService) send(ctx context.Context, delivery pendingDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "my-notes-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.id.String())
+	req.Header.Set(WebhookSignatureHeader, signWebhookPayload(delivery.secret, time.Now(), delivery.payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	code := resp.StatusCode
+	if code < 200 || code >= 300 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorLength))
+		message := strings.TrimSpace(string(excerpt))
+		if message == "" {
+			message = http.StatusText(code)
+		}
+		return &code, fmt.Errorf("webhook responded %d: %s", code, message)
+	}
+	return &code, nil
+}
+
+// recordAttempt stores the outcome of an attempt, scheduling a retry for
+// failures until the attempts run out
+func (s *WebhookService) recordAttempt(ctx context.Context, delivery pendingDelivery, code *int, sendErr error, now time.Time) error {
+	attempts := delivery.attempts + 1
+
+	var err error
+	switch {
+	case sendErr == nil:
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', attempts = $2, response_code = $3, error = NULL, delivered_at = $4
+			WHERE id = $1
+		`, delivery.id, attempts, code, now)
+	case attempts >= webhookMaxAttempts:
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'failed', attempts = $2, response_code = $3, error = $4
+			WHERE id = $1
+		`, delivery.id, attempts, code, truncateWebhookError(sendErr))
+	default:
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET attempts = $2, response_code = $3, error = $4, next_attempt_at = $5
+			WHERE id = $1
+		`, delivery.id, attempts, code, truncateWebhookError(sendErr), now.Add(webhookBackoff(attempts)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// CleanupOldDeliveries removes delivery logs past the retention period.
+// Pending deliveries are kept until they are delivered or fail.
+func (s *WebhookService) CleanupOldDeliveries(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE created_at < $1 AND status <> 'pending'
+	`, time.Now().Add(-webhookDeliveryRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup webhook deliveries: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// webhookBackoff returns the delay before retrying a delivery that failed
+// its attempts-th attempt
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryDelay
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
+
+// signWebhookPayload returns the signature header value of a payload sent at
+// now: the Unix timestamp and the hex HMAC-SHA256 of "timestamp.payload"
+// keyed with the webhook secret. Receivers should reject old timestamps to
+// prevent replays.
+func signWebhookPayload(secret string, now time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncateWebhookError returns the error message stored with a failed attempt
+func truncateWebhookError(err error) string {
+	message := err.Error()
+	if len(message) > maxWebhookErrorLength {
+		message = message[:maxWebhookErrorLength]
+	}
+	return message
+}
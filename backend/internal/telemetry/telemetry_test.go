@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupWithoutExporterPropagatesTraceContext(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: "none"}, "test")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	// An incoming trace is passed on even though no spans are recorded
+	incoming := http.Header{}
+	incoming.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(incoming))
+
+	outgoing := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(outgoing))
+	if outgoing.Get("traceparent") != incoming.Get("traceparent") {
+		t.Errorf("Expected traceparent to be propagated, got %q", outgoing.Get("traceparent"))
+	}
+	if !trace.SpanContextFromContext(ctx).IsSampled() {
+		t.Error("Expected the incoming sampling decision to be kept")
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), config.TracingConfig{Exporter: "zipkin"}, "test"); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+}
+
+func TestSetupStdoutExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter:    "stdout",
+		ServiceName: "silence-notes-api",
+		SampleRatio: 1,
+	}, "test")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	_, span := Tracer().Start(context.Background(), "test")
+	if !span.SpanContext().IsSampled() {
+		t.Error("Expected spans to be recorded with a sample ratio of 1")
+	}
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
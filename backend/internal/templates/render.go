@@ -0,0 +1,247 @@
+// Package templates renders note templates. A template is note content with
+// placeholders:
+//
+//	{{title}}                     a variable
+//	{{title|Untitled}}            a variable with a default for when it is empty
+//	{{#if mood}}...{{/if}}        text kept when a variable is set
+//	{{#if weekday == Friday}}...{{else}}...{{/if}}
+//
+// Besides the values supplied by the caller, templates can use the computed
+// variables today, yesterday, tomorrow, now, time, weekday, user.name and
+// user.email. Supplied values take precedence over computed ones.
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DateLayout and TimeLayout format the computed date and time variables
+const (
+	DateLayout = "2006-01-02"
+	TimeLayout = "15:04"
+)
+
+// placeholderRegex matches a {{...}} placeholder
+var placeholderRegex = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// Context is what a template is rendered with
+type Context struct {
+	// Now is the time the computed date variables are based on, in the
+	// user's time zone; the zero value means the current time in UTC
+	Now time.Time
+	// UserName and UserEmail are the user.name and user.email variables
+	UserName  string
+	UserEmail string
+	// Vars are the values supplied by the caller
+	Vars map[string]string
+}
+
+// value returns the value of a variable and whether it is set
+func (c *Context) value(name string) (string, bool) {
+	if v, ok := c.Vars[name]; ok {
+		return v, true
+	}
+
+	now := c.Now
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	switch name {
+	case "today":
+		return now.Format(DateLayout), true
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format(DateLayout), true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format(DateLayout), true
+	case "now":
+		return now.Format(DateLayout + " " + TimeLayout), true
+	case "time":
+		return now.Format(TimeLayout), true
+	case "weekday":
+		return now.Weekday().String(), true
+	case "user.name":
+		return c.UserName, c.UserName != ""
+	case "user.email":
+		return c.UserEmail, c.UserEmail != ""
+	}
+	return "", false
+}
+
+// node is a parsed piece of a template
+type node interface {
+	render(b *strings.Builder, c *Context)
+}
+
+// textNode is literal text
+type textNode string
+
+func (n textNode) render(b *strings.Builder, _ *Context) {
+	b.WriteString(string(n))
+}
+
+// varNode is a {{name}} or {{name|default}} placeholder. Variables that are
+// unknown and have no default are kept as written.
+type varNode struct {
+	raw        string
+	name       string
+	def        string
+	hasDefault bool
+}
+
+func (n varNode) render(b *strings.Builder, c *Context) {
+	if v, ok := c.value(n.name); ok && v != "" {
+		b.WriteString(v)
+	} else if n.hasDefault {
+		b.WriteString(n.def)
+	} else if !ok {
+		b.WriteString(n.raw)
+	}
+}
+
+// ifNode is a {{#if ...}} block. Without an operator the condition holds
+// when the variable is set and not empty; == and != compare its value
+// case-insensitively.
+type ifNode struct {
+	name      string
+	op        string
+	operand   string
+	then      []node
+	otherwise []node
+}
+
+func (n ifNode) holds(c *Context) bool {
+	v, _ := c.value(n.name)
+	switch n.op {
+	case "==":
+		return strings.EqualFold(v, n.operand)
+	case "!=":
+		return !strings.EqualFold(v, n.operand)
+	}
+	return v != ""
+}
+
+func (n ifNode) render(b *strings.Builder, c *Context) {
+	nodes := n.otherwise
+	if n.holds(c) {
+		nodes = n.then
+	}
+	for _, child := range nodes {
+		child.render(b, c)
+	}
+}
+
+// Template is a parsed template
+type Template struct {
+	nodes []node
+}
+
+// Parse parses a template. It fails on unbalanced or malformed blocks.
+func Parse(text string) (*Template, error) {
+	p := &parser{text: text, matches: placeholderRegex.FindAllStringSubmatchIndex(text, -1)}
+	nodes, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("unexpected {{%s}}", end)
+	}
+	return &Template{nodes: nodes}, nil
+}
+
+// Render renders the template
+func (t *Template) Render(c Context) string {
+	var b strings.Builder
+	for _, n := range t.nodes {
+		n.render(&b, &c)
+	}
+	return b.String()
+}
+
+// Render parses and renders a template
+func Render(text string, c Context) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(c), nil
+}
+
+// parser builds the node tree from the placeholders found in text
+type parser struct {
+	text    string
+	matches [][]int
+	next    int
+	pos     int
+}
+
+// parse parses nodes up to the end of the text or an {{else}} or {{/if}},
+// which it returns
+func (p *parser) parse() ([]node, string, error) {
+	var nodes []node
+	for p.next < len(p.matches) {
+		m := p.matches[p.next]
+		p.next++
+		if m[0] > p.pos {
+			nodes = append(nodes, textNode(p.text[p.pos:m[0]]))
+		}
+		p.pos = m[1]
+		raw, inner := p.text[m[0]:m[1]], p.text[m[2]:m[3]]
+
+		switch {
+		case inner == "else" || inner == "/if":
+			return nodes, inner, nil
+		case strings.HasPrefix(inner, "#if ") || inner == "#if":
+			n, err := p.parseIf(strings.TrimSpace(strings.TrimPrefix(inner, "#if")))
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, n)
+		case strings.HasPrefix(inner, "#") || strings.HasPrefix(inner, "/"):
+			return nil, "", fmt.Errorf("unknown block %s", raw)
+		default:
+			n := varNode{raw: raw, name: inner}
+			if i := strings.Index(inner, "|"); i >= 0 {
+				n.name, n.def, n.hasDefault = strings.TrimSpace(inner[:i]), strings.TrimSpace(inner[i+1:]), true
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	if p.pos < len(p.text) {
+		nodes = append(nodes, textNode(p.text[p.pos:]))
+		p.pos = len(p.text)
+	}
+	return nodes, "", nil
+}
+
+// parseIf parses an {{#if}} block after its opening placeholder
+func (p *parser) parseIf(condition string) (node, error) {
+	n := ifNode{name: condition}
+	for _, op := range []string{"==", "!="} {
+		if i := strings.Index(condition, op); i >= 0 {
+			n.name, n.op = strings.TrimSpace(condition[:i]), op
+			n.operand = strings.Trim(strings.TrimSpace(condition[i+len(op):]), `"'`)
+			break
+		}
+	}
+	if n.name == "" {
+		return nil, fmt.Errorf("{{#if}} needs a variable")
+	}
+
+	var end string
+	var err error
+	if n.then, end, err = p.parse(); err != nil {
+		return nil, err
+	}
+	if end == "else" {
+		if n.otherwise, end, err = p.parse(); err != nil {
+			return nil, err
+		}
+	}
+	if end != "/if" {
+		return nil, fmt.Errorf("{{#if %s}} is not closed with {{/if}}", condition)
+	}
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	c := Context{
+		Now:       time.Date(2024, 3, 8, 9, 5, 0, 0, time.UTC),
+		UserName:  "ada",
+		UserEmail: "ada@example.com",
+		Vars:      map[string]string{"title": "Launch", "empty": "", "today": "2024-01-01"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"plain text", "no placeholders", "no placeholders"},
+		{"variable", "# {{ title }}", "# Launch"},
+		{"computed", "{{now}} {{weekday}} {{yesterday}} {{tomorrow}} {{time}}", "2024-03-08 09:05 Friday 2024-03-07 2024-03-09 09:05"},
+		{"supplied overrides computed", "{{today}}", "2024-01-01"},
+		{"user", "{{user.name}} <{{user.email}}>", "ada <ada@example.com>"},
+		{"default", "{{mood|fine}} {{empty|none}} {{title|Untitled}}", "fine none Launch"},
+		{"unknown kept", "{{unknown}} and {{empty}}", "{{unknown}} and "},
+		{"if set", "{{#if title}}has {{title}}{{/if}}{{#if mood}}mood{{/if}}", "has Launch"},
+		{"if else", "{{#if mood}}yes{{else}}no{{/if}}", "no"},
+		{"comparison", `{{#if weekday == friday}}review{{/if}}{{#if weekday != "Friday"}}work{{/if}}`, "review"},
+		{"nested", "{{#if title}}[{{#if mood}}m{{else}}{{title}}{{/if}}]{{/if}}", "[Launch]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.template, c)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, template := range []string{
+		"{{#if title}}unclosed",
+		"{{/if}}",
+		"text {{else}}",
+		"{{#if}}x{{/if}}",
+		"{{#each items}}{{/each}}",
+	} {
+		if _, err := Parse(template); err == nil {
+			t.Errorf("Parse(%q) error = nil, want an error", template)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+)
+
+// UserOption customizes a user created by NewTestUser
+type UserOption func(*models.User)
+
+// WithEmail sets the email of the user
+func WithEmail(email string) UserOption {
+	return func(u *models.User) { u.Email = email }
+}
+
+// WithGoogleID sets the Google ID of the user
+func WithGoogleID(googleID string) UserOption {
+	return func(u *models.User) { u.GoogleID = googleID }
+}
+
+// NewTestUser creates a user with a unique Google ID and email, unless set by
+// the options
+func NewTestUser(t testing.TB, q Querier, opts ...UserOption) *models.User {
+	t.Helper()
+
+	user := &models.User{ID: uuid.New()}
+	user.GoogleID = "google_" + user.ID.String()
+	user.Email = fmt.Sprintf("user-%s@example.com", user.ID)
+	for _, opt := range opts {
+		opt(user)
+	}
+
+	query := `
+		INSERT INTO users (id, google_id, email, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING created_at, updated_at, role
+	`
+	err := q.QueryRowContext(context.Background(), query, user.ID, user.GoogleID, user.Email).
+		Scan(&user.CreatedAt, &user.UpdatedAt, &user.Role)
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
+
+// NewTestNote creates a note of the user
+func NewTestNote(t testing.TB, q Querier, userID uuid.UUID, title, content string) *models.Note {
+	t.Helper()
+
+	note := &models.Note{ID: uuid.New(), UserID: userID, Title: &title, Content: content}
+	note.NotebookID = defaultTestNotebook(t, q, userID)
+	query := `
+		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version, notebook_id)
+		VALUES ($1, $2, $3, $4, NOW(), NOW(), 1, $5)
+		RETURNING created_at, updated_at, version, language
+	`
+	err := q.QueryRowContext(context.Background(), query, note.ID, userID, title, content, note.NotebookID).
+		Scan(&note.CreatedAt, &note.UpdatedAt, &note.Version, &note.Language)
+	if err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+	return note
+}
+
+// defaultTestNotebook returns the user's default notebook, creating it the
+// way the notebook service does
+func defaultTestNotebook(t testing.TB, q Querier, userID uuid.UUID) uuid.UUID {
+	t.Helper()
+
+	_, err := q.ExecContext(context.Background(), `
+		INSERT INTO notebooks (id, user_id, name, is_default)
+		VALUES ($1, $2, $3, TRUE)
+		ON CONFLICT DO NOTHING
+	`, uuid.New(), userID, models.DefaultNotebookName)
+	if err != nil {
+		t.Fatalf("Failed to create default notebook: %v", err)
+	}
+
+	var notebookID uuid.UUID
+	err = q.QueryRowContext(context.Background(),
+		"SELECT id FROM notebooks WHERE user_id = $1 AND is_default = TRUE", userID).Scan(&notebookID)
+	if err != nil {
+		t.Fatalf("Failed to get default notebook: %v", err)
+	}
+	return notebookID
+}
+
+// NewTestNoteWithTags creates a note of the user whose content ends with the
+// tags, and associates the note with them. Tags the user does not have yet
+// are created, along with the parents of nested tags.
+func NewTestNoteWithTags(t testing.TB, q Querier, userID uuid.UUID, content string, tags ...string) *models.Note {
+	t.Helper()
+
+	if len(tags) > 0 {
+		content = strings.TrimSpace(content + "\n\n" + strings.Join(tags, " "))
+	}
+	note := NewTestNote(t, q, userID, "Test Note", content)
+
+	for _, tag := range tags {
+		tagID := NewTestTag(t, q, userID, tag)
+		_, err := q.ExecContext(context.Background(),
+			"INSERT INTO note_tags (note_id, tag_id, created_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING",
+			note.ID, tagID)
+		if err != nil {
+			t.Fatalf("Failed to tag test note with %s: %v", tag, err)
+		}
+	}
+	return note
+}
+
+// NewTestTag returns the ID of the user's tag with the name, creating it and
+// the parents of a nested tag if needed
+func NewTestTag(t testing.TB, q Querier, userID uuid.UUID, name string) uuid.UUID {
+	t.Helper()
+
+	var parentID *uuid.UUID
+	if i := strings.LastIndex(name, "/"); i > 0 {
+		id := NewTestTag(t, q, userID, name[:i])
+		parentID = &id
+	}
+
+	// Updating on conflict returns the ID of an existing tag of any case
+	query := `
+		INSERT INTO tags (id, user_id, parent_id, name, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, LOWER(name)) DO UPDATE SET name = tags.name
+		RETURNING id
+	`
+	var id uuid.UUID
+	if err := q.QueryRowContext(context.Background(), query, uuid.New(), userID, parentID, name).Scan(&id); err != nil {
+		t.Fatalf("Failed to create test tag %s: %v", name, err)
+	}
+	return id
+}
+
+// SeedNotes creates n notes of the user, numbered from 1 in their titles and
+// content
+func SeedNotes(t testing.TB, q Querier, userID uuid.UUID, n int) []*models.Note {
+	t.Helper()
+
+	notes := make([]*models.Note, 0, n)
+	for i := 1; i <= n; i++ {
+		notes = append(notes, NewTestNote(t, q, userID,
+			fmt.Sprintf("Test Note %d", i), fmt.Sprintf("Content of test note %d", i)))
+	}
+	return notes
+}
@@ -0,0 +1,58 @@
+// Package testutil provides fixtures for tests against a database: a
+// migrated test database, a transaction rolled back after each test, and
+// factories for users, notes and tags. Test databases are SQLite files
+// unless USE_POSTGRE_DURING_TEST is set, which runs the tests against
+// PostgreSQL (see config.GetTestDatabaseConfig).
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/database"
+)
+
+// Querier runs queries. *sql.DB and *sql.Tx both implement it, so fixtures
+// can be created inside a test transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// NewTestDB creates a database for the test, applies the migrations in
+// migrationsPath, or in its sqlite directory for SQLite, and drops the
+// database when the test finishes
+func NewTestDB(t testing.TB, cfg config.DatabaseConfig, migrationsPath string) *sql.DB {
+	t.Helper()
+
+	db, err := database.CreateTestDatabase(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { database.DropTestDatabase(db) })
+
+	if err := database.NewMigrator(db, migrationsPath).Up(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+// BeginTx starts a transaction that is rolled back when the test finishes,
+// so the fixtures and changes of the test never reach other tests. Code
+// under test must run its queries on the returned transaction.
+func BeginTx(t testing.TB, db *sql.DB) *sql.Tx {
+	t.Helper()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			t.Errorf("Failed to roll back test transaction: %v", err)
+		}
+	})
+	return tx
+}
@@ -0,0 +1,46 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtures(t *testing.T) {
+	db := NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+
+	t.Run("in transaction", func(t *testing.T) {
+		tx := BeginTx(t, db)
+
+		user := NewTestUser(t, tx)
+		other := NewTestUser(t, tx, WithEmail("other@example.com"))
+		assert.NotEqual(t, user.Email, other.Email)
+		assert.Equal(t, "other@example.com", other.Email)
+
+		note := NewTestNoteWithTags(t, tx, user.ID, "Plans", "#work/projecta", "#work")
+		assert.Contains(t, note.Content, "#work/projecta")
+
+		var tagged int
+		err := tx.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM note_tags WHERE note_id = $1", note.ID).Scan(&tagged)
+		require.NoError(t, err)
+		assert.Equal(t, 2, tagged)
+
+		// The parent of a nested tag is created once
+		var tags int
+		err = tx.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM tags WHERE user_id = $1", user.ID).Scan(&tags)
+		require.NoError(t, err)
+		assert.Equal(t, 2, tags)
+
+		notes := SeedNotes(t, tx, user.ID, 3)
+		require.Len(t, notes, 3)
+		assert.Equal(t, "Test Note 3", *notes[2].Title)
+	})
+
+	// The fixtures were rolled back with the transaction
+	var users int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users))
+	assert.Equal(t, 0, users)
+}
@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// The RFC 6238 test vectors, truncated to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	if _, err := Code("not base32!", time.Now()); err != ErrInvalidSecret {
+		t.Errorf("Expected ErrInvalidSecret, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+	now := time.Now()
+	previous, _ := Code(secret, now.Add(-Period))
+	stale, _ := Code(secret, now.Add(-3*Period))
+
+	step, ok := Validate(secret, previous, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Expected the previous code to match step %d, got %d, %v", Step(now)-1, step, ok)
+	}
+	if _, ok := Validate(secret, stale, now, 1); ok {
+		t.Error("Expected a code outside the skew to be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Error("Expected a short code to be rejected")
+	}
+
+	// Codes are accepted with the spaces apps show them with
+	current, _ := Code(secret, now)
+	if _, ok := Validate(strings.ToLower(secret), current[:3]+" "+current[3:], now, 0); !ok {
+		t.Error("Expected a spaced code of a lowercase secret to match")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "Silence Notes", "user@example.com")
+	want := "otpauth://totp/Silence%20Notes:user@example.com?algorithm=SHA1&digits=6&issuer=Silence+Notes&period=30&secret=JBSWY3DPEHPK3PXP"
+	if uri != want {
+		t.Errorf("ProvisioningURI = %s, want %s", uri, want)
+	}
+}
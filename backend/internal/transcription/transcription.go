@@ -0,0 +1,170 @@
+// Package transcription turns recorded speech, such as voice memos, into
+// text. Backends implement Transcriber; OpenAI calls an OpenAI-compatible
+// speech-to-text API.
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnavailable is returned when the backend cannot be reached or
+	// refuses the server's credentials
+	ErrUnavailable = errors.New("transcription backend unavailable")
+	// ErrInvalidAudio is returned when the backend cannot decode the audio
+	ErrInvalidAudio = errors.New("audio could not be transcribed")
+)
+
+// Transcriber transcribes recorded speech
+type Transcriber interface {
+	// Transcribe returns the transcript of an audio file. The filename's
+	// extension names the format; language is an ISO-639-1 hint, empty to
+	// detect the language.
+	Transcribe(ctx context.Context, audio []byte, filename, language string) (*Transcript, error)
+}
+
+// Transcript is the text of a recording
+type Transcript struct {
+	Text     string
+	Language string
+	Duration time.Duration
+	// Segments are the consecutive parts of the text with their times, empty
+	// when the backend does not time them
+	Segments []Segment
+}
+
+// Segment is a part of a transcript
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// maxErrorBodySize is the part of an error response kept for the log
+const maxErrorBodySize = 512
+
+// OpenAI transcribes with the /audio/transcriptions endpoint of the OpenAI
+// API, which Whisper servers such as Groq and faster-whisper-server also
+// serve
+type OpenAI struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAI creates an OpenAI transcriber for the API at baseURL, such as
+// https://api.openai.com/v1. Local servers may not need an apiKey.
+func NewOpenAI(baseURL, apiKey, model string, timeout time.Duration) *OpenAI {
+	return &OpenAI{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// verboseTranscription is the verbose_json response of the API
+type verboseTranscription struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
+// Transcribe implements Transcriber
+func (o *OpenAI) Transcribe(ctx context.Context, audio []byte, filename, language string) (*Transcript, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := map[string]string{
+		"model":                     o.model,
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "segment",
+	}
+	if language != "" {
+		fields["language"] = language
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(audio); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		err := fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnsupportedMediaType {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAudio, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	var result verboseTranscription
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrUnavailable, err)
+	}
+
+	transcript := &Transcript{
+		Text:     strings.TrimSpace(result.Text),
+		Language: result.Language,
+		Duration: seconds(result.Duration),
+	}
+	for _, segment := range result.Segments {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" {
+			continue
+		}
+		transcript.Segments = append(transcript.Segments, Segment{
+			Start: seconds(segment.Start),
+			End:   seconds(segment.End),
+			Text:  text,
+		})
+	}
+	return transcript, nil
+}
+
+// seconds converts the API's fractional seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
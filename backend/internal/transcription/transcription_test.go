@@ -0,0 +1,71 @@
+package transcription
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenAITranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Expected the audio file: %v", err)
+			return
+		}
+		audio, _ := io.ReadAll(file)
+		if header.Filename != "memo.m4a" {
+			t.Errorf("Unexpected file name %s", header.Filename)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("response_format") != "verbose_json" || r.FormValue("language") != "en" {
+			t.Errorf("Unexpected form %v", r.MultipartForm.Value)
+		}
+		if string(audio) == "noise" {
+			http.Error(w, `{"error":{"message":"Invalid file format."}}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text":" Buy milk. Call Ada. ","language":"english","duration":7.5,"segments":[
+			{"start":0,"end":2.4,"text":" Buy milk."},
+			{"start":2.4,"end":3,"text":"  "},
+			{"start":3,"end":7.5,"text":" Call Ada."}]}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewOpenAI(server.URL+"/v1/", "key", "whisper-1", 5*time.Second)
+	transcript, err := transcriber.Transcribe(context.Background(), []byte("audio"), "memo.m4a", "en")
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if transcript.Text != "Buy milk. Call Ada." || transcript.Language != "english" || transcript.Duration != 7500*time.Millisecond {
+		t.Errorf("Unexpected transcript %+v", transcript)
+	}
+	want := []Segment{
+		{Start: 0, End: 2400 * time.Millisecond, Text: "Buy milk."},
+		{Start: 3 * time.Second, End: 7500 * time.Millisecond, Text: "Call Ada."},
+	}
+	if len(transcript.Segments) != len(want) {
+		t.Fatalf("Expected %d segments, got %+v", len(want), transcript.Segments)
+	}
+	for i, segment := range transcript.Segments {
+		if segment != want[i] {
+			t.Errorf("Segment %d = %+v, want %+v", i, segment, want[i])
+		}
+	}
+
+	if _, err := transcriber.Transcribe(context.Background(), []byte("noise"), "memo.m4a", "en"); !errors.Is(err, ErrInvalidAudio) {
+		t.Errorf("Expected ErrInvalidAudio, got %v", err)
+	}
+	unauthorized := NewOpenAI(server.URL+"/v1", "wrong", "whisper-1", 5*time.Second)
+	if _, err := unauthorized.Transcribe(context.Background(), []byte("audio"), "memo.m4a", ""); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package validation checks request structs against their validate tags and
+// reports every invalid field, named as in the request JSON.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate is shared, as it caches the rules of each struct type
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Name fields after their JSON keys
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	// Note content is limited by the configured models.MaxContentLength
+	v.RegisterValidation("maxcontent", func(field validator.FieldLevel) bool {
+		return utf8.RuneCountInString(field.Field().String()) <= models.MaxContentLength()
+	})
+	return v
+}
+
+// Error lists the invalid fields of a request
+type Error struct {
+	Fields []models.FieldError
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Struct validates a request struct, or a slice of them. It returns an *Error
+// listing the invalid fields, or nil.
+func Struct(request interface{}) error {
+	var err error
+	value := reflect.Indirect(reflect.ValueOf(request))
+	if value.Kind() == reflect.Slice {
+		err = validate.Var(request, "dive")
+	} else {
+		err = validate.Struct(request)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return fmt.Errorf("failed to validate request: %w", err)
+	}
+
+	result := &Error{Fields: make([]models.FieldError, len(fieldErrors))}
+	for i, fieldError := range fieldErrors {
+		result.Fields[i] = models.FieldError{
+			Field:   strings.TrimPrefix(fieldError.Namespace(), value.Type().Name()+"."),
+			Rule:    fieldError.Tag(),
+			Message: message(fieldError),
+		}
+	}
+	return result
+}
+
+// message describes a failed rule
+func message(fieldError validator.FieldError) string {
+	kind := fieldError.Kind()
+	if kind == reflect.Ptr {
+		kind = fieldError.Type().Elem().Kind()
+	}
+	unit := ""
+	switch kind {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fieldError.Param(), unit)
+	case "maxcontent":
+		return fmt.Sprintf("must be at most %d%s", models.MaxContentLength(), unit)
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fieldError.Param(), unit)
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fieldError.Param()), ", ")
+	case "uuid":
+		return "must be a UUID"
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be a URL"
+	default:
+		return "is invalid"
+	}
+}
@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/models"
+)
+
+// fieldMessages returns the messages of err by field
+func fieldMessages(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var invalid *Error
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	messages := make(map[string]string, len(invalid.Fields))
+	for _, field := range invalid.Fields {
+		messages[field.Field] = field.Message
+	}
+	return messages
+}
+
+func TestStructReportsEveryField(t *testing.T) {
+	err := Struct(&models.CreateNoteRequest{Title: strings.Repeat("t", 501)})
+
+	want := map[string]string{
+		"title":   "must be at most 500 characters",
+		"content": "is required",
+	}
+	got := fieldMessages(t, err)
+	if len(got) != len(want) {
+		t.Fatalf("got fields %v, want %v", got, want)
+	}
+	for field, message := range want {
+		if got[field] != message {
+			t.Errorf("%s: got %q, want %q", field, got[field], message)
+		}
+	}
+}
+
+func TestStructNamesNestedFields(t *testing.T) {
+	content := strings.Repeat("c", 10001)
+	version := 0
+
+	t.Run("slice of requests", func(t *testing.T) {
+		err := Struct([]models.CreateNoteRequest{{Content: "ok"}, {}})
+		if got := fieldMessages(t, err); got["[1].content"] != "is required" {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("pointer fields", func(t *testing.T) {
+		err := Struct(&models.UpdateNoteRequest{Content: &content, Version: &version})
+		got := fieldMessages(t, err)
+		if got["content"] != "must be at most 10000 characters" || got["version"] != "must be at least 1" {
+			t.Errorf("got %v", got)
+		}
+	})
+
+	t.Run("oneof", func(t *testing.T) {
+		err := Struct(&models.SearchNotesRequest{Limit: 20, OrderBy: "size", OrderDir: "desc"})
+		if got := fieldMessages(t, err); got["order_by"] != "must be one of created_at, updated_at, title" {
+			t.Errorf("got %v", got)
+		}
+	})
+}
+
+func TestStructAcceptsValidRequests(t *testing.T) {
+	requests := []interface{}{
+		&models.CreateNoteRequest{Content: "Buy milk"},
+		&models.UpdateNoteRequest{},
+		&models.MergeTagsRequest{Source: "#a", Target: "#b"},
+		[]models.CreateNoteRequest{{Content: "a"}, {Content: "b"}},
+	}
+	for _, request := range requests {
+		if err := Struct(request); err != nil {
+			t.Errorf("Struct(%T) = %v", request, err)
+		}
+	}
+}
+
+func TestStructChecksConfiguredContentLimit(t *testing.T) {
+	defer models.SetMaxContentLength(models.DefaultMaxContentLength)
+
+	request := &models.CreateNoteRequest{Content: strings.Repeat("c", models.DefaultMaxContentLength+1)}
+	if got := fieldMessages(t, Struct(request)); got["content"] != "must be at most 10000 characters" {
+		t.Errorf("got %v", got)
+	}
+
+	// The limit counts characters, not bytes
+	multibyte := &models.CreateNoteRequest{Content: strings.Repeat("é", models.DefaultMaxContentLength)}
+	if err := Struct(multibyte); err != nil {
+		t.Errorf("Expected multibyte content at the limit to be valid, got %v", err)
+	}
+
+	models.SetMaxContentLength(1 << 20)
+	if err := Struct(request); err != nil {
+		t.Errorf("Expected the content within the raised limit, got %v", err)
+	}
+}
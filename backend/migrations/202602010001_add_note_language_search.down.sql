@@ -0,0 +1,8 @@
+-- Remove language detection and full-text search vector from notes
+DROP INDEX IF EXISTS idx_notes_language;
+DROP INDEX IF EXISTS idx_notes_search_vector;
+DROP TRIGGER IF EXISTS update_notes_search_vector ON notes;
+DROP FUNCTION IF EXISTS update_notes_search_vector();
+DROP FUNCTION IF EXISTS note_search_config(TEXT);
+ALTER TABLE notes DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE notes DROP COLUMN IF EXISTS language;
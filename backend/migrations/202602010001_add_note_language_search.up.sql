@@ -0,0 +1,43 @@
+-- Add detected language and full-text search vector to notes
+ALTER TABLE notes ADD COLUMN language VARCHAR(8) NOT NULL DEFAULT 'und';
+ALTER TABLE notes ADD COLUMN search_vector TSVECTOR;
+
+COMMENT ON COLUMN notes.language IS 'Detected content language (ISO 639-1: en, id, or und when unknown)';
+COMMENT ON COLUMN notes.search_vector IS 'Full-text search vector built with the text search configuration of the note language';
+
+-- Map a note language code to its text search configuration
+CREATE OR REPLACE FUNCTION note_search_config(lang TEXT)
+RETURNS regconfig AS $$
+BEGIN
+    RETURN CASE lang
+        WHEN 'en' THEN 'english'::regconfig
+        WHEN 'id' THEN 'indonesian'::regconfig
+        ELSE 'simple'::regconfig
+    END;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Keep search_vector in sync with title, content and language
+CREATE OR REPLACE FUNCTION update_notes_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_notes_search_vector
+    BEFORE INSERT OR UPDATE OF title, content, language ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_notes_search_vector();
+
+-- Backfill existing notes. Their language is detected on their next write,
+-- or for all of them by the detect_note_languages maintenance task.
+UPDATE notes SET search_vector =
+    setweight(to_tsvector(note_search_config(language), COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector(note_search_config(language), COALESCE(content, '')), 'B');
+
+CREATE INDEX idx_notes_search_vector ON notes USING GIN(search_vector);
+CREATE INDEX idx_notes_language ON notes(language);
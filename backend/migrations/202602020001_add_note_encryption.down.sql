@@ -0,0 +1,21 @@
+-- Remove private notes and restore the content-indexing search trigger.
+-- Private notes keep their ciphertext content; make them public before rolling back.
+DROP INDEX IF EXISTS idx_notes_user_private;
+
+CREATE OR REPLACE FUNCTION update_notes_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_notes_search_vector ON notes;
+CREATE TRIGGER update_notes_search_vector
+    BEFORE INSERT OR UPDATE OF title, content, language ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_notes_search_vector();
+
+ALTER TABLE notes DROP COLUMN IF EXISTS is_private;
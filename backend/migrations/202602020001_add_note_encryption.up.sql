@@ -0,0 +1,25 @@
+-- Add private (encrypted at rest) notes
+ALTER TABLE notes ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN notes.is_private IS 'Content is AES-GCM encrypted with a per-user key and excluded from full-text indexing';
+
+-- Never index encrypted content: private notes are only searchable by title
+CREATE OR REPLACE FUNCTION update_notes_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.title, '')), 'A');
+    IF NOT NEW.is_private THEN
+        NEW.search_vector = NEW.search_vector ||
+            setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.content, '')), 'B');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_notes_search_vector ON notes;
+CREATE TRIGGER update_notes_search_vector
+    BEFORE INSERT OR UPDATE OF title, content, language, is_private ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_notes_search_vector();
+
+CREATE INDEX idx_notes_user_private ON notes(user_id) WHERE is_private;
@@ -0,0 +1,22 @@
+-- Create notifications table for in-app user notifications
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for listing a user's notifications, newest first
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC);
+
+-- Index for unread counts
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+COMMENT ON TABLE notifications IS 'In-app notifications delivered to users';
+COMMENT ON COLUMN notifications.type IS 'Notification kind, e.g. search_match';
+COMMENT ON COLUMN notifications.data IS 'Type-specific payload such as related note and subscription IDs';
+COMMENT ON COLUMN notifications.read_at IS 'Timestamp when the user marked the notification read';
@@ -0,0 +1,3 @@
+-- Drop search subscriptions
+DROP TABLE IF EXISTS search_subscription_matches;
+DROP TABLE IF EXISTS search_subscriptions;
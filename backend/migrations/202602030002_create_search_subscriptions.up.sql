@@ -0,0 +1,31 @@
+-- Create search subscriptions: users are notified when a note starts matching a query
+CREATE TABLE search_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_search_subscriptions_user_id ON search_subscriptions(user_id);
+
+-- Notes currently matching each subscription. A note is notified once when it
+-- starts matching and its row is removed when it stops matching.
+CREATE TABLE search_subscription_matches (
+    subscription_id UUID NOT NULL REFERENCES search_subscriptions(id) ON DELETE CASCADE,
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    matched_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (subscription_id, note_id)
+);
+
+CREATE INDEX idx_search_subscription_matches_note_id ON search_subscription_matches(note_id);
+
+COMMENT ON TABLE search_subscriptions IS 'Search queries users subscribe to for change notifications';
+COMMENT ON COLUMN search_subscriptions.query IS 'Search query in the search query syntax (phrases, tag:, title:, -negation, dates)';
+COMMENT ON TABLE search_subscription_matches IS 'Notes currently matching a subscription, used to notify only on new matches';
+
+CREATE TRIGGER update_search_subscriptions_updated_at
+    BEFORE UPDATE ON search_subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
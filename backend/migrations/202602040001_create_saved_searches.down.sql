@@ -0,0 +1,3 @@
+-- Drop saved searches
+ALTER TABLE search_subscriptions DROP COLUMN IF EXISTS saved_search_id;
+DROP TABLE IF EXISTS saved_searches;
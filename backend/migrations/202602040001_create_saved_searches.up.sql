@@ -0,0 +1,32 @@
+-- Create saved searches ("smart folders"): a stored query, tag filters and sort order
+CREATE TABLE saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    order_by VARCHAR(20) NOT NULL DEFAULT 'created_at',
+    order_dir VARCHAR(4) NOT NULL DEFAULT 'desc',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, name),
+    CHECK (order_by IN ('created_at', 'updated_at', 'title')),
+    CHECK (order_dir IN ('asc', 'desc'))
+);
+
+CREATE INDEX idx_saved_searches_user_id ON saved_searches(user_id);
+
+COMMENT ON TABLE saved_searches IS 'Saved searches rendered as smart folders';
+COMMENT ON COLUMN saved_searches.query IS 'Search query in the search query syntax';
+COMMENT ON COLUMN saved_searches.tags IS 'Tags every matching note must have, in addition to the query';
+
+CREATE TRIGGER update_saved_searches_updated_at
+    BEFORE UPDATE ON saved_searches
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Subscriptions can follow a saved search; they are removed with it
+ALTER TABLE search_subscriptions
+    ADD COLUMN saved_search_id UUID REFERENCES saved_searches(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_search_subscriptions_saved_search_id ON search_subscriptions(saved_search_id);
@@ -0,0 +1,3 @@
+-- Drop import sessions
+DROP TRIGGER IF EXISTS update_import_sessions_updated_at ON import_sessions;
+DROP TABLE IF EXISTS import_sessions;
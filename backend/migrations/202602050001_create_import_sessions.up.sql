@@ -0,0 +1,30 @@
+-- Create import sessions: an uploaded file waiting for the user to map its columns to note fields
+CREATE TABLE import_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    format VARCHAR(20) NOT NULL,
+    content TEXT NOT NULL,
+    columns TEXT[] NOT NULL DEFAULT '{}',
+    row_count INTEGER NOT NULL DEFAULT 0,
+    mapping JSONB,
+    result JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW() + INTERVAL '24 hours',
+    CHECK (status IN ('pending', 'mapped', 'completed', 'failed'))
+);
+
+CREATE INDEX idx_import_sessions_user_id ON import_sessions(user_id);
+CREATE INDEX idx_import_sessions_expires_at ON import_sessions(expires_at);
+
+COMMENT ON TABLE import_sessions IS 'Multi-step imports: upload, map columns, execute';
+COMMENT ON COLUMN import_sessions.content IS 'Raw uploaded file, kept until the import is executed or expires';
+COMMENT ON COLUMN import_sessions.mapping IS 'Column to note field mapping submitted by the client';
+COMMENT ON COLUMN import_sessions.result IS 'Outcome of executing the import: created note count and row errors';
+
+CREATE TRIGGER update_import_sessions_updated_at
+    BEFORE UPDATE ON import_sessions
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
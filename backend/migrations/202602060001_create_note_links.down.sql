@@ -0,0 +1,3 @@
+-- Drop note links
+DROP INDEX IF EXISTS idx_notes_user_lower_title;
+DROP TABLE IF EXISTS note_links;
@@ -0,0 +1,21 @@
+-- Create note links: [[wiki]] links by title and note://UUID links by ID
+CREATE TABLE note_links (
+    source_note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    target_note_id UUID REFERENCES notes(id) ON DELETE CASCADE,
+    target_title TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((target_note_id IS NULL) <> (target_title IS NULL))
+);
+
+CREATE UNIQUE INDEX idx_note_links_source_target_id ON note_links(source_note_id, target_note_id)
+    WHERE target_note_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_note_links_source_target_title ON note_links(source_note_id, LOWER(target_title))
+    WHERE target_title IS NOT NULL;
+CREATE INDEX idx_note_links_target_note_id ON note_links(target_note_id);
+CREATE INDEX idx_note_links_target_title ON note_links(LOWER(target_title));
+
+-- Title links resolve against note titles at read time
+CREATE INDEX idx_notes_user_lower_title ON notes(user_id, LOWER(title));
+
+COMMENT ON TABLE note_links IS 'Links between notes, rebuilt whenever the source note is written';
+COMMENT ON COLUMN note_links.target_title IS 'Title of a [[wiki]] link; matches notes of the same user case-insensitively';
@@ -0,0 +1,2 @@
+-- Drop action confirmations
+DROP TABLE IF EXISTS action_confirmations;
@@ -0,0 +1,20 @@
+-- Create action confirmations: one-time codes confirming destructive operations
+CREATE TABLE action_confirmations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    operation VARCHAR(50) NOT NULL,
+    scope TEXT NOT NULL,
+    affected INTEGER NOT NULL DEFAULT 0,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_action_confirmations_user_id ON action_confirmations(user_id);
+CREATE INDEX idx_action_confirmations_expires_at ON action_confirmations(expires_at);
+
+COMMENT ON TABLE action_confirmations IS 'Pending confirmations for destructive operations such as bulk deletes';
+COMMENT ON COLUMN action_confirmations.scope IS 'Identifies the exact operation (e.g. the set of note IDs) the code confirms';
+COMMENT ON COLUMN action_confirmations.code_hash IS 'SHA-256 hash of the one-time code';
@@ -0,0 +1,4 @@
+-- Drop activity events and account anomalies
+ALTER TABLE users DROP COLUMN IF EXISTS read_only_until;
+DROP TABLE IF EXISTS account_anomalies;
+DROP TABLE IF EXISTS activity_events;
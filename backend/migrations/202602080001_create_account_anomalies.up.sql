@@ -0,0 +1,37 @@
+-- Create activity events and account anomalies for suspicious activity detection
+CREATE TABLE activity_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    count INTEGER NOT NULL DEFAULT 1,
+    ip_address VARCHAR(45),
+    country VARCHAR(2),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_activity_events_user_created ON activity_events(user_id, created_at DESC);
+CREATE INDEX idx_activity_events_created_at ON activity_events(created_at);
+
+CREATE TABLE account_anomalies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rule VARCHAR(50) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    summary TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'confirmed', 'dismissed')),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_account_anomalies_status_created ON account_anomalies(status, created_at DESC);
+CREATE INDEX idx_account_anomalies_user_rule ON account_anomalies(user_id, rule);
+
+ALTER TABLE users ADD COLUMN read_only_until TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON TABLE activity_events IS 'Account activity analyzed for unusual patterns';
+COMMENT ON COLUMN activity_events.count IS 'Number of items the event covers, e.g. notes removed by a bulk delete';
+COMMENT ON TABLE account_anomalies IS 'Unusual account activity awaiting administrator review';
+COMMENT ON COLUMN users.read_only_until IS 'Account is locked to read-only access until this time';
@@ -0,0 +1,2 @@
+-- Drop user preferences
+ALTER TABLE users DROP COLUMN IF EXISTS auto_apply_tag_suggestions;
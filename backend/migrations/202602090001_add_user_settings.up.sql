@@ -0,0 +1,4 @@
+-- Add user preferences
+ALTER TABLE users ADD COLUMN auto_apply_tag_suggestions BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN users.auto_apply_tag_suggestions IS 'Add confident LLM tag suggestions to notes automatically';
@@ -0,0 +1,3 @@
+-- Drop legal holds
+DROP TABLE IF EXISTS legal_hold_audit;
+DROP TABLE IF EXISTS legal_holds;
@@ -0,0 +1,29 @@
+-- Create legal holds: notes under hold cannot be deleted until the hold is released
+CREATE TABLE legal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- Not foreign keys: holds and their audit trail outlive the held data
+    user_id UUID NOT NULL,
+    note_id UUID,
+    reason TEXT NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    released_by UUID,
+    released_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_legal_holds_active_user ON legal_holds(user_id) WHERE released_at IS NULL;
+
+CREATE TABLE legal_hold_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    hold_id UUID NOT NULL REFERENCES legal_holds(id),
+    action VARCHAR(20) NOT NULL CHECK (action IN ('applied', 'released')),
+    actor_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_legal_hold_audit_hold_id ON legal_hold_audit(hold_id, created_at);
+
+COMMENT ON TABLE legal_holds IS 'Legal holds preventing deletion of a user''s notes';
+COMMENT ON COLUMN legal_holds.note_id IS 'Held note; NULL holds every note of the user and the account itself';
+COMMENT ON TABLE legal_hold_audit IS 'Append-only record of legal holds being applied and released';
@@ -0,0 +1,3 @@
+-- Drop data migration tables
+DROP TABLE IF EXISTS outgoing_migrations;
+DROP TABLE IF EXISTS migration_transfers;
@@ -0,0 +1,57 @@
+-- Create data migration tables: a destination deployment accepts a user's data
+-- in numbered chunks pushed by the source deployment
+CREATE TABLE migration_transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    next_chunk INTEGER NOT NULL DEFAULT 0,
+    notes_received INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CHECK (status IN ('pending', 'receiving', 'completed'))
+);
+
+CREATE INDEX idx_migration_transfers_user_id ON migration_transfers(user_id);
+CREATE INDEX idx_migration_transfers_expires_at ON migration_transfers(expires_at);
+
+COMMENT ON TABLE migration_transfers IS 'Incoming migrations waiting for or receiving chunks from a source deployment';
+COMMENT ON COLUMN migration_transfers.token_hash IS 'SHA-256 hash of the token the source deployment authenticates with';
+COMMENT ON COLUMN migration_transfers.next_chunk IS 'Sequence number of the next chunk to accept; earlier chunks may be resent';
+
+CREATE TRIGGER update_migration_transfers_updated_at
+    BEFORE UPDATE ON migration_transfers
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE outgoing_migrations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    destination_url TEXT NOT NULL,
+    transfer_id UUID NOT NULL,
+    token TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    chunks_sent INTEGER NOT NULL DEFAULT 0,
+    notes_sent INTEGER NOT NULL DEFAULT 0,
+    notes_skipped INTEGER NOT NULL DEFAULT 0,
+    cursor_created_at TIMESTAMP WITH TIME ZONE,
+    cursor_note_id UUID,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CHECK (status IN ('running', 'failed', 'completed'))
+);
+
+CREATE INDEX idx_outgoing_migrations_user_id ON outgoing_migrations(user_id);
+
+COMMENT ON TABLE outgoing_migrations IS 'Migrations pushing a user''s data to another deployment';
+COMMENT ON COLUMN outgoing_migrations.token IS 'Transfer token issued by the destination, cleared once the migration completes';
+COMMENT ON COLUMN outgoing_migrations.cursor_created_at IS 'Creation time and ID of the last note acknowledged by the destination';
+
+CREATE TRIGGER update_outgoing_migrations_updated_at
+    BEFORE UPDATE ON outgoing_migrations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,9 @@
+-- Drop change log
+DROP TRIGGER IF EXISTS record_search_subscriptions_update ON search_subscriptions;
+DROP TRIGGER IF EXISTS record_search_subscriptions_write ON search_subscriptions;
+DROP TRIGGER IF EXISTS record_saved_searches_update ON saved_searches;
+DROP TRIGGER IF EXISTS record_saved_searches_write ON saved_searches;
+DROP TRIGGER IF EXISTS record_notes_update ON notes;
+DROP TRIGGER IF EXISTS record_notes_write ON notes;
+DROP FUNCTION IF EXISTS record_change();
+DROP TABLE IF EXISTS change_log;
@@ -0,0 +1,82 @@
+-- Create change log: an ordered record of API-visible changes that sync clients page through
+CREATE TABLE change_log (
+    id BIGSERIAL PRIMARY KEY,
+    tx_id BIGINT NOT NULL DEFAULT txid_current(),
+    user_id UUID NOT NULL,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id UUID NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    version INTEGER,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (operation IN ('created', 'updated', 'deleted'))
+);
+
+CREATE INDEX idx_change_log_user_cursor ON change_log(user_id, tx_id, id);
+CREATE INDEX idx_change_log_changed_at ON change_log(changed_at);
+
+COMMENT ON TABLE change_log IS 'Changes to notes, saved searches and subscriptions, written by triggers';
+COMMENT ON COLUMN change_log.tx_id IS 'Writing transaction; the feed is ordered by transaction so that late commits are not skipped';
+COMMENT ON COLUMN change_log.user_id IS 'Owner of the changed entity; no foreign key so that deletes cascading from users can be logged';
+COMMENT ON COLUMN change_log.version IS 'Entity version after the change, for entities with optimistic locking';
+
+-- Record a change of the row; the entity type is passed as the trigger argument
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    op TEXT;
+    ver INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+        op := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        rec := NEW;
+        op := 'created';
+    ELSE
+        rec := NEW;
+        op := 'updated';
+    END IF;
+
+    IF TG_ARGV[0] = 'note' THEN
+        ver := rec.version;
+    END IF;
+
+    INSERT INTO change_log (user_id, entity_type, entity_id, operation, version)
+    VALUES (rec.user_id, TG_ARGV[0], rec.id, op, ver);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_notes_write
+    AFTER INSERT OR DELETE ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION record_change('note');
+
+CREATE TRIGGER record_notes_update
+    AFTER UPDATE ON notes
+    FOR EACH ROW
+    WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION record_change('note');
+
+CREATE TRIGGER record_saved_searches_write
+    AFTER INSERT OR DELETE ON saved_searches
+    FOR EACH ROW
+    EXECUTE FUNCTION record_change('saved_search');
+
+CREATE TRIGGER record_saved_searches_update
+    AFTER UPDATE ON saved_searches
+    FOR EACH ROW
+    WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION record_change('saved_search');
+
+CREATE TRIGGER record_search_subscriptions_write
+    AFTER INSERT OR DELETE ON search_subscriptions
+    FOR EACH ROW
+    EXECUTE FUNCTION record_change('subscription');
+
+CREATE TRIGGER record_search_subscriptions_update
+    AFTER UPDATE ON search_subscriptions
+    FOR EACH ROW
+    WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION record_change('subscription');
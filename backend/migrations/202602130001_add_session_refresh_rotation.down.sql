@@ -0,0 +1,7 @@
+-- Drop refresh token rotation columns
+ALTER TABLE user_sessions
+    DROP COLUMN IF EXISTS revoked_reason,
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS refreshed_at,
+    DROP COLUMN IF EXISTS refresh_token_id,
+    DROP COLUMN IF EXISTS device_name;
@@ -0,0 +1,11 @@
+-- Track the current refresh token of each session so rotated tokens cannot be replayed
+ALTER TABLE user_sessions
+    ADD COLUMN device_name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN refresh_token_id VARCHAR(64),
+    ADD COLUMN refreshed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN revoked_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN revoked_reason VARCHAR(50);
+
+COMMENT ON COLUMN user_sessions.device_name IS 'Readable device description, e.g. "Chrome on macOS"';
+COMMENT ON COLUMN user_sessions.refresh_token_id IS 'Token ID of the only refresh token the session accepts; NULL for sessions created before rotation';
+COMMENT ON COLUMN user_sessions.revoked_reason IS 'Why the session ended: logout, revoked or refresh_token_reuse';
@@ -0,0 +1,2 @@
+-- Drop checklist progress cache
+DROP TABLE IF EXISTS note_checklist_progress;
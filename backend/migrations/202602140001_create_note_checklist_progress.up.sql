@@ -0,0 +1,10 @@
+-- Cache checklist item counts per note version for tag progress rollups
+CREATE TABLE note_checklist_progress (
+    note_id UUID PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    checked INTEGER NOT NULL DEFAULT 0,
+    unchecked INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE note_checklist_progress IS 'Checklist counts parsed from note content, valid while the note version matches';
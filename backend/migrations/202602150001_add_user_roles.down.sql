@@ -0,0 +1,7 @@
+-- Drop administrator roles and account disabling
+ALTER TABLE users DROP CONSTRAINT IF EXISTS valid_user_role;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disabled_reason,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,12 @@
+-- Add administrator roles and account disabling
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user',
+    ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN disabled_reason TEXT;
+
+ALTER TABLE users ADD CONSTRAINT valid_user_role
+CHECK (role IN ('user', 'admin'));
+
+COMMENT ON COLUMN users.role IS 'Access role: user or admin';
+COMMENT ON COLUMN users.disabled_at IS 'Set while an administrator has disabled the account; disabled users cannot sign in';
+COMMENT ON COLUMN users.disabled_reason IS 'Why the account was disabled, shown to administrators';
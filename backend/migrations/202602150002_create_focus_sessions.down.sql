@@ -0,0 +1,2 @@
+-- Drop focus sessions
+DROP TABLE IF EXISTS focus_sessions;
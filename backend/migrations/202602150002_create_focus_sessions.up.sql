@@ -0,0 +1,17 @@
+-- Record time spent focused on notes
+CREATE TABLE focus_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT valid_focus_session_end CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+-- A user focuses on one note at a time
+CREATE UNIQUE INDEX idx_focus_sessions_running ON focus_sessions(user_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_focus_sessions_user_started ON focus_sessions(user_id, started_at);
+CREATE INDEX idx_focus_sessions_note ON focus_sessions(note_id);
+
+COMMENT ON TABLE focus_sessions IS 'Time-boxed focus sessions recorded against notes, summarised as a timesheet';
+COMMENT ON COLUMN focus_sessions.ended_at IS 'NULL while the session is running';
@@ -0,0 +1,24 @@
+-- Share tags between users again, keeping the oldest tag of each name
+UPDATE note_tags nt
+SET tag_id = keep.id
+FROM tags t, (
+    SELECT DISTINCT ON (name) id, name
+    FROM tags
+    ORDER BY name, created_at, id
+) keep
+WHERE t.id = nt.tag_id
+  AND keep.name = t.name
+  AND nt.tag_id <> keep.id;
+
+DELETE FROM tags t
+WHERE EXISTS (
+    SELECT 1 FROM tags older
+    WHERE older.name = t.name
+      AND (older.created_at, older.id) < (t.created_at, t.id)
+);
+
+ALTER TABLE tags DROP CONSTRAINT tags_user_id_name_key;
+ALTER TABLE tags DROP COLUMN user_id;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
+
+COMMENT ON COLUMN tags.name IS 'Tag name (unique, max 100 characters)';
@@ -0,0 +1,32 @@
+-- Scope tags to users. Tags were shared by every user, so tag listings and
+-- lookups could reveal tag names other users created.
+ALTER TABLE tags ADD COLUMN user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE tags DROP CONSTRAINT tags_name_key;
+
+-- Give every user a copy of each shared tag their notes use
+INSERT INTO tags (id, user_id, name, created_at)
+SELECT gen_random_uuid(), n.user_id, t.name, MIN(t.created_at)
+FROM tags t
+JOIN note_tags nt ON nt.tag_id = t.id
+JOIN notes n ON n.id = nt.note_id
+WHERE t.user_id IS NULL
+GROUP BY n.user_id, t.name;
+
+-- Point note tags at the copy owned by the note's user
+UPDATE note_tags nt
+SET tag_id = ut.id
+FROM notes n, tags t, tags ut
+WHERE n.id = nt.note_id
+  AND t.id = nt.tag_id
+  AND t.user_id IS NULL
+  AND ut.user_id = n.user_id
+  AND ut.name = t.name;
+
+-- Shared tags are no longer referenced
+DELETE FROM tags WHERE user_id IS NULL;
+
+ALTER TABLE tags ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name);
+
+COMMENT ON COLUMN tags.user_id IS 'User owning the tag; tag names are unique per user';
+COMMENT ON COLUMN tags.name IS 'Tag name (unique per user, max 100 characters)';
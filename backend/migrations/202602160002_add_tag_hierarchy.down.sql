@@ -0,0 +1,9 @@
+-- Nested tags cannot be represented without the hierarchy
+DELETE FROM tags WHERE name LIKE '%/%';
+
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS valid_tag_name;
+ALTER TABLE tags ADD CONSTRAINT valid_tag_name
+CHECK (name ~ '^#[a-zA-Z0-9_-]+$');
+
+DROP INDEX IF EXISTS idx_tags_parent_id;
+ALTER TABLE tags DROP COLUMN IF EXISTS parent_id;
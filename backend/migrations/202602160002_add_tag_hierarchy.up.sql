@@ -0,0 +1,12 @@
+-- Support nested tags such as #work/projecta. Each nested tag points at its
+-- parent tag of the same user, which is created along with it.
+ALTER TABLE tags ADD COLUMN parent_id UUID REFERENCES tags(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_tags_parent_id ON tags(parent_id);
+
+-- Allow "/" between the segments of a tag name
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS valid_tag_name;
+ALTER TABLE tags ADD CONSTRAINT valid_tag_name
+CHECK (name ~ '^#[a-zA-Z0-9_-]+(/[a-zA-Z0-9_-]+)*$');
+
+COMMENT ON COLUMN tags.parent_id IS 'Parent tag of a nested tag, e.g. #work for #work/projecta';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_digest_frequency;
+ALTER TABLE users DROP COLUMN IF EXISTS digest_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS digest_frequency;
@@ -0,0 +1,9 @@
+-- Users opt in to daily or weekly digest emails
+ALTER TABLE users ADD COLUMN digest_frequency VARCHAR(10) NOT NULL DEFAULT 'off'
+    CHECK (digest_frequency IN ('off', 'daily', 'weekly'));
+ALTER TABLE users ADD COLUMN digest_sent_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_digest_frequency ON users(digest_frequency) WHERE digest_frequency <> 'off';
+
+COMMENT ON COLUMN users.digest_frequency IS 'How often the user receives a digest email: off, daily or weekly';
+COMMENT ON COLUMN users.digest_sent_at IS 'When the last digest email was sent';
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS queue_webhook_tags_insert ON tags;
+DROP TRIGGER IF EXISTS queue_webhook_notes_update ON notes;
+DROP TRIGGER IF EXISTS queue_webhook_notes_write ON notes;
+DROP FUNCTION IF EXISTS queue_webhook_deliveries();
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,93 @@
+-- Outbound webhooks: users register a URL that is sent signed note and tag events
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (events <@ ARRAY['note.created', 'note.updated', 'note.deleted', 'tag.created']::TEXT[]),
+    CHECK (cardinality(events) > 0)
+);
+
+CREATE INDEX idx_webhooks_user_id ON webhooks(user_id);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER,
+    error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (status IN ('pending', 'delivered', 'failed'))
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+COMMENT ON TABLE webhooks IS 'Outbound webhooks registered by users';
+COMMENT ON COLUMN webhooks.secret IS 'Key of the HMAC-SHA256 signature sent with every delivery';
+COMMENT ON COLUMN webhooks.events IS 'Events delivered to the webhook';
+COMMENT ON TABLE webhook_deliveries IS 'Events queued for and sent to webhooks, written by triggers';
+COMMENT ON COLUMN webhook_deliveries.next_attempt_at IS 'When a pending delivery is attempted next';
+
+-- Queue a delivery of the event to every active webhook of the row's owner
+-- that subscribed to it. Running in the writing transaction means an event is
+-- queued exactly when its change commits. Titles of private notes are not
+-- sent out.
+CREATE OR REPLACE FUNCTION queue_webhook_deliveries()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    ev TEXT;
+    ev_data JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    IF TG_TABLE_NAME = 'tags' THEN
+        ev := 'tag.created';
+        ev_data := jsonb_build_object('id', rec.id, 'name', rec.name, 'parent_id', rec.parent_id);
+    ELSE
+        ev := 'note.' || CASE TG_OP WHEN 'INSERT' THEN 'created' WHEN 'UPDATE' THEN 'updated' ELSE 'deleted' END;
+        ev_data := jsonb_build_object(
+            'id', rec.id,
+            'title', CASE WHEN rec.is_private THEN NULL ELSE rec.title END,
+            'is_private', rec.is_private,
+            'version', rec.version
+        );
+    END IF;
+
+    INSERT INTO webhook_deliveries (webhook_id, event, payload)
+    SELECT w.id, ev, jsonb_build_object('event', ev, 'created_at', NOW(), 'data', ev_data)
+    FROM webhooks w
+    WHERE w.user_id = rec.user_id AND w.active AND ev = ANY(w.events);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER queue_webhook_notes_write
+    AFTER INSERT OR DELETE ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION queue_webhook_deliveries();
+
+CREATE TRIGGER queue_webhook_notes_update
+    AFTER UPDATE ON notes
+    FOR EACH ROW
+    WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION queue_webhook_deliveries();
+
+CREATE TRIGGER queue_webhook_tags_insert
+    AFTER INSERT ON tags
+    FOR EACH ROW
+    EXECUTE FUNCTION queue_webhook_deliveries();
@@ -0,0 +1,18 @@
+-- API keys let automation tools and browser extensions write to a user's
+-- account without a session
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+COMMENT ON TABLE api_keys IS 'API keys authenticating integrations such as the capture endpoint';
+COMMENT ON COLUMN api_keys.prefix IS 'Start of the key, shown so users can tell their keys apart';
+COMMENT ON COLUMN api_keys.key_hash IS 'Hex SHA-256 of the key; the key itself is only shown once';
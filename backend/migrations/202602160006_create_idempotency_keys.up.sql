@@ -0,0 +1,20 @@
+-- Idempotency keys let clients retry note creation without creating
+-- duplicates; the first response is stored and replayed for 24 hours
+CREATE TABLE idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Responses of requests sent with an Idempotency-Key header, replayed on retries';
+COMMENT ON COLUMN idempotency_keys.request_hash IS 'Hex SHA-256 of the method, path and body; a key cannot be reused for another request';
+COMMENT ON COLUMN idempotency_keys.status_code IS 'NULL while the first request is still being handled';
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS llm_token_budget;
+
+DROP TABLE IF EXISTS llm_usage;
@@ -0,0 +1,22 @@
+-- Record the tokens each user consumes through LLM features, so usage can
+-- be reported and monthly budgets enforced
+CREATE TABLE llm_usage (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feature VARCHAR(50) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    prompt_tokens INTEGER NOT NULL,
+    completion_tokens INTEGER NOT NULL,
+    cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_llm_usage_user_created_at ON llm_usage(user_id, created_at);
+
+ALTER TABLE users ADD COLUMN llm_token_budget INTEGER;
+
+COMMENT ON TABLE llm_usage IS 'Tokens consumed by each LLM call made for a user';
+COMMENT ON COLUMN llm_usage.feature IS 'Feature that made the call, e.g. prettify or qa';
+COMMENT ON COLUMN llm_usage.cost_usd IS 'Estimated from the configured cost per 1K tokens of the provider';
+COMMENT ON COLUMN users.llm_token_budget IS 'Monthly LLM token budget overriding LLM_MONTHLY_TOKEN_BUDGET; NULL uses the default';
@@ -0,0 +1,22 @@
+-- Cache LLM responses by a hash of the content they were generated from, so
+-- repeating an operation on unchanged content skips the LLM
+CREATE TABLE llm_cache (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feature VARCHAR(50) NOT NULL,
+    note_id UUID REFERENCES notes(id) ON DELETE CASCADE,
+    source_hash VARCHAR(64) NOT NULL,
+    result_hash VARCHAR(64),
+    response TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (user_id, feature, source_hash)
+);
+
+CREATE INDEX idx_llm_cache_note_id ON llm_cache(note_id);
+CREATE INDEX idx_llm_cache_expires_at ON llm_cache(expires_at);
+
+COMMENT ON TABLE llm_cache IS 'LLM responses reused while the content they were generated from is unchanged';
+COMMENT ON COLUMN llm_cache.source_hash IS 'Hex SHA-256 of the content sent to the LLM';
+COMMENT ON COLUMN llm_cache.result_hash IS 'Hex SHA-256 of the note content the response produced, if it was saved to the note';
+COMMENT ON COLUMN llm_cache.note_id IS 'Note the response was generated from; edits to the note invalidate it';
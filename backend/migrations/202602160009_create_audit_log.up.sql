@@ -0,0 +1,21 @@
+-- Create the audit log of actions users take on their notes
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- Not a foreign key: the audit trail outlives deleted accounts
+    user_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    resource_type VARCHAR(20) NOT NULL,
+    resource_id UUID,
+    count INTEGER NOT NULL DEFAULT 1,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_user_created ON audit_log(user_id, created_at DESC);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+
+COMMENT ON TABLE audit_log IS 'Who did what to which note, when and from where';
+COMMENT ON COLUMN audit_log.action IS 'create, update, delete or prettify';
+COMMENT ON COLUMN audit_log.resource_id IS 'Affected resource; NULL for bulk actions covering several';
+COMMENT ON COLUMN audit_log.count IS 'Number of resources the action covers, e.g. notes removed by a bulk delete';
@@ -0,0 +1,6 @@
+-- Accounts are deleted after a grace period in which the owner can restore them
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+COMMENT ON COLUMN users.deletion_scheduled_at IS 'The account and all its data are purged at this time unless the owner restores it';
@@ -0,0 +1,17 @@
+-- Create the checklist items parsed from note content
+CREATE TABLE note_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    line INTEGER NOT NULL CHECK (line > 0),
+    text TEXT NOT NULL,
+    checked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (note_id, line)
+);
+
+CREATE INDEX idx_note_tasks_user_checked ON note_tasks(user_id, checked);
+
+COMMENT ON TABLE note_tasks IS 'Checklist items of non-private notes, rebuilt whenever a note is written';
+COMMENT ON COLUMN note_tasks.line IS '1-based line of the item in the note content';
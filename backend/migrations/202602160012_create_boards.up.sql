@@ -0,0 +1,21 @@
+-- Create kanban boards whose columns are status tags
+CREATE TABLE boards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    columns TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, name),
+    CHECK (cardinality(columns) BETWEEN 2 AND 10)
+);
+
+CREATE INDEX idx_boards_user_id ON boards(user_id);
+
+COMMENT ON TABLE boards IS 'Kanban boards; a note is a card in the column of its status tag';
+COMMENT ON COLUMN boards.columns IS 'Status tags in column order, e.g. {#todo,#doing,#done}';
+
+CREATE TRIGGER update_boards_updated_at
+    BEFORE UPDATE ON boards
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
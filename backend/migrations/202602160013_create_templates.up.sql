@@ -0,0 +1,26 @@
+-- Create note templates
+CREATE TABLE templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    title VARCHAR(500) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_templates_user_id ON templates(user_id);
+
+COMMENT ON TABLE templates IS 'Reusable note content with {{placeholders}}';
+COMMENT ON COLUMN templates.tags IS 'Tags added to every note created from the template';
+COMMENT ON COLUMN templates.usage_count IS 'Number of notes created from the template';
+
+CREATE TRIGGER update_templates_updated_at
+    BEFORE UPDATE ON templates
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
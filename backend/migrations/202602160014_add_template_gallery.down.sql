@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_templates_public_category;
+ALTER TABLE templates DROP COLUMN IF EXISTS cloned_from;
+ALTER TABLE templates DROP COLUMN IF EXISTS clone_count;
+ALTER TABLE templates DROP COLUMN IF EXISTS category;
@@ -0,0 +1,10 @@
+-- Share public templates in a gallery
+ALTER TABLE templates
+    ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN clone_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN cloned_from UUID REFERENCES templates(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_templates_public_category ON templates(category) WHERE is_public;
+
+COMMENT ON COLUMN templates.clone_count IS 'Number of times other users cloned the template';
+COMMENT ON COLUMN templates.cloned_from IS 'Public template this one was cloned from';
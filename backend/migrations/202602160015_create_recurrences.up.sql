@@ -0,0 +1,28 @@
+-- Create schedules creating notes from templates
+CREATE TABLE recurrences (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    template_id UUID NOT NULL REFERENCES templates(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    weekday SMALLINT NOT NULL DEFAULT 0 CHECK (weekday BETWEEN 0 AND 6),
+    month_day SMALLINT NOT NULL DEFAULT 0 CHECK (month_day BETWEEN 0 AND 31),
+    time_of_day VARCHAR(5) NOT NULL,
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_note_id UUID REFERENCES notes(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_recurrences_user_id ON recurrences(user_id);
+CREATE INDEX idx_recurrences_due ON recurrences(next_run_at) WHERE NOT paused;
+
+COMMENT ON TABLE recurrences IS 'Schedules creating a note from a template, e.g. a daily journal';
+COMMENT ON COLUMN recurrences.time_of_day IS 'HH:MM in time_zone';
+
+CREATE TRIGGER update_recurrences_updated_at
+    BEFORE UPDATE ON recurrences
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
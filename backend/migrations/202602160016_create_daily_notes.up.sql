@@ -0,0 +1,17 @@
+-- Create daily notes, one note per user and day, and the template new
+-- daily notes start from
+ALTER TABLE users ADD COLUMN journal_template_id UUID REFERENCES templates(id) ON DELETE SET NULL;
+
+COMMENT ON COLUMN users.journal_template_id IS 'Template new daily notes are created from';
+
+CREATE TABLE daily_notes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX idx_daily_notes_note_id ON daily_notes(note_id);
+
+COMMENT ON TABLE daily_notes IS 'The journal note of each day; deleting the note frees the day';
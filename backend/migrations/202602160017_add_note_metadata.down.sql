@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notes_metadata_source;
+ALTER TABLE notes DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,6 @@
+-- Record where notes were written: location, source app and device
+ALTER TABLE notes ADD COLUMN metadata JSONB;
+
+CREATE INDEX idx_notes_metadata_source ON notes(user_id, (metadata->>'source')) WHERE metadata IS NOT NULL;
+
+COMMENT ON COLUMN notes.metadata IS 'Location, source app and device the note was created with';
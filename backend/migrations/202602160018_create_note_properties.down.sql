@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notes_properties;
+ALTER TABLE notes DROP COLUMN IF EXISTS properties;
+DROP TABLE IF EXISTS note_properties;
@@ -0,0 +1,17 @@
+-- Custom typed note properties, defined per user
+CREATE TABLE note_properties (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('string', 'number', 'date', 'select')),
+    options JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+ALTER TABLE notes ADD COLUMN properties JSONB;
+
+CREATE INDEX idx_notes_properties ON notes USING GIN (properties) WHERE properties IS NOT NULL;
+
+COMMENT ON COLUMN note_properties.options IS 'Options of select properties';
+COMMENT ON COLUMN notes.properties IS 'Values of the note properties by property name';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notes_color;
+ALTER TABLE notes DROP COLUMN IF EXISTS icon;
+ALTER TABLE notes DROP COLUMN IF EXISTS color;
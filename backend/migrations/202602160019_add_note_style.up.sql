@@ -0,0 +1,8 @@
+-- Color labels and emoji icons for visual organization of notes
+ALTER TABLE notes ADD COLUMN color VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE notes ADD COLUMN icon VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_notes_color ON notes(user_id, color) WHERE color <> '';
+
+COMMENT ON COLUMN notes.color IS 'Color label of the note, empty without one';
+COMMENT ON COLUMN notes.icon IS 'Emoji shown with the note, empty without one';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notes_notebook_id;
+ALTER TABLE notes DROP COLUMN IF EXISTS notebook_id;
+DROP TABLE IF EXISTS notebooks;
@@ -0,0 +1,31 @@
+-- Notebooks group a user's notes into workspaces; every note belongs to one
+CREATE TABLE notebooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+CREATE UNIQUE INDEX idx_notebooks_default ON notebooks(user_id) WHERE is_default;
+
+-- Existing notes move to a default notebook of their owner, keeping their
+-- updated_at
+INSERT INTO notebooks (user_id, name, is_default)
+SELECT DISTINCT user_id, 'Personal', TRUE FROM notes;
+
+ALTER TABLE notes ADD COLUMN notebook_id UUID REFERENCES notebooks(id);
+
+ALTER TABLE notes DISABLE TRIGGER update_notes_updated_at;
+UPDATE notes SET notebook_id = notebooks.id
+FROM notebooks
+WHERE notebooks.user_id = notes.user_id AND notebooks.is_default;
+ALTER TABLE notes ENABLE TRIGGER update_notes_updated_at;
+
+ALTER TABLE notes ALTER COLUMN notebook_id SET NOT NULL;
+
+CREATE INDEX idx_notes_notebook_id ON notes(notebook_id);
+
+COMMENT ON COLUMN notebooks.is_default IS 'The notebook of notes created without one; each user has exactly one';
@@ -0,0 +1,15 @@
+-- Notes of shared notebooks go back to the default notebooks of their authors
+UPDATE notes SET notebook_id = notebooks.id
+FROM notebooks
+WHERE notebooks.user_id = notes.user_id AND notebooks.is_default
+    AND notes.notebook_id IN (SELECT id FROM notebooks WHERE organization_id IS NOT NULL);
+DELETE FROM notebooks WHERE organization_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_notebooks_organization_name;
+DROP INDEX IF EXISTS idx_notebooks_user_name;
+ALTER TABLE notebooks ADD CONSTRAINT notebooks_user_id_name_key UNIQUE (user_id, name);
+ALTER TABLE notebooks DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
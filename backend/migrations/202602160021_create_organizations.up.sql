@@ -0,0 +1,45 @@
+-- Organizations are teams sharing notebooks; members have a role
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id),
+    CHECK (role IN ('admin', 'editor', 'viewer'))
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+CREATE TABLE organization_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    CHECK (role IN ('admin', 'editor', 'viewer'))
+);
+
+CREATE INDEX idx_organization_invitations_org ON organization_invitations(organization_id);
+
+-- Shared notebooks belong to an organization; their names are unique within
+-- it, apart from the personal notebooks of their creator
+ALTER TABLE notebooks ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+ALTER TABLE notebooks DROP CONSTRAINT notebooks_user_id_name_key;
+
+CREATE UNIQUE INDEX idx_notebooks_user_name ON notebooks(user_id, name) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX idx_notebooks_organization_name ON notebooks(organization_id, name) WHERE organization_id IS NOT NULL;
+
+COMMENT ON COLUMN notebooks.organization_id IS 'The organization sharing the notebook, NULL for personal notebooks';
+COMMENT ON COLUMN organization_invitations.token_hash IS 'SHA-256 of the invitation token, which is only sent to the invitee';
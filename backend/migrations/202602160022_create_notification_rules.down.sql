@@ -0,0 +1,9 @@
+DELETE FROM webhook_deliveries WHERE event = 'rule.matched';
+UPDATE webhooks SET events = array_remove(events, 'rule.matched');
+DELETE FROM webhooks WHERE cardinality(events) = 0;
+ALTER TABLE webhooks DROP CONSTRAINT webhooks_events_check;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_events_check
+    CHECK (events <@ ARRAY['note.created', 'note.updated', 'note.deleted', 'tag.created']::TEXT[]);
+
+DROP TABLE IF EXISTS notification_rule_matches;
+DROP TABLE IF EXISTS notification_rules;
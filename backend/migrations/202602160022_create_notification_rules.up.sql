@@ -0,0 +1,36 @@
+-- Notification rules: users are notified when a note with a tag is created
+-- or shared with them, in the app, by email or through their webhooks
+CREATE TABLE notification_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tag VARCHAR(100) NOT NULL,
+    events TEXT[] NOT NULL,
+    channels TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (events <@ ARRAY['note_created', 'note_shared']::TEXT[]),
+    CHECK (cardinality(events) > 0),
+    CHECK (channels <@ ARRAY['in_app', 'email', 'webhook']::TEXT[]),
+    CHECK (cardinality(channels) > 0)
+);
+
+CREATE INDEX idx_notification_rules_user_id ON notification_rules(user_id);
+CREATE INDEX idx_notification_rules_tag ON notification_rules(tag);
+
+-- Notes each rule has notified about, so a note notifies a rule once
+CREATE TABLE notification_rule_matches (
+    rule_id UUID NOT NULL REFERENCES notification_rules(id) ON DELETE CASCADE,
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    matched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (rule_id, note_id)
+);
+
+CREATE INDEX idx_notification_rule_matches_note_id ON notification_rule_matches(note_id);
+
+-- Webhooks can subscribe to the matches of notification rules
+ALTER TABLE webhooks DROP CONSTRAINT webhooks_events_check;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_events_check
+    CHECK (events <@ ARRAY['note.created', 'note.updated', 'note.deleted', 'tag.created', 'rule.matched']::TEXT[]);
+
+COMMENT ON TABLE notification_rules IS 'Tags users are notified about when notes with them are created or shared';
+COMMENT ON COLUMN notification_rules.tag IS 'Lowercased tag name with its leading #';
+COMMENT ON TABLE notification_rule_matches IS 'Notes a rule has notified about';
@@ -0,0 +1,11 @@
+-- Notes published read-only at a public slug, served without authentication
+CREATE TABLE published_notes (
+    note_id UUID PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+    slug VARCHAR(80) NOT NULL UNIQUE,
+    noindex BOOLEAN NOT NULL DEFAULT FALSE,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE published_notes IS 'Notes published read-only at /p/{slug}';
+COMMENT ON COLUMN published_notes.noindex IS 'Whether search engines are asked not to index the page';
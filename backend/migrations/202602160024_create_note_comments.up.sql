@@ -0,0 +1,15 @@
+-- Comments on shared and published notes
+CREATE TABLE note_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (length(content) BETWEEN 1 AND 5000)
+);
+
+CREATE INDEX idx_note_comments_note_created ON note_comments(note_id, created_at);
+CREATE INDEX idx_note_comments_user_id ON note_comments(user_id);
+
+COMMENT ON TABLE note_comments IS 'Comments on shared and published notes';
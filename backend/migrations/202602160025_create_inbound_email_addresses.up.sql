@@ -0,0 +1,10 @@
+-- Secret addresses users email notes to; mail received at an address
+-- becomes a note of its user
+CREATE TABLE inbound_email_addresses (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE inbound_email_addresses IS 'Secret email addresses turning received mail into notes';
+COMMENT ON COLUMN inbound_email_addresses.token IS 'Local part of the address, lowercase hex';
@@ -0,0 +1,17 @@
+-- RSS and Atom feeds of recent notes, authenticated by a token in the URL
+CREATE TABLE note_feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('recent', 'published')),
+    notebook_id UUID REFERENCES notebooks(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_note_feeds_user_id ON note_feeds(user_id);
+
+COMMENT ON TABLE note_feeds IS 'Feeds of recently created or published notes for feed readers';
+COMMENT ON COLUMN note_feeds.token_hash IS 'SHA-256 of the token in the feed URL, which is only shown once';
+COMMENT ON COLUMN note_feeds.notebook_id IS 'Notebook the feed is limited to, NULL for all the user''s notes';
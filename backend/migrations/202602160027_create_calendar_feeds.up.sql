@@ -0,0 +1,11 @@
+-- iCal feeds of due dates and recurring notes, authenticated by a token in
+-- the URL
+CREATE TABLE calendar_feeds (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE calendar_feeds IS 'iCal feeds calendar apps subscribe to';
+COMMENT ON COLUMN calendar_feeds.token_hash IS 'SHA-256 of the token in the feed URL, which is only shown once';
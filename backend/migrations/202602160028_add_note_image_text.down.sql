@@ -0,0 +1,20 @@
+-- Restore the search trigger without image text
+CREATE OR REPLACE FUNCTION update_notes_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.title, '')), 'A');
+    IF NOT NEW.is_private THEN
+        NEW.search_vector = NEW.search_vector ||
+            setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.content, '')), 'B');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_notes_search_vector ON notes;
+CREATE TRIGGER update_notes_search_vector
+    BEFORE INSERT OR UPDATE OF title, content, language, is_private ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_notes_search_vector();
+
+ALTER TABLE notes DROP COLUMN IF EXISTS image_text;
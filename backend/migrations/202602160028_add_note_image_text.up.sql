@@ -0,0 +1,25 @@
+-- Text recognized in images uploaded to notes, so photos of whiteboards and
+-- documents are found by search
+ALTER TABLE notes ADD COLUMN image_text TEXT;
+
+COMMENT ON COLUMN notes.image_text IS 'Text recognized by OCR in images uploaded to the note; never set for private notes';
+
+-- Index image text with weight C, after title and content
+CREATE OR REPLACE FUNCTION update_notes_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.title, '')), 'A');
+    IF NOT NEW.is_private THEN
+        NEW.search_vector = NEW.search_vector ||
+            setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.content, '')), 'B') ||
+            setweight(to_tsvector(note_search_config(NEW.language), COALESCE(NEW.image_text, '')), 'C');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_notes_search_vector ON notes;
+CREATE TRIGGER update_notes_search_vector
+    BEFORE INSERT OR UPDATE OF title, content, language, is_private, image_text ON notes
+    FOR EACH ROW
+    EXECUTE FUNCTION update_notes_search_vector();
@@ -0,0 +1,16 @@
+-- Spelling and grammar corrections of notes waiting for the user to apply them
+CREATE TABLE proofread_proposals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    note_version INTEGER NOT NULL,
+    title TEXT,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_proofread_proposals_note_user ON proofread_proposals(note_id, user_id);
+
+COMMENT ON TABLE proofread_proposals IS 'Proofread notes shown to users as a diff before they apply them';
+COMMENT ON COLUMN proofread_proposals.note_version IS 'Version of the note proofread; the proposal only applies to that version';
@@ -0,0 +1,5 @@
+-- Merged case duplicates are not restored
+DROP INDEX IF EXISTS tags_user_id_lower_name_key;
+ALTER TABLE tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name);
+
+COMMENT ON COLUMN tags.name IS 'Tag name (unique per user, max 100 characters)';
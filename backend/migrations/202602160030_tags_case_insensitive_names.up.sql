@@ -0,0 +1,37 @@
+-- Make tag names unique per user regardless of case. Notes were tagged
+-- through a case-sensitive lookup, so #Work and #work could end up as two
+-- tags of the same user. Case duplicates are merged into the oldest tag of
+-- each name first.
+CREATE TEMP TABLE tag_merges AS
+SELECT t.id AS duplicate_id, (
+    SELECT k.id FROM tags k
+    WHERE k.user_id = t.user_id AND LOWER(k.name) = LOWER(t.name)
+    ORDER BY k.created_at, k.id
+    LIMIT 1
+) AS keep_id
+FROM tags t;
+
+DELETE FROM tag_merges WHERE duplicate_id = keep_id;
+
+-- Notes tagged with a duplicate are tagged with the kept tag instead
+INSERT INTO note_tags (note_id, tag_id, created_at)
+SELECT nt.note_id, m.keep_id, MIN(nt.created_at)
+FROM note_tags nt
+JOIN tag_merges m ON m.duplicate_id = nt.tag_id
+GROUP BY nt.note_id, m.keep_id
+ON CONFLICT DO NOTHING;
+
+-- Nested tags move below the kept parent
+UPDATE tags
+SET parent_id = (SELECT keep_id FROM tag_merges WHERE duplicate_id = tags.parent_id)
+WHERE parent_id IN (SELECT duplicate_id FROM tag_merges);
+
+-- Deleting the duplicates deletes their note tags
+DELETE FROM tags WHERE id IN (SELECT duplicate_id FROM tag_merges);
+
+DROP TABLE tag_merges;
+
+ALTER TABLE tags DROP CONSTRAINT tags_user_id_name_key;
+CREATE UNIQUE INDEX tags_user_id_lower_name_key ON tags (user_id, LOWER(name));
+
+COMMENT ON COLUMN tags.name IS 'Tag name (unique per user regardless of case, max 100 characters)';
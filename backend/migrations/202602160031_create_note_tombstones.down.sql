@@ -0,0 +1,2 @@
+ALTER TABLE notes DROP COLUMN IF EXISTS tags_changed_at;
+DROP TABLE IF EXISTS note_tombstones;
@@ -0,0 +1,14 @@
+-- Record deleted notes so sync can tell clients which notes to remove, and
+-- when the tags of a note last changed so tag-only changes are synced too
+CREATE TABLE note_tombstones (
+    note_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_note_tombstones_user_deleted_at ON note_tombstones(user_id, deleted_at);
+
+ALTER TABLE notes ADD COLUMN tags_changed_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON TABLE note_tombstones IS 'Notes deleted by their users, returned by sync until clients catch up';
+COMMENT ON COLUMN notes.tags_changed_at IS 'When the tags of the note last changed without changing the note';
@@ -0,0 +1,31 @@
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    op TEXT;
+    ver INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+        op := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        rec := NEW;
+        op := 'created';
+    ELSE
+        rec := NEW;
+        op := 'updated';
+    END IF;
+
+    IF TG_ARGV[0] = 'note' THEN
+        ver := rec.version;
+    END IF;
+
+    INSERT INTO change_log (user_id, entity_type, entity_id, operation, version)
+    VALUES (rec.user_id, TG_ARGV[0], rec.id, op, ver);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+DROP INDEX IF EXISTS idx_change_log_user_seq;
+ALTER TABLE change_log DROP COLUMN IF EXISTS seq;
+DROP TABLE IF EXISTS change_sequences;
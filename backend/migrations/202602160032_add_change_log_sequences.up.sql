@@ -0,0 +1,62 @@
+-- Number the change log of each user with a sequence. Writers of a user
+-- take the next number from change_sequences, whose row stays locked until
+-- they commit, so sequence numbers are gap-free and in commit order.
+CREATE TABLE change_sequences (
+    user_id UUID PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE change_sequences IS 'Last change log sequence number of each user; no foreign key, like change_log';
+
+ALTER TABLE change_log ADD COLUMN seq BIGINT;
+
+UPDATE change_log c
+SET seq = numbered.seq
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY tx_id, id) AS seq
+    FROM change_log
+) numbered
+WHERE c.id = numbered.id;
+
+INSERT INTO change_sequences (user_id, last_seq)
+SELECT user_id, MAX(seq) FROM change_log GROUP BY user_id;
+
+ALTER TABLE change_log ALTER COLUMN seq SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_change_log_user_seq ON change_log(user_id, seq);
+
+COMMENT ON COLUMN change_log.seq IS 'Position of the change among the changes of the user, from 1';
+
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec RECORD;
+    op TEXT;
+    ver INTEGER;
+    next_seq BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+        op := 'deleted';
+    ELSIF TG_OP = 'INSERT' THEN
+        rec := NEW;
+        op := 'created';
+    ELSE
+        rec := NEW;
+        op := 'updated';
+    END IF;
+
+    IF TG_ARGV[0] = 'note' THEN
+        ver := rec.version;
+    END IF;
+
+    INSERT INTO change_sequences (user_id, last_seq)
+    VALUES (rec.user_id, 1)
+    ON CONFLICT (user_id) DO UPDATE SET last_seq = change_sequences.last_seq + 1
+    RETURNING last_seq INTO next_seq;
+
+    INSERT INTO change_log (user_id, entity_type, entity_id, operation, version, seq)
+    VALUES (rec.user_id, TG_ARGV[0], rec.id, op, ver, next_seq);
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
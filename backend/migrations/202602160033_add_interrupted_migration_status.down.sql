@@ -0,0 +1,5 @@
+UPDATE outgoing_migrations SET status = 'failed' WHERE status = 'interrupted';
+
+ALTER TABLE outgoing_migrations DROP CONSTRAINT outgoing_migrations_status_check;
+ALTER TABLE outgoing_migrations ADD CONSTRAINT outgoing_migrations_status_check
+    CHECK (status IN ('running', 'failed', 'completed'));
@@ -0,0 +1,5 @@
+-- Migrations cut short by a server shutdown are marked interrupted and
+-- resumed when the server starts again
+ALTER TABLE outgoing_migrations DROP CONSTRAINT outgoing_migrations_status_check;
+ALTER TABLE outgoing_migrations ADD CONSTRAINT outgoing_migrations_status_check
+    CHECK (status IN ('running', 'interrupted', 'failed', 'completed'));
@@ -0,0 +1,5 @@
+-- Record the country each session signed in from, so sign-ins from new
+-- locations can be reported to the user
+ALTER TABLE user_sessions ADD COLUMN country VARCHAR(2) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN user_sessions.country IS 'Country the session signed in from, empty when unknown';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS two_factor_credentials;
+ALTER TABLE users DROP COLUMN IF EXISTS two_factor_enabled;
@@ -0,0 +1,28 @@
+-- Optional TOTP two-factor authentication. The secret is kept apart from
+-- the users row; users.two_factor_enabled lets authentication check it
+-- without another query.
+ALTER TABLE users ADD COLUMN two_factor_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE two_factor_credentials (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE two_factor_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_two_factor_recovery_codes_user_id ON two_factor_recovery_codes(user_id);
+
+COMMENT ON COLUMN users.two_factor_enabled IS 'Sign-ins need a TOTP or recovery code';
+COMMENT ON COLUMN two_factor_credentials.secret IS 'Base32 TOTP secret, encrypted when a master key is configured';
+COMMENT ON COLUMN two_factor_credentials.enabled_at IS 'NULL while enrollment awaits its first code';
+COMMENT ON COLUMN two_factor_credentials.last_used_step IS 'Time step of the last accepted code, so codes cannot be replayed';
+COMMENT ON TABLE two_factor_recovery_codes IS 'SHA-256 hashes of single-use recovery codes';
@@ -0,0 +1,5 @@
+-- Let organizations require a watermark on exports of the notes in their
+-- notebooks, naming who exported them and when
+ALTER TABLE organizations ADD COLUMN watermark_exports BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN organizations.watermark_exports IS 'Whether exports of notes in the organization''s notebooks are watermarked';
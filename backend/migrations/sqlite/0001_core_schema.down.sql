@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS blacklisted_tokens;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,94 @@
+-- Core schema for the SQLite development and test database.
+-- Mirrors the PostgreSQL migrations for users, sessions, notes and tags.
+-- Ids are UUID strings; now() and gen_random_uuid() are registered by the
+-- application. Triggers and full-text search are PostgreSQL only: the
+-- services set updated_at and version themselves. SQLite does not enforce
+-- VARCHAR lengths, so the limits the services rely on are checked instead.
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    google_id TEXT UNIQUE NOT NULL,
+    email TEXT UNIQUE NOT NULL,
+    avatar_url TEXT,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW()),
+    read_only_until TIMESTAMP,
+    auto_apply_tag_suggestions BOOLEAN NOT NULL DEFAULT FALSE,
+    role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    disabled_at TIMESTAMP,
+    disabled_reason TEXT,
+    digest_frequency TEXT NOT NULL DEFAULT 'off' CHECK (digest_frequency IN ('off', 'daily', 'weekly')),
+    digest_sent_at TIMESTAMP,
+    llm_token_budget INTEGER
+);
+
+CREATE INDEX idx_users_created_at ON users(created_at);
+
+CREATE TABLE user_sessions (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW()),
+    last_seen TIMESTAMP NOT NULL DEFAULT (NOW()),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    device_name TEXT NOT NULL DEFAULT '',
+    refresh_token_id TEXT,
+    refreshed_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    revoked_reason TEXT
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+CREATE INDEX idx_user_sessions_active ON user_sessions(is_active);
+CREATE INDEX idx_user_sessions_last_seen ON user_sessions(last_seen);
+
+CREATE TABLE blacklisted_tokens (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    token_id TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    reason TEXT DEFAULT 'logout'
+);
+
+CREATE INDEX idx_blacklisted_tokens_expires_at ON blacklisted_tokens(expires_at);
+
+CREATE TABLE notes (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT CHECK (length(title) <= 500),
+    content TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW()),
+    version INTEGER NOT NULL DEFAULT 1,
+    prettified_at TIMESTAMP,
+    ai_improved BOOLEAN NOT NULL DEFAULT FALSE,
+    language TEXT NOT NULL DEFAULT 'und',
+    is_private BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_notes_user_id ON notes(user_id);
+CREATE INDEX idx_notes_updated_at ON notes(updated_at);
+CREATE INDEX idx_notes_user_created ON notes(user_id, created_at DESC);
+
+CREATE TABLE tags (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id TEXT REFERENCES tags(id) ON DELETE SET NULL,
+    name TEXT NOT NULL CHECK (length(name) <= 100 AND name LIKE '#%'),
+    created_at TIMESTAMP DEFAULT (NOW()),
+    UNIQUE (user_id, name)
+);
+
+CREATE INDEX idx_tags_parent_id ON tags(parent_id);
+
+CREATE TABLE note_tags (
+    note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX idx_note_tags_tag_id ON note_tags(tag_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS legal_hold_audit;
+DROP TABLE IF EXISTS legal_holds;
+DROP TABLE IF EXISTS audit_log;
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+ALTER TABLE users DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,42 @@
+-- Scheduled account deletion, and the tables the account purge touches:
+-- the audit log and legal holds, which block deletion
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at);
+
+CREATE TABLE audit_log (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    resource_type TEXT NOT NULL,
+    resource_id TEXT,
+    count INTEGER NOT NULL DEFAULT 1,
+    ip_address TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE INDEX idx_audit_log_user_created ON audit_log(user_id, created_at DESC);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+
+CREATE TABLE legal_holds (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL,
+    note_id TEXT,
+    reason TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    released_by TEXT,
+    released_at TIMESTAMP
+);
+
+CREATE INDEX idx_legal_holds_user ON legal_holds(user_id);
+
+CREATE TABLE legal_hold_audit (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    hold_id TEXT NOT NULL REFERENCES legal_holds(id),
+    action TEXT NOT NULL CHECK (action IN ('applied', 'released')),
+    actor_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT (NOW())
+);
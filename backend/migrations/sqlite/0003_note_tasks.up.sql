@@ -0,0 +1,14 @@
+-- Checklist items parsed from note content
+CREATE TABLE note_tasks (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    line INTEGER NOT NULL CHECK (line > 0),
+    text TEXT NOT NULL,
+    checked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW()),
+    UNIQUE (note_id, line)
+);
+
+CREATE INDEX idx_note_tasks_user_checked ON note_tasks(user_id, checked);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS daily_notes;
+ALTER TABLE users DROP COLUMN journal_template_id;
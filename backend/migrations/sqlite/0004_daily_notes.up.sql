@@ -0,0 +1,13 @@
+-- Daily notes, one note per user and day. Templates are PostgreSQL only, so
+-- the journal template is not a foreign key here.
+ALTER TABLE users ADD COLUMN journal_template_id TEXT;
+
+CREATE TABLE daily_notes (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX idx_daily_notes_note_id ON daily_notes(note_id);
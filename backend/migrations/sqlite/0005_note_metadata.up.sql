@@ -0,0 +1,2 @@
+-- Record where notes were written: location, source app and device, as JSON
+ALTER TABLE notes ADD COLUMN metadata TEXT;
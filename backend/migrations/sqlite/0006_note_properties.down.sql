@@ -0,0 +1,2 @@
+ALTER TABLE notes DROP COLUMN properties;
+DROP TABLE IF EXISTS note_properties;
@@ -0,0 +1,12 @@
+-- Custom typed note properties, defined per user. Options and values are JSON.
+CREATE TABLE note_properties (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('string', 'number', 'date', 'select')),
+    options TEXT,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    UNIQUE(user_id, name)
+);
+
+ALTER TABLE notes ADD COLUMN properties TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE notes DROP COLUMN icon;
+ALTER TABLE notes DROP COLUMN color;
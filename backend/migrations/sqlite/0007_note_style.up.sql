@@ -0,0 +1,3 @@
+-- Color labels and emoji icons for visual organization of notes
+ALTER TABLE notes ADD COLUMN color TEXT NOT NULL DEFAULT '';
+ALTER TABLE notes ADD COLUMN icon TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,24 @@
+-- Notebooks group a user's notes into workspaces; every note belongs to one
+CREATE TABLE notebooks (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW()),
+    UNIQUE(user_id, name)
+);
+
+CREATE UNIQUE INDEX idx_notebooks_default ON notebooks(user_id) WHERE is_default;
+
+INSERT INTO notebooks (user_id, name, is_default)
+SELECT DISTINCT user_id, 'Personal', TRUE FROM notes;
+
+-- SQLite cannot add a NOT NULL reference; the services always set it
+ALTER TABLE notes ADD COLUMN notebook_id TEXT REFERENCES notebooks(id);
+
+UPDATE notes SET notebook_id = (
+    SELECT id FROM notebooks WHERE notebooks.user_id = notes.user_id AND is_default
+);
+
+CREATE INDEX idx_notes_notebook_id ON notes(notebook_id);
@@ -0,0 +1,29 @@
+UPDATE notes SET notebook_id = (
+    SELECT id FROM notebooks WHERE notebooks.user_id = notes.user_id AND is_default
+)
+WHERE notebook_id IN (SELECT id FROM notebooks WHERE organization_id IS NOT NULL);
+
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TABLE notebooks_old (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW()),
+    UNIQUE(user_id, name)
+);
+
+INSERT INTO notebooks_old (id, user_id, name, is_default, created_at, updated_at)
+SELECT id, user_id, name, is_default, created_at, updated_at FROM notebooks
+WHERE organization_id IS NULL;
+
+DROP TABLE notebooks;
+ALTER TABLE notebooks_old RENAME TO notebooks;
+
+CREATE UNIQUE INDEX idx_notebooks_default ON notebooks(user_id) WHERE is_default;
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
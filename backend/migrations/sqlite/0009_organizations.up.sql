@@ -0,0 +1,56 @@
+-- Organizations are teams sharing notebooks; members have a role
+CREATE TABLE organizations (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    name TEXT NOT NULL,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW())
+);
+
+CREATE TABLE organization_members (
+    organization_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'editor', 'viewer')),
+    created_at TIMESTAMP DEFAULT (NOW()),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+CREATE TABLE organization_invitations (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    organization_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'editor', 'viewer')),
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP
+);
+
+CREATE INDEX idx_organization_invitations_org ON organization_invitations(organization_id);
+
+-- SQLite cannot drop the unique name constraint, so the notebooks table is
+-- rebuilt; the notes referencing it are checked when the migration commits
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TABLE notebooks_new (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    organization_id TEXT REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT (NOW()),
+    updated_at TIMESTAMP DEFAULT (NOW())
+);
+
+INSERT INTO notebooks_new (id, user_id, name, is_default, created_at, updated_at)
+SELECT id, user_id, name, is_default, created_at, updated_at FROM notebooks;
+
+DROP TABLE notebooks;
+ALTER TABLE notebooks_new RENAME TO notebooks;
+
+CREATE UNIQUE INDEX idx_notebooks_default ON notebooks(user_id) WHERE is_default;
+CREATE UNIQUE INDEX idx_notebooks_user_name ON notebooks(user_id, name) WHERE organization_id IS NULL;
+CREATE UNIQUE INDEX idx_notebooks_organization_name ON notebooks(organization_id, name) WHERE organization_id IS NOT NULL;
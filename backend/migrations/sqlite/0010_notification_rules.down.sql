@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_rule_matches;
+DROP TABLE IF EXISTS notification_rules;
@@ -0,0 +1,23 @@
+-- Notification rules: users are notified when a note with a tag is created
+-- or shared with them. Events and channels are stored as PostgreSQL array
+-- literals, which the services read and write on both databases.
+CREATE TABLE notification_rules (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    events TEXT NOT NULL,
+    channels TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX idx_notification_rules_user_id ON notification_rules(user_id);
+CREATE INDEX idx_notification_rules_tag ON notification_rules(tag);
+
+CREATE TABLE notification_rule_matches (
+    rule_id TEXT NOT NULL REFERENCES notification_rules(id) ON DELETE CASCADE,
+    note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    matched_at TIMESTAMP NOT NULL DEFAULT (NOW()),
+    PRIMARY KEY (rule_id, note_id)
+);
+
+CREATE INDEX idx_notification_rule_matches_note_id ON notification_rule_matches(note_id);
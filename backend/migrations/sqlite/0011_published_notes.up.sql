@@ -0,0 +1,8 @@
+-- Notes published read-only at a public slug, served without authentication
+CREATE TABLE published_notes (
+    note_id TEXT PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+    slug TEXT NOT NULL UNIQUE,
+    noindex BOOLEAN NOT NULL DEFAULT FALSE,
+    published_at TIMESTAMP NOT NULL DEFAULT (NOW()),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
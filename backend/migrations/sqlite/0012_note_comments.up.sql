@@ -0,0 +1,12 @@
+-- Comments on shared and published notes
+CREATE TABLE note_comments (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW()),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX idx_note_comments_note_created ON note_comments(note_id, created_at);
+CREATE INDEX idx_note_comments_user_id ON note_comments(user_id);
@@ -0,0 +1,7 @@
+-- Secret addresses users email notes to; mail received at an address
+-- becomes a note of its user
+CREATE TABLE inbound_email_addresses (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
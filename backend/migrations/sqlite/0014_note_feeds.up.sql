@@ -0,0 +1,13 @@
+-- RSS and Atom feeds of recent notes, authenticated by a token in the URL
+CREATE TABLE note_feeds (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('recent', 'published')),
+    notebook_id TEXT REFERENCES notebooks(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX idx_note_feeds_user_id ON note_feeds(user_id);
@@ -0,0 +1,8 @@
+-- iCal feeds of due dates and recurring notes, authenticated by a token in
+-- the URL
+CREATE TABLE calendar_feeds (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
@@ -0,0 +1 @@
+ALTER TABLE notes DROP COLUMN image_text;
@@ -0,0 +1,2 @@
+-- Text recognized by OCR in images uploaded to notes
+ALTER TABLE notes ADD COLUMN image_text TEXT;
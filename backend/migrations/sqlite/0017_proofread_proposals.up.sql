@@ -0,0 +1,13 @@
+-- Spelling and grammar corrections of notes waiting for the user to apply them
+CREATE TABLE proofread_proposals (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    note_version INTEGER NOT NULL,
+    title TEXT,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW()),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_proofread_proposals_note_user ON proofread_proposals(note_id, user_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS tags_user_id_lower_name_key;
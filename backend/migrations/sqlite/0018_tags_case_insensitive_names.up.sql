@@ -0,0 +1,28 @@
+-- Make tag names unique per user regardless of case, merging case
+-- duplicates into the oldest tag of each name
+CREATE TEMP TABLE tag_merges AS
+SELECT t.id AS duplicate_id, (
+    SELECT k.id FROM tags k
+    WHERE k.user_id = t.user_id AND LOWER(k.name) = LOWER(t.name)
+    ORDER BY k.created_at, k.id
+    LIMIT 1
+) AS keep_id
+FROM tags t;
+
+DELETE FROM tag_merges WHERE duplicate_id = keep_id;
+
+INSERT OR IGNORE INTO note_tags (note_id, tag_id, created_at)
+SELECT nt.note_id, m.keep_id, MIN(nt.created_at)
+FROM note_tags nt
+JOIN tag_merges m ON m.duplicate_id = nt.tag_id
+GROUP BY nt.note_id, m.keep_id;
+
+UPDATE tags
+SET parent_id = (SELECT keep_id FROM tag_merges WHERE duplicate_id = tags.parent_id)
+WHERE parent_id IN (SELECT duplicate_id FROM tag_merges);
+
+DELETE FROM tags WHERE id IN (SELECT duplicate_id FROM tag_merges);
+
+DROP TABLE tag_merges;
+
+CREATE UNIQUE INDEX tags_user_id_lower_name_key ON tags (user_id, LOWER(name));
@@ -0,0 +1,10 @@
+-- Deleted notes returned by sync, and when the tags of a note last changed
+CREATE TABLE note_tombstones (
+    note_id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deleted_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX idx_note_tombstones_user_deleted_at ON note_tombstones(user_id, deleted_at);
+
+ALTER TABLE notes ADD COLUMN tags_changed_at TIMESTAMP;
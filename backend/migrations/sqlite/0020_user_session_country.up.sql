@@ -0,0 +1,2 @@
+-- Country each session signed in from, empty when unknown
+ALTER TABLE user_sessions ADD COLUMN country TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,20 @@
+-- Optional TOTP two-factor authentication
+ALTER TABLE users ADD COLUMN two_factor_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE two_factor_credentials (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE TABLE two_factor_recovery_codes (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW())
+);
+
+CREATE INDEX idx_two_factor_recovery_codes_user_id ON two_factor_recovery_codes(user_id);
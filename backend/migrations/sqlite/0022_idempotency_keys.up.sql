@@ -0,0 +1,16 @@
+-- Idempotency keys let clients retry note creation without creating
+-- duplicates; the first response is stored and replayed for 24 hours
+CREATE TABLE idempotency_keys (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER,
+    response_body BLOB,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW()),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
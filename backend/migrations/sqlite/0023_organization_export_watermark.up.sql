@@ -0,0 +1,2 @@
+-- Whether exports of notes in the organization's notebooks are watermarked
+ALTER TABLE organizations ADD COLUMN watermark_exports BOOLEAN NOT NULL DEFAULT FALSE;
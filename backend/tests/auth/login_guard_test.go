@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginGuardBackoff(t *testing.T) {
+	guard := auth.NewLoginGuard(auth.LoginGuardConfig{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutAttempts: 6,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	})
+	ip := auth.IPKey("203.0.113.7")
+
+	// The free attempts do not slow the client down
+	assert.Zero(t, guard.Fail(ip))
+	assert.Zero(t, guard.Fail(ip))
+	assert.Zero(t, guard.Wait(ip))
+
+	// Then the wait doubles with each failure, up to the maximum
+	assert.Equal(t, time.Second, guard.Fail(ip).Round(time.Second))
+	assert.Equal(t, 2*time.Second, guard.Fail(ip).Round(time.Second))
+	assert.Equal(t, 4*time.Second, guard.Fail(ip).Round(time.Second))
+	assert.Greater(t, guard.Wait(ip), 3*time.Second)
+
+	// Until the key is locked out
+	assert.Equal(t, time.Hour, guard.Fail(ip).Round(time.Second))
+
+	// Other keys are not affected
+	assert.Zero(t, guard.Wait(auth.IPKey("203.0.113.8")))
+	assert.Equal(t, time.Hour, guard.Wait(auth.UserKey("user"), ip).Round(time.Second))
+
+	guard.Succeed(ip)
+	assert.Zero(t, guard.Wait(ip))
+}
+
+func TestLoginGuardForgetsOldFailures(t *testing.T) {
+	guard := auth.NewLoginGuard(auth.LoginGuardConfig{
+		FreeAttempts: 1,
+		BaseDelay:    time.Millisecond,
+		MaxDelay:     time.Millisecond,
+		Window:       20 * time.Millisecond,
+	})
+	user := auth.UserKey("user")
+
+	guard.Fail(user)
+	assert.Equal(t, time.Millisecond, guard.Fail(user).Round(time.Millisecond))
+	assert.Zero(t, guard.Cleanup(), "Expected recent failures to be kept")
+
+	// Past the window the failures are forgotten, and counting starts over
+	time.Sleep(30 * time.Millisecond)
+	assert.Zero(t, guard.Fail(user))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 1, guard.Cleanup())
+}
+
+func TestLoginGuardZeroConfigNeverBlocks(t *testing.T) {
+	guard := auth.NewLoginGuard(auth.LoginGuardConfig{})
+	ip := auth.IPKey("203.0.113.7")
+	for i := 0; i < 50; i++ {
+		guard.Fail(ip)
+	}
+	assert.Zero(t, guard.Wait(ip))
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchNoteService writes every note it is given, except notes whose
+// content is "conflict" and notes it does not know, recording the size of
+// each batch
+type batchNoteService struct {
+	services.NoteServiceInterface
+	batches []int
+}
+
+func (s *batchNoteService) BatchCreateNotes(ctx context.Context, userID string, requests []*models.CreateNoteRequest) ([]models.Note, error) {
+	s.batches = append(s.batches, len(requests))
+	notes := make([]models.Note, len(requests))
+	for i, request := range requests {
+		if request.Content == "conflict" {
+			return nil, services.ErrVersionMismatch
+		}
+		notes[i] = *request.ToNote(uuid.MustParse(userID))
+	}
+	return notes, nil
+}
+
+func (s *batchNoteService) BatchUpdateNotes(ctx context.Context, userID string, requests []struct {
+	NoteID  string
+	Request *models.UpdateNoteRequest
+}) ([]models.Note, error) {
+	s.batches = append(s.batches, len(requests))
+	notes := make([]models.Note, len(requests))
+	for i, request := range requests {
+		if request.NoteID == "missing" {
+			return nil, services.ErrNoteNotFound
+		}
+		notes[i] = models.Note{ID: uuid.New(), UserID: uuid.MustParse(userID), Content: *request.Request.Content, Version: 2}
+	}
+	return notes, nil
+}
+
+// batchResults sends a batch request and decodes its per-item results
+func batchResults(t *testing.T, router http.Handler, user *models.User, method, path, body string) *models.BatchResults {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "user", user))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data models.BatchResults `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return &response.Data
+}
+
+func TestPartialBatches(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	service := &batchNoteService{}
+	h := handlers.NewNotesHandler(service, nil, nil, nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/notes/batch", h.BatchCreateNotes).Methods("POST")
+	router.HandleFunc("/api/v1/notes/batch", h.BatchUpdateNotes).Methods("PUT")
+
+	created := batchResults(t, router, user, "POST", "/api/v1/notes/batch?atomic=false",
+		`[{"content":"Buy milk #errands"},{"content":""},{"content":"conflict"},{"content":"Call mom"}]`)
+	assert.Equal(t, 2, created.Succeeded)
+	assert.Equal(t, 2, created.Failed)
+	require.Len(t, created.Results, 4)
+	for i, result := range created.Results {
+		assert.Equal(t, i, result.Index)
+	}
+	assert.Equal(t, models.BatchItemCreated, created.Results[0].Status)
+	require.NotNil(t, created.Results[0].Note)
+	assert.Equal(t, []string{"#errands"}, created.Results[0].Note.Tags)
+	assert.Equal(t, models.BatchItemError, created.Results[1].Status)
+	assert.Equal(t, handlers.ErrCodeValidationFailed, created.Results[1].Error.Code)
+	assert.Equal(t, "content", created.Results[1].Error.Fields[0].Field)
+	assert.Equal(t, "NOTE_VERSION_CONFLICT", created.Results[2].Error.Code)
+	assert.Equal(t, models.BatchItemCreated, created.Results[3].Status)
+	// Each valid note is created in a batch of its own
+	assert.Equal(t, []int{1, 1, 1}, service.batches)
+
+	updated := batchResults(t, router, user, "PUT", "/api/v1/notes/batch?atomic=false",
+		`{"updates":[{"note_id":"missing","updates":{"content":"a"}},{"note_id":"`+uuid.NewString()+`","updates":{"content":"b"}},{"note_id":"x","updates":{"version":0}}]}`)
+	assert.Equal(t, 1, updated.Succeeded)
+	assert.Equal(t, 2, updated.Failed)
+	assert.Equal(t, "NOTE_NOT_FOUND", updated.Results[0].Error.Code)
+	assert.Equal(t, models.BatchItemUpdated, updated.Results[1].Status)
+	assert.Equal(t, "b", updated.Results[1].Note.Content)
+	assert.Equal(t, "version", updated.Results[2].Error.Fields[0].Field)
+
+	// Atomic batches still fail as a whole
+	req := httptest.NewRequest("POST", "/api/v1/notes/batch", strings.NewReader(`[{"content":"a"},{"content":"conflict"}]`))
+	req = req.WithContext(context.WithValue(req.Context(), "user", user))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
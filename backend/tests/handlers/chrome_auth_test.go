@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chromeAuthEnv signs in through the Chrome handler against a fake Google
+// tokeninfo endpoint and refreshes through the auth handler, with sessions
+// stored in the test database
+type chromeAuthEnv struct {
+	chrome         *handlers.ChromeAuthHandler
+	auth           *handlers.AuthHandler
+	sessionService *services.SessionService
+}
+
+func newChromeAuthEnv(t *testing.T) *chromeAuthEnv {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	google := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"email":          "chrome-user@example.com",
+			"email_verified": "true",
+			"user_id":        "google-chrome-user",
+		})
+	}))
+	t.Cleanup(google.Close)
+
+	tokenService := auth.NewTokenService(
+		"test-secret-key-that-is-long-enough-for-hs256",
+		15*time.Minute,
+		24*time.Hour,
+		"silence-notes",
+		"silence-notes-users",
+	)
+	userService := services.NewUserService(db)
+	sessionService := services.NewSessionService(db)
+
+	chrome := handlers.NewChromeAuthHandler(tokenService, userService)
+	chrome.SetTokenInfoURL(google.URL)
+	chrome.SetSessionService(sessionService)
+	authHandler := handlers.NewAuthHandler(tokenService, userService)
+	authHandler.SetSessionService(sessionService)
+
+	return &chromeAuthEnv{chrome: chrome, auth: authHandler, sessionService: sessionService}
+}
+
+// signIn exchanges a Chrome token from a browser with userAgent
+func (e *chromeAuthEnv) signIn(t *testing.T, userAgent string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(handlers.ChromeAuthRequest{Token: t.Name() + "-chrome-token"})
+	req := httptest.NewRequest("POST", "/api/v1/auth/chrome", bytes.NewBuffer(body))
+	req.Header.Set("User-Agent", userAgent)
+	w := httptest.NewRecorder()
+	e.chrome.ExchangeChromeToken(w, req)
+	return w
+}
+
+// mustSignIn signs in and returns the response
+func (e *chromeAuthEnv) mustSignIn(t *testing.T, userAgent string) handlers.ChromeAuthResponse {
+	w := e.signIn(t, userAgent)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data handlers.ChromeAuthResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response.Data
+}
+
+// refresh refreshes the session of refreshToken, returning the response and
+// the new refresh token
+func (e *chromeAuthEnv) refresh(refreshToken string) (*httptest.ResponseRecorder, string) {
+	body, _ := json.Marshal(auth.RefreshTokenRequest{RefreshToken: refreshToken})
+	req := httptest.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	e.auth.RefreshToken(w, req)
+
+	var response struct {
+		Data struct {
+			RefreshToken string `json:"refresh_token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response.Data.RefreshToken
+}
+
+func TestChromeSignInsWithSameUserAgentGetOwnSessions(t *testing.T) {
+	env := newChromeAuthEnv(t)
+	userAgent := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0.0.0 Safari/537.36"
+
+	// Two machines running the same Chrome build
+	first := env.mustSignIn(t, userAgent)
+	second := env.mustSignIn(t, userAgent)
+	assert.NotEqual(t, first.SessionID, second.SessionID)
+
+	// Each refreshes in turn without revoking the other
+	firstRefresh := first.RefreshToken
+	secondRefresh := second.RefreshToken
+	for i := 0; i < 2; i++ {
+		w, next := env.refresh(firstRefresh)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		firstRefresh = next
+
+		w, next = env.refresh(secondRefresh)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		secondRefresh = next
+	}
+}
+
+func TestEndedSessionRefreshesDoNotLockOutSignIn(t *testing.T) {
+	env := newChromeAuthEnv(t)
+	guard := auth.NewLoginGuard(auth.LoginGuardConfig{
+		FreeAttempts:    1,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: 3,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	})
+	env.chrome.SetLoginGuard(guard)
+	env.auth.SetLoginGuard(guard)
+	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Chrome/120.0.0.0 Safari/537.36"
+
+	signedIn := env.mustSignIn(t, userAgent)
+	err := env.sessionService.RevokeSession(context.Background(), signedIn.User.ID.String(), signedIn.SessionID, services.SessionRevokedByUser)
+	require.NoError(t, err)
+
+	// A client that missed the revocation keeps trying its refresh token
+	for i := 0; i < 5; i++ {
+		w, _ := env.refresh(signedIn.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "Session has ended")
+	}
+
+	// The user can still sign in again
+	env.mustSignIn(t, userAgent)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/encryption"
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingNoteService fails every note lookup and deletion with err; unused
+// methods panic
+type failingNoteService struct {
+	services.NoteServiceInterface
+	err error
+}
+
+func (s *failingNoteService) GetNoteByID(ctx context.Context, userID, noteID string) (*models.Note, error) {
+	return nil, s.err
+}
+
+func (s *failingNoteService) DeleteNote(ctx context.Context, userID, noteID string) error {
+	return s.err
+}
+
+func TestServiceErrorResponses(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+
+	tests := []struct {
+		name    string
+		method  string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"missing note", "GET", services.ErrNoteNotFound, http.StatusNotFound, "NOTE_NOT_FOUND", "Note not found"},
+		{"legal hold", "DELETE", fmt.Errorf("note %s: %w", "1", services.ErrLegalHold), http.StatusConflict, "LEGAL_HOLD", "Note 1"},
+		{"LLM budget exceeded", "GET", fmt.Errorf("failed to get note: %w", services.ErrLLMBudgetExceeded), http.StatusTooManyRequests, "LLM_BUDGET_EXCEEDED", "Failed to get note"},
+		{"missing encryption key", "GET", encryption.ErrKeyUnavailable, http.StatusServiceUnavailable, "ENCRYPTION_UNAVAILABLE", "Encryption key not available"},
+		{"database failure", "GET", errors.New("failed to get note: connection refused"), http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := handlers.NewNotesHandler(&failingNoteService{err: tt.err}, nil, nil, nil)
+			router := mux.NewRouter()
+			router.HandleFunc("/api/v1/notes/{id}", h.GetNote).Methods("GET")
+			router.HandleFunc("/api/v1/notes/{id}", h.DeleteNote).Methods("DELETE")
+
+			req := httptest.NewRequest(tt.method, "/api/v1/notes/"+uuid.New().String(), nil)
+			req = req.WithContext(context.WithValue(req.Context(), "user", user))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			var response models.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.False(t, response.Success)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, tt.code, response.Error.Code)
+			assert.Equal(t, tt.message, response.Error.Message)
+			assert.NotContains(t, w.Body.String(), "connection refused")
+		})
+	}
+}
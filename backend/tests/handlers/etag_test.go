@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedNoteService holds a single note and checks update versions like
+// NoteService; unused methods panic
+type versionedNoteService struct {
+	services.NoteServiceInterface
+	note *models.Note
+}
+
+func (s *versionedNoteService) GetNoteByID(ctx context.Context, userID, noteID string) (*models.Note, error) {
+	if noteID != s.note.ID.String() {
+		return nil, services.ErrNoteNotFound
+	}
+	note := *s.note
+	return &note, nil
+}
+
+func (s *versionedNoteService) UpdateNote(ctx context.Context, userID, noteID string, request *models.UpdateNoteRequest) (*models.Note, error) {
+	if noteID != s.note.ID.String() {
+		return nil, services.ErrNoteNotFound
+	}
+	if request.Version != nil && *request.Version != s.note.Version {
+		return nil, services.ErrVersionMismatch
+	}
+	request.ApplyUpdates(s.note)
+	s.note.Version++
+	note := *s.note
+	return &note, nil
+}
+
+func TestNoteETags(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	title := "Title"
+	svc := &versionedNoteService{note: &models.Note{ID: uuid.New(), UserID: user.ID, Title: &title, Content: "v1", Version: 1}}
+	h := handlers.NewNotesHandler(svc, nil, nil, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/notes/{id}", h.GetNote).Methods("GET")
+	router.HandleFunc("/api/v1/notes/{id}", h.UpdateNote).Methods("PUT")
+
+	serve := func(method string, body interface{}, ifMatch string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/api/v1/notes/"+svc.note.ID.String(), bytes.NewReader(payload))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	update := func(content string) map[string]string {
+		return map[string]string{"content": content}
+	}
+
+	w := serve("GET", nil, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+
+	t.Run("updates when If-Match has the current ETag", func(t *testing.T) {
+		w := serve("PUT", update("v2"), `"1"`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("returns the current copy when If-Match is stale", func(t *testing.T) {
+		w := serve("PUT", update("stale"), `"1"`)
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, handlers.ErrCodePreconditionFailed, response.Error.Code)
+		require.NotNil(t, response.Error.Current)
+		assert.Equal(t, "v2", response.Error.Current.Content)
+		assert.Equal(t, 2, response.Error.Current.Version)
+	})
+
+	t.Run("matches any ETag of a list or a wildcard", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("PUT", update("v3"), `"1", "2"`).Code)
+		assert.Equal(t, http.StatusOK, serve("PUT", update("v4"), `*`).Code)
+	})
+
+	t.Run("never matches weak or malformed ETags", func(t *testing.T) {
+		assert.Equal(t, http.StatusPreconditionFailed, serve("PUT", update("x"), `W/"4"`).Code)
+		assert.Equal(t, http.StatusPreconditionFailed, serve("PUT", update("x"), `4`).Code)
+	})
+
+	t.Run("treats a stale body version like If-Match", func(t *testing.T) {
+		w := serve("PUT", map[string]interface{}{"content": "x", "version": 1}, "")
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("returns 404 for conditional updates of missing notes", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/api/v1/notes/"+uuid.New().String(), bytes.NewReader([]byte(`{"content":"x"}`)))
+		req.Header.Set("If-Match", `"1"`)
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
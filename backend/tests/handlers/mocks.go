@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -16,7 +17,7 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) CreateOrUpdateFromGoogle(userInfo *auth.GoogleUserInfo) (*models.User, error) {
+func (m *MockUserService) CreateOrUpdateFromGoogle(ctx context.Context, userInfo *auth.GoogleUserInfo) (*models.User, error) {
 	args := m.Called(userInfo)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -24,7 +25,7 @@ func (m *MockUserService) CreateOrUpdateFromGoogle(userInfo *auth.GoogleUserInfo
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) GetByID(userID string) (*models.User, error) {
+func (m *MockUserService) GetByID(ctx context.Context, userID string) (*models.User, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -32,7 +33,7 @@ func (m *MockUserService) GetByID(userID string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) Update(user *models.User) (*models.User, error) {
+func (m *MockUserService) Update(ctx context.Context, user *models.User) (*models.User, error) {
 	args := m.Called(user)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -40,12 +41,25 @@ func (m *MockUserService) Update(user *models.User) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserService) Delete(userID string) error {
+func (m *MockUserService) Delete(ctx context.Context, userID string) error {
 	args := m.Called(userID)
 	return args.Error(0)
 }
 
-func (m *MockUserService) CreateSession(userID, ipAddress, userAgent string) (*models.UserSession, error) {
+func (m *MockUserService) ScheduleDeletion(ctx context.Context, userID string) (*time.Time, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockUserService) CancelDeletion(ctx context.Context, userID string) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserService) CreateSession(ctx context.Context, userID, ipAddress, userAgent string) (*models.UserSession, error) {
 	args := m.Called(userID, ipAddress, userAgent)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -53,12 +67,12 @@ func (m *MockUserService) CreateSession(userID, ipAddress, userAgent string) (*m
 	return args.Get(0).(*models.UserSession), args.Error(1)
 }
 
-func (m *MockUserService) UpdateSessionActivity(sessionID, ipAddress, userAgent string) error {
+func (m *MockUserService) UpdateSessionActivity(ctx context.Context, sessionID, ipAddress, userAgent string) error {
 	args := m.Called(sessionID, ipAddress, userAgent)
 	return args.Error(0)
 }
 
-func (m *MockUserService) GetActiveSessions(userID string) ([]models.UserSession, error) {
+func (m *MockUserService) GetActiveSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -66,17 +80,17 @@ func (m *MockUserService) GetActiveSessions(userID string) ([]models.UserSession
 	return args.Get(0).([]models.UserSession), args.Error(1)
 }
 
-func (m *MockUserService) DeleteSession(sessionID, userID string) error {
+func (m *MockUserService) DeleteSession(ctx context.Context, sessionID, userID string) error {
 	args := m.Called(sessionID, userID)
 	return args.Error(0)
 }
 
-func (m *MockUserService) DeleteAllSessions(userID string) error {
+func (m *MockUserService) DeleteAllSessions(ctx context.Context, userID string) error {
 	args := m.Called(userID)
 	return args.Error(0)
 }
 
-func (m *MockUserService) GetUserStats(userID string) (*models.UserStats, error) {
+func (m *MockUserService) GetUserStats(ctx context.Context, userID string) (*models.UserStats, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -84,7 +98,23 @@ func (m *MockUserService) GetUserStats(userID string) (*models.UserStats, error)
 	return args.Get(0).(*models.UserStats), args.Error(1)
 }
 
-func (m *MockUserService) SearchUsers(query string, page, limit int) ([]models.User, int, error) {
+func (m *MockUserService) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserSettings), args.Error(1)
+}
+
+func (m *MockUserService) UpdateSettings(ctx context.Context, userID string, request *models.UpdateUserSettingsRequest) (*models.UserSettings, error) {
+	args := m.Called(userID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserSettings), args.Error(1)
+}
+
+func (m *MockUserService) SearchUsers(ctx context.Context, query string, page, limit int) ([]models.User, int, error) {
 	args := m.Called(query, page, limit)
 	if args.Get(0) == nil {
 		return nil, args.Int(1), args.Error(2)
@@ -12,10 +12,10 @@ import (
 	"testing"
 
 	"github.com/gpd/my-notes/internal/config"
-	"github.com/gpd/my-notes/internal/database"
 	"github.com/gpd/my-notes/internal/handlers"
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -46,34 +46,20 @@ func (suite *NotesIntegrationTestSuite) SetupSuite() {
 	cfg, err := config.LoadConfig("")
 	require.NoError(suite.T(), err, "Failed to load config")
 
-	// Create test database
-	db, err := database.CreateTestDatabase(cfg.Database)
-	require.NoError(suite.T(), err, "Failed to create test database")
-	suite.db = db
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(suite.T(), err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the suite
+	suite.db = testutil.NewTestDB(suite.T(), cfg.Database, "../../migrations")
 
 	// Create test user
-	suite.userEmail = "test@example.com"
-	suite.userID = uuid.New()
-
-	// Insert test user into database
-	query := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-	`
-	_, err = suite.db.Exec(query, suite.userID, "google_"+suite.userID.String(), suite.userEmail)
-	require.NoError(suite.T(), err, "Failed to create test user")
+	user := testutil.NewTestUser(suite.T(), suite.db)
+	suite.userID = user.ID
+	suite.userEmail = user.Email
 
 	// Create tag service with real database
 	tagService := services.NewTagService(suite.db)
 
 	// Create note service with real database
 	noteService := services.NewNoteService(suite.db, tagService)
-	suite.noteHandler = handlers.NewNotesHandler(noteService, nil, nil) // semanticSearchService and prettifyService not needed for tests
+	suite.noteHandler = handlers.NewNotesHandler(noteService, nil, nil, nil) // semanticSearchService, prettifyService and confirmationService not needed for tests
 
 	// Setup router with routes
 	suite.router = mux.NewRouter()
@@ -93,14 +79,6 @@ func (suite *NotesIntegrationTestSuite) setupRoutes() {
 	suite.router.HandleFunc("/api/v1/notes/{id}", suite.noteHandler.DeleteNote).Methods("DELETE")
 }
 
-func (suite *NotesIntegrationTestSuite) TearDownSuite() {
-	// Clean up test database
-	if suite.db != nil {
-		database.DropTestDatabase(suite.db)
-		suite.db.Close()
-	}
-}
-
 func (suite *NotesIntegrationTestSuite) SetupTest() {
 	// Clean up notes between tests but keep the user
 	_, err := suite.db.Exec("DELETE FROM notes WHERE user_id = $1", suite.userID)
@@ -277,7 +255,7 @@ func (suite *NotesIntegrationTestSuite) TestNotesAPI_ErrorHandling() {
 		"content": "", // Empty content
 	}
 	rr = suite.makeRequest("POST", "/api/v1/notes", invalidReq, nil)
-	suite.Equal(http.StatusBadRequest, rr.Code)
+	suite.Equal(http.StatusUnprocessableEntity, rr.Code)
 
 	// Test non-existent note
 	nonExistentID := uuid.New()
@@ -305,7 +283,22 @@ func (suite *NotesIntegrationTestSuite) TestNotesAPI_ErrorHandling() {
 		Version: &[]int{999}[0], // Wrong version
 	}
 	rr = suite.makeRequest("PUT", "/api/v1/notes/"+createResp.Data.ID.String(), updateReq, nil)
-	suite.Equal(http.StatusConflict, rr.Code)
+	suite.Equal(http.StatusPreconditionFailed, rr.Code)
+
+	// Test If-Match with the ETag from GET
+	rr = suite.makeRequest("GET", "/api/v1/notes/"+createResp.Data.ID.String(), nil, nil)
+	suite.Equal(`"1"`, rr.Header().Get("ETag"))
+
+	updateReq.Version = nil
+	rr = suite.makeRequest("PUT", "/api/v1/notes/"+createResp.Data.ID.String(), updateReq, map[string]string{"If-Match": `"1"`})
+	suite.Equal(http.StatusOK, rr.Code)
+	suite.Equal(`"2"`, rr.Header().Get("ETag"))
+
+	rr = suite.makeRequest("PUT", "/api/v1/notes/"+createResp.Data.ID.String(), updateReq, map[string]string{"If-Match": `"1"`})
+	suite.Equal(http.StatusPreconditionFailed, rr.Code)
+	var conflictResp models.APIResponse
+	require.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &conflictResp))
+	suite.Equal(2, conflictResp.Error.Current.Version)
 }
 
 func (suite *NotesIntegrationTestSuite) TestNotesAPI_AutoTitleGeneration() {
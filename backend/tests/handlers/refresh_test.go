@@ -456,4 +456,82 @@ func TestTokenRefreshResponseStructure(t *testing.T) {
 	assert.Greater(t, response.Data.ExpiresIn, 0, "expires_in should be positive")
 
 	mockUserService.AssertExpectations(t)
-}
\ No newline at end of file
+}
+func TestTokenRefreshBacksOffAfterFailures(t *testing.T) {
+	handler, _ := setupAuthHandler(t)
+	handler.SetLoginGuard(auth.NewLoginGuard(auth.LoginGuardConfig{
+		FreeAttempts: 1,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Minute,
+		Window:       time.Hour,
+	}))
+
+	refresh := func(ipAddress string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/auth/refresh",
+			bytes.NewBufferString(`{"refresh_token": "invalid-token"}`))
+		req = req.WithContext(context.WithValue(req.Context(), "clientIP", ipAddress))
+		w := httptest.NewRecorder()
+		handler.RefreshToken(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, refresh("203.0.113.7").Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh("203.0.113.7").Code)
+
+	// The address must now wait before trying again
+	w := refresh("203.0.113.7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+
+	// Other addresses are not held back
+	assert.Equal(t, http.StatusUnauthorized, refresh("203.0.113.8").Code)
+}
+
+func TestTokenRefreshRequiresTwoFactor(t *testing.T) {
+	handler, mockUserService := setupAuthHandler(t)
+	tokenService := auth.NewTokenService(
+		"test-secret-key-that-is-long-enough-for-hs256",
+		15*time.Minute,
+		24*time.Hour,
+		"silence-notes",
+		"silence-notes-users",
+	)
+
+	user := createTestUser()
+	user.TwoFactorEnabled = true
+	mockUserService.On("GetByID", user.ID.String()).Return(user, nil)
+
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(auth.RefreshTokenRequest{RefreshToken: refreshToken})
+		req := httptest.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		handler.RefreshToken(w, req)
+		return w
+	}
+
+	// Sessions that signed in before two-factor authentication was enabled
+	// cannot be renewed
+	tokenPair, err := tokenService.GenerateTokenPair(user)
+	assert.NoError(t, err)
+	w := refresh(tokenPair.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "TWO_FACTOR_REQUIRED")
+
+	// Sessions that signed in with a code keep their two-factor authentication
+	tokenPair, err = tokenService.GenerateTwoFactorTokenPair(user, "two-factor-session")
+	assert.NoError(t, err)
+	w = refresh(tokenPair.RefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			AccessToken string `json:"access_token"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	claims, err := tokenService.ValidateToken(context.Background(), response.Data.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, claims.TwoFactor)
+	assert.Equal(t, "two-factor-session", claims.SessionID)
+}
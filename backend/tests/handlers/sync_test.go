@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncNoteService returns one changed note, tagged #work, and one deleted
+// note, recording the time it was asked for changes since
+type syncNoteService struct {
+	services.NoteServiceInterface
+	note    models.Note
+	deleted uuid.UUID
+	since   time.Time
+}
+
+func (s *syncNoteService) GetNotesForSync(ctx context.Context, userID string, limit, offset int, since *time.Time, includeDeleted bool) ([]models.Note, int, error) {
+	s.since = *since
+	return []models.Note{s.note}, 1, nil
+}
+
+func (s *syncNoteService) GetDeletedNoteIDs(ctx context.Context, userID string, since time.Time) ([]uuid.UUID, error) {
+	return []uuid.UUID{s.deleted}, nil
+}
+
+func (s *syncNoteService) GetTagsForNotes(ctx context.Context, noteIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	return map[uuid.UUID][]string{s.note.ID: {"#work"}}, nil
+}
+
+func (s *syncNoteService) GetNotesByIDs(ctx context.Context, userID string, noteIDs []uuid.UUID) ([]models.Note, error) {
+	for _, id := range noteIDs {
+		if id == s.note.ID {
+			return []models.Note{s.note}, nil
+		}
+	}
+	return []models.Note{}, nil
+}
+
+func (s *syncNoteService) DetectConflicts(ctx context.Context, userID string, notes []models.Note) ([]models.NoteConflict, error) {
+	return []models.NoteConflict{}, nil
+}
+
+func TestSyncNotesCursor(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	service := &syncNoteService{
+		note:    models.Note{ID: uuid.New(), UserID: user.ID, Content: "Tagged without a hashtag", Version: 1},
+		deleted: uuid.New(),
+	}
+	h := handlers.NewNotesHandler(service, nil, nil, nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/notes/sync", h.SyncNotes).Methods("GET")
+
+	sync := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/notes/sync"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := sync("")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data models.SyncResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data.Notes, 1)
+	assert.Equal(t, []string{"#work"}, response.Data.Notes[0].Tags)
+	assert.Equal(t, []uuid.UUID{service.deleted}, response.Data.Deleted)
+	require.NotEmpty(t, response.Data.Cursor)
+
+	// The cursor of the last sync takes precedence over since
+	w = sync("?since=2020-01-01T00:00:00Z&cursor=" + response.Data.Cursor)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	since, err := services.ParseSyncCursor(response.Data.Cursor, time.Now())
+	require.NoError(t, err)
+	assert.True(t, service.since.Equal(since), "Expected changes since %v, got %v", since, service.since)
+
+	w = sync("?cursor=invalid")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_CURSOR")
+
+	expired := services.NewSyncCursor(time.Now().Add(-90 * 24 * time.Hour))
+	w = sync("?cursor=" + expired)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "CURSOR_EXPIRED")
+}
+
+// eventChangeService returns its events after the last sequence number asked for
+type eventChangeService struct {
+	services.ChangeServiceInterface
+	events []models.ChangeRecord
+}
+
+func (s *eventChangeService) ListEvents(ctx context.Context, userID, entityType string, lastSeq int64, limit int) (*models.ChangeEvents, error) {
+	events := &models.ChangeEvents{Events: []models.ChangeRecord{}, LastSeq: lastSeq}
+	for _, event := range s.events {
+		if event.Seq > lastSeq && event.EntityType == entityType {
+			events.Events = append(events.Events, event)
+			events.LastSeq = event.Seq
+		}
+	}
+	return events, nil
+}
+
+func TestSyncNotesLastSeq(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	service := &syncNoteService{note: models.Note{ID: uuid.New(), UserID: user.ID, Content: "Changed", Version: 2}}
+	removed := uuid.New()
+	changes := &eventChangeService{events: []models.ChangeRecord{
+		{Seq: 1, EntityType: models.ChangeEntityNote, EntityID: removed, Operation: models.ChangeOperationCreated},
+		{Seq: 2, EntityType: models.ChangeEntityNote, EntityID: service.note.ID, Operation: models.ChangeOperationCreated},
+		{Seq: 3, EntityType: models.ChangeEntitySavedSearch, EntityID: uuid.New(), Operation: models.ChangeOperationCreated},
+		{Seq: 4, EntityType: models.ChangeEntityNote, EntityID: removed, Operation: models.ChangeOperationDeleted},
+		{Seq: 5, EntityType: models.ChangeEntityNote, EntityID: service.note.ID, Operation: models.ChangeOperationUpdated},
+	}}
+	h := handlers.NewNotesHandler(service, nil, nil, nil)
+	h.SetChangeService(changes)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/notes/sync", h.SyncNotes).Methods("GET")
+
+	sync := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/notes/sync"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := sync("?last_seq=0")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data models.SyncResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data.Events, 4)
+	assert.Equal(t, int64(5), *response.Data.LastSeq)
+	require.Len(t, response.Data.Notes, 1)
+	assert.Equal(t, service.note.ID, response.Data.Notes[0].ID)
+	assert.Equal(t, []uuid.UUID{removed}, response.Data.Deleted)
+	assert.Empty(t, response.Data.Cursor)
+
+	// A sync from the last sequence number is empty
+	w = sync("?last_seq=5")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var empty struct {
+		Data models.SyncResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &empty))
+	assert.Empty(t, empty.Data.Events)
+	assert.Empty(t, empty.Data.Notes)
+	assert.Equal(t, int64(5), *empty.Data.LastSeq)
+
+	w = sync("?last_seq=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_CURSOR")
+}
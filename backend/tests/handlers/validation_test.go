@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestValidation(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	// Invalid requests never reach the service
+	notesHandler := handlers.NewNotesHandler(&failingNoteService{}, nil, nil, nil)
+	tagsHandler := handlers.NewTagsHandler(nil, nil, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/notes", notesHandler.CreateNote).Methods("POST")
+	router.HandleFunc("/api/v1/notes/batch", notesHandler.BatchCreateNotes).Methods("POST")
+	router.HandleFunc("/api/v1/notes/batch", notesHandler.BatchUpdateNotes).Methods("PUT")
+	router.HandleFunc("/api/v1/notes/{id}", notesHandler.UpdateNote).Methods("PUT")
+	router.HandleFunc("/api/v1/search/notes", notesHandler.SearchNotes).Methods("GET")
+	router.HandleFunc("/api/v1/tags/merge", tagsHandler.MergeTags).Methods("POST")
+
+	longContent := strings.Repeat("c", 10001)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		fields map[string]string
+	}{
+		{"create without content", "POST", "/api/v1/notes", `{"title":"t"}`,
+			map[string]string{"content": "is required"}},
+		{"create with every field invalid", "POST", "/api/v1/notes", `{"title":"` + strings.Repeat("t", 501) + `"}`,
+			map[string]string{"title": "must be at most 500 characters", "content": "is required"}},
+		{"update with content too long", "PUT", "/api/v1/notes/" + uuid.NewString(), `{"content":"` + longContent + `"}`,
+			map[string]string{"content": "must be at most 10000 characters"}},
+		{"batch create", "POST", "/api/v1/notes/batch", `[{"content":"a"},{"content":""}]`,
+			map[string]string{"[1].content": "is required"}},
+		{"batch update", "PUT", "/api/v1/notes/batch", `{"updates":[{"note_id":"` + uuid.NewString() + `","updates":{"version":0}}]}`,
+			map[string]string{"updates[0].updates.version": "must be at least 1"}},
+		{"search with unknown order", "GET", "/api/v1/search/notes?query=a&order_by=size", "",
+			map[string]string{"order_by": "must be one of created_at, updated_at, title"}},
+		{"merge without target", "POST", "/api/v1/tags/merge", `{"source":"#a"}`,
+			map[string]string{"target": "is required"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), "user", user))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+			var response models.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			require.NotNil(t, response.Error)
+			assert.Equal(t, handlers.ErrCodeValidationFailed, response.Error.Code)
+
+			fields := make(map[string]string, len(response.Error.Fields))
+			for _, field := range response.Error.Fields {
+				fields[field.Field] = field.Message
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+
+	t.Run("malformed JSON is still a bad request", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/notes", strings.NewReader("{"))
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -10,11 +10,12 @@ import (
 
 	"database/sql"
 
+	"github.com/gpd/my-notes/internal/app"
 	"github.com/gpd/my-notes/internal/config"
 	"github.com/gpd/my-notes/internal/database"
-	"github.com/gpd/my-notes/internal/handlers"
 	"github.com/gpd/my-notes/internal/server"
 	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/gpd/my-notes/tests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -72,11 +73,8 @@ func (suite *AuthFlowTestSuite) SetupSuite() {
 		},
 	}
 
-	// Initialize handlers
-	handlers := handlers.NewHandlers()
-
 	// Create test server
-	suite.server = server.NewServer(testConfig, handlers, db)
+	suite.server = server.NewServer(app.New(testConfig, db, app.Options{}))
 	suite.testConfig = testConfig
 }
 
@@ -216,7 +214,9 @@ func (suite *AuthFlowTestSuite) TestRateLimiting() {
 			var response map[string]interface{}
 			err := json.Unmarshal(w.Body.Bytes(), &response)
 			require.NoError(suite.T(), err)
-			assert.Equal(suite.T(), "Rate limit exceeded", response["error"])
+			errorBody, _ := response["error"].(map[string]interface{})
+			assert.Equal(suite.T(), "RATE_LIMITED", errorBody["code"])
+			assert.Equal(suite.T(), "Rate limit exceeded", errorBody["message"])
 			return
 		}
 	}
@@ -371,21 +371,14 @@ func (suite *AuthFlowTestSuite) TestIntegrationWithDatabase() {
 	assert.NoError(suite.T(), err)
 
 	// Test that we can create and query users
-	testUserID := "550e8400-e29b-41d4-a716-446655440001" // valid UUID format
-
-	// Insert test user
-	_, err = suite.db.Exec(`
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (id) DO NOTHING
-	`, testUserID, "google-123", "test@example.com")
-	assert.NoError(suite.T(), err)
+	testUser := testutil.NewTestUser(suite.T(), suite.db)
 
 	// Query test user
 	var user models.User
-	err = suite.db.QueryRow("SELECT id, email FROM users WHERE id = $1", testUserID).Scan(&user.ID, &user.Email)
+	err = suite.db.QueryRow("SELECT id, email FROM users WHERE id = $1", testUser.ID).Scan(&user.ID, &user.Email)
 	assert.NoError(suite.T(), err)
-	assert.Equal(suite.T(), testUserID, user.ID.String())
+	assert.Equal(suite.T(), testUser.ID, user.ID)
+	assert.Equal(suite.T(), testUser.Email, user.Email)
 }
 
 // TestAuthFlowTestSuite runs the test suite
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gpd/my-notes/internal/app"
 	"github.com/gpd/my-notes/internal/auth"
 	"github.com/gpd/my-notes/internal/config"
 	"github.com/gpd/my-notes/internal/database"
@@ -17,8 +18,8 @@ import (
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/gpd/my-notes/internal/server"
 	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/gpd/my-notes/tests"
-	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -109,11 +110,10 @@ func (suite *LogoutTestSuite) SetupSuite() {
 	authHandler := handlers.NewAuthHandler(suite.tokenService, userService)
 	authHandler.SetBlacklist(suite.blacklistSvc)
 
-	h := &handlers.Handlers{}
-	h.SetAuthHandlers(authHandler, nil)
-
-	// Create server
-	suite.server = server.NewServer(testConfig, h, suite.db)
+	// Create server, serving the auth handler of the suite
+	a := app.New(testConfig, suite.db, app.Options{})
+	a.Handlers.SetAuthHandlers(authHandler, nil)
+	suite.server = server.NewServer(a)
 }
 
 // TearDownSuite runs once after all tests
@@ -138,24 +138,10 @@ func (suite *LogoutTestSuite) SetupTest() {
 
 // createTestUser creates a test user and returns tokens
 func (suite *LogoutTestSuite) createTestUser() (*models.User, *auth.TokenPair, error) {
-	userID := uuid.New()
-	googleID := "google_" + userID.String()
-
-	// Insert user
-	query := `INSERT INTO users (id, google_id, email, avatar_url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
-		RETURNING id, google_id, email, avatar_url, created_at, updated_at`
-
-	var user models.User
-	err := suite.db.QueryRow(query, userID, googleID, "test@example.com", "").Scan(
-		&user.ID, &user.GoogleID, &user.Email, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt,
-	)
-	if err != nil {
-		return nil, nil, err
-	}
+	user := testutil.NewTestUser(suite.T(), suite.db)
 
 	// Generate tokens
-	tokenPair, err := suite.tokenService.GenerateTokenPair(&user)
+	tokenPair, err := suite.tokenService.GenerateTokenPair(user)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -175,7 +161,7 @@ func (suite *LogoutTestSuite) createTestUser() (*models.User, *auth.TokenPair, e
 		return nil, nil, err
 	}
 
-	return &user, tokenPair, nil
+	return user, tokenPair, nil
 }
 
 // TestLogout_Success tests that logout successfully adds token to blacklist
@@ -427,7 +427,8 @@ func (suite *SecurityTestSuite) TestErrorInformationLeakage() {
 			require.NoError(t, err)
 
 			// Check that error messages don't leak sensitive information
-			if errorMsg, ok := response["error"].(string); ok {
+			errorBody, _ := response["error"].(map[string]interface{})
+			if errorMsg, ok := errorBody["message"].(string); ok {
 				// Should not contain sensitive information
 				sensitiveTerms := []string{
 					"password", "secret", "key", "token", "database",
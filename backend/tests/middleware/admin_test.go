@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/middleware"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	handler := middleware.RequireAdmin([]string{" Owner@Example.com "})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(user *models.User) int {
+		req := httptest.NewRequest("GET", "/api/v1/admin/users", nil)
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("allows the admin role", func(t *testing.T) {
+		user := &models.User{ID: uuid.New(), Email: "admin@example.com", Role: models.UserRoleAdmin}
+		assert.Equal(t, http.StatusOK, serve(user))
+	})
+
+	t.Run("allows configured administrator emails", func(t *testing.T) {
+		user := &models.User{ID: uuid.New(), Email: "owner@example.com", Role: models.UserRoleUser}
+		assert.Equal(t, http.StatusOK, serve(user))
+	})
+
+	t.Run("rejects other users", func(t *testing.T) {
+		user := &models.User{ID: uuid.New(), Email: "user@example.com", Role: models.UserRoleUser}
+		assert.Equal(t, http.StatusForbidden, serve(user))
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(nil))
+	})
+}
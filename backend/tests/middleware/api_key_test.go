@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gpd/my-notes/internal/auth"
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/middleware"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPIKeyService accepts the keys in its map
+type fakeAPIKeyService struct {
+	services.APIKeyServiceInterface
+	keys map[string]string
+}
+
+func (s *fakeAPIKeyService) Authenticate(ctx context.Context, key string) (string, error) {
+	userID, ok := s.keys[key]
+	if !ok {
+		return "", services.ErrInvalidAPIKey
+	}
+	return userID, nil
+}
+
+// fakeKeyUserService returns the users in its map
+type fakeKeyUserService struct {
+	services.UserServiceInterface
+	users map[string]*models.User
+}
+
+func (s *fakeKeyUserService) GetByID(ctx context.Context, userID string) (*models.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	active := &models.User{ID: uuid.New(), Email: "active@example.com"}
+	disabledAt := time.Now()
+	disabled := &models.User{ID: uuid.New(), Email: "disabled@example.com", DisabledAt: &disabledAt}
+
+	keys := &fakeAPIKeyService{keys: map[string]string{
+		"mn_active":   active.ID.String(),
+		"mn_disabled": disabled.ID.String(),
+	}}
+	users := &fakeKeyUserService{users: map[string]*models.User{
+		active.ID.String():   active,
+		disabled.ID.String(): disabled,
+	}}
+
+	var authenticated *models.User
+	handler := middleware.APIKeyAuth(keys, users)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated, _ = r.Context().Value("user").(*models.User)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(header, value string) int {
+		authenticated = nil
+		req := httptest.NewRequest("POST", "/api/v1/capture", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("accepts the X-API-Key header", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(middleware.APIKeyHeader, "mn_active"))
+		assert.Equal(t, active, authenticated)
+	})
+
+	t.Run("accepts a bearer key", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("Authorization", "Bearer mn_active"))
+		assert.Equal(t, active, authenticated)
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(middleware.APIKeyHeader, "mn_revoked"))
+		assert.Nil(t, authenticated)
+	})
+
+	t.Run("requires a key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve("", ""))
+	})
+
+	t.Run("rejects disabled accounts", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(middleware.APIKeyHeader, "mn_disabled"))
+	})
+}
+
+func TestEnhancedAuthAPIKeys(t *testing.T) {
+	tokenService := auth.NewTokenService("test-secret-key-for-testing-only", 15*time.Minute, 24*time.Hour, "test-issuer", "test-audience")
+	userService := NewMockUserService()
+	user := createTestUser(t)
+	userService.AddUser(user)
+
+	securityMiddleware := middleware.NewSecurityMiddleware(tokenService, userService, config.GetDefaultSecurityConfig(), nil)
+	securityMiddleware.SetAPIKeyService(&fakeAPIKeyService{keys: map[string]string{"mn_active": user.ID.String()}})
+
+	var apiKeyAuth bool
+	handler := securityMiddleware.EnhancedAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeyAuth, _ = r.Context().Value("apiKeyAuth").(bool)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, key string) int {
+		apiKeyAuth = false
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("accepts keys on note routes", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("/api/v1/notes", "mn_active"))
+		assert.True(t, apiKeyAuth)
+		assert.Equal(t, http.StatusOK, serve("/api/v1/search/notes", "mn_active"))
+	})
+
+	t.Run("rejects keys on other routes", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve("/api/v1/api-keys", "mn_active"))
+		assert.Equal(t, http.StatusForbidden, serve("/api/v1/account", "mn_active"))
+		assert.Equal(t, http.StatusForbidden, serve("/api/v1/notesx", "mn_active"))
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve("/api/v1/notes", "mn_unknown"))
+		assert.False(t, apiKeyAuth)
+	})
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gpd/my-notes/internal/config"
+	"github.com/gpd/my-notes/internal/handlers"
+	"github.com/gpd/my-notes/internal/middleware"
+	"github.com/gpd/my-notes/internal/models"
+	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idempotencyEntry is a key held by fakeIdempotencyService
+type idempotencyEntry struct {
+	request  string
+	response *models.IdempotentResponse
+}
+
+// fakeIdempotencyService keeps keys in memory
+type fakeIdempotencyService struct {
+	entries map[string]*idempotencyEntry
+}
+
+func (s *fakeIdempotencyService) Begin(ctx context.Context, userID, key, method, path, requestHash string) (*models.IdempotentResponse, error) {
+	request := method + " " + path + " " + requestHash
+	entry, ok := s.entries[userID+"/"+key]
+	if !ok {
+		s.entries[userID+"/"+key] = &idempotencyEntry{request: request}
+		return nil, nil
+	}
+	if entry.request != request {
+		return nil, services.ErrIdempotencyKeyReused
+	}
+	if entry.response == nil {
+		return nil, services.ErrIdempotencyKeyInProgress
+	}
+	return entry.response, nil
+}
+
+func (s *fakeIdempotencyService) Complete(ctx context.Context, userID, key string, response *models.IdempotentResponse) error {
+	s.entries[userID+"/"+key].response = response
+	return nil
+}
+
+func (s *fakeIdempotencyService) Release(ctx context.Context, userID, key string) error {
+	if entry, ok := s.entries[userID+"/"+key]; ok && entry.response == nil {
+		delete(s.entries, userID+"/"+key)
+	}
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+
+	setup := func(status int) (http.Handler, *fakeIdempotencyService, *int) {
+		svc := &fakeIdempotencyService{entries: make(map[string]*idempotencyEntry)}
+		calls := 0
+		handler := middleware.Idempotency(svc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"call":%d,"body":%s}`, calls, body)
+		}))
+		return handler, svc, &calls
+	}
+
+	serve := func(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/notes", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("passes requests without a key through", func(t *testing.T) {
+		handler, svc, calls := setup(http.StatusCreated)
+		serve(handler, "", `{}`)
+		serve(handler, "", `{}`)
+		assert.Equal(t, 2, *calls)
+		assert.Empty(t, svc.entries)
+	})
+
+	t.Run("replays the response to a retry", func(t *testing.T) {
+		handler, _, calls := setup(http.StatusCreated)
+		first := serve(handler, "key-1", `{"title":"a"}`)
+		retry := serve(handler, "key-1", `{"title":"a"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, `{"call":1,"body":{"title":"a"}}`, first.Body.String())
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+		assert.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+	})
+
+	t.Run("rejects a key reused for another request", func(t *testing.T) {
+		handler, _, calls := setup(http.StatusCreated)
+		serve(handler, "key-1", `{"title":"a"}`)
+		w := serve(handler, "key-1", `{"title":"b"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("rejects a retry while the request is in progress", func(t *testing.T) {
+		handler, svc, calls := setup(http.StatusCreated)
+		serve(handler, "key-1", `{}`)
+		// Still claimed, as if the first request had not finished
+		svc.entries[user.ID.String()+"/key-1"].response = nil
+		w := serve(handler, "key-1", `{}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("lets server errors and confirmations be retried", func(t *testing.T) {
+		for _, status := range []int{http.StatusInternalServerError, http.StatusPreconditionRequired} {
+			handler, svc, calls := setup(status)
+			serve(handler, "key-1", `{}`)
+			assert.Empty(t, svc.entries)
+			serve(handler, "key-1", `{}`)
+			assert.Equal(t, 2, *calls)
+		}
+	})
+
+	t.Run("rejects keys that are too long", func(t *testing.T) {
+		handler, _, calls := setup(http.StatusCreated)
+		w := serve(handler, strings.Repeat("k", 256), `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 0, *calls)
+	})
+}
+
+func TestIdempotentNoteCreationInDatabase(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration tests in short mode")
+	}
+
+	db := testutil.NewTestDB(t, config.GetTestDatabaseConfig(), "../../migrations")
+	user := testutil.NewTestUser(t, db)
+	noteService := services.NewNoteService(db, services.NewTagService(db))
+	notesHandler := handlers.NewNotesHandler(noteService, nil, nil, nil)
+	handler := middleware.Idempotency(services.NewIdempotencyService(db))(http.HandlerFunc(notesHandler.CreateNote))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/notes", strings.NewReader(`{"title":"Retried","content":"Sent twice"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, "create-once")
+		req = req.WithContext(context.WithValue(req.Context(), "user", user))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := serve()
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	retry := serve()
+	require.Equal(t, http.StatusCreated, retry.Code, retry.Body.String())
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM notes WHERE user_id = $1", user.ID.String()).Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/gpd/my-notes/internal/middleware"
 	"github.com/gpd/my-notes/internal/models"
 	"github.com/gpd/my-notes/internal/services"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/gpd/my-notes/tests"
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
@@ -39,27 +41,14 @@ func (suite *SessionMiddlewareTestSuite) SetupSuite() {
 	cfg, err := config.LoadConfig("")
 	require.NoError(suite.T(), err, "Failed to load config")
 
-	// Create test database
-	db, err := database.CreateTestDatabase(cfg.Database)
-	require.NoError(suite.T(), err, "Failed to create test database")
-	suite.db = db
-
-	// Run migrations
-	migrator := database.NewMigrator(db, "../../migrations")
-	err = migrator.Up()
-	require.NoError(suite.T(), err, "Failed to run migrations")
+	// Create a migrated test database, dropped after the suite
+	suite.db = testutil.NewTestDB(suite.T(), cfg.Database, "../../migrations")
 
 	suite.userService = services.NewUserService(suite.db)
-	suite.testUserID = "3631d096-4834-4a5f-a173-ae871efb408e"
 	suite.cleanupSessions = make([]string, 0)
 
 	// Create test user (required for session foreign key constraint)
-	_, err = suite.db.Exec(`
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		ON CONFLICT (id) DO NOTHING
-	`, suite.testUserID, "test-google-id-session-test", "session-test@example.com")
-	require.NoError(suite.T(), err, "Failed to create test user")
+	suite.testUserID = testutil.NewTestUser(suite.T(), suite.db).ID.String()
 }
 
 // SetupTest runs before each test
@@ -86,17 +75,10 @@ func (suite *SessionMiddlewareTestSuite) TearDownTest() {
 	suite.cleanupSessions = make([]string, 0)
 }
 
-// TearDownSuite runs once after all tests
-func (suite *SessionMiddlewareTestSuite) TearDownSuite() {
-	if suite.db != nil {
-		database.DropTestDatabase(suite.db)
-	}
-}
-
 // TestSessionCleanupIntegration tests the complete session cleanup functionality
 func (suite *SessionMiddlewareTestSuite) TestSessionCleanupIntegration() {
 	// Get initial session count
-	initialSessions, err := suite.userService.GetActiveSessions(suite.testUserID)
+	initialSessions, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err, "Failed to get initial sessions")
 
 	initialCount := len(initialSessions)
@@ -115,7 +97,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionCleanupIntegration() {
 	time.Sleep(10 * time.Millisecond)
 
 	// Verify we have exceeded the limit
-	sessionsBeforeCleanup, err := suite.userService.GetActiveSessions(suite.testUserID)
+	sessionsBeforeCleanup, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err, "Failed to get sessions before cleanup")
 	assert.GreaterOrEqual(suite.T(), len(sessionsBeforeCleanup), 5, "Should have at least 5 sessions (initial + test)")
 
@@ -127,7 +109,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionCleanupIntegration() {
 	require.NoError(suite.T(), err, "Session limit check should succeed after cleanup")
 
 	// Verify cleanup occurred
-	sessionsAfterCleanup, err := suite.userService.GetActiveSessions(suite.testUserID)
+	sessionsAfterCleanup, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err, "Failed to get sessions after cleanup")
 
 	suite.T().Logf("Session count after cleanup: %d", len(sessionsAfterCleanup))
@@ -153,7 +135,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionCleanupIntegration() {
 // TestSessionCleanupOrdering tests that sessions are cleaned up in the correct order (oldest first)
 func (suite *SessionMiddlewareTestSuite) TestSessionCleanupOrdering() {
 	// Get session count before creating our test sessions
-	sessionsBefore, err := suite.userService.GetActiveSessions(suite.testUserID)
+	sessionsBefore, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err)
 	initialCount := len(sessionsBefore)
 
@@ -172,7 +154,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionCleanupOrdering() {
 	}
 
 	// Verify we now have more sessions
-	sessionsAfterCreation, err := suite.userService.GetActiveSessions(suite.testUserID)
+	sessionsAfterCreation, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err)
 	require.Greater(suite.T(), len(sessionsAfterCreation), initialCount,
 		"Should have more sessions after creating test sessions")
@@ -183,7 +165,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionCleanupOrdering() {
 	require.NoError(suite.T(), err)
 
 	// Verify cleanup occurred - we should have fewer sessions now
-	finalSessions, err := suite.userService.GetActiveSessions(suite.testUserID)
+	finalSessions, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err)
 
 	// We should have approximately the initial count + 2 (our limit for new sessions)
@@ -211,7 +193,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionInvalidation() {
 	suite.cleanupSessions = append(suite.cleanupSessions, session.ID)
 
 	// Verify session is active
-	activeSessions, err := suite.userService.GetActiveSessions(suite.testUserID)
+	activeSessions, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err)
 
 	foundActive := false
@@ -228,7 +210,7 @@ func (suite *SessionMiddlewareTestSuite) TestSessionInvalidation() {
 	require.NoError(suite.T(), err, "Session invalidation should succeed")
 
 	// Verify session is no longer active
-	activeSessions, err = suite.userService.GetActiveSessions(suite.testUserID)
+	activeSessions, err = suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	require.NoError(suite.T(), err)
 
 	foundActive = false
@@ -287,7 +269,7 @@ func (suite *SessionMiddlewareTestSuite) simulateSessionLimitCheck() error {
 // simulateSessionLimitCheckWithCustomLimit simulates the session limit check with a custom limit
 func (suite *SessionMiddlewareTestSuite) simulateSessionLimitCheckWithCustomLimit(maxSessions int) error {
 	// This simulates what happens in the session middleware when checking concurrency limits
-	sessions, err := suite.userService.GetActiveSessions(suite.testUserID)
+	sessions, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	if err != nil {
 		return fmt.Errorf("failed to check session limits: %w", err)
 	}
@@ -302,7 +284,7 @@ func (suite *SessionMiddlewareTestSuite) simulateSessionLimitCheckWithCustomLimi
 
 // cleanupOldestSessions simulates the cleanup logic from the middleware
 func (suite *SessionMiddlewareTestSuite) cleanupOldestSessions(maxSessions int) error {
-	sessions, err := suite.userService.GetActiveSessions(suite.testUserID)
+	sessions, err := suite.userService.GetActiveSessions(context.Background(), suite.testUserID)
 	if err != nil {
 		return fmt.Errorf("failed to get sessions for cleanup: %w", err)
 	}
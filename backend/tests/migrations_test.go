@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gpd/my-notes/internal/database"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +63,37 @@ func TestMigrationStatus(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestMigrationChecksums(t *testing.T) {
+	if !USE_POSTGRE_DURING_TEST {
+		t.Skip("PostgreSQL tests are disabled. Set USE_POSTGRE_DURING_TEST=true to enable.")
+	}
+
+	// Create test database
+	db := SetupTestDB(t)
+	defer CleanupTestDB(t, db)
+
+	migrator := database.NewMigrator(db, "../migrations")
+	require.NoError(t, migrator.CreateMigrationsTable())
+
+	// Applied migrations match their files
+	drifted, err := migrator.Verify()
+	require.NoError(t, err)
+	assert.Empty(t, drifted)
+
+	// A changed checksum is reported as drift
+	var version string
+	require.NoError(t, db.QueryRow("SELECT version FROM schema_migrations ORDER BY version LIMIT 1").Scan(&version))
+	_, err = db.Exec("UPDATE schema_migrations SET checksum = 'changed' WHERE version = $1", version)
+	require.NoError(t, err)
+
+	drifted, err = migrator.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, []string{version}, drifted)
+
+	// Migrating refuses to run on drift
+	assert.Error(t, migrator.To("0"))
+}
+
 func TestUsersTableStructure(t *testing.T) {
 	if !USE_POSTGRE_DURING_TEST {
 		t.Skip("PostgreSQL tests are disabled. Set USE_POSTGRE_DURING_TEST=true to enable.")
@@ -237,6 +269,7 @@ func TestTagsTableStructure(t *testing.T) {
 	// Check columns
 	expectedColumns := map[string]string{
 		"id":         "uuid",
+		"user_id":    "uuid",
 		"name":       "character varying",
 		"created_at": "timestamp with time zone",
 	}
@@ -256,30 +289,37 @@ func TestTagsTableStructure(t *testing.T) {
 		})
 	}
 
-	// Test unique constraint on name
+	// Test unique constraint on user and name
 	t.Run("UniqueNameConstraint", func(t *testing.T) {
-		_ = CreateTestTag(t, db, "#test")
+		userID := CreateTestUser(t, db, "tags@example.com")
+		otherUserID := CreateTestUser(t, db, "tags2@example.com")
+		_ = CreateTestTag(t, db, userID, "#test")
 
-		// Try to create another tag with same name (should fail)
+		// Try to create another tag with same name for the same user (should fail)
 		query := `
-			INSERT INTO tags (id, name, created_at)
-			VALUES ($1, $2, NOW())
+			INSERT INTO tags (id, user_id, name, created_at)
+			VALUES ($1, $2, $3, NOW())
 		`
-		_, err := db.Exec(query, "test_tag_2", "#test")
-		assert.Error(t, err, "Should fail due to unique constraint on name")
+		_, err := db.Exec(query, uuid.New(), userID, "#test")
+		assert.Error(t, err, "Should fail due to unique constraint on user and name")
+
+		// Other users have their own tags with the same name
+		_, err = db.Exec(query, uuid.New(), otherUserID, "#test")
+		assert.NoError(t, err)
 	})
 
 	// Test valid_tag_name constraint
 	t.Run("ValidTagNameConstraint", func(t *testing.T) {
 		// Try to create tag with invalid name (should fail)
+		userID := CreateTestUser(t, db, "tagnames@example.com")
 		query := `
-			INSERT INTO tags (id, name, created_at)
-			VALUES ($1, $2, NOW())
+			INSERT INTO tags (id, user_id, name, created_at)
+			VALUES ($1, $2, $3, NOW())
 		`
-		_, err := db.Exec(query, "test_tag_invalid", "invalid-tag") // doesn't start with #
+		_, err := db.Exec(query, uuid.New(), userID, "invalid-tag") // doesn't start with #
 		assert.Error(t, err, "Should fail due to valid_tag_name constraint")
 
-		_, err = db.Exec(query, "test_tag_invalid2", "#invalid tag") // contains space
+		_, err = db.Exec(query, uuid.New(), userID, "#invalid tag") // contains space
 		assert.Error(t, err, "Should fail due to valid_tag_name constraint")
 	})
 }
@@ -319,7 +359,7 @@ func TestNoteTagsTableStructure(t *testing.T) {
 		// Create user, note, and tag
 		userID := CreateTestUser(t, db, "jointable@example.com")
 		noteID := CreateTestNote(t, db, userID, "Test Note", "Test content")
-		tagID := CreateTestTag(t, db, "#test")
+		tagID := CreateTestTag(t, db, userID, "#test")
 
 		// Create note-tag relationship
 		query := `
@@ -350,7 +390,7 @@ func TestNoteTagsTableStructure(t *testing.T) {
 		// Create user, note, and tag
 		userID := CreateTestUser(t, db, "cascade2@example.com")
 		noteID := CreateTestNote(t, db, userID, "Test Note", "Test content")
-		tagID := CreateTestTag(t, db, "#test2")
+		tagID := CreateTestTag(t, db, userID, "#test2")
 
 		// Create relationship
 		query := `
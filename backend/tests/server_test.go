@@ -9,9 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gpd/my-notes/internal/app"
 	"github.com/gpd/my-notes/internal/config"
-	"github.com/gpd/my-notes/internal/handlers"
 	"github.com/gpd/my-notes/internal/server"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	_ "github.com/lib/pq"
@@ -75,8 +76,7 @@ func TestServerInitialization(t *testing.T) {
 	}
 
 	db := createTestDB()
-	handlersInstance := handlers.NewHandlers()
-	srv := server.NewServer(cfg, handlersInstance, db)
+	srv := server.NewServer(app.New(cfg, db, app.Options{}))
 
 	require.NotNil(t, srv)
 	require.NotNil(t, srv.GetRouter())
@@ -104,9 +104,8 @@ func TestHealthEndpoint(t *testing.T) {
 		},
 	}
 
-	handlers := handlers.NewHandlers()
 	db := createTestDB()
-	srv := server.NewServer(cfg, handlers, db)
+	srv := server.NewServer(app.New(cfg, db, app.Options{}))
 	router := srv.GetRouter()
 
 	// Create request
@@ -147,14 +146,13 @@ func TestCORSMiddleware(t *testing.T) {
 		CORS: config.CORSConfig{
 			AllowedOrigins: []string{"http://localhost:3000", "chrome-extension://*"},
 			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"*"},
-			MaxAge:         86400,
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+			MaxAge:         600,
 		},
 	}
 
-	handlers := handlers.NewHandlers()
 	db := createTestDB()
-	srv := server.NewServer(cfg, handlers, db)
+	srv := server.NewServer(app.New(cfg, db, app.Options{}))
 	router := srv.GetRouter()
 
 	tests := []struct {
@@ -192,6 +190,13 @@ func TestCORSMiddleware(t *testing.T) {
 			expectedOrigin: "", // Disallowed origins get no CORS headers
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "Disallowed preflight request",
+			origin:         "http://evil.com",
+			method:         "OPTIONS",
+			expectedOrigin: "",
+			expectedStatus: http.StatusNoContent,
+		},
 	}
 
 	for _, tt := range tests {
@@ -210,10 +215,15 @@ func TestCORSMiddleware(t *testing.T) {
 			// CORS middleware doesn't set Access-Control-Allow-Credentials (not configured to allow credentials)
 			assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Credentials"))
 
-			if tt.method == "OPTIONS" {
+			assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+
+			// The configured CORS settings replace the environment's
+			if tt.method == "OPTIONS" && tt.expectedOrigin != "" {
 				assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", rr.Header().Get("Access-Control-Allow-Methods"))
-				assert.Equal(t, "Content-Type, Authorization, X-Request-ID", rr.Header().Get("Access-Control-Allow-Headers"))
-				assert.Equal(t, "86400", rr.Header().Get("Access-Control-Max-Age"))
+				assert.Equal(t, "Content-Type, Authorization", rr.Header().Get("Access-Control-Allow-Headers"))
+				assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+			} else if tt.method == "OPTIONS" {
+				assert.Empty(t, rr.Header().Get("Access-Control-Allow-Methods"))
 			}
 		})
 	}
@@ -222,14 +232,13 @@ func TestCORSMiddleware(t *testing.T) {
 func TestRequestIDMiddleware(t *testing.T) {
 	cfg := GetServerTestConfig()
 
-	handlers := handlers.NewHandlers()
-	srv := server.NewServer(&config.Config{
+	srv := server.NewServer(app.New(&config.Config{
 		Server: cfg.Server,
 		App:    cfg.App,
 		CORS: config.CORSConfig{
 			AllowedOrigins: []string{"*"},
 		},
-	}, handlers, createTestDB())
+	}, createTestDB(), app.Options{}))
 	router := srv.GetRouter()
 
 	req, err := http.NewRequest("GET", "/api/v1/health", nil)
@@ -253,14 +262,13 @@ func TestRequestIDMiddleware(t *testing.T) {
 func TestSecurityHeadersMiddleware(t *testing.T) {
 	cfg := GetServerTestConfig()
 
-	handlers := handlers.NewHandlers()
-	srv := server.NewServer(&config.Config{
+	srv := server.NewServer(app.New(&config.Config{
 		Server: cfg.Server,
 		App:    cfg.App,
 		CORS: config.CORSConfig{
 			AllowedOrigins: []string{"*"},
 		},
-	}, handlers, createTestDB())
+	}, createTestDB(), app.Options{}))
 	router := srv.GetRouter()
 
 	req, err := http.NewRequest("GET", "/api/v1/health", nil)
@@ -281,14 +289,13 @@ func TestSecurityHeadersMiddleware(t *testing.T) {
 func TestContentTypeMiddleware(t *testing.T) {
 	cfg := GetServerTestConfig()
 
-	handlers := handlers.NewHandlers()
-	srv := server.NewServer(&config.Config{
+	srv := server.NewServer(app.New(&config.Config{
 		Server: cfg.Server,
 		App:    cfg.App,
 		CORS: config.CORSConfig{
 			AllowedOrigins: []string{"*"},
 		},
-	}, handlers, createTestDB())
+	}, createTestDB(), app.Options{}))
 	router := srv.GetRouter()
 
 	tests := []struct {
@@ -324,14 +331,13 @@ func TestContentTypeMiddleware(t *testing.T) {
 func TestNotFoundHandler(t *testing.T) {
 	cfg := GetServerTestConfig()
 
-	handlers := handlers.NewHandlers()
-	srv := server.NewServer(&config.Config{
+	srv := server.NewServer(app.New(&config.Config{
 		Server: cfg.Server,
 		App:    cfg.App,
 		CORS: config.CORSConfig{
 			AllowedOrigins: []string{"*"},
 		},
-	}, handlers, createTestDB())
+	}, createTestDB(), app.Options{}))
 	router := srv.GetRouter()
 
 	req, err := http.NewRequest("GET", "/nonexistent/path", nil)
@@ -346,20 +352,89 @@ func TestNotFoundHandler(t *testing.T) {
 	var response map[string]interface{}
 	err = json.Unmarshal(rr.Body.Bytes(), &response)
 	require.NoError(t, err)
-	assert.Equal(t, "Not found", response["error"])
+	errorBody, ok := response["error"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "NOT_FOUND", errorBody["code"])
+	assert.Equal(t, "Not found", errorBody["message"])
+}
+
+func TestOpenAPIDocs(t *testing.T) {
+	srv := server.NewServer(app.New(GetServerTestConfig(), createTestDB(), app.Options{}))
+	router := srv.GetRouter()
+
+	req, err := http.NewRequest("GET", "/api/v1/openapi.json", nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "test-agent")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// The spec is only served when every route is in handlers.APIRoutes
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Summary   string                     `json:"summary"`
+			Security  []map[string][]string      `json:"security"`
+			Responses map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			SecuritySchemes map[string]json.RawMessage `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &spec))
+	assert.Contains(t, spec.Components.SecuritySchemes, "bearerAuth")
+	assert.Contains(t, spec.Components.SecuritySchemes, "apiKeyAuth")
+
+	for path, operations := range spec.Paths {
+		for method, operation := range operations {
+			assert.NotEmpty(t, operation.Summary, "%s %s has no summary", method, path)
+			assert.Contains(t, operation.Responses, "500", "%s %s has no error responses", method, path)
+		}
+	}
+
+	notes := spec.Paths["/api/v1/notes"]["post"]
+	assert.Len(t, notes.Security, 2, "notes accept access tokens and API keys")
+	assert.Contains(t, notes.Responses, "201")
+	assert.Empty(t, spec.Paths["/api/v1/health"]["get"].Security)
+
+	req, err = http.NewRequest("GET", "/api/v1/docs", nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "test-agent")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rr.Body.String(), "openapi.json")
+}
+
+func TestBatchNoteRoutes(t *testing.T) {
+	srv := server.NewServer(app.New(GetServerTestConfig(), createTestDB(), app.Options{}))
+	router := srv.GetRouter()
+
+	// "batch" must not be taken as the ID of /notes/{id}
+	for _, method := range []string{"POST", "PUT"} {
+		req := httptest.NewRequest(method, "/api/v1/notes/batch", nil)
+		var match mux.RouteMatch
+		require.True(t, router.Match(req, &match), "%s /notes/batch has no route", method)
+		template, err := match.Route.GetPathTemplate()
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/notes/batch", template, "%s /notes/batch", method)
+	}
 }
 
 func TestServerGracefulShutdown(t *testing.T) {
 	cfg := GetServerTestConfig()
 
-	handlers := handlers.NewHandlers()
-	srv := server.NewServer(&config.Config{
+	srv := server.NewServer(app.New(&config.Config{
 		Server: cfg.Server,
 		App:    cfg.App,
 		CORS: config.CORSConfig{
 			AllowedOrigins: []string{"*"},
 		},
-	}, handlers, createTestDB())
+	}, createTestDB(), app.Options{}))
 
 	// Test that shutdown doesn't panic
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
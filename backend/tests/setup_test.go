@@ -8,6 +8,7 @@ import (
 
 	"github.com/gpd/my-notes/internal/config"
 	"github.com/gpd/my-notes/internal/database"
+	"github.com/gpd/my-notes/internal/testutil"
 	"github.com/google/uuid"
 )
 
@@ -114,22 +115,7 @@ func CreateTestUser(t *testing.T, db *sql.DB, email string) string {
 		t.Skip("PostgreSQL tests are disabled. Set USE_POSTGRE_DURING_TEST=true to enable.")
 	}
 
-	userID := uuid.New()
-	googleID := fmt.Sprintf("google_%s", userID.String())
-
-	query := `
-		INSERT INTO users (id, google_id, email, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id
-	`
-
-	var id string
-	err := db.QueryRow(query, userID, googleID, email).Scan(&id)
-	if err != nil {
-		t.Fatalf("Failed to create test user: %v", err)
-	}
-
-	return id
+	return testutil.NewTestUser(t, db, testutil.WithEmail(email)).ID.String()
 }
 
 // CreateTestNote creates a test note in the database
@@ -139,45 +125,17 @@ func CreateTestNote(t *testing.T, db *sql.DB, userID string, title, content stri
 		t.Skip("PostgreSQL tests are disabled. Set USE_POSTGRE_DURING_TEST=true to enable.")
 	}
 
-	noteID := uuid.New()
-
-	query := `
-		INSERT INTO notes (id, user_id, title, content, created_at, updated_at, version)
-		VALUES ($1, $2, $3, $4, NOW(), NOW(), 1)
-		RETURNING id
-	`
-
-	var id string
-	err := db.QueryRow(query, noteID, userID, title, content).Scan(&id)
-	if err != nil {
-		t.Fatalf("Failed to create test note: %v", err)
-	}
-
-	return id
+	return testutil.NewTestNote(t, db, uuid.MustParse(userID), title, content).ID.String()
 }
 
-// CreateTestTag creates a test tag in the database
-func CreateTestTag(t *testing.T, db *sql.DB, name string) string {
+// CreateTestTag creates a test tag of the user in the database
+func CreateTestTag(t *testing.T, db *sql.DB, userID, name string) string {
 	t.Helper()
 	if !USE_POSTGRE_DURING_TEST {
 		t.Skip("PostgreSQL tests are disabled. Set USE_POSTGRE_DURING_TEST=true to enable.")
 	}
 
-	tagID := uuid.New()
-
-	query := `
-		INSERT INTO tags (id, name, created_at)
-		VALUES ($1, $2, NOW())
-		RETURNING id
-	`
-
-	var id string
-	err := db.QueryRow(query, tagID, name).Scan(&id)
-	if err != nil {
-		t.Fatalf("Failed to create test tag: %v", err)
-	}
-
-	return id
+	return testutil.NewTestTag(t, db, uuid.MustParse(userID), name).String()
 }
 
 // AssertTableRowCount asserts the number of rows in a table